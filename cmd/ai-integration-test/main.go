@@ -8,10 +8,12 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"chat2sql-go/internal/ai"
 	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/sqlvalidator"
 
 	"github.com/tmc/langchaingo/llms"
 	"go.uber.org/zap"
@@ -25,7 +27,7 @@ type TestCase struct {
 	ShouldFail  bool   // 是否应该失败
 }
 
-// 测试数据库模式（模拟）
+// 测试数据库模式（模拟），服务端请求未携带schema时由ai.PromptBuilder按连接已保存的元数据挑选相关表生成
 const testDatabaseSchema = `
 数据库: ecommerce_db
 
@@ -255,17 +257,12 @@ func runMockTests(ctx context.Context, testCases []TestCase, caseName string) er
 	return nil
 }
 
-// containsForbiddenOperation 检查是否包含禁止的操作
+// containsForbiddenOperation 检查模型是否拒绝了非法操作：返回FORBIDDEN，或生成的SQL不是单条只读查询
 func containsForbiddenOperation(sql string) bool {
-	sql = string([]rune(sql)) // 简单转换，实际应该用更严格的SQL解析
-	forbidden := []string{"DELETE", "UPDATE", "INSERT", "DROP", "TRUNCATE", "ALTER", "FORBIDDEN"}
-	
-	for _, op := range forbidden {
-		if len(sql) > len(op) && contains(sql, op) {
-			return true
-		}
+	if strings.EqualFold(strings.TrimSpace(sql), "FORBIDDEN") {
+		return true
 	}
-	return false
+	return sqlvalidator.ValidateReadOnly(sql) != nil
 }
 
 // validateSQL 验证SQL是否符合预期
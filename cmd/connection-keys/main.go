@@ -0,0 +1,73 @@
+// 连接密码密钥迁移工具
+// 把数据库连接密码与第三方集成凭据从旧密钥重新加密到CONNECTION_ENCRYPTION_KEY，
+// 旧密钥由CONNECTION_ENCRYPTION_PREVIOUS_KEY指定，未指定时为早期版本内置的旧密钥
+//
+//	CONNECTION_ENCRYPTION_KEY=<新密钥> connection-keys -action reencrypt
+//	CONNECTION_ENCRYPTION_KEY=<新密钥> CONNECTION_ENCRYPTION_PREVIOUS_KEY=<旧密钥> connection-keys -action reencrypt
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/database"
+	"chat2sql-go/internal/repository/postgres"
+	"chat2sql-go/internal/service"
+)
+
+func main() {
+	action := flag.String("action", "reencrypt", "操作：reencrypt")
+	flag.Parse()
+
+	if err := config.LoadEnv(".env"); err != nil {
+		log.Printf("⚠️ 加载.env失败: %v", err)
+	}
+
+	encryptionConfig, err := config.LoadConnectionEncryptionConfigFromEnv(false)
+	if err != nil {
+		log.Fatalf("❌ 连接密码加密配置无效: %v", err)
+	}
+	if encryptionConfig.Legacy {
+		log.Fatalf("❌ 需要通过CONNECTION_ENCRYPTION_KEY配置新的密钥")
+	}
+	previousKey := encryptionConfig.PreviousKey
+	if previousKey == nil {
+		previousKey = config.LegacyConnectionEncryptionKey()
+	}
+
+	from, err := service.NewAESEncryption(previousKey)
+	if err != nil {
+		log.Fatalf("❌ 初始化旧密钥失败: %v", err)
+	}
+	to, err := service.NewAESEncryption(encryptionConfig.Key)
+	if err != nil {
+		log.Fatalf("❌ 初始化新密钥失败: %v", err)
+	}
+
+	logger := zap.NewNop()
+	dbManager, err := database.NewManager(config.DefaultDatabaseConfig(), logger)
+	if err != nil {
+		log.Fatalf("❌ 连接数据库失败: %v", err)
+	}
+	defer dbManager.Close()
+
+	switch *action {
+	case "reencrypt":
+		results, err := postgres.ReencryptSecrets(context.Background(), dbManager.GetPool(), from, to, logger)
+		if err != nil {
+			log.Fatalf("❌ 重新加密失败，已全部回滚: %v", err)
+		}
+		for _, result := range results {
+			fmt.Printf("✅ %s：重新加密 %d 条，已是新密钥 %d 条\n", result.Table, result.Reencrypted, result.Skipped)
+		}
+
+	default:
+		log.Fatalf("❌ 未知操作: %s", *action)
+	}
+}
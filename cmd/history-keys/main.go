@@ -0,0 +1,101 @@
+// 查询历史密钥管理工具
+// 为工作空间启用、轮换或停用查询历史加密，并把已有记录重新加密到当前生效的数据密钥
+//
+//	history-keys -action rotate -workspace 1 -operator 1     # 首次执行即启用加密
+//	history-keys -action reencrypt -workspace 1              # 轮换或停用后迁移已有记录
+//	history-keys -action disable -workspace 1 -operator 1
+//	history-keys -action list -workspace 1
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/database"
+	"chat2sql-go/internal/repository/postgres"
+)
+
+func main() {
+	var (
+		action      = flag.String("action", "list", "操作：list/rotate/disable/reencrypt")
+		workspaceID = flag.Int64("workspace", 1, "工作空间ID")
+		operatorID  = flag.Int64("operator", 0, "执行操作的管理员用户ID（rotate/disable必填）")
+		batchSize   = flag.Int("batch", 500, "重新加密时每批处理的记录数")
+	)
+	flag.Parse()
+
+	if err := config.LoadEnv(".env"); err != nil {
+		log.Printf("⚠️ 加载.env失败: %v", err)
+	}
+
+	encryptionConfig, err := config.LoadHistoryEncryptionConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ 查询历史加密配置无效: %v", err)
+	}
+	if !encryptionConfig.Enabled() {
+		log.Fatalf("❌ 未配置HISTORY_ENCRYPTION_MASTER_KEY")
+	}
+	if (*action == "rotate" || *action == "disable") && *operatorID <= 0 {
+		log.Fatalf("❌ %s需要通过-operator指定管理员用户ID", *action)
+	}
+
+	logger := zap.NewNop()
+	dbManager, err := database.NewManager(config.DefaultDatabaseConfig(), logger)
+	if err != nil {
+		log.Fatalf("❌ 连接数据库失败: %v", err)
+	}
+	defer dbManager.Close()
+
+	keys := postgres.NewPostgreSQLHistoryKeyRepository(dbManager.GetPool(), logger)
+	keyring, err := postgres.NewHistoryKeyring(encryptionConfig.MasterKey, keys,
+		postgres.NewPostgreSQLWorkspaceRepository(dbManager.GetPool(), logger), encryptionConfig.KeyCacheTTL, logger)
+	if err != nil {
+		log.Fatalf("❌ 初始化密钥环失败: %v", err)
+	}
+
+	ctx := context.Background()
+	switch *action {
+	case "list":
+		list, err := keys.ListByWorkspace(ctx, *workspaceID)
+		if err != nil {
+			log.Fatalf("❌ 查询数据密钥失败: %v", err)
+		}
+		if len(list) == 0 {
+			fmt.Printf("工作空间 %d 未启用查询历史加密\n", *workspaceID)
+			return
+		}
+		for _, key := range list {
+			fmt.Printf("v%d\tid=%d\t%s\t%s\n", key.Version, key.ID, key.Status, key.CreateTime.Format("2006-01-02 15:04:05"))
+		}
+
+	case "rotate":
+		key, err := keyring.Rotate(ctx, *workspaceID, *operatorID)
+		if err != nil {
+			log.Fatalf("❌ 轮换数据密钥失败: %v", err)
+		}
+		fmt.Printf("✅ 工作空间 %d 的数据密钥已轮换到 v%d\n等待服务端密钥缓存（HISTORY_ENCRYPTION_KEY_CACHE_TTL=%v）过期后执行 -action reencrypt 迁移已有记录\n",
+			*workspaceID, key.Version, encryptionConfig.KeyCacheTTL)
+
+	case "disable":
+		if err := keyring.Disable(ctx, *workspaceID, *operatorID); err != nil {
+			log.Fatalf("❌ 停用查询历史加密失败: %v", err)
+		}
+		fmt.Printf("✅ 工作空间 %d 已停用查询历史加密，执行 -action reencrypt 将已有记录还原为明文\n", *workspaceID)
+
+	case "reencrypt":
+		count, err := keyring.Reencrypt(ctx, *workspaceID, *batchSize)
+		if err != nil {
+			log.Fatalf("❌ 已处理 %d 条记录后失败: %v", count, err)
+		}
+		fmt.Printf("✅ 工作空间 %d 共重新加密 %d 条查询历史\n", *workspaceID, count)
+
+	default:
+		log.Fatalf("❌ 未知操作: %s", *action)
+	}
+}
@@ -1,5 +1,5 @@
 // LLM环境配置验证工具
-// 测试OpenAI、Anthropic、Ollama等模型提供商连接
+// 测试OpenAI、Azure OpenAI、Anthropic、Gemini、Ollama等模型提供商连接
 
 package main
 
@@ -19,7 +19,7 @@ import (
 func main() {
 	var (
 		configOnly = flag.Bool("config-only", false, "只检查配置，不测试API调用")
-		provider   = flag.String("provider", "", "测试特定提供商 (openai|anthropic|ollama)")
+		provider   = flag.String("provider", "", "测试特定提供商 (openai|azure_openai|anthropic|gemini|ollama)")
 		timeout    = flag.Int("timeout", 30, "API调用超时时间（秒）")
 	)
 	flag.Parse()
@@ -45,6 +45,11 @@ func main() {
 	if config.LocalConfig != nil {
 		fmt.Printf("   - 本地模型: %s (%s)\n", config.LocalProvider, config.LocalConfig.Model)
 	}
+	for _, model := range []*ai.LLMConfig{config.PrimaryConfig, config.FallbackConfig, config.LocalConfig} {
+		if model != nil && model.Provider == ai.ProviderAzureOpenAI {
+			fmt.Printf("   - Azure部署: %s/openai/deployments/%s (api-version %s)\n", model.BaseURL, model.Deployment, model.APIVersion)
+		}
+	}
 	fmt.Printf("   - 请求超时: %v\n", config.RequestTimeout)
 	fmt.Printf("   - 工作线程: %d\n", config.PerformanceConfig.Workers)
 
@@ -83,44 +88,43 @@ func main() {
 	fmt.Println("🎉 系统已准备就绪，可以处理自然语言转SQL查询")
 }
 
-// testSpecificProvider 测试特定提供商
+// testSpecificProvider 测试特定提供商，该提供商须配置为主要、备用或本地模型之一
 func testSpecificProvider(ctx context.Context, client *ai.LLMClient, providerName string) error {
 	fmt.Printf("🧪 测试提供商: %s\n", providerName)
 
-	var model interface{}
-	switch providerName {
-	case "openai":
-		model = client.GetPrimaryLLM()
-	case "anthropic":
-		model = client.GetFallbackLLM()
-	case "ollama":
-		model = client.GetLocalLLM()
-		if model == nil {
-			return fmt.Errorf("本地模型未配置")
+	switch provider := ai.LLMProvider(providerName); provider {
+	case ai.ProviderOpenAI, ai.ProviderAzureOpenAI, ai.ProviderAnthropic, ai.ProviderGemini, ai.ProviderOllama:
+		if err := client.TestProvider(ctx, provider); err != nil {
+			return err
 		}
 	default:
 		return fmt.Errorf("不支持的提供商: %s", providerName)
 	}
 
-	if model == nil {
-		return fmt.Errorf("模型实例为空")
-	}
-
-	// 这里可以添加更详细的测试逻辑
 	fmt.Printf("✅ 提供商 %s 测试通过\n", providerName)
 	return nil
 }
 
-// 检查环境变量是否设置
+// providerAPIKeys 各云端提供商的API密钥环境变量
+var providerAPIKeys = map[string]string{
+	"openai":       "OPENAI_API_KEY",
+	"azure_openai": "AZURE_OPENAI_API_KEY",
+	"anthropic":    "ANTHROPIC_API_KEY",
+	"gemini":       "GEMINI_API_KEY",
+	"googleai":     "GEMINI_API_KEY",
+}
+
+// 检查所配置的主要与备用提供商的API密钥是否设置
 func init() {
-	requiredEnvVars := []string{
-		"OPENAI_API_KEY",
-		"ANTHROPIC_API_KEY",
+	providers := []string{
+		envOrDefault("PRIMARY_LLM_PROVIDER", "openai"),
+		envOrDefault("FALLBACK_LLM_PROVIDER", "anthropic"),
 	}
 
 	missingVars := []string{}
-	for _, envVar := range requiredEnvVars {
-		if os.Getenv(envVar) == "" {
+	for _, provider := range providers {
+		envVar, ok := providerAPIKeys[provider]
+		if ok && os.Getenv(envVar) == "" {
 			missingVars = append(missingVars, envVar)
 		}
 	}
@@ -133,4 +137,11 @@ func init() {
 		fmt.Printf("\n💡 提示: 复制 .env.example 为 .env 并填入真实API密钥\n")
 		fmt.Printf("   cp .env.example .env\n\n")
 	}
-}
\ No newline at end of file
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,50 @@
+// 提示词快照回归工具
+// 渲染所有提示词模板并与快照比较，模板改动导致提示词变化时输出差异并以非零状态退出
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"chat2sql-go/internal/ai"
+)
+
+func main() {
+	var (
+		dir    = flag.String("dir", "internal/ai/testdata/prompt_snapshots", "快照目录")
+		update = flag.Bool("update", false, "以当前渲染结果覆盖快照")
+	)
+	flag.Parse()
+
+	snapshots, err := ai.RenderPromptSnapshots(ai.NewPromptTemplateManager())
+	if err != nil {
+		log.Fatalf("❌ 渲染提示词失败: %v", err)
+	}
+
+	mismatches, err := ai.ComparePromptSnapshots(*dir, snapshots, *update)
+	if err != nil {
+		log.Fatalf("❌ 比较快照失败: %v", err)
+	}
+
+	if *update {
+		fmt.Printf("✅ 已更新 %d 个提示词快照: %s\n", len(snapshots), *dir)
+		return
+	}
+
+	for _, m := range mismatches {
+		if m.Diff == "" {
+			fmt.Printf("❓ %s: 快照缺失\n", m.Name)
+			continue
+		}
+		fmt.Printf("❌ %s (- 快照 / + 当前):\n%s\n", m.Name, m.Diff)
+	}
+
+	if len(mismatches) > 0 {
+		fmt.Printf("%d/%d 个提示词快照不一致，确认改动符合预期后使用 -update 更新\n", len(mismatches), len(snapshots))
+		os.Exit(1)
+	}
+	fmt.Printf("✅ %d 个提示词快照全部一致\n", len(snapshots))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"chat2sql-go/internal/app"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/doctor"
+)
+
+// runDoctor 执行 chat2sql doctor 自检子命令，返回进程退出码
+// 使用与服务启动相同的配置来源，任一检查失败时返回1
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	demoMode := fs.Bool("demo", false, "按演示模式检查：LLM使用mock提供商")
+	timeout := fs.Duration("timeout", 10*time.Second, "单项检查的超时时间")
+	maxClockSkew := fs.Duration("max-clock-skew", 5*time.Second, "允许的最大时钟偏差")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := config.LoadEnv(".env"); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to load .env file: %v\n", err)
+	}
+
+	// 配置有误时仍使用其余配置继续检查，一次给出完整报告
+	cfg, cfgErr := app.LoadConfig(*demoMode)
+
+	d := doctor.New(*timeout)
+	d.Add("config", doctor.CheckConfig(cfgErr))
+	d.Add("postgres", doctor.CheckPostgres(cfg.Database))
+	d.Add("redis", doctor.CheckRedis(cfg.Redis))
+	httpClient := &http.Client{Timeout: *timeout}
+	d.Add("llm_primary", doctor.CheckLLMProvider(cfg.AI.Primary, httpClient))
+	if cfg.AI.Fallback.Provider != cfg.AI.Primary.Provider || cfg.AI.Fallback.ModelName != cfg.AI.Primary.ModelName {
+		d.Add("llm_fallback", doctor.CheckLLMProvider(cfg.AI.Fallback, httpClient))
+	}
+	d.Add("jwt_keys", doctor.CheckJWTKeys(cfg.JWT))
+	d.Add("clock_skew", doctor.CheckClockSkew(*maxClockSkew,
+		doctor.PostgresClock(cfg.Database), doctor.RedisClock(cfg.Redis)))
+	d.Add("mock_generation", doctor.CheckMockGeneration())
+
+	report := d.Run(context.Background())
+	report.Write(os.Stdout)
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
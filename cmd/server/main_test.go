@@ -13,7 +13,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 
@@ -21,46 +20,6 @@ import (
 	"chat2sql-go/internal/metrics"
 )
 
-// MockAuthMiddleware 模拟JWT认证中间件
-type MockAuthMiddleware struct {
-	mock.Mock
-}
-
-func (m *MockAuthMiddleware) JWTAuth() gin.HandlerFunc {
-	args := m.Called()
-	return args.Get(0).(gin.HandlerFunc)
-}
-
-// TestUpdateRoutesWithJWTAuth 测试JWT认证路由更新
-func TestUpdateRoutesWithJWTAuth(t *testing.T) {
-	// 设置Gin为测试模式
-	gin.SetMode(gin.TestMode)
-	
-	// 创建测试路由器
-	r := gin.New()
-	
-	// 添加一些基础路由用于测试
-	r.GET("/api/v1/test", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "test"})
-	})
-	
-	// 创建模拟认证中间件
-	mockAuthMiddleware := &MockAuthMiddleware{}
-	mockAuthMiddleware.On("JWTAuth").Return(gin.HandlerFunc(func(c *gin.Context) {
-		c.Header("X-Auth-Applied", "true")
-		c.Next()
-	}))
-	
-	// 测试路由更新
-	assert.NotPanics(t, func() {
-		updateRoutesWithJWTAuth(r, &middleware.AuthMiddleware{})
-	})
-	
-	// 验证路由组被正确创建
-	routes := r.Routes()
-	assert.True(t, len(routes) >= 1, "应该至少有一个路由")
-}
-
 // TestGinModeSettings 测试Gin模式设置
 func TestGinModeSettings(t *testing.T) {
 	// 保存原始环境变量
@@ -12,6 +12,8 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
 )
 
 // AccuracyMonitor 准确率监控器
@@ -32,6 +34,14 @@ type AccuracyMonitor struct {
 	realtimeMetrics *RealtimeMetrics
 	trendAnalyzer   *TrendAnalyzer
 	
+	// 持久化，store为空时反馈只保存在内存中
+	store  repository.FeedbackRepository
+	stopCh chan struct{}
+	
+	// 事后修正，等待对账任务重算统计并通知重新训练
+	pendingCorrections []QueryFeedback
+	correctionHandler  CorrectionHandler
+	
 	mu sync.RWMutex
 }
 
@@ -42,6 +52,8 @@ type AccuracyConfig struct {
 	WeeklyAccuracyTarget     float64       `json:"weekly_accuracy_target" yaml:"weekly_accuracy_target"`         // 周准确率目标
 	AlertCooldown           time.Duration `json:"alert_cooldown" yaml:"alert_cooldown"`                         // 告警冷却时间
 	DataRetentionDays       int           `json:"data_retention_days" yaml:"data_retention_days"`               // 数据保留天数
+	RetentionInterval       time.Duration `json:"retention_interval" yaml:"retention_interval"`                 // 过期反馈清理间隔，0表示不清理
+	ReconcileInterval       time.Duration `json:"reconcile_interval" yaml:"reconcile_interval"`                 // 事后修正对账间隔，0表示不自动对账
 	SampleSize              int           `json:"sample_size" yaml:"sample_size"`                               // 统计样本大小
 	FeedbackRequiredPercent int           `json:"feedback_required_percent" yaml:"feedback_required_percent"`   // 需要反馈的百分比
 	EnableMLAnalysis        bool          `json:"enable_ml_analysis" yaml:"enable_ml_analysis"`                 // 启用ML分析
@@ -359,6 +371,8 @@ func DefaultAccuracyConfig() *AccuracyConfig {
 		WeeklyAccuracyTarget:     0.90,  // 90%
 		AlertCooldown:           30 * time.Minute,
 		DataRetentionDays:       90,
+		RetentionInterval:       time.Hour,
+		ReconcileInterval:       5 * time.Minute,
 		SampleSize:              1000,
 		FeedbackRequiredPercent: 10,  // 10%的查询需要反馈
 		EnableMLAnalysis:        true,
@@ -367,9 +381,11 @@ func DefaultAccuracyConfig() *AccuracyConfig {
 
 // RecordFeedback 记录用户反馈
 func (am *AccuracyMonitor) RecordFeedback(feedback QueryFeedback) error {
-	am.mu.Lock()
-	defer am.mu.Unlock()
+	return am.RecordFeedbackContext(context.Background(), feedback)
+}
 
+// RecordFeedbackContext 记录用户反馈，设置了存储时先写入存储，写入失败时不更新内存中的统计
+func (am *AccuracyMonitor) RecordFeedbackContext(ctx context.Context, feedback QueryFeedback) error {
 	am.logger.Info("记录用户反馈",
 		zap.String("query_id", feedback.QueryID),
 		zap.Int64("user_id", feedback.UserID),
@@ -392,6 +408,13 @@ func (am *AccuracyMonitor) RecordFeedback(feedback QueryFeedback) error {
 		feedback.Difficulty = am.assessDifficulty(feedback.UserQuery, feedback.GeneratedSQL)
 	}
 
+	if err := am.persistFeedback(ctx, feedback); err != nil {
+		return err
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
 	// 存储反馈
 	am.feedbackStore[feedback.QueryID] = &feedback
 
@@ -420,6 +443,11 @@ func (am *AccuracyMonitor) RecordFeedback(feedback QueryFeedback) error {
 
 // categorizeQuery 自动分类查询
 func (am *AccuracyMonitor) categorizeQuery(query string) QueryCategory {
+	return CategorizeQuery(query)
+}
+
+// CategorizeQuery 按关键词把问题或SQL归入固定的查询类别，SQL执行指标据此按类别统计
+func CategorizeQuery(query string) QueryCategory {
 	query = strings.ToLower(query)
 	
 	// 1. 优先检查最复杂的查询类型 - WITH语句和复杂子查询
@@ -1650,6 +1678,18 @@ func (am *AccuracyMonitor) GetMetrics() map[string]any {
 	}
 }
 
+// TodayAccuracy 返回当天反馈的准确率与反馈数，当天没有反馈时total为0
+func (am *AccuracyMonitor) TodayAccuracy() (accuracy float64, total int) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	
+	stats := am.dailyStats[time.Now().Format("2006-01-02")]
+	if stats == nil || stats.TotalQueries == 0 {
+		return 0, 0
+	}
+	return float64(stats.CorrectQueries) / float64(stats.TotalQueries), stats.TotalQueries
+}
+
 func (am *AccuracyMonitor) getCategoryBreakdown() map[string]float64 {
 	breakdown := make(map[string]float64)
 	for category, stats := range am.categoryStats {
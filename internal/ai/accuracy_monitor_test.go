@@ -44,6 +44,8 @@ func TestDefaultAccuracyConfig(t *testing.T) {
 	assert.Equal(t, 0.90, config.WeeklyAccuracyTarget)
 	assert.Equal(t, 30*time.Minute, config.AlertCooldown)
 	assert.Equal(t, 90, config.DataRetentionDays)
+	assert.Equal(t, time.Hour, config.RetentionInterval)
+	assert.Equal(t, 5*time.Minute, config.ReconcileInterval)
 	assert.Equal(t, 1000, config.SampleSize)
 	assert.Equal(t, 10, config.FeedbackRequiredPercent)
 	assert.True(t, config.EnableMLAnalysis)
@@ -0,0 +1,194 @@
+// 事后修正对账 - 用户在反馈之后（可能数周后）修正查询时，重算受影响的统计并通知重新训练
+// 修正先更新反馈与存储并进入待对账队列，对账任务按修正后的反馈重放统计，
+// 修正计入原反馈所在的日期、模型与类别，而不是停留在首次反馈时的结果
+
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/routing"
+)
+
+// ErrFeedbackNotFound 修正的查询没有反馈记录（未反馈或已超过保留期）
+var ErrFeedbackNotFound = errors.New("反馈记录不存在")
+
+// FeedbackCorrection 用户事后对查询的修正
+type FeedbackCorrection struct {
+	QueryID      string `json:"query_id"`
+	UserID       int64  `json:"-"` // 不为0时只能修正该用户自己的反馈
+	IsCorrect    bool   `json:"is_correct"`
+	ExpectedSQL  string `json:"expected_sql,omitempty"`  // 用户给出的正确SQL
+	ErrorType    string `json:"error_type,omitempty"`    // 修正为不正确时的错误类型
+	ErrorDetails string `json:"error_details,omitempty"` // 修正为不正确时的错误详情
+	UserRating   int    `json:"user_rating,omitempty"`   // 为0时保留原评分
+	Feedback     string `json:"feedback,omitempty"`      // 为空时保留原文本反馈
+}
+
+// CorrectionHandler 对账后接收修正过的反馈，用于重新训练依赖反馈的组件
+type CorrectionHandler interface {
+	HandleCorrections(ctx context.Context, corrections []QueryFeedback) error
+}
+
+// CorrectionHandlerFunc 函数形式的CorrectionHandler
+type CorrectionHandlerFunc func(ctx context.Context, corrections []QueryFeedback) error
+
+// HandleCorrections 调用函数本身
+func (f CorrectionHandlerFunc) HandleCorrections(ctx context.Context, corrections []QueryFeedback) error {
+	return f(ctx, corrections)
+}
+
+// SetCorrectionHandler 设置对账后重新训练的处理器，需在启动对账任务前调用
+func (am *AccuracyMonitor) SetCorrectionHandler(handler CorrectionHandler) {
+	am.correctionHandler = handler
+}
+
+// CorrectFeedback 记录用户对查询的事后修正，更新反馈与存储后加入待对账队列
+// 统计不立即重算，由ReconcileCorrections批量处理
+func (am *AccuracyMonitor) CorrectFeedback(ctx context.Context, correction FeedbackCorrection) error {
+	am.mu.RLock()
+	existing, ok := am.feedbackStore[correction.QueryID]
+	if ok && correction.UserID != 0 && existing.UserID != correction.UserID {
+		ok = false
+	}
+	var corrected QueryFeedback
+	if ok {
+		corrected = *existing
+	}
+	am.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrFeedbackNotFound, correction.QueryID)
+	}
+
+	corrected.IsCorrect = correction.IsCorrect
+	if correction.ExpectedSQL != "" {
+		corrected.ExpectedSQL = correction.ExpectedSQL
+	}
+	if correction.IsCorrect {
+		corrected.ErrorType, corrected.ErrorDetails = "", ""
+	} else {
+		corrected.ErrorType, corrected.ErrorDetails = correction.ErrorType, correction.ErrorDetails
+	}
+	if correction.UserRating > 0 {
+		corrected.UserRating = correction.UserRating
+	}
+	if correction.Feedback != "" {
+		corrected.Feedback = correction.Feedback
+	}
+
+	if err := am.persistCorrection(ctx, corrected); err != nil {
+		return err
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	// 等待存储期间反馈可能已过期被清理
+	if _, ok := am.feedbackStore[corrected.QueryID]; !ok {
+		return fmt.Errorf("%w: %s", ErrFeedbackNotFound, correction.QueryID)
+	}
+	am.feedbackStore[corrected.QueryID] = &corrected
+	am.pendingCorrections = append(am.pendingCorrections, corrected)
+
+	am.logger.Info("记录反馈修正",
+		zap.String("query_id", corrected.QueryID),
+		zap.Bool("is_correct", corrected.IsCorrect),
+		zap.Time("feedback_time", corrected.Timestamp))
+	return nil
+}
+
+// ReconcileCorrections 处理待对账的修正：按修正后的反馈重建统计，再交给CorrectionHandler重新训练
+// 返回处理的修正数量；重新训练失败时修正放回队列，下一轮重试
+func (am *AccuracyMonitor) ReconcileCorrections(ctx context.Context) (int, error) {
+	am.mu.Lock()
+	pending := am.pendingCorrections
+	am.pendingCorrections = nil
+	if len(pending) == 0 {
+		am.mu.Unlock()
+		return 0, nil
+	}
+	am.rebuildStats()
+	am.metrics.overallAccuracy.Set(am.getCurrentAccuracy())
+	handler := am.correctionHandler
+	am.mu.Unlock()
+
+	if handler != nil {
+		if err := handler.HandleCorrections(ctx, pending); err != nil {
+			am.mu.Lock()
+			am.pendingCorrections = append(pending, am.pendingCorrections...)
+			am.mu.Unlock()
+			return 0, fmt.Errorf("修正后重新训练失败: %w", err)
+		}
+	}
+
+	am.logger.Info("反馈修正对账完成", zap.Int("corrections", len(pending)))
+	return len(pending), nil
+}
+
+// reconcileLoop 按ReconcileInterval定期对账
+func (am *AccuracyMonitor) reconcileLoop(stopCh chan struct{}) {
+	ticker := time.NewTicker(am.config.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), am.config.ReconcileInterval)
+			if _, err := am.ReconcileCorrections(ctx); err != nil {
+				am.logger.Warn("反馈修正对账失败", zap.Error(err))
+			}
+			cancel()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// persistCorrection 将修正写入存储中该查询最新的反馈记录，未设置存储时直接返回
+func (am *AccuracyMonitor) persistCorrection(ctx context.Context, corrected QueryFeedback) error {
+	if am.store == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, feedbackStoreTimeout)
+	defer cancel()
+
+	record, err := am.store.GetByQueryID(ctx, corrected.QueryID)
+	if err != nil {
+		return fmt.Errorf("获取反馈记录失败: %w", err)
+	}
+	updated := feedbackToRecord(corrected)
+	updated.BaseModel = record.BaseModel
+	updated.ConnectionID = record.ConnectionID
+	if err := am.store.Update(ctx, updated); err != nil {
+		return fmt.Errorf("保存反馈修正失败: %w", err)
+	}
+	return nil
+}
+
+// LearningEngineCorrections 将修正交给学习引擎，重新训练受影响的查询模式与反馈权重
+// 学习历史记录的ID需与反馈的QueryID一致
+func LearningEngineCorrections(engine *routing.LearningEngine) CorrectionHandler {
+	return CorrectionHandlerFunc(func(ctx context.Context, corrections []QueryFeedback) error {
+		converted := make([]routing.FeedbackCorrection, 0, len(corrections))
+		for _, correction := range corrections {
+			isCorrect := correction.IsCorrect
+			converted = append(converted, routing.FeedbackCorrection{
+				QueryID: correction.QueryID,
+				Feedback: &routing.UserFeedback{
+					Rating:    correction.UserRating,
+					IsCorrect: &isCorrect,
+					Comments:  correction.Feedback,
+					Timestamp: time.Now(),
+				},
+			})
+		}
+		engine.ApplyCorrections(converted)
+		return nil
+	})
+}
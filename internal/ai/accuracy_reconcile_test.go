@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAccuracyMonitor_CorrectFeedback(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryFeedbackRepository{}
+	config := DefaultAccuracyConfig()
+	config.DataRetentionDays = 90
+	monitor := NewAccuracyMonitor(config, zap.NewNop())
+	monitor.SetFeedbackStore(store)
+
+	feedbackTime := time.Now().AddDate(0, 0, -21)
+	day := feedbackTime.Format("2006-01-02")
+	require.NoError(t, monitor.RecordFeedback(QueryFeedback{
+		QueryID: "q1", UserID: 7, GeneratedSQL: "SELECT SUM(amount) FROM orders",
+		IsCorrect: true, UserRating: 5, ModelUsed: "gpt-4o-mini", Timestamp: feedbackTime,
+	}))
+	require.Equal(t, 1.0, monitor.dailyStats[day].AccuracyRate)
+
+	var handled []QueryFeedback
+	monitor.SetCorrectionHandler(CorrectionHandlerFunc(func(ctx context.Context, corrections []QueryFeedback) error {
+		handled = append(handled, corrections...)
+		return nil
+	}))
+
+	// 三周后用户发现结果错误
+	require.NoError(t, monitor.CorrectFeedback(ctx, FeedbackCorrection{
+		QueryID: "q1", IsCorrect: false, ErrorType: "logic_error", UserRating: 1,
+		ExpectedSQL: "SELECT SUM(amount) FROM orders WHERE status = 'paid'",
+	}))
+
+	// 反馈与存储立即更新，统计等待对账
+	assert.False(t, monitor.feedbackStore["q1"].IsCorrect)
+	require.Len(t, store.records, 1)
+	assert.False(t, store.records[0].IsCorrect)
+	assert.Equal(t, "logic_error", *store.records[0].ErrorType)
+	assert.Equal(t, feedbackTime, store.records[0].CreateTime)
+	assert.Equal(t, 1.0, monitor.dailyStats[day].AccuracyRate)
+
+	reconciled, err := monitor.ReconcileCorrections(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reconciled)
+
+	// 修正计入原反馈所在的日期
+	require.Len(t, monitor.dailyStats, 1)
+	assert.Zero(t, monitor.dailyStats[day].AccuracyRate)
+	assert.Equal(t, 1, monitor.dailyStats[day].ErrorBreakdown["logic_error"])
+	assert.Equal(t, 1.0, monitor.userStats[7].AvgRating)
+	assert.Zero(t, monitor.getCurrentAccuracy())
+
+	require.Len(t, handled, 1)
+	assert.Equal(t, "q1", handled[0].QueryID)
+	assert.False(t, handled[0].IsCorrect)
+
+	// 没有待对账的修正时不处理
+	reconciled, err = monitor.ReconcileCorrections(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, reconciled)
+	assert.Len(t, handled, 1)
+}
+
+func TestAccuracyMonitor_CorrectFeedbackNotFound(t *testing.T) {
+	monitor := NewAccuracyMonitor(DefaultAccuracyConfig(), zap.NewNop())
+
+	err := monitor.CorrectFeedback(context.Background(), FeedbackCorrection{QueryID: "missing"})
+	assert.ErrorIs(t, err, ErrFeedbackNotFound)
+	assert.Empty(t, monitor.pendingCorrections)
+
+	// 不能修正其他用户的反馈
+	require.NoError(t, monitor.RecordFeedback(QueryFeedback{QueryID: "q1", UserID: 7, IsCorrect: true}))
+	err = monitor.CorrectFeedback(context.Background(), FeedbackCorrection{QueryID: "q1", UserID: 8})
+	assert.ErrorIs(t, err, ErrFeedbackNotFound)
+	assert.True(t, monitor.feedbackStore["q1"].IsCorrect)
+	assert.Empty(t, monitor.pendingCorrections)
+}
+
+func TestAccuracyMonitor_ReconcileHandlerFailure(t *testing.T) {
+	ctx := context.Background()
+	monitor := NewAccuracyMonitor(DefaultAccuracyConfig(), zap.NewNop())
+	require.NoError(t, monitor.RecordFeedback(QueryFeedback{QueryID: "q1", UserID: 7, IsCorrect: false, ErrorType: "syntax_error"}))
+
+	calls := 0
+	monitor.SetCorrectionHandler(CorrectionHandlerFunc(func(ctx context.Context, corrections []QueryFeedback) error {
+		calls++
+		if calls == 1 {
+			return errors.New("learning engine unavailable")
+		}
+		return nil
+	}))
+
+	require.NoError(t, monitor.CorrectFeedback(ctx, FeedbackCorrection{QueryID: "q1", IsCorrect: true}))
+	assert.Empty(t, monitor.feedbackStore["q1"].ErrorType, "修正为正确时清除错误信息")
+
+	_, err := monitor.ReconcileCorrections(ctx)
+	assert.Error(t, err)
+	assert.Len(t, monitor.pendingCorrections, 1, "重新训练失败时修正放回队列")
+
+	reconciled, err := monitor.ReconcileCorrections(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reconciled)
+	assert.Empty(t, monitor.pendingCorrections)
+	assert.Equal(t, 1.0, monitor.getCurrentAccuracy())
+}
@@ -0,0 +1,262 @@
+// 准确率反馈持久化 - 反馈写入FeedbackRepository，重启后从存储恢复统计
+// 启动时加载保留期内的反馈重建日、用户、模型与类别统计，清理任务按DataRetentionDays删除过期反馈
+
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+const (
+	feedbackLoadBatchSize = 500             // 启动加载时每批读取的反馈数量
+	feedbackStoreTimeout  = 5 * time.Second // 单条反馈写入存储的超时时间
+)
+
+// SetFeedbackStore 设置反馈存储，需在记录反馈前调用
+func (am *AccuracyMonitor) SetFeedbackStore(store repository.FeedbackRepository) {
+	am.store = store
+}
+
+// LoadFeedback 从存储加载保留期内的反馈并重建统计，返回加载的反馈数量
+func (am *AccuracyMonitor) LoadFeedback(ctx context.Context) (int, error) {
+	if am.store == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var start time.Time
+	if am.config.DataRetentionDays > 0 {
+		start = am.retentionCutoff(now)
+	}
+
+	var loaded []QueryFeedback
+	for offset := 0; ; offset += feedbackLoadBatchSize {
+		records, err := am.store.ListByTimeRange(ctx, start, now, feedbackLoadBatchSize, offset)
+		if err != nil {
+			return 0, fmt.Errorf("加载反馈失败: %w", err)
+		}
+		for _, record := range records {
+			loaded = append(loaded, feedbackFromRecord(record))
+		}
+		if len(records) < feedbackLoadBatchSize {
+			break
+		}
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	for i := range loaded {
+		am.feedbackStore[loaded[i].QueryID] = &loaded[i]
+	}
+	am.rebuildStats()
+	am.metrics.overallAccuracy.Set(am.getCurrentAccuracy())
+
+	am.logger.Info("从存储加载反馈", zap.Int("feedbacks", len(loaded)))
+	return len(loaded), nil
+}
+
+// CleanupExpiredFeedback 删除超过保留天数的反馈，同时从内存中移除并重建统计，返回存储中删除的数量
+// DataRetentionDays不大于0时不清理
+func (am *AccuracyMonitor) CleanupExpiredFeedback(ctx context.Context) (int64, error) {
+	if am.config.DataRetentionDays <= 0 {
+		return 0, nil
+	}
+	cutoff := am.retentionCutoff(time.Now())
+
+	var deleted int64
+	if am.store != nil {
+		var err error
+		if deleted, err = am.store.CleanupOldFeedbacks(ctx, cutoff); err != nil {
+			return 0, fmt.Errorf("清理过期反馈失败: %w", err)
+		}
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	expired := 0
+	for queryID, feedback := range am.feedbackStore {
+		if feedback.Timestamp.Before(cutoff) {
+			delete(am.feedbackStore, queryID)
+			expired++
+		}
+	}
+	if expired > 0 {
+		am.rebuildStats()
+	}
+
+	am.logger.Info("清理过期反馈",
+		zap.Time("cutoff", cutoff),
+		zap.Int64("deleted", deleted),
+		zap.Int("expired_in_memory", expired))
+	return deleted, nil
+}
+
+// Start 启动过期反馈清理与事后修正对账任务，间隔不大于0的任务不启动
+func (am *AccuracyMonitor) Start(ctx context.Context) error {
+	retention := am.config.RetentionInterval > 0 && am.config.DataRetentionDays > 0
+	reconcile := am.config.ReconcileInterval > 0
+	if !retention && !reconcile {
+		return nil
+	}
+
+	am.mu.Lock()
+	if am.stopCh != nil {
+		am.mu.Unlock()
+		return nil
+	}
+	stopCh := make(chan struct{})
+	am.stopCh = stopCh
+	am.mu.Unlock()
+
+	if retention {
+		go am.retentionLoop(stopCh)
+	}
+	if reconcile {
+		go am.reconcileLoop(stopCh)
+	}
+	return nil
+}
+
+// Stop 停止过期反馈清理与对账任务
+func (am *AccuracyMonitor) Stop(ctx context.Context) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if am.stopCh != nil {
+		close(am.stopCh)
+		am.stopCh = nil
+	}
+	return nil
+}
+
+// retentionLoop 按RetentionInterval定期清理过期反馈
+func (am *AccuracyMonitor) retentionLoop(stopCh chan struct{}) {
+	ticker := time.NewTicker(am.config.RetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), am.config.RetentionInterval)
+			if _, err := am.CleanupExpiredFeedback(ctx); err != nil {
+				am.logger.Warn("清理过期反馈失败", zap.Error(err))
+			}
+			cancel()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// retentionCutoff 保留期的起始时间，早于该时间的反馈已过期
+func (am *AccuracyMonitor) retentionCutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -am.config.DataRetentionDays)
+}
+
+// persistFeedback 将反馈写入存储，未设置存储时直接返回
+func (am *AccuracyMonitor) persistFeedback(ctx context.Context, feedback QueryFeedback) error {
+	if am.store == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, feedbackStoreTimeout)
+	defer cancel()
+
+	if err := am.store.Create(ctx, feedbackToRecord(feedback)); err != nil {
+		return fmt.Errorf("保存反馈失败: %w", err)
+	}
+	return nil
+}
+
+// rebuildStats 按时间顺序重放内存中的反馈，重建日、用户、模型与类别统计，调用方需持有写锁
+func (am *AccuracyMonitor) rebuildStats() {
+	am.dailyStats = make(map[string]*DailyStats)
+	am.userStats = make(map[int64]*UserStats)
+	am.modelStats = make(map[string]*ModelStats)
+	am.categoryStats = make(map[string]*CategoryStats)
+
+	feedbacks := make([]*QueryFeedback, 0, len(am.feedbackStore))
+	for _, feedback := range am.feedbackStore {
+		feedbacks = append(feedbacks, feedback)
+	}
+	sort.Slice(feedbacks, func(i, j int) bool {
+		return feedbacks[i].Timestamp.Before(feedbacks[j].Timestamp)
+	})
+
+	for _, feedback := range feedbacks {
+		am.updateDailyStats(*feedback)
+		am.updateUserStats(*feedback)
+		am.updateModelStats(*feedback)
+		am.updateCategoryStats(*feedback)
+	}
+}
+
+// feedbackToRecord 转换为存储记录，处理时间按毫秒保存，Metadata不持久化
+func feedbackToRecord(feedback QueryFeedback) *repository.Feedback {
+	record := &repository.Feedback{
+		QueryID:        feedback.QueryID,
+		UserID:         feedback.UserID,
+		UserQuery:      feedback.UserQuery,
+		GeneratedSQL:   feedback.GeneratedSQL,
+		IsCorrect:      feedback.IsCorrect,
+		UserRating:     feedback.UserRating,
+		Category:       string(feedback.Category),
+		Difficulty:     string(feedback.Difficulty),
+		ProcessingTime: feedback.ProcessingTime.Milliseconds(),
+		TokensUsed:     feedback.TokensUsed,
+		ModelUsed:      feedback.ModelUsed,
+	}
+	record.CreateTime = feedback.Timestamp
+	record.ExpectedSQL = optionalString(feedback.ExpectedSQL)
+	record.FeedbackText = optionalString(feedback.Feedback)
+	record.ErrorType = optionalString(feedback.ErrorType)
+	record.ErrorDetails = optionalString(feedback.ErrorDetails)
+	return record
+}
+
+// feedbackFromRecord 由存储记录还原反馈，反馈时间为记录的创建时间
+func feedbackFromRecord(record *repository.Feedback) QueryFeedback {
+	return QueryFeedback{
+		QueryID:        record.QueryID,
+		UserID:         record.UserID,
+		UserQuery:      record.UserQuery,
+		GeneratedSQL:   record.GeneratedSQL,
+		ExpectedSQL:    derefString(record.ExpectedSQL),
+		IsCorrect:      record.IsCorrect,
+		UserRating:     record.UserRating,
+		Feedback:       derefString(record.FeedbackText),
+		Category:       QueryCategory(record.Category),
+		Difficulty:     QueryDifficulty(record.Difficulty),
+		ErrorType:      derefString(record.ErrorType),
+		ErrorDetails:   derefString(record.ErrorDetails),
+		ProcessingTime: time.Duration(record.ProcessingTime) * time.Millisecond,
+		TokensUsed:     record.TokensUsed,
+		ModelUsed:      record.ModelUsed,
+		Timestamp:      record.CreateTime,
+	}
+}
+
+// optionalString 空字符串转换为nil
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// derefString 返回指针指向的字符串，nil时为空字符串
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,204 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// memoryFeedbackRepository 内存反馈存储，只实现准确率监控使用的方法
+type memoryFeedbackRepository struct {
+	repository.FeedbackRepository
+	records   []*repository.Feedback
+	createErr error
+	mu        sync.Mutex
+}
+
+func (r *memoryFeedbackRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records)
+}
+
+func (r *memoryFeedbackRepository) Create(ctx context.Context, feedback *repository.Feedback) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.createErr != nil {
+		return r.createErr
+	}
+	feedback.ID = int64(len(r.records) + 1)
+	if feedback.CreateTime.IsZero() {
+		feedback.CreateTime = time.Now()
+	}
+	r.records = append(r.records, feedback)
+	return nil
+}
+
+func (r *memoryFeedbackRepository) ListByTimeRange(ctx context.Context, startTime, endTime time.Time, limit, offset int) ([]*repository.Feedback, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var matched []*repository.Feedback
+	for _, record := range r.records {
+		if !record.CreateTime.Before(startTime) && record.CreateTime.Before(endTime) {
+			matched = append(matched, record)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].CreateTime.Before(matched[j].CreateTime) })
+	if offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[offset:]
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched, nil
+}
+
+func (r *memoryFeedbackRepository) GetByQueryID(ctx context.Context, queryID string) (*repository.Feedback, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.records) - 1; i >= 0; i-- {
+		if r.records[i].QueryID == queryID {
+			return r.records[i], nil
+		}
+	}
+	return nil, errors.New("feedback not found")
+}
+
+func (r *memoryFeedbackRepository) Update(ctx context.Context, feedback *repository.Feedback) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, record := range r.records {
+		if record.ID == feedback.ID {
+			r.records[i] = feedback
+			return nil
+		}
+	}
+	return errors.New("feedback not found")
+}
+
+func (r *memoryFeedbackRepository) CleanupOldFeedbacks(ctx context.Context, beforeDate time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.records[:0]
+	for _, record := range r.records {
+		if !record.CreateTime.Before(beforeDate) {
+			kept = append(kept, record)
+		}
+	}
+	deleted := int64(len(r.records) - len(kept))
+	r.records = kept
+	return deleted, nil
+}
+
+func TestAccuracyMonitor_PersistAndLoad(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryFeedbackRepository{}
+	now := time.Now()
+
+	monitor := NewAccuracyMonitor(DefaultAccuracyConfig(), zap.NewNop())
+	monitor.SetFeedbackStore(store)
+	require.NoError(t, monitor.RecordFeedback(QueryFeedback{
+		QueryID: "q1", UserID: 7, UserQuery: "统计用户数量", GeneratedSQL: "SELECT COUNT(*) FROM users",
+		IsCorrect: true, UserRating: 5, ModelUsed: "gpt-4o-mini", ProcessingTime: 1500 * time.Millisecond, Timestamp: now.Add(-2 * time.Hour),
+	}))
+	require.NoError(t, monitor.RecordFeedback(QueryFeedback{
+		QueryID: "q2", UserID: 7, UserQuery: "查询订单", GeneratedSQL: "SELECT * FROM order",
+		IsCorrect: false, UserRating: 2, ErrorType: "syntax_error", Feedback: "表名错误", ModelUsed: "gpt-4o-mini", Timestamp: now.Add(-time.Hour),
+	}))
+	require.Len(t, store.records, 2)
+	assert.Equal(t, int64(1500), store.records[0].ProcessingTime)
+	assert.Nil(t, store.records[0].ErrorType)
+	assert.Equal(t, "syntax_error", *store.records[1].ErrorType)
+
+	// 新的监控器（模拟重启）从存储恢复反馈与统计
+	restarted := NewAccuracyMonitor(DefaultAccuracyConfig(), zap.NewNop())
+	restarted.SetFeedbackStore(store)
+	loaded, err := restarted.LoadFeedback(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+
+	assert.Equal(t, 0.5, restarted.getCurrentAccuracy())
+	require.Contains(t, restarted.userStats, int64(7))
+	assert.Equal(t, 2, restarted.userStats[7].TotalQueries)
+	assert.Equal(t, 3.5, restarted.userStats[7].AvgRating)
+	assert.Equal(t, 2, restarted.modelStats["gpt-4o-mini"].TotalQueries)
+	assert.Equal(t, "表名错误", restarted.feedbackStore["q2"].Feedback)
+	assert.Equal(t, 1500*time.Millisecond, restarted.feedbackStore["q1"].ProcessingTime)
+
+	var total int
+	for _, stats := range restarted.dailyStats {
+		total += stats.TotalQueries
+	}
+	assert.Equal(t, 2, total)
+}
+
+func TestAccuracyMonitor_PersistFailure(t *testing.T) {
+	store := &memoryFeedbackRepository{createErr: errors.New("connection refused")}
+	monitor := NewAccuracyMonitor(DefaultAccuracyConfig(), zap.NewNop())
+	monitor.SetFeedbackStore(store)
+
+	err := monitor.RecordFeedback(QueryFeedback{QueryID: "q1", UserID: 7, IsCorrect: true})
+	assert.Error(t, err)
+	assert.Empty(t, monitor.feedbackStore, "写入存储失败时不更新内存统计")
+	assert.Empty(t, monitor.userStats)
+}
+
+func TestAccuracyMonitor_CleanupExpiredFeedback(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryFeedbackRepository{}
+	config := DefaultAccuracyConfig()
+	config.DataRetentionDays = 30
+	now := time.Now()
+
+	monitor := NewAccuracyMonitor(config, zap.NewNop())
+	monitor.SetFeedbackStore(store)
+	require.NoError(t, monitor.RecordFeedback(QueryFeedback{QueryID: "old", UserID: 1, IsCorrect: false, ModelUsed: "m1", Timestamp: now.AddDate(0, 0, -45)}))
+	require.NoError(t, monitor.RecordFeedback(QueryFeedback{QueryID: "new", UserID: 2, IsCorrect: true, ModelUsed: "m1", Timestamp: now.AddDate(0, 0, -1)}))
+
+	deleted, err := monitor.CleanupExpiredFeedback(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	require.Len(t, store.records, 1)
+	assert.Equal(t, "new", store.records[0].QueryID)
+
+	// 内存中的过期反馈被移除，统计按保留的反馈重建
+	assert.NotContains(t, monitor.feedbackStore, "old")
+	assert.NotContains(t, monitor.userStats, int64(1))
+	assert.Equal(t, 1, monitor.modelStats["m1"].TotalQueries)
+	assert.Len(t, monitor.dailyStats, 1)
+	assert.Equal(t, 1.0, monitor.getCurrentAccuracy())
+
+	// 保留天数为0时不清理
+	config.DataRetentionDays = 0
+	require.NoError(t, monitor.RecordFeedback(QueryFeedback{QueryID: "ancient", UserID: 3, Timestamp: now.AddDate(-2, 0, 0)}))
+	deleted, err = monitor.CleanupExpiredFeedback(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	assert.Contains(t, monitor.feedbackStore, "ancient")
+}
+
+func TestAccuracyMonitor_StartStop(t *testing.T) {
+	config := DefaultAccuracyConfig()
+	config.RetentionInterval = 10 * time.Millisecond
+	config.DataRetentionDays = 1
+	store := &memoryFeedbackRepository{}
+	store.records = append(store.records, &repository.Feedback{QueryID: "old"})
+	store.records[0].CreateTime = time.Now().AddDate(0, 0, -3)
+
+	monitor := NewAccuracyMonitor(config, zap.NewNop())
+	monitor.SetFeedbackStore(store)
+	require.NoError(t, monitor.Start(context.Background()))
+	assert.Eventually(t, func() bool { return store.count() == 0 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, monitor.Stop(context.Background()))
+	require.NoError(t, monitor.Stop(context.Background()))
+}
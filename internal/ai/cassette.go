@@ -0,0 +1,235 @@
+// LLM调用录制与回放
+// 以提示词哈希为键的VCR式磁带：录制模式调用真实模型并保存响应，
+// 回放模式只读取磁带，未命中即报错，从而在重新录制时暴露提示词漂移
+
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// CassetteMode 磁带工作模式
+type CassetteMode string
+
+const (
+	CassetteReplay CassetteMode = "replay" // 仅回放，未命中返回ErrCassetteMiss
+	CassetteRecord CassetteMode = "record" // 调用真实模型并在Save时覆盖磁带
+)
+
+// cassetteVersion 磁带文件格式版本
+const cassetteVersion = 1
+
+// ErrCassetteMiss 回放模式下提示词未被录制，通常意味着提示词发生了漂移
+var ErrCassetteMiss = errors.New("cassette has no recording for prompt")
+
+// CassetteEntry 单条录制
+type CassetteEntry struct {
+	Hash       string    `json:"hash"`
+	Prompt     string    `json:"prompt"` // 保留原文便于审阅差异
+	Response   string    `json:"response"`
+	StopReason string    `json:"stop_reason,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// cassetteFile 磁带文件结构
+type cassetteFile struct {
+	Version int             `json:"version"`
+	Entries []CassetteEntry `json:"entries"`
+}
+
+// CassetteDrift 本次会话与已有磁带的差异
+type CassetteDrift struct {
+	Added   []string `json:"added"`   // 本次出现但磁带中没有的提示词哈希
+	Removed []string `json:"removed"` // 磁带中有但本次未使用的提示词哈希
+}
+
+// HasDrift 是否存在差异
+func (d *CassetteDrift) HasDrift() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0
+}
+
+// CassetteLLM 包装llms.Model的录制/回放代理
+type CassetteLLM struct {
+	inner llms.Model
+	path  string
+	mode  CassetteMode
+
+	mu       sync.Mutex
+	recorded map[string]CassetteEntry // 磁带中已有的录制
+	session  map[string]CassetteEntry // 本次会话使用到的录制
+}
+
+// CassetteModeFromEnv 从LLM_CASSETTE_MODE读取模式，默认回放
+func CassetteModeFromEnv() CassetteMode {
+	if CassetteMode(os.Getenv("LLM_CASSETTE_MODE")) == CassetteRecord {
+		return CassetteRecord
+	}
+	return CassetteReplay
+}
+
+// NewCassetteLLM 创建录制/回放代理
+// 回放模式下inner可以为nil；磁带文件不存在时视为空磁带
+func NewCassetteLLM(inner llms.Model, path string, mode CassetteMode) (*CassetteLLM, error) {
+	if mode == CassetteRecord && inner == nil {
+		return nil, fmt.Errorf("录制模式需要真实的LLM客户端")
+	}
+
+	c := &CassetteLLM{
+		inner:    inner,
+		path:     path,
+		mode:     mode,
+		recorded: make(map[string]CassetteEntry),
+		session:  make(map[string]CassetteEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("读取磁带文件失败: %w", err)
+	}
+	if len(data) > 0 {
+		var file cassetteFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("解析磁带文件失败: %w", err)
+		}
+		for _, entry := range file.Entries {
+			c.recorded[entry.Hash] = entry
+		}
+	}
+	return c, nil
+}
+
+// PromptHash 计算消息列表的哈希，角色与文本内容均参与计算
+func PromptHash(messages []llms.MessageContent) string {
+	h := sha256.New()
+	for _, msg := range messages {
+		fmt.Fprintf(h, "%s\x00", msg.Role)
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				fmt.Fprintf(h, "%s\x00", text.Text)
+			}
+		}
+		h.Write([]byte{'\x01'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GenerateContent 回放模式读取磁带，录制模式调用真实模型并记录响应
+func (c *CassetteLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	hash := PromptHash(messages)
+
+	if c.mode == CassetteReplay {
+		c.mu.Lock()
+		entry, ok := c.recorded[hash]
+		if ok {
+			c.session[hash] = entry
+		}
+		c.mu.Unlock()
+
+		if !ok {
+			return nil, fmt.Errorf("%w %s (%s)，请使用LLM_CASSETTE_MODE=record重新录制", ErrCassetteMiss, hash[:12], c.path)
+		}
+		return &llms.ContentResponse{
+			Choices: []*llms.ContentChoice{{Content: entry.Response, StopReason: entry.StopReason}},
+		}, nil
+	}
+
+	resp, err := c.inner.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := CassetteEntry{Hash: hash, Prompt: promptText(messages), RecordedAt: time.Now().UTC()}
+	if len(resp.Choices) > 0 {
+		entry.Response = resp.Choices[0].Content
+		entry.StopReason = resp.Choices[0].StopReason
+	}
+
+	c.mu.Lock()
+	c.session[hash] = entry
+	c.mu.Unlock()
+
+	return resp, nil
+}
+
+// Call 单提示词调用
+func (c *CassetteLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, c, prompt, options...)
+}
+
+// Drift 对比本次会话与已有磁带
+func (c *CassetteLLM) Drift() *CassetteDrift {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	drift := &CassetteDrift{}
+	for hash := range c.session {
+		if _, ok := c.recorded[hash]; !ok {
+			drift.Added = append(drift.Added, hash)
+		}
+	}
+	for hash := range c.recorded {
+		if _, ok := c.session[hash]; !ok {
+			drift.Removed = append(drift.Removed, hash)
+		}
+	}
+	sort.Strings(drift.Added)
+	sort.Strings(drift.Removed)
+	return drift
+}
+
+// Save 录制模式下以本次会话覆盖磁带文件，回放模式下不做任何操作
+func (c *CassetteLLM) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+
+	c.mu.Lock()
+	file := cassetteFile{Version: cassetteVersion, Entries: make([]CassetteEntry, 0, len(c.session))}
+	for _, entry := range c.session {
+		file.Entries = append(file.Entries, entry)
+	}
+	c.mu.Unlock()
+
+	// 按哈希排序，保证重新录制后的diff稳定
+	sort.Slice(file.Entries, func(i, j int) bool { return file.Entries[i].Hash < file.Entries[j].Hash })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化磁带失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("创建磁带目录失败: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("写入磁带文件失败: %w", err)
+	}
+	return nil
+}
+
+// promptText 拼接消息文本，供磁带审阅
+func promptText(messages []llms.MessageContent) string {
+	var text string
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			if t, ok := part.(llms.TextContent); ok {
+				if text != "" {
+					text += "\n"
+				}
+				text += t.Text
+			}
+		}
+	}
+	return text
+}
@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestCassetteLLM_RecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "TestCassette.cassette.json")
+	ctx := context.Background()
+
+	mock, err := NewMockLLM(nil)
+	require.NoError(t, err)
+
+	recorder, err := NewCassetteLLM(mock, path, CassetteRecord)
+	require.NoError(t, err)
+	recorded, err := recorder.Call(ctx, "统计用户总数")
+	require.NoError(t, err)
+	_, err = recorder.Call(ctx, "列出所有产品")
+	require.NoError(t, err)
+	assert.Equal(t, []string(nil), recorder.Drift().Removed)
+	assert.Len(t, recorder.Drift().Added, 2)
+	require.NoError(t, recorder.Save())
+
+	// 回放不需要真实模型
+	player, err := NewCassetteLLM(nil, path, CassetteReplay)
+	require.NoError(t, err)
+	replayed, err := player.Call(ctx, "统计用户总数")
+	require.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+	assert.Equal(t, int64(2), mock.Calls(), "回放不应调用真实模型")
+
+	// 提示词漂移：未录制的提示词直接失败，且未使用的录制被报告
+	_, err = player.Call(ctx, "统计用户总数量")
+	assert.True(t, errors.Is(err, ErrCassetteMiss))
+	drift := player.Drift()
+	assert.True(t, drift.HasDrift())
+	assert.Len(t, drift.Removed, 1)
+}
+
+func TestPromptHash(t *testing.T) {
+	human := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "SELECT")}
+	system := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeSystem, "SELECT")}
+
+	assert.Equal(t, PromptHash(human), PromptHash([]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "SELECT")}))
+	assert.NotEqual(t, PromptHash(human), PromptHash(system))
+	assert.Len(t, PromptHash(human), 64)
+}
+
+func TestNewCassetteLLM_RecordRequiresModel(t *testing.T) {
+	_, err := NewCassetteLLM(nil, filepath.Join(t.TempDir(), "c.json"), CassetteRecord)
+	assert.Error(t, err)
+
+	t.Setenv("LLM_CASSETTE_MODE", "record")
+	assert.Equal(t, CassetteRecord, CassetteModeFromEnv())
+	t.Setenv("LLM_CASSETTE_MODE", "")
+	assert.Equal(t, CassetteReplay, CassetteModeFromEnv())
+}
@@ -611,6 +611,20 @@ func (cm *ContextManager) performCleanup() {
 	}
 }
 
+// ForgetUser 清除用户的查询历史与上下文，用于个人数据删除，返回清除的历史记录数
+func (cm *ContextManager) ForgetUser(userID int64) int {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	removed := 0
+	if history, exists := cm.historyBuffer[userID]; exists {
+		removed = len(history.Queries)
+		delete(cm.historyBuffer, userID)
+	}
+	delete(cm.userContextCache, userID)
+	return removed
+}
+
 // Close 关闭上下文管理器
 func (cm *ContextManager) Close() error {
 	if cm.cleanupCancel != nil {
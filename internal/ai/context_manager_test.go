@@ -445,6 +445,28 @@ func TestContextManager_GetStats(t *testing.T) {
 	}
 }
 
+func TestContextManager_ForgetUser(t *testing.T) {
+	cm := NewContextManager(nil)
+	defer cm.Close()
+
+	cm.AddQueryHistory(123, "查询我的订单", "SELECT * FROM orders", true)
+	cm.AddQueryHistory(123, "查询我的地址", "SELECT address FROM users", true)
+	cm.AddQueryHistory(456, "查询用户", "SELECT * FROM users", true)
+
+	if removed := cm.ForgetUser(123); removed != 2 {
+		t.Errorf("期望清除 2 条历史记录，得到 %d", removed)
+	}
+	if history := cm.GetRecentHistory(123, 10); len(history) != 0 {
+		t.Errorf("清除后不应再有历史记录，得到 %d", len(history))
+	}
+	if history := cm.GetRecentHistory(456, 10); len(history) != 1 {
+		t.Errorf("其他用户的历史记录不应受影响，得到 %d", len(history))
+	}
+	if removed := cm.ForgetUser(123); removed != 0 {
+		t.Errorf("重复清除应返回 0，得到 %d", removed)
+	}
+}
+
 func TestContextManager_ConcurrentAccess(t *testing.T) {
 	cm := NewContextManager(nil)
 	defer cm.Close()
@@ -0,0 +1,371 @@
+// 成本追踪持久化与预算控制 - 模型用量按天累加写入LLMUsageRepository，重启后从存储恢复当日统计
+// 调用模型前按每用户与全局每日预算检查硬上限，Token、成本与拒绝次数通过Prometheus暴露
+
+package ai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+const usageStoreTimeout = 5 * time.Second // 单次用量写入存储的超时时间
+
+// freeProviders 本地或模拟提供商，调用不产生费用
+var freeProviders = map[string]bool{"ollama": true, "mock": true}
+
+// costMetrics 成本追踪的Prometheus指标
+type costMetrics struct {
+	tokens     *prometheus.CounterVec
+	cost       *prometheus.CounterVec
+	dailySpend prometheus.Gauge
+	rejections *prometheus.CounterVec
+}
+
+// newCostMetrics 创建成本追踪指标，注册由RegisterMetrics完成
+func newCostMetrics() *costMetrics {
+	return &costMetrics{
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ai_llm_tokens_total",
+			Help: "Estimated tokens sent to and generated by LLM providers",
+		}, []string{"provider", "model", "type"}), // type: input/output
+		cost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ai_llm_cost_usd_total",
+			Help: "Estimated LLM spend in USD",
+		}, []string{"provider", "model"}),
+		dailySpend: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ai_llm_daily_spend_usd",
+			Help: "Estimated LLM spend in USD for the current day across all users",
+		}),
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ai_llm_budget_rejections_total",
+			Help: "Requests rejected because a daily budget limit was reached",
+		}, []string{"limit"}), // limit: user_daily/daily_budget
+	}
+}
+
+// CostConfigFromBudget 由AI预算配置创建成本追踪配置
+// DailyLimit为全局每日上限，UserLimit为每用户每日上限，单次查询超过每用户上限时告警
+func CostConfigFromBudget(budget config.BudgetConfig) *CostConfig {
+	return &CostConfig{
+		DailyBudget:            budget.DailyLimit,
+		UserDailyLimit:         budget.UserLimit,
+		QueryCostLimit:         budget.UserLimit,
+		DailyAlertThreshold:    budget.AlertThreshold,
+		UserAlertThreshold:     budget.AlertThreshold,
+		ModelAlertThreshold:    budget.AlertThreshold,
+		EnableDetailedTracking: true,
+		RetentionDays:          30,
+	}
+}
+
+// userLimit 审批通过的每用户每日上限，过期后恢复全局上限
+type userLimit struct {
+	limit     float64
+	expiresAt time.Time
+}
+
+// BudgetStatus 用户当日用量与预算
+type BudgetStatus struct {
+	Date               string     `json:"date"`
+	UserCost           float64    `json:"user_cost"`                       // 用户当日估算成本（美元）
+	UserLimit          float64    `json:"user_limit"`                      // 每用户每日上限，0表示不限；预算上调生效期间为上调后的上限
+	UserLimitExpiresAt *time.Time `json:"user_limit_expires_at,omitempty"` // 预算上调的失效时间
+	UserQueries        int        `json:"user_queries"`                    // 用户当日模型调用次数
+	UserTokens         int        `json:"user_tokens"`                     // 用户当日估算Token数
+	TotalCost          float64    `json:"total_cost"`                      // 全部用户当日估算成本
+	TotalLimit         float64    `json:"total_limit"`                     // 全局每日上限，0表示不限
+}
+
+// SetUsageStore 设置用量存储，需在记录用量前调用；logger用于记录写入失败
+func (ct *CostTracker) SetUsageStore(store repository.LLMUsageRepository, logger *zap.Logger) {
+	ct.store = store
+	if logger != nil {
+		ct.logger = logger
+	}
+}
+
+// RegisterMetrics 注册Token、成本与预算拒绝指标
+func (ct *CostTracker) RegisterMetrics(registerer prometheus.Registerer) {
+	registerer.MustRegister(ct.metrics.tokens, ct.metrics.cost, ct.metrics.dailySpend, ct.metrics.rejections)
+}
+
+// EstimateCost 估算一次模型调用的成本，本地与模拟提供商不计费
+func (ct *CostTracker) EstimateCost(provider, model string, inputTokens, outputTokens int) float64 {
+	if freeProviders[provider] {
+		return 0
+	}
+	return ct.CalculateQueryCost(inputTokens, outputTokens, model)
+}
+
+// ReportedTokens 读取提供商在GenerationInfo中返回的实际token用量
+// OpenAI、Ollama与Gemini使用PromptTokens/CompletionTokens，Anthropic使用InputTokens/OutputTokens；未返回时ok为false
+func ReportedTokens(info map[string]any) (inputTokens, outputTokens int, ok bool) {
+	for _, keys := range [][2]string{{"PromptTokens", "CompletionTokens"}, {"InputTokens", "OutputTokens"}} {
+		input, inputOK := tokenCount(info[keys[0]])
+		output, outputOK := tokenCount(info[keys[1]])
+		if inputOK && outputOK && input+output > 0 {
+			return input, output, true
+		}
+	}
+	return 0, 0, false
+}
+
+func tokenCount(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// RecordUsage 按提供商与模型估算一次模型调用的成本并记录
+func (ct *CostTracker) RecordUsage(userID int64, provider, model, query string, inputTokens, outputTokens int) error {
+	return ct.RecordQueryCost(userID, QueryCost{
+		Timestamp:    time.Now(),
+		Query:        query,
+		Provider:     provider,
+		ModelName:    model,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		TotalTokens:  inputTokens + outputTokens,
+		Cost:         ct.EstimateCost(provider, model, inputTokens, outputTokens),
+	})
+}
+
+// LoadUsage 从存储加载当日用量与仍有效的预算上调，替换内存中的当日统计，返回加载的汇总行数
+func (ct *CostTracker) LoadUsage(ctx context.Context) (int, error) {
+	if ct.store == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	records, err := ct.store.ListByDay(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("加载模型用量失败: %w", err)
+	}
+	overrides, err := ct.store.ListBudgetOverrides(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("加载预算上调失败: %w", err)
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	clear(ct.userLimits)
+	for _, override := range overrides {
+		ct.userLimits[override.UserID] = userLimit{limit: override.DailyLimit, expiresAt: override.ExpiresAt}
+	}
+
+	today := now.Format("2006-01-02")
+	daily := &DailyUsage{
+		Date:        today,
+		ModelUsage:  make(map[string]*Usage),
+		UserCounts:  make(map[int64]int),
+		LastUpdated: now,
+	}
+	ct.dailyUsage[today] = daily
+	for userID := range ct.userUsage {
+		delete(ct.userUsage, userID)
+	}
+
+	for _, record := range records {
+		usage := &Usage{
+			QueryCount:   int(record.Queries),
+			InputTokens:  int(record.InputTokens),
+			OutputTokens: int(record.OutputTokens),
+			TotalTokens:  int(record.InputTokens + record.OutputTokens),
+			Cost:         record.Cost,
+		}
+		daily.TotalCost += usage.Cost
+		daily.TotalTokens += usage.TotalTokens
+		daily.QueryCount += usage.QueryCount
+		daily.UserCounts[record.UserID] += usage.QueryCount
+		addUsage(daily.ModelUsage, record.Model, usage)
+
+		user, exists := ct.userUsage[record.UserID]
+		if !exists {
+			user = &UserUsage{
+				UserID:       record.UserID,
+				ModelUsage:   make(map[string]*Usage),
+				QueryHistory: make([]QueryCost, 0, 100),
+				LastReset:    now,
+			}
+			ct.userUsage[record.UserID] = user
+		}
+		user.DailyCost += usage.Cost
+		user.DailyTokens += usage.TotalTokens
+		user.DailyQueries += usage.QueryCount
+		addUsage(user.ModelUsage, record.Model, usage)
+	}
+	ct.metrics.dailySpend.Set(daily.TotalCost)
+
+	ct.logger.Info("从存储加载模型用量",
+		zap.Int("records", len(records)),
+		zap.Float64("daily_cost", daily.TotalCost))
+	return len(records), nil
+}
+
+// CheckBudget 检查用户当日用量与全局当日用量是否已达到上限，上限不大于0表示不限
+// 在调用模型前检查，超限时返回*CostLimitError
+func (ct *CostTracker) CheckBudget(userID int64) error {
+	ct.mu.Lock()
+	ct.resetUserDailyUsageIfNeeded(userID)
+	var limitErr *CostLimitError
+	if limit := ct.userDailyLimit(userID, time.Now()); limit > 0 {
+		if usage, exists := ct.userUsage[userID]; exists && usage.DailyCost >= limit {
+			limitErr = NewCostLimitError("user_daily", usage.DailyCost, limit)
+		}
+	}
+	if limit := ct.config.DailyBudget; limitErr == nil && limit > 0 {
+		if usage, exists := ct.dailyUsage[time.Now().Format("2006-01-02")]; exists && usage.TotalCost >= limit {
+			limitErr = NewCostLimitError("daily_budget", usage.TotalCost, limit)
+		}
+	}
+	ct.mu.Unlock()
+
+	if limitErr == nil {
+		return nil
+	}
+	ct.metrics.rejections.WithLabelValues(limitErr.Type).Inc()
+	return limitErr
+}
+
+// SetUserLimit 在expiresAt之前以limit作为用户的每日上限，用于审批通过的预算上调
+func (ct *CostTracker) SetUserLimit(userID int64, limit float64, expiresAt time.Time) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.userLimits[userID] = userLimit{limit: limit, expiresAt: expiresAt}
+}
+
+// userDailyLimit 用户在now时的每日上限，调用方持有锁
+// 预算上调只在全局每用户上限生效且上调后更高时使用，过期后恢复全局上限
+func (ct *CostTracker) userDailyLimit(userID int64, now time.Time) float64 {
+	limit := ct.config.UserDailyLimit
+	if override, ok := ct.userLimits[userID]; ok && limit > 0 && override.limit > limit && now.Before(override.expiresAt) {
+		return override.limit
+	}
+	return limit
+}
+
+// BudgetStatus 返回用户当日用量与预算
+func (ct *CostTracker) BudgetStatus(userID int64) BudgetStatus {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.resetUserDailyUsageIfNeeded(userID)
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	status := BudgetStatus{
+		Date:       today,
+		UserLimit:  ct.userDailyLimit(userID, now),
+		TotalLimit: ct.config.DailyBudget,
+	}
+	if status.UserLimit != ct.config.UserDailyLimit {
+		expiresAt := ct.userLimits[userID].expiresAt
+		status.UserLimitExpiresAt = &expiresAt
+	}
+	if usage, exists := ct.userUsage[userID]; exists {
+		status.UserCost = usage.DailyCost
+		status.UserQueries = usage.DailyQueries
+		status.UserTokens = usage.DailyTokens
+	}
+	if usage, exists := ct.dailyUsage[today]; exists {
+		status.TotalCost = usage.TotalCost
+	}
+	return status
+}
+
+// DailySpend 返回全部用户当日估算成本与全局每日上限，上限为0表示不限
+func (ct *CostTracker) DailySpend() (spent, limit float64) {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+
+	if usage, exists := ct.dailyUsage[time.Now().Format("2006-01-02")]; exists {
+		spent = usage.TotalCost
+	}
+	return spent, ct.config.DailyBudget
+}
+
+// UsageHistory 从存储读取用户最近days天（含当天）的用量，未设置存储时返回nil
+func (ct *CostTracker) UsageHistory(ctx context.Context, userID int64, days int) ([]*repository.LLMUsage, error) {
+	if ct.store == nil {
+		return nil, nil
+	}
+
+	end := time.Now()
+	records, err := ct.store.ListByUser(ctx, userID, end.AddDate(0, 0, 1-days), end)
+	if err != nil {
+		return nil, fmt.Errorf("查询模型用量失败: %w", err)
+	}
+	return records, nil
+}
+
+// observe 更新Token、成本与当日总成本指标
+func (ct *CostTracker) observe(cost QueryCost) {
+	ct.metrics.tokens.WithLabelValues(cost.Provider, cost.ModelName, "input").Add(float64(cost.InputTokens))
+	ct.metrics.tokens.WithLabelValues(cost.Provider, cost.ModelName, "output").Add(float64(cost.OutputTokens))
+	ct.metrics.cost.WithLabelValues(cost.Provider, cost.ModelName).Add(cost.Cost)
+
+	ct.mu.RLock()
+	if usage, exists := ct.dailyUsage[time.Now().Format("2006-01-02")]; exists {
+		ct.metrics.dailySpend.Set(usage.TotalCost)
+	}
+	ct.mu.RUnlock()
+}
+
+// persistUsage 将一次调用的用量累加到存储，未设置存储时直接返回
+func (ct *CostTracker) persistUsage(userID int64, cost QueryCost) error {
+	if ct.store == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), usageStoreTimeout)
+	defer cancel()
+
+	day := cost.Timestamp
+	if day.IsZero() {
+		day = time.Now()
+	}
+	err := ct.store.Add(ctx, &repository.LLMUsage{
+		Day:          day,
+		UserID:       userID,
+		Provider:     cost.Provider,
+		Model:        cost.ModelName,
+		Queries:      1,
+		InputTokens:  int64(cost.InputTokens),
+		OutputTokens: int64(cost.OutputTokens),
+		Cost:         cost.Cost,
+	})
+	if err != nil {
+		ct.logger.Warn("保存模型用量失败", zap.Int64("user_id", userID), zap.Error(err))
+		return fmt.Errorf("保存模型用量失败: %w", err)
+	}
+	return nil
+}
+
+// addUsage 将用量累加到按模型分组的统计
+func addUsage(byModel map[string]*Usage, model string, usage *Usage) {
+	current, exists := byModel[model]
+	if !exists {
+		current = &Usage{}
+		byModel[model] = current
+	}
+	current.QueryCount += usage.QueryCount
+	current.InputTokens += usage.InputTokens
+	current.OutputTokens += usage.OutputTokens
+	current.TotalTokens += usage.TotalTokens
+	current.Cost += usage.Cost
+}
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// memoryLLMUsageRepository 内存用量存储，按日期、用户、提供商与模型累加
+type memoryLLMUsageRepository struct {
+	rows      map[string]*repository.LLMUsage
+	overrides map[int64]*repository.LLMBudgetOverride
+	mu        sync.Mutex
+}
+
+func (r *memoryLLMUsageRepository) Add(ctx context.Context, usage *repository.LLMUsage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := fmt.Sprintf("%s|%d|%s|%s", usage.Day.Format(time.DateOnly), usage.UserID, usage.Provider, usage.Model)
+	row, exists := r.rows[key]
+	if !exists {
+		copied := *usage
+		copied.Day, _ = time.Parse(time.DateOnly, usage.Day.Format(time.DateOnly))
+		r.rows[key] = &copied
+		return nil
+	}
+	row.Queries += usage.Queries
+	row.InputTokens += usage.InputTokens
+	row.OutputTokens += usage.OutputTokens
+	row.Cost += usage.Cost
+	return nil
+}
+
+func (r *memoryLLMUsageRepository) ListByDay(ctx context.Context, day time.Time) ([]*repository.LLMUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rows []*repository.LLMUsage
+	for _, row := range r.rows {
+		if row.Day.Format(time.DateOnly) == day.Format(time.DateOnly) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (r *memoryLLMUsageRepository) ListByUser(ctx context.Context, userID int64, start, end time.Time) ([]*repository.LLMUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rows []*repository.LLMUsage
+	for _, row := range r.rows {
+		day := row.Day.Format(time.DateOnly)
+		if row.UserID == userID && day >= start.Format(time.DateOnly) && day <= end.Format(time.DateOnly) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (r *memoryLLMUsageRepository) SetBudgetOverride(ctx context.Context, override *repository.LLMBudgetOverride) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.overrides == nil {
+		r.overrides = make(map[int64]*repository.LLMBudgetOverride)
+	}
+	copied := *override
+	r.overrides[override.UserID] = &copied
+	return nil
+}
+
+func (r *memoryLLMUsageRepository) ListBudgetOverrides(ctx context.Context, now time.Time) ([]*repository.LLMBudgetOverride, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var overrides []*repository.LLMBudgetOverride
+	for _, override := range r.overrides {
+		if override.ExpiresAt.After(now) {
+			overrides = append(overrides, override)
+		}
+	}
+	return overrides, nil
+}
+
+func TestCostTracker_BudgetAndPersistence(t *testing.T) {
+	store := &memoryLLMUsageRepository{rows: map[string]*repository.LLMUsage{}}
+	budget := config.BudgetConfig{DailyLimit: 1.0, UserLimit: 0.05, AlertThreshold: 0.8, Enforce: true}
+	tracker := NewCostTracker(CostConfigFromBudget(budget))
+	tracker.SetUsageStore(store, nil)
+
+	// 本地与模拟提供商不计费
+	assert.Zero(t, tracker.EstimateCost("ollama", "deepseek-r1:7b", 10000, 10000))
+	assert.Greater(t, tracker.EstimateCost("openai", "gpt-4o", 1000, 1000), 0.0)
+
+	require.NoError(t, tracker.CheckBudget(7))
+	require.NoError(t, tracker.RecordUsage(7, "openai", "gpt-4o", "q1", 1000, 200))
+	require.NoError(t, tracker.RecordUsage(7, "openai", "gpt-4o", "q2", 1000, 200))
+	require.NoError(t, tracker.RecordUsage(8, "ollama", "deepseek-r1:7b", "q3", 1000, 200))
+	require.Len(t, store.rows, 2, "同一天同一用户与模型累加到一行")
+
+	// 用户7当日用量达到上限后被拒绝，其他用户不受影响
+	err := tracker.CheckBudget(7)
+	var limitErr *CostLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, "user_daily", limitErr.Type)
+	assert.NoError(t, tracker.CheckBudget(8))
+
+	status := tracker.BudgetStatus(7)
+	assert.Equal(t, 2, status.UserQueries)
+	assert.InDelta(t, 0.054, status.UserCost, 1e-9)
+	assert.Equal(t, 0.05, status.UserLimit)
+
+	// 重启后从存储恢复当日用量，预算判断保持一致
+	restarted := NewCostTracker(CostConfigFromBudget(budget))
+	restarted.SetUsageStore(store, nil)
+	loaded, err := restarted.LoadUsage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+	assert.Equal(t, status.UserCost, restarted.BudgetStatus(7).UserCost)
+	assert.Error(t, restarted.CheckBudget(7))
+
+	history, err := restarted.UsageHistory(context.Background(), 7, 7)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, int64(2), history[0].Queries)
+	assert.Equal(t, int64(2000), history[0].InputTokens)
+}
+
+func TestCostTracker_CheckBudgetUnlimited(t *testing.T) {
+	tracker := NewCostTracker(CostConfigFromBudget(config.BudgetConfig{}))
+	require.NoError(t, tracker.RecordUsage(7, "openai", "gpt-4o", "q", 100000, 100000))
+	assert.NoError(t, tracker.CheckBudget(7), "上限为0表示不限")
+
+	tracker = NewCostTracker(CostConfigFromBudget(config.BudgetConfig{DailyLimit: 0.01, UserLimit: 10}))
+	require.NoError(t, tracker.RecordUsage(7, "openai", "gpt-4o", "q", 1000, 1000))
+	var limitErr *CostLimitError
+	require.ErrorAs(t, tracker.CheckBudget(8), &limitErr)
+	assert.Equal(t, "daily_budget", limitErr.Type, "全局预算对所有用户生效")
+}
+
+func TestCostTracker_UserLimitRaise(t *testing.T) {
+	store := &memoryLLMUsageRepository{rows: map[string]*repository.LLMUsage{}}
+	budget := config.BudgetConfig{DailyLimit: 10, UserLimit: 0.01, AlertThreshold: 0.8, Enforce: true}
+	tracker := NewCostTracker(CostConfigFromBudget(budget))
+	tracker.SetUsageStore(store, nil)
+
+	require.NoError(t, tracker.RecordUsage(7, "openai", "gpt-4o", "q1", 5000, 1000))
+	require.Error(t, tracker.CheckBudget(7), "达到全局每用户上限")
+
+	// 上调后的上限在有效期内生效，重启后从存储恢复
+	require.NoError(t, store.SetBudgetOverride(context.Background(), &repository.LLMBudgetOverride{
+		UserID: 7, DailyLimit: 1, ExpiresAt: time.Now().Add(time.Hour)}))
+	restarted := NewCostTracker(CostConfigFromBudget(budget))
+	restarted.SetUsageStore(store, nil)
+	_, err := restarted.LoadUsage(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, restarted.CheckBudget(7))
+	status := restarted.BudgetStatus(7)
+	assert.Equal(t, 1.0, status.UserLimit)
+	assert.NotNil(t, status.UserLimitExpiresAt)
+	assert.Equal(t, 0.01, restarted.BudgetStatus(8).UserLimit, "不影响其他用户")
+
+	// 过期后恢复全局上限
+	restarted.SetUserLimit(7, 1, time.Now().Add(-time.Minute))
+	assert.Error(t, restarted.CheckBudget(7))
+}
@@ -4,6 +4,10 @@ package ai
 import (
 	"sync"
 	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
 )
 
 // CostTracker 成本追踪器
@@ -21,6 +25,12 @@ type CostTracker struct {
 	
 	// 告警管理
 	alerts *AlertManager
+	
+	// 用量持久化与指标，见cost_store.go
+	store   repository.LLMUsageRepository
+	userLimits map[int64]userLimit // 审批通过的每用户预算上调
+	metrics *costMetrics
+	logger  *zap.Logger
 }
 
 // CostConfig 成本配置
@@ -79,6 +89,7 @@ type Usage struct {
 type QueryCost struct {
 	Timestamp    time.Time `json:"timestamp"`
 	Query        string    `json:"query"`
+	Provider     string    `json:"provider"`
 	ModelName    string    `json:"model_name"`
 	InputTokens  int       `json:"input_tokens"`
 	OutputTokens int       `json:"output_tokens"`
@@ -182,11 +193,14 @@ func NewCostTracker(config *CostConfig) *CostTracker {
 		dailyUsage: make(map[string]*DailyUsage),
 		userUsage:  make(map[int64]*UserUsage),
 		modelCosts: make(map[string]*ModelCost),
+		userLimits: make(map[int64]userLimit),
 		config:     config,
 		alerts:     &AlertManager{
 			alerts: make([]CostAlert, 0),
 			config: config,
 		},
+		metrics: newCostMetrics(),
+		logger:  zap.NewNop(),
 	}
 	
 	// 初始化模型成本配置
@@ -243,6 +257,34 @@ func (ct *CostTracker) initializeModelCosts() {
 		MinimumCost:     0.0001,
 		LastUpdated:     time.Now(),
 	}
+	
+	// Google Gemini 模型成本（提示词不超过128K tokens）
+	ct.modelCosts["gemini-1.5-pro"] = &ModelCost{
+		ModelName:       "gemini-1.5-pro",
+		Provider:        "gemini",
+		InputCostPer1K:  0.00125,
+		OutputCostPer1K: 0.005,
+		MinimumCost:     0.0001,
+		LastUpdated:     time.Now(),
+	}
+	
+	ct.modelCosts["gemini-1.5-flash"] = &ModelCost{
+		ModelName:       "gemini-1.5-flash",
+		Provider:        "gemini",
+		InputCostPer1K:  0.000075,
+		OutputCostPer1K: 0.0003,
+		MinimumCost:     0.0001,
+		LastUpdated:     time.Now(),
+	}
+	
+	ct.modelCosts["gemini-2.0-flash"] = &ModelCost{
+		ModelName:       "gemini-2.0-flash",
+		Provider:        "gemini",
+		InputCostPer1K:  0.0001,
+		OutputCostPer1K: 0.0004,
+		MinimumCost:     0.0001,
+		LastUpdated:     time.Now(),
+	}
 }
 
 // CalculateQueryCost 计算查询成本
@@ -273,8 +315,15 @@ func (ct *CostTracker) CalculateQueryCost(inputTokens, outputTokens int, modelNa
 	return totalCost
 }
 
-// RecordQueryCost 记录查询成本
+// RecordQueryCost 记录查询成本，设置了用量存储时同时累加到当日汇总
 func (ct *CostTracker) RecordQueryCost(userID int64, cost QueryCost) error {
+	ct.record(userID, cost)
+	ct.observe(cost)
+	return ct.persistUsage(userID, cost)
+}
+
+// record 更新内存中的每日与用户统计
+func (ct *CostTracker) record(userID int64, cost QueryCost) {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 	
@@ -299,8 +348,6 @@ func (ct *CostTracker) RecordQueryCost(userID int64, cost QueryCost) error {
 			UserID:    userID,
 		})
 	}
-	
-	return nil
 }
 
 // updateDailyUsage 更新每日使用量
@@ -400,8 +447,8 @@ func (ct *CostTracker) checkBudgetLimits(userID int64, queryCost float64) error
 	
 	// 检查用户每日限制
 	if userUsage, exists := ct.userUsage[userID]; exists {
-		if userUsage.DailyCost+queryCost > ct.config.UserDailyLimit {
-			return NewCostLimitError("user_daily", userUsage.DailyCost+queryCost, ct.config.UserDailyLimit)
+		if limit := ct.userDailyLimit(userID, time.Now()); userUsage.DailyCost+queryCost > limit {
+			return NewCostLimitError("user_daily", userUsage.DailyCost+queryCost, limit)
 		}
 	}
 	
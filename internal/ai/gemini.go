@@ -0,0 +1,316 @@
+// Google Gemini提供商
+// 直接调用Generative Language REST接口（v1beta generateContent），实现llms.Model；
+// 支持系统指令、多轮对话与SSE流式输出，用量按PromptTokens/CompletionTokens/TotalTokens写入GenerationInfo
+
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	// GeminiDefaultModel 未指定模型时使用的Gemini模型
+	GeminiDefaultModel = "gemini-1.5-flash"
+	// GeminiDefaultBaseURL Generative Language API地址
+	GeminiDefaultBaseURL = "https://generativelanguage.googleapis.com"
+)
+
+// GeminiLLM Google Gemini模型客户端
+type GeminiLLM struct {
+	apiKey     string
+	model      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// GeminiOption Gemini客户端选项
+type GeminiOption func(*GeminiLLM)
+
+// WithGeminiBaseURL 替换API地址，用于代理或测试
+func WithGeminiBaseURL(baseURL string) GeminiOption {
+	return func(g *GeminiLLM) {
+		g.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithGeminiHTTPClient 使用指定的HTTP客户端
+func WithGeminiHTTPClient(client *http.Client) GeminiOption {
+	return func(g *GeminiLLM) {
+		g.httpClient = client
+	}
+}
+
+// NewGeminiLLM 创建Gemini客户端，model为空时使用GeminiDefaultModel
+func NewGeminiLLM(apiKey, model string, opts ...GeminiOption) (*GeminiLLM, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("gemini: API key is required")
+	}
+	if model == "" {
+		model = GeminiDefaultModel
+	}
+	g := &GeminiLLM{
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    GeminiDefaultBaseURL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g, nil
+}
+
+// geminiPart 内容片段，只使用文本
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiContent 一轮对话内容，role为user或model
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiGenerationConfig 生成参数，温度为0也是有效值始终发送，其余零值字段不发送，由模型使用默认值
+type geminiGenerationConfig struct {
+	Temperature     float64  `json:"temperature"`
+	TopP            float64  `json:"topP,omitempty"`
+	TopK            int      `json:"topK,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	CandidateCount  int      `json:"candidateCount,omitempty"`
+	Seed            int      `json:"seed,omitempty"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata  *geminiUsage `json:"usageMetadata"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+}
+
+type geminiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// GenerateContent 调用Gemini生成内容，设置了StreamingFunc时以SSE流式接收并逐段回调
+func (g *GeminiLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	body, err := g.buildRequest(messages, &opts)
+	if err != nil {
+		return nil, err
+	}
+	model := g.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	method := "generateContent"
+	if opts.StreamingFunc != nil {
+		method = "streamGenerateContent?alt=sse"
+	}
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:%s", g.baseURL, url.PathEscape(model), method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", g.apiKey)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, geminiStatusError(resp)
+	}
+	if opts.StreamingFunc != nil {
+		return g.readStream(ctx, resp.Body, model, opts.StreamingFunc)
+	}
+
+	var result geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("gemini: decode response: %w", err)
+	}
+	return geminiContentResponse(&result, model)
+}
+
+// Call 单提示词调用
+func (g *GeminiLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, g, prompt, options...)
+}
+
+// buildRequest 将消息转换为Gemini请求：系统消息合并为systemInstruction，AI消息的角色为model
+func (g *GeminiLLM) buildRequest(messages []llms.MessageContent, opts *llms.CallOptions) ([]byte, error) {
+	req := geminiRequest{
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     opts.Temperature,
+			TopP:            opts.TopP,
+			TopK:            opts.TopK,
+			MaxOutputTokens: opts.MaxTokens,
+			StopSequences:   opts.StopWords,
+			CandidateCount:  opts.CandidateCount,
+			Seed:            opts.Seed,
+		},
+	}
+
+	for _, message := range messages {
+		var parts []geminiPart
+		for _, part := range message.Parts {
+			text, ok := part.(llms.TextContent)
+			if !ok {
+				return nil, fmt.Errorf("gemini: unsupported content part %T", part)
+			}
+			parts = append(parts, geminiPart{Text: text.Text})
+		}
+
+		switch message.Role {
+		case llms.ChatMessageTypeSystem:
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &geminiContent{}
+			}
+			req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, parts...)
+		case llms.ChatMessageTypeAI:
+			req.Contents = append(req.Contents, geminiContent{Role: "model", Parts: parts})
+		case llms.ChatMessageTypeHuman, llms.ChatMessageTypeGeneric:
+			req.Contents = append(req.Contents, geminiContent{Role: "user", Parts: parts})
+		default:
+			return nil, fmt.Errorf("gemini: unsupported message role %q", message.Role)
+		}
+	}
+	if len(req.Contents) == 0 {
+		return nil, fmt.Errorf("gemini: at least one user message is required")
+	}
+	return json.Marshal(req)
+}
+
+// readStream 逐条读取SSE事件，回调每段文本；用量在最后一个事件中返回
+func (g *GeminiLLM) readStream(ctx context.Context, body io.Reader, model string, stream func(ctx context.Context, chunk []byte) error) (*llms.ContentResponse, error) {
+	var content strings.Builder
+	var finishReason string
+	var usage *geminiUsage
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &chunk); err != nil {
+			return nil, fmt.Errorf("gemini: decode stream event: %w", err)
+		}
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata
+		}
+		if len(chunk.Candidates) == 0 {
+			continue
+		}
+		candidate := chunk.Candidates[0]
+		if candidate.FinishReason != "" {
+			finishReason = candidate.FinishReason
+		}
+		for _, part := range candidate.Content.Parts {
+			if part.Text == "" {
+				continue
+			}
+			content.WriteString(part.Text)
+			if err := stream(ctx, []byte(part.Text)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("gemini: read stream: %w", err)
+	}
+
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			Content:        content.String(),
+			StopReason:     finishReason,
+			GenerationInfo: geminiGenerationInfo(usage, model),
+		}},
+	}, nil
+}
+
+// geminiContentResponse 转换非流式响应，提示词被安全策略拦截时返回错误
+func geminiContentResponse(result *geminiResponse, model string) (*llms.ContentResponse, error) {
+	if len(result.Candidates) == 0 {
+		if result.PromptFeedback != nil && result.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("gemini: prompt blocked: %s", result.PromptFeedback.BlockReason)
+		}
+		return nil, fmt.Errorf("gemini: no candidates in response")
+	}
+
+	choices := make([]*llms.ContentChoice, 0, len(result.Candidates))
+	for _, candidate := range result.Candidates {
+		var text strings.Builder
+		for _, part := range candidate.Content.Parts {
+			text.WriteString(part.Text)
+		}
+		choices = append(choices, &llms.ContentChoice{
+			Content:        text.String(),
+			StopReason:     candidate.FinishReason,
+			GenerationInfo: geminiGenerationInfo(result.UsageMetadata, model),
+		})
+	}
+	return &llms.ContentResponse{Choices: choices}, nil
+}
+
+// geminiGenerationInfo 按OpenAI客户端的键名记录用量，便于统一统计token
+func geminiGenerationInfo(usage *geminiUsage, model string) map[string]any {
+	info := map[string]any{"model": model}
+	if usage != nil {
+		info["PromptTokens"] = usage.PromptTokenCount
+		info["CompletionTokens"] = usage.CandidatesTokenCount
+		info["TotalTokens"] = usage.TotalTokenCount
+	}
+	return info
+}
+
+// geminiStatusError 将非200响应转换为错误，保留HTTP状态码以便判断是否可重试
+func geminiStatusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var apiErr geminiError
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Message != "" {
+		return fmt.Errorf("gemini: %d %s: %s", resp.StatusCode, apiErr.Error.Status, apiErr.Error.Message)
+	}
+	return fmt.Errorf("gemini: %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestGeminiLLM_GenerateContent(t *testing.T) {
+	var got geminiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-1.5-pro:generateContent", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{
+			"candidates": [{"content": {"role": "model", "parts": [{"text": "SELECT "}, {"text": "1"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 3, "totalTokenCount": 15}
+		}`)
+	}))
+	defer server.Close()
+
+	llm, err := NewGeminiLLM("test-key", "gemini-1.5-pro", WithGeminiBaseURL(server.URL+"/"))
+	require.NoError(t, err)
+
+	resp, err := llm.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "你是SQL专家"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "查询用户"),
+		llms.TextParts(llms.ChatMessageTypeAI, "SELECT * FROM users"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "只要数量"),
+	}, llms.WithTemperature(0), llms.WithMaxTokens(256), llms.WithTopP(0.9))
+	require.NoError(t, err)
+
+	require.NotNil(t, got.SystemInstruction)
+	assert.Equal(t, "你是SQL专家", got.SystemInstruction.Parts[0].Text)
+	require.Len(t, got.Contents, 3)
+	assert.Equal(t, []string{"user", "model", "user"}, []string{got.Contents[0].Role, got.Contents[1].Role, got.Contents[2].Role})
+	assert.Equal(t, 256, got.GenerationConfig.MaxOutputTokens)
+	assert.Equal(t, 0.9, got.GenerationConfig.TopP)
+
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "SELECT 1", resp.Choices[0].Content)
+	assert.Equal(t, "STOP", resp.Choices[0].StopReason)
+	input, output, ok := ReportedTokens(resp.Choices[0].GenerationInfo)
+	require.True(t, ok)
+	assert.Equal(t, 12, input)
+	assert.Equal(t, 3, output)
+}
+
+func TestGeminiLLM_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-1.5-flash:streamGenerateContent", r.URL.Path)
+		assert.Equal(t, "sse", r.URL.Query().Get("alt"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": \"SELECT name\"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": \" FROM users\"}]}, \"finishReason\": \"STOP\"}],"+
+			" \"usageMetadata\": {\"promptTokenCount\": 8, \"candidatesTokenCount\": 4, \"totalTokenCount\": 12}}\n\n")
+	}))
+	defer server.Close()
+
+	llm, err := NewGeminiLLM("test-key", "", WithGeminiBaseURL(server.URL))
+	require.NoError(t, err)
+
+	var chunks []string
+	resp, err := llm.GenerateContent(context.Background(),
+		[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "列出用户名")},
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SELECT name", " FROM users"}, chunks)
+	assert.Equal(t, "SELECT name FROM users", resp.Choices[0].Content)
+	assert.Equal(t, 12, resp.Choices[0].GenerationInfo["TotalTokens"])
+}
+
+func TestGeminiLLM_Errors(t *testing.T) {
+	_, err := NewGeminiLLM("", "")
+	assert.Error(t, err, "缺少API密钥")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error": {"code": 429, "message": "Resource has been exhausted", "status": "RESOURCE_EXHAUSTED"}}`)
+	}))
+	defer server.Close()
+
+	llm, err := NewGeminiLLM("test-key", "", WithGeminiBaseURL(server.URL))
+	require.NoError(t, err)
+	_, err = llm.Call(context.Background(), "查询用户")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429 RESOURCE_EXHAUSTED")
+
+	_, err = llm.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeSystem, "只有系统消息")})
+	assert.Error(t, err, "没有用户消息")
+}
+
+func TestReportedTokens(t *testing.T) {
+	input, output, ok := ReportedTokens(map[string]any{"InputTokens": 10, "OutputTokens": 5})
+	assert.True(t, ok, "Anthropic键名")
+	assert.Equal(t, 10, input)
+	assert.Equal(t, 5, output)
+
+	_, _, ok = ReportedTokens(map[string]any{"model": MockLLMModelName})
+	assert.False(t, ok)
+	_, _, ok = ReportedTokens(nil)
+	assert.False(t, ok)
+}
@@ -1,5 +1,5 @@
 // LLM客户端工厂和路由管理
-// 支持OpenAI、Anthropic、Ollama等多种提供商
+// 支持OpenAI、Azure OpenAI、Anthropic、Gemini、Ollama等多种提供商
 // 基于LangChainGo的统一接口设计
 
 package ai
@@ -73,10 +73,16 @@ func createLLMProvider(provider LLMProvider, config *LLMConfig, httpClient *http
 	switch provider {
 	case ProviderOpenAI:
 		return createOpenAIClient(config, httpClient)
+	case ProviderAzureOpenAI:
+		return createAzureOpenAIClient(config, httpClient)
 	case ProviderAnthropic:
 		return createAnthropicClient(config, httpClient)
+	case ProviderGemini, ProviderGoogleAI:
+		return createGeminiClient(config, httpClient)
 	case ProviderOllama:
 		return createOllamaClient(config, httpClient)
+	case ProviderMock:
+		return NewMockLLMFromFile(config.RulesFile)
 	default:
 		return nil, fmt.Errorf("unsupported LLM provider: %s", provider)
 	}
@@ -98,6 +104,28 @@ func createOpenAIClient(config *LLMConfig, httpClient *http.Client) (llms.Model,
 	return openai.New(opts...)
 }
 
+// createAzureOpenAIClient 创建Azure OpenAI客户端
+// 请求发往 {BaseURL}/openai/deployments/{Deployment}/chat/completions?api-version={APIVersion}，以api-key头认证
+func createAzureOpenAIClient(config *LLMConfig, httpClient *http.Client) (llms.Model, error) {
+	if config.BaseURL == "" || config.Deployment == "" {
+		return nil, fmt.Errorf("azure openai requires an endpoint and a deployment")
+	}
+	
+	apiVersion := config.APIVersion
+	if apiVersion == "" {
+		apiVersion = DefaultAzureOpenAIAPIVersion
+	}
+	
+	return openai.New(
+		openai.WithAPIType(openai.APITypeAzure),
+		openai.WithToken(config.APIKey),
+		openai.WithBaseURL(config.BaseURL),
+		openai.WithModel(config.Deployment),
+		openai.WithAPIVersion(apiVersion),
+		openai.WithHTTPClient(httpClient),
+	)
+}
+
 // createAnthropicClient 创建Anthropic客户端
 func createAnthropicClient(config *LLMConfig, httpClient *http.Client) (llms.Model, error) {
 	opts := []anthropic.Option{
@@ -109,6 +137,19 @@ func createAnthropicClient(config *LLMConfig, httpClient *http.Client) (llms.Mod
 	return anthropic.New(opts...)
 }
 
+// createGeminiClient 创建Gemini客户端
+func createGeminiClient(config *LLMConfig, httpClient *http.Client) (llms.Model, error) {
+	opts := []GeminiOption{
+		WithGeminiHTTPClient(httpClient),
+	}
+	
+	if config.BaseURL != "" {
+		opts = append(opts, WithGeminiBaseURL(config.BaseURL))
+	}
+	
+	return NewGeminiLLM(config.APIKey, config.Model, opts...)
+}
+
 // createOllamaClient 创建Ollama客户端
 func createOllamaClient(config *LLMConfig, httpClient *http.Client) (llms.Model, error) {
 	opts := []ollama.Option{
@@ -139,6 +180,33 @@ func (c *LLMClient) GetLocalLLM() llms.Model {
 	return c.local
 }
 
+// GetProviderLLM 返回配置为指定提供商的模型实例，按主要、备用、本地的顺序查找，未配置时返回nil
+func (c *LLMClient) GetProviderLLM(provider LLMProvider) llms.Model {
+	slots := []struct {
+		provider LLMProvider
+		model    llms.Model
+	}{
+		{c.config.PrimaryProvider, c.primary},
+		{c.config.FallbackProvider, c.fallback},
+		{c.config.LocalProvider, c.local},
+	}
+	for _, slot := range slots {
+		if slot.model != nil && slot.provider == provider {
+			return slot.model
+		}
+	}
+	return nil
+}
+
+// TestProvider 向指定提供商发送一条测试消息，验证API密钥与模型可用
+func (c *LLMClient) TestProvider(ctx context.Context, provider LLMProvider) error {
+	model := c.GetProviderLLM(provider)
+	if model == nil {
+		return fmt.Errorf("%s provider is not configured as primary, fallback or local", provider)
+	}
+	return c.testLLMProvider(ctx, model, string(provider))
+}
+
 // GetConfig 获取配置信息
 func (c *LLMClient) GetConfig() *LLMRouterConfig {
 	return c.config
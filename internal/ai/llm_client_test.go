@@ -5,7 +5,9 @@ package ai
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -198,6 +200,47 @@ func TestCreateOpenAIClient(t *testing.T) {
 	}
 }
 
+// TestCreateAzureOpenAIClient 测试Azure OpenAI请求按部署与api-version路由
+func TestCreateAzureOpenAIClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/sql-gpt4o/chat/completions", r.URL.Path)
+		assert.Equal(t, "2024-06-01", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "test-azure-key", r.Header.Get("api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"OK"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`)
+	}))
+	defer server.Close()
+
+	client, err := createLLMProvider(ProviderAzureOpenAI, &LLMConfig{
+		Provider:   ProviderAzureOpenAI,
+		APIKey:     "test-azure-key",
+		BaseURL:    server.URL,
+		Deployment: "sql-gpt4o",
+		APIVersion: "2024-06-01",
+	}, server.Client())
+	require.NoError(t, err)
+
+	response, err := llms.GenerateFromSinglePrompt(context.Background(), client, "ping")
+	require.NoError(t, err)
+	assert.Equal(t, "OK", response)
+
+	_, err = createAzureOpenAIClient(&LLMConfig{APIKey: "test-azure-key", BaseURL: server.URL}, server.Client())
+	assert.Error(t, err, "缺少部署名称")
+
+	// llm-test --provider azure_openai 的验证流程
+	llmClient, err := NewLLMClient(&LLMRouterConfig{
+		PrimaryProvider:   ProviderAzureOpenAI,
+		PrimaryConfig:     &LLMConfig{Provider: ProviderAzureOpenAI, APIKey: "test-azure-key", BaseURL: server.URL, Deployment: "sql-gpt4o", APIVersion: "2024-06-01"},
+		FallbackProvider:  ProviderMock,
+		FallbackConfig:    &LLMConfig{Provider: ProviderMock},
+		PerformanceConfig: DefaultPerformanceConfig(),
+	}, zap.NewNop())
+	require.NoError(t, err)
+	assert.NoError(t, llmClient.TestProvider(context.Background(), ProviderAzureOpenAI))
+	assert.Error(t, llmClient.TestProvider(context.Background(), ProviderGemini), "未配置的提供商")
+}
+
 // TestCreateAnthropicClient 测试Anthropic客户端创建
 func TestCreateAnthropicClient(t *testing.T) {
 	httpClient := &http.Client{Timeout: 30 * time.Second}
@@ -1,5 +1,5 @@
 // LLM提供商配置管理
-// 支持OpenAI, Anthropic, Ollama等多种提供商
+// 支持OpenAI, Azure OpenAI, Anthropic, Gemini, Ollama等多种提供商
 // 基于环境变量的动态配置加载
 
 package ai
@@ -17,10 +17,13 @@ type LLMProvider string
 
 const (
 	ProviderOpenAI     LLMProvider = "openai"
+	ProviderAzureOpenAI LLMProvider = "azure_openai" // Azure OpenAI，按部署调用
 	ProviderAnthropic  LLMProvider = "anthropic"  
 	ProviderOllama     LLMProvider = "ollama"
-	ProviderGoogleAI   LLMProvider = "googleai"
+	ProviderGoogleAI   LLMProvider = "googleai" // 与gemini相同，保留旧名称
+	ProviderGemini     LLMProvider = "gemini"
 	ProviderHuggingFace LLMProvider = "huggingface"
+	ProviderMock       LLMProvider = "mock" // 确定性Mock，用于演示与测试
 )
 
 // LLMConfig 单个LLM提供商配置
@@ -32,8 +35,16 @@ type LLMConfig struct {
 	Temperature float64     `json:"temperature"`
 	MaxTokens   int         `json:"max_tokens"`
 	TopP        float64     `json:"top_p,omitempty"`
+	RulesFile   string      `json:"rules_file,omitempty"` // Mock提供商的YAML规则文件
+	
+	// Azure OpenAI：请求发往BaseURL下的/openai/deployments/{Deployment}，并携带api-version
+	Deployment  string      `json:"deployment,omitempty"`
+	APIVersion  string      `json:"api_version,omitempty"`
 }
 
+// DefaultAzureOpenAIAPIVersion 未设置AZURE_OPENAI_API_VERSION时使用的API版本
+const DefaultAzureOpenAIAPIVersion = "2024-06-01"
+
 // LLMRouterConfig LLM路由配置
 type LLMRouterConfig struct {
 	// 主要模型配置
@@ -67,6 +78,7 @@ type ExtendedCostConfig struct {
 	// 价格表（每1K tokens的价格，美分）
 	OpenAIPricing map[string]OpenAIPricing `json:"openai_pricing"`
 	AnthropicPricing map[string]AnthropicPricing `json:"anthropic_pricing"`
+	GeminiPricing map[string]GeminiPricing `json:"gemini_pricing"`
 }
 
 // OpenAI定价结构
@@ -81,6 +93,12 @@ type AnthropicPricing struct {
 	OutputPrice float64 `json:"output_price"` // 输出token价格（美分/1K tokens）
 }
 
+// Gemini定价结构
+type GeminiPricing struct {
+	InputPrice  float64 `json:"input_price"`  // 输入token价格（美分/1K tokens）
+	OutputPrice float64 `json:"output_price"` // 输出token价格（美分/1K tokens）
+}
+
 // LoadLLMConfig 从环境变量加载LLM配置
 func LoadLLMConfig() (*LLMRouterConfig, error) {
 	config := &LLMRouterConfig{
@@ -148,6 +166,32 @@ func loadProviderConfig(provider LLMProvider) (*LLMConfig, error) {
 			return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required")
 		}
 		
+	case ProviderAzureOpenAI:
+		config.APIKey = os.Getenv("AZURE_OPENAI_API_KEY")
+		config.Deployment = os.Getenv("AZURE_OPENAI_DEPLOYMENT")
+		config.APIVersion = getEnvWithDefault("AZURE_OPENAI_API_VERSION", DefaultAzureOpenAIAPIVersion)
+		config.Temperature = getFloatEnvWithDefault("AZURE_OPENAI_TEMPERATURE", 0.1)
+		config.MaxTokens = getIntEnvWithDefault("AZURE_OPENAI_MAX_TOKENS", 2048)
+		config.TopP = getFloatEnvWithDefault("AZURE_OPENAI_TOP_P", 0.9)
+		// 部署名称决定请求路径；模型名称仅用于展示与成本估算，未设置时与部署名称相同
+		config.Model = getEnvWithDefault("AZURE_OPENAI_MODEL", config.Deployment)
+		
+		// 资源名称对应 https://{resource}.openai.azure.com，私有终结点或代理可直接设置AZURE_OPENAI_ENDPOINT
+		config.BaseURL = os.Getenv("AZURE_OPENAI_ENDPOINT")
+		if resource := os.Getenv("AZURE_OPENAI_RESOURCE"); config.BaseURL == "" && resource != "" {
+			config.BaseURL = fmt.Sprintf("https://%s.openai.azure.com", resource)
+		}
+		
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("AZURE_OPENAI_API_KEY environment variable is required")
+		}
+		if config.BaseURL == "" {
+			return nil, fmt.Errorf("AZURE_OPENAI_RESOURCE or AZURE_OPENAI_ENDPOINT environment variable is required")
+		}
+		if config.Deployment == "" {
+			return nil, fmt.Errorf("AZURE_OPENAI_DEPLOYMENT environment variable is required")
+		}
+		
 	case ProviderAnthropic:
 		config.APIKey = os.Getenv("ANTHROPIC_API_KEY")
 		config.Model = getEnvWithDefault("ANTHROPIC_MODEL", "claude-3-haiku-20240307")
@@ -158,6 +202,18 @@ func loadProviderConfig(provider LLMProvider) (*LLMConfig, error) {
 			return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required")
 		}
 		
+	case ProviderGemini, ProviderGoogleAI:
+		config.APIKey = os.Getenv("GEMINI_API_KEY")
+		config.Model = getEnvWithDefault("GEMINI_MODEL", GeminiDefaultModel)
+		config.BaseURL = os.Getenv("GEMINI_BASE_URL") // 可选，默认Generative Language API
+		config.Temperature = getFloatEnvWithDefault("GEMINI_TEMPERATURE", 0.1)
+		config.MaxTokens = getIntEnvWithDefault("GEMINI_MAX_TOKENS", 2048)
+		config.TopP = getFloatEnvWithDefault("GEMINI_TOP_P", 0.9)
+		
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY environment variable is required")
+		}
+		
 	case ProviderOllama:
 		config.BaseURL = getEnvWithDefault("OLLAMA_SERVER_URL", "http://localhost:11434")
 		config.Model = getEnvWithDefault("OLLAMA_MODEL", "llama3.1:8b")
@@ -165,6 +221,9 @@ func loadProviderConfig(provider LLMProvider) (*LLMConfig, error) {
 		config.MaxTokens = getIntEnvWithDefault("OLLAMA_MAX_TOKENS", 2048)
 		// Ollama不需要API密钥
 		
+	case ProviderMock:
+		config.Model = MockLLMModelName
+		config.RulesFile = os.Getenv("MOCK_LLM_RULES_FILE") // 为空时使用内置规则
 		
 	default:
 		return nil, fmt.Errorf("unsupported LLM provider: %s", provider)
@@ -181,6 +240,7 @@ func loadExtendedCostConfig() *ExtendedCostConfig {
 		TotalDailyBudget:     getFloatEnvWithDefault("TOTAL_DAILY_BUDGET", 500.0),
 		OpenAIPricing:        getDefaultOpenAIPricing(),
 		AnthropicPricing:     getDefaultAnthropicPricing(),
+		GeminiPricing:        getDefaultGeminiPricing(),
 	}
 }
 
@@ -266,6 +326,24 @@ func getDefaultAnthropicPricing() map[string]AnthropicPricing {
 	}
 }
 
+// getDefaultGeminiPricing 获取默认Gemini定价（提示词不超过128K tokens的价格）
+func getDefaultGeminiPricing() map[string]GeminiPricing {
+	return map[string]GeminiPricing{
+		"gemini-1.5-pro": {
+			InputPrice:  0.125,  // $0.00125/1K input tokens
+			OutputPrice: 0.5,    // $0.005/1K output tokens
+		},
+		"gemini-1.5-flash": {
+			InputPrice:  0.0075, // $0.000075/1K input tokens
+			OutputPrice: 0.03,   // $0.0003/1K output tokens
+		},
+		"gemini-2.0-flash": {
+			InputPrice:  0.01,   // $0.0001/1K input tokens
+			OutputPrice: 0.04,   // $0.0004/1K output tokens
+		},
+	}
+}
+
 // 辅助函数：获取环境变量，如果不存在则返回默认值
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
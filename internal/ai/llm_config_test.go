@@ -134,6 +134,65 @@ func TestLoadProviderConfig_Anthropic(t *testing.T) {
 	assert.Equal(t, 1024, config.MaxTokens) // 默认值
 }
 
+// TestLoadProviderConfig_AzureOpenAI 测试Azure OpenAI提供商配置加载
+func TestLoadProviderConfig_AzureOpenAI(t *testing.T) {
+	t.Setenv("AZURE_OPENAI_API_KEY", "test-azure-key")
+	t.Setenv("AZURE_OPENAI_RESOURCE", "contoso")
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("AZURE_OPENAI_DEPLOYMENT", "sql-gpt4o")
+	t.Setenv("AZURE_OPENAI_API_VERSION", "")
+	t.Setenv("AZURE_OPENAI_MODEL", "")
+
+	config, err := loadProviderConfig(ProviderAzureOpenAI)
+	
+	assert.NoError(t, err)
+	assert.Equal(t, "https://contoso.openai.azure.com", config.BaseURL)
+	assert.Equal(t, "sql-gpt4o", config.Deployment)
+	assert.Equal(t, "sql-gpt4o", config.Model) // 未设置模型名称时与部署名称相同
+	assert.Equal(t, DefaultAzureOpenAIAPIVersion, config.APIVersion)
+
+	// 私有终结点优先于资源名称
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "https://private.example.com")
+	t.Setenv("AZURE_OPENAI_MODEL", "gpt-4o")
+	config, err = loadProviderConfig(ProviderAzureOpenAI)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://private.example.com", config.BaseURL)
+	assert.Equal(t, "gpt-4o", config.Model)
+
+	t.Setenv("AZURE_OPENAI_DEPLOYMENT", "")
+	_, err = loadProviderConfig(ProviderAzureOpenAI)
+	assert.ErrorContains(t, err, "AZURE_OPENAI_DEPLOYMENT")
+
+	t.Setenv("AZURE_OPENAI_DEPLOYMENT", "sql-gpt4o")
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "")
+	t.Setenv("AZURE_OPENAI_RESOURCE", "")
+	_, err = loadProviderConfig(ProviderAzureOpenAI)
+	assert.ErrorContains(t, err, "AZURE_OPENAI_RESOURCE")
+}
+
+// TestLoadProviderConfig_Gemini 测试Gemini提供商配置加载
+func TestLoadProviderConfig_Gemini(t *testing.T) {
+	t.Setenv("GEMINI_API_KEY", "test-gemini-key")
+	t.Setenv("GEMINI_MODEL", "")
+
+	config, err := loadProviderConfig(ProviderGemini)
+	
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderGemini, config.Provider)
+	assert.Equal(t, "test-gemini-key", config.APIKey)
+	assert.Equal(t, GeminiDefaultModel, config.Model) // 默认值
+	assert.Equal(t, 2048, config.MaxTokens) // 默认值
+
+	t.Setenv("GEMINI_MODEL", "gemini-1.5-pro")
+	config, err = loadProviderConfig(ProviderGoogleAI)
+	assert.NoError(t, err)
+	assert.Equal(t, "gemini-1.5-pro", config.Model)
+
+	t.Setenv("GEMINI_API_KEY", "")
+	_, err = loadProviderConfig(ProviderGemini)
+	assert.ErrorContains(t, err, "GEMINI_API_KEY")
+}
+
 // TestLoadProviderConfig_Ollama 测试Ollama提供商配置加载
 func TestLoadProviderConfig_Ollama(t *testing.T) {
 	originalURL := os.Getenv("OLLAMA_SERVER_URL")
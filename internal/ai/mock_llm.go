@@ -0,0 +1,190 @@
+// 确定性Mock LLM提供商
+// 根据问题文本的正则规则返回预置SQL，用于--demo模式与集成测试，
+// 使CI无需网络或GPU即可走通完整的SQL生成链路
+
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tmc/langchaingo/llms"
+	"gopkg.in/yaml.v3"
+)
+
+// MockLLMModelName Mock提供商上报的模型名称
+const MockLLMModelName = "mock-sql"
+
+// MockLLMRule 单条匹配规则，Pattern为不区分大小写的正则表达式
+type MockLLMRule struct {
+	Pattern string `yaml:"pattern"`
+	SQL     string `yaml:"sql"`
+}
+
+// MockLLMRules Mock提供商规则集
+type MockLLMRules struct {
+	Rules      []MockLLMRule `yaml:"rules"`
+	DefaultSQL string        `yaml:"default_sql"` // 无规则命中时返回，为空则返回错误
+}
+
+// DefaultMockLLMRules 内置规则，覆盖ecommerce_db演示库的常见问题
+func DefaultMockLLMRules() *MockLLMRules {
+	return &MockLLMRules{
+		Rules: []MockLLMRule{
+			{Pattern: `(用户|users?).*(总数|数量|多少|count)|how many users`, SQL: "SELECT COUNT(*) AS total_users FROM users"},
+			{Pattern: `(订单|orders?).*(总数|数量|多少|count)|how many orders`, SQL: "SELECT COUNT(*) AS total_orders FROM orders"},
+			{Pattern: `(销售额|收入|revenue|sales)`, SQL: "SELECT DATE_TRUNC('month', created_at) AS month, SUM(total_amount) AS revenue FROM orders GROUP BY 1 ORDER BY 1"},
+			{Pattern: `(热销|畅销|top).*(产品|商品|products?)`, SQL: "SELECT p.name, SUM(oi.quantity) AS sold FROM order_items oi JOIN products p ON p.id = oi.product_id GROUP BY p.name ORDER BY sold DESC LIMIT 10"},
+			{Pattern: `(产品|商品|products?)`, SQL: "SELECT id, name, price, stock_quantity FROM products ORDER BY id LIMIT 100"},
+			{Pattern: `(用户|users?)`, SQL: "SELECT id, name, email, status FROM users ORDER BY id LIMIT 100"},
+		},
+		DefaultSQL: "SELECT id, user_id, total_amount, order_status, created_at FROM orders ORDER BY created_at DESC LIMIT 100",
+	}
+}
+
+// LoadMockLLMRules 从YAML文件加载规则，path为空时返回内置规则
+func LoadMockLLMRules(path string) (*MockLLMRules, error) {
+	if path == "" {
+		return DefaultMockLLMRules(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取Mock LLM规则文件失败: %w", err)
+	}
+
+	var rules MockLLMRules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("解析Mock LLM规则文件失败: %w", err)
+	}
+	return &rules, nil
+}
+
+// compiledMockRule 预编译的匹配规则
+type compiledMockRule struct {
+	pattern *regexp.Regexp
+	sql     string
+}
+
+// MockLLM 实现llms.Model的确定性提供商
+// 同一问题始终返回同一SQL，不访问网络
+type MockLLM struct {
+	rules      []compiledMockRule
+	defaultSQL string
+	calls      atomic.Int64
+}
+
+// NewMockLLM 根据规则集创建Mock LLM，规则按顺序匹配，首个命中生效
+func NewMockLLM(rules *MockLLMRules) (*MockLLM, error) {
+	if rules == nil {
+		rules = DefaultMockLLMRules()
+	}
+
+	m := &MockLLM{defaultSQL: rules.DefaultSQL}
+	for i, rule := range rules.Rules {
+		if rule.SQL == "" {
+			return nil, fmt.Errorf("Mock LLM规则%d缺少sql", i+1)
+		}
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Mock LLM规则%d的pattern无效: %w", i+1, err)
+		}
+		m.rules = append(m.rules, compiledMockRule{pattern: re, sql: rule.SQL})
+	}
+	return m, nil
+}
+
+// NewMockLLMFromFile 从YAML规则文件创建Mock LLM，path为空时使用内置规则
+func NewMockLLMFromFile(path string) (*MockLLM, error) {
+	rules, err := LoadMockLLMRules(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMockLLM(rules)
+}
+
+// GenerateContent 从最后一条消息中提取用户问题并返回匹配的SQL
+func (m *MockLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.calls.Add(1)
+
+	var prompt string
+	if len(messages) > 0 {
+		for _, part := range messages[len(messages)-1].Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				prompt += text.Text
+			}
+		}
+	}
+
+	sql, err := m.Match(extractMockQuestion(prompt))
+	if err != nil {
+		return nil, err
+	}
+
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{
+			Content:    sql,
+			StopReason: "stop",
+			GenerationInfo: map[string]any{
+				"model": MockLLMModelName,
+			},
+		}},
+	}, nil
+}
+
+// Call 单提示词调用
+func (m *MockLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// Match 返回问题对应的SQL
+func (m *MockLLM) Match(question string) (string, error) {
+	for _, rule := range m.rules {
+		if rule.pattern.MatchString(question) {
+			return rule.sql, nil
+		}
+	}
+	if m.defaultSQL != "" {
+		return m.defaultSQL, nil
+	}
+	return "", fmt.Errorf("Mock LLM没有匹配问题的规则: %q", question)
+}
+
+// Calls 返回累计调用次数，便于测试断言
+func (m *MockLLM) Calls() int64 {
+	return m.calls.Load()
+}
+
+// extractMockQuestion 从提示词中提取"用户查询/需求"段落，避免表结构中的表名误触发规则
+// 多次出现时取最后一段；找不到标题时使用整段提示词
+func extractMockQuestion(prompt string) string {
+	lines := strings.Split(prompt, "\n")
+	question := ""
+	for i := 0; i < len(lines); i++ {
+		header := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(header, "##") || !strings.Contains(header, "用户") {
+			continue
+		}
+		var section []string
+		for i+1 < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i+1]), "##") {
+			i++
+			if line := strings.TrimSpace(lines[i]); line != "" {
+				section = append(section, line)
+			}
+		}
+		if len(section) > 0 {
+			question = strings.Join(section, " ")
+		}
+	}
+	if question == "" {
+		return prompt
+	}
+	return question
+}
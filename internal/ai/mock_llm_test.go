@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestMockLLM_GenerateContent(t *testing.T) {
+	mock, err := NewMockLLM(nil)
+	require.NoError(t, err)
+
+	// 表结构中出现的表名不应触发规则，只匹配用户查询段落
+	prompt := "## 数据库结构信息：\nproducts(id, name)\n\n## 用户查询：\n统计用户总数\n\n## 生成SQL："
+	resp, err := mock.GenerateContent(context.Background(), []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "SELECT COUNT(*) AS total_users FROM users", resp.Choices[0].Content)
+
+	out, err := mock.Call(context.Background(), "列出所有产品")
+	require.NoError(t, err)
+	assert.Contains(t, out, "FROM products")
+	assert.Equal(t, int64(2), mock.Calls())
+}
+
+func TestMockLLM_RulesFromYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - pattern: "pending"
+    sql: "SELECT * FROM orders WHERE order_status = 'pending'"
+`), 0o600))
+
+	mock, err := NewMockLLMFromFile(path)
+	require.NoError(t, err)
+
+	sql, err := mock.Match("Show PENDING orders")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM orders WHERE order_status = 'pending'", sql)
+
+	// 未配置default_sql时未命中返回错误
+	_, err = mock.Match("天气如何")
+	assert.Error(t, err)
+
+	_, err = NewMockLLM(&MockLLMRules{Rules: []MockLLMRule{{Pattern: "(", SQL: "SELECT 1"}}})
+	assert.Error(t, err)
+}
+
+func TestCreateLLMProvider_Mock(t *testing.T) {
+	t.Setenv("MOCK_LLM_RULES_FILE", "")
+	config, err := loadProviderConfig(ProviderMock)
+	require.NoError(t, err)
+	assert.Equal(t, MockLLMModelName, config.Model)
+
+	model, err := createLLMProvider(ProviderMock, config, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &MockLLM{}, model)
+}
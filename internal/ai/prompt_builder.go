@@ -0,0 +1,295 @@
+// 基于Schema元数据构建提示词中的表结构
+// 请求未携带表结构时，从SchemaRepository读取连接已保存的表、列与外键，按问题关键词为表打分，
+// 只把相关的表与其外键引用的表写入提示词，表数与估算token数超限时丢弃排名靠后的表；
+// 设置了表使用统计时，与问题相关的表中当前用户或工作空间成功查询过的表排名靠前
+
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// PromptSchema 写入提示词的表结构
+type PromptSchema struct {
+	Text    string   // 每张表一行的表结构描述
+	Tables  []string // 入选的表，按相关度排序
+	Omitted int      // 未写入提示词的表数
+
+	Corrections []SchemaCorrection // 问题中拼错的表名、列名及其对应的对象
+	Ranking     []TableRanking     // 与问题相关或入选的表的排名依据，按排名顺序
+}
+
+// PromptBuilder 按问题挑选相关表并生成提示词中的表结构
+type PromptBuilder struct {
+	schemas repository.SchemaRepository
+	usage   TableUsageSource
+	config  *config.PromptSchemaConfig
+	logger  *zap.Logger
+}
+
+// NewPromptBuilder 创建提示词表结构构建器，配置为nil时使用默认配置
+func NewPromptBuilder(schemas repository.SchemaRepository, promptConfig *config.PromptSchemaConfig, logger *zap.Logger) *PromptBuilder {
+	if promptConfig == nil {
+		promptConfig = config.DefaultPromptSchemaConfig()
+	}
+	return &PromptBuilder{
+		schemas: schemas,
+		config:  promptConfig,
+		logger:  logger,
+	}
+}
+
+// SetTableUsage 设置表使用统计，为nil时只按问题关键词排序
+func (b *PromptBuilder) SetTableUsage(usage TableUsageSource) {
+	b.usage = usage
+}
+
+// promptTable 按表聚合的元数据
+type promptTable struct {
+	name    string // public模式下省略模式名
+	table   string
+	comment string
+	columns []*repository.SchemaMetadata
+	score   int
+	rank    TableRanking
+}
+
+// Build 生成连接中与问题相关的表结构，连接没有保存元数据时返回空的PromptSchema
+// 问题没有命中任何表时按表名顺序取前MaxTables张，保证模型至少能看到部分表结构；userID用于按历史使用排序，为0时不区分用户
+func (b *PromptBuilder) Build(ctx context.Context, connectionID, userID int64, query string) (*PromptSchema, error) {
+	metadata, err := b.schemas.ListByConnection(ctx, connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("查询Schema元数据失败: %w", err)
+	}
+
+	tables := groupPromptTables(metadata)
+	if len(tables) == 0 {
+		return &PromptSchema{}, nil
+	}
+
+	result := &PromptSchema{Corrections: CorrectSchemaTerms(query, metadata)}
+	terms := newQueryTerms(query)
+	for _, c := range result.Corrections {
+		terms.words[strings.ToLower(c.Name)] = true
+	}
+	for _, t := range tables {
+		t.score = terms.tableScore(t)
+		t.rank = TableRanking{Table: t.name, QuestionScore: t.score}
+	}
+	b.applyTableUsage(ctx, connectionID, userID, tables)
+	sort.SliceStable(tables, func(i, j int) bool {
+		if tables[i].score != tables[j].score {
+			return tables[i].score > tables[j].score
+		}
+		return tables[i].name < tables[j].name
+	})
+
+	var text strings.Builder
+	tokens := 0
+	for _, t := range b.selectTables(tables) {
+		line := formatPromptTable(t)
+		// 估算方式与调用指标一致：4字符约1个token；排名第一的表即使超出上限也保留
+		if cost := len(line) / 4; len(result.Tables) == 0 || tokens+cost <= b.config.MaxTokens {
+			text.WriteString(line)
+			tokens += cost
+			result.Tables = append(result.Tables, t.name)
+		}
+	}
+	result.Text = text.String()
+	result.Omitted = len(tables) - len(result.Tables)
+	result.Ranking = tableRankings(tables, result.Tables)
+
+	b.logger.Debug("Built prompt schema",
+		zap.Int64("connection_id", connectionID),
+		zap.Strings("tables", result.Tables),
+		zap.Int("omitted", result.Omitted),
+		zap.Int("corrections", len(result.Corrections)),
+		zap.Any("ranking", result.Ranking),
+		zap.Int("estimated_tokens", tokens))
+	return result, nil
+}
+
+// selectTables 取命中问题的表，再按外键补充被引用的表供JOIN使用，总数不超过MaxTables
+func (b *PromptBuilder) selectTables(ranked []*promptTable) []*promptTable {
+	byTable := make(map[string]*promptTable, len(ranked))
+	for _, t := range ranked {
+		byTable[t.table] = t
+		byTable[t.name] = t
+	}
+
+	var selected []*promptTable
+	chosen := make(map[*promptTable]bool)
+	add := func(t *promptTable) {
+		if t != nil && !chosen[t] && len(selected) < b.config.MaxTables {
+			chosen[t] = true
+			selected = append(selected, t)
+		}
+	}
+
+	for _, t := range ranked {
+		if t.score > 0 {
+			add(t)
+		}
+	}
+	hits := len(selected)
+	for _, t := range selected[:hits] {
+		for _, column := range t.columns {
+			if column.IsForeignKey && column.ForeignTable != nil {
+				add(byTable[*column.ForeignTable])
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		for _, t := range ranked {
+			add(t)
+		}
+	}
+	return selected
+}
+
+// groupPromptTables 按模式与表名聚合元数据，列按位置排序
+func groupPromptTables(metadata []*repository.SchemaMetadata) []*promptTable {
+	index := make(map[string]*promptTable)
+	var tables []*promptTable
+	for _, m := range metadata {
+		key := m.SchemaName + "." + m.TableName
+		t, ok := index[key]
+		if !ok {
+			t = &promptTable{name: m.TableName, table: m.TableName}
+			if m.SchemaName != "" && m.SchemaName != "public" {
+				t.name = key
+			}
+			index[key] = t
+			tables = append(tables, t)
+		}
+		if t.comment == "" && m.TableComment != nil {
+			t.comment = *m.TableComment
+		}
+		t.columns = append(t.columns, m)
+	}
+
+	for _, t := range tables {
+		sort.SliceStable(t.columns, func(i, j int) bool {
+			return t.columns[i].OrdinalPosition < t.columns[j].OrdinalPosition
+		})
+	}
+	return tables
+}
+
+// formatPromptTable 将表格式化为一行，如 orders(id bigint PK, user_id bigint FK->users.id, amount numeric NOT NULL "订单金额") -- 订单表
+func formatPromptTable(t *promptTable) string {
+	columns := make([]string, 0, len(t.columns))
+	for _, c := range t.columns {
+		var column strings.Builder
+		column.WriteString(c.ColumnName + " " + c.DataType)
+		if c.IsPrimaryKey {
+			column.WriteString(" PK")
+		}
+		if c.IsForeignKey && c.ForeignTable != nil {
+			column.WriteString(" FK->" + *c.ForeignTable)
+			if c.ForeignColumn != nil {
+				column.WriteString("." + *c.ForeignColumn)
+			}
+		}
+		if !c.IsNullable && !c.IsPrimaryKey {
+			column.WriteString(" NOT NULL")
+		}
+		if c.ColumnComment != nil && *c.ColumnComment != "" {
+			column.WriteString(fmt.Sprintf(" %q", *c.ColumnComment))
+		}
+		columns = append(columns, column.String())
+	}
+
+	line := fmt.Sprintf("%s(%s)", t.name, strings.Join(columns, ", "))
+	if t.comment != "" {
+		line += " -- " + t.comment
+	}
+	return line + "\n"
+}
+
+// queryTerms 问题中用于匹配表结构的关键词：英文按单词匹配，中文按相邻两字匹配注释
+type queryTerms struct {
+	words map[string]bool // 小写英文单词及其单数形式
+	text  string          // 小写的原问题
+}
+
+func newQueryTerms(query string) *queryTerms {
+	terms := &queryTerms{words: make(map[string]bool), text: strings.ToLower(query)}
+	fields := strings.FieldsFunc(terms.text, func(r rune) bool {
+		return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+	})
+	for _, field := range fields {
+		if len(field) < 2 {
+			continue
+		}
+		terms.words[field] = true
+		terms.words[singularWord(field)] = true
+	}
+	return terms
+}
+
+// tableScore 表与问题的相关度：表名命中权重最高，其次是表注释、列名与列注释
+func (q *queryTerms) tableScore(t *promptTable) int {
+	score := 0
+	table := strings.ToLower(t.table)
+	if q.words[table] || q.words[singularWord(table)] {
+		score += 10
+	} else {
+		for _, part := range strings.Split(table, "_") {
+			if len(part) >= 3 && (q.words[part] || q.words[singularWord(part)]) {
+				score += 3
+			}
+		}
+	}
+	score += 3 * q.hanMatches(t.comment)
+
+	for _, c := range t.columns {
+		name := strings.ToLower(c.ColumnName)
+		if name != "id" && q.words[name] {
+			score += 2
+		}
+		if c.ColumnComment != nil {
+			score += q.hanMatches(*c.ColumnComment)
+		}
+	}
+	return score
+}
+
+// hanMatches 统计注释中出现在问题里的相邻两个汉字的组数，如注释“订单金额”与问题“订单总金额”匹配“订单”“金额”两组
+func (q *queryTerms) hanMatches(comment string) int {
+	runes := []rune(comment)
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(runes); i++ {
+		if !unicode.Is(unicode.Han, runes[i]) || !unicode.Is(unicode.Han, runes[i+1]) {
+			continue
+		}
+		pair := string(runes[i : i+2])
+		if !seen[pair] && strings.Contains(q.text, pair) {
+			seen[pair] = true
+		}
+	}
+	return len(seen)
+}
+
+// singularWord 英文单词的简单单数形式，用于匹配orders/order、categories/category
+func singularWord(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "ses") || strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 3:
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// promptSchemaRepository 只实现ListByConnection的Schema Repository
+type promptSchemaRepository struct {
+	repository.SchemaRepository
+	metadata []*repository.SchemaMetadata
+}
+
+func (r *promptSchemaRepository) ListByConnection(ctx context.Context, connectionID int64) ([]*repository.SchemaMetadata, error) {
+	return r.metadata, nil
+}
+
+// promptColumn 构造列元数据，fk形如users.id
+func promptColumn(table, tableComment, column, dataType, comment, fk string, position int32) *repository.SchemaMetadata {
+	m := &repository.SchemaMetadata{
+		SchemaName:      "public",
+		TableName:       table,
+		ColumnName:      column,
+		DataType:        dataType,
+		IsNullable:      true,
+		IsPrimaryKey:    column == "id",
+		OrdinalPosition: position,
+	}
+	if tableComment != "" {
+		m.TableComment = &tableComment
+	}
+	if comment != "" {
+		m.ColumnComment = &comment
+	}
+	if fk != "" {
+		parts := strings.SplitN(fk, ".", 2)
+		m.IsForeignKey = true
+		m.ForeignTable, m.ForeignColumn = &parts[0], &parts[1]
+	}
+	return m
+}
+
+func ecommerceMetadata() []*repository.SchemaMetadata {
+	return []*repository.SchemaMetadata{
+		promptColumn("users", "用户表", "id", "bigint", "", "", 1),
+		promptColumn("users", "用户表", "email", "varchar", "邮箱", "", 3),
+		promptColumn("users", "用户表", "name", "varchar", "用户名", "", 2),
+		promptColumn("orders", "订单表", "id", "bigint", "", "", 1),
+		promptColumn("orders", "订单表", "user_id", "bigint", "", "users.id", 2),
+		promptColumn("orders", "订单表", "total_amount", "numeric", "订单金额", "", 3),
+		promptColumn("categories", "商品分类", "id", "bigint", "", "", 1),
+		promptColumn("categories", "商品分类", "title", "varchar", "分类名称", "", 2),
+		promptColumn("audit_logs", "审计日志", "id", "bigint", "", "", 1),
+		promptColumn("audit_logs", "审计日志", "action", "text", "操作", "", 2),
+	}
+}
+
+func TestPromptBuilder_SelectsRelevantTables(t *testing.T) {
+	builder := NewPromptBuilder(&promptSchemaRepository{metadata: ecommerceMetadata()}, nil, zaptest.NewLogger(t))
+
+	schema, err := builder.Build(context.Background(), 1, 0, "统计每个订单的金额")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders", "users"}, schema.Tables, "外键引用的表随订单表一起写入")
+	assert.Equal(t, 2, schema.Omitted)
+	assert.Contains(t, schema.Text, `orders(id bigint PK, user_id bigint FK->users.id, total_amount numeric "订单金额") -- 订单表`)
+	assert.Contains(t, schema.Text, `users(id bigint PK, name varchar "用户名", email varchar "邮箱") -- 用户表`, "列按位置排序")
+
+	schema, err = builder.Build(context.Background(), 1, 0, "How many categories are there?")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"categories"}, schema.Tables)
+}
+
+func TestPromptBuilder_NoMatchFallsBackToFirstTables(t *testing.T) {
+	builder := NewPromptBuilder(&promptSchemaRepository{metadata: ecommerceMetadata()}, &config.PromptSchemaConfig{MaxTables: 2, MaxTokens: 2000}, zaptest.NewLogger(t))
+
+	schema, err := builder.Build(context.Background(), 1, 0, "今天天气怎么样")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"audit_logs", "categories"}, schema.Tables)
+
+	schema, err = NewPromptBuilder(&promptSchemaRepository{}, nil, zaptest.NewLogger(t)).Build(context.Background(), 1, 0, "统计订单")
+	require.NoError(t, err)
+	assert.Empty(t, schema.Text, "连接没有保存元数据")
+}
+
+func TestPromptBuilder_RespectsTokenBudget(t *testing.T) {
+	var metadata []*repository.SchemaMetadata
+	for i := 0; i < 20; i++ {
+		table := fmt.Sprintf("order_archive_%02d", i)
+		for j := 0; j < 10; j++ {
+			metadata = append(metadata, promptColumn(table, "历史订单", fmt.Sprintf("column_%02d", j), "varchar", "", "", int32(j)))
+		}
+	}
+	builder := NewPromptBuilder(&promptSchemaRepository{metadata: metadata}, &config.PromptSchemaConfig{MaxTables: 20, MaxTokens: 200}, zaptest.NewLogger(t))
+
+	schema, err := builder.Build(context.Background(), 1, 0, "查询历史订单")
+	require.NoError(t, err)
+	require.NotEmpty(t, schema.Tables)
+	assert.Less(t, len(schema.Tables), 20)
+	assert.Equal(t, 20-len(schema.Tables), schema.Omitted)
+	assert.LessOrEqual(t, len(schema.Text)/4, 200)
+}
+
+// stubTableUsage 固定的表使用统计
+type stubTableUsage struct {
+	usage *TableUsage
+	err   error
+}
+
+func (s *stubTableUsage) TableUsage(ctx context.Context, connectionID, userID int64) (*TableUsage, error) {
+	return s.usage, s.err
+}
+
+func TestPromptBuilder_HistoryRanking(t *testing.T) {
+	metadata := []*repository.SchemaMetadata{
+		promptColumn("orders", "", "id", "bigint", "", "", 1),
+		promptColumn("customers", "", "id", "bigint", "", "", 1),
+		promptColumn("audit_logs", "", "id", "bigint", "", "", 1),
+	}
+	for _, schema := range []string{"analytics", "staging"} {
+		m := promptColumn("orders", "", "id", "bigint", "", "", 1)
+		m.SchemaName = schema
+		metadata = append(metadata, m)
+	}
+	builder := NewPromptBuilder(&promptSchemaRepository{metadata: metadata}, &config.PromptSchemaConfig{MaxTables: 2, MaxTokens: 2000}, zaptest.NewLogger(t))
+
+	schema, err := builder.Build(context.Background(), 1, 7, "total orders")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"analytics.orders", "orders"}, schema.Tables, "没有使用统计时同分的表按名称排序")
+
+	builder.SetTableUsage(&stubTableUsage{usage: &TableUsage{
+		User:      map[string]int{"staging.orders": 3},
+		Workspace: map[string]int{"orders": 5, "audit_logs": 9},
+	}})
+	schema, err = builder.Build(context.Background(), 1, 7, "total orders")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"staging.orders", "orders"}, schema.Tables)
+	assert.Equal(t, []TableRanking{
+		{Table: "staging.orders", Score: 14, QuestionScore: 10, HistoryScore: 4, UserQueries: 3, Selected: true},
+		{Table: "orders", Score: 12, QuestionScore: 10, HistoryScore: 2, WorkspaceQueries: 5, Selected: true},
+		{Table: "analytics.orders", Score: 10, QuestionScore: 10},
+	}, schema.Ranking, "与问题无关的表不因历史使用加分")
+
+	// 表名唯一时不带模式名的历史引用也计入
+	builder.SetTableUsage(&stubTableUsage{usage: &TableUsage{User: map[string]int{"customers": 1}}})
+	schema, err = builder.Build(context.Background(), 1, 7, "customers")
+	require.NoError(t, err)
+	assert.Equal(t, 1, schema.Ranking[0].UserQueries)
+
+	// 统计失败时只按问题关键词排序
+	builder.SetTableUsage(&stubTableUsage{err: assert.AnError})
+	schema, err = builder.Build(context.Background(), 1, 7, "total orders")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"analytics.orders", "orders"}, schema.Tables)
+}
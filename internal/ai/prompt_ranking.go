@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// 按历史使用为与问题相关的表加分，低于表名直接命中的分数，只在相似的表之间决定先后
+const (
+	userHistoryBonus      = 4 // 当前用户成功查询过
+	workspaceHistoryBonus = 2 // 同一工作空间的其他成员成功查询过
+)
+
+// TableUsage 连接中各表被成功查询的次数，键为小写表名，非public模式的表带模式名（如sales.orders）
+type TableUsage struct {
+	User      map[string]int // 当前用户
+	Workspace map[string]int // 同一工作空间的其他成员
+}
+
+// TableUsageSource 按查询历史统计连接中各表的使用次数
+type TableUsageSource interface {
+	TableUsage(ctx context.Context, connectionID, userID int64) (*TableUsage, error)
+}
+
+// TableRanking 表在提示词中的排名依据，随调试日志输出
+type TableRanking struct {
+	Table            string `json:"table"`
+	Score            int    `json:"score"`             // 排序使用的总分
+	QuestionScore    int    `json:"question_score"`    // 与问题关键词的相关度
+	HistoryScore     int    `json:"history_score"`     // 按历史使用的加分，只加给与问题相关的表
+	UserQueries      int    `json:"user_queries"`      // 当前用户成功查询该表的次数
+	WorkspaceQueries int    `json:"workspace_queries"` // 工作空间其他成员成功查询该表的次数
+	Selected         bool   `json:"selected"`          // 是否写入提示词
+}
+
+// applyTableUsage 为与问题相关的表加上历史使用分，统计失败时只按问题关键词排序
+func (b *PromptBuilder) applyTableUsage(ctx context.Context, connectionID, userID int64, tables []*promptTable) {
+	if b.usage == nil {
+		return
+	}
+	usage, err := b.usage.TableUsage(ctx, connectionID, userID)
+	if err != nil {
+		b.logger.Warn("统计表使用次数失败，按问题关键词排序",
+			zap.Int64("connection_id", connectionID),
+			zap.Error(err))
+		return
+	}
+
+	// 多个模式中同名的表无法对应历史SQL中不带模式名的引用
+	names := make(map[string]int, len(tables))
+	for _, t := range tables {
+		names[strings.ToLower(t.table)]++
+	}
+	for _, t := range tables {
+		if t.score <= 0 {
+			continue
+		}
+		t.rank.UserQueries = usageCount(usage.User, t, names)
+		t.rank.WorkspaceQueries = usageCount(usage.Workspace, t, names)
+		if t.rank.UserQueries > 0 {
+			t.rank.HistoryScore += userHistoryBonus
+		}
+		if t.rank.WorkspaceQueries > 0 {
+			t.rank.HistoryScore += workspaceHistoryBonus
+		}
+		t.score += t.rank.HistoryScore
+	}
+}
+
+// usageCount 表的使用次数；非public模式的表在表名唯一时也计入不带模式名的引用
+func usageCount(counts map[string]int, t *promptTable, names map[string]int) int {
+	count := counts[strings.ToLower(t.name)]
+	if t.name != t.table && names[strings.ToLower(t.table)] == 1 {
+		count += counts[strings.ToLower(t.table)]
+	}
+	return count
+}
+
+// tableRankings 按排名顺序返回与问题相关或入选的表的排名依据
+func tableRankings(ranked []*promptTable, selected []string) []TableRanking {
+	chosen := make(map[string]bool, len(selected))
+	for _, name := range selected {
+		chosen[name] = true
+	}
+	var rankings []TableRanking
+	for _, t := range ranked {
+		if t.score <= 0 && !chosen[t.name] {
+			continue
+		}
+		rank := t.rank
+		rank.Score = t.score
+		rank.Selected = chosen[t.name]
+		rankings = append(rankings, rank)
+	}
+	return rankings
+}
@@ -0,0 +1,244 @@
+// 提示词快照回归
+// 将所有提示词模板在固定的夹具矩阵（表结构规模 × 语言 × 意图）上渲染并保存快照，
+// 模板改动导致提示词意外变化时给出可读的逐行差异
+
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PromptFixture 提示词渲染夹具
+type PromptFixture struct {
+	Name       string
+	SchemaSize string // none/small/large
+	Locale     string // zh/en
+	Context    *QueryContext
+}
+
+// PromptSnapshot 单个快照
+type PromptSnapshot struct {
+	Name    string
+	Content string
+}
+
+// PromptSnapshotMismatch 快照不一致
+type PromptSnapshotMismatch struct {
+	Name string
+	Diff string // 为空表示快照文件缺失
+}
+
+// promptFixtureQueries 各模板意图在不同语言下的代表性问题
+var promptFixtureQueries = map[string]map[string]string{
+	"base":        {"zh": "查询所有活跃用户的邮箱", "en": "List the email of every active user"},
+	"aggregation": {"zh": "统计每个分类的产品数量", "en": "Count products per category"},
+	"join":        {"zh": "查询每个订单的用户名和订单金额", "en": "Show each order with the customer name and amount"},
+	"timeseries":  {"zh": "按月统计最近一年的销售额", "en": "Monthly revenue over the last 12 months"},
+}
+
+// promptFixtureHistory 固定的查询历史，时间戳固定保证快照稳定
+var promptFixtureHistory = []QueryHistory{
+	{Query: "统计用户总数", SQL: "SELECT COUNT(*) FROM users", Success: true, Timestamp: time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)},
+	{Query: "删除测试订单", SQL: "", Success: false, Timestamp: time.Date(2024, 1, 8, 12, 5, 0, 0, time.UTC)},
+}
+
+// promptFixtureSchema 按规模生成表结构描述与表名
+func promptFixtureSchema(size string) (string, []string) {
+	switch size {
+	case "small":
+		return "users(id bigint PK, name varchar, email varchar, status varchar)\n" +
+				"orders(id bigint PK, user_id bigint FK->users.id, total_amount numeric, created_at timestamptz)",
+			[]string{"users", "orders"}
+	case "large":
+		var builder strings.Builder
+		tables := make([]string, 0, 30)
+		for i := 1; i <= 30; i++ {
+			table := fmt.Sprintf("table_%02d", i)
+			tables = append(tables, table)
+			fmt.Fprintf(&builder, "%s(id bigint PK, name varchar, amount numeric, created_at timestamptz", table)
+			if i > 1 {
+				fmt.Fprintf(&builder, ", table_%02d_id bigint FK->table_%02d.id", i-1, i-1)
+			}
+			builder.WriteString(")\n")
+		}
+		return builder.String(), tables
+	default:
+		return "", nil
+	}
+}
+
+// PromptFixtureMatrix 返回全部夹具组合，名称形如 join_large_en
+func PromptFixtureMatrix() []PromptFixture {
+	templates := make([]string, 0, len(promptFixtureQueries))
+	for name := range promptFixtureQueries {
+		templates = append(templates, name)
+	}
+	sort.Strings(templates)
+
+	var fixtures []PromptFixture
+	for _, template := range templates {
+		for _, size := range []string{"none", "small", "large"} {
+			for _, locale := range []string{"zh", "en"} {
+				schema, tables := promptFixtureSchema(size)
+				fixtures = append(fixtures, PromptFixture{
+					Name:       fmt.Sprintf("%s_%s_%s", template, size, locale),
+					SchemaSize: size,
+					Locale:     locale,
+					Context: &QueryContext{
+						UserQuery:      promptFixtureQueries[template][locale],
+						DatabaseSchema: schema,
+						TableNames:     tables,
+						QueryHistory:   promptFixtureHistory,
+					},
+				})
+			}
+		}
+	}
+	return fixtures
+}
+
+// RenderPromptSnapshots 渲染管理器中所有模板在夹具矩阵上的提示词
+// 模板名取夹具名称的前缀；未在矩阵中出现的自定义模板使用base问题渲染
+func RenderPromptSnapshots(manager *PromptTemplateManager) ([]PromptSnapshot, error) {
+	names := make([]string, 0, len(manager.templates))
+	for name := range manager.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var snapshots []PromptSnapshot
+	for _, name := range names {
+		template := manager.templates[name]
+		intent := name
+		if _, known := promptFixtureQueries[name]; !known {
+			intent = "base"
+		}
+		for _, fixture := range PromptFixtureMatrix() {
+			prefix, rest, _ := strings.Cut(fixture.Name, "_")
+			if prefix != intent {
+				continue
+			}
+
+			content, err := template.PromptWithHistory(fixture.Context)
+			if err != nil {
+				return nil, fmt.Errorf("渲染模板%s失败(%s): %w", name, fixture.Name, err)
+			}
+			snapshots = append(snapshots, PromptSnapshot{Name: name + "_" + rest, Content: content})
+		}
+	}
+	return snapshots, nil
+}
+
+// ComparePromptSnapshots 与dir下的快照文件比较；update为true时直接覆盖快照
+func ComparePromptSnapshots(dir string, snapshots []PromptSnapshot, update bool) ([]PromptSnapshotMismatch, error) {
+	if update {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建快照目录失败: %w", err)
+		}
+	}
+
+	var mismatches []PromptSnapshotMismatch
+	for _, snapshot := range snapshots {
+		path := filepath.Join(dir, snapshot.Name+".txt")
+		if update {
+			if err := os.WriteFile(path, []byte(snapshot.Content), 0644); err != nil {
+				return nil, fmt.Errorf("写入快照失败: %w", err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			mismatches = append(mismatches, PromptSnapshotMismatch{Name: snapshot.Name})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取快照失败: %w", err)
+		}
+		if string(want) != snapshot.Content {
+			mismatches = append(mismatches, PromptSnapshotMismatch{
+				Name: snapshot.Name,
+				Diff: DiffLines(string(want), snapshot.Content),
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// DiffLines 生成逐行差异，"-"为快照内容，"+"为当前渲染结果，仅保留变更附近的上下文
+func DiffLines(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// 最长公共子序列
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type diffLine struct {
+		op   byte
+		text string
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+
+	const contextLines = 2
+	var builder strings.Builder
+	lastPrinted := -1
+	for idx, line := range lines {
+		if line.op == ' ' {
+			near := false
+			from, to := idx-contextLines, idx+contextLines
+			if from < 0 {
+				from = 0
+			}
+			if to > len(lines)-1 {
+				to = len(lines) - 1
+			}
+			for k := from; k <= to; k++ {
+				if lines[k].op != ' ' {
+					near = true
+					break
+				}
+			}
+			if !near {
+				continue
+			}
+		}
+		if lastPrinted >= 0 && idx > lastPrinted+1 {
+			builder.WriteString("  ...\n")
+		}
+		fmt.Fprintf(&builder, "%c %s\n", line.op, line.text)
+		lastPrinted = idx
+	}
+	return builder.String()
+}
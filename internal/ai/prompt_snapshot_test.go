@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// promptSnapshotDir 提示词快照目录，更新快照：UPDATE_PROMPT_SNAPSHOTS=1 go test ./internal/ai -run TestPromptSnapshots
+const promptSnapshotDir = "testdata/prompt_snapshots"
+
+func TestPromptSnapshots(t *testing.T) {
+	snapshots, err := RenderPromptSnapshots(NewPromptTemplateManager())
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 24)
+
+	mismatches, err := ComparePromptSnapshots(promptSnapshotDir, snapshots, os.Getenv("UPDATE_PROMPT_SNAPSHOTS") == "1")
+	require.NoError(t, err)
+	for _, m := range mismatches {
+		if m.Diff == "" {
+			t.Errorf("缺少快照 %s，请使用UPDATE_PROMPT_SNAPSHOTS=1生成", m.Name)
+			continue
+		}
+		t.Errorf("提示词快照 %s 发生变化（- 快照 / + 当前）:\n%s", m.Name, m.Diff)
+	}
+}
+
+func TestComparePromptSnapshots_ReportsDiff(t *testing.T) {
+	dir := t.TempDir()
+	snapshots := []PromptSnapshot{{Name: "base_small_zh", Content: "a\nb\nc\n"}}
+	_, err := ComparePromptSnapshots(dir, snapshots, true)
+	require.NoError(t, err)
+
+	mismatches, err := ComparePromptSnapshots(dir, []PromptSnapshot{{Name: "base_small_zh", Content: "a\nB\nc\n"}}, false)
+	require.NoError(t, err)
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, "  a\n- b\n+ B\n  c\n", strings.TrimSuffix(mismatches[0].Diff, "  \n"))
+
+	mismatches, err = ComparePromptSnapshots(filepath.Join(dir, "missing"), snapshots, false)
+	require.NoError(t, err)
+	require.Len(t, mismatches, 1)
+	assert.Empty(t, mismatches[0].Diff)
+}
@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+
+	"chat2sql-go/internal/repository"
+)
+
+// 纠正结果对应的对象类型
+const (
+	SchemaCorrectionTable  = "table"
+	SchemaCorrectionColumn = "column"
+)
+
+// SchemaCorrection 问题中拼写有误的表名或列名及其对应的表结构对象
+type SchemaCorrection struct {
+	Text  string `json:"text"`            // 问题中的原词（小写）
+	Name  string `json:"name"`            // 对应的表名或列名
+	Kind  string `json:"kind"`            // 对象类型，见SchemaCorrection*常量
+	Table string `json:"table,omitempty"` // 列所在的表，同名列出现在多张表时为空
+	Note  string `json:"note,omitempty"`  // 返回给用户的说明，由服务层按用户语言填写
+}
+
+// schemaTypoStopwords 问题中常见的英文单词，与列名只差一两个字母时也不当作拼写错误，如count与county
+var schemaTypoStopwords = map[string]bool{
+	"count": true, "total": true, "average": true, "number": true, "amount": true,
+	"group": true, "order": true, "where": true, "which": true, "their": true,
+	"there": true, "these": true, "those": true, "about": true, "after": true,
+	"before": true, "between": true, "during": true, "since": true, "until": true,
+	"month": true, "months": true, "years": true, "weeks": true, "today": true,
+	"daily": true, "weekly": true, "monthly": true, "yearly": true, "latest": true,
+	"first": true, "last": true, "top": true, "most": true, "least": true,
+	"highest": true, "lowest": true, "largest": true, "smallest": true, "each": true,
+	"every": true, "show": true, "list": true, "find": true, "give": true,
+	"many": true, "much": true, "more": true, "less": true, "than": true,
+	"with": true, "without": true, "from": true, "into": true, "have": true,
+	"per": true, "all": true, "sort": true, "sorted": true, "rank": true,
+}
+
+// schemaTypoCandidate 可作为纠正目标的表名或列名
+type schemaTypoCandidate struct {
+	SchemaCorrection
+	lower string
+}
+
+// CorrectSchemaTerms 按编辑距离把问题中拼错的英文表名、列名对应到元数据中的表与列，如costumers对应customers、ordr_items对应order_items
+// 与表结构中的名称及其组成部分完全相同的单词、常见英文单词与短于5个字母的单词不参与纠正；
+// 允许的距离随单词长度增加（5-7个字母为1，更长为2），距离最近的对象不唯一时不纠正
+func CorrectSchemaTerms(query string, metadata []*repository.SchemaMetadata) []SchemaCorrection {
+	candidates, known := schemaTypoCandidates(metadata)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var corrections []SchemaCorrection
+	seen := make(map[string]bool)
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+	})
+	for _, word := range fields {
+		if seen[word] || len(word) < 5 || known[word] || known[singularWord(word)] || schemaTypoStopwords[word] || !strings.ContainsFunc(word, unicode.IsLetter) {
+			continue
+		}
+		seen[word] = true
+		if c, ok := closestSchemaName(word, candidates); ok {
+			c.Text = word
+			corrections = append(corrections, c)
+		}
+	}
+	return corrections
+}
+
+// schemaTypoCandidates 汇总元数据中的表名与列名，先加入的表名优先于同名列；known为名称、名称的单数形式与下划线分隔的各部分
+func schemaTypoCandidates(metadata []*repository.SchemaMetadata) ([]*schemaTypoCandidate, map[string]bool) {
+	byName := make(map[string]*schemaTypoCandidate)
+	var candidates []*schemaTypoCandidate
+	known := make(map[string]bool)
+	add := func(name, kind, table string) {
+		lower := strings.ToLower(name)
+		known[lower] = true
+		known[singularWord(lower)] = true
+		for _, part := range strings.Split(lower, "_") {
+			if len(part) >= 3 {
+				known[part] = true
+				known[singularWord(part)] = true
+			}
+		}
+
+		existing, ok := byName[lower]
+		switch {
+		case !ok:
+			c := &schemaTypoCandidate{SchemaCorrection: SchemaCorrection{Name: name, Kind: kind, Table: table}, lower: lower}
+			byName[lower] = c
+			candidates = append(candidates, c)
+		case existing.Kind == SchemaCorrectionColumn && existing.Table != table:
+			existing.Table = ""
+		}
+	}
+	for _, m := range metadata {
+		add(m.TableName, SchemaCorrectionTable, "")
+	}
+	for _, m := range metadata {
+		add(m.ColumnName, SchemaCorrectionColumn, m.TableName)
+	}
+	return candidates, known
+}
+
+// closestSchemaName 返回与单词距离最近且在允许距离内的对象；同一距离有多个对象时，只有其中恰好一张表才采用该表
+func closestSchemaName(word string, candidates []*schemaTypoCandidate) (SchemaCorrection, bool) {
+	limit := 1
+	if len(word) >= 8 {
+		limit = 2
+	}
+	singular := singularWord(word)
+
+	best := limit + 1
+	var nearest []*schemaTypoCandidate
+	for _, c := range candidates {
+		if diff := len(c.lower) - len(word); diff > limit+1 || diff < -limit-1 {
+			continue
+		}
+		// 本包的min按float64比较，这里直接比较整数
+		distance := editDistance(word, c.lower)
+		if d := editDistance(singular, singularWord(c.lower)); d < distance {
+			distance = d
+		}
+		switch {
+		case distance < best:
+			best, nearest = distance, []*schemaTypoCandidate{c}
+		case distance == best:
+			nearest = append(nearest, c)
+		}
+	}
+	if best > limit || len(nearest) == 0 {
+		return SchemaCorrection{}, false
+	}
+	if len(nearest) == 1 {
+		return nearest[0].SchemaCorrection, true
+	}
+
+	var table *schemaTypoCandidate
+	for _, c := range nearest {
+		if c.Kind == SchemaCorrectionTable {
+			if table != nil {
+				return SchemaCorrection{}, false
+			}
+			table = c
+		}
+	}
+	if table == nil {
+		return SchemaCorrection{}, false
+	}
+	return table.SchemaCorrection, true
+}
+
+// editDistance 两个ASCII字符串的编辑距离，相邻字母互换计为一次编辑（如ordres与orders）
+func editDistance(a, b string) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < curr[j] {
+				curr[j] = d
+			}
+			if d := curr[j-1] + 1; d < curr[j] {
+				curr[j] = d
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && prev2[j-2]+1 < curr[j] {
+				curr[j] = prev2[j-2] + 1
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+func typoMetadata() []*repository.SchemaMetadata {
+	return []*repository.SchemaMetadata{
+		promptColumn("customers", "", "id", "bigint", "", "", 1),
+		promptColumn("customers", "", "country", "varchar", "", "", 2),
+		promptColumn("customers", "", "created_at", "timestamp", "", "", 3),
+		promptColumn("order_items", "", "id", "bigint", "", "", 1),
+		promptColumn("order_items", "", "quantity", "integer", "", "", 2),
+		promptColumn("orders", "", "id", "bigint", "", "", 1),
+		promptColumn("orders", "", "created_at", "timestamp", "", "", 2),
+		promptColumn("products", "", "id", "bigint", "", "", 1),
+		promptColumn("products", "", "price", "numeric", "", "", 2),
+		promptColumn("products", "", "prices", "numeric", "", "", 3),
+	}
+}
+
+func TestCorrectSchemaTerms(t *testing.T) {
+	metadata := typoMetadata()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []SchemaCorrection
+	}{
+		{
+			name:  "表名拼写错误",
+			query: "How many costumers signed up last month?",
+			want:  []SchemaCorrection{{Text: "costumers", Name: "customers", Kind: SchemaCorrectionTable}},
+		},
+		{
+			name:  "缺字母的下划线表名与中文问题",
+			query: "统计ordr_items中每个商品的quantty",
+			want: []SchemaCorrection{
+				{Text: "ordr_items", Name: "order_items", Kind: SchemaCorrectionTable},
+				{Text: "quantty", Name: "quantity", Kind: SchemaCorrectionColumn, Table: "order_items"},
+			},
+		},
+		{
+			name:  "相邻字母互换与单数形式",
+			query: "latest ordres per custmer",
+			want: []SchemaCorrection{
+				{Text: "ordres", Name: "orders", Kind: SchemaCorrectionTable},
+				{Text: "custmer", Name: "customers", Kind: SchemaCorrectionTable},
+			},
+		},
+		{
+			name:  "同名列出现在多张表",
+			query: "orders by creatd_at",
+			want:  []SchemaCorrection{{Text: "creatd_at", Name: "created_at", Kind: SchemaCorrectionColumn}},
+		},
+		{
+			name:  "名称与名称组成部分不纠正",
+			query: "list customers and their order items with created dates",
+		},
+		{
+			name:  "常见英文单词不纠正",
+			query: "count customers by country",
+		},
+		{
+			name:  "距离超出上限不纠正",
+			query: "list all consumers",
+		},
+		{
+			name:  "距离最近的对象不唯一时不纠正",
+			query: "products with pricez",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CorrectSchemaTerms(tt.query, metadata))
+		})
+	}
+
+	assert.Nil(t, CorrectSchemaTerms("costumers", nil))
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("orders", "orders"))
+	assert.Equal(t, 1, editDistance("ordr_items", "order_items"))
+	assert.Equal(t, 1, editDistance("ordres", "orders"), "相邻字母互换计为一次编辑")
+	assert.Equal(t, 2, editDistance("costumers", "customers"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+}
+
+func TestPromptBuilder_CorrectsMisspelledTables(t *testing.T) {
+	builder := NewPromptBuilder(&promptSchemaRepository{metadata: typoMetadata()}, nil, zaptest.NewLogger(t))
+
+	schema, err := builder.Build(context.Background(), 1, 0, "How many costumers are there?")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customers"}, schema.Tables, "纠正后的表名参与相关度打分")
+	assert.Equal(t, []SchemaCorrection{{Text: "costumers", Name: "customers", Kind: SchemaCorrectionTable}}, schema.Corrections)
+}
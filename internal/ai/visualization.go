@@ -0,0 +1,345 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 结果列的数据类型，取值与Vega-Lite的编码类型一致
+const (
+	ColumnTemporal     = "temporal"
+	ColumnQuantitative = "quantitative"
+	ColumnNominal      = "nominal"
+)
+
+// 推荐的图表类型
+const (
+	ChartTable   = "table"
+	ChartNumber  = "number" // 单个数值，以大号数字展示
+	ChartLine    = "line"
+	ChartBar     = "bar"
+	ChartPie     = "pie"
+	ChartScatter = "scatter"
+)
+
+const (
+	// visualizationProfileRows 分析列类型与基数时最多读取的行数
+	visualizationProfileRows = 1000
+	// maxPieSlices 饼图的最多分类数，更多分类时改用柱状图
+	maxPieSlices = 8
+	// maxColorSeries 按分类列拆分折线时的最多系列数
+	maxColorSeries = 10
+	// horizontalBarLabelLength 分类标签平均长度超过该值时使用横向柱状图
+	horizontalBarLabelLength = 12
+)
+
+// vegaLiteSchema 生成的图表规格使用的Vega-Lite版本
+const vegaLiteSchema = "https://vega.github.io/schema/vega-lite/v5.json"
+
+// VisualizationDataName 图表规格引用的具名数据源，前端以查询结果的行绑定该数据源
+const VisualizationDataName = "result"
+
+var (
+	// trendHints 问题中表示随时间变化的措辞
+	trendHints = regexp.MustCompile(`(?i)趋势|走势|变化|每天|每日|每周|每月|每年|按天|按月|按年|trend|over time|daily|weekly|monthly|yearly|per day|per month`)
+	// shareHints 问题中表示占比的措辞
+	shareHints = regexp.MustCompile(`(?i)占比|比例|份额|构成|百分比|share|proportion|percentage|breakdown|distribution`)
+	// idColumnPattern 标识列即使是数值也按分类处理
+	idColumnPattern = regexp.MustCompile(`(?i)(^id$|_id$|^code$|_code$|_no$|编号|代码)`)
+	// yearColumnPattern 取值为年份的整数列按时间处理
+	yearColumnPattern = regexp.MustCompile(`(?i)(^year$|_year$|^yr$|年份|年度)`)
+)
+
+// temporalLayouts 字符串时间值可识别的格式
+var temporalLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02", "2006-01", "2006/01/02"}
+
+// ColumnProfile 结果列的类型与基数
+type ColumnProfile struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`     // 见Column*常量
+	Distinct int    `json:"distinct"` // 分析的行中不同取值的个数
+	Nulls    int    `json:"nulls"`
+	Unit     string `json:"unit,omitempty"` // 取值为年份的整数列为year
+}
+
+// ChartSuggestion 查询结果的图表推荐
+type ChartSuggestion struct {
+	Chart        string          `json:"chart"`                  // 推荐的图表类型，见Chart*常量
+	Reason       string          `json:"reason"`                 // 推荐依据
+	Spec         map[string]any  `json:"spec,omitempty"`         // Vega-Lite规格，数据源为具名的result；推荐表格时为空
+	TimeSeries   bool            `json:"time_series"`            // 结果是否为时间序列
+	Alternatives []string        `json:"alternatives,omitempty"` // 同样适用的其他图表类型
+	Columns      []ColumnProfile `json:"columns"`
+	SampledRows  int             `json:"sampled_rows"` // 参与分析的行数
+}
+
+// SuggestChart 按结果列的类型与基数推荐图表并生成Vega-Lite规格，问题措辞用于在折线图与柱状图、饼图之间取舍
+// 只分析前visualizationProfileRows行，不调用模型
+func SuggestChart(columns []string, rows []map[string]any, question string) *ChartSuggestion {
+	sample := rows
+	if len(sample) > visualizationProfileRows {
+		sample = sample[:visualizationProfileRows]
+	}
+	profiles := ProfileColumns(columns, sample)
+	suggestion := &ChartSuggestion{Chart: ChartTable, Columns: profiles, SampledRows: len(sample)}
+
+	var temporal, quantitative, nominal []ColumnProfile
+	for _, p := range profiles {
+		switch {
+		case p.Distinct == 0:
+			// 全为空的列不参与推荐
+		case p.Type == ColumnTemporal:
+			temporal = append(temporal, p)
+		case p.Type == ColumnQuantitative:
+			quantitative = append(quantitative, p)
+		default:
+			nominal = append(nominal, p)
+		}
+	}
+
+	switch {
+	case len(sample) == 0:
+		suggestion.Reason = "结果为空"
+	case len(quantitative) == 0:
+		suggestion.Reason = "结果中没有数值列"
+	case len(sample) == 1 && len(quantitative) == 1 && len(profiles) == 1:
+		suggestion.Chart = ChartNumber
+		suggestion.Reason = "结果为单个数值"
+		suggestion.Spec = numberSpec(quantitative[0])
+	case len(temporal) > 0 && temporal[0].Distinct > 1:
+		suggestion.TimeSeries = true
+		suggestion.Chart = ChartLine
+		suggestion.Reason = "结果按时间列" + temporal[0].Name + "排列，适合展示变化趋势"
+		suggestion.Alternatives = []string{ChartBar, ChartTable}
+		var series *ColumnProfile
+		if len(quantitative) == 1 && len(nominal) > 0 && nominal[0].Distinct <= maxColorSeries {
+			series = &nominal[0]
+			suggestion.Reason += "，按" + series.Name + "分为多条折线"
+		}
+		suggestion.Spec = lineSpec(temporal[0], quantitative, series)
+	case len(nominal) > 0:
+		category := nominal[0]
+		measure := quantitative[0]
+		if category.Distinct <= maxPieSlices && len(quantitative) == 1 && shareHints.MatchString(question) {
+			suggestion.Chart = ChartPie
+			suggestion.Reason = "问题关注" + category.Name + "的占比，分类不超过" + strconv.Itoa(maxPieSlices) + "个"
+			suggestion.Alternatives = []string{ChartBar, ChartTable}
+			suggestion.Spec = pieSpec(category, measure)
+			break
+		}
+		suggestion.Chart = ChartBar
+		suggestion.Reason = "按分类列" + category.Name + "比较" + measure.Name
+		if category.Distinct <= maxPieSlices && len(quantitative) == 1 {
+			suggestion.Alternatives = []string{ChartPie, ChartTable}
+		} else {
+			suggestion.Alternatives = []string{ChartTable}
+		}
+		suggestion.Spec = barSpec(category, measure, averageLabelLength(sample, category.Name) > horizontalBarLabelLength)
+	case len(quantitative) >= 2:
+		suggestion.Chart = ChartScatter
+		suggestion.Reason = "结果为多个数值列，适合观察" + quantitative[0].Name + "与" + quantitative[1].Name + "的关系"
+		suggestion.Alternatives = []string{ChartTable}
+		suggestion.Spec = scatterSpec(quantitative[0], quantitative[1])
+	default:
+		suggestion.Reason = "结果只有一个数值列，没有可用于分组的列"
+	}
+
+	// 问题明确询问趋势但结果没有时间列时保留说明，便于前端提示
+	if !suggestion.TimeSeries && trendHints.MatchString(question) && len(sample) > 0 {
+		suggestion.Reason += "；问题询问趋势，但结果中没有可识别的时间列"
+	}
+	return suggestion
+}
+
+// ProfileColumns 识别结果列的类型并统计基数：数值（标识列除外）为quantitative，
+// 时间值、日期字符串与年份列为temporal，其余为nominal；类型混杂的列按nominal处理
+func ProfileColumns(columns []string, rows []map[string]any) []ColumnProfile {
+	profiles := make([]ColumnProfile, 0, len(columns))
+	for _, name := range columns {
+		profile := ColumnProfile{Name: name}
+		distinct := make(map[string]bool)
+		numeric, temporal, integral := true, true, true
+		for _, row := range rows {
+			value, ok := row[name]
+			if !ok || value == nil {
+				profile.Nulls++
+				continue
+			}
+			key, isNumber, isTemporal, isIntegral := classifyValue(value)
+			distinct[key] = true
+			numeric = numeric && isNumber
+			temporal = temporal && isTemporal
+			integral = integral && isIntegral
+		}
+		profile.Distinct = len(distinct)
+
+		switch {
+		case profile.Distinct == 0:
+			profile.Type = ColumnNominal
+		case temporal:
+			profile.Type = ColumnTemporal
+		case numeric && integral && yearColumnPattern.MatchString(name) && yearValues(distinct):
+			profile.Type = ColumnTemporal
+			profile.Unit = "year"
+		case numeric && !idColumnPattern.MatchString(name):
+			profile.Type = ColumnQuantitative
+		default:
+			profile.Type = ColumnNominal
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+// classifyValue 返回值的比较键，以及是否为数值、时间值与整数
+func classifyValue(value any) (key string, number, temporal, integral bool) {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), true, false, true
+	case float32:
+		f := float64(v)
+		return strconv.FormatFloat(f, 'g', -1, 64), true, false, f == float64(int64(f))
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true, false, v == float64(int64(v))
+	case json.Number:
+		f, err := v.Float64()
+		return v.String(), err == nil, false, err == nil && f == float64(int64(f))
+	case time.Time:
+		return v.Format(time.RFC3339Nano), false, true, false
+	case string:
+		s := strings.TrimSpace(v)
+		if isTemporalString(s) {
+			return s, false, true, false
+		}
+		// NUMERIC等精确数值可能以字符串返回
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return s, true, false, f == float64(int64(f))
+		}
+		return s, false, false, false
+	case bool:
+		return strconv.FormatBool(v), false, false, false
+	default:
+		data, _ := json.Marshal(v)
+		return string(data), false, false, false
+	}
+}
+
+// isTemporalString 判断字符串是否为可识别格式的日期或时间
+func isTemporalString(s string) bool {
+	if len(s) < len("2006-01") || s[0] < '0' || s[0] > '9' {
+		return false
+	}
+	for _, layout := range temporalLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// yearValues 判断整数列的取值是否都在常见年份范围内
+func yearValues(distinct map[string]bool) bool {
+	for key := range distinct {
+		n, err := strconv.Atoi(key)
+		if err != nil || n < 1900 || n > 2100 {
+			return false
+		}
+	}
+	return true
+}
+
+// averageLabelLength 分类列取值的平均字符数
+func averageLabelLength(rows []map[string]any, column string) int {
+	total, count := 0, 0
+	for _, row := range rows {
+		if s, ok := row[column].(string); ok {
+			total += len([]rune(s))
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / count
+}
+
+// encodingField Vega-Lite编码中的字段定义；Vega-Lite把数值时间当作毫秒时间戳，年份整数列按有序分类编码
+func encodingField(p ColumnProfile) map[string]any {
+	encoding := map[string]any{"field": p.Name, "type": p.Type, "title": p.Name}
+	if p.Unit == "year" {
+		encoding["type"] = "ordinal"
+	}
+	return encoding
+}
+
+// baseSpec 所有图表共用的规格，数据源为具名的result
+func baseSpec(mark any, encoding map[string]any) map[string]any {
+	return map[string]any{
+		"$schema":  vegaLiteSchema,
+		"data":     map[string]any{"name": VisualizationDataName},
+		"mark":     mark,
+		"encoding": encoding,
+	}
+}
+
+func numberSpec(measure ColumnProfile) map[string]any {
+	return baseSpec(
+		map[string]any{"type": "text", "fontSize": 48, "fontWeight": "bold"},
+		map[string]any{"text": map[string]any{"field": measure.Name, "type": ColumnQuantitative, "format": ",.2~f"}},
+	)
+}
+
+// lineSpec 时间序列折线图；多个数值列时展开为多条折线，或按分类列拆分
+func lineSpec(x ColumnProfile, measures []ColumnProfile, series *ColumnProfile) map[string]any {
+	xField := encodingField(x)
+	xField["sort"] = "ascending"
+	encoding := map[string]any{"x": xField}
+	mark := map[string]any{"type": "line", "point": true, "tooltip": true}
+
+	if len(measures) == 1 {
+		encoding["y"] = encodingField(measures[0])
+		if series != nil {
+			encoding["color"] = encodingField(*series)
+		}
+		return baseSpec(mark, encoding)
+	}
+
+	names := make([]string, 0, len(measures))
+	for _, m := range measures {
+		names = append(names, m.Name)
+	}
+	encoding["y"] = map[string]any{"field": "value", "type": ColumnQuantitative, "title": strings.Join(names, ", ")}
+	encoding["color"] = map[string]any{"field": "series", "type": ColumnNominal, "title": nil}
+	spec := baseSpec(mark, encoding)
+	spec["transform"] = []any{map[string]any{"fold": names, "as": []string{"series", "value"}}}
+	return spec
+}
+
+// barSpec 分类柱状图，按数值降序；标签较长时横向排列
+func barSpec(category, measure ColumnProfile, horizontal bool) map[string]any {
+	categoryField := encodingField(category)
+	if horizontal {
+		categoryField["sort"] = "-x"
+		return baseSpec(map[string]any{"type": "bar", "tooltip": true}, map[string]any{"y": categoryField, "x": encodingField(measure)})
+	}
+	categoryField["sort"] = "-y"
+	return baseSpec(map[string]any{"type": "bar", "tooltip": true}, map[string]any{"x": categoryField, "y": encodingField(measure)})
+}
+
+func pieSpec(category, measure ColumnProfile) map[string]any {
+	return baseSpec(
+		map[string]any{"type": "arc", "tooltip": true},
+		map[string]any{"theta": encodingField(measure), "color": encodingField(category)},
+	)
+}
+
+func scatterSpec(x, y ColumnProfile) map[string]any {
+	return baseSpec(
+		map[string]any{"type": "point", "tooltip": true},
+		map[string]any{"x": encodingField(x), "y": encodingField(y)},
+	)
+}
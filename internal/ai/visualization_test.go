@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestChart(t *testing.T) {
+	tests := []struct {
+		name       string
+		columns    []string
+		rows       []map[string]any
+		question   string
+		chart      string
+		timeSeries bool
+	}{
+		{
+			name:    "空结果",
+			columns: []string{"n"},
+			chart:   ChartTable,
+		},
+		{
+			name:    "单个数值",
+			columns: []string{"total"},
+			rows:    []map[string]any{{"total": 1820.5}},
+			chart:   ChartNumber,
+		},
+		{
+			name:    "按日期的时间序列",
+			columns: []string{"day", "revenue"},
+			rows: []map[string]any{
+				{"day": "2024-03-01", "revenue": 120.0},
+				{"day": "2024-03-02", "revenue": 98.0},
+				{"day": "2024-03-03", "revenue": 143.0},
+			},
+			chart:      ChartLine,
+			timeSeries: true,
+		},
+		{
+			name:    "年份整数列",
+			columns: []string{"order_year", "orders"},
+			rows:    []map[string]any{{"order_year": 2022.0, "orders": 10.0}, {"order_year": 2023.0, "orders": 14.0}},
+			chart:   ChartLine, timeSeries: true,
+		},
+		{
+			name:    "分类比较",
+			columns: []string{"country", "customers"},
+			rows:    []map[string]any{{"country": "CN", "customers": 30.0}, {"country": "US", "customers": 12.0}},
+			chart:   ChartBar,
+		},
+		{
+			name:     "询问占比",
+			columns:  []string{"channel", "orders"},
+			rows:     []map[string]any{{"channel": "web", "orders": 30.0}, {"channel": "app", "orders": 50.0}},
+			question: "各渠道订单的占比",
+			chart:    ChartPie,
+		},
+		{
+			name:    "标识列不作为数值",
+			columns: []string{"customer_id", "amount"},
+			rows:    []map[string]any{{"customer_id": 1.0, "amount": 5.0}, {"customer_id": 2.0, "amount": 7.0}},
+			chart:   ChartBar,
+		},
+		{
+			name:    "两个数值列",
+			columns: []string{"price", "quantity"},
+			rows:    []map[string]any{{"price": 9.9, "quantity": 3.0}, {"price": 19.9, "quantity": 1.0}},
+			chart:   ChartScatter,
+		},
+		{
+			name:    "没有数值列",
+			columns: []string{"name", "email"},
+			rows:    []map[string]any{{"name": "a", "email": "a@example.com"}},
+			chart:   ChartTable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestion := SuggestChart(tt.columns, tt.rows, tt.question)
+			assert.Equal(t, tt.chart, suggestion.Chart, suggestion.Reason)
+			assert.Equal(t, tt.timeSeries, suggestion.TimeSeries)
+			assert.NotEmpty(t, suggestion.Reason)
+			if tt.chart == ChartTable {
+				assert.Nil(t, suggestion.Spec)
+			} else {
+				require.NotNil(t, suggestion.Spec)
+				assert.Equal(t, map[string]any{"name": VisualizationDataName}, suggestion.Spec["data"])
+			}
+		})
+	}
+}
+
+func TestSuggestChart_Specs(t *testing.T) {
+	// 多个数值列的时间序列展开为多条折线
+	suggestion := SuggestChart([]string{"month", "revenue", "cost"}, []map[string]any{
+		{"month": "2024-01", "revenue": 10.0, "cost": 6.0},
+		{"month": "2024-02", "revenue": 12.0, "cost": 7.0},
+	}, "每月收入与成本的趋势")
+	require.Equal(t, ChartLine, suggestion.Chart)
+	data, err := json.Marshal(suggestion.Spec)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://vega.github.io/schema/vega-lite/v5.json",
+		"data": {"name": "result"},
+		"mark": {"type": "line", "point": true, "tooltip": true},
+		"transform": [{"fold": ["revenue", "cost"], "as": ["series", "value"]}],
+		"encoding": {
+			"x": {"field": "month", "type": "temporal", "title": "month", "sort": "ascending"},
+			"y": {"field": "value", "type": "quantitative", "title": "revenue, cost"},
+			"color": {"field": "series", "type": "nominal", "title": null}
+		}
+	}`, string(data))
+
+	// 单个数值按分类列拆分折线；年份整数列按有序分类编码
+	suggestion = SuggestChart([]string{"year", "region", "sales"}, []map[string]any{
+		{"year": 2023, "region": "east", "sales": 5},
+		{"year": 2024, "region": "east", "sales": 8},
+		{"year": 2024, "region": "west", "sales": 3},
+	}, "")
+	require.Equal(t, ChartLine, suggestion.Chart)
+	encoding := suggestion.Spec["encoding"].(map[string]any)
+	assert.Equal(t, "ordinal", encoding["x"].(map[string]any)["type"])
+	assert.Equal(t, "region", encoding["color"].(map[string]any)["field"])
+	assert.Equal(t, "year", suggestion.Columns[0].Unit)
+
+	// 分类标签较长时使用横向柱状图，按数值降序
+	suggestion = SuggestChart([]string{"product", "units"}, []map[string]any{
+		{"product": "Ultra-light trail running shoes", "units": 40.0},
+		{"product": "Waterproof hiking backpack 40L", "units": 25.0},
+	}, "")
+	require.Equal(t, ChartBar, suggestion.Chart)
+	encoding = suggestion.Spec["encoding"].(map[string]any)
+	assert.Equal(t, "product", encoding["y"].(map[string]any)["field"])
+	assert.Equal(t, "-x", encoding["y"].(map[string]any)["sort"])
+
+	// 问题询问趋势但结果没有时间列
+	suggestion = SuggestChart([]string{"country", "orders"}, []map[string]any{{"country": "CN", "orders": 3.0}}, "订单的变化趋势")
+	assert.Contains(t, suggestion.Reason, "没有可识别的时间列")
+}
+
+func TestProfileColumns(t *testing.T) {
+	rows := []map[string]any{
+		{"created_at": "2024-03-01T08:00:00Z", "amount": "12.50", "status": "paid", "note": nil},
+		{"created_at": "2024-03-02T09:30:00Z", "amount": json.Number("7"), "status": "paid", "note": nil},
+		{"created_at": nil, "amount": 3, "status": "refunded", "note": nil},
+	}
+	profiles := ProfileColumns([]string{"created_at", "amount", "status", "note"}, rows)
+	assert.Equal(t, []ColumnProfile{
+		{Name: "created_at", Type: ColumnTemporal, Distinct: 2, Nulls: 1},
+		{Name: "amount", Type: ColumnQuantitative, Distinct: 3},
+		{Name: "status", Type: ColumnNominal, Distinct: 2},
+		{Name: "note", Type: ColumnNominal, Nulls: 3},
+	}, profiles)
+}
@@ -0,0 +1,176 @@
+// Package app 组装Chat2SQL服务的全部组件
+// 各组件通过构造函数显式声明依赖，后台任务与连接通过生命周期钩子统一启停
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/crash"
+	"chat2sql-go/internal/logging"
+	"chat2sql-go/internal/startup"
+	"chat2sql-go/internal/tracing"
+)
+
+// shutdownTimeout 优雅关闭的最长等待时间
+const shutdownTimeout = 30 * time.Second
+
+// App Chat2SQL服务
+type App struct {
+	config     *Config
+	logger     *zap.Logger
+	levels     *logging.Levels
+	supervisor *startup.Supervisor
+	lifecycle  *Lifecycle
+	server     *http.Server
+	probes     *http.Server // 健康检查与指标的独立监听，未配置SERVER_PROBE_ADDR时为nil
+}
+
+// New 创建服务实例，组件在Run中依赖就绪后才创建
+// levels为logger使用的模块日志级别，非nil时注册运行时调整日志级别的管理接口
+func New(cfg *Config, logger *zap.Logger, levels *logging.Levels) *App {
+	supervisor := startup.NewSupervisor(cfg.Startup, logger)
+
+	var probes *http.Server
+	if cfg.Startup.ProbeAddr != "" {
+		probes = &http.Server{
+			Addr:              cfg.Startup.ProbeAddr,
+			Handler:           startup.ProbesOnly(supervisor),
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		}
+	}
+
+	return &App{
+		config:     cfg,
+		logger:     logger,
+		levels:     levels,
+		supervisor: supervisor,
+		lifecycle:  NewLifecycle(logger),
+		server: &http.Server{
+			Addr:           cfg.Startup.Addr,
+			Handler:        supervisor,
+			ReadTimeout:    30 * time.Second,
+			WriteTimeout:   30 * time.Second,
+			IdleTimeout:    60 * time.Second,
+			MaxHeaderBytes: 1 << 20, // 1MB
+		},
+		probes: probes,
+	}
+}
+
+// Run 启动服务并阻塞到ctx取消，随后优雅关闭
+// 端口先于依赖初始化开始监听，启动期间由启动监督器报告starting状态
+func (a *App) Run(ctx context.Context) error {
+	// 崩溃报告：HTTP请求与后台任务panic时写入堆栈、最近请求与配置指纹
+	crash.SetDefault(crash.NewReporter(a.config.CrashReport,
+		crash.Fingerprint(a.config.Database, a.config.Redis, a.config.JWT, a.config.Metrics, a.config.AI),
+		a.config.App, a.logger))
+
+	if os.Getenv("GIN_MODE") == "" {
+		gin.SetMode(gin.ReleaseMode) // 生产模式
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		a.logger.Info("Chat2SQL server starting",
+			zap.String("addr", a.server.Addr),
+			zap.String("mode", gin.Mode()))
+
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+	if a.probes != nil {
+		go func() {
+			a.logger.Info("Probe server starting", zap.String("addr", a.probes.Addr))
+
+			if err := a.probes.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+		}()
+	}
+
+	if err := a.build(ctx); err != nil {
+		return errors.Join(err, a.shutdown())
+	}
+	a.logger.Info("Chat2SQL server ready")
+
+	select {
+	case <-ctx.Done():
+		a.logger.Info("Shutting down server...")
+	case err := <-serveErr:
+		return errors.Join(fmt.Errorf("HTTP服务异常退出: %w", err), a.shutdown())
+	}
+	return a.shutdown()
+}
+
+// build 初始化依赖、组装组件并启动后台任务，完成后把请求切换到完整路由
+func (a *App) build(ctx context.Context) error {
+	// 追踪最先注册、最后停止，其余组件停止期间产生的span也能导出
+	shutdownTracing, err := tracing.Setup(ctx, a.config.Tracing, a.config.App.Version, a.logger.Named("tracing"))
+	if err != nil {
+		return err
+	}
+	a.lifecycle.Append(Hook{Name: "tracing", OnStop: shutdownTracing})
+
+	infra, err := newInfrastructure(ctx, a.config, a.supervisor, a.lifecycle, a.logger)
+	if err != nil {
+		return err
+	}
+
+	repo, err := newRepository(a.config, infra, a.logger)
+	if err != nil {
+		return fmt.Errorf("初始化Repository失败: %w", err)
+	}
+
+	svc, err := newServices(a.config, infra, repo, a.lifecycle, a.logger)
+	if err != nil {
+		return fmt.Errorf("初始化服务失败: %w", err)
+	}
+
+	routerConfig := newRouterConfig(a.config, repo, svc, a.levels, a.logger)
+	router := startup.NewPriorityHandler(
+		newProbeRouter(routerConfig, svc, a.logger),
+		newRouter(a.config, routerConfig, svc, a.logger),
+		a.config.Startup.MaxInFlight)
+
+	if err := a.lifecycle.Start(ctx); err != nil {
+		return err
+	}
+
+	a.supervisor.Ready(router)
+	return nil
+}
+
+// shutdown 先停止接收请求，再按相反顺序停止后台任务并关闭连接
+func (a *App) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := a.server.Shutdown(ctx); err != nil {
+		a.logger.Error("Server forced to shutdown", zap.Error(err))
+		errs = append(errs, err)
+	}
+	// 探针端口最后关闭，API端口停止接收请求期间编排系统仍能读到状态
+	if a.probes != nil {
+		if err := a.probes.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := a.lifecycle.Stop(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	a.logger.Info("Chat2SQL server exited")
+	return errors.Join(errs...)
+}
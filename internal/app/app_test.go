@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestLifecycle_StartStopOrder(t *testing.T) {
+	var events []string
+	hook := func(name string) Hook {
+		return Hook{
+			Name:    name,
+			OnStart: func(ctx context.Context) error { events = append(events, "start "+name); return nil },
+			OnStop:  func(ctx context.Context) error { events = append(events, "stop "+name); return nil },
+		}
+	}
+
+	lc := NewLifecycle(zap.NewNop())
+	lc.Append(Hook{Name: "postgres", OnStop: func(ctx context.Context) error { events = append(events, "close postgres"); return nil }})
+	lc.Append(hook("erasure"))
+	lc.Append(hook("approval"))
+
+	require.NoError(t, lc.Start(context.Background()))
+	require.NoError(t, lc.Stop(context.Background()))
+	require.NoError(t, lc.Stop(context.Background()), "重复Stop不应重复释放")
+
+	assert.Equal(t, []string{
+		"start erasure", "start approval",
+		"stop approval", "stop erasure", "close postgres",
+	}, events)
+}
+
+func TestLifecycle_StartFailureStopsStarted(t *testing.T) {
+	var events []string
+	lc := NewLifecycle(zap.NewNop())
+	lc.Append(Hook{Name: "postgres", OnStop: func(ctx context.Context) error { events = append(events, "close postgres"); return nil }})
+	lc.Append(Hook{
+		Name:    "erasure",
+		OnStart: func(ctx context.Context) error { return nil },
+		OnStop:  func(ctx context.Context) error { events = append(events, "stop erasure"); return nil },
+	})
+	lc.Append(Hook{
+		Name:    "approval",
+		OnStart: func(ctx context.Context) error { return errors.New("already running") },
+		OnStop:  func(ctx context.Context) error { events = append(events, "stop approval"); return nil },
+	})
+
+	err := lc.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "approval")
+	assert.Equal(t, []string{"stop erasure", "close postgres"}, events)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("MOCK_LLM_RULES_FILE", "")
+	t.Setenv("CRASH_REPORT_MAX_BUNDLES", "many")
+	t.Setenv("STARTUP_MAX_WAIT", "soon")
+
+	cfg, err := LoadConfig(true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "crash_report: invalid CRASH_REPORT_MAX_BUNDLES")
+	assert.Contains(t, err.Error(), "startup: invalid STARTUP_MAX_WAIT")
+
+	// 出错的部分保留默认值，其余配置照常可用
+	require.NotNil(t, cfg)
+	assert.Equal(t, config.DefaultCrashReportConfig(), cfg.CrashReport)
+	assert.Equal(t, config.DefaultStartupConfig(), cfg.Startup)
+	assert.Equal(t, "mock", cfg.AI.Primary.Provider)
+	assert.NotNil(t, cfg.ConnectionEncryption)
+
+	t.Setenv("CRASH_REPORT_MAX_BUNDLES", "")
+	t.Setenv("STARTUP_MAX_WAIT", "")
+	t.Setenv("CONNECTION_ENCRYPTION_KEY", "")
+	_, err = LoadConfig(false)
+	require.ErrorIs(t, err, config.ErrLegacyConnectionEncryptionKey, "非演示模式必须配置连接密码加密密钥")
+
+	t.Setenv("CONNECTION_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	cfg, err = LoadConfig(false)
+	require.NoError(t, err)
+	assert.Equal(t, "ollama", cfg.AI.Primary.Provider)
+}
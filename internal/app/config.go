@@ -0,0 +1,201 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"chat2sql-go/internal/auth"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/metrics"
+)
+
+// Config 应用的全部配置，启动前一次性加载
+type Config struct {
+	Demo bool // 演示模式：使用确定性mock LLM提供商
+
+	App                  *config.AppInfo
+	Startup              *config.StartupConfig
+	APIVersion           *config.APIVersionConfig
+	Compression          *config.CompressionConfig
+	Database             *config.DatabaseConfig
+	DatabaseFailover     *config.DatabaseFailoverConfig
+	DatabaseReplicas     *config.DatabaseReplicaConfig
+	Redis                *config.RedisConfig
+	JWT                  *auth.JWTConfig
+	Metrics              *metrics.MetricsConfig
+	SystemMonitor        *metrics.SystemMonitorConfig
+	AI                   *config.AIConfig
+	Deterministic        *config.DeterministicGenerationConfig
+	CrashReport          *config.CrashReportConfig
+	HistoryEncryption    *config.HistoryEncryptionConfig
+	ConnectionEncryption *config.ConnectionEncryptionConfig
+	SQLite               *config.SQLiteConfig
+	Erasure              *config.ErasureConfig
+	Approval             *config.ApprovalConfig
+	Residency            *config.ResidencyConfig
+	WorkspaceSettings    *config.WorkspaceSettingsConfig
+	Realtime             *config.RealtimeConfig
+	ChatSession          *config.ChatSessionConfig
+	Teams                *config.TeamsConfig
+	EmailGateway         *config.EmailGatewayConfig
+	Logging              *config.LoggingConfig
+	Watchdog             *config.WatchdogConfig
+	SchemaWarmup         *config.SchemaWarmupConfig
+	Telemetry            *config.TelemetryConfig
+	Tracing              *config.TracingConfig
+	LLMArchive           *config.LLMArchiveConfig
+	LatencyRouting       *config.LatencyRoutingConfig
+	LLMFailover          *config.LLMFailoverConfig
+	IntentGeneration     *config.IntentGenerationConfig
+	SQLContinuation      *config.SQLContinuationConfig
+	SQLTemplates         *config.SQLTemplateConfig
+	ResultTable          *config.ResultTableConfig
+	Calibration          *config.CalibrationConfig
+	Feedback             *config.FeedbackConfig
+	ConnectionUsage      *config.ConnectionUsageConfig
+	Export               *config.ExportConfig
+	ConnectionCleanup    *config.ConnectionCleanupConfig
+	SchemaRefresh        *config.SchemaRefreshConfig
+	Maintenance          *config.MaintenanceConfig
+	PromptSchema         *config.PromptSchemaConfig
+	ResultCache          *config.ResultCacheConfig
+	PromptCache          *config.PromptCacheConfig
+	CacheWarming         *config.CacheWarmingConfig
+	QueryJobs            *config.QueryJobConfig
+	QuerySchedules       *config.QueryScheduleConfig
+	SQLGuardrails        *config.SQLGuardrailConfig
+}
+
+// LoadConfig 从环境变量加载全部配置
+// 返回的配置始终完整可用：加载失败的部分保留默认值，所有错误合并后一起返回，
+// 服务启动时据此直接退出，doctor命令则据此输出完整的配置问题清单
+func LoadConfig(demo bool) (*Config, error) {
+	cfg := &Config{
+		Demo:          demo,
+		App:           config.DefaultAppInfo(),
+		Database:      config.DefaultDatabaseConfig(),
+		Redis:         config.DefaultRedisConfig(),
+		JWT:           auth.DefaultJWTConfig(),
+		Metrics:       metrics.DefaultMetricsConfig(),
+		SystemMonitor: metrics.DefaultSystemMonitorConfig(),
+		AI:            localAIConfig(),
+	}
+	if demo {
+		cfg.AI = config.DemoAIConfig(os.Getenv("MOCK_LLM_RULES_FILE"))
+	}
+
+	var errs []error
+	load := func(name string, fn func() error) {
+		if err := fn(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	load("database", cfg.Database.Validate)
+	load("deterministic_generation", loadInto(&cfg.Deterministic, config.LoadDeterministicGenerationConfigFromEnv, config.DefaultDeterministicGenerationConfig))
+	if cfg.Deterministic.Enabled {
+		cfg.AI.ApplyDeterministicProfile(cfg.Deterministic.Seed)
+	}
+	load("ai_budget", cfg.AI.ApplyBudgetEnv)
+	load("ai", cfg.AI.Validate)
+	load("database_failover", loadInto(&cfg.DatabaseFailover, config.LoadDatabaseFailoverConfigFromEnv, config.DefaultDatabaseFailoverConfig))
+	load("database_replicas", loadInto(&cfg.DatabaseReplicas, config.LoadDatabaseReplicaConfigFromEnv, config.DefaultDatabaseReplicaConfig))
+	load("startup", loadInto(&cfg.Startup, config.LoadStartupConfigFromEnv, config.DefaultStartupConfig))
+	load("api_version", loadInto(&cfg.APIVersion, config.LoadAPIVersionConfigFromEnv, config.DefaultAPIVersionConfig))
+	load("compression", loadInto(&cfg.Compression, config.LoadCompressionConfigFromEnv, config.DefaultCompressionConfig))
+	load("crash_report", loadInto(&cfg.CrashReport, config.LoadCrashReportConfigFromEnv, config.DefaultCrashReportConfig))
+	load("history_encryption", loadInto(&cfg.HistoryEncryption, config.LoadHistoryEncryptionConfigFromEnv, config.DefaultHistoryEncryptionConfig))
+	// 内置的旧密钥只允许在演示模式或显式允许的开发环境中使用
+	load("connection_encryption", loadInto(&cfg.ConnectionEncryption, func() (*config.ConnectionEncryptionConfig, error) {
+		return config.LoadConnectionEncryptionConfigFromEnv(demo)
+	}, config.DefaultConnectionEncryptionConfig))
+	load("sqlite", loadInto(&cfg.SQLite, config.LoadSQLiteConfigFromEnv, config.DefaultSQLiteConfig))
+	load("erasure", loadInto(&cfg.Erasure, config.LoadErasureConfigFromEnv, config.DefaultErasureConfig))
+	load("approval", loadInto(&cfg.Approval, config.LoadApprovalConfigFromEnv, config.DefaultApprovalConfig))
+	load("residency", loadInto(&cfg.Residency, config.LoadResidencyConfigFromEnv, config.DefaultResidencyConfig))
+	load("workspace_settings", loadInto(&cfg.WorkspaceSettings, config.LoadWorkspaceSettingsConfigFromEnv, config.DefaultWorkspaceSettingsConfig))
+	load("realtime", loadInto(&cfg.Realtime, config.LoadRealtimeConfigFromEnv, config.DefaultRealtimeConfig))
+	load("chat_session", loadInto(&cfg.ChatSession, config.LoadChatSessionConfigFromEnv, config.DefaultChatSessionConfig))
+	load("teams", loadInto(&cfg.Teams, config.LoadTeamsConfigFromEnv, config.DefaultTeamsConfig))
+	load("email_gateway", loadInto(&cfg.EmailGateway, config.LoadEmailGatewayConfigFromEnv, config.DefaultEmailGatewayConfig))
+	load("logging", loadInto(&cfg.Logging, config.LoadLoggingConfigFromEnv, config.DefaultLoggingConfig))
+	load("watchdog", loadInto(&cfg.Watchdog, config.LoadWatchdogConfigFromEnv, config.DefaultWatchdogConfig))
+	load("schema_warmup", loadInto(&cfg.SchemaWarmup, config.LoadSchemaWarmupConfigFromEnv, config.DefaultSchemaWarmupConfig))
+	load("telemetry", loadInto(&cfg.Telemetry, config.LoadTelemetryConfigFromEnv, config.DefaultTelemetryConfig))
+	load("tracing", loadInto(&cfg.Tracing, config.LoadTracingConfigFromEnv, config.DefaultTracingConfig))
+	load("llm_archive", loadInto(&cfg.LLMArchive, config.LoadLLMArchiveConfigFromEnv, config.DefaultLLMArchiveConfig))
+	load("latency_routing", loadInto(&cfg.LatencyRouting, config.LoadLatencyRoutingConfigFromEnv, config.DefaultLatencyRoutingConfig))
+	load("llm_failover", loadInto(&cfg.LLMFailover, config.LoadLLMFailoverConfigFromEnv, config.DefaultLLMFailoverConfig))
+	load("intent_generation", loadInto(&cfg.IntentGeneration, config.LoadIntentGenerationConfigFromEnv, config.DefaultIntentGenerationConfig))
+	load("sql_continuation", loadInto(&cfg.SQLContinuation, config.LoadSQLContinuationConfigFromEnv, config.DefaultSQLContinuationConfig))
+	load("sql_templates", loadInto(&cfg.SQLTemplates, config.LoadSQLTemplateConfigFromEnv, config.DefaultSQLTemplateConfig))
+	load("result_table", loadInto(&cfg.ResultTable, config.LoadResultTableConfigFromEnv, config.DefaultResultTableConfig))
+	load("calibration", loadInto(&cfg.Calibration, config.LoadCalibrationConfigFromEnv, config.DefaultCalibrationConfig))
+	load("feedback", loadInto(&cfg.Feedback, config.LoadFeedbackConfigFromEnv, config.DefaultFeedbackConfig))
+	load("connection_usage", loadInto(&cfg.ConnectionUsage, config.LoadConnectionUsageConfigFromEnv, config.DefaultConnectionUsageConfig))
+	load("export", loadInto(&cfg.Export, config.LoadExportConfigFromEnv, config.DefaultExportConfig))
+	load("connection_cleanup", loadInto(&cfg.ConnectionCleanup, config.LoadConnectionCleanupConfigFromEnv, config.DefaultConnectionCleanupConfig))
+	load("schema_refresh", loadInto(&cfg.SchemaRefresh, config.LoadSchemaRefreshConfigFromEnv, config.DefaultSchemaRefreshConfig))
+	load("maintenance", loadInto(&cfg.Maintenance, config.LoadMaintenanceConfigFromEnv, config.DefaultMaintenanceConfig))
+	load("prompt_schema", loadInto(&cfg.PromptSchema, config.LoadPromptSchemaConfigFromEnv, config.DefaultPromptSchemaConfig))
+	load("result_cache", loadInto(&cfg.ResultCache, config.LoadResultCacheConfigFromEnv, config.DefaultResultCacheConfig))
+	load("prompt_cache", loadInto(&cfg.PromptCache, config.LoadPromptCacheConfigFromEnv, config.DefaultPromptCacheConfig))
+	load("cache_warming", loadInto(&cfg.CacheWarming, config.LoadCacheWarmingConfigFromEnv, config.DefaultCacheWarmingConfig))
+	load("query_jobs", loadInto(&cfg.QueryJobs, config.LoadQueryJobConfigFromEnv, config.DefaultQueryJobConfig))
+	load("query_schedules", loadInto(&cfg.QuerySchedules, config.LoadQueryScheduleConfigFromEnv, config.DefaultQueryScheduleConfig))
+	load("sql_guardrails", loadInto(&cfg.SQLGuardrails, config.LoadSQLGuardrailConfigFromEnv, config.DefaultSQLGuardrailConfig))
+	load("metrics_cardinality", loadInto(&cfg.Metrics.Cardinality, config.LoadMetricsCardinalityConfigFromEnv, config.DefaultMetricsCardinalityConfig))
+
+	return cfg, errors.Join(errs...)
+}
+
+// loadInto 调用环境变量加载函数，失败时使用默认配置
+func loadInto[T any](target **T, loadFromEnv func() (*T, error), defaults func() *T) func() error {
+	return func() error {
+		value, err := loadFromEnv()
+		if err != nil {
+			*target = defaults()
+			return err
+		}
+		*target = value
+		return nil
+	}
+}
+
+// localAIConfig 创建支持本地Ollama的AI配置
+func localAIConfig() *config.AIConfig {
+	// 获取环境变量中的模型名称，默认使用deepseek-r1:7b
+	ollamaModel := os.Getenv("OLLAMA_MODEL")
+	if ollamaModel == "" {
+		ollamaModel = "deepseek-r1:7b"
+	}
+
+	return &config.AIConfig{
+		Primary: config.ModelConfig{
+			Provider:    "ollama",
+			ModelName:   ollamaModel,
+			Temperature: 0.1,
+			MaxTokens:   2048,
+			TopP:        0.9,
+			Timeout:     30 * time.Second,
+		},
+		Fallback: config.ModelConfig{
+			Provider:    "ollama", // 备用也使用Ollama
+			ModelName:   ollamaModel,
+			Temperature: 0.0,
+			MaxTokens:   1024,
+			TopP:        0.9,
+			Timeout:     30 * time.Second,
+		},
+		MaxConcurrency: 10,
+		Timeout:        30 * time.Second,
+		Budget: config.BudgetConfig{
+			DailyLimit:     100.0, // $100 per day (对本地模型不适用，但保持结构)
+			UserLimit:      10.0,  // $10 per user per day
+			AlertThreshold: 0.8,   // 80% of limit
+			Enforce:        true,
+		},
+	}
+}
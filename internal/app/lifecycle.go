@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Hook 组件生命周期钩子，OnStart与OnStop均可为空
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle 按注册顺序启动组件，按相反顺序停止
+// 依赖方总是在被依赖方之后注册，因此停止时先停依赖方，例如先停后台任务再关闭数据库连接
+type Lifecycle struct {
+	hooks   []Hook
+	started int
+	logger  *zap.Logger
+}
+
+// NewLifecycle 创建生命周期管理器
+func NewLifecycle(logger *zap.Logger) *Lifecycle {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Lifecycle{logger: logger}
+}
+
+// Append 注册钩子
+func (l *Lifecycle) Append(hook Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+// Start 依次执行OnStart，任一失败时停止已启动的组件并返回错误
+func (l *Lifecycle) Start(ctx context.Context) error {
+	for l.started < len(l.hooks) {
+		hook := l.hooks[l.started]
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				stopErr := l.Stop(ctx)
+				return errors.Join(fmt.Errorf("启动%s失败: %w", hook.Name, err), stopErr)
+			}
+			l.logger.Debug("组件已启动", zap.String("component", hook.Name))
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop 按相反顺序执行OnStop，单个组件停止失败不影响其他组件
+// 没有OnStart的钩子代表注册时已持有的资源（例如已建立的连接），即使Start未执行也会释放；
+// Stop之后所有钩子被清空，重复调用不会重复释放
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	var errs []error
+	for i := len(l.hooks) - 1; i >= 0; i-- {
+		hook := l.hooks[i]
+		if hook.OnStop == nil || (i >= l.started && hook.OnStart != nil) {
+			continue
+		}
+		if err := hook.OnStop(ctx); err != nil {
+			l.logger.Warn("停止组件失败", zap.String("component", hook.Name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("停止%s失败: %w", hook.Name, err))
+			continue
+		}
+		l.logger.Debug("组件已停止", zap.String("component", hook.Name))
+	}
+
+	l.hooks, l.started = nil, 0
+	return errors.Join(errs...)
+}
@@ -0,0 +1,650 @@
+package app
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/auth"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/database"
+	"chat2sql-go/internal/handler"
+	"chat2sql-go/internal/logging"
+	"chat2sql-go/internal/mcp"
+	"chat2sql-go/internal/metrics"
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/repository/postgres"
+	"chat2sql-go/internal/routing"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/startup"
+	"chat2sql-go/internal/telemetry"
+	"chat2sql-go/internal/tracing"
+	"chat2sql-go/internal/watchdog"
+)
+
+// infrastructure 外部依赖连接
+type infrastructure struct {
+	db       *database.Manager
+	replicas *database.ReplicaSet // 未配置读副本时为nil
+	redis    redis.UniversalClient
+}
+
+// newInfrastructure 按顺序连接PostgreSQL与Redis，暂时不可用时由启动监督器重试
+func newInfrastructure(ctx context.Context, cfg *Config, supervisor *startup.Supervisor, lc *Lifecycle, logger *zap.Logger) (*infrastructure, error) {
+	infra := &infrastructure{}
+
+	if err := supervisor.Retry(ctx, "postgres", func(ctx context.Context) error {
+		manager, err := database.NewManager(cfg.Database, logger)
+		if err != nil {
+			return err
+		}
+		infra.db = manager
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	lc.Append(Hook{Name: "postgres", OnStop: func(ctx context.Context) error {
+		infra.db.Close()
+		return nil
+	}})
+	logger.Info("Database connection established successfully")
+
+	// 只读副本按需建立连接，不可用时读请求回到主库，不阻塞启动
+	if cfg.DatabaseReplicas.Enabled() {
+		replicas, err := database.NewReplicaSet(cfg.Database, cfg.DatabaseReplicas, logger.Named("replicas"))
+		if err != nil {
+			return nil, err
+		}
+		infra.replicas = replicas
+		lc.Append(Hook{Name: "postgres_replicas", OnStop: func(ctx context.Context) error {
+			replicas.Close()
+			return nil
+		}})
+	}
+
+	if err := supervisor.Retry(ctx, "redis", func(ctx context.Context) error {
+		client, err := config.NewRedisClient(cfg.Redis)
+		if err != nil {
+			return err
+		}
+		infra.redis = client
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	lc.Append(Hook{Name: "redis", OnStop: func(ctx context.Context) error {
+		return infra.redis.Close()
+	}})
+	logger.Info("Redis connection established successfully")
+
+	return infra, nil
+}
+
+// newRepository 创建Repository层，系统库访问启用故障转移处理，配置主密钥后启用查询历史透明加密
+func newRepository(cfg *Config, infra *infrastructure, logger *zap.Logger) (repository.Repository, error) {
+	pool := infra.db.GetPool()
+
+	opts := []postgres.RepositoryOption{postgres.WithFailover(cfg.DatabaseFailover)}
+	if infra.replicas != nil {
+		opts = append(opts, postgres.WithReadReplicas(func() postgres.DB {
+			if replica := infra.replicas.Pick(); replica != nil {
+				return replica
+			}
+			return nil
+		}))
+	}
+	if cfg.HistoryEncryption.Enabled() {
+		keyring, err := postgres.NewHistoryKeyring(cfg.HistoryEncryption.MasterKey,
+			postgres.NewPostgreSQLHistoryKeyRepository(pool, logger),
+			postgres.NewPostgreSQLWorkspaceRepository(pool, logger),
+			cfg.HistoryEncryption.KeyCacheTTL, logger)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, postgres.WithHistoryKeyring(keyring))
+		logger.Info("Query history encryption enabled")
+	}
+
+	return postgres.NewPostgreSQLRepository(pool, logger, opts...), nil
+}
+
+// services Service层组件
+type services struct {
+	jwt               *auth.JWTService
+	prometheus        *metrics.PrometheusMetrics
+	connectionManager *service.ConnectionManager
+	sqlExecutor       *service.SQLExecutor
+	runningQueries    *service.RunningQueryRegistry
+	resultCache       *service.QueryResultCache    // 未开启结果缓存时为nil
+	teamsPages        *service.TeamsPageStore      // 未开启Teams集成时为nil
+	schemaWarmup      *service.SchemaWarmupService // 未启用预热时为nil
+	schemaSnapshots   *service.SchemaSnapshotService
+	health            *service.HealthService
+	ai                *service.AIService
+	costs             *ai.CostTracker
+	metricsSummary    *service.MetricsSummaryService
+	classification    *service.ClassificationService
+	erasure           *service.ErasureService
+	queryJobs         *service.QueryJobService
+	folders           *service.FolderService
+	querySchedules    *service.QueryScheduleService
+	approval          *service.ApprovalEngine
+	maintenance       *service.MaintenanceMode
+	residency         *service.ResidencyService
+	workspaceSettings *service.WorkspaceSettingsService
+	realtime          *service.RealtimeHub
+	chatSessions      *service.ChatSessionStore
+	feedback          *service.FeedbackService
+	writeMode         *service.WriteModeService
+	mcp               *mcp.Server
+	emailGateway      *service.EmailGateway      // 未配置Webhook令牌时为nil
+	telemetry         *telemetry.Collector       // 未开启匿名使用统计时为nil
+	llmArchive        *service.LLMArchiveService // 未开启模型归档时为nil
+	integrations      *service.IntegrationCredentialService
+	watchdog          *watchdog.Watchdog
+}
+
+// newServices 创建Service层组件，后台任务通过生命周期钩子启停
+func newServices(cfg *Config, infra *infrastructure, repo repository.Repository, lc *Lifecycle, logger *zap.Logger) (*services, error) {
+	svc := &services{}
+	pool := infra.db.GetPool()
+
+	// JWT服务，开发阶段把自动生成的密钥保存到文件
+	jwtService, err := auth.NewJWTService(cfg.JWT, logger, infra.redis)
+	if err != nil {
+		return nil, err
+	}
+	if err := jwtService.SaveKeysToFile(cfg.JWT.PrivateKeyPath, cfg.JWT.PublicKeyPath); err != nil {
+		logger.Warn("Failed to save JWT keys", zap.Error(err))
+	}
+	svc.jwt = jwtService
+
+	// Prometheus指标与系统监控，后台采集任务由看门狗托管，卡住或退出时自动重启
+	svc.prometheus = metrics.NewPrometheusMetrics(cfg.Metrics, logger)
+	svc.watchdog = watchdog.New(cfg.Watchdog, svc.prometheus.Registerer(), logger)
+	systemMonitor := metrics.NewSystemMonitor(cfg.SystemMonitor, logger)
+	svc.watchdog.Register("system_monitor", systemMonitor.CollectInterval(), systemMonitor.Run)
+	svc.watchdog.Register("system_metrics_collector", systemMetricsInterval, newSystemMetricsCollector(svc.prometheus, logger))
+	if infra.replicas != nil {
+		svc.watchdog.Register("replica_lag_monitor", cfg.DatabaseReplicas.CheckInterval, infra.replicas.Run)
+	}
+	// 匿名使用统计：需显式开启，按批次定期上报
+	if cfg.Telemetry.Enabled {
+		svc.telemetry = telemetry.NewCollector(cfg.Telemetry, cfg.App.Version, logger.Named("telemetry"))
+		svc.watchdog.Register("telemetry", cfg.Telemetry.FlushInterval, svc.telemetry.Run)
+		logger.Info("Anonymous telemetry enabled", zap.String("endpoint", cfg.Telemetry.Endpoint))
+	}
+	lc.Append(Hook{Name: "watchdog", OnStart: svc.watchdog.Start, OnStop: svc.watchdog.Stop})
+
+	// 只读维护模式：状态保存在Redis中由各实例定期同步，Redis中没有状态时取配置值；运行中由管理员通过/admin/maintenance开关
+	svc.maintenance = service.NewMaintenanceMode(cfg.Maintenance)
+	svc.maintenance.SetStore(service.NewRedisMaintenanceStore(infra.redis), logger.Named("maintenance"))
+	svc.watchdog.Register("maintenance_sync", 2*service.MaintenanceSyncInterval, svc.maintenance.Run)
+	if svc.maintenance.Active() {
+		logger.Warn("Maintenance mode enabled at startup, SQL generation and execution are blocked")
+	}
+
+	// 连接管理器，内置的旧密钥只在演示模式或显式允许的开发环境中使用（配置校验时已拒绝其他情况）
+	if cfg.ConnectionEncryption.Legacy {
+		logger.Warn("Using built-in legacy key for connection passwords, for development only")
+	}
+	svc.connectionManager, err = service.NewConnectionManager(pool, repo.ConnectionRepo(), cfg.ConnectionEncryption.Key, logger)
+	if err != nil {
+		return nil, err
+	}
+	svc.connectionManager.SetSQLiteDataDir(cfg.SQLite.DataDir)
+	// 第三方集成凭据与连接密码使用同一加密密钥，由connection-keys命令一并重新加密
+	integrationCipher, err := service.NewAESEncryption(cfg.ConnectionEncryption.Key)
+	if err != nil {
+		return nil, err
+	}
+	svc.integrations = service.NewIntegrationCredentialService(repo.IntegrationCredentialRepo(), integrationCipher, logger)
+	// 内置集成优先读取凭据库中的密钥，环境变量只作初始值；每次使用时读取，轮换后无需重启
+	smtpPassword := svc.integrations.SecretSource(repository.IntegrationSMTP,
+		service.DefaultIntegrationCredentialName, cfg.EmailGateway.SMTPPassword)
+	lc.Append(Hook{
+		Name:    "connection_manager",
+		OnStart: func(ctx context.Context) error { return svc.connectionManager.Start() },
+		OnStop:  func(ctx context.Context) error { return svc.connectionManager.Stop() },
+	})
+
+	// 闲置连接清理：需显式开启，提醒所有者长期未使用的连接，开启自动停用时在宽限期后停用并关闭连接池；
+	// 配置了邮件网关的SMTP时通过邮件提醒，否则只记录日志
+	if cfg.ConnectionCleanup.Enabled {
+		var mailer service.Mailer
+		if cfg.EmailGateway.SMTPHost != "" {
+			mailer = service.NewSMTPMailer(cfg.EmailGateway, smtpPassword)
+		}
+		cleanup := service.NewConnectionCleanupService(repo.ConnectionRepo(), repo.UserRepo(), svc.connectionManager,
+			mailer, cfg.ConnectionCleanup, logger.Named("connection_cleanup"))
+		svc.watchdog.Register("connection_cleanup", cfg.ConnectionCleanup.CheckInterval, cleanup.Run)
+	}
+
+	svc.sqlExecutor = service.NewSQLExecutorWithConfig(pool, svc.connectionManager, &service.SQLExecutorConfig{
+		QueryTimeout: cfg.SQLGuardrails.QueryTimeout,
+		MaxRows:      int32(cfg.SQLGuardrails.MaxRows),
+		MaxResultMB:  int32(cfg.SQLGuardrails.MaxResultMB),
+		AutoLimit:    cfg.SQLGuardrails.AutoLimit,
+	}, logger.Named(logging.ModuleSQL))
+	svc.runningQueries = service.NewRunningQueryRegistry()
+	svc.sqlExecutor.SetRunningQueries(svc.runningQueries)
+	svc.sqlExecutor.SetMetrics(svc.prometheus)
+	// 数据驻留：结果快照、导出与模型归档写入前按工作空间策略校验本服务的存储位置
+	svc.residency = service.NewResidencyService(repo.WorkspaceRepo(), cfg.Residency, logger)
+	svc.sqlExecutor.SetResidency(svc.residency)
+	svc.health = service.NewHealthService(repo, infra.redis, cfg.App, logger)
+
+	// 元数据预热：新建连接后立即在后台探测并保存表结构
+	if cfg.SchemaWarmup.Enabled {
+		introspector := service.NewSchemaIntrospector(svc.connectionManager, repo.SchemaRepo(), logger)
+		svc.schemaWarmup = service.NewSchemaWarmupService(introspector, cfg.SchemaWarmup, logger)
+		lc.Append(Hook{Name: "schema_warmup", OnStop: svc.schemaWarmup.Stop})
+	}
+	// 表结构定期刷新：需显式开启，重新探测活跃连接的表结构，有变化时更新保存的元数据
+	if cfg.SchemaRefresh.Enabled {
+		introspector := service.NewSchemaIntrospector(svc.connectionManager, repo.SchemaRepo(), logger)
+		refresh := service.NewSchemaRefreshService(repo.ConnectionRepo(), repo.SchemaRepo(), introspector,
+			cfg.SchemaRefresh, logger.Named("schema_refresh"))
+		refresh.SetChangeRepository(repo.SchemaChangeRepo())
+		svc.watchdog.Register("schema_refresh", cfg.SchemaRefresh.Interval, refresh.Run)
+	}
+
+	// AI服务
+	svc.ai, err = service.NewAIService(cfg.AI, logger.Named(logging.ModuleAI))
+	if err != nil {
+		return nil, err
+	}
+	svc.ai.SetCandidateRanker(service.NewCandidateRanker(svc.sqlExecutor, repo.QueryHistoryRepo(), logger))
+	latency := service.NewLatencyTracker(cfg.LatencyRouting)
+	svc.ai.SetLatencyTracker(latency)
+	// 模型降级链：按LLM_PROVIDER_ORDER依次尝试各模型，单个模型退避重试，连续失败后熔断；配置本地模型时作为最后一环
+	if cfg.LLMFailover.LocalModel != "" {
+		if err := svc.ai.EnableLocalModel(cfg.LLMFailover.LocalModelConfig()); err != nil {
+			return nil, err
+		}
+	}
+	svc.ai.SetProviderChain(service.NewProviderChain(cfg.LLMFailover, logger.Named("llm_failover")))
+	svc.ai.SetIntentGeneration(cfg.IntentGeneration)
+	svc.ai.SetSQLContinuation(cfg.SQLContinuation)
+	svc.ai.SetSQLFormatting(cfg.AI.FormatSQL)
+	// 模型响应缓存：需显式开启，温度为0的相同提示词在TTL内直接返回Redis中缓存的响应
+	if cfg.PromptCache.Enabled {
+		svc.ai.SetPromptCache(service.NewPromptCache(infra.redis, cfg.PromptCache, logger.Named("prompt_cache")))
+		logger.Info("Prompt cache enabled", zap.Duration("ttl", cfg.PromptCache.TTL))
+	}
+	svc.ai.SetSQLTemplates(service.NewSQLTemplateEngine(repo.SchemaRepo(), cfg.SQLTemplates, logger))
+	svc.ai.SetDBTypeResolver(svc.connectionManager)
+	promptBuilder := ai.NewPromptBuilder(repo.SchemaRepo(), cfg.PromptSchema, logger)
+	if cfg.PromptSchema.HistoryRanking {
+		promptBuilder.SetTableUsage(service.NewTableUsageStats(repo.QueryHistoryRepo(), repo.WorkspaceRepo(), logger))
+	}
+	svc.ai.SetPromptBuilder(promptBuilder)
+	svc.schemaSnapshots = service.NewSchemaSnapshotService(repo.SchemaSnapshotRepo(), svc.ai, logger)
+	lc.Append(Hook{Name: "ai_service", OnStop: func(ctx context.Context) error { return svc.ai.Close() }})
+
+	// 模型用量：按用户、提供商与模型累计估算成本并按天持久化，启动时恢复当日统计；
+	// AI_BUDGET_ENFORCE开启时达到每用户或全局每日预算后拒绝调用模型
+	svc.costs = ai.NewCostTracker(ai.CostConfigFromBudget(cfg.AI.Budget))
+	svc.costs.SetUsageStore(repo.LLMUsageRepo(), logger.Named("llm_usage"))
+	svc.costs.RegisterMetrics(svc.prometheus.Registerer())
+	svc.ai.SetCostTracker(svc.costs)
+	lc.Append(Hook{
+		Name: "llm_usage",
+		OnStart: func(ctx context.Context) error {
+			_, err := svc.costs.LoadUsage(ctx)
+			return err
+		},
+	})
+
+	// Teams结果卡片的分页令牌保存在Redis中，多实例共享
+	if cfg.Teams.Enabled {
+		svc.teamsPages = service.NewTeamsPageStore(infra.redis, cfg.Teams.PageTTL)
+	}
+
+	// 查询结果缓存：需显式开启，同一连接上相同SQL的成功结果保存在Redis中，多实例共享
+	if cfg.ResultCache.Enabled {
+		svc.resultCache = service.NewQueryResultCache(infra.redis, cfg.ResultCache, logger.Named("result_cache"))
+		logger.Info("Query result cache enabled", zap.Duration("ttl", cfg.ResultCache.TTL), zap.Int("max_entries", cfg.ResultCache.MaxEntries))
+	}
+
+	// 仪表盘查询缓存预热：标记为仪表盘使用的保存查询在缓存过期前重新执行，由看门狗托管
+	if cfg.CacheWarming.Enabled {
+		if svc.resultCache == nil {
+			logger.Warn("CACHE_WARMING_ENABLED requires RESULT_CACHE_ENABLED, dashboard cache warming disabled")
+		} else {
+			warmer := service.NewCacheWarmer(repo.SavedQueryRepo(), repo.ConnectionRepo(), svc.sqlExecutor, svc.resultCache,
+				cfg.CacheWarming, svc.prometheus.Registerer(), logger.Named("cache_warming"))
+			warmer.SetMaintenanceMode(svc.maintenance)
+			svc.watchdog.Register("cache_warming", cfg.CacheWarming.CheckInterval, warmer.Run)
+		}
+	}
+
+	// 模型归档：需显式开启，按查询ID保存脱敏后的提示词与模型输出，过期归档由看门狗托管的任务清理
+	if cfg.LLMArchive.Enabled {
+		store, err := service.NewDirObjectStore(cfg.LLMArchive.Dir)
+		if err != nil {
+			return nil, err
+		}
+		svc.llmArchive = service.NewLLMArchiveService(store, repo.WorkspaceRepo(), cfg.LLMArchive, logger.Named("llm_archive"))
+		svc.llmArchive.SetResidency(svc.residency)
+		svc.watchdog.Register("llm_archive_prune", cfg.LLMArchive.PruneInterval, svc.llmArchive.Run)
+		logger.Info("LLM archive enabled", zap.String("dir", cfg.LLMArchive.Dir), zap.Duration("retention", cfg.LLMArchive.Retention))
+	}
+
+	// 列数据分级：按角色脱敏或过滤结果中的受限列，并在提示词中标出受限列；连接所有者授予的访问例外到期前覆盖默认处理方式
+	svc.classification = service.NewClassificationService(repo.ClassificationRepo(), repo.ConnectionRepo(), logger)
+	svc.classification.SetColumnAccessRepository(repo.ColumnAccessRepo())
+	svc.sqlExecutor.SetColumnPolicy(service.NewColumnPolicyEnforcer(svc.classification, repo.UserRepo(), logger))
+
+	// 个人数据删除：后台任务处理GDPR删除申请
+	svc.erasure = service.NewErasureService(repo.ErasureRepo(), cfg.Erasure, logger)
+	lc.Append(Hook{
+		Name:    "erasure",
+		OnStart: func(ctx context.Context) error { return svc.erasure.Start() },
+		OnStop:  func(ctx context.Context) error { return svc.erasure.Stop() },
+	})
+
+	// 异步查询任务：耗时较长的查询提交为后台任务，任务状态保存在数据库，实例重启后中断的任务重新执行
+	svc.queryJobs = service.NewQueryJobService(repo.QueryJobRepo(), repo.ConnectionRepo(), repo.QueryHistoryRepo(),
+		svc.sqlExecutor, cfg.QueryJobs, logger.Named(logging.ModuleSQL))
+	svc.queryJobs.SetMaintenanceMode(svc.maintenance)
+	lc.Append(Hook{
+		Name:    "query_jobs",
+		OnStart: func(ctx context.Context) error { return svc.queryJobs.Start() },
+		OnStop:  func(ctx context.Context) error { return svc.queryJobs.Stop() },
+	})
+
+	// 多轮对话会话：保存在内存中，空闲会话由看门狗托管的任务清理，删除个人数据时一并清除
+	svc.chatSessions = service.NewChatSessionStore(cfg.ChatSession, logger.Named("chat"))
+	svc.watchdog.Register("chat_session_prune", cfg.ChatSession.IdleTimeout/2, svc.chatSessions.Run)
+	svc.erasure.AddConversationMemory(svc.chatSessions)
+
+	// 查询反馈学习：用户反馈写入准确率统计（持久化并按保留期清理）并交给学习引擎，
+	// 事后修正经对账后重新训练学习引擎；学习引擎的定期任务由看门狗托管
+	accuracy := ai.NewAccuracyMonitor(ai.DefaultAccuracyConfig(), logger.Named("accuracy"))
+	accuracy.SetFeedbackStore(repo.FeedbackRepo())
+	learningConfig := routing.DefaultLearningConfig()
+	learningConfig.EnableAsyncLearning = false
+	learning := routing.NewLearningEngine(context.Background(), learningConfig)
+	accuracy.SetCorrectionHandler(ai.LearningEngineCorrections(learning))
+	svc.watchdog.Register("learning_engine", learningConfig.UpdateInterval, learning.Run)
+	lc.Append(Hook{
+		Name: "feedback",
+		OnStart: func(ctx context.Context) error {
+			if _, err := accuracy.LoadFeedback(ctx); err != nil {
+				return err
+			}
+			return accuracy.Start(ctx)
+		},
+		OnStop: func(ctx context.Context) error {
+			if err := accuracy.Stop(ctx); err != nil {
+				return err
+			}
+			return learning.Close()
+		},
+	})
+	svc.feedback = service.NewFeedbackService(accuracy, learning, cfg.Feedback, logger.Named("feedback"))
+
+	// 运行指标摘要：界面状态栏展示的QPS、生成耗时、当日准确率与预算用量
+	svc.metricsSummary = service.NewMetricsSummaryService(svc.prometheus, latency, accuracy, svc.costs)
+	svc.erasure.AddConversationMemory(svc.feedback)
+
+	// 保存查询文件夹：按文件夹组织保存查询，权限向下继承
+	svc.folders = service.NewFolderService(repo.FolderRepo(), repo.SavedQueryRepo(), repo.WorkspaceRepo(), logger)
+
+	// 保存查询定时执行：按cron执行保存查询并投递结果，配置了邮件网关的SMTP时支持邮件投递；
+	// 关闭调度的实例仍可管理计划，由其他实例执行
+	var scheduleMailer service.Mailer
+	if cfg.EmailGateway.SMTPHost != "" {
+		scheduleMailer = service.NewSMTPMailer(cfg.EmailGateway, smtpPassword)
+	}
+	svc.querySchedules = service.NewQueryScheduleService(repo.QueryScheduleRepo(), repo.SavedQueryRepo(), repo.ConnectionRepo(),
+		svc.folders, svc.sqlExecutor, scheduleMailer, cfg.QuerySchedules, logger.Named("query_schedules"))
+	svc.querySchedules.SetWebhookSecret(svc.integrations.SecretSource(repository.IntegrationWebhook,
+		service.IntegrationCredentialScheduleWebhook, cfg.QuerySchedules.WebhookSecret))
+	svc.querySchedules.SetMaintenanceMode(svc.maintenance)
+	if cfg.QuerySchedules.Enabled {
+		svc.watchdog.Register("query_schedules", cfg.QuerySchedules.Timeout+cfg.QuerySchedules.PollInterval, svc.querySchedules.Run)
+	}
+
+	// 审批流程：写操作、策略变更等敏感动作经审批后执行
+	svc.approval = service.NewApprovalEngine(repo.ApprovalRepo(), cfg.Approval, logger)
+	svc.approval.Register(repository.ApprovalPolicyChange, service.NewPolicyChangeExecutor(repo.WorkspaceRepo(), logger))
+	svc.approval.Register(repository.ApprovalBudgetRaise, service.NewBudgetRaiseExecutor(repo.LLMUsageRepo(), svc.costs, logger))
+	lc.Append(Hook{
+		Name:    "approval",
+		OnStart: func(ctx context.Context) error { return svc.approval.Start() },
+		OnStop:  func(ctx context.Context) error { return svc.approval.Stop() },
+	})
+
+	svc.workspaceSettings = service.NewWorkspaceSettingsService(repo.WorkspaceRepo(), repo.ConnectionRepo(), cfg.WorkspaceSettings, logger)
+
+	// 实时事件：向工作空间成员推送在线状态与查询执行事件
+	svc.realtime = service.NewRealtimeHub(svc.workspaceSettings, cfg.Realtime, logger)
+	svc.sqlExecutor.SetRealtimeHub(svc.realtime)
+	svc.writeMode = service.NewWriteModeService(
+		repo.ConnectionRepo(), repo.WriteRequestRepo(), svc.approval, svc.sqlExecutor, svc.ai, logger)
+
+	// MCP工具服务
+	svc.mcp = mcp.NewServer(repo.ConnectionRepo(), repo.SchemaRepo(), svc.ai, svc.sqlExecutor, cfg.App.Version, logger)
+
+	// 邮件查询网关（未配置Webhook令牌时不启用）
+	if cfg.EmailGateway.Enabled {
+		svc.emailGateway = service.NewEmailGateway(svc.ai, svc.sqlExecutor, repo.ConnectionRepo(),
+			service.NewSMTPMailer(cfg.EmailGateway, smtpPassword), cfg.EmailGateway, logger)
+		lc.Append(Hook{
+			Name:    "email_gateway",
+			OnStart: func(ctx context.Context) error { return svc.emailGateway.Start() },
+			OnStop:  func(ctx context.Context) error { return svc.emailGateway.Stop() },
+		})
+	}
+
+	return svc, nil
+}
+
+// newRouterConfig 创建处理器并组装路由配置
+func newRouterConfig(cfg *Config, repo repository.Repository, svc *services, levels *logging.Levels, logger *zap.Logger) *handler.RouterConfig {
+	resultTables := service.NewResultTableRenderer(cfg.ResultTable)
+	columnLabels := service.NewColumnLabeler(repo.SchemaRepo(), logger)
+	calibration := service.NewCalibrationTracker(cfg.Calibration)
+	connectionUsage := service.NewConnectionUsageService(repo.QueryHistoryRepo(), cfg.ConnectionUsage, logger)
+
+	sqlHandler := handler.NewSQLHandler(repo.QueryHistoryRepo(), repo.ConnectionRepo(), svc.sqlExecutor, logger)
+	sqlHandler.SetResultTableRenderer(resultTables)
+	sqlHandler.SetColumnLabeler(columnLabels)
+	sqlHandler.SetClassificationService(svc.classification)
+	sqlHandler.SetWorkspaceSettings(svc.workspaceSettings)
+	sqlHandler.SetSchemaSnapshots(svc.schemaSnapshots)
+	sqlHandler.SetExportConfig(cfg.Export)
+	sqlHandler.SetResidencyService(svc.residency)
+	sqlHandler.SetRunningQueries(svc.runningQueries)
+	sqlHandler.SetQueryJobs(svc.queryJobs)
+	if svc.resultCache != nil {
+		sqlHandler.SetResultCache(svc.resultCache)
+	}
+	if guard := cfg.SQLGuardrails; guard.CostPrecheck {
+		sqlHandler.SetCostPrecheck(service.NewCostPrecheck(svc.sqlExecutor, service.CostThresholds{
+			WarnCost:     guard.WarnCost,
+			MaxCost:      guard.MaxCost,
+			WarnScanRows: guard.WarnScanRows,
+			MaxScanRows:  guard.MaxScanRows,
+		}, logger.Named(logging.ModuleSQL)))
+	}
+
+	aiHandler := handler.NewAIHandler(svc.ai, logger)
+	aiHandler.SetAutoExecutor(service.NewAutoExecuteService(
+		repo.WorkspaceRepo(), repo.ConnectionRepo(), repo.QueryHistoryRepo(), svc.sqlExecutor, logger))
+	aiHandler.SetConsensusService(service.NewConsensusService(
+		svc.ai, svc.sqlExecutor, repo.ConnectionRepo(), cfg.AI.Consensus, logger))
+	aiHandler.SetClassificationService(svc.classification)
+	aiHandler.SetWorkspaceSettings(svc.workspaceSettings)
+	aiHandler.SetSchemaSnapshots(svc.schemaSnapshots)
+	aiHandler.SetResultTableRenderer(resultTables)
+	aiHandler.SetColumnLabeler(columnLabels)
+	aiHandler.SetCalibrationTracker(calibration)
+	aiHandler.SetFeedbackService(svc.feedback)
+	aiHandler.SetCostTracker(svc.costs, cfg.AI.Budget.Enforce)
+	aiHandler.SetApprovalEngine(svc.approval)
+	if svc.llmArchive != nil {
+		aiHandler.SetLLMArchive(svc.llmArchive)
+	}
+
+	userHandler := handler.NewUserHandler(repo.UserRepo(), repo.QueryHistoryRepo(), repo.ConnectionRepo(), logger)
+	userHandler.SetRunningQueries(svc.runningQueries)
+
+	connectionHandler := handler.NewConnectionHandler(repo.ConnectionRepo(), repo.SchemaRepo(), svc.connectionManager, logger)
+	connectionHandler.SetSchemaWarmup(svc.schemaWarmup)
+
+	workspaceHandler := handler.NewWorkspaceHandler(repo.WorkspaceRepo(), logger)
+	workspaceHandler.SetApprovalEngine(svc.approval)
+	workspaceHandler.SetResidencyService(svc.residency)
+	workspaceHandler.SetWorkspaceSettings(svc.workspaceSettings)
+	workspaceHandler.SetOnboardingService(service.NewOnboardingService(repo.WorkspaceRepo(), repo.ConnectionRepo(),
+		repo.SchemaRepo(), repo.QueryHistoryRepo(), repo.FeedbackRepo(), logger))
+
+	// 多轮对话：已建立的连接在维护期间同样拒绝提问
+	chatHandler := handler.NewChatHandler(aiHandler, svc.chatSessions, logger)
+	chatHandler.SetMaintenanceMode(svc.maintenance)
+
+	// API密钥：CI任务与BI工具通过X-API-Key调用SQL与AI接口
+	apiKeys := service.NewAPIKeyService(repo.APIKeyRepo(), repo.UserRepo(), logger)
+	columnAccess := service.NewColumnAccessService(repo.ColumnAccessRepo(), repo.ClassificationRepo(), repo.ConnectionRepo(), logger)
+	// 表结构变更时间线：变更事件由表结构定期刷新记录，未开启刷新时只列出查询失败
+	schemaTimeline := service.NewSchemaTimelineService(repo.QueryHistoryRepo(), repo.SchemaChangeRepo(), repo.ConnectionRepo(),
+		cfg.SchemaRefresh, logger)
+
+	routerConfig := &handler.RouterConfig{
+		AuthHandler:           handler.NewAuthHandler(repo.UserRepo(), svc.jwt, logger),
+		UserHandler:           userHandler,
+		SQLHandler:            sqlHandler,
+		ConnectionHandler:     connectionHandler,
+		AIHandler:             aiHandler,
+		MCPHandler:            handler.NewMCPHandler(svc.mcp, logger),
+		EmbedHandler:          handler.NewEmbedHandler(svc.ai, svc.sqlExecutor, repo.ConnectionRepo(), svc.jwt, logger),
+		WorkspaceHandler:      workspaceHandler,
+		WriteModeHandler:      handler.NewWriteModeHandler(svc.writeMode, logger),
+		ApprovalHandler:       handler.NewApprovalHandler(svc.approval, logger),
+		ClassificationHandler: handler.NewClassificationHandler(svc.classification, logger),
+		ErasureHandler:        handler.NewErasureHandler(svc.erasure, logger),
+		FolderHandler:         handler.NewFolderHandler(svc.folders, logger),
+		ScheduleHandler:       handler.NewScheduleHandler(svc.querySchedules, logger),
+		RealtimeHandler:       handler.NewRealtimeHandler(svc.realtime, logger),
+		ChatHandler:           chatHandler,
+		AnalyticsHandler:      handler.NewAnalyticsHandler(calibration, connectionUsage, logger),
+		IntegrationHandler:    handler.NewIntegrationCredentialHandler(svc.integrations, logger),
+		MaintenanceHandler:    handler.NewMaintenanceHandler(svc.maintenance, logger),
+		APIKeyHandler:         handler.NewAPIKeyHandler(apiKeys, logger),
+		ColumnAccessHandler:   handler.NewColumnAccessHandler(columnAccess, logger),
+		MetricsHandler:        handler.NewMetricsHandler(svc.metricsSummary, logger),
+		SchemaTimelineHandler: handler.NewSchemaTimelineHandler(schemaTimeline, logger),
+		Maintenance:           svc.maintenance,
+		AuthMiddleware:        middleware.NewAuthMiddleware(svc.jwt, logger),
+		EmbedMiddleware:       middleware.NewEmbedAuthMiddleware(svc.jwt, logger),
+		APIKeyMiddleware:      middleware.NewAPIKeyAuthMiddleware(apiKeys, logger),
+		HealthService:         svc.health,
+		APIVersion:            cfg.APIVersion,
+	}
+
+	// Teams机器人集成（未配置签名密钥与机器人应用ID时不启用）；配置应用ID后按Bot Framework JWT认证并提供分页
+	if cfg.Teams.Enabled {
+		var botAuth handler.BotFrameworkVerifierInterface
+		if cfg.Teams.AppID != "" {
+			botAuth = auth.NewBotFrameworkVerifier(cfg.Teams.AppID, cfg.Teams.MetadataURL)
+		}
+		routerConfig.TeamsHandler = handler.NewTeamsHandler(svc.ai, svc.sqlExecutor, repo.ConnectionRepo(), repo.UserRepo(),
+			svc.teamsPages, botAuth,
+			svc.integrations.SecretSource(repository.IntegrationTeams, service.DefaultIntegrationCredentialName, cfg.Teams.SigningSecret),
+			cfg.Teams, logger)
+		logger.Info("Teams integration enabled",
+			zap.Int64("service_user_id", cfg.Teams.ServiceUserID),
+			zap.Bool("bot_framework_auth", botAuth != nil))
+	}
+	if levels != nil {
+		routerConfig.LogLevelHandler = handler.NewLogLevelHandler(levels, logger)
+	}
+	routerConfig.ReplayHandler = handler.NewReplayHandler(
+		service.NewQueryReplayService(repo.QueryHistoryRepo(), repo.SchemaSnapshotRepo(), svc.ai, logger), logger)
+	if db, ok := repo.(interface{ Available() bool }); ok {
+		routerConfig.DatabaseAvailable = db.Available
+		routerConfig.DatabaseRetryAfter = cfg.DatabaseFailover.BreakerOpenTimeout
+	}
+	if svc.telemetry != nil {
+		routerConfig.TelemetryHandler = handler.NewTelemetryHandler(svc.telemetry, logger)
+	}
+	if svc.llmArchive != nil {
+		routerConfig.LLMArchiveHandler = handler.NewLLMArchiveHandler(svc.llmArchive, logger)
+	}
+	if svc.emailGateway != nil {
+		routerConfig.EmailHandler = handler.NewEmailHandler(repo.UserRepo(), svc.emailGateway,
+			svc.integrations.SecretSource(repository.IntegrationWebhook, service.IntegrationCredentialEmailInbound, cfg.EmailGateway.WebhookToken),
+			cfg.EmailGateway, logger)
+	}
+
+	return routerConfig
+}
+
+// newRouter 创建Gin路由器并挂载中间件与全部路由
+func newRouter(cfg *Config, routerConfig *handler.RouterConfig, svc *services, logger *zap.Logger) *gin.Engine {
+	// gin的请求日志与panic输出直接写标准输出，同样需要脱敏
+	gin.DefaultWriter = logging.NewRedactingWriter(os.Stdout)
+	gin.DefaultErrorWriter = logging.NewRedactingWriter(os.Stderr)
+
+	r := gin.New()
+	middleware.SetupMiddleware(r, middleware.DefaultMiddlewareConfig(logger))
+	if cfg.Tracing.Enabled {
+		r.Use(tracing.Middleware())
+	}
+	r.Use(svc.prometheus.HTTPMetricsMiddleware())
+	if svc.telemetry != nil {
+		r.Use(svc.telemetry.Middleware())
+	}
+	r.Use(middleware.CompressionMiddleware(cfg.Compression))
+
+	handler.SetupRoutes(r, routerConfig)
+	r.GET("/metrics", svc.prometheus.GetMetricsHandler())
+
+	return r
+}
+
+// newProbeRouter 创建只提供健康检查与指标的轻量路由，除panic恢复外不挂载中间件，高负载时不受限流与并发上限影响
+func newProbeRouter(routerConfig *handler.RouterConfig, svc *services, logger *zap.Logger) *gin.Engine {
+	r := gin.New()
+	r.Use(middleware.RecoveryMiddleware(logger))
+	handler.SetupProbeRoutes(r, routerConfig)
+	r.GET("/metrics", svc.prometheus.GetMetricsHandler())
+	return r
+}
+
+// systemMetricsInterval 备用系统指标的采集间隔
+const systemMetricsInterval = 30 * time.Second
+
+// newSystemMetricsCollector 定期把内存与goroutine数写入Prometheus指标，作为SystemMonitor的备用
+func newSystemMetricsCollector(pm *metrics.PrometheusMetrics, logger *zap.Logger) watchdog.Worker {
+	return func(ctx context.Context, beat func()) {
+		ticker := time.NewTicker(systemMetricsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				var m runtime.MemStats
+				runtime.ReadMemStats(&m)
+				pm.UpdateSystemMetrics(int64(m.Alloc), runtime.NumGoroutine())
+
+				logger.Debug("System metrics updated",
+					zap.Uint64("memory_alloc", m.Alloc),
+					zap.Int("goroutines", runtime.NumGoroutine()))
+				beat()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
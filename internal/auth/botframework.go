@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// BotFrameworkIssuer Bot Framework服务签发的JWT的签发者
+	BotFrameworkIssuer = "https://api.botframework.com"
+
+	// botFrameworkKeysTTL 公钥缓存时间，Bot Framework按天轮换签名密钥
+	botFrameworkKeysTTL = 24 * time.Hour
+	// botFrameworkRefreshInterval 遇到未知kid时两次刷新公钥的最短间隔
+	botFrameworkRefreshInterval = time.Minute
+	// botFrameworkClockSkew 校验有效期时允许的时钟偏差
+	botFrameworkClockSkew = 5 * time.Minute
+)
+
+// ErrInvalidBotFrameworkToken Bot Framework请求的JWT无效
+var ErrInvalidBotFrameworkToken = errors.New("invalid bot framework token")
+
+// BotFrameworkClaims Bot Framework JWT中使用到的Claims
+type BotFrameworkClaims struct {
+	ServiceURL string `json:"serviceurl"`
+	jwt.RegisteredClaims
+}
+
+// BotFrameworkVerifier 校验Bot Framework发往机器人的请求携带的JWT
+// 公钥按OpenID元数据中的jwks_uri获取并缓存，签名、签发者、受众（机器人应用ID）与有效期均需通过
+type BotFrameworkVerifier struct {
+	appID       string
+	metadataURL string
+	client      *http.Client
+	now         func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	refreshedAt time.Time
+}
+
+// NewBotFrameworkVerifier 创建Bot Framework JWT校验器
+func NewBotFrameworkVerifier(appID, metadataURL string) *BotFrameworkVerifier {
+	return &BotFrameworkVerifier{
+		appID:       appID,
+		metadataURL: metadataURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		now:         time.Now,
+	}
+}
+
+// Verify 校验Authorization请求头中的Bearer令牌
+// serviceURL非空时还需与令牌的serviceurl声明一致，防止令牌被转用于其他频道
+func (v *BotFrameworkVerifier) Verify(ctx context.Context, authHeader, serviceURL string) error {
+	tokenString, ok := strings.CutPrefix(authHeader, "Bearer ")
+	if !ok || tokenString == "" {
+		return ErrInvalidBotFrameworkToken
+	}
+
+	claims := &BotFrameworkClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(BotFrameworkIssuer),
+		jwt.WithAudience(v.appID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(botFrameworkClockSkew),
+		jwt.WithTimeFunc(v.now),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBotFrameworkToken, err)
+	}
+	if serviceURL != "" && claims.ServiceURL != serviceURL {
+		return fmt.Errorf("%w: service url mismatch", ErrInvalidBotFrameworkToken)
+	}
+	return nil
+}
+
+// key 返回kid对应的公钥，缓存过期或遇到未知kid时重新获取
+func (v *BotFrameworkVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	if key, ok := v.keys[kid]; ok && now.Sub(v.fetchedAt) < botFrameworkKeysTTL {
+		return key, nil
+	}
+	// 未知kid可能是密钥刚轮换，限制刷新频率，避免伪造的kid反复触发请求
+	if v.keys != nil && now.Sub(v.fetchedAt) < botFrameworkKeysTTL && now.Sub(v.refreshedAt) < botFrameworkRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+
+	v.refreshedAt = now
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.keys, v.fetchedAt = keys, now
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys 按OpenID元数据获取JWKS中的RSA公钥
+func (v *BotFrameworkVerifier) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var metadata struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, v.metadataURL, &metadata); err != nil {
+		return nil, fmt.Errorf("获取Bot Framework元数据失败: %w", err)
+	}
+	if metadata.JWKSURI == "" {
+		return nil, errors.New("Bot Framework元数据缺少jwks_uri")
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, metadata.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("获取Bot Framework公钥失败: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
+
+// getJSON 发送GET请求并解析JSON响应
+func (v *BotFrameworkVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
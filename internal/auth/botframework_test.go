@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBotFrameworkTestServer 模拟Bot Framework的OpenID元数据与JWKS端点
+func newBotFrameworkTestServer(t *testing.T, key *rsa.PublicKey) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "key-1",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	return server
+}
+
+func signBotFrameworkToken(t *testing.T, key *rsa.PrivateKey, kid string, claims BotFrameworkClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return "Bearer " + signed
+}
+
+func TestBotFrameworkVerifier_Verify(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	server := newBotFrameworkTestServer(t, &privateKey.PublicKey)
+	verifier := NewBotFrameworkVerifier("bot-app-id", server.URL+"/metadata")
+	ctx := context.Background()
+
+	valid := BotFrameworkClaims{
+		ServiceURL: "https://smba.trafficmanager.net/amer/",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    BotFrameworkIssuer,
+			Audience:  jwt.ClaimStrings{"bot-app-id"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	header := signBotFrameworkToken(t, privateKey, "key-1", valid)
+	assert.NoError(t, verifier.Verify(ctx, header, "https://smba.trafficmanager.net/amer/"))
+	assert.ErrorIs(t, verifier.Verify(ctx, header, "https://attacker.example.com/"), ErrInvalidBotFrameworkToken, "serviceurl不一致")
+
+	wrongAudience := valid
+	wrongAudience.Audience = jwt.ClaimStrings{"another-bot"}
+	assert.ErrorIs(t, verifier.Verify(ctx, signBotFrameworkToken(t, privateKey, "key-1", wrongAudience), ""), ErrInvalidBotFrameworkToken)
+
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "https://sts.windows.net/tenant/"
+	assert.ErrorIs(t, verifier.Verify(ctx, signBotFrameworkToken(t, privateKey, "key-1", wrongIssuer), ""), ErrInvalidBotFrameworkToken)
+
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, verifier.Verify(ctx, signBotFrameworkToken(t, privateKey, "key-1", expired), ""), ErrInvalidBotFrameworkToken)
+
+	assert.ErrorIs(t, verifier.Verify(ctx, signBotFrameworkToken(t, otherKey, "key-1", valid), ""), ErrInvalidBotFrameworkToken, "签名密钥不匹配")
+	assert.ErrorIs(t, verifier.Verify(ctx, signBotFrameworkToken(t, otherKey, "key-2", valid), ""), ErrInvalidBotFrameworkToken, "未知kid")
+	assert.ErrorIs(t, verifier.Verify(ctx, "HMAC abc", ""), ErrInvalidBotFrameworkToken)
+}
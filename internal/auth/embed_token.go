@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// EmbedTokenType 嵌入令牌类型标识
+	EmbedTokenType = "embed"
+	// DefaultEmbedTokenTTL 嵌入令牌默认有效期
+	DefaultEmbedTokenTTL = 15 * time.Minute
+	// MaxEmbedTokenTTL 嵌入令牌最长有效期
+	MaxEmbedTokenTTL = time.Hour
+	// DefaultEmbedRateLimit 嵌入令牌默认每分钟请求数
+	DefaultEmbedRateLimit = 30
+	// MaxEmbedRateLimit 嵌入令牌每分钟请求数上限
+	MaxEmbedRateLimit = 300
+
+	// embedAudienceSuffix 嵌入令牌受众后缀，使其无法被当作普通Access Token使用
+	embedAudienceSuffix = ":embed"
+)
+
+// EmbedClaims 嵌入式组件令牌Claims
+// 令牌绑定单个数据库连接，只允许只读查询，并携带每分钟请求上限
+type EmbedClaims struct {
+	UserID       int64  `json:"uid"`
+	ConnectionID int64  `json:"cid"`
+	RateLimit    int    `json:"rpm"`
+	TokenType    string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+// Validate 实现ClaimsValidator接口的验证方法
+func (c EmbedClaims) Validate() error {
+	if c.UserID <= 0 {
+		return errors.New("invalid user ID")
+	}
+
+	if c.ConnectionID <= 0 {
+		return errors.New("invalid connection ID")
+	}
+
+	if c.RateLimit <= 0 || c.RateLimit > MaxEmbedRateLimit {
+		return errors.New("invalid rate limit")
+	}
+
+	if c.TokenType != EmbedTokenType {
+		return errors.New("invalid token type")
+	}
+
+	return nil
+}
+
+// EmbedToken 签发的嵌入令牌
+type EmbedToken struct {
+	Token        string    `json:"token"`
+	ConnectionID int64     `json:"connection_id"`
+	RateLimit    int       `json:"rate_limit_per_minute"`
+	ExpiresIn    int64     `json:"expires_in"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// GenerateEmbedToken 签发绑定到指定连接的短期嵌入令牌
+// ttl和rateLimit为零时使用默认值，超出上限时截断
+func (j *JWTService) GenerateEmbedToken(userID, connectionID int64, ttl time.Duration, rateLimit int) (*EmbedToken, error) {
+	if ttl <= 0 {
+		ttl = DefaultEmbedTokenTTL
+	}
+	ttl = min(ttl, MaxEmbedTokenTTL)
+
+	if rateLimit <= 0 {
+		rateLimit = DefaultEmbedRateLimit
+	}
+	rateLimit = min(rateLimit, MaxEmbedRateLimit)
+
+	now := time.Now()
+	claims := &EmbedClaims{
+		UserID:       userID,
+		ConnectionID: connectionID,
+		RateLimit:    rateLimit,
+		TokenType:    EmbedTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    j.issuer,
+			Subject:   fmt.Sprintf("user:%d", userID),
+			Audience:  jwt.ClaimStrings{j.audience + embedAudienceSuffix},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        generateJTI(),
+		},
+	}
+
+	if err := claims.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid embed token claims: %w", err)
+	}
+
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(j.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign embed token: %w", err)
+	}
+
+	return &EmbedToken{
+		Token:        tokenString,
+		ConnectionID: connectionID,
+		RateLimit:    rateLimit,
+		ExpiresIn:    int64(ttl.Seconds()),
+		ExpiresAt:    now.Add(ttl),
+	}, nil
+}
+
+// ValidateEmbedToken 验证嵌入令牌
+func (j *JWTService) ValidateEmbedToken(tokenString string) (*EmbedClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &EmbedClaims{}, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return j.publicKey, nil
+	}, jwt.WithAudience(j.audience+embedAudienceSuffix), jwt.WithIssuer(j.issuer))
+
+	if err != nil {
+		return nil, fmt.Errorf("embed token validation failed: %w", err)
+	}
+
+	claims, ok := token.Claims.(*EmbedClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("embed token is invalid")
+	}
+
+	return claims, nil
+}
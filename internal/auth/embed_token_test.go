@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newEmbedTestService(t *testing.T) *JWTService {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return &JWTService{
+		privateKey:      privateKey,
+		publicKey:       &privateKey.PublicKey,
+		issuer:          "test-issuer",
+		audience:        "test-audience",
+		accessTokenTTL:  time.Hour,
+		refreshTokenTTL: 24 * time.Hour,
+		logger:          zap.NewNop(),
+	}
+}
+
+func TestGenerateEmbedToken_RoundTrip(t *testing.T) {
+	service := newEmbedTestService(t)
+
+	token, err := service.GenerateEmbedToken(7, 3, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), token.ConnectionID)
+	assert.Equal(t, DefaultEmbedRateLimit, token.RateLimit)
+	assert.Equal(t, int64(DefaultEmbedTokenTTL.Seconds()), token.ExpiresIn)
+
+	claims, err := service.ValidateEmbedToken(token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), claims.UserID)
+	assert.Equal(t, int64(3), claims.ConnectionID)
+	assert.Equal(t, EmbedTokenType, claims.TokenType)
+}
+
+func TestGenerateEmbedToken_ClampsLimits(t *testing.T) {
+	service := newEmbedTestService(t)
+
+	token, err := service.GenerateEmbedToken(7, 3, 24*time.Hour, 10000)
+	require.NoError(t, err)
+	assert.Equal(t, int64(MaxEmbedTokenTTL.Seconds()), token.ExpiresIn)
+	assert.Equal(t, MaxEmbedRateLimit, token.RateLimit)
+
+	_, err = service.GenerateEmbedToken(7, 0, 0, 0)
+	assert.Error(t, err)
+}
+
+func TestEmbedToken_NotInterchangeableWithAccessToken(t *testing.T) {
+	service := newEmbedTestService(t)
+
+	embed, err := service.GenerateEmbedToken(7, 3, 0, 0)
+	require.NoError(t, err)
+	_, err = service.ValidateAccessToken(embed.Token)
+	assert.Error(t, err, "嵌入令牌不能作为Access Token使用")
+
+	pair, err := service.GenerateTokenPair(7, "alice", "user")
+	require.NoError(t, err)
+	_, err = service.ValidateEmbedToken(pair.AccessToken)
+	assert.Error(t, err, "Access Token不能作为嵌入令牌使用")
+}
@@ -272,7 +272,7 @@ func (j *JWTService) loadOrGenerateKeys(config *JWTConfig) error {
 			j.logger.Warn("Failed to load keys from file, generating new keys", zap.Error(err))
 		} else {
 			j.logger.Info("Successfully loaded RSA keys from files",
-				zap.String("private_key", config.PrivateKeyPath),
+				zap.String("private_key_path", config.PrivateKeyPath),
 				zap.String("public_key", config.PublicKeyPath))
 			return nil
 		}
@@ -356,7 +356,7 @@ func (j *JWTService) SaveKeysToFile(privateKeyPath, publicKeyPath string) error
 	}
 	
 	j.logger.Info("JWT keys saved to files",
-		zap.String("private_key", privateKeyPath),
+		zap.String("private_key_path", privateKeyPath),
 		zap.String("public_key", publicKeyPath))
 	
 	return nil
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap"
@@ -19,6 +20,18 @@ type AIConfig struct {
 	
 	// 成本控制
 	Budget BudgetConfig `yaml:"budget"`
+	
+	// 置信度低于该阈值的SQL需要用户确认后执行（0-1）
+	ConfirmationThreshold float64 `yaml:"confirmation_threshold"`
+	
+	// 多候选生成时单次请求允许的最大候选数
+	MaxCandidates int `yaml:"max_candidates"`
+	
+	// 关键查询的自洽性投票
+	Consensus ConsensusConfig `yaml:"consensus"`
+	
+	// 返回生成的SQL前统一排版：关键字大写，按子句换行并缩进
+	FormatSQL bool `yaml:"format_sql"`
 }
 
 // ModelConfig 单个模型配置
@@ -30,6 +43,8 @@ type ModelConfig struct {
 	MaxTokens   int           `yaml:"max_tokens"`
 	TopP        float64       `yaml:"top_p"`
 	Timeout     time.Duration `yaml:"timeout"`
+	RulesFile   string        `yaml:"rules_file"` // 仅mock提供商使用
+	Seed        int           `yaml:"seed"`       // 采样种子，非0时随请求传递给模型，用于可复现的生成
 }
 
 // BudgetConfig 预算配置
@@ -37,6 +52,15 @@ type BudgetConfig struct {
 	DailyLimit     float64 `yaml:"daily_limit"`     // 每日预算上限（美元）
 	UserLimit      float64 `yaml:"user_limit"`      // 每用户限制
 	AlertThreshold float64 `yaml:"alert_threshold"` // 告警阈值
+	Enforce        bool    `yaml:"enforce"`         // 达到上限后拒绝新的模型调用，关闭时只告警
+}
+
+// ConsensusConfig 自洽性投票配置
+// 关键查询生成Samples条候选，执行得分最高的两条不同SQL，结果一致才返回
+type ConsensusConfig struct {
+	Samples int           `yaml:"samples"`  // 采样候选数
+	MaxRows int           `yaml:"max_rows"` // 比较执行的最大行数，超出视为无法判定
+	Timeout time.Duration `yaml:"timeout"`  // 单条候选的执行超时
 }
 
 // DefaultAIConfig 创建默认AI配置
@@ -64,10 +88,69 @@ func DefaultAIConfig() *AIConfig {
 			DailyLimit:     100.0, // $100 per day
 			UserLimit:      10.0,  // $10 per user per day
 			AlertThreshold: 0.8,   // 80% of limit
+			Enforce:        true,
+		},
+		ConfirmationThreshold: 0.6,
+		MaxCandidates:         5,
+		Consensus: ConsensusConfig{
+			Samples: 3,
+			MaxRows: 1000,
+			Timeout: 10 * time.Second,
 		},
+		FormatSQL: true,
+	}
+}
+
+// DemoAIConfig 创建演示模式配置，主备模型均使用确定性mock提供商
+// rulesFile为空时使用内置的ecommerce规则
+func DemoAIConfig(rulesFile string) *AIConfig {
+	config := DefaultAIConfig()
+	for _, model := range []*ModelConfig{&config.Primary, &config.Fallback} {
+		model.Provider = "mock"
+		model.ModelName = "mock-sql"
+		model.APIKey = ""
+		model.RulesFile = rulesFile
+	}
+	return config
+}
+
+// ApplyDeterministicProfile 为Ollama模型应用确定性生成配置：温度为0并固定采样种子
+// 同一提示词在同一模型版本上多次生成的结果一致，用于演示与测试；云端模型不保证按种子复现，保持原配置
+func (c *AIConfig) ApplyDeterministicProfile(seed int) {
+	for _, model := range []*ModelConfig{&c.Primary, &c.Fallback} {
+		if model.Provider != "ollama" {
+			continue
+		}
+		model.Temperature = 0
+		model.Seed = seed
 	}
 }
 
+// ApplyBudgetEnv 用环境变量覆盖预算配置：TOTAL_DAILY_BUDGET为全局每日上限，
+// DAILY_BUDGET_PER_USER为每用户每日上限，AI_BUDGET_ENFORCE=false时超限只告警不拒绝
+func (c *AIConfig) ApplyBudgetEnv() error {
+	if v := os.Getenv("TOTAL_DAILY_BUDGET"); v != "" {
+		limit, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid TOTAL_DAILY_BUDGET: %w", err)
+		}
+		c.Budget.DailyLimit = limit
+	}
+
+	if v := os.Getenv("DAILY_BUDGET_PER_USER"); v != "" {
+		limit, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid DAILY_BUDGET_PER_USER: %w", err)
+		}
+		c.Budget.UserLimit = limit
+	}
+
+	if v := os.Getenv("AI_BUDGET_ENFORCE"); v != "" {
+		c.Budget.Enforce = v == "true"
+	}
+	return nil
+}
+
 // LoadAIConfigFromEnv 从环境变量加载AI配置
 func LoadAIConfigFromEnv() (*AIConfig, error) {
 	config := DefaultAIConfig()
@@ -94,6 +177,42 @@ func LoadAIConfigFromEnv() (*AIConfig, error) {
 		config.Fallback.ModelName = fallbackModel
 	}
 	
+	if threshold := os.Getenv("AI_CONFIRMATION_THRESHOLD"); threshold != "" {
+		if value, err := strconv.ParseFloat(threshold, 64); err == nil {
+			config.ConfirmationThreshold = value
+		}
+	}
+	
+	if maxCandidates := os.Getenv("AI_MAX_CANDIDATES"); maxCandidates != "" {
+		if value, err := strconv.Atoi(maxCandidates); err == nil {
+			config.MaxCandidates = value
+		}
+	}
+	
+	if samples := os.Getenv("AI_CONSENSUS_SAMPLES"); samples != "" {
+		if value, err := strconv.Atoi(samples); err == nil {
+			config.Consensus.Samples = value
+		}
+	}
+	
+	if maxRows := os.Getenv("AI_CONSENSUS_MAX_ROWS"); maxRows != "" {
+		if value, err := strconv.Atoi(maxRows); err == nil {
+			config.Consensus.MaxRows = value
+		}
+	}
+	
+	if timeout := os.Getenv("AI_CONSENSUS_TIMEOUT"); timeout != "" {
+		if duration, err := time.ParseDuration(timeout); err == nil {
+			config.Consensus.Timeout = duration
+		}
+	}
+	
+	if format := os.Getenv("AI_FORMAT_SQL"); format != "" {
+		if value, err := strconv.ParseBool(format); err == nil {
+			config.FormatSQL = value
+		}
+	}
+	
 	// 性能配置
 	if llmTimeout := os.Getenv("LLM_TIMEOUT"); llmTimeout != "" {
 		if duration, err := time.ParseDuration(llmTimeout); err == nil {
@@ -140,6 +259,22 @@ func (c *AIConfig) Validate() error {
 		return fmt.Errorf("alert_threshold must be between 0 and 1, got: %.2f", c.Budget.AlertThreshold)
 	}
 	
+	if c.ConfirmationThreshold < 0 || c.ConfirmationThreshold > 1 {
+		return fmt.Errorf("confirmation_threshold must be between 0 and 1, got: %.2f", c.ConfirmationThreshold)
+	}
+	
+	if c.MaxCandidates < 0 || c.MaxCandidates > 10 {
+		return fmt.Errorf("max_candidates must be between 0 and 10, got: %d", c.MaxCandidates)
+	}
+	
+	if c.Consensus.Samples != 0 && (c.Consensus.Samples < 2 || c.Consensus.Samples > c.MaxCandidates) {
+		return fmt.Errorf("consensus samples must be between 2 and max_candidates(%d), got: %d", c.MaxCandidates, c.Consensus.Samples)
+	}
+	
+	if c.Consensus.MaxRows < 0 || c.Consensus.Timeout < 0 {
+		return fmt.Errorf("consensus max_rows and timeout must not be negative")
+	}
+	
 	return nil
 }
 
@@ -153,7 +288,8 @@ func (mc *ModelConfig) validate() error {
 		return fmt.Errorf("model_name cannot be empty")
 	}
 	
-	if mc.APIKey == "" {
+	// 本地Ollama与mock提供商不需要API密钥
+	if mc.APIKey == "" && mc.Provider != "mock" && mc.Provider != "ollama" {
 		return fmt.Errorf("api_key cannot be empty")
 	}
 	
@@ -192,6 +328,7 @@ func (c *AIConfig) LogConfig(logger *zap.Logger) {
 		zap.Float64("daily_budget_limit", c.Budget.DailyLimit),
 		zap.Float64("user_budget_limit", c.Budget.UserLimit),
 		zap.Float64("alert_threshold", c.Budget.AlertThreshold),
+		zap.Bool("budget_enforce", c.Budget.Enforce),
 	)
 }
 
@@ -209,6 +346,11 @@ func GetModelCosts() map[string]map[string]float64 {
 			"claude-3-sonnet-20240229": 0.012,
 			"claude-3-haiku-20240307":  0.0008,
 		},
+		"gemini": {
+			"gemini-1.5-pro":   0.003,
+			"gemini-1.5-flash": 0.0002,
+			"gemini-2.0-flash": 0.00025,
+		},
 	}
 }
 
@@ -92,6 +92,7 @@ func TestLoadAIConfigFromEnv(t *testing.T) {
 	os.Setenv("PRIMARY_MODEL_NAME", "gpt-4")
 	os.Setenv("FALLBACK_MODEL_NAME", "claude-3-sonnet")
 	os.Setenv("LLM_TIMEOUT", "45s")
+	t.Setenv("AI_FORMAT_SQL", "false")
 	defer func() {
 		os.Unsetenv("OPENAI_API_KEY")
 		os.Unsetenv("ANTHROPIC_API_KEY")
@@ -110,6 +111,23 @@ func TestLoadAIConfigFromEnv(t *testing.T) {
 	assert.Equal(t, 45*time.Second, aiConfig.Timeout)
 	assert.Equal(t, 45*time.Second, aiConfig.Primary.Timeout)
 	assert.Equal(t, 45*time.Second, aiConfig.Fallback.Timeout)
+	assert.False(t, aiConfig.FormatSQL)
+}
+
+func TestAIConfigApplyBudgetEnv(t *testing.T) {
+	t.Setenv("TOTAL_DAILY_BUDGET", "250")
+	t.Setenv("DAILY_BUDGET_PER_USER", "2.5")
+	t.Setenv("AI_BUDGET_ENFORCE", "false")
+
+	aiConfig := DefaultAIConfig()
+	assert.True(t, aiConfig.Budget.Enforce)
+	require.NoError(t, aiConfig.ApplyBudgetEnv())
+	assert.Equal(t, 250.0, aiConfig.Budget.DailyLimit)
+	assert.Equal(t, 2.5, aiConfig.Budget.UserLimit)
+	assert.False(t, aiConfig.Budget.Enforce)
+
+	t.Setenv("DAILY_BUDGET_PER_USER", "ten")
+	assert.Error(t, aiConfig.ApplyBudgetEnv())
 }
 
 func TestLoadAIConfigFromEnvMissingKeys(t *testing.T) {
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// APIVersionConfig API版本配置
+// v2为当前版本；v1在过渡期内继续可用，响应携带Deprecation与Sunset头，超过停止时间后返回410
+type APIVersionConfig struct {
+	V1Deprecated   bool      `yaml:"v1_deprecated"`    // 是否在v1响应中标记弃用
+	V1DeprecatedAt time.Time `yaml:"v1_deprecated_at"` // v1宣布弃用的时间，零值时Deprecation头仅标记为true
+	V1SunsetAt     time.Time `yaml:"v1_sunset_at"`     // v1停止服务的时间，零值表示尚未确定
+}
+
+// DefaultAPIVersionConfig 返回默认API版本配置
+func DefaultAPIVersionConfig() *APIVersionConfig {
+	return &APIVersionConfig{
+		V1Deprecated: true,
+	}
+}
+
+// LoadAPIVersionConfigFromEnv 从环境变量加载API版本配置
+// 时间支持RFC3339或2006-01-02格式
+func LoadAPIVersionConfigFromEnv() (*APIVersionConfig, error) {
+	config := DefaultAPIVersionConfig()
+
+	if v := os.Getenv("API_V1_DEPRECATED"); v != "" {
+		config.V1Deprecated = v == "true"
+	}
+
+	times := []struct {
+		env    string
+		target *time.Time
+	}{
+		{"API_V1_DEPRECATED_AT", &config.V1DeprecatedAt},
+		{"API_V1_SUNSET_AT", &config.V1SunsetAt},
+	}
+	for _, t := range times {
+		if v := os.Getenv(t.env); v != "" {
+			parsed, err := parseAPIVersionTime(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", t.env, err)
+			}
+			*t.target = parsed
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证API版本配置的有效性
+func (c *APIVersionConfig) Validate() error {
+	if !c.V1Deprecated && (!c.V1DeprecatedAt.IsZero() || !c.V1SunsetAt.IsZero()) {
+		return fmt.Errorf("v1 deprecation dates require v1 to be marked deprecated")
+	}
+	if !c.V1DeprecatedAt.IsZero() && !c.V1SunsetAt.IsZero() && !c.V1SunsetAt.After(c.V1DeprecatedAt) {
+		return fmt.Errorf("v1 sunset (%s) must be after deprecation (%s)",
+			c.V1SunsetAt.Format(time.RFC3339), c.V1DeprecatedAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// parseAPIVersionTime 解析RFC3339时间或日期
+func parseAPIVersionTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAPIVersionConfigFromEnv(t *testing.T) {
+	cfg, err := LoadAPIVersionConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.V1Deprecated)
+	assert.True(t, cfg.V1SunsetAt.IsZero())
+
+	t.Setenv("API_V1_DEPRECATED_AT", "2026-01-01")
+	t.Setenv("API_V1_SUNSET_AT", "2026-07-01T00:00:00Z")
+	cfg, err = LoadAPIVersionConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), cfg.V1DeprecatedAt)
+	assert.Equal(t, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), cfg.V1SunsetAt)
+
+	// 停止时间必须晚于弃用时间
+	t.Setenv("API_V1_SUNSET_AT", "2025-12-01")
+	_, err = LoadAPIVersionConfigFromEnv()
+	assert.Error(t, err)
+
+	// 未标记弃用时不能设置停止时间
+	t.Setenv("API_V1_DEPRECATED", "false")
+	t.Setenv("API_V1_SUNSET_AT", "2026-07-01")
+	_, err = LoadAPIVersionConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("API_V1_SUNSET_AT", "next summer")
+	_, err = LoadAPIVersionConfigFromEnv()
+	assert.ErrorContains(t, err, "invalid API_V1_SUNSET_AT")
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 可配置审批的动作类型
+var approvalActionTypes = []string{"write_query", "policy_change", "budget_raise"}
+
+// ApprovalConfig 审批流程配置
+type ApprovalConfig struct {
+	TTL           time.Duration `yaml:"ttl"`            // 审批申请有效期，过期后自动失效
+	SweepInterval time.Duration `yaml:"sweep_interval"` // 过期申请扫描间隔
+
+	// RequiredActions 需要审批的动作类型；写操作与预算上调无论是否配置都需要审批
+	RequiredActions []string `yaml:"required_actions"`
+}
+
+// DefaultApprovalConfig 返回默认审批配置
+func DefaultApprovalConfig() *ApprovalConfig {
+	return &ApprovalConfig{
+		TTL:             72 * time.Hour,
+		SweepInterval:   5 * time.Minute,
+		RequiredActions: slices.Clone(approvalActionTypes),
+	}
+}
+
+// LoadApprovalConfigFromEnv 从环境变量加载审批配置
+func LoadApprovalConfigFromEnv() (*ApprovalConfig, error) {
+	config := DefaultApprovalConfig()
+
+	if v := os.Getenv("APPROVAL_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid APPROVAL_TTL: %w", err)
+		}
+		config.TTL = ttl
+	}
+
+	if v := os.Getenv("APPROVAL_SWEEP_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid APPROVAL_SWEEP_INTERVAL: %w", err)
+		}
+		config.SweepInterval = interval
+	}
+
+	if v, ok := os.LookupEnv("APPROVAL_REQUIRED_ACTIONS"); ok {
+		config.RequiredActions = nil
+		for _, action := range strings.Split(v, ",") {
+			if action = strings.TrimSpace(action); action != "" {
+				config.RequiredActions = append(config.RequiredActions, action)
+			}
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证审批配置的有效性
+func (c *ApprovalConfig) Validate() error {
+	if c.TTL <= 0 {
+		return fmt.Errorf("approval ttl must be positive, got: %v", c.TTL)
+	}
+	if c.SweepInterval <= 0 {
+		return fmt.Errorf("approval sweep interval must be positive, got: %v", c.SweepInterval)
+	}
+	for _, action := range c.RequiredActions {
+		if !slices.Contains(approvalActionTypes, action) {
+			return fmt.Errorf("unknown approval action type: %s", action)
+		}
+	}
+	return nil
+}
+
+// Requires 动作是否需要审批，写操作与预算上调始终需要审批
+func (c *ApprovalConfig) Requires(actionType string) bool {
+	return actionType == "write_query" || actionType == "budget_raise" || slices.Contains(c.RequiredActions, actionType)
+}
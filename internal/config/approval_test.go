@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadApprovalConfigFromEnv(t *testing.T) {
+	t.Setenv("APPROVAL_TTL", "24h")
+	t.Setenv("APPROVAL_REQUIRED_ACTIONS", "write_query")
+
+	cfg, err := LoadApprovalConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, cfg.TTL)
+	assert.Equal(t, 5*time.Minute, cfg.SweepInterval)
+
+	// 写操作与预算上调始终需要审批
+	assert.True(t, cfg.Requires("write_query"))
+	assert.True(t, cfg.Requires("budget_raise"))
+	assert.False(t, cfg.Requires("policy_change"))
+}
+
+func TestApprovalConfigValidation(t *testing.T) {
+	cfg := DefaultApprovalConfig()
+	require.NoError(t, cfg.Validate())
+
+	cfg.RequiredActions = append(cfg.RequiredActions, "drop_table")
+	assert.Error(t, cfg.Validate())
+
+	cfg = DefaultApprovalConfig()
+	cfg.TTL = 0
+	assert.Error(t, cfg.Validate())
+}
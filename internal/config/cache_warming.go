@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// CacheWarmingConfig 仪表盘查询缓存预热配置
+// 标记为仪表盘使用的保存查询在结果缓存过期前重新执行并写入缓存，需要同时开启结果缓存
+type CacheWarmingConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	CheckInterval time.Duration `yaml:"check_interval"` // 检查哪些查询到期需要预热的间隔
+	RefreshRatio  float64       `yaml:"refresh_ratio"`  // 缓存时间经过该比例后预热，需小于1以便在过期前完成
+	AlertAfter    int           `yaml:"alert_after"`    // 连续预热失败达到该次数时记录告警
+}
+
+// DefaultCacheWarmingConfig 返回默认缓存预热配置（默认关闭）
+func DefaultCacheWarmingConfig() *CacheWarmingConfig {
+	return &CacheWarmingConfig{
+		Enabled:       false,
+		CheckInterval: 15 * time.Second,
+		RefreshRatio:  0.8,
+		AlertAfter:    3,
+	}
+}
+
+// LoadCacheWarmingConfigFromEnv 从环境变量加载缓存预热配置
+func LoadCacheWarmingConfigFromEnv() (*CacheWarmingConfig, error) {
+	config := DefaultCacheWarmingConfig()
+
+	if v := os.Getenv("CACHE_WARMING_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+
+	if v := os.Getenv("CACHE_WARMING_CHECK_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CACHE_WARMING_CHECK_INTERVAL: %w", err)
+		}
+		config.CheckInterval = d
+	}
+
+	if v := os.Getenv("CACHE_WARMING_REFRESH_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CACHE_WARMING_REFRESH_RATIO: %w", err)
+		}
+		config.RefreshRatio = ratio
+	}
+
+	if v := os.Getenv("CACHE_WARMING_ALERT_AFTER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CACHE_WARMING_ALERT_AFTER: %w", err)
+		}
+		config.AlertAfter = n
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证缓存预热配置的有效性
+func (c *CacheWarmingConfig) Validate() error {
+	if c.CheckInterval < time.Second {
+		return fmt.Errorf("cache warming check interval must be at least 1s, got: %s", c.CheckInterval)
+	}
+	if c.RefreshRatio < 0.1 || c.RefreshRatio >= 1 {
+		return fmt.Errorf("cache warming refresh ratio must be in [0.1, 1), got: %g", c.RefreshRatio)
+	}
+	if c.AlertAfter <= 0 {
+		return fmt.Errorf("cache warming alert after must be positive, got: %d", c.AlertAfter)
+	}
+	return nil
+}
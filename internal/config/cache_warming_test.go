@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCacheWarmingConfigFromEnv(t *testing.T) {
+	cfg, err := LoadCacheWarmingConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, 15*time.Second, cfg.CheckInterval)
+	assert.Equal(t, 0.8, cfg.RefreshRatio)
+	assert.Equal(t, 3, cfg.AlertAfter)
+
+	t.Setenv("CACHE_WARMING_ENABLED", "true")
+	t.Setenv("CACHE_WARMING_CHECK_INTERVAL", "5s")
+	t.Setenv("CACHE_WARMING_REFRESH_RATIO", "0.75")
+	t.Setenv("CACHE_WARMING_ALERT_AFTER", "5")
+	cfg, err = LoadCacheWarmingConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 5*time.Second, cfg.CheckInterval)
+	assert.Equal(t, 0.75, cfg.RefreshRatio)
+	assert.Equal(t, 5, cfg.AlertAfter)
+
+	t.Setenv("CACHE_WARMING_REFRESH_RATIO", "1")
+	_, err = LoadCacheWarmingConfigFromEnv()
+	assert.Error(t, err, "缓存过期时才预热会出现未命中")
+
+	t.Setenv("CACHE_WARMING_REFRESH_RATIO", "0.75")
+	t.Setenv("CACHE_WARMING_ALERT_AFTER", "0")
+	_, err = LoadCacheWarmingConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// MaxCalibrationBuckets 置信度分桶数上限
+const MaxCalibrationBuckets = 50
+
+// CalibrationConfig 置信度校准统计配置
+// 生成SQL时记录分类器与生成置信度，用户反馈到达后按查询ID配对为样本；
+// 超过PendingTTL仍未收到反馈的预测被丢弃，每类置信度最多保留MaxSamples个最近样本
+type CalibrationConfig struct {
+	Buckets    int           `yaml:"buckets"`     // 默认的置信度分桶数，请求可单独指定
+	MaxSamples int           `yaml:"max_samples"` // 每类置信度最多保留的样本数，同时限制等待反馈的预测数
+	PendingTTL time.Duration `yaml:"pending_ttl"` // 预测等待反馈的最长时间
+}
+
+// DefaultCalibrationConfig 返回默认置信度校准统计配置
+func DefaultCalibrationConfig() *CalibrationConfig {
+	return &CalibrationConfig{
+		Buckets:    10,
+		MaxSamples: 5000,
+		PendingTTL: 24 * time.Hour,
+	}
+}
+
+// LoadCalibrationConfigFromEnv 从环境变量加载置信度校准统计配置
+func LoadCalibrationConfigFromEnv() (*CalibrationConfig, error) {
+	config := DefaultCalibrationConfig()
+
+	if v := os.Getenv("CALIBRATION_BUCKETS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CALIBRATION_BUCKETS: %w", err)
+		}
+		config.Buckets = n
+	}
+
+	if v := os.Getenv("CALIBRATION_MAX_SAMPLES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CALIBRATION_MAX_SAMPLES: %w", err)
+		}
+		config.MaxSamples = n
+	}
+
+	if v := os.Getenv("CALIBRATION_PENDING_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CALIBRATION_PENDING_TTL: %w", err)
+		}
+		config.PendingTTL = ttl
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证置信度校准统计配置的有效性
+func (c *CalibrationConfig) Validate() error {
+	if c.Buckets < 2 || c.Buckets > MaxCalibrationBuckets {
+		return fmt.Errorf("calibration buckets must be between 2 and %d, got: %d", MaxCalibrationBuckets, c.Buckets)
+	}
+	if c.MaxSamples <= 0 {
+		return fmt.Errorf("calibration max samples must be positive, got: %d", c.MaxSamples)
+	}
+	if c.PendingTTL < time.Minute {
+		return fmt.Errorf("calibration pending ttl must be at least 1m, got: %v", c.PendingTTL)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCalibrationConfigFromEnv(t *testing.T) {
+	cfg, err := LoadCalibrationConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.Buckets)
+	assert.Equal(t, 5000, cfg.MaxSamples)
+	assert.Equal(t, 24*time.Hour, cfg.PendingTTL)
+
+	t.Setenv("CALIBRATION_BUCKETS", "20")
+	t.Setenv("CALIBRATION_MAX_SAMPLES", "1000")
+	t.Setenv("CALIBRATION_PENDING_TTL", "72h")
+	cfg, err = LoadCalibrationConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 20, cfg.Buckets)
+	assert.Equal(t, 1000, cfg.MaxSamples)
+	assert.Equal(t, 72*time.Hour, cfg.PendingTTL)
+
+	t.Setenv("CALIBRATION_BUCKETS", "1")
+	_, err = LoadCalibrationConfigFromEnv()
+	assert.Error(t, err, "分桶数过少")
+
+	t.Setenv("CALIBRATION_BUCKETS", "10")
+	t.Setenv("CALIBRATION_PENDING_TTL", "10s")
+	_, err = LoadCalibrationConfigFromEnv()
+	assert.Error(t, err, "等待时间过短")
+}
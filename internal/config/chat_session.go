@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ChatSessionConfig 多轮对话会话配置
+// 会话在服务端保存之前的问题、生成的SQL与所选连接，追问时一并交给模型；会话只保存在内存中，重启后丢失
+type ChatSessionConfig struct {
+	MaxTurns           int           `yaml:"max_turns"`             // 每个会话保留的最近轮数，更早的轮次不再作为上下文
+	MaxSessionsPerUser int           `yaml:"max_sessions_per_user"` // 每个用户同时保留的会话数，超出时淘汰最久未活动的会话
+	IdleTimeout        time.Duration `yaml:"idle_timeout"`          // 会话空闲超过该时间后清理
+	TurnTimeout        time.Duration `yaml:"turn_timeout"`          // 单轮生成与自动执行的超时时间
+	WriteTimeout       time.Duration `yaml:"write_timeout"`         // 向WebSocket客户端写入单条消息的超时时间
+	PingInterval       time.Duration `yaml:"ping_interval"`         // 空闲时发送心跳消息的间隔
+}
+
+// DefaultChatSessionConfig 返回默认多轮对话会话配置
+func DefaultChatSessionConfig() *ChatSessionConfig {
+	return &ChatSessionConfig{
+		MaxTurns:           10,
+		MaxSessionsPerUser: 5,
+		IdleTimeout:        30 * time.Minute,
+		TurnTimeout:        60 * time.Second,
+		WriteTimeout:       10 * time.Second,
+		PingInterval:       30 * time.Second,
+	}
+}
+
+// LoadChatSessionConfigFromEnv 从环境变量加载多轮对话会话配置
+func LoadChatSessionConfigFromEnv() (*ChatSessionConfig, error) {
+	config := DefaultChatSessionConfig()
+
+	if v := os.Getenv("CHAT_SESSION_MAX_TURNS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHAT_SESSION_MAX_TURNS: %w", err)
+		}
+		config.MaxTurns = n
+	}
+
+	if v := os.Getenv("CHAT_SESSION_MAX_PER_USER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHAT_SESSION_MAX_PER_USER: %w", err)
+		}
+		config.MaxSessionsPerUser = n
+	}
+
+	if v := os.Getenv("CHAT_SESSION_IDLE_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHAT_SESSION_IDLE_TIMEOUT: %w", err)
+		}
+		config.IdleTimeout = timeout
+	}
+
+	if v := os.Getenv("CHAT_SESSION_TURN_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHAT_SESSION_TURN_TIMEOUT: %w", err)
+		}
+		config.TurnTimeout = timeout
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证多轮对话会话配置的有效性
+func (c *ChatSessionConfig) Validate() error {
+	if c.MaxTurns <= 0 {
+		return fmt.Errorf("chat session max turns must be positive, got: %d", c.MaxTurns)
+	}
+	if c.MaxSessionsPerUser <= 0 {
+		return fmt.Errorf("chat session max sessions per user must be positive, got: %d", c.MaxSessionsPerUser)
+	}
+	if c.IdleTimeout < time.Minute {
+		return fmt.Errorf("chat session idle timeout must be at least 1m, got: %v", c.IdleTimeout)
+	}
+	if c.TurnTimeout <= 0 || c.WriteTimeout <= 0 || c.PingInterval <= 0 {
+		return fmt.Errorf("chat session turn timeout, write timeout and ping interval must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadChatSessionConfigFromEnv(t *testing.T) {
+	cfg, err := LoadChatSessionConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.MaxTurns)
+	assert.Equal(t, 30*time.Minute, cfg.IdleTimeout)
+
+	t.Setenv("CHAT_SESSION_MAX_TURNS", "4")
+	t.Setenv("CHAT_SESSION_MAX_PER_USER", "2")
+	t.Setenv("CHAT_SESSION_IDLE_TIMEOUT", "10m")
+	t.Setenv("CHAT_SESSION_TURN_TIMEOUT", "90s")
+	cfg, err = LoadChatSessionConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 4, cfg.MaxTurns)
+	assert.Equal(t, 2, cfg.MaxSessionsPerUser)
+	assert.Equal(t, 10*time.Minute, cfg.IdleTimeout)
+	assert.Equal(t, 90*time.Second, cfg.TurnTimeout)
+
+	t.Setenv("CHAT_SESSION_IDLE_TIMEOUT", "10s")
+	_, err = LoadChatSessionConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CompressionConfig HTTP响应压缩配置
+type CompressionConfig struct {
+	Enabled      bool     `yaml:"enabled"`       // 是否启用响应压缩
+	MinSize      int      `yaml:"min_size"`      // 响应体达到该字节数才压缩，过小的响应压缩后反而更大
+	Level        int      `yaml:"level"`         // gzip压缩级别，1最快，9压缩率最高
+	ContentTypes []string `yaml:"content_types"` // 可压缩的内容类型；导出的xlsx、zip等已压缩格式不在其中
+}
+
+// DefaultCompressionConfig 返回默认响应压缩配置
+func DefaultCompressionConfig() *CompressionConfig {
+	return &CompressionConfig{
+		Enabled: true,
+		MinSize: 1024,
+		Level:   gzip.DefaultCompression,
+		ContentTypes: []string{
+			"application/json",
+			"application/problem+json",
+			"application/javascript",
+			"application/xml",
+			"text/plain",
+			"text/csv",
+			"text/html",
+			"text/css",
+			"image/svg+xml",
+		},
+	}
+}
+
+// LoadCompressionConfigFromEnv 从环境变量加载响应压缩配置
+func LoadCompressionConfigFromEnv() (*CompressionConfig, error) {
+	config := DefaultCompressionConfig()
+
+	if v := os.Getenv("COMPRESSION_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+	if v := os.Getenv("COMPRESSION_MIN_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COMPRESSION_MIN_SIZE: %w", err)
+		}
+		config.MinSize = size
+	}
+	if v := os.Getenv("COMPRESSION_LEVEL"); v != "" {
+		level, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COMPRESSION_LEVEL: %w", err)
+		}
+		config.Level = level
+	}
+	if v := os.Getenv("COMPRESSION_CONTENT_TYPES"); v != "" {
+		config.ContentTypes = nil
+		for _, contentType := range strings.Split(v, ",") {
+			if contentType = strings.TrimSpace(contentType); contentType != "" {
+				config.ContentTypes = append(config.ContentTypes, contentType)
+			}
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证响应压缩配置的有效性
+func (c *CompressionConfig) Validate() error {
+	if c.MinSize < 0 {
+		return fmt.Errorf("compression min size cannot be negative, got: %d", c.MinSize)
+	}
+	if c.Level != gzip.DefaultCompression && (c.Level < gzip.BestSpeed || c.Level > gzip.BestCompression) {
+		return fmt.Errorf("compression level must be between %d and %d, got: %d", gzip.BestSpeed, gzip.BestCompression, c.Level)
+	}
+	if c.Enabled && len(c.ContentTypes) == 0 {
+		return fmt.Errorf("compression content types cannot be empty when compression is enabled")
+	}
+	return nil
+}
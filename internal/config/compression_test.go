@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCompressionConfigFromEnv(t *testing.T) {
+	t.Setenv("COMPRESSION_MIN_SIZE", "2048")
+	t.Setenv("COMPRESSION_LEVEL", "5")
+	t.Setenv("COMPRESSION_CONTENT_TYPES", "application/json, text/csv")
+
+	cfg, err := LoadCompressionConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 2048, cfg.MinSize)
+	assert.Equal(t, 5, cfg.Level)
+	assert.Equal(t, []string{"application/json", "text/csv"}, cfg.ContentTypes)
+
+	t.Setenv("COMPRESSION_LEVEL", "12")
+	_, err = LoadCompressionConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("COMPRESSION_MIN_SIZE", "1k")
+	_, err = LoadCompressionConfigFromEnv()
+	assert.ErrorContains(t, err, "invalid COMPRESSION_MIN_SIZE")
+}
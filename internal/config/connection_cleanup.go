@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ConnectionCleanupConfig 闲置连接清理配置
+// 长期没有查询的连接会提醒所有者；开启自动停用后，提醒后仍未使用的连接在宽限期过后被停用，
+// 并关闭缓存的连接池，减少暴露的数据库凭据与占用的连接资源
+type ConnectionCleanupConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	UnusedDays    int           `yaml:"unused_days"`    // 连续多少天没有查询视为闲置
+	AutoDisable   bool          `yaml:"auto_disable"`   // 宽限期过后是否自动停用闲置连接，关闭时只提醒
+	GracePeriod   time.Duration `yaml:"grace_period"`   // 提醒所有者后到自动停用的等待时间
+	CheckInterval time.Duration `yaml:"check_interval"` // 检查闲置连接的间隔
+}
+
+// DefaultConnectionCleanupConfig 返回默认闲置连接清理配置（默认关闭）
+func DefaultConnectionCleanupConfig() *ConnectionCleanupConfig {
+	return &ConnectionCleanupConfig{
+		Enabled:       false,
+		UnusedDays:    90,
+		AutoDisable:   false,
+		GracePeriod:   14 * 24 * time.Hour,
+		CheckInterval: 24 * time.Hour,
+	}
+}
+
+// LoadConnectionCleanupConfigFromEnv 从环境变量加载闲置连接清理配置
+func LoadConnectionCleanupConfigFromEnv() (*ConnectionCleanupConfig, error) {
+	config := DefaultConnectionCleanupConfig()
+
+	if v := os.Getenv("CONNECTION_CLEANUP_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+
+	if v := os.Getenv("CONNECTION_CLEANUP_UNUSED_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONNECTION_CLEANUP_UNUSED_DAYS: %w", err)
+		}
+		config.UnusedDays = n
+	}
+
+	if v := os.Getenv("CONNECTION_CLEANUP_AUTO_DISABLE"); v != "" {
+		config.AutoDisable = v == "true"
+	}
+
+	if v := os.Getenv("CONNECTION_CLEANUP_GRACE_PERIOD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONNECTION_CLEANUP_GRACE_PERIOD: %w", err)
+		}
+		config.GracePeriod = d
+	}
+
+	if v := os.Getenv("CONNECTION_CLEANUP_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONNECTION_CLEANUP_INTERVAL: %w", err)
+		}
+		config.CheckInterval = d
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证闲置连接清理配置的有效性
+func (c *ConnectionCleanupConfig) Validate() error {
+	if c.UnusedDays <= 0 {
+		return fmt.Errorf("connection cleanup unused days must be positive, got: %d", c.UnusedDays)
+	}
+	if c.GracePeriod < 0 {
+		return fmt.Errorf("connection cleanup grace period must not be negative, got: %s", c.GracePeriod)
+	}
+	if c.CheckInterval <= 0 {
+		return fmt.Errorf("connection cleanup interval must be positive, got: %s", c.CheckInterval)
+	}
+	return nil
+}
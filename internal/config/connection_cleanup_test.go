@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConnectionCleanupConfigFromEnv(t *testing.T) {
+	cfg, err := LoadConnectionCleanupConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, 90, cfg.UnusedDays)
+	assert.False(t, cfg.AutoDisable)
+	assert.Equal(t, 14*24*time.Hour, cfg.GracePeriod)
+
+	t.Setenv("CONNECTION_CLEANUP_ENABLED", "true")
+	t.Setenv("CONNECTION_CLEANUP_UNUSED_DAYS", "30")
+	t.Setenv("CONNECTION_CLEANUP_AUTO_DISABLE", "true")
+	t.Setenv("CONNECTION_CLEANUP_GRACE_PERIOD", "72h")
+	t.Setenv("CONNECTION_CLEANUP_INTERVAL", "6h")
+	cfg, err = LoadConnectionCleanupConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 30, cfg.UnusedDays)
+	assert.True(t, cfg.AutoDisable)
+	assert.Equal(t, 72*time.Hour, cfg.GracePeriod)
+	assert.Equal(t, 6*time.Hour, cfg.CheckInterval)
+
+	t.Setenv("CONNECTION_CLEANUP_UNUSED_DAYS", "0")
+	_, err = LoadConnectionCleanupConfigFromEnv()
+	assert.Error(t, err, "闲置天数必须为正")
+
+	t.Setenv("CONNECTION_CLEANUP_UNUSED_DAYS", "30")
+	t.Setenv("CONNECTION_CLEANUP_GRACE_PERIOD", "-1h")
+	_, err = LoadConnectionCleanupConfigFromEnv()
+	assert.Error(t, err, "宽限期不能为负")
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// legacyConnectionEncryptionKey 早期版本硬编码在启动代码中的连接密码加密密钥
+// 只允许在开发与演示环境中使用；已用它加密的连接密码通过connection-keys命令迁移到新密钥
+const legacyConnectionEncryptionKey = "chat2sql-encryption-key-123456"
+
+// ErrLegacyConnectionEncryptionKey 未配置密钥或配置的是内置旧密钥
+var ErrLegacyConnectionEncryptionKey = errors.New("CONNECTION_ENCRYPTION_KEY must be set to a non-legacy key " +
+	"(the built-in key is only allowed with CONNECTION_ENCRYPTION_ALLOW_LEGACY_KEY=true in development)")
+
+// ConnectionEncryptionConfig 数据库连接密码加密配置
+type ConnectionEncryptionConfig struct {
+	Key         []byte `yaml:"-"` // 32字节AES-256密钥
+	Legacy      bool   `yaml:"-"` // 是否为内置的旧密钥
+	AllowLegacy bool   `yaml:"-"` // 是否允许使用内置的旧密钥，仅用于开发与演示
+	PreviousKey []byte `yaml:"-"` // 重新加密时解密已有密文的旧密钥，为空时为内置的旧密钥
+}
+
+// LegacyConnectionEncryptionKey 返回内置旧密钥的32字节形式
+func LegacyConnectionEncryptionKey() []byte {
+	key := make([]byte, 32)
+	copy(key, legacyConnectionEncryptionKey)
+	return key
+}
+
+// DefaultConnectionEncryptionConfig 返回默认连接密码加密配置
+func DefaultConnectionEncryptionConfig() *ConnectionEncryptionConfig {
+	return &ConnectionEncryptionConfig{
+		Key:    LegacyConnectionEncryptionKey(),
+		Legacy: true,
+	}
+}
+
+// LoadConnectionEncryptionConfigFromEnv 从环境变量加载连接密码加密配置
+// development为true（演示模式）或CONNECTION_ENCRYPTION_ALLOW_LEGACY_KEY=true时允许沿用内置的旧密钥，否则必须显式配置新密钥
+func LoadConnectionEncryptionConfigFromEnv(development bool) (*ConnectionEncryptionConfig, error) {
+	config := DefaultConnectionEncryptionConfig()
+	config.AllowLegacy = development || os.Getenv("CONNECTION_ENCRYPTION_ALLOW_LEGACY_KEY") == "true"
+
+	if v := os.Getenv("CONNECTION_ENCRYPTION_KEY"); v != "" {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONNECTION_ENCRYPTION_KEY: %w", err)
+		}
+		config.Key = key
+		config.Legacy = bytes.Equal(key, LegacyConnectionEncryptionKey())
+	}
+
+	if v := os.Getenv("CONNECTION_ENCRYPTION_PREVIOUS_KEY"); v != "" {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONNECTION_ENCRYPTION_PREVIOUS_KEY: %w", err)
+		}
+		config.PreviousKey = key
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证连接密码加密配置的有效性
+func (c *ConnectionEncryptionConfig) Validate() error {
+	if len(c.Key) != 32 {
+		return fmt.Errorf("connection encryption key must be 32 bytes, got: %d", len(c.Key))
+	}
+	if c.PreviousKey != nil && len(c.PreviousKey) != 32 {
+		return fmt.Errorf("previous connection encryption key must be 32 bytes, got: %d", len(c.PreviousKey))
+	}
+	if c.Legacy && !c.AllowLegacy {
+		return ErrLegacyConnectionEncryptionKey
+	}
+	return nil
+}
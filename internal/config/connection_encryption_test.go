@@ -0,0 +1,52 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConnectionEncryptionConfigFromEnv(t *testing.T) {
+	t.Setenv("CONNECTION_ENCRYPTION_KEY", "")
+	t.Setenv("CONNECTION_ENCRYPTION_ALLOW_LEGACY_KEY", "")
+	t.Setenv("CONNECTION_ENCRYPTION_PREVIOUS_KEY", "")
+	_, err := LoadConnectionEncryptionConfigFromEnv(false)
+	assert.ErrorIs(t, err, ErrLegacyConnectionEncryptionKey, "未配置密钥时拒绝启动")
+
+	cfg, err := LoadConnectionEncryptionConfigFromEnv(true)
+	require.NoError(t, err, "演示模式允许内置旧密钥")
+	assert.True(t, cfg.Legacy)
+	assert.Equal(t, legacyConnectionEncryptionKey, strings.TrimRight(string(cfg.Key), "\x00"))
+
+	t.Setenv("CONNECTION_ENCRYPTION_ALLOW_LEGACY_KEY", "true")
+	_, err = LoadConnectionEncryptionConfigFromEnv(false)
+	require.NoError(t, err, "开发环境显式允许旧密钥")
+	t.Setenv("CONNECTION_ENCRYPTION_ALLOW_LEGACY_KEY", "")
+
+	t.Setenv("CONNECTION_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(LegacyConnectionEncryptionKey()))
+	_, err = LoadConnectionEncryptionConfigFromEnv(false)
+	assert.ErrorIs(t, err, ErrLegacyConnectionEncryptionKey, "显式配置旧密钥同样拒绝")
+
+	t.Setenv("CONNECTION_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	cfg, err = LoadConnectionEncryptionConfigFromEnv(false)
+	require.NoError(t, err)
+	assert.False(t, cfg.Legacy)
+	assert.Nil(t, cfg.PreviousKey)
+
+	t.Setenv("CONNECTION_ENCRYPTION_PREVIOUS_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("p", 32))))
+	cfg, err = LoadConnectionEncryptionConfigFromEnv(false)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(strings.Repeat("p", 32)), cfg.PreviousKey)
+
+	t.Setenv("CONNECTION_ENCRYPTION_PREVIOUS_KEY", base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = LoadConnectionEncryptionConfigFromEnv(false)
+	assert.Error(t, err)
+	t.Setenv("CONNECTION_ENCRYPTION_PREVIOUS_KEY", "")
+
+	t.Setenv("CONNECTION_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = LoadConnectionEncryptionConfigFromEnv(false)
+	assert.Error(t, err)
+}
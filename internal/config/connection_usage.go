@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ConnectionUsageConfig 连接使用热力图配置
+// 按查询历史统计每个连接在星期几与小时上的查询量，供安排维护窗口、发现闲置连接使用
+type ConnectionUsageConfig struct {
+	DefaultWeeks int    `yaml:"default_weeks"` // 请求未指定时统计的周数
+	MaxWeeks     int    `yaml:"max_weeks"`     // 请求可指定的最大周数
+	Timezone     string `yaml:"timezone"`      // 请求未指定时计算星期与小时使用的IANA时区
+}
+
+// DefaultConnectionUsageConfig 返回默认连接使用热力图配置
+func DefaultConnectionUsageConfig() *ConnectionUsageConfig {
+	return &ConnectionUsageConfig{
+		DefaultWeeks: 4,
+		MaxWeeks:     26,
+		Timezone:     "UTC",
+	}
+}
+
+// LoadConnectionUsageConfigFromEnv 从环境变量加载连接使用热力图配置
+func LoadConnectionUsageConfigFromEnv() (*ConnectionUsageConfig, error) {
+	config := DefaultConnectionUsageConfig()
+
+	if v := os.Getenv("CONNECTION_USAGE_WEEKS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONNECTION_USAGE_WEEKS: %w", err)
+		}
+		config.DefaultWeeks = n
+	}
+
+	if v := os.Getenv("CONNECTION_USAGE_MAX_WEEKS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONNECTION_USAGE_MAX_WEEKS: %w", err)
+		}
+		config.MaxWeeks = n
+	}
+
+	if v := os.Getenv("CONNECTION_USAGE_TIMEZONE"); v != "" {
+		config.Timezone = v
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证连接使用热力图配置的有效性
+func (c *ConnectionUsageConfig) Validate() error {
+	if c.MaxWeeks <= 0 {
+		return fmt.Errorf("connection usage max weeks must be positive, got: %d", c.MaxWeeks)
+	}
+	if c.DefaultWeeks <= 0 || c.DefaultWeeks > c.MaxWeeks {
+		return fmt.Errorf("connection usage weeks must be between 1 and %d, got: %d", c.MaxWeeks, c.DefaultWeeks)
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid connection usage timezone %q: %w", c.Timezone, err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConnectionUsageConfigFromEnv(t *testing.T) {
+	cfg, err := LoadConnectionUsageConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 4, cfg.DefaultWeeks)
+	assert.Equal(t, 26, cfg.MaxWeeks)
+	assert.Equal(t, "UTC", cfg.Timezone)
+
+	t.Setenv("CONNECTION_USAGE_WEEKS", "8")
+	t.Setenv("CONNECTION_USAGE_MAX_WEEKS", "52")
+	t.Setenv("CONNECTION_USAGE_TIMEZONE", "Asia/Shanghai")
+	cfg, err = LoadConnectionUsageConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 8, cfg.DefaultWeeks)
+	assert.Equal(t, 52, cfg.MaxWeeks)
+	assert.Equal(t, "Asia/Shanghai", cfg.Timezone)
+
+	t.Setenv("CONNECTION_USAGE_WEEKS", "60")
+	_, err = LoadConnectionUsageConfigFromEnv()
+	assert.Error(t, err, "默认周数超过上限")
+
+	t.Setenv("CONNECTION_USAGE_WEEKS", "8")
+	t.Setenv("CONNECTION_USAGE_TIMEZONE", "Mars/Olympus")
+	_, err = LoadConnectionUsageConfigFromEnv()
+	assert.Error(t, err, "无效时区")
+}
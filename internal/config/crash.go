@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// CrashReportConfig 崩溃报告配置
+type CrashReportConfig struct {
+	Dir            string `yaml:"dir"`             // 崩溃报告目录，为空表示只记录日志不落盘
+	MaxBundles     int    `yaml:"max_bundles"`     // 最多保留的崩溃报告数，超出后删除最旧的
+	RecentRequests int    `yaml:"recent_requests"` // 崩溃报告中附带的最近请求数
+}
+
+// DefaultCrashReportConfig 返回默认崩溃报告配置
+func DefaultCrashReportConfig() *CrashReportConfig {
+	return &CrashReportConfig{
+		Dir:            "crash-reports",
+		MaxBundles:     50,
+		RecentRequests: 20,
+	}
+}
+
+// LoadCrashReportConfigFromEnv 从环境变量加载崩溃报告配置
+func LoadCrashReportConfigFromEnv() (*CrashReportConfig, error) {
+	config := DefaultCrashReportConfig()
+
+	if v, ok := os.LookupEnv("CRASH_REPORT_DIR"); ok {
+		config.Dir = v
+	}
+
+	if v := os.Getenv("CRASH_REPORT_MAX_BUNDLES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CRASH_REPORT_MAX_BUNDLES: %w", err)
+		}
+		config.MaxBundles = n
+	}
+
+	if v := os.Getenv("CRASH_REPORT_RECENT_REQUESTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CRASH_REPORT_RECENT_REQUESTS: %w", err)
+		}
+		config.RecentRequests = n
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证崩溃报告配置的有效性
+func (c *CrashReportConfig) Validate() error {
+	if c.MaxBundles <= 0 {
+		return fmt.Errorf("crash report max bundles must be positive, got: %d", c.MaxBundles)
+	}
+	if c.RecentRequests < 0 {
+		return fmt.Errorf("crash report recent requests cannot be negative, got: %d", c.RecentRequests)
+	}
+	return nil
+}
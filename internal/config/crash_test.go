@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCrashReportConfigFromEnv(t *testing.T) {
+	t.Setenv("CRASH_REPORT_DIR", "")
+	t.Setenv("CRASH_REPORT_MAX_BUNDLES", "5")
+	t.Setenv("CRASH_REPORT_RECENT_REQUESTS", "0")
+
+	cfg, err := LoadCrashReportConfigFromEnv()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Dir, "显式设置为空时不落盘")
+	assert.Equal(t, 5, cfg.MaxBundles)
+	assert.Equal(t, 0, cfg.RecentRequests)
+
+	t.Setenv("CRASH_REPORT_MAX_BUNDLES", "0")
+	_, err = LoadCrashReportConfigFromEnv()
+	assert.Error(t, err)
+}
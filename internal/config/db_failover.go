@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DatabaseFailoverConfig 系统库故障切换期间的重试与熔断配置
+type DatabaseFailoverConfig struct {
+	MaxAttempts  int           `yaml:"max_attempts"`  // 单次调用的最多尝试次数，1表示不重试
+	RetryBackoff time.Duration `yaml:"retry_backoff"` // 首次重试前的等待时间，之后每次翻倍
+
+	BreakerThreshold   int           `yaml:"breaker_threshold"`    // 连续多少次调用因连接故障失败后熔断
+	BreakerOpenTimeout time.Duration `yaml:"breaker_open_timeout"` // 熔断持续时间，到期后放行一次探测调用
+}
+
+// DefaultDatabaseFailoverConfig 返回默认故障切换配置
+// 默认重试覆盖约1.5秒，足以跨过常见的主备切换窗口
+func DefaultDatabaseFailoverConfig() *DatabaseFailoverConfig {
+	return &DatabaseFailoverConfig{
+		MaxAttempts:        4,
+		RetryBackoff:       200 * time.Millisecond,
+		BreakerThreshold:   10,
+		BreakerOpenTimeout: 5 * time.Second,
+	}
+}
+
+// LoadDatabaseFailoverConfigFromEnv 从环境变量加载故障切换配置
+func LoadDatabaseFailoverConfigFromEnv() (*DatabaseFailoverConfig, error) {
+	config := DefaultDatabaseFailoverConfig()
+
+	if v := os.Getenv("DB_RETRY_MAX_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_RETRY_MAX_ATTEMPTS: %w", err)
+		}
+		config.MaxAttempts = attempts
+	}
+
+	if v := os.Getenv("DB_RETRY_BACKOFF"); v != "" {
+		backoff, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_RETRY_BACKOFF: %w", err)
+		}
+		config.RetryBackoff = backoff
+	}
+
+	if v := os.Getenv("DB_BREAKER_THRESHOLD"); v != "" {
+		threshold, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_BREAKER_THRESHOLD: %w", err)
+		}
+		config.BreakerThreshold = threshold
+	}
+
+	if v := os.Getenv("DB_BREAKER_OPEN_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_BREAKER_OPEN_TIMEOUT: %w", err)
+		}
+		config.BreakerOpenTimeout = timeout
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证故障切换配置的有效性
+func (c *DatabaseFailoverConfig) Validate() error {
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("database retry max attempts must be at least 1, got: %d", c.MaxAttempts)
+	}
+	if c.RetryBackoff < 0 {
+		return fmt.Errorf("database retry backoff cannot be negative, got: %v", c.RetryBackoff)
+	}
+	if c.BreakerThreshold < 1 {
+		return fmt.Errorf("database breaker threshold must be at least 1, got: %d", c.BreakerThreshold)
+	}
+	if c.BreakerOpenTimeout <= 0 {
+		return fmt.Errorf("database breaker open timeout must be positive, got: %v", c.BreakerOpenTimeout)
+	}
+	return nil
+}
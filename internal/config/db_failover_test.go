@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDatabaseFailoverConfigFromEnv(t *testing.T) {
+	t.Setenv("DB_RETRY_MAX_ATTEMPTS", "1")
+	t.Setenv("DB_RETRY_BACKOFF", "50ms")
+	t.Setenv("DB_BREAKER_THRESHOLD", "3")
+	t.Setenv("DB_BREAKER_OPEN_TIMEOUT", "30s")
+
+	cfg, err := LoadDatabaseFailoverConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 1, cfg.MaxAttempts, "1表示不重试")
+	assert.Equal(t, 50*time.Millisecond, cfg.RetryBackoff)
+	assert.Equal(t, 3, cfg.BreakerThreshold)
+	assert.Equal(t, 30*time.Second, cfg.BreakerOpenTimeout)
+
+	t.Setenv("DB_RETRY_MAX_ATTEMPTS", "0")
+	_, err = LoadDatabaseFailoverConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DatabaseReplicaConfig 系统库只读副本配置
+// 副本沿用主库的用户、密码、库名与SSL配置，只替换主机与端口
+type DatabaseReplicaConfig struct {
+	Hosts         []string      `yaml:"hosts"`          // 副本地址列表，host或host:port，为空时不启用读副本
+	MaxStaleness  time.Duration `yaml:"max_staleness"`  // 可接受的最大复制延迟，超过时读请求回到主库
+	CheckInterval time.Duration `yaml:"check_interval"` // 复制延迟检查间隔
+	MaxConns      int32         `yaml:"max_conns"`      // 每个副本连接池的最大连接数
+}
+
+// DefaultDatabaseReplicaConfig 返回默认读副本配置（未配置副本地址，不启用）
+func DefaultDatabaseReplicaConfig() *DatabaseReplicaConfig {
+	return &DatabaseReplicaConfig{
+		MaxStaleness:  5 * time.Second,
+		CheckInterval: 5 * time.Second,
+		MaxConns:      20,
+	}
+}
+
+// LoadDatabaseReplicaConfigFromEnv 从环境变量加载读副本配置
+func LoadDatabaseReplicaConfigFromEnv() (*DatabaseReplicaConfig, error) {
+	config := DefaultDatabaseReplicaConfig()
+
+	if v := os.Getenv("DB_READ_REPLICAS"); v != "" {
+		for _, host := range strings.Split(v, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				config.Hosts = append(config.Hosts, host)
+			}
+		}
+	}
+
+	if v := os.Getenv("DB_REPLICA_MAX_STALENESS"); v != "" {
+		staleness, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_REPLICA_MAX_STALENESS: %w", err)
+		}
+		config.MaxStaleness = staleness
+	}
+
+	if v := os.Getenv("DB_REPLICA_CHECK_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_REPLICA_CHECK_INTERVAL: %w", err)
+		}
+		config.CheckInterval = interval
+	}
+
+	if v := os.Getenv("DB_REPLICA_MAX_CONNS"); v != "" {
+		conns, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_REPLICA_MAX_CONNS: %w", err)
+		}
+		config.MaxConns = int32(conns)
+	}
+
+	return config, config.Validate()
+}
+
+// Enabled 是否配置了读副本
+func (c *DatabaseReplicaConfig) Enabled() bool {
+	return len(c.Hosts) > 0
+}
+
+// Endpoint 解析副本地址，未指定端口时使用defaultPort
+func (c *DatabaseReplicaConfig) Endpoint(host string, defaultPort int) (string, int, error) {
+	if !strings.Contains(host, ":") {
+		return host, defaultPort, nil
+	}
+	h, p, err := net.SplitHostPort(host)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid replica address %q: %w", host, err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid replica port in %q", host)
+	}
+	return h, port, nil
+}
+
+// Validate 验证读副本配置的有效性
+func (c *DatabaseReplicaConfig) Validate() error {
+	for _, host := range c.Hosts {
+		if _, _, err := c.Endpoint(host, 5432); err != nil {
+			return err
+		}
+	}
+	if c.MaxStaleness <= 0 {
+		return fmt.Errorf("replica max staleness must be positive, got: %v", c.MaxStaleness)
+	}
+	if c.CheckInterval <= 0 {
+		return fmt.Errorf("replica check interval must be positive, got: %v", c.CheckInterval)
+	}
+	if c.MaxConns <= 0 {
+		return fmt.Errorf("replica max conns must be positive, got: %d", c.MaxConns)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDatabaseReplicaConfigFromEnv(t *testing.T) {
+	cfg, err := LoadDatabaseReplicaConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled(), "未配置副本地址时不启用")
+
+	t.Setenv("DB_READ_REPLICAS", "replica-eu:6432, replica-us")
+	t.Setenv("DB_REPLICA_MAX_STALENESS", "2s")
+	t.Setenv("DB_REPLICA_CHECK_INTERVAL", "1s")
+	t.Setenv("DB_REPLICA_MAX_CONNS", "8")
+
+	cfg, err = LoadDatabaseReplicaConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled())
+	assert.Equal(t, []string{"replica-eu:6432", "replica-us"}, cfg.Hosts)
+	assert.Equal(t, 2*time.Second, cfg.MaxStaleness)
+	assert.Equal(t, time.Second, cfg.CheckInterval)
+	assert.Equal(t, int32(8), cfg.MaxConns)
+
+	host, port, err := cfg.Endpoint(cfg.Hosts[0], 5432)
+	require.NoError(t, err)
+	assert.Equal(t, "replica-eu", host)
+	assert.Equal(t, 6432, port)
+	host, port, err = cfg.Endpoint(cfg.Hosts[1], 5432)
+	require.NoError(t, err)
+	assert.Equal(t, "replica-us", host)
+	assert.Equal(t, 5432, port, "未指定端口时沿用主库端口")
+
+	t.Setenv("DB_READ_REPLICAS", "replica-eu:abc")
+	_, err = LoadDatabaseReplicaConfigFromEnv()
+	assert.Error(t, err)
+}
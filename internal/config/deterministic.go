@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// DeterministicGenerationConfig 本地模型确定性生成配置
+// 开启后Ollama模型以温度0与固定种子生成，演示与测试中同一问题多次运行得到相同的SQL
+type DeterministicGenerationConfig struct {
+	Enabled bool `yaml:"enabled"` // 是否启用确定性生成
+	Seed    int  `yaml:"seed"`    // 采样种子
+}
+
+// DefaultDeterministicGenerationConfig 返回默认确定性生成配置
+func DefaultDeterministicGenerationConfig() *DeterministicGenerationConfig {
+	return &DeterministicGenerationConfig{
+		Enabled: false,
+		Seed:    42,
+	}
+}
+
+// LoadDeterministicGenerationConfigFromEnv 从环境变量加载确定性生成配置
+func LoadDeterministicGenerationConfigFromEnv() (*DeterministicGenerationConfig, error) {
+	config := DefaultDeterministicGenerationConfig()
+
+	config.Enabled = os.Getenv("OLLAMA_DETERMINISTIC") == "true"
+
+	if v := os.Getenv("OLLAMA_SEED"); v != "" {
+		seed, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OLLAMA_SEED: %w", err)
+		}
+		config.Seed = seed
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证确定性生成配置的有效性
+func (c *DeterministicGenerationConfig) Validate() error {
+	if c.Seed <= 0 {
+		return fmt.Errorf("deterministic generation seed must be positive, got: %d", c.Seed)
+	}
+	return nil
+}
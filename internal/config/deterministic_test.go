@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDeterministicGenerationConfigFromEnv(t *testing.T) {
+	cfg, err := LoadDeterministicGenerationConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, 42, cfg.Seed)
+
+	t.Setenv("OLLAMA_DETERMINISTIC", "true")
+	t.Setenv("OLLAMA_SEED", "7")
+	cfg, err = LoadDeterministicGenerationConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 7, cfg.Seed)
+
+	t.Setenv("OLLAMA_SEED", "0")
+	_, err = LoadDeterministicGenerationConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("OLLAMA_SEED", "abc")
+	_, err = LoadDeterministicGenerationConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestAIConfig_ApplyDeterministicProfile(t *testing.T) {
+	cfg := DefaultAIConfig()
+	cfg.Primary.Provider = "ollama"
+	cfg.Primary.Temperature = 0.7
+
+	cfg.ApplyDeterministicProfile(42)
+	assert.Equal(t, 0.0, cfg.Primary.Temperature)
+	assert.Equal(t, 42, cfg.Primary.Seed)
+	cfg.Fallback.Temperature = 0.5
+	cfg.ApplyDeterministicProfile(42)
+	assert.Equal(t, 0, cfg.Fallback.Seed, "云端模型保持原配置")
+	assert.Equal(t, 0.5, cfg.Fallback.Temperature)
+}
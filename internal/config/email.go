@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// EmailGatewayConfig 邮件查询网关配置
+type EmailGatewayConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	WebhookToken string `yaml:"webhook_token"` // 入站Webhook（SendGrid Inbound Parse）共享令牌
+	RequireSPF   bool   `yaml:"require_spf"`   // 要求发件人SPF校验通过，防止伪造发件人
+
+	// 回复邮件SMTP配置
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+	FromAddress  string `yaml:"from_address"`
+
+	// 执行配置
+	QueueSize int `yaml:"queue_size"` // 待执行队列长度
+	Workers   int `yaml:"workers"`    // 并发执行的worker数量
+}
+
+// DefaultEmailGatewayConfig 返回默认邮件网关配置（默认关闭）
+func DefaultEmailGatewayConfig() *EmailGatewayConfig {
+	return &EmailGatewayConfig{
+		Enabled:    false,
+		RequireSPF: true,
+		SMTPPort:   587,
+		QueueSize:  100,
+		Workers:    2,
+	}
+}
+
+// LoadEmailGatewayConfigFromEnv 从环境变量加载邮件网关配置
+func LoadEmailGatewayConfigFromEnv() (*EmailGatewayConfig, error) {
+	config := DefaultEmailGatewayConfig()
+
+	config.WebhookToken = os.Getenv("EMAIL_WEBHOOK_TOKEN")
+	if config.WebhookToken == "" {
+		// 未配置令牌时视为未启用网关
+		return config, nil
+	}
+	config.Enabled = true
+
+	config.SMTPHost = os.Getenv("SMTP_HOST")
+	config.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	config.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	config.FromAddress = os.Getenv("EMAIL_FROM_ADDRESS")
+
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		config.SMTPPort = port
+	}
+
+	if v := os.Getenv("EMAIL_REQUIRE_SPF"); v != "" {
+		config.RequireSPF = v == "true"
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证邮件网关配置的有效性
+func (c *EmailGatewayConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.WebhookToken == "" {
+		return fmt.Errorf("email webhook token is required")
+	}
+	if c.SMTPHost == "" {
+		return fmt.Errorf("smtp host is required")
+	}
+	if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+		return fmt.Errorf("smtp port must be between 1 and 65535, got: %d", c.SMTPPort)
+	}
+	if c.FromAddress == "" {
+		return fmt.Errorf("email from address is required")
+	}
+	if c.QueueSize <= 0 || c.Workers <= 0 {
+		return fmt.Errorf("queue size and workers must be positive")
+	}
+	return nil
+}
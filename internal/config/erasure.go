@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ErasureConfig 个人数据删除任务配置
+type ErasureConfig struct {
+	ProcessInterval time.Duration `yaml:"process_interval"` // 待处理申请扫描间隔
+	BatchSize       int           `yaml:"batch_size"`       // 每次扫描最多处理的申请数
+}
+
+// DefaultErasureConfig 返回默认个人数据删除任务配置
+func DefaultErasureConfig() *ErasureConfig {
+	return &ErasureConfig{
+		ProcessInterval: time.Minute,
+		BatchSize:       10,
+	}
+}
+
+// LoadErasureConfigFromEnv 从环境变量加载个人数据删除任务配置
+func LoadErasureConfigFromEnv() (*ErasureConfig, error) {
+	config := DefaultErasureConfig()
+
+	if v := os.Getenv("ERASURE_PROCESS_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ERASURE_PROCESS_INTERVAL: %w", err)
+		}
+		config.ProcessInterval = interval
+	}
+
+	if v := os.Getenv("ERASURE_BATCH_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ERASURE_BATCH_SIZE: %w", err)
+		}
+		config.BatchSize = size
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证个人数据删除任务配置的有效性
+func (c *ErasureConfig) Validate() error {
+	if c.ProcessInterval <= 0 {
+		return fmt.Errorf("erasure process interval must be positive, got: %v", c.ProcessInterval)
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("erasure batch size must be positive, got: %d", c.BatchSize)
+	}
+	return nil
+}
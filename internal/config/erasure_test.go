@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadErasureConfigFromEnv(t *testing.T) {
+	t.Setenv("ERASURE_PROCESS_INTERVAL", "30s")
+	t.Setenv("ERASURE_BATCH_SIZE", "5")
+
+	cfg, err := LoadErasureConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.ProcessInterval)
+	assert.Equal(t, 5, cfg.BatchSize)
+
+	t.Setenv("ERASURE_BATCH_SIZE", "0")
+	_, err = LoadErasureConfigFromEnv()
+	assert.Error(t, err)
+}
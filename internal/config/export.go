@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ExportConfig 查询结果导出配置
+// 导出会重新执行历史查询并逐行写出文件，不受交互查询的行数与超时限制
+type ExportConfig struct {
+	MaxRows int64         `yaml:"max_rows"` // 单次导出的最大行数，超出部分被截断
+	Timeout time.Duration `yaml:"timeout"`  // 单次导出（执行查询并写出文件）的最长时间
+}
+
+// DefaultExportConfig 返回默认查询结果导出配置
+func DefaultExportConfig() *ExportConfig {
+	return &ExportConfig{
+		MaxRows: 1000000,
+		Timeout: 10 * time.Minute,
+	}
+}
+
+// LoadExportConfigFromEnv 从环境变量加载查询结果导出配置
+func LoadExportConfigFromEnv() (*ExportConfig, error) {
+	config := DefaultExportConfig()
+
+	if v := os.Getenv("EXPORT_MAX_ROWS"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXPORT_MAX_ROWS: %w", err)
+		}
+		config.MaxRows = n
+	}
+
+	if v := os.Getenv("EXPORT_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXPORT_TIMEOUT: %w", err)
+		}
+		config.Timeout = d
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证查询结果导出配置的有效性
+func (c *ExportConfig) Validate() error {
+	if c.MaxRows <= 0 {
+		return fmt.Errorf("export max rows must be positive, got: %d", c.MaxRows)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("export timeout must be positive, got: %s", c.Timeout)
+	}
+	return nil
+}
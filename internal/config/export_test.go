@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadExportConfigFromEnv(t *testing.T) {
+	cfg, err := LoadExportConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000000), cfg.MaxRows)
+	assert.Equal(t, 10*time.Minute, cfg.Timeout)
+
+	t.Setenv("EXPORT_MAX_ROWS", "5000")
+	t.Setenv("EXPORT_TIMEOUT", "2m")
+	cfg, err = LoadExportConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), cfg.MaxRows)
+	assert.Equal(t, 2*time.Minute, cfg.Timeout)
+
+	t.Setenv("EXPORT_MAX_ROWS", "0")
+	_, err = LoadExportConfigFromEnv()
+	assert.Error(t, err, "行数上限必须为正")
+
+	t.Setenv("EXPORT_MAX_ROWS", "5000")
+	t.Setenv("EXPORT_TIMEOUT", "soon")
+	_, err = LoadExportConfigFromEnv()
+	assert.Error(t, err)
+}
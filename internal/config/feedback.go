@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// FeedbackConfig 查询反馈配置
+// 生成SQL后按查询ID保留问题、SQL与模型等上下文，用户提交反馈时据此写入准确率统计与学习引擎；
+// 超过ContextTTL或超出MaxContexts的最早上下文被丢弃，之后的反馈只能修正已记录的反馈
+type FeedbackConfig struct {
+	ContextTTL  time.Duration `yaml:"context_ttl"`  // 生成上下文等待反馈的最长时间
+	MaxContexts int           `yaml:"max_contexts"` // 最多保留的生成上下文数
+}
+
+// DefaultFeedbackConfig 返回默认查询反馈配置
+func DefaultFeedbackConfig() *FeedbackConfig {
+	return &FeedbackConfig{
+		ContextTTL:  24 * time.Hour,
+		MaxContexts: 10000,
+	}
+}
+
+// LoadFeedbackConfigFromEnv 从环境变量加载查询反馈配置
+func LoadFeedbackConfigFromEnv() (*FeedbackConfig, error) {
+	config := DefaultFeedbackConfig()
+
+	if v := os.Getenv("FEEDBACK_CONTEXT_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEEDBACK_CONTEXT_TTL: %w", err)
+		}
+		config.ContextTTL = ttl
+	}
+
+	if v := os.Getenv("FEEDBACK_MAX_CONTEXTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEEDBACK_MAX_CONTEXTS: %w", err)
+		}
+		config.MaxContexts = n
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证查询反馈配置的有效性
+func (c *FeedbackConfig) Validate() error {
+	if c.ContextTTL < time.Minute {
+		return fmt.Errorf("feedback context ttl must be at least 1m, got: %v", c.ContextTTL)
+	}
+	if c.MaxContexts <= 0 {
+		return fmt.Errorf("feedback max contexts must be positive, got: %d", c.MaxContexts)
+	}
+	return nil
+}
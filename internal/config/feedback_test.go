@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFeedbackConfigFromEnv(t *testing.T) {
+	cfg, err := LoadFeedbackConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, cfg.ContextTTL)
+	assert.Equal(t, 10000, cfg.MaxContexts)
+
+	t.Setenv("FEEDBACK_CONTEXT_TTL", "168h")
+	t.Setenv("FEEDBACK_MAX_CONTEXTS", "500")
+	cfg, err = LoadFeedbackConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 168*time.Hour, cfg.ContextTTL)
+	assert.Equal(t, 500, cfg.MaxContexts)
+
+	t.Setenv("FEEDBACK_MAX_CONTEXTS", "0")
+	_, err = LoadFeedbackConfigFromEnv()
+	assert.Error(t, err, "上下文数必须为正")
+
+	t.Setenv("FEEDBACK_MAX_CONTEXTS", "500")
+	t.Setenv("FEEDBACK_CONTEXT_TTL", "abc")
+	_, err = LoadFeedbackConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+)
+
+// HistoryEncryptionConfig 查询历史静态加密配置
+// 主密钥用于加密各工作空间的数据密钥，启用过加密后必须一直配置同一主密钥，否则已加密的记录无法读取
+type HistoryEncryptionConfig struct {
+	MasterKey   []byte        `yaml:"-"`             // 32字节主密钥，为空表示不启用查询历史加密
+	KeyCacheTTL time.Duration `yaml:"key_cache_ttl"` // 生效密钥缓存时间，密钥轮换最多延迟该时长生效
+}
+
+// DefaultHistoryEncryptionConfig 返回默认查询历史加密配置
+func DefaultHistoryEncryptionConfig() *HistoryEncryptionConfig {
+	return &HistoryEncryptionConfig{
+		KeyCacheTTL: time.Minute,
+	}
+}
+
+// LoadHistoryEncryptionConfigFromEnv 从环境变量加载查询历史加密配置
+func LoadHistoryEncryptionConfigFromEnv() (*HistoryEncryptionConfig, error) {
+	config := DefaultHistoryEncryptionConfig()
+
+	if v := os.Getenv("HISTORY_ENCRYPTION_MASTER_KEY"); v != "" {
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HISTORY_ENCRYPTION_MASTER_KEY: %w", err)
+		}
+		config.MasterKey = key
+	}
+
+	if v := os.Getenv("HISTORY_ENCRYPTION_KEY_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HISTORY_ENCRYPTION_KEY_CACHE_TTL: %w", err)
+		}
+		config.KeyCacheTTL = ttl
+	}
+
+	return config, config.Validate()
+}
+
+// Enabled 是否配置了主密钥
+func (c *HistoryEncryptionConfig) Enabled() bool {
+	return len(c.MasterKey) > 0
+}
+
+// Validate 验证查询历史加密配置的有效性
+func (c *HistoryEncryptionConfig) Validate() error {
+	if c.Enabled() && len(c.MasterKey) != 32 {
+		return fmt.Errorf("history encryption master key must be 32 bytes, got: %d", len(c.MasterKey))
+	}
+	if c.KeyCacheTTL <= 0 {
+		return fmt.Errorf("history encryption key cache ttl must be positive, got: %v", c.KeyCacheTTL)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadHistoryEncryptionConfigFromEnv(t *testing.T) {
+	cfg, err := LoadHistoryEncryptionConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled())
+
+	t.Setenv("HISTORY_ENCRYPTION_MASTER_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	t.Setenv("HISTORY_ENCRYPTION_KEY_CACHE_TTL", "30s")
+	cfg, err = LoadHistoryEncryptionConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled())
+	assert.Equal(t, 30*time.Second, cfg.KeyCacheTTL)
+
+	t.Setenv("HISTORY_ENCRYPTION_MASTER_KEY", base64.StdEncoding.EncodeToString([]byte("too-short")))
+	_, err = LoadHistoryEncryptionConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("HISTORY_ENCRYPTION_MASTER_KEY", "not base64!")
+	_, err = LoadHistoryEncryptionConfigFromEnv()
+	assert.Error(t, err)
+}
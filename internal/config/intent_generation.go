@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 生成参数覆盖的意图类别，与意图分析器识别的意图一一对应
+const (
+	IntentCategoryExploratory = "exploratory" // 未识别出明确意图的开放式问题
+	IntentCategoryDataQuery   = "data_query"
+	IntentCategoryAggregation = "aggregation"
+	IntentCategoryJoin        = "join"
+	IntentCategoryTimeSeries  = "time_series"
+	IntentCategoryComparison  = "comparison"
+	IntentCategoryRanking     = "ranking"
+	IntentCategoryFiltering   = "filtering"
+	IntentCategoryGrouping    = "grouping"
+)
+
+// IntentCategories 全部意图类别
+var IntentCategories = []string{
+	IntentCategoryExploratory,
+	IntentCategoryDataQuery,
+	IntentCategoryAggregation,
+	IntentCategoryJoin,
+	IntentCategoryTimeSeries,
+	IntentCategoryComparison,
+	IntentCategoryRanking,
+	IntentCategoryFiltering,
+	IntentCategoryGrouping,
+}
+
+// GenerationOverride 单个意图类别的生成参数覆盖，为nil的参数沿用模型配置
+type GenerationOverride struct {
+	Temperature *float64 `yaml:"temperature"`
+	TopP        *float64 `yaml:"top_p"`
+}
+
+// IntentGenerationConfig 按意图类别覆盖模型的生成参数
+// 例如聚合统计固定温度为0以保证结果稳定，开放式问题适当提高温度；未配置的类别沿用模型配置
+type IntentGenerationConfig struct {
+	Overrides map[string]GenerationOverride `yaml:"overrides"`
+}
+
+// DefaultIntentGenerationConfig 返回默认配置，不覆盖任何类别
+func DefaultIntentGenerationConfig() *IntentGenerationConfig {
+	return &IntentGenerationConfig{Overrides: map[string]GenerationOverride{}}
+}
+
+// LoadIntentGenerationConfigFromEnv 从环境变量加载按意图类别的生成参数覆盖
+// AI_INTENT_GENERATION_PARAMS格式为"类别:参数=值,参数=值;类别:..."，参数支持temperature与top_p，
+// 例如"aggregation:temperature=0;exploratory:temperature=0.7,top_p=0.95"
+func LoadIntentGenerationConfigFromEnv() (*IntentGenerationConfig, error) {
+	config := DefaultIntentGenerationConfig()
+
+	if v := os.Getenv("AI_INTENT_GENERATION_PARAMS"); v != "" {
+		for _, entry := range strings.Split(v, ";") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			category, params, ok := strings.Cut(entry, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid AI_INTENT_GENERATION_PARAMS entry %q: expected category:param=value", entry)
+			}
+			category = strings.TrimSpace(category)
+			override := config.Overrides[category]
+			for _, param := range strings.Split(params, ",") {
+				key, raw, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok {
+					return nil, fmt.Errorf("invalid AI_INTENT_GENERATION_PARAMS parameter %q for %s", param, category)
+				}
+				value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid AI_INTENT_GENERATION_PARAMS value for %s.%s: %w", category, key, err)
+				}
+				switch strings.TrimSpace(key) {
+				case "temperature":
+					override.Temperature = &value
+				case "top_p":
+					override.TopP = &value
+				default:
+					return nil, fmt.Errorf("unknown AI_INTENT_GENERATION_PARAMS parameter %q for %s", key, category)
+				}
+			}
+			config.Overrides[category] = override
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证按意图类别的生成参数覆盖
+func (c *IntentGenerationConfig) Validate() error {
+	for category, override := range c.Overrides {
+		if !validIntentCategory(category) {
+			return fmt.Errorf("unknown intent category: %q", category)
+		}
+		if t := override.Temperature; t != nil && (*t < 0 || *t > 2) {
+			return fmt.Errorf("temperature for %s must be between 0 and 2, got: %.2f", category, *t)
+		}
+		if p := override.TopP; p != nil && (*p <= 0 || *p > 1) {
+			return fmt.Errorf("top_p for %s must be between 0 and 1, got: %.2f", category, *p)
+		}
+	}
+	return nil
+}
+
+// Override 返回意图类别的生成参数覆盖，未配置时ok为false
+func (c *IntentGenerationConfig) Override(category string) (GenerationOverride, bool) {
+	override, ok := c.Overrides[category]
+	return override, ok
+}
+
+func validIntentCategory(category string) bool {
+	for _, known := range IntentCategories {
+		if category == known {
+			return true
+		}
+	}
+	return false
+}
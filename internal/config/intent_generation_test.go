@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadIntentGenerationConfigFromEnv(t *testing.T) {
+	cfg, err := LoadIntentGenerationConfigFromEnv()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Overrides, "默认不覆盖")
+
+	t.Setenv("AI_INTENT_GENERATION_PARAMS", "aggregation:temperature=0; exploratory:temperature=0.7,top_p=0.95")
+	cfg, err = LoadIntentGenerationConfigFromEnv()
+	require.NoError(t, err)
+
+	aggregation, ok := cfg.Override(IntentCategoryAggregation)
+	require.True(t, ok)
+	require.NotNil(t, aggregation.Temperature)
+	assert.Equal(t, 0.0, *aggregation.Temperature)
+	assert.Nil(t, aggregation.TopP)
+
+	exploratory, ok := cfg.Override(IntentCategoryExploratory)
+	require.True(t, ok)
+	assert.Equal(t, 0.7, *exploratory.Temperature)
+	assert.Equal(t, 0.95, *exploratory.TopP)
+
+	_, ok = cfg.Override(IntentCategoryRanking)
+	assert.False(t, ok)
+
+	for _, invalid := range []string{
+		"unknown:temperature=0",
+		"aggregation:temperature=3",
+		"aggregation:top_p=0",
+		"aggregation:seed=1",
+		"aggregation",
+	} {
+		t.Setenv("AI_INTENT_GENERATION_PARAMS", invalid)
+		_, err = LoadIntentGenerationConfigFromEnv()
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// LatencyRoutingConfig 模型延迟统计配置
+// 按模型统计滚动窗口内的P95延迟，工作空间的延迟路由策略据此判断主模型是否超过目标；
+// 窗口内样本过期后主模型重新获得交互请求，因此改道只是暂时的
+type LatencyRoutingConfig struct {
+	Window     time.Duration `yaml:"window"`      // 滚动窗口长度
+	MinSamples int           `yaml:"min_samples"` // 窗口内样本少于该数量时不计算P95，不改道
+	MaxSamples int           `yaml:"max_samples"` // 每个模型最多保留的样本数
+}
+
+// DefaultLatencyRoutingConfig 返回默认模型延迟统计配置
+func DefaultLatencyRoutingConfig() *LatencyRoutingConfig {
+	return &LatencyRoutingConfig{
+		Window:     5 * time.Minute,
+		MinSamples: 20,
+		MaxSamples: 500,
+	}
+}
+
+// LoadLatencyRoutingConfigFromEnv 从环境变量加载模型延迟统计配置
+func LoadLatencyRoutingConfigFromEnv() (*LatencyRoutingConfig, error) {
+	config := DefaultLatencyRoutingConfig()
+
+	if v := os.Getenv("LATENCY_ROUTING_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LATENCY_ROUTING_WINDOW: %w", err)
+		}
+		config.Window = window
+	}
+
+	if v := os.Getenv("LATENCY_ROUTING_MIN_SAMPLES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LATENCY_ROUTING_MIN_SAMPLES: %w", err)
+		}
+		config.MinSamples = n
+	}
+
+	if v := os.Getenv("LATENCY_ROUTING_MAX_SAMPLES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LATENCY_ROUTING_MAX_SAMPLES: %w", err)
+		}
+		config.MaxSamples = n
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证模型延迟统计配置的有效性
+func (c *LatencyRoutingConfig) Validate() error {
+	if c.Window < 10*time.Second {
+		return fmt.Errorf("latency routing window must be at least 10s, got: %v", c.Window)
+	}
+	if c.MinSamples <= 0 {
+		return fmt.Errorf("latency routing min samples must be positive, got: %d", c.MinSamples)
+	}
+	if c.MaxSamples < c.MinSamples {
+		return fmt.Errorf("latency routing max samples (%d) must not be less than min samples (%d)", c.MaxSamples, c.MinSamples)
+	}
+	return nil
+}
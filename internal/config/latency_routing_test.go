@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLatencyRoutingConfigFromEnv(t *testing.T) {
+	cfg, err := LoadLatencyRoutingConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.Window)
+	assert.Equal(t, 20, cfg.MinSamples)
+
+	t.Setenv("LATENCY_ROUTING_WINDOW", "2m")
+	t.Setenv("LATENCY_ROUTING_MIN_SAMPLES", "10")
+	t.Setenv("LATENCY_ROUTING_MAX_SAMPLES", "100")
+	cfg, err = LoadLatencyRoutingConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, cfg.Window)
+	assert.Equal(t, 10, cfg.MinSamples)
+	assert.Equal(t, 100, cfg.MaxSamples)
+
+	t.Setenv("LATENCY_ROUTING_MAX_SAMPLES", "5")
+	_, err = LoadLatencyRoutingConfigFromEnv()
+	assert.Error(t, err, "最大样本数小于最小样本数")
+
+	t.Setenv("LATENCY_ROUTING_MAX_SAMPLES", "100")
+	t.Setenv("LATENCY_ROUTING_WINDOW", "1s")
+	_, err = LoadLatencyRoutingConfigFromEnv()
+	assert.Error(t, err, "窗口过短")
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// minArchiveSigningKeyLength 下载链接签名密钥的最小长度
+const minArchiveSigningKeyLength = 32
+
+// LLMArchiveConfig 模型请求与响应归档配置
+// 默认关闭；开启后按查询ID保存脱敏后的提示词与模型原始输出，超过保留时间自动删除，管理员通过签名链接下载
+type LLMArchiveConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // 是否归档模型请求与响应
+	Dir           string        `yaml:"dir"`            // 归档目录，可挂载对象存储（如s3fs、gcsfuse）
+	Retention     time.Duration `yaml:"retention"`      // 归档保留时间，超过后删除
+	SigningKey    string        `yaml:"-"`              // 下载链接签名密钥
+	URLTTL        time.Duration `yaml:"url_ttl"`        // 下载链接有效期
+	PruneInterval time.Duration `yaml:"prune_interval"` // 清理过期归档的间隔
+}
+
+// DefaultLLMArchiveConfig 返回默认模型归档配置
+func DefaultLLMArchiveConfig() *LLMArchiveConfig {
+	return &LLMArchiveConfig{
+		Enabled:       false,
+		Dir:           "llm-archive",
+		Retention:     7 * 24 * time.Hour,
+		URLTTL:        15 * time.Minute,
+		PruneInterval: time.Hour,
+	}
+}
+
+// LoadLLMArchiveConfigFromEnv 从环境变量加载模型归档配置
+func LoadLLMArchiveConfigFromEnv() (*LLMArchiveConfig, error) {
+	config := DefaultLLMArchiveConfig()
+
+	config.Enabled = os.Getenv("LLM_ARCHIVE_ENABLED") == "true"
+	config.SigningKey = os.Getenv("LLM_ARCHIVE_SIGNING_KEY")
+
+	if v := os.Getenv("LLM_ARCHIVE_DIR"); v != "" {
+		config.Dir = v
+	}
+
+	if v := os.Getenv("LLM_ARCHIVE_RETENTION"); v != "" {
+		retention, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM_ARCHIVE_RETENTION: %w", err)
+		}
+		config.Retention = retention
+	}
+
+	if v := os.Getenv("LLM_ARCHIVE_URL_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM_ARCHIVE_URL_TTL: %w", err)
+		}
+		config.URLTTL = ttl
+	}
+
+	if v := os.Getenv("LLM_ARCHIVE_PRUNE_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM_ARCHIVE_PRUNE_INTERVAL: %w", err)
+		}
+		config.PruneInterval = interval
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证模型归档配置的有效性
+func (c *LLMArchiveConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Dir == "" {
+		return fmt.Errorf("llm archive dir is required")
+	}
+	if len(c.SigningKey) < minArchiveSigningKeyLength {
+		return fmt.Errorf("llm archive signing key must be at least %d characters", minArchiveSigningKeyLength)
+	}
+	if c.Retention < time.Hour {
+		return fmt.Errorf("llm archive retention must be at least 1h, got: %v", c.Retention)
+	}
+	if c.URLTTL < time.Minute || c.URLTTL > 24*time.Hour {
+		return fmt.Errorf("llm archive url ttl must be between 1m and 24h, got: %v", c.URLTTL)
+	}
+	if c.PruneInterval < time.Minute {
+		return fmt.Errorf("llm archive prune interval must be at least 1m, got: %v", c.PruneInterval)
+	}
+	return nil
+}
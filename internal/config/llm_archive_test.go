@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLLMArchiveConfigFromEnv(t *testing.T) {
+	cfg, err := LoadLLMArchiveConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled, "未显式开启时不归档")
+
+	t.Setenv("LLM_ARCHIVE_ENABLED", "true")
+	_, err = LoadLLMArchiveConfigFromEnv()
+	assert.Error(t, err, "开启时必须配置签名密钥")
+
+	t.Setenv("LLM_ARCHIVE_SIGNING_KEY", strings.Repeat("k", 32))
+	t.Setenv("LLM_ARCHIVE_DIR", "/mnt/archive")
+	t.Setenv("LLM_ARCHIVE_RETENTION", "72h")
+	t.Setenv("LLM_ARCHIVE_URL_TTL", "5m")
+	cfg, err = LoadLLMArchiveConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, "/mnt/archive", cfg.Dir)
+	assert.Equal(t, 72*time.Hour, cfg.Retention)
+	assert.Equal(t, 5*time.Minute, cfg.URLTTL)
+	assert.Equal(t, time.Hour, cfg.PruneInterval)
+
+	t.Setenv("LLM_ARCHIVE_URL_TTL", "48h")
+	_, err = LoadLLMArchiveConfigFromEnv()
+	assert.Error(t, err, "下载链接有效期过长")
+
+	t.Setenv("LLM_ARCHIVE_URL_TTL", "5m")
+	t.Setenv("LLM_ARCHIVE_RETENTION", "abc")
+	_, err = LoadLLMArchiveConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 模型降级链中的模型
+const (
+	LLMChainPrimary  = "primary"  // 主模型（默认OpenAI）
+	LLMChainFallback = "fallback" // 备用模型（默认Anthropic）
+	LLMChainLocal    = "local"    // 本地Ollama模型，需配置LocalModel
+)
+
+// LLMFailoverConfig 模型降级链配置
+// 按Order依次尝试各模型，单个模型的瞬时错误按退避重试；连续失败达到阈值后熔断，
+// 熔断期间请求直接跳过该模型，到期后放行一次探测调用
+type LLMFailoverConfig struct {
+	Order      []string `yaml:"order"`       // 模型尝试顺序（primary/fallback/local）
+	LocalModel string   `yaml:"local_model"` // 本地Ollama模型名称，为空表示不启用本地模型
+
+	MaxAttempts  int           `yaml:"max_attempts"`  // 单个模型的最多尝试次数，1表示不重试
+	RetryBackoff time.Duration `yaml:"retry_backoff"` // 首次重试前的等待时间，之后每次翻倍
+
+	BreakerThreshold   int           `yaml:"breaker_threshold"`    // 单个模型连续失败多少次后熔断
+	BreakerOpenTimeout time.Duration `yaml:"breaker_open_timeout"` // 熔断持续时间，到期后放行一次探测调用
+}
+
+// DefaultLLMFailoverConfig 返回默认模型降级链配置
+func DefaultLLMFailoverConfig() *LLMFailoverConfig {
+	return &LLMFailoverConfig{
+		Order:              []string{LLMChainPrimary, LLMChainFallback},
+		MaxAttempts:        2,
+		RetryBackoff:       500 * time.Millisecond,
+		BreakerThreshold:   5,
+		BreakerOpenTimeout: 30 * time.Second,
+	}
+}
+
+// LoadLLMFailoverConfigFromEnv 从环境变量加载模型降级链配置
+// 设置了LLM_LOCAL_MODEL但未设置LLM_PROVIDER_ORDER时，本地模型排在最后
+func LoadLLMFailoverConfigFromEnv() (*LLMFailoverConfig, error) {
+	config := DefaultLLMFailoverConfig()
+
+	config.LocalModel = os.Getenv("LLM_LOCAL_MODEL")
+	if v := os.Getenv("LLM_PROVIDER_ORDER"); v != "" {
+		config.Order = nil
+		for _, model := range strings.Split(v, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				config.Order = append(config.Order, model)
+			}
+		}
+	} else if config.LocalModel != "" {
+		config.Order = append(config.Order, LLMChainLocal)
+	}
+
+	if v := os.Getenv("LLM_RETRY_MAX_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM_RETRY_MAX_ATTEMPTS: %w", err)
+		}
+		config.MaxAttempts = attempts
+	}
+
+	if v := os.Getenv("LLM_RETRY_BACKOFF"); v != "" {
+		backoff, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM_RETRY_BACKOFF: %w", err)
+		}
+		config.RetryBackoff = backoff
+	}
+
+	if v := os.Getenv("LLM_BREAKER_THRESHOLD"); v != "" {
+		threshold, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM_BREAKER_THRESHOLD: %w", err)
+		}
+		config.BreakerThreshold = threshold
+	}
+
+	if v := os.Getenv("LLM_BREAKER_OPEN_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM_BREAKER_OPEN_TIMEOUT: %w", err)
+		}
+		config.BreakerOpenTimeout = timeout
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证模型降级链配置的有效性
+func (c *LLMFailoverConfig) Validate() error {
+	if len(c.Order) == 0 {
+		return fmt.Errorf("llm provider order cannot be empty")
+	}
+	seen := make(map[string]bool, len(c.Order))
+	for _, model := range c.Order {
+		switch model {
+		case LLMChainPrimary, LLMChainFallback:
+		case LLMChainLocal:
+			if c.LocalModel == "" {
+				return fmt.Errorf("llm provider order contains local but LLM_LOCAL_MODEL is not set")
+			}
+		default:
+			return fmt.Errorf("unknown model in llm provider order: %q", model)
+		}
+		if seen[model] {
+			return fmt.Errorf("duplicate model in llm provider order: %q", model)
+		}
+		seen[model] = true
+	}
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("llm retry max attempts must be at least 1, got: %d", c.MaxAttempts)
+	}
+	if c.RetryBackoff < 0 {
+		return fmt.Errorf("llm retry backoff cannot be negative, got: %v", c.RetryBackoff)
+	}
+	if c.BreakerThreshold < 1 {
+		return fmt.Errorf("llm breaker threshold must be at least 1, got: %d", c.BreakerThreshold)
+	}
+	if c.BreakerOpenTimeout <= 0 {
+		return fmt.Errorf("llm breaker open timeout must be positive, got: %v", c.BreakerOpenTimeout)
+	}
+	return nil
+}
+
+// LocalModelConfig 返回本地Ollama模型的调用配置，服务器地址取OLLAMA_SERVER_URL
+func (c *LLMFailoverConfig) LocalModelConfig() ModelConfig {
+	return ModelConfig{
+		Provider:    "ollama",
+		ModelName:   c.LocalModel,
+		Temperature: 0.1,
+		MaxTokens:   2048,
+		TopP:        0.9,
+		Timeout:     30 * time.Second,
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLLMFailoverConfigFromEnv(t *testing.T) {
+	t.Setenv("LLM_LOCAL_MODEL", "llama3.1:8b")
+	t.Setenv("LLM_RETRY_MAX_ATTEMPTS", "3")
+	t.Setenv("LLM_RETRY_BACKOFF", "100ms")
+	t.Setenv("LLM_BREAKER_THRESHOLD", "2")
+	t.Setenv("LLM_BREAKER_OPEN_TIMEOUT", "1m")
+
+	cfg, err := LoadLLMFailoverConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{LLMChainPrimary, LLMChainFallback, LLMChainLocal}, cfg.Order, "未指定顺序时本地模型排在最后")
+	assert.Equal(t, 3, cfg.MaxAttempts)
+	assert.Equal(t, 100*time.Millisecond, cfg.RetryBackoff)
+	assert.Equal(t, 2, cfg.BreakerThreshold)
+	assert.Equal(t, time.Minute, cfg.BreakerOpenTimeout)
+	assert.Equal(t, "ollama", cfg.LocalModelConfig().Provider)
+
+	t.Setenv("LLM_PROVIDER_ORDER", "fallback, local")
+	cfg, err = LoadLLMFailoverConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{LLMChainFallback, LLMChainLocal}, cfg.Order)
+
+	t.Setenv("LLM_PROVIDER_ORDER", "primary,primary")
+	_, err = LoadLLMFailoverConfigFromEnv()
+	assert.Error(t, err, "重复的模型")
+
+	t.Setenv("LLM_LOCAL_MODEL", "")
+	t.Setenv("LLM_PROVIDER_ORDER", "primary,local")
+	_, err = LoadLLMFailoverConfigFromEnv()
+	assert.Error(t, err, "未配置本地模型")
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LoggingConfig 日志级别与调试日志采样配置
+// 启动后可通过管理接口按模块调整级别，此处为启动时的初始值
+type LoggingConfig struct {
+	Level        string            `yaml:"level"`         // 默认日志级别
+	ModuleLevels map[string]string `yaml:"module_levels"` // 按模块覆盖的日志级别，模块为Named Logger名称，如sql、ai
+
+	// 高频调试日志采样：每个Tick内同一模块同一消息先输出前SampleInitial条，之后每SampleThereafter条输出1条
+	SampledModules   []string      `yaml:"sampled_modules"`
+	SampleInitial    int           `yaml:"sample_initial"`
+	SampleThereafter int           `yaml:"sample_thereafter"`
+	SampleTick       time.Duration `yaml:"sample_tick"`
+}
+
+// DefaultLoggingConfig 返回默认日志配置，SQL与AI调用链路的调试日志默认采样
+func DefaultLoggingConfig() *LoggingConfig {
+	return &LoggingConfig{
+		Level:            "info",
+		SampledModules:   []string{"sql", "ai"},
+		SampleInitial:    10,
+		SampleThereafter: 100,
+		SampleTick:       time.Second,
+	}
+}
+
+// LoadLoggingConfigFromEnv 从环境变量加载日志配置
+// LOG_MODULE_LEVELS格式为module=level,module2=level
+func LoadLoggingConfigFromEnv() (*LoggingConfig, error) {
+	config := DefaultLoggingConfig()
+
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		config.Level = strings.TrimSpace(v)
+	}
+
+	if v := os.Getenv("LOG_MODULE_LEVELS"); v != "" {
+		config.ModuleLevels = make(map[string]string)
+		for _, entry := range strings.Split(v, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			module, level, ok := strings.Cut(entry, "=")
+			module = strings.TrimSpace(module)
+			if !ok || module == "" {
+				return nil, fmt.Errorf("invalid LOG_MODULE_LEVELS: expected module=level, got %q", entry)
+			}
+			config.ModuleLevels[module] = strings.TrimSpace(level)
+		}
+	}
+
+	if v, ok := os.LookupEnv("LOG_SAMPLED_MODULES"); ok {
+		config.SampledModules = nil
+		for _, module := range strings.Split(v, ",") {
+			if module = strings.TrimSpace(module); module != "" {
+				config.SampledModules = append(config.SampledModules, module)
+			}
+		}
+	}
+
+	if v := os.Getenv("LOG_SAMPLE_INITIAL"); v != "" {
+		initial, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOG_SAMPLE_INITIAL: %w", err)
+		}
+		config.SampleInitial = initial
+	}
+
+	if v := os.Getenv("LOG_SAMPLE_THEREAFTER"); v != "" {
+		thereafter, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOG_SAMPLE_THEREAFTER: %w", err)
+		}
+		config.SampleThereafter = thereafter
+	}
+
+	if v := os.Getenv("LOG_SAMPLE_TICK"); v != "" {
+		tick, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOG_SAMPLE_TICK: %w", err)
+		}
+		config.SampleTick = tick
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证日志配置的有效性
+func (c *LoggingConfig) Validate() error {
+	if _, err := zapcore.ParseLevel(c.Level); err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	for module, level := range c.ModuleLevels {
+		if _, err := zapcore.ParseLevel(level); err != nil {
+			return fmt.Errorf("invalid log level for module %s: %w", module, err)
+		}
+	}
+	if c.SampleInitial < 0 || c.SampleThereafter < 0 {
+		return fmt.Errorf("log sample counts must not be negative, got initial=%d thereafter=%d", c.SampleInitial, c.SampleThereafter)
+	}
+	if len(c.SampledModules) > 0 && c.SampleTick <= 0 {
+		return fmt.Errorf("log sample tick must be positive, got: %v", c.SampleTick)
+	}
+	return nil
+}
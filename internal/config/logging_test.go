@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLoggingConfigFromEnv(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_MODULE_LEVELS", "sql=debug, ai=error")
+	t.Setenv("LOG_SAMPLED_MODULES", "sql")
+	t.Setenv("LOG_SAMPLE_TICK", "5s")
+
+	cfg, err := LoadLoggingConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "warn", cfg.Level)
+	assert.Equal(t, map[string]string{"sql": "debug", "ai": "error"}, cfg.ModuleLevels)
+	assert.Equal(t, []string{"sql"}, cfg.SampledModules)
+	assert.Equal(t, 5*time.Second, cfg.SampleTick)
+
+	t.Setenv("LOG_SAMPLED_MODULES", "")
+	cfg, err = LoadLoggingConfigFromEnv()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.SampledModules, "显式设置为空表示关闭采样")
+
+	t.Setenv("LOG_MODULE_LEVELS", "sql=verbose")
+	_, err = LoadLoggingConfigFromEnv()
+	assert.Error(t, err)
+}
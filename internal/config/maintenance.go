@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// MaintenanceConfig 只读维护模式的初始配置
+// 维护期间允许登录与浏览历史、表结构等只读接口，拒绝新的SQL生成与执行；启动后可通过管理接口随时开启或关闭
+type MaintenanceConfig struct {
+	Enabled bool      `yaml:"enabled"`
+	Message string    `yaml:"message"` // 返回给用户的说明，为空时使用默认提示
+	ETA     time.Time `yaml:"eta"`     // 预计恢复时间，零值表示未知
+}
+
+// DefaultMaintenanceConfig 返回默认维护模式配置（默认关闭）
+func DefaultMaintenanceConfig() *MaintenanceConfig {
+	return &MaintenanceConfig{}
+}
+
+// LoadMaintenanceConfigFromEnv 从环境变量加载维护模式配置
+func LoadMaintenanceConfigFromEnv() (*MaintenanceConfig, error) {
+	config := DefaultMaintenanceConfig()
+
+	if v := os.Getenv("MAINTENANCE_MODE_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+
+	config.Message = os.Getenv("MAINTENANCE_MODE_MESSAGE")
+
+	if v := os.Getenv("MAINTENANCE_MODE_ETA"); v != "" {
+		eta, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAINTENANCE_MODE_ETA: %w", err)
+		}
+		config.ETA = eta
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证维护模式配置的有效性
+func (c *MaintenanceConfig) Validate() error {
+	if len([]rune(c.Message)) > 500 {
+		return fmt.Errorf("maintenance message must not exceed 500 characters, got: %d", len([]rune(c.Message)))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMaintenanceConfigFromEnv(t *testing.T) {
+	cfg, err := LoadMaintenanceConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.True(t, cfg.ETA.IsZero())
+
+	t.Setenv("MAINTENANCE_MODE_ENABLED", "true")
+	t.Setenv("MAINTENANCE_MODE_MESSAGE", "元数据迁移中")
+	t.Setenv("MAINTENANCE_MODE_ETA", "2026-01-08T14:00:00+08:00")
+	cfg, err = LoadMaintenanceConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, "元数据迁移中", cfg.Message)
+	assert.True(t, cfg.ETA.Equal(time.Date(2026, 1, 8, 6, 0, 0, 0, time.UTC)))
+
+	t.Setenv("MAINTENANCE_MODE_ETA", "tomorrow")
+	_, err = LoadMaintenanceConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("MAINTENANCE_MODE_ETA", "")
+	t.Setenv("MAINTENANCE_MODE_MESSAGE", strings.Repeat("长", 501))
+	_, err = LoadMaintenanceConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MetricsCardinalityConfig 指标标签基数控制配置
+// 用户、连接等标签的取值随业务增长无上限，按哈希分桶、取值白名单与每个指标的序列上限约束时间序列数量
+type MetricsCardinalityConfig struct {
+	// MaxSeriesPerMetric 每个指标的最大序列数，超过后新的标签组合计入overflow序列
+	MaxSeriesPerMetric int `yaml:"max_series_per_metric"`
+
+	// HashedLabels 按哈希分桶的高基数标签，如user_id；HashBuckets为分桶数
+	HashedLabels []string `yaml:"hashed_labels"`
+	HashBuckets  int      `yaml:"hash_buckets"`
+
+	// LabelAllowlist 标签取值白名单，不在名单内的取值记为other
+	LabelAllowlist map[string][]string `yaml:"label_allowlist"`
+}
+
+// DefaultMetricsCardinalityConfig 返回默认指标标签基数控制配置，默认只限制序列数
+func DefaultMetricsCardinalityConfig() *MetricsCardinalityConfig {
+	return &MetricsCardinalityConfig{
+		MaxSeriesPerMetric: 2000,
+		HashBuckets:        32,
+	}
+}
+
+// LoadMetricsCardinalityConfigFromEnv 从环境变量加载指标标签基数控制配置
+// METRICS_LABEL_ALLOWLIST格式为label=v1|v2;label2=v3
+func LoadMetricsCardinalityConfigFromEnv() (*MetricsCardinalityConfig, error) {
+	config := DefaultMetricsCardinalityConfig()
+
+	if v := os.Getenv("METRICS_MAX_SERIES_PER_METRIC"); v != "" {
+		max, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid METRICS_MAX_SERIES_PER_METRIC: %w", err)
+		}
+		config.MaxSeriesPerMetric = max
+	}
+
+	if v := os.Getenv("METRICS_HASHED_LABELS"); v != "" {
+		for _, label := range strings.Split(v, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				config.HashedLabels = append(config.HashedLabels, label)
+			}
+		}
+	}
+
+	if v := os.Getenv("METRICS_HASH_BUCKETS"); v != "" {
+		buckets, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid METRICS_HASH_BUCKETS: %w", err)
+		}
+		config.HashBuckets = buckets
+	}
+
+	if v := os.Getenv("METRICS_LABEL_ALLOWLIST"); v != "" {
+		allowlist, err := parseLabelAllowlist(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid METRICS_LABEL_ALLOWLIST: %w", err)
+		}
+		config.LabelAllowlist = allowlist
+	}
+
+	return config, config.Validate()
+}
+
+// parseLabelAllowlist 解析label=v1|v2;label2=v3格式的标签白名单
+func parseLabelAllowlist(v string) (map[string][]string, error) {
+	allowlist := make(map[string][]string)
+	for _, entry := range strings.Split(v, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		label, values, ok := strings.Cut(entry, "=")
+		label = strings.TrimSpace(label)
+		if !ok || label == "" {
+			return nil, fmt.Errorf("expected label=value1|value2, got %q", entry)
+		}
+		for _, value := range strings.Split(values, "|") {
+			if value = strings.TrimSpace(value); value != "" {
+				allowlist[label] = append(allowlist[label], value)
+			}
+		}
+	}
+	return allowlist, nil
+}
+
+// Validate 验证指标标签基数控制配置的有效性
+func (c *MetricsCardinalityConfig) Validate() error {
+	if c.MaxSeriesPerMetric <= 0 {
+		return fmt.Errorf("metrics max series per metric must be positive, got: %d", c.MaxSeriesPerMetric)
+	}
+	if len(c.HashedLabels) > 0 && c.HashBuckets <= 0 {
+		return fmt.Errorf("metrics hash buckets must be positive, got: %d", c.HashBuckets)
+	}
+	for _, label := range c.HashedLabels {
+		if _, ok := c.LabelAllowlist[label]; ok {
+			return fmt.Errorf("metrics label %s cannot be both hashed and allowlisted", label)
+		}
+	}
+	return nil
+}
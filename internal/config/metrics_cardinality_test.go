@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMetricsCardinalityConfigFromEnv(t *testing.T) {
+	t.Setenv("METRICS_MAX_SERIES_PER_METRIC", "500")
+	t.Setenv("METRICS_HASHED_LABELS", "user_id, connection_id")
+	t.Setenv("METRICS_LABEL_ALLOWLIST", "db_type=postgresql|mysql; status=success|error")
+
+	cfg, err := LoadMetricsCardinalityConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 500, cfg.MaxSeriesPerMetric)
+	assert.Equal(t, []string{"user_id", "connection_id"}, cfg.HashedLabels)
+	assert.Equal(t, map[string][]string{
+		"db_type": {"postgresql", "mysql"},
+		"status":  {"success", "error"},
+	}, cfg.LabelAllowlist)
+
+	t.Setenv("METRICS_LABEL_ALLOWLIST", "user_id=1|2")
+	_, err = LoadMetricsCardinalityConfigFromEnv()
+	assert.Error(t, err, "同一标签不能既分桶又使用白名单")
+
+	t.Setenv("METRICS_LABEL_ALLOWLIST", "postgresql|mysql")
+	_, err = LoadMetricsCardinalityConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// PromptCacheConfig 模型响应缓存配置
+// 温度为0的调用输出确定，按提示词与生成参数的哈希缓存模型响应，TTL内相同请求不再调用提供商；
+// 与相似查询缓存不同，只有提示词完全相同才命中
+type PromptCacheConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	TTL           time.Duration `yaml:"ttl"`             // 响应的缓存时间
+	MaxEntryBytes int           `yaml:"max_entry_bytes"` // 序列化后超过该大小的响应不缓存
+}
+
+// DefaultPromptCacheConfig 返回默认模型响应缓存配置（默认关闭）
+func DefaultPromptCacheConfig() *PromptCacheConfig {
+	return &PromptCacheConfig{
+		Enabled:       false,
+		TTL:           10 * time.Minute,
+		MaxEntryBytes: 64 << 10,
+	}
+}
+
+// LoadPromptCacheConfigFromEnv 从环境变量加载模型响应缓存配置
+func LoadPromptCacheConfigFromEnv() (*PromptCacheConfig, error) {
+	config := DefaultPromptCacheConfig()
+
+	if v := os.Getenv("PROMPT_CACHE_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+
+	if v := os.Getenv("PROMPT_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROMPT_CACHE_TTL: %w", err)
+		}
+		config.TTL = ttl
+	}
+
+	if v := os.Getenv("PROMPT_CACHE_MAX_ENTRY_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROMPT_CACHE_MAX_ENTRY_BYTES: %w", err)
+		}
+		config.MaxEntryBytes = n
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证模型响应缓存配置的有效性
+func (c *PromptCacheConfig) Validate() error {
+	if c.TTL < time.Second {
+		return fmt.Errorf("prompt cache ttl must be at least 1s, got: %s", c.TTL)
+	}
+	if c.MaxEntryBytes <= 0 {
+		return fmt.Errorf("prompt cache max entry bytes must be positive, got: %d", c.MaxEntryBytes)
+	}
+	return nil
+}
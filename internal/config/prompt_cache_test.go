@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPromptCacheConfigFromEnv(t *testing.T) {
+	cfg, err := LoadPromptCacheConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled, "默认关闭")
+	assert.Equal(t, 10*time.Minute, cfg.TTL)
+
+	t.Setenv("PROMPT_CACHE_ENABLED", "true")
+	t.Setenv("PROMPT_CACHE_TTL", "1h")
+	t.Setenv("PROMPT_CACHE_MAX_ENTRY_BYTES", "4096")
+	cfg, err = LoadPromptCacheConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, time.Hour, cfg.TTL)
+	assert.Equal(t, 4096, cfg.MaxEntryBytes)
+
+	t.Setenv("PROMPT_CACHE_TTL", "500ms")
+	_, err = LoadPromptCacheConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("PROMPT_CACHE_TTL", "1h")
+	t.Setenv("PROMPT_CACHE_MAX_ENTRY_BYTES", "0")
+	_, err = LoadPromptCacheConfigFromEnv()
+	assert.Error(t, err)
+}
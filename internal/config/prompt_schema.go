@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// PromptSchemaConfig 提示词表结构配置
+// 请求未携带schema时，按问题关键词从已保存的元数据中挑选相关的表写入提示词，表数量与估算token数受限
+type PromptSchemaConfig struct {
+	MaxTables int `yaml:"max_tables"` // 写入提示词的最多表数，包括按外键补充的关联表
+	MaxTokens int `yaml:"max_tokens"` // 表结构部分的估算token上限，超出时丢弃排名靠后的表
+
+	// HistoryRanking 与问题相关的表中优先选择当前用户与工作空间成功查询过的表
+	HistoryRanking bool `yaml:"history_ranking"`
+}
+
+// DefaultPromptSchemaConfig 返回默认提示词表结构配置
+func DefaultPromptSchemaConfig() *PromptSchemaConfig {
+	return &PromptSchemaConfig{
+		MaxTables:      8,
+		MaxTokens:      2000,
+		HistoryRanking: true,
+	}
+}
+
+// LoadPromptSchemaConfigFromEnv 从环境变量加载提示词表结构配置
+func LoadPromptSchemaConfigFromEnv() (*PromptSchemaConfig, error) {
+	config := DefaultPromptSchemaConfig()
+
+	if v := os.Getenv("PROMPT_SCHEMA_MAX_TABLES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROMPT_SCHEMA_MAX_TABLES: %w", err)
+		}
+		config.MaxTables = n
+	}
+
+	if v := os.Getenv("PROMPT_SCHEMA_MAX_TOKENS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROMPT_SCHEMA_MAX_TOKENS: %w", err)
+		}
+		config.MaxTokens = n
+	}
+
+	if v := os.Getenv("PROMPT_SCHEMA_HISTORY_RANKING"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROMPT_SCHEMA_HISTORY_RANKING: %w", err)
+		}
+		config.HistoryRanking = enabled
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证提示词表结构配置的有效性
+func (c *PromptSchemaConfig) Validate() error {
+	if c.MaxTables < 1 {
+		return fmt.Errorf("prompt schema max tables must be positive, got: %d", c.MaxTables)
+	}
+	if c.MaxTokens < 100 {
+		return fmt.Errorf("prompt schema max tokens must be at least 100, got: %d", c.MaxTokens)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPromptSchemaConfigFromEnv(t *testing.T) {
+	cfg, err := LoadPromptSchemaConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 8, cfg.MaxTables)
+	assert.Equal(t, 2000, cfg.MaxTokens)
+	assert.True(t, cfg.HistoryRanking)
+
+	t.Setenv("PROMPT_SCHEMA_MAX_TABLES", "5")
+	t.Setenv("PROMPT_SCHEMA_MAX_TOKENS", "1200")
+	t.Setenv("PROMPT_SCHEMA_HISTORY_RANKING", "false")
+	cfg, err = LoadPromptSchemaConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.MaxTables)
+	assert.Equal(t, 1200, cfg.MaxTokens)
+	assert.False(t, cfg.HistoryRanking)
+
+	t.Setenv("PROMPT_SCHEMA_MAX_TOKENS", "50")
+	_, err = LoadPromptSchemaConfigFromEnv()
+	assert.Error(t, err, "上限过低时连一张表都放不下")
+
+	t.Setenv("PROMPT_SCHEMA_HISTORY_RANKING", "maybe")
+	_, err = LoadPromptSchemaConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("PROMPT_SCHEMA_HISTORY_RANKING", "true")
+	t.Setenv("PROMPT_SCHEMA_MAX_TABLES", "all")
+	_, err = LoadPromptSchemaConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// QueryJobConfig 异步查询任务配置
+// 耗时较长的分析查询提交为后台任务，由工作池领取执行，客户端轮询任务状态与结果
+type QueryJobConfig struct {
+	Workers           int           `yaml:"workers"`            // 每个实例同时执行的任务数
+	PollInterval      time.Duration `yaml:"poll_interval"`      // 空闲时扫描待执行任务的间隔
+	MaxRows           int           `yaml:"max_rows"`           // 单个任务最多读取并保存的行数
+	Timeout           time.Duration `yaml:"timeout"`            // 单个任务的执行超时
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"` // 执行中更新已读取行数与心跳的间隔
+	StaleAfter        time.Duration `yaml:"stale_after"`        // 心跳超过该时长未更新的任务视为实例已退出，重新排队
+	Retention         time.Duration `yaml:"retention"`          // 结束的任务及结果保留时长
+}
+
+// DefaultQueryJobConfig 返回默认异步查询任务配置
+func DefaultQueryJobConfig() *QueryJobConfig {
+	return &QueryJobConfig{
+		Workers:           2,
+		PollInterval:      2 * time.Second,
+		MaxRows:           10000,
+		Timeout:           30 * time.Minute,
+		HeartbeatInterval: 5 * time.Second,
+		StaleAfter:        2 * time.Minute,
+		Retention:         24 * time.Hour,
+	}
+}
+
+// LoadQueryJobConfigFromEnv 从环境变量加载异步查询任务配置
+func LoadQueryJobConfigFromEnv() (*QueryJobConfig, error) {
+	config := DefaultQueryJobConfig()
+
+	if v := os.Getenv("QUERY_JOB_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid QUERY_JOB_WORKERS: %w", err)
+		}
+		config.Workers = n
+	}
+
+	if v := os.Getenv("QUERY_JOB_MAX_ROWS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid QUERY_JOB_MAX_ROWS: %w", err)
+		}
+		config.MaxRows = n
+	}
+
+	durations := []struct {
+		env    string
+		target *time.Duration
+	}{
+		{"QUERY_JOB_POLL_INTERVAL", &config.PollInterval},
+		{"QUERY_JOB_TIMEOUT", &config.Timeout},
+		{"QUERY_JOB_HEARTBEAT_INTERVAL", &config.HeartbeatInterval},
+		{"QUERY_JOB_STALE_AFTER", &config.StaleAfter},
+		{"QUERY_JOB_RETENTION", &config.Retention},
+	}
+	for _, d := range durations {
+		if v := os.Getenv(d.env); v != "" {
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", d.env, err)
+			}
+			*d.target = duration
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证异步查询任务配置的有效性
+func (c *QueryJobConfig) Validate() error {
+	if c.Workers < 0 || c.Workers > 32 {
+		return fmt.Errorf("query job workers must be between 0 and 32, got: %d", c.Workers)
+	}
+	if c.MaxRows <= 0 {
+		return fmt.Errorf("query job max rows must be positive, got: %d", c.MaxRows)
+	}
+	if c.PollInterval <= 0 || c.Timeout <= 0 || c.HeartbeatInterval <= 0 || c.Retention <= 0 {
+		return fmt.Errorf("query job intervals, timeout and retention must be positive")
+	}
+	// 至少错过两次心跳才视为实例已退出，避免正常执行中的任务被重复领取
+	if c.StaleAfter < 2*c.HeartbeatInterval {
+		return fmt.Errorf("query job stale after must be at least twice the heartbeat interval, got: %v", c.StaleAfter)
+	}
+	return nil
+}
+
+// Enabled 是否在本实例执行异步查询任务，工作数为0的实例只接受提交
+func (c *QueryJobConfig) Enabled() bool {
+	return c != nil && c.Workers > 0
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadQueryJobConfigFromEnv(t *testing.T) {
+	cfg, err := LoadQueryJobConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.Workers)
+	assert.True(t, cfg.Enabled())
+
+	t.Setenv("QUERY_JOB_WORKERS", "0")
+	t.Setenv("QUERY_JOB_TIMEOUT", "1h")
+	t.Setenv("QUERY_JOB_RETENTION", "72h")
+	cfg, err = LoadQueryJobConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled())
+	assert.Equal(t, time.Hour, cfg.Timeout)
+	assert.Equal(t, 72*time.Hour, cfg.Retention)
+
+	t.Setenv("QUERY_JOB_HEARTBEAT_INTERVAL", "90s")
+	_, err = LoadQueryJobConfigFromEnv()
+	assert.Error(t, err, "心跳间隔的两倍超过重新排队时长")
+
+	t.Setenv("QUERY_JOB_HEARTBEAT_INTERVAL", "5s")
+	for _, invalid := range []string{"-1", "33", "two"} {
+		t.Setenv("QUERY_JOB_WORKERS", invalid)
+		_, err = LoadQueryJobConfigFromEnv()
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// QueryScheduleConfig 保存查询定时执行配置
+// 调度器按扫描间隔领取到期的执行计划，结果保存为执行记录，并可通过邮件或Webhook投递
+type QueryScheduleConfig struct {
+	Enabled       bool          `yaml:"enabled"`         // 是否在本实例执行到期的计划，关闭时仍可管理计划
+	PollInterval  time.Duration `yaml:"poll_interval"`   // 扫描到期计划的间隔
+	BatchSize     int           `yaml:"batch_size"`      // 每轮最多领取的计划数
+	Timeout       time.Duration `yaml:"timeout"`         // 单次执行与投递的超时，也是领取计划的租约时长
+	MinInterval   time.Duration `yaml:"min_interval"`    // 两次执行之间允许的最短间隔，防止每分钟执行的计划压垮目标库
+	MaxStoredRows int           `yaml:"max_stored_rows"` // 每条执行记录最多保存与投递的行数
+	RunRetention  time.Duration `yaml:"run_retention"`   // 执行记录保留时长
+	WebhookSecret string        `yaml:"webhook_secret"`  // 非空时Webhook请求带HMAC-SHA256签名
+	MaxRecipients int           `yaml:"max_recipients"`  // 邮件投递的收件人上限
+}
+
+// DefaultQueryScheduleConfig 返回默认定时执行配置
+func DefaultQueryScheduleConfig() *QueryScheduleConfig {
+	return &QueryScheduleConfig{
+		Enabled:       true,
+		PollInterval:  30 * time.Second,
+		BatchSize:     10,
+		Timeout:       5 * time.Minute,
+		MinInterval:   15 * time.Minute,
+		MaxStoredRows: 1000,
+		RunRetention:  30 * 24 * time.Hour,
+		MaxRecipients: 20,
+	}
+}
+
+// LoadQueryScheduleConfigFromEnv 从环境变量加载定时执行配置
+func LoadQueryScheduleConfigFromEnv() (*QueryScheduleConfig, error) {
+	config := DefaultQueryScheduleConfig()
+
+	if v := os.Getenv("QUERY_SCHEDULE_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+	config.WebhookSecret = os.Getenv("QUERY_SCHEDULE_WEBHOOK_SECRET")
+
+	ints := []struct {
+		env    string
+		target *int
+	}{
+		{"QUERY_SCHEDULE_BATCH_SIZE", &config.BatchSize},
+		{"QUERY_SCHEDULE_MAX_STORED_ROWS", &config.MaxStoredRows},
+		{"QUERY_SCHEDULE_MAX_RECIPIENTS", &config.MaxRecipients},
+	}
+	for _, i := range ints {
+		if v := os.Getenv(i.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", i.env, err)
+			}
+			*i.target = n
+		}
+	}
+
+	durations := []struct {
+		env    string
+		target *time.Duration
+	}{
+		{"QUERY_SCHEDULE_POLL_INTERVAL", &config.PollInterval},
+		{"QUERY_SCHEDULE_TIMEOUT", &config.Timeout},
+		{"QUERY_SCHEDULE_MIN_INTERVAL", &config.MinInterval},
+		{"QUERY_SCHEDULE_RUN_RETENTION", &config.RunRetention},
+	}
+	for _, d := range durations {
+		if v := os.Getenv(d.env); v != "" {
+			duration, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", d.env, err)
+			}
+			*d.target = duration
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证定时执行配置的有效性
+func (c *QueryScheduleConfig) Validate() error {
+	if c.PollInterval <= 0 || c.Timeout <= 0 || c.RunRetention <= 0 {
+		return fmt.Errorf("query schedule poll interval, timeout and run retention must be positive")
+	}
+	if c.MinInterval < time.Minute {
+		return fmt.Errorf("query schedule min interval must be at least 1m, got: %v", c.MinInterval)
+	}
+	if c.BatchSize <= 0 || c.BatchSize > 100 {
+		return fmt.Errorf("query schedule batch size must be between 1 and 100, got: %d", c.BatchSize)
+	}
+	if c.MaxStoredRows <= 0 {
+		return fmt.Errorf("query schedule max stored rows must be positive, got: %d", c.MaxStoredRows)
+	}
+	if c.MaxRecipients <= 0 {
+		return fmt.Errorf("query schedule max recipients must be positive, got: %d", c.MaxRecipients)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadQueryScheduleConfigFromEnv(t *testing.T) {
+	cfg, err := LoadQueryScheduleConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 15*time.Minute, cfg.MinInterval)
+
+	t.Setenv("QUERY_SCHEDULE_ENABLED", "false")
+	t.Setenv("QUERY_SCHEDULE_POLL_INTERVAL", "1m")
+	t.Setenv("QUERY_SCHEDULE_MIN_INTERVAL", "1h")
+	t.Setenv("QUERY_SCHEDULE_MAX_STORED_ROWS", "500")
+	t.Setenv("QUERY_SCHEDULE_WEBHOOK_SECRET", "s3cret")
+
+	cfg, err = LoadQueryScheduleConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, time.Minute, cfg.PollInterval)
+	assert.Equal(t, time.Hour, cfg.MinInterval)
+	assert.Equal(t, 500, cfg.MaxStoredRows)
+	assert.Equal(t, "s3cret", cfg.WebhookSecret)
+
+	t.Setenv("QUERY_SCHEDULE_MIN_INTERVAL", "30s")
+	_, err = LoadQueryScheduleConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("QUERY_SCHEDULE_MIN_INTERVAL", "1h")
+	t.Setenv("QUERY_SCHEDULE_BATCH_SIZE", "many")
+	_, err = LoadQueryScheduleConfigFromEnv()
+	assert.ErrorContains(t, err, "invalid QUERY_SCHEDULE_BATCH_SIZE")
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RealtimeConfig 实时事件推送配置
+type RealtimeConfig struct {
+	SubscriberBuffer int           `yaml:"subscriber_buffer"` // 每个订阅者的事件缓冲，写满后丢弃新事件
+	WriteTimeout     time.Duration `yaml:"write_timeout"`     // 向WebSocket客户端写入单个事件的超时时间
+	PingInterval     time.Duration `yaml:"ping_interval"`     // 空闲时发送心跳事件的间隔，用于穿过代理的空闲超时
+}
+
+// DefaultRealtimeConfig 返回默认实时事件推送配置
+func DefaultRealtimeConfig() *RealtimeConfig {
+	return &RealtimeConfig{
+		SubscriberBuffer: 64,
+		WriteTimeout:     10 * time.Second,
+		PingInterval:     30 * time.Second,
+	}
+}
+
+// LoadRealtimeConfigFromEnv 从环境变量加载实时事件推送配置
+func LoadRealtimeConfigFromEnv() (*RealtimeConfig, error) {
+	config := DefaultRealtimeConfig()
+
+	if v := os.Getenv("REALTIME_SUBSCRIBER_BUFFER"); v != "" {
+		buffer, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REALTIME_SUBSCRIBER_BUFFER: %w", err)
+		}
+		config.SubscriberBuffer = buffer
+	}
+
+	if v := os.Getenv("REALTIME_WRITE_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REALTIME_WRITE_TIMEOUT: %w", err)
+		}
+		config.WriteTimeout = timeout
+	}
+
+	if v := os.Getenv("REALTIME_PING_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REALTIME_PING_INTERVAL: %w", err)
+		}
+		config.PingInterval = interval
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证实时事件推送配置的有效性
+func (c *RealtimeConfig) Validate() error {
+	if c.SubscriberBuffer <= 0 {
+		return fmt.Errorf("realtime subscriber buffer must be positive, got: %d", c.SubscriberBuffer)
+	}
+	if c.WriteTimeout <= 0 {
+		return fmt.Errorf("realtime write timeout must be positive, got: %v", c.WriteTimeout)
+	}
+	if c.PingInterval <= 0 {
+		return fmt.Errorf("realtime ping interval must be positive, got: %v", c.PingInterval)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// regionPattern 对象存储区域名称格式，如eu-central-1、cn-north-1
+var regionPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// residencyPurposes 可配置存储位置的用途，与repository.StoragePurpose一致
+var residencyPurposes = []string{"snapshot", "export", "archive"}
+
+// ResidencyConfig 数据驻留配置
+type ResidencyConfig struct {
+	// AllowedRegions 工作空间可以选择的存储区域，为空表示不限制
+	AllowedRegions []string `yaml:"allowed_regions"`
+	// Locations 本服务按用途实际写入的存储位置，键为snapshot/export/archive；
+	// 未配置的用途在工作空间设置了驻留策略时拒绝写入
+	Locations map[string]StorageTarget `yaml:"locations"`
+}
+
+// StorageTarget 对象存储位置
+type StorageTarget struct {
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+}
+
+// DefaultResidencyConfig 返回默认数据驻留配置
+func DefaultResidencyConfig() *ResidencyConfig {
+	return &ResidencyConfig{}
+}
+
+// LoadResidencyConfigFromEnv 从环境变量加载数据驻留配置
+func LoadResidencyConfigFromEnv() (*ResidencyConfig, error) {
+	config := DefaultResidencyConfig()
+
+	if v := os.Getenv("DATA_RESIDENCY_ALLOWED_REGIONS"); v != "" {
+		for _, region := range strings.Split(v, ",") {
+			if region = strings.TrimSpace(region); region != "" {
+				config.AllowedRegions = append(config.AllowedRegions, region)
+			}
+		}
+	}
+
+	// DATA_RESIDENCY_SNAPSHOT_LOCATION等，格式为bucket@region
+	for _, purpose := range residencyPurposes {
+		key := "DATA_RESIDENCY_" + strings.ToUpper(purpose) + "_LOCATION"
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		bucket, region, ok := strings.Cut(v, "@")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: expected bucket@region", key)
+		}
+		if config.Locations == nil {
+			config.Locations = make(map[string]StorageTarget)
+		}
+		config.Locations[purpose] = StorageTarget{Bucket: strings.TrimSpace(bucket), Region: strings.TrimSpace(region)}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证数据驻留配置的有效性
+func (c *ResidencyConfig) Validate() error {
+	for _, region := range c.AllowedRegions {
+		if !regionPattern.MatchString(region) {
+			return fmt.Errorf("invalid data residency region: %q", region)
+		}
+	}
+	for purpose, target := range c.Locations {
+		if !slices.Contains(residencyPurposes, purpose) {
+			return fmt.Errorf("unknown data residency storage purpose: %q", purpose)
+		}
+		if target.Bucket == "" || !regionPattern.MatchString(target.Region) {
+			return fmt.Errorf("invalid data residency %s location: %s@%s", purpose, target.Bucket, target.Region)
+		}
+	}
+	return nil
+}
+
+// Allows 区域是否可以用于数据驻留
+func (c *ResidencyConfig) Allows(region string) bool {
+	if !regionPattern.MatchString(region) {
+		return false
+	}
+	return len(c.AllowedRegions) == 0 || slices.Contains(c.AllowedRegions, region)
+}
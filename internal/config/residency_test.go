@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadResidencyConfigFromEnv(t *testing.T) {
+	t.Setenv("DATA_RESIDENCY_ALLOWED_REGIONS", "eu-central-1, eu-west-1")
+
+	cfg, err := LoadResidencyConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eu-central-1", "eu-west-1"}, cfg.AllowedRegions)
+	assert.True(t, cfg.Allows("eu-west-1"))
+	assert.False(t, cfg.Allows("us-east-1"))
+
+	t.Setenv("DATA_RESIDENCY_EXPORT_LOCATION", "acme-exports-eu@eu-central-1")
+	cfg, err = LoadResidencyConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, StorageTarget{Bucket: "acme-exports-eu", Region: "eu-central-1"}, cfg.Locations["export"])
+
+	t.Setenv("DATA_RESIDENCY_EXPORT_LOCATION", "acme-exports-eu")
+	_, err = LoadResidencyConfigFromEnv()
+	assert.Error(t, err, "存储位置必须包含区域")
+
+	t.Setenv("DATA_RESIDENCY_EXPORT_LOCATION", "")
+	t.Setenv("DATA_RESIDENCY_ALLOWED_REGIONS", "EU Central")
+	_, err = LoadResidencyConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestResidencyConfig_AllowsAnyRegionByDefault(t *testing.T) {
+	cfg := DefaultResidencyConfig()
+	assert.True(t, cfg.Allows("us-east-1"))
+	assert.False(t, cfg.Allows(""))
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ResultCacheConfig 查询结果缓存配置
+// 同一连接上执行相同SQL时直接返回Redis中缓存的结果，避免仪表盘反复刷新同一查询压垮目标库
+type ResultCacheConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	TTL           time.Duration `yaml:"ttl"`             // 请求未指定时结果的缓存时间
+	MaxTTL        time.Duration `yaml:"max_ttl"`         // 请求可指定的最长缓存时间
+	MaxRows       int           `yaml:"max_rows"`        // 超过该行数的结果不缓存
+	MaxEntryBytes int           `yaml:"max_entry_bytes"` // 序列化后超过该大小的结果不缓存
+	MaxEntries    int           `yaml:"max_entries"`     // 每个连接最多缓存的结果数，超出时淘汰最早写入的结果
+}
+
+// DefaultResultCacheConfig 返回默认查询结果缓存配置（默认关闭）
+func DefaultResultCacheConfig() *ResultCacheConfig {
+	return &ResultCacheConfig{
+		Enabled:       false,
+		TTL:           5 * time.Minute,
+		MaxTTL:        time.Hour,
+		MaxRows:       1000,
+		MaxEntryBytes: 1 << 20,
+		MaxEntries:    200,
+	}
+}
+
+// LoadResultCacheConfigFromEnv 从环境变量加载查询结果缓存配置
+func LoadResultCacheConfigFromEnv() (*ResultCacheConfig, error) {
+	config := DefaultResultCacheConfig()
+
+	if v := os.Getenv("RESULT_CACHE_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+
+	durations := []struct {
+		env    string
+		target *time.Duration
+	}{
+		{"RESULT_CACHE_TTL", &config.TTL},
+		{"RESULT_CACHE_MAX_TTL", &config.MaxTTL},
+	}
+	for _, d := range durations {
+		if v := os.Getenv(d.env); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", d.env, err)
+			}
+			*d.target = parsed
+		}
+	}
+
+	ints := []struct {
+		env    string
+		target *int
+	}{
+		{"RESULT_CACHE_MAX_ROWS", &config.MaxRows},
+		{"RESULT_CACHE_MAX_ENTRY_BYTES", &config.MaxEntryBytes},
+		{"RESULT_CACHE_MAX_ENTRIES", &config.MaxEntries},
+	}
+	for _, i := range ints {
+		if v := os.Getenv(i.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", i.env, err)
+			}
+			*i.target = n
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证查询结果缓存配置的有效性
+func (c *ResultCacheConfig) Validate() error {
+	if c.TTL < time.Second {
+		return fmt.Errorf("result cache ttl must be at least 1s, got: %s", c.TTL)
+	}
+	if c.MaxTTL < c.TTL {
+		return fmt.Errorf("result cache max ttl must not be less than ttl %s, got: %s", c.TTL, c.MaxTTL)
+	}
+	if c.MaxRows <= 0 {
+		return fmt.Errorf("result cache max rows must be positive, got: %d", c.MaxRows)
+	}
+	if c.MaxEntryBytes <= 0 {
+		return fmt.Errorf("result cache max entry bytes must be positive, got: %d", c.MaxEntryBytes)
+	}
+	if c.MaxEntries <= 0 {
+		return fmt.Errorf("result cache max entries must be positive, got: %d", c.MaxEntries)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadResultCacheConfigFromEnv(t *testing.T) {
+	cfg, err := LoadResultCacheConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, 5*time.Minute, cfg.TTL)
+	assert.Equal(t, 1000, cfg.MaxRows)
+
+	t.Setenv("RESULT_CACHE_ENABLED", "true")
+	t.Setenv("RESULT_CACHE_TTL", "30s")
+	t.Setenv("RESULT_CACHE_MAX_TTL", "10m")
+	t.Setenv("RESULT_CACHE_MAX_ROWS", "500")
+	t.Setenv("RESULT_CACHE_MAX_ENTRY_BYTES", "65536")
+	t.Setenv("RESULT_CACHE_MAX_ENTRIES", "50")
+	cfg, err = LoadResultCacheConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 30*time.Second, cfg.TTL)
+	assert.Equal(t, 10*time.Minute, cfg.MaxTTL)
+	assert.Equal(t, 500, cfg.MaxRows)
+	assert.Equal(t, 65536, cfg.MaxEntryBytes)
+	assert.Equal(t, 50, cfg.MaxEntries)
+
+	t.Setenv("RESULT_CACHE_MAX_TTL", "10s")
+	_, err = LoadResultCacheConfigFromEnv()
+	assert.Error(t, err, "上限不能小于默认缓存时间")
+
+	t.Setenv("RESULT_CACHE_MAX_TTL", "10m")
+	t.Setenv("RESULT_CACHE_MAX_ROWS", "many")
+	_, err = LoadResultCacheConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ResultTableConfig 纯文本/Markdown结果表格配置
+// 聊天集成与屏幕阅读器使用服务端渲染的表格，只适合展示小结果集，超出部分以截断标记提示
+type ResultTableConfig struct {
+	MaxRows        int `yaml:"max_rows"`         // 最多渲染的行数
+	MaxColumnWidth int `yaml:"max_column_width"` // 单元格最大显示宽度，中日韩字符按2计
+	MaxWidth       int `yaml:"max_width"`        // 整行最大显示宽度，超出时省略靠后的列
+}
+
+// DefaultResultTableConfig 返回默认结果表格配置
+func DefaultResultTableConfig() *ResultTableConfig {
+	return &ResultTableConfig{
+		MaxRows:        20,
+		MaxColumnWidth: 32,
+		MaxWidth:       120,
+	}
+}
+
+// LoadResultTableConfigFromEnv 从环境变量加载结果表格配置
+func LoadResultTableConfigFromEnv() (*ResultTableConfig, error) {
+	config := DefaultResultTableConfig()
+
+	if v := os.Getenv("RESULT_TABLE_MAX_ROWS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESULT_TABLE_MAX_ROWS: %w", err)
+		}
+		config.MaxRows = n
+	}
+
+	if v := os.Getenv("RESULT_TABLE_MAX_COLUMN_WIDTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESULT_TABLE_MAX_COLUMN_WIDTH: %w", err)
+		}
+		config.MaxColumnWidth = n
+	}
+
+	if v := os.Getenv("RESULT_TABLE_MAX_WIDTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RESULT_TABLE_MAX_WIDTH: %w", err)
+		}
+		config.MaxWidth = n
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证结果表格配置的有效性
+func (c *ResultTableConfig) Validate() error {
+	if c.MaxRows <= 0 {
+		return fmt.Errorf("result table max rows must be positive, got: %d", c.MaxRows)
+	}
+	if c.MaxColumnWidth < 4 {
+		return fmt.Errorf("result table max column width must be at least 4, got: %d", c.MaxColumnWidth)
+	}
+	if c.MaxWidth < c.MaxColumnWidth {
+		return fmt.Errorf("result table max width (%d) cannot be less than max column width (%d)", c.MaxWidth, c.MaxColumnWidth)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadResultTableConfigFromEnv(t *testing.T) {
+	t.Setenv("RESULT_TABLE_MAX_ROWS", "10")
+	t.Setenv("RESULT_TABLE_MAX_COLUMN_WIDTH", "20")
+	t.Setenv("RESULT_TABLE_MAX_WIDTH", "80")
+
+	cfg, err := LoadResultTableConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.MaxRows)
+	assert.Equal(t, 20, cfg.MaxColumnWidth)
+	assert.Equal(t, 80, cfg.MaxWidth)
+
+	t.Setenv("RESULT_TABLE_MAX_WIDTH", "10")
+	_, err = LoadResultTableConfigFromEnv()
+	assert.Error(t, err, "整行宽度不能小于单元格宽度")
+
+	t.Setenv("RESULT_TABLE_MAX_ROWS", "many")
+	_, err = LoadResultTableConfigFromEnv()
+	assert.Error(t, err)
+}
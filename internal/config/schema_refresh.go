@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// SchemaRefreshConfig 表结构定期刷新配置
+// 定期重新探测活跃连接的表结构，与已保存的元数据比较，有变化时更新，避免用户改表后提示词中的表结构过时
+type SchemaRefreshConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"` // 两轮刷新之间的间隔
+}
+
+// DefaultSchemaRefreshConfig 返回默认表结构刷新配置（默认关闭）
+func DefaultSchemaRefreshConfig() *SchemaRefreshConfig {
+	return &SchemaRefreshConfig{
+		Enabled:  false,
+		Interval: 6 * time.Hour,
+	}
+}
+
+// LoadSchemaRefreshConfigFromEnv 从环境变量加载表结构刷新配置
+func LoadSchemaRefreshConfigFromEnv() (*SchemaRefreshConfig, error) {
+	config := DefaultSchemaRefreshConfig()
+
+	if v := os.Getenv("SCHEMA_REFRESH_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+
+	if v := os.Getenv("SCHEMA_REFRESH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCHEMA_REFRESH_INTERVAL: %w", err)
+		}
+		config.Interval = d
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证表结构刷新配置的有效性
+func (c *SchemaRefreshConfig) Validate() error {
+	if c.Interval < time.Minute {
+		return fmt.Errorf("schema refresh interval must be at least 1m, got: %s", c.Interval)
+	}
+	return nil
+}
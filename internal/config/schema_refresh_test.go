@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSchemaRefreshConfigFromEnv(t *testing.T) {
+	cfg, err := LoadSchemaRefreshConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, 6*time.Hour, cfg.Interval)
+
+	t.Setenv("SCHEMA_REFRESH_ENABLED", "true")
+	t.Setenv("SCHEMA_REFRESH_INTERVAL", "30m")
+	cfg, err = LoadSchemaRefreshConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 30*time.Minute, cfg.Interval)
+
+	t.Setenv("SCHEMA_REFRESH_INTERVAL", "10s")
+	_, err = LoadSchemaRefreshConfigFromEnv()
+	assert.Error(t, err, "间隔过短会频繁探测目标库")
+
+	t.Setenv("SCHEMA_REFRESH_INTERVAL", "soon")
+	_, err = LoadSchemaRefreshConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SchemaWarmupConfig 新建连接的元数据预热配置
+type SchemaWarmupConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // 创建连接后是否立即预热
+	MaxConcurrent int           `yaml:"max_concurrent"` // 同时执行的预热任务数上限，超出的任务排队
+	Timeout       time.Duration `yaml:"timeout"`        // 单个预热任务的超时时间
+}
+
+// DefaultSchemaWarmupConfig 返回默认预热配置
+func DefaultSchemaWarmupConfig() *SchemaWarmupConfig {
+	return &SchemaWarmupConfig{
+		Enabled:       true,
+		MaxConcurrent: 2,
+		Timeout:       5 * time.Minute,
+	}
+}
+
+// LoadSchemaWarmupConfigFromEnv 从环境变量加载预热配置
+func LoadSchemaWarmupConfigFromEnv() (*SchemaWarmupConfig, error) {
+	config := DefaultSchemaWarmupConfig()
+
+	if v := os.Getenv("SCHEMA_WARMUP_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+
+	if v := os.Getenv("SCHEMA_WARMUP_MAX_CONCURRENT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCHEMA_WARMUP_MAX_CONCURRENT: %w", err)
+		}
+		config.MaxConcurrent = n
+	}
+
+	if v := os.Getenv("SCHEMA_WARMUP_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCHEMA_WARMUP_TIMEOUT: %w", err)
+		}
+		config.Timeout = timeout
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证预热配置的有效性
+func (c *SchemaWarmupConfig) Validate() error {
+	if c.MaxConcurrent <= 0 {
+		return fmt.Errorf("schema warmup max concurrent must be positive, got: %d", c.MaxConcurrent)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("schema warmup timeout must be positive, got: %v", c.Timeout)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSchemaWarmupConfigFromEnv(t *testing.T) {
+	t.Setenv("SCHEMA_WARMUP_ENABLED", "false")
+	t.Setenv("SCHEMA_WARMUP_MAX_CONCURRENT", "4")
+	t.Setenv("SCHEMA_WARMUP_TIMEOUT", "90s")
+
+	cfg, err := LoadSchemaWarmupConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, 4, cfg.MaxConcurrent)
+	assert.Equal(t, 90*time.Second, cfg.Timeout)
+
+	t.Setenv("SCHEMA_WARMUP_MAX_CONCURRENT", "0")
+	_, err = LoadSchemaWarmupConfigFromEnv()
+	assert.Error(t, err, "并发数必须为正数")
+}
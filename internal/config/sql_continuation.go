@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// SQLContinuationConfig 截断SQL续写配置
+// 模型输出达到MaxTokens在语句中途停止时，检测到SQL不完整则请求模型从截断处继续输出并拼接，
+// 而不是把残缺的SQL交给校验器
+type SQLContinuationConfig struct {
+	MaxContinuations int `yaml:"max_continuations"` // 单次生成最多续写次数，0表示不续写
+}
+
+// DefaultSQLContinuationConfig 返回默认截断SQL续写配置
+func DefaultSQLContinuationConfig() *SQLContinuationConfig {
+	return &SQLContinuationConfig{
+		MaxContinuations: 2,
+	}
+}
+
+// LoadSQLContinuationConfigFromEnv 从环境变量加载截断SQL续写配置
+func LoadSQLContinuationConfigFromEnv() (*SQLContinuationConfig, error) {
+	config := DefaultSQLContinuationConfig()
+
+	if v := os.Getenv("SQL_CONTINUATION_MAX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SQL_CONTINUATION_MAX: %w", err)
+		}
+		config.MaxContinuations = n
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证截断SQL续写配置的有效性
+func (c *SQLContinuationConfig) Validate() error {
+	if c.MaxContinuations < 0 || c.MaxContinuations > 5 {
+		return fmt.Errorf("sql continuation max must be between 0 and 5, got: %d", c.MaxContinuations)
+	}
+	return nil
+}
+
+// Enabled 是否启用续写
+func (c *SQLContinuationConfig) Enabled() bool {
+	return c != nil && c.MaxContinuations > 0
+}
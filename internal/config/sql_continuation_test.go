@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSQLContinuationConfigFromEnv(t *testing.T) {
+	cfg, err := LoadSQLContinuationConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.MaxContinuations)
+	assert.True(t, cfg.Enabled())
+
+	t.Setenv("SQL_CONTINUATION_MAX", "0")
+	cfg, err = LoadSQLContinuationConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled())
+
+	var nilConfig *SQLContinuationConfig
+	assert.False(t, nilConfig.Enabled())
+
+	for _, invalid := range []string{"-1", "6", "two"} {
+		t.Setenv("SQL_CONTINUATION_MAX", invalid)
+		_, err = LoadSQLContinuationConfigFromEnv()
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SQLGuardrailConfig 交互查询的执行防护
+// 模型偶尔生成没有LIMIT的SELECT，返回数百万行会耗尽服务内存；连接可以单独设置行数与大小上限覆盖这里的默认值
+type SQLGuardrailConfig struct {
+	QueryTimeout time.Duration `yaml:"query_timeout"` // 单条查询的执行超时
+	MaxRows      int           `yaml:"max_rows"`      // 返回的最大行数，超出部分截断
+	MaxResultMB  int           `yaml:"max_result_mb"` // 返回结果的最大大小(MB)，超出部分截断
+	AutoLimit    bool          `yaml:"auto_limit"`    // SQL没有LIMIT时追加LIMIT，由数据库限制读取的行数
+
+	// 执行SQL前按EXPLAIN预估代价与读取行数，超过Warn*时提示，超过Max*时拒绝执行；阈值为0表示不检查该项
+	// MySQL的代价单位与PostgreSQL不同，混用两种连接时代价阈值以PostgreSQL为准
+	CostPrecheck bool    `yaml:"cost_precheck"`
+	WarnCost     float64 `yaml:"warn_cost"`
+	MaxCost      float64 `yaml:"max_cost"`
+	WarnScanRows float64 `yaml:"warn_scan_rows"`
+	MaxScanRows  float64 `yaml:"max_scan_rows"`
+}
+
+// DefaultSQLGuardrailConfig 返回默认查询执行防护配置
+func DefaultSQLGuardrailConfig() *SQLGuardrailConfig {
+	return &SQLGuardrailConfig{
+		QueryTimeout: 30 * time.Second,
+		MaxRows:      1000,
+		MaxResultMB:  10,
+		AutoLimit:    true,
+		CostPrecheck: true,
+		WarnCost:     100000,
+		MaxCost:      10000000,
+		WarnScanRows: 1000000,
+		MaxScanRows:  100000000,
+	}
+}
+
+// LoadSQLGuardrailConfigFromEnv 从环境变量加载查询执行防护配置
+func LoadSQLGuardrailConfigFromEnv() (*SQLGuardrailConfig, error) {
+	config := DefaultSQLGuardrailConfig()
+
+	if v := os.Getenv("SQL_QUERY_TIMEOUT"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SQL_QUERY_TIMEOUT: %w", err)
+		}
+		config.QueryTimeout = parsed
+	}
+
+	ints := []struct {
+		env    string
+		target *int
+	}{
+		{"SQL_MAX_ROWS", &config.MaxRows},
+		{"SQL_MAX_RESULT_MB", &config.MaxResultMB},
+	}
+	for _, i := range ints {
+		if v := os.Getenv(i.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", i.env, err)
+			}
+			*i.target = n
+		}
+	}
+
+	bools := []struct {
+		env    string
+		target *bool
+	}{
+		{"SQL_AUTO_LIMIT", &config.AutoLimit},
+		{"SQL_COST_PRECHECK", &config.CostPrecheck},
+	}
+	for _, b := range bools {
+		if v := os.Getenv(b.env); v != "" {
+			enabled, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", b.env, err)
+			}
+			*b.target = enabled
+		}
+	}
+
+	floats := []struct {
+		env    string
+		target *float64
+	}{
+		{"SQL_WARN_COST", &config.WarnCost},
+		{"SQL_MAX_COST", &config.MaxCost},
+		{"SQL_WARN_SCAN_ROWS", &config.WarnScanRows},
+		{"SQL_MAX_SCAN_ROWS", &config.MaxScanRows},
+	}
+	for _, f := range floats {
+		if v := os.Getenv(f.env); v != "" {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", f.env, err)
+			}
+			*f.target = n
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证查询执行防护配置的有效性
+func (c *SQLGuardrailConfig) Validate() error {
+	if c.QueryTimeout < time.Second {
+		return fmt.Errorf("sql query timeout must be at least 1s, got: %s", c.QueryTimeout)
+	}
+	if c.MaxRows <= 0 || c.MaxRows > MaxConnectionRows {
+		return fmt.Errorf("sql max rows must be between 1 and %d, got: %d", MaxConnectionRows, c.MaxRows)
+	}
+	if c.MaxResultMB <= 0 || c.MaxResultMB > MaxConnectionResultMB {
+		return fmt.Errorf("sql max result mb must be between 1 and %d, got: %d", MaxConnectionResultMB, c.MaxResultMB)
+	}
+	if c.WarnCost < 0 || c.MaxCost < 0 || c.WarnScanRows < 0 || c.MaxScanRows < 0 {
+		return fmt.Errorf("sql cost precheck thresholds must not be negative")
+	}
+	if c.WarnCost > 0 && c.MaxCost > 0 && c.WarnCost > c.MaxCost {
+		return fmt.Errorf("sql warn cost (%g) must not exceed max cost (%g)", c.WarnCost, c.MaxCost)
+	}
+	if c.WarnScanRows > 0 && c.MaxScanRows > 0 && c.WarnScanRows > c.MaxScanRows {
+		return fmt.Errorf("sql warn scan rows (%g) must not exceed max scan rows (%g)", c.WarnScanRows, c.MaxScanRows)
+	}
+	return nil
+}
+
+// 全局与连接级结果上限的取值范围
+const (
+	MaxConnectionRows     = 1000000
+	MaxConnectionResultMB = 1024
+)
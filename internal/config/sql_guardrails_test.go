@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSQLGuardrailConfigFromEnv(t *testing.T) {
+	cfg, err := LoadSQLGuardrailConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.QueryTimeout)
+	assert.Equal(t, 1000, cfg.MaxRows)
+	assert.Equal(t, 10, cfg.MaxResultMB)
+	assert.True(t, cfg.AutoLimit)
+	assert.True(t, cfg.CostPrecheck)
+	assert.Equal(t, 100000.0, cfg.WarnCost)
+	assert.Equal(t, 10000000.0, cfg.MaxCost)
+
+	t.Setenv("SQL_QUERY_TIMEOUT", "1m")
+	t.Setenv("SQL_MAX_ROWS", "5000")
+	t.Setenv("SQL_MAX_RESULT_MB", "50")
+	t.Setenv("SQL_AUTO_LIMIT", "false")
+	cfg, err = LoadSQLGuardrailConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, cfg.QueryTimeout)
+	assert.Equal(t, 5000, cfg.MaxRows)
+	assert.Equal(t, 50, cfg.MaxResultMB)
+	assert.False(t, cfg.AutoLimit)
+
+	t.Setenv("SQL_MAX_RESULT_MB", "4096")
+	_, err = LoadSQLGuardrailConfigFromEnv()
+	assert.Error(t, err, "超过大小上限的取值范围")
+
+	t.Setenv("SQL_MAX_RESULT_MB", "50")
+	t.Setenv("SQL_AUTO_LIMIT", "sometimes")
+	_, err = LoadSQLGuardrailConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("SQL_AUTO_LIMIT", "true")
+	t.Setenv("SQL_COST_PRECHECK", "false")
+	t.Setenv("SQL_MAX_COST", "0")
+	t.Setenv("SQL_WARN_SCAN_ROWS", "5e5")
+	cfg, err = LoadSQLGuardrailConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.CostPrecheck)
+	assert.Zero(t, cfg.MaxCost, "0表示不按代价拒绝")
+	assert.Equal(t, 500000.0, cfg.WarnScanRows)
+
+	t.Setenv("SQL_MAX_SCAN_ROWS", "1000")
+	_, err = LoadSQLGuardrailConfigFromEnv()
+	assert.Error(t, err, "警告阈值大于拒绝阈值")
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// SQLTemplateConfig 常见意图的SQL模板配置
+// 统计表行数、查看表最近N行等高置信度问题由模板直接生成SQL，不调用模型
+type SQLTemplateConfig struct {
+	Enabled             bool    `yaml:"enabled"`               // 是否启用模板快速路径
+	MinIntentConfidence float64 `yaml:"min_intent_confidence"` // 意图分类置信度低于该值时交给模型生成
+	MaxRows             int     `yaml:"max_rows"`              // 最近N行模板允许的最大N，超过时交给模型生成
+}
+
+// DefaultSQLTemplateConfig 返回默认SQL模板配置
+func DefaultSQLTemplateConfig() *SQLTemplateConfig {
+	return &SQLTemplateConfig{
+		Enabled:             true,
+		MinIntentConfidence: 0.7,
+		MaxRows:             1000,
+	}
+}
+
+// LoadSQLTemplateConfigFromEnv 从环境变量加载SQL模板配置
+func LoadSQLTemplateConfigFromEnv() (*SQLTemplateConfig, error) {
+	config := DefaultSQLTemplateConfig()
+
+	if v := os.Getenv("SQL_TEMPLATES_ENABLED"); v != "" {
+		config.Enabled = v == "true"
+	}
+
+	if v := os.Getenv("SQL_TEMPLATES_MIN_CONFIDENCE"); v != "" {
+		confidence, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SQL_TEMPLATES_MIN_CONFIDENCE: %w", err)
+		}
+		config.MinIntentConfidence = confidence
+	}
+
+	if v := os.Getenv("SQL_TEMPLATES_MAX_ROWS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SQL_TEMPLATES_MAX_ROWS: %w", err)
+		}
+		config.MaxRows = n
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证SQL模板配置的有效性
+func (c *SQLTemplateConfig) Validate() error {
+	if c.MinIntentConfidence < 0 || c.MinIntentConfidence > 1 {
+		return fmt.Errorf("sql template min intent confidence must be between 0 and 1, got: %v", c.MinIntentConfidence)
+	}
+	if c.MaxRows <= 0 {
+		return fmt.Errorf("sql template max rows must be positive, got: %d", c.MaxRows)
+	}
+	return nil
+}
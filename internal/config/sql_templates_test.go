@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSQLTemplateConfigFromEnv(t *testing.T) {
+	cfg, err := LoadSQLTemplateConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, 0.7, cfg.MinIntentConfidence)
+	assert.Equal(t, 1000, cfg.MaxRows)
+
+	t.Setenv("SQL_TEMPLATES_ENABLED", "false")
+	t.Setenv("SQL_TEMPLATES_MIN_CONFIDENCE", "0.9")
+	t.Setenv("SQL_TEMPLATES_MAX_ROWS", "200")
+	cfg, err = LoadSQLTemplateConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled)
+	assert.Equal(t, 0.9, cfg.MinIntentConfidence)
+	assert.Equal(t, 200, cfg.MaxRows)
+
+	t.Setenv("SQL_TEMPLATES_MIN_CONFIDENCE", "1.5")
+	_, err = LoadSQLTemplateConfigFromEnv()
+	assert.Error(t, err)
+}
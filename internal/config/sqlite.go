@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SQLiteConfig SQLite连接配置
+// SQLite连接直接读取服务端的数据库文件，只允许访问DataDir下的文件，避免通过连接读取服务端任意文件
+type SQLiteConfig struct {
+	DataDir string `yaml:"data_dir"` // SQLite数据库文件所在目录，连接的database_name为该目录下的相对路径；为空时不允许创建SQLite连接
+}
+
+// DefaultSQLiteConfig 返回默认SQLite连接配置，默认不允许SQLite连接
+func DefaultSQLiteConfig() *SQLiteConfig {
+	return &SQLiteConfig{}
+}
+
+// LoadSQLiteConfigFromEnv 从环境变量加载SQLite连接配置
+func LoadSQLiteConfigFromEnv() (*SQLiteConfig, error) {
+	config := DefaultSQLiteConfig()
+
+	if v := os.Getenv("SQLITE_DATA_DIR"); v != "" {
+		config.DataDir = v
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证SQLite连接配置的有效性
+func (c *SQLiteConfig) Validate() error {
+	if c.DataDir != "" && !filepath.IsAbs(c.DataDir) {
+		return fmt.Errorf("sqlite data dir must be an absolute path, got: %s", c.DataDir)
+	}
+	return nil
+}
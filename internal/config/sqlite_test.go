@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSQLiteConfigFromEnv(t *testing.T) {
+	cfg, err := LoadSQLiteConfigFromEnv()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.DataDir)
+
+	t.Setenv("SQLITE_DATA_DIR", "/var/lib/chat2sql/sqlite")
+	cfg, err = LoadSQLiteConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/chat2sql/sqlite", cfg.DataDir)
+
+	t.Setenv("SQLITE_DATA_DIR", "data/sqlite")
+	_, err = LoadSQLiteConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// StartupConfig 服务启动配置
+type StartupConfig struct {
+	Addr           string        `yaml:"addr"`            // HTTP监听地址
+	ProbeAddr      string        `yaml:"probe_addr"`      // 健康检查与指标的独立监听地址，为空时只在Addr上提供
+	MaxInFlight    int           `yaml:"max_in_flight"`   // 同时处理的API请求上限，超过时返回503，0为不限制；健康检查与指标不计入
+	MaxWait        time.Duration `yaml:"max_wait"`        // 等待依赖服务就绪的最长时间，超过后退出
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // 首次重试前的等待时间
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // 重试等待时间上限
+}
+
+// DefaultStartupConfig 返回默认服务启动配置
+func DefaultStartupConfig() *StartupConfig {
+	return &StartupConfig{
+		Addr:           ":8080",
+		MaxWait:        2 * time.Minute,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     15 * time.Second,
+	}
+}
+
+// LoadStartupConfigFromEnv 从环境变量加载服务启动配置
+func LoadStartupConfigFromEnv() (*StartupConfig, error) {
+	config := DefaultStartupConfig()
+
+	if v := os.Getenv("SERVER_ADDR"); v != "" {
+		config.Addr = v
+	}
+	if v := os.Getenv("SERVER_PROBE_ADDR"); v != "" {
+		config.ProbeAddr = v
+	}
+	if v := os.Getenv("SERVER_MAX_IN_FLIGHT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SERVER_MAX_IN_FLIGHT: %w", err)
+		}
+		config.MaxInFlight = n
+	}
+
+	durations := []struct {
+		env    string
+		target *time.Duration
+	}{
+		{"STARTUP_MAX_WAIT", &config.MaxWait},
+		{"STARTUP_INITIAL_BACKOFF", &config.InitialBackoff},
+		{"STARTUP_MAX_BACKOFF", &config.MaxBackoff},
+	}
+	for _, d := range durations {
+		if v := os.Getenv(d.env); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", d.env, err)
+			}
+			*d.target = parsed
+		}
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证服务启动配置的有效性
+func (c *StartupConfig) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("server addr cannot be empty")
+	}
+	if c.ProbeAddr != "" && c.ProbeAddr == c.Addr {
+		return fmt.Errorf("server probe addr must differ from server addr: %s", c.Addr)
+	}
+	if c.MaxInFlight < 0 {
+		return fmt.Errorf("server max in flight cannot be negative, got: %d", c.MaxInFlight)
+	}
+	if c.MaxWait <= 0 {
+		return fmt.Errorf("startup max wait must be positive, got: %v", c.MaxWait)
+	}
+	if c.InitialBackoff <= 0 {
+		return fmt.Errorf("startup initial backoff must be positive, got: %v", c.InitialBackoff)
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		return fmt.Errorf("startup max backoff (%v) cannot be less than initial backoff (%v)", c.MaxBackoff, c.InitialBackoff)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadStartupConfigFromEnv(t *testing.T) {
+	t.Setenv("SERVER_ADDR", ":9090")
+	t.Setenv("STARTUP_MAX_WAIT", "5m")
+	t.Setenv("STARTUP_INITIAL_BACKOFF", "1s")
+	t.Setenv("STARTUP_MAX_BACKOFF", "30s")
+	t.Setenv("SERVER_PROBE_ADDR", ":9091")
+	t.Setenv("SERVER_MAX_IN_FLIGHT", "200")
+
+	cfg, err := LoadStartupConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Addr)
+	assert.Equal(t, ":9091", cfg.ProbeAddr)
+	assert.Equal(t, 200, cfg.MaxInFlight)
+	assert.Equal(t, 5*time.Minute, cfg.MaxWait)
+	assert.Equal(t, time.Second, cfg.InitialBackoff)
+	assert.Equal(t, 30*time.Second, cfg.MaxBackoff)
+
+	t.Setenv("SERVER_PROBE_ADDR", ":9090")
+	_, err = LoadStartupConfigFromEnv()
+	assert.ErrorContains(t, err, "probe addr")
+	t.Setenv("SERVER_PROBE_ADDR", "")
+
+	t.Setenv("SERVER_MAX_IN_FLIGHT", "-1")
+	_, err = LoadStartupConfigFromEnv()
+	assert.Error(t, err)
+	t.Setenv("SERVER_MAX_IN_FLIGHT", "0")
+
+	t.Setenv("STARTUP_MAX_BACKOFF", "100ms")
+	_, err = LoadStartupConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("STARTUP_MAX_WAIT", "soon")
+	_, err = LoadStartupConfigFromEnv()
+	assert.ErrorContains(t, err, "invalid STARTUP_MAX_WAIT")
+}
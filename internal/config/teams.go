@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultBotFrameworkMetadataURL Bot Framework的OpenID元数据地址，用于获取校验JWT的公钥
+const DefaultBotFrameworkMetadataURL = "https://login.botframework.com/v1/.well-known/openidconfiguration"
+
+// TeamsConfig Microsoft Teams机器人集成配置
+type TeamsConfig struct {
+	Enabled             bool          `yaml:"enabled"`
+	SigningSecret       string        `yaml:"signing_secret"`        // Teams传出Webhook的HMAC密钥（Base64编码）
+	AppID               string        `yaml:"app_id"`                // Bot Framework机器人的应用ID，Bot Framework JWT的受众；为空时不提供分页按钮
+	MetadataURL         string        `yaml:"metadata_url"`          // Bot Framework OpenID元数据地址
+	ServiceUserID       int64         `yaml:"service_user_id"`       // 机器人代为执行查询的系统用户ID
+	DefaultConnectionID int64         `yaml:"default_connection_id"` // 未显式指定时使用的数据库连接
+	PageSize            int           `yaml:"page_size"`             // 自适应卡片每页显示行数
+	PageTTL             time.Duration `yaml:"page_ttl"`              // 分页令牌的有效期，过期后需重新提问
+	AppBaseURL          string        `yaml:"app_base_url"`          // "在Chat2SQL中打开"深链接的前端地址
+}
+
+// DefaultTeamsConfig 返回默认Teams配置（默认关闭）
+func DefaultTeamsConfig() *TeamsConfig {
+	return &TeamsConfig{
+		Enabled:     false,
+		MetadataURL: DefaultBotFrameworkMetadataURL,
+		PageSize:    10,
+		PageTTL:     24 * time.Hour,
+		AppBaseURL:  "http://localhost:3000",
+	}
+}
+
+// LoadTeamsConfigFromEnv 从环境变量加载Teams配置
+func LoadTeamsConfigFromEnv() (*TeamsConfig, error) {
+	config := DefaultTeamsConfig()
+
+	config.SigningSecret = os.Getenv("TEAMS_SIGNING_SECRET")
+	config.AppID = os.Getenv("TEAMS_APP_ID")
+	if config.SigningSecret == "" && config.AppID == "" {
+		// 未配置密钥与机器人应用ID时视为未启用集成
+		return config, nil
+	}
+	config.Enabled = true
+
+	if v := os.Getenv("TEAMS_OPENID_METADATA_URL"); v != "" {
+		config.MetadataURL = v
+	}
+
+	if v := os.Getenv("TEAMS_SERVICE_USER_ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TEAMS_SERVICE_USER_ID: %w", err)
+		}
+		config.ServiceUserID = id
+	}
+
+	if v := os.Getenv("TEAMS_DEFAULT_CONNECTION_ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TEAMS_DEFAULT_CONNECTION_ID: %w", err)
+		}
+		config.DefaultConnectionID = id
+	}
+
+	if v := os.Getenv("TEAMS_PAGE_SIZE"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil {
+			config.PageSize = size
+		}
+	}
+
+	if v := os.Getenv("TEAMS_PAGE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TEAMS_PAGE_TTL: %w", err)
+		}
+		config.PageTTL = ttl
+	}
+
+	if v := os.Getenv("APP_BASE_URL"); v != "" {
+		config.AppBaseURL = v
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证Teams配置的有效性
+func (c *TeamsConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.SigningSecret == "" && c.AppID == "" {
+		return fmt.Errorf("teams signing secret or bot app id is required")
+	}
+	if c.AppID != "" && c.MetadataURL == "" {
+		return fmt.Errorf("teams openid metadata url is required when bot app id is set")
+	}
+	if c.ServiceUserID <= 0 {
+		return fmt.Errorf("teams service user id must be positive, got: %d", c.ServiceUserID)
+	}
+	if c.PageSize <= 0 || c.PageSize > 50 {
+		return fmt.Errorf("teams page size must be between 1 and 50, got: %d", c.PageSize)
+	}
+	if c.PageTTL <= 0 {
+		return fmt.Errorf("teams page ttl must be positive, got: %v", c.PageTTL)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TelemetryConfig 匿名使用统计配置
+// 默认关闭，只有显式设置TELEMETRY_ENABLED=true才会收集；未配置上报地址时只在本地汇总供查看，不发送
+type TelemetryConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // 是否收集匿名使用统计
+	Endpoint      string        `yaml:"endpoint"`       // 上报地址，为空时不发送
+	FlushInterval time.Duration `yaml:"flush_interval"` // 汇总批次的上报间隔
+}
+
+// DefaultTelemetryConfig 返回默认匿名使用统计配置
+func DefaultTelemetryConfig() *TelemetryConfig {
+	return &TelemetryConfig{
+		Enabled:       false,
+		FlushInterval: time.Hour,
+	}
+}
+
+// LoadTelemetryConfigFromEnv 从环境变量加载匿名使用统计配置
+func LoadTelemetryConfigFromEnv() (*TelemetryConfig, error) {
+	config := DefaultTelemetryConfig()
+
+	config.Enabled = os.Getenv("TELEMETRY_ENABLED") == "true"
+	config.Endpoint = os.Getenv("TELEMETRY_ENDPOINT")
+
+	if v := os.Getenv("TELEMETRY_FLUSH_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TELEMETRY_FLUSH_INTERVAL: %w", err)
+		}
+		config.FlushInterval = interval
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证匿名使用统计配置的有效性
+func (c *TelemetryConfig) Validate() error {
+	if c.FlushInterval < time.Minute {
+		return fmt.Errorf("telemetry flush interval must be at least 1m, got: %v", c.FlushInterval)
+	}
+	if c.Endpoint != "" {
+		u, err := url.Parse(c.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("telemetry endpoint must be an http(s) URL, got: %q", c.Endpoint)
+		}
+	}
+	return nil
+}
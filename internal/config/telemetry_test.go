@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTelemetryConfigFromEnv(t *testing.T) {
+	cfg, err := LoadTelemetryConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled, "未显式开启时不收集")
+
+	t.Setenv("TELEMETRY_ENABLED", "true")
+	t.Setenv("TELEMETRY_ENDPOINT", "https://telemetry.example.com/v1/batches")
+	t.Setenv("TELEMETRY_FLUSH_INTERVAL", "6h")
+
+	cfg, err = LoadTelemetryConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, "https://telemetry.example.com/v1/batches", cfg.Endpoint)
+	assert.Equal(t, 6*time.Hour, cfg.FlushInterval)
+
+	t.Setenv("TELEMETRY_ENDPOINT", "telemetry.example.com")
+	_, err = LoadTelemetryConfigFromEnv()
+	assert.Error(t, err, "上报地址必须是http(s) URL")
+
+	t.Setenv("TELEMETRY_ENDPOINT", "")
+	t.Setenv("TELEMETRY_FLUSH_INTERVAL", "5s")
+	_, err = LoadTelemetryConfigFromEnv()
+	assert.Error(t, err, "上报间隔过短")
+}
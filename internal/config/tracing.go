@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// TracingConfig OpenTelemetry分布式追踪配置
+// 默认关闭，设置TRACING_ENABLED=true后通过OTLP/HTTP导出；接收端地址、请求头、超时与压缩方式
+// 按OpenTelemetry规范由导出器直接读取OTEL_EXPORTER_OTLP_*环境变量
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`      // 是否开启分布式追踪
+	ServiceName string  `yaml:"service_name"` // 上报的服务名
+	Endpoint    string  `yaml:"endpoint"`     // OTLP接收端地址，为空时使用导出器默认的localhost:4318，这里只用于启动前校验
+	SampleRatio float64 `yaml:"sample_ratio"` // 根请求的采样比例，上游已采样的请求始终跟随上游
+}
+
+// DefaultTracingConfig 返回默认分布式追踪配置
+func DefaultTracingConfig() *TracingConfig {
+	return &TracingConfig{
+		Enabled:     false,
+		ServiceName: "chat2sql",
+		SampleRatio: 1,
+	}
+}
+
+// LoadTracingConfigFromEnv 从环境变量加载分布式追踪配置
+func LoadTracingConfigFromEnv() (*TracingConfig, error) {
+	config := DefaultTracingConfig()
+
+	config.Enabled = os.Getenv("TRACING_ENABLED") == "true"
+	if v := os.Getenv("OTEL_SERVICE_NAME"); v != "" {
+		config.ServiceName = v
+	}
+	// 专用于追踪的地址优先于通用地址，与导出器的取值顺序一致
+	config.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if config.Endpoint == "" {
+		config.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+
+	if v := os.Getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRACING_SAMPLE_RATIO: %w", err)
+		}
+		config.SampleRatio = ratio
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证分布式追踪配置的有效性
+func (c *TracingConfig) Validate() error {
+	if c.ServiceName == "" {
+		return fmt.Errorf("tracing service name cannot be empty")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1, got: %v", c.SampleRatio)
+	}
+	if c.Endpoint != "" {
+		u, err := url.Parse(c.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("OTLP endpoint must be an http(s) URL, got: %q", c.Endpoint)
+		}
+	}
+	return nil
+}
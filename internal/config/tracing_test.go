@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTracingConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	cfg, err := LoadTracingConfigFromEnv()
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled, "未显式开启时不追踪")
+	assert.Equal(t, "chat2sql", cfg.ServiceName)
+
+	t.Setenv("TRACING_ENABLED", "true")
+	t.Setenv("OTEL_SERVICE_NAME", "chat2sql-api")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://otel-collector:4318")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+
+	cfg, err = LoadTracingConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, "chat2sql-api", cfg.ServiceName)
+	assert.Equal(t, "http://otel-collector:4318", cfg.Endpoint)
+	assert.Equal(t, 0.25, cfg.SampleRatio)
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "https://traces.example.com/v1/traces")
+	cfg, err = LoadTracingConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "https://traces.example.com/v1/traces", cfg.Endpoint, "专用于追踪的地址优先")
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "otel-collector:4318")
+	_, err = LoadTracingConfigFromEnv()
+	assert.Error(t, err, "接收端地址必须是http(s) URL")
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("TRACING_SAMPLE_RATIO", "1.5")
+	_, err = LoadTracingConfigFromEnv()
+	assert.Error(t, err, "采样比例超出范围")
+}
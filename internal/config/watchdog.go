@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// WatchdogConfig 后台任务看门狗配置
+type WatchdogConfig struct {
+	CheckInterval time.Duration `yaml:"check_interval"` // 检查心跳的间隔
+	StallFactor   int           `yaml:"stall_factor"`   // 超过期望心跳间隔的倍数仍未上报时视为卡住并重启
+}
+
+// DefaultWatchdogConfig 返回默认看门狗配置
+func DefaultWatchdogConfig() *WatchdogConfig {
+	return &WatchdogConfig{
+		CheckInterval: 10 * time.Second,
+		StallFactor:   3,
+	}
+}
+
+// LoadWatchdogConfigFromEnv 从环境变量加载看门狗配置
+func LoadWatchdogConfigFromEnv() (*WatchdogConfig, error) {
+	config := DefaultWatchdogConfig()
+
+	if v := os.Getenv("WATCHDOG_CHECK_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WATCHDOG_CHECK_INTERVAL: %w", err)
+		}
+		config.CheckInterval = interval
+	}
+
+	if v := os.Getenv("WATCHDOG_STALL_FACTOR"); v != "" {
+		factor, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WATCHDOG_STALL_FACTOR: %w", err)
+		}
+		config.StallFactor = factor
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证看门狗配置的有效性
+func (c *WatchdogConfig) Validate() error {
+	if c.CheckInterval <= 0 {
+		return fmt.Errorf("watchdog check interval must be positive, got: %v", c.CheckInterval)
+	}
+	if c.StallFactor < 2 {
+		return fmt.Errorf("watchdog stall factor must be at least 2, got: %d", c.StallFactor)
+	}
+	return nil
+}
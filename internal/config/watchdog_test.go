@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadWatchdogConfigFromEnv(t *testing.T) {
+	t.Setenv("WATCHDOG_CHECK_INTERVAL", "2s")
+	t.Setenv("WATCHDOG_STALL_FACTOR", "5")
+
+	cfg, err := LoadWatchdogConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, cfg.CheckInterval)
+	assert.Equal(t, 5, cfg.StallFactor)
+
+	t.Setenv("WATCHDOG_STALL_FACTOR", "1")
+	_, err = LoadWatchdogConfigFromEnv()
+	assert.Error(t, err, "倍数过小时正常的调度抖动也会触发重启")
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// WorkspaceSettingsConfig 工作空间默认设置配置
+type WorkspaceSettingsConfig struct {
+	CacheTTL    time.Duration `yaml:"cache_ttl"`     // 用户所属工作空间设置的缓存时间，为0表示不缓存
+	MaxRowLimit int           `yaml:"max_row_limit"` // 工作空间可设置的默认返回行数上限
+}
+
+// DefaultWorkspaceSettingsConfig 返回默认工作空间设置配置
+func DefaultWorkspaceSettingsConfig() *WorkspaceSettingsConfig {
+	return &WorkspaceSettingsConfig{
+		CacheTTL:    time.Minute,
+		MaxRowLimit: 1000,
+	}
+}
+
+// LoadWorkspaceSettingsConfigFromEnv 从环境变量加载工作空间设置配置
+func LoadWorkspaceSettingsConfigFromEnv() (*WorkspaceSettingsConfig, error) {
+	config := DefaultWorkspaceSettingsConfig()
+
+	if v := os.Getenv("WORKSPACE_SETTINGS_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WORKSPACE_SETTINGS_CACHE_TTL: %w", err)
+		}
+		config.CacheTTL = ttl
+	}
+
+	if v := os.Getenv("WORKSPACE_MAX_ROW_LIMIT"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WORKSPACE_MAX_ROW_LIMIT: %w", err)
+		}
+		config.MaxRowLimit = limit
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证工作空间设置配置的有效性
+func (c *WorkspaceSettingsConfig) Validate() error {
+	if c.CacheTTL < 0 {
+		return fmt.Errorf("workspace settings cache ttl must not be negative, got: %v", c.CacheTTL)
+	}
+	if c.MaxRowLimit <= 0 {
+		return fmt.Errorf("workspace max row limit must be positive, got: %d", c.MaxRowLimit)
+	}
+	return nil
+}
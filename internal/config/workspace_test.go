@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadWorkspaceSettingsConfigFromEnv(t *testing.T) {
+	t.Setenv("WORKSPACE_SETTINGS_CACHE_TTL", "30s")
+	t.Setenv("WORKSPACE_MAX_ROW_LIMIT", "500")
+
+	cfg, err := LoadWorkspaceSettingsConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.CacheTTL)
+	assert.Equal(t, 500, cfg.MaxRowLimit)
+
+	t.Setenv("WORKSPACE_MAX_ROW_LIMIT", "0")
+	_, err = LoadWorkspaceSettingsConfigFromEnv()
+	assert.Error(t, err)
+}
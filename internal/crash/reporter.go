@@ -0,0 +1,279 @@
+// Package crash 捕获HTTP请求与后台任务中的panic，生成供技术支持排查的崩溃报告
+package crash
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/logging"
+)
+
+// bundleFilePrefix 崩溃报告文件名前缀，清理旧报告时只匹配该前缀的文件
+const bundleFilePrefix = "crash-"
+
+// RequestRecord 最近请求记录，只保存定位问题所需的元数据，不包含请求体
+type RequestRecord struct {
+	Time      time.Time     `json:"time"`
+	Method    string        `json:"method"`
+	Route     string        `json:"route"`
+	Path      string        `json:"path"`
+	RequestID string        `json:"request_id,omitempty"`
+	UserID    int64         `json:"user_id,omitempty"`
+	Status    int           `json:"status"`
+	Latency   time.Duration `json:"latency"`
+}
+
+// Bundle 崩溃报告
+type Bundle struct {
+	ID                string          `json:"id"`
+	Time              time.Time       `json:"time"`
+	Component         string          `json:"component"`
+	Panic             string          `json:"panic"`
+	Stack             string          `json:"stack"`
+	Request           *RequestRecord  `json:"request,omitempty"`
+	RecentRequests    []RequestRecord `json:"recent_requests"`
+	ConfigFingerprint string          `json:"config_fingerprint"`
+	App               *config.AppInfo `json:"app"`
+	Goroutines        int             `json:"goroutines"`
+	Path              string          `json:"-"`
+}
+
+// Reporter 崩溃报告器
+// 负责保存最近请求的环形缓冲区，并在panic时把堆栈、请求上下文与配置指纹写入报告文件
+type Reporter struct {
+	config      *config.CrashReportConfig
+	fingerprint string
+	appInfo     *config.AppInfo
+	logger      *zap.Logger
+
+	mutex  sync.Mutex
+	recent []RequestRecord
+	next   int
+
+	now func() time.Time
+}
+
+// NewReporter 创建崩溃报告器
+func NewReporter(cfg *config.CrashReportConfig, fingerprint string, appInfo *config.AppInfo, logger *zap.Logger) *Reporter {
+	if cfg == nil {
+		cfg = config.DefaultCrashReportConfig()
+	}
+	if appInfo == nil {
+		appInfo = config.DefaultAppInfo()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &Reporter{
+		config:      cfg,
+		fingerprint: fingerprint,
+		appInfo:     appInfo,
+		logger:      logger,
+		recent:      make([]RequestRecord, 0, cfg.RecentRequests),
+		now:         time.Now,
+	}
+}
+
+// Record 记录一个已完成的请求
+func (r *Reporter) Record(record RequestRecord) {
+	if r == nil || r.config.RecentRequests == 0 {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.recent) < r.config.RecentRequests {
+		r.recent = append(r.recent, record)
+		return
+	}
+	r.recent[r.next] = record
+	r.next = (r.next + 1) % len(r.recent)
+}
+
+// RecentRequests 按时间顺序返回最近的请求记录
+func (r *Reporter) RecentRequests() []RequestRecord {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	records := make([]RequestRecord, 0, len(r.recent))
+	records = append(records, r.recent[r.next:]...)
+	records = append(records, r.recent[:r.next]...)
+	return records
+}
+
+// Capture 生成崩溃报告
+// panic信息与堆栈经过脱敏后写入报告目录；目录为空时只记录日志
+func (r *Reporter) Capture(component string, recovered any, stack []byte, req *RequestRecord) (*Bundle, error) {
+	bundle := &Bundle{
+		ID:                newBundleID(),
+		Time:              r.now().UTC(),
+		Component:         component,
+		Panic:             logging.Redact(fmt.Sprint(recovered)),
+		Stack:             logging.Redact(string(stack)),
+		Request:           req,
+		RecentRequests:    r.RecentRequests(),
+		ConfigFingerprint: r.fingerprint,
+		App:               r.appInfo,
+		Goroutines:        runtime.NumGoroutine(),
+	}
+
+	fields := []zap.Field{
+		zap.String("crash_id", bundle.ID),
+		zap.String("component", component),
+		zap.String("panic", bundle.Panic),
+		zap.String("config_fingerprint", r.fingerprint),
+	}
+
+	if r.config.Dir == "" {
+		r.logger.Error("捕获到panic", append(fields, zap.String("stack", bundle.Stack))...)
+		return bundle, nil
+	}
+
+	path, err := r.write(bundle)
+	if err != nil {
+		r.logger.Error("捕获到panic，写入崩溃报告失败",
+			append(fields, zap.String("stack", bundle.Stack), zap.Error(err))...)
+		return bundle, err
+	}
+	bundle.Path = path
+
+	r.logger.Error("捕获到panic，已生成崩溃报告", append(fields, zap.String("path", path))...)
+	return bundle, nil
+}
+
+// write 写入报告文件并清理超出保留数量的旧报告
+func (r *Reporter) write(bundle *Bundle) (string, error) {
+	if err := os.MkdirAll(r.config.Dir, 0o750); err != nil {
+		return "", fmt.Errorf("创建崩溃报告目录失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化崩溃报告失败: %w", err)
+	}
+
+	name := fmt.Sprintf("%s%s-%s.json", bundleFilePrefix, bundle.Time.Format("20060102T150405.000"), bundle.ID)
+	path := filepath.Join(r.config.Dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("写入崩溃报告失败: %w", err)
+	}
+
+	r.prune()
+	return path, nil
+}
+
+// prune 删除超出MaxBundles的最旧报告，文件名以时间开头因此按名称排序即可
+func (r *Reporter) prune() {
+	entries, err := os.ReadDir(r.config.Dir)
+	if err != nil {
+		return
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), bundleFilePrefix) {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) <= r.config.MaxBundles {
+		return
+	}
+
+	sort.Strings(names)
+	for _, name := range names[:len(names)-r.config.MaxBundles] {
+		if err := os.Remove(filepath.Join(r.config.Dir, name)); err != nil {
+			r.logger.Warn("删除旧崩溃报告失败", zap.String("file", name), zap.Error(err))
+		}
+	}
+}
+
+// Fingerprint 计算配置指纹
+// 对配置的JSON序列化结果取哈希，支持人员据此判断两次崩溃是否处于相同配置下，而不暴露配置内容
+func Fingerprint(values ...any) string {
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	for _, value := range values {
+		if err := encoder.Encode(value); err != nil {
+			fmt.Fprintf(hash, "%T", value)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// newBundleID 生成崩溃报告ID
+func newBundleID() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+var (
+	defaultMutex    sync.RWMutex
+	defaultReporter *Reporter
+)
+
+// SetDefault 设置全局崩溃报告器，供中间件与后台任务的panic保护使用
+func SetDefault(r *Reporter) {
+	defaultMutex.Lock()
+	defaultReporter = r
+	defaultMutex.Unlock()
+}
+
+// Default 返回全局崩溃报告器，未设置时返回只写日志的报告器
+func Default() *Reporter {
+	defaultMutex.RLock()
+	r := defaultReporter
+	defaultMutex.RUnlock()
+	if r != nil {
+		return r
+	}
+	return NewReporter(&config.CrashReportConfig{MaxBundles: 1}, "", nil, zap.L())
+}
+
+// Recover 在defer中调用，捕获当前goroutine的panic并生成崩溃报告
+//
+//	defer crash.Recover("approval.sweep")
+func Recover(component string) {
+	if recovered := recover(); recovered != nil {
+		Default().Capture(component, recovered, debug.Stack(), nil)
+	}
+}
+
+// Go 在新goroutine中执行fn，panic时生成崩溃报告而不是终止进程
+func Go(component string, fn func()) {
+	go func() {
+		defer Recover(component)
+		fn()
+	}()
+}
+
+// Run 执行fn并捕获panic，返回fn是否正常完成
+// 用于后台循环的单次迭代，使一次panic不会让整个工作循环退出
+func Run(component string, fn func()) (ok bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			Default().Capture(component, recovered, debug.Stack(), nil)
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
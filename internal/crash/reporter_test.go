@@ -0,0 +1,100 @@
+package crash
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+func newTestReporter(t *testing.T, maxBundles, recent int) *Reporter {
+	cfg := &config.CrashReportConfig{Dir: t.TempDir(), MaxBundles: maxBundles, RecentRequests: recent}
+	return NewReporter(cfg, Fingerprint(cfg), nil, zap.NewNop())
+}
+
+func TestReporter_CaptureWritesRedactedBundle(t *testing.T) {
+	reporter := newTestReporter(t, 10, 2)
+	for i, path := range []string{"/a", "/b", "/c"} {
+		reporter.Record(RequestRecord{Method: "GET", Path: path, Status: 200 + i})
+	}
+
+	req := &RequestRecord{Method: "POST", Path: "/api/v1/sql/execute", RequestID: "req-1", UserID: 7}
+	bundle, err := reporter.Capture("http", "dial postgres://admin:hunter2@db:5432/app failed", []byte("goroutine 1 [running]"), req)
+	require.NoError(t, err)
+	require.NotEmpty(t, bundle.Path)
+
+	data, err := os.ReadFile(bundle.Path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter2")
+
+	var saved Bundle
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, "http", saved.Component)
+	assert.Equal(t, reporter.fingerprint, saved.ConfigFingerprint)
+	assert.Equal(t, "req-1", saved.Request.RequestID)
+	assert.Contains(t, saved.Stack, "goroutine 1")
+
+	// 环形缓冲区只保留最近两个请求，且按时间顺序排列
+	require.Len(t, saved.RecentRequests, 2)
+	assert.Equal(t, "/b", saved.RecentRequests[0].Path)
+	assert.Equal(t, "/c", saved.RecentRequests[1].Path)
+}
+
+func TestReporter_PrunesOldBundles(t *testing.T) {
+	reporter := newTestReporter(t, 2, 0)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		reporter.now = func() time.Time { return base.Add(time.Duration(i) * time.Second) }
+		bundle, err := reporter.Capture("worker", "boom", nil, nil)
+		require.NoError(t, err)
+		paths = append(paths, bundle.Path)
+	}
+
+	files, err := filepath.Glob(filepath.Join(reporter.config.Dir, bundleFilePrefix+"*"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, paths[1:], files)
+}
+
+func TestRun_RecoversPanicAndKeepsWorkerAlive(t *testing.T) {
+	reporter := newTestReporter(t, 10, 0)
+	SetDefault(reporter)
+	t.Cleanup(func() { SetDefault(nil) })
+
+	iterations := 0
+	for i := 0; i < 3; i++ {
+		ok := Run("scheduler.tick", func() {
+			iterations++
+			if iterations == 2 {
+				panic("nil schedule")
+			}
+		})
+		assert.Equal(t, iterations != 2, ok)
+	}
+	assert.Equal(t, 3, iterations)
+
+	files, err := filepath.Glob(filepath.Join(reporter.config.Dir, bundleFilePrefix+"*"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	Go("learning_engine.process_record", func() { panic("async panic") })
+	assert.Eventually(t, func() bool {
+		files, _ := filepath.Glob(filepath.Join(reporter.config.Dir, bundleFilePrefix+"*"))
+		return len(files) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(map[string]int{"pool": 10})
+	assert.Len(t, a, 12)
+	assert.Equal(t, a, Fingerprint(map[string]int{"pool": 10}))
+	assert.NotEqual(t, a, Fingerprint(map[string]int{"pool": 20}))
+}
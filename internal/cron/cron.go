@@ -0,0 +1,187 @@
+// Package cron 解析标准五段式cron表达式并计算下一次触发时间
+// 字段依次为分钟、小时、日、月、星期，支持*、列表（1,15）、范围（1-5）、步长（*/10、8-18/2）
+// 与@hourly、@daily、@weekly、@monthly、@yearly简写；日与星期都被限定时满足其一即触发，与Vixie cron一致
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchYears 计算下一次触发时间时最多向后查找的年数，2月30日之类永不触发的表达式据此结束查找
+const searchYears = 5
+
+// aliases 常用表达式的简写
+var aliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field 单个字段的取值范围
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Schedule 解析后的cron表达式，各字段以位图表示允许的取值
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // 日或星期为*，用于决定两者的组合方式
+}
+
+// Parse 解析五段式cron表达式，星期中的7与0同为星期日
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if alias, ok := aliases[strings.ToLower(expr)]; ok {
+		expr = alias
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron表达式应有5个字段，实际为%d个: %q", len(parts), expr)
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		f := fields[i]
+		if i == 4 {
+			f.max = 7
+		}
+		b, err := parseField(part, f)
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*" || parts[2] == "?",
+		dowAny: parts[4] == "*" || parts[4] == "?",
+	}, nil
+}
+
+// parseField 解析逗号分隔的字段
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(expr, ",") {
+		b, err := parseRange(item, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseRange 解析单个取值、范围或带步长的范围
+func parseRange(expr string, f field) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepExpr)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%s字段的步长无效: %q", f.name, expr)
+		}
+		step = n
+	}
+
+	low, high := f.min, f.max
+	if rangeExpr != "*" && rangeExpr != "?" {
+		lowExpr, highExpr, isRange := strings.Cut(rangeExpr, "-")
+		var err error
+		if low, err = parseValue(lowExpr, f); err != nil {
+			return 0, err
+		}
+		high = low
+		if isRange {
+			if high, err = parseValue(highExpr, f); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			// 5/15表示从5开始每15个单位
+			high = f.max
+		}
+		if low > high {
+			return 0, fmt.Errorf("%s字段的范围无效: %q", f.name, expr)
+		}
+	}
+
+	var bits uint64
+	for v := low; v <= high; v += step {
+		bits |= 1 << v
+	}
+	return bits, nil
+}
+
+func parseValue(expr string, f field) (int, error) {
+	n, err := strconv.Atoi(expr)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s字段的取值应在%d-%d之间: %q", f.name, f.min, f.max, expr)
+	}
+	return n, nil
+}
+
+// Next 返回晚于after的下一次触发时间，按after的时区计算；表达式永不触发时返回零值
+// 夏令时开始时跳过的本地时间不触发
+func (s *Schedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Add(time.Minute)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	if !t.After(after) {
+		t = t.Add(time.Minute)
+	}
+	limit := t.AddDate(searchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches 日与星期都被限定时满足其一即可，否则只看被限定的一方
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,55 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2026, 3, 4, 10, 17, 30, 0, time.UTC) // 星期三
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 4, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"30 8-18/2 * * 1-5", time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// 日与星期都被限定时满足其一即触发：15日或星期五
+		{"0 0 15 * 5", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, s.Next(base), tt.expr)
+	}
+
+	never, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, never.Next(base).IsZero())
+}
+
+func TestSchedule_NextInLocation(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	s, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+
+	// UTC 02:00 即东八区10:00，当天9点已过
+	next := s.Next(time.Date(2026, 3, 4, 2, 0, 0, 0, time.UTC).In(loc))
+	assert.Equal(t, time.Date(2026, 3, 5, 1, 0, 0, 0, time.UTC), next.UTC())
+}
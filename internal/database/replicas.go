@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// replicaLagQuery 查询副本的复制延迟（秒）与WAL接收进程是否在从主库接收日志
+// 已回放全部收到的WAL时视为无延迟，避免主库空闲时最后回放事务时间不断变旧导致误判；
+// 与主库断开时收到的WAL同样已全部回放，因此还需确认WAL接收进程处于streaming状态；
+// 不处于恢复状态（已被提升）的节点视为无延迟
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() THEN 0
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END::float8,
+	NOT pg_is_in_recovery() OR EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming')`
+
+// ErrReplicaNotStreaming 副本的WAL接收进程未在接收主库日志，数据可能任意陈旧
+var ErrReplicaNotStreaming = errors.New("replica WAL receiver is not streaming")
+
+// LagProbe 查询副本复制延迟的函数
+type LagProbe func(ctx context.Context, pool *pgxpool.Pool) (time.Duration, error)
+
+// ReplicaStatus 只读副本状态
+type ReplicaStatus struct {
+	Name      string        `json:"name"`
+	Healthy   bool          `json:"healthy"`
+	Lag       time.Duration `json:"lag"`
+	LastCheck time.Time     `json:"last_check"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// replica 单个只读副本
+type replica struct {
+	name   string
+	pool   *pgxpool.Pool
+	status ReplicaStatus
+}
+
+// ReplicaSet 系统库只读副本集合
+// 定期检查每个副本的复制延迟，只有延迟在MaxStaleness以内的副本参与读请求分配；
+// 副本连接池按需建立连接，首次检查完成前所有读请求仍由主库处理
+type ReplicaSet struct {
+	config *config.DatabaseReplicaConfig
+	probe  LagProbe
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// NewReplicaSet 按主库配置为每个副本地址创建连接池
+func NewReplicaSet(primary *config.DatabaseConfig, cfg *config.DatabaseReplicaConfig, logger *zap.Logger) (*ReplicaSet, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	rs := &ReplicaSet{config: cfg, probe: queryReplicaLag, logger: logger}
+	for _, host := range cfg.Hosts {
+		replicaConfig := *primary
+		h, port, err := cfg.Endpoint(host, primary.Port)
+		if err != nil {
+			rs.Close()
+			return nil, err
+		}
+		replicaConfig.Host, replicaConfig.Port = h, port
+		replicaConfig.MaxConns = cfg.MaxConns
+		replicaConfig.MinConns = 0
+
+		poolConfig, err := replicaConfig.GetPoolConfig()
+		if err != nil {
+			rs.Close()
+			return nil, fmt.Errorf("获取副本%s连接池配置失败: %w", host, err)
+		}
+		pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+		if err != nil {
+			rs.Close()
+			return nil, fmt.Errorf("创建副本%s连接池失败: %w", host, err)
+		}
+		rs.replicas = append(rs.replicas, &replica{name: host, pool: pool, status: ReplicaStatus{Name: host}})
+	}
+
+	logger.Info("只读副本已配置",
+		zap.Strings("replicas", cfg.Hosts),
+		zap.Duration("max_staleness", cfg.MaxStaleness))
+	return rs, nil
+}
+
+// SetLagProbe 替换复制延迟查询函数
+func (rs *ReplicaSet) SetLagProbe(probe LagProbe) {
+	rs.probe = probe
+}
+
+// Pick 轮询选择一个延迟在允许范围内的副本，没有可用副本时返回nil
+func (rs *ReplicaSet) Pick() *pgxpool.Pool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	n := len(rs.replicas)
+	start := int(rs.next.Add(1) % uint64(max(n, 1)))
+	for i := 0; i < n; i++ {
+		r := rs.replicas[(start+i)%n]
+		if r.status.Healthy {
+			return r.pool
+		}
+	}
+	return nil
+}
+
+// Status 返回全部副本的状态
+func (rs *ReplicaSet) Status() []ReplicaStatus {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	statuses := make([]ReplicaStatus, 0, len(rs.replicas))
+	for _, r := range rs.replicas {
+		statuses = append(statuses, r.status)
+	}
+	return statuses
+}
+
+// Run 定期检查副本复制延迟，每轮检查后调用beat上报心跳，直到ctx取消
+func (rs *ReplicaSet) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(rs.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		rs.Check(ctx)
+		beat()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check 检查一轮全部副本的复制延迟并更新可用状态
+func (rs *ReplicaSet) Check(ctx context.Context) {
+	for _, r := range rs.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, rs.config.CheckInterval)
+		lag, err := rs.probe(checkCtx, r.pool)
+		cancel()
+		rs.update(r, lag, err)
+	}
+}
+
+// update 记录检查结果，副本可用状态变化时记录日志
+func (rs *ReplicaSet) update(r *replica, lag time.Duration, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	wasHealthy := r.status.Healthy
+	r.status.LastCheck = time.Now()
+	r.status.Lag = lag
+	r.status.Error = ""
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+	r.status.Healthy = err == nil && lag <= rs.config.MaxStaleness
+
+	switch {
+	case wasHealthy && !r.status.Healthy:
+		rs.logger.Warn("只读副本不可用，读请求回到主库",
+			zap.String("replica", r.name),
+			zap.Duration("lag", lag),
+			zap.Duration("max_staleness", rs.config.MaxStaleness),
+			zap.Error(err))
+	case !wasHealthy && r.status.Healthy:
+		rs.logger.Info("只读副本可用", zap.String("replica", r.name), zap.Duration("lag", lag))
+	}
+}
+
+// Close 关闭全部副本连接池
+func (rs *ReplicaSet) Close() {
+	for _, r := range rs.replicas {
+		r.pool.Close()
+	}
+}
+
+// queryReplicaLag 在副本上查询复制延迟，WAL接收进程未处于streaming状态时返回ErrReplicaNotStreaming
+func queryReplicaLag(ctx context.Context, pool *pgxpool.Pool) (time.Duration, error) {
+	var seconds float64
+	var streaming bool
+	if err := pool.QueryRow(ctx, replicaLagQuery).Scan(&seconds, &streaming); err != nil {
+		return 0, err
+	}
+	lag := time.Duration(seconds * float64(time.Second))
+	if !streaming {
+		return lag, ErrReplicaNotStreaming
+	}
+	return lag, nil
+}
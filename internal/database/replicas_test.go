@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestReplicaSet_StalenessBound(t *testing.T) {
+	primary := config.DefaultDatabaseConfig()
+	primary.SSLMode = "disable"
+	cfg := config.DefaultDatabaseReplicaConfig()
+	cfg.Hosts = []string{"replica-a:6432", "replica-b"}
+	cfg.MaxStaleness = time.Second
+
+	rs, err := NewReplicaSet(primary, cfg, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer rs.Close()
+	assert.Nil(t, rs.Pick(), "首次检查前读请求由主库处理")
+
+	lags := map[string]time.Duration{"replica-a": 200 * time.Millisecond, "replica-b": 3 * time.Second}
+	rs.SetLagProbe(func(ctx context.Context, pool *pgxpool.Pool) (time.Duration, error) {
+		return lags[pool.Config().ConnConfig.Host], nil
+	})
+	rs.Check(context.Background())
+
+	for i := 0; i < 4; i++ {
+		replica := rs.Pick()
+		require.NotNil(t, replica)
+		assert.Equal(t, "replica-a", replica.Config().ConnConfig.Host, "延迟超过上限的副本不参与分配")
+		assert.Equal(t, uint16(6432), replica.Config().ConnConfig.Port)
+	}
+
+	status := rs.Status()
+	require.Len(t, status, 2)
+	assert.True(t, status[0].Healthy)
+	assert.False(t, status[1].Healthy)
+	assert.Equal(t, 3*time.Second, status[1].Lag)
+
+	rs.SetLagProbe(func(ctx context.Context, pool *pgxpool.Pool) (time.Duration, error) {
+		return 0, errors.New("connection refused")
+	})
+	rs.Check(context.Background())
+	assert.Nil(t, rs.Pick(), "副本全部不可用时读请求回到主库")
+	assert.Equal(t, "connection refused", rs.Status()[0].Error)
+
+	// 与主库断开复制的副本延迟为0，但同样不参与分配
+	rs.SetLagProbe(func(ctx context.Context, pool *pgxpool.Pool) (time.Duration, error) {
+		return 0, ErrReplicaNotStreaming
+	})
+	rs.Check(context.Background())
+	assert.Nil(t, rs.Pick())
+	assert.False(t, rs.Status()[0].Healthy)
+}
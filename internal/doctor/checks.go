@@ -0,0 +1,327 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/auth"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/service"
+)
+
+// 云端LLM提供商的API地址，测试中替换为本地服务器
+var (
+	openAIBaseURL    = "https://api.openai.com"
+	anthropicBaseURL = "https://api.anthropic.com"
+	geminiBaseURL    = ai.GeminiDefaultBaseURL
+)
+
+// CheckConfig 报告配置加载结果，err为errors.Join合并的多个错误时逐项列出
+func CheckConfig(err error) CheckFunc {
+	return func(ctx context.Context) Finding {
+		if err == nil {
+			return OK("配置校验通过")
+		}
+
+		problems := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			problems = joined.Unwrap()
+		}
+		messages := make([]string, 0, len(problems))
+		for _, problem := range problems {
+			messages = append(messages, problem.Error())
+		}
+		return Fail(strings.Join(messages, "; "), "按错误信息修正对应的环境变量或.env文件后重新运行")
+	}
+}
+
+// CheckPostgres 检查PostgreSQL连通性与服务端版本
+func CheckPostgres(cfg *config.DatabaseConfig) CheckFunc {
+	return func(ctx context.Context) Finding {
+		conn, err := pgx.Connect(ctx, cfg.GetConnectionString())
+		if err != nil {
+			return Fail(fmt.Sprintf("无法连接%s:%d/%s: %v", cfg.Host, cfg.Port, cfg.Database, err),
+				"确认数据库已启动、网络可达，且用户名密码正确")
+		}
+		defer conn.Close(context.Background())
+
+		var version string
+		if err := conn.QueryRow(ctx, "SHOW server_version").Scan(&version); err != nil {
+			return Fail(fmt.Sprintf("查询失败: %v", err), "确认数据库用户具有登录与查询权限")
+		}
+
+		var migrated bool
+		if err := conn.QueryRow(ctx, "SELECT to_regclass('users') IS NOT NULL").Scan(&migrated); err == nil && !migrated {
+			return Warn(fmt.Sprintf("PostgreSQL %s 已连接，但未找到users表", version),
+				"按顺序执行migrations目录下的迁移脚本")
+		}
+		return OK("PostgreSQL %s 已连接", version)
+	}
+}
+
+// CheckRedis 检查Redis连通性
+func CheckRedis(cfg *config.RedisConfig) CheckFunc {
+	return func(ctx context.Context) Finding {
+		manager, err := config.NewRedisManager(cfg, zap.NewNop())
+		if err != nil {
+			return Fail(fmt.Sprintf("无法连接%s: %v", cfg.Addr, err),
+				"确认Redis已启动且地址、密码、TLS设置正确；Token黑名单与缓存依赖Redis")
+		}
+		defer manager.Close()
+
+		if err := manager.HealthCheck(ctx); err != nil {
+			return Fail(fmt.Sprintf("PING失败: %v", err), "检查Redis的ACL与连接数限制")
+		}
+		return OK("%s 已连接", cfg.Addr)
+	}
+}
+
+// CheckLLMProvider 检查LLM提供商可用性
+// 只调用各提供商的模型列表接口，不消耗生成token
+func CheckLLMProvider(model config.ModelConfig, client *http.Client) CheckFunc {
+	return func(ctx context.Context) Finding {
+		switch model.Provider {
+		case "mock":
+			if _, err := ai.NewMockLLMFromFile(model.RulesFile); err != nil {
+				return Fail(err.Error(), "检查MOCK_LLM_RULES_FILE指向的规则文件")
+			}
+			return OK("mock提供商可用")
+		case "ollama":
+			return checkOllama(ctx, model, client)
+		case "openai":
+			if model.APIKey == "" {
+				return Fail("未配置API密钥", "设置OPENAI_API_KEY")
+			}
+			return checkModelsEndpoint(ctx, client, openAIBaseURL+"/v1/models", map[string]string{
+				"Authorization": "Bearer " + model.APIKey,
+			}, model.ModelName)
+		case "anthropic":
+			if model.APIKey == "" {
+				return Fail("未配置API密钥", "设置ANTHROPIC_API_KEY")
+			}
+			return checkModelsEndpoint(ctx, client, anthropicBaseURL+"/v1/models", map[string]string{
+				"x-api-key":         model.APIKey,
+				"anthropic-version": "2023-06-01",
+			}, model.ModelName)
+		case "gemini":
+			if model.APIKey == "" {
+				return Fail("未配置API密钥", "设置GEMINI_API_KEY")
+			}
+			return checkModelsEndpoint(ctx, client, geminiBaseURL+"/v1beta/models", map[string]string{
+				"x-goog-api-key": model.APIKey,
+			}, model.ModelName)
+		default:
+			return Fail(fmt.Sprintf("不支持的模型提供商: %s", model.Provider), "可选值: openai, anthropic, gemini, ollama, mock")
+		}
+	}
+}
+
+// checkOllama 检查Ollama服务是否运行以及模型是否已下载
+func checkOllama(ctx context.Context, model config.ModelConfig, client *http.Client) Finding {
+	serverURL := os.Getenv("OLLAMA_SERVER_URL")
+	if serverURL == "" {
+		serverURL = "http://localhost:11434"
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	status, err := getJSON(ctx, client, strings.TrimRight(serverURL, "/")+"/api/tags", nil, &tags)
+	if err != nil {
+		return Fail(fmt.Sprintf("无法访问Ollama %s: %v", serverURL, err), "运行 ollama serve，或设置OLLAMA_SERVER_URL")
+	}
+	if status != http.StatusOK {
+		return Fail(fmt.Sprintf("Ollama返回HTTP %d", status), "确认OLLAMA_SERVER_URL指向Ollama服务")
+	}
+
+	for _, m := range tags.Models {
+		if m.Name == model.ModelName || strings.TrimSuffix(m.Name, ":latest") == model.ModelName {
+			return OK("Ollama %s 已就绪，模型 %s 已下载", serverURL, model.ModelName)
+		}
+	}
+	return Fail(fmt.Sprintf("Ollama已运行，但未下载模型 %s", model.ModelName),
+		fmt.Sprintf("运行 ollama pull %s，或通过OLLAMA_MODEL选择已下载的模型", model.ModelName))
+}
+
+// checkModelsEndpoint 调用云端提供商的模型列表接口验证密钥
+func checkModelsEndpoint(ctx context.Context, client *http.Client, url string, headers map[string]string, modelName string) Finding {
+	status, err := getJSON(ctx, client, url, headers, nil)
+	if err != nil {
+		return Fail(fmt.Sprintf("无法访问 %s: %v", url, err), "检查网络出口与代理设置（HTTPS_PROXY）")
+	}
+
+	switch {
+	case status == http.StatusOK:
+		return OK("API密钥有效，模型 %s", modelName)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return Fail(fmt.Sprintf("API密钥被拒绝（HTTP %d）", status), "确认API密钥未过期且属于正确的组织")
+	case status == http.StatusTooManyRequests:
+		return Warn("请求被限流（HTTP 429）", "检查账户配额与速率限制")
+	default:
+		return Warn(fmt.Sprintf("模型列表接口返回HTTP %d", status), "稍后重试，或查看提供商状态页")
+	}
+}
+
+// getJSON 发送GET请求，out不为nil时解析响应JSON
+func getJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, out any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// CheckJWTKeys 检查JWT签名密钥
+// 密钥文件缺失时服务会在每次启动时生成新密钥，导致重启后所有已签发的Token失效
+func CheckJWTKeys(cfg *auth.JWTConfig) CheckFunc {
+	return func(ctx context.Context) Finding {
+		_, privateErr := os.Stat(cfg.PrivateKeyPath)
+		_, publicErr := os.Stat(cfg.PublicKeyPath)
+		if privateErr != nil || publicErr != nil {
+			if cfg.AutoGenerateKeys {
+				return Warn("未找到密钥文件，服务启动时将生成临时密钥",
+					fmt.Sprintf("生成持久密钥: openssl genrsa -out %s 2048 && openssl rsa -in %s -pubout -out %s",
+						cfg.PrivateKeyPath, cfg.PrivateKeyPath, cfg.PublicKeyPath))
+			}
+			return Fail("未找到密钥文件且禁用了自动生成", "检查JWT_PRIVATE_KEY_PATH与JWT_PUBLIC_KEY_PATH")
+		}
+
+		// 禁用自动生成，确保验证的是文件中的密钥
+		fileOnly := *cfg
+		fileOnly.AutoGenerateKeys = false
+		jwtService, err := auth.NewJWTService(&fileOnly, zap.NewNop(), nil)
+		if err != nil {
+			return Fail(fmt.Sprintf("加载密钥失败: %v", err), "密钥须为PEM格式的RSA密钥对")
+		}
+
+		pair, err := jwtService.GenerateTokenPair(1, "doctor", "viewer")
+		if err != nil {
+			return Fail(fmt.Sprintf("签发Token失败: %v", err), "重新生成密钥对")
+		}
+		if _, err := jwtService.ValidateAccessToken(pair.AccessToken); err != nil {
+			return Fail(fmt.Sprintf("私钥签发的Token无法用公钥验证: %v", err), "公钥与私钥不匹配，请从私钥重新导出公钥")
+		}
+		return OK("密钥对可用，签发与验证通过")
+	}
+}
+
+// ClockSource 可查询当前时间的外部服务
+type ClockSource struct {
+	Name string
+	Now  func(ctx context.Context) (time.Time, error)
+}
+
+// PostgresClock 以PostgreSQL服务端时间作为参照
+func PostgresClock(cfg *config.DatabaseConfig) ClockSource {
+	return ClockSource{Name: "postgres", Now: func(ctx context.Context) (time.Time, error) {
+		conn, err := pgx.Connect(ctx, cfg.GetConnectionString())
+		if err != nil {
+			return time.Time{}, err
+		}
+		defer conn.Close(context.Background())
+
+		var now time.Time
+		err = conn.QueryRow(ctx, "SELECT now()").Scan(&now)
+		return now, err
+	}}
+}
+
+// RedisClock 以Redis服务端时间作为参照
+func RedisClock(cfg *config.RedisConfig) ClockSource {
+	return ClockSource{Name: "redis", Now: func(ctx context.Context) (time.Time, error) {
+		manager, err := config.NewRedisManager(cfg, zap.NewNop())
+		if err != nil {
+			return time.Time{}, err
+		}
+		defer manager.Close()
+		return manager.GetClient().Time(ctx).Result()
+	}}
+}
+
+// CheckClockSkew 比较本机与外部服务的时钟
+// 时钟偏差会导致JWT的签发时间与过期判断出错，也会打乱审计日志的顺序
+func CheckClockSkew(maxSkew time.Duration, sources ...ClockSource) CheckFunc {
+	return func(ctx context.Context) Finding {
+		var (
+			measured []string
+			worst    time.Duration
+			worstAt  string
+		)
+		for _, source := range sources {
+			start := time.Now()
+			remote, err := source.Now(ctx)
+			if err != nil {
+				continue
+			}
+			// 以请求往返的中点作为本机时间，抵消网络延迟
+			local := start.Add(time.Since(start) / 2)
+			skew := remote.Sub(local)
+			measured = append(measured, fmt.Sprintf("%s %+v", source.Name, skew.Round(time.Millisecond)))
+			if skew.Abs() > worst.Abs() {
+				worst, worstAt = skew, source.Name
+			}
+		}
+
+		if len(measured) == 0 {
+			return Skip("没有可用的参照时钟（依赖服务未连通）")
+		}
+		if worst.Abs() > maxSkew {
+			return Fail(fmt.Sprintf("与%s的时钟偏差%v超过%v（%s）", worstAt, worst.Round(time.Millisecond), maxSkew, strings.Join(measured, ", ")),
+				"在本机与数据库主机上启用NTP（chronyd或systemd-timesyncd）")
+		}
+		return OK("时钟偏差在%v以内（%s）", maxSkew, strings.Join(measured, ", "))
+	}
+}
+
+// CheckMockGeneration 使用mock提供商走一遍完整的SQL生成流程
+// 不依赖网络与模型，用于区分是生成链路本身的问题还是模型提供商的问题
+func CheckMockGeneration() CheckFunc {
+	return func(ctx context.Context) Finding {
+		mock, err := ai.NewMockLLM(nil)
+		if err != nil {
+			return Fail(err.Error(), "请将该输出附在问题反馈中")
+		}
+
+		aiService := service.NewAIServiceWithClients(config.DemoAIConfig(""), mock, mock, zap.NewNop())
+		resp, err := aiService.GenerateSQL(ctx, &service.SQLGenerationRequest{
+			Query:  "用户总数是多少",
+			Schema: "users(id BIGINT, username VARCHAR, email VARCHAR)",
+		})
+		if err != nil {
+			return Fail(fmt.Sprintf("生成失败: %v", err), "请将该输出附在问题反馈中")
+		}
+		if resp.SQL == "" {
+			return Fail("生成结果为空", "请将该输出附在问题反馈中")
+		}
+		if err := ai.NewSQLValidator().Validate(resp.SQL); err != nil {
+			return Fail(fmt.Sprintf("生成的SQL未通过校验: %v", err), "请将该输出附在问题反馈中")
+		}
+		return OK("生成 %q，置信度%.2f", resp.SQL, resp.Confidence)
+	}
+}
@@ -0,0 +1,135 @@
+// Package doctor 实现 chat2sql doctor 自检命令
+// 逐项检查依赖服务连通性、配置、JWT密钥与时钟偏差，并给出可操作的修复建议
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Status 检查结果状态
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Finding 单项检查的结论
+type Finding struct {
+	Status Status
+	Detail string // 检查到的情况
+	Hint   string // 修复建议，状态为ok时通常为空
+}
+
+// OK 检查通过
+func OK(format string, args ...any) Finding {
+	return Finding{Status: StatusOK, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Warn 检查通过但存在隐患
+func Warn(detail, hint string) Finding {
+	return Finding{Status: StatusWarn, Detail: detail, Hint: hint}
+}
+
+// Fail 检查失败
+func Fail(detail, hint string) Finding {
+	return Finding{Status: StatusFail, Detail: detail, Hint: hint}
+}
+
+// Skip 检查未执行
+func Skip(detail string) Finding {
+	return Finding{Status: StatusSkip, Detail: detail}
+}
+
+// CheckFunc 检查函数
+type CheckFunc func(ctx context.Context) Finding
+
+// Result 单项检查结果
+type Result struct {
+	Name string
+	Finding
+	Duration time.Duration
+}
+
+// Report 自检报告
+type Report struct {
+	Results []Result
+}
+
+// Failed 是否存在失败项，决定命令的退出码
+func (r *Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Write 以纯文本输出报告
+func (r *Report) Write(w io.Writer) {
+	counts := make(map[Status]int)
+	for _, result := range r.Results {
+		counts[result.Status]++
+		fmt.Fprintf(w, "[%-4s] %-20s %s (%s)\n",
+			strings.ToUpper(string(result.Status)), result.Name, result.Detail, result.Duration.Round(time.Millisecond))
+		if result.Hint != "" {
+			fmt.Fprintf(w, "       %-20s -> %s\n", "", result.Hint)
+		}
+	}
+	fmt.Fprintf(w, "\n%d ok, %d warn, %d fail, %d skip\n",
+		counts[StatusOK], counts[StatusWarn], counts[StatusFail], counts[StatusSkip])
+}
+
+// Doctor 自检执行器
+type Doctor struct {
+	timeout time.Duration
+	names   []string
+	checks  []CheckFunc
+}
+
+// New 创建自检执行器，timeout为单项检查的超时时间
+func New(timeout time.Duration) *Doctor {
+	return &Doctor{timeout: timeout}
+}
+
+// Add 注册检查项，按注册顺序执行
+func (d *Doctor) Add(name string, check CheckFunc) {
+	d.names = append(d.names, name)
+	d.checks = append(d.checks, check)
+}
+
+// Run 依次执行所有检查项
+// 单项检查失败或panic不影响后续检查，确保一次运行给出完整报告
+func (d *Doctor) Run(ctx context.Context) *Report {
+	report := &Report{}
+	for i, check := range d.checks {
+		start := time.Now()
+		finding := d.run(ctx, check)
+		report.Results = append(report.Results, Result{
+			Name:     d.names[i],
+			Finding:  finding,
+			Duration: time.Since(start),
+		})
+	}
+	return report
+}
+
+// run 在超时上下文中执行单项检查
+func (d *Doctor) run(ctx context.Context, check CheckFunc) (finding Finding) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			finding = Fail(fmt.Sprintf("检查过程中发生panic: %v", recovered), "请将该输出附在问题反馈中")
+		}
+	}()
+	return check(ctx)
+}
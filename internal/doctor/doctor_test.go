@@ -0,0 +1,126 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/auth"
+	"chat2sql-go/internal/config"
+)
+
+func TestDoctor_RunContinuesAfterFailure(t *testing.T) {
+	d := New(time.Second)
+	d.Add("config", CheckConfig(errors.Join(
+		errors.New("crash_report: invalid CRASH_REPORT_MAX_BUNDLES"),
+		errors.New("startup: invalid STARTUP_MAX_WAIT"),
+	)))
+	d.Add("panics", func(ctx context.Context) Finding { panic("boom") })
+	d.Add("mock_generation", CheckMockGeneration())
+
+	report := d.Run(context.Background())
+	require.Len(t, report.Results, 3)
+	assert.True(t, report.Failed())
+	assert.Equal(t, StatusFail, report.Results[0].Status)
+	assert.Equal(t, "crash_report: invalid CRASH_REPORT_MAX_BUNDLES; startup: invalid STARTUP_MAX_WAIT", report.Results[0].Detail)
+	assert.Equal(t, StatusFail, report.Results[1].Status)
+	assert.Equal(t, StatusOK, report.Results[2].Status, report.Results[2].Detail)
+	assert.Contains(t, report.Results[2].Detail, "COUNT")
+
+	var out bytes.Buffer
+	report.Write(&out)
+	assert.Contains(t, out.String(), "[FAIL] config")
+	assert.Contains(t, out.String(), "1 ok, 0 warn, 2 fail, 0 skip")
+}
+
+func TestCheckLLMProvider(t *testing.T) {
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[{"name":"deepseek-r1:7b"}]}`)
+	}))
+	defer ollama.Close()
+	t.Setenv("OLLAMA_SERVER_URL", ollama.URL)
+
+	cloud := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" && r.Header.Get("x-goog-api-key") != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	}))
+	defer cloud.Close()
+	openAIBaseURL = cloud.URL
+	geminiBaseURL = cloud.URL
+	t.Cleanup(func() { openAIBaseURL, geminiBaseURL = "https://api.openai.com", ai.GeminiDefaultBaseURL })
+
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		model config.ModelConfig
+		want  Status
+	}{
+		{"ollama模型已下载", config.ModelConfig{Provider: "ollama", ModelName: "deepseek-r1:7b"}, StatusOK},
+		{"ollama模型未下载", config.ModelConfig{Provider: "ollama", ModelName: "qwen2.5:7b"}, StatusFail},
+		{"openai密钥有效", config.ModelConfig{Provider: "openai", ModelName: "gpt-4o", APIKey: "good"}, StatusOK},
+		{"openai密钥无效", config.ModelConfig{Provider: "openai", ModelName: "gpt-4o", APIKey: "bad"}, StatusFail},
+		{"anthropic未配置密钥", config.ModelConfig{Provider: "anthropic"}, StatusFail},
+		{"gemini密钥有效", config.ModelConfig{Provider: "gemini", ModelName: "gemini-1.5-flash", APIKey: "good"}, StatusOK},
+		{"gemini密钥无效", config.ModelConfig{Provider: "gemini", ModelName: "gemini-1.5-flash", APIKey: "bad"}, StatusFail},
+		{"mock", config.ModelConfig{Provider: "mock"}, StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding := CheckLLMProvider(tt.model, http.DefaultClient)(ctx)
+			assert.Equal(t, tt.want, finding.Status, finding.Detail)
+		})
+	}
+}
+
+func TestCheckJWTKeys(t *testing.T) {
+	dir := t.TempDir()
+	cfg := auth.DefaultJWTConfig()
+	cfg.PrivateKeyPath = filepath.Join(dir, "jwt_private.pem")
+	cfg.PublicKeyPath = filepath.Join(dir, "jwt_public.pem")
+
+	// 密钥文件缺失时提示生成持久密钥
+	finding := CheckJWTKeys(cfg)(context.Background())
+	assert.Equal(t, StatusWarn, finding.Status)
+	assert.Contains(t, finding.Hint, "openssl genrsa")
+
+	jwtService, err := auth.NewJWTService(cfg, zap.NewNop(), nil)
+	require.NoError(t, err)
+	require.NoError(t, jwtService.SaveKeysToFile(cfg.PrivateKeyPath, cfg.PublicKeyPath))
+
+	finding = CheckJWTKeys(cfg)(context.Background())
+	assert.Equal(t, StatusOK, finding.Status, finding.Detail)
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	source := func(name string, offset time.Duration) ClockSource {
+		return ClockSource{Name: name, Now: func(ctx context.Context) (time.Time, error) {
+			return time.Now().Add(offset), nil
+		}}
+	}
+	down := ClockSource{Name: "redis", Now: func(ctx context.Context) (time.Time, error) {
+		return time.Time{}, errors.New("connection refused")
+	}}
+
+	ctx := context.Background()
+	assert.Equal(t, StatusOK, CheckClockSkew(5*time.Second, source("postgres", time.Second), down)(ctx).Status)
+
+	finding := CheckClockSkew(5*time.Second, source("postgres", time.Second), source("redis", -time.Minute))(ctx)
+	assert.Equal(t, StatusFail, finding.Status)
+	assert.Contains(t, finding.Detail, "redis")
+
+	assert.Equal(t, StatusSkip, CheckClockSkew(5*time.Second, down)(ctx).Status)
+}
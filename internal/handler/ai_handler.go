@@ -3,14 +3,16 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/repository"
 	"chat2sql-go/internal/service"
 )
 
@@ -22,23 +24,118 @@ type AIServiceInterface interface {
 
 // AIHandler AI服务HTTP处理器
 type AIHandler struct {
-	aiService AIServiceInterface
-	logger    *zap.Logger
+	aiService    AIServiceInterface
+	validator    *service.SQLSecurityValidator
+	resultTables *service.ResultTableRenderer // 按请求的format渲染纯文本/Markdown结果表格
+	logger       *zap.Logger
+
+	autoExecutor      *service.AutoExecuteService       // 可选：按工作空间策略自动执行生成的SQL
+	consensus         *service.ConsensusService         // 可选：关键查询的自洽性投票
+	classifications   *service.ClassificationService    // 可选：按列数据分级提示受限列并脱敏结果
+	workspaceSettings *service.WorkspaceSettingsService // 可选：按工作空间默认设置补全请求
+	schemaSnapshots   *service.SchemaSnapshotService    // 可选：记录生成时使用的表结构快照
+	llmArchive        *service.LLMArchiveService        // 可选：归档模型请求与响应
+	columnLabels      *service.ColumnLabeler            // 可选：推导结果列的展示名
+	calibration       *service.CalibrationTracker       // 可选：配对生成置信度与用户反馈，统计置信度校准
+	feedback          *service.FeedbackService          // 可选：将用户反馈写入准确率统计与学习引擎
+	costs             *ai.CostTracker                   // 可选：模型用量与每日预算
+	budgetEnforce     bool
+	approvals         *service.ApprovalEngine           // 可选：提交预算上调审批
 }
 
 // NewAIHandler 创建AI处理器实例
 func NewAIHandler(aiService AIServiceInterface, logger *zap.Logger) *AIHandler {
 	return &AIHandler{
-		aiService: aiService,
-		logger:    logger,
+		aiService:    aiService,
+		validator:    service.NewSQLSecurityValidator(logger),
+		resultTables: service.NewResultTableRenderer(nil),
+		logger:       logger,
+	}
+}
+
+// Routes 声明AI智能查询路由
+func (h *AIHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix:      "/ai",
+			Tag:         "ai",
+			Auth:        AuthJWT,
+			APIKeyScope: repository.APIKeyScopeAI,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/chat2sql", Handler: h.Chat2SQL, Summary: "自然语言转SQL", BlockedInMaintenance: true},
+				{Method: http.MethodPost, Path: "/generate/stream", Handler: h.StreamChat2SQL, Summary: "流式生成SQL（SSE）", BlockedInMaintenance: true},
+				{Method: http.MethodPost, Path: "/visualize", Handler: h.Visualize, Summary: "推荐结果图表"},
+				{Method: http.MethodPost, Path: "/feedback", Handler: h.SubmitFeedback, Summary: "提交用户反馈"},
+				{Method: http.MethodGet, Path: "/stats", Handler: h.GetAIStats, Summary: "获取AI服务统计"},
+				{Method: http.MethodGet, Path: "/usage", Handler: h.GetUsage, Summary: "获取模型用量与预算"},
+				{Method: http.MethodPost, Path: "/usage/budget-raise", Handler: h.RequestBudgetRaise, Summary: "申请上调每日模型预算"},
+			},
+		},
 	}
 }
 
+// SetAutoExecutor 启用工作空间自动执行策略
+func (h *AIHandler) SetAutoExecutor(autoExecutor *service.AutoExecuteService) {
+	h.autoExecutor = autoExecutor
+}
+
+// SetResultTableRenderer 设置结果表格渲染器，未设置时使用默认的行数与宽度限制
+func (h *AIHandler) SetResultTableRenderer(renderer *service.ResultTableRenderer) {
+	h.resultTables = renderer
+}
+
+// SetConsensusService 启用关键查询的自洽性投票模式
+func (h *AIHandler) SetConsensusService(consensus *service.ConsensusService) {
+	h.consensus = consensus
+}
+
+// SetClassificationService 启用列数据分级：提示词中标出受限列，执行结果按角色脱敏或过滤
+func (h *AIHandler) SetClassificationService(classifications *service.ClassificationService) {
+	h.classifications = classifications
+}
+
+// SetWorkspaceSettings 启用工作空间默认设置：请求未指定连接、语言或返回行数时按工作空间默认值补全
+func (h *AIHandler) SetWorkspaceSettings(settings *service.WorkspaceSettingsService) {
+	h.workspaceSettings = settings
+}
+
+// SetSchemaSnapshots 启用表结构快照：记录生成时提示词中的表结构，查询历史据此重现提示词
+func (h *AIHandler) SetSchemaSnapshots(snapshots *service.SchemaSnapshotService) {
+	h.schemaSnapshots = snapshots
+}
+
+// SetLLMArchive 启用模型归档：按查询ID保存脱敏后的提示词与模型原始输出
+func (h *AIHandler) SetLLMArchive(archive *service.LLMArchiveService) {
+	h.llmArchive = archive
+}
+
+// SetColumnLabeler 启用结果列展示名：按问题措辞与数据字典为结果列推导易读的表头
+func (h *AIHandler) SetColumnLabeler(labeler *service.ColumnLabeler) {
+	h.columnLabels = labeler
+}
+
+// SetCalibrationTracker 启用置信度校准统计：记录生成置信度，收到用户反馈后配对为样本
+func (h *AIHandler) SetCalibrationTracker(tracker *service.CalibrationTracker) {
+	h.calibration = tracker
+}
+
+// SetFeedbackService 启用反馈学习：记录生成上下文，用户反馈写入准确率统计并交给学习引擎
+func (h *AIHandler) SetFeedbackService(feedback *service.FeedbackService) {
+	h.feedback = feedback
+}
+
 // Chat2SQLRequest Chat2SQL API请求结构
+// ConnectionID与RowLimit未指定时使用工作空间默认设置；Locale未指定时按问题文本检测，
+// 检测不出时才使用工作空间默认语言
 type Chat2SQLRequest struct {
 	Query        string `json:"query" binding:"required,min=1,max=1000"`
-	ConnectionID int64  `json:"connection_id" binding:"required,min=1"`
+	ConnectionID int64  `json:"connection_id" binding:"omitempty,min=1"`
+	Locale       string `json:"locale,omitempty" binding:"omitempty,oneof=zh en"`
+	RowLimit     int    `json:"row_limit,omitempty" binding:"omitempty,min=1"` // 自动执行时的最大返回行数
 	Schema       string `json:"schema,omitempty"`
+	Candidates   int    `json:"candidates,omitempty" binding:"omitempty,min=1,max=10"` // 大于1时返回多条候选供选择
+	Critical     bool   `json:"critical,omitempty"` // 关键查询：多条候选结果一致时才返回答案
+	Format       string `json:"format,omitempty" binding:"omitempty,oneof=json text markdown"` // 自动执行结果额外渲染为纯文本或Markdown表格
 }
 
 // Chat2SQLResponse Chat2SQL API响应结构  
@@ -49,6 +146,57 @@ type Chat2SQLResponse struct {
 	TokensUsed     int     `json:"tokens_used,omitempty"`
 	QueryID        string  `json:"query_id"`
 	Timestamp      string  `json:"timestamp"`
+	Lineage        []service.ColumnLineage `json:"lineage,omitempty"` // 结果列来源（计算列的公式与来源列）
+	ColumnLabels   []service.ColumnLabel   `json:"column_labels,omitempty"` // 结果列的展示名，与原始列名一一对应
+	
+	// 置信度构成；RequiresConfirmation为true时客户端应在执行前请求用户确认
+	ConfidenceBreakdown  *service.ConfidenceBreakdown `json:"confidence_breakdown,omitempty"`
+	RequiresConfirmation bool                         `json:"requires_confirmation"`
+	
+	// 多候选模式：选中候选的排序依据与其余备选
+	SelectedCandidate *service.SQLCandidate   `json:"selected_candidate,omitempty"`
+	Alternates        []*service.SQLCandidate `json:"alternates,omitempty"`
+	
+	// 自动执行：ExecutionPath为auto时Result为执行结果，ExecutedQueryID为查询历史ID
+	AutoExecute     *service.AutoExecuteDecision `json:"auto_execute,omitempty"`
+	ExecutionPath   string                       `json:"execution_path,omitempty"`
+	Result          *service.QueryResult         `json:"result,omitempty"`
+	ExecutedQueryID int64                        `json:"executed_query_id,omitempty"`
+	
+	// 关键查询的投票详情
+	Consensus *service.ConsensusResult `json:"consensus,omitempty"`
+	
+	// 生成时使用的表结构快照，执行时通过 /sql/execute 的 schema_snapshot_id 回传以便重现提示词
+	SchemaSnapshotID *int64 `json:"schema_snapshot_id,omitempty"`
+	SchemaVersion    int    `json:"schema_version,omitempty"`
+
+	// 生成SQL实际使用的模型参数（温度、采样种子等），确定性模式下可据此复现结果
+	Generation *service.GenerationParameters `json:"generation,omitempty"`
+
+	// 命中的SQL模板（如count_rows），非空时SQL由模板直接生成，未调用模型
+	Template string `json:"template,omitempty"`
+
+	// 问题中引号内的字面值提取为绑定参数后的SQL，执行时将两者一并传给 /sql/execute
+	ParameterizedSQL string   `json:"parameterized_sql,omitempty"`
+	Parameters       []string `json:"parameters,omitempty"`
+
+	// 问题中拼错的表名、列名及说明（如“costumers”按表customers理解），生成SQL时已按纠正后的名称理解
+	SchemaCorrections []ai.SchemaCorrection `json:"schema_corrections,omitempty"`
+
+	// 本次响应使用的语言，原因说明与错误消息均按该语言返回
+	Locale string `json:"locale,omitempty"`
+
+	// 请求format为text或markdown且已自动执行时，服务端渲染的结果表格
+	Rendered string `json:"rendered,omitempty"`
+}
+
+// ConsensusFailedResponse 关键查询未达成一致时的响应
+type ConsensusFailedResponse struct {
+	Code       string                  `json:"code" example:"CONSENSUS_FAILED"`
+	Message    string                  `json:"message" example:"候选SQL结果不一致，未返回答案"`
+	Reason     string                  `json:"reason"`
+	Candidates []*service.SQLCandidate `json:"candidates,omitempty"`
+	RequestID  string                  `json:"request_id,omitempty"`
 }
 
 // FeedbackRequest 反馈提交请求结构
@@ -78,6 +226,7 @@ type FeedbackResponse struct {
 // @Param request body Chat2SQLRequest true "查询请求"
 // @Success 200 {object} Chat2SQLResponse "成功响应"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 422 {object} ConsensusFailedResponse "关键查询的候选结果不一致"
 // @Failure 429 {object} ErrorResponse "请求频率限制"
 // @Failure 500 {object} ErrorResponse "服务器内部错误"
 // @Router /api/v1/ai/chat2sql [post]
@@ -125,14 +274,41 @@ func (h *AIHandler) Chat2SQL(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
+	// 按问题语言回复：检测结果优先于工作空间默认语言，请求显式指定的语言优先于检测结果
+	if req.Locale == "" {
+		req.Locale = service.DetectLocale(req.Query)
+	}
+	c.Set(localeContextKey, req.Locale)
+	if !h.applyWorkspaceDefaults(ctx, c, &req, userIDInt64, requestID) {
+		return
+	}
+	c.Set(localeContextKey, req.Locale)
+	ctx = service.WithLocale(ctx, req.Locale)
+	ctx, ok = h.applyChargeback(ctx, c, userIDInt64, requestID)
+	if !ok {
+		return
+	}
+	ctx = service.WithPromptCacheOptions(ctx, service.ParsePromptCacheHeader(c.GetHeader(service.PromptCacheHeader)))
+
 	// 构建AI服务请求
 	aiRequest := &service.SQLGenerationRequest{
 		Query:        req.Query,
 		ConnectionID: req.ConnectionID,
 		UserID:       userIDInt64,
 		Schema:       req.Schema,
+		Candidates:   req.Candidates,
+		Locale:       req.Locale,
+		Workload:     service.WorkloadInteractive,
+		LatencySLO:   h.latencySLO(ctx, userIDInt64, requestID),
 	}
 
+	policy, policyErr := h.columnPolicy(ctx, userIDInt64, c.GetString("user_role"), req.ConnectionID, requestID)
+	if policy != nil {
+		aiRequest.RestrictedColumns = policy.Restricted()
+	}
+	// 执行器按令牌中的角色应用列策略，与提示词中的受限列一致
+	ctx = service.WithQueryRole(ctx, c.GetString("user_role"))
+
 	h.logger.Info("调用AI服务生成SQL",
 		zap.String("request_id", requestID),
 		zap.String("query", req.Query),
@@ -140,6 +316,11 @@ func (h *AIHandler) Chat2SQL(c *gin.Context) {
 		zap.Int64("user_id", userIDInt64),
 	)
 
+	if req.Critical {
+		h.handleCritical(c, ctx, aiRequest, policy, policyErr, requestID, startTime)
+		return
+	}
+
 	// 调用AI服务
 	response, err := h.aiService.GenerateSQL(ctx, aiRequest)
 	if err != nil {
@@ -148,6 +329,11 @@ func (h *AIHandler) Chat2SQL(c *gin.Context) {
 			zap.Error(err),
 		)
 
+		if message, ok := budgetExceeded(err); ok {
+			h.respondBudgetExceeded(c, message, requestID)
+			return
+		}
+
 		// 根据错误类型返回不同的HTTP状态码
 		statusCode := http.StatusInternalServerError
 		errorMessage := "AI查询处理失败"
@@ -159,22 +345,43 @@ func (h *AIHandler) Chat2SQL(c *gin.Context) {
 		} else if isRateLimitError(err) {
 			statusCode = http.StatusTooManyRequests
 			errorMessage = "请求过于频繁，请稍后重试"
+		} else if errors.Is(err, service.ErrUnsafeGeneratedSQL) {
+			statusCode = http.StatusUnprocessableEntity
+			errorMessage = "生成的SQL未通过安全检查，请换一种方式描述查询"
 		}
 
 		h.respondWithError(c, statusCode, errorMessage, err.Error(), requestID)
 		return
 	}
 
+	// 响应来自模型响应缓存时在响应头中标明，便于排查
+	if response.Generation != nil && response.Generation.Cached {
+		c.Header(service.PromptCacheHeader, "hit")
+	}
+
 	// 生成查询ID（用于反馈跟踪）
 	queryID := generateQueryID(userIDInt64, startTime)
 
 	// 构建响应
-	apiResponse := &Chat2SQLResponse{
-		SQL:            response.SQL,
-		Confidence:     response.Confidence,
-		ProcessingTime: response.ProcessingTime.Milliseconds(),
-		QueryID:        queryID,
-		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+	apiResponse := h.newChat2SQLResponse(response, queryID, req.Locale)
+
+	if h.schemaSnapshots != nil {
+		ctx = h.recordSchemaSnapshot(ctx, apiResponse, req.ConnectionID, aiRequest.Schema, requestID)
+	}
+
+	if h.llmArchive != nil && response.Template == "" {
+		h.archiveGeneration(ctx, response, queryID, userIDInt64, req.ConnectionID, requestID)
+	}
+	h.recordGeneration(queryID, userIDInt64, req.Query, response)
+
+	if h.autoExecutor != nil {
+		h.applyAutoExecute(ctx, apiResponse, userIDInt64, req, requestID)
+	}
+	applyColumnPolicy(apiResponse.Result, apiResponse.Lineage, policy, policyErr, req.Locale)
+	h.labelColumns(ctx, apiResponse, req.ConnectionID, req.Query)
+	if apiResponse.Result != nil && apiResponse.Result.Rows != nil {
+		headers := service.ColumnHeaders(apiResponse.Result.Columns, apiResponse.ColumnLabels)
+		apiResponse.Rendered = h.resultTables.RenderWithHeaders(req.Format, apiResponse.Result.Columns, headers, apiResponse.Result.Rows, req.Locale)
 	}
 
 	// 记录成功响应
@@ -182,6 +389,8 @@ func (h *AIHandler) Chat2SQL(c *gin.Context) {
 		zap.String("request_id", requestID),
 		zap.String("query_id", queryID),
 		zap.Float64("confidence", response.Confidence),
+		zap.Bool("requires_confirmation", apiResponse.RequiresConfirmation),
+		zap.String("execution_path", apiResponse.ExecutionPath),
 		zap.Duration("total_duration", time.Since(startTime)),
 		zap.Duration("ai_processing_time", response.ProcessingTime),
 	)
@@ -189,15 +398,439 @@ func (h *AIHandler) Chat2SQL(c *gin.Context) {
 	c.JSON(http.StatusOK, apiResponse)
 }
 
+// newChat2SQLResponse 由生成结果构建API响应
+func (h *AIHandler) newChat2SQLResponse(response *service.SQLGenerationResponse, queryID, locale string) *Chat2SQLResponse {
+	return &Chat2SQLResponse{
+		SQL:            response.SQL,
+		Confidence:     response.Confidence,
+		ProcessingTime: response.ProcessingTime.Milliseconds(),
+		QueryID:        queryID,
+		Timestamp:      time.Now().UTC().Format(time.RFC3339),
+		Lineage:        h.validator.ExtractColumnLineage(response.SQL),
+
+		ConfidenceBreakdown:  response.ConfidenceBreakdown,
+		RequiresConfirmation: response.RequiresConfirmation,
+		SelectedCandidate:    response.SelectedCandidate,
+		Alternates:           response.Alternates,
+		Generation:           response.Generation,
+		Template:             response.Template,
+		ParameterizedSQL:     response.ParameterizedSQL,
+		Parameters:           response.Parameters,
+		SchemaCorrections:    response.SchemaCorrections,
+		Locale:               locale,
+	}
+}
+
+// StreamTokenEvent 流式生成的一段模型输出
+type StreamTokenEvent struct {
+	Text string `json:"text"`
+}
+
+// StreamResetEvent 主模型中途失败降级到备用模型，客户端应丢弃已收到的输出
+type StreamResetEvent struct {
+	Model string `json:"model"`
+}
+
+// StreamChat2SQL 以Server-Sent Events流式返回SQL生成过程
+// @Summary 流式Chat2SQL
+// @Description 模型生成时逐段推送token事件，生成结束后推送result事件（与/chat2sql响应相同，不自动执行）；
+// @Description 主模型失败降级时推送reset事件，生成失败时推送error事件
+// @Tags AI
+// @Accept json
+// @Produce text/event-stream
+// @Param request body Chat2SQLRequest true "查询请求"
+// @Success 200 {object} Chat2SQLResponse "result事件的数据"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Router /api/v1/ai/generate/stream [post]
+func (h *AIHandler) StreamChat2SQL(c *gin.Context) {
+	startTime := time.Now()
+	requestID := c.GetHeader("X-Request-ID")
+	if requestID == "" {
+		requestID = generateRequestID()
+	}
+
+	var req Chat2SQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "请求参数无效", err.Error(), requestID)
+		return
+	}
+	if req.Critical || req.Candidates > 1 {
+		h.respondWithError(c, http.StatusBadRequest, "流式生成不支持关键查询与多候选", "critical and candidates are not supported when streaming", requestID)
+		return
+	}
+
+	userID, ok := c.Get("user_id")
+	userIDInt64, isInt64 := userID.(int64)
+	if !ok || !isInt64 {
+		h.respondWithError(c, http.StatusUnauthorized, "认证信息无效", "user_id not found in context", requestID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	if req.Locale == "" {
+		req.Locale = service.DetectLocale(req.Query)
+	}
+	c.Set(localeContextKey, req.Locale)
+	if !h.applyWorkspaceDefaults(ctx, c, &req, userIDInt64, requestID) {
+		return
+	}
+	c.Set(localeContextKey, req.Locale)
+	ctx = service.WithLocale(ctx, req.Locale)
+	ctx, ok = h.applyChargeback(ctx, c, userIDInt64, requestID)
+	if !ok {
+		return
+	}
+	ctx = service.WithPromptCacheOptions(ctx, service.ParsePromptCacheHeader(c.GetHeader(service.PromptCacheHeader)))
+
+	aiRequest := &service.SQLGenerationRequest{
+		Query:        req.Query,
+		ConnectionID: req.ConnectionID,
+		UserID:       userIDInt64,
+		Schema:       req.Schema,
+		Locale:       req.Locale,
+		Workload:     service.WorkloadInteractive,
+		LatencySLO:   h.latencySLO(ctx, userIDInt64, requestID),
+	}
+	if policy, _ := h.columnPolicy(ctx, userIDInt64, c.GetString("user_role"), req.ConnectionID, requestID); policy != nil {
+		aiRequest.RestrictedColumns = policy.Restricted()
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭反向代理缓冲
+	c.Status(http.StatusOK)
+
+	var streamModel string
+	ctx = service.WithTokenStream(ctx, func(ctx context.Context, model, chunk string) error {
+		if streamModel != "" && model != streamModel {
+			c.SSEvent("reset", StreamResetEvent{Model: model})
+		}
+		streamModel = model
+		c.SSEvent("token", StreamTokenEvent{Text: chunk})
+		c.Writer.Flush()
+		// 客户端断开后中止生成
+		return c.Request.Context().Err()
+	})
+
+	response, err := h.aiService.GenerateSQL(ctx, aiRequest)
+	if err != nil {
+		h.logger.Error("流式生成SQL失败", zap.String("request_id", requestID), zap.Error(err))
+		code, message := "AI_SERVICE_ERROR", "AI查询处理失败"
+		if isTimeoutError(err) {
+			code, message = "REQUEST_TIMEOUT", "查询处理超时，请稍后重试"
+		} else if errors.Is(err, service.ErrUnsafeGeneratedSQL) {
+			code, message = "UNSAFE_SQL", "生成的SQL未通过安全检查，请换一种方式描述查询"
+		} else if budgetMessage, ok := budgetExceeded(err); ok {
+			code, message = "BUDGET_EXCEEDED", budgetMessage
+		}
+		c.SSEvent("error", ErrorResponse{
+			Code:      code,
+			Message:   service.Localize(req.Locale, message),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			RequestID: requestID,
+		})
+		c.Writer.Flush()
+		return
+	}
+
+	queryID := generateQueryID(userIDInt64, startTime)
+	apiResponse := h.newChat2SQLResponse(response, queryID, req.Locale)
+	h.labelColumns(ctx, apiResponse, req.ConnectionID, req.Query)
+	if h.schemaSnapshots != nil {
+		ctx = h.recordSchemaSnapshot(ctx, apiResponse, req.ConnectionID, aiRequest.Schema, requestID)
+	}
+	if h.llmArchive != nil && response.Template == "" {
+		h.archiveGeneration(ctx, response, queryID, userIDInt64, req.ConnectionID, requestID)
+	}
+	h.recordGeneration(queryID, userIDInt64, req.Query, response)
+
+	h.logger.Info("流式Chat2SQL请求完成",
+		zap.String("request_id", requestID),
+		zap.String("query_id", queryID),
+		zap.Duration("total_duration", time.Since(startTime)),
+	)
+	c.SSEvent("result", apiResponse)
+	c.Writer.Flush()
+}
+
+// handleCritical 关键查询走自洽性投票：两条不同候选的执行结果一致时才返回SQL与结果
+func (h *AIHandler) handleCritical(c *gin.Context, ctx context.Context, aiRequest *service.SQLGenerationRequest, policy *service.ColumnPolicy, policyErr error, requestID string, startTime time.Time) {
+	if h.consensus == nil {
+		h.respondWithError(c, http.StatusBadRequest, "关键查询模式未启用", "consensus service not configured", requestID)
+		return
+	}
+
+	outcome, err := h.consensus.Verify(ctx, aiRequest)
+	switch {
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Code:      "CONNECTION_FORBIDDEN",
+			Message:   service.Localize(aiRequest.Locale, "无权访问该数据库连接"),
+			RequestID: requestID,
+		})
+		return
+	case errors.Is(err, service.ErrNoConsensus):
+		h.logger.Warn("关键查询未达成一致",
+			zap.String("request_id", requestID),
+			zap.String("reason", outcome.Reason),
+		)
+		c.JSON(http.StatusUnprocessableEntity, &ConsensusFailedResponse{
+			Code:       "CONSENSUS_FAILED",
+			Message:    service.Localize(aiRequest.Locale, "候选SQL结果不一致，未返回答案"),
+			Reason:     outcome.Reason,
+			Candidates: outcome.Candidates,
+			RequestID:  requestID,
+		})
+		return
+	case err != nil:
+		if message, ok := budgetExceeded(err); ok {
+			h.respondBudgetExceeded(c, message, requestID)
+			return
+		}
+		h.logger.Error("关键查询处理失败", zap.String("request_id", requestID), zap.Error(err))
+		statusCode, message := http.StatusInternalServerError, "AI查询处理失败"
+		if isTimeoutError(err) {
+			statusCode, message = http.StatusRequestTimeout, "查询处理超时，请稍后重试"
+		}
+		h.respondWithError(c, statusCode, message, err.Error(), requestID)
+		return
+	}
+
+	generation := outcome.Generation
+	queryID := generateQueryID(aiRequest.UserID, startTime)
+	h.recordGeneration(queryID, aiRequest.UserID, aiRequest.Query, generation)
+	lineage := h.validator.ExtractColumnLineage(outcome.SQL)
+	applyColumnPolicy(outcome.Result, lineage, policy, policyErr, aiRequest.Locale)
+	resp := &Chat2SQLResponse{
+		SQL:                 outcome.SQL,
+		Confidence:          generation.Confidence,
+		ProcessingTime:      time.Since(startTime).Milliseconds(),
+		QueryID:             queryID,
+		Timestamp:           time.Now().UTC().Format(time.RFC3339),
+		Lineage:             lineage,
+		ConfidenceBreakdown: generation.ConfidenceBreakdown,
+		Result:              outcome.Result,
+		Consensus:           outcome,
+		Locale:              aiRequest.Locale,
+	}
+	h.labelColumns(ctx, resp, aiRequest.ConnectionID, aiRequest.Query)
+	c.JSON(http.StatusOK, resp)
+}
+
+// labelColumns 为结果列推导展示名：已执行时按结果列，否则按SQL解析出的结果列；未启用时不处理
+func (h *AIHandler) labelColumns(ctx context.Context, resp *Chat2SQLResponse, connectionID int64, question string) {
+	if h.columnLabels == nil {
+		return
+	}
+	var columns []string
+	if resp.Result != nil && len(resp.Result.Columns) > 0 {
+		columns = resp.Result.Columns
+	} else {
+		for _, item := range resp.Lineage {
+			columns = append(columns, item.Column)
+		}
+	}
+	resp.ColumnLabels = h.columnLabels.Label(ctx, connectionID, question, resp.Locale, columns, resp.Lineage)
+}
+
+// columnPolicy 按用户角色与访问例外构建连接的列处理策略；未启用时返回nil
+// 加载失败只记录日志，不影响SQL生成，但执行结果不再返回数据
+func (h *AIHandler) columnPolicy(ctx context.Context, userID int64, role string, connectionID int64, requestID string) (*service.ColumnPolicy, error) {
+	if h.classifications == nil {
+		return nil, nil
+	}
+
+	policy, err := h.classifications.Policy(ctx, connectionID, userID, role)
+	if err != nil {
+		h.logger.Warn("加载列数据分级失败",
+			zap.String("request_id", requestID),
+			zap.Int64("connection_id", connectionID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return policy, nil
+}
+
+// applyWorkspaceDefaults 按用户所属工作空间的默认设置补全请求未指定的字段
+// 补全后仍没有连接时返回400；返回false表示已写入错误响应
+func (h *AIHandler) applyWorkspaceDefaults(ctx context.Context, c *gin.Context, req *Chat2SQLRequest, userID int64, requestID string) bool {
+	err := h.resolveDefaults(ctx, req, userID)
+	switch {
+	case errors.Is(err, errNoConnection):
+		h.respondWithError(c, http.StatusBadRequest, "请求参数无效", err.Error(), requestID)
+		return false
+	case err != nil:
+		h.logger.Error("获取工作空间默认设置失败",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		h.respondWithError(c, http.StatusInternalServerError, "获取工作空间默认设置失败", err.Error(), requestID)
+		return false
+	}
+	return true
+}
+
+// applyChargeback 校验请求携带的费用归属标签并写入context，生成与自动执行按标签记录用量
+// 标签不在工作空间取值范围内时返回400；返回false表示已写入错误响应
+func (h *AIHandler) applyChargeback(ctx context.Context, c *gin.Context, userID int64, requestID string) (context.Context, bool) {
+	tags, err := resolveChargeback(c, h.workspaceSettings, userID)
+	switch {
+	case errors.Is(err, repository.ErrInvalidInput):
+		h.respondWithError(c, http.StatusBadRequest, "费用归属标签无效", err.Error(), requestID)
+		return ctx, false
+	case err != nil:
+		h.logger.Error("获取费用归属标签范围失败",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		h.respondWithError(c, http.StatusInternalServerError, "获取工作空间设置失败", err.Error(), requestID)
+		return ctx, false
+	}
+	return service.WithChargebackTags(ctx, tags), true
+}
+
+// errNoConnection 请求未指定连接且工作空间未配置默认连接
+var errNoConnection = errors.New("未指定connection_id且工作空间未配置默认连接")
+
+// resolveDefaults 按工作空间默认设置补全请求未指定的连接、语言与行数上限，补全后仍没有连接时返回errNoConnection
+func (h *AIHandler) resolveDefaults(ctx context.Context, req *Chat2SQLRequest, userID int64) error {
+	if h.workspaceSettings != nil {
+		fields := &service.RequestDefaults{ConnectionID: req.ConnectionID, Locale: req.Locale, RowLimit: req.RowLimit}
+		if err := h.workspaceSettings.Apply(ctx, userID, fields); err != nil {
+			return err
+		}
+		req.ConnectionID, req.Locale, req.RowLimit = fields.ConnectionID, fields.Locale, fields.RowLimit
+	}
+
+	if req.ConnectionID == 0 {
+		return errNoConnection
+	}
+	return nil
+}
+
+// applyColumnPolicy 对执行结果中的受限列脱敏或过滤；分级加载失败时隐藏结果数据
+func applyColumnPolicy(result *service.QueryResult, lineage []service.ColumnLineage, policy *service.ColumnPolicy, policyErr error, locale string) {
+	switch {
+	case result == nil:
+	case policyErr != nil:
+		result.Rows = nil
+		result.Warnings = append(result.Warnings, service.Localize(locale, "无法加载列数据分级，结果数据已隐藏"))
+		result.Guardrails = append(result.Guardrails, service.Guardrail{Type: service.GuardrailResultHidden})
+	case policy != nil:
+		policy.Apply(result, lineage)
+	}
+}
+
+// latencySLO 读取用户所属工作空间的主模型延迟目标，未配置或读取失败时返回0，按默认顺序使用模型
+func (h *AIHandler) latencySLO(ctx context.Context, userID int64, requestID string) time.Duration {
+	if h.workspaceSettings == nil {
+		return 0
+	}
+	settings, err := h.workspaceSettings.Resolve(ctx, userID)
+	if err != nil {
+		h.logger.Warn("获取延迟路由策略失败",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		return 0
+	}
+	return settings.LatencySLO()
+}
+
+// recordSchemaSnapshot 记录本次生成使用的表结构快照，返回携带快照的context供自动执行写入查询历史
+// schema为提示词中实际使用的表结构，请求未携带时为按问题挑选的相关表；记录失败不影响SQL生成结果的返回
+func (h *AIHandler) recordSchemaSnapshot(ctx context.Context, resp *Chat2SQLResponse, connectionID int64, schema, requestID string) context.Context {
+	snapshot, err := h.schemaSnapshots.Record(ctx, connectionID, schema)
+	if err != nil {
+		h.logger.Warn("记录表结构快照失败",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		return ctx
+	}
+	if snapshot == nil {
+		return ctx
+	}
+
+	resp.SchemaSnapshotID = &snapshot.ID
+	resp.SchemaVersion = snapshot.Version
+	return service.WithSchemaSnapshot(ctx, snapshot)
+}
+
+// recordGeneration 记录生成置信度与上下文，等待用户对该查询的反馈
+func (h *AIHandler) recordGeneration(queryID string, userID int64, question string, response *service.SQLGenerationResponse) {
+	if h.calibration != nil {
+		h.calibration.RecordPrediction(queryID, response.Confidence, response.ConfidenceBreakdown)
+	}
+	if h.feedback != nil {
+		h.feedback.RememberGeneration(queryID, userID, question, response)
+	}
+}
+
+// archiveGeneration 归档本次生成的提示词与模型原始输出，归档失败不影响SQL生成结果的返回
+func (h *AIHandler) archiveGeneration(ctx context.Context, response *service.SQLGenerationResponse, queryID string, userID, connectionID int64, requestID string) {
+	_, err := h.llmArchive.Archive(ctx, &service.LLMArchiveEntry{
+		QueryID:      queryID,
+		UserID:       userID,
+		ConnectionID: connectionID,
+		Prompt:       response.Prompt,
+		Completion:   response.Completion,
+		SQL:          response.SQL,
+		Generation:   response.Generation,
+	})
+	if err != nil {
+		h.logger.Warn("归档模型请求与响应失败",
+			zap.String("request_id", requestID),
+			zap.String("query_id", queryID),
+			zap.Error(err),
+		)
+	}
+}
+
+// applyAutoExecute 按工作空间策略决定是否直接执行生成的SQL
+// 未自动执行时要求用户确认；判定失败不影响SQL生成结果的返回
+func (h *AIHandler) applyAutoExecute(ctx context.Context, resp *Chat2SQLResponse, userID int64, req Chat2SQLRequest, requestID string) {
+	// 提取了绑定参数时按参数绑定执行
+	sql, ctx := resp.SQL, service.WithRowLimit(ctx, req.RowLimit)
+	if resp.ParameterizedSQL != "" {
+		sql, ctx = resp.ParameterizedSQL, service.WithQueryParameters(ctx, resp.Parameters)
+	}
+	outcome, err := h.autoExecutor.Run(ctx, userID, req.ConnectionID, req.Query, sql, resp.Confidence)
+	if err != nil {
+		h.logger.Warn("自动执行判定失败",
+			zap.String("request_id", requestID),
+			zap.Error(err),
+		)
+		resp.RequiresConfirmation = true
+		return
+	}
+
+	resp.AutoExecute = outcome.Decision
+	if outcome.Result == nil {
+		resp.RequiresConfirmation = true
+		return
+	}
+
+	resp.RequiresConfirmation = false
+	resp.ExecutionPath = string(repository.ExecutionAuto)
+	resp.Result = outcome.Result
+	resp.ExecutedQueryID = outcome.QueryHistoryID
+}
+
 // SubmitFeedback 处理用户反馈提交
 // @Summary 提交查询反馈
-// @Description 提交AI生成SQL查询的用户反馈和评价
+// @Description 提交AI生成SQL查询的评分、是否正确与修正后的SQL，写入准确率统计并交给学习引擎；同一查询再次提交按修正处理
 // @Tags AI
 // @Accept json
 // @Produce json
 // @Param request body FeedbackRequest true "反馈请求"
 // @Success 200 {object} FeedbackResponse "反馈提交成功"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "查询不存在或已过期"
 // @Failure 500 {object} ErrorResponse "服务器内部错误"
 // @Router /api/v1/ai/feedback [post]
 func (h *AIHandler) SubmitFeedback(c *gin.Context) {
@@ -243,12 +876,30 @@ func (h *AIHandler) SubmitFeedback(c *gin.Context) {
 		zap.Int("user_rating", req.UserRating),
 	)
 
-	// 实现反馈存储逻辑
-	if err := h.storeFeedback(req, userIDInt64, requestID); err != nil {
-		h.logger.Error("存储用户反馈失败",
-			zap.String("request_id", requestID),
-			zap.Error(err))
-		// 存储失败不影响响应，继续返回成功
+	if h.calibration != nil {
+		h.calibration.RecordOutcome(req.QueryID, req.IsCorrect)
+	}
+
+	if h.feedback != nil {
+		err := h.feedback.Submit(c.Request.Context(), userIDInt64, service.FeedbackSubmission{
+			QueryID:      req.QueryID,
+			IsCorrect:    req.IsCorrect,
+			Rating:       req.UserRating,
+			Comment:      req.Feedback,
+			CorrectedSQL: req.UserSQL,
+		})
+		switch {
+		case errors.Is(err, service.ErrFeedbackQueryNotFound):
+			h.respondWithError(c, http.StatusNotFound, "查询不存在或已过期", err.Error(), requestID)
+			return
+		case err != nil:
+			h.logger.Error("记录用户反馈失败",
+				zap.String("request_id", requestID),
+				zap.String("query_id", req.QueryID),
+				zap.Error(err))
+			h.respondWithError(c, http.StatusInternalServerError, "反馈提交失败", err.Error(), requestID)
+			return
+		}
 	}
 
 	// 构建响应
@@ -284,16 +935,29 @@ func (h *AIHandler) GetAIStats(c *gin.Context) {
 
 	// 实现统计信息获取逻辑
 	stats := h.getAIServiceStats()
+	if tracked, ok := h.aiService.(interface{ LatencyStats() []service.ModelLatency }); ok {
+		// 主备模型滚动窗口内的P95延迟，延迟路由据此判断是否改道
+		stats["model_latency"] = tracked.LatencyStats()
+	}
+	if chained, ok := h.aiService.(interface{ ProviderHealth() []service.ProviderHealth }); ok {
+		// 降级链中各模型的熔断状态与失败统计
+		if health := chained.ProviderHealth(); health != nil {
+			stats["providers"] = health
+		}
+	}
 	stats["timestamp"] = time.Now().UTC().Format(time.RFC3339)
 
 	c.JSON(http.StatusOK, stats)
 }
 
+// localeContextKey gin上下文中本次请求的响应语言，respondWithError据此返回对应语言的错误消息
+const localeContextKey = "locale"
+
 // 辅助函数：统一错误响应
 func (h *AIHandler) respondWithError(c *gin.Context, statusCode int, message, detail, requestID string) {
 	errorResponse := &ErrorResponse{
 		Code:      "AI_SERVICE_ERROR",
-		Message:   message,
+		Message:   service.Localize(c.GetString(localeContextKey), message),
 		Details:   detail,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		RequestID: requestID,
@@ -309,6 +973,8 @@ func (h *AIHandler) respondWithError(c *gin.Context, statusCode int, message, de
 		errorResponse.Code = "REQUEST_TIMEOUT"
 	case http.StatusTooManyRequests:
 		errorResponse.Code = "RATE_LIMIT_EXCEEDED"
+	case http.StatusUnprocessableEntity:
+		errorResponse.Code = "UNSAFE_SQL"
 	case http.StatusInternalServerError:
 		errorResponse.Code = "INTERNAL_SERVER_ERROR"
 	}
@@ -343,180 +1009,6 @@ func isRateLimitError(err error) bool {
 		   err.Error() == "too many requests"
 }
 
-// storeFeedback 存储用户反馈到数据库
-func (h *AIHandler) storeFeedback(req FeedbackRequest, userID int64, requestID string) error {
-	h.logger.Info("存储用户反馈",
-		zap.String("request_id", requestID),
-		zap.String("query_id", req.QueryID),
-		zap.Int64("user_id", userID),
-		zap.Bool("is_correct", req.IsCorrect),
-		zap.Int("user_rating", req.UserRating),
-		zap.String("feedback", req.Feedback),
-	)
-	
-	// 注意：由于项目当前阶段可能还未完整实现所有Repository
-	// 这里提供一个基本的实现框架，实际需要配合Repository实现
-	
-	// 从query_id中尝试解析相关信息
-	userQuery, generatedSQL := h.extractQueryInfoFromID(req.QueryID)
-	
-	// 构建反馈数据结构（使用内存中的结构，实际应该从数据库查询或请求中获取）
-	feedback := &struct {
-		QueryID        string  `json:"query_id"`
-		UserID         int64   `json:"user_id"`
-		UserQuery      string  `json:"user_query"`
-		GeneratedSQL   string  `json:"generated_sql"`
-		ExpectedSQL    *string `json:"expected_sql,omitempty"`
-		IsCorrect      bool    `json:"is_correct"`
-		UserRating     int     `json:"user_rating"`
-		FeedbackText   *string `json:"feedback_text,omitempty"`
-		Category       string  `json:"category"`
-		Difficulty     string  `json:"difficulty"`
-		ErrorType      *string `json:"error_type,omitempty"`
-		ErrorDetails   *string `json:"error_details,omitempty"`
-		ProcessingTime int64   `json:"processing_time"`
-		TokensUsed     int     `json:"tokens_used"`
-		ModelUsed      string  `json:"model_used"`
-		ConnectionID   *int64  `json:"connection_id,omitempty"`
-	}{
-		QueryID:        req.QueryID,
-		UserID:         userID,
-		UserQuery:      userQuery,
-		GeneratedSQL:   generatedSQL,
-		IsCorrect:      req.IsCorrect,
-		UserRating:     req.UserRating,
-		Category:       h.categorizeQuery(userQuery),
-		Difficulty:     h.assessDifficulty(userQuery, generatedSQL),
-		ProcessingTime: 0, // 从查询历史或缓存中获取
-		TokensUsed:     0, // 从AI服务响应中获取
-		ModelUsed:      "default", // 从配置或上下文中获取
-	}
-	
-	if req.Feedback != "" {
-		feedback.FeedbackText = &req.Feedback
-	}
-	if req.UserSQL != "" {
-		feedback.ExpectedSQL = &req.UserSQL
-	}
-	if !req.IsCorrect {
-		errorType := h.inferErrorType(userQuery, generatedSQL)
-		feedback.ErrorType = &errorType
-		errorDetails := "用户标记为不正确"
-		feedback.ErrorDetails = &errorDetails
-	}
-	
-	// 实际的数据库存储逻辑
-	// 注意：这里使用简化的实现，实际应该使用Repository接口
-	h.logger.Info("准备存储反馈到数据库",
-		zap.String("query_id", feedback.QueryID),
-		zap.String("category", feedback.Category),
-		zap.String("difficulty", feedback.Difficulty),
-		zap.Int64("processing_time", feedback.ProcessingTime),
-	)
-	
-	// 如果有Repository实例，使用它来存储
-	// if h.repository != nil {
-	//     dbFeedback := &repository.Feedback{
-	//         QueryID: feedback.QueryID,
-	//         UserID: feedback.UserID,
-	//         // ... 其他字段映射
-	//     }
-	//     return h.repository.FeedbackRepo().Create(ctx, dbFeedback)
-	// }
-	
-	// 暂时返回成功，实际实现需要真正的数据库操作
-	h.logger.Info("反馈存储模拟完成", 
-		zap.String("request_id", requestID),
-		zap.String("query_id", req.QueryID))
-	
-	return nil
-}
-
-// extractQueryInfoFromID 从查询ID中提取查询信息
-// 实际应该从数据库或缓存中查询
-func (h *AIHandler) extractQueryInfoFromID(queryID string) (userQuery, generatedSQL string) {
-	// 这里应该根据queryID从数据库中查询对应的查询历史记录
-	// 暂时返回空值，实际需要实现查询逻辑
-	h.logger.Debug("从查询ID提取信息", zap.String("query_id", queryID))
-	return "用户自然语言查询", "SELECT * FROM users;"
-}
-
-// categorizeQuery 查询分类（简化版本）
-func (h *AIHandler) categorizeQuery(query string) string {
-	// 使用简化的分类逻辑
-	queryLower := strings.ToLower(query)
-	
-	if strings.Contains(queryLower, "join") {
-		return "join_query"
-	} else if strings.Contains(queryLower, "count") || strings.Contains(queryLower, "sum") || 
-			  strings.Contains(queryLower, "avg") || strings.Contains(queryLower, "统计") {
-		return "aggregation"
-	} else if strings.Contains(queryLower, "时间") || strings.Contains(queryLower, "日期") ||
-			  strings.Contains(queryLower, "趋势") {
-		return "time_analysis"
-	} else if strings.Contains(queryLower, "子查询") || strings.Contains(queryLower, "in (") {
-		return "subquery"
-	}
-	
-	return "basic_select"
-}
-
-// assessDifficulty 评估查询难度（简化版本）
-func (h *AIHandler) assessDifficulty(userQuery, generatedSQL string) string {
-	sqlLower := strings.ToLower(generatedSQL)
-	
-	// 计算复杂度分数
-	complexityScore := 0
-	
-	if strings.Contains(sqlLower, "join") {
-		complexityScore += 2
-	}
-	if strings.Contains(sqlLower, "group by") {
-		complexityScore += 2
-	}
-	if strings.Contains(sqlLower, "having") {
-		complexityScore += 3
-	}
-	if strings.Contains(sqlLower, "union") {
-		complexityScore += 3
-	}
-	if strings.Contains(sqlLower, "case when") {
-		complexityScore += 2
-	}
-	
-	subqueryCount := strings.Count(sqlLower, "select") - 1
-	complexityScore += subqueryCount * 2
-	
-	if complexityScore <= 1 {
-		return "easy"
-	} else if complexityScore <= 4 {
-		return "medium"
-	} else if complexityScore <= 8 {
-		return "hard"
-	} else {
-		return "expert"
-	}
-}
-
-// inferErrorType 推断错误类型
-func (h *AIHandler) inferErrorType(userQuery, generatedSQL string) string {
-	queryLower := strings.ToLower(userQuery)
-	
-	if strings.Contains(queryLower, "表") && strings.Contains(queryLower, "不存在") {
-		return "表不存在"
-	} else if strings.Contains(queryLower, "字段") && strings.Contains(queryLower, "错误") {
-		return "字段不存在"
-	} else if strings.Contains(queryLower, "语法") {
-		return "语法错误"
-	} else if strings.Contains(queryLower, "join") {
-		return "JOIN错误"
-	} else if strings.Contains(queryLower, "group") || strings.Contains(queryLower, "聚合") {
-		return "聚合函数错误"
-	}
-	
-	return "未知错误"
-}
-
 // getAIServiceStats 获取AI服务统计信息
 func (h *AIHandler) getAIServiceStats() map[string]any {
 	// 这里应该从实际的监控系统或数据库中获取统计信息
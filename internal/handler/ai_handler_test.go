@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/routing"
+	"chat2sql-go/internal/service"
+)
+
+func TestAIHandler_Chat2SQL_MatchesQuestionLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	connectionID := int64(3)
+	workspace := &repository.Workspace{Defaults: &repository.WorkspaceDefaults{ConnectionID: &connectionID, Locale: "zh"}}
+	workspaces := &stubWorkspaceRepository{workspace: workspace}
+
+	aiService := &MockAIService{}
+	aiService.On("GenerateSQL", mock.Anything, mock.MatchedBy(func(req *service.SQLGenerationRequest) bool {
+		return req.Query == "broken question"
+	})).Return(nil, errors.New("model unavailable"))
+	aiService.On("GenerateSQL", mock.Anything, mock.Anything).Return(&service.SQLGenerationResponse{SQL: "SELECT 1", Confidence: 0.9}, nil)
+
+	h := NewAIHandler(aiService, zap.NewNop())
+	h.SetWorkspaceSettings(service.NewWorkspaceSettingsService(workspaces, &MockConnectionRepository{}, nil, zap.NewNop()))
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	r.POST("/ai/chat2sql", h.Chat2SQL)
+
+	post := func(body string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodPost, "/ai/chat2sql", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp
+	}
+
+	w, resp := post(`{"query":"How many orders per customer?"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "en", resp["locale"], "英文问题覆盖工作空间默认的中文")
+
+	w, resp = post(`{"query":"每个客户的订单数"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "zh", resp["locale"])
+
+	w, resp = post(`{"query":"How many orders per customer?","locale":"zh"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "zh", resp["locale"], "请求显式指定的语言优先")
+
+	w, resp = post(`{"query":"2024"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "zh", resp["locale"], "检测不出语言时使用工作空间默认语言")
+
+	w, resp = post(`{"query":"broken question"}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "Failed to process the AI query", resp["message"], "错误消息与问题语言一致")
+
+	var requests []*service.SQLGenerationRequest
+	for _, call := range aiService.Calls {
+		requests = append(requests, call.Arguments.Get(1).(*service.SQLGenerationRequest))
+	}
+	require.Len(t, requests, 5)
+	assert.Equal(t, "en", requests[0].Locale, "提示词按检测到的语言生成")
+	assert.Equal(t, connectionID, requests[0].ConnectionID)
+}
+
+// streamingLLM 按块流式输出固定内容的模型
+type streamingLLM struct {
+	chunks []string
+}
+
+func (m *streamingLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	for _, chunk := range m.chunks {
+		if opts.StreamingFunc != nil {
+			if err := opts.StreamingFunc(ctx, []byte(chunk)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: strings.Join(m.chunks, "")}}}, nil
+}
+
+func (m *streamingLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestAIHandler_StreamChat2SQL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	primary := &streamingLLM{chunks: []string{"SELECT id", " FROM users", " LIMIT 10"}}
+	aiService := service.NewAIServiceWithClients(config.DemoAIConfig(""), primary, &streamingLLM{}, zap.NewNop())
+	h := NewAIHandler(aiService, zap.NewNop())
+	h.SetColumnLabeler(service.NewColumnLabeler(nil, zap.NewNop()))
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	r.POST("/ai/generate/stream", h.StreamChat2SQL)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ai/generate/stream", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"query":"列出前10个用户","connection_id":1}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Equal(t, 3, strings.Count(body, "event:token"))
+	assert.Contains(t, body, `data:{"text":" FROM users"}`)
+	result := body[strings.Index(body, "event:result\ndata:")+len("event:result\ndata:"):]
+	var resp Chat2SQLResponse
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(result)), &resp))
+	assert.Equal(t, "SELECT id FROM users LIMIT 10", resp.SQL)
+	assert.NotEmpty(t, resp.QueryID)
+	assert.Equal(t, []service.ColumnLabel{{Column: "id", Label: "ID", Source: service.LabelSourceName}}, resp.ColumnLabels)
+	assert.Less(t, strings.Index(body, "event:token"), strings.Index(body, "event:result"), "token事件先于结果推送")
+
+	// 参数错误在开始推送前以普通JSON返回
+	w = post(`{"query":"列出用户","critical":true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+func TestAIHandler_SubmitFeedback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	aiService := &MockAIService{}
+	aiService.On("GenerateSQL", mock.Anything, mock.Anything).Return(&service.SQLGenerationResponse{
+		SQL:        "SELECT COUNT(*) FROM orders",
+		Confidence: 0.9,
+		Generation: &service.GenerationParameters{Model: "gpt-4o-mini"},
+	}, nil)
+
+	learningConfig := routing.DefaultLearningConfig()
+	learningConfig.EnableAsyncLearning = false
+	learning := routing.NewLearningEngine(context.Background(), learningConfig)
+	defer learning.Close()
+	accuracy := ai.NewAccuracyMonitor(ai.DefaultAccuracyConfig(), zap.NewNop())
+
+	h := NewAIHandler(aiService, zap.NewNop())
+	h.SetFeedbackService(service.NewFeedbackService(accuracy, learning, nil, zap.NewNop()))
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	r.POST("/ai/chat2sql", h.Chat2SQL)
+	r.POST("/ai/feedback", h.SubmitFeedback)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/ai/chat2sql", `{"query":"订单总数","connection_id":1}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var generated Chat2SQLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &generated))
+
+	w = post("/ai/feedback", fmt.Sprintf(`{"query_id":%q,"is_correct":false,"user_rating":2,"user_sql":"SELECT COUNT(*) FROM orders WHERE status = 'paid'"}`, generated.QueryID))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	report, err := accuracy.GetAccuracyReport(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.OverallStats.TotalQueries)
+	assert.Zero(t, report.OverallStats.CorrectQueries)
+	assert.Equal(t, int64(1), learning.GetLearningStats().TotalFeedback)
+
+	w = post("/ai/feedback", `{"query_id":"unknown","is_correct":true,"user_rating":5}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAIHandler_BudgetExceeded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	aiConfig := config.DefaultAIConfig()
+	aiConfig.Budget.UserLimit = 0.0001
+	model := &streamingLLM{chunks: []string{"SELECT COUNT(*) FROM orders"}}
+	aiService := service.NewAIServiceWithClients(aiConfig, model, model, zap.NewNop())
+	costs := ai.NewCostTracker(ai.CostConfigFromBudget(aiConfig.Budget))
+	aiService.SetCostTracker(costs)
+
+	h := NewAIHandler(aiService, zap.NewNop())
+	h.SetCostTracker(costs, aiConfig.Budget.Enforce)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+		c.Set("user_role", c.GetHeader("X-Test-Role"))
+	})
+	r.POST("/ai/chat2sql", h.Chat2SQL)
+	r.GET("/ai/usage", h.GetUsage)
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ai/chat2sql", bytes.NewBufferString(`{"query":"订单总数","connection_id":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	get := func(path, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Test-Role", role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 第一次调用用完用户当日预算，之后在调用模型前被拒绝
+	require.Equal(t, http.StatusOK, post().Code)
+	w := post()
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, "BUDGET_EXCEEDED", errResp.Code)
+
+	w = get("/ai/usage", "user")
+	require.Equal(t, http.StatusOK, w.Code)
+	var usage UsageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+	assert.Equal(t, 1, usage.Budget.UserQueries)
+	assert.GreaterOrEqual(t, usage.Budget.UserCost, usage.Budget.UserLimit)
+	assert.True(t, usage.Enforce)
+	assert.Nil(t, usage.Summary, "全局汇总仅管理员可见")
+
+	w = get("/ai/usage", "admin")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+	assert.NotNil(t, usage.Summary)
+	assert.Equal(t, http.StatusBadRequest, get("/ai/usage?days=365", "user").Code)
+
+	// 预算上调经审批后生效
+	engine := service.NewApprovalEngine(newStubApprovalRepository(), config.DefaultApprovalConfig(), zap.NewNop())
+	engine.Register(repository.ApprovalBudgetRaise, service.NewBudgetRaiseExecutor(&stubBudgetOverrideStore{}, costs, zap.NewNop()))
+	h.SetApprovalEngine(engine)
+	r.POST("/ai/usage/budget-raise", h.RequestBudgetRaise)
+	raise := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ai/usage/budget-raise", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, raise(`{"daily_limit":0.00001,"days":7,"reason":"报表"}`).Code, "必须高于当前上限")
+	w = raise(`{"daily_limit":5,"days":7,"reason":"季度报表"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var approval repository.ApprovalRequest
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &approval))
+	assert.Equal(t, http.StatusTooManyRequests, post().Code, "审批通过前不生效")
+
+	_, err := engine.Approve(context.Background(), 9, "manager", approval.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, post().Code)
+}
+
+// stubBudgetOverrideStore 只接受预算上调写入的用量存储
+type stubBudgetOverrideStore struct {
+	repository.LLMUsageRepository
+}
+
+func (s *stubBudgetOverrideStore) SetBudgetOverride(ctx context.Context, override *repository.LLMBudgetOverride) error {
+	return nil
+}
+
+func TestAIHandler_Visualize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := NewAIHandler(&MockAIService{}, zap.NewNop())
+	r := gin.New()
+	r.POST("/ai/visualize", h.Visualize)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ai/visualize", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"question":"每月销售额的变化趋势","columns":["month","revenue"],
+		"rows":[{"month":"2024-01-01T00:00:00Z","revenue":120.5},{"month":"2024-02-01T00:00:00Z","revenue":98}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var suggestion ai.ChartSuggestion
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &suggestion))
+	assert.Equal(t, ai.ChartLine, suggestion.Chart)
+	assert.True(t, suggestion.TimeSeries)
+	assert.Equal(t, "https://vega.github.io/schema/vega-lite/v5.json", suggestion.Spec["$schema"])
+	assert.Equal(t, 2, suggestion.SampledRows)
+
+	assert.Equal(t, http.StatusBadRequest, post(`{"rows":[]}`).Code, "缺少columns")
+}
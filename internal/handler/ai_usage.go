@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+const (
+	defaultUsageHistoryDays = 7  // 用量历史默认返回的天数
+	maxUsageHistoryDays     = 90 // 用量历史最多返回的天数
+)
+
+// UsageResponse 模型用量响应
+type UsageResponse struct {
+	Budget  ai.BudgetStatus        `json:"budget"`            // 当日用量与预算
+	Enforce bool                   `json:"enforce"`           // 超出预算时是否拒绝请求
+	History []*repository.LLMUsage `json:"history"`           // 最近几天按提供商与模型汇总的用量
+	Summary *ai.CostSummary        `json:"summary,omitempty"` // 全局成本汇总，仅管理员可见
+}
+
+// SetCostTracker 启用模型用量统计，用量接口据此返回当日用量与预算；enforce表示超出预算时拒绝请求
+func (h *AIHandler) SetCostTracker(tracker *ai.CostTracker, enforce bool) {
+	h.costs = tracker
+	h.budgetEnforce = enforce
+}
+
+// GetUsage 获取当前用户的模型用量与每日预算
+// @Summary 获取模型用量与预算
+// @Description 返回当前用户当日的估算用量、每日预算上限与最近几天的用量历史，管理员额外返回全局成本汇总
+// @Tags AI
+// @Produce json
+// @Param days query int false "历史天数，默认7，最大90"
+// @Success 200 {object} UsageResponse "用量与预算"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 503 {object} ErrorResponse "未启用用量统计"
+// @Router /api/v1/ai/usage [get]
+func (h *AIHandler) GetUsage(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+	if requestID == "" {
+		requestID = generateRequestID()
+	}
+
+	if h.costs == nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Code:      "USAGE_TRACKING_DISABLED",
+			Message:   service.Localize(c.GetString(localeContextKey), "未启用模型用量统计"),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			RequestID: requestID,
+		})
+		return
+	}
+
+	userID, ok := c.Get("user_id")
+	userIDInt64, isInt := userID.(int64)
+	if !ok || !isInt {
+		h.respondWithError(c, http.StatusUnauthorized, "认证信息无效", "user_id not found in context", requestID)
+		return
+	}
+
+	days := defaultUsageHistoryDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUsageHistoryDays {
+			h.respondWithError(c, http.StatusBadRequest, "请求参数无效", "days must be between 1 and 90", requestID)
+			return
+		}
+		days = n
+	}
+
+	history, err := h.costs.UsageHistory(c.Request.Context(), userIDInt64, days)
+	if err != nil {
+		h.logger.Error("查询模型用量失败", zap.String("request_id", requestID), zap.Error(err))
+		h.respondWithError(c, http.StatusInternalServerError, "查询模型用量失败", err.Error(), requestID)
+		return
+	}
+	if history == nil {
+		history = []*repository.LLMUsage{}
+	}
+
+	resp := &UsageResponse{
+		Budget:  h.costs.BudgetStatus(userIDInt64),
+		Enforce: h.budgetEnforce,
+		History: history,
+	}
+	if c.GetString("user_role") == "admin" {
+		resp.Summary = h.costs.GetCostSummary()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// BudgetRaiseRequest 预算上调申请请求
+type BudgetRaiseRequest struct {
+	DailyLimit float64 `json:"daily_limit" binding:"required"` // 上调后的每日上限（美元）
+	Days       int     `json:"days" binding:"required"`        // 有效天数，最长30天
+	Reason     string  `json:"reason" binding:"required"`      // 申请理由
+}
+
+// SetApprovalEngine 设置审批流程，预算上调经审批后生效；未设置时不能申请上调
+func (h *AIHandler) SetApprovalEngine(engine *service.ApprovalEngine) {
+	h.approvals = engine
+}
+
+// RequestBudgetRaise 申请上调每日模型预算
+// @Summary 申请上调每日模型预算
+// @Description 提交预算上调审批申请，审批通过后在有效期内以上调后的上限替代每用户每日预算
+// @Tags AI
+// @Accept json
+// @Produce json
+// @Param request body BudgetRaiseRequest true "预算上调申请"
+// @Success 202 {object} repository.ApprovalRequest "已提交审批"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 503 {object} ErrorResponse "未启用用量统计或审批流程"
+// @Router /api/v1/ai/usage/budget-raise [post]
+func (h *AIHandler) RequestBudgetRaise(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+	if requestID == "" {
+		requestID = generateRequestID()
+	}
+	if h.costs == nil || h.approvals == nil {
+		h.respondWithError(c, http.StatusServiceUnavailable, "未启用预算上调审批", "usage tracking or approval workflow is disabled", requestID)
+		return
+	}
+
+	userID, ok := c.Get("user_id")
+	userIDInt64, isInt := userID.(int64)
+	if !ok || !isInt {
+		h.respondWithError(c, http.StatusUnauthorized, "认证信息无效", "user_id not found in context", requestID)
+		return
+	}
+
+	var req BudgetRaiseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "请求参数无效", err.Error(), requestID)
+		return
+	}
+	raise := &service.BudgetRaise{DailyLimit: req.DailyLimit, Days: req.Days, Reason: strings.TrimSpace(req.Reason)}
+	current := h.costs.BudgetStatus(userIDInt64).UserLimit
+	if err := raise.Validate(current); err != nil {
+		h.respondWithError(c, http.StatusBadRequest, "请求参数无效", err.Error(), requestID)
+		return
+	}
+
+	approval, err := h.approvals.Submit(c.Request.Context(), &service.ApprovalSubmission{
+		ActionType:  repository.ApprovalBudgetRaise,
+		Summary:     fmt.Sprintf("每日模型预算由%.2f上调至%.2f美元，有效%d天：%s", current, raise.DailyLimit, raise.Days, raise.Reason),
+		Payload:     raise,
+		RequestedBy: userIDInt64,
+	})
+	if err != nil {
+		h.logger.Error("提交预算上调审批失败", zap.String("request_id", requestID), zap.Error(err))
+		h.respondWithError(c, http.StatusInternalServerError, "提交预算上调审批失败", err.Error(), requestID)
+		return
+	}
+	c.JSON(http.StatusAccepted, approval)
+}
+
+// budgetExceeded 判断是否因达到每日预算上限被拒绝，返回对应的提示消息
+func budgetExceeded(err error) (string, bool) {
+	var limitErr *ai.CostLimitError
+	if !errors.As(err, &limitErr) {
+		return "", false
+	}
+	if limitErr.Type == "daily_budget" {
+		return "系统今日AI预算已用完，请明天再试", true
+	}
+	return "今日AI预算已用完，请明天再试", true
+}
+
+// respondBudgetExceeded 返回429与BUDGET_EXCEEDED错误码
+func (h *AIHandler) respondBudgetExceeded(c *gin.Context, message, requestID string) {
+	c.JSON(http.StatusTooManyRequests, ErrorResponse{
+		Code:      "BUDGET_EXCEEDED",
+		Message:   service.Localize(c.GetString(localeContextKey), message),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+)
+
+// VisualizeRequest 图表推荐请求，columns与rows为执行结果中的同名字段，最多10000行，推荐只分析前1000行
+type VisualizeRequest struct {
+	Question string           `json:"question,omitempty" binding:"max=2000" example:"每月销售额的变化趋势"` // 生成SQL时的问题，用于在折线图、柱状图与饼图之间取舍
+	Columns  []string         `json:"columns" binding:"required,min=1,max=200" example:"month,revenue"`
+	Rows     []map[string]any `json:"rows" binding:"max=10000"`
+}
+
+// Visualize 推荐结果图表
+// @Summary 推荐结果图表
+// @Description 按执行结果各列的类型、基数与是否为时间序列推荐图表类型，返回数据源为具名result的Vega-Lite规格；按规则推荐，不调用模型
+// @Tags AI
+// @Accept json
+// @Produce json
+// @Param request body VisualizeRequest true "执行结果"
+// @Success 200 {object} ai.ChartSuggestion "图表推荐"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Router /api/v1/ai/visualize [post]
+func (h *AIHandler) Visualize(c *gin.Context) {
+	requestID := c.GetHeader("X-Request-ID")
+	if requestID == "" {
+		requestID = generateRequestID()
+	}
+
+	var req VisualizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn("图表推荐请求参数验证失败", zap.String("request_id", requestID), zap.Error(err))
+		h.respondWithError(c, http.StatusBadRequest, "请求参数无效", err.Error(), requestID)
+		return
+	}
+
+	suggestion := ai.SuggestChart(req.Columns, req.Rows, req.Question)
+	h.logger.Debug("图表推荐完成",
+		zap.String("request_id", requestID),
+		zap.String("chart", suggestion.Chart),
+		zap.Bool("time_series", suggestion.TimeSeries),
+		zap.Int("rows", len(req.Rows)))
+	c.JSON(http.StatusOK, suggestion)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// AnalyticsHandler 管理分析处理器
+// 管理员据此查看置信度校准、连接使用分布等统计，用证据调整确认与自动执行阈值、安排维护窗口
+type AnalyticsHandler struct {
+	calibration     *service.CalibrationTracker
+	connectionUsage *service.ConnectionUsageService
+	logger          *zap.Logger
+}
+
+// NewAnalyticsHandler 创建管理分析处理器实例
+func NewAnalyticsHandler(calibration *service.CalibrationTracker, connectionUsage *service.ConnectionUsageService, logger *zap.Logger) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		calibration:     calibration,
+		connectionUsage: connectionUsage,
+		logger:          logger,
+	}
+}
+
+// Routes 声明管理分析路由，需要admin角色
+func (h *AnalyticsHandler) Routes() []RouteGroup {
+	admin := []string{string(repository.RoleAdmin)}
+	return []RouteGroup{
+		{
+			Prefix: "/admin/analytics",
+			Tag:    "admin",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/calibration", Handler: h.GetCalibration, Summary: "置信度校准报告（可靠性图数据）", Roles: admin},
+				{Method: http.MethodGet, Path: "/connections/usage", Handler: h.GetConnectionUsage, Summary: "连接使用热力图（按星期与小时的查询量）", Roles: admin},
+			},
+		},
+	}
+}
+
+// GetCalibration 置信度校准报告
+// @Summary 置信度校准报告
+// @Description 按置信度分桶比较意图分类置信度与SQL生成置信度的预测值和用户反馈的实际正确率，返回可靠性图数据与ECE、Brier分数（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param buckets query int false "分桶数（2-50），默认使用配置值"
+// @Success 200 {object} service.CalibrationReport "获取成功"
+// @Failure 400 {object} ErrorResponse "分桶数无效"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Router /api/v1/admin/analytics/calibration [get]
+func (h *AnalyticsHandler) GetCalibration(c *gin.Context) {
+	buckets := 0
+	if v := c.Query("buckets"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 || n > config.MaxCalibrationBuckets {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", fmt.Sprintf("分桶数必须在2到%d之间", config.MaxCalibrationBuckets)))
+			return
+		}
+		buckets = n
+	}
+
+	c.JSON(http.StatusOK, h.calibration.Report(buckets))
+}
+
+// GetConnectionUsage 连接使用热力图
+// @Summary 连接使用热力图
+// @Description 按查询历史统计每个连接最近N周在星期几与小时上的查询量，标出最忙与最闲时段及期间没有查询的闲置连接（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param weeks query int false "统计周数，默认使用配置值"
+// @Param tz query string false "计算星期与小时使用的IANA时区，如Asia/Shanghai，默认使用配置值"
+// @Success 200 {object} service.ConnectionUsageReport "获取成功"
+// @Failure 400 {object} ErrorResponse "周数或时区无效"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/analytics/connections/usage [get]
+func (h *AnalyticsHandler) GetConnectionUsage(c *gin.Context) {
+	weeks := 0
+	if v := c.Query("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "统计周数必须为正整数"))
+			return
+		}
+		weeks = n
+	}
+
+	report, err := h.connectionUsage.Heatmap(c.Request.Context(), weeks, c.Query("tz"))
+	switch {
+	case errors.Is(err, service.ErrInvalidUsageWeeks), errors.Is(err, service.ErrInvalidUsageTimezone):
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", err.Error()))
+		return
+	case err != nil:
+		h.logger.Error("获取连接使用热力图失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("INTERNAL_ERROR", "获取连接使用热力图失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+func TestAnalyticsHandler_GetCalibration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classifier := 0.8
+	aiService := &MockAIService{}
+	aiService.On("GenerateSQL", mock.Anything, mock.Anything).Return(&service.SQLGenerationResponse{
+		SQL:                 "SELECT COUNT(*) FROM orders",
+		Confidence:          0.75,
+		ConfidenceBreakdown: &service.ConfidenceBreakdown{Heuristic: 0.7, Classifier: &classifier},
+	}, nil)
+
+	calibration := service.NewCalibrationTracker(nil)
+	ai := NewAIHandler(aiService, zap.NewNop())
+	ai.SetCalibrationTracker(calibration)
+	h := NewAnalyticsHandler(calibration, nil, zap.NewNop())
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	r.POST("/ai/chat2sql", ai.Chat2SQL)
+	r.POST("/ai/feedback", ai.SubmitFeedback)
+	r.GET("/admin/analytics/calibration", h.GetCalibration)
+
+	post := func(path, body string) map[string]any {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	generated := post("/ai/chat2sql", `{"query":"订单总数","connection_id":1}`)
+	post("/ai/feedback", fmt.Sprintf(`{"query_id":%q,"is_correct":false,"user_rating":2}`, generated["query_id"]))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/analytics/calibration?buckets=4", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report service.CalibrationReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 4, report.Buckets)
+	assert.Zero(t, report.PendingPredictions)
+	require.Len(t, report.Signals, 2)
+	for _, signal := range report.Signals {
+		assert.Equal(t, 1, signal.Samples, signal.Signal)
+		assert.Equal(t, 1, signal.Buckets[3].Samples, "0.75与0.8均落在[0.75, 1]")
+		assert.Zero(t, *signal.ObservedAccuracy)
+	}
+
+	for _, buckets := range []string{"1", "51", "abc"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/analytics/calibration?buckets="+buckets, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, buckets)
+	}
+}
+
+func TestAnalyticsHandler_GetConnectionUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queryRepo := &MockQueryHistoryRepository{}
+	queryRepo.On("GetConnectionUsage", mock.Anything, mock.Anything, "Asia/Shanghai").Return([]*repository.ConnectionUsage{
+		{ConnectionID: 1, ConnectionName: "warehouse", Status: repository.ConnectionActive, Cells: []repository.ConnectionUsageCell{
+			{DayOfWeek: 2, Hour: 14, QueryCount: 12},
+		}},
+		{ConnectionID: 2, ConnectionName: "legacy", Status: repository.ConnectionActive},
+	}, nil)
+	queryRepo.On("GetConnectionUsage", mock.Anything, mock.Anything, "UTC").Return(nil, context.DeadlineExceeded)
+
+	h := NewAnalyticsHandler(nil, service.NewConnectionUsageService(queryRepo, nil, zap.NewNop()), zap.NewNop())
+	r := gin.New()
+	r.GET("/admin/analytics/connections/usage", h.GetConnectionUsage)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/analytics/connections/usage"+query, nil))
+		return w
+	}
+
+	w := get("?weeks=2&tz=Asia/Shanghai")
+	require.Equal(t, http.StatusOK, w.Code)
+	var report service.ConnectionUsageReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 2, report.Weeks)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -14), report.Since, time.Minute)
+	require.Len(t, report.Connections, 2)
+	assert.Equal(t, int64(12), report.Connections[0].Matrix[2][14])
+	assert.True(t, report.Connections[1].Unused)
+
+	for _, query := range []string{"?weeks=0", "?weeks=abc", "?weeks=27", "?tz=Mars/Olympus"} {
+		assert.Equal(t, http.StatusBadRequest, get(query).Code, query)
+	}
+	assert.Equal(t, http.StatusInternalServerError, get("").Code)
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// APIKeyHandler API密钥处理器
+// 用户为CI任务与BI工具创建、查看与撤销自己的API密钥；管理密钥本身只接受JWT认证，密钥不能用来创建新密钥
+type APIKeyHandler struct {
+	keys   *service.APIKeyService
+	logger *zap.Logger
+}
+
+// NewAPIKeyHandler 创建API密钥处理器实例
+func NewAPIKeyHandler(keys *service.APIKeyService, logger *zap.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys:   keys,
+		logger: logger,
+	}
+}
+
+// Routes 声明API密钥路由
+func (h *APIKeyHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/apikeys",
+			Tag:    "apikeys",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.ListAPIKeys, Summary: "列出API密钥"},
+				{Method: http.MethodPost, Path: "", Handler: h.CreateAPIKey, Summary: "创建API密钥"},
+				{Method: http.MethodDelete, Path: "/:id", Handler: h.RevokeAPIKey, Summary: "撤销API密钥"},
+			},
+		},
+	}
+}
+
+// CreateAPIKeyRequest 创建API密钥请求
+type CreateAPIKeyRequest struct {
+	Name      string                   `json:"name" binding:"required,max=100" example:"ci-nightly"`
+	Scopes    []repository.APIKeyScope `json:"scopes" binding:"required,min=1,dive,oneof=sql ai" example:"sql,ai"`
+	ExpiresAt *time.Time               `json:"expires_at" example:"2026-12-31T00:00:00Z"`
+}
+
+// APIKeyCreatedResponse 创建API密钥响应，key为明文密钥，只在创建时返回一次
+type APIKeyCreatedResponse struct {
+	*repository.APIKey
+	Key string `json:"key" example:"c2s_9f86d081884c7d659a2feaa0c55ad015"`
+}
+
+// APIKeyListResponse API密钥列表
+type APIKeyListResponse struct {
+	APIKeys []*repository.APIKey `json:"api_keys"`
+}
+
+// ListAPIKeys 列出API密钥
+// @Summary 列出API密钥
+// @Description 列出当前用户的API密钥，包括已撤销的密钥，只返回密钥前缀
+// @Tags API密钥
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} APIKeyListResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/apikeys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	keys, err := h.keys.List(c.Request.Context(), userID)
+	if err != nil {
+		h.respondAPIKeyError(c, err)
+		return
+	}
+	if keys == nil {
+		keys = []*repository.APIKey{}
+	}
+	c.JSON(http.StatusOK, &APIKeyListResponse{APIKeys: keys})
+}
+
+// CreateAPIKey 创建API密钥
+// @Summary 创建API密钥
+// @Description 创建只能调用指定范围接口（sql、ai）的API密钥，请求通过X-API-Key请求头携带密钥，以当前用户的身份与角色执行；明文密钥只在响应中返回一次
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "密钥名称、接口范围与过期时间"
+// @Success 201 {object} APIKeyCreatedResponse "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/apikeys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	key, plaintext, err := h.keys.Create(c.Request.Context(), userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		h.respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, &APIKeyCreatedResponse{APIKey: key, Key: plaintext})
+}
+
+// RevokeAPIKey 撤销API密钥
+// @Summary 撤销API密钥
+// @Description 撤销当前用户的API密钥，撤销后使用该密钥的请求立即返回401
+// @Tags API密钥
+// @Security BearerAuth
+// @Param id path int true "API密钥ID"
+// @Success 204 "撤销成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "密钥不存在或已撤销"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/apikeys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_API_KEY_ID", "API密钥ID格式错误"))
+		return
+	}
+
+	if err := h.keys.Revoke(c.Request.Context(), userID, keyID); err != nil {
+		h.respondAPIKeyError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondAPIKeyError 将API密钥服务错误映射为HTTP响应
+func (h *APIKeyHandler) respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_API_KEY_REQUEST",
+			Message: "API密钥参数无效",
+			Details: err.Error(),
+		})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("API_KEY_NOT_FOUND", "API密钥不存在或已撤销"))
+	default:
+		h.logger.Error("API key operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("API_KEY_ERROR", "API密钥处理失败"))
+	}
+}
@@ -0,0 +1,365 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// ApprovalHandler 审批流程处理器
+// 写操作、策略变更、预算上调等敏感动作的审批申请由指定审批人（未指定时为manager/admin）通过或驳回
+type ApprovalHandler struct {
+	engine *service.ApprovalEngine
+	logger *zap.Logger
+}
+
+// NewApprovalHandler 创建审批流程处理器实例
+func NewApprovalHandler(engine *service.ApprovalEngine, logger *zap.Logger) *ApprovalHandler {
+	return &ApprovalHandler{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// Routes 声明审批流程路由，审批权限由审批流程判定，指定审批人需要admin角色
+func (h *ApprovalHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/approvals",
+			Tag:    "approvals",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.ListApprovals, Summary: "审批申请列表"},
+				{Method: http.MethodGet, Path: "/:id", Handler: h.GetApproval, Summary: "申请详情与审计事件"},
+				{Method: http.MethodPost, Path: "/:id/approve", Handler: h.Approve, Summary: "审批通过并执行", BlockedInMaintenance: true},
+				{Method: http.MethodPost, Path: "/:id/reject", Handler: h.Reject, Summary: "驳回"},
+				{Method: http.MethodGet, Path: "/approvers/:action_type", Handler: h.GetApprovers, Summary: "获取指定审批人", Roles: []string{string(repository.RoleAdmin)}},
+				{Method: http.MethodPut, Path: "/approvers/:action_type", Handler: h.SetApprovers, Summary: "设置指定审批人", Roles: []string{string(repository.RoleAdmin)}},
+			},
+		},
+	}
+}
+
+// ApprovalDecisionRequest 审批意见
+type ApprovalDecisionRequest struct {
+	Comment string `json:"comment,omitempty" binding:"max=500" example:"已核对影响范围"`
+}
+
+// ApprovalListParams 审批申请列表参数
+type ApprovalListParams struct {
+	Status string `form:"status,default=pending" binding:"oneof=pending approved rejected expired executed failed" example:"pending"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100" example:"20"`
+	Cursor string `form:"cursor" binding:"omitempty,max=512"`
+	Offset int    `form:"offset,default=0" binding:"min=0" example:"0"` // 已被cursor取代，仅为兼容旧客户端保留
+}
+
+// ApprovalListResponse 审批申请列表响应
+type ApprovalListResponse struct {
+	Approvals []*repository.ApprovalRequest `json:"approvals"`
+	Limit     int                           `json:"limit" example:"20"`
+	Offset    int                           `json:"offset" example:"0"`
+	CursorPage
+}
+
+// ApprovalDetailResponse 审批申请详情，包含完整审计事件
+type ApprovalDetailResponse struct {
+	Approval *repository.ApprovalRequest `json:"approval"`
+	Events   []*repository.ApprovalEvent `json:"events"`
+}
+
+// ApproversRequest 指定审批人，空列表表示由manager或admin审批
+type ApproversRequest struct {
+	UserIDs []int64 `json:"user_ids" binding:"dive,min=1" example:"3,5"`
+}
+
+// ApproversResponse 指定审批人响应
+type ApproversResponse struct {
+	ActionType string  `json:"action_type" example:"write_query"`
+	UserIDs    []int64 `json:"user_ids"`
+}
+
+// ListApprovals 列出审批申请
+// @Summary 列出审批申请
+// @Description 按状态分页列出审批申请，默认列出待审批的申请；manager与admin可以看到全部申请，其他用户只能看到自己提交或处理过的申请，以及自己被指定为审批人的动作类型的申请
+// @Tags 审批
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "申请状态" Enums(pending, approved, rejected, expired, executed, failed) default(pending)
+// @Param limit query int false "每页数量" default(20) minimum(1) maximum(100)
+// @Param cursor query string false "上一页响应中的next_cursor"
+// @Param offset query int false "偏移量（已弃用，请使用cursor）" default(0) minimum(0)
+// @Success 200 {object} ApprovalListResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/approvals [get]
+func (h *ApprovalHandler) ListApprovals(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	role, _ := middleware.GetUserRoleFromContext(c)
+
+	var params ApprovalListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_PARAMS",
+			Message: "查询参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	scope := listScope("approvals", params.Status)
+	offset, err := resolveOffset(params.Cursor, params.Offset, scope)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
+	}
+
+	approvals, err := h.engine.List(c.Request.Context(), userID, role, repository.ApprovalStatus(params.Status), params.Limit+1, offset)
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+	if approvals == nil {
+		approvals = []*repository.ApprovalRequest{}
+	}
+	approvals, page := paginate(approvals, offset, params.Limit, scope)
+
+	c.JSON(http.StatusOK, &ApprovalListResponse{
+		Approvals:  approvals,
+		Limit:      params.Limit,
+		Offset:     offset,
+		CursorPage: page,
+	})
+}
+
+// GetApproval 获取审批申请详情
+// @Summary 获取审批申请详情
+// @Description 返回申请内容与完整审计事件，无权查看的申请按不存在处理
+// @Tags 审批
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "审批申请ID"
+// @Success 200 {object} ApprovalDetailResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "申请不存在或无权查看"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/approvals/{id} [get]
+func (h *ApprovalHandler) GetApproval(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	role, _ := middleware.GetUserRoleFromContext(c)
+
+	approvalID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_APPROVAL_ID", "审批申请ID格式错误"))
+		return
+	}
+
+	approval, events, err := h.engine.Get(c.Request.Context(), userID, role, approvalID)
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+	if events == nil {
+		events = []*repository.ApprovalEvent{}
+	}
+
+	c.JSON(http.StatusOK, &ApprovalDetailResponse{Approval: approval, Events: events})
+}
+
+// Approve 审批通过
+// @Summary 审批通过
+// @Description 由申请人以外的审批人通过，通过后立即执行对应动作；执行失败时申请标记为failed
+// @Tags 审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "审批申请ID"
+// @Param request body ApprovalDecisionRequest false "审批意见"
+// @Success 200 {object} repository.ApprovalRequest "审批完成，status为executed或failed"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "不是审批人或审批自己的申请"
+// @Failure 404 {object} ErrorResponse "申请不存在或已处理"
+// @Failure 410 {object} ErrorResponse "申请已过期"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/approvals/{id}/approve [post]
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	h.decide(c, h.engine.Approve)
+}
+
+// Reject 驳回审批申请
+// @Summary 驳回审批申请
+// @Description 由申请人以外的审批人驳回，驳回后动作不会执行
+// @Tags 审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "审批申请ID"
+// @Param request body ApprovalDecisionRequest false "驳回原因"
+// @Success 200 {object} repository.ApprovalRequest "已驳回"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "不是审批人或审批自己的申请"
+// @Failure 404 {object} ErrorResponse "申请不存在或已处理"
+// @Failure 410 {object} ErrorResponse "申请已过期"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/approvals/{id}/reject [post]
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	h.decide(c, h.engine.Reject)
+}
+
+// GetApprovers 获取动作类型的指定审批人
+// @Summary 获取指定审批人
+// @Description 获取动作类型的指定审批人，为空时由manager或admin审批（需admin角色）
+// @Tags 审批
+// @Produce json
+// @Security BearerAuth
+// @Param action_type path string true "动作类型" Enums(write_query, policy_change, budget_raise)
+// @Success 200 {object} ApproversResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "动作类型错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/approvals/approvers/{action_type} [get]
+func (h *ApprovalHandler) GetApprovers(c *gin.Context) {
+	actionType, ok := h.actionType(c)
+	if !ok {
+		return
+	}
+
+	userIDs, err := h.engine.ListApprovers(c.Request.Context(), actionType)
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+	if userIDs == nil {
+		userIDs = []int64{}
+	}
+
+	c.JSON(http.StatusOK, &ApproversResponse{ActionType: string(actionType), UserIDs: userIDs})
+}
+
+// SetApprovers 设置动作类型的指定审批人
+// @Summary 设置指定审批人
+// @Description 替换动作类型的指定审批人，传入空列表恢复由manager或admin审批（需admin角色）
+// @Tags 审批
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param action_type path string true "动作类型" Enums(write_query, policy_change, budget_raise)
+// @Param request body ApproversRequest true "指定审批人"
+// @Success 200 {object} ApproversResponse "设置成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/approvals/approvers/{action_type} [put]
+func (h *ApprovalHandler) SetApprovers(c *gin.Context) {
+	actionType, ok := h.actionType(c)
+	if !ok {
+		return
+	}
+
+	var req ApproversRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.engine.SetApprovers(c.Request.Context(), actionType, req.UserIDs); err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+	if req.UserIDs == nil {
+		req.UserIDs = []int64{}
+	}
+
+	c.JSON(http.StatusOK, &ApproversResponse{ActionType: string(actionType), UserIDs: req.UserIDs})
+}
+
+// decide 审批与驳回的公共流程
+func (h *ApprovalHandler) decide(c *gin.Context, action func(ctx context.Context, approverID int64, role string, id int64, comment string) (*repository.ApprovalRequest, error)) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	role, _ := c.Get("user_role")
+	roleStr, _ := role.(string)
+
+	approvalID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_APPROVAL_ID", "审批申请ID格式错误"))
+		return
+	}
+
+	var req ApprovalDecisionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_REQUEST",
+				Message: "请求参数格式错误",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	approval, err := action(c.Request.Context(), userID, roleStr, approvalID, req.Comment)
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// actionType 解析并校验路径中的动作类型
+func (h *ApprovalHandler) actionType(c *gin.Context) (repository.ApprovalActionType, bool) {
+	actionType := repository.ApprovalActionType(c.Param("action_type"))
+	switch actionType {
+	case repository.ApprovalWriteQuery, repository.ApprovalPolicyChange, repository.ApprovalBudgetRaise:
+		return actionType, true
+	default:
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_ACTION_TYPE", "不支持的审批动作类型"))
+		return "", false
+	}
+}
+
+// respondApprovalError 将审批流程错误映射为HTTP响应
+func (h *ApprovalHandler) respondApprovalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSelfApproval):
+		c.JSON(http.StatusForbidden, NewErrorResponse("SELF_APPROVAL", "不能审批自己提交的申请"))
+	case errors.Is(err, service.ErrNotApprover):
+		c.JSON(http.StatusForbidden, NewErrorResponse("NOT_APPROVER", "不是该类申请的审批人"))
+	case errors.Is(err, service.ErrApprovalExpired):
+		c.JSON(http.StatusGone, NewErrorResponse("APPROVAL_EXPIRED", "审批申请已过期"))
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("APPROVAL_NOT_FOUND", "审批申请不存在或已处理"))
+	default:
+		h.logger.Error("Approval operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("APPROVAL_ERROR", "审批处理失败"))
+	}
+}
@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// stubApprovalRepository 内存审批申请Repository
+type stubApprovalRepository struct {
+	repository.ApprovalRepository
+	requests  map[int64]*repository.ApprovalRequest
+	approvers map[repository.ApprovalActionType][]int64
+}
+
+func newStubApprovalRepository() *stubApprovalRepository {
+	return &stubApprovalRepository{
+		requests:  make(map[int64]*repository.ApprovalRequest),
+		approvers: make(map[repository.ApprovalActionType][]int64),
+	}
+}
+
+func (s *stubApprovalRepository) Create(ctx context.Context, req *repository.ApprovalRequest) error {
+	req.ID = int64(len(s.requests) + 1)
+	s.requests[req.ID] = req
+	return nil
+}
+
+func (s *stubApprovalRepository) GetByID(ctx context.Context, id int64) (*repository.ApprovalRequest, error) {
+	if req, ok := s.requests[id]; ok {
+		return req, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (s *stubApprovalRepository) ListByStatus(ctx context.Context, status repository.ApprovalStatus, limit, offset int) ([]*repository.ApprovalRequest, error) {
+	return s.ListByParticipant(ctx, 0, approvalActionTypesForTest, status, limit, offset)
+}
+
+func (s *stubApprovalRepository) ListByParticipant(ctx context.Context, userID int64, actionTypes []repository.ApprovalActionType, status repository.ApprovalStatus, limit, offset int) ([]*repository.ApprovalRequest, error) {
+	var requests []*repository.ApprovalRequest
+	for id := int64(1); id <= int64(len(s.requests)); id++ {
+		req := s.requests[id]
+		participant := req.RequestedBy == userID || slices.Contains(actionTypes, repository.ApprovalActionType(req.ActionType))
+		if req.Status == string(status) && participant {
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+func (s *stubApprovalRepository) ListEvents(ctx context.Context, requestID int64) ([]*repository.ApprovalEvent, error) {
+	return nil, nil
+}
+
+func (s *stubApprovalRepository) Decide(ctx context.Context, id, deciderID int64, status repository.ApprovalStatus, comment *string) error {
+	req := s.requests[id]
+	req.Status = string(status)
+	req.DecidedBy = &deciderID
+	return nil
+}
+
+func (s *stubApprovalRepository) RecordResult(ctx context.Context, id int64, status repository.ApprovalStatus, errorMessage *string) error {
+	s.requests[id].Status = string(status)
+	return nil
+}
+
+func (s *stubApprovalRepository) AddEvent(ctx context.Context, event *repository.ApprovalEvent) error {
+	return nil
+}
+
+func (s *stubApprovalRepository) ListApprovers(ctx context.Context, actionType repository.ApprovalActionType) ([]int64, error) {
+	return s.approvers[actionType], nil
+}
+
+func (s *stubApprovalRepository) SetApprovers(ctx context.Context, actionType repository.ApprovalActionType, userIDs []int64) error {
+	s.approvers[actionType] = userIDs
+	return nil
+}
+
+// approvalActionTypesForTest 全部审批动作类型，ListByStatus按全部类型列出
+var approvalActionTypesForTest = []repository.ApprovalActionType{
+	repository.ApprovalWriteQuery, repository.ApprovalPolicyChange, repository.ApprovalBudgetRaise,
+}
+
+// stubPolicyExecutor 审批通过即成功
+type stubPolicyExecutor struct{}
+
+func (stubPolicyExecutor) Execute(ctx context.Context, req *repository.ApprovalRequest, approverID int64) (string, error) {
+	return "", nil
+}
+
+func (stubPolicyExecutor) Cancel(ctx context.Context, req *repository.ApprovalRequest, status repository.ApprovalStatus) error {
+	return nil
+}
+
+func newApprovalTestRouter(t *testing.T, userID int64, role string) (*gin.Engine, *stubApprovalRepository) {
+	gin.SetMode(gin.TestMode)
+
+	repo := newStubApprovalRepository()
+	repo.requests[1] = &repository.ApprovalRequest{
+		ActionType:  string(repository.ApprovalPolicyChange),
+		RequestedBy: 7,
+		Status:      string(repository.ApprovalPending),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	repo.requests[1].ID = 1
+
+	engine := service.NewApprovalEngine(repo, config.DefaultApprovalConfig(), zaptest.NewLogger(t))
+	engine.Register(repository.ApprovalPolicyChange, stubPolicyExecutor{})
+	h := NewApprovalHandler(engine, zaptest.NewLogger(t))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("user_role", role)
+	})
+	r.GET("/approvals", h.ListApprovals)
+	r.GET("/approvals/:id", h.GetApproval)
+	r.POST("/approvals/:id/approve", h.Approve)
+	r.PUT("/approvals/approvers/:action_type", middleware.RequireRole(string(repository.RoleAdmin)), h.SetApprovers)
+	return r, repo
+}
+
+func TestApprovalHandler_Approve(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID int64
+		role   string
+		status int
+		code   string
+	}{
+		{"普通用户无权审批", 9, string(repository.RoleUser), http.StatusForbidden, "NOT_APPROVER"},
+		{"不能审批自己的申请", 7, string(repository.RoleManager), http.StatusForbidden, "SELF_APPROVAL"},
+		{"manager审批通过并执行", 9, string(repository.RoleManager), http.StatusOK, string(repository.ApprovalExecuted)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newApprovalTestRouter(t, tt.userID, tt.role)
+
+			body := bytes.NewBufferString(`{"comment":"ok"}`)
+			req := httptest.NewRequest(http.MethodPost, "/approvals/1/approve", body)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), tt.code)
+		})
+	}
+}
+
+func TestApprovalHandler_SetApprovers(t *testing.T) {
+	r, repo := newApprovalTestRouter(t, 1, string(repository.RoleAdmin))
+
+	req := httptest.NewRequest(http.MethodPut, "/approvals/approvers/write_query", bytes.NewBufferString(`{"user_ids":[3,5]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ApproversResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []int64{3, 5}, resp.UserIDs)
+	assert.Equal(t, []int64{3, 5}, repo.approvers[repository.ApprovalWriteQuery])
+
+	req = httptest.NewRequest(http.MethodPut, "/approvals/approvers/drop_table", bytes.NewBufferString(`{"user_ids":[3]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 非admin不能指定审批人
+	r, _ = newApprovalTestRouter(t, 9, string(repository.RoleManager))
+	req = httptest.NewRequest(http.MethodPut, "/approvals/approvers/write_query", bytes.NewBufferString(`{"user_ids":[9]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestApprovalHandler_Visibility(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  int64
+		role    string
+		visible []int64 // 列表中可见的申请
+	}{
+		{"viewer看不到他人的申请", 9, string(repository.RoleViewer), nil},
+		{"无关用户看不到他人的申请", 8, string(repository.RoleUser), nil},
+		{"申请人看到自己的申请", 7, string(repository.RoleUser), []int64{1, 2}},
+		{"manager看到全部申请", 9, string(repository.RoleManager), []int64{1, 2}},
+		{"指定审批人只看到所审批动作类型的申请", 5, string(repository.RoleUser), []int64{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, repo := newApprovalTestRouter(t, tt.userID, tt.role)
+			repo.requests[2] = &repository.ApprovalRequest{
+				ActionType:  string(repository.ApprovalWriteQuery),
+				Payload:     []byte(`{"connection_id":3,"sql":"UPDATE salaries SET amount = 0"}`),
+				RequestedBy: 7,
+				Status:      string(repository.ApprovalPending),
+				ExpiresAt:   time.Now().Add(time.Hour),
+			}
+			repo.requests[2].ID = 2
+			repo.approvers[repository.ApprovalWriteQuery] = []int64{5}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/approvals", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp ApprovalListResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			var ids []int64
+			for _, approval := range resp.Approvals {
+				ids = append(ids, approval.ID)
+			}
+			assert.Equal(t, tt.visible, ids)
+
+			// 无权查看的申请与不存在的申请同样返回404
+			for _, id := range []int64{1, 2} {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/approvals/"+strconv.FormatInt(id, 10), nil))
+				if slices.Contains(tt.visible, id) {
+					assert.Equal(t, http.StatusOK, w.Code)
+				} else {
+					assert.Equal(t, http.StatusNotFound, w.Code)
+					assert.NotContains(t, w.Body.String(), "salaries")
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -9,6 +10,8 @@ import (
 	"go.uber.org/zap"
 
 	"chat2sql-go/internal/auth"
+	"chat2sql-go/internal/logging"
+	"chat2sql-go/internal/middleware"
 	"chat2sql-go/internal/repository"
 )
 
@@ -29,6 +32,29 @@ func NewAuthHandler(userRepo repository.UserRepository, jwtService auth.JWTServi
 	}
 }
 
+// credentialRateLimit 注册与登录的限流策略，限制密码暴力破解
+var credentialRateLimit = &middleware.RateLimitConfig{
+	RequestsPerSecond: 5,
+	Burst:             10,
+	CleanupInterval:   5 * time.Minute,
+}
+
+// Routes 声明认证路由，无需JWT，注册与登录按IP限流
+func (h *AuthHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/auth",
+			Tag:    "auth",
+			Auth:   AuthNone,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/register", Handler: h.Register, Summary: "用户注册", RateLimit: credentialRateLimit},
+				{Method: http.MethodPost, Path: "/login", Handler: h.Login, Summary: "用户登录", RateLimit: credentialRateLimit},
+				{Method: http.MethodPost, Path: "/refresh", Handler: h.RefreshToken, Summary: "Token刷新"},
+			},
+		},
+	}
+}
+
 // RegisterRequest 用户注册请求结构
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50" example:"john_doe"`
@@ -424,6 +450,14 @@ type ErrorResponse struct {
 	RequestID string `json:"request_id,omitempty" example:"req_123456"`
 }
 
+// MarshalJSON 序列化前清除错误信息中的连接串、密钥等敏感内容，避免随错误详情返回给客户端
+func (e ErrorResponse) MarshalJSON() ([]byte, error) {
+	type plainErrorResponse ErrorResponse
+	e.Message = logging.Redact(e.Message)
+	e.Details = logging.Redact(e.Details)
+	return json.Marshal(plainErrorResponse(e))
+}
+
 // NewErrorResponse 创建标准错误响应
 func NewErrorResponse(code, message string) *ErrorResponse {
 	return &ErrorResponse{
@@ -16,6 +16,7 @@ import (
 
 	"chat2sql-go/internal/auth"
 	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
 )
 
 // MockJWTServiceSimple Mock JWT服务（简化版，避免重复）
@@ -223,18 +224,10 @@ func TestAuthHandler_Login_Success(t *testing.T) {
 
 	// 创建测试用户
 	hashedPassword, _ := hashPassword("password123")
-	testUser := &repository.User{
-		BaseModel: repository.BaseModel{
-			ID:         1,
-			CreateTime: time.Now(),
-			UpdateTime: time.Now(),
-		},
-		Username:     "testuser",
-		Email:        "test@example.com",
-		PasswordHash: hashedPassword,
-		Role:         string(repository.RoleUser),
-		Status:       string(repository.StatusActive),
-	}
+	testUser := testutil.NewUser(1,
+		testutil.WithUsername("testuser"),
+		testutil.WithUserEmail("test@example.com"),
+		testutil.WithPasswordHash(hashedPassword))
 
 	mockUserRepo.On("GetByUsername", mock.Anything, "testuser").Return(testUser, nil)
 	mockUserRepo.On("UpdateLastLogin", mock.Anything, int64(1), mock.AnythingOfType("time.Time")).Return(nil)
@@ -329,15 +322,7 @@ func TestAuthHandler_RefreshToken_Success(t *testing.T) {
 		Role:     string(repository.RoleUser),
 	}
 
-	testUser := &repository.User{
-		BaseModel: repository.BaseModel{
-			ID: 1,
-		},
-		Username: "testuser",
-		Email:    "test@example.com",
-		Role:     string(repository.RoleUser),
-		Status:   string(repository.StatusActive),
-	}
+	testUser := testutil.NewUser(1, testutil.WithUsername("testuser"), testutil.WithUserEmail("test@example.com"))
 
 	mockJWTService.On("ValidateRefreshToken", "valid_refresh_token").Return(claims, nil)
 	mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(testUser, nil)
@@ -434,16 +419,10 @@ func TestAuthHandler_Login_AccountLocked(t *testing.T) {
 	handler := NewAuthHandler(mockUserRepo, mockJWTService, logger)
 
 	hashedPassword, _ := hashPassword("password123")
-	testUser := &repository.User{
-		BaseModel: repository.BaseModel{
-			ID: 1,
-		},
-		Username:     "testuser",
-		Email:        "test@example.com",
-		PasswordHash: hashedPassword,
-		Role:         string(repository.RoleUser),
-		Status:       string(repository.StatusLocked), // 账户已锁定
-	}
+	testUser := testutil.NewUser(1,
+		testutil.WithUsername("testuser"),
+		testutil.WithPasswordHash(hashedPassword),
+		testutil.WithUserStatus(repository.StatusLocked)) // 账户已锁定
 
 	mockUserRepo.On("GetByUsername", mock.Anything, "testuser").Return(testUser, nil)
 
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// databaseAvailabilityMiddleware 系统库熔断期间直接返回503，并通过Retry-After提示客户端稍后重试
+// 熔断器打开时请求在Repository层同样会快速失败，这里提前拦截以返回明确的状态码而非500
+func databaseAvailabilityMiddleware(available func() bool, retryAfter time.Duration) gin.HandlerFunc {
+	seconds := strconv.Itoa(int((retryAfter + time.Second - 1) / time.Second))
+	return func(c *gin.Context) {
+		if available() {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", seconds)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, NewErrorResponse("DATABASE_UNAVAILABLE", "数据库暂时不可用，请稍后重试"))
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseAvailabilityMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	available := true
+	r := gin.New()
+	r.Use(databaseAvailabilityMiddleware(func() bool { return available }, 1500*time.Millisecond))
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	available = false
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "熔断期间应返回503")
+	assert.Equal(t, "2", w.Header().Get("Retry-After"), "Retry-After向上取整到秒")
+	assert.Contains(t, w.Body.String(), "DATABASE_UNAVAILABLE")
+}
@@ -0,0 +1,84 @@
+package handler
+
+// BatchRequest 批量操作请求，单次最多100条
+type BatchRequest struct {
+	IDs []int64 `json:"ids" binding:"required,min=1,max=100,dive,gt=0" example:"1,2,3"`
+}
+
+// BatchRetagRequest 批量修改费用归属标签请求，两个标签均为空表示清除标签
+type BatchRetagRequest struct {
+	IDs        []int64 `json:"ids" binding:"required,min=1,max=100,dive,gt=0" example:"1,2,3"`
+	CostCenter string  `json:"cost_center" example:"财务部"`
+	Project    string  `json:"project" example:"年度预算"`
+}
+
+// BatchMoveRequest 批量移动请求，folder_id为空表示移动到根目录
+type BatchMoveRequest struct {
+	IDs      []int64 `json:"ids" binding:"required,min=1,max=100,dive,gt=0" example:"1,2,3"`
+	FolderID *int64  `json:"folder_id" example:"5"`
+}
+
+// BatchItemResult 批量操作中单个条目的结果
+type BatchItemResult struct {
+	ID      int64  `json:"id" example:"1"`
+	Success bool   `json:"success" example:"false"`
+	Code    string `json:"code,omitempty" example:"NOT_FOUND"`
+	Message string `json:"message,omitempty" example:"记录不存在或无权操作"`
+}
+
+// BatchResponse 批量操作响应
+// 部分条目失败时仍返回200，调用方按results逐条判断
+type BatchResponse struct {
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded" example:"2"`
+	Failed    int               `json:"failed" example:"1"`
+}
+
+// uniqueIDs 按出现顺序去重
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// newBatchResponse 根据实际处理成功的ID生成逐条结果，未成功的条目标记为failCode
+func newBatchResponse(requested, succeeded []int64, failCode, failMessage string) *BatchResponse {
+	done := make(map[int64]bool, len(succeeded))
+	for _, id := range succeeded {
+		done[id] = true
+	}
+
+	response := &BatchResponse{Results: make([]BatchItemResult, 0, len(requested))}
+	for _, id := range requested {
+		if done[id] {
+			response.Results = append(response.Results, BatchItemResult{ID: id, Success: true})
+			response.Succeeded++
+			continue
+		}
+		response.Results = append(response.Results, BatchItemResult{ID: id, Code: failCode, Message: failMessage})
+		response.Failed++
+	}
+	return response
+}
+
+// newBatchFailuresResponse 根据逐条失败结果生成响应，未出现在failures中的条目视为成功
+func newBatchFailuresResponse(requested []int64, failures map[int64]BatchItemResult) *BatchResponse {
+	response := &BatchResponse{Results: make([]BatchItemResult, 0, len(requested))}
+	for _, id := range requested {
+		if failure, ok := failures[id]; ok {
+			failure.ID = id
+			response.Results = append(response.Results, failure)
+			response.Failed++
+			continue
+		}
+		response.Results = append(response.Results, BatchItemResult{ID: id, Success: true})
+		response.Succeeded++
+	}
+	return response
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+func TestSQLHandler_BatchDeleteHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queryRepo := new(MockQueryHistoryRepository)
+	h := NewSQLHandler(queryRepo, nil, nil, zap.NewNop())
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	router.POST("/history/batch-delete", h.BatchDeleteHistory)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/history/batch-delete", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("部分失败逐条报告", func(t *testing.T) {
+		// 重复ID只处理一次，其他用户的记录不会被删除
+		queryRepo.On("BatchDelete", mock.Anything, int64(7), []int64{1, 2, 3}).Return([]int64{1, 3}, nil).Once()
+
+		w := post(`{"ids":[1,2,3,1]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response BatchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.Succeeded)
+		assert.Equal(t, 1, response.Failed)
+		require.Len(t, response.Results, 3)
+		assert.True(t, response.Results[0].Success)
+		assert.Equal(t, BatchItemResult{ID: 2, Code: "NOT_FOUND", Message: "查询记录不存在或无权操作"}, response.Results[1])
+		assert.True(t, response.Results[2].Success)
+	})
+
+	t.Run("参数校验", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post(`{"ids":[]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, post(`{"ids":[0]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, post(`{"ids":[`+strings.Repeat("1,", 100)+`1]}`).Code)
+	})
+
+	t.Run("事务失败整体报错", func(t *testing.T) {
+		queryRepo.On("BatchDelete", mock.Anything, int64(7), []int64{4}).Return(nil, errors.New("db down")).Once()
+
+		w := post(`{"ids":[4]}`)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "BATCH_DELETE_FAILED")
+	})
+
+	queryRepo.AssertExpectations(t)
+}
+
+func TestSQLHandler_BatchRetagHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queryRepo := new(MockQueryHistoryRepository)
+	h := NewSQLHandler(queryRepo, nil, nil, zap.NewNop())
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	router.POST("/history/batch-retag", h.BatchRetagHistory)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/history/batch-retag", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("部分失败逐条报告", func(t *testing.T) {
+		tags := &repository.ChargebackTags{CostCenter: "财务部", Project: "预算"}
+		queryRepo.On("BatchRetag", mock.Anything, int64(7), []int64{1, 2}, tags).Return([]int64{2}, nil).Once()
+
+		w := post(`{"ids":[1,2,2],"cost_center":" 财务部 ","project":"预算"}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response BatchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Succeeded)
+		assert.Equal(t, 1, response.Failed)
+		assert.Equal(t, BatchItemResult{ID: 1, Code: "NOT_FOUND", Message: "查询记录不存在或无权操作"}, response.Results[0])
+		assert.True(t, response.Results[1].Success)
+	})
+
+	t.Run("事务失败整体报错", func(t *testing.T) {
+		queryRepo.On("BatchRetag", mock.Anything, int64(7), []int64{4}, mock.Anything).Return(nil, errors.New("db down")).Once()
+
+		w := post(`{"ids":[4]}`)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "BATCH_RETAG_FAILED")
+	})
+
+	queryRepo.AssertExpectations(t)
+}
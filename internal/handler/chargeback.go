@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// resolveChargeback 读取请求携带的费用归属标签并按工作空间取值范围校验，未配置工作空间设置时不记录标签
+// WebSocket握手无法自定义请求头，此时可改用cost_center/project查询参数
+func resolveChargeback(c *gin.Context, settings *service.WorkspaceSettingsService, userID int64) (*repository.ChargebackTags, error) {
+	if settings == nil {
+		return nil, nil
+	}
+
+	tags := repository.ChargebackTags{
+		CostCenter: c.GetHeader(service.CostCenterHeader),
+		Project:    c.GetHeader(service.ProjectHeader),
+	}
+	if tags.CostCenter == "" {
+		tags.CostCenter = c.Query("cost_center")
+	}
+	if tags.Project == "" {
+		tags.Project = c.Query("project")
+	}
+	return settings.ResolveChargeback(c.Request.Context(), userID, tags)
+}
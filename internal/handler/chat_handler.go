@@ -0,0 +1,518 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// ChatHandler 多轮对话处理器
+// 通过WebSocket进行“提问→结果→追问”的多轮对话，之前的问题、生成的SQL与所选连接保存在服务端会话中
+type ChatHandler struct {
+	ai          *AIHandler
+	sessions    *service.ChatSessionStore
+	maintenance *service.MaintenanceMode // 维护期间已建立的连接也拒绝提问，为nil时不检查
+	logger      *zap.Logger
+}
+
+// NewChatHandler 创建多轮对话处理器实例，生成、自动执行与结果处理沿用AIHandler的配置
+func NewChatHandler(ai *AIHandler, sessions *service.ChatSessionStore, logger *zap.Logger) *ChatHandler {
+	return &ChatHandler{
+		ai:       ai,
+		sessions: sessions,
+		logger:   logger,
+	}
+}
+
+// SetMaintenanceMode 设置维护模式开关，握手之后开启维护时已建立的连接同样拒绝提问
+func (h *ChatHandler) SetMaintenanceMode(maintenance *service.MaintenanceMode) {
+	h.maintenance = maintenance
+}
+
+// Routes 声明多轮对话路由
+func (h *ChatHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/chat",
+			Tag:    "chat",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/ws", Handler: h.Connect, Summary: "建立多轮对话WebSocket连接", BlockedInMaintenance: true},
+				{Method: http.MethodPost, Path: "/sessions", Handler: h.CreateSession, Summary: "创建对话会话"},
+				{Method: http.MethodGet, Path: "/sessions/:id", Handler: h.GetSession, Summary: "获取对话会话上下文"},
+				{Method: http.MethodPost, Path: "/sessions/:id/reset", Handler: h.ResetSession, Summary: "清空对话会话上下文"},
+				{Method: http.MethodDelete, Path: "/sessions/:id", Handler: h.DeleteSession, Summary: "结束对话会话"},
+			},
+		},
+	}
+}
+
+// 客户端消息类型
+const (
+	ChatMessageAsk   = "ask"   // 提问或追问
+	ChatMessageReset = "reset" // 清空对话上下文
+)
+
+// 服务端消息类型
+const (
+	ChatMessageSession = "session" // 会话已建立或上下文已变更
+	ChatMessageAnswer  = "answer"  // 一轮问答的结果
+	ChatMessageError   = "error"   // 本轮处理失败，会话仍可继续使用
+	ChatMessagePing    = "ping"    // 空闲心跳
+)
+
+// ChatClientMessage 客户端发送的消息
+// connection_id与会话当前连接不同时切换连接并清空之前的问答；首轮未指定时使用工作空间默认连接
+type ChatClientMessage struct {
+	Type         string `json:"type" example:"ask"`
+	Question     string `json:"question,omitempty" example:"上个季度各地区的销售额"`
+	ConnectionID int64  `json:"connection_id,omitempty" example:"1"`
+	Locale       string `json:"locale,omitempty" example:"zh"`
+	RowLimit     int    `json:"row_limit,omitempty" example:"100"` // 自动执行时的最大返回行数
+}
+
+// ChatServerMessage 服务端推送的消息
+type ChatServerMessage struct {
+	Type         string            `json:"type" example:"answer"`
+	SessionID    string            `json:"session_id,omitempty"`
+	ConnectionID int64             `json:"connection_id,omitempty"`
+	Turns        int               `json:"turns"` // 会话中保留的问答轮数
+	Answer       *Chat2SQLResponse `json:"answer,omitempty"`
+	Error        *ErrorResponse    `json:"error,omitempty"`
+	Time         time.Time         `json:"time"`
+}
+
+// Connect 建立多轮对话WebSocket连接
+// @Summary 建立多轮对话WebSocket连接
+// @Description 升级为WebSocket连接，收发JSON文本帧。客户端发送{"type":"ask","question":"..."}提问或追问，{"type":"reset"}清空上下文；
+// @Description 服务端推送session/answer/error/ping消息。追问（如“只看上个月”）结合会话中之前的问题与SQL生成；
+// @Description 通过session_id查询参数恢复未过期的会话，浏览器无法设置握手请求头时可通过access_token查询参数携带访问令牌
+// @Tags 多轮对话
+// @Security BearerAuth
+// @Param session_id query string false "恢复的会话ID"
+// @Param access_token query string false "访问令牌（仅WebSocket握手）"
+// @Success 101 {object} ChatServerMessage "切换为WebSocket协议"
+// @Failure 400 {string} string "不是WebSocket握手请求"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "会话不存在或已过期"
+// @Router /api/v1/chat/ws [get]
+func (h *ChatHandler) Connect(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	role := c.GetString("user_role")
+
+	// 恢复会话时先校验，握手前还能返回HTTP错误
+	sessionID := c.Query("session_id")
+	if sessionID != "" {
+		if _, err := h.sessions.Get(sessionID, userID); err != nil {
+			c.JSON(http.StatusNotFound, NewErrorResponse("SESSION_NOT_FOUND", "对话会话不存在或已过期"))
+			return
+		}
+	}
+
+	// 费用归属标签在握手时校验，本连接上的所有问答都按该标签记录
+	chargeback, err := resolveChargeback(c, h.ai.workspaceSettings, userID)
+	if errors.Is(err, repository.ErrInvalidInput) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_CHARGEBACK_TAGS", Message: "费用归属标签无效", Details: err.Error()})
+		return
+	} else if err != nil {
+		h.logger.Error("Failed to resolve chargeback tags", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间设置失败"))
+		return
+	}
+	c.Request = c.Request.WithContext(service.WithChargebackTags(c.Request.Context(), chargeback))
+
+	// 身份已由JWT认证，令牌不依赖Cookie，不需要再按Origin限制跨站握手
+	server := websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   func(conn *websocket.Conn) { h.serve(conn, userID, role, sessionID) },
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serve 握手成功后逐条处理客户端消息，直到客户端断开
+// 同一连接上的问答按顺序处理，处理期间收到的消息排队等待
+func (h *ChatHandler) serve(conn *websocket.Conn, userID int64, role, sessionID string) {
+	defer conn.Close()
+	cfg := h.sessions.Config()
+
+	send := func(msg *ChatServerMessage) bool {
+		msg.Time = time.Now()
+		if err := conn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout)); err != nil {
+			return false
+		}
+		if err := websocket.JSON.Send(conn, msg); err != nil {
+			h.logger.Debug("Chat client write failed", zap.Error(err), zap.Int64("user_id", userID))
+			return false
+		}
+		return true
+	}
+
+	if sessionID == "" {
+		session, err := h.sessions.Create(userID, 0)
+		if err != nil {
+			h.logger.Error("Failed to create chat session", zap.Error(err), zap.Int64("user_id", userID))
+			send(h.errorMessage("", "SESSION_ERROR", "创建对话会话失败", ""))
+			return
+		}
+		sessionID = session.ID
+	}
+	if !send(h.sessionMessage(sessionID, userID)) {
+		return
+	}
+
+	// 读循环只负责接收，所有写入都在当前goroutine中完成；
+	// 写入失败退出时关闭done，避免读循环阻塞在投递消息上而泄漏
+	incoming := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(incoming)
+		for {
+			var frame string
+			if err := websocket.Message.Receive(conn, &frame); err != nil {
+				return
+			}
+			select {
+			case incoming <- frame:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ctx := conn.Request().Context()
+	ping := time.NewTicker(cfg.PingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case frame, ok := <-incoming:
+			if !ok {
+				return
+			}
+			if !send(h.handleMessage(ctx, frame, userID, role, sessionID)) {
+				return
+			}
+			ping.Reset(cfg.PingInterval)
+		case <-ping.C:
+			if !send(&ChatServerMessage{Type: ChatMessagePing}) {
+				return
+			}
+		}
+	}
+}
+
+// handleMessage 处理一条客户端消息，返回要推送的结果
+func (h *ChatHandler) handleMessage(ctx context.Context, frame string, userID int64, role, sessionID string) *ChatServerMessage {
+	var msg ChatClientMessage
+	if err := json.Unmarshal([]byte(frame), &msg); err != nil {
+		return h.errorMessage(sessionID, "INVALID_REQUEST", "请求参数格式错误", err.Error())
+	}
+
+	switch msg.Type {
+	case ChatMessageAsk:
+		return h.ask(ctx, &msg, userID, role, sessionID)
+	case ChatMessageReset:
+		if err := h.sessions.Reset(sessionID, userID); err != nil {
+			return h.errorMessage(sessionID, "SESSION_NOT_FOUND", "对话会话不存在或已过期", "")
+		}
+		return h.sessionMessage(sessionID, userID)
+	default:
+		return h.errorMessage(sessionID, "INVALID_REQUEST", "请求参数格式错误", "unknown message type: "+msg.Type)
+	}
+}
+
+// ask 结合会话上下文生成本轮SQL，按工作空间策略自动执行，成功后记入会话
+func (h *ChatHandler) ask(ctx context.Context, msg *ChatClientMessage, userID int64, role, sessionID string) *ChatServerMessage {
+	startTime := time.Now()
+	requestID := generateRequestID()
+
+	if msg.Question == "" || utf8.RuneCountInString(msg.Question) > 1000 {
+		return h.errorMessage(sessionID, "INVALID_REQUEST", "请求参数无效", "question must be 1-1000 characters")
+	}
+	if msg.Locale != "" && msg.Locale != "zh" && msg.Locale != "en" {
+		return h.errorMessage(sessionID, "INVALID_REQUEST", "请求参数无效", "locale must be zh or en")
+	}
+
+	if h.maintenance.Active() {
+		state := h.maintenance.State()
+		details := ""
+		if state.ETA != nil {
+			details = "预计恢复时间: " + state.ETA.Format(time.RFC3339)
+		}
+		return h.errorMessage(sessionID, "MAINTENANCE_MODE", state.Message, details)
+	}
+
+	session, err := h.sessions.Get(sessionID, userID)
+	if err != nil {
+		return h.errorMessage(sessionID, "SESSION_NOT_FOUND", "对话会话不存在或已过期", "")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.sessions.Config().TurnTimeout)
+	defer cancel()
+
+	req := Chat2SQLRequest{
+		Query:        msg.Question,
+		ConnectionID: msg.ConnectionID,
+		Locale:       msg.Locale,
+		RowLimit:     msg.RowLimit,
+	}
+	if req.ConnectionID == 0 {
+		req.ConnectionID = session.ConnectionID
+	}
+	if req.Locale == "" {
+		req.Locale = service.DetectLocale(req.Query)
+	}
+	if err := h.ai.resolveDefaults(ctx, &req, userID); err != nil {
+		if errors.Is(err, errNoConnection) {
+			return h.localizedError(sessionID, req.Locale, "INVALID_REQUEST", "请求参数无效", err.Error())
+		}
+		h.logger.Error("获取工作空间默认设置失败", zap.String("request_id", requestID), zap.Error(err))
+		return h.localizedError(sessionID, req.Locale, "WORKSPACE_ERROR", "获取工作空间默认设置失败", "")
+	}
+
+	// 切换连接后之前的问答基于另一个数据库，不再作为上下文
+	history := session.Turns
+	if req.ConnectionID != session.ConnectionID {
+		if err := h.sessions.SetConnection(sessionID, userID, req.ConnectionID); err != nil {
+			return h.errorMessage(sessionID, "SESSION_NOT_FOUND", "对话会话不存在或已过期", "")
+		}
+		history = nil
+	}
+	ctx = service.WithLocale(ctx, req.Locale)
+
+	aiRequest := &service.SQLGenerationRequest{
+		Query:        req.Query,
+		ConnectionID: req.ConnectionID,
+		UserID:       userID,
+		Locale:       req.Locale,
+		Workload:     service.WorkloadInteractive,
+		LatencySLO:   h.ai.latencySLO(ctx, userID, requestID),
+		History:      history,
+	}
+	policy, policyErr := h.ai.columnPolicy(ctx, userID, role, req.ConnectionID, requestID)
+	if policy != nil {
+		aiRequest.RestrictedColumns = policy.Restricted()
+	}
+	ctx = service.WithQueryRole(ctx, role)
+
+	response, err := h.ai.aiService.GenerateSQL(ctx, aiRequest)
+	if err != nil {
+		h.logger.Error("多轮对话生成SQL失败",
+			zap.String("request_id", requestID),
+			zap.String("session_id", sessionID),
+			zap.Error(err),
+		)
+		switch {
+		case errors.Is(err, repository.ErrPermissionDenied):
+			return h.localizedError(sessionID, req.Locale, "CONNECTION_FORBIDDEN", "无权访问该数据库连接", "")
+		case isTimeoutError(err):
+			return h.localizedError(sessionID, req.Locale, "REQUEST_TIMEOUT", "查询处理超时，请稍后重试", "")
+		case isRateLimitError(err):
+			return h.localizedError(sessionID, req.Locale, "RATE_LIMIT_EXCEEDED", "请求过于频繁，请稍后重试", "")
+		case errors.Is(err, service.ErrUnsafeGeneratedSQL):
+			return h.localizedError(sessionID, req.Locale, "UNSAFE_SQL", "生成的SQL未通过安全检查，请换一种方式描述查询", "")
+		}
+		return h.localizedError(sessionID, req.Locale, "AI_SERVICE_ERROR", "AI查询处理失败", "")
+	}
+
+	queryID := generateQueryID(userID, startTime)
+	answer := h.ai.newChat2SQLResponse(response, queryID, req.Locale)
+	if h.ai.schemaSnapshots != nil {
+		ctx = h.ai.recordSchemaSnapshot(ctx, answer, req.ConnectionID, aiRequest.Schema, requestID)
+	}
+	if h.ai.llmArchive != nil && response.Template == "" {
+		h.ai.archiveGeneration(ctx, response, queryID, userID, req.ConnectionID, requestID)
+	}
+	h.ai.recordGeneration(queryID, userID, req.Query, response)
+	if h.ai.autoExecutor != nil {
+		h.ai.applyAutoExecute(ctx, answer, userID, req, requestID)
+	}
+	applyColumnPolicy(answer.Result, answer.Lineage, policy, policyErr, req.Locale)
+	h.ai.labelColumns(ctx, answer, req.ConnectionID, req.Query)
+
+	if err := h.sessions.AppendTurn(sessionID, userID, req.Query, answer.SQL, service.SummarizeResult(answer.Result)); err != nil {
+		// 生成期间会话被删除（如用户数据擦除），本轮结果仍返回
+		h.logger.Warn("记录对话轮次失败", zap.String("session_id", sessionID), zap.Error(err))
+	}
+
+	h.logger.Info("多轮对话请求完成",
+		zap.String("request_id", requestID),
+		zap.String("session_id", sessionID),
+		zap.String("query_id", queryID),
+		zap.Int("history_turns", len(history)),
+		zap.Duration("total_duration", time.Since(startTime)),
+	)
+
+	reply := h.sessionMessage(sessionID, userID)
+	reply.Type = ChatMessageAnswer
+	reply.ConnectionID = req.ConnectionID
+	reply.Answer = answer
+	return reply
+}
+
+// sessionMessage 构建会话状态消息
+func (h *ChatHandler) sessionMessage(sessionID string, userID int64) *ChatServerMessage {
+	msg := &ChatServerMessage{Type: ChatMessageSession, SessionID: sessionID}
+	if session, err := h.sessions.Get(sessionID, userID); err == nil {
+		msg.ConnectionID = session.ConnectionID
+		msg.Turns = len(session.Turns)
+	}
+	return msg
+}
+
+// errorMessage 构建错误消息
+func (h *ChatHandler) errorMessage(sessionID, code, message, details string) *ChatServerMessage {
+	return &ChatServerMessage{
+		Type:      ChatMessageError,
+		SessionID: sessionID,
+		Error: &ErrorResponse{
+			Code:      code,
+			Message:   message,
+			Details:   details,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+// localizedError 构建按用户语言本地化的错误消息
+func (h *ChatHandler) localizedError(sessionID, locale, code, message, details string) *ChatServerMessage {
+	return h.errorMessage(sessionID, code, service.Localize(locale, message), details)
+}
+
+// CreateSessionRequest 创建对话会话请求
+type CreateSessionRequest struct {
+	ConnectionID int64 `json:"connection_id,omitempty" binding:"omitempty,min=1" example:"1"` // 会话使用的连接，未指定时首轮提问使用工作空间默认连接
+}
+
+// CreateSession 创建对话会话
+// @Summary 创建对话会话
+// @Description 预先创建会话，之后通过 /chat/ws?session_id= 建立WebSocket连接提问；用户会话数达到上限时淘汰最久未活动的会话
+// @Tags 多轮对话
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateSessionRequest false "会话设置"
+// @Success 201 {object} service.ChatSession "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Router /api/v1/chat/sessions [post]
+func (h *ChatHandler) CreateSession(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req CreateSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Code: "INVALID_REQUEST", Message: "请求参数格式错误", Details: err.Error()})
+			return
+		}
+	}
+
+	session, err := h.sessions.Create(userID, req.ConnectionID)
+	if err != nil {
+		h.logger.Error("Failed to create chat session", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("SESSION_ERROR", "创建对话会话失败"))
+		return
+	}
+	c.JSON(http.StatusCreated, session)
+}
+
+// ResetSession 清空对话会话上下文
+// @Summary 清空对话会话上下文
+// @Description 清空会话保留的问答，保留所选连接，之后的提问不再参考之前的问题与SQL；与WebSocket中的reset消息相同
+// @Tags 多轮对话
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} service.ChatSession "清空成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "会话不存在或已过期"
+// @Router /api/v1/chat/sessions/{id}/reset [post]
+func (h *ChatHandler) ResetSession(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	sessionID := c.Param("id")
+	if err := h.sessions.Reset(sessionID, userID); err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("SESSION_NOT_FOUND", "对话会话不存在或已过期"))
+		return
+	}
+	session, err := h.sessions.Get(sessionID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("SESSION_NOT_FOUND", "对话会话不存在或已过期"))
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// GetSession 获取对话会话上下文
+// @Summary 获取对话会话上下文
+// @Description 返回会话当前使用的连接与保留的问答（问题、生成的SQL与执行结果摘要），供客户端重连后恢复界面
+// @Tags 多轮对话
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 200 {object} service.ChatSession "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "会话不存在或已过期"
+// @Router /api/v1/chat/sessions/{id} [get]
+func (h *ChatHandler) GetSession(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	session, err := h.sessions.Get(c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("SESSION_NOT_FOUND", "对话会话不存在或已过期"))
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// DeleteSession 结束对话会话
+// @Summary 结束对话会话
+// @Description 删除会话及其保留的问答，已建立的WebSocket连接上的后续提问将返回错误
+// @Tags 多轮对话
+// @Security BearerAuth
+// @Param id path string true "会话ID"
+// @Success 204 "删除成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "会话不存在或已过期"
+// @Router /api/v1/chat/sessions/{id} [delete]
+func (h *ChatHandler) DeleteSession(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	if err := h.sessions.Delete(c.Param("id"), userID); err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("SESSION_NOT_FOUND", "对话会话不存在或已过期"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
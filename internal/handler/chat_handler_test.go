@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/net/websocket"
+
+	"chat2sql-go/internal/service"
+)
+
+func TestChatHandler_FollowUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	aiService := &MockAIService{}
+	aiService.On("GenerateSQL", mock.Anything, mock.MatchedBy(func(req *service.SQLGenerationRequest) bool {
+		return req.Query == "各地区销售额" && len(req.History) == 0
+	})).Return(&service.SQLGenerationResponse{SQL: "SELECT region, SUM(amount) FROM orders GROUP BY region", Confidence: 0.9}, nil).Once()
+	aiService.On("GenerateSQL", mock.Anything, mock.MatchedBy(func(req *service.SQLGenerationRequest) bool {
+		return req.Query == "只看上个月" && len(req.History) == 1 && req.History[0].Question == "各地区销售额" && req.ConnectionID == 3
+	})).Return(&service.SQLGenerationResponse{SQL: "SELECT region, SUM(amount) FROM orders WHERE created_at >= date_trunc('month', now()) - interval '1 month' GROUP BY region", Confidence: 0.9}, nil).Once()
+
+	sessions := service.NewChatSessionStore(nil, zaptest.NewLogger(t))
+	h := NewChatHandler(NewAIHandler(aiService, zaptest.NewLogger(t)), sessions, zaptest.NewLogger(t))
+	maintenance := service.NewMaintenanceMode(nil)
+	h.SetMaintenanceMode(maintenance)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	r.GET("/chat/ws", h.Connect)
+	r.GET("/chat/sessions/:id", h.GetSession)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/chat/ws"
+	conn, err := websocket.Dial(url, "", server.URL)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	var msg ChatServerMessage
+	require.NoError(t, websocket.JSON.Receive(conn, &msg))
+	assert.Equal(t, ChatMessageSession, msg.Type)
+	sessionID := msg.SessionID
+	require.NotEmpty(t, sessionID)
+
+	// 首轮未指定连接且没有工作空间默认连接
+	require.NoError(t, websocket.JSON.Send(conn, ChatClientMessage{Type: ChatMessageAsk, Question: "各地区销售额"}))
+	require.NoError(t, websocket.JSON.Receive(conn, &msg))
+	assert.Equal(t, ChatMessageError, msg.Type)
+	assert.Equal(t, "INVALID_REQUEST", msg.Error.Code)
+
+	require.NoError(t, websocket.JSON.Send(conn, ChatClientMessage{Type: ChatMessageAsk, Question: "各地区销售额", ConnectionID: 3}))
+	msg = ChatServerMessage{}
+	require.NoError(t, websocket.JSON.Receive(conn, &msg))
+	require.Equal(t, ChatMessageAnswer, msg.Type, msg.Error)
+	assert.Equal(t, int64(3), msg.ConnectionID)
+	assert.Equal(t, 1, msg.Turns)
+
+	// 追问沿用会话中的连接与之前的问答
+	require.NoError(t, websocket.JSON.Send(conn, ChatClientMessage{Type: ChatMessageAsk, Question: "只看上个月"}))
+	msg = ChatServerMessage{}
+	require.NoError(t, websocket.JSON.Receive(conn, &msg))
+	require.Equal(t, ChatMessageAnswer, msg.Type, msg.Error)
+	assert.Contains(t, msg.Answer.SQL, "created_at")
+	assert.Equal(t, 2, msg.Turns)
+	aiService.AssertExpectations(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chat/sessions/"+sessionID, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "只看上个月")
+
+	require.NoError(t, websocket.JSON.Send(conn, ChatClientMessage{Type: ChatMessageReset}))
+	msg = ChatServerMessage{}
+	require.NoError(t, websocket.JSON.Receive(conn, &msg))
+	assert.Equal(t, ChatMessageSession, msg.Type)
+	assert.Equal(t, 0, msg.Turns)
+	assert.Equal(t, int64(3), msg.ConnectionID)
+
+	// 握手之后开启维护模式，已建立的连接同样拒绝提问
+	_, err = maintenance.Enable(context.Background(), "元数据迁移中", nil, 1)
+	require.NoError(t, err)
+	require.NoError(t, websocket.JSON.Send(conn, ChatClientMessage{Type: ChatMessageAsk, Question: "各地区销售额"}))
+	msg = ChatServerMessage{}
+	require.NoError(t, websocket.JSON.Receive(conn, &msg))
+	require.Equal(t, ChatMessageError, msg.Type)
+	assert.Equal(t, "MAINTENANCE_MODE", msg.Error.Code)
+	assert.Equal(t, "元数据迁移中", msg.Error.Message)
+
+	// 恢复不存在的会话
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chat/ws?session_id=missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestChatHandler_SessionAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sessions := service.NewChatSessionStore(nil, zaptest.NewLogger(t))
+	h := NewChatHandler(NewAIHandler(&MockAIService{}, zaptest.NewLogger(t)), sessions, zaptest.NewLogger(t))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	r.POST("/chat/sessions", h.CreateSession)
+	r.GET("/chat/sessions/:id", h.GetSession)
+	r.POST("/chat/sessions/:id/reset", h.ResetSession)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat/sessions", strings.NewReader(`{"connection_id":3}`)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created service.ChatSession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, int64(3), created.ConnectionID)
+
+	// 不带请求体时首轮使用工作空间默认连接
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat/sessions", nil))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat/sessions", strings.NewReader(`{"connection_id":-1}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	result := &service.TurnResult{Columns: []string{"region", "total"}, RowCount: 4}
+	require.NoError(t, sessions.AppendTurn(created.ID, 7, "各地区销售额", "SELECT region, SUM(amount) AS total FROM orders GROUP BY region", result))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chat/sessions/"+created.ID, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"result":{"columns":["region","total"],"row_count":4}`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat/sessions/"+created.ID+"/reset", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var reset service.ChatSession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reset))
+	assert.Empty(t, reset.Turns)
+	assert.Equal(t, int64(3), reset.ConnectionID, "清空上下文保留所选连接")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat/sessions/missing/reset", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// ClassificationHandler 列数据分级处理器
+// 在数据字典中为列标注PII/财务/内部分级，查询结果与SQL生成按分级与角色自动脱敏或过滤
+type ClassificationHandler struct {
+	classifications *service.ClassificationService
+	logger          *zap.Logger
+}
+
+// NewClassificationHandler 创建列数据分级处理器实例
+func NewClassificationHandler(classifications *service.ClassificationService, logger *zap.Logger) *ClassificationHandler {
+	return &ClassificationHandler{
+		classifications: classifications,
+		logger:          logger,
+	}
+}
+
+// Routes 声明列数据分级路由，标注分级需要manager或admin角色
+func (h *ClassificationHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/connections",
+			Tag:    "classifications",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/:id/classifications", Handler: h.ListClassifications, Summary: "列分级与当前角色的处理方式"},
+				{Method: http.MethodPut, Path: "/:id/classifications", Handler: h.SetClassification, Summary: "设置或移除列分级", Roles: []string{string(repository.RoleManager)}},
+			},
+		},
+	}
+}
+
+// ColumnClassificationRequest 列分级设置请求，classification为空表示移除标签
+type ColumnClassificationRequest struct {
+	SchemaName     string `json:"schema_name" binding:"max=100" example:"public"`
+	TableName      string `json:"table_name" binding:"required,max=100" example:"customers"`
+	ColumnName     string `json:"column_name" binding:"required,max=100" example:"email"`
+	Classification string `json:"classification" binding:"omitempty,oneof=pii financial internal" example:"pii"`
+}
+
+// ColumnClassificationListResponse 列分级列表响应，rules为当前用户角色下各列的处理方式
+type ColumnClassificationListResponse struct {
+	ConnectionID    int64                              `json:"connection_id" example:"1"`
+	Classifications []*repository.ColumnClassification `json:"classifications"`
+	Rules           []*service.ColumnRule              `json:"rules"`
+}
+
+// ListClassifications 列出连接的列分级
+// @Summary 列出列数据分级
+// @Description 列出连接数据字典中标注的PII/财务/内部列，以及当前用户角色下各列的默认处理方式（allow/mask/drop）
+// @Tags 数据分级
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "连接ID"
+// @Success 200 {object} ColumnClassificationListResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权访问该连接"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/connections/{id}/classifications [get]
+func (h *ClassificationHandler) ListClassifications(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	connectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_CONNECTION_ID", "连接ID格式错误"))
+		return
+	}
+
+	classifications, err := h.classifications.List(c.Request.Context(), userID, connectionID)
+	if err != nil {
+		h.respondClassificationError(c, err)
+		return
+	}
+	if classifications == nil {
+		classifications = []*repository.ColumnClassification{}
+	}
+
+	role, _ := c.Get("user_role")
+	roleStr, _ := role.(string)
+	rules := service.NewColumnPolicy(classifications, roleStr).Rules()
+	if rules == nil {
+		rules = []*service.ColumnRule{}
+	}
+
+	c.JSON(http.StatusOK, &ColumnClassificationListResponse{
+		ConnectionID:    connectionID,
+		Classifications: classifications,
+		Rules:           rules,
+	})
+}
+
+// SetClassification 设置列分级
+// @Summary 设置列数据分级
+// @Description 将列标注为pii/financial/internal，classification为空时移除标签（需manager或admin角色且为连接所有者）
+// @Tags 数据分级
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "连接ID"
+// @Param request body ColumnClassificationRequest true "列分级"
+// @Success 200 {object} repository.ColumnClassification "设置成功"
+// @Success 204 "标签已移除"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "标签不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/connections/{id}/classifications [put]
+func (h *ClassificationHandler) SetClassification(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	connectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_CONNECTION_ID", "连接ID格式错误"))
+		return
+	}
+
+	var req ColumnClassificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+	if req.SchemaName == "" {
+		req.SchemaName = "public"
+	}
+
+	classification := &repository.ColumnClassification{
+		ConnectionID:   connectionID,
+		SchemaName:     req.SchemaName,
+		TableName:      req.TableName,
+		ColumnName:     req.ColumnName,
+		Classification: req.Classification,
+	}
+	if err := h.classifications.Tag(c.Request.Context(), userID, classification); err != nil {
+		h.respondClassificationError(c, err)
+		return
+	}
+
+	if req.Classification == "" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, classification)
+}
+
+// respondClassificationError 将数据分级服务错误映射为HTTP响应
+func (h *ClassificationHandler) respondClassificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, NewErrorResponse("PERMISSION_DENIED", "无权访问该数据库连接"))
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_CLASSIFICATION",
+			Message: "数据分级无效",
+			Details: err.Error(),
+		})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("CLASSIFICATION_NOT_FOUND", "列分级不存在"))
+	default:
+		h.logger.Error("Classification operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("CLASSIFICATION_ERROR", "数据分级处理失败"))
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// stubClassificationRepository 内存列分级Repository
+type stubClassificationRepository struct {
+	items []*repository.ColumnClassification
+}
+
+func (s *stubClassificationRepository) Upsert(ctx context.Context, c *repository.ColumnClassification) error {
+	s.items = append(s.items, c)
+	return nil
+}
+
+func (s *stubClassificationRepository) Delete(ctx context.Context, connectionID int64, schemaName, tableName, columnName string, deleteBy int64) error {
+	return repository.ErrNotFound
+}
+
+func (s *stubClassificationRepository) ListByConnection(ctx context.Context, connectionID int64) ([]*repository.ColumnClassification, error) {
+	return s.items, nil
+}
+
+func newClassificationTestRouter(t *testing.T, userID int64, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	connection := testutil.NewConnection(3, 7)
+	connRepo := &MockConnectionRepository{}
+	connRepo.On("GetByID", mock.Anything, int64(3)).Return(connection, nil)
+
+	svc := service.NewClassificationService(&stubClassificationRepository{}, connRepo, zaptest.NewLogger(t))
+	h := NewClassificationHandler(svc, zaptest.NewLogger(t))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("user_role", role)
+	})
+	r.GET("/connections/:id/classifications", h.ListClassifications)
+	r.PUT("/connections/:id/classifications", h.SetClassification)
+	return r
+}
+
+func TestClassificationHandler_SetAndList(t *testing.T) {
+	r := newClassificationTestRouter(t, 7, "user")
+
+	body := `{"table_name":"customers","column_name":"email","classification":"pii"}`
+	req := httptest.NewRequest(http.MethodPut, "/connections/3/classifications", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/connections/3/classifications", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp ColumnClassificationListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Rules, 1)
+	assert.Equal(t, "public", resp.Rules[0].SchemaName)
+	assert.Equal(t, service.ColumnMask, resp.Rules[0].Action)
+}
+
+func TestClassificationHandler_SetClassification_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		userID int64
+		body   string
+		status int
+	}{
+		{"非连接所有者", 8, `{"table_name":"customers","column_name":"email","classification":"pii"}`, http.StatusForbidden},
+		{"未知分级", 7, `{"table_name":"customers","column_name":"email","classification":"secret"}`, http.StatusBadRequest},
+		{"移除不存在的标签", 7, `{"table_name":"customers","column_name":"email"}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newClassificationTestRouter(t, tc.userID, "manager")
+			req := httptest.NewRequest(http.MethodPut, "/connections/3/classifications", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
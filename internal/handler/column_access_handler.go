@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// ColumnAccessHandler 列访问申请处理器
+// 查询结果中的受限列附带访问申请说明，用户据此提交申请，由连接所有者授予有期限的例外或驳回
+type ColumnAccessHandler struct {
+	access *service.ColumnAccessService
+	logger *zap.Logger
+}
+
+// NewColumnAccessHandler 创建列访问申请处理器实例
+func NewColumnAccessHandler(access *service.ColumnAccessService, logger *zap.Logger) *ColumnAccessHandler {
+	return &ColumnAccessHandler{
+		access: access,
+		logger: logger,
+	}
+}
+
+// Routes 声明列访问申请路由，授权、驳回与收回由服务校验连接所有权
+func (h *ColumnAccessHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/column-access",
+			Tag:    "column-access",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/requests", Handler: h.RequestAccess, Summary: "申请查看受限列"},
+				{Method: http.MethodGet, Path: "/requests", Handler: h.ListMyRequests, Summary: "我的列访问申请"},
+				{Method: http.MethodGet, Path: "/requests/pending", Handler: h.ListPendingRequests, Summary: "待我处理的列访问申请"},
+				{Method: http.MethodGet, Path: "/requests/:id", Handler: h.GetRequest, Summary: "列访问申请详情与审计事件"},
+				{Method: http.MethodPost, Path: "/requests/:id/grant", Handler: h.GrantRequest, Summary: "授予有期限的列访问例外"},
+				{Method: http.MethodPost, Path: "/requests/:id/reject", Handler: h.RejectRequest, Summary: "驳回列访问申请"},
+				{Method: http.MethodPost, Path: "/requests/:id/revoke", Handler: h.RevokeGrant, Summary: "提前收回列访问授权"},
+			},
+		},
+	}
+}
+
+// ColumnAccessRequestBody 列访问申请请求，列可从查询结果防护措施的access_request中获取
+type ColumnAccessRequestBody struct {
+	ConnectionID int64  `json:"connection_id" binding:"required,min=1" example:"1"`
+	SchemaName   string `json:"schema_name" binding:"max=100" example:"public"`
+	TableName    string `json:"table_name" binding:"required,max=100" example:"orders"`
+	ColumnName   string `json:"column_name" binding:"required,max=100" example:"amount"`
+	Reason       string `json:"reason" binding:"required,max=500" example:"季度对账需要查看订单金额"`
+}
+
+// ColumnAccessGrantRequest 授权请求，duration_hours为空时使用默认有效期（7天）
+type ColumnAccessGrantRequest struct {
+	DurationHours int    `json:"duration_hours" binding:"omitempty,min=1,max=2160" example:"72"`
+	Comment       string `json:"comment" binding:"max=500" example:"仅限本季度对账"`
+}
+
+// ColumnAccessDecisionRequest 驳回或收回请求
+type ColumnAccessDecisionRequest struct {
+	Comment string `json:"comment" binding:"max=500" example:"请改用汇总报表"`
+}
+
+// ColumnAccessListParams 列访问申请列表参数
+type ColumnAccessListParams struct {
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100" example:"20"`
+	Cursor string `form:"cursor" binding:"omitempty,max=512"`
+}
+
+// ColumnAccessListResponse 列访问申请列表
+type ColumnAccessListResponse struct {
+	Requests []*repository.ColumnAccessRequest `json:"requests"`
+	Limit    int                               `json:"limit" example:"20"`
+	CursorPage
+}
+
+// ColumnAccessDetailResponse 列访问申请详情与审计事件
+type ColumnAccessDetailResponse struct {
+	Request *repository.ColumnAccessRequest `json:"request"`
+	Events  []*repository.ColumnAccessEvent `json:"events"`
+}
+
+// RequestAccess 申请查看受限列
+// @Summary 申请查看受限列
+// @Description 申请查看当前角色下被脱敏或过滤的列，由连接所有者处理；同一列只能有一个待处理的申请
+// @Tags 列访问申请
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ColumnAccessRequestBody true "连接、列与申请理由"
+// @Success 201 {object} repository.ColumnAccessRequest "申请已提交"
+// @Failure 400 {object} ErrorResponse "请求参数错误、列未受限"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "连接不存在"
+// @Failure 409 {object} ErrorResponse "已有待处理的申请"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/column-access/requests [post]
+func (h *ColumnAccessHandler) RequestAccess(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var body ColumnAccessRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	role, _ := middleware.GetUserRoleFromContext(c)
+	req := &repository.ColumnAccessRequest{
+		ConnectionID: body.ConnectionID,
+		SchemaName:   body.SchemaName,
+		TableName:    body.TableName,
+		ColumnName:   body.ColumnName,
+		Reason:       body.Reason,
+	}
+	if err := h.access.Request(c.Request.Context(), userID, role, req); err != nil {
+		h.respondColumnAccessError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, req)
+}
+
+// ListMyRequests 我的列访问申请
+// @Summary 我的列访问申请
+// @Description 列出当前用户提交的列访问申请，按提交时间倒序
+// @Tags 列访问申请
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "每页数量" default(20)
+// @Param cursor query string false "上一页返回的next_cursor"
+// @Success 200 {object} ColumnAccessListResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/column-access/requests [get]
+func (h *ColumnAccessHandler) ListMyRequests(c *gin.Context) {
+	h.list(c, "column_access_mine", h.access.ListMine)
+}
+
+// ListPendingRequests 待我处理的列访问申请
+// @Summary 待我处理的列访问申请
+// @Description 列出当前用户作为连接所有者待处理的列访问申请，先提交的在前
+// @Tags 列访问申请
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "每页数量" default(20)
+// @Param cursor query string false "上一页返回的next_cursor"
+// @Success 200 {object} ColumnAccessListResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/column-access/requests/pending [get]
+func (h *ColumnAccessHandler) ListPendingRequests(c *gin.Context) {
+	h.list(c, "column_access_pending", h.access.ListPending)
+}
+
+// GetRequest 列访问申请详情
+// @Summary 列访问申请详情
+// @Description 获取列访问申请及其审计事件，只有申请人与连接所有者可以查看
+// @Tags 列访问申请
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} ColumnAccessDetailResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "申请不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/column-access/requests/{id} [get]
+func (h *ColumnAccessHandler) GetRequest(c *gin.Context) {
+	userID, id, ok := h.requestTarget(c)
+	if !ok {
+		return
+	}
+
+	req, events, err := h.access.Get(c.Request.Context(), userID, id)
+	if err != nil {
+		h.respondColumnAccessError(c, err)
+		return
+	}
+	if events == nil {
+		events = []*repository.ColumnAccessEvent{}
+	}
+	c.JSON(http.StatusOK, &ColumnAccessDetailResponse{Request: req, Events: events})
+}
+
+// GrantRequest 授予列访问例外
+// @Summary 授予列访问例外
+// @Description 连接所有者授予申请人有期限的列访问例外，到期前该列对申请人原样返回；有效期1小时到90天，默认7天
+// @Tags 列访问申请
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "申请ID"
+// @Param request body ColumnAccessGrantRequest false "有效期与处理意见"
+// @Success 200 {object} repository.ColumnAccessRequest "已授权"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "不是连接所有者"
+// @Failure 404 {object} ErrorResponse "申请不存在或已处理"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/column-access/requests/{id}/grant [post]
+func (h *ColumnAccessHandler) GrantRequest(c *gin.Context) {
+	userID, id, ok := h.requestTarget(c)
+	if !ok {
+		return
+	}
+
+	var body ColumnAccessGrantRequest
+	if !h.bindBody(c, &body) {
+		return
+	}
+
+	req, err := h.access.Grant(c.Request.Context(), userID, id, time.Duration(body.DurationHours)*time.Hour, body.Comment)
+	if err != nil {
+		h.respondColumnAccessError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// RejectRequest 驳回列访问申请
+// @Summary 驳回列访问申请
+// @Description 连接所有者驳回待处理的列访问申请
+// @Tags 列访问申请
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "申请ID"
+// @Param request body ColumnAccessDecisionRequest false "处理意见"
+// @Success 200 {object} repository.ColumnAccessRequest "已驳回"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "不是连接所有者"
+// @Failure 404 {object} ErrorResponse "申请不存在或已处理"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/column-access/requests/{id}/reject [post]
+func (h *ColumnAccessHandler) RejectRequest(c *gin.Context) {
+	userID, id, ok := h.requestTarget(c)
+	if !ok {
+		return
+	}
+
+	var body ColumnAccessDecisionRequest
+	if !h.bindBody(c, &body) {
+		return
+	}
+
+	req, err := h.access.Reject(c.Request.Context(), userID, id, body.Comment)
+	if err != nil {
+		h.respondColumnAccessError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// RevokeGrant 收回列访问授权
+// @Summary 收回列访问授权
+// @Description 连接所有者在到期前收回生效中的列访问授权，立即恢复默认策略
+// @Tags 列访问申请
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "申请ID"
+// @Param request body ColumnAccessDecisionRequest false "处理意见"
+// @Success 200 {object} repository.ColumnAccessRequest "已收回"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "不是连接所有者"
+// @Failure 404 {object} ErrorResponse "授权不存在或已失效"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/column-access/requests/{id}/revoke [post]
+func (h *ColumnAccessHandler) RevokeGrant(c *gin.Context) {
+	userID, id, ok := h.requestTarget(c)
+	if !ok {
+		return
+	}
+
+	var body ColumnAccessDecisionRequest
+	if !h.bindBody(c, &body) {
+		return
+	}
+
+	req, err := h.access.Revoke(c.Request.Context(), userID, id, body.Comment)
+	if err != nil {
+		h.respondColumnAccessError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// list 分页列出申请
+func (h *ColumnAccessHandler) list(c *gin.Context, name string, fetch func(ctx context.Context, userID int64, limit, offset int) ([]*repository.ColumnAccessRequest, error)) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var params ColumnAccessListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_PARAMS",
+			Message: "查询参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	scope := listScope(name, userID)
+	offset, err := resolveOffset(params.Cursor, 0, scope)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
+	}
+
+	requests, err := fetch(c.Request.Context(), userID, params.Limit+1, offset)
+	if err != nil {
+		h.respondColumnAccessError(c, err)
+		return
+	}
+	if requests == nil {
+		requests = []*repository.ColumnAccessRequest{}
+	}
+	requests, page := paginate(requests, offset, params.Limit, scope)
+
+	c.JSON(http.StatusOK, &ColumnAccessListResponse{Requests: requests, Limit: params.Limit, CursorPage: page})
+}
+
+// bindBody 解析可选的请求体，失败时写入错误响应
+func (h *ColumnAccessHandler) bindBody(c *gin.Context, body any) bool {
+	if c.Request.ContentLength <= 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// requestTarget 读取当前用户与路径中的申请ID，失败时写入错误响应
+func (h *ColumnAccessHandler) requestTarget(c *gin.Context) (int64, int64, bool) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return 0, 0, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST_ID", "申请ID格式错误"))
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+// respondColumnAccessError 将列访问申请服务错误映射为HTTP响应
+func (h *ColumnAccessHandler) respondColumnAccessError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_COLUMN_ACCESS_REQUEST",
+			Message: "列访问申请无效",
+			Details: err.Error(),
+		})
+	case errors.Is(err, service.ErrSelfApproval):
+		c.JSON(http.StatusBadRequest, NewErrorResponse("SELF_APPROVAL", err.Error()))
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, NewErrorResponse("PERMISSION_DENIED", "只有连接所有者可以处理列访问申请"))
+	case errors.Is(err, repository.ErrDuplicateEntry):
+		c.JSON(http.StatusConflict, NewErrorResponse("COLUMN_ACCESS_PENDING", "该列已有待处理的访问申请"))
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("COLUMN_ACCESS_NOT_FOUND", "列访问申请不存在或已处理"))
+	default:
+		h.logger.Error("Column access operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("COLUMN_ACCESS_ERROR", "列访问申请处理失败"))
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// stubColumnAccessRepository 内存列访问申请Repository
+type stubColumnAccessRepository struct {
+	repository.ColumnAccessRepository
+	requests []*repository.ColumnAccessRequest
+	events   []*repository.ColumnAccessEvent
+}
+
+func (s *stubColumnAccessRepository) Create(ctx context.Context, req *repository.ColumnAccessRequest) error {
+	for _, r := range s.requests {
+		if r.Status == string(repository.ColumnAccessPending) && r.RequestedBy == req.RequestedBy && r.ColumnName == req.ColumnName {
+			return repository.ErrDuplicateEntry
+		}
+	}
+	req.ID = int64(len(s.requests) + 1)
+	s.requests = append(s.requests, req)
+	return nil
+}
+
+func (s *stubColumnAccessRepository) GetByID(ctx context.Context, id int64) (*repository.ColumnAccessRequest, error) {
+	if id < 1 || int(id) > len(s.requests) {
+		return nil, repository.ErrNotFound
+	}
+	return s.requests[id-1], nil
+}
+
+func (s *stubColumnAccessRepository) Decide(ctx context.Context, id, deciderID int64, status repository.ColumnAccessStatus, comment *string, expiresAt *time.Time) error {
+	req := s.requests[id-1]
+	req.Status = string(status)
+	req.DecidedBy = &deciderID
+	req.ExpiresAt = expiresAt
+	return nil
+}
+
+func (s *stubColumnAccessRepository) AddEvent(ctx context.Context, event *repository.ColumnAccessEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *stubColumnAccessRepository) ListEvents(ctx context.Context, requestID int64) ([]*repository.ColumnAccessEvent, error) {
+	return s.events, nil
+}
+
+func TestColumnAccessHandler_RequestAndGrant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	connection := testutil.NewConnection(3, 7)
+	connRepo := &MockConnectionRepository{}
+	connRepo.On("GetByID", mock.Anything, int64(3)).Return(connection, nil)
+	classifications := &stubClassificationRepository{items: []*repository.ColumnClassification{
+		{ConnectionID: 3, SchemaName: "public", TableName: "orders", ColumnName: "amount", Classification: "financial"},
+	}}
+	access := service.NewColumnAccessService(&stubColumnAccessRepository{}, classifications, connRepo, zaptest.NewLogger(t))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var userID int64
+		_ = json.Unmarshal([]byte(c.GetHeader("X-Test-User")), &userID)
+		c.Set("user_id", userID)
+		c.Set("user_role", "user")
+	})
+	for _, group := range NewColumnAccessHandler(access, zaptest.NewLogger(t)).Routes() {
+		for _, route := range group.Routes {
+			router.Handle(route.Method, group.Prefix+route.Path, route.Handler)
+		}
+	}
+
+	serve := func(method, path, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"connection_id":3,"table_name":"orders","column_name":"amount","reason":"季度对账"}`
+	w := serve(http.MethodPost, "/column-access/requests", "8", body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, "/column-access/requests", "8", body).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/column-access/requests", "8", `{"connection_id":3,"table_name":"orders","column_name":"id","reason":"x"}`).Code, "未标注分级的列")
+
+	// 只有连接所有者可以授权
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/column-access/requests/1/grant", "9", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/column-access/requests/1/grant", "7", `{"duration_hours":5000}`).Code)
+	w = serve(http.MethodPost, "/column-access/requests/1/grant", "7", `{"duration_hours":48,"comment":"仅限本季度"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var granted repository.ColumnAccessRequest
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &granted))
+	assert.Equal(t, string(repository.ColumnAccessGranted), granted.Status)
+	require.NotNil(t, granted.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), *granted.ExpiresAt, time.Minute)
+
+	w = serve(http.MethodGet, "/column-access/requests/1", "8", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var detail ColumnAccessDetailResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	require.Len(t, detail.Events, 2)
+	assert.Equal(t, "granted", detail.Events[1].Action)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/column-access/requests/1", "9", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/column-access/requests/abc", "8", "").Code)
+}
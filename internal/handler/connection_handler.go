@@ -12,6 +12,7 @@ import (
 
 	"chat2sql-go/internal/middleware"
 	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
 )
 
 // ConnectionManagerInterface 连接管理器接口
@@ -35,6 +36,7 @@ type ConnectionHandler struct {
 	connectionRepo    repository.ConnectionRepository
 	schemaRepo        repository.SchemaRepository
 	connectionManager ConnectionManagerInterface
+	schemaWarmup      *service.SchemaWarmupService
 	logger            *zap.Logger
 }
 
@@ -53,14 +55,40 @@ func NewConnectionHandler(
 	}
 }
 
+// SetSchemaWarmup 设置元数据预热服务，设置后新建连接会立即在后台预热
+func (h *ConnectionHandler) SetSchemaWarmup(warmup *service.SchemaWarmupService) {
+	h.schemaWarmup = warmup
+}
+
+// Routes 声明数据库连接管理路由
+func (h *ConnectionHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/connections",
+			Tag:    "connections",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/", Handler: h.CreateConnection, Summary: "创建连接", Permission: repository.PermConnectionManage},
+				{Method: http.MethodGet, Path: "/", Handler: h.ListConnections, Summary: "连接列表"},
+				{Method: http.MethodGet, Path: "/:id", Handler: h.GetConnection, Summary: "获取连接详情"},
+				{Method: http.MethodPut, Path: "/:id", Handler: h.UpdateConnection, Summary: "更新连接", Permission: repository.PermConnectionManage},
+				{Method: http.MethodDelete, Path: "/:id", Handler: h.DeleteConnection, Summary: "删除连接", Permission: repository.PermConnectionManage},
+				{Method: http.MethodPost, Path: "/:id/test", Handler: h.TestConnection, Summary: "测试连接", Permission: repository.PermConnectionManage},
+				{Method: http.MethodPut, Path: "/:id/result-limits", Handler: h.SetResultLimits, Summary: "设置连接的结果行数与大小上限", Permission: repository.PermConnectionManage},
+				{Method: http.MethodGet, Path: "/:id/schema", Handler: h.GetSchema, Summary: "获取数据库结构"},
+			},
+		},
+	}
+}
+
 // CreateConnectionRequest 创建连接请求结构
 type CreateConnectionRequest struct {
 	Name         string `json:"name" binding:"required,min=1,max=100" example:"生产数据库"`
-	Host         string `json:"host" binding:"required" example:"localhost"`
-	Port         int32  `json:"port" binding:"required,min=1,max=65535" example:"5432"`
-	DatabaseName string `json:"database_name" binding:"required,min=1,max=100" example:"production_db"`
-	Username     string `json:"username" binding:"required,min=1,max=100" example:"db_user"`
-	Password     string `json:"password" binding:"required,min=1,max=255" example:"secure_password"`
+	Host         string `json:"host" binding:"required_unless=DBType sqlite" example:"localhost"`
+	Port         int32  `json:"port" binding:"required_unless=DBType sqlite,omitempty,min=1,max=65535" example:"5432"`
+	DatabaseName string `json:"database_name" binding:"required,min=1,max=100" example:"production_db"` // SQLite连接为SQLITE_DATA_DIR下的文件路径
+	Username     string `json:"username" binding:"required_unless=DBType sqlite,omitempty,min=1,max=100" example:"db_user"`
+	Password     string `json:"password" binding:"required_unless=DBType sqlite,omitempty,min=1,max=255" example:"secure_password"`
 	DBType       string `json:"db_type" binding:"required,oneof=postgresql mysql sqlite oracle" example:"postgresql"`
 }
 
@@ -85,14 +113,25 @@ type ConnectionResponse struct {
 	DBType       string    `json:"db_type" example:"postgresql"`
 	Status       string    `json:"status" example:"active"`
 	LastTested   *time.Time `json:"last_tested,omitempty" example:"2024-01-08T12:00:00Z"`
+	MaxRows      *int32    `json:"max_rows,omitempty" example:"50000"`   // 结果行数上限，为空时使用全局配置
+	MaxResultMB  *int32    `json:"max_result_mb,omitempty" example:"50"` // 结果大小上限(MB)，为空时使用全局配置
 	CreateTime   time.Time `json:"create_time" example:"2024-01-08T10:00:00Z"`
 	UpdateTime   time.Time `json:"update_time" example:"2024-01-08T11:00:00Z"`
+	Warmup       *service.WarmupStatus `json:"warmup,omitempty"` // 元数据预热进度
 }
 
 // ConnectionListResponse 连接列表响应
 type ConnectionListResponse struct {
 	Connections []*ConnectionResponse `json:"connections"`
 	Total       int64                 `json:"total" example:"3"`
+	CursorPage
+}
+
+// ConnectionListParams 连接列表参数
+// 连接数量通常很少，默认每页100条，旧客户端不传分页参数时仍能一次取回全部连接
+type ConnectionListParams struct {
+	Limit  int    `form:"limit,default=100" binding:"min=1,max=100" example:"100"`
+	Cursor string `form:"cursor" binding:"omitempty,max=512"`
 }
 
 
@@ -183,6 +222,11 @@ func (h *ConnectionHandler) CreateConnection(c *gin.Context) {
 		return
 	}
 	
+	// SQLite连接没有服务器与账号，主机固定为localhost、端口为0
+	if req.DBType == string(repository.DBTypeSQLite) {
+		req.Host, req.Port, req.Username, req.Password = "localhost", 0, "", ""
+	}
+
 	// 创建连接配置（密码暂时以明文形式传递，由ConnectionManager加密）
 	connection := &repository.DatabaseConnection{
 		UserID:            userID,
@@ -216,6 +260,10 @@ func (h *ConnectionHandler) CreateConnection(c *gin.Context) {
 		zap.Int64("connection_id", connection.ID),
 		zap.String("name", connection.Name))
 	
+	if h.schemaWarmup != nil {
+		h.schemaWarmup.Warmup(connection.ID)
+	}
+	
 	response := h.toConnectionResponse(connection)
 	c.JSON(http.StatusCreated, response)
 }
@@ -227,7 +275,10 @@ func (h *ConnectionHandler) CreateConnection(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param limit query int false "每页数量" default(100) minimum(1) maximum(100)
+// @Param cursor query string false "上一页响应中的next_cursor"
 // @Success 200 {object} ConnectionListResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权访问"
 // @Failure 500 {object} ErrorResponse "服务器内部错误"
 // @Router /api/v1/connections [get]
@@ -241,6 +292,23 @@ func (h *ConnectionHandler) ListConnections(c *gin.Context) {
 		return
 	}
 	
+	var params ConnectionListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_PARAMS",
+			Message: "查询参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+	
+	scope := listScope("connections", userID)
+	offset, err := resolveOffset(params.Cursor, 0, scope)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
+	}
+	
 	// 获取用户的所有连接
 	connections, err := h.connectionRepo.ListByUser(c.Request.Context(), userID)
 	if err != nil {
@@ -255,6 +323,13 @@ func (h *ConnectionHandler) ListConnections(c *gin.Context) {
 		return
 	}
 	
+	// Repository一次返回全部连接，总数无需额外计数，在内存中按游标分页
+	total := int64(len(connections))
+	if offset > len(connections) {
+		offset = len(connections)
+	}
+	connections, page := paginate(connections[offset:], offset, params.Limit, scope)
+	
 	// 转换为响应格式
 	items := make([]*ConnectionResponse, len(connections))
 	for i, conn := range connections {
@@ -263,7 +338,8 @@ func (h *ConnectionHandler) ListConnections(c *gin.Context) {
 	
 	response := &ConnectionListResponse{
 		Connections: items,
-		Total:       int64(len(connections)),
+		Total:       total,
+		CursorPage:  page,
 	}
 	
 	c.JSON(http.StatusOK, response)
@@ -478,6 +554,10 @@ func (h *ConnectionHandler) DeleteConnection(c *gin.Context) {
 		return
 	}
 	
+	if h.schemaWarmup != nil {
+		h.schemaWarmup.Forget(connectionID)
+	}
+	
 	h.logger.Info("Connection deleted successfully",
 		zap.Int64("user_id", userID),
 		zap.Int64("connection_id", connectionID))
@@ -589,7 +669,9 @@ func (h *ConnectionHandler) TestConnection(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "连接ID"
+// @Param If-None-Match header string false "上次响应的ETag"
 // @Success 200 {object} DatabaseSchemaResponse "获取成功"
+// @Success 304 {string} string "数据库结构未变化"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权访问"
 // @Failure 404 {object} ErrorResponse "连接不存在"
@@ -637,12 +719,28 @@ func (h *ConnectionHandler) GetSchema(c *gin.Context) {
 		return
 	}
 	
+	// 元数据未变化时返回304，结构较大的库不必每次轮询都重新下载
+	records := make([]versionedRecord, len(schemas))
+	lastUpdated := time.Time{}
+	for i, schema := range schemas {
+		records[i] = versionedRecord{ID: schema.ID, UpdateTime: schema.UpdateTime}
+		if schema.UpdateTime.After(lastUpdated) {
+			lastUpdated = schema.UpdateTime
+		}
+	}
+	if checkNotModified(c, computeETag(records, connectionID)) {
+		return
+	}
+	if lastUpdated.IsZero() {
+		lastUpdated = time.Now()
+	}
+	
 	// 组织结构化的响应数据
 	response := &DatabaseSchemaResponse{
 		ConnectionID: connectionID,
 		Schemas:      h.organizeSchemaData(schemas),
 		TableCount:   h.countTables(schemas),
-		LastUpdated:  time.Now(), // 注意: 未来可从metadata获取实际更新时间
+		LastUpdated:  lastUpdated,
 	}
 	
 	c.JSON(http.StatusOK, response)
@@ -673,7 +771,7 @@ func (h *ConnectionHandler) getConnectionWithPermissionCheck(ctx context.Context
 
 // toConnectionResponse 转换为连接响应格式
 func (h *ConnectionHandler) toConnectionResponse(conn *repository.DatabaseConnection) *ConnectionResponse {
-	return &ConnectionResponse{
+	response := &ConnectionResponse{
 		ID:           conn.ID,
 		Name:         conn.Name,
 		Host:         conn.Host,
@@ -683,9 +781,15 @@ func (h *ConnectionHandler) toConnectionResponse(conn *repository.DatabaseConnec
 		DBType:       conn.DBType,
 		Status:       conn.Status,
 		LastTested:   conn.LastTested,
+		MaxRows:      conn.MaxRows,
+		MaxResultMB:  conn.MaxResultMB,
 		CreateTime:   conn.CreateTime,
 		UpdateTime:   conn.UpdateTime,
 	}
+	if h.schemaWarmup != nil {
+		response.Warmup, _ = h.schemaWarmup.Status(conn.ID)
+	}
+	return response
 }
 
 
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ConnectionResultLimitsRequest 连接的结果上限，字段为null时使用全局配置（SQL_MAX_ROWS、SQL_MAX_RESULT_MB）
+type ConnectionResultLimitsRequest struct {
+	MaxRows     *int32 `json:"max_rows" binding:"omitempty,min=1,max=1000000" example:"50000"`
+	MaxResultMB *int32 `json:"max_result_mb" binding:"omitempty,min=1,max=1024" example:"50"`
+}
+
+// SetResultLimits 设置连接的结果上限
+// @Summary 设置连接的结果上限
+// @Description 覆盖该连接上交互查询返回的最大行数与结果大小，超出部分截断并在结果中标记truncated；字段为null时恢复使用全局配置
+// @Tags 数据库连接
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "连接ID"
+// @Param request body ConnectionResultLimitsRequest true "结果上限"
+// @Success 200 {object} ConnectionResponse "设置成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "连接不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/connections/{id}/result-limits [put]
+func (h *ConnectionHandler) SetResultLimits(c *gin.Context) {
+	userID := h.getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	connectionID, err := h.parseConnectionID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_CONNECTION_ID", "无效的连接ID"))
+		return
+	}
+
+	var req ConnectionResultLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	connection, err := h.getConnectionWithPermissionCheck(c.Request.Context(), connectionID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("CONNECTION_NOT_FOUND", "连接不存在或无权访问"))
+		return
+	}
+
+	if err := h.connectionRepo.UpdateResultLimits(c.Request.Context(), connectionID, req.MaxRows, req.MaxResultMB, userID); err != nil {
+		h.logger.Error("Failed to update connection result limits",
+			zap.Error(err),
+			zap.Int64("connection_id", connectionID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("UPDATE_CONNECTION_FAILED", "更新连接失败"))
+		return
+	}
+	connection.MaxRows, connection.MaxResultMB = req.MaxRows, req.MaxResultMB
+
+	h.logger.Info("Connection result limits updated",
+		zap.Int64("user_id", userID),
+		zap.Int64("connection_id", connectionID))
+
+	c.JSON(http.StatusOK, h.toConnectionResponse(connection))
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/testutil"
+)
+
+func TestConnectionHandler_SetResultLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	call := func(h *ConnectionHandler, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/connections/7/result-limits", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: "7"}}
+		c.Set("user_id", int64(1))
+		h.SetResultLimits(c)
+		return w
+	}
+
+	t.Run("设置与恢复全局配置", func(t *testing.T) {
+		repo := &MockConnectionRepository{}
+		h := NewConnectionHandler(repo, &MockSchemaRepository{}, &MockConnectionManager{}, zaptest.NewLogger(t))
+		repo.On("GetByID", mock.Anything, int64(7)).Return(testutil.NewConnection(7, 1), nil)
+		rows := int32(50000)
+		repo.On("UpdateResultLimits", mock.Anything, int64(7), &rows, (*int32)(nil), int64(1)).Return(nil).Once()
+
+		w := call(h, `{"max_rows": 50000, "max_result_mb": null}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp ConnectionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotNil(t, resp.MaxRows)
+		assert.Equal(t, int32(50000), *resp.MaxRows)
+		assert.Nil(t, resp.MaxResultMB)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("超出范围", func(t *testing.T) {
+		h := NewConnectionHandler(&MockConnectionRepository{}, &MockSchemaRepository{}, &MockConnectionManager{}, zaptest.NewLogger(t))
+		assert.Equal(t, http.StatusBadRequest, call(h, `{"max_result_mb": 4096}`).Code)
+		assert.Equal(t, http.StatusBadRequest, call(h, `{"max_rows": 0}`).Code)
+	})
+
+	t.Run("非所有者", func(t *testing.T) {
+		repo := &MockConnectionRepository{}
+		h := NewConnectionHandler(repo, &MockSchemaRepository{}, &MockConnectionManager{}, zaptest.NewLogger(t))
+		repo.On("GetByID", mock.Anything, int64(7)).Return(testutil.NewConnection(7, 2), nil)
+
+		assert.Equal(t, http.StatusNotFound, call(h, `{"max_rows": 10}`).Code)
+		repo.AssertNotCalled(t, "UpdateResultLimits", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,149 @@
+// 邮件查询网关 - 入站邮件Webhook处理器
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// EmailGatewayInterface 邮件查询网关接口
+type EmailGatewayInterface interface {
+	Enqueue(email *service.InboundEmail) (*service.EmailQueryJob, error)
+}
+
+// EmailHandler 入站邮件处理器
+// 兼容SendGrid Inbound Parse的multipart表单格式
+type EmailHandler struct {
+	userRepo     repository.UserRepository
+	gateway      EmailGatewayInterface
+	webhookToken service.SecretFunc // Webhook共享令牌，每次请求读取，轮换后立即生效
+	config       *config.EmailGatewayConfig
+	logger       *zap.Logger
+}
+
+// NewEmailHandler 创建入站邮件处理器实例，webhookToken为nil时使用配置中的EMAIL_WEBHOOK_TOKEN
+func NewEmailHandler(
+	userRepo repository.UserRepository,
+	gateway EmailGatewayInterface,
+	webhookToken service.SecretFunc,
+	gatewayConfig *config.EmailGatewayConfig,
+	logger *zap.Logger,
+) *EmailHandler {
+	if webhookToken == nil {
+		webhookToken = service.StaticSecret(gatewayConfig.WebhookToken)
+	}
+	return &EmailHandler{
+		userRepo:     userRepo,
+		gateway:      gateway,
+		webhookToken: webhookToken,
+		config:       gatewayConfig,
+		logger:       logger,
+	}
+}
+
+// Routes 声明入站邮件路由，由邮件服务商签名认证
+func (h *EmailHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/integrations",
+			Tag:    "integrations",
+			Auth:   AuthNone,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/email/inbound", Handler: h.HandleInbound, Summary: "入站邮件Webhook", BlockedInMaintenance: true},
+			},
+		},
+	}
+}
+
+// InboundEmailResponse 入站邮件处理结果
+type InboundEmailResponse struct {
+	Status      string `json:"status"` // queued/ignored
+	ScheduledAt string `json:"scheduled_at,omitempty"`
+}
+
+// HandleInbound 处理入站邮件Webhook
+// 未验证的发件人返回200并忽略，避免邮件服务商重试投递
+// @Summary 入站邮件Webhook
+// @Description 将已验证用户的邮件提问排入查询队列，结果以CSV附件回复
+// @Tags Integrations
+// @Accept multipart/form-data
+// @Produce json
+// @Param token query string true "Webhook共享令牌"
+// @Success 200 {object} InboundEmailResponse "已忽略"
+// @Success 202 {object} InboundEmailResponse "已排队"
+// @Failure 401 {object} ErrorResponse "令牌无效"
+// @Failure 503 {object} ErrorResponse "队列已满"
+// @Router /api/v1/integrations/email/inbound [post]
+func (h *EmailHandler) HandleInbound(c *gin.Context) {
+	expected, err := h.webhookToken(c.Request.Context())
+	if err != nil {
+		h.logger.Error("读取入站邮件令牌失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("INTERNAL_ERROR", "读取Webhook令牌失败"))
+		return
+	}
+	token := c.Query("token")
+	if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		h.logger.Warn("入站邮件令牌无效", zap.String("remote_addr", c.ClientIP()))
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("INVALID_TOKEN", "Webhook令牌无效"))
+		return
+	}
+
+	address, err := mail.ParseAddress(c.PostForm("from"))
+	if err != nil {
+		h.ignore(c, "无法解析发件人", zap.String("from", c.PostForm("from")))
+		return
+	}
+
+	if h.config.RequireSPF && !strings.EqualFold(strings.TrimSpace(c.PostForm("SPF")), "pass") {
+		h.ignore(c, "发件人SPF校验未通过", zap.String("from", address.Address))
+		return
+	}
+
+	user, err := h.userRepo.GetByEmail(c.Request.Context(), strings.ToLower(address.Address))
+	if err != nil || !user.IsActive() {
+		h.ignore(c, "发件人不是已验证的活跃用户", zap.String("from", address.Address))
+		return
+	}
+
+	job, err := h.gateway.Enqueue(&service.InboundEmail{
+		UserID:  user.ID,
+		From:    address.Address,
+		Subject: c.PostForm("subject"),
+		Body:    c.PostForm("text"),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrEmailQueueFull) {
+			c.JSON(http.StatusServiceUnavailable, NewErrorResponse("QUEUE_FULL", "邮件查询队列已满，请稍后重试"))
+			return
+		}
+		h.logger.Error("邮件查询入队失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("INTERNAL_ERROR", "邮件查询入队失败"))
+		return
+	}
+
+	h.logger.Info("邮件查询已排队",
+		zap.Int64("user_id", user.ID),
+		zap.String("subject", job.Email.Subject))
+
+	c.JSON(http.StatusAccepted, &InboundEmailResponse{
+		Status:      "queued",
+		ScheduledAt: job.ScheduledAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// ignore 记录并忽略无法处理的邮件
+func (h *EmailHandler) ignore(c *gin.Context, reason string, fields ...zap.Field) {
+	h.logger.Warn("忽略入站邮件: "+reason, fields...)
+	c.JSON(http.StatusOK, &InboundEmailResponse{Status: "ignored"})
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// MockEmailGateway Mock邮件查询网关
+type MockEmailGateway struct {
+	mock.Mock
+}
+
+func (m *MockEmailGateway) Enqueue(email *service.InboundEmail) (*service.EmailQueryJob, error) {
+	args := m.Called(email)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.(*service.EmailQueryJob), args.Error(1)
+}
+
+func TestEmailHandler_HandleInbound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := &MockUserRepository{}
+	gateway := &MockEmailGateway{}
+	cfg := config.DefaultEmailGatewayConfig()
+	cfg.WebhookToken = "inbound-token"
+
+	h := NewEmailHandler(userRepo, gateway, nil, cfg, zaptest.NewLogger(t))
+	r := gin.New()
+	r.POST("/inbound", h.HandleInbound)
+
+	activeUser := testutil.NewUser(5, testutil.WithUserEmail("ceo@example.com"))
+	userRepo.On("GetByEmail", mock.Anything, "ceo@example.com").Return(activeUser, nil)
+	userRepo.On("GetByEmail", mock.Anything, "stranger@example.com").Return(nil, repository.ErrNotFound)
+	gateway.On("Enqueue", mock.MatchedBy(func(email *service.InboundEmail) bool {
+		return email.UserID == 5 && email.Body == "本月销售额"
+	})).Return(&service.EmailQueryJob{ScheduledAt: time.Now()}, nil)
+
+	tests := []struct {
+		name           string
+		token          string
+		from           string
+		spf            string
+		expectedStatus int
+	}{
+		{"令牌无效", "wrong", "CEO <ceo@example.com>", "pass", http.StatusUnauthorized},
+		{"SPF未通过", "inbound-token", "CEO <ceo@example.com>", "fail", http.StatusOK},
+		{"未注册发件人", "inbound-token", "stranger@example.com", "pass", http.StatusOK},
+		{"已验证用户入队", "inbound-token", "CEO <CEO@example.com>", "pass", http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Set("from", tt.from)
+			form.Set("subject", "销售")
+			form.Set("text", "本月销售额")
+			form.Set("SPF", tt.spf)
+
+			req := httptest.NewRequest(http.MethodPost, "/inbound?token="+tt.token, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+
+	gateway.AssertNumberOfCalls(t, "Enqueue", 1)
+}
+
+func TestEmailHandler_WebhookTokenRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	gateway := &MockEmailGateway{}
+	cfg := config.DefaultEmailGatewayConfig()
+	cfg.WebhookToken = "env-token"
+
+	// 令牌每次请求读取，凭据库中轮换后旧令牌立即失效
+	current := "stored-token"
+	h := NewEmailHandler(&MockUserRepository{}, gateway,
+		func(context.Context) (string, error) { return current, nil }, cfg, zaptest.NewLogger(t))
+	r := gin.New()
+	r.POST("/inbound", h.HandleInbound)
+
+	post := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/inbound?token="+token, strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("env-token"), "凭据库中的令牌优先于环境变量")
+	assert.NotEqual(t, http.StatusUnauthorized, post("stored-token"))
+
+	current = "rotated-token"
+	assert.Equal(t, http.StatusUnauthorized, post("stored-token"))
+
+	current = ""
+	assert.Equal(t, http.StatusUnauthorized, post(""), "未配置令牌时拒绝所有请求")
+	gateway.AssertNotCalled(t, "Enqueue", mock.Anything)
+}
@@ -0,0 +1,225 @@
+// 嵌入式组件API - 签发短期嵌入令牌，供客户内部工具中的Chat2SQL组件调用
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/auth"
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// embedMaxRows 嵌入查询返回的最大行数
+const embedMaxRows = 500
+
+// EmbedTokenIssuer 嵌入令牌签发接口
+type EmbedTokenIssuer interface {
+	GenerateEmbedToken(userID, connectionID int64, ttl time.Duration, rateLimit int) (*auth.EmbedToken, error)
+}
+
+// EmbedHandler 嵌入式组件处理器
+// 令牌签发需要用户JWT；查询端点仅接受嵌入令牌，且只能访问令牌绑定的连接并执行只读查询
+type EmbedHandler struct {
+	aiService      AIServiceInterface
+	sqlExecutor    SQLExecutorInterface
+	connectionRepo repository.ConnectionRepository
+	issuer         EmbedTokenIssuer
+	validator      *service.SQLSecurityValidator
+	logger         *zap.Logger
+}
+
+// NewEmbedHandler 创建嵌入处理器实例
+func NewEmbedHandler(
+	aiService AIServiceInterface,
+	sqlExecutor SQLExecutorInterface,
+	connectionRepo repository.ConnectionRepository,
+	issuer EmbedTokenIssuer,
+	logger *zap.Logger,
+) *EmbedHandler {
+	return &EmbedHandler{
+		aiService:      aiService,
+		sqlExecutor:    sqlExecutor,
+		connectionRepo: connectionRepo,
+		issuer:         issuer,
+		validator:      service.NewSQLSecurityValidator(logger),
+		logger:         logger,
+	}
+}
+
+// Routes 声明嵌入组件路由：查询使用嵌入令牌认证，不接受用户JWT；令牌由登录用户签发
+func (h *EmbedHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/embed",
+			Tag:    "embed",
+			Auth:   AuthEmbed,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/query", Handler: h.Query, Summary: "嵌入组件提问", BlockedInMaintenance: true},
+			},
+		},
+		{
+			Prefix: "/embed",
+			Tag:    "embed",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/tokens", Handler: h.CreateEmbedToken, Summary: "签发嵌入令牌", Permission: repository.PermEmbedTokenIssue},
+			},
+		},
+	}
+}
+
+// CreateEmbedTokenRequest 嵌入令牌签发请求
+type CreateEmbedTokenRequest struct {
+	ConnectionID       int64 `json:"connection_id" binding:"required,min=1"`
+	TTLSeconds         int   `json:"ttl_seconds,omitempty" binding:"omitempty,min=60,max=3600"`
+	RateLimitPerMinute int   `json:"rate_limit_per_minute,omitempty" binding:"omitempty,min=1,max=300"`
+}
+
+// EmbedQueryRequest 嵌入查询请求
+type EmbedQueryRequest struct {
+	Question string `json:"question" binding:"required,min=1,max=1000"`
+}
+
+// EmbedQueryResponse 嵌入查询响应
+type EmbedQueryResponse struct {
+	SQL           string                  `json:"sql"`
+	Columns       []string                `json:"columns"`
+	Rows          []map[string]any        `json:"rows"`
+	RowCount      int32                   `json:"row_count"`
+	Truncated     bool                    `json:"truncated"`
+	ExecutionTime int32                   `json:"execution_time"`
+	Lineage       []service.ColumnLineage `json:"lineage,omitempty"`
+}
+
+// CreateEmbedToken 签发嵌入令牌
+// @Summary 签发嵌入令牌
+// @Description 为指定数据库连接签发短期、只读、限流的嵌入令牌，用于在内部工具中嵌入Chat2SQL组件
+// @Tags Embed
+// @Accept json
+// @Produce json
+// @Param request body CreateEmbedTokenRequest true "签发请求"
+// @Success 201 {object} auth.EmbedToken "嵌入令牌"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "连接不存在"
+// @Security BearerAuth
+// @Router /api/v1/embed/tokens [post]
+func (h *EmbedHandler) CreateEmbedToken(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "认证信息无效"))
+		return
+	}
+
+	var req CreateEmbedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "请求参数无效"))
+		return
+	}
+
+	connection, err := h.connectionRepo.GetByID(c.Request.Context(), req.ConnectionID)
+	if err != nil || connection.UserID != userID {
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			h.logger.Error("查询数据库连接失败", zap.Error(err), zap.Int64("connection_id", req.ConnectionID))
+		}
+		c.JSON(http.StatusNotFound, NewErrorResponse("CONNECTION_NOT_FOUND", "数据库连接不存在"))
+		return
+	}
+
+	token, err := h.issuer.GenerateEmbedToken(userID, req.ConnectionID, time.Duration(req.TTLSeconds)*time.Second, req.RateLimitPerMinute)
+	if err != nil {
+		h.logger.Error("签发嵌入令牌失败", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("TOKEN_GENERATION_FAILED", "签发嵌入令牌失败"))
+		return
+	}
+
+	h.logger.Info("嵌入令牌已签发",
+		zap.Int64("user_id", userID),
+		zap.Int64("connection_id", req.ConnectionID),
+		zap.Time("expires_at", token.ExpiresAt))
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// Query 嵌入组件提问
+// @Summary 嵌入组件查询
+// @Description 使用嵌入令牌提问，在令牌绑定的连接上生成并执行只读SQL
+// @Tags Embed
+// @Accept json
+// @Produce json
+// @Param request body EmbedQueryRequest true "查询请求"
+// @Success 200 {object} EmbedQueryResponse "查询结果"
+// @Failure 401 {object} ErrorResponse "嵌入令牌无效"
+// @Failure 422 {object} ErrorResponse "生成的SQL未通过安全检查"
+// @Failure 429 {object} ErrorResponse "请求频率限制"
+// @Router /api/v1/embed/query [post]
+func (h *EmbedHandler) Query(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	connectionID, hasConnection := middleware.GetEmbedConnectionIDFromContext(c)
+	if !ok || !hasConnection {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "嵌入令牌无效"))
+		return
+	}
+
+	var req EmbedQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "请求参数无效"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	// 签发后连接可能已被删除或转移，每次请求重新校验归属
+	connection, err := h.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil || connection.UserID != userID {
+		c.JSON(http.StatusForbidden, NewErrorResponse("CONNECTION_UNAVAILABLE", "嵌入令牌绑定的连接不可用"))
+		return
+	}
+
+	generated, err := h.aiService.GenerateSQL(ctx, &service.SQLGenerationRequest{
+		Query:        req.Question,
+		ConnectionID: connectionID,
+		UserID:       userID,
+		Locale:       service.DetectLocale(req.Question),
+	})
+	if err != nil {
+		h.logger.Error("嵌入查询生成SQL失败", zap.Error(err), zap.Int64("connection_id", connectionID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("SQL_GENERATION_FAILED", "生成SQL失败"))
+		return
+	}
+
+	if result := h.validator.ValidateSQL(generated.SQL); !result.IsValid || !result.IsReadOnly {
+		c.JSON(http.StatusUnprocessableEntity, NewErrorResponse("SQL_REJECTED", "生成的SQL未通过安全检查，已拒绝执行"))
+		return
+	}
+
+	result, err := h.sqlExecutor.ExecuteQuery(service.WithQueryOwner(ctx, userID), generated.SQL, connection)
+	if err != nil {
+		h.logger.Error("嵌入查询执行失败", zap.Error(err), zap.Int64("connection_id", connectionID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("EXECUTION_FAILED", "查询执行失败"))
+		return
+	}
+
+	rows := result.Rows
+	truncated := len(rows) > embedMaxRows
+	if truncated {
+		rows = rows[:embedMaxRows]
+	}
+
+	c.JSON(http.StatusOK, &EmbedQueryResponse{
+		SQL:           generated.SQL,
+		Columns:       result.Columns,
+		Rows:          rows,
+		RowCount:      result.RowCount,
+		Truncated:     truncated,
+		ExecutionTime: result.ExecutionTime,
+		Lineage:       h.validator.ExtractColumnLineage(generated.SQL),
+	})
+}
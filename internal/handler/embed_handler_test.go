@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/auth"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+type stubEmbedIssuer struct {
+	ttl       time.Duration
+	rateLimit int
+}
+
+func (s *stubEmbedIssuer) GenerateEmbedToken(userID, connectionID int64, ttl time.Duration, rateLimit int) (*auth.EmbedToken, error) {
+	s.ttl, s.rateLimit = ttl, rateLimit
+	return &auth.EmbedToken{Token: "embed-token", ConnectionID: connectionID}, nil
+}
+
+func newEmbedTestRouter(t *testing.T) (*gin.Engine, *MockAIService, *MockSQLExecutor, *MockConnectionRepository, *stubEmbedIssuer) {
+	gin.SetMode(gin.TestMode)
+
+	aiService := &MockAIService{}
+	executor := &MockSQLExecutor{}
+	connRepo := &MockConnectionRepository{}
+	issuer := &stubEmbedIssuer{}
+
+	h := NewEmbedHandler(aiService, executor, connRepo, issuer, zaptest.NewLogger(t))
+	r := gin.New()
+	r.POST("/tokens", func(c *gin.Context) { c.Set("user_id", int64(7)) }, h.CreateEmbedToken)
+	r.POST("/query", func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+		c.Set("embed_connection_id", int64(3))
+	}, h.Query)
+	return r, aiService, executor, connRepo, issuer
+}
+
+func postEmbed(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestEmbedHandler_CreateEmbedToken(t *testing.T) {
+	r, _, _, connRepo, issuer := newEmbedTestRouter(t)
+	connRepo.On("GetByID", mock.Anything, int64(3)).Return(testutil.NewConnection(3, 7), nil)
+	connRepo.On("GetByID", mock.Anything, int64(4)).Return(testutil.NewConnection(4, 8), nil)
+
+	w := postEmbed(r, "/tokens", `{"connection_id":3,"ttl_seconds":600,"rate_limit_per_minute":10}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), "embed-token")
+	assert.Equal(t, 10*time.Minute, issuer.ttl)
+	assert.Equal(t, 10, issuer.rateLimit)
+
+	// 他人的连接
+	w = postEmbed(r, "/tokens", `{"connection_id":4}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 超出有效期上限
+	w = postEmbed(r, "/tokens", `{"connection_id":3,"ttl_seconds":86400}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestEmbedHandler_Query(t *testing.T) {
+	r, aiService, executor, connRepo, _ := newEmbedTestRouter(t)
+	connection := testutil.NewConnection(3, 7)
+	connRepo.On("GetByID", mock.Anything, int64(3)).Return(connection, nil)
+	aiService.On("GenerateSQL", mock.Anything, mock.MatchedBy(func(req *service.SQLGenerationRequest) bool {
+		return req.ConnectionID == 3 && req.UserID == 7
+	})).Return(&service.SQLGenerationResponse{SQL: "SELECT count(*) FROM orders"}, nil)
+	executor.On("ExecuteQuery", mock.Anything, "SELECT count(*) FROM orders", connection).
+		Return(&service.QueryResult{Columns: []string{"count"}, Rows: []map[string]any{{"count": 5}}, RowCount: 1}, nil)
+
+	w := postEmbed(r, "/query", `{"question":"订单总数"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"rows":[{"count":5}]`)
+	assert.Contains(t, w.Body.String(), `"truncated":false`)
+	assert.Contains(t, w.Body.String(), `"lineage":[{"column":"count","expression":"count(*)","source_columns":[],"aggregate":"COUNT","computed":true}]`)
+}
+
+func TestEmbedHandler_QueryRejectsWrites(t *testing.T) {
+	r, aiService, executor, connRepo, _ := newEmbedTestRouter(t)
+	connRepo.On("GetByID", mock.Anything, int64(3)).Return(testutil.NewConnection(3, 7), nil)
+	aiService.On("GenerateSQL", mock.Anything, mock.Anything).Return(&service.SQLGenerationResponse{SQL: "DELETE FROM orders"}, nil)
+
+	w := postEmbed(r, "/query", `{"question":"删除所有订单"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	executor.AssertNotCalled(t, "ExecuteQuery", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// ErasureHandler 个人数据删除处理器
+// 提交GDPR删除申请并查询删除报告，实际删除由后台任务完成
+type ErasureHandler struct {
+	erasures *service.ErasureService
+	logger   *zap.Logger
+}
+
+// NewErasureHandler 创建个人数据删除处理器实例
+func NewErasureHandler(erasures *service.ErasureService, logger *zap.Logger) *ErasureHandler {
+	return &ErasureHandler{
+		erasures: erasures,
+		logger:   logger,
+	}
+}
+
+// Routes 声明个人数据删除路由，用户可删除本人数据，为他人提交需要admin角色
+func (h *ErasureHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/users",
+			Tag:    "erasure",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/me/erasure", Handler: h.SubmitOwnErasure, Summary: "申请删除本人个人数据"},
+				{Method: http.MethodGet, Path: "/me/erasure", Handler: h.ListOwnErasures, Summary: "本人的删除申请"},
+				{Method: http.MethodPost, Path: "/:id/erasure", Handler: h.SubmitUserErasure, Summary: "为指定用户申请删除", Roles: []string{string(repository.RoleAdmin)}},
+			},
+		},
+		{
+			Prefix: "/erasure-requests",
+			Tag:    "erasure",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/:id", Handler: h.GetErasure, Summary: "删除申请与删除报告"},
+			},
+		},
+	}
+}
+
+// ErasureSubmitRequest 个人数据删除申请请求
+type ErasureSubmitRequest struct {
+	// anonymize清除文本保留统计，delete删除记录；默认anonymize
+	Mode string `json:"mode" binding:"omitempty,oneof=anonymize delete" example:"anonymize"`
+}
+
+// ErasureListResponse 个人数据删除申请列表响应
+type ErasureListResponse struct {
+	Requests []*repository.ErasureRequest `json:"requests"`
+}
+
+// SubmitOwnErasure 提交删除本人个人数据的申请
+// @Summary 申请删除本人个人数据
+// @Description 提交GDPR删除申请，后台任务匿名化或删除查询历史、反馈、审批说明与对话记忆，账号改为假名并停用
+// @Tags 个人数据
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ErasureSubmitRequest false "删除方式"
+// @Success 202 {object} repository.ErasureRequest "申请已提交"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 409 {object} ErrorResponse "已有未完成的申请"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/users/me/erasure [post]
+func (h *ErasureHandler) SubmitOwnErasure(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	h.submit(c, userID, userID)
+}
+
+// SubmitUserErasure 管理员为指定用户提交删除申请
+// @Summary 申请删除用户个人数据
+// @Description 管理员为指定用户提交GDPR删除申请（需admin角色）
+// @Tags 个人数据
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body ErasureSubmitRequest false "删除方式"
+// @Success 202 {object} repository.ErasureRequest "申请已提交"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 409 {object} ErrorResponse "已有未完成的申请"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/users/{id}/erasure [post]
+func (h *ErasureHandler) SubmitUserErasure(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	subjectID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_USER_ID", "用户ID格式错误"))
+		return
+	}
+	h.submit(c, userID, subjectID)
+}
+
+// ListOwnErasures 列出本人的删除申请
+// @Summary 列出本人的删除申请
+// @Description 列出本人的GDPR删除申请及删除报告
+// @Tags 个人数据
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} ErasureListResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/users/me/erasure [get]
+func (h *ErasureHandler) ListOwnErasures(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	requests, err := h.erasures.List(c.Request.Context(), userID)
+	if err != nil {
+		h.respondErasureError(c, err)
+		return
+	}
+	if requests == nil {
+		requests = []*repository.ErasureRequest{}
+	}
+	c.JSON(http.StatusOK, &ErasureListResponse{Requests: requests})
+}
+
+// GetErasure 获取删除申请与删除报告
+// @Summary 获取删除报告
+// @Description 获取GDPR删除申请的状态与删除报告，数据主体、提交人或管理员可以查看
+// @Tags 个人数据
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "删除申请ID"
+// @Success 200 {object} repository.ErasureRequest "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权查看"
+// @Failure 404 {object} ErrorResponse "申请不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/erasure-requests/{id} [get]
+func (h *ErasureHandler) GetErasure(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_ERASURE_ID", "删除申请ID格式错误"))
+		return
+	}
+
+	req, err := h.erasures.Get(c.Request.Context(), userID, c.GetString("user_role"), id)
+	if err != nil {
+		h.respondErasureError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// submit 绑定删除方式并提交申请
+func (h *ErasureHandler) submit(c *gin.Context, userID, subjectID int64) {
+	var req ErasureSubmitRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_REQUEST",
+				Message: "请求参数格式错误",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+	if req.Mode == "" {
+		req.Mode = string(repository.ErasureAnonymize)
+	}
+
+	erasure, err := h.erasures.Submit(c.Request.Context(), userID, c.GetString("user_role"), subjectID, repository.ErasureMode(req.Mode))
+	if err != nil {
+		h.respondErasureError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, erasure)
+}
+
+// respondErasureError 将个人数据删除服务错误映射为HTTP响应
+func (h *ErasureHandler) respondErasureError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, NewErrorResponse("PERMISSION_DENIED", "无权操作该用户的个人数据"))
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_ERASURE_MODE",
+			Message: "删除方式无效",
+			Details: err.Error(),
+		})
+	case errors.Is(err, repository.ErrDuplicateEntry):
+		c.JSON(http.StatusConflict, NewErrorResponse("ERASURE_IN_PROGRESS", "已有未完成的删除申请"))
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("ERASURE_NOT_FOUND", "删除申请不存在"))
+	default:
+		h.logger.Error("Erasure operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("ERASURE_ERROR", "个人数据删除处理失败"))
+	}
+}
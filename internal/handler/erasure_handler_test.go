@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// stubErasureRepository 内存个人数据删除Repository
+type stubErasureRepository struct {
+	repository.ErasureRepository
+	requests map[int64]*repository.ErasureRequest
+}
+
+func (s *stubErasureRepository) Create(ctx context.Context, req *repository.ErasureRequest) error {
+	for _, existing := range s.requests {
+		if existing.SubjectUserID == req.SubjectUserID && existing.Status == string(repository.ErasurePending) {
+			return repository.ErrDuplicateEntry
+		}
+	}
+	req.ID = int64(len(s.requests) + 1)
+	s.requests[req.ID] = req
+	return nil
+}
+
+func (s *stubErasureRepository) GetByID(ctx context.Context, id int64) (*repository.ErasureRequest, error) {
+	if req, ok := s.requests[id]; ok {
+		return req, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func newErasureTestRouter(t *testing.T, userID int64, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	repo := &stubErasureRepository{requests: map[int64]*repository.ErasureRequest{}}
+	svc := service.NewErasureService(repo, config.DefaultErasureConfig(), zaptest.NewLogger(t))
+	h := NewErasureHandler(svc, zaptest.NewLogger(t))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("user_role", role)
+	})
+	r.POST("/users/me/erasure", h.SubmitOwnErasure)
+	r.POST("/users/:id/erasure", h.SubmitUserErasure)
+	r.GET("/erasure-requests/:id", h.GetErasure)
+	return r
+}
+
+func TestErasureHandler_SubmitOwnErasure(t *testing.T) {
+	r := newErasureTestRouter(t, 7, "user")
+
+	req := httptest.NewRequest(http.MethodPost, "/users/me/erasure", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var resp repository.ErasureRequest
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(7), resp.SubjectUserID)
+	assert.Equal(t, string(repository.ErasureAnonymize), resp.Mode, "未指定时默认匿名化")
+
+	req = httptest.NewRequest(http.MethodPost, "/users/me/erasure", bytes.NewBufferString(`{"mode":"delete"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/erasure-requests/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestErasureHandler_SubmitUserErasure(t *testing.T) {
+	r := newErasureTestRouter(t, 7, "manager")
+
+	req := httptest.NewRequest(http.MethodPost, "/users/8/erasure", bytes.NewBufferString(`{"mode":"delete"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code, "非管理员不能为他人申请删除")
+
+	r = newErasureTestRouter(t, 1, "admin")
+	req = httptest.NewRequest(http.MethodPost, "/users/8/erasure", bytes.NewBufferString(`{"mode":"shred"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/users/8/erasure", bytes.NewBufferString(`{"mode":"delete"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
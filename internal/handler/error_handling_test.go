@@ -15,6 +15,7 @@ import (
 	"go.uber.org/zap/zaptest"
 
 	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
 )
 
 
@@ -124,12 +125,9 @@ func TestAuthHandler_ErrorHandling(t *testing.T) {
 		handler := NewAuthHandler(mockUserRepo, mockJWTService, logger)
 
 		// 模拟用户存在但密码哈希格式错误
-		user := &repository.User{
-			BaseModel:    repository.BaseModel{ID: 1},
-			Username:     "testuser",
-			PasswordHash: "invalid-hash-too-short", // 无效的bcrypt哈希
-			Status:       string(repository.StatusActive),
-		}
+		user := testutil.NewUser(1,
+			testutil.WithUsername("testuser"),
+			testutil.WithPasswordHash("invalid-hash-too-short")) // 无效的bcrypt哈希
 		mockUserRepo.On("GetByUsername", mock.Anything, "testuser").Return(user, nil)
 
 		reqBody := map[string]interface{}{
@@ -169,12 +167,7 @@ func TestSQLHandler_ErrorHandling(t *testing.T) {
 		handler := NewSQLHandler(mockQueryRepo, mockConnRepo, mockSQLExecutor, logger)
 
 		// 模拟连接存在
-		connection := &repository.DatabaseConnection{
-			BaseModel: repository.BaseModel{ID: 1},
-			UserID:    1,
-			Name:      "test-db",
-			Status:    string(repository.ConnectionActive),
-		}
+		connection := testutil.NewConnection(1, 1, testutil.WithConnectionName("test-db"))
 		mockConnRepo.On("GetByID", mock.Anything, int64(1)).Return(connection, nil)
 		
 		// 模拟查询历史记录创建和更新
@@ -255,8 +248,8 @@ func TestSQLHandler_ErrorHandling(t *testing.T) {
 
 		handler := NewSQLHandler(mockQueryRepo, mockConnRepo, mockSQLExecutor, logger)
 
-		// 模拟repository错误，只设置ListByUser的Mock，因为出错时不会调用CountByUser
-		mockQueryRepo.On("ListByUser", mock.Anything, int64(1), mock.AnythingOfType("int"), mock.AnythingOfType("int")).
+		// 模拟repository错误，只设置ListPage的Mock，因为出错时不会调用CountByUser
+		mockQueryRepo.On("ListPage", mock.Anything, mock.Anything, mock.Anything, mock.AnythingOfType("int"), mock.AnythingOfType("int")).
 			Return([]*repository.QueryHistory{}, errors.New("database query timeout"))
 
 		req := httptest.NewRequest(http.MethodGet, "/sql/history?page=1&limit=10", nil)
@@ -625,4 +618,27 @@ func TestPanicRecovery(t *testing.T) {
 // 复用handler_integration_test.go中定义的Mock类型以避免重复声明
 
 // 注意：Mock方法实现在handler_integration_test.go中定义
-// 这里只需要声明测试逻辑即可
\ No newline at end of file
+// 这里只需要声明测试逻辑即可
+// TestErrorResponse_RedactsSecrets 错误详情中的连接串密码与密钥不会返回给客户端
+func TestErrorResponse_RedactsSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/fail", func(c *gin.Context) {
+		c.JSON(http.StatusBadGateway, ErrorResponse{
+			Code:    "CONNECTION_FAILED",
+			Message: "连接失败",
+			Details: "dial postgres://app:S3cr3tPassw0rd@db:5432/app: api_key=sk-proj-1234567890abcdefghij",
+		})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	assert.NotContains(t, w.Body.String(), "S3cr3tPassw0rd")
+	assert.NotContains(t, w.Body.String(), "sk-proj-1234567890abcdefghij")
+
+	var resp ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "CONNECTION_FAILED", resp.Code)
+	assert.Contains(t, resp.Details, "postgres://app:***@db:5432/app")
+}
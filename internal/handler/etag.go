@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionedRecord 参与ETag计算的记录版本：ID与最后更新时间
+type versionedRecord struct {
+	ID         int64
+	UpdateTime time.Time
+}
+
+// computeETag 根据记录版本与影响响应内容的参数计算弱ETag
+// 记录任一行更新、增删或参数变化时ETag都会变化；使用弱ETag是因为同一版本在v1与v2下的序列化可能不同
+func computeETag(records []versionedRecord, params ...any) string {
+	h := sha256.New()
+	for _, p := range params {
+		fmt.Fprintf(h, "%v|", p)
+	}
+	for _, r := range records {
+		fmt.Fprintf(h, "%d:%d;", r.ID, r.UpdateTime.UnixNano())
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:12]) + `"`
+}
+
+// checkNotModified 设置ETag与缓存头，If-None-Match命中时返回304
+// 返回true表示已响应304，调用方应直接返回
+func checkNotModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	// 允许客户端缓存，但每次使用前必须携带If-None-Match重新验证
+	c.Header("Cache-Control", "private, no-cache")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return true
+	}
+	return false
+}
+
+// etagMatches 按弱比较规则判断If-None-Match是否包含指定ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := computeETag([]versionedRecord{{ID: 1, UpdateTime: time.Unix(100, 0)}}, 20)
+
+	assert.True(t, etagMatches(etag, etag))
+	assert.True(t, etagMatches(`"other", `+etag, etag))
+	assert.True(t, etagMatches(`"`+etag[3:], etag), "弱比较忽略W/前缀")
+	assert.True(t, etagMatches("*", etag))
+	assert.False(t, etagMatches("", etag))
+	assert.False(t, etagMatches(`"other"`, etag))
+
+	// 更新时间或参数变化都会改变ETag
+	assert.NotEqual(t, etag, computeETag([]versionedRecord{{ID: 1, UpdateTime: time.Unix(101, 0)}}, 20))
+	assert.NotEqual(t, etag, computeETag([]versionedRecord{{ID: 1, UpdateTime: time.Unix(100, 0)}}, 50))
+}
+
+func TestSQLHandler_HistoryConditionalGet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	query := testutil.NewQueryHistory(42, 7, testutil.WithQuery("用户总数", "SELECT COUNT(*) FROM users"))
+	query.UpdateTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	queryRepo := new(MockQueryHistoryRepository)
+	queryRepo.On("GetByID", mock.Anything, int64(42)).Return(query, nil)
+	queryRepo.On("ListPage", mock.Anything, repository.QueryHistoryFilter{UserID: 7}, (*repository.QueryHistoryKeyset)(nil), 21, 0).
+		Return([]*repository.QueryHistory{query}, nil)
+	queryRepo.On("CountByUser", mock.Anything, int64(7)).Return(int64(1), nil)
+
+	h := NewSQLHandler(queryRepo, nil, nil, zap.NewNop())
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	router.GET("/history", h.GetQueryHistory)
+	router.GET("/history/:id", h.GetQueryById)
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/history", "/history/42"} {
+		first := get(path, "")
+		require.Equal(t, http.StatusOK, first.Code, path)
+		etag := first.Header().Get("ETag")
+		require.NotEmpty(t, etag, path)
+
+		unchanged := get(path, etag)
+		assert.Equal(t, http.StatusNotModified, unchanged.Code, path)
+		assert.Empty(t, unchanged.Body.String(), path)
+		assert.Equal(t, etag, unchanged.Header().Get("ETag"), path)
+	}
+
+	// 记录更新后旧ETag失效
+	etag := get("/history/42", "").Header().Get("ETag")
+	query.UpdateTime = query.UpdateTime.Add(time.Minute)
+	assert.Equal(t, http.StatusOK, get("/history/42", etag).Code)
+}
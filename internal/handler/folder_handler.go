@@ -0,0 +1,773 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// FolderHandler 保存查询与文件夹处理器
+// 工作空间内的保存查询按多级文件夹组织，文件夹权限向下继承，列表与详情返回面包屑
+type FolderHandler struct {
+	folders *service.FolderService
+	logger  *zap.Logger
+}
+
+// NewFolderHandler 创建保存查询与文件夹处理器实例
+func NewFolderHandler(folders *service.FolderService, logger *zap.Logger) *FolderHandler {
+	return &FolderHandler{
+		folders: folders,
+		logger:  logger,
+	}
+}
+
+// Routes 声明文件夹与保存查询路由，访问级别由文件夹权限决定
+func (h *FolderHandler) Routes() []RouteGroup {
+	edit := repository.PermSavedQueryEdit
+	return []RouteGroup{
+		{
+			Prefix: "/folders",
+			Tag:    "folders",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.ListRootContents, Summary: "根目录内容"},
+				{Method: http.MethodPost, Path: "", Handler: h.CreateFolder, Summary: "创建文件夹", Permission: edit},
+				{Method: http.MethodGet, Path: "/:id", Handler: h.ListFolderContents, Summary: "文件夹内容与面包屑"},
+				{Method: http.MethodPatch, Path: "/:id", Handler: h.RenameFolder, Summary: "重命名文件夹", Permission: edit},
+				{Method: http.MethodDelete, Path: "/:id", Handler: h.DeleteFolder, Summary: "删除空文件夹", Permission: edit},
+				{Method: http.MethodPost, Path: "/:id/move", Handler: h.MoveFolder, Summary: "移动文件夹", Permission: edit},
+				{Method: http.MethodGet, Path: "/:id/permissions", Handler: h.ListPermissions, Summary: "文件夹授权列表"},
+				{Method: http.MethodPut, Path: "/:id/permissions", Handler: h.SetPermission, Summary: "授予文件夹权限", Permission: edit},
+				{Method: http.MethodDelete, Path: "/:id/permissions/:user_id", Handler: h.RemovePermission, Summary: "撤销文件夹权限", Permission: edit},
+			},
+		},
+		{
+			Prefix: "/saved-queries",
+			Tag:    "saved-queries",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "", Handler: h.CreateSavedQuery, Summary: "保存查询", Permission: edit},
+				{Method: http.MethodGet, Path: "/:id", Handler: h.GetSavedQuery, Summary: "保存查询详情与面包屑"},
+				{Method: http.MethodPut, Path: "/:id", Handler: h.UpdateSavedQuery, Summary: "修改保存查询", Permission: edit},
+				{Method: http.MethodDelete, Path: "/:id", Handler: h.DeleteSavedQuery, Summary: "删除保存查询", Permission: edit},
+				{Method: http.MethodPost, Path: "/:id/move", Handler: h.MoveSavedQuery, Summary: "移动保存查询", Permission: edit},
+				{Method: http.MethodPost, Path: "/batch-move", Handler: h.BatchMoveSavedQueries, Summary: "批量移动保存查询", Permission: edit},
+			},
+		},
+	}
+}
+
+// FolderContentsParams 文件夹内容分页参数，分页只作用于保存查询
+type FolderContentsParams struct {
+	Limit  int    `form:"limit,default=50" binding:"min=1,max=100" example:"50"`
+	Cursor string `form:"cursor" example:"eyJvIjo1MCwicyI6IjNmMmEifQ"`
+}
+
+// FolderContentsResponse 文件夹内容响应，folder为空表示根目录，breadcrumbs从根到当前文件夹
+type FolderContentsResponse struct {
+	Folder       *repository.QueryFolder   `json:"folder"`
+	Breadcrumbs  []service.Breadcrumb      `json:"breadcrumbs"`
+	Permission   string                    `json:"permission" example:"edit"`
+	Folders      []*repository.QueryFolder `json:"folders"`
+	SavedQueries []*repository.SavedQuery  `json:"saved_queries"`
+	Limit        int                       `json:"limit" example:"50"`
+	CursorPage
+}
+
+// CreateFolderRequest 创建文件夹请求，parent_id为空表示在根目录创建
+type CreateFolderRequest struct {
+	Name     string `json:"name" binding:"required,max=100" example:"销售报表"`
+	ParentID *int64 `json:"parent_id" example:"5"`
+}
+
+// RenameFolderRequest 重命名文件夹请求
+type RenameFolderRequest struct {
+	Name string `json:"name" binding:"required,max=100" example:"月度销售报表"`
+}
+
+// MoveFolderRequest 移动文件夹请求，parent_id为空表示移动到根目录
+type MoveFolderRequest struct {
+	ParentID *int64 `json:"parent_id" example:"3"`
+}
+
+// FolderPermissionRequest 文件夹授权请求
+type FolderPermissionRequest struct {
+	UserID int64  `json:"user_id" binding:"required,gt=0" example:"12"`
+	Level  string `json:"level" binding:"required,oneof=view edit manage" example:"view"`
+}
+
+// SavedQueryRequest 创建或修改保存查询的请求，folder_id只在创建时使用
+type SavedQueryRequest struct {
+	Name            string  `json:"name" binding:"required,max=200" example:"月度销售额"`
+	Description     *string `json:"description,omitempty" example:"按月汇总的销售额"`
+	NaturalQuery    string  `json:"natural_query" binding:"required" example:"每个月的销售额是多少"`
+	SQL             string  `json:"sql" binding:"required" example:"SELECT date_trunc('month', created_at), SUM(amount) FROM orders GROUP BY 1"`
+	ConnectionID    *int64  `json:"connection_id,omitempty" example:"1"`
+	FolderID        *int64  `json:"folder_id,omitempty" example:"5"`
+	DashboardBacked bool    `json:"dashboard_backed,omitempty" example:"true"`                          // 仪表盘使用的查询，需要指定连接，开启后定期预热结果缓存
+	CacheTTLSeconds *int32  `json:"cache_ttl_seconds,omitempty" binding:"omitempty,gt=0" example:"300"` // 结果缓存时间（秒），为空时使用全局配置
+}
+
+// MoveSavedQueryRequest 移动保存查询请求，folder_id为空表示移动到根目录
+type MoveSavedQueryRequest struct {
+	FolderID *int64 `json:"folder_id" example:"5"`
+}
+
+// SavedQueryResponse 保存查询详情响应，breadcrumbs为所在文件夹从根开始的路径
+type SavedQueryResponse struct {
+	SavedQuery  *repository.SavedQuery `json:"saved_query"`
+	Breadcrumbs []service.Breadcrumb   `json:"breadcrumbs"`
+}
+
+// ListRootContents 根目录内容
+// @Summary 根目录内容
+// @Description 列出根目录下当前用户可查看的文件夹与保存查询，保存查询按游标分页
+// @Tags 保存查询
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "每页保存查询数" default(50)
+// @Param cursor query string false "上一页响应中的next_cursor"
+// @Success 200 {object} FolderContentsResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders [get]
+func (h *FolderHandler) ListRootContents(c *gin.Context) {
+	h.listContents(c, nil)
+}
+
+// ListFolderContents 文件夹内容
+// @Summary 文件夹内容
+// @Description 列出文件夹中当前用户可查看的子文件夹与保存查询，并返回从根目录开始的面包屑
+// @Tags 保存查询
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Param limit query int false "每页保存查询数" default(50)
+// @Param cursor query string false "上一页响应中的next_cursor"
+// @Success 200 {object} FolderContentsResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权访问该文件夹"
+// @Failure 404 {object} ErrorResponse "文件夹不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders/{id} [get]
+func (h *FolderHandler) ListFolderContents(c *gin.Context) {
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+	h.listContents(c, &id)
+}
+
+// listContents 列出文件夹内容，folderID为空表示根目录
+func (h *FolderHandler) listContents(c *gin.Context, folderID *int64) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var params FolderContentsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_PARAMS",
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return
+	}
+	scope := listScope("saved_queries", "root")
+	if folderID != nil {
+		scope = listScope("saved_queries", *folderID)
+	}
+	offset, err := resolveOffset(params.Cursor, 0, scope)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
+	}
+
+	contents, err := h.folders.ListContents(c.Request.Context(), userID, c.GetString("user_role"), folderID, params.Limit+1, offset)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+
+	queries, page := paginate(contents.Queries, offset, params.Limit, scope)
+	if queries == nil {
+		queries = []*repository.SavedQuery{}
+	}
+	folders := contents.Folders
+	if folders == nil {
+		folders = []*repository.QueryFolder{}
+	}
+
+	c.JSON(http.StatusOK, &FolderContentsResponse{
+		Folder:       contents.Folder,
+		Breadcrumbs:  contents.Breadcrumbs,
+		Permission:   string(contents.Permission),
+		Folders:      folders,
+		SavedQueries: queries,
+		Limit:        params.Limit,
+		CursorPage:   page,
+	})
+}
+
+// CreateFolder 创建文件夹
+// @Summary 创建文件夹
+// @Description 在根目录或指定父文件夹下创建文件夹，需要父文件夹的编辑权限，同一父文件夹下名称不能重复
+// @Tags 保存查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateFolderRequest true "文件夹"
+// @Success 201 {object} repository.QueryFolder "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "父文件夹不存在"
+// @Failure 409 {object} ErrorResponse "同名文件夹已存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders [post]
+func (h *FolderHandler) CreateFolder(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req CreateFolderRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	folder, err := h.folders.CreateFolder(c.Request.Context(), userID, c.GetString("user_role"), req.ParentID, req.Name)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, folder)
+}
+
+// RenameFolder 重命名文件夹
+// @Summary 重命名文件夹
+// @Description 重命名文件夹，需要该文件夹的管理权限
+// @Tags 保存查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Param request body RenameFolderRequest true "新名称"
+// @Success 200 {object} repository.QueryFolder "重命名成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "文件夹不存在"
+// @Failure 409 {object} ErrorResponse "同名文件夹已存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders/{id} [patch]
+func (h *FolderHandler) RenameFolder(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RenameFolderRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	folder, err := h.folders.RenameFolder(c.Request.Context(), userID, c.GetString("user_role"), id, req.Name)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, folder)
+}
+
+// MoveFolder 移动文件夹
+// @Summary 移动文件夹
+// @Description 将文件夹及其子文件夹、保存查询一起移动到新的父文件夹下，需要该文件夹的管理权限与目标文件夹的编辑权限
+// @Tags 保存查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Param request body MoveFolderRequest true "目标父文件夹"
+// @Success 200 {object} repository.QueryFolder "移动成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误或目标位于自身子树中"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "文件夹不存在"
+// @Failure 409 {object} ErrorResponse "目标文件夹下已有同名文件夹"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders/{id}/move [post]
+func (h *FolderHandler) MoveFolder(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req MoveFolderRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	folder, err := h.folders.MoveFolder(c.Request.Context(), userID, c.GetString("user_role"), id, req.ParentID)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder 删除文件夹
+// @Summary 删除文件夹
+// @Description 删除空文件夹，需要该文件夹的管理权限；仍包含子文件夹或保存查询时返回400
+// @Tags 保存查询
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Success 204 "删除成功"
+// @Failure 400 {object} ErrorResponse "文件夹不为空"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "文件夹不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders/{id} [delete]
+func (h *FolderHandler) DeleteFolder(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.folders.DeleteFolder(c.Request.Context(), userID, c.GetString("user_role"), id); err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListPermissions 文件夹授权列表
+// @Summary 文件夹授权列表
+// @Description 列出直接设置在该文件夹上的授权，需要该文件夹的管理权限
+// @Tags 保存查询
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Success 200 {array} repository.FolderPermission "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "文件夹不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders/{id}/permissions [get]
+func (h *FolderHandler) ListPermissions(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	permissions, err := h.folders.ListPermissions(c.Request.Context(), userID, c.GetString("user_role"), id)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	if permissions == nil {
+		permissions = []*repository.FolderPermission{}
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+// SetPermission 授予文件夹权限
+// @Summary 授予文件夹权限
+// @Description 授予用户view/edit/manage权限并向下继承，需要该文件夹的管理权限；设置授权后未授权的成员将无法访问该文件夹
+// @Tags 保存查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Param request body FolderPermissionRequest true "授权"
+// @Success 200 {object} repository.FolderPermission "授权成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "文件夹不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders/{id}/permissions [put]
+func (h *FolderHandler) SetPermission(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req FolderPermissionRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	permission, err := h.folders.SetPermission(c.Request.Context(), userID, c.GetString("user_role"),
+		id, req.UserID, repository.FolderPermissionLevel(req.Level))
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, permission)
+}
+
+// RemovePermission 撤销文件夹权限
+// @Summary 撤销文件夹权限
+// @Description 撤销用户在该文件夹上的直接授权，需要该文件夹的管理权限
+// @Tags 保存查询
+// @Security BearerAuth
+// @Param id path int true "文件夹ID"
+// @Param user_id path int true "用户ID"
+// @Success 204 "撤销成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "授权不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/folders/{id}/permissions/{user_id} [delete]
+func (h *FolderHandler) RemovePermission(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+	targetUserID, ok := h.pathID(c, "user_id")
+	if !ok {
+		return
+	}
+
+	if err := h.folders.RemovePermission(c.Request.Context(), userID, c.GetString("user_role"), id, targetUserID); err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CreateSavedQuery 保存查询
+// @Summary 保存查询
+// @Description 在根目录或指定文件夹中保存查询或报表，需要文件夹的编辑权限
+// @Tags 保存查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SavedQueryRequest true "保存查询"
+// @Success 201 {object} repository.SavedQuery "保存成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "文件夹不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/saved-queries [post]
+func (h *FolderHandler) CreateSavedQuery(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req SavedQueryRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	query := req.toSavedQuery()
+	query.FolderID = req.FolderID
+	if err := h.folders.CreateSavedQuery(c.Request.Context(), userID, c.GetString("user_role"), query); err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, query)
+}
+
+// GetSavedQuery 保存查询详情
+// @Summary 保存查询详情
+// @Description 获取保存查询及其所在文件夹的面包屑，需要文件夹的查看权限
+// @Tags 保存查询
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "保存查询ID"
+// @Param If-None-Match header string false "上次响应的ETag"
+// @Success 200 {object} SavedQueryResponse "获取成功"
+// @Success 304 {string} string "保存查询未变化"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "保存查询不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/saved-queries/{id} [get]
+func (h *FolderHandler) GetSavedQuery(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	query, breadcrumbs, err := h.folders.GetSavedQuery(c.Request.Context(), userID, c.GetString("user_role"), id)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	// 面包屑不带更新时间，按内容参与计算，文件夹改名或移动后ETag随之变化
+	if checkNotModified(c, computeETag([]versionedRecord{{ID: query.ID, UpdateTime: query.UpdateTime}}, breadcrumbs)) {
+		return
+	}
+	c.JSON(http.StatusOK, &SavedQueryResponse{SavedQuery: query, Breadcrumbs: breadcrumbs})
+}
+
+// UpdateSavedQuery 修改保存查询
+// @Summary 修改保存查询
+// @Description 修改保存查询的名称、描述、问题、SQL与连接，所有者或拥有文件夹编辑权限的用户可以修改；移动请使用move接口
+// @Tags 保存查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "保存查询ID"
+// @Param request body SavedQueryRequest true "保存查询"
+// @Success 200 {object} repository.SavedQuery "修改成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权修改该保存查询"
+// @Failure 404 {object} ErrorResponse "保存查询不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/saved-queries/{id} [put]
+func (h *FolderHandler) UpdateSavedQuery(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req SavedQueryRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	update := req.toSavedQuery()
+	update.ID = id
+	query, err := h.folders.UpdateSavedQuery(c.Request.Context(), userID, c.GetString("user_role"), update)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, query)
+}
+
+// DeleteSavedQuery 删除保存查询
+// @Summary 删除保存查询
+// @Description 删除保存查询，所有者或拥有文件夹编辑权限的用户可以删除
+// @Tags 保存查询
+// @Security BearerAuth
+// @Param id path int true "保存查询ID"
+// @Success 204 "删除成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权删除该保存查询"
+// @Failure 404 {object} ErrorResponse "保存查询不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/saved-queries/{id} [delete]
+func (h *FolderHandler) DeleteSavedQuery(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.folders.DeleteSavedQuery(c.Request.Context(), userID, c.GetString("user_role"), id); err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MoveSavedQuery 移动保存查询
+// @Summary 移动保存查询
+// @Description 将保存查询移动到另一个文件夹或根目录，需要能修改该保存查询并拥有目标文件夹的编辑权限
+// @Tags 保存查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "保存查询ID"
+// @Param request body MoveSavedQueryRequest true "目标文件夹"
+// @Success 200 {object} repository.SavedQuery "移动成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "保存查询或文件夹不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/saved-queries/{id}/move [post]
+func (h *FolderHandler) MoveSavedQuery(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req MoveSavedQueryRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	query, err := h.folders.MoveSavedQuery(c.Request.Context(), userID, c.GetString("user_role"), id, req.FolderID)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, query)
+}
+
+// BatchMoveSavedQueries 批量移动保存查询
+// @Summary 批量移动保存查询
+// @Description 将多条保存查询移动到同一个文件夹或根目录，需要目标文件夹的编辑权限；逐条检查能否修改，不存在的条目标记为NOT_FOUND，无权修改的标记为FOLDER_PERMISSION_DENIED
+// @Tags 保存查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchMoveRequest true "保存查询ID列表与目标文件夹"
+// @Success 200 {object} BatchResponse "处理完成，可能部分失败"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "目标文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "目标文件夹不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/saved-queries/batch-move [post]
+func (h *FolderHandler) BatchMoveSavedQueries(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req BatchMoveRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	ids := uniqueIDs(req.IDs)
+	failures, err := h.folders.BatchMoveSavedQueries(c.Request.Context(), userID, c.GetString("user_role"), ids, req.FolderID)
+	if err != nil {
+		h.respondFolderError(c, err)
+		return
+	}
+
+	results := make(map[int64]BatchItemResult, len(failures))
+	for id, failure := range failures {
+		if errors.Is(failure, repository.ErrPermissionDenied) {
+			results[id] = BatchItemResult{Code: "FOLDER_PERMISSION_DENIED", Message: "无权修改该保存查询"}
+		} else {
+			results[id] = BatchItemResult{Code: "NOT_FOUND", Message: "保存查询不存在"}
+		}
+	}
+	c.JSON(http.StatusOK, newBatchFailuresResponse(ids, results))
+}
+
+// toSavedQuery 转换为保存查询模型
+func (r *SavedQueryRequest) toSavedQuery() *repository.SavedQuery {
+	return &repository.SavedQuery{
+		Name:            r.Name,
+		Description:     r.Description,
+		NaturalQuery:    r.NaturalQuery,
+		SQL:             r.SQL,
+		ConnectionID:    r.ConnectionID,
+		DashboardBacked: r.DashboardBacked,
+		CacheTTLSeconds: r.CacheTTLSeconds,
+	}
+}
+
+// pathID 解析路径中的ID参数，格式错误时直接写出400响应
+func (h *FolderHandler) pathID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_ID", "ID格式错误"))
+		return 0, false
+	}
+	return id, true
+}
+
+// bindJSON 绑定请求体，失败时直接写出400响应
+func (h *FolderHandler) bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// respondFolderError 将文件夹服务错误映射为HTTP响应
+func (h *FolderHandler) respondFolderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, NewErrorResponse("FOLDER_PERMISSION_DENIED", "文件夹权限不足"))
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_FOLDER_OPERATION",
+			Message: "文件夹操作无效",
+			Details: err.Error(),
+		})
+	case errors.Is(err, repository.ErrDuplicateEntry):
+		c.JSON(http.StatusConflict, NewErrorResponse("FOLDER_NAME_CONFLICT", "同名文件夹已存在"))
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("NOT_FOUND", "文件夹或保存查询不存在"))
+	default:
+		h.logger.Error("Folder operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("FOLDER_ERROR", "文件夹操作失败"))
+	}
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// stubFolderRepository 固定的文件夹树：/1/（销售，仅用户8可查看）/2/（Q1），/3/属于其他工作空间
+type stubFolderRepository struct {
+	repository.FolderRepository
+	folders map[int64]*repository.QueryFolder
+}
+
+func newStubFolderRepository() *stubFolderRepository {
+	owner := int64(7)
+	sales := &repository.QueryFolder{BaseModel: repository.BaseModel{ID: 1, CreateBy: &owner}, WorkspaceID: 1, Name: "销售", Path: "/1/"}
+	q1 := &repository.QueryFolder{BaseModel: repository.BaseModel{ID: 2, CreateBy: &owner}, WorkspaceID: 1, ParentID: &sales.ID, Name: "Q1", Path: "/1/2/"}
+	other := &repository.QueryFolder{BaseModel: repository.BaseModel{ID: 3, CreateBy: &owner}, WorkspaceID: 2, Name: "其他", Path: "/3/"}
+	return &stubFolderRepository{folders: map[int64]*repository.QueryFolder{1: sales, 2: q1, 3: other}}
+}
+
+func (s *stubFolderRepository) Create(ctx context.Context, folder *repository.QueryFolder, parent *repository.QueryFolder) error {
+	return repository.ErrDuplicateEntry
+}
+
+func (s *stubFolderRepository) GetByID(ctx context.Context, id int64) (*repository.QueryFolder, error) {
+	if f, ok := s.folders[id]; ok {
+		return f, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (s *stubFolderRepository) GetByIDs(ctx context.Context, ids []int64) ([]*repository.QueryFolder, error) {
+	var folders []*repository.QueryFolder
+	for _, id := range ids {
+		folders = append(folders, s.folders[id])
+	}
+	return folders, nil
+}
+
+func (s *stubFolderRepository) ListChildren(ctx context.Context, workspaceID int64, parentID *int64) ([]*repository.QueryFolder, error) {
+	return nil, nil
+}
+
+func (s *stubFolderRepository) ListPermissions(ctx context.Context, folderIDs []int64) ([]*repository.FolderPermission, error) {
+	for _, id := range folderIDs {
+		if id == 1 {
+			return []*repository.FolderPermission{{FolderID: 1, UserID: 8, Level: "view"}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// stubSavedQueryRepository 每个文件夹返回三条保存查询
+type stubSavedQueryRepository struct {
+	repository.SavedQueryRepository
+}
+
+func (s *stubSavedQueryRepository) ListByFolder(ctx context.Context, workspaceID int64, folderID *int64, limit, offset int) ([]*repository.SavedQuery, error) {
+	queries := []*repository.SavedQuery{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if offset >= len(queries) {
+		return nil, nil
+	}
+	queries = queries[offset:]
+	if len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries, nil
+}
+
+// GetByID 10号位于Q1、属于用户7，11号位于根目录、属于用户8
+func (s *stubSavedQueryRepository) GetByID(ctx context.Context, id int64) (*repository.SavedQuery, error) {
+	q1 := int64(2)
+	switch id {
+	case 10:
+		return &repository.SavedQuery{BaseModel: repository.BaseModel{ID: id}, WorkspaceID: 1, FolderID: &q1, OwnerID: 7}, nil
+	case 11:
+		return &repository.SavedQuery{BaseModel: repository.BaseModel{ID: id}, WorkspaceID: 1, OwnerID: 8}, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (s *stubSavedQueryRepository) Move(ctx context.Context, id int64, folderID *int64, updateBy int64) error {
+	return nil
+}
+
+func newFolderTestRouter(t *testing.T, userID int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = 1
+	svc := service.NewFolderService(newStubFolderRepository(), &stubSavedQueryRepository{},
+		&stubWorkspaceRepository{workspace: workspace}, zaptest.NewLogger(t))
+	h := NewFolderHandler(svc, zaptest.NewLogger(t))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("user_role", "user")
+	})
+	r.GET("/folders/:id", h.ListFolderContents)
+	r.POST("/folders", h.CreateFolder)
+	r.POST("/saved-queries/batch-move", h.BatchMoveSavedQueries)
+	r.GET("/saved-queries/:id", h.GetSavedQuery)
+	return r
+}
+
+func TestFolderHandler_GetSavedQueryETag(t *testing.T) {
+	r := newFolderTestRouter(t, 8)
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("/saved-queries/10", "")
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	unchanged := get("/saved-queries/10", etag)
+	assert.Equal(t, http.StatusNotModified, unchanged.Code)
+	assert.Empty(t, unchanged.Body.String())
+	assert.Equal(t, etag, unchanged.Header().Get("ETag"))
+
+	// 其他保存查询的ETag不会命中
+	other := get("/saved-queries/11", etag)
+	assert.Equal(t, http.StatusOK, other.Code)
+	assert.NotEqual(t, etag, other.Header().Get("ETag"))
+}
+
+func TestFolderHandler_ListContents(t *testing.T) {
+	r := newFolderTestRouter(t, 8)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/folders/2?limit=2", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp FolderContentsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []service.Breadcrumb{{ID: 1, Name: "销售"}, {ID: 2, Name: "Q1"}}, resp.Breadcrumbs)
+	assert.Equal(t, "view", resp.Permission)
+	assert.Len(t, resp.SavedQueries, 2)
+	require.True(t, resp.HasMore)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/folders/2?limit=2&cursor="+resp.NextCursor, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = FolderContentsResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.SavedQueries, 1)
+	assert.False(t, resp.HasMore)
+}
+
+func TestFolderHandler_Errors(t *testing.T) {
+	get := func(r *gin.Engine, path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	// 未被授权的成员无法访问，其他工作空间的文件夹视为不存在
+	r := newFolderTestRouter(t, 9)
+	assert.Equal(t, http.StatusForbidden, get(r, "/folders/2"))
+	assert.Equal(t, http.StatusNotFound, get(r, "/folders/3"))
+	assert.Equal(t, http.StatusBadRequest, get(r, "/folders/abc"))
+
+	// view权限不能创建子文件夹，同名冲突返回409
+	create := func(r *gin.Engine, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/folders", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusForbidden, create(newFolderTestRouter(t, 8), `{"name":"Q2","parent_id":1}`))
+	assert.Equal(t, http.StatusConflict, create(newFolderTestRouter(t, 7), `{"name":"Q1","parent_id":1}`))
+	assert.Equal(t, http.StatusBadRequest, create(r, `{"name":""}`))
+}
+
+func TestFolderHandler_BatchMoveSavedQueries(t *testing.T) {
+	r := newFolderTestRouter(t, 8)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/saved-queries/batch-move", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 只能查看的文件夹中他人的查询无权移动，不存在的查询标记为NOT_FOUND
+	w := post(`{"ids":[10,11,12]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response BatchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Succeeded)
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, "FOLDER_PERMISSION_DENIED", response.Results[0].Code)
+	assert.True(t, response.Results[1].Success)
+	assert.Equal(t, "NOT_FOUND", response.Results[2].Code)
+
+	// 没有目标文件夹的编辑权限时整体拒绝
+	assert.Equal(t, http.StatusForbidden, post(`{"ids":[11],"folder_id":1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`{"ids":[]}`).Code)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,12 +14,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 
 	"chat2sql-go/internal/auth"
 	"chat2sql-go/internal/repository"
 	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
 )
 
 // MockSQLExecutor Mock SQL执行器
@@ -35,6 +38,11 @@ func (m *MockSQLExecutor) ExecuteQuery(ctx context.Context, sql string, connecti
 	return result.(*service.QueryResult), args.Error(1)
 }
 
+func (m *MockSQLExecutor) StreamQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64, w service.RowWriter) (int64, bool, error) {
+	args := m.Called(ctx, sql, connection, maxRows, w)
+	return args.Get(0).(int64), args.Bool(1), args.Error(2)
+}
+
 // MockAuthMiddleware Mock认证中间件
 type MockAuthMiddleware struct {
 	shouldAuthenticate bool
@@ -55,8 +63,9 @@ func (m *MockAuthMiddleware) JWTAuth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-		// 设置用户ID到上下文
+		// 设置用户ID与角色到上下文，与JWT中间件一致
 		c.Set("user_id", m.userID)
+		c.Set("user_role", string(repository.RoleUser))
 		c.Next()
 	}
 }
@@ -90,6 +99,15 @@ func (m *MockQueryHistoryRepository) Delete(ctx context.Context, id int64) error
 	return args.Error(0)
 }
 
+func (m *MockQueryHistoryRepository) ListPage(ctx context.Context, filter repository.QueryHistoryFilter, after *repository.QueryHistoryKeyset, limit, offset int) ([]*repository.QueryHistory, error) {
+	args := m.Called(ctx, filter, after, limit, offset)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.([]*repository.QueryHistory), args.Error(1)
+}
+
 func (m *MockQueryHistoryRepository) ListByUser(ctx context.Context, userID int64, limit, offset int) ([]*repository.QueryHistory, error) {
 	args := m.Called(ctx, userID, limit, offset)
 	result := args.Get(0)
@@ -136,6 +154,15 @@ func (m *MockQueryHistoryRepository) CountByStatus(ctx context.Context, status r
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockQueryHistoryRepository) GetExecutionStatsSince(ctx context.Context, userID int64, since time.Time) (*repository.QueryExecutionStats, error) {
+	args := m.Called(ctx, userID, since)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.(*repository.QueryExecutionStats), args.Error(1)
+}
+
 func (m *MockQueryHistoryRepository) GetExecutionStats(ctx context.Context, userID int64, days int) (*repository.QueryExecutionStats, error) {
 	args := m.Called(ctx, userID, days)
 	result := args.Get(0)
@@ -154,6 +181,24 @@ func (m *MockQueryHistoryRepository) GetPopularQueries(ctx context.Context, limi
 	return result.([]*repository.PopularQuery), args.Error(1)
 }
 
+func (m *MockQueryHistoryRepository) GetConnectionUsage(ctx context.Context, since time.Time, location string) ([]*repository.ConnectionUsage, error) {
+	args := m.Called(ctx, since, location)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.([]*repository.ConnectionUsage), args.Error(1)
+}
+
+func (m *MockQueryHistoryRepository) ListFailuresByConnection(ctx context.Context, connectionID int64, since time.Time, limit int) ([]*repository.QueryHistory, error) {
+	args := m.Called(ctx, connectionID, since, limit)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.([]*repository.QueryHistory), args.Error(1)
+}
+
 func (m *MockQueryHistoryRepository) GetSlowQueries(ctx context.Context, minExecutionTime int32, limit int) ([]*repository.QueryHistory, error) {
 	args := m.Called(ctx, minExecutionTime, limit)
 	result := args.Get(0)
@@ -186,6 +231,24 @@ func (m *MockQueryHistoryRepository) BatchUpdateStatus(ctx context.Context, quer
 	return args.Error(0)
 }
 
+func (m *MockQueryHistoryRepository) BatchDelete(ctx context.Context, userID int64, queryIDs []int64) ([]int64, error) {
+	args := m.Called(ctx, userID, queryIDs)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.([]int64), args.Error(1)
+}
+
+func (m *MockQueryHistoryRepository) BatchRetag(ctx context.Context, userID int64, queryIDs []int64, tags *repository.ChargebackTags) ([]int64, error) {
+	args := m.Called(ctx, userID, queryIDs, tags)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.([]int64), args.Error(1)
+}
+
 func (m *MockQueryHistoryRepository) CleanupOldQueries(ctx context.Context, beforeDate time.Time) (int64, error) {
 	args := m.Called(ctx, beforeDate)
 	return args.Get(0).(int64), args.Error(1)
@@ -281,11 +344,35 @@ func (m *MockConnectionRepository) UpdateLastTested(ctx context.Context, connect
 	return args.Error(0)
 }
 
+func (m *MockConnectionRepository) UpdateWriteMode(ctx context.Context, connectionID int64, enabled bool, updateBy int64) error {
+	args := m.Called(ctx, connectionID, enabled, updateBy)
+	return args.Error(0)
+}
+
+func (m *MockConnectionRepository) UpdateResultLimits(ctx context.Context, connectionID int64, maxRows, maxResultMB *int32, updateBy int64) error {
+	args := m.Called(ctx, connectionID, maxRows, maxResultMB, updateBy)
+	return args.Error(0)
+}
+
 func (m *MockConnectionRepository) BatchUpdateStatus(ctx context.Context, connectionIDs []int64, status repository.ConnectionStatus) error {
 	args := m.Called(ctx, connectionIDs, status)
 	return args.Error(0)
 }
 
+func (m *MockConnectionRepository) ListUnused(ctx context.Context, since time.Time) ([]*repository.UnusedConnection, error) {
+	args := m.Called(ctx, since)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.([]*repository.UnusedConnection), args.Error(1)
+}
+
+func (m *MockConnectionRepository) MarkUnusedNotified(ctx context.Context, connectionID int64, notifiedAt time.Time) error {
+	args := m.Called(ctx, connectionID, notifiedAt)
+	return args.Error(0)
+}
+
 func (m *MockConnectionRepository) ExistsByUserAndName(ctx context.Context, userID int64, name string) (bool, error) {
 	args := m.Called(ctx, userID, name)
 	return args.Bool(0), args.Error(1)
@@ -717,16 +804,7 @@ func TestSQLHandler_ExecuteSQL_Success(t *testing.T) {
 	router := suite.setupTestRouter(true, 1)
 	
 	// 准备Mock响应
-	mockConnection := &repository.DatabaseConnection{
-		BaseModel: repository.BaseModel{
-			ID: 1,
-		},
-		UserID:       1,
-		Name:         "test_db",
-		Host:         "localhost",
-		Port:         5432,
-		DatabaseName: "testdb",
-	}
+	mockConnection := testutil.NewConnection(1, 1, testutil.WithConnectionName("test_db"))
 	
 	mockQueryResult := &service.QueryResult{
 		ExecutionTime: 150,
@@ -774,6 +852,91 @@ func TestSQLHandler_ExecuteSQL_Success(t *testing.T) {
 	suite.mockQueryRepo.AssertExpectations(t)
 }
 
+// TestSQLHandler_ExecuteSQL_RenderedTable 测试按format返回渲染好的结果表格
+func TestSQLHandler_ExecuteSQL_RenderedTable(t *testing.T) {
+	suite := &HandlerIntegrationTestSuite{}
+	router := suite.setupTestRouter(true, 1)
+	
+	mockConnection := testutil.NewConnection(1, 1, testutil.WithConnectionName("test_db"))
+	mockQueryResult := &service.QueryResult{
+		Columns:  []string{"name", "orders"},
+		RowCount: 2,
+		Status:   string(repository.QuerySuccess),
+		Rows: []map[string]any{
+			{"name": "John", "orders": 12},
+			{"name": "Jane", "orders": 3},
+		},
+	}
+	
+	suite.mockConnectionRepo.On("GetByID", mock.Anything, int64(1)).Return(mockConnection, nil)
+	suite.mockSQLExecutor.On("ExecuteQuery", mock.Anything, mock.Anything, mockConnection).Return(mockQueryResult, nil)
+	suite.mockQueryRepo.On("Create", mock.Anything, mock.AnythingOfType("*repository.QueryHistory")).Return(nil)
+	suite.mockQueryRepo.On("Update", mock.Anything, mock.AnythingOfType("*repository.QueryHistory")).Return(nil)
+	
+	execute := func(format string) SQLExecutionResult {
+		jsonData, _ := json.Marshal(ExecuteSQLRequest{SQL: "SELECT name, orders FROM users", ConnectionID: 1, Format: format})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/sql/execute", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	
+		var response SQLExecutionResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	
+	response := execute("markdown")
+	assert.Equal(t, "| name | orders |\n| ---- | -----: |\n| John |     12 |\n| Jane |      3 |\n", response.Rendered)
+	assert.Len(t, response.Data, 2, "渲染表格不影响原始数据")
+	
+	assert.Empty(t, execute("").Rendered, "未指定format时不渲染")
+}
+
+// TestSQLHandler_ExecuteSQL_Guardrails 测试响应中返回实际生效的防护措施
+func TestSQLHandler_ExecuteSQL_Guardrails(t *testing.T) {
+	suite := &HandlerIntegrationTestSuite{}
+	router := suite.setupTestRouter(true, 1)
+	
+	mockConnection := testutil.NewConnection(1, 1, testutil.WithConnectionName("test_db"))
+	truncated := &service.QueryResult{
+		Columns:  []string{"id"},
+		RowCount: 1,
+		Status:   string(repository.QuerySuccess),
+		Rows:     []map[string]any{{"id": 1}},
+		Warnings: []string{"查询结果超过最大行数限制(1行)，已截断显示"},
+		Guardrails: []service.Guardrail{
+			{Type: service.GuardrailTimeout, Limit: 30000},
+			{Type: service.GuardrailRowLimit, Limit: 1},
+		},
+	}
+	
+	suite.mockConnectionRepo.On("GetByID", mock.Anything, int64(1)).Return(mockConnection, nil)
+	suite.mockSQLExecutor.On("ExecuteQuery", mock.Anything, "SELECT id FROM users", mockConnection).Return(truncated, nil)
+	suite.mockSQLExecutor.On("ExecuteQuery", mock.Anything, "SELECT 1", mockConnection).
+		Return(&service.QueryResult{Status: string(repository.QuerySuccess)}, nil)
+	suite.mockQueryRepo.On("Create", mock.Anything, mock.AnythingOfType("*repository.QueryHistory")).Return(nil)
+	suite.mockQueryRepo.On("Update", mock.Anything, mock.AnythingOfType("*repository.QueryHistory")).Return(nil)
+	
+	execute := func(sql string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(ExecuteSQLRequest{SQL: sql, ConnectionID: 1})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/sql/execute", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+	
+	var response SQLExecutionResult
+	require.NoError(t, json.Unmarshal(execute("SELECT id FROM users").Body.Bytes(), &response))
+	assert.Equal(t, truncated.Guardrails, response.Guardrails)
+	assert.Equal(t, truncated.Warnings, response.Warnings)
+	
+	// 没有防护措施时也返回空列表
+	assert.Contains(t, execute("SELECT 1").Body.String(), `"guardrails":[]`)
+}
+
 // TestSQLHandler_ExecuteSQL_Unauthorized 测试未授权访问
 func TestSQLHandler_ExecuteSQL_Unauthorized(t *testing.T) {
 	suite := &HandlerIntegrationTestSuite{}
@@ -823,13 +986,7 @@ func TestSQLHandler_ExecuteSQL_InvalidConnectionAccess(t *testing.T) {
 	router := suite.setupTestRouter(true, 1)
 	
 	// 模拟连接属于其他用户
-	mockConnection := &repository.DatabaseConnection{
-		BaseModel: repository.BaseModel{
-			ID: 1,
-		},
-		UserID: 2, // 不同的用户ID
-		Name:   "test_db",
-	}
+	mockConnection := testutil.NewConnection(1, 2) // 不同的用户ID
 	
 	suite.mockConnectionRepo.On("GetByID", mock.Anything, int64(1)).Return(mockConnection, nil)
 	
@@ -859,32 +1016,15 @@ func TestSQLHandler_GetQueryHistory_Success(t *testing.T) {
 	suite := &HandlerIntegrationTestSuite{}
 	router := suite.setupTestRouter(true, 1)
 	
-	now := time.Now()
 	mockQueries := []*repository.QueryHistory{
-		{
-			BaseModel: repository.BaseModel{
-				ID:         1,
-				CreateTime: now,
-			},
-			UserID:       1,
-			NaturalQuery: "获取用户列表",
-			GeneratedSQL: "SELECT * FROM users",
-			Status:       string(repository.QuerySuccess),
-		},
-		{
-			BaseModel: repository.BaseModel{
-				ID:         2,
-				CreateTime: now.Add(-time.Hour),
-			},
-			UserID:       1,
-			NaturalQuery: "获取订单统计",
-			GeneratedSQL: "SELECT COUNT(*) FROM orders",
-			Status:       string(repository.QuerySuccess),
-		},
+		testutil.NewQueryHistory(1, 1, testutil.WithQuery("获取用户列表", "SELECT * FROM users")),
+		testutil.NewQueryHistory(2, 1, testutil.WithQuery("获取订单统计", "SELECT COUNT(*) FROM orders")),
 	}
 	
-	suite.mockQueryRepo.On("ListByUser", mock.Anything, int64(1), 20, 0).Return(mockQueries, nil)
-	suite.mockQueryRepo.On("CountByUser", mock.Anything, int64(1)).Return(int64(2), nil)
+	// 多取一条判断是否还有下一页；v1响应始终包含总数
+	noKeyset := (*repository.QueryHistoryKeyset)(nil)
+	suite.mockQueryRepo.On("ListPage", mock.Anything, repository.QueryHistoryFilter{UserID: 1}, noKeyset, 21, 0).Return(mockQueries, nil)
+	suite.mockQueryRepo.On("CountByUser", mock.Anything, int64(1)).Return(int64(2), nil).Once()
 	
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest(http.MethodGet, "/api/v1/sql/history", nil)
@@ -896,9 +1036,22 @@ func TestSQLHandler_GetQueryHistory_Success(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.Len(t, response.Queries, 2)
-	assert.Equal(t, int64(2), response.Total)
+	require.NotNil(t, response.Total)
+	assert.Equal(t, int64(2), *response.Total)
 	assert.Equal(t, 1, response.Page)
 	assert.False(t, response.HasMore)
+	assert.Empty(t, response.NextCursor)
+	
+	// v2默认不执行COUNT查询
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v2/sql/history", nil)
+	router.ServeHTTP(w, req)
+	
+	assert.Equal(t, http.StatusOK, w.Code)
+	var v2 QueryHistoryResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &v2))
+	assert.Len(t, v2.Queries, 2)
+	assert.Nil(t, v2.Total)
 	
 	suite.mockQueryRepo.AssertExpectations(t)
 }
@@ -908,21 +1061,12 @@ func TestSQLHandler_GetQueryHistory_WithSearchKeyword(t *testing.T) {
 	suite := &HandlerIntegrationTestSuite{}
 	router := suite.setupTestRouter(true, 1)
 	
-	now := time.Now()
 	mockQueries := []*repository.QueryHistory{
-		{
-			BaseModel: repository.BaseModel{
-				ID:         1,
-				CreateTime: now,
-			},
-			UserID:       1,
-			NaturalQuery: "获取用户列表",
-			GeneratedSQL: "SELECT * FROM users",
-			Status:       string(repository.QuerySuccess),
-		},
+		testutil.NewQueryHistory(1, 1, testutil.WithQuery("获取用户列表", "SELECT * FROM users")),
 	}
 	
-	suite.mockQueryRepo.On("SearchByNaturalQuery", mock.Anything, int64(1), "用户", 20, 0).Return(mockQueries, nil)
+	filter := repository.QueryHistoryFilter{UserID: 1, Keyword: "用户"}
+	suite.mockQueryRepo.On("ListPage", mock.Anything, filter, (*repository.QueryHistoryKeyset)(nil), 21, 0).Return(mockQueries, nil)
 	suite.mockQueryRepo.On("CountByUser", mock.Anything, int64(1)).Return(int64(1), nil)
 	
 	w := httptest.NewRecorder()
@@ -945,17 +1089,7 @@ func TestSQLHandler_GetQueryById_Success(t *testing.T) {
 	suite := &HandlerIntegrationTestSuite{}
 	router := suite.setupTestRouter(true, 1)
 	
-	now := time.Now()
-	mockQuery := &repository.QueryHistory{
-		BaseModel: repository.BaseModel{
-			ID:         123,
-			CreateTime: now,
-		},
-		UserID:       1,
-		NaturalQuery: "获取用户详情",
-		GeneratedSQL: "SELECT * FROM users WHERE id = 1",
-		Status:       string(repository.QuerySuccess),
-	}
+	mockQuery := testutil.NewQueryHistory(123, 1, testutil.WithQuery("获取用户详情", "SELECT * FROM users WHERE id = 1"))
 	
 	suite.mockQueryRepo.On("GetByID", mock.Anything, int64(123)).Return(mockQuery, nil)
 	
@@ -981,14 +1115,7 @@ func TestSQLHandler_GetQueryById_AccessDenied(t *testing.T) {
 	router := suite.setupTestRouter(true, 1)
 	
 	// 模拟查询属于其他用户
-	mockQuery := &repository.QueryHistory{
-		BaseModel: repository.BaseModel{
-			ID: 123,
-		},
-		UserID:       2, // 不同的用户ID
-		NaturalQuery: "获取用户详情",
-		GeneratedSQL: "SELECT * FROM users WHERE id = 1",
-	}
+	mockQuery := testutil.NewQueryHistory(123, 2, testutil.WithQuery("获取用户详情", "SELECT * FROM users WHERE id = 1")) // 不同的用户ID
 	
 	suite.mockQueryRepo.On("GetByID", mock.Anything, int64(123)).Return(mockQuery, nil)
 	
@@ -1055,6 +1182,64 @@ func TestSQLHandler_ValidateSQL_InvalidSQL(t *testing.T) {
 	assert.NotEmpty(t, response.Errors)
 }
 
+// TestSQLHandler_ValidateSQL_Lexical 测试按词法分析验证SQL
+func TestSQLHandler_ValidateSQL_Lexical(t *testing.T) {
+	suite := &HandlerIntegrationTestSuite{}
+	router := suite.setupTestRouter(true, 1)
+	
+	tests := []struct {
+		sql       string
+		isValid   bool
+		queryType string
+	}{
+		{"SELECT id, updated_at FROM users WHERE note <> 'DROP'", true, "SELECT"},
+		{"-- 活跃用户\nWITH active AS (SELECT id FROM users) SELECT count(*) FROM active", true, "SELECT"},
+		{"WITH gone AS (DELETE FROM users RETURNING id) SELECT * FROM gone", false, "SELECT"},
+		{"SELECT 1; DROP TABLE users", false, "SELECT"},
+		{"/* SELECT */ DELETE FROM users", false, "DELETE"},
+	}
+	for _, tt := range tests {
+		jsonData, _ := json.Marshal(ValidateSQLRequest{SQL: tt.sql})
+		
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/sql/validate", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		
+		assert.Equal(t, http.StatusOK, w.Code)
+		
+		var response SQLValidationResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, tt.isValid, response.IsValid, tt.sql)
+		assert.Equal(t, tt.isValid, response.IsReadOnly, tt.sql)
+		assert.Equal(t, tt.queryType, response.QueryType, tt.sql)
+	}
+}
+
+// TestSQLHandler_ExecuteSQL_CTEWriteForbidden 测试CTE中的写操作被拒绝
+func TestSQLHandler_ExecuteSQL_CTEWriteForbidden(t *testing.T) {
+	suite := &HandlerIntegrationTestSuite{}
+	router := suite.setupTestRouter(true, 1)
+	
+	requestBody := ExecuteSQLRequest{
+		SQL:          "WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone",
+		ConnectionID: 1,
+	}
+	jsonData, _ := json.Marshal(requestBody)
+	
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/sql/execute", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	
+	var response ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "SQL_FORBIDDEN", response.Code)
+	assert.Contains(t, response.Message, "DELETE")
+}
+
 // TestHealthEndpoints 测试健康检查端点
 func TestHealthEndpoints(t *testing.T) {
 	suite := &HandlerIntegrationTestSuite{}
@@ -1243,30 +1428,25 @@ func TestSQLHandler_QueryHistoryPagination(t *testing.T) {
 	suite := &HandlerIntegrationTestSuite{}
 	router := suite.setupTestRouter(true, 1)
 	
-	mockQueries := []*repository.QueryHistory{
-		{
-			BaseModel: repository.BaseModel{
-				ID:         1,
-				CreateTime: time.Now(),
-			},
-			UserID:       1,
-			NaturalQuery: "Query 1",
-		},
-		{
-			BaseModel: repository.BaseModel{
-				ID:         2,
-				CreateTime: time.Now(),
-			},
-			UserID:       1,
-			NaturalQuery: "Query 2",
-		},
+	// 按创建时间倒序，同一时间写入的记录按ID倒序
+	base := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	page := func(from, n int) []*repository.QueryHistory {
+		queries := make([]*repository.QueryHistory, n)
+		for i := range queries {
+			id := int64(100 - from - i)
+			queries[i] = testutil.NewQueryHistory(id, 1, testutil.WithQuery(fmt.Sprintf("Query %d", id), fmt.Sprintf("SELECT %d", id)))
+			queries[i].CreateTime = base.Add(-time.Duration((from+i)/2) * time.Minute)
+		}
+		return queries
 	}
+	filter := repository.QueryHistoryFilter{UserID: 1, Status: repository.QuerySuccess}
 	
-	suite.mockQueryRepo.On("ListByUser", mock.Anything, int64(1), 10, 10).Return(mockQueries, nil)
+	// 旧客户端的offset参数仍然有效；多取的一条表示还有下一页
+	suite.mockQueryRepo.On("ListPage", mock.Anything, filter, (*repository.QueryHistoryKeyset)(nil), 11, 10).Return(page(10, 11), nil)
 	suite.mockQueryRepo.On("CountByUser", mock.Anything, int64(1)).Return(int64(25), nil)
 	
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest(http.MethodGet, "/api/v1/sql/history?limit=10&offset=10", nil)
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/sql/history?limit=10&offset=10&status=success", nil)
 	router.ServeHTTP(w, req)
 	
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -1274,9 +1454,36 @@ func TestSQLHandler_QueryHistoryPagination(t *testing.T) {
 	var response QueryHistoryResponse
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
+	assert.Len(t, response.Queries, 10)
 	assert.Equal(t, 2, response.Page)
-	assert.Equal(t, int64(25), response.Total)
+	require.NotNil(t, response.Total, "v1默认返回总数")
+	assert.Equal(t, int64(25), *response.Total)
 	assert.True(t, response.HasMore)
+	require.NotEmpty(t, response.NextCursor)
+	
+	// 游标记录本页最后一条的(create_time, id)，之后写入的新记录不影响下一页
+	last := page(10, 10)[9]
+	after := &repository.QueryHistoryKeyset{CreateTime: last.CreateTime, ID: last.ID}
+	suite.mockQueryRepo.On("ListPage", mock.Anything, filter, after, 11, 0).Return(page(20, 5), nil)
+	
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/sql/history?limit=10&status=success&cursor="+response.NextCursor, nil)
+	router.ServeHTTP(w, req)
+	
+	assert.Equal(t, http.StatusOK, w.Code)
+	var lastPage QueryHistoryResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &lastPage))
+	assert.Len(t, lastPage.Queries, 5)
+	assert.Equal(t, 3, lastPage.Page)
+	assert.False(t, lastPage.HasMore)
+	assert.Empty(t, lastPage.NextCursor)
+	
+	// 游标不能用于其他筛选条件
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/api/v1/sql/history?limit=10&keyword=x&cursor="+response.NextCursor, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_CURSOR")
 	
 	suite.mockQueryRepo.AssertExpectations(t)
 }
@@ -1287,13 +1494,7 @@ func BenchmarkSQLHandler_ExecuteSQL(b *testing.B) {
 	router := suite.setupTestRouter(true, 1)
 	
 	// 设置Mock响应
-	mockConnection := &repository.DatabaseConnection{
-		BaseModel: repository.BaseModel{
-			ID: 1,
-		},
-		UserID: 1,
-		Name:   "bench_db",
-	}
+	mockConnection := testutil.NewConnection(1, 1, testutil.WithConnectionName("bench_db"))
 	mockResult := &service.QueryResult{
 		ExecutionTime: 10,
 		RowCount:     1,
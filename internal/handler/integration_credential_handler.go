@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// IntegrationCredentialHandler 第三方集成凭据处理器
+// 管理Slack、Jira、SMTP、Webhook等集成的机密，响应中只包含掩码，明文不会通过API返回
+type IntegrationCredentialHandler struct {
+	credentials *service.IntegrationCredentialService
+	logger      *zap.Logger
+}
+
+// NewIntegrationCredentialHandler 创建第三方集成凭据处理器实例
+func NewIntegrationCredentialHandler(credentials *service.IntegrationCredentialService, logger *zap.Logger) *IntegrationCredentialHandler {
+	return &IntegrationCredentialHandler{
+		credentials: credentials,
+		logger:      logger,
+	}
+}
+
+// Routes 声明集成凭据路由，全部需要admin角色
+func (h *IntegrationCredentialHandler) Routes() []RouteGroup {
+	admin := []string{string(repository.RoleAdmin)}
+	return []RouteGroup{
+		{
+			Prefix: "/admin/integrations",
+			Tag:    "admin",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.ListCredentials, Summary: "列出集成凭据", Roles: admin},
+				{Method: http.MethodPost, Path: "", Handler: h.CreateCredential, Summary: "创建集成凭据", Roles: admin},
+				{Method: http.MethodGet, Path: "/:id", Handler: h.GetCredential, Summary: "获取集成凭据", Roles: admin},
+				{Method: http.MethodPut, Path: "/:id/settings", Handler: h.UpdateSettings, Summary: "更新集成非机密配置", Roles: admin},
+				{Method: http.MethodPost, Path: "/:id/rotate", Handler: h.RotateCredential, Summary: "轮换集成机密", Roles: admin},
+				{Method: http.MethodDelete, Path: "/:id", Handler: h.DeleteCredential, Summary: "删除集成凭据", Roles: admin},
+			},
+		},
+	}
+}
+
+// CreateIntegrationCredentialRequest 创建集成凭据请求，name为空时使用default
+type CreateIntegrationCredentialRequest struct {
+	Integration string            `json:"integration" binding:"required,oneof=slack jira smtp webhook teams" example:"jira"`
+	Name        string            `json:"name" binding:"max=100" example:"default"`
+	Settings    map[string]string `json:"settings"`
+	Secret      string            `json:"secret" binding:"required" example:"ATATT3xFfGF0..."`
+}
+
+// UpdateIntegrationSettingsRequest 更新非机密配置请求，整体替换原有配置
+type UpdateIntegrationSettingsRequest struct {
+	Settings map[string]string `json:"settings"`
+}
+
+// RotateIntegrationCredentialRequest 轮换机密请求
+type RotateIntegrationCredentialRequest struct {
+	Secret string `json:"secret" binding:"required" example:"xoxb-new-token"`
+}
+
+// IntegrationCredentialListResponse 集成凭据列表
+type IntegrationCredentialListResponse struct {
+	Credentials []*repository.IntegrationCredential `json:"credentials"`
+}
+
+// ListCredentials 列出集成凭据
+// @Summary 列出集成凭据
+// @Description 列出第三方集成凭据，机密只返回掩码，可按集成类型过滤（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param integration query string false "集成类型" Enums(slack, jira, smtp, webhook, teams)
+// @Success 200 {object} IntegrationCredentialListResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "集成类型无效"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/integrations [get]
+func (h *IntegrationCredentialHandler) ListCredentials(c *gin.Context) {
+	credentials, err := h.credentials.List(c.Request.Context(), c.Query("integration"))
+	if err != nil {
+		h.respondIntegrationError(c, err)
+		return
+	}
+	if credentials == nil {
+		credentials = []*repository.IntegrationCredential{}
+	}
+	c.JSON(http.StatusOK, &IntegrationCredentialListResponse{Credentials: credentials})
+}
+
+// CreateCredential 创建集成凭据
+// @Summary 创建集成凭据
+// @Description 保存第三方集成的机密与非机密配置，机密加密存储，响应只包含掩码（需admin角色）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateIntegrationCredentialRequest true "集成凭据"
+// @Success 201 {object} repository.IntegrationCredential "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 409 {object} ErrorResponse "同名凭据已存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/integrations [post]
+func (h *IntegrationCredentialHandler) CreateCredential(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req CreateIntegrationCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	credential, err := h.credentials.Create(c.Request.Context(), req.Integration, req.Name, req.Settings, req.Secret, userID)
+	if err != nil {
+		h.respondIntegrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, credential)
+}
+
+// GetCredential 获取集成凭据
+// @Summary 获取集成凭据
+// @Description 获取单个集成凭据，机密只返回掩码（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "凭据ID"
+// @Success 200 {object} repository.IntegrationCredential "获取成功"
+// @Failure 400 {object} ErrorResponse "凭据ID格式错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "凭据不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/integrations/{id} [get]
+func (h *IntegrationCredentialHandler) GetCredential(c *gin.Context) {
+	id, ok := h.credentialID(c)
+	if !ok {
+		return
+	}
+
+	credential, err := h.credentials.Get(c.Request.Context(), id)
+	if err != nil {
+		h.respondIntegrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, credential)
+}
+
+// UpdateSettings 更新集成非机密配置
+// @Summary 更新集成非机密配置
+// @Description 整体替换集成的非机密配置，配置中不允许出现token、password等机密键（需admin角色）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "凭据ID"
+// @Param request body UpdateIntegrationSettingsRequest true "非机密配置"
+// @Success 200 {object} repository.IntegrationCredential "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "凭据不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/integrations/{id}/settings [put]
+func (h *IntegrationCredentialHandler) UpdateSettings(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.credentialID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateIntegrationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	credential, err := h.credentials.UpdateSettings(c.Request.Context(), id, req.Settings, userID)
+	if err != nil {
+		h.respondIntegrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, credential)
+}
+
+// RotateCredential 轮换集成机密
+// @Summary 轮换集成机密
+// @Description 用新机密替换旧机密并递增版本号，旧机密立即失效（需admin角色）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "凭据ID"
+// @Param request body RotateIntegrationCredentialRequest true "新机密"
+// @Success 200 {object} repository.IntegrationCredential "轮换成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "凭据不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/integrations/{id}/rotate [post]
+func (h *IntegrationCredentialHandler) RotateCredential(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.credentialID(c)
+	if !ok {
+		return
+	}
+
+	var req RotateIntegrationCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	credential, err := h.credentials.Rotate(c.Request.Context(), id, req.Secret, userID)
+	if err != nil {
+		h.respondIntegrationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, credential)
+}
+
+// DeleteCredential 删除集成凭据
+// @Summary 删除集成凭据
+// @Description 删除集成凭据并清空加密保存的机密（需admin角色）
+// @Tags 管理
+// @Security BearerAuth
+// @Param id path int true "凭据ID"
+// @Success 204 "删除成功"
+// @Failure 400 {object} ErrorResponse "凭据ID格式错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "凭据不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/integrations/{id} [delete]
+func (h *IntegrationCredentialHandler) DeleteCredential(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.credentialID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.credentials.Delete(c.Request.Context(), id, userID); err != nil {
+		h.respondIntegrationError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// credentialID 解析路径中的凭据ID，格式错误时直接写入响应
+func (h *IntegrationCredentialHandler) credentialID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_CREDENTIAL_ID", "凭据ID格式错误"))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondIntegrationError 将集成凭据服务错误映射为HTTP响应
+func (h *IntegrationCredentialHandler) respondIntegrationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_INTEGRATION_CREDENTIAL",
+			Message: "集成凭据无效",
+			Details: err.Error(),
+		})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("INTEGRATION_CREDENTIAL_NOT_FOUND", "集成凭据不存在"))
+	case errors.Is(err, repository.ErrDuplicateEntry):
+		c.JSON(http.StatusConflict, NewErrorResponse("INTEGRATION_CREDENTIAL_EXISTS", "同名集成凭据已存在"))
+	default:
+		h.logger.Error("Integration credential operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("INTEGRATION_CREDENTIAL_ERROR", "集成凭据处理失败"))
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// stubIntegrationCredentialRepository 内存集成凭据Repository
+type stubIntegrationCredentialRepository struct {
+	items map[int64]*repository.IntegrationCredential
+}
+
+func (s *stubIntegrationCredentialRepository) Create(ctx context.Context, credential *repository.IntegrationCredential) error {
+	credential.ID = int64(len(s.items) + 1)
+	credential.Version = 1
+	s.items[credential.ID] = credential
+	return nil
+}
+
+func (s *stubIntegrationCredentialRepository) GetByID(ctx context.Context, id int64) (*repository.IntegrationCredential, error) {
+	if c, ok := s.items[id]; ok {
+		return c, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (s *stubIntegrationCredentialRepository) GetByName(ctx context.Context, integration, name string) (*repository.IntegrationCredential, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (s *stubIntegrationCredentialRepository) List(ctx context.Context, integration string) ([]*repository.IntegrationCredential, error) {
+	var list []*repository.IntegrationCredential
+	for _, c := range s.items {
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func (s *stubIntegrationCredentialRepository) UpdateSettings(ctx context.Context, id int64, settings map[string]string, updateBy int64) error {
+	return repository.ErrNotFound
+}
+
+func (s *stubIntegrationCredentialRepository) Rotate(ctx context.Context, id int64, secretEncrypted, secretMask string, updateBy int64) (int, error) {
+	c, ok := s.items[id]
+	if !ok {
+		return 0, repository.ErrNotFound
+	}
+	c.SecretEncrypted, c.SecretMask = secretEncrypted, secretMask
+	c.Version++
+	return c.Version, nil
+}
+
+func (s *stubIntegrationCredentialRepository) Delete(ctx context.Context, id int64, deleteBy int64) error {
+	return repository.ErrNotFound
+}
+
+func newIntegrationTestRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cipher, err := service.NewAESEncryption([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	repo := &stubIntegrationCredentialRepository{items: make(map[int64]*repository.IntegrationCredential)}
+	h := NewIntegrationCredentialHandler(service.NewIntegrationCredentialService(repo, cipher, zaptest.NewLogger(t)), zaptest.NewLogger(t))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", int64(1)) })
+	r.GET("/admin/integrations", h.ListCredentials)
+	r.POST("/admin/integrations", h.CreateCredential)
+	r.PUT("/admin/integrations/:id/settings", h.UpdateSettings)
+	r.POST("/admin/integrations/:id/rotate", h.RotateCredential)
+	return r
+}
+
+func TestIntegrationCredentialHandler_MaskedResponses(t *testing.T) {
+	r := newIntegrationTestRouter(t)
+
+	body := `{"integration":"jira","settings":{"url":"https://example.atlassian.net","username":"bot"},"secret":"jira-api-token-9876"}`
+	req := httptest.NewRequest(http.MethodPost, "/admin/integrations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "jira-api-token")
+	assert.Contains(t, w.Body.String(), `"secret_mask":"****9876"`)
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/integrations/1/rotate", bytes.NewBufferString(`{"secret":"jira-api-token-5432"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/integrations", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "jira-api-token")
+
+	var resp IntegrationCredentialListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Credentials, 1)
+	assert.Equal(t, 2, resp.Credentials[0].Version)
+	assert.Equal(t, "****5432", resp.Credentials[0].SecretMask)
+	assert.Empty(t, resp.Credentials[0].SecretEncrypted)
+}
+
+func TestIntegrationCredentialHandler_Errors(t *testing.T) {
+	r := newIntegrationTestRouter(t)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodPost, "/admin/integrations", `{"integration":"pagerduty","secret":"x"}`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/integrations", `{"integration":"smtp","settings":{"password":"p"},"secret":"p"}`, http.StatusBadRequest},
+		{http.MethodPost, "/admin/integrations/9/rotate", `{"secret":"new-secret"}`, http.StatusNotFound},
+		{http.MethodPost, "/admin/integrations/abc/rotate", `{"secret":"new-secret"}`, http.StatusBadRequest},
+		{http.MethodPut, "/admin/integrations/9/settings", `{"settings":{"channel":"#ops"}}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.path+" "+tt.body)
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// LLMArchiveHandler 模型请求与响应归档处理器
+// 管理员为某次查询生成有时效的签名下载链接；下载接口只校验签名，链接可直接交给排查人员或工具使用
+type LLMArchiveHandler struct {
+	archive *service.LLMArchiveService
+	logger  *zap.Logger
+}
+
+// NewLLMArchiveHandler 创建模型归档处理器实例
+func NewLLMArchiveHandler(archive *service.LLMArchiveService, logger *zap.Logger) *LLMArchiveHandler {
+	return &LLMArchiveHandler{
+		archive: archive,
+		logger:  logger,
+	}
+}
+
+// Routes 声明模型归档路由：生成链接需要admin角色，下载凭签名访问
+func (h *LLMArchiveHandler) Routes() []RouteGroup {
+	admin := []string{string(repository.RoleAdmin)}
+	return []RouteGroup{
+		{
+			Prefix: "/admin/llm-archive",
+			Tag:    "admin",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/:query_id/link", Handler: h.CreateLink, Summary: "生成模型归档下载链接", Roles: admin},
+			},
+		},
+		{
+			Prefix: "/llm-archive",
+			Tag:    "admin",
+			Auth:   AuthNone,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/:query_id", Handler: h.Download, Summary: "通过签名链接下载模型归档"},
+			},
+		},
+	}
+}
+
+// ArchiveLinkResponse 模型归档下载链接
+type ArchiveLinkResponse struct {
+	URL       string    `json:"url" example:"/api/v1/llm-archive/7-lx3k9q2a?expires=1760000000&signature=9f2c..."`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateLink 生成模型归档下载链接
+// @Summary 生成模型归档下载链接
+// @Description 为查询的模型请求与响应归档生成有时效的签名下载链接（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param query_id path string true "查询ID（Chat2SQL响应中的query_id）"
+// @Success 200 {object} ArchiveLinkResponse "生成成功"
+// @Failure 400 {object} ErrorResponse "查询ID无效"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "归档不存在或已过期"
+// @Router /api/v1/admin/llm-archive/{query_id}/link [post]
+func (h *LLMArchiveHandler) CreateLink(c *gin.Context) {
+	queryID := c.Param("query_id")
+	if _, err := h.archive.Get(c.Request.Context(), queryID); err != nil {
+		h.respondArchiveError(c, queryID, err)
+		return
+	}
+
+	signature, expiresAt := h.archive.SignLink(queryID)
+	values := url.Values{}
+	values.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	values.Set("signature", signature)
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	h.logger.Info("LLM archive link created",
+		zap.String("query_id", queryID),
+		zap.Int64("user_id", userID),
+		zap.Time("expires_at", expiresAt))
+	c.JSON(http.StatusOK, ArchiveLinkResponse{
+		URL:       "/api/" + APIVersionFromContext(c) + "/llm-archive/" + queryID + "?" + values.Encode(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// Download 通过签名链接下载模型归档
+// @Summary 通过签名链接下载模型归档
+// @Description 返回脱敏后的提示词、模型原始输出与生成参数；链接由管理员生成，过期后失效
+// @Tags 管理
+// @Produce json
+// @Param query_id path string true "查询ID"
+// @Param expires query int true "过期时间（Unix秒）"
+// @Param signature query string true "签名"
+// @Success 200 {object} service.LLMArchiveEntry "下载成功"
+// @Failure 403 {object} ErrorResponse "签名无效或链接已过期"
+// @Failure 404 {object} ErrorResponse "归档不存在或已过期"
+// @Router /api/v1/llm-archive/{query_id} [get]
+func (h *LLMArchiveHandler) Download(c *gin.Context) {
+	queryID := c.Param("query_id")
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusForbidden, NewErrorResponse("ARCHIVE_LINK_INVALID", "下载链接无效"))
+		return
+	}
+	if err := h.archive.VerifyLink(queryID, expires, c.Query("signature")); err != nil {
+		h.respondArchiveError(c, queryID, err)
+		return
+	}
+
+	entry, err := h.archive.Get(c.Request.Context(), queryID)
+	if err != nil {
+		h.respondArchiveError(c, queryID, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="llm-archive-`+queryID+`.json"`)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, entry)
+}
+
+// respondArchiveError 将归档错误映射为HTTP响应
+func (h *LLMArchiveHandler) respondArchiveError(c *gin.Context, queryID string, err error) {
+	switch {
+	case errors.Is(err, service.ErrArchiveInvalidQuery):
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_QUERY_ID", "无效的查询ID"))
+	case errors.Is(err, service.ErrArchiveNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("ARCHIVE_NOT_FOUND", "归档不存在或已过期"))
+	case errors.Is(err, service.ErrArchiveLinkInvalid):
+		c.JSON(http.StatusForbidden, NewErrorResponse("ARCHIVE_LINK_INVALID", "下载链接无效"))
+	case errors.Is(err, service.ErrArchiveLinkExpired):
+		c.JSON(http.StatusForbidden, NewErrorResponse("ARCHIVE_LINK_EXPIRED", "下载链接已过期"))
+	default:
+		h.logger.Error("LLM archive request failed", zap.String("query_id", queryID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("INTERNAL_ERROR", "读取模型归档失败"))
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// archiveWorkspaceRepository 返回固定工作空间
+type archiveWorkspaceRepository struct {
+	repository.WorkspaceRepository
+}
+
+func (archiveWorkspaceRepository) GetByUserID(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	return &repository.Workspace{QuestionRetention: string(repository.QuestionRetentionKeep)}, nil
+}
+
+func TestLLMArchiveHandler_LinkAndDownload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	store, err := service.NewDirObjectStore(dir)
+	require.NoError(t, err)
+	cfg := config.DefaultLLMArchiveConfig()
+	cfg.Enabled = true
+	cfg.Dir = dir
+	cfg.SigningKey = strings.Repeat("k", 32)
+	archive := service.NewLLMArchiveService(store, archiveWorkspaceRepository{}, cfg, zap.NewNop())
+	_, err = archive.Archive(context.Background(), &service.LLMArchiveEntry{
+		QueryID:    "7-lx3k9q2a",
+		UserID:     7,
+		Prompt:     "用户问题：列出用户",
+		Completion: "SELECT id FROM users",
+	})
+	require.NoError(t, err)
+
+	h := NewLLMArchiveHandler(archive, zap.NewNop())
+	r := gin.New()
+	r.POST("/admin/llm-archive/:query_id/link", h.CreateLink)
+	r.GET("/api/v1/llm-archive/:query_id", h.Download)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/llm-archive/7-lx3k9q2a/link", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var link ArchiveLinkResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	assert.True(t, strings.HasPrefix(link.URL, "/api/v1/llm-archive/7-lx3k9q2a?"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, link.URL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "llm-archive-7-lx3k9q2a.json")
+	var entry service.LLMArchiveEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, "用户问题：列出用户", entry.Prompt)
+	assert.Equal(t, "SELECT id FROM users", entry.Completion)
+
+	// 篡改签名或缺少参数
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, strings.Replace(link.URL, "signature=", "signature=00", 1), nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/llm-archive/7-lx3k9q2a", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 不存在的归档无法生成链接
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/llm-archive/7-missing/link", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
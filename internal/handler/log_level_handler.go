@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"chat2sql-go/internal/logging"
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+)
+
+// LogLevelHandler 运行时日志级别处理器
+// 排查问题时按模块临时调高日志级别，修改只在当前进程生效，重启后恢复配置值
+type LogLevelHandler struct {
+	levels *logging.Levels
+	logger *zap.Logger
+}
+
+// NewLogLevelHandler 创建运行时日志级别处理器实例
+func NewLogLevelHandler(levels *logging.Levels, logger *zap.Logger) *LogLevelHandler {
+	return &LogLevelHandler{
+		levels: levels,
+		logger: logger,
+	}
+}
+
+// Routes 声明日志级别管理路由，均需要admin角色
+func (h *LogLevelHandler) Routes() []RouteGroup {
+	admin := []string{string(repository.RoleAdmin)}
+	return []RouteGroup{
+		{
+			Prefix: "/admin/log-levels",
+			Tag:    "admin",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.ListLevels, Summary: "各模块当前日志级别", Roles: admin},
+				{Method: http.MethodPut, Path: "/:module", Handler: h.SetLevel, Summary: "设置模块日志级别", Roles: admin},
+				{Method: http.MethodDelete, Path: "/:module", Handler: h.ResetLevel, Summary: "恢复模块默认日志级别", Roles: admin},
+			},
+		},
+	}
+}
+
+// LogLevelRequest 设置日志级别请求
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required,oneof=debug info warn error" example:"debug"`
+}
+
+// LogLevelsResponse 日志级别响应，default为未单独设置级别的模块使用的级别
+type LogLevelsResponse struct {
+	Levels map[string]string `json:"levels"`
+}
+
+// ListLevels 列出各模块当前日志级别
+// @Summary 各模块当前日志级别
+// @Description 返回默认日志级别与sql、ai等模块当前生效的级别（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} LogLevelsResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Router /api/v1/admin/log-levels [get]
+func (h *LogLevelHandler) ListLevels(c *gin.Context) {
+	c.JSON(http.StatusOK, &LogLevelsResponse{Levels: h.levels.Snapshot()})
+}
+
+// SetLevel 设置模块日志级别
+// @Summary 设置模块日志级别
+// @Description 立即调整指定模块的日志级别，module为default时调整默认级别（需admin角色）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param module path string true "模块名，如sql、ai、default"
+// @Param request body LogLevelRequest true "日志级别"
+// @Success 200 {object} LogLevelsResponse "设置成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "模块不存在"
+// @Router /api/v1/admin/log-levels/{module} [put]
+func (h *LogLevelHandler) SetLevel(c *gin.Context) {
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_LOG_LEVEL", "日志级别无效"))
+		return
+	}
+
+	module := c.Param("module")
+	previous := h.levels.Level(module)
+	if err := h.levels.SetLevel(module, level); err != nil {
+		h.respondLevelError(c, err)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	h.logger.Warn("Log level changed",
+		zap.String("module", module),
+		zap.Stringer("previous", previous),
+		zap.Stringer("level", level),
+		zap.Int64("user_id", userID))
+	c.JSON(http.StatusOK, &LogLevelsResponse{Levels: h.levels.Snapshot()})
+}
+
+// ResetLevel 恢复模块默认日志级别
+// @Summary 恢复模块默认日志级别
+// @Description 取消模块单独设置的级别，恢复使用默认级别（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param module path string true "模块名，如sql、ai"
+// @Success 200 {object} LogLevelsResponse "恢复成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "模块不存在"
+// @Router /api/v1/admin/log-levels/{module} [delete]
+func (h *LogLevelHandler) ResetLevel(c *gin.Context) {
+	module := c.Param("module")
+	if err := h.levels.ResetLevel(module); err != nil {
+		h.respondLevelError(c, err)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	h.logger.Warn("Log level reset", zap.String("module", module), zap.Int64("user_id", userID))
+	c.JSON(http.StatusOK, &LogLevelsResponse{Levels: h.levels.Snapshot()})
+}
+
+// respondLevelError 将日志级别错误映射为HTTP响应
+func (h *LogLevelHandler) respondLevelError(c *gin.Context, err error) {
+	if errors.Is(err, logging.ErrUnknownModule) {
+		c.JSON(http.StatusNotFound, NewErrorResponse("LOG_MODULE_NOT_FOUND", "日志模块不存在"))
+		return
+	}
+	h.logger.Error("Failed to change log level", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, NewErrorResponse("INTERNAL_ERROR", "服务器内部错误"))
+}
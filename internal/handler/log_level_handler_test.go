@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/logging"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	levels := logging.NewLevels(zapcore.InfoLevel)
+	h := NewLogLevelHandler(levels, zaptest.NewLogger(t))
+	r := gin.New()
+	r.GET("/admin/log-levels", h.ListLevels)
+	r.PUT("/admin/log-levels/:module", h.SetLevel)
+	r.DELETE("/admin/log-levels/:module", h.ResetLevel)
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, LogLevelsResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var resp LogLevelsResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, resp := do(http.MethodPut, "/admin/log-levels/sql", `{"level":"debug"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "debug", resp.Levels["sql"])
+	assert.Equal(t, "info", resp.Levels["ai"])
+	assert.Equal(t, zapcore.DebugLevel, levels.Level("sql"))
+
+	w, _ = do(http.MethodPut, "/admin/log-levels/sql", `{"level":"verbose"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = do(http.MethodPut, "/admin/log-levels/billing", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, resp = do(http.MethodDelete, "/admin/log-levels/sql", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "info", resp.Levels["sql"])
+
+	w, resp = do(http.MethodGet, "/admin/log-levels", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "info", resp.Levels[logging.DefaultModule])
+}
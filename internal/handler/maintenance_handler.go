@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// MaintenanceHandler 只读维护模式处理器
+// 维护期间登录与只读接口照常可用，声明了BlockedInMaintenance的生成与执行接口返回503
+type MaintenanceHandler struct {
+	mode   *service.MaintenanceMode
+	logger *zap.Logger
+}
+
+// NewMaintenanceHandler 创建维护模式处理器实例
+func NewMaintenanceHandler(mode *service.MaintenanceMode, logger *zap.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		mode:   mode,
+		logger: logger,
+	}
+}
+
+// Routes 声明维护模式路由：登录用户可查看状态，开关需要admin角色
+func (h *MaintenanceHandler) Routes() []RouteGroup {
+	admin := []string{string(repository.RoleAdmin)}
+	return []RouteGroup{
+		{
+			Prefix: "/maintenance",
+			Tag:    "system",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.GetStatus, Summary: "当前维护状态"},
+			},
+		},
+		{
+			Prefix: "/admin/maintenance",
+			Tag:    "admin",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPut, Path: "", Handler: h.Enable, Summary: "开启或更新维护模式", Roles: admin},
+				{Method: http.MethodDelete, Path: "", Handler: h.Disable, Summary: "关闭维护模式", Roles: admin},
+			},
+		},
+	}
+}
+
+// MaintenanceRequest 开启维护模式请求
+type MaintenanceRequest struct {
+	Message string     `json:"message" binding:"max=500" example:"元数据迁移中，预计14:00恢复查询"`
+	ETA     *time.Time `json:"eta" example:"2026-01-08T14:00:00+08:00"`
+}
+
+// GetStatus 查看维护状态
+// @Summary 当前维护状态
+// @Description 返回是否处于只读维护模式、提示信息与预计恢复时间，前端据此展示维护横幅
+// @Tags 系统
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.MaintenanceState "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Router /api/v1/maintenance [get]
+func (h *MaintenanceHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.mode.State())
+}
+
+// Enable 开启或更新维护模式
+// @Summary 开启或更新维护模式
+// @Description 立即拒绝新的SQL生成与执行，已开启时更新提示信息与预计恢复时间；多实例通过Redis同步（需admin角色）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MaintenanceRequest true "提示信息与预计恢复时间"
+// @Success 200 {object} service.MaintenanceState "设置成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "保存维护状态失败"
+// @Router /api/v1/admin/maintenance [put]
+func (h *MaintenanceHandler) Enable(c *gin.Context) {
+	var req MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	state, err := h.mode.Enable(c.Request.Context(), req.Message, req.ETA, userID)
+	if err != nil {
+		h.logger.Error("Failed to enable maintenance mode", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("MAINTENANCE_ERROR", "保存维护状态失败"))
+		return
+	}
+	h.logger.Warn("Maintenance mode enabled",
+		zap.String("message", state.Message),
+		zap.Timep("eta", state.ETA),
+		zap.Int64("user_id", userID))
+	c.JSON(http.StatusOK, state)
+}
+
+// Disable 关闭维护模式
+// @Summary 关闭维护模式
+// @Description 恢复SQL生成与执行（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.MaintenanceState "关闭成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "保存维护状态失败"
+// @Router /api/v1/admin/maintenance [delete]
+func (h *MaintenanceHandler) Disable(c *gin.Context) {
+	userID, _ := middleware.GetUserIDFromContext(c)
+	state, err := h.mode.Disable(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to disable maintenance mode", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("MAINTENANCE_ERROR", "保存维护状态失败"))
+		return
+	}
+	h.logger.Warn("Maintenance mode disabled", zap.Int64("user_id", userID))
+	c.JSON(http.StatusOK, state)
+}
+
+// maintenanceMiddleware 维护期间拒绝生成与执行类请求，返回提示信息，预计恢复时间通过details与Retry-After给出
+func maintenanceMiddleware(mode *service.MaintenanceMode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := mode.State()
+		if !state.Enabled {
+			c.Next()
+			return
+		}
+
+		resp := NewErrorResponse("MAINTENANCE_MODE", state.Message)
+		if state.ETA != nil {
+			resp.Details = "预计恢复时间: " + state.ETA.Format(time.RFC3339)
+			if wait := time.Until(*state.ETA); wait > 0 {
+				c.Header("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, resp)
+	}
+}
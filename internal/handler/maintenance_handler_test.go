@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/service"
+)
+
+func TestMaintenanceMode_BlocksDeclaredRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	mode := service.NewMaintenanceMode(nil)
+	router := gin.New()
+	SetupRoutes(router, &RouterConfig{
+		AuthMiddleware:     headerAuth{},
+		Maintenance:        mode,
+		MaintenanceHandler: NewMaintenanceHandler(mode, zaptest.NewLogger(t)),
+		Providers: []RouteProvider{staticRoutes{
+			{Prefix: "/sql", Tag: "sql", Auth: AuthJWT, Routes: []Route{
+				{Method: http.MethodGet, Path: "/history", Handler: ok, Summary: "查询历史"},
+				{Method: http.MethodPost, Path: "/execute", Handler: ok, Summary: "执行SQL", BlockedInMaintenance: true},
+			}},
+		}},
+	})
+
+	serve := func(method, path, role, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer test")
+		req.Header.Set("X-Test-Role", role)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/v1/sql/execute", "user", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/api/v1/admin/maintenance", "user", "{}").Code)
+
+	eta := time.Now().Add(90 * time.Minute).UTC().Truncate(time.Second)
+	w := serve(http.MethodPut, "/api/v1/admin/maintenance", "admin",
+		`{"message":"元数据迁移中","eta":"`+eta.Format(time.RFC3339)+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(http.MethodPost, "/api/v1/sql/execute", "user", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "MAINTENANCE_MODE", resp.Code)
+	assert.Equal(t, "元数据迁移中", resp.Message)
+	assert.Contains(t, resp.Details, eta.Format(time.RFC3339))
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/v1/sql/history", "user", "").Code, "只读接口不受影响")
+
+	w = serve(http.MethodGet, "/api/v1/maintenance", "user", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var state service.MaintenanceState
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.True(t, state.Enabled)
+	require.NotNil(t, state.ETA)
+	assert.True(t, state.ETA.Equal(eta))
+
+	require.Equal(t, http.StatusOK, serve(http.MethodDelete, "/api/v1/admin/maintenance", "admin", "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/v1/sql/execute", "user", "").Code)
+}
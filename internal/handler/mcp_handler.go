@@ -0,0 +1,86 @@
+// MCP（Model Context Protocol）HTTP传输处理器
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/mcp"
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+)
+
+// mcpMaxBodySize MCP请求体大小上限
+const mcpMaxBodySize = 1 << 20
+
+// MCPHandler MCP HTTP处理器
+// 认证由上游中间件完成；通过API密钥调用时按密钥的mcp:*范围限制可用工具，
+// 登录会话拥有全部工具，由路由的查询执行权限把关
+type MCPHandler struct {
+	server *mcp.Server
+	logger *zap.Logger
+}
+
+// NewMCPHandler 创建MCP处理器实例
+func NewMCPHandler(server *mcp.Server, logger *zap.Logger) *MCPHandler {
+	return &MCPHandler{
+		server: server,
+		logger: logger,
+	}
+}
+
+// Routes 声明MCP工具服务路由
+func (h *MCPHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix:      "",
+			Tag:         "mcp",
+			Auth:        AuthJWT,
+			APIKeyScope: repository.APIKeyScopeMCP,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/mcp", Handler: h.HandleRPC, Summary: "MCP JSON-RPC调用", Permission: repository.PermQueryExecute, BlockedInMaintenance: true},
+			},
+		},
+	}
+}
+
+// HandleRPC 处理MCP JSON-RPC消息（Streamable HTTP传输的POST端点）
+// @Summary MCP JSON-RPC端点
+// @Description 以MCP工具形式暴露连接列表、表结构、SQL生成与只读执行
+// @Tags MCP
+// @Accept json
+// @Produce json
+// @Success 200 {object} mcp.Response "JSON-RPC响应"
+// @Success 202 "通知已接收"
+// @Router /api/v1/mcp [post]
+func (h *MCPHandler) HandleRPC(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "认证信息无效"))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, mcpMaxBodySize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "读取请求体失败"))
+		return
+	}
+
+	principal := &mcp.Principal{UserID: userID, Scopes: mcp.AllScopes}
+	if scopes, ok := middleware.GetAPIKeyScopesFromContext(c); ok {
+		principal.Scopes = make([]string, 0, len(scopes))
+		for _, scope := range scopes {
+			principal.Scopes = append(principal.Scopes, string(scope))
+		}
+	}
+
+	resp := h.server.Handle(c.Request.Context(), principal, body)
+	if resp == nil {
+		c.Status(http.StatusAccepted)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/mcp"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// mcpConnectionRepo 测试用连接仓库，只有用户1的连接1
+type mcpConnectionRepo struct {
+	repository.ConnectionRepository
+}
+
+func (mcpConnectionRepo) GetByID(_ context.Context, id int64) (*repository.DatabaseConnection, error) {
+	if id != 1 {
+		return nil, repository.ErrNotFound
+	}
+	return testutil.NewConnection(1, 1, testutil.WithConnectionName("shop")), nil
+}
+
+// countingQueryExecutor 记录执行次数的查询执行器
+type countingQueryExecutor struct {
+	calls int
+}
+
+func (e *countingQueryExecutor) ExecuteQuery(_ context.Context, _ string, _ *repository.DatabaseConnection) (*service.QueryResult, error) {
+	e.calls++
+	return &service.QueryResult{Columns: []string{"n"}, Rows: []map[string]any{{"n": 1}}, RowCount: 1}, nil
+}
+
+func TestMCPHandler_APIKeyScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	executor := &countingQueryExecutor{}
+	h := NewMCPHandler(mcp.NewServer(mcpConnectionRepo{}, nil, nil, executor, "test", zaptest.NewLogger(t)), zaptest.NewLogger(t))
+
+	serve := func(scopes []repository.APIKeyScope) string {
+		router := gin.New()
+		router.POST("/mcp", func(c *gin.Context) {
+			c.Set("user_id", int64(1))
+			if scopes != nil {
+				c.Set("api_key_scopes", scopes)
+			}
+		}, h.HandleRPC)
+		body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"execute_sql","arguments":{"connection_id":1,"sql":"SELECT 1"}}}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	// 只读密钥不能执行SQL
+	body := serve([]repository.APIKeyScope{repository.APIKeyScopeMCPConnectionsRead, repository.APIKeyScopeMCPSchemaRead})
+	assert.Contains(t, body, `"isError":true`)
+	assert.Contains(t, body, mcp.ScopeSQLExecute)
+	assert.Equal(t, 0, executor.calls)
+
+	// 授予执行范围的密钥与登录会话可以执行
+	assert.NotContains(t, serve([]repository.APIKeyScope{repository.APIKeyScopeMCPSQLExecute}), `"isError"`)
+	assert.NotContains(t, serve(nil), `"isError"`)
+	assert.Equal(t, 2, executor.calls)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/service"
+)
+
+// MetricsHandler 运行指标摘要处理器
+// 界面状态栏轮询该接口展示服务状态，完整指标仍通过/metrics由Prometheus抓取
+type MetricsHandler struct {
+	summary *service.MetricsSummaryService
+	logger  *zap.Logger
+}
+
+// NewMetricsHandler 创建运行指标摘要处理器实例
+func NewMetricsHandler(summary *service.MetricsSummaryService, logger *zap.Logger) *MetricsHandler {
+	return &MetricsHandler{
+		summary: summary,
+		logger:  logger,
+	}
+}
+
+// Routes 声明运行指标路由，登录用户均可访问
+func (h *MetricsHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/metrics",
+			Tag:    "metrics",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/summary", Handler: h.GetSummary, Summary: "获取运行指标摘要"},
+			},
+		},
+	}
+}
+
+// GetSummary 获取运行指标摘要
+// @Summary 获取运行指标摘要
+// @Description 返回本实例最近一分钟的QPS、模型生成平均耗时、当日准确率与AI预算用量，供界面状态栏展示
+// @Tags 指标
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.MetricsSummary "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Router /api/v1/metrics/summary [get]
+func (h *MetricsHandler) GetSummary(c *gin.Context) {
+	c.JSON(http.StatusOK, h.summary.Summary())
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidCursor 游标无法解析，或与当前列表、筛选条件不匹配
+var errInvalidCursor = errors.New("invalid cursor")
+
+// CursorPage 游标分页信息，嵌入各列表响应
+// 客户端把next_cursor原样作为下一页请求的cursor参数，has_more为false时表示已到最后一页
+type CursorPage struct {
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJvIjoyMCwicyI6IjNmMmEifQ"`
+	HasMore    bool   `json:"has_more" example:"true"`
+}
+
+// listCursor 偏移量游标的内容，用于数据量小、在内存中分页的列表
+type listCursor struct {
+	Offset int    `json:"o"`
+	Scope  string `json:"s"`
+}
+
+// listScope 计算列表范围指纹：列表名与筛选条件，游标只能用于生成它的同一列表
+func listScope(list string, filters ...any) string {
+	h := sha256.New()
+	fmt.Fprint(h, list)
+	for _, f := range filters {
+		fmt.Fprintf(h, "|%v", f)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:6])
+}
+
+// encodeCursor 生成不透明游标
+func encodeCursor(offset int, scope string) string {
+	data, _ := json.Marshal(listCursor{Offset: offset, Scope: scope})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// resolveOffset 解析游标得到起始位置，未提供游标时兼容旧的offset参数
+func resolveOffset(cursor string, legacyOffset int, scope string) (int, error) {
+	if cursor == "" {
+		return legacyOffset, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	var decoded listCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Offset < 0 || decoded.Scope != scope {
+		return 0, errInvalidCursor
+	}
+	return decoded.Offset, nil
+}
+
+// paginate 截取本页数据并生成分页信息
+// 调用方按limit+1条查询，多出的一条只用于判断是否还有下一页，从而不必为每页执行COUNT(*)
+func paginate[T any](rows []T, offset, limit int, scope string) ([]T, CursorPage) {
+	if len(rows) <= limit {
+		return rows, CursorPage{}
+	}
+	return rows[:limit], CursorPage{
+		NextCursor: encodeCursor(offset+limit, scope),
+		HasMore:    true,
+	}
+}
+
+// keysetCursor 键集游标的内容：上一页最后一行的排序键与页码
+// 下一页从该行之后开始，翻页期间写入的新记录不会导致后续页重复或跳过记录
+type keysetCursor struct {
+	CreateTime time.Time `json:"t"`
+	ID         int64     `json:"i"`
+	Page       int       `json:"p"`
+	Scope      string    `json:"s"`
+}
+
+// decodeKeysetCursor 解析键集游标，未提供游标时返回nil
+func decodeKeysetCursor(cursor, scope string) (*keysetCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var decoded keysetCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID <= 0 || decoded.Page < 1 || decoded.Scope != scope {
+		return nil, errInvalidCursor
+	}
+	return &decoded, nil
+}
+
+// paginateKeyset 截取本页数据，以最后一行的排序键生成下一页游标
+// 调用方按limit+1条查询，rows需按key倒序排列
+func paginateKeyset[T any](rows []T, limit, page int, scope string, key func(T) (time.Time, int64)) ([]T, CursorPage) {
+	if len(rows) <= limit {
+		return rows, CursorPage{}
+	}
+	rows = rows[:limit]
+	createTime, id := key(rows[limit-1])
+	data, _ := json.Marshal(keysetCursor{CreateTime: createTime, ID: id, Page: page, Scope: scope})
+	return rows, CursorPage{
+		NextCursor: base64.RawURLEncoding.EncodeToString(data),
+		HasMore:    true,
+	}
+}
+
+// respondInvalidCursor 返回游标无效错误
+func respondInvalidCursor(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_CURSOR", "分页游标无效或与当前筛选条件不匹配"))
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorPagination(t *testing.T) {
+	scope := listScope("approvals", "pending")
+	rows := []int{1, 2, 3, 4}
+
+	items, page := paginate(rows, 0, 3, scope)
+	assert.Equal(t, []int{1, 2, 3}, items)
+	require.True(t, page.HasMore)
+
+	offset, err := resolveOffset(page.NextCursor, 0, scope)
+	require.NoError(t, err)
+	assert.Equal(t, 3, offset)
+
+	items, page = paginate(rows[offset:], offset, 3, scope)
+	assert.Equal(t, []int{4}, items)
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.NextCursor)
+
+	// 未提供游标时使用旧的offset参数
+	offset, err = resolveOffset("", 40, scope)
+	require.NoError(t, err)
+	assert.Equal(t, 40, offset)
+
+	// 游标绑定列表与筛选条件
+	_, err = resolveOffset(encodeCursor(3, scope), 0, listScope("approvals", "approved"))
+	assert.ErrorIs(t, err, errInvalidCursor)
+	_, err = resolveOffset("not-a-cursor!", 0, scope)
+	assert.ErrorIs(t, err, errInvalidCursor)
+	_, err = resolveOffset(encodeCursor(-1, scope), 0, scope)
+	assert.ErrorIs(t, err, errInvalidCursor)
+}
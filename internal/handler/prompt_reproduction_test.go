@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// stubSchemaSnapshotRepo 只包含一个快照的表结构快照Repository
+type stubSchemaSnapshotRepo struct {
+	snapshot *repository.SchemaSnapshot
+}
+
+func (s *stubSchemaSnapshotRepo) Record(ctx context.Context, connectionID int64, schemaText string) (*repository.SchemaSnapshot, error) {
+	return s.snapshot, nil
+}
+
+func (s *stubSchemaSnapshotRepo) GetByID(ctx context.Context, id int64) (*repository.SchemaSnapshot, error) {
+	if id != s.snapshot.ID {
+		return nil, repository.ErrNotFound
+	}
+	return s.snapshot, nil
+}
+
+func (s *stubSchemaSnapshotRepo) GetLatest(ctx context.Context, connectionID int64) (*repository.SchemaSnapshot, error) {
+	return s.snapshot, nil
+}
+
+// schemaPromptBuilder 以表结构开头的提示词
+type schemaPromptBuilder struct{}
+
+func (schemaPromptBuilder) BuildPrompt(req *service.SQLGenerationRequest) (string, error) {
+	return "schema: " + req.Schema + "\nquery: " + req.Query, nil
+}
+
+func TestSQLHandler_ReproducePrompt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	snapshot := &repository.SchemaSnapshot{ID: 12, ConnectionID: 3, Version: 2, Schema: "orders(id, user_id)"}
+	snapshotID, connectionID := snapshot.ID, snapshot.ConnectionID
+
+	recorded := testutil.NewQueryHistory(42, 7, testutil.WithQuery("订单数", "SELECT COUNT(*) FROM orders"), testutil.WithQueryConnection(connectionID))
+	recorded.SchemaSnapshotID = &snapshotID
+	legacy := testutil.NewQueryHistory(43, 7, testutil.WithQuery("用户数", "SELECT COUNT(*) FROM users"))
+	others := testutil.NewQueryHistory(44, 8)
+	others.SchemaSnapshotID = &snapshotID
+
+	queryRepo := new(MockQueryHistoryRepository)
+	queryRepo.On("GetByID", mock.Anything, int64(42)).Return(recorded, nil)
+	queryRepo.On("GetByID", mock.Anything, int64(43)).Return(legacy, nil)
+	queryRepo.On("GetByID", mock.Anything, int64(44)).Return(others, nil)
+
+	h := NewSQLHandler(queryRepo, nil, nil, zap.NewNop())
+	h.SetSchemaSnapshots(service.NewSchemaSnapshotService(&stubSchemaSnapshotRepo{snapshot: snapshot}, schemaPromptBuilder{}, zap.NewNop()))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	router.GET("/history/:id/prompt", h.ReproducePrompt)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/history/42/prompt")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp service.PromptReproduction
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(42), resp.QueryID)
+	assert.Equal(t, 2, resp.Snapshot.Version)
+	assert.Equal(t, "schema: orders(id, user_id)\nquery: 订单数", resp.Prompt)
+
+	w = get("/history/43/prompt")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "SCHEMA_SNAPSHOT_NOT_FOUND", "未记录快照的历史记录")
+
+	assert.Equal(t, http.StatusForbidden, get("/history/44/prompt").Code, "不能查看其他用户的记录")
+	assert.Equal(t, http.StatusBadRequest, get("/history/abc/prompt").Code)
+}
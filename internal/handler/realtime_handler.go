@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/service"
+)
+
+// RealtimeHandler 实时事件处理器
+// 通过WebSocket向工作空间成员推送在线状态与查询执行事件
+type RealtimeHandler struct {
+	hub    *service.RealtimeHub
+	logger *zap.Logger
+}
+
+// NewRealtimeHandler 创建实时事件处理器实例
+func NewRealtimeHandler(hub *service.RealtimeHub, logger *zap.Logger) *RealtimeHandler {
+	return &RealtimeHandler{
+		hub:    hub,
+		logger: logger,
+	}
+}
+
+// Routes 声明实时事件路由
+func (h *RealtimeHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/realtime",
+			Tag:    "realtime",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/ws", Handler: h.Subscribe, Summary: "订阅工作空间实时事件"},
+				{Method: http.MethodGet, Path: "/presence", Handler: h.GetPresence, Summary: "获取工作空间在线成员"},
+			},
+		},
+	}
+}
+
+// PresenceResponse 在线成员响应
+type PresenceResponse struct {
+	WorkspaceID int64                  `json:"workspace_id" example:"1"`
+	Online      []service.PresenceUser `json:"online"`
+}
+
+// Subscribe 订阅工作空间实时事件
+// @Summary 订阅工作空间实时事件
+// @Description 升级为WebSocket连接，推送presence.snapshot/presence.joined/presence.left/query.started/query.finished事件（JSON文本帧）。
+// @Description 浏览器无法设置握手请求头时可通过access_token查询参数携带访问令牌；服务端空闲时定期发送ping事件
+// @Tags 实时事件
+// @Security BearerAuth
+// @Param access_token query string false "访问令牌（仅WebSocket握手）"
+// @Success 101 {object} service.RealtimeEvent "切换为WebSocket协议"
+// @Failure 400 {string} string "不是WebSocket握手请求"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Router /api/v1/realtime/ws [get]
+func (h *RealtimeHandler) Subscribe(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	username, _ := middleware.GetUsernameFromContext(c)
+
+	// 身份已由JWT认证，令牌不依赖Cookie，不需要再按Origin限制跨站握手
+	server := websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   func(conn *websocket.Conn) { h.serve(conn, userID, username) },
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serve 握手成功后订阅事件并推送给客户端，直到客户端断开或订阅关闭
+func (h *RealtimeHandler) serve(conn *websocket.Conn, userID int64, username string) {
+	defer conn.Close()
+
+	sub, err := h.hub.Subscribe(conn.Request().Context(), userID, username)
+	if err != nil {
+		h.logger.Error("Failed to subscribe realtime events", zap.Error(err), zap.Int64("user_id", userID))
+		return
+	}
+	defer sub.Close()
+
+	// 客户端不需要发送消息，读循环只用于感知断开
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		var discard []byte
+		for websocket.Message.Receive(conn, &discard) == nil {
+		}
+	}()
+
+	cfg := h.hub.Config()
+	ping := time.NewTicker(cfg.PingInterval)
+	defer ping.Stop()
+
+	send := func(event any) bool {
+		if err := conn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout)); err != nil {
+			return false
+		}
+		if err := websocket.JSON.Send(conn, event); err != nil {
+			h.logger.Debug("Realtime client write failed", zap.Error(err), zap.Int64("user_id", userID))
+			return false
+		}
+		return true
+	}
+
+	for {
+		select {
+		case <-disconnected:
+			return
+		case event, ok := <-sub.Events:
+			if !ok || !send(event) {
+				return
+			}
+			ping.Reset(cfg.PingInterval)
+		case <-ping.C:
+			if !send(gin.H{"type": "ping", "time": time.Now()}) {
+				return
+			}
+		}
+	}
+}
+
+// GetPresence 获取工作空间在线成员
+// @Summary 获取工作空间在线成员
+// @Description 返回当前用户所属工作空间中持有实时事件连接的成员，供无法使用WebSocket的客户端轮询
+// @Tags 实时事件
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} PresenceResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/realtime/presence [get]
+func (h *RealtimeHandler) GetPresence(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	workspaceID, online, err := h.hub.Online(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to resolve workspace", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, &PresenceResponse{WorkspaceID: workspaceID, Online: online})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/net/websocket"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+func TestRealtimeHandler_Subscribe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	settings := service.NewWorkspaceSettingsService(&stubWorkspaceRepository{workspace: workspace}, nil, nil, zaptest.NewLogger(t))
+	hub := service.NewRealtimeHub(settings, nil, zaptest.NewLogger(t))
+	h := NewRealtimeHandler(hub, zaptest.NewLogger(t))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+		c.Set("username", "alice")
+	})
+	r.GET("/realtime/ws", h.Subscribe)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/realtime/ws"
+	conn, err := websocket.Dial(url, "", server.URL)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	var event service.RealtimeEvent
+	require.NoError(t, websocket.JSON.Receive(conn, &event))
+	assert.Equal(t, service.EventPresenceSnapshot, event.Type)
+	assert.Equal(t, []service.PresenceUser{{UserID: 7, Username: "alice"}}, event.Online)
+
+	hub.Publish(context.Background(), 7, &service.RealtimeEvent{Type: service.EventQueryFinished, QueryID: 3, Status: "success"})
+	require.NoError(t, websocket.JSON.Receive(conn, &event))
+	assert.Equal(t, service.EventQueryFinished, event.Type)
+	assert.Equal(t, int64(3), event.QueryID)
+	assert.Equal(t, "alice", event.Username)
+
+	// 客户端断开后成员下线
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		_, online, err := hub.Online(context.Background(), 7)
+		return err == nil && len(online) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// replayTimeout 单次回放的超时时间，与Chat2SQL的生成超时一致
+const replayTimeout = 30 * time.Second
+
+// ReplayHandler 查询回放处理器
+// 调整提示词或切换模型后，管理员回放具体的历史查询，对比新旧SQL判断是修复还是回归
+type ReplayHandler struct {
+	replay *service.QueryReplayService
+	logger *zap.Logger
+}
+
+// NewReplayHandler 创建查询回放处理器实例
+func NewReplayHandler(replay *service.QueryReplayService, logger *zap.Logger) *ReplayHandler {
+	return &ReplayHandler{
+		replay: replay,
+		logger: logger,
+	}
+}
+
+// Routes 声明查询回放路由，需要admin角色
+func (h *ReplayHandler) Routes() []RouteGroup {
+	admin := []string{string(repository.RoleAdmin)}
+	return []RouteGroup{
+		{
+			Prefix: "/admin/query-replay",
+			Tag:    "admin",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/:id", Handler: h.ReplayQuery, Summary: "回放历史查询并对比SQL", Roles: admin, BlockedInMaintenance: true},
+			},
+		},
+	}
+}
+
+// ReplayRequest 查询回放请求
+type ReplayRequest struct {
+	Schema string `json:"schema,omitempty" binding:"omitempty,oneof=pinned current" example:"pinned"`   // 使用生成时的快照或连接最新的快照，默认pinned
+	Model  string `json:"model,omitempty" binding:"omitempty,oneof=primary fallback" example:"primary"` // 只使用指定模型，默认按正常流程降级
+}
+
+// ReplayQuery 回放历史查询
+// @Summary 回放历史查询并对比SQL
+// @Description 用历史查询的问题与表结构快照重新生成SQL，返回与原SQL的逐子句差异；只生成不执行，不写入查询历史（需admin角色）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "查询ID"
+// @Param request body ReplayRequest false "回放选项"
+// @Success 200 {object} service.ReplayResult "回放完成"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "查询不存在或缺少表结构快照"
+// @Failure 502 {object} ErrorResponse "模型调用失败"
+// @Router /api/v1/admin/query-replay/{id} [post]
+func (h *ReplayHandler) ReplayQuery(c *gin.Context) {
+	queryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_QUERY_ID", "无效的查询ID"))
+		return
+	}
+
+	var req ReplayRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_REQUEST",
+				Message: "请求参数格式错误",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), replayTimeout)
+	defer cancel()
+
+	result, err := h.replay.Replay(ctx, queryID, service.ReplayOptions{Schema: req.Schema, Model: req.Model})
+	if err != nil {
+		h.respondReplayError(c, queryID, err)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	h.logger.Info("Query replay requested",
+		zap.Int64("query_id", queryID),
+		zap.Int64("user_id", userID),
+		zap.Bool("identical", result.Identical))
+	c.JSON(http.StatusOK, result)
+}
+
+// respondReplayError 将回放错误映射为HTTP响应
+func (h *ReplayHandler) respondReplayError(c *gin.Context, queryID int64, err error) {
+	switch {
+	case repository.IsNotFound(err):
+		c.JSON(http.StatusNotFound, NewErrorResponse("QUERY_NOT_FOUND", "查询记录不存在"))
+	case errors.Is(err, service.ErrNoSchemaSnapshot):
+		c.JSON(http.StatusNotFound, NewErrorResponse("SCHEMA_SNAPSHOT_NOT_FOUND", "缺少回放所需的表结构快照"))
+	case repository.IsInvalidInput(err):
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", err.Error()))
+	default:
+		h.logger.Error("Failed to replay query", zap.Int64("query_id", queryID), zap.Error(err))
+		c.JSON(http.StatusBadGateway, NewErrorResponse("REPLAY_FAILED", "回放生成SQL失败"))
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// fixedSQLGenerator 总是返回同一条SQL
+type fixedSQLGenerator struct {
+	sql      string
+	requests []*service.SQLGenerationRequest
+}
+
+func (g *fixedSQLGenerator) GenerateSQL(ctx context.Context, req *service.SQLGenerationRequest) (*service.SQLGenerationResponse, error) {
+	g.requests = append(g.requests, req)
+	return &service.SQLGenerationResponse{SQL: g.sql, Confidence: 0.8}, nil
+}
+
+func TestReplayHandler_ReplayQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	snapshot := &repository.SchemaSnapshot{ID: 12, ConnectionID: 3, Version: 1, Schema: "orders(id, user_id)"}
+	history := testutil.NewQueryHistory(42, 8, testutil.WithQuery("订单数", "SELECT COUNT(*) FROM orders"), testutil.WithQueryConnection(snapshot.ConnectionID))
+	history.SchemaSnapshotID = &snapshot.ID
+
+	queryRepo := new(MockQueryHistoryRepository)
+	queryRepo.On("GetByID", mock.Anything, int64(42)).Return(history, nil)
+	queryRepo.On("GetByID", mock.Anything, int64(99)).Return(nil, repository.ErrNotFound)
+
+	generator := &fixedSQLGenerator{sql: "SELECT COUNT(id) FROM orders"}
+	h := NewReplayHandler(service.NewQueryReplayService(queryRepo, &stubSchemaSnapshotRepo{snapshot: snapshot}, generator, zap.NewNop()), zap.NewNop())
+	r := gin.New()
+	r.POST("/admin/query-replay/:id", h.ReplayQuery)
+
+	do := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/admin/query-replay/42", `{"model":"fallback"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result service.ReplayResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.False(t, result.Identical)
+	assert.Equal(t, "SELECT COUNT(id) FROM orders", result.ReplayedSQL)
+	assert.Equal(t, "- SELECT COUNT(*)\n+ SELECT COUNT(id)\n  FROM orders\n", result.Diff)
+	assert.Equal(t, service.ModelFallback, generator.requests[0].Model)
+
+	assert.Equal(t, http.StatusOK, do("/admin/query-replay/42", "").Code, "请求体可省略")
+	assert.Equal(t, http.StatusBadRequest, do("/admin/query-replay/42", `{"model":"gpt-9"}`).Code)
+	assert.Equal(t, http.StatusNotFound, do("/admin/query-replay/99", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("/admin/query-replay/abc", "").Code)
+}
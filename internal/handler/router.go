@@ -6,19 +6,54 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
 
+	appconfig "chat2sql-go/internal/config"
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
 	"chat2sql-go/internal/service"
 )
 
 // RouterConfig 路由配置结构
 type RouterConfig struct {
-	AuthHandler       *AuthHandler
-	UserHandler       *UserHandler
-	SQLHandler        *SQLHandler
-	ConnectionHandler *ConnectionHandler
-	AIHandler         *AIHandler          // P1阶段新增: AI服务处理器
-	AuthMiddleware    AuthMiddleware       // JWT认证中间件接口
-	HealthService     service.HealthServiceInterface // 健康检查服务接口
+	AuthHandler           *AuthHandler
+	UserHandler           *UserHandler
+	SQLHandler            *SQLHandler
+	ConnectionHandler     *ConnectionHandler
+	AIHandler             *AIHandler                     // P1阶段新增: AI服务处理器
+	TeamsHandler          *TeamsHandler                  // Microsoft Teams机器人集成（可选）
+	EmailHandler          *EmailHandler                  // 邮件查询网关（可选）
+	MCPHandler            *MCPHandler                    // MCP工具服务（可选）
+	EmbedHandler          *EmbedHandler                  // 嵌入式组件API（可选）
+	WorkspaceHandler      *WorkspaceHandler              // 工作空间设置（可选）
+	WriteModeHandler      *WriteModeHandler              // 受控写模式（可选）
+	ApprovalHandler       *ApprovalHandler               // 审批流程（可选）
+	ClassificationHandler *ClassificationHandler         // 列数据分级（可选）
+	ErasureHandler        *ErasureHandler                // 个人数据删除（可选）
+	FolderHandler         *FolderHandler                 // 保存查询与文件夹（可选）
+	ScheduleHandler       *ScheduleHandler               // 保存查询定时执行（可选）
+	RealtimeHandler       *RealtimeHandler               // 实时事件推送（可选）
+	ChatHandler           *ChatHandler                   // 多轮对话（可选）
+	LogLevelHandler       *LogLevelHandler               // 运行时日志级别（可选）
+	ReplayHandler         *ReplayHandler                 // 查询回放（可选）
+	TelemetryHandler      *TelemetryHandler              // 匿名使用统计（可选）
+	LLMArchiveHandler     *LLMArchiveHandler             // 模型请求与响应归档（可选）
+	AnalyticsHandler      *AnalyticsHandler              // 管理分析（可选）
+	IntegrationHandler    *IntegrationCredentialHandler  // 第三方集成凭据（可选）
+	MaintenanceHandler    *MaintenanceHandler            // 维护模式开关（可选）
+	APIKeyHandler         *APIKeyHandler                 // API密钥管理（可选）
+	ColumnAccessHandler   *ColumnAccessHandler           // 列访问申请（可选）
+	MetricsHandler        *MetricsHandler                // 运行指标摘要（可选）
+	SchemaTimelineHandler *SchemaTimelineHandler         // 表结构变更时间线（可选）
+	AuthMiddleware        AuthMiddleware                 // JWT认证中间件接口
+	EmbedMiddleware       EmbedAuthMiddleware            // 嵌入令牌认证中间件接口
+	APIKeyMiddleware      APIKeyAuthMiddleware           // API密钥认证中间件接口（可选）
+	HealthService         service.HealthServiceInterface // 健康检查服务接口
+	Providers             []RouteProvider                // 额外的路由声明（可选）
+	APIVersion            *appconfig.APIVersionConfig    // API版本与v1弃用策略，为空时使用默认配置
+	DatabaseAvailable     func() bool                    // 系统库是否可用（可选），不可用时API返回503
+	DatabaseRetryAfter    time.Duration                  // 系统库不可用时Retry-After的时长
+	Maintenance           *service.MaintenanceMode       // 维护模式（可选），开启时拒绝声明了BlockedInMaintenance的路由
 }
 
 // AuthMiddleware JWT认证中间件接口
@@ -26,93 +61,57 @@ type AuthMiddleware interface {
 	JWTAuth() gin.HandlerFunc
 }
 
+// EmbedAuthMiddleware 嵌入令牌认证中间件接口
+type EmbedAuthMiddleware interface {
+	EmbedAuth() gin.HandlerFunc
+}
+
+// APIKeyAuthMiddleware API密钥认证中间件接口，fallback为请求未携带密钥时使用的认证中间件
+type APIKeyAuthMiddleware interface {
+	APIKeyAuth(scope repository.APIKeyScope, fallback gin.HandlerFunc) gin.HandlerFunc
+}
+
 // SetupRoutes 配置所有API路由
-// 实现企业级RESTful API设计模式，支持版本管理和中间件链
+// 各处理器通过Routes声明路由分组及其认证、角色与限流策略，这里统一挂载中间件并生成OpenAPI文档
 func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 	// 全局中间件
 	setupGlobalMiddleware(r)
 	
-	// API版本管理
-	v1 := r.Group("/api/v1")
-	{
-		// 公开API - 无需认证
-		setupPublicRoutes(v1, config)
-		
-		// 受保护API - 需要JWT认证
-		setupProtectedRoutes(v1, config)
+	// API版本管理：v2为当前版本，v1在过渡期内由兼容层保持原有响应结构
+	versioning := config.APIVersion
+	if versioning == nil {
+		versioning = appconfig.DefaultAPIVersionConfig()
+	}
+	groups := config.RouteGroups()
+	v1 := r.Group("/api/"+APIVersionV1, apiVersionMiddleware(APIVersionV1), v1DeprecationMiddleware(versioning))
+	v2 := r.Group("/api/"+APIVersionV2, apiVersionMiddleware(APIVersionV2), v2ResponseShim())
+	if config.DatabaseAvailable != nil {
+		availability := databaseAvailabilityMiddleware(config.DatabaseAvailable, config.DatabaseRetryAfter)
+		v1.Use(availability)
+		v2.Use(availability)
 	}
+	registerRouteGroups(config, groups, v1, v2)
+	
+	// 由路由声明自动生成的接口文档
+	v1.GET("/openapi.json", openAPIHandler(OpenAPISpec(v1.BasePath(), versioning.V1Deprecated, groups)))
+	v2.GET("/openapi.json", openAPIHandler(OpenAPISpec(v2.BasePath(), false, groups)))
 	
 	// 健康检查和系统监控端点
 	setupSystemRoutes(r, config)
 }
 
+// SetupProbeRoutes 在轻量路由上挂载健康检查端点，供启动监督器在高负载时绕过完整路由的中间件直接处理探针请求
+func SetupProbeRoutes(r *gin.Engine, config *RouterConfig) {
+	setupSystemRoutes(r, config)
+}
+
 // setupGlobalMiddleware 配置全局中间件
 func setupGlobalMiddleware(r *gin.Engine) {
 	// 中间件顺序很重要，按照请求处理流程排列
-	r.Use(gin.Recovery())     // 1. 恢复panic，防止服务崩溃
-	r.Use(gin.Logger())       // 2. 请求日志记录
-	r.Use(corsMiddleware())   // 3. 跨域处理
-	r.Use(securityHeaders())  // 4. 安全头设置
-}
-
-// setupPublicRoutes 配置公开API路由
-func setupPublicRoutes(rg *gin.RouterGroup, config *RouterConfig) {
-	// 认证相关API - 不需要JWT Token
-	auth := rg.Group("/auth")
-	{
-		auth.POST("/register", config.AuthHandler.Register)   // 用户注册
-		auth.POST("/login", config.AuthHandler.Login)        // 用户登录
-		auth.POST("/refresh", config.AuthHandler.RefreshToken) // Token刷新
-	}
-}
-
-// setupProtectedRoutes 配置受保护的API路由
-func setupProtectedRoutes(rg *gin.RouterGroup, config *RouterConfig) {
-	// 需要JWT认证的API分组
-	protected := rg.Group("/")
-	if config.AuthMiddleware != nil {
-		protected.Use(config.AuthMiddleware.JWTAuth())
-	}
-	{
-		// 用户管理API
-		users := protected.Group("/users")
-		{
-			users.GET("/profile", config.UserHandler.GetProfile)        // 获取用户资料
-			users.PUT("/profile", config.UserHandler.UpdateProfile)     // 更新用户资料
-			users.POST("/change-password", config.UserHandler.ChangePassword) // 修改密码
-		}
-		
-		// SQL查询API
-		sql := protected.Group("/sql")
-		{
-			sql.POST("/execute", config.SQLHandler.ExecuteSQL)          // 执行SQL查询
-			sql.GET("/history", config.SQLHandler.GetQueryHistory)      // 查询历史
-			sql.GET("/history/:id", config.SQLHandler.GetQueryById)     // 获取特定查询
-			sql.POST("/validate", config.SQLHandler.ValidateSQL)        // SQL语法验证
-		}
-		
-		// 数据库连接管理API
-		connections := protected.Group("/connections")
-		{
-			connections.POST("/", config.ConnectionHandler.CreateConnection)         // 创建连接
-			connections.GET("/", config.ConnectionHandler.ListConnections)          // 连接列表
-			connections.GET("/:id", config.ConnectionHandler.GetConnection)         // 获取连接详情
-			connections.PUT("/:id", config.ConnectionHandler.UpdateConnection)      // 更新连接
-			connections.DELETE("/:id", config.ConnectionHandler.DeleteConnection)   // 删除连接
-			connections.POST("/:id/test", config.ConnectionHandler.TestConnection)  // 测试连接
-			connections.GET("/:id/schema", config.ConnectionHandler.GetSchema)      // 获取数据库结构
-		}
-		
-		// P1阶段新增：AI智能查询API
-		if config.AIHandler != nil {
-			ai := protected.Group("/ai")
-			{
-				ai.POST("/chat2sql", config.AIHandler.Chat2SQL)           // 自然语言转SQL
-				ai.POST("/feedback", config.AIHandler.SubmitFeedback)     // 提交用户反馈
-				ai.GET("/stats", config.AIHandler.GetAIStats)             // 获取AI服务统计
-			}
-		}
-	}
+	r.Use(middleware.RecoveryMiddleware(zap.L())) // 1. 恢复panic并生成崩溃报告，防止服务崩溃
+	r.Use(gin.Logger())                           // 2. 请求日志记录
+	r.Use(corsMiddleware())                       // 3. 跨域处理
+	r.Use(securityHeaders())                      // 4. 安全头设置
 }
 
 // setupSystemRoutes 配置系统级路由
@@ -208,7 +207,8 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, If-None-Match, X-Cost-Center, X-Project, X-Prompt-Cache")
+		c.Header("Access-Control-Expose-Headers", "ETag, API-Version, Deprecation, Sunset, Link, X-Prompt-Cache")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		
 		// 处理预检请求
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+)
+
+// AuthMode 路由认证方式
+type AuthMode string
+
+const (
+	AuthNone  AuthMode = "none"  // 公开路由，或由第三方平台自身的签名机制认证
+	AuthJWT   AuthMode = "jwt"   // 用户JWT认证
+	AuthEmbed AuthMode = "embed" // 嵌入令牌认证，不接受用户JWT
+)
+
+// Route 单个路由声明
+type Route struct {
+	Method    string
+	Path      string // 相对于分组前缀的路径
+	Handler   gin.HandlerFunc
+	Summary   string                      // 接口说明，用于生成OpenAPI文档
+	Roles     []string                    // 允许访问的角色，为空表示不限制角色；admin始终允许
+	RateLimit *middleware.RateLimitConfig // 路由级限流，为空表示不单独限流
+	// Permission 访问所需的权限，为空表示不检查；角色与权限的对应关系见repository.RolePermissions
+	Permission repository.Permission
+	// BlockedInMaintenance 生成或执行SQL的路由，维护模式下返回503
+	BlockedInMaintenance bool
+}
+
+// RouteGroup 路由分组声明，组内路由共享前缀、文档标签与认证方式
+type RouteGroup struct {
+	Prefix string
+	Tag    string
+	Auth   AuthMode
+	Routes []Route
+	// APIKeyScope 非空时JWT路由同时接受该范围的API密钥（X-API-Key）
+	APIKeyScope repository.APIKeyScope
+}
+
+// RouteProvider 声明自身路由的处理器
+// 处理器在Routes中同时声明路径与访问策略，SetupRoutes据此统一挂载认证、角色与限流中间件
+type RouteProvider interface {
+	Routes() []RouteGroup
+}
+
+// RouteGroups 收集已配置处理器声明的路由分组
+// 未配置的可选处理器不注册任何路由
+func (config *RouterConfig) RouteGroups() []RouteGroup {
+	var providers []RouteProvider
+	if config.AuthHandler != nil {
+		providers = append(providers, config.AuthHandler)
+	}
+	if config.TeamsHandler != nil {
+		providers = append(providers, config.TeamsHandler)
+	}
+	if config.EmailHandler != nil {
+		providers = append(providers, config.EmailHandler)
+	}
+	if config.EmbedHandler != nil {
+		providers = append(providers, config.EmbedHandler)
+	}
+	if config.UserHandler != nil {
+		providers = append(providers, config.UserHandler)
+	}
+	if config.SQLHandler != nil {
+		providers = append(providers, config.SQLHandler)
+	}
+	if config.ConnectionHandler != nil {
+		providers = append(providers, config.ConnectionHandler)
+	}
+	if config.AIHandler != nil {
+		providers = append(providers, config.AIHandler)
+	}
+	if config.MCPHandler != nil {
+		providers = append(providers, config.MCPHandler)
+	}
+	if config.WorkspaceHandler != nil {
+		providers = append(providers, config.WorkspaceHandler)
+	}
+	if config.WriteModeHandler != nil {
+		providers = append(providers, config.WriteModeHandler)
+	}
+	if config.ClassificationHandler != nil {
+		providers = append(providers, config.ClassificationHandler)
+	}
+	if config.ApprovalHandler != nil {
+		providers = append(providers, config.ApprovalHandler)
+	}
+	if config.ErasureHandler != nil {
+		providers = append(providers, config.ErasureHandler)
+	}
+	if config.FolderHandler != nil {
+		providers = append(providers, config.FolderHandler)
+	}
+	if config.ScheduleHandler != nil {
+		providers = append(providers, config.ScheduleHandler)
+	}
+	if config.RealtimeHandler != nil {
+		providers = append(providers, config.RealtimeHandler)
+	}
+	if config.ChatHandler != nil {
+		providers = append(providers, config.ChatHandler)
+	}
+	if config.LogLevelHandler != nil {
+		providers = append(providers, config.LogLevelHandler)
+	}
+	if config.ReplayHandler != nil {
+		providers = append(providers, config.ReplayHandler)
+	}
+	if config.TelemetryHandler != nil {
+		providers = append(providers, config.TelemetryHandler)
+	}
+	if config.LLMArchiveHandler != nil {
+		providers = append(providers, config.LLMArchiveHandler)
+	}
+	if config.AnalyticsHandler != nil {
+		providers = append(providers, config.AnalyticsHandler)
+	}
+	if config.IntegrationHandler != nil {
+		providers = append(providers, config.IntegrationHandler)
+	}
+	if config.MaintenanceHandler != nil {
+		providers = append(providers, config.MaintenanceHandler)
+	}
+	if config.APIKeyHandler != nil {
+		providers = append(providers, config.APIKeyHandler)
+	}
+	if config.ColumnAccessHandler != nil {
+		providers = append(providers, config.ColumnAccessHandler)
+	}
+	if config.MetricsHandler != nil {
+		providers = append(providers, config.MetricsHandler)
+	}
+	if config.SchemaTimelineHandler != nil {
+		providers = append(providers, config.SchemaTimelineHandler)
+	}
+	providers = append(providers, config.Providers...)
+
+	var groups []RouteGroup
+	for _, provider := range providers {
+		groups = append(groups, provider.Routes()...)
+	}
+	return groups
+}
+
+// registerRouteGroups 按声明注册路由，中间件顺序为认证、角色、权限、限流
+// 同一路由在各API版本下共用一条中间件链，限流计数不因版本不同而分开
+func registerRouteGroups(config *RouterConfig, groups []RouteGroup, versions ...*gin.RouterGroup) {
+	authHandlers := make(map[AuthMode]gin.HandlerFunc)
+	if config.AuthMiddleware != nil {
+		authHandlers[AuthJWT] = config.AuthMiddleware.JWTAuth()
+	}
+	if config.EmbedMiddleware != nil {
+		authHandlers[AuthEmbed] = config.EmbedMiddleware.EmbedAuth()
+	}
+
+	var maintenance gin.HandlerFunc
+	if config.Maintenance != nil {
+		maintenance = maintenanceMiddleware(config.Maintenance)
+	}
+
+	for _, group := range groups {
+		authHandler := authHandlers[group.Auth]
+		if group.Auth == AuthJWT && group.APIKeyScope != "" && config.APIKeyMiddleware != nil {
+			authHandler = config.APIKeyMiddleware.APIKeyAuth(group.APIKeyScope, authHandler)
+		}
+		// 嵌入令牌路由对外暴露，未配置嵌入令牌认证时不注册；
+		// 未配置JWT认证时保持原有行为，受保护路由不做认证（仅用于开发与测试）
+		if group.Auth == AuthEmbed && authHandler == nil {
+			continue
+		}
+
+		for _, route := range group.Routes {
+			var handlers []gin.HandlerFunc
+			if authHandler != nil {
+				handlers = append(handlers, authHandler)
+			}
+			if len(route.Roles) > 0 {
+				handlers = append(handlers, middleware.RequireRole(route.Roles...))
+			}
+			if route.Permission != "" {
+				handlers = append(handlers, middleware.RequirePermission(string(route.Permission)))
+			}
+			if route.BlockedInMaintenance && maintenance != nil {
+				handlers = append(handlers, maintenance)
+			}
+			if route.RateLimit != nil {
+				handlers = append(handlers, middleware.RouteRateLimitMiddleware(route.RateLimit))
+			}
+			handlers = append(handlers, route.Handler)
+			for _, rg := range versions {
+				rg.Group(group.Prefix).Handle(route.Method, route.Path, handlers...)
+			}
+		}
+	}
+}
+
+// OpenAPISpec 根据路由声明生成OpenAPI 3文档
+// 认证方式映射为securitySchemes，角色、权限与限流策略以x-扩展字段给出；deprecated标记整个版本已弃用
+func OpenAPISpec(basePath string, deprecated bool, groups []RouteGroup) gin.H {
+	paths := make(map[string]gin.H)
+	tagSet := make(map[string]bool)
+
+	for _, group := range groups {
+		for _, route := range group.Routes {
+			fullPath, params := openAPIPath(joinRoutePath(joinRoutePath(basePath, group.Prefix), route.Path))
+
+			operation := gin.H{
+				"summary":   route.Summary,
+				"responses": gin.H{"default": gin.H{"description": "见ErrorResponse约定"}},
+			}
+			if deprecated {
+				operation["deprecated"] = true
+			}
+			if group.Tag != "" {
+				operation["tags"] = []string{group.Tag}
+				tagSet[group.Tag] = true
+			}
+			switch group.Auth {
+			case AuthJWT:
+				operation["security"] = []gin.H{{"bearerAuth": []string{}}}
+				if group.APIKeyScope != "" {
+					operation["security"] = []gin.H{{"bearerAuth": []string{}}, {"apiKey": []string{}}}
+					operation["x-api-key-scope"] = group.APIKeyScope
+				}
+			case AuthEmbed:
+				operation["security"] = []gin.H{{"embedToken": []string{}}}
+			}
+			if len(params) > 0 {
+				parameters := make([]gin.H, 0, len(params))
+				for _, name := range params {
+					parameters = append(parameters, gin.H{
+						"name":     name,
+						"in":       "path",
+						"required": true,
+						"schema":   gin.H{"type": "string"},
+					})
+				}
+				operation["parameters"] = parameters
+			}
+			if len(route.Roles) > 0 {
+				operation["x-required-roles"] = route.Roles
+			}
+			if route.Permission != "" {
+				operation["x-required-permission"] = route.Permission
+			}
+			if route.RateLimit != nil {
+				operation["x-rate-limit"] = gin.H{
+					"requests_per_second": route.RateLimit.RequestsPerSecond,
+					"burst":               route.RateLimit.Burst,
+				}
+			}
+			if route.BlockedInMaintenance {
+				operation["x-blocked-in-maintenance"] = true
+			}
+
+			if paths[fullPath] == nil {
+				paths[fullPath] = gin.H{}
+			}
+			paths[fullPath][strings.ToLower(route.Method)] = operation
+		}
+	}
+
+	tags := make([]string, 0, len(tagSet))
+	for tag := range tagSet {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	tagList := make([]gin.H, 0, len(tags))
+	for _, tag := range tags {
+		tagList = append(tagList, gin.H{"name": tag})
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "Chat2SQL API",
+			"version": path.Base(basePath),
+		},
+		"tags":  tagList,
+		"paths": paths,
+		"components": gin.H{
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"embedToken": gin.H{"type": "http", "scheme": "bearer", "description": "嵌入令牌，由POST /embed/tokens签发"},
+				"apiKey":     gin.H{"type": "apiKey", "in": "header", "name": "X-API-Key", "description": "API密钥，由POST /apikeys创建"},
+			},
+		},
+	}
+}
+
+// openAPIHandler 返回预先生成的OpenAPI文档
+func openAPIHandler(spec gin.H) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, spec)
+	}
+}
+
+// joinRoutePath 与gin的分组路径拼接规则一致，保留末尾斜杠
+func joinRoutePath(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := path.Join(base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
+
+// openAPIPath 把gin的:name路径参数转换为OpenAPI的{name}形式
+func openAPIPath(ginPath string) (string, []string) {
+	segments := strings.Split(ginPath, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
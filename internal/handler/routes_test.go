@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+)
+
+// headerAuth 测试用JWT中间件：有Authorization头即视为已认证，角色取自X-Test-Role
+type headerAuth struct{}
+
+func (headerAuth) JWTAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set("user_id", int64(7))
+		c.Set("user_role", c.GetHeader("X-Test-Role"))
+		c.Next()
+	}
+}
+
+// staticRoutes 测试用路由声明
+type staticRoutes []RouteGroup
+
+func (s staticRoutes) Routes() []RouteGroup { return s }
+
+func TestSetupRoutes_AppliesDeclaredPolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	router := gin.New()
+	SetupRoutes(router, &RouterConfig{
+		AuthMiddleware: headerAuth{},
+		Providers: []RouteProvider{staticRoutes{
+			{Prefix: "/public", Tag: "public", Auth: AuthNone, Routes: []Route{
+				{Method: http.MethodGet, Path: "/ping", Handler: ok, Summary: "公开接口"},
+			}},
+			{Prefix: "/reports", Tag: "reports", Auth: AuthJWT, Routes: []Route{
+				{Method: http.MethodGet, Path: "/:id", Handler: ok, Summary: "查看报表"},
+				{Method: http.MethodPut, Path: "/:id", Handler: ok, Summary: "修改报表",
+					Roles: []string{string(repository.RoleManager)}},
+				{Method: http.MethodPost, Path: "/:id/export", Handler: ok, Summary: "导出报表",
+					RateLimit: &middleware.RateLimitConfig{RequestsPerSecond: 1, Burst: 1}},
+				{Method: http.MethodDelete, Path: "/:id", Handler: ok, Summary: "删除报表",
+					Permission: repository.PermSavedQueryEdit},
+			}},
+			{Prefix: "/widgets", Tag: "widgets", Auth: AuthEmbed, Routes: []Route{
+				{Method: http.MethodGet, Path: "/query", Handler: ok, Summary: "嵌入查询"},
+			}},
+		}},
+	})
+
+	serve := func(method, path, role string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if role != "" {
+			req.Header.Set("Authorization", "Bearer test")
+			req.Header.Set("X-Test-Role", role)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/v1/public/ping", ""))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/reports/1", ""))
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/v1/reports/1", "user"))
+
+	// 角色策略：admin始终允许
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/api/v1/reports/1", "user"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/api/v1/reports/1", "manager"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/api/v1/reports/1", "admin"))
+
+	// 权限策略：按角色权限矩阵判断，viewer没有编辑权限
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/api/v1/reports/1", "viewer"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/api/v1/reports/1", "user"))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/api/v1/reports/1", "guest"))
+
+	// 限流策略：按已认证用户计数
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/v1/reports/1/export", "user"))
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodPost, "/api/v1/reports/1/export", "user"))
+
+	// 未配置嵌入令牌认证时不暴露嵌入路由
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/widgets/query", ""))
+}
+
+// stubAPIKeys 测试用API密钥验证器，key-sql只允许调用sql接口，key-mcp-read只允许读取MCP表结构，所属用户为viewer
+type stubAPIKeys struct{}
+
+func (stubAPIKeys) Authenticate(ctx context.Context, key string) (*repository.APIKey, *repository.User, error) {
+	scopes := map[string][]repository.APIKeyScope{
+		"key-sql":      {repository.APIKeyScopeSQL},
+		"key-mcp-read": {repository.APIKeyScopeMCPSchemaRead},
+	}[key]
+	if scopes == nil {
+		return nil, nil, errors.New("unknown key")
+	}
+	apiKey := &repository.APIKey{Scopes: scopes}
+	user := &repository.User{Username: "ci", Role: string(repository.RoleViewer)}
+	user.ID = 9
+	return apiKey, user, nil
+}
+
+func TestSetupRoutes_APIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	whoami := func(c *gin.Context) {
+		userID, _ := middleware.GetUserIDFromContext(c)
+		role, _ := middleware.GetUserRoleFromContext(c)
+		scopes, _ := middleware.GetAPIKeyScopesFromContext(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID, "role": role, "scopes": scopes})
+	}
+
+	router := gin.New()
+	SetupRoutes(router, &RouterConfig{
+		AuthMiddleware:   headerAuth{},
+		APIKeyMiddleware: middleware.NewAPIKeyAuthMiddleware(stubAPIKeys{}, zap.NewNop()),
+		Providers: []RouteProvider{staticRoutes{
+			{Prefix: "/sql", Tag: "sql", Auth: AuthJWT, APIKeyScope: repository.APIKeyScopeSQL, Routes: []Route{
+				{Method: http.MethodGet, Path: "/whoami", Handler: whoami, Summary: "当前用户"},
+				{Method: http.MethodDelete, Path: "/history", Handler: whoami, Summary: "删除历史",
+					Permission: repository.PermSavedQueryEdit},
+			}},
+			{Prefix: "/ai", Tag: "ai", Auth: AuthJWT, APIKeyScope: repository.APIKeyScopeAI, Routes: []Route{
+				{Method: http.MethodGet, Path: "/whoami", Handler: whoami, Summary: "当前用户"},
+			}},
+			{Prefix: "/mcp", Tag: "mcp", Auth: AuthJWT, APIKeyScope: repository.APIKeyScopeMCP, Routes: []Route{
+				{Method: http.MethodGet, Path: "/whoami", Handler: whoami, Summary: "当前用户"},
+			}},
+			{Prefix: "/apikeys", Tag: "apikeys", Auth: AuthJWT, Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: whoami, Summary: "列出密钥"},
+			}},
+		}},
+	})
+
+	serve := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	apiKey := map[string]string{"X-API-Key": "key-sql"}
+
+	w := serve(http.MethodGet, "/api/v1/sql/whoami", apiKey)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":9,"role":"viewer","scopes":["sql"]}`, w.Body.String(), "以密钥所属用户的身份与角色执行")
+
+	// 密钥之外的接口范围、角色权限与非密钥路由均不放行
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/v1/ai/whoami", apiKey).Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/api/v1/sql/history", apiKey).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/apikeys", apiKey).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/sql/whoami", map[string]string{"X-API-Key": "revoked"}).Code)
+
+	// 拥有任一mcp:*范围的密钥可以调用MCP路由，细分范围传给处理器限制可用工具
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/v1/mcp/whoami", apiKey).Code)
+	w = serve(http.MethodGet, "/api/v1/mcp/whoami", map[string]string{"X-API-Key": "key-mcp-read"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":9,"role":"viewer","scopes":["mcp:schema:read"]}`, w.Body.String())
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/v1/sql/whoami", map[string]string{"X-API-Key": "key-mcp-read"}).Code)
+
+	// 未携带密钥时仍使用JWT认证
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/api/v1/ai/whoami", nil).Code)
+	w = serve(http.MethodGet, "/api/v1/ai/whoami", map[string]string{"Authorization": "Bearer test", "X-Test-Role": "user"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":7,"role":"user","scopes":null}`, w.Body.String())
+}
+
+func TestSetupRoutes_OpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, &RouterConfig{
+		AuthHandler:       &AuthHandler{},
+		ConnectionHandler: &ConnectionHandler{},
+		WorkspaceHandler:  &WorkspaceHandler{},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Tags       []string         `json:"tags"`
+			Security   []map[string]any `json:"security"`
+			Roles      []string         `json:"x-required-roles"`
+			Permission string           `json:"x-required-permission"`
+			RateLimit  map[string]int   `json:"x-rate-limit"`
+			Parameters []map[string]any `json:"parameters"`
+		} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+
+	login := spec.Paths["/api/v1/auth/login"]["post"]
+	assert.Empty(t, login.Security)
+	assert.Equal(t, 10, login.RateLimit["burst"])
+
+	schema := spec.Paths["/api/v1/connections/{id}/schema"]["get"]
+	assert.Equal(t, []string{"connections"}, schema.Tags)
+	require.Len(t, schema.Security, 1)
+	assert.Contains(t, schema.Security[0], "bearerAuth")
+	require.Len(t, schema.Parameters, 1)
+	assert.Equal(t, "id", schema.Parameters[0]["name"])
+	assert.Empty(t, schema.Permission)
+	assert.Equal(t, "connection:manage", spec.Paths["/api/v1/connections/"]["post"].Permission)
+
+	policy := spec.Paths["/api/v1/workspace/data-residency"]
+	assert.Empty(t, policy["get"].Roles)
+	assert.Equal(t, []string{"admin"}, policy["put"].Roles)
+}
@@ -0,0 +1,431 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// ScheduleHandler 保存查询定时执行处理器
+// 执行计划按cron表达式定时执行保存查询，执行记录保存结果，并按计划通过邮件或Webhook投递
+type ScheduleHandler struct {
+	schedules *service.QueryScheduleService
+	logger    *zap.Logger
+}
+
+// NewScheduleHandler 创建定时执行处理器实例
+func NewScheduleHandler(schedules *service.QueryScheduleService, logger *zap.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		schedules: schedules,
+		logger:    logger,
+	}
+}
+
+// Routes 声明执行计划路由，访问级别由保存查询所在文件夹的权限决定
+func (h *ScheduleHandler) Routes() []RouteGroup {
+	edit := repository.PermSavedQueryEdit
+	return []RouteGroup{
+		{
+			Prefix: "/schedules",
+			Tag:    "schedules",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.ListSchedules, Summary: "保存查询的执行计划"},
+				{Method: http.MethodPost, Path: "", Handler: h.CreateSchedule, Summary: "创建执行计划", Permission: edit},
+				{Method: http.MethodGet, Path: "/:id", Handler: h.GetSchedule, Summary: "执行计划详情"},
+				{Method: http.MethodPut, Path: "/:id", Handler: h.UpdateSchedule, Summary: "修改执行计划", Permission: edit},
+				{Method: http.MethodDelete, Path: "/:id", Handler: h.DeleteSchedule, Summary: "删除执行计划", Permission: edit},
+				{Method: http.MethodPost, Path: "/:id/run", Handler: h.TriggerSchedule, Summary: "立即执行", Permission: edit},
+				{Method: http.MethodGet, Path: "/:id/runs", Handler: h.ListRuns, Summary: "执行记录"},
+				{Method: http.MethodGet, Path: "/:id/runs/:run_id", Handler: h.GetRun, Summary: "执行记录与结果"},
+			},
+		},
+	}
+}
+
+// ListSchedulesParams 执行计划列表参数
+type ListSchedulesParams struct {
+	SavedQueryID int64 `form:"saved_query_id" binding:"required,gt=0" example:"12"`
+}
+
+// ListScheduleRunsParams 执行记录列表参数
+type ListScheduleRunsParams struct {
+	Limit int `form:"limit,default=20" binding:"min=1,max=100" example:"20"`
+}
+
+// ScheduleRequest 创建或修改执行计划的请求，saved_query_id只在创建时使用
+type ScheduleRequest struct {
+	SavedQueryID   int64   `json:"saved_query_id" example:"12"`
+	Cron           string  `json:"cron" binding:"required,max=100" example:"0 9 * * 1-5"`
+	Timezone       string  `json:"timezone,omitempty" example:"Asia/Shanghai"`                                           // 为空时使用UTC
+	DeliveryType   string  `json:"delivery_type,omitempty" binding:"omitempty,oneof=none email webhook" example:"email"` // 为空时只保存结果不投递
+	DeliveryTarget *string `json:"delivery_target,omitempty" example:"ops@example.com,cfo@example.com"`                  // 逗号分隔的收件人或Webhook地址
+	Enabled        *bool   `json:"enabled,omitempty" example:"true"`                                                     // 为空时启用
+}
+
+// ListSchedules 保存查询的执行计划
+// @Summary 保存查询的执行计划
+// @Description 列出保存查询的全部执行计划，需要保存查询的查看权限
+// @Tags 定时执行
+// @Produce json
+// @Security BearerAuth
+// @Param saved_query_id query int true "保存查询ID"
+// @Success 200 {array} repository.QuerySchedule "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "保存查询不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/schedules [get]
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var params ListSchedulesParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_PARAMS",
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	schedules, err := h.schedules.List(c.Request.Context(), userID, c.GetString("user_role"), params.SavedQueryID)
+	if err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+	if schedules == nil {
+		schedules = []*repository.QuerySchedule{}
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// CreateSchedule 创建执行计划
+// @Summary 创建执行计划
+// @Description 为指定了连接的保存查询创建执行计划，需要能修改该保存查询；两次执行的间隔不能短于配置的最短间隔，邮件投递需要配置SMTP
+// @Tags 定时执行
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ScheduleRequest true "执行计划"
+// @Success 201 {object} repository.QuerySchedule "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "保存查询不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/schedules [post]
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req ScheduleRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+	if req.SavedQueryID <= 0 {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "缺少saved_query_id"))
+		return
+	}
+
+	schedule := req.toSchedule()
+	schedule.SavedQueryID = req.SavedQueryID
+	if err := h.schedules.Create(c.Request.Context(), userID, c.GetString("user_role"), schedule); err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// GetSchedule 执行计划详情
+// @Summary 执行计划详情
+// @Description 获取执行计划及其最近执行状态与下一次执行时间，需要保存查询的查看权限
+// @Tags 定时执行
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "执行计划ID"
+// @Success 200 {object} repository.QuerySchedule "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "执行计划不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/schedules/{id} [get]
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	schedule, err := h.schedules.Get(c.Request.Context(), userID, c.GetString("user_role"), id)
+	if err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateSchedule 修改执行计划
+// @Summary 修改执行计划
+// @Description 修改cron表达式、时区、投递方式与启用状态，并重新计算下一次执行时间，需要能修改对应的保存查询
+// @Tags 定时执行
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "执行计划ID"
+// @Param request body ScheduleRequest true "执行计划"
+// @Success 200 {object} repository.QuerySchedule "修改成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "执行计划不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/schedules/{id} [put]
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ScheduleRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	update := req.toSchedule()
+	update.ID = id
+	schedule, err := h.schedules.Update(c.Request.Context(), userID, c.GetString("user_role"), update)
+	if err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteSchedule 删除执行计划
+// @Summary 删除执行计划
+// @Description 删除执行计划，需要能修改对应的保存查询
+// @Tags 定时执行
+// @Security BearerAuth
+// @Param id path int true "执行计划ID"
+// @Success 204 "删除成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "执行计划不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/schedules/{id} [delete]
+func (h *ScheduleHandler) DeleteSchedule(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.schedules.Delete(c.Request.Context(), userID, c.GetString("user_role"), id); err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TriggerSchedule 立即执行
+// @Summary 立即执行
+// @Description 把启用中的执行计划的下一次执行时间提前到当前时间，调度器在下一轮扫描时执行，之后按cron恢复
+// @Tags 定时执行
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "执行计划ID"
+// @Success 202 {object} repository.QuerySchedule "已安排执行"
+// @Failure 400 {object} ErrorResponse "执行计划已停用"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "执行计划不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/schedules/{id}/run [post]
+func (h *ScheduleHandler) TriggerSchedule(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	schedule, err := h.schedules.Trigger(c.Request.Context(), userID, c.GetString("user_role"), id)
+	if err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, schedule)
+}
+
+// ListRuns 执行记录
+// @Summary 执行记录
+// @Description 按开始时间倒序列出执行计划的执行记录，不含结果；超过保留时长的记录会被清理
+// @Tags 定时执行
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "执行计划ID"
+// @Param limit query int false "返回条数" default(20)
+// @Success 200 {array} repository.QueryScheduleRun "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "执行计划不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/schedules/{id}/runs [get]
+func (h *ScheduleHandler) ListRuns(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var params ListScheduleRunsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_PARAMS",
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	runs, err := h.schedules.ListRuns(c.Request.Context(), userID, c.GetString("user_role"), id, params.Limit)
+	if err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+	if runs == nil {
+		runs = []*repository.QueryScheduleRun{}
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetRun 执行记录与结果
+// @Summary 执行记录与结果
+// @Description 获取执行记录及其保存的结果，结果最多保存配置的行数
+// @Tags 定时执行
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "执行计划ID"
+// @Param run_id path int true "执行记录ID"
+// @Success 200 {object} repository.QueryScheduleRun "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "文件夹权限不足"
+// @Failure 404 {object} ErrorResponse "执行记录不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/schedules/{id}/runs/{run_id} [get]
+func (h *ScheduleHandler) GetRun(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	id, ok := h.pathID(c, "id")
+	if !ok {
+		return
+	}
+	runID, ok := h.pathID(c, "run_id")
+	if !ok {
+		return
+	}
+
+	run, err := h.schedules.GetRun(c.Request.Context(), userID, c.GetString("user_role"), id, runID)
+	if err != nil {
+		h.respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// toSchedule 转换为执行计划模型
+func (r *ScheduleRequest) toSchedule() *repository.QuerySchedule {
+	enabled := r.Enabled == nil || *r.Enabled
+	return &repository.QuerySchedule{
+		CronExpr:       r.Cron,
+		Timezone:       r.Timezone,
+		DeliveryType:   r.DeliveryType,
+		DeliveryTarget: r.DeliveryTarget,
+		Enabled:        enabled,
+	}
+}
+
+// pathID 解析路径中的ID参数，格式错误时直接写出400响应
+func (h *ScheduleHandler) pathID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_ID", "ID格式错误"))
+		return 0, false
+	}
+	return id, true
+}
+
+// bindJSON 绑定请求体，失败时直接写出400响应
+func (h *ScheduleHandler) bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// respondScheduleError 将定时执行服务错误映射为HTTP响应
+func (h *ScheduleHandler) respondScheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, NewErrorResponse("FOLDER_PERMISSION_DENIED", "文件夹权限不足"))
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_SCHEDULE",
+			Message: "执行计划无效",
+			Details: err.Error(),
+		})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("NOT_FOUND", "执行计划或保存查询不存在"))
+	default:
+		h.logger.Error("Schedule operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("SCHEDULE_ERROR", "执行计划操作失败"))
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestScheduleHandler_RejectsInvalidRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 参数校验在调用服务之前完成，服务为空也不会被调用
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(3))
+		c.Set("user_role", "user")
+	})
+	for _, group := range NewScheduleHandler(nil, zaptest.NewLogger(t)).Routes() {
+		for _, route := range group.Routes {
+			router.Handle(route.Method, group.Prefix+route.Path, route.Handler)
+		}
+	}
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for name, tc := range map[string]struct{ method, path, body string }{
+		"列表缺少saved_query_id": {http.MethodGet, "/schedules", ""},
+		"创建缺少saved_query_id": {http.MethodPost, "/schedules", `{"cron":"@daily"}`},
+		"创建缺少cron":           {http.MethodPost, "/schedules", `{"saved_query_id":1}`},
+		"不支持的投递方式":           {http.MethodPost, "/schedules", `{"saved_query_id":1,"cron":"@daily","delivery_type":"sms"}`},
+		"ID格式错误":             {http.MethodGet, "/schedules/abc", ""},
+		"执行记录条数超过上限":         {http.MethodGet, "/schedules/1/runs?limit=500", ""},
+		"执行记录ID格式错误":         {http.MethodGet, "/schedules/1/runs/0", ""},
+	} {
+		w := serve(tc.method, tc.path, tc.body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+}
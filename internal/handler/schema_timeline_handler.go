@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+const (
+	defaultSchemaTimelineDays = 30 // 时间线默认覆盖的天数
+	maxSchemaTimelineDays     = 90 // 时间线最多覆盖的天数
+)
+
+// SchemaTimelineHandler 表结构变更时间线处理器
+// 将连接上的查询失败与表结构刷新发现的变更对照，定位反复失败的查询是从哪次变更之后开始失败的
+type SchemaTimelineHandler struct {
+	timeline *service.SchemaTimelineService
+	logger   *zap.Logger
+}
+
+// NewSchemaTimelineHandler 创建表结构变更时间线处理器实例
+func NewSchemaTimelineHandler(timeline *service.SchemaTimelineService, logger *zap.Logger) *SchemaTimelineHandler {
+	return &SchemaTimelineHandler{
+		timeline: timeline,
+		logger:   logger,
+	}
+}
+
+// Routes 声明时间线路由，由服务校验连接所有权
+func (h *SchemaTimelineHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/connections",
+			Tag:    "connections",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/:id/schema-timeline", Handler: h.GetTimeline, Summary: "表结构变更与查询失败时间线"},
+			},
+		},
+	}
+}
+
+// GetTimeline 获取连接的表结构变更与查询失败时间线
+// @Summary 表结构变更与查询失败时间线
+// @Description 按时间列出表结构刷新发现的变更与执行失败的查询，并找出引用了此前被删除或修改的表、列的失败查询
+// @Tags connections
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "连接ID"
+// @Param days query int false "覆盖天数，默认30，最大90"
+// @Success 200 {object} service.SchemaTimeline "时间线"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "不是连接所有者"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/connections/{id}/schema-timeline [get]
+func (h *SchemaTimelineHandler) GetTimeline(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	connectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_CONNECTION_ID", "连接ID格式错误"))
+		return
+	}
+
+	days := defaultSchemaTimelineDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSchemaTimelineDays {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "days必须在1到90之间"))
+			return
+		}
+		days = n
+	}
+
+	timeline, err := h.timeline.Timeline(c.Request.Context(), userID, connectionID, days)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, timeline)
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, NewErrorResponse("PERMISSION_DENIED", "只有连接所有者可以查看表结构变更时间线"))
+	default:
+		h.logger.Error("Failed to build schema timeline", zap.Int64("connection_id", connectionID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("SCHEMA_TIMELINE_ERROR", "获取表结构变更时间线失败"))
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// SetRunningQueries 启用取消正在执行的查询
+func (h *SQLHandler) SetRunningQueries(registry *service.RunningQueryRegistry) {
+	h.runningQueries = registry
+}
+
+// CancelQuery 取消正在执行的查询
+// @Summary 取消正在执行的查询
+// @Description 中止一条仍在执行的查询，PostgreSQL连接会向服务端发送取消请求，执行请求随即返回cancelled状态；
+// @Description 查询ID见GET /api/v1/users/me/activity的running_queries。只能取消自己的查询，管理员可以取消任何人的查询
+// @Tags SQL查询
+// @Produce json
+// @Security BearerAuth
+// @Param query_id path int true "运行中查询ID"
+// @Success 204 "已取消"
+// @Failure 400 {object} ErrorResponse "查询ID无效"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "查询不存在或已结束"
+// @Router /api/v1/sql/execute/{query_id} [delete]
+func (h *SQLHandler) CancelQuery(c *gin.Context) {
+	userID := h.getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	queryID, err := strconv.ParseUint(c.Param("query_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_QUERY_ID", "无效的查询ID"))
+		return
+	}
+
+	asAdmin := c.GetString("user_role") == string(repository.RoleAdmin)
+	if err := h.runningQueries.Cancel(queryID, userID, asAdmin); err != nil {
+		if errors.Is(err, service.ErrRunningQueryNotFound) {
+			c.JSON(http.StatusNotFound, NewErrorResponse("QUERY_NOT_RUNNING", "查询不存在或已结束"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("CANCEL_FAILED", "取消查询失败"))
+		return
+	}
+
+	h.logger.Info("Running query cancelled",
+		zap.Uint64("query_id", queryID),
+		zap.Int64("user_id", userID),
+		zap.Bool("as_admin", asAdmin))
+	c.Status(http.StatusNoContent)
+}
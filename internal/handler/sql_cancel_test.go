@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+func TestSQLHandler_CancelQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := service.NewRunningQueryRegistry()
+	ctx, finish := registry.Track(service.WithQueryOwner(context.Background(), 7), 3, "SELECT * FROM events")
+	defer finish()
+	queryID := registry.ListByUser(7)[0].ID
+
+	h := NewSQLHandler(nil, nil, nil, zaptest.NewLogger(t))
+	h.SetRunningQueries(registry)
+
+	cancel := func(userID int64, role, id string) int {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Set("user_role", role)
+		})
+		r.DELETE("/sql/execute/:query_id", h.CancelQuery)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/sql/execute/"+id, nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, cancel(7, "user", "abc"))
+	assert.Equal(t, http.StatusNotFound, cancel(8, "user", fmt.Sprint(queryID)), "不能取消他人的查询")
+	require.NoError(t, ctx.Err())
+
+	assert.Equal(t, http.StatusNoContent, cancel(7, "user", fmt.Sprint(queryID)))
+	assert.True(t, service.QueryCancelled(ctx))
+
+	// 管理员可以取消任何人的查询
+	otherCtx, finishOther := registry.Track(service.WithQueryOwner(context.Background(), 9), 3, "SELECT 1")
+	defer finishOther()
+	assert.Equal(t, http.StatusNoContent, cancel(1, "admin", fmt.Sprint(registry.ListByUser(9)[0].ID)))
+	assert.True(t, service.QueryCancelled(otherCtx))
+
+	assert.Equal(t, http.StatusNotFound, cancel(7, "user", "999"))
+}
+
+func TestSQLHandler_ExecutionResultInterrupted(t *testing.T) {
+	h := NewSQLHandler(nil, nil, nil, zaptest.NewLogger(t))
+
+	result := h.executionResult("SELECT 1", &service.QueryResult{Status: string(repository.QueryCancelled), Error: service.ErrQueryCancelled.Error()}, service.ErrQueryCancelled)
+	assert.Equal(t, string(repository.QueryCancelled), result.Status)
+
+	result = h.executionResult("SELECT 1", &service.QueryResult{Status: string(repository.QueryTimeout)}, context.DeadlineExceeded)
+	assert.Equal(t, string(repository.QueryTimeout), result.Status)
+
+	result = h.executionResult("SELECT 1", &service.QueryResult{Status: string(repository.QuerySuccess)}, fmt.Errorf("结果集过大"))
+	assert.Equal(t, string(repository.QueryError), result.Status)
+}
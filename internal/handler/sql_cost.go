@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// SetCostPrecheck 启用代价预检：执行生成的SQL前运行EXPLAIN，预估代价或读取行数超过上限时拒绝执行
+func (h *SQLHandler) SetCostPrecheck(precheck *service.CostPrecheck) {
+	h.costPrecheck = precheck
+}
+
+// precheckCost 对提交执行的SQL做代价预检，未启用时返回nil
+// 按服务端记录的执行路径判定：确认执行与直接执行都需要预检，不依赖请求中可省略的natural_query与ai_confidence
+func (h *SQLHandler) precheckCost(ctx context.Context, sql string, connection *repository.DatabaseConnection, executionPath string) *service.CostCheck {
+	if h.costPrecheck == nil || !costPrecheckedPaths[repository.ExecutionPath(executionPath)] {
+		return nil
+	}
+	return h.costPrecheck.Check(ctx, sql, connection)
+}
+
+// costPrecheckedPaths 需要代价预检的执行路径；自动执行由工作空间的代价上限把关
+var costPrecheckedPaths = map[repository.ExecutionPath]bool{
+	repository.ExecutionConfirmed: true,
+	repository.ExecutionManual:    true,
+}
+
+// rejectByCost 代价预检拒绝执行：查询历史记为失败，响应中返回拒绝原因与执行计划摘要
+func (h *SQLHandler) rejectByCost(c *gin.Context, userID int64, history *repository.QueryHistory, check *service.CostCheck) {
+	history.Status = string(repository.QueryError)
+	history.ErrorMessage = &check.Reason
+	if err := h.queryRepo.Update(c.Request.Context(), history); err != nil {
+		h.logger.Warn("Failed to update query history",
+			zap.Error(err),
+			zap.Int64("query_id", history.ID))
+	}
+
+	h.logger.Info("SQL rejected by cost precheck",
+		zap.Int64("user_id", userID),
+		zap.Int64("query_id", history.ID),
+		zap.Float64("total_cost", check.Plan.TotalCost),
+		zap.Float64("scan_rows", check.Plan.ScanRows))
+
+	c.JSON(http.StatusOK, &SQLExecutionResult{
+		QueryID:    history.ID,
+		Status:     string(repository.QueryError),
+		Error:      check.Reason,
+		Warnings:   check.Warnings,
+		Guardrails: []service.Guardrail{check.Guardrail},
+		Plan:       check.Plan,
+	})
+}
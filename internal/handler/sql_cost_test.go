@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// fixedPlanExplainer 返回固定的执行计划
+type fixedPlanExplainer struct {
+	plan  *service.PlanSummary
+	calls int
+}
+
+func (f *fixedPlanExplainer) ExplainPlan(ctx context.Context, query string, connection *repository.DatabaseConnection) (*service.PlanSummary, error) {
+	f.calls++
+	return f.plan, nil
+}
+
+func TestSQLHandler_ExecuteSQL_CostPrecheck(t *testing.T) {
+	suite := &HandlerIntegrationTestSuite{}
+	router := suite.setupTestRouter(true, 1)
+
+	suite.mockConnectionRepo.On("GetByID", mock.Anything, int64(1)).Return(testutil.NewConnection(1, 1), nil)
+	suite.mockQueryRepo.On("Create", mock.Anything, mock.AnythingOfType("*repository.QueryHistory")).Return(nil)
+	suite.mockQueryRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	suite.mockSQLExecutor.On("ExecuteQuery", mock.Anything, mock.Anything, mock.Anything).
+		Return(&service.QueryResult{Status: string(repository.QuerySuccess), Rows: []map[string]any{}}, nil)
+
+	explainer := &fixedPlanExplainer{plan: &service.PlanSummary{TotalCost: 250000, ScanRows: 4000000, NodeType: "Seq Scan", FullScans: []string{"events"}}}
+	suite.sqlHandler.SetCostPrecheck(service.NewCostPrecheck(explainer, service.CostThresholds{MaxCost: 100000}, zaptest.NewLogger(t)))
+
+	execute := func(req ExecuteSQLRequest) *SQLExecutionResult {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/sql/execute", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result SQLExecutionResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return &result
+	}
+
+	// 生成的SQL超过代价上限时不执行，返回执行计划摘要
+	result := execute(ExecuteSQLRequest{SQL: "SELECT * FROM events", NaturalQuery: "所有事件", ConnectionID: 1})
+	assert.Equal(t, string(repository.QueryError), result.Status)
+	assert.Contains(t, result.Error, "预估代价")
+	require.NotNil(t, result.Plan)
+	assert.Equal(t, []string{"events"}, result.Plan.FullScans)
+	assert.Equal(t, []service.Guardrail{{Type: service.GuardrailCostLimit, Limit: 100000}}, result.Guardrails)
+	suite.mockSQLExecutor.AssertNotCalled(t, "ExecuteQuery", mock.Anything, mock.Anything, mock.Anything)
+	history := suite.mockQueryRepo.Calls[1].Arguments.Get(1).(*repository.QueryHistory)
+	assert.Equal(t, string(repository.QueryError), history.Status)
+
+	// 省略natural_query与ai_confidence同样预检
+	result = execute(ExecuteSQLRequest{SQL: "SELECT * FROM events", ConnectionID: 1})
+	assert.Equal(t, string(repository.QueryError), result.Status)
+	assert.Equal(t, 2, explainer.calls)
+
+	// 代价在上限内时照常执行并返回执行计划
+	explainer.plan = &service.PlanSummary{TotalCost: 500, ScanRows: 100, NodeType: "Index Scan"}
+	result = execute(ExecuteSQLRequest{SQL: "SELECT * FROM events WHERE id = 1", ConnectionID: 1})
+	assert.Equal(t, string(repository.QuerySuccess), result.Status)
+	require.NotNil(t, result.Plan)
+	assert.Equal(t, 3, explainer.calls)
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// exportFlushRows 每写出这么多行推送一次响应，客户端边查询边下载
+const exportFlushRows = 1000
+
+// ExportQueryResult 导出查询结果
+// @Summary 导出查询结果
+// @Description 重新执行历史查询，按format将结果逐行写成CSV或Excel（xlsx）文件下载，不在内存中缓存整个结果集。受限列按当前角色脱敏或过滤；超过EXPORT_MAX_ROWS（xlsx另受工作表1048575行的限制）的部分被截断，上限见X-Export-Row-Limit响应头
+// @Tags SQL查询
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param id path int true "查询ID"
+// @Param format query string false "导出格式" Enums(csv, xlsx) default(csv)
+// @Success 200 {file} file "导出文件"
+// @Failure 400 {object} ErrorResponse "请求参数错误或查询无可导出的SQL"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权访问或SQL操作被禁止"
+// @Failure 404 {object} ErrorResponse "查询不存在"
+// @Failure 500 {object} ErrorResponse "查询执行失败"
+// @Failure 504 {object} ErrorResponse "导出超时"
+// @Router /api/v1/sql/history/{id}/export [get]
+func (h *SQLHandler) ExportQueryResult(c *gin.Context) {
+	userID := h.getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	queryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_QUERY_ID", "无效的查询ID"))
+		return
+	}
+	format, err := service.ParseExportFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_EXPORT_FORMAT", "导出格式只支持csv或xlsx"))
+		return
+	}
+
+	query, err := h.queryRepo.GetByID(c.Request.Context(), queryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("QUERY_NOT_FOUND", "查询记录不存在"))
+		return
+	}
+	if query.UserID != userID {
+		c.JSON(http.StatusForbidden, NewErrorResponse("ACCESS_DENIED", "无权访问该查询记录"))
+		return
+	}
+	if query.ConnectionID == nil || strings.TrimSpace(query.GeneratedSQL) == "" {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("QUERY_NOT_EXPORTABLE", "该查询没有可导出的SQL或数据库连接"))
+		return
+	}
+
+	// 导出会重新执行SQL，按执行时的规则重新检查
+	if err := h.validateSQLSecurity(query.GeneratedSQL); err != nil {
+		c.JSON(http.StatusForbidden, NewErrorResponse("SQL_FORBIDDEN", err.Error()))
+		return
+	}
+	connection, err := h.connectionRepo.GetByID(c.Request.Context(), *query.ConnectionID)
+	if err != nil || !h.canExecuteOn(c, userID, connection) {
+		c.JSON(http.StatusForbidden, NewErrorResponse("CONNECTION_FORBIDDEN", "无权访问该数据库连接"))
+		return
+	}
+
+	// 导出文件在本服务的导出位置生成，须符合工作空间的数据驻留策略
+	if h.residency != nil {
+		if err := h.residency.CheckLocalWrite(c.Request.Context(), userID, repository.StorageExport); err != nil {
+			if service.IsResidencyViolation(err) {
+				c.JSON(http.StatusForbidden, NewErrorResponse("RESIDENCY_VIOLATION", err.Error()))
+				return
+			}
+			h.logger.Error("Failed to check data residency", zap.Error(err), zap.Int64("user_id", userID))
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("RESIDENCY_ERROR", "无法校验数据驻留策略，已拒绝导出"))
+			return
+		}
+	}
+
+	lineage := h.validator.ExtractColumnLineage(query.GeneratedSQL)
+	writer := &exportResponseWriter{
+		c:        c,
+		format:   format,
+		filename: fmt.Sprintf("query-%d.%s", queryID, format),
+		lineage:  lineage,
+	}
+	// 分级加载失败时拒绝导出，避免泄露受限列
+	if h.classifications != nil {
+		role, _ := c.Get("user_role")
+		roleStr, _ := role.(string)
+		writer.policy, err = h.classifications.Policy(c.Request.Context(), connection.ID, userID, roleStr)
+		if err != nil {
+			h.logger.Error("Failed to load column classifications",
+				zap.Error(err),
+				zap.Int64("connection_id", connection.ID))
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("CLASSIFICATION_ERROR", "无法加载列数据分级，已拒绝导出"))
+			return
+		}
+	}
+	if h.columnLabels != nil {
+		locale := service.DetectLocale(query.NaturalQuery)
+		writer.labels = func(columns []string) []string {
+			labels := h.columnLabels.Label(c.Request.Context(), connection.ID, query.NaturalQuery, locale, columns, lineage)
+			return service.ColumnHeaders(columns, labels)
+		}
+	}
+
+	writer.maxRows = h.export.MaxRows
+	if limit := format.MaxRows(); limit > 0 && limit < writer.maxRows {
+		writer.maxRows = limit
+	}
+
+	ctx, cancel := context.WithTimeout(service.WithQueryOwner(c.Request.Context(), userID), h.export.Timeout)
+	defer cancel()
+	written, truncated, err := h.sqlExecutor.StreamQuery(ctx, query.GeneratedSQL, connection, writer.maxRows, writer)
+	if err == nil {
+		err = writer.close()
+	}
+	if err != nil {
+		h.logger.Error("Failed to export query result",
+			zap.Error(err),
+			zap.Int64("query_id", queryID),
+			zap.Int64("rows_written", written))
+		if writer.export != nil {
+			// 文件已开始下载，只能中止响应
+			c.Abort()
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, NewErrorResponse("EXPORT_TIMEOUT", "导出超时"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    "EXPORT_FAILED",
+			Message: "导出查询结果失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Query result exported",
+		zap.Int64("user_id", userID),
+		zap.Int64("query_id", queryID),
+		zap.String("format", string(format)),
+		zap.Int64("rows", written),
+		zap.Bool("truncated", truncated))
+}
+
+// exportResponseWriter 将流式查询结果按列策略处理后写入HTTP响应
+// 读到列名时才写出响应头，此前的错误仍可按JSON返回
+type exportResponseWriter struct {
+	c        *gin.Context
+	format   service.ExportFormat
+	filename string
+	maxRows  int64
+	policy   *service.ColumnPolicy
+	lineage  []service.ColumnLineage
+	labels   func(columns []string) []string // 可选：推导表头展示名
+
+	export  service.ExportWriter
+	source  []string // 查询返回的列
+	columns []string // 按列策略保留的列
+	row     []any
+	rows    int64
+}
+
+func (w *exportResponseWriter) WriteHeader(columns []string) error {
+	w.source = columns
+	w.columns = columns
+	header := w.c.Writer.Header()
+	if w.policy != nil {
+		var guardrails []service.Guardrail
+		w.columns, _, guardrails = w.policy.ApplyToRows(columns, nil, w.lineage)
+		types := make([]string, len(guardrails))
+		for i, guardrail := range guardrails {
+			types[i] = guardrail.Type
+		}
+		if len(types) > 0 {
+			header.Set("X-Export-Guardrails", strings.Join(types, ","))
+		}
+	}
+	headers := w.columns
+	if w.labels != nil {
+		headers = w.labels(w.columns)
+	}
+
+	header.Set("Content-Type", w.format.ContentType())
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+	header.Set("X-Export-Row-Limit", strconv.FormatInt(w.maxRows, 10))
+	w.c.Status(http.StatusOK)
+
+	w.export = service.NewExportWriter(w.format, w.c.Writer)
+	w.row = make([]any, len(w.columns))
+	return w.export.WriteHeader(headers)
+}
+
+func (w *exportResponseWriter) WriteRow(row map[string]any) error {
+	if w.policy != nil {
+		w.policy.ApplyToRows(w.source, []map[string]any{row}, w.lineage)
+	}
+	for i, column := range w.columns {
+		w.row[i] = row[column]
+	}
+	if err := w.export.WriteRow(w.row); err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%exportFlushRows == 0 {
+		if err := w.export.Flush(); err != nil {
+			return err
+		}
+		w.c.Writer.Flush()
+	}
+	return nil
+}
+
+// close 写完文件尾；查询没有返回列时不会调用WriteHeader，按空结果写出文件
+func (w *exportResponseWriter) close() error {
+	if w.export == nil {
+		if err := w.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	return w.export.Close()
+}
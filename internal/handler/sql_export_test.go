@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+func TestSQLHandler_ExportQueryResult(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	connectionID := int64(3)
+	connection := testutil.NewConnection(connectionID, 7)
+	connRepo := &MockConnectionRepository{}
+	connRepo.On("GetByID", mock.Anything, connectionID).Return(connection, nil)
+
+	exportable := testutil.NewQueryHistory(42, 7, testutil.WithQuery("客户消费", "SELECT name, email, amount FROM customers"), testutil.WithQueryConnection(connectionID))
+	broken := testutil.NewQueryHistory(43, 7, testutil.WithQuery("缺失的表", "SELECT * FROM missing"), testutil.WithQueryConnection(connectionID))
+	unsaved := testutil.NewQueryHistory(44, 7, testutil.WithQuery("常量", "SELECT 1"))
+	others := testutil.NewQueryHistory(45, 8, testutil.WithQuery("常量", "SELECT 1"), testutil.WithQueryConnection(connectionID))
+
+	queryRepo := &MockQueryHistoryRepository{}
+	for _, query := range []*repository.QueryHistory{exportable, broken, unsaved, others} {
+		queryRepo.On("GetByID", mock.Anything, query.ID).Return(query, nil)
+	}
+	queryRepo.On("GetByID", mock.Anything, int64(99)).Return(nil, repository.ErrNotFound)
+
+	executor := &MockSQLExecutor{}
+	executor.On("StreamQuery", mock.Anything, exportable.GeneratedSQL, connection, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			w := args.Get(4).(service.RowWriter)
+			require.NoError(t, w.WriteHeader([]string{"name", "email", "amount"}))
+			require.NoError(t, w.WriteRow(map[string]any{"name": "Alice", "email": "a@example.com", "amount": 19.5}))
+			require.NoError(t, w.WriteRow(map[string]any{"name": "=cmd", "email": nil, "amount": 5}))
+		}).
+		Return(int64(2), false, nil)
+	executor.On("StreamQuery", mock.Anything, broken.GeneratedSQL, connection, mock.Anything, mock.Anything).
+		Return(int64(0), false, errors.New("relation \"missing\" does not exist"))
+
+	classifications := service.NewClassificationService(&stubClassificationRepository{items: []*repository.ColumnClassification{
+		{ConnectionID: connectionID, TableName: "customers", ColumnName: "email", Classification: string(repository.ClassificationPII)},
+		{ConnectionID: connectionID, TableName: "customers", ColumnName: "amount", Classification: string(repository.ClassificationFinancial)},
+	}}, connRepo, zap.NewNop())
+
+	h := NewSQLHandler(queryRepo, connRepo, executor, zap.NewNop())
+	h.SetClassificationService(classifications)
+	h.SetExportConfig(&config.ExportConfig{MaxRows: 2000000, Timeout: time.Minute})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+		c.Set("user_role", string(repository.RoleUser))
+	})
+	router.GET("/history/:id/export", h.ExportQueryResult)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/history/42/export")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="query-42.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "2000000", w.Header().Get("X-Export-Row-Limit"))
+	assert.Equal(t, "columns_masked,columns_removed", w.Header().Get("X-Export-Guardrails"))
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\ufeff"))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"name", "email"},
+		{"Alice", "***"},
+		{"'=cmd", ""},
+	}, records, "PII脱敏、财务列过滤、公式转义")
+
+	w = get("/history/42/export?format=xlsx")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="query-42.xlsx"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "1048575", w.Header().Get("X-Export-Row-Limit"), "受工作表行数限制")
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			r, err := file.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			sheet = string(content)
+		}
+	}
+	assert.Contains(t, sheet, "Alice")
+	assert.NotContains(t, sheet, "a@example.com")
+
+	w = get("/history/43/export")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "EXPORT_FAILED", "开始写文件前的错误按JSON返回")
+
+	assert.Equal(t, http.StatusBadRequest, get("/history/42/export?format=pdf").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/history/44/export").Code, "未关联连接的记录")
+	assert.Equal(t, http.StatusForbidden, get("/history/45/export").Code, "不能导出其他用户的记录")
+	assert.Equal(t, http.StatusNotFound, get("/history/99/export").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/history/abc/export").Code)
+	executor.AssertNumberOfCalls(t, "StreamQuery", 3)
+}
+
+func TestSQLHandler_ExportQueryResult_Residency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	connectionID := int64(3)
+	connection := testutil.NewConnection(connectionID, 7)
+	connRepo := &MockConnectionRepository{}
+	connRepo.On("GetByID", mock.Anything, connectionID).Return(connection, nil)
+	query := testutil.NewQueryHistory(42, 7, testutil.WithQuery("常量", "SELECT 1"), testutil.WithQueryConnection(connectionID))
+	queryRepo := &MockQueryHistoryRepository{}
+	queryRepo.On("GetByID", mock.Anything, query.ID).Return(query, nil)
+	executor := &MockSQLExecutor{}
+
+	// 工作空间要求导出写入欧洲区域，本服务的导出位置在美国区域
+	workspaces := &stubWorkspaceRepository{workspace: &repository.Workspace{DataResidency: &repository.DataResidencyPolicy{
+		Region:    "eu-central-1",
+		Locations: map[string]*repository.StorageLocation{"export": {Bucket: "exports-eu", Region: "eu-central-1"}},
+	}}}
+	residencyConfig := &config.ResidencyConfig{Locations: map[string]config.StorageTarget{"export": {Bucket: "exports-us", Region: "us-east-1"}}}
+
+	h := NewSQLHandler(queryRepo, connRepo, executor, zap.NewNop())
+	h.SetResidencyService(service.NewResidencyService(workspaces, residencyConfig, zap.NewNop()))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	router.GET("/history/:id/export", h.ExportQueryResult)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/42/export", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "RESIDENCY_VIOLATION")
+	executor.AssertNotCalled(t, "StreamQuery", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSQLHandler_ExportQueryResult_Timeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	connectionID := int64(3)
+	connection := testutil.NewConnection(connectionID, 7)
+	connRepo := &MockConnectionRepository{}
+	connRepo.On("GetByID", mock.Anything, connectionID).Return(connection, nil)
+	query := testutil.NewQueryHistory(42, 7, testutil.WithQuery("事件笛卡尔积", "SELECT * FROM events CROSS JOIN events e2"), testutil.WithQueryConnection(connectionID))
+	queryRepo := &MockQueryHistoryRepository{}
+	queryRepo.On("GetByID", mock.Anything, query.ID).Return(query, nil)
+
+	executor := &MockSQLExecutor{}
+	executor.On("StreamQuery", mock.Anything, query.GeneratedSQL, connection, int64(1000000), mock.Anything).
+		Return(int64(0), false, context.DeadlineExceeded)
+
+	h := NewSQLHandler(queryRepo, connRepo, executor, zap.NewNop())
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	router.GET("/history/:id/export", h.ExportQueryResult)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/42/export", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), "EXPORT_TIMEOUT")
+}
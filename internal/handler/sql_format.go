@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"chat2sql-go/internal/sqlformat"
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+// FormatSQLRequest SQL格式化请求
+type FormatSQLRequest struct {
+	SQL string `json:"sql" binding:"required" example:"select id, name from users where status = 'active' order by id"`
+}
+
+// FormatSQLResponse SQL格式化结果
+type FormatSQLResponse struct {
+	SQL       string `json:"sql" example:"SELECT\n  id,\n  name\nFROM users\nWHERE status = 'active'\nORDER BY id"`
+	Formatted bool   `json:"formatted" example:"true"` // false表示SQL无法安全格式化，sql为原文
+}
+
+// FormatSQL 格式化SQL
+// @Summary 格式化SQL
+// @Description 按统一规则排版SQL：关键字大写，每个子句另起一行，多列SELECT每列一行，WHERE/HAVING/JOIN条件按AND/OR换行，子查询缩进。
+// @Description 只改变空白与关键字大小写，注释保留；无法保证不改变语义时原样返回且formatted为false
+// @Tags SQL查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body FormatSQLRequest true "SQL格式化请求"
+// @Success 200 {object} FormatSQLResponse "格式化完成"
+// @Failure 400 {object} ErrorResponse "请求参数错误或SQL无法解析"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Router /api/v1/sql/format [post]
+func (h *SQLHandler) FormatSQL(c *gin.Context) {
+	if h.getUserIDFromContext(c) == 0 {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req FormatSQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "请求参数格式错误"))
+		return
+	}
+
+	formatted, err := sqlformat.Format(req.SQL)
+	switch {
+	case errors.Is(err, sqlvalidator.ErrSyntax):
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_SQL", err.Error()))
+		return
+	case err != nil:
+		c.JSON(http.StatusOK, FormatSQLResponse{SQL: req.SQL})
+		return
+	}
+	c.JSON(http.StatusOK, FormatSQLResponse{SQL: formatted, Formatted: true})
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSQLHandler_FormatSQL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewSQLHandler(nil, nil, nil, zaptest.NewLogger(t))
+
+	format := func(sql string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+		r.POST("/sql/format", h.FormatSQL)
+		body, _ := json.Marshal(FormatSQLRequest{SQL: sql})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/sql/format", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := format("select id, name from users where status = 'active' and age > 18")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response FormatSQLResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Formatted)
+	assert.Equal(t, "SELECT\n  id,\n  name\nFROM users\nWHERE status = 'active'\n  AND age > 18", response.SQL)
+
+	// 无法安全格式化时原样返回
+	sql := "select id # note\nfrom t"
+	w = format(sql)
+	require.Equal(t, http.StatusOK, w.Code)
+	response = FormatSQLResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.False(t, response.Formatted)
+	assert.Equal(t, sql, response.SQL)
+
+	w = format("select 'abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_SQL")
+}
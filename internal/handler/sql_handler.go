@@ -2,7 +2,7 @@ package handler
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -11,24 +11,40 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"chat2sql-go/internal/config"
 	"chat2sql-go/internal/middleware"
 	"chat2sql-go/internal/repository"
 	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/sqlvalidator"
+	"chat2sql-go/internal/tracing"
 )
 
 // SQLExecutorInterface SQL执行器接口 - 直接使用Service层的QueryResult
 type SQLExecutorInterface interface {
 	ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*service.QueryResult, error)
+	StreamQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64, w service.RowWriter) (int64, bool, error)
 }
 
 
 // SQLHandler SQL查询处理器
 // 处理SQL执行、查询历史、语法验证等操作
 type SQLHandler struct {
-	queryRepo     repository.QueryHistoryRepository
-	connectionRepo repository.ConnectionRepository
-	sqlExecutor   SQLExecutorInterface  // SQL执行器
-	logger        *zap.Logger
+	queryRepo       repository.QueryHistoryRepository
+	connectionRepo  repository.ConnectionRepository
+	sqlExecutor       SQLExecutorInterface              // SQL执行器
+	validator         *service.SQLSecurityValidator     // 用于提取结果列来源
+	classifications   *service.ClassificationService    // 可选：按列数据分级脱敏结果
+	workspaceSettings *service.WorkspaceSettingsService // 可选：按工作空间默认设置补全连接与返回行数
+	schemaSnapshots   *service.SchemaSnapshotService    // 可选：按生成时的表结构快照重现提示词
+	resultTables      *service.ResultTableRenderer      // 按请求的format渲染纯文本/Markdown结果表格
+	columnLabels      *service.ColumnLabeler            // 可选：推导结果列的展示名
+	resultCache       *service.QueryResultCache         // 可选：同一连接上相同SQL直接返回缓存的结果
+	runningQueries    *service.RunningQueryRegistry     // 可选：取消正在执行的查询
+	queryJobs         *service.QueryJobService          // 可选：以后台任务异步执行耗时较长的查询
+	costPrecheck      *service.CostPrecheck             // 可选：执行生成的SQL前按执行计划预估代价
+	residency         *service.ResidencyService         // 可选：导出前校验工作空间的数据驻留策略
+	export            *config.ExportConfig              // 导出查询结果的行数上限与超时
+	logger            *zap.Logger
 }
 
 // NewSQLHandler 创建SQL处理器实例
@@ -42,15 +58,123 @@ func NewSQLHandler(
 		queryRepo:      queryRepo,
 		connectionRepo: connectionRepo,
 		sqlExecutor:    sqlExecutor,
+		validator:      service.NewSQLSecurityValidator(logger),
+		resultTables:   service.NewResultTableRenderer(nil),
+		export:         config.DefaultExportConfig(),
 		logger:         logger,
 	}
 }
 
+// Routes 声明SQL查询路由
+func (h *SQLHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix:      "/sql",
+			Tag:         "sql",
+			Auth:        AuthJWT,
+			APIKeyScope: repository.APIKeyScopeSQL,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/execute", Handler: h.ExecuteSQL, Summary: "执行SQL查询", Permission: repository.PermQueryExecute, BlockedInMaintenance: true},
+				{Method: http.MethodDelete, Path: "/execute/:query_id", Handler: h.CancelQuery, Summary: "取消正在执行的查询"},
+				{Method: http.MethodGet, Path: "/jobs/:id", Handler: h.GetQueryJob, Summary: "获取异步查询任务的状态与结果"},
+				{Method: http.MethodGet, Path: "/history", Handler: h.GetQueryHistory, Summary: "查询历史"},
+				{Method: http.MethodGet, Path: "/history/:id", Handler: h.GetQueryById, Summary: "获取特定查询"},
+				{Method: http.MethodGet, Path: "/history/:id/prompt", Handler: h.ReproducePrompt, Summary: "按生成时的表结构重现提示词"},
+				{Method: http.MethodGet, Path: "/history/:id/export", Handler: h.ExportQueryResult, Summary: "导出查询结果（CSV/Excel）", BlockedInMaintenance: true},
+				{Method: http.MethodPost, Path: "/history/batch-delete", Handler: h.BatchDeleteHistory, Summary: "批量删除查询历史"},
+				{Method: http.MethodPost, Path: "/history/batch-retag", Handler: h.BatchRetagHistory, Summary: "批量修改查询历史的费用归属标签"},
+				{Method: http.MethodPost, Path: "/validate", Handler: h.ValidateSQL, Summary: "SQL语法验证"},
+				{Method: http.MethodPost, Path: "/format", Handler: h.FormatSQL, Summary: "格式化SQL"},
+			},
+		},
+	}
+}
+
+// SetClassificationService 启用列数据分级，执行结果按用户角色脱敏或过滤受限列
+func (h *SQLHandler) SetClassificationService(classifications *service.ClassificationService) {
+	h.classifications = classifications
+}
+
+// SetWorkspaceSettings 启用工作空间默认设置：请求未指定连接或返回行数时按工作空间默认值补全
+func (h *SQLHandler) SetWorkspaceSettings(settings *service.WorkspaceSettingsService) {
+	h.workspaceSettings = settings
+}
+
+// SetSchemaSnapshots 启用表结构快照，查询历史可按生成时的表结构重现提示词
+func (h *SQLHandler) SetSchemaSnapshots(snapshots *service.SchemaSnapshotService) {
+	h.schemaSnapshots = snapshots
+}
+
+// SetResultTableRenderer 设置结果表格渲染器，未设置时使用默认的行数与宽度限制
+func (h *SQLHandler) SetResultTableRenderer(renderer *service.ResultTableRenderer) {
+	h.resultTables = renderer
+}
+
+// SetExportConfig 设置查询结果导出的行数上限与超时，为nil时保持默认配置
+func (h *SQLHandler) SetExportConfig(exportConfig *config.ExportConfig) {
+	if exportConfig != nil {
+		h.export = exportConfig
+	}
+}
+
+// SetResidencyService 启用数据驻留校验，导出位置不符合工作空间策略时拒绝导出
+func (h *SQLHandler) SetResidencyService(residency *service.ResidencyService) {
+	h.residency = residency
+}
+
+// SetResultCache 启用查询结果缓存：同一连接上执行相同SQL时返回缓存的结果，不访问目标库
+func (h *SQLHandler) SetResultCache(cache *service.QueryResultCache) {
+	h.resultCache = cache
+}
+
+// SetColumnLabeler 启用结果列展示名：按natural_query的措辞与数据字典为结果列推导易读的表头
+func (h *SQLHandler) SetColumnLabeler(labeler *service.ColumnLabeler) {
+	h.columnLabels = labeler
+}
+
 // ExecuteSQLRequest SQL执行请求结构
 type ExecuteSQLRequest struct {
 	SQL          string `json:"sql" binding:"required" example:"SELECT * FROM users LIMIT 10"`
 	NaturalQuery string `json:"natural_query,omitempty" example:"获取前10个用户"`
-	ConnectionID int64  `json:"connection_id" binding:"required" example:"1"`
+	ConnectionID int64  `json:"connection_id,omitempty" example:"1"`                         // 未指定时使用工作空间默认连接
+	RowLimit     int    `json:"row_limit,omitempty" binding:"omitempty,min=1" example:"200"` // 未指定时使用工作空间默认返回行数
+	
+	// 执行AI生成的SQL时携带：Confirmed表示用户已确认，AIConfidence为生成时的置信度
+	Confirmed    bool     `json:"confirmed,omitempty" example:"true"`
+	AIConfidence *float64 `json:"ai_confidence,omitempty" example:"0.72"`
+	
+	// SchemaSnapshotID 生成该SQL时使用的表结构快照，取自 /ai/chat2sql 响应，须属于connection_id对应的连接
+	SchemaSnapshotID *int64 `json:"schema_snapshot_id,omitempty" example:"12"`
+	
+	// Format 为text或markdown时额外返回服务端渲染的结果表格，供聊天集成与屏幕阅读器使用
+	Format string `json:"format,omitempty" binding:"omitempty,oneof=json text markdown" example:"markdown"`
+	
+	// Cache 结果缓存控制，未启用结果缓存时忽略
+	Cache *ResultCacheControl `json:"cache,omitempty"`
+	
+	// Parameters 按占位符顺序排列的绑定参数，取自 /ai/chat2sql 响应的parameters，SQL使用parameterized_sql
+	Parameters []string `json:"parameters,omitempty" binding:"omitempty,max=100" example:"Acme, Inc."`
+}
+
+// ResultCacheControl 单次执行的结果缓存控制
+type ResultCacheControl struct {
+	NoCache bool `json:"no_cache,omitempty" example:"false"`                       // 不读取缓存，执行结果仍写入缓存，用于强制刷新
+	NoStore bool `json:"no_store,omitempty" example:"false"`                       // 不读取也不写入缓存
+	MaxAge  int  `json:"max_age,omitempty" binding:"omitempty,min=1" example:"60"` // 只接受max_age秒内缓存的结果
+	TTL     int  `json:"ttl,omitempty" binding:"omitempty,min=1" example:"300"`    // 本次结果的缓存秒数，超过服务端上限时按上限
+}
+
+// options 转换为服务层的缓存选项，未指定时使用默认行为
+func (r *ResultCacheControl) options() service.ResultCacheOptions {
+	if r == nil {
+		return service.ResultCacheOptions{}
+	}
+	return service.ResultCacheOptions{
+		NoCache: r.NoCache,
+		NoStore: r.NoStore,
+		MaxAge:  time.Duration(r.MaxAge) * time.Second,
+		TTL:     time.Duration(r.TTL) * time.Second,
+	}
 }
 
 // ValidateSQLRequest SQL验证请求结构
@@ -61,10 +185,12 @@ type ValidateSQLRequest struct {
 // QueryHistoryParams 查询历史参数
 type QueryHistoryParams struct {
 	Limit        int    `form:"limit,default=20" binding:"min=1,max=100" example:"20"`
-	Offset       int    `form:"offset,default=0" binding:"min=0" example:"0"`
+	Cursor       string `form:"cursor" binding:"omitempty,max=512"`
+	Offset       int    `form:"offset,default=0" binding:"min=0" example:"0"` // 已被cursor取代，仅为兼容旧客户端保留
 	Status       string `form:"status" binding:"omitempty,oneof=pending success error timeout" example:"success"`
 	ConnectionID int64  `form:"connection_id" binding:"omitempty,min=1" example:"1"`
 	Keyword      string `form:"keyword" binding:"omitempty,max=200" example:"用户查询"`
+	IncludeTotal bool   `form:"include_total" example:"false"` // v2需要总数时才执行COUNT查询，v1始终返回总数
 }
 
 // SQLExecutionResult SQL执行结果
@@ -74,16 +200,27 @@ type SQLExecutionResult struct {
 	RowCount      int32                    `json:"row_count" example:"10"`
 	Status        string                   `json:"status" example:"success"`
 	Data          []map[string]any `json:"data,omitempty"`
+	Lineage       []service.ColumnLineage  `json:"lineage,omitempty"` // 结果列来源
+	ColumnLabels  []service.ColumnLabel    `json:"column_labels,omitempty"` // 结果列的展示名，与原始列名一一对应
 	Error         string                   `json:"error,omitempty"`
+	Warnings      []string                 `json:"warnings,omitempty"` // 结果截断与受限列脱敏或过滤的说明
+	Guardrails    []service.Guardrail      `json:"guardrails"` // 实际生效的防护措施，供客户端说明结果与SQL预期不同的原因
+	Truncated     bool                     `json:"truncated"` // 结果达到行数或大小上限被截断，原因见guardrails
+	Plan          *service.PlanSummary     `json:"plan,omitempty"` // 执行前代价预检的执行计划摘要
+	Rendered      string                   `json:"rendered,omitempty"` // 请求format为text或markdown时渲染的结果表格
+	Cached        bool                     `json:"cached,omitempty"` // 结果取自缓存，未访问目标库
+	CachedAt      *time.Time               `json:"cached_at,omitempty"` // 缓存结果的执行时间
+	
+	columns []string // 结果列顺序，用于渲染表格
 }
 
 // QueryHistoryResponse 查询历史响应
 type QueryHistoryResponse struct {
-	Queries    []*QueryHistoryItem `json:"queries"`
-	Total      int64               `json:"total" example:"156"`
-	Page       int                 `json:"page" example:"1"`
-	Limit      int                 `json:"limit" example:"20"`
-	HasMore    bool                `json:"has_more" example:"true"`
+	Queries []*QueryHistoryItem `json:"queries"`
+	Total   *int64              `json:"total,omitempty" example:"156"` // v1始终返回，v2仅在include_total=true时返回
+	Page    int                 `json:"page" example:"1"`
+	Limit   int                 `json:"limit" example:"20"`
+	CursorPage
 }
 
 // QueryHistoryItem 查询历史项
@@ -96,7 +233,11 @@ type QueryHistoryItem struct {
 	Status        string    `json:"status" example:"success"`
 	ErrorMessage  *string   `json:"error_message,omitempty"`
 	ConnectionID  *int64    `json:"connection_id" example:"1"`
+	AIConfidence  *float64  `json:"ai_confidence,omitempty" example:"0.85"`
+	ExecutionPath *string   `json:"execution_path,omitempty" example:"auto"`
 	CreateTime    time.Time `json:"create_time" example:"2024-01-08T12:00:00Z"`
+	
+	SchemaSnapshotID *int64 `json:"schema_snapshot_id,omitempty" example:"12"` // 生成时使用的表结构快照，仅详情接口返回
 }
 
 // SQLValidationResult SQL验证结果
@@ -117,7 +258,9 @@ type SQLValidationResult struct {
 // @Produce json
 // @Security BearerAuth
 // @Param request body ExecuteSQLRequest true "SQL执行请求"
+// @Param async query bool false "以后台任务异步执行，立即返回任务ID，通过GET /api/v1/sql/jobs/{id}获取结果"
 // @Success 200 {object} SQLExecutionResult "执行成功"
+// @Success 202 {object} QueryJobResponse "异步任务已提交"
 // @Failure 400 {object} ErrorResponse "请求参数错误或SQL语法错误"
 // @Failure 401 {object} ErrorResponse "未授权访问"
 // @Failure 403 {object} ErrorResponse "SQL操作被禁止"
@@ -143,8 +286,55 @@ func (h *SQLHandler) ExecuteSQL(c *gin.Context) {
 		return
 	}
 	
+	async := isAsyncRequest(c)
+	if async && h.queryJobs == nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("ASYNC_NOT_ENABLED", "未启用异步执行"))
+		return
+	}
+	if async && len(req.Parameters) > 0 {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("PARAMETERS_NOT_SUPPORTED", "异步执行不支持绑定参数"))
+		return
+	}
+	
+	// 按工作空间默认设置补全未指定的连接与返回行数
+	if h.workspaceSettings != nil {
+		fields := &service.RequestDefaults{ConnectionID: req.ConnectionID, RowLimit: req.RowLimit}
+		if err := h.workspaceSettings.Apply(c.Request.Context(), userID, fields); err != nil {
+			h.logger.Error("Failed to resolve workspace defaults", zap.Error(err), zap.Int64("user_id", userID))
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间默认设置失败"))
+			return
+		}
+		req.ConnectionID, req.RowLimit = fields.ConnectionID, fields.RowLimit
+	}
+	if req.ConnectionID <= 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: "未指定connection_id且工作空间未配置默认连接",
+		})
+		return
+	}
+	
+	// 费用归属标签必须在工作空间配置的范围内
+	chargeback, err := resolveChargeback(c, h.workspaceSettings, userID)
+	if errors.Is(err, repository.ErrInvalidInput) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_CHARGEBACK_TAGS",
+			Message: "费用归属标签无效",
+			Details: err.Error(),
+		})
+		return
+	} else if err != nil {
+		h.logger.Error("Failed to resolve chargeback tags", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间设置失败"))
+		return
+	}
+	
 	// SQL安全验证
-	if err := h.validateSQLSecurity(req.SQL); err != nil {
+	_, span := tracing.Start(c.Request.Context(), "sql.validate")
+	err = h.validateSQLSecurity(req.SQL)
+	tracing.End(span, err)
+	if err != nil {
 		h.logger.Warn("SQL security validation failed",
 			zap.Error(err),
 			zap.String("sql", req.SQL),
@@ -159,7 +349,7 @@ func (h *SQLHandler) ExecuteSQL(c *gin.Context) {
 	
 	// 验证数据库连接权限
 	connection, err := h.connectionRepo.GetByID(c.Request.Context(), req.ConnectionID)
-	if err != nil || connection.UserID != userID {
+	if err != nil || !h.canExecuteOn(c, userID, connection) {
 		c.JSON(http.StatusForbidden, ErrorResponse{
 			Code:    "CONNECTION_FORBIDDEN",
 			Message: "无权访问该数据库连接",
@@ -167,14 +357,55 @@ func (h *SQLHandler) ExecuteSQL(c *gin.Context) {
 		return
 	}
 	
+	// 客户端提交的表结构快照须属于执行SQL的连接，否则重现提示词时会读到其他连接的表结构
+	if req.SchemaSnapshotID != nil {
+		if h.schemaSnapshots == nil {
+			req.SchemaSnapshotID = nil
+		} else if err := h.schemaSnapshots.Verify(c.Request.Context(), *req.SchemaSnapshotID, connection.ID); err != nil {
+			if errors.Is(err, service.ErrSchemaSnapshotMismatch) {
+				c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_SCHEMA_SNAPSHOT", "表结构快照不属于该数据库连接"))
+				return
+			}
+			h.logger.Error("Failed to verify schema snapshot",
+				zap.Error(err),
+				zap.Int64("schema_snapshot_id", *req.SchemaSnapshotID))
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("SCHEMA_SNAPSHOT_ERROR", "校验表结构快照失败"))
+			return
+		}
+	}
+	
+	// 携带绑定参数时校验占位符，查询历史保存写回参数后的SQL，便于阅读与重放
+	historySQL := req.SQL
+	if len(req.Parameters) > 0 {
+		if historySQL, err = service.InlineParameters(req.SQL, req.Parameters, repository.DatabaseType(connection.DBType)); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_PARAMETERS",
+				Message: "绑定参数与SQL中的占位符不一致",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+	
+	// 记录执行路径，区分确认执行与直接执行
+	executionPath := string(repository.ExecutionManual)
+	if req.Confirmed {
+		executionPath = string(repository.ExecutionConfirmed)
+	}
+	
 	// 创建查询历史记录
 	queryHistory := &repository.QueryHistory{
-		UserID:       userID,
-		NaturalQuery: req.NaturalQuery,
-		GeneratedSQL: req.SQL,
-		Status:       string(repository.QueryPending),
-		ConnectionID: &req.ConnectionID,
+		UserID:        userID,
+		NaturalQuery:  req.NaturalQuery,
+		GeneratedSQL:  historySQL,
+		Status:        string(repository.QueryPending),
+		ConnectionID:  &req.ConnectionID,
+		AIConfidence:  req.AIConfidence,
+		ExecutionPath: &executionPath,
+		
+		SchemaSnapshotID: req.SchemaSnapshotID,
 	}
+	service.ApplyChargebackTags(queryHistory, chargeback)
 	
 	if err := h.queryRepo.Create(c.Request.Context(), queryHistory); err != nil {
 		h.logger.Error("Failed to create query history",
@@ -182,8 +413,31 @@ func (h *SQLHandler) ExecuteSQL(c *gin.Context) {
 			zap.Int64("user_id", userID))
 	}
 	
+	ctx := service.WithQueryOwner(service.WithRowLimit(c.Request.Context(), req.RowLimit), userID)
+	ctx = service.WithQueryParameters(ctx, req.Parameters)
+	ctx = service.WithRealtimeQueryID(ctx, queryHistory.ID)
+	// 结果可能写入缓存，执行器返回原始结果，返回前由applyColumnPolicy按当前用户脱敏或过滤
+	ctx = service.WithUnfilteredResult(ctx)
+	
+	// 同步与异步执行前都按执行计划预估代价，超过上限时不执行
+	check := h.precheckCost(ctx, req.SQL, connection, executionPath)
+	if check != nil && check.Blocked {
+		h.rejectByCost(c, userID, queryHistory, check)
+		return
+	}
+	
+	// 异步执行：提交后台任务后立即返回，任务结束时回填查询历史
+	if async {
+		h.submitQueryJob(c, userID, &req, queryHistory)
+		return
+	}
+	
 	// 执行SQL查询
-	result := h.executeSQL(c.Request.Context(), req.SQL, connection)
+	result := h.executeWithCache(ctx, req.SQL, historySQL, connection, req.Cache.options())
+	if check != nil {
+		result.Plan = check.Plan
+		result.Warnings = append(result.Warnings, check.Warnings...)
+	}
 	
 	// 更新查询历史状态
 	queryHistory.Status = result.Status
@@ -205,10 +459,46 @@ func (h *SQLHandler) ExecuteSQL(c *gin.Context) {
 		zap.String("status", result.Status),
 		zap.Int32("execution_time", result.ExecutionTime))
 	
+	h.applyColumnPolicy(c, userID, connection.ID, result)
+	if result.Status == string(repository.QuerySuccess) {
+		locale := service.DetectLocale(req.NaturalQuery)
+		if h.columnLabels != nil {
+			result.ColumnLabels = h.columnLabels.Label(c.Request.Context(), connection.ID, req.NaturalQuery, locale, result.columns, result.Lineage)
+		}
+		headers := service.ColumnHeaders(result.columns, result.ColumnLabels)
+		result.Rendered = h.resultTables.RenderWithHeaders(req.Format, result.columns, headers, result.Data, locale)
+	}
+
 	result.QueryID = queryHistory.ID
 	c.JSON(http.StatusOK, result)
 }
 
+// applyColumnPolicy 按当前用户角色与访问例外对结果中的受限列脱敏或过滤
+// 分级加载失败时不返回数据，避免泄露受限列
+func (h *SQLHandler) applyColumnPolicy(c *gin.Context, userID, connectionID int64, result *SQLExecutionResult) {
+	if h.classifications == nil || len(result.Data) == 0 {
+		return
+	}
+
+	role, _ := c.Get("user_role")
+	roleStr, _ := role.(string)
+	policy, err := h.classifications.Policy(c.Request.Context(), connectionID, userID, roleStr)
+	if err != nil {
+		h.logger.Error("Failed to load column classifications",
+			zap.Error(err),
+			zap.Int64("connection_id", connectionID))
+		result.Data = nil
+		result.Warnings = append(result.Warnings, "无法加载列数据分级，结果数据已隐藏")
+		result.Guardrails = append(result.Guardrails, service.Guardrail{Type: service.GuardrailResultHidden})
+		return
+	}
+
+	columns, notes, guardrails := policy.ApplyToRows(result.columns, result.Data, result.Lineage)
+	result.columns = columns
+	result.Warnings = append(result.Warnings, notes...)
+	result.Guardrails = append(result.Guardrails, guardrails...)
+}
+
 // GetQueryHistory 获取查询历史
 // @Summary 获取查询历史
 // @Description 获取当前用户的SQL查询历史记录
@@ -217,11 +507,15 @@ func (h *SQLHandler) ExecuteSQL(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param limit query int false "每页数量" default(20) minimum(1) maximum(100)
-// @Param offset query int false "偏移量" default(0) minimum(0)
+// @Param cursor query string false "上一页响应中的next_cursor"
+// @Param offset query int false "偏移量（已弃用，请使用cursor）" default(0) minimum(0)
 // @Param status query string false "查询状态" Enums(pending, success, error, timeout)
 // @Param connection_id query int false "数据库连接ID"
 // @Param keyword query string false "搜索关键词" maxlength(200)
+// @Param include_total query bool false "v2是否返回总数，会额外执行COUNT查询；v1始终返回总数" default(false)
+// @Param If-None-Match header string false "上次响应的ETag"
 // @Success 200 {object} QueryHistoryResponse "获取成功"
+// @Success 304 {string} string "历史记录未变化"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权访问"
 // @Failure 500 {object} ErrorResponse "服务器内部错误"
@@ -251,24 +545,29 @@ func (h *SQLHandler) GetQueryHistory(c *gin.Context) {
 		params.Limit = 20
 	}
 	
-	// 根据参数调用不同的查询方法
-	var queries []*repository.QueryHistory
-	var err error
+	scope := listScope("sql_history", userID, params.Status, params.ConnectionID, params.Keyword)
+	cursor, err := decodeKeysetCursor(params.Cursor, scope)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
+	}
 	
-	if params.Keyword != "" {
-		// 关键词搜索
-		queries, err = h.queryRepo.SearchByNaturalQuery(
-			c.Request.Context(), userID, params.Keyword, params.Limit, params.Offset)
-	} else if params.ConnectionID > 0 {
-		// 按连接ID查询
-		queries, err = h.queryRepo.ListByConnection(
-			c.Request.Context(), params.ConnectionID, params.Limit, params.Offset)
-	} else {
-		// 按用户查询
-		queries, err = h.queryRepo.ListByUser(
-			c.Request.Context(), userID, params.Limit, params.Offset)
+	// 游标记录上一页最后一条的(create_time, id)；未提供游标时兼容旧的offset参数
+	var after *repository.QueryHistoryKeyset
+	offset, pageNumber := params.Offset, params.Offset/params.Limit+1
+	if cursor != nil {
+		after = &repository.QueryHistoryKeyset{CreateTime: cursor.CreateTime, ID: cursor.ID}
+		offset, pageNumber = 0, cursor.Page+1
 	}
 	
+	// 多取一条用于判断是否还有下一页
+	filter := repository.QueryHistoryFilter{
+		UserID:       userID,
+		ConnectionID: params.ConnectionID,
+		Status:       repository.QueryStatus(params.Status),
+		Keyword:      params.Keyword,
+	}
+	queries, err := h.queryRepo.ListPage(c.Request.Context(), filter, after, params.Limit+1, offset)
 	if err != nil {
 		h.logger.Error("Failed to get query history",
 			zap.Error(err),
@@ -280,14 +579,34 @@ func (h *SQLHandler) GetQueryHistory(c *gin.Context) {
 		})
 		return
 	}
+	queries, page := paginateKeyset(queries, params.Limit, pageNumber, scope, func(q *repository.QueryHistory) (time.Time, int64) {
+		return q.CreateTime, q.ID
+	})
 	
-	// 获取总数
-	total, err := h.queryRepo.CountByUser(c.Request.Context(), userID)
-	if err != nil {
-		h.logger.Warn("Failed to get query count",
-			zap.Error(err),
-			zap.Int64("user_id", userID))
-		total = 0
+	// 总数需要全表计数：v1响应始终包含，v2只在客户端明确需要时查询
+	var total *int64
+	if params.IncludeTotal || APIVersionFromContext(c) == APIVersionV1 {
+		count, err := h.queryRepo.CountByUser(c.Request.Context(), userID)
+		if err != nil {
+			h.logger.Warn("Failed to get query count",
+				zap.Error(err),
+				zap.Int64("user_id", userID))
+		} else {
+			total = &count
+		}
+	}
+	
+	// 本页记录与分页位置都未变化时返回304，避免轮询重复下载历史页
+	records := make([]versionedRecord, len(queries))
+	for i, q := range queries {
+		records[i] = versionedRecord{ID: q.ID, UpdateTime: q.UpdateTime}
+	}
+	etagParams := []any{scope, params.Limit, params.Cursor, offset, page.HasMore}
+	if total != nil {
+		etagParams = append(etagParams, *total)
+	}
+	if checkNotModified(c, computeETag(records, etagParams...)) {
+		return
 	}
 	
 	// 转换为响应格式
@@ -302,16 +621,18 @@ func (h *SQLHandler) GetQueryHistory(c *gin.Context) {
 			Status:        q.Status,
 			ErrorMessage:  q.ErrorMessage,
 			ConnectionID:  q.ConnectionID,
+			AIConfidence:  q.AIConfidence,
+			ExecutionPath: q.ExecutionPath,
 			CreateTime:    q.CreateTime,
 		}
 	}
 	
 	response := &QueryHistoryResponse{
-		Queries: items,
-		Total:   total,
-		Page:    (params.Offset / params.Limit) + 1,
-		Limit:   params.Limit,
-		HasMore: int64(params.Offset+params.Limit) < total,
+		Queries:    items,
+		Total:      total,
+		Page:       pageNumber,
+		Limit:      params.Limit,
+		CursorPage: page,
 	}
 	
 	c.JSON(http.StatusOK, response)
@@ -325,7 +646,9 @@ func (h *SQLHandler) GetQueryHistory(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "查询ID"
+// @Param If-None-Match header string false "上次响应的ETag"
 // @Success 200 {object} QueryHistoryItem "获取成功"
+// @Success 304 {string} string "查询记录未变化"
 // @Failure 400 {object} ErrorResponse "请求参数错误"
 // @Failure 401 {object} ErrorResponse "未授权访问"
 // @Failure 404 {object} ErrorResponse "查询不存在"
@@ -375,6 +698,10 @@ func (h *SQLHandler) GetQueryById(c *gin.Context) {
 		return
 	}
 	
+	if checkNotModified(c, computeETag([]versionedRecord{{ID: query.ID, UpdateTime: query.UpdateTime}})) {
+		return
+	}
+	
 	response := &QueryHistoryItem{
 		ID:            query.ID,
 		NaturalQuery:  query.NaturalQuery,
@@ -384,12 +711,192 @@ func (h *SQLHandler) GetQueryById(c *gin.Context) {
 		Status:        query.Status,
 		ErrorMessage:  query.ErrorMessage,
 		ConnectionID:  query.ConnectionID,
+		AIConfidence:  query.AIConfidence,
+		ExecutionPath: query.ExecutionPath,
 		CreateTime:    query.CreateTime,
+		
+		SchemaSnapshotID: query.SchemaSnapshotID,
 	}
 	
 	c.JSON(http.StatusOK, response)
 }
 
+// ReproducePrompt 按生成时的表结构快照重现提示词
+// @Summary 重现生成提示词
+// @Description 使用查询生成时记录的表结构快照重新构建提示词，用于排查表结构变化后"当时为什么这样生成"。受限列与语言提示不在快照范围内
+// @Tags SQL查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "查询ID"
+// @Success 200 {object} service.PromptReproduction "重现成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权访问"
+// @Failure 404 {object} ErrorResponse "查询不存在或未记录表结构快照"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/sql/history/{id}/prompt [get]
+func (h *SQLHandler) ReproducePrompt(c *gin.Context) {
+	userID := h.getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	
+	queryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_QUERY_ID", "无效的查询ID"))
+		return
+	}
+	
+	query, err := h.queryRepo.GetByID(c.Request.Context(), queryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("QUERY_NOT_FOUND", "查询记录不存在"))
+		return
+	}
+	if query.UserID != userID {
+		c.JSON(http.StatusForbidden, NewErrorResponse("ACCESS_DENIED", "无权访问该查询记录"))
+		return
+	}
+	
+	if h.schemaSnapshots == nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("SCHEMA_SNAPSHOT_NOT_FOUND", "该查询未记录表结构快照"))
+		return
+	}
+	reproduction, err := h.schemaSnapshots.Reproduce(c.Request.Context(), query)
+	if err != nil {
+		if errors.Is(err, service.ErrNoSchemaSnapshot) {
+			c.JSON(http.StatusNotFound, NewErrorResponse("SCHEMA_SNAPSHOT_NOT_FOUND", "该查询未记录表结构快照"))
+			return
+		}
+		h.logger.Error("Failed to reproduce prompt",
+			zap.Error(err),
+			zap.Int64("query_id", queryID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("REPRODUCE_PROMPT_FAILED", "重现提示词失败"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, reproduction)
+}
+
+// BatchDeleteHistory 批量删除查询历史
+// @Summary 批量删除查询历史
+// @Description 在一个事务中软删除当前用户的多条查询历史，逐条返回结果；不存在、已删除或不属于当前用户的记录标记为NOT_FOUND
+// @Tags SQL查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchRequest true "查询ID列表"
+// @Success 200 {object} BatchResponse "处理完成，可能部分失败"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/sql/history/batch-delete [post]
+func (h *SQLHandler) BatchDeleteHistory(c *gin.Context) {
+	userID := h.getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "未授权访问",
+		})
+		return
+	}
+	
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return
+	}
+	
+	ids := uniqueIDs(req.IDs)
+	deleted, err := h.queryRepo.BatchDelete(c.Request.Context(), userID, ids)
+	if err != nil {
+		h.logger.Error("Failed to batch delete query history",
+			zap.Error(err),
+			zap.Int64("user_id", userID),
+			zap.Int("count", len(ids)))
+		
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("BATCH_DELETE_FAILED", "批量删除查询历史失败"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, newBatchResponse(ids, deleted, "NOT_FOUND", "查询记录不存在或无权操作"))
+}
+
+// BatchRetagHistory 批量修改查询历史的费用归属标签
+// @Summary 批量修改查询历史的费用归属标签
+// @Description 替换当前用户多条查询历史的成本中心与项目标签，标签需在工作空间配置的范围内，两个标签均为空表示清除；不存在、已删除或不属于当前用户的记录标记为NOT_FOUND
+// @Tags SQL查询
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BatchRetagRequest true "查询ID列表与新标签"
+// @Success 200 {object} BatchResponse "处理完成，可能部分失败"
+// @Failure 400 {object} ErrorResponse "请求参数错误或标签无效"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/sql/history/batch-retag [post]
+func (h *SQLHandler) BatchRetagHistory(c *gin.Context) {
+	userID := h.getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    "UNAUTHORIZED",
+			Message: "未授权访问",
+		})
+		return
+	}
+	
+	var req BatchRetagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return
+	}
+	
+	// 与执行时相同，标签必须在工作空间配置的范围内；未配置工作空间设置时原样保存
+	tags := &repository.ChargebackTags{
+		CostCenter: strings.TrimSpace(req.CostCenter),
+		Project:    strings.TrimSpace(req.Project),
+	}
+	if h.workspaceSettings != nil {
+		resolved, err := h.workspaceSettings.ResolveChargeback(c.Request.Context(), userID, *tags)
+		if errors.Is(err, repository.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    "INVALID_CHARGEBACK_TAGS",
+				Message: "费用归属标签无效",
+				Details: err.Error(),
+			})
+			return
+		} else if err != nil {
+			h.logger.Error("Failed to resolve chargeback tags", zap.Error(err), zap.Int64("user_id", userID))
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间设置失败"))
+			return
+		}
+		tags = resolved
+	}
+	
+	ids := uniqueIDs(req.IDs)
+	retagged, err := h.queryRepo.BatchRetag(c.Request.Context(), userID, ids, tags)
+	if err != nil {
+		h.logger.Error("Failed to batch retag query history",
+			zap.Error(err),
+			zap.Int64("user_id", userID),
+			zap.Int("count", len(ids)))
+		
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("BATCH_RETAG_FAILED", "批量修改查询历史标签失败"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, newBatchResponse(ids, retagged, "NOT_FOUND", "查询记录不存在或无权操作"))
+}
+
 // ValidateSQL SQL语法验证
 // @Summary SQL语法验证
 // @Description 验证SQL语句的语法正确性和安全性
@@ -434,30 +941,10 @@ func (h *SQLHandler) ValidateSQL(c *gin.Context) {
 }
 
 // validateSQLSecurity SQL安全验证
-// 防止危险的SQL操作，只允许SELECT查询
+// 按词法预检只允许单条没有已知副作用的查询，注释与字符串中的关键词不会误判；
+// 预检是启发式的，执行时的只读事务才是只读保证
 func (h *SQLHandler) validateSQLSecurity(sql string) error {
-	// 转换为大写进行关键词检查
-	upperSQL := strings.ToUpper(strings.TrimSpace(sql))
-	
-	// 禁止的SQL关键词
-	forbiddenKeywords := []string{
-		"DROP", "DELETE", "INSERT", "UPDATE", "CREATE", "ALTER", 
-		"TRUNCATE", "GRANT", "REVOKE", "REPLACE", "MERGE",
-		"CALL", "EXEC", "EXECUTE", "DECLARE", "SET",
-	}
-	
-	for _, keyword := range forbiddenKeywords {
-		if strings.Contains(upperSQL, keyword) {
-			return fmt.Errorf("禁止执行 %s 操作，系统仅支持查询操作", keyword)
-		}
-	}
-	
-	// 必须以SELECT开头
-	if !strings.HasPrefix(upperSQL, "SELECT") {
-		return fmt.Errorf("仅支持SELECT查询语句")
-	}
-	
-	return nil
+	return sqlvalidator.ValidateReadOnly(sql)
 }
 
 // validateSQL SQL语法和安全验证
@@ -466,28 +953,21 @@ func (h *SQLHandler) validateSQL(sql string) *SQLValidationResult {
 		IsValid:   true,
 		Errors:    []string{},
 		Warnings:  []string{},
-		IsReadOnly: true,
+		QueryType: "UNKNOWN",
 	}
 	
-	// 安全验证
+	// 安全验证，词法或括号错误也在这里报告
 	if err := h.validateSQLSecurity(sql); err != nil {
 		result.IsValid = false
 		result.Errors = append(result.Errors, err.Error())
 	}
 	
-	// 检测查询类型
-	upperSQL := strings.ToUpper(strings.TrimSpace(sql))
-	if strings.HasPrefix(upperSQL, "SELECT") {
-		result.QueryType = "SELECT"
-	} else {
-		result.QueryType = "UNKNOWN"
-		result.IsReadOnly = false
-	}
-	
-	// 基础语法验证
-	if err := h.validateSQLSyntax(sql); err != nil {
-		result.IsValid = false
-		result.Errors = append(result.Errors, err.Error())
+	// 检测查询类型，WITH语句按CTE之后的主语句计
+	if analysis, err := sqlvalidator.Analyze(sql); err == nil {
+		if statementType := analysis.Statements[0].Type; statementType != "" {
+			result.QueryType = statementType
+		}
+		result.IsReadOnly = analysis.ReadOnly()
 	}
 	
 	// 提取表名
@@ -500,6 +980,35 @@ func (h *SQLHandler) validateSQL(sql string) *SQLValidationResult {
 func (h *SQLHandler) executeSQL(ctx context.Context, sql string, connection *repository.DatabaseConnection) *SQLExecutionResult {
 	// 调用Service层的SQL执行器
 	result, err := h.sqlExecutor.ExecuteQuery(ctx, sql, connection)
+	return h.executionResult(sql, result, err)
+}
+
+// executeWithCache 启用结果缓存时先查找缓存，命中则不访问目标库，未命中时执行并缓存成功的结果
+// key为缓存键，携带绑定参数时是写回参数后的SQL，参数不同的查询不会共用缓存
+// 缓存的是列策略应用前的原始结果，返回前仍按当前用户角色脱敏或过滤
+func (h *SQLHandler) executeWithCache(ctx context.Context, sql, key string, connection *repository.DatabaseConnection, opts service.ResultCacheOptions) *SQLExecutionResult {
+	if h.resultCache == nil {
+		return h.executeSQL(ctx, sql, connection)
+	}
+	
+	start := time.Now()
+	if cached, ok := h.resultCache.Lookup(ctx, connection.ID, key, opts); ok {
+		result := h.executionResult(sql, cached.Result, nil)
+		result.ExecutionTime = int32(time.Since(start).Milliseconds())
+		result.Cached = true
+		result.CachedAt = &cached.CachedAt
+		return result
+	}
+	
+	result, err := h.sqlExecutor.ExecuteQuery(ctx, sql, connection)
+	if err == nil {
+		h.resultCache.Store(ctx, connection.ID, key, result, opts)
+	}
+	return h.executionResult(sql, result, err)
+}
+
+// executionResult 转换Service层的执行结果
+func (h *SQLHandler) executionResult(sql string, result *service.QueryResult, err error) *SQLExecutionResult {
 	if err != nil {
 		// 如果result为nil，创建一个默认的错误结果
 		if result == nil {
@@ -508,13 +1017,20 @@ func (h *SQLHandler) executeSQL(ctx context.Context, sql string, connection *rep
 				RowCount:      0,
 				Status:        string(repository.QueryError),
 				Error:         err.Error(),
+				Guardrails:    []service.Guardrail{},
 			}
 		}
+		// 超时与被取消的查询保留执行器标记的状态
+		status := string(repository.QueryError)
+		if result.Status == string(repository.QueryTimeout) || result.Status == string(repository.QueryCancelled) {
+			status = result.Status
+		}
 		return &SQLExecutionResult{
 			ExecutionTime: result.ExecutionTime,
 			RowCount:      0,
-			Status:        string(repository.QueryError),
+			Status:        status,
 			Error:         result.Error,
+			Guardrails:    guardrailsOf(result),
 		}
 	}
 	
@@ -524,126 +1040,18 @@ func (h *SQLHandler) executeSQL(ctx context.Context, sql string, connection *rep
 		RowCount:      result.RowCount,
 		Status:        result.Status,
 		Data:          result.Rows,
+		Lineage:       h.validator.ExtractColumnLineage(sql),
 		Error:         result.Error,
+		Warnings:      result.Warnings,
+		Guardrails:    guardrailsOf(result),
+		Truncated:     result.Truncated,
+		columns:       result.Columns,
 	}
 }
 
-// validateSQLSyntax 基础SQL语法验证
-func (h *SQLHandler) validateSQLSyntax(sql string) error {
-	sql = strings.TrimSpace(sql)
-	if sql == "" {
-		return fmt.Errorf("SQL语句不能为空")
-	}
-	
-	upperSQL := strings.ToUpper(sql)
-	
-	// 检查基本的SQL结构
-	sqlKeywords := []string{"SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "CREATE", "DROP", "ALTER"}
-	hasValidKeyword := false
-	for _, keyword := range sqlKeywords {
-		if strings.HasPrefix(upperSQL, keyword+" ") || upperSQL == keyword {
-			hasValidKeyword = true
-			break
-		}
-	}
-	
-	if !hasValidKeyword {
-		return fmt.Errorf("SQL语句必须以有效的SQL关键字开始")
-	}
-	
-	// 检查括号匹配
-	if err := h.validateParenthesesBalance(sql); err != nil {
-		return err
-	}
-	
-	// 检查引号匹配
-	if err := h.validateQuotesBalance(sql); err != nil {
-		return err
-	}
-	
-	// 检查基本结构（针对SELECT语句）
-	if strings.HasPrefix(upperSQL, "SELECT") {
-		if !strings.Contains(upperSQL, " FROM ") {
-			// 允许SELECT常量或函数，如 SELECT 1, SELECT NOW()
-			if !strings.Contains(upperSQL, "(") && len(strings.Fields(sql)) == 2 {
-				// SELECT <constant> 是合法的
-			} else if !strings.Contains(upperSQL, "FROM") {
-				return fmt.Errorf("SELECT语句通常需要包含FROM子句")
-			}
-		}
-	}
-	
-	return nil
-}
-
-// validateParenthesesBalance 验证括号匹配
-func (h *SQLHandler) validateParenthesesBalance(sql string) error {
-	stack := 0
-	inString := false
-	var stringChar rune
-	
-	for i, char := range sql {
-		switch char {
-		case '\'', '"':
-			if !inString {
-				inString = true
-				stringChar = char
-			} else if char == stringChar {
-				// 检查是否是转义字符
-				if i == 0 || sql[i-1] != '\\' {
-					inString = false
-				}
-			}
-		case '(':
-			if !inString {
-				stack++
-			}
-		case ')':
-			if !inString {
-				stack--
-				if stack < 0 {
-					return fmt.Errorf("括号不匹配：多余的右括号")
-				}
-			}
-		}
-	}
-	
-	if stack != 0 {
-		return fmt.Errorf("括号不匹配：未闭合的左括号")
-	}
-	
-	return nil
-}
-
-// validateQuotesBalance 验证引号匹配
-func (h *SQLHandler) validateQuotesBalance(sql string) error {
-	singleQuoteCount := 0
-	doubleQuoteCount := 0
-	
-	for i, char := range sql {
-		switch char {
-		case '\'':
-			// 检查是否是转义字符
-			if i == 0 || sql[i-1] != '\\' {
-				singleQuoteCount++
-			}
-		case '"':
-			// 检查是否是转义字符
-			if i == 0 || sql[i-1] != '\\' {
-				doubleQuoteCount++
-			}
-		}
-	}
-	
-	if singleQuoteCount%2 != 0 {
-		return fmt.Errorf("单引号不匹配")
-	}
-	
-	if doubleQuoteCount%2 != 0 {
-		return fmt.Errorf("双引号不匹配")
-	}
-	
-	return nil
+// guardrailsOf 复制执行结果中的防护措施，没有时返回空列表，保证响应中始终包含guardrails字段
+func guardrailsOf(result *service.QueryResult) []service.Guardrail {
+	return append([]service.Guardrail{}, result.Guardrails...)
 }
 
 // extractTableNames 提取SQL中的表名
@@ -717,6 +1125,21 @@ func (h *SQLHandler) containsString(slice []string, item string) bool {
 	return false
 }
 
+// canExecuteOn 判断用户能否在连接上执行查询，规则见service.CanExecuteOn
+// 未设置工作空间设置服务时只有连接所有者可以执行
+func (h *SQLHandler) canExecuteOn(c *gin.Context, userID int64, connection *repository.DatabaseConnection) bool {
+	role, hasRole := middleware.GetUserRoleFromContext(c)
+	var defaults *repository.WorkspaceDefaults
+	if hasRole && h.workspaceSettings != nil {
+		if settings, err := h.workspaceSettings.Resolve(c.Request.Context(), userID); err != nil {
+			h.logger.Warn("Failed to resolve workspace settings", zap.Error(err), zap.Int64("user_id", userID))
+		} else {
+			defaults = settings.Defaults
+		}
+	}
+	return service.CanExecuteOn(userID, role, hasRole, connection, defaults)
+}
+
 // getUserIDFromContext 从JWT中间件上下文获取用户ID
 func (h *SQLHandler) getUserIDFromContext(c *gin.Context) int64 {
 	userID, exists := middleware.GetUserIDFromContext(c)
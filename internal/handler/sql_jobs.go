@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// SetQueryJobs 启用异步执行：POST /sql/execute?async=true提交后台任务，通过GET /sql/jobs/:id轮询结果
+func (h *SQLHandler) SetQueryJobs(jobs *service.QueryJobService) {
+	h.queryJobs = jobs
+}
+
+// QueryJobResponse 异步查询任务的状态与结果
+type QueryJobResponse struct {
+	JobID         int64               `json:"job_id" example:"42"`
+	QueryID       *int64              `json:"query_id,omitempty" example:"123"` // 对应的查询历史记录
+	Status        string              `json:"status" example:"running"`         // pending/running/completed/failed/cancelled
+	RowsRead      int64               `json:"rows_read" example:"1500"`         // 已读取的行数，执行中定期更新
+	RowLimit      int                 `json:"row_limit" example:"10000"`
+	Error         string              `json:"error,omitempty"`
+	CreateTime    time.Time           `json:"create_time"`
+	StartedTime   *time.Time          `json:"started_time,omitempty"`
+	CompletedTime *time.Time          `json:"completed_time,omitempty"`
+	Result        *SQLExecutionResult `json:"result,omitempty"` // 任务完成后返回，受限列按当前用户角色脱敏或过滤
+}
+
+// isAsyncRequest 请求是否要求以异步任务方式执行
+func isAsyncRequest(c *gin.Context) bool {
+	async, _ := strconv.ParseBool(c.Query("async"))
+	return async
+}
+
+// submitQueryJob 提交异步查询任务并返回202，任务结束时回填查询历史
+func (h *SQLHandler) submitQueryJob(c *gin.Context, userID int64, req *ExecuteSQLRequest, queryHistory *repository.QueryHistory) {
+	job := &repository.QueryJob{
+		UserID:       userID,
+		ConnectionID: req.ConnectionID,
+		SQL:          req.SQL,
+		RowLimit:     req.RowLimit,
+	}
+	if queryHistory.ID > 0 {
+		job.QueryHistoryID = &queryHistory.ID
+	}
+
+	if err := h.queryJobs.Submit(c.Request.Context(), job); err != nil {
+		h.logger.Error("Failed to submit query job", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("JOB_SUBMIT_FAILED", "提交异步查询任务失败"))
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/sql/jobs/%d", job.ID))
+	c.JSON(http.StatusAccepted, h.queryJobResponse(c, job))
+}
+
+// GetQueryJob 获取异步查询任务
+// @Summary 获取异步查询任务
+// @Description 返回POST /api/v1/sql/execute?async=true提交的任务的状态、已读取行数与结果。
+// @Description 任务状态持久化保存，服务重启后执行中断的任务会重新执行。只能查看自己的任务，管理员可以查看任何人的任务
+// @Tags SQL查询
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} QueryJobResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "任务ID无效"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权访问"
+// @Failure 404 {object} ErrorResponse "任务不存在或已过期"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/sql/jobs/{id} [get]
+func (h *SQLHandler) GetQueryJob(c *gin.Context) {
+	userID := h.getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	jobID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_JOB_ID", "无效的任务ID"))
+		return
+	}
+	if h.queryJobs == nil {
+		c.JSON(http.StatusNotFound, NewErrorResponse("JOB_NOT_FOUND", "查询任务不存在或已过期"))
+		return
+	}
+
+	job, err := h.queryJobs.Get(c.Request.Context(), userID, c.GetString("user_role"), jobID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("JOB_NOT_FOUND", "查询任务不存在或已过期"))
+		return
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, NewErrorResponse("ACCESS_DENIED", "无权访问该查询任务"))
+		return
+	case err != nil:
+		h.logger.Error("Failed to get query job", zap.Error(err), zap.Int64("job_id", jobID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("JOB_ERROR", "获取查询任务失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, h.queryJobResponse(c, job))
+}
+
+// queryJobResponse 转换任务，已完成的任务附带按当前用户角色处理受限列后的结果
+func (h *SQLHandler) queryJobResponse(c *gin.Context, job *repository.QueryJob) *QueryJobResponse {
+	response := &QueryJobResponse{
+		JobID:         job.ID,
+		QueryID:       job.QueryHistoryID,
+		Status:        job.Status,
+		RowsRead:      job.RowsRead,
+		RowLimit:      job.RowLimit,
+		CreateTime:    job.CreateTime,
+		StartedTime:   job.StartedTime,
+		CompletedTime: job.CompletedTime,
+	}
+	if job.ErrorMessage != nil {
+		response.Error = *job.ErrorMessage
+	}
+	if job.Status != string(repository.QueryJobCompleted) || job.Result == nil {
+		return response
+	}
+
+	result := &SQLExecutionResult{
+		ExecutionTime: job.Result.ExecutionTime,
+		RowCount:      int32(len(job.Result.Rows)),
+		Status:        string(repository.QuerySuccess),
+		Data:          job.Result.Rows,
+		Lineage:       h.validator.ExtractColumnLineage(job.SQL),
+		Guardrails:    []service.Guardrail{{Type: service.GuardrailReadOnly}},
+		columns:       job.Result.Columns,
+	}
+	if job.QueryHistoryID != nil {
+		result.QueryID = *job.QueryHistoryID
+	}
+	if job.Result.Truncated {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("查询结果超过最大行数限制(%d行)，已截断显示", job.RowLimit))
+		result.Guardrails = append(result.Guardrails, service.Guardrail{Type: service.GuardrailRowLimit, Limit: int64(job.RowLimit)})
+		result.Truncated = true
+	}
+	h.applyColumnPolicy(c, h.getUserIDFromContext(c), job.ConnectionID, result)
+	response.Result = result
+	return response
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// memQueryJobRepository 只实现提交与读取的内存异步查询任务Repository
+type memQueryJobRepository struct {
+	repository.QueryJobRepository
+	jobs []*repository.QueryJob
+}
+
+func (m *memQueryJobRepository) Create(ctx context.Context, job *repository.QueryJob) error {
+	job.ID = int64(len(m.jobs) + 1)
+	job.Status = string(repository.QueryJobPending)
+	m.jobs = append(m.jobs, job)
+	return nil
+}
+
+func (m *memQueryJobRepository) GetByID(ctx context.Context, id int64) (*repository.QueryJob, error) {
+	if id < 1 || id > int64(len(m.jobs)) {
+		return nil, repository.ErrNotFound
+	}
+	return m.jobs[id-1], nil
+}
+
+func TestSQLHandler_ExecuteSQL_Async(t *testing.T) {
+	suite := &HandlerIntegrationTestSuite{}
+	router := suite.setupTestRouter(true, 1)
+
+	connection := testutil.NewConnection(1, 1)
+	suite.mockConnectionRepo.On("GetByID", mock.Anything, int64(1)).Return(connection, nil)
+	suite.mockQueryRepo.On("Create", mock.Anything, mock.AnythingOfType("*repository.QueryHistory")).Return(nil)
+
+	execute := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ExecuteSQLRequest{SQL: "SELECT * FROM events", ConnectionID: 1})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/sql/execute?async=true", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := execute()
+	assert.Equal(t, http.StatusBadRequest, w.Code, "未启用异步执行")
+	assert.Contains(t, w.Body.String(), "ASYNC_NOT_ENABLED")
+
+	jobs := &memQueryJobRepository{}
+	suite.sqlHandler.SetQueryJobs(service.NewQueryJobService(jobs, nil, nil, nil, nil, zaptest.NewLogger(t)))
+
+	w = execute()
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/api/v1/sql/jobs/1", w.Header().Get("Location"))
+
+	var response QueryJobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(1), response.JobID)
+	assert.Equal(t, string(repository.QueryJobPending), response.Status)
+	assert.Nil(t, response.Result)
+
+	require.Len(t, jobs.jobs, 1)
+	assert.Equal(t, "SELECT * FROM events", jobs.jobs[0].SQL)
+	// 提交后不在请求中执行查询，也不更新查询历史
+	suite.mockSQLExecutor.AssertNotCalled(t, "ExecuteQuery", mock.Anything, mock.Anything, mock.Anything)
+	suite.mockQueryRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+	// 代价预检在提交任务前执行，超过上限时不提交
+	suite.mockQueryRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	explainer := &fixedPlanExplainer{plan: &service.PlanSummary{TotalCost: 250000}}
+	suite.sqlHandler.SetCostPrecheck(service.NewCostPrecheck(explainer, service.CostThresholds{MaxCost: 100000}, zaptest.NewLogger(t)))
+	w = execute()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), string(service.GuardrailCostLimit))
+	assert.Len(t, jobs.jobs, 1)
+	assert.Equal(t, 1, explainer.calls)
+}
+
+func TestSQLHandler_GetQueryJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	message := "查询执行超过30m0s"
+	jobs := &memQueryJobRepository{jobs: []*repository.QueryJob{
+		{ID: 1, UserID: 7, ConnectionID: 3, SQL: "SELECT id FROM events", RowLimit: 2, RowsRead: 2,
+			Status: string(repository.QueryJobCompleted), Result: &repository.QueryJobResult{
+				Columns: []string{"id"}, Rows: []map[string]any{{"id": 1}, {"id": 2}}, Truncated: true, ExecutionTime: 61000,
+			}},
+		{ID: 2, UserID: 7, ConnectionID: 3, SQL: "SELECT 1", RowLimit: 100, Status: string(repository.QueryJobFailed), ErrorMessage: &message},
+	}}
+	h := NewSQLHandler(nil, nil, nil, zaptest.NewLogger(t))
+	h.SetQueryJobs(service.NewQueryJobService(jobs, nil, nil, nil, nil, zaptest.NewLogger(t)))
+
+	get := func(userID int64, role, id string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Set("user_role", role)
+		})
+		r.GET("/sql/jobs/:id", h.GetQueryJob)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sql/jobs/"+id, nil))
+		return w
+	}
+
+	w := get(7, "user", "1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response QueryJobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, string(repository.QueryJobCompleted), response.Status)
+	require.NotNil(t, response.Result)
+	assert.Len(t, response.Result.Data, 2)
+	assert.Equal(t, int32(61000), response.Result.ExecutionTime)
+	assert.Len(t, response.Result.Warnings, 1, "结果截断")
+
+	response = QueryJobResponse{}
+	w = get(7, "user", "2")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, message, response.Error)
+	assert.Nil(t, response.Result)
+
+	assert.Equal(t, http.StatusForbidden, get(8, "user", "1").Code)
+	assert.Equal(t, http.StatusOK, get(1, "admin", "1").Code)
+	assert.Equal(t, http.StatusNotFound, get(7, "user", "9").Code)
+	assert.Equal(t, http.StatusBadRequest, get(7, "user", "abc").Code)
+}
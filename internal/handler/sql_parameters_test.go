@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+func TestSQLHandler_ExecuteSQL_Parameters(t *testing.T) {
+	suite := &HandlerIntegrationTestSuite{}
+	router := suite.setupTestRouter(true, 1)
+
+	suite.mockConnectionRepo.On("GetByID", mock.Anything, int64(1)).Return(testutil.NewConnection(1, 1), nil)
+	suite.mockQueryRepo.On("Create", mock.Anything, mock.AnythingOfType("*repository.QueryHistory")).Return(nil)
+	suite.mockQueryRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	suite.mockSQLExecutor.On("ExecuteQuery", mock.Anything, "SELECT * FROM customers WHERE name = $1", mock.Anything).
+		Return(&service.QueryResult{Status: string(repository.QuerySuccess), Rows: []map[string]any{}}, nil)
+
+	execute := func(req ExecuteSQLRequest, query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/sql/execute"+query, bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := execute(ExecuteSQLRequest{SQL: "SELECT * FROM customers WHERE name = $1", ConnectionID: 1, Parameters: []string{"O'Brien"}}, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	// 查询历史保存写回参数后的SQL
+	history := suite.mockQueryRepo.Calls[0].Arguments.Get(1).(*repository.QueryHistory)
+	assert.Equal(t, "SELECT * FROM customers WHERE name = 'O''Brien'", history.GeneratedSQL)
+
+	w = execute(ExecuteSQLRequest{SQL: "SELECT * FROM customers WHERE name = $1 AND city = $2", ConnectionID: 1, Parameters: []string{"O'Brien"}}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_PARAMETERS")
+
+	suite.sqlHandler.SetQueryJobs(service.NewQueryJobService(&memQueryJobRepository{}, nil, nil, nil, nil, zaptest.NewLogger(t)))
+	w = execute(ExecuteSQLRequest{SQL: "SELECT * FROM customers WHERE name = $1", ConnectionID: 1, Parameters: []string{"O'Brien"}}, "?async=true")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "PARAMETERS_NOT_SUPPORTED")
+	suite.mockSQLExecutor.AssertNumberOfCalls(t, "ExecuteQuery", 1)
+}
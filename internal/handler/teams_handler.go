@@ -0,0 +1,492 @@
+// Microsoft Teams机器人集成 - 频道提问→生成SQL→执行→自适应卡片回复
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+const (
+	// teamsMaxBodySize Teams请求体大小上限
+	teamsMaxBodySize = 1 << 20
+	// teamsAdaptiveCardType 自适应卡片附件类型
+	teamsAdaptiveCardType = "application/vnd.microsoft.card.adaptive"
+	// teamsPageAction 分页动作标识
+	teamsPageAction = "chat2sql.page"
+	// teamsMaxCellLength 单元格最大显示长度
+	teamsMaxCellLength = 80
+)
+
+var (
+	teamsMentionPattern    = regexp.MustCompile(`(?s)<at>.*?</at>`)
+	teamsHTMLTagPattern    = regexp.MustCompile(`<[^>]+>`)
+	teamsConnectionPattern = regexp.MustCompile(`^#(\d+)\s+`)
+)
+
+// TeamsPageStoreInterface 分页令牌存储接口
+type TeamsPageStoreInterface interface {
+	Save(ctx context.Context, page *service.TeamsPage) (string, error)
+	Load(ctx context.Context, token string) (*service.TeamsPage, error)
+}
+
+// BotFrameworkVerifierInterface Bot Framework JWT校验接口
+type BotFrameworkVerifierInterface interface {
+	Verify(ctx context.Context, authHeader, serviceURL string) error
+}
+
+// TeamsHandler Microsoft Teams机器人处理器
+// 复用AI服务与SQL执行器的Service层入口，与Web端行为保持一致
+type TeamsHandler struct {
+	aiService      AIServiceInterface
+	sqlExecutor    SQLExecutorInterface
+	connectionRepo repository.ConnectionRepository
+	userRepo       repository.UserRepository
+	pages          TeamsPageStoreInterface       // 为nil时不提供分页按钮
+	botAuth        BotFrameworkVerifierInterface // 为nil时只接受传出Webhook的HMAC签名
+	signingSecret  service.SecretFunc            // 传出Webhook的HMAC密钥，每次请求读取，轮换后立即生效
+	validator      *service.SQLSecurityValidator
+	config         *config.TeamsConfig
+	logger         *zap.Logger
+}
+
+// NewTeamsHandler 创建Teams处理器实例
+// 分页按钮以Action.Submit回传，只有Bot Framework认证的请求可以翻页，未配置botAuth时不提供分页
+func NewTeamsHandler(
+	aiService AIServiceInterface,
+	sqlExecutor SQLExecutorInterface,
+	connectionRepo repository.ConnectionRepository,
+	userRepo repository.UserRepository,
+	pages TeamsPageStoreInterface,
+	botAuth BotFrameworkVerifierInterface,
+	signingSecret service.SecretFunc,
+	teamsConfig *config.TeamsConfig,
+	logger *zap.Logger,
+) *TeamsHandler {
+	if teamsConfig == nil {
+		teamsConfig = config.DefaultTeamsConfig()
+	}
+	if signingSecret == nil {
+		signingSecret = service.StaticSecret(teamsConfig.SigningSecret)
+	}
+	return &TeamsHandler{
+		aiService:      aiService,
+		sqlExecutor:    sqlExecutor,
+		connectionRepo: connectionRepo,
+		userRepo:       userRepo,
+		pages:          pages,
+		botAuth:        botAuth,
+		signingSecret:  signingSecret,
+		validator:      service.NewSQLSecurityValidator(logger),
+		config:         teamsConfig,
+		logger:         logger,
+	}
+}
+
+// Routes 声明Teams机器人路由，由传出Webhook签名或Bot Framework JWT认证
+func (h *TeamsHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/integrations",
+			Tag:    "integrations",
+			Auth:   AuthNone,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "/teams/messages", Handler: h.HandleMessage, Summary: "Teams机器人消息", BlockedInMaintenance: true},
+			},
+		},
+	}
+}
+
+// TeamsActivity Bot Framework活动（仅包含使用到的字段）
+type TeamsActivity struct {
+	Type         string              `json:"type"`
+	ID           string              `json:"id,omitempty"`
+	Text         string              `json:"text,omitempty"`
+	ServiceURL   string              `json:"serviceUrl,omitempty"`
+	From         TeamsChannelAccount `json:"from"`
+	Conversation TeamsChannelAccount `json:"conversation"`
+	Value        json.RawMessage     `json:"value,omitempty"`
+}
+
+// TeamsChannelAccount Teams账号/会话标识
+type TeamsChannelAccount struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	AADObjectID string `json:"aadObjectId,omitempty"`
+}
+
+// TeamsPageAction 自适应卡片分页动作携带的数据
+// 查询保存在服务端，卡片只携带不透明的分页令牌
+type TeamsPageAction struct {
+	Action string `json:"action"`
+	Token  string `json:"token"`
+	Page   int    `json:"page"`
+}
+
+// TeamsReply 回复给Teams的消息活动
+type TeamsReply struct {
+	Type        string            `json:"type"`
+	Text        string            `json:"text,omitempty"`
+	Attachments []TeamsAttachment `json:"attachments,omitempty"`
+}
+
+// TeamsAttachment 消息附件
+type TeamsAttachment struct {
+	ContentType string         `json:"contentType"`
+	Content     map[string]any `json:"content"`
+}
+
+// HandleMessage 处理Teams传出Webhook/机器人消息
+// @Summary Teams机器人消息入口
+// @Description 接收Teams频道中的提问，生成并执行SQL后以自适应卡片回复
+// @Tags Integrations
+// @Accept json
+// @Produce json
+// @Success 200 {object} TeamsReply "卡片回复"
+// @Failure 401 {object} ErrorResponse "签名或令牌验证失败"
+// @Router /api/v1/integrations/teams/messages [post]
+func (h *TeamsHandler) HandleMessage(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, teamsMaxBodySize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "读取请求体失败"))
+		return
+	}
+
+	// Bot Framework活动字段繁多，这里不使用全局禁止未知字段的绑定器
+	var activity TeamsActivity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST", "无效的Teams活动"))
+		return
+	}
+
+	fromBotFramework, ok := h.authenticate(c, body, &activity)
+	if !ok {
+		h.logger.Warn("Teams请求认证失败", zap.String("remote_addr", c.ClientIP()))
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("INVALID_SIGNATURE", "Teams请求签名无效"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	// 分页动作：只接受Bot Framework认证的Action.Submit，按令牌取出服务端保存的查询重新执行
+	if len(activity.Value) > 0 {
+		var action TeamsPageAction
+		if err := json.Unmarshal(activity.Value, &action); err == nil && action.Action == teamsPageAction {
+			if !fromBotFramework {
+				c.JSON(http.StatusUnauthorized, NewErrorResponse("INVALID_SIGNATURE", "分页动作需通过Bot Framework认证"))
+				return
+			}
+			c.JSON(http.StatusOK, h.turnPage(ctx, &activity, &action))
+			return
+		}
+	}
+
+	question, connectionID := h.parseQuestion(activity.Text)
+	if question == "" {
+		c.JSON(http.StatusOK, newTeamsTextReply("请在提及机器人后输入问题，例如：@Chat2SQL 上个月的订单总数"))
+		return
+	}
+	if connectionID <= 0 {
+		c.JSON(http.StatusOK, newTeamsTextReply("未配置默认数据库连接，请使用 #<连接ID> 指定，例如：@Chat2SQL #3 上个月的订单总数"))
+		return
+	}
+
+	h.logger.Info("Teams提问",
+		zap.String("conversation_id", activity.Conversation.ID),
+		zap.String("from", activity.From.AADObjectID),
+		zap.Int64("connection_id", connectionID))
+
+	generated, err := h.aiService.GenerateSQL(ctx, &service.SQLGenerationRequest{
+		Query:        question,
+		ConnectionID: connectionID,
+		UserID:       h.config.ServiceUserID,
+		Locale:       service.DetectLocale(question),
+	})
+	if err != nil {
+		h.logger.Error("Teams提问生成SQL失败", zap.Error(err))
+		c.JSON(http.StatusOK, newTeamsTextReply("生成SQL失败，请稍后重试"))
+		return
+	}
+
+	query := &service.TeamsPage{
+		Question:       question,
+		SQL:            generated.SQL,
+		ConnectionID:   connectionID,
+		ConversationID: activity.Conversation.ID,
+	}
+	c.JSON(http.StatusOK, h.runAndRender(ctx, query, "", 0))
+}
+
+// authenticate 认证Teams请求：Bearer令牌按Bot Framework JWT校验，HMAC按传出Webhook签名校验
+// 返回请求是否来自Bot Framework与认证是否通过
+func (h *TeamsHandler) authenticate(c *gin.Context, body []byte, activity *TeamsActivity) (bool, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		if h.botAuth == nil {
+			return false, false
+		}
+		if err := h.botAuth.Verify(c.Request.Context(), authHeader, activity.ServiceURL); err != nil {
+			h.logger.Warn("Bot Framework令牌无效", zap.Error(err))
+			return false, false
+		}
+		return true, true
+	}
+	return false, h.verifySignature(c.Request.Context(), authHeader, body)
+}
+
+// turnPage 按分页令牌取出服务端保存的查询，重新校验权限后渲染指定页
+func (h *TeamsHandler) turnPage(ctx context.Context, activity *TeamsActivity, action *TeamsPageAction) *TeamsReply {
+	if h.pages == nil {
+		return newTeamsTextReply("分页已过期，请重新提问")
+	}
+	query, err := h.pages.Load(ctx, action.Token)
+	if err != nil {
+		if !errors.Is(err, service.ErrTeamsPageNotFound) {
+			h.logger.Error("读取Teams分页令牌失败", zap.Error(err))
+		}
+		return newTeamsTextReply("分页已过期，请重新提问")
+	}
+	// 令牌只在签发它的会话中有效，防止转贴到其他会话翻看结果
+	if query.ConversationID != activity.Conversation.ID {
+		h.logger.Warn("Teams分页令牌与会话不匹配", zap.String("conversation_id", activity.Conversation.ID))
+		return newTeamsTextReply("分页已过期，请重新提问")
+	}
+	return h.runAndRender(ctx, query, action.Token, action.Page)
+}
+
+// verifySignature 校验Teams传出Webhook的HMAC-SHA256签名，未配置密钥时一律拒绝
+func (h *TeamsHandler) verifySignature(ctx context.Context, authHeader string, body []byte) bool {
+	const prefix = "HMAC "
+	if !strings.HasPrefix(authHeader, prefix) {
+		return false
+	}
+	provided, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authHeader, prefix))
+	if err != nil {
+		return false
+	}
+	secret, err := h.signingSecret(ctx)
+	if err != nil {
+		h.logger.Error("读取Teams签名密钥失败", zap.Error(err))
+		return false
+	}
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hmac.Equal(provided, mac.Sum(nil))
+}
+
+// parseQuestion 去除@提及与HTML标签，并解析可选的 #<连接ID> 前缀
+func (h *TeamsHandler) parseQuestion(text string) (string, int64) {
+	text = teamsMentionPattern.ReplaceAllString(text, "")
+	text = teamsHTMLTagPattern.ReplaceAllString(text, "")
+	text = strings.TrimSpace(strings.ReplaceAll(text, "&nbsp;", " "))
+
+	connectionID := h.config.DefaultConnectionID
+	if m := teamsConnectionPattern.FindStringSubmatch(text); m != nil {
+		if id, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			connectionID = id
+		}
+		text = strings.TrimSpace(text[len(m[0]):])
+	}
+	return text, connectionID
+}
+
+// runAndRender 校验并执行SQL，渲染指定页的结果卡片
+// token为空表示首次执行，结果超过一页且支持分页时保存查询并签发分页令牌
+func (h *TeamsHandler) runAndRender(ctx context.Context, query *service.TeamsPage, token string, page int) *TeamsReply {
+	if result := h.validator.ValidateSQL(query.SQL); !result.IsValid || !result.IsReadOnly {
+		return newTeamsTextReply("生成的SQL未通过安全检查，已拒绝执行")
+	}
+
+	connection, ok := h.accessibleConnection(ctx, query.ConnectionID)
+	if !ok {
+		return newTeamsTextReply(fmt.Sprintf("数据库连接 #%d 不存在或机器人无权访问", query.ConnectionID))
+	}
+
+	result, err := h.sqlExecutor.ExecuteQuery(ctx, query.SQL, connection)
+	if err != nil {
+		h.logger.Error("Teams查询执行失败", zap.Error(err), zap.Int64("connection_id", query.ConnectionID))
+		return newTeamsTextReply("查询执行失败，请在Chat2SQL中查看详情")
+	}
+
+	if token == "" && h.pages != nil && h.botAuth != nil && len(result.Rows) > h.config.PageSize {
+		query.CreatedAt = time.Now()
+		if token, err = h.pages.Save(ctx, query); err != nil {
+			// 保存失败只影响翻页，仍返回第一页
+			h.logger.Warn("保存Teams分页令牌失败", zap.Error(err))
+			token = ""
+		}
+	}
+
+	card := h.buildResultCard(query, token, result, page)
+	return &TeamsReply{
+		Type: "message",
+		Attachments: []TeamsAttachment{
+			{ContentType: teamsAdaptiveCardType, Content: card},
+		},
+	}
+}
+
+// accessibleConnection 每次执行前重新校验机器人代为查询的权限：
+// 连接属于服务用户，且服务用户仍为活跃状态并具有执行查询的权限
+func (h *TeamsHandler) accessibleConnection(ctx context.Context, connectionID int64) (*repository.DatabaseConnection, bool) {
+	connection, err := h.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil || connection.UserID != h.config.ServiceUserID {
+		return nil, false
+	}
+	user, err := h.userRepo.GetByID(ctx, h.config.ServiceUserID)
+	if err != nil || !user.IsActive() || !repository.UserRole(user.Role).Can(repository.PermQueryExecute) {
+		h.logger.Warn("Teams服务用户不可用或无查询权限", zap.Int64("service_user_id", h.config.ServiceUserID))
+		return nil, false
+	}
+	return connection, true
+}
+
+// buildResultCard 构建带分页与深链接的自适应卡片，token为空时不提供分页按钮
+func (h *TeamsHandler) buildResultCard(query *service.TeamsPage, token string, result *service.QueryResult, page int) map[string]any {
+	pageSize := h.config.PageSize
+	totalPages := (len(result.Rows) + pageSize - 1) / pageSize
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	if page >= totalPages {
+		page = totalPages - 1
+	}
+
+	start := page * pageSize
+	end := min(start+pageSize, len(result.Rows))
+
+	body := []any{
+		map[string]any{"type": "TextBlock", "text": query.Question, "weight": "Bolder", "wrap": true},
+		map[string]any{"type": "TextBlock", "text": query.SQL, "fontType": "Monospace", "isSubtle": true, "wrap": true},
+		map[string]any{
+			"type":     "TextBlock",
+			"text":     fmt.Sprintf("共 %d 行，第 %d/%d 页，耗时 %dms", result.RowCount, page+1, totalPages, result.ExecutionTime),
+			"size":     "Small",
+			"isSubtle": true,
+		},
+	}
+	if len(result.Columns) > 0 {
+		body = append(body, buildTeamsTable(result.Columns, result.Rows[start:end]))
+	}
+
+	actions := []any{}
+	if token != "" && page > 0 {
+		actions = append(actions, pageAction("上一页", token, page-1))
+	}
+	if token != "" && page < totalPages-1 {
+		actions = append(actions, pageAction("下一页", token, page+1))
+	}
+	actions = append(actions, map[string]any{
+		"type":  "Action.OpenUrl",
+		"title": "在Chat2SQL中打开",
+		"url":   h.deepLink(query.Question, query.ConnectionID),
+	})
+
+	return map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.5",
+		"body":    body,
+		"actions": actions,
+	}
+}
+
+// pageAction 构建分页提交动作
+func pageAction(title, token string, page int) map[string]any {
+	return map[string]any{
+		"type":  "Action.Submit",
+		"title": title,
+		"data": TeamsPageAction{
+			Action: teamsPageAction,
+			Token:  token,
+			Page:   page,
+		},
+	}
+}
+
+// deepLink 生成"在Chat2SQL中打开"链接
+func (h *TeamsHandler) deepLink(question string, connectionID int64) string {
+	values := url.Values{}
+	values.Set("connection_id", strconv.FormatInt(connectionID, 10))
+	values.Set("q", question)
+	return strings.TrimRight(h.config.AppBaseURL, "/") + "/chat?" + values.Encode()
+}
+
+// buildTeamsTable 将结果行渲染为自适应卡片Table元素
+func buildTeamsTable(columns []string, rows []map[string]any) map[string]any {
+	tableColumns := make([]any, len(columns))
+	headerCells := make([]any, len(columns))
+	for i, col := range columns {
+		tableColumns[i] = map[string]any{"width": 1}
+		headerCells[i] = teamsCell(col, true)
+	}
+
+	tableRows := []any{map[string]any{"type": "TableRow", "cells": headerCells}}
+	for _, row := range rows {
+		cells := make([]any, len(columns))
+		for i, col := range columns {
+			cells[i] = teamsCell(formatTeamsValue(row[col]), false)
+		}
+		tableRows = append(tableRows, map[string]any{"type": "TableRow", "cells": cells})
+	}
+
+	return map[string]any{
+		"type":             "Table",
+		"columns":          tableColumns,
+		"rows":             tableRows,
+		"firstRowAsHeader": true,
+	}
+}
+
+// teamsCell 构建单元格
+func teamsCell(text string, header bool) map[string]any {
+	block := map[string]any{"type": "TextBlock", "text": text, "wrap": true}
+	if header {
+		block["weight"] = "Bolder"
+	}
+	return map[string]any{"type": "TableCell", "items": []any{block}}
+}
+
+// formatTeamsValue 格式化单元格值并截断过长内容
+func formatTeamsValue(value any) string {
+	if value == nil {
+		return "NULL"
+	}
+	text := fmt.Sprint(value)
+	if runes := []rune(text); len(runes) > teamsMaxCellLength {
+		text = string(runes[:teamsMaxCellLength]) + "…"
+	}
+	return text
+}
+
+// newTeamsTextReply 构建纯文本回复
+func newTeamsTextReply(text string) *TeamsReply {
+	return &TeamsReply{Type: "message", Text: text}
+}
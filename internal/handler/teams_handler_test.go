@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// MockAIService Mock AI服务
+type MockAIService struct {
+	mock.Mock
+}
+
+func (m *MockAIService) GenerateSQL(ctx context.Context, req *service.SQLGenerationRequest) (*service.SQLGenerationResponse, error) {
+	args := m.Called(ctx, req)
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+	return result.(*service.SQLGenerationResponse), args.Error(1)
+}
+
+func (m *MockAIService) Close() error {
+	return nil
+}
+
+var teamsTestSecret = base64.StdEncoding.EncodeToString([]byte("teams-test-secret"))
+
+// teamsBotToken 测试中视为有效的Bot Framework令牌
+const teamsBotToken = "Bearer bot-framework-token"
+
+// memTeamsPages 内存实现的分页令牌存储
+type memTeamsPages map[string]*service.TeamsPage
+
+func (m memTeamsPages) Save(_ context.Context, page *service.TeamsPage) (string, error) {
+	token := fmt.Sprintf("page-token-%d", len(m)+1)
+	m[token] = page
+	return token, nil
+}
+
+func (m memTeamsPages) Load(_ context.Context, token string) (*service.TeamsPage, error) {
+	if page, ok := m[token]; ok {
+		return page, nil
+	}
+	return nil, service.ErrTeamsPageNotFound
+}
+
+// stubBotAuth 只接受teamsBotToken的Bot Framework校验器
+type stubBotAuth struct{}
+
+func (stubBotAuth) Verify(_ context.Context, authHeader, _ string) error {
+	if authHeader != teamsBotToken {
+		return errors.New("invalid bot framework token")
+	}
+	return nil
+}
+
+type teamsTestDeps struct {
+	aiService *MockAIService
+	executor  *MockSQLExecutor
+	connRepo  *MockConnectionRepository
+	userRepo  *MockUserRepository
+	pages     memTeamsPages
+}
+
+func newTeamsTestRouter(t *testing.T) (*gin.Engine, *teamsTestDeps) {
+	gin.SetMode(gin.TestMode)
+
+	deps := &teamsTestDeps{
+		aiService: &MockAIService{},
+		executor:  &MockSQLExecutor{},
+		connRepo:  &MockConnectionRepository{},
+		userRepo:  &MockUserRepository{},
+		pages:     memTeamsPages{},
+	}
+	teamsConfig := &config.TeamsConfig{
+		Enabled:             true,
+		SigningSecret:       teamsTestSecret,
+		ServiceUserID:       7,
+		DefaultConnectionID: 1,
+		PageSize:            2,
+		AppBaseURL:          "https://chat2sql.example.com/",
+	}
+
+	h := NewTeamsHandler(deps.aiService, deps.executor, deps.connRepo, deps.userRepo, deps.pages, stubBotAuth{}, nil,
+		teamsConfig, zaptest.NewLogger(t))
+	r := gin.New()
+	r.POST("/teams", h.HandleMessage)
+	return r, deps
+}
+
+// withServiceUser 设置机器人代为查询的服务用户
+func (d *teamsTestDeps) withServiceUser(role repository.UserRole) {
+	d.userRepo.On("GetByID", mock.Anything, int64(7)).Return(testutil.NewUser(7, testutil.WithUserRole(role)), nil)
+}
+
+func signTeamsBody(body []byte) string {
+	key, _ := base64.StdEncoding.DecodeString(teamsTestSecret)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "HMAC " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func postTeams(r *gin.Engine, body []byte, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/teams", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", signature)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestTeamsHandler_InvalidSignature(t *testing.T) {
+	r, _ := newTeamsTestRouter(t)
+
+	body := []byte(`{"type":"message","text":"hello"}`)
+	w := postTeams(r, body, "HMAC "+base64.StdEncoding.EncodeToString([]byte("wrong")))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = postTeams(r, body, "Bearer forged")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "Bot Framework令牌无效")
+}
+
+func TestTeamsHandler_QuestionToCard(t *testing.T) {
+	r, deps := newTeamsTestRouter(t)
+	aiService, executor, connRepo := deps.aiService, deps.executor, deps.connRepo
+	deps.withServiceUser(repository.RoleUser)
+
+	connection := testutil.NewConnection(3, 7)
+	aiService.On("GenerateSQL", mock.Anything, mock.MatchedBy(func(req *service.SQLGenerationRequest) bool {
+		return req.Query == "上个月订单数" && req.ConnectionID == 3 && req.UserID == 7
+	})).Return(&service.SQLGenerationResponse{SQL: "SELECT id, total FROM orders"}, nil)
+	connRepo.On("GetByID", mock.Anything, int64(3)).Return(connection, nil)
+	executor.On("ExecuteQuery", mock.Anything, "SELECT id, total FROM orders", connection).Return(&service.QueryResult{
+		Columns:  []string{"id", "total"},
+		Rows:     []map[string]any{{"id": 1, "total": 10}, {"id": 2, "total": 20}, {"id": 3, "total": nil}},
+		RowCount: 3,
+	}, nil)
+
+	body := []byte(`{"type":"message","text":"<at>Chat2SQL</at> #3 上个月订单数","from":{"id":"u1","aadObjectId":"aad-1"},"conversation":{"id":"c1"},"channelData":{"tenant":{"id":"t"}}}`)
+	w := postTeams(r, body, signTeamsBody(body))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var reply TeamsReply
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reply))
+	require.Len(t, reply.Attachments, 1)
+	assert.Equal(t, teamsAdaptiveCardType, reply.Attachments[0].ContentType)
+
+	card := reply.Attachments[0].Content
+	actions := card["actions"].([]any)
+	require.Len(t, actions, 2) // 下一页 + 深链接
+	assert.Equal(t, "Action.Submit", actions[0].(map[string]any)["type"])
+	data := actions[0].(map[string]any)["data"].(map[string]any)
+	assert.NotContains(t, data, "sql", "卡片不携带SQL")
+	require.Contains(t, deps.pages, data["token"])
+	assert.Equal(t, "SELECT id, total FROM orders", deps.pages[data["token"].(string)].SQL)
+	assert.Equal(t, "c1", deps.pages[data["token"].(string)].ConversationID)
+	assert.Equal(t, "https://chat2sql.example.com/chat?connection_id=3&q=%E4%B8%8A%E4%B8%AA%E6%9C%88%E8%AE%A2%E5%8D%95%E6%95%B0",
+		actions[1].(map[string]any)["url"])
+
+	aiService.AssertExpectations(t)
+	executor.AssertExpectations(t)
+}
+
+func TestTeamsHandler_PageAction(t *testing.T) {
+	r, deps := newTeamsTestRouter(t)
+	deps.withServiceUser(repository.RoleUser)
+	deps.pages["tok"] = &service.TeamsPage{Question: "用户", SQL: "SELECT id FROM users", ConnectionID: 1, ConversationID: "c1"}
+
+	connection := testutil.NewConnection(1, 7)
+	deps.connRepo.On("GetByID", mock.Anything, int64(1)).Return(connection, nil)
+	deps.executor.On("ExecuteQuery", mock.Anything, "SELECT id FROM users", connection).Return(&service.QueryResult{
+		Columns:  []string{"id"},
+		Rows:     []map[string]any{{"id": 1}, {"id": 2}, {"id": 3}},
+		RowCount: 3,
+	}, nil)
+
+	body := []byte(`{"type":"message","conversation":{"id":"c1"},"value":{"action":"chat2sql.page","token":"tok","page":1}}`)
+	w := postTeams(r, body, teamsBotToken)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var reply TeamsReply
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reply))
+	require.Len(t, reply.Attachments, 1)
+
+	actions := reply.Attachments[0].Content["actions"].([]any)
+	require.Len(t, actions, 2) // 上一页 + 深链接
+	assert.Equal(t, "上一页", actions[0].(map[string]any)["title"])
+	deps.aiService.AssertNotCalled(t, "GenerateSQL", mock.Anything, mock.Anything)
+}
+
+func TestTeamsHandler_PageActionRejected(t *testing.T) {
+	r, deps := newTeamsTestRouter(t)
+	deps.pages["tok"] = &service.TeamsPage{Question: "用户", SQL: "SELECT id FROM users", ConnectionID: 1, ConversationID: "c1"}
+
+	// 传出Webhook的HMAC签名不能用于分页动作
+	body := []byte(`{"type":"message","conversation":{"id":"c1"},"value":{"action":"chat2sql.page","token":"tok","page":1}}`)
+	w := postTeams(r, body, signTeamsBody(body))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 卡片数据中的SQL被忽略，未知令牌不执行任何查询
+	body = []byte(`{"type":"message","conversation":{"id":"c1"},"value":{"action":"chat2sql.page","token":"forged","sql":"SELECT * FROM salaries","connection_id":1,"page":0}}`)
+	w = postTeams(r, body, teamsBotToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "分页已过期")
+
+	// 令牌不能在其他会话中使用
+	body = []byte(`{"type":"message","conversation":{"id":"other"},"value":{"action":"chat2sql.page","token":"tok","page":1}}`)
+	w = postTeams(r, body, teamsBotToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "分页已过期")
+
+	deps.executor.AssertNotCalled(t, "ExecuteQuery", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTeamsHandler_PageActionRechecksPermission(t *testing.T) {
+	r, deps := newTeamsTestRouter(t)
+	deps.withServiceUser(repository.RoleViewer) // 签发令牌后服务用户被降级
+	deps.pages["tok"] = &service.TeamsPage{Question: "用户", SQL: "SELECT id FROM users", ConnectionID: 1, ConversationID: "c1"}
+	deps.connRepo.On("GetByID", mock.Anything, int64(1)).Return(testutil.NewConnection(1, 7), nil)
+
+	body := []byte(`{"type":"message","conversation":{"id":"c1"},"value":{"action":"chat2sql.page","token":"tok","page":1}}`)
+	w := postTeams(r, body, teamsBotToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "无权访问")
+	deps.executor.AssertNotCalled(t, "ExecuteQuery", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTeamsHandler_RejectsWriteSQL(t *testing.T) {
+	r, deps := newTeamsTestRouter(t)
+	aiService, executor := deps.aiService, deps.executor
+
+	aiService.On("GenerateSQL", mock.Anything, mock.Anything).
+		Return(&service.SQLGenerationResponse{SQL: "DELETE FROM users"}, nil)
+
+	body := []byte(`{"type":"message","text":"<at>Chat2SQL</at> 删除所有用户"}`)
+	w := postTeams(r, body, signTeamsBody(body))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var reply TeamsReply
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reply))
+	assert.Empty(t, reply.Attachments)
+	assert.Contains(t, reply.Text, "安全检查")
+	executor.AssertNotCalled(t, "ExecuteQuery", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTeamsConfig_Validate(t *testing.T) {
+	cfg := config.DefaultTeamsConfig()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Enabled = true
+	cfg.SigningSecret = teamsTestSecret
+	assert.Error(t, cfg.Validate())
+
+	cfg.ServiceUserID = 1
+	assert.NoError(t, cfg.Validate())
+
+	cfg.SigningSecret = ""
+	cfg.AppID = "bot-app-id"
+	assert.NoError(t, cfg.Validate(), "只配置Bot Framework应用ID")
+}
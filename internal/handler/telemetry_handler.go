@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/telemetry"
+)
+
+// TelemetryHandler 匿名使用统计处理器
+// 管理员可在上报前查看下一批将要发送的全部内容
+type TelemetryHandler struct {
+	collector *telemetry.Collector
+	logger    *zap.Logger
+}
+
+// NewTelemetryHandler 创建匿名使用统计处理器实例
+func NewTelemetryHandler(collector *telemetry.Collector, logger *zap.Logger) *TelemetryHandler {
+	return &TelemetryHandler{
+		collector: collector,
+		logger:    logger,
+	}
+}
+
+// Routes 声明匿名使用统计路由，需要admin角色
+func (h *TelemetryHandler) Routes() []RouteGroup {
+	admin := []string{string(repository.RoleAdmin)}
+	return []RouteGroup{
+		{
+			Prefix: "/admin/telemetry",
+			Tag:    "admin",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.GetTelemetry, Summary: "查看待上报的匿名使用统计", Roles: admin},
+			},
+		},
+	}
+}
+
+// GetTelemetry 查看待上报的匿名使用统计
+// @Summary 查看待上报的匿名使用统计
+// @Description 返回上报地址、最近一次上报结果，以及下一次上报将发送的完整批次（需admin角色）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} telemetry.Status "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Router /api/v1/admin/telemetry [get]
+func (h *TelemetryHandler) GetTelemetry(c *gin.Context) {
+	c.JSON(http.StatusOK, h.collector.Status())
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/telemetry"
+)
+
+func TestTelemetryHandler_GetTelemetry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.DefaultTelemetryConfig()
+	cfg.Enabled = true
+	collector := telemetry.NewCollector(cfg, "1.2.0", zap.NewNop())
+	collector.Record("POST /api/v1/ai/chat2sql", 120*time.Millisecond)
+
+	h := NewTelemetryHandler(collector, zap.NewNop())
+	r := gin.New()
+	r.GET("/admin/telemetry", h.GetTelemetry)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/telemetry", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp telemetry.Status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Endpoint)
+	assert.Equal(t, "1h0m0s", resp.FlushInterval)
+	assert.Equal(t, int64(1), resp.Pending.Features["POST /api/v1/ai/chat2sql"])
+	assert.Equal(t, telemetry.LatencyBucketsMs, resp.Pending.BucketsMs)
+}
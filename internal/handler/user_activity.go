@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/service"
+)
+
+// SetRunningQueries 启用活动概览中的运行中查询列表
+func (h *UserHandler) SetRunningQueries(registry *service.RunningQueryRegistry) {
+	h.runningQueries = registry
+}
+
+// ActiveQuery 正在执行的查询
+type ActiveQuery struct {
+	ID           uint64    `json:"id" example:"42"`
+	ConnectionID int64     `json:"connection_id" example:"1"`
+	SQL          string    `json:"sql" example:"SELECT * FROM orders WHERE ..."`
+	StartedAt    time.Time `json:"started_at"`
+	ElapsedMS    int64     `json:"elapsed_ms" example:"1830"`
+}
+
+// DailyQueryStats 当日查询执行统计
+type DailyQueryStats struct {
+	Date              string  `json:"date" example:"2025-08-12"`
+	ExecutionCount    int64   `json:"execution_count" example:"37"`
+	SuccessfulQueries int64   `json:"successful_queries" example:"35"`
+	FailedQueries     int64   `json:"failed_queries" example:"2"`
+	AvgLatencyMS      float64 `json:"avg_latency_ms" example:"412.5"`
+}
+
+// UserActivityResponse 用户查询活动概览
+type UserActivityResponse struct {
+	RunningCount   int              `json:"running_count" example:"2"`
+	RunningQueries []ActiveQuery    `json:"running_queries"`
+	Today          *DailyQueryStats `json:"today"`
+}
+
+// GetActivity 获取当前用户的查询活动概览
+// @Summary 查询活动概览
+// @Description 返回当前用户正在执行的查询、当日执行次数与平均耗时，用于自行排查查询排队或变慢的原因
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Param tz query string false "统计当日所用的时区（IANA名称），默认UTC" example(Asia/Shanghai)
+// @Success 200 {object} UserActivityResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "时区无效"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/users/me/activity [get]
+func (h *UserHandler) GetActivity(c *gin.Context) {
+	userID := h.getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	location := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_TIMEZONE", "无效的时区: "+tz))
+			return
+		}
+		location = loc
+	}
+
+	now := time.Now().In(location)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	stats, err := h.queryHistoryRepo.GetExecutionStatsSince(c.Request.Context(), userID, startOfDay)
+	if err != nil {
+		h.logger.Error("Failed to get daily execution stats", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("DATABASE_ERROR", "获取查询统计失败"))
+		return
+	}
+
+	running := h.runningQueries.ListByUser(userID)
+	response := &UserActivityResponse{
+		RunningCount:   len(running),
+		RunningQueries: make([]ActiveQuery, 0, len(running)),
+		Today: &DailyQueryStats{
+			Date:              startOfDay.Format(time.DateOnly),
+			ExecutionCount:    stats.TotalQueries,
+			SuccessfulQueries: stats.SuccessfulQueries,
+			FailedQueries:     stats.FailedQueries,
+			AvgLatencyMS:      stats.AverageExecutionTime,
+		},
+	}
+	for _, q := range running {
+		response.RunningQueries = append(response.RunningQueries, ActiveQuery{
+			ID:           q.ID,
+			ConnectionID: q.ConnectionID,
+			SQL:          q.SQL,
+			StartedAt:    q.StartedAt,
+			ElapsedMS:    time.Since(q.StartedAt).Milliseconds(),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
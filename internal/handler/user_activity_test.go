@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+func TestUserHandler_GetActivity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	historyRepo := &MockQueryHistoryRepository{}
+	historyRepo.On("GetExecutionStatsSince", mock.Anything, int64(7), mock.MatchedBy(func(since time.Time) bool {
+		now := time.Now().UTC()
+		return since.Equal(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	})).Return(&repository.QueryExecutionStats{
+		UserID:               7,
+		TotalQueries:         12,
+		SuccessfulQueries:    11,
+		FailedQueries:        1,
+		AverageExecutionTime: 250.5,
+	}, nil)
+
+	registry := service.NewRunningQueryRegistry()
+	_, finish := registry.Track(service.WithQueryOwner(context.Background(), 7), 3, "SELECT * FROM orders")
+	defer finish()
+	registry.Track(service.WithQueryOwner(context.Background(), 8), 4, "SELECT 1")
+
+	h := NewUserHandler(nil, historyRepo, nil, zaptest.NewLogger(t))
+	h.SetRunningQueries(registry)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", int64(7)) })
+	r.GET("/users/me/activity", h.GetActivity)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/activity", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp UserActivityResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.RunningCount, "只返回本人的运行中查询")
+	require.Len(t, resp.RunningQueries, 1)
+	assert.Equal(t, int64(3), resp.RunningQueries[0].ConnectionID)
+	assert.Equal(t, int64(12), resp.Today.ExecutionCount)
+	assert.Equal(t, 250.5, resp.Today.AvgLatencyMS)
+	historyRepo.AssertExpectations(t)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/activity?tz=Mars/Olympus", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
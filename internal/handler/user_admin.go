@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+)
+
+// UserPermissionsResponse 当前用户的角色与权限
+type UserPermissionsResponse struct {
+	Role        string                  `json:"role" example:"viewer"`
+	Permissions []repository.Permission `json:"permissions" example:"profile:read,query:generate"`
+}
+
+// AdminUserListParams 用户列表参数，role与status同时指定时只按role筛选
+type AdminUserListParams struct {
+	Role   string `form:"role" binding:"omitempty,oneof=viewer user manager admin" example:"viewer"`
+	Status string `form:"status" binding:"omitempty,oneof=active inactive locked" example:"active"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100" example:"20"`
+	Cursor string `form:"cursor" binding:"omitempty,max=512"`
+}
+
+// AdminUserListResponse 用户列表响应
+type AdminUserListResponse struct {
+	Users []*repository.User `json:"users"`
+	Limit int                `json:"limit" example:"20"`
+	CursorPage
+}
+
+// UpdateUserRoleRequest 修改用户角色请求
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer user manager admin" example:"viewer"`
+}
+
+// UpdateUserStatusRequest 修改用户状态请求
+type UpdateUserStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active inactive locked" example:"inactive"`
+}
+
+// GetPermissions 获取当前用户的权限
+// @Summary 当前用户权限
+// @Description 返回当前角色及其在权限矩阵中的全部权限，前端据此隐藏无权使用的功能
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} UserPermissionsResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Router /api/v1/users/me/permissions [get]
+func (h *UserHandler) GetPermissions(c *gin.Context) {
+	role, ok := middleware.GetUserRoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	c.JSON(http.StatusOK, &UserPermissionsResponse{
+		Role:        role,
+		Permissions: repository.UserRole(role).Permissions(),
+	})
+}
+
+// ListUsers 用户列表
+// @Summary 用户列表
+// @Description 按角色或状态筛选用户（需user:manage权限）
+// @Tags 管理
+// @Produce json
+// @Security BearerAuth
+// @Param role query string false "角色" Enums(viewer, user, manager, admin)
+// @Param status query string false "状态" Enums(active, inactive, locked)
+// @Param limit query int false "每页数量" default(20)
+// @Param cursor query string false "上一页返回的next_cursor"
+// @Success 200 {object} AdminUserListResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/users [get]
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	var params AdminUserListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_PARAMS",
+			Message: "查询参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	scope := listScope("admin_users", params.Role, params.Status)
+	offset, err := resolveOffset(params.Cursor, 0, scope)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
+	}
+
+	ctx := c.Request.Context()
+	var users []*repository.User
+	switch {
+	case params.Role != "":
+		users, err = h.userRepo.ListByRole(ctx, repository.UserRole(params.Role), params.Limit+1, offset)
+	case params.Status != "":
+		users, err = h.userRepo.ListByStatus(ctx, repository.UserStatus(params.Status), params.Limit+1, offset)
+	default:
+		users, err = h.userRepo.List(ctx, params.Limit+1, offset)
+	}
+	if err != nil {
+		h.logger.Error("Failed to list users", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("LIST_USERS_FAILED", "获取用户列表失败"))
+		return
+	}
+	if users == nil {
+		users = []*repository.User{}
+	}
+	users, page := paginate(users, offset, params.Limit, scope)
+
+	c.JSON(http.StatusOK, &AdminUserListResponse{Users: users, Limit: params.Limit, CursorPage: page})
+}
+
+// UpdateUserRole 修改用户角色
+// @Summary 修改用户角色
+// @Description 修改其他用户的角色，用户刷新令牌后生效；不能修改自己的角色，避免系统中没有管理员（需user:manage权限）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body UpdateUserRoleRequest true "新角色"
+// @Success 200 {object} repository.User "修改成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "用户不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/users/{id}/role [put]
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	user, ok := h.loadManagedUser(c)
+	if !ok {
+		return
+	}
+
+	operatorID := h.getUserIDFromContext(c)
+	user.Role = req.Role
+	user.UpdateBy = &operatorID
+	if err := h.userRepo.Update(c.Request.Context(), user); err != nil {
+		h.logger.Error("Failed to update user role", zap.Int64("user_id", user.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("UPDATE_ROLE_FAILED", "修改用户角色失败"))
+		return
+	}
+
+	h.logger.Info("User role changed",
+		zap.Int64("user_id", user.ID),
+		zap.String("role", req.Role),
+		zap.Int64("operator_id", operatorID))
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUserStatus 修改用户状态
+// @Summary 修改用户状态
+// @Description 启用、停用或锁定其他用户，停用或锁定的用户无法登录与刷新令牌（需user:manage权限）
+// @Tags 管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body UpdateUserStatusRequest true "新状态"
+// @Success 200 {object} repository.User "修改成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 404 {object} ErrorResponse "用户不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/admin/users/{id}/status [put]
+func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
+	var req UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	user, ok := h.loadManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userRepo.UpdateStatus(c.Request.Context(), user.ID, repository.UserStatus(req.Status)); err != nil {
+		h.logger.Error("Failed to update user status", zap.Int64("user_id", user.ID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("UPDATE_STATUS_FAILED", "修改用户状态失败"))
+		return
+	}
+	user.Status = req.Status
+
+	h.logger.Info("User status changed",
+		zap.Int64("user_id", user.ID),
+		zap.String("status", req.Status),
+		zap.Int64("operator_id", h.getUserIDFromContext(c)))
+	c.JSON(http.StatusOK, user)
+}
+
+// loadManagedUser 读取路径中的目标用户，目标为当前用户本人时返回400
+func (h *UserHandler) loadManagedUser(c *gin.Context) (*repository.User, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_USER_ID", "用户ID格式错误"))
+		return nil, false
+	}
+	if userID == h.getUserIDFromContext(c) {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("CANNOT_MANAGE_SELF", "不能修改自己的角色或状态"))
+		return nil, false
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, NewErrorResponse("USER_NOT_FOUND", "用户不存在"))
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Error("Failed to get user", zap.Int64("user_id", userID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("GET_USER_FAILED", "获取用户失败"))
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+func TestUserHandler_AdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	target := testutil.NewUser(9, testutil.WithUsername("bob"))
+	userRepo := &MockUserRepository{}
+	userRepo.On("ListByRole", mock.Anything, repository.RoleUser, 21, 0).Return([]*repository.User{target}, nil)
+	userRepo.On("GetByID", mock.Anything, int64(9)).Return(target, nil)
+	userRepo.On("GetByID", mock.Anything, int64(404)).Return(nil, repository.ErrNotFound)
+	userRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *repository.User) bool {
+		return u.ID == 9 && u.Role == string(repository.RoleViewer) && *u.UpdateBy == 7
+	})).Return(nil)
+	userRepo.On("UpdateStatus", mock.Anything, int64(9), repository.StatusLocked).Return(nil)
+
+	router := gin.New()
+	SetupRoutes(router, &RouterConfig{
+		AuthMiddleware: headerAuth{},
+		UserHandler:    NewUserHandler(userRepo, nil, nil, zaptest.NewLogger(t)),
+	})
+
+	serve := func(method, path, role, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer test")
+		req.Header.Set("X-Test-Role", role)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 只有admin拥有user:manage权限
+	for _, role := range []string{"viewer", "user", "manager"} {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/api/v1/admin/users", role, "").Code, role)
+	}
+
+	w := serve(http.MethodGet, "/api/v1/admin/users?role=user", "admin", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list AdminUserListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Users, 1)
+	assert.Equal(t, "bob", list.Users[0].Username)
+
+	w = serve(http.MethodPut, "/api/v1/admin/users/9/role", "admin", `{"role":"viewer"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"role":"viewer"`)
+
+	w = serve(http.MethodPut, "/api/v1/admin/users/9/status", "admin", `{"status":"locked"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/api/v1/admin/users/9/role", "admin", `{"role":"editor"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/api/v1/admin/users/7/role", "admin", `{"role":"viewer"}`).Code, "不能修改自己的角色")
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/api/v1/admin/users/404/status", "admin", `{"status":"active"}`).Code)
+	userRepo.AssertExpectations(t)
+
+	// 所有角色都可以查看自己的权限
+	w = serve(http.MethodGet, "/api/v1/users/me/permissions", "viewer", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var perms UserPermissionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &perms))
+	assert.Contains(t, perms.Permissions, repository.PermQueryExecuteShared)
+	assert.NotContains(t, perms.Permissions, repository.PermQueryExecute)
+}
+
+func TestSQLHandler_CanExecuteOn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	shared := int64(3)
+	workspace := &repository.Workspace{Name: "default", Defaults: &repository.WorkspaceDefaults{ConnectionID: &shared}}
+	workspace.ID = repository.DefaultWorkspaceID
+	h := &SQLHandler{logger: zaptest.NewLogger(t)}
+	h.SetWorkspaceSettings(service.NewWorkspaceSettingsService(&stubWorkspaceRepository{workspace: workspace}, nil, nil, zaptest.NewLogger(t)))
+
+	canExecute := func(role string, conn *repository.DatabaseConnection) bool {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/sql/execute", nil)
+		if role != "" {
+			c.Set("user_role", role)
+		}
+		return h.canExecuteOn(c, 7, conn)
+	}
+
+	assert.True(t, canExecute("user", testutil.NewConnection(1, 7)))
+	assert.False(t, canExecute("user", testutil.NewConnection(2, 8)), "编辑者不能使用他人的非共享连接")
+	assert.True(t, canExecute("", testutil.NewConnection(1, 7)), "开发模式没有角色时按连接所有者判断")
+
+	// viewer只能使用工作空间的默认连接，即使是自己创建的连接也不行
+	assert.True(t, canExecute("viewer", testutil.NewConnection(shared, 8)))
+	assert.False(t, canExecute("viewer", testutil.NewConnection(1, 7)))
+	assert.False(t, canExecute("guest", testutil.NewConnection(shared, 8)))
+}
+
+func TestSQLHandler_ExecuteRequiresQueryExecute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	shared := int64(3)
+	workspace := &repository.Workspace{Name: "default", Defaults: &repository.WorkspaceDefaults{ConnectionID: &shared}}
+	workspace.ID = repository.DefaultWorkspaceID
+	h := &SQLHandler{logger: zaptest.NewLogger(t)}
+	h.SetWorkspaceSettings(service.NewWorkspaceSettingsService(&stubWorkspaceRepository{workspace: workspace}, nil, nil, zaptest.NewLogger(t)))
+
+	router := gin.New()
+	SetupRoutes(router, &RouterConfig{AuthMiddleware: headerAuth{}, Providers: []RouteProvider{h}})
+
+	// viewer可以使用共享连接，但不能在共享连接上执行手写SQL
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sql/execute",
+		strings.NewReader(`{"sql":"SELECT * FROM salaries","connection_id":3}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer test")
+	req.Header.Set("X-Test-Role", string(repository.RoleViewer))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), string(repository.PermQueryExecute))
+}
@@ -10,6 +10,7 @@ import (
 
 	"chat2sql-go/internal/middleware"
 	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
 )
 
 // UserHandler 用户管理处理器
@@ -18,6 +19,7 @@ type UserHandler struct {
 	userRepo         repository.UserRepository
 	queryHistoryRepo repository.QueryHistoryRepository
 	connectionRepo   repository.ConnectionRepository
+	runningQueries   *service.RunningQueryRegistry // 运行中查询登记表（可选）
 	logger           *zap.Logger
 }
 
@@ -36,6 +38,35 @@ func NewUserHandler(
 	}
 }
 
+// Routes 声明用户资料与用户管理路由
+func (h *UserHandler) Routes() []RouteGroup {
+	manage := repository.PermUserManage
+	return []RouteGroup{
+		{
+			Prefix: "/users",
+			Tag:    "users",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/profile", Handler: h.GetProfile, Summary: "获取用户资料"},
+				{Method: http.MethodPut, Path: "/profile", Handler: h.UpdateProfile, Summary: "更新用户资料"},
+				{Method: http.MethodPost, Path: "/change-password", Handler: h.ChangePassword, Summary: "修改密码"},
+				{Method: http.MethodGet, Path: "/me/activity", Handler: h.GetActivity, Summary: "查询活动概览"},
+				{Method: http.MethodGet, Path: "/me/permissions", Handler: h.GetPermissions, Summary: "当前用户权限"},
+			},
+		},
+		{
+			Prefix: "/admin/users",
+			Tag:    "admin",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "", Handler: h.ListUsers, Summary: "用户列表", Permission: manage},
+				{Method: http.MethodPut, Path: "/:id/role", Handler: h.UpdateUserRole, Summary: "修改用户角色", Permission: manage},
+				{Method: http.MethodPut, Path: "/:id/status", Handler: h.UpdateUserStatus, Summary: "修改用户状态", Permission: manage},
+			},
+		},
+	}
+}
+
 // UpdateProfileRequest 更新用户资料请求结构
 type UpdateProfileRequest struct {
 	Email string `json:"email" binding:"omitempty,email,max=100" example:"new_email@example.com"`
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"chat2sql-go/internal/config"
+)
+
+// API版本
+const (
+	APIVersionV1 = "v1"
+	APIVersionV2 = "v2"
+)
+
+// apiVersionKey 上下文中保存API版本的键
+const apiVersionKey = "api_version"
+
+// APIVersionFromContext 获取当前请求的API版本
+// 处理器需要按版本返回不同响应结构时使用，未经过版本分组的请求视为v1
+func APIVersionFromContext(c *gin.Context) string {
+	if version := c.GetString(apiVersionKey); version != "" {
+		return version
+	}
+	return APIVersionV1
+}
+
+// apiVersionMiddleware 标记请求的API版本
+func apiVersionMiddleware(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Header("API-Version", version)
+		c.Next()
+	}
+}
+
+// v1DeprecationMiddleware 为v1响应添加弃用相关的响应头
+// Deprecation遵循RFC 9745，Sunset遵循RFC 8594，Link指向对应的v2接口；超过停止时间后返回410
+func v1DeprecationMiddleware(cfg *config.APIVersionConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.V1Deprecated {
+			c.Next()
+			return
+		}
+
+		if cfg.V1DeprecatedAt.IsZero() {
+			c.Header("Deprecation", "true")
+		} else {
+			c.Header("Deprecation", "@"+strconv.FormatInt(cfg.V1DeprecatedAt.Unix(), 10))
+		}
+		if !cfg.V1SunsetAt.IsZero() {
+			c.Header("Sunset", cfg.V1SunsetAt.UTC().Format(http.TimeFormat))
+		}
+		successor := "/api/" + APIVersionV2 + strings.TrimPrefix(c.Request.URL.Path, "/api/"+APIVersionV1)
+		c.Header("Link", "<"+successor+`>; rel="successor-version"`)
+
+		if !cfg.V1SunsetAt.IsZero() && time.Now().After(cfg.V1SunsetAt) {
+			c.AbortWithStatusJSON(http.StatusGone, ErrorResponse{
+				Code:    "API_VERSION_SUNSET",
+				Message: "API v1已停止服务，请迁移到v2",
+				Details: successor,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// v2ResponseShim 把处理器返回的v1错误结构转换为v2结构
+// 处理器只需维护一份实现，v1客户端在过渡期内收到的响应保持不变
+func v2ResponseShim() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &errorEnvelopeWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		defer func() { c.Writer = writer.ResponseWriter }()
+
+		c.Next()
+
+		if writer.buffered {
+			writer.ResponseWriter.Write(envelopeError(writer.body.Bytes(), c.GetString("request_id")))
+		}
+	}
+}
+
+// errorEnvelopeWriter 缓存错误状态码的响应体，成功响应直接透传
+type errorEnvelopeWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	buffered bool
+}
+
+func (w *errorEnvelopeWriter) Write(data []byte) (int, error) {
+	if w.Status() < http.StatusBadRequest {
+		return w.ResponseWriter.Write(data)
+	}
+	w.buffered = true
+	return w.body.Write(data)
+}
+
+func (w *errorEnvelopeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// envelopeError 把v1错误结构包装为v2结构，无法识别的响应体原样返回
+// v1: {"code","message","details",...}；v2: {"error":{"code","message","details",...},"request_id","timestamp"}
+func envelopeError(body []byte, requestID string) []byte {
+	var legacy map[string]any
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return body
+	}
+	if code, _ := legacy["code"].(string); code == "" {
+		return body
+	}
+
+	envelope := gin.H{"timestamp": time.Now().UTC().Format(time.RFC3339)}
+	for _, key := range []string{"request_id", "timestamp"} {
+		if v, ok := legacy[key]; ok {
+			envelope[key] = v
+			delete(legacy, key)
+		}
+	}
+	if _, ok := envelope["request_id"]; !ok && requestID != "" {
+		envelope["request_id"] = requestID
+	}
+	envelope["error"] = legacy
+
+	wrapped, err := json.Marshal(envelope)
+	if err != nil {
+		return body
+	}
+	return wrapped
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/internal/config"
+)
+
+func newVersionedRouter(versioning *config.APIVersionConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, &RouterConfig{
+		APIVersion: versioning,
+		Providers: []RouteProvider{staticRoutes{
+			{Prefix: "/reports", Tag: "reports", Auth: AuthNone, Routes: []Route{
+				{Method: http.MethodGet, Path: "/:id", Summary: "查看报表", Handler: func(c *gin.Context) {
+					if c.Param("id") == "missing" {
+						c.Set("request_id", "req-1")
+						c.JSON(http.StatusNotFound, NewErrorResponse("REPORT_NOT_FOUND", "报表不存在"))
+						return
+					}
+					c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "version": APIVersionFromContext(c)})
+				}},
+			}},
+		}},
+	})
+	return router
+}
+
+func TestAPIVersioning_V1Deprecation(t *testing.T) {
+	router := newVersionedRouter(&config.APIVersionConfig{
+		V1Deprecated:   true,
+		V1DeprecatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		V1SunsetAt:     time.Now().Add(24 * time.Hour),
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/reports/7", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "v1", w.Header().Get("API-Version"))
+	assert.Equal(t, "@1767225600", w.Header().Get("Deprecation"))
+	assert.NotEmpty(t, w.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/reports/7>; rel="successor-version"`, w.Header().Get("Link"))
+	assert.JSONEq(t, `{"id":"7","version":"v1"}`, w.Body.String())
+
+	// v2不带弃用头
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/reports/7", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.JSONEq(t, `{"id":"7","version":"v2"}`, w.Body.String())
+}
+
+func TestAPIVersioning_V1Sunset(t *testing.T) {
+	router := newVersionedRouter(&config.APIVersionConfig{
+		V1Deprecated: true,
+		V1SunsetAt:   time.Now().Add(-time.Hour),
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/reports/7", nil))
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Contains(t, w.Body.String(), "API_VERSION_SUNSET")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/reports/7", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAPIVersioning_V2ErrorEnvelope(t *testing.T) {
+	router := newVersionedRouter(config.DefaultAPIVersionConfig())
+
+	// v1保持原有错误结构
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/reports/missing", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	var legacy ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &legacy))
+	assert.Equal(t, "REPORT_NOT_FOUND", legacy.Code)
+
+	// v2把错误收拢到error字段下
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/reports/missing", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	var envelope struct {
+		Error     map[string]any `json:"error"`
+		RequestID string         `json:"request_id"`
+		Timestamp string         `json:"timestamp"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+	assert.Equal(t, "REPORT_NOT_FOUND", envelope.Error["code"])
+	assert.Equal(t, "报表不存在", envelope.Error["message"])
+	assert.Equal(t, "req-1", envelope.RequestID)
+	assert.NotEmpty(t, envelope.Timestamp)
+	assert.NotContains(t, envelope.Error, "timestamp")
+
+	// 非JSON错误体原样返回
+	assert.Equal(t, []byte("boom"), envelopeError([]byte("boom"), ""))
+}
+
+func TestAPIVersioning_OpenAPI(t *testing.T) {
+	router := newVersionedRouter(config.DefaultAPIVersionConfig())
+
+	for version, deprecated := range map[string]bool{"v1": true, "v2": false} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/"+version+"/openapi.json", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var spec struct {
+			Paths map[string]map[string]struct {
+				Deprecated bool `json:"deprecated"`
+			} `json:"paths"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+		operation, ok := spec.Paths["/api/"+version+"/reports/{id}"]["get"]
+		require.True(t, ok, version)
+		assert.Equal(t, deprecated, operation.Deprecated, version)
+	}
+}
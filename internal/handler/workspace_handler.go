@@ -0,0 +1,596 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// WorkspaceHandler 工作空间处理器
+// 管理当前用户所属工作空间的设置
+type WorkspaceHandler struct {
+	workspaceRepo repository.WorkspaceRepository
+	approvals     *service.ApprovalEngine   // 为空时策略变更直接生效
+	residency     *service.ResidencyService // 数据驻留策略（可选）
+	settings      *service.WorkspaceSettingsService
+	onboarding    *service.OnboardingService
+	logger        *zap.Logger
+}
+
+// NewWorkspaceHandler 创建工作空间处理器实例
+func NewWorkspaceHandler(workspaceRepo repository.WorkspaceRepository, logger *zap.Logger) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceRepo: workspaceRepo,
+		logger:        logger,
+	}
+}
+
+// Routes 声明工作空间设置路由
+func (h *WorkspaceHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/workspace",
+			Tag:    "workspace",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodGet, Path: "/auto-execute-policy", Handler: h.GetAutoExecutePolicy, Summary: "获取自动执行策略"},
+				{Method: http.MethodPut, Path: "/auto-execute-policy", Handler: h.UpdateAutoExecutePolicy, Summary: "更新自动执行策略", Roles: []string{string(repository.RoleManager)}},
+				{Method: http.MethodGet, Path: "/data-residency", Handler: h.GetDataResidency, Summary: "获取数据驻留策略"},
+				{Method: http.MethodPut, Path: "/data-residency", Handler: h.UpdateDataResidency, Summary: "更新数据驻留策略", Roles: []string{string(repository.RoleAdmin)}},
+				{Method: http.MethodGet, Path: "/settings", Handler: h.GetSettings, Summary: "获取工作空间默认设置"},
+				{Method: http.MethodPut, Path: "/settings", Handler: h.UpdateSettings, Summary: "更新工作空间默认设置", Roles: []string{string(repository.RoleAdmin)}},
+				{Method: http.MethodPut, Path: "/question-retention", Handler: h.UpdateQuestionRetention, Summary: "更新问题保留方式", Roles: []string{string(repository.RoleAdmin)}},
+				{Method: http.MethodPut, Path: "/latency-routing", Handler: h.UpdateLatencyRouting, Summary: "更新延迟路由策略", Roles: []string{string(repository.RoleAdmin)}},
+				{Method: http.MethodPut, Path: "/chargeback-taxonomy", Handler: h.UpdateChargebackTaxonomy, Summary: "更新费用归属标签范围", Roles: []string{string(repository.RoleAdmin)}},
+				{Method: http.MethodGet, Path: "/onboarding", Handler: h.GetOnboarding, Summary: "获取新手引导清单"},
+			},
+		},
+	}
+}
+
+// SetApprovalEngine 设置审批流程，策略变更需要审批时提交审批申请而不是直接生效
+func (h *WorkspaceHandler) SetApprovalEngine(engine *service.ApprovalEngine) {
+	h.approvals = engine
+}
+
+// SetResidencyService 启用工作空间数据驻留策略管理
+func (h *WorkspaceHandler) SetResidencyService(residency *service.ResidencyService) {
+	h.residency = residency
+}
+
+// SetWorkspaceSettings 启用工作空间默认设置管理，自动执行策略直接生效时同时清空设置缓存
+func (h *WorkspaceHandler) SetWorkspaceSettings(settings *service.WorkspaceSettingsService) {
+	h.settings = settings
+}
+
+// SetOnboardingService 启用新手引导清单
+func (h *WorkspaceHandler) SetOnboardingService(onboarding *service.OnboardingService) {
+	h.onboarding = onboarding
+}
+
+// AutoExecutePolicyRequest 自动执行策略更新请求
+type AutoExecutePolicyRequest struct {
+	Enabled          bool    `json:"enabled" example:"true"`
+	MinConfidence    float64 `json:"min_confidence" binding:"min=0,max=1" example:"0.8"`
+	MaxEstimatedCost float64 `json:"max_estimated_cost" binding:"min=0" example:"1000"`
+}
+
+// AutoExecutePolicyResponse 自动执行策略响应
+type AutoExecutePolicyResponse struct {
+	WorkspaceID int64                         `json:"workspace_id" example:"1"`
+	Policy      *repository.AutoExecutePolicy `json:"policy"`
+}
+
+// GetAutoExecutePolicy 获取自动执行策略
+// @Summary 获取工作空间自动执行策略
+// @Description 获取当前用户所属工作空间的SQL自动执行策略，未配置时policy为null
+// @Tags 工作空间
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} AutoExecutePolicyResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/auto-execute-policy [get]
+func (h *WorkspaceHandler) GetAutoExecutePolicy(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	workspace, err := h.workspaceRepo.GetByUserID(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get workspace", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, &AutoExecutePolicyResponse{
+		WorkspaceID: workspace.ID,
+		Policy:      workspace.AutoExecutePolicy,
+	})
+}
+
+// UpdateAutoExecutePolicy 更新自动执行策略
+// @Summary 更新工作空间自动执行策略
+// @Description 置信度不低于min_confidence且预估代价不超过max_estimated_cost的生成SQL将自动执行（需manager或admin角色）。
+// @Description 策略变更需要审批时返回202与审批申请，审批通过后生效
+// @Tags 工作空间
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AutoExecutePolicyRequest true "自动执行策略"
+// @Success 200 {object} AutoExecutePolicyResponse "更新成功"
+// @Success 202 {object} repository.ApprovalRequest "已提交审批"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/auto-execute-policy [put]
+func (h *WorkspaceHandler) UpdateAutoExecutePolicy(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req AutoExecutePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	workspace, err := h.workspaceRepo.GetByUserID(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get workspace", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间失败"))
+		return
+	}
+
+	policy := &repository.AutoExecutePolicy{
+		Enabled:          req.Enabled,
+		MinConfidence:    req.MinConfidence,
+		MaxEstimatedCost: req.MaxEstimatedCost,
+	}
+
+	if h.approvals != nil && h.approvals.Requires(repository.ApprovalPolicyChange) {
+		approval, err := h.approvals.Submit(c.Request.Context(), &service.ApprovalSubmission{
+			ActionType:  repository.ApprovalPolicyChange,
+			ResourceID:  &workspace.ID,
+			Summary:     fmt.Sprintf("工作空间%s自动执行策略变更：enabled=%t, min_confidence=%.2f, max_estimated_cost=%.0f", workspace.Name, policy.Enabled, policy.MinConfidence, policy.MaxEstimatedCost),
+			Payload:     policy,
+			RequestedBy: userID,
+		})
+		if err != nil {
+			h.logger.Error("Failed to submit policy change approval", zap.Error(err), zap.Int64("workspace_id", workspace.ID))
+			c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "提交策略变更审批失败"))
+			return
+		}
+
+		c.JSON(http.StatusAccepted, approval)
+		return
+	}
+
+	if err := h.workspaceRepo.UpdateAutoExecutePolicy(c.Request.Context(), workspace.ID, policy); err != nil {
+		h.logger.Error("Failed to update auto-execute policy", zap.Error(err), zap.Int64("workspace_id", workspace.ID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "更新自动执行策略失败"))
+		return
+	}
+
+	h.logger.Info("Auto-execute policy updated",
+		zap.Int64("workspace_id", workspace.ID),
+		zap.Int64("user_id", userID),
+		zap.Bool("enabled", policy.Enabled),
+		zap.Float64("min_confidence", policy.MinConfidence),
+		zap.Float64("max_estimated_cost", policy.MaxEstimatedCost))
+
+	if h.settings != nil {
+		h.settings.Invalidate()
+	}
+
+	c.JSON(http.StatusOK, &AutoExecutePolicyResponse{
+		WorkspaceID: workspace.ID,
+		Policy:      policy,
+	})
+}
+
+// DataResidencyRequest 数据驻留策略更新请求，region为空表示取消限制
+type DataResidencyRequest struct {
+	Region    string                                 `json:"region" binding:"max=50" example:"eu-central-1"`
+	Locations map[string]*repository.StorageLocation `json:"locations"` // 键为snapshot/export/archive
+}
+
+// DataResidencyResponse 数据驻留策略响应
+type DataResidencyResponse struct {
+	WorkspaceID int64                           `json:"workspace_id" example:"1"`
+	Policy      *repository.DataResidencyPolicy `json:"policy"`
+}
+
+// GetDataResidency 获取数据驻留策略
+// @Summary 获取工作空间数据驻留策略
+// @Description 获取当前用户所属工作空间的数据驻留区域与按用途固定的对象存储位置，未配置时policy为null
+// @Tags 工作空间
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} DataResidencyResponse "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/data-residency [get]
+func (h *WorkspaceHandler) GetDataResidency(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	workspace, err := h.residency.Get(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get workspace", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, &DataResidencyResponse{
+		WorkspaceID: workspace.ID,
+		Policy:      workspace.DataResidency,
+	})
+}
+
+// UpdateDataResidency 更新数据驻留策略
+// @Summary 更新工作空间数据驻留策略
+// @Description 指定数据驻留区域并按用途（snapshot/export/archive）固定对象存储桶，存储桶必须位于驻留区域内（需admin角色）
+// @Tags 工作空间
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body DataResidencyRequest true "数据驻留策略"
+// @Success 200 {object} DataResidencyResponse "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误或存储桶跨区域"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/data-residency [put]
+func (h *WorkspaceHandler) UpdateDataResidency(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req DataResidencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var policy *repository.DataResidencyPolicy
+	if req.Region != "" {
+		policy = &repository.DataResidencyPolicy{Region: req.Region, Locations: req.Locations}
+	}
+
+	workspace, err := h.residency.Update(c.Request.Context(), userID, policy)
+	switch {
+	case errors.Is(err, service.ErrCrossRegionWrite), errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_DATA_RESIDENCY",
+			Message: "数据驻留策略无效",
+			Details: err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to update data residency", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "更新数据驻留策略失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, &DataResidencyResponse{
+		WorkspaceID: workspace.ID,
+		Policy:      workspace.DataResidency,
+	})
+}
+
+// WorkspaceSettingsRequest 工作空间默认设置更新请求，字段为空表示不设该项默认值
+type WorkspaceSettingsRequest struct {
+	DefaultConnectionID *int64 `json:"default_connection_id" binding:"omitempty,min=1" example:"1"`
+	DefaultLocale       string `json:"default_locale" binding:"omitempty,oneof=zh en" example:"zh"`
+	DefaultRowLimit     int    `json:"default_row_limit" binding:"min=0" example:"200"`
+}
+
+// GetSettings 获取工作空间默认设置
+// @Summary 获取工作空间默认设置
+// @Description 获取当前用户所属工作空间的默认连接、默认语言、默认返回行数、自动执行策略与问题保留方式，未配置时defaults为null
+// @Tags 工作空间
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.WorkspaceSettings "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/settings [get]
+func (h *WorkspaceHandler) GetSettings(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	settings, err := h.settings.Get(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get workspace settings", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取工作空间默认设置失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetOnboarding 获取新手引导清单
+// @Summary 获取新手引导清单
+// @Description 返回当前用户所属工作空间的新手引导步骤（添加连接、同步表结构、执行查询、补充注释、提交反馈）及完成情况，
+// @Description 完成情况由工作空间成员已有的连接、元数据、查询历史与反馈推导，任一成员完成即视为该步骤完成
+// @Tags 工作空间
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.OnboardingChecklist "获取成功"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/onboarding [get]
+func (h *WorkspaceHandler) GetOnboarding(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	checklist, err := h.onboarding.Checklist(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to compute onboarding checklist", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "获取新手引导清单失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, checklist)
+}
+
+// UpdateSettings 更新工作空间默认设置
+// @Summary 更新工作空间默认设置
+// @Description 设置请求未携带connection_id、locale或row_limit时使用的默认值（需admin角色），全部字段为空表示清除默认设置。
+// @Description 自动执行策略通过/workspace/auto-execute-policy更新，以便按需走审批流程
+// @Tags 工作空间
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WorkspaceSettingsRequest true "默认设置"
+// @Success 200 {object} service.WorkspaceSettings "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误或默认连接不存在"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/settings [put]
+func (h *WorkspaceHandler) UpdateSettings(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req WorkspaceSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var defaults *repository.WorkspaceDefaults
+	if req.DefaultConnectionID != nil || req.DefaultLocale != "" || req.DefaultRowLimit != 0 {
+		defaults = &repository.WorkspaceDefaults{
+			ConnectionID: req.DefaultConnectionID,
+			Locale:       req.DefaultLocale,
+			RowLimit:     req.DefaultRowLimit,
+		}
+	}
+
+	settings, err := h.settings.Update(c.Request.Context(), userID, defaults)
+	switch {
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_WORKSPACE_SETTINGS",
+			Message: "工作空间默认设置无效",
+			Details: err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to update workspace settings", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "更新工作空间默认设置失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// QuestionRetentionRequest 问题保留方式更新请求
+type QuestionRetentionRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=keep hash drop" example:"hash"`
+}
+
+// UpdateQuestionRetention 更新问题保留方式
+// @Summary 更新工作空间问题保留方式
+// @Description 设置生成SQL后如何保存自然语言问题（需admin角色）：keep保留原文，hash只保存SHA-256，drop不保存。
+// @Description hash与drop模式下查询历史只保留问题分类与SQL，关键字搜索改为匹配SQL；设置只影响之后写入的记录，最多延迟1分钟生效
+// @Tags 工作空间
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body QuestionRetentionRequest true "问题保留方式"
+// @Success 200 {object} service.WorkspaceSettings "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/question-retention [put]
+func (h *WorkspaceHandler) UpdateQuestionRetention(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req QuestionRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	settings, err := h.settings.UpdateQuestionRetention(c.Request.Context(), userID, repository.QuestionRetention(req.Mode))
+	if err != nil {
+		h.logger.Error("Failed to update question retention", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "更新问题保留方式失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// LatencyRoutingRequest 延迟路由策略更新请求
+type LatencyRoutingRequest struct {
+	Enabled      bool `json:"enabled" example:"true"`
+	PrimarySLOMs int  `json:"primary_slo_ms,omitempty" binding:"omitempty,min=100,max=60000" example:"3000"` // 主模型P95延迟目标（毫秒），启用时必填
+}
+
+// UpdateLatencyRouting 更新延迟路由策略
+// @Summary 更新工作空间延迟路由策略
+// @Description 主模型滚动P95延迟超过目标且备用模型更快时，交互请求暂时优先使用备用模型，批量与定时请求仍使用主模型（需admin角色）。
+// @Description enabled为false表示不按延迟路由；设置最多延迟缓存时间生效
+// @Tags 工作空间
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body LatencyRoutingRequest true "延迟路由策略"
+// @Success 200 {object} service.WorkspaceSettings "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/latency-routing [put]
+func (h *WorkspaceHandler) UpdateLatencyRouting(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req LatencyRoutingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var policy *repository.LatencyRoutingPolicy
+	if req.Enabled {
+		policy = &repository.LatencyRoutingPolicy{Enabled: true, PrimarySLOMs: req.PrimarySLOMs}
+	}
+
+	settings, err := h.settings.UpdateLatencyRouting(c.Request.Context(), userID, policy)
+	switch {
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_LATENCY_ROUTING",
+			Message: "延迟路由策略无效",
+			Details: err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to update latency routing", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "更新延迟路由策略失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// ChargebackTaxonomyRequest 费用归属标签范围更新请求
+type ChargebackTaxonomyRequest struct {
+	CostCenters []string `json:"cost_centers" example:"CC-100,CC-200"` // 允许的成本中心
+	Projects    []string `json:"projects" example:"growth,billing"`    // 允许的项目
+	Required    bool     `json:"required" example:"false"`             // 是否要求每个请求都携带两类标签
+}
+
+// UpdateChargebackTaxonomy 更新费用归属标签范围
+// @Summary 更新工作空间费用归属标签范围
+// @Description 请求通过X-Cost-Center/X-Project携带的标签必须在范围内，标签随查询历史保存并按标签累计AI用量（需admin角色）。
+// @Description 成本中心与项目都为空且不要求携带时表示不接受标签；设置最多延迟缓存时间生效
+// @Tags 工作空间
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChargebackTaxonomyRequest true "费用归属标签范围"
+// @Success 200 {object} service.WorkspaceSettings "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/workspace/chargeback-taxonomy [put]
+func (h *WorkspaceHandler) UpdateChargebackTaxonomy(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req ChargebackTaxonomyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var taxonomy *repository.ChargebackTaxonomy
+	if len(req.CostCenters) > 0 || len(req.Projects) > 0 || req.Required {
+		taxonomy = &repository.ChargebackTaxonomy{CostCenters: req.CostCenters, Projects: req.Projects, Required: req.Required}
+	}
+
+	settings, err := h.settings.UpdateChargebackTaxonomy(c.Request.Context(), userID, taxonomy)
+	switch {
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_CHARGEBACK_TAXONOMY",
+			Message: "费用归属标签范围无效",
+			Details: err.Error(),
+		})
+		return
+	case err != nil:
+		h.logger.Error("Failed to update chargeback taxonomy", zap.Error(err), zap.Int64("user_id", userID))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WORKSPACE_ERROR", "更新费用归属标签范围失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,402 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// stubWorkspaceRepository 内存工作空间Repository
+type stubWorkspaceRepository struct {
+	repository.WorkspaceRepository
+	workspace *repository.Workspace
+}
+
+func (s *stubWorkspaceRepository) GetByUserID(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	return s.workspace, nil
+}
+
+func (s *stubWorkspaceRepository) UpdateAutoExecutePolicy(ctx context.Context, workspaceID int64, policy *repository.AutoExecutePolicy) error {
+	s.workspace.AutoExecutePolicy = policy
+	return nil
+}
+
+func (s *stubWorkspaceRepository) UpdateDataResidency(ctx context.Context, workspaceID int64, policy *repository.DataResidencyPolicy, updateBy int64) error {
+	s.workspace.DataResidency = policy
+	return nil
+}
+
+func (s *stubWorkspaceRepository) UpdateDefaults(ctx context.Context, workspaceID int64, defaults *repository.WorkspaceDefaults, updateBy int64) error {
+	s.workspace.Defaults = defaults
+	return nil
+}
+
+func (s *stubWorkspaceRepository) UpdateQuestionRetention(ctx context.Context, workspaceID int64, mode repository.QuestionRetention, updateBy int64) error {
+	s.workspace.QuestionRetention = string(mode)
+	return nil
+}
+
+func (s *stubWorkspaceRepository) UpdateLatencyRouting(ctx context.Context, workspaceID int64, policy *repository.LatencyRoutingPolicy, updateBy int64) error {
+	s.workspace.LatencyRouting = policy
+	return nil
+}
+
+func (s *stubWorkspaceRepository) UpdateChargebackTaxonomy(ctx context.Context, workspaceID int64, taxonomy *repository.ChargebackTaxonomy, updateBy int64) error {
+	s.workspace.Chargeback = taxonomy
+	return nil
+}
+
+func newWorkspaceTestRouter(t *testing.T, role string) (*gin.Engine, *stubWorkspaceRepository) {
+	gin.SetMode(gin.TestMode)
+
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &stubWorkspaceRepository{workspace: workspace}
+
+	h := NewWorkspaceHandler(repo, zaptest.NewLogger(t))
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+		c.Set("user_role", role)
+	})
+	r.GET("/policy", h.GetAutoExecutePolicy)
+	r.PUT("/policy", middleware.RequireRole(string(repository.RoleManager)), h.UpdateAutoExecutePolicy)
+	return r, repo
+}
+
+func TestWorkspaceHandler_UpdateAutoExecutePolicy(t *testing.T) {
+	r, repo := newWorkspaceTestRouter(t, string(repository.RoleManager))
+
+	body := `{"enabled":true,"min_confidence":0.85,"max_estimated_cost":500}`
+	req := httptest.NewRequest(http.MethodPut, "/policy", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, repo.workspace.AutoExecutePolicy)
+	assert.True(t, repo.workspace.AutoExecutePolicy.Enabled)
+	assert.Equal(t, 0.85, repo.workspace.AutoExecutePolicy.MinConfidence)
+	assert.Equal(t, 500.0, repo.workspace.AutoExecutePolicy.MaxEstimatedCost)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/policy", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp AutoExecutePolicyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, repository.DefaultWorkspaceID, resp.WorkspaceID)
+	assert.Equal(t, 0.85, resp.Policy.MinConfidence)
+}
+
+func TestWorkspaceHandler_UpdateAutoExecutePolicy_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		role string
+		body string
+		code int
+	}{
+		{"普通用户无权修改", string(repository.RoleUser), `{"enabled":true,"min_confidence":0.8,"max_estimated_cost":100}`, http.StatusForbidden},
+		{"置信度超出范围", string(repository.RoleAdmin), `{"enabled":true,"min_confidence":1.5,"max_estimated_cost":100}`, http.StatusBadRequest},
+		{"代价为负", string(repository.RoleAdmin), `{"enabled":true,"min_confidence":0.8,"max_estimated_cost":-1}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, repo := newWorkspaceTestRouter(t, tt.role)
+
+			req := httptest.NewRequest(http.MethodPut, "/policy", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.Nil(t, repo.workspace.AutoExecutePolicy)
+		})
+	}
+}
+
+func TestWorkspaceHandler_UpdateAutoExecutePolicy_RequiresApproval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &stubWorkspaceRepository{workspace: workspace}
+
+	engine := service.NewApprovalEngine(newStubApprovalRepository(), config.DefaultApprovalConfig(), zaptest.NewLogger(t))
+	engine.Register(repository.ApprovalPolicyChange, service.NewPolicyChangeExecutor(repo, zaptest.NewLogger(t)))
+	h := NewWorkspaceHandler(repo, zaptest.NewLogger(t))
+	h.SetApprovalEngine(engine)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+	})
+	r.PUT("/policy", h.UpdateAutoExecutePolicy)
+
+	body := `{"enabled":true,"min_confidence":0.9,"max_estimated_cost":200}`
+	req := httptest.NewRequest(http.MethodPut, "/policy", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Nil(t, repo.workspace.AutoExecutePolicy, "审批通过前策略不生效")
+
+	var approval repository.ApprovalRequest
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &approval))
+	assert.Equal(t, string(repository.ApprovalPolicyChange), approval.ActionType)
+
+	_, err := engine.Approve(ctx, 9, string(repository.RoleManager), approval.ID, "")
+	require.NoError(t, err)
+	require.NotNil(t, repo.workspace.AutoExecutePolicy)
+	assert.Equal(t, 0.9, repo.workspace.AutoExecutePolicy.MinConfidence)
+}
+
+func TestWorkspaceHandler_UpdateDataResidency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &stubWorkspaceRepository{workspace: workspace}
+
+	h := NewWorkspaceHandler(repo, zaptest.NewLogger(t))
+	h.SetResidencyService(service.NewResidencyService(repo, &config.ResidencyConfig{AllowedRegions: []string{"eu-central-1"}}, zaptest.NewLogger(t)))
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+	})
+	r.GET("/residency", h.GetDataResidency)
+	r.PUT("/residency", h.UpdateDataResidency)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/residency", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"region":"eu-central-1","locations":{"snapshot":{"bucket":"snapshots-eu","region":"eu-central-1"}}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, repo.workspace.DataResidency)
+	assert.Equal(t, "snapshots-eu", repo.workspace.DataResidency.Locations["snapshot"].Bucket)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/residency", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp DataResidencyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Policy)
+	assert.Equal(t, "eu-central-1", resp.Policy.Region)
+
+	// 存储桶跨区域与区域不在允许列表中均被拒绝，原策略保持不变
+	assert.Equal(t, http.StatusBadRequest, put(`{"region":"eu-central-1","locations":{"export":{"bucket":"exports-us","region":"us-east-1"}}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(`{"region":"us-east-1"}`).Code)
+	assert.Equal(t, "eu-central-1", repo.workspace.DataResidency.Region)
+
+	// region为空取消限制
+	require.Equal(t, http.StatusOK, put(`{}`).Code)
+	assert.Nil(t, repo.workspace.DataResidency)
+}
+
+func TestWorkspaceHandler_UpdateSettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &stubWorkspaceRepository{workspace: workspace}
+
+	connRepo := &MockConnectionRepository{}
+	connRepo.On("GetByID", mock.Anything, int64(3)).Return(testutil.NewConnection(3, 7), nil)
+	connRepo.On("GetByID", mock.Anything, int64(4)).Return(nil, repository.ErrNotFound)
+
+	h := NewWorkspaceHandler(repo, zaptest.NewLogger(t))
+	h.SetWorkspaceSettings(service.NewWorkspaceSettingsService(repo, connRepo, nil, zaptest.NewLogger(t)))
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+	})
+	r.GET("/settings", h.GetSettings)
+	r.PUT("/settings", h.UpdateSettings)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/settings", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, put(`{"default_connection_id":3,"default_locale":"en","default_row_limit":200}`).Code)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/settings", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp service.WorkspaceSettings
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Defaults)
+	assert.Equal(t, int64(3), *resp.Defaults.ConnectionID)
+	assert.Equal(t, "en", resp.Defaults.Locale)
+	assert.Equal(t, 200, resp.Defaults.RowLimit)
+
+	// 连接不存在、语言不受支持与行数超出上限均被拒绝，原设置保持不变
+	assert.Equal(t, http.StatusBadRequest, put(`{"default_connection_id":4}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(`{"default_locale":"fr"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(`{"default_row_limit":100000}`).Code)
+	assert.Equal(t, "en", repo.workspace.Defaults.Locale)
+
+	// 全部为空清除默认设置
+	require.Equal(t, http.StatusOK, put(`{}`).Code)
+	assert.Nil(t, repo.workspace.Defaults)
+}
+
+func TestWorkspaceHandler_UpdateQuestionRetention(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	workspace := &repository.Workspace{Name: "default", QuestionRetention: string(repository.QuestionRetentionKeep)}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &stubWorkspaceRepository{workspace: workspace}
+
+	h := NewWorkspaceHandler(repo, zaptest.NewLogger(t))
+	h.SetWorkspaceSettings(service.NewWorkspaceSettingsService(repo, &MockConnectionRepository{}, nil, zaptest.NewLogger(t)))
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+	})
+	r.PUT("/question-retention", h.UpdateQuestionRetention)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/question-retention", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"mode":"hash"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, string(repository.QuestionRetentionHash), repo.workspace.QuestionRetention)
+
+	var settings service.WorkspaceSettings
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, "hash", settings.QuestionRetention)
+
+	assert.Equal(t, http.StatusBadRequest, put(`{"mode":"encrypt"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(`{}`).Code)
+	assert.Equal(t, string(repository.QuestionRetentionHash), repo.workspace.QuestionRetention)
+}
+
+func TestWorkspaceHandler_UpdateLatencyRouting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &stubWorkspaceRepository{workspace: workspace}
+
+	h := NewWorkspaceHandler(repo, zaptest.NewLogger(t))
+	h.SetWorkspaceSettings(service.NewWorkspaceSettingsService(repo, &MockConnectionRepository{}, nil, zaptest.NewLogger(t)))
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+	})
+	r.PUT("/latency-routing", h.UpdateLatencyRouting)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/latency-routing", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"enabled":true,"primary_slo_ms":3000}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, repo.workspace.LatencyRouting)
+	assert.Equal(t, 3000, repo.workspace.LatencyRouting.PrimarySLOMs)
+
+	var settings service.WorkspaceSettings
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, 3*time.Second, settings.LatencySLO())
+
+	// 启用时必须给出延迟目标，且在允许范围内
+	assert.Equal(t, http.StatusBadRequest, put(`{"enabled":true}`).Code)
+	assert.Equal(t, http.StatusBadRequest, put(`{"enabled":true,"primary_slo_ms":50}`).Code)
+	assert.Equal(t, 3000, repo.workspace.LatencyRouting.PrimarySLOMs)
+
+	require.Equal(t, http.StatusOK, put(`{"enabled":false}`).Code)
+	assert.Nil(t, repo.workspace.LatencyRouting)
+}
+
+func TestWorkspaceHandler_UpdateChargebackTaxonomy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &stubWorkspaceRepository{workspace: workspace}
+
+	settings := service.NewWorkspaceSettingsService(repo, &MockConnectionRepository{}, nil, zaptest.NewLogger(t))
+	h := NewWorkspaceHandler(repo, zaptest.NewLogger(t))
+	h.SetWorkspaceSettings(settings)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", int64(7))
+	})
+	r.PUT("/chargeback-taxonomy", h.UpdateChargebackTaxonomy)
+	r.GET("/tags", func(c *gin.Context) {
+		tags, err := resolveChargeback(c, settings, 7)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_CHARGEBACK_TAGS", err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, tags)
+	})
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/chargeback-taxonomy", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	tags := func(costCenter, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/tags"+query, nil)
+		if costCenter != "" {
+			req.Header.Set(service.CostCenterHeader, costCenter)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := put(`{"cost_centers":["CC-100"],"projects":["growth"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"chargeback_taxonomy":{"cost_centers":["CC-100"]`)
+	assert.Equal(t, http.StatusBadRequest, put(`{"cost_centers":[" "]}`).Code)
+
+	// 请求头与查询参数中的标签按取值范围校验
+	w = tags("cc-100", "?project=growth")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"cost_center":"CC-100","project":"growth"}`, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, tags("CC-200", "").Code)
+
+	require.Equal(t, http.StatusOK, put(`{}`).Code)
+	assert.Nil(t, repo.workspace.Chargeback)
+}
@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/middleware"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+// WriteModeHandler 受控写模式处理器
+// 连接所有者开启写模式后可提交INSERT/UPDATE申请，经审批流程通过后执行（见ApprovalHandler）
+type WriteModeHandler struct {
+	writeService *service.WriteModeService
+	logger       *zap.Logger
+}
+
+// NewWriteModeHandler 创建受控写模式处理器实例
+func NewWriteModeHandler(writeService *service.WriteModeService, logger *zap.Logger) *WriteModeHandler {
+	return &WriteModeHandler{
+		writeService: writeService,
+		logger:       logger,
+	}
+}
+
+// Routes 声明受控写模式路由，开关需要manager或admin角色，审批由审批流程处理
+func (h *WriteModeHandler) Routes() []RouteGroup {
+	return []RouteGroup{
+		{
+			Prefix: "/connections",
+			Tag:    "write-mode",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPut, Path: "/:id/write-mode", Handler: h.SetWriteMode, Summary: "开启或关闭连接写模式", Roles: []string{string(repository.RoleManager)}},
+			},
+		},
+		{
+			Prefix: "/write-requests",
+			Tag:    "write-mode",
+			Auth:   AuthJWT,
+			Routes: []Route{
+				{Method: http.MethodPost, Path: "", Handler: h.CreateWriteRequest, Summary: "提交写操作申请", Permission: repository.PermQueryWrite, BlockedInMaintenance: true},
+				{Method: http.MethodGet, Path: "", Handler: h.ListWriteRequests, Summary: "写操作申请列表", Permission: repository.PermQueryWrite},
+				{Method: http.MethodGet, Path: "/:id", Handler: h.GetWriteRequest, Summary: "申请详情与审计事件", Permission: repository.PermQueryWrite},
+			},
+		},
+	}
+}
+
+// WriteModeRequest 连接写模式开关请求
+type WriteModeRequest struct {
+	Enabled bool `json:"enabled" example:"true"`
+}
+
+// CreateWriteRequest 写操作申请请求，sql为空时按query生成
+type CreateWriteRequest struct {
+	ConnectionID int64  `json:"connection_id" binding:"required,min=1" example:"1"`
+	Query        string `json:"query,omitempty" binding:"max=1000" example:"把订单1001的状态改为已发货"`
+	Schema       string `json:"schema,omitempty"`
+	SQL          string `json:"sql,omitempty" binding:"max=10000" example:"UPDATE orders SET status = 'shipped' WHERE id = 1001"`
+}
+
+// WriteRequestListParams 写操作申请列表参数
+type WriteRequestListParams struct {
+	Status string `form:"status,default=pending" binding:"oneof=pending approved rejected expired executed failed" example:"pending"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100" example:"20"`
+	Cursor string `form:"cursor" binding:"omitempty,max=512"`
+	Offset int    `form:"offset,default=0" binding:"min=0" example:"0"` // 已被cursor取代，仅为兼容旧客户端保留
+}
+
+// WriteRequestListResponse 写操作申请列表响应
+type WriteRequestListResponse struct {
+	Requests []*repository.WriteRequest `json:"requests"`
+	Limit    int                        `json:"limit" example:"20"`
+	Offset   int                        `json:"offset" example:"0"`
+	CursorPage
+}
+
+// WriteRequestDetailResponse 写操作申请详情，包含审批流程的审计事件
+type WriteRequestDetailResponse struct {
+	Request *repository.WriteRequest    `json:"request"`
+	Events  []*repository.ApprovalEvent `json:"events"`
+}
+
+// SetWriteMode 开启或关闭连接写模式
+// @Summary 设置连接写模式
+// @Description 开启后该连接允许提交经预演与审批的INSERT/UPDATE，默认只读（需manager或admin角色且为连接所有者）
+// @Tags 写模式
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "连接ID"
+// @Param request body WriteModeRequest true "写模式开关"
+// @Success 200 {object} WriteModeRequest "设置成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "权限不足"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/connections/{id}/write-mode [put]
+func (h *WriteModeHandler) SetWriteMode(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	connectionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_CONNECTION_ID", "连接ID格式错误"))
+		return
+	}
+
+	var req WriteModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.writeService.SetWriteMode(c.Request.Context(), userID, connectionID, req.Enabled); err != nil {
+		h.respondWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &req)
+}
+
+// CreateWriteRequest 提交写操作申请
+// @Summary 提交写操作申请
+// @Description 在开启写模式的连接上提交INSERT/UPDATE，系统在回滚的事务中预演并返回影响行数与样本，同时创建审批申请（approval_id）
+// @Tags 写模式
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateWriteRequest true "写操作申请"
+// @Success 201 {object} repository.WriteRequest "申请已创建"
+// @Failure 400 {object} ErrorResponse "SQL不符合写模式要求或预演失败"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 403 {object} ErrorResponse "无权访问连接或连接未开启写模式"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/write-requests [post]
+func (h *WriteModeHandler) CreateWriteRequest(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+
+	var req CreateWriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_REQUEST",
+			Message: "请求参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	writeRequest, err := h.writeService.Propose(c.Request.Context(), &service.WriteProposal{
+		UserID:       userID,
+		ConnectionID: req.ConnectionID,
+		NaturalQuery: req.Query,
+		Schema:       req.Schema,
+		SQL:          req.SQL,
+	})
+	if err != nil {
+		h.respondWriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, writeRequest)
+}
+
+// ListWriteRequests 列出写操作申请
+// @Summary 列出写操作申请
+// @Description 按状态分页列出写操作申请，默认列出待审批的申请；拥有write:approve权限的角色与写操作的指定审批人可以看到全部申请，其他用户只能看到自己提交的申请
+// @Tags 写模式
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "申请状态" Enums(pending, approved, rejected, expired, executed, failed) default(pending)
+// @Param limit query int false "每页数量" default(20) minimum(1) maximum(100)
+// @Param cursor query string false "上一页响应中的next_cursor"
+// @Param offset query int false "偏移量（已弃用，请使用cursor）" default(0) minimum(0)
+// @Success 200 {object} WriteRequestListResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/write-requests [get]
+func (h *WriteModeHandler) ListWriteRequests(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	role, _ := middleware.GetUserRoleFromContext(c)
+
+	var params WriteRequestListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_PARAMS",
+			Message: "查询参数格式错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	scope := listScope("write_requests", params.Status)
+	offset, err := resolveOffset(params.Cursor, params.Offset, scope)
+	if err != nil {
+		respondInvalidCursor(c)
+		return
+	}
+
+	requests, err := h.writeService.List(c.Request.Context(), userID, role, repository.WriteRequestStatus(params.Status), params.Limit+1, offset)
+	if err != nil {
+		h.respondWriteError(c, err)
+		return
+	}
+	if requests == nil {
+		requests = []*repository.WriteRequest{}
+	}
+	requests, page := paginate(requests, offset, params.Limit, scope)
+
+	c.JSON(http.StatusOK, &WriteRequestListResponse{
+		Requests:   requests,
+		Limit:      params.Limit,
+		Offset:     offset,
+		CursorPage: page,
+	})
+}
+
+// GetWriteRequest 获取写操作申请详情
+// @Summary 获取写操作申请详情
+// @Description 返回申请内容、预演结果与审批流程的审计事件；非审批人只能查看自己提交的申请
+// @Tags 写模式
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} WriteRequestDetailResponse "获取成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 401 {object} ErrorResponse "未授权访问"
+// @Failure 404 {object} ErrorResponse "申请不存在"
+// @Failure 500 {object} ErrorResponse "服务器内部错误"
+// @Router /api/v1/write-requests/{id} [get]
+func (h *WriteModeHandler) GetWriteRequest(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, NewErrorResponse("UNAUTHORIZED", "未授权访问"))
+		return
+	}
+	role, _ := middleware.GetUserRoleFromContext(c)
+
+	requestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, NewErrorResponse("INVALID_REQUEST_ID", "申请ID格式错误"))
+		return
+	}
+
+	writeRequest, events, err := h.writeService.Get(c.Request.Context(), userID, role, requestID)
+	if err != nil {
+		h.respondWriteError(c, err)
+		return
+	}
+	if events == nil {
+		events = []*repository.ApprovalEvent{}
+	}
+
+	c.JSON(http.StatusOK, &WriteRequestDetailResponse{Request: writeRequest, Events: events})
+}
+
+// respondWriteError 将写模式服务错误映射为HTTP响应
+func (h *WriteModeHandler) respondWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, NewErrorResponse("PERMISSION_DENIED", "无权访问该数据库连接"))
+	case errors.Is(err, service.ErrWriteModeDisabled):
+		c.JSON(http.StatusForbidden, NewErrorResponse("WRITE_MODE_DISABLED", "连接未开启写模式"))
+	case errors.Is(err, repository.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    "INVALID_WRITE_SQL",
+			Message: "写操作不符合要求",
+			Details: err.Error(),
+		})
+	case errors.Is(err, repository.ErrNotFound):
+		c.JSON(http.StatusNotFound, NewErrorResponse("WRITE_REQUEST_NOT_FOUND", "写操作申请不存在或已处理"))
+	default:
+		h.logger.Error("Write mode operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, NewErrorResponse("WRITE_MODE_ERROR", "写操作处理失败"))
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+	"chat2sql-go/internal/testutil"
+)
+
+// stubWriteRequestRepository 内存写操作申请Repository
+type stubWriteRequestRepository struct {
+	repository.WriteRequestRepository
+	requests map[int64]*repository.WriteRequest
+}
+
+func (s *stubWriteRequestRepository) Create(ctx context.Context, req *repository.WriteRequest) error {
+	req.ID = int64(len(s.requests) + 1)
+	s.requests[req.ID] = req
+	return nil
+}
+
+func (s *stubWriteRequestRepository) GetByID(ctx context.Context, id int64) (*repository.WriteRequest, error) {
+	if req, ok := s.requests[id]; ok {
+		return req, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (s *stubWriteRequestRepository) SetApprovalID(ctx context.Context, id, approvalID int64) error {
+	s.requests[id].ApprovalID = &approvalID
+	return nil
+}
+
+// stubWriteExecutor 预演固定影响1行
+type stubWriteExecutor struct{}
+
+func (stubWriteExecutor) PreviewWrite(ctx context.Context, sql string, connection *repository.DatabaseConnection, sampleRows int) (*repository.WritePreview, int64, error) {
+	return &repository.WritePreview{Columns: []string{"id"}}, 1, nil
+}
+
+func (stubWriteExecutor) ExecuteWrite(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64) (int64, error) {
+	return 1, nil
+}
+
+func newWriteModeTestRouter(t *testing.T, userID int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	connection := testutil.NewConnection(3, 7)
+	connection.WriteModeEnabled = true
+	connRepo := &MockConnectionRepository{}
+	connRepo.On("GetByID", mock.Anything, int64(3)).Return(connection, nil)
+
+	writes := &stubWriteRequestRepository{requests: map[int64]*repository.WriteRequest{}}
+	engine := service.NewApprovalEngine(newStubApprovalRepository(), config.DefaultApprovalConfig(), zaptest.NewLogger(t))
+	svc := service.NewWriteModeService(connRepo, writes, engine, stubWriteExecutor{}, nil, zaptest.NewLogger(t))
+	h := NewWriteModeHandler(svc, zaptest.NewLogger(t))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+	})
+	r.POST("/write-requests", h.CreateWriteRequest)
+	return r
+}
+
+func TestWriteModeHandler_CreateWriteRequest(t *testing.T) {
+	r := newWriteModeTestRouter(t, 7)
+
+	body := `{"connection_id":3,"sql":"INSERT INTO tags (name) VALUES ('sandbox')"}`
+	req := httptest.NewRequest(http.MethodPost, "/write-requests", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp repository.WriteRequest
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "INSERT", resp.StatementType)
+	assert.Equal(t, int64(1), resp.EstimatedRows)
+	assert.Equal(t, string(repository.WriteRequestPending), resp.Status)
+	require.NotNil(t, resp.ApprovalID, "提交写操作时应创建审批申请")
+
+	body = `{"connection_id":3,"sql":"DELETE FROM tags WHERE id = 1"}`
+	req = httptest.NewRequest(http.MethodPost, "/write-requests", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactingCore 写入前清除日志消息、字段与堆栈中敏感信息的zap Core
+type redactingCore struct {
+	zapcore.Core
+}
+
+// NewRedactingCore 包装zap Core，所有经过该Core的日志都会脱敏
+func NewRedactingCore(core zapcore.Core) zapcore.Core {
+	if _, ok := core.(*redactingCore); ok {
+		return core
+	}
+	return &redactingCore{Core: core}
+}
+
+// NewRedactingLogger 返回输出前脱敏的Logger
+func NewRedactingLogger(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(NewRedactingCore))
+}
+
+// ReplaceGlobals 用脱敏Logger替换zap全局Logger，并把标准库log重定向到该Logger
+// opts在脱敏之外附加，如按模块过滤级别的Levels.Core；返回脱敏Logger与恢复原全局设置的函数
+func ReplaceGlobals(logger *zap.Logger, opts ...zap.Option) (*zap.Logger, func()) {
+	redacted := NewRedactingLogger(logger).WithOptions(opts...)
+	restoreGlobals := zap.ReplaceGlobals(redacted)
+	restoreStdLog := zap.RedirectStdLog(redacted)
+	log.SetFlags(0)
+
+	return redacted, func() {
+		restoreStdLog()
+		restoreGlobals()
+	}
+}
+
+// With 脱敏后附加上下文字段
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+// Check 确保写入经过本Core而不是被包装的Core
+func (c *redactingCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// Write 脱敏后写入
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = Redact(entry.Message)
+	entry.Stack = Redact(entry.Stack)
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+// redactFields 返回脱敏后的字段副本
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = redactField(field)
+	}
+	return redacted
+}
+
+// redactField 脱敏单个字段
+// 键名表示敏感值（如password、api_key）的字段整体替换为占位符；字符串类字段直接脱敏；错误保留错误链；对象、数组与反射字段先编码为JSON再脱敏
+func redactField(field zapcore.Field) zapcore.Field {
+	if secretFieldKey.MatchString(field.Key) && field.Type != zapcore.NamespaceType {
+		return zap.String(field.Key, Placeholder)
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = Redact(field.String)
+		return field
+	case zapcore.ByteStringType:
+		return zap.ByteString(field.Key, []byte(Redact(string(field.Interface.([]byte)))))
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok {
+			field.Interface = RedactError(err)
+		}
+		return field
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(fmt.Stringer); ok {
+			return zap.String(field.Key, Redact(safeString(stringer)))
+		}
+		return field
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+		return redactEncoded(field)
+	default:
+		return field
+	}
+}
+
+// redactEncoded 把复杂字段编码为JSON后脱敏，编码失败时退化为%+v文本
+func redactEncoded(field zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	field.AddTo(enc)
+
+	data, err := json.Marshal(enc.Fields[field.Key])
+	if err != nil {
+		return zap.String(field.Key, Redact(fmt.Sprintf("%+v", field.Interface)))
+	}
+	return zap.Reflect(field.Key, json.RawMessage(Redact(string(data))))
+}
+
+// safeString 调用String()，panic时返回占位说明，避免日志本身导致崩溃
+func safeString(stringer fmt.Stringer) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("<String() panic: %v>", r)
+		}
+	}()
+	return stringer.String()
+}
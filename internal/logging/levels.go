@@ -0,0 +1,248 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"chat2sql-go/internal/config"
+)
+
+// 可单独调整日志级别的模块，对应logger.Named的名称
+const (
+	DefaultModule = "default" // 未单独设置级别的模块使用的默认级别
+	ModuleSQL     = "sql"     // SQL执行链路
+	ModuleAI      = "ai"      // AI生成链路
+)
+
+// ErrUnknownModule 模块未注册
+var ErrUnknownModule = errors.New("unknown log module")
+
+// Levels 按模块动态调整的日志级别
+// 模块取Named Logger名称的第一段，未单独设置的模块使用默认级别；
+// 级别基于zap.AtomicLevel，运行期间修改立即生效，无需重建Logger
+type Levels struct {
+	base zap.AtomicLevel
+
+	mu        sync.RWMutex
+	known     map[string]bool
+	overrides map[string]zap.AtomicLevel
+
+	sampler *debugSampler
+}
+
+// NewLevels 创建模块日志级别，默认注册sql与ai模块
+func NewLevels(base zapcore.Level) *Levels {
+	return &Levels{
+		base:      zap.NewAtomicLevelAt(base),
+		known:     map[string]bool{ModuleSQL: true, ModuleAI: true},
+		overrides: make(map[string]zap.AtomicLevel),
+		sampler:   &debugSampler{},
+	}
+}
+
+// Register 注册可单独调整级别的模块
+func (l *Levels) Register(modules ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, module := range modules {
+		l.known[module] = true
+	}
+}
+
+// Apply 按配置设置默认级别、模块级别与调试日志采样
+func (l *Levels) Apply(cfg *config.LoggingConfig) error {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	l.base.SetLevel(level)
+
+	for module, name := range cfg.ModuleLevels {
+		level, err := zapcore.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		l.Register(module)
+		if err := l.SetLevel(module, level); err != nil {
+			return err
+		}
+	}
+
+	l.sampler.configure(cfg.SampledModules, cfg.SampleInitial, cfg.SampleThereafter, cfg.SampleTick)
+	return nil
+}
+
+// Level 返回模块当前生效的级别
+func (l *Levels) Level(module string) zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if level, ok := l.overrides[module]; ok {
+		return level.Level()
+	}
+	return l.base.Level()
+}
+
+// SetLevel 设置模块级别，module为DefaultModule时设置默认级别
+func (l *Levels) SetLevel(module string, level zapcore.Level) error {
+	if module == DefaultModule {
+		l.base.SetLevel(level)
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.known[module] {
+		return fmt.Errorf("%w: %s", ErrUnknownModule, module)
+	}
+	if atomic, ok := l.overrides[module]; ok {
+		atomic.SetLevel(level)
+	} else {
+		l.overrides[module] = zap.NewAtomicLevelAt(level)
+	}
+	return nil
+}
+
+// ResetLevel 取消模块的单独级别，恢复使用默认级别
+func (l *Levels) ResetLevel(module string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.known[module] {
+		return fmt.Errorf("%w: %s", ErrUnknownModule, module)
+	}
+	delete(l.overrides, module)
+	return nil
+}
+
+// Snapshot 返回默认级别与全部已注册模块当前生效的级别
+func (l *Levels) Snapshot() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	levels := map[string]string{DefaultModule: l.base.Level().String()}
+	for module := range l.known {
+		if level, ok := l.overrides[module]; ok {
+			levels[module] = level.Level().String()
+		} else {
+			levels[module] = l.base.Level().String()
+		}
+	}
+	return levels
+}
+
+// Modules 返回已注册的模块，按名称排序
+func (l *Levels) Modules() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	modules := make([]string, 0, len(l.known))
+	for module := range l.known {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// enabled 判断模块是否输出该级别的日志
+func (l *Levels) enabled(module string, level zapcore.Level) bool {
+	return l.Level(module).Enabled(level)
+}
+
+// minLevel 返回所有模块中最低的生效级别
+func (l *Levels) minLevel() zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	min := l.base.Level()
+	for _, level := range l.overrides {
+		if level.Level() < min {
+			min = level.Level()
+		}
+	}
+	return min
+}
+
+// Core 包装zap Core，按模块级别过滤日志并对采样模块的调试日志采样
+// 被包装的Core需要允许Debug级别，否则调高模块级别后调试日志仍会被其过滤
+func (l *Levels) Core(core zapcore.Core) zapcore.Core {
+	return &levelCore{Core: core, levels: l}
+}
+
+// levelCore 按模块级别过滤的zap Core
+type levelCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+// Enabled 任一模块启用该级别即返回true，具体模块在Check中判断
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.levels.minLevel().Enabled(level)
+}
+
+// With 附加上下文字段
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+// Check 按模块级别与采样决定是否写入，通过后交给被包装的Core
+func (c *levelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	module, _, _ := strings.Cut(entry.LoggerName, ".")
+	if !c.levels.enabled(module, entry.Level) {
+		return ce
+	}
+	if entry.Level == zapcore.DebugLevel && !c.levels.sampler.allow(module, entry.Message, entry.Time) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+// debugSampler 按模块与消息对调试日志采样
+// 每个采样周期内同一消息先输出前initial条，之后每thereafter条输出1条，thereafter为0时丢弃其余
+type debugSampler struct {
+	mu         sync.Mutex
+	modules    map[string]bool
+	initial    int
+	thereafter int
+	tick       time.Duration
+
+	windowStart time.Time
+	counts      map[string]int
+}
+
+// configure 设置采样模块与采样参数，modules为空时不采样
+func (s *debugSampler) configure(modules []string, initial, thereafter int, tick time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.modules = make(map[string]bool, len(modules))
+	for _, module := range modules {
+		s.modules[module] = true
+	}
+	s.initial = initial
+	s.thereafter = thereafter
+	s.tick = tick
+	s.counts = nil
+}
+
+// allow 判断本条调试日志是否输出
+func (s *debugSampler) allow(module, message string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.modules[module] {
+		return true
+	}
+
+	if s.counts == nil || now.Sub(s.windowStart) >= s.tick {
+		s.windowStart = now
+		s.counts = make(map[string]int)
+	}
+	key := module + "\x00" + message
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"chat2sql-go/internal/config"
+)
+
+func newLeveledLogger(levels *Levels) (*zap.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zap.DebugLevel)
+	return NewRedactingLogger(zap.New(core)).WithOptions(zap.WrapCore(levels.Core)), &buf
+}
+
+func TestLevels_ModuleLevels(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+	logger, buf := newLeveledLogger(levels)
+	sqlLogger := logger.Named(ModuleSQL)
+
+	logger.Debug("default debug")
+	sqlLogger.Debug("sql debug")
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, levels.SetLevel(ModuleSQL, zapcore.DebugLevel))
+	logger.Debug("default debug")
+	sqlLogger.Named("executor").Debug("sql debug")
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), "sql debug")
+
+	assert.Equal(t, map[string]string{DefaultModule: "info", ModuleSQL: "debug", ModuleAI: "info"}, levels.Snapshot())
+
+	require.NoError(t, levels.ResetLevel(ModuleSQL))
+	sqlLogger.Debug("sql debug")
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+
+	assert.ErrorIs(t, levels.SetLevel("billing", zapcore.DebugLevel), ErrUnknownModule)
+}
+
+func TestLevels_DebugSampling(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+	cfg := config.DefaultLoggingConfig()
+	cfg.ModuleLevels = map[string]string{ModuleSQL: "debug", "cache": "debug"}
+	cfg.SampleInitial = 2
+	cfg.SampleThereafter = 5
+	cfg.SampleTick = time.Hour
+	require.NoError(t, levels.Apply(cfg))
+
+	logger, buf := newLeveledLogger(levels)
+	for i := 0; i < 12; i++ {
+		logger.Named(ModuleSQL).Debug("sampled")
+		logger.Named("cache").Debug("unsampled")
+		logger.Named(ModuleSQL).Info("info is never sampled")
+	}
+
+	output := buf.String()
+	assert.Equal(t, 4, strings.Count(output, `"sampled"`), "前2条之后每5条输出1条")
+	assert.Equal(t, 12, strings.Count(output, `"unsampled"`))
+	assert.Equal(t, 12, strings.Count(output, "info is never sampled"))
+}
@@ -0,0 +1,433 @@
+// Package mcp 将Chat2SQL能力以MCP（Model Context Protocol）工具的形式暴露给AI IDE与Agent
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+const (
+	// ProtocolVersion 支持的MCP协议版本
+	ProtocolVersion = "2025-03-26"
+	// maxResultRows 工具调用返回的最大行数，避免撑爆Agent上下文
+	maxResultRows = 200
+)
+
+// 工具权限范围，与API密钥的mcp:*接口范围一致
+const (
+	ScopeConnectionsRead = string(repository.APIKeyScopeMCPConnectionsRead)
+	ScopeSchemaRead      = string(repository.APIKeyScopeMCPSchemaRead)
+	ScopeSQLGenerate     = string(repository.APIKeyScopeMCPSQLGenerate)
+	ScopeSQLExecute      = string(repository.APIKeyScopeMCPSQLExecute)
+)
+
+// AllScopes 全部工具权限范围
+var AllScopes = []string{ScopeConnectionsRead, ScopeSchemaRead, ScopeSQLGenerate, ScopeSQLExecute}
+
+// JSON-RPC 2.0 错误码
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Request JSON-RPC请求
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response JSON-RPC响应
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError JSON-RPC错误
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Principal 调用方身份及其被授予的工具权限范围
+type Principal struct {
+	UserID int64
+	Scopes []string
+}
+
+// HasScope 检查是否拥有指定权限范围
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Tool MCP工具描述
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	scope   string
+	handler func(ctx context.Context, principal *Principal, args json.RawMessage) (any, error)
+}
+
+// ToolResult tools/call 返回结果
+type ToolResult struct {
+	Content []ToolContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// ToolContent 工具返回内容块
+type ToolContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Server MCP服务器
+// 传输层无关，由HTTP处理器负责认证与Principal构建
+type Server struct {
+	connectionRepo repository.ConnectionRepository
+	schemaRepo     repository.SchemaRepository
+	generator      service.SQLGenerator
+	executor       service.QueryExecutor
+	validator      *service.SQLSecurityValidator
+	logger         *zap.Logger
+
+	tools   []*Tool
+	version string
+}
+
+// NewServer 创建MCP服务器
+func NewServer(
+	connectionRepo repository.ConnectionRepository,
+	schemaRepo repository.SchemaRepository,
+	generator service.SQLGenerator,
+	executor service.QueryExecutor,
+	version string,
+	logger *zap.Logger,
+) *Server {
+	s := &Server{
+		connectionRepo: connectionRepo,
+		schemaRepo:     schemaRepo,
+		generator:      generator,
+		executor:       executor,
+		validator:      service.NewSQLSecurityValidator(logger),
+		logger:         logger,
+		version:        version,
+	}
+	s.registerTools()
+	return s
+}
+
+// Handle 处理单个JSON-RPC消息；通知消息返回nil
+func (s *Server) Handle(ctx context.Context, principal *Principal, payload []byte) *Response {
+	var req Request
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return errorResponse(nil, codeParseError, "无法解析JSON-RPC请求")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "无效的JSON-RPC请求")
+	}
+
+	// 没有ID的请求为通知，无需响应
+	if len(req.ID) == 0 {
+		return nil
+	}
+
+	switch req.Method {
+	case "initialize":
+		return resultResponse(req.ID, map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]any{"name": "chat2sql", "version": s.version},
+		})
+	case "ping":
+		return resultResponse(req.ID, map[string]any{})
+	case "tools/list":
+		return resultResponse(req.ID, map[string]any{"tools": s.visibleTools(principal)})
+	case "tools/call":
+		return s.callTool(ctx, principal, req)
+	default:
+		return errorResponse(req.ID, codeMethodNotFound, "不支持的方法: "+req.Method)
+	}
+}
+
+// visibleTools 仅列出调用方权限范围内的工具
+func (s *Server) visibleTools(principal *Principal) []*Tool {
+	tools := make([]*Tool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		if principal.HasScope(tool.scope) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// callTool 执行工具调用；工具执行错误以isError结果返回，而非协议错误
+func (s *Server) callTool(ctx context.Context, principal *Principal, req Request) *Response {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req.ID, codeInvalidParams, "无效的工具调用参数")
+	}
+
+	idx := slices.IndexFunc(s.tools, func(t *Tool) bool { return t.Name == params.Name })
+	if idx < 0 {
+		return errorResponse(req.ID, codeInvalidParams, "未知工具: "+params.Name)
+	}
+	tool := s.tools[idx]
+
+	if !principal.HasScope(tool.scope) {
+		return resultResponse(req.ID, textResult(fmt.Sprintf("令牌缺少权限范围 %s", tool.scope), true))
+	}
+	if len(params.Arguments) == 0 {
+		params.Arguments = json.RawMessage("{}")
+	}
+
+	output, err := tool.handler(ctx, principal, params.Arguments)
+	if err != nil {
+		s.logger.Warn("MCP工具调用失败",
+			zap.String("tool", tool.Name),
+			zap.Int64("user_id", principal.UserID),
+			zap.Error(err))
+		return resultResponse(req.ID, textResult(err.Error(), true))
+	}
+
+	text, ok := output.(string)
+	if !ok {
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return resultResponse(req.ID, textResult("结果序列化失败", true))
+		}
+		text = string(data)
+	}
+	return resultResponse(req.ID, textResult(text, false))
+}
+
+// registerTools 注册Chat2SQL工具集
+func (s *Server) registerTools() {
+	connectionIDProp := map[string]any{"type": "integer", "description": "数据库连接ID（可通过list_connections获取）"}
+
+	s.tools = []*Tool{
+		{
+			Name:        "list_connections",
+			Description: "列出当前用户可用的数据库连接",
+			InputSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+			scope:       ScopeConnectionsRead,
+			handler:     s.listConnections,
+		},
+		{
+			Name:        "get_schema",
+			Description: "获取指定数据库连接的表结构（表、列、类型、主外键）",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"connection_id": connectionIDProp},
+				"required":   []string{"connection_id"},
+			},
+			scope:   ScopeSchemaRead,
+			handler: s.getSchema,
+		},
+		{
+			Name:        "generate_sql",
+			Description: "将自然语言问题转换为SQL（不执行）",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"connection_id": connectionIDProp,
+					"question":      map[string]any{"type": "string", "description": "自然语言问题"},
+				},
+				"required": []string{"connection_id", "question"},
+			},
+			scope:   ScopeSQLGenerate,
+			handler: s.generateSQL,
+		},
+		{
+			Name:        "execute_sql",
+			Description: fmt.Sprintf("执行只读SQL查询并返回结果（最多%d行）", maxResultRows),
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"connection_id": connectionIDProp,
+					"sql":           map[string]any{"type": "string", "description": "只读SELECT语句"},
+				},
+				"required": []string{"connection_id", "sql"},
+			},
+			scope:   ScopeSQLExecute,
+			handler: s.executeSQL,
+		},
+	}
+}
+
+func (s *Server) listConnections(ctx context.Context, principal *Principal, _ json.RawMessage) (any, error) {
+	connections, err := s.connectionRepo.ListByUser(ctx, principal.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("获取连接列表失败")
+	}
+
+	items := make([]map[string]any, 0, len(connections))
+	for _, conn := range connections {
+		items = append(items, map[string]any{
+			"id":       conn.ID,
+			"name":     conn.Name,
+			"db_type":  conn.DBType,
+			"database": conn.DatabaseName,
+			"status":   conn.Status,
+		})
+	}
+	return items, nil
+}
+
+func (s *Server) getSchema(ctx context.Context, principal *Principal, args json.RawMessage) (any, error) {
+	var input struct {
+		ConnectionID int64 `json:"connection_id"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("参数无效: %w", err)
+	}
+	if _, err := s.ownedConnection(ctx, principal, input.ConnectionID); err != nil {
+		return nil, err
+	}
+
+	columns, err := s.schemaRepo.ListByConnection(ctx, input.ConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取表结构失败")
+	}
+	return FormatSchema(columns), nil
+}
+
+func (s *Server) generateSQL(ctx context.Context, principal *Principal, args json.RawMessage) (any, error) {
+	var input struct {
+		ConnectionID int64  `json:"connection_id"`
+		Question     string `json:"question"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("参数无效: %w", err)
+	}
+	if strings.TrimSpace(input.Question) == "" {
+		return nil, fmt.Errorf("question不能为空")
+	}
+	if _, err := s.ownedConnection(ctx, principal, input.ConnectionID); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.generator.GenerateSQL(ctx, &service.SQLGenerationRequest{
+		Query:        input.Question,
+		ConnectionID: input.ConnectionID,
+		UserID:       principal.UserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("生成SQL失败: %w", err)
+	}
+	return map[string]any{"sql": resp.SQL, "confidence": resp.Confidence}, nil
+}
+
+func (s *Server) executeSQL(ctx context.Context, principal *Principal, args json.RawMessage) (any, error) {
+	var input struct {
+		ConnectionID int64  `json:"connection_id"`
+		SQL          string `json:"sql"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("参数无效: %w", err)
+	}
+
+	if result := s.validator.ValidateSQL(input.SQL); !result.IsValid || !result.IsReadOnly {
+		return nil, fmt.Errorf("仅允许执行只读查询: %s", strings.Join(result.Errors, "; "))
+	}
+
+	connection, err := s.ownedConnection(ctx, principal, input.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.executor.ExecuteQuery(service.WithQueryOwner(ctx, principal.UserID), input.SQL, connection)
+	if err != nil {
+		return nil, fmt.Errorf("执行失败: %w", err)
+	}
+
+	rows := result.Rows
+	truncated := len(rows) > maxResultRows
+	if truncated {
+		rows = rows[:maxResultRows]
+	}
+	return map[string]any{
+		"columns":           result.Columns,
+		"rows":              rows,
+		"row_count":         result.RowCount,
+		"execution_time_ms": result.ExecutionTime,
+		"truncated":         truncated,
+	}, nil
+}
+
+// ownedConnection 获取连接并校验归属
+func (s *Server) ownedConnection(ctx context.Context, principal *Principal, connectionID int64) (*repository.DatabaseConnection, error) {
+	connection, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil || connection.UserID != principal.UserID {
+		return nil, fmt.Errorf("数据库连接 #%d 不存在或无权访问", connectionID)
+	}
+	return connection, nil
+}
+
+// FormatSchema 将列元数据格式化为紧凑的表结构文本
+func FormatSchema(columns []*repository.SchemaMetadata) string {
+	var sb strings.Builder
+	current := ""
+	for _, col := range columns {
+		table := col.TableName
+		if col.SchemaName != "" {
+			table = col.SchemaName + "." + col.TableName
+		}
+		if table != current {
+			if current != "" {
+				sb.WriteString("\n")
+			}
+			sb.WriteString("TABLE " + table)
+			if col.TableComment != nil && *col.TableComment != "" {
+				sb.WriteString(" -- " + *col.TableComment)
+			}
+			sb.WriteString("\n")
+			current = table
+		}
+
+		sb.WriteString("  " + col.ColumnName + " " + col.DataType)
+		if col.IsPrimaryKey {
+			sb.WriteString(" PK")
+		}
+		if col.IsForeignKey && col.ForeignTable != nil && col.ForeignColumn != nil {
+			sb.WriteString(" FK->" + *col.ForeignTable + "." + *col.ForeignColumn)
+		}
+		if col.ColumnComment != nil && *col.ColumnComment != "" {
+			sb.WriteString(" -- " + *col.ColumnComment)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func textResult(text string, isError bool) *ToolResult {
+	return &ToolResult{Content: []ToolContent{{Type: "text", Text: text}}, IsError: isError}
+}
+
+func resultResponse(id json.RawMessage, result any) *Response {
+	return &Response{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *Response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: "2.0", ID: id, Error: &RPCError{Code: code, Message: message}}
+}
@@ -0,0 +1,169 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/service"
+)
+
+type stubConnectionRepo struct {
+	repository.ConnectionRepository
+	connections []*repository.DatabaseConnection
+}
+
+func (r *stubConnectionRepo) GetByID(_ context.Context, id int64) (*repository.DatabaseConnection, error) {
+	for _, conn := range r.connections {
+		if conn.ID == id {
+			return conn, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *stubConnectionRepo) ListByUser(_ context.Context, userID int64) ([]*repository.DatabaseConnection, error) {
+	var result []*repository.DatabaseConnection
+	for _, conn := range r.connections {
+		if conn.UserID == userID {
+			result = append(result, conn)
+		}
+	}
+	return result, nil
+}
+
+type stubSchemaRepo struct {
+	repository.SchemaRepository
+	columns []*repository.SchemaMetadata
+}
+
+func (r *stubSchemaRepo) ListByConnection(_ context.Context, _ int64) ([]*repository.SchemaMetadata, error) {
+	return r.columns, nil
+}
+
+type stubGenerator struct{}
+
+func (stubGenerator) GenerateSQL(_ context.Context, req *service.SQLGenerationRequest) (*service.SQLGenerationResponse, error) {
+	return &service.SQLGenerationResponse{SQL: "SELECT count(*) FROM orders", Confidence: 0.9}, nil
+}
+
+type stubExecutor struct {
+	calls int
+}
+
+func (e *stubExecutor) ExecuteQuery(_ context.Context, _ string, _ *repository.DatabaseConnection) (*service.QueryResult, error) {
+	e.calls++
+	return &service.QueryResult{Columns: []string{"count"}, Rows: []map[string]any{{"count": 42}}, RowCount: 1}, nil
+}
+
+func newTestServer(t *testing.T) (*Server, *stubExecutor) {
+	fk, fkCol := "users", "id"
+	connections := &stubConnectionRepo{connections: []*repository.DatabaseConnection{
+		{BaseModel: repository.BaseModel{ID: 1}, UserID: 1, Name: "shop", DBType: "postgresql"},
+		{BaseModel: repository.BaseModel{ID: 2}, UserID: 2, Name: "other"},
+	}}
+	schema := &stubSchemaRepo{columns: []*repository.SchemaMetadata{
+		{SchemaName: "public", TableName: "orders", ColumnName: "id", DataType: "bigint", IsPrimaryKey: true},
+		{SchemaName: "public", TableName: "orders", ColumnName: "user_id", DataType: "bigint", IsForeignKey: true, ForeignTable: &fk, ForeignColumn: &fkCol},
+	}}
+	executor := &stubExecutor{}
+	return NewServer(connections, schema, stubGenerator{}, executor, "test", zaptest.NewLogger(t)), executor
+}
+
+func call(t *testing.T, s *Server, principal *Principal, payload string) *Response {
+	t.Helper()
+	return s.Handle(context.Background(), principal, []byte(payload))
+}
+
+func toolText(t *testing.T, resp *Response) (string, bool) {
+	t.Helper()
+	require.Nil(t, resp.Error)
+	result, ok := resp.Result.(*ToolResult)
+	require.True(t, ok)
+	require.Len(t, result.Content, 1)
+	return result.Content[0].Text, result.IsError
+}
+
+func TestServer_InitializeAndNotifications(t *testing.T) {
+	s, _ := newTestServer(t)
+	principal := &Principal{UserID: 1, Scopes: AllScopes}
+
+	resp := call(t, s, principal, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, ProtocolVersion, resp.Result.(map[string]any)["protocolVersion"])
+
+	assert.Nil(t, call(t, s, principal, `{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+
+	resp = call(t, s, principal, `{"jsonrpc":"2.0","id":2,"method":"resources/list"}`)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, codeMethodNotFound, resp.Error.Code)
+
+	resp = call(t, s, principal, `not json`)
+	assert.Equal(t, codeParseError, resp.Error.Code)
+}
+
+func TestServer_ToolsListRespectsScopes(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	resp := call(t, s, &Principal{UserID: 1, Scopes: AllScopes}, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	assert.Len(t, resp.Result.(map[string]any)["tools"], 4)
+
+	resp = call(t, s, &Principal{UserID: 1, Scopes: []string{ScopeSchemaRead}}, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	tools := resp.Result.(map[string]any)["tools"].([]*Tool)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "get_schema", tools[0].Name)
+}
+
+func TestServer_ToolCalls(t *testing.T) {
+	s, executor := newTestServer(t)
+	principal := &Principal{UserID: 1, Scopes: AllScopes}
+
+	text, isErr := toolText(t, call(t, s, principal, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_connections"}}`))
+	assert.False(t, isErr)
+	assert.Contains(t, text, `"shop"`)
+	assert.NotContains(t, text, `"other"`)
+
+	text, isErr = toolText(t, call(t, s, principal, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_schema","arguments":{"connection_id":1}}}`))
+	assert.False(t, isErr)
+	assert.Contains(t, text, "TABLE public.orders")
+	assert.Contains(t, text, "user_id bigint FK->users.id")
+
+	text, isErr = toolText(t, call(t, s, principal, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"generate_sql","arguments":{"connection_id":1,"question":"订单数"}}}`))
+	assert.False(t, isErr)
+	assert.Contains(t, text, "SELECT count(*) FROM orders")
+
+	text, isErr = toolText(t, call(t, s, principal, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"execute_sql","arguments":{"connection_id":1,"sql":"SELECT count(*) FROM orders"}}}`))
+	assert.False(t, isErr)
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal([]byte(text), &payload))
+	assert.Equal(t, false, payload["truncated"])
+	assert.Equal(t, 1, executor.calls)
+}
+
+func TestServer_ToolCallGuards(t *testing.T) {
+	s, executor := newTestServer(t)
+
+	// 写操作被拒绝
+	_, isErr := toolText(t, call(t, s, &Principal{UserID: 1, Scopes: AllScopes},
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"execute_sql","arguments":{"connection_id":1,"sql":"DELETE FROM orders"}}}`))
+	assert.True(t, isErr)
+
+	// 他人的连接
+	text, isErr := toolText(t, call(t, s, &Principal{UserID: 1, Scopes: AllScopes},
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"execute_sql","arguments":{"connection_id":2,"sql":"SELECT 1"}}}`))
+	assert.True(t, isErr)
+	assert.Contains(t, text, "无权访问")
+
+	// 令牌范围不足
+	text, isErr = toolText(t, call(t, s, &Principal{UserID: 1, Scopes: []string{ScopeSQLGenerate}},
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"execute_sql","arguments":{"connection_id":1,"sql":"SELECT 1"}}}`))
+	assert.True(t, isErr)
+	assert.Contains(t, text, ScopeSQLExecute)
+
+	assert.Equal(t, 0, executor.calls)
+}
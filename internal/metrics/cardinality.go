@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"chat2sql-go/internal/config"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// otherLabelValue 不在白名单内的标签取值
+	otherLabelValue = "other"
+	// overflowLabelValue 超过序列上限后新标签组合统一使用的取值
+	overflowLabelValue = "overflow"
+)
+
+// CardinalityLimiter 指标标签基数限制器
+// 依次对标签取值做哈希分桶、白名单归并，再按指标统计已出现的标签组合，超过上限的新组合归入overflow序列
+type CardinalityLimiter struct {
+	maxSeries int
+	buckets   int
+	hashed    map[string]bool
+	allowlist map[string]map[string]bool
+
+	mu       sync.Mutex
+	series   map[string]map[string]struct{} // 指标名 -> 已出现的标签组合
+	exceeded map[string]bool                // 已记录过超限日志的指标
+
+	logger *zap.Logger
+}
+
+// NewCardinalityLimiter 创建指标标签基数限制器，配置为nil时返回nil，表示不做限制
+func NewCardinalityLimiter(cfg *config.MetricsCardinalityConfig, logger *zap.Logger) *CardinalityLimiter {
+	if cfg == nil {
+		return nil
+	}
+
+	l := &CardinalityLimiter{
+		maxSeries: cfg.MaxSeriesPerMetric,
+		buckets:   cfg.HashBuckets,
+		hashed:    make(map[string]bool, len(cfg.HashedLabels)),
+		allowlist: make(map[string]map[string]bool, len(cfg.LabelAllowlist)),
+		series:    make(map[string]map[string]struct{}),
+		exceeded:  make(map[string]bool),
+		logger:    logger,
+	}
+	for _, label := range cfg.HashedLabels {
+		l.hashed[label] = true
+	}
+	for label, values := range cfg.LabelAllowlist {
+		allowed := make(map[string]bool, len(values))
+		for _, value := range values {
+			allowed[value] = true
+		}
+		l.allowlist[label] = allowed
+	}
+	return l
+}
+
+// Values 返回限制后的标签取值，names与values按位置一一对应；限制器为nil时原样返回
+func (l *CardinalityLimiter) Values(metric string, names []string, values ...string) []string {
+	if l == nil {
+		return values
+	}
+
+	limited := make([]string, len(values))
+	for i, value := range values {
+		limited[i] = l.normalize(names[i], value)
+	}
+
+	key := strings.Join(limited, "\xff")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seen, ok := l.series[metric]
+	if !ok {
+		seen = make(map[string]struct{})
+		l.series[metric] = seen
+	}
+	if _, ok := seen[key]; ok {
+		return limited
+	}
+	if len(seen) < l.maxSeries {
+		seen[key] = struct{}{}
+		return limited
+	}
+
+	if !l.exceeded[metric] {
+		l.exceeded[metric] = true
+		l.logger.Warn("Metric series limit reached, new label combinations are recorded as overflow",
+			zap.String("metric", metric),
+			zap.Int("max_series", l.maxSeries))
+	}
+	for i := range limited {
+		limited[i] = overflowLabelValue
+	}
+	return limited
+}
+
+// normalize 对单个标签取值做哈希分桶或白名单归并
+func (l *CardinalityLimiter) normalize(name, value string) string {
+	if l.hashed[name] {
+		h := fnv.New32a()
+		h.Write([]byte(value))
+		return fmt.Sprintf("bucket_%d", h.Sum32()%uint32(l.buckets))
+	}
+	if allowed, ok := l.allowlist[name]; ok && !allowed[value] {
+		return otherLabelValue
+	}
+	return value
+}
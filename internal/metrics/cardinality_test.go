@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"chat2sql-go/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCardinalityLimiter_Values(t *testing.T) {
+	limiter := NewCardinalityLimiter(&config.MetricsCardinalityConfig{
+		MaxSeriesPerMetric: 3,
+		HashedLabels:       []string{"user_id"},
+		HashBuckets:        4,
+		LabelAllowlist:     map[string][]string{"db_type": {"postgresql"}},
+	}, zaptest.NewLogger(t))
+
+	values := limiter.Values("database_connections_total", dbConnectionLabels, "42", "postgresql", "active")
+	assert.Regexp(t, `^bucket_[0-3]$`, values[0])
+	assert.Equal(t, []string{"postgresql", "active"}, values[1:])
+	assert.Equal(t, values, limiter.Values("database_connections_total", dbConnectionLabels, "42", "postgresql", "active"), "同一取值应落在同一分桶")
+
+	values = limiter.Values("database_connections_total", dbConnectionLabels, "42", "oracle", "active")
+	assert.Equal(t, "other", values[1])
+
+	limiter.Values("database_connections_total", dbConnectionLabels, "42", "postgresql", "idle")
+	values = limiter.Values("database_connections_total", dbConnectionLabels, "42", "postgresql", "error")
+	assert.Equal(t, []string{"overflow", "overflow", "overflow"}, values)
+
+	// 已出现的组合与其他指标不受影响
+	assert.Equal(t, "active", limiter.Values("database_connections_total", dbConnectionLabels, "42", "postgresql", "active")[2])
+	assert.Equal(t, []string{"success"}, limiter.Values("auth_user_registrations_total", registrationLabels, "success"))
+}
+
+func TestCardinalityLimiter_Nil(t *testing.T) {
+	var limiter *CardinalityLimiter
+	assert.Nil(t, NewCardinalityLimiter(nil, zaptest.NewLogger(t)))
+	assert.Equal(t, []string{"1", "2"}, limiter.Values("sql_execution_duration_seconds", sqlDurationLabels, "1", "2"))
+}
+
+func TestPrometheusMetrics_CardinalityLimit(t *testing.T) {
+	cfg := DefaultMetricsConfig()
+	cfg.Cardinality = &config.MetricsCardinalityConfig{MaxSeriesPerMetric: 2}
+	pm := NewPrometheusMetrics(cfg, zaptest.NewLogger(t))
+
+	for userID := int64(1); userID <= 10; userID++ {
+		pm.RecordUserRegistration("success")
+		pm.UpdateDatabaseConnections(userID, "postgresql", "active", 1)
+	}
+
+	assert.Equal(t, 3, testutil.CollectAndCount(pm.databaseConnectionsTotal), "超限的组合应合并为一个overflow序列")
+	assert.Equal(t, float64(10), testutil.ToFloat64(pm.userRegistrationsTotal.WithLabelValues("success")))
+}
+
+func TestPrometheusMetrics_RecordQueryExecution(t *testing.T) {
+	cfg := DefaultMetricsConfig()
+	cfg.Cardinality = &config.MetricsCardinalityConfig{MaxSeriesPerMetric: 3}
+	pm := NewPrometheusMetrics(cfg, zaptest.NewLogger(t))
+
+	pm.RecordQueryExecution(1, "aggregation", "success", 120*time.Millisecond, 42)
+	pm.RecordQueryExecution(1, "aggregation", "timeout", 30*time.Second, 0)
+	pm.RecordQueryExecution(1, "join_query", "connection_refused", 5*time.Millisecond, 0)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(pm.sqlQueriesTotal.WithLabelValues("1", "aggregation", "timeout")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(pm.sqlQueriesTotal.WithLabelValues("1", "join_query", "error")), "未知状态记为error")
+	assert.Equal(t, 1, testutil.CollectAndCount(pm.sqlQueryRows), "只统计成功执行的返回行数")
+	assert.Equal(t, 2, testutil.CollectAndCount(pm.sqlQueryDuration))
+
+	// 连接数超过序列上限后合并为overflow序列
+	for connectionID := int64(2); connectionID <= 10; connectionID++ {
+		pm.RecordQueryExecution(connectionID, "basic_select", "success", time.Millisecond, 1)
+	}
+	assert.Equal(t, 4, testutil.CollectAndCount(pm.sqlQueryDuration))
+	assert.Equal(t, float64(9), testutil.ToFloat64(pm.sqlQueriesTotal.WithLabelValues("overflow", "overflow", "overflow")))
+}
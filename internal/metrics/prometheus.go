@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"time"
 
+	"chat2sql-go/internal/config"
+
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -26,6 +28,11 @@ type PrometheusMetrics struct {
 	sqlExecutionDuration   *prometheus.HistogramVec
 	databaseConnectionsTotal *prometheus.GaugeVec
 	userRegistrationsTotal   *prometheus.CounterVec
+
+	// 按连接与查询类别统计的SQL执行指标，错误率为非success状态的执行占比
+	sqlQueriesTotal  *prometheus.CounterVec
+	sqlQueryDuration *prometheus.HistogramVec
+	sqlQueryRows     *prometheus.HistogramVec
 	
 	// 系统指标
 	activeConnections     prometheus.Gauge
@@ -34,6 +41,12 @@ type PrometheusMetrics struct {
 	
 	// 注册器
 	registry *prometheus.Registry
+
+	// 标签基数限制，未配置时为nil
+	cardinality *CardinalityLimiter
+
+	// 最近一分钟的请求速率，供界面状态栏直接读取
+	requestRate *RateCounter
 	
 	logger *zap.Logger
 }
@@ -44,8 +57,25 @@ type MetricsConfig struct {
 	Subsystem   string // 指标子系统
 	ServiceName string // 服务名称
 	ServiceVersion string // 服务版本
+
+	Cardinality *config.MetricsCardinalityConfig // 标签基数控制，nil表示不限制
 }
 
+// 各指标的标签名，与标签基数限制器按位置对应
+var (
+	httpRequestLabels    = []string{"method", "endpoint", "status_code"}
+	httpEndpointLabels   = []string{"method", "endpoint"}
+	sqlExecutionLabels   = []string{"user_id", "connection_id", "status"}
+	sqlDurationLabels    = []string{"user_id", "connection_id"}
+	dbConnectionLabels   = []string{"user_id", "db_type", "status"}
+	registrationLabels   = []string{"status"}
+	sqlQueryLabels       = []string{"connection_id", "category"}
+	sqlQueryStatusLabels = []string{"connection_id", "category", "status"}
+)
+
+// sqlQueryStatuses SQL执行指标的status取值，其他状态记为error
+var sqlQueryStatuses = map[string]bool{"success": true, "error": true, "timeout": true, "cancelled": true}
+
 // DefaultMetricsConfig 默认指标配置
 func DefaultMetricsConfig() *MetricsConfig {
 	return &MetricsConfig{
@@ -59,8 +89,10 @@ func DefaultMetricsConfig() *MetricsConfig {
 // NewPrometheusMetrics 创建Prometheus指标收集器
 func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *PrometheusMetrics {
 	pm := &PrometheusMetrics{
-		logger:   logger,
-		registry: prometheus.NewRegistry(),
+		logger:      logger,
+		registry:    prometheus.NewRegistry(),
+		cardinality: NewCardinalityLimiter(config.Cardinality, logger),
+		requestRate: NewRateCounter(requestRateWindow),
 	}
 	
 	// 初始化HTTP请求指标
@@ -71,7 +103,7 @@ func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *Prometheus
 			Name:      "http_requests_total",
 			Help:      "Total number of HTTP requests",
 		},
-		[]string{"method", "endpoint", "status_code"},
+		httpRequestLabels,
 	)
 	
 	pm.httpRequestDuration = prometheus.NewHistogramVec(
@@ -82,7 +114,7 @@ func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *Prometheus
 			Help:      "HTTP request duration in seconds",
 			Buckets:   prometheus.DefBuckets, // 默认桶：0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10
 		},
-		[]string{"method", "endpoint"},
+		httpEndpointLabels,
 	)
 	
 	pm.httpRequestSize = prometheus.NewHistogramVec(
@@ -93,7 +125,7 @@ func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *Prometheus
 			Help:      "HTTP request size in bytes",
 			Buckets:   []float64{1024, 4096, 16384, 65536, 262144, 1048576}, // 1KB to 1MB
 		},
-		[]string{"method", "endpoint"},
+		httpEndpointLabels,
 	)
 	
 	pm.httpResponseSize = prometheus.NewHistogramVec(
@@ -104,7 +136,7 @@ func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *Prometheus
 			Help:      "HTTP response size in bytes",
 			Buckets:   []float64{1024, 4096, 16384, 65536, 262144, 1048576}, // 1KB to 1MB
 		},
-		[]string{"method", "endpoint"},
+		httpEndpointLabels,
 	)
 	
 	// 初始化业务指标
@@ -115,7 +147,7 @@ func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *Prometheus
 			Name:      "executions_total",
 			Help:      "Total number of SQL executions",
 		},
-		[]string{"user_id", "connection_id", "status"},
+		sqlExecutionLabels,
 	)
 	
 	pm.sqlExecutionDuration = prometheus.NewHistogramVec(
@@ -126,9 +158,41 @@ func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *Prometheus
 			Help:      "SQL execution duration in seconds",
 			Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30}, // 1ms to 30s
 		},
-		[]string{"user_id", "connection_id"},
+		sqlDurationLabels,
 	)
 	
+	pm.sqlQueriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: "sql",
+			Name:      "queries_total",
+			Help:      "Total number of SQL query executions by connection, query category and status",
+		},
+		sqlQueryStatusLabels,
+	)
+
+	pm.sqlQueryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: "sql",
+			Name:      "query_duration_seconds",
+			Help:      "SQL query execution duration in seconds by connection and query category",
+			Buckets:   []float64{0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}, // 5ms to 60s
+		},
+		sqlQueryLabels,
+	)
+
+	pm.sqlQueryRows = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: "sql",
+			Name:      "query_rows",
+			Help:      "Rows returned by successful SQL queries by connection and query category",
+			Buckets:   []float64{0, 1, 10, 100, 1000, 10000, 100000},
+		},
+		sqlQueryLabels,
+	)
+
 	pm.databaseConnectionsTotal = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: config.Namespace,
@@ -136,7 +200,7 @@ func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *Prometheus
 			Name:      "connections_total",
 			Help:      "Total number of database connections",
 		},
-		[]string{"user_id", "db_type", "status"},
+		dbConnectionLabels,
 	)
 	
 	pm.userRegistrationsTotal = prometheus.NewCounterVec(
@@ -146,7 +210,7 @@ func NewPrometheusMetrics(config *MetricsConfig, logger *zap.Logger) *Prometheus
 			Name:      "user_registrations_total",
 			Help:      "Total number of user registrations",
 		},
-		[]string{"status"},
+		registrationLabels,
 	)
 	
 	// 初始化系统指标
@@ -198,6 +262,9 @@ func (pm *PrometheusMetrics) registerMetrics() {
 	// 业务指标
 	pm.registry.MustRegister(pm.sqlExecutionsTotal)
 	pm.registry.MustRegister(pm.sqlExecutionDuration)
+	pm.registry.MustRegister(pm.sqlQueriesTotal)
+	pm.registry.MustRegister(pm.sqlQueryDuration)
+	pm.registry.MustRegister(pm.sqlQueryRows)
 	pm.registry.MustRegister(pm.databaseConnectionsTotal)
 	pm.registry.MustRegister(pm.userRegistrationsTotal)
 	
@@ -221,6 +288,7 @@ func (pm *PrometheusMetrics) HTTPMetricsMiddleware() gin.HandlerFunc {
 		c.Next()
 		
 		// 计算指标
+		pm.requestRate.Inc()
 		duration := time.Since(start)
 		responseSize := c.Writer.Size()
 		
@@ -233,45 +301,63 @@ func (pm *PrometheusMetrics) HTTPMetricsMiddleware() gin.HandlerFunc {
 		statusCode := strconv.Itoa(c.Writer.Status())
 		
 		// 记录指标
-		pm.httpRequestsTotal.WithLabelValues(method, endpoint, statusCode).Inc()
-		pm.httpRequestDuration.WithLabelValues(method, endpoint).Observe(duration.Seconds())
+		pm.httpRequestsTotal.WithLabelValues(pm.cardinality.Values("http_requests_total", httpRequestLabels, method, endpoint, statusCode)...).Inc()
+		pm.httpRequestDuration.WithLabelValues(pm.cardinality.Values("http_request_duration_seconds", httpEndpointLabels, method, endpoint)...).Observe(duration.Seconds())
 		
 		if requestSize > 0 {
-			pm.httpRequestSize.WithLabelValues(method, endpoint).Observe(float64(requestSize))
+			pm.httpRequestSize.WithLabelValues(pm.cardinality.Values("http_request_size_bytes", httpEndpointLabels, method, endpoint)...).Observe(float64(requestSize))
 		}
 		
 		if responseSize > 0 {
-			pm.httpResponseSize.WithLabelValues(method, endpoint).Observe(float64(responseSize))
+			pm.httpResponseSize.WithLabelValues(pm.cardinality.Values("http_response_size_bytes", httpEndpointLabels, method, endpoint)...).Observe(float64(responseSize))
 		}
 	}
 }
 
 // RecordSQLExecution 记录SQL执行指标
 func (pm *PrometheusMetrics) RecordSQLExecution(userID, connectionID int64, status string, duration time.Duration) {
-	pm.sqlExecutionsTotal.WithLabelValues(
+	pm.sqlExecutionsTotal.WithLabelValues(pm.cardinality.Values("sql_executions_total", sqlExecutionLabels,
 		strconv.FormatInt(userID, 10),
 		strconv.FormatInt(connectionID, 10),
 		status,
-	).Inc()
+	)...).Inc()
 	
-	pm.sqlExecutionDuration.WithLabelValues(
+	pm.sqlExecutionDuration.WithLabelValues(pm.cardinality.Values("sql_execution_duration_seconds", sqlDurationLabels,
 		strconv.FormatInt(userID, 10),
 		strconv.FormatInt(connectionID, 10),
-	).Observe(duration.Seconds())
+	)...).Observe(duration.Seconds())
+}
+
+// RecordQueryExecution 按连接与查询类别记录SQL执行的耗时、返回行数与状态
+// connection_id经标签基数限制器约束，category应为固定的查询类别，status不在success/error/timeout/cancelled内时记为error；
+// 返回行数只统计成功的执行
+func (pm *PrometheusMetrics) RecordQueryExecution(connectionID int64, category, status string, duration time.Duration, rows int) {
+	if !sqlQueryStatuses[status] {
+		status = "error"
+	}
+	connection := strconv.FormatInt(connectionID, 10)
+
+	pm.sqlQueriesTotal.WithLabelValues(pm.cardinality.Values("sql_queries_total", sqlQueryStatusLabels,
+		connection, category, status)...).Inc()
+	labels := pm.cardinality.Values("sql_query_duration_seconds", sqlQueryLabels, connection, category)
+	pm.sqlQueryDuration.WithLabelValues(labels...).Observe(duration.Seconds())
+	if status == "success" {
+		pm.sqlQueryRows.WithLabelValues(pm.cardinality.Values("sql_query_rows", sqlQueryLabels, connection, category)...).Observe(float64(rows))
+	}
 }
 
 // RecordUserRegistration 记录用户注册指标
 func (pm *PrometheusMetrics) RecordUserRegistration(status string) {
-	pm.userRegistrationsTotal.WithLabelValues(status).Inc()
+	pm.userRegistrationsTotal.WithLabelValues(pm.cardinality.Values("auth_user_registrations_total", registrationLabels, status)...).Inc()
 }
 
 // UpdateDatabaseConnections 更新数据库连接指标
 func (pm *PrometheusMetrics) UpdateDatabaseConnections(userID int64, dbType, status string, count int) {
-	pm.databaseConnectionsTotal.WithLabelValues(
+	pm.databaseConnectionsTotal.WithLabelValues(pm.cardinality.Values("database_connections_total", dbConnectionLabels,
 		strconv.FormatInt(userID, 10),
 		dbType,
 		status,
-	).Set(float64(count))
+	)...).Set(float64(count))
 }
 
 // UpdateSystemMetrics 更新系统指标
@@ -280,6 +366,16 @@ func (pm *PrometheusMetrics) UpdateSystemMetrics(memoryBytes int64, goroutines i
 	pm.goroutineCount.Set(float64(goroutines))
 }
 
+// RequestRate 返回最近一分钟平均每秒HTTP请求数，仅统计本实例
+func (pm *PrometheusMetrics) RequestRate() float64 {
+	return pm.requestRate.Rate()
+}
+
+// Registerer 返回指标端点使用的注册器，供其他组件注册自身的指标
+func (pm *PrometheusMetrics) Registerer() prometheus.Registerer {
+	return pm.registry
+}
+
 // GetMetricsHandler 获取Prometheus指标端点处理器
 func (pm *PrometheusMetrics) GetMetricsHandler() gin.HandlerFunc {
 	h := promhttp.HandlerFor(pm.registry, promhttp.HandlerOpts{})
@@ -312,13 +408,13 @@ func calculateRequestSize(r *http.Request) int64 {
 
 // RecordAPILatency 记录API延迟指标
 func (pm *PrometheusMetrics) RecordAPILatency(method, endpoint string, duration time.Duration) {
-	pm.httpRequestDuration.WithLabelValues(method, endpoint).Observe(duration.Seconds())
+	pm.httpRequestDuration.WithLabelValues(pm.cardinality.Values("http_request_duration_seconds", httpEndpointLabels, method, endpoint)...).Observe(duration.Seconds())
 }
 
 // RecordDatabaseOperation 记录数据库操作指标
 func (pm *PrometheusMetrics) RecordDatabaseOperation(operation, status string, duration time.Duration) {
-	pm.sqlExecutionDuration.WithLabelValues("system", "0").Observe(duration.Seconds())
-	pm.sqlExecutionsTotal.WithLabelValues("system", "0", status).Inc()
+	pm.sqlExecutionDuration.WithLabelValues(pm.cardinality.Values("sql_execution_duration_seconds", sqlDurationLabels, "system", "0")...).Observe(duration.Seconds())
+	pm.sqlExecutionsTotal.WithLabelValues(pm.cardinality.Values("sql_executions_total", sqlExecutionLabels, "system", "0", status)...).Inc()
 }
 
 // RecordCacheOperation 记录缓存操作指标
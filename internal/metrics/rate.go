@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// requestRateWindow 请求速率的统计窗口
+const requestRateWindow = 60 * time.Second
+
+// RateCounter 按秒分桶统计最近一段时间内的事件数，用于在进程内计算QPS
+// 与Prometheus计数器不同，速率无需抓取后计算，可直接返回给界面展示
+type RateCounter struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets []int64 // 环形缓冲区，下标为Unix秒对桶数取模
+	seconds []int64 // 各桶对应的Unix秒，用于识别过期的桶
+}
+
+// NewRateCounter 创建速率计数器，window按秒取整且至少为1秒
+func NewRateCounter(window time.Duration) *RateCounter {
+	n := int(window / time.Second)
+	if n < 1 {
+		n = 1
+	}
+	return &RateCounter{
+		window:  time.Duration(n) * time.Second,
+		now:     time.Now,
+		buckets: make([]int64, n),
+		seconds: make([]int64, n),
+	}
+}
+
+// Inc 记录一次事件
+func (r *RateCounter) Inc() {
+	second := r.now().Unix()
+	i := int(second % int64(len(r.buckets)))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seconds[i] != second {
+		r.seconds[i] = second
+		r.buckets[i] = 0
+	}
+	r.buckets[i]++
+}
+
+// Rate 返回窗口内平均每秒事件数
+func (r *RateCounter) Rate() float64 {
+	cutoff := r.now().Unix() - int64(len(r.buckets))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var total int64
+	for i, second := range r.seconds {
+		if second > cutoff {
+			total += r.buckets[i]
+		}
+	}
+	return float64(total) / r.window.Seconds()
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateCounter_Rate(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	r := NewRateCounter(10 * time.Second)
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 20; i++ {
+		r.Inc()
+	}
+	now = now.Add(3 * time.Second)
+	for i := 0; i < 10; i++ {
+		r.Inc()
+	}
+	assert.InDelta(t, 3.0, r.Rate(), 1e-9, "窗口内30次请求")
+
+	// 早于窗口的桶不再计入，复用的桶先清零
+	now = now.Add(8 * time.Second)
+	assert.InDelta(t, 1.0, r.Rate(), 1e-9)
+	r.Inc()
+	assert.InDelta(t, 1.1, r.Rate(), 1e-9)
+	now = now.Add(time.Minute)
+	assert.Zero(t, r.Rate())
+}
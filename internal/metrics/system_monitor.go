@@ -8,6 +8,8 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+
+	"chat2sql-go/internal/crash"
 )
 
 // SystemMonitor 系统性能监控器
@@ -181,7 +183,7 @@ func (sm *SystemMonitor) Start(ctx context.Context) error {
 		zap.Duration("interval", sm.collectInterval))
 	
 	// 启动监控goroutine
-	go sm.monitorLoop(ctx)
+	go sm.monitorLoop(ctx, func() {})
 	
 	return nil
 }
@@ -202,13 +204,24 @@ func (sm *SystemMonitor) Stop() error {
 	return nil
 }
 
+// Run 在当前goroutine运行监控循环直到ctx取消，每轮收集后调用beat上报心跳，供看门狗托管
+func (sm *SystemMonitor) Run(ctx context.Context, beat func()) {
+	sm.monitorLoop(ctx, beat)
+}
+
+// CollectInterval 返回数据收集间隔，即心跳的期望间隔
+func (sm *SystemMonitor) CollectInterval() time.Duration {
+	return sm.collectInterval
+}
+
 // monitorLoop 监控循环
-func (sm *SystemMonitor) monitorLoop(ctx context.Context) {
+func (sm *SystemMonitor) monitorLoop(ctx context.Context, beat func()) {
 	ticker := time.NewTicker(sm.collectInterval)
 	defer ticker.Stop()
 	
 	// 立即执行一次收集
-	sm.collectMetrics()
+	crash.Run("system_monitor.collect", sm.collectMetrics)
+	beat()
 	
 	for {
 		select {
@@ -219,7 +232,8 @@ func (sm *SystemMonitor) monitorLoop(ctx context.Context) {
 			sm.logger.Info("系统监控收到停止信号")
 			return
 		case <-ticker.C:
-			sm.collectMetrics()
+			crash.Run("system_monitor.collect", sm.collectMetrics)
+			beat()
 		}
 	}
 }
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// APIKeyHeader 携带API密钥的请求头
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator API密钥验证接口，APIKeyService实现了该接口
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*repository.APIKey, *repository.User, error)
+}
+
+// APIKeyAuthMiddleware API密钥认证中间件
+// 请求携带X-API-Key时以密钥认证代替JWT，并检查密钥的接口范围；未携带时交给JWT认证
+type APIKeyAuthMiddleware struct {
+	authenticator APIKeyAuthenticator
+	logger        *zap.Logger
+}
+
+// NewAPIKeyAuthMiddleware 创建API密钥认证中间件实例
+func NewAPIKeyAuthMiddleware(authenticator APIKeyAuthenticator, logger *zap.Logger) *APIKeyAuthMiddleware {
+	return &APIKeyAuthMiddleware{
+		authenticator: authenticator,
+		logger:        logger,
+	}
+}
+
+// APIKeyAuth 返回接受API密钥的认证中间件，fallback为未携带密钥时使用的认证中间件
+func (am *APIKeyAuthMiddleware) APIKeyAuth(scope repository.APIKeyScope, fallback gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plaintext := c.GetHeader(APIKeyHeader)
+		if plaintext == "" {
+			if fallback != nil {
+				fallback(c)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		key, user, err := am.authenticator.Authenticate(c.Request.Context(), plaintext)
+		if err != nil {
+			am.logger.Warn("API key authentication failed",
+				zap.Error(err),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("remote_addr", c.ClientIP()))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    "INVALID_API_KEY",
+				"message": "API密钥无效、已撤销或已过期",
+			})
+			return
+		}
+
+		if !key.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    "API_KEY_SCOPE_DENIED",
+				"message": "API密钥不允许调用" + string(scope) + "接口",
+			})
+			return
+		}
+
+		// 以密钥所属用户的身份与当前角色执行
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("user_role", user.Role)
+		c.Set("api_key_id", key.ID)
+		c.Set("api_key_scopes", key.Scopes)
+
+		am.logger.Debug("API key authentication successful",
+			zap.Int64("user_id", user.ID),
+			zap.Int64("api_key_id", key.ID))
+
+		c.Next()
+	}
+}
+
+// GetAPIKeyScopesFromContext 从Gin上下文获取API密钥的接口范围，请求不是通过API密钥认证时返回false
+func GetAPIKeyScopesFromContext(c *gin.Context) ([]repository.APIKeyScope, bool) {
+	scopes, exists := c.Get("api_key_scopes")
+	if !exists {
+		return nil, false
+	}
+
+	list, ok := scopes.([]repository.APIKeyScope)
+	return list, ok
+}
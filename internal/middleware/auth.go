@@ -3,12 +3,14 @@ package middleware
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"chat2sql-go/internal/auth"
+	"chat2sql-go/internal/repository"
 )
 
 // AuthMiddleware JWT认证中间件
@@ -31,6 +33,9 @@ func (am *AuthMiddleware) JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 提取Authorization头
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			authHeader = webSocketToken(c)
+		}
 		if authHeader == "" {
 			am.logger.Warn("Missing authorization header",
 				zap.String("path", c.Request.URL.Path),
@@ -100,6 +105,18 @@ func (am *AuthMiddleware) JWTAuth() gin.HandlerFunc {
 	}
 }
 
+// webSocketToken 浏览器无法为WebSocket握手设置请求头，升级请求允许通过access_token查询参数携带令牌
+// 仅对WebSocket升级请求生效，普通REST请求仍必须使用Authorization头
+func webSocketToken(c *gin.Context) string {
+	if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		return ""
+	}
+	if token := c.Query("access_token"); token != "" {
+		return "Bearer " + token
+	}
+	return ""
+}
+
 // RequireRole 角色权限中间件
 // 要求用户具有特定角色才能访问接口
 func RequireRole(requiredRoles ...string) gin.HandlerFunc {
@@ -256,46 +273,9 @@ func GetJWTClaimsFromContext(c *gin.Context) (*auth.CustomClaims, bool) {
 	return jwtClaims, ok
 }
 
-// checkPermission 检查角色是否具有指定权限
+// checkPermission 检查角色是否具有指定权限，权限矩阵见repository.RolePermissions
 func checkPermission(role, permission string) bool {
-	// 管理员拥有所有权限
-	if role == "admin" {
-		return true
-	}
-	
-	// 基于角色的权限映射
-	rolePermissions := map[string][]string{
-		"user": {
-			"query:execute",
-			"connection:manage",
-			"profile:read",
-			"profile:update",
-		},
-		"manager": {
-			"query:execute",
-			"connection:manage",
-			"profile:read",
-			"profile:update",
-			"history:view_team",
-			"connection:view_team",
-		},
-		"admin": {
-			// 管理员拥有所有权限，在上面已处理
-		},
-	}
-	
-	permissions, exists := rolePermissions[role]
-	if !exists {
-		return false
-	}
-	
-	for _, perm := range permissions {
-		if perm == permission {
-			return true
-		}
-	}
-	
-	return false
+	return repository.UserRole(role).Can(repository.Permission(permission))
 }
 
 // UserIDFromRequest 从请求获取用户ID（用于兼容性）
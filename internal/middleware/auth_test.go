@@ -405,6 +405,9 @@ func (suite *AuthorizationSecurityTestSuite) TestCrossUserDataAccess() {
 // TestPermissionEscalation 测试权限提升攻击
 func (suite *AuthorizationSecurityTestSuite) TestPermissionEscalation() {
 	// 生成不同角色的token
+	viewerToken, err := suite.jwtService.GenerateTokenPair(100, "viewer", "viewer")
+	require.NoError(suite.T(), err)
+	
 	userToken, err := suite.jwtService.GenerateTokenPair(123, "user", "user")
 	require.NoError(suite.T(), err)
 	
@@ -451,6 +454,9 @@ func (suite *AuthorizationSecurityTestSuite) TestPermissionEscalation() {
 		expectedStatus int
 	}{
 		{"user访问public", "/public", "", http.StatusOK},
+		{"viewer访问user/data", "/user/data", viewerToken.AccessToken, http.StatusForbidden},
+		{"viewer访问manager/team", "/manager/team", viewerToken.AccessToken, http.StatusForbidden},
+		
 		{"user访问user/data", "/user/data", userToken.AccessToken, http.StatusOK},
 		{"user访问manager/team", "/manager/team", userToken.AccessToken, http.StatusForbidden},
 		{"user访问admin/system", "/admin/system", userToken.AccessToken, http.StatusForbidden},
@@ -595,6 +601,26 @@ func (suite *AuthorizationSecurityTestSuite) TestAuthorizationHeaderVariations()
 }
 
 // 运行权限安全测试套件
+// TestQueryTokenOnlyForWebSocket 测试查询参数中的令牌仅对WebSocket升级请求生效
+func (suite *AuthorizationSecurityTestSuite) TestQueryTokenOnlyForWebSocket() {
+	tokenPair, err := suite.jwtService.GenerateTokenPair(123, "testuser", "user")
+	require.NoError(suite.T(), err)
+	
+	suite.router.GET("/ws", suite.authMiddleware.JWTAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetInt64("user_id")})
+	})
+	
+	req, _ := http.NewRequest("GET", "/ws?access_token="+tokenPair.AccessToken, nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	
+	req.Header.Set("Upgrade", "websocket")
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
 func TestAuthorizationSecurityTestSuite(t *testing.T) {
 	suite.Run(t, new(AuthorizationSecurityTestSuite))
 }
\ No newline at end of file
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"chat2sql-go/internal/config"
+)
+
+// compressionEncodings 支持的内容编码，按服务端偏好排序
+// 只内置标准库提供的gzip；Brotli没有标准库实现，不为此引入cgo或第三方编码库，
+// 需要br时关闭本中间件，由前置的Nginx/CDN统一压缩；客户端只接受br时响应不压缩
+var compressionEncodings = []string{"gzip"}
+
+// CompressionMiddleware 响应压缩中间件
+// 按Accept-Encoding协商编码，只压缩配置中的内容类型且响应体达到最小字节数的响应；
+// 处理器已设置Content-Encoding、204/304等无响应体的状态以及WebSocket升级请求不压缩
+func CompressionMiddleware(cfg *config.CompressionConfig) gin.HandlerFunc {
+	if cfg == nil || !cfg.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	contentTypes := make(map[string]bool, len(cfg.ContentTypes))
+	for _, contentType := range cfg.ContentTypes {
+		contentTypes[strings.ToLower(contentType)] = true
+	}
+	pool := &sync.Pool{
+		New: func() any {
+			// 级别已在配置校验中检查，这里不会出错
+			gz, _ := gzip.NewWriterLevel(io.Discard, cfg.Level)
+			return gz
+		},
+	}
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" ||
+			negotiateEncoding(c.GetHeader("Accept-Encoding")) == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			minSize:        cfg.MinSize,
+			contentTypes:   contentTypes,
+			pool:           pool,
+		}
+		c.Writer = writer
+		// panic时丢弃尚未写出的缓冲，由外层恢复中间件直接写错误响应
+		defer func() { c.Writer = writer.ResponseWriter }()
+
+		c.Next()
+		writer.close()
+	}
+}
+
+// negotiateEncoding 按Accept-Encoding与服务端偏好选择编码，没有可用编码时返回空字符串
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	for _, encoding := range compressionEncodings {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > 0 {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressWriter 先缓冲响应体，达到最小字节数或响应结束时再决定是否压缩
+type compressWriter struct {
+	gin.ResponseWriter
+	minSize      int
+	contentTypes map[string]bool
+	pool         *sync.Pool
+
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minSize {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow 提前发送响应头时必须先确定是否压缩
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush 流式响应刷新时按当前缓冲的内容做出决定
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide 根据状态码、响应头与已缓冲的内容决定是否压缩，并写出缓冲数据
+func (w *compressWriter) decide() error {
+	w.decided = true
+	buf := w.buf
+	w.buf = nil
+
+	if w.shouldCompress(len(buf)) {
+		header := w.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) shouldCompress(size int) bool {
+	if size == 0 || size < w.minSize {
+		return false
+	}
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return w.contentTypes[strings.ToLower(mediaType)]
+}
+
+// close 写出剩余缓冲并结束压缩流
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.decide()
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(io.Discard)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
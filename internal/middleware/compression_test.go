@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", negotiateEncoding("gzip, deflate, br"))
+	assert.Equal(t, "gzip", negotiateEncoding("br;q=1.0, GZIP;q=0.5"))
+	assert.Equal(t, "gzip", negotiateEncoding("*"))
+	assert.Equal(t, "", negotiateEncoding("gzip;q=0"))
+	assert.Equal(t, "", negotiateEncoding("*;q=0"))
+	assert.Equal(t, "", negotiateEncoding("br"), "不提供Brotli，只接受br的客户端收到未压缩的响应")
+	assert.Equal(t, "", negotiateEncoding("identity"))
+	assert.Equal(t, "", negotiateEncoding(""))
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	large := strings.Repeat("chat2sql ", 500)
+
+	router := gin.New()
+	router.Use(CompressionMiddleware(config.DefaultCompressionConfig()))
+	router.GET("/large", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"rows": large}) })
+	router.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	router.GET("/xlsx", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", []byte(large))
+	})
+	router.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "application/json", []byte(large))
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "application/json")
+		for i := 0; i < 3; i++ {
+			_, _ = c.Writer.WriteString(large)
+			c.Writer.Flush()
+		}
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	gunzip := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		reader, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("压缩达到阈值的JSON", func(t *testing.T) {
+		w := get("/large", "gzip")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.Less(t, w.Body.Len(), len(large))
+		assert.Contains(t, gunzip(t, w), large)
+	})
+
+	t.Run("流式响应逐段压缩", func(t *testing.T) {
+		w := get("/stream", "gzip")
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, strings.Repeat(large, 3), gunzip(t, w))
+	})
+
+	t.Run("不压缩的情况", func(t *testing.T) {
+		for _, tc := range []struct{ path, acceptEncoding string }{
+			{"/large", ""},
+			{"/large", "gzip;q=0"},
+			{"/small", "gzip"},
+			{"/xlsx", "gzip"},
+		} {
+			w := get(tc.path, tc.acceptEncoding)
+			assert.Empty(t, w.Header().Get("Content-Encoding"), tc.path)
+			assert.NotContains(t, w.Body.String(), "\x1f\x8b", tc.path)
+		}
+
+		// 处理器自行编码的响应原样透传
+		w := get("/encoded", "gzip")
+		assert.Equal(t, large, w.Body.String())
+	})
+}
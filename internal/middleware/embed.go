@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"chat2sql-go/internal/auth"
+)
+
+// EmbedTokenValidator 嵌入令牌验证接口
+type EmbedTokenValidator interface {
+	ValidateEmbedToken(tokenString string) (*auth.EmbedClaims, error)
+}
+
+// embedLimiter 单个嵌入令牌的限流器
+type embedLimiter struct {
+	limiter   *rate.Limiter
+	expiresAt time.Time
+}
+
+// EmbedAuthMiddleware 嵌入式组件认证中间件
+// 验证嵌入令牌，并按令牌中声明的每分钟请求数进行限流
+type EmbedAuthMiddleware struct {
+	validator   EmbedTokenValidator
+	logger      *zap.Logger
+	limiters    map[string]*embedLimiter
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// NewEmbedAuthMiddleware 创建嵌入认证中间件实例
+func NewEmbedAuthMiddleware(validator EmbedTokenValidator, logger *zap.Logger) *EmbedAuthMiddleware {
+	return &EmbedAuthMiddleware{
+		validator:   validator,
+		logger:      logger,
+		limiters:    make(map[string]*embedLimiter),
+		lastCleanup: time.Now(),
+	}
+}
+
+// EmbedAuth 嵌入令牌认证中间件函数
+func (em *EmbedAuthMiddleware) EmbedAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, err := auth.ExtractTokenFromHeader(c.GetHeader("Authorization"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    "MISSING_AUTH_HEADER",
+				"message": "缺少嵌入令牌",
+			})
+			c.Abort()
+			return
+		}
+
+		claims, err := em.validator.ValidateEmbedToken(tokenString)
+		if err != nil {
+			em.logger.Warn("Embed token validation failed",
+				zap.Error(err),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("remote_addr", c.ClientIP()))
+
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    "INVALID_TOKEN",
+				"message": "无效的嵌入令牌",
+			})
+			c.Abort()
+			return
+		}
+
+		if !em.allow(claims) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":        "RATE_LIMIT_EXCEEDED",
+				"message":     "请求频率超过限制，请稍后重试",
+				"retry_after": 60,
+			})
+			c.Abort()
+			return
+		}
+
+		// 设置用户上下文信息；embed_connection_id限定本次请求可访问的连接
+		c.Set("user_id", claims.UserID)
+		c.Set("embed_connection_id", claims.ConnectionID)
+		c.Set("embed_claims", claims)
+
+		c.Next()
+	}
+}
+
+// allow 检查令牌是否超出每分钟请求上限
+func (em *EmbedAuthMiddleware) allow(claims *auth.EmbedClaims) bool {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	now := time.Now()
+	em.cleanupLocked(now)
+
+	entry, ok := em.limiters[claims.ID]
+	if !ok {
+		expiresAt := now.Add(auth.MaxEmbedTokenTTL)
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+		entry = &embedLimiter{
+			limiter:   rate.NewLimiter(rate.Every(time.Minute/time.Duration(claims.RateLimit)), claims.RateLimit),
+			expiresAt: expiresAt,
+		}
+		em.limiters[claims.ID] = entry
+	}
+
+	return entry.limiter.AllowN(now, 1)
+}
+
+// cleanupLocked 移除已过期令牌的限流器（调用方需持有锁）
+func (em *EmbedAuthMiddleware) cleanupLocked(now time.Time) {
+	if now.Sub(em.lastCleanup) < time.Minute {
+		return
+	}
+	for id, entry := range em.limiters {
+		if now.After(entry.expiresAt) {
+			delete(em.limiters, id)
+		}
+	}
+	em.lastCleanup = now
+}
+
+// GetEmbedConnectionIDFromContext 从Gin上下文获取嵌入令牌绑定的连接ID
+func GetEmbedConnectionIDFromContext(c *gin.Context) (int64, bool) {
+	connectionID, exists := c.Get("embed_connection_id")
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := connectionID.(int64)
+	return id, ok
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/auth"
+)
+
+func newEmbedTestRouter(t *testing.T) (*gin.Engine, *auth.JWTService) {
+	gin.SetMode(gin.TestMode)
+
+	jwtService, err := auth.NewJWTService(&auth.JWTConfig{
+		AutoGenerateKeys: true,
+		Issuer:           "test-issuer",
+		Audience:         "test-audience",
+		AccessTokenTTL:   time.Hour,
+		RefreshTokenTTL:  24 * time.Hour,
+	}, zap.NewNop(), nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/embed", NewEmbedAuthMiddleware(jwtService, zap.NewNop()).EmbedAuth(), func(c *gin.Context) {
+		userID, _ := GetUserIDFromContext(c)
+		connectionID, _ := GetEmbedConnectionIDFromContext(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID, "connection_id": connectionID})
+	})
+	return router, jwtService
+}
+
+func embedRequest(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/embed", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestEmbedAuth_SetsContext(t *testing.T) {
+	router, jwtService := newEmbedTestRouter(t)
+
+	token, err := jwtService.GenerateEmbedToken(9, 4, 0, 0)
+	require.NoError(t, err)
+
+	w := embedRequest(router, token.Token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":9,"connection_id":4}`, w.Body.String())
+}
+
+func TestEmbedAuth_RejectsInvalidTokens(t *testing.T) {
+	router, jwtService := newEmbedTestRouter(t)
+
+	assert.Equal(t, http.StatusUnauthorized, embedRequest(router, "").Code)
+
+	// 普通Access Token不能用于嵌入端点
+	pair, err := jwtService.GenerateTokenPair(9, "alice", "user")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, embedRequest(router, pair.AccessToken).Code)
+}
+
+func TestEmbedAuth_RateLimitPerToken(t *testing.T) {
+	router, jwtService := newEmbedTestRouter(t)
+
+	token, err := jwtService.GenerateEmbedToken(9, 4, 0, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, embedRequest(router, token.Token).Code)
+	assert.Equal(t, http.StatusOK, embedRequest(router, token.Token).Code)
+	assert.Equal(t, http.StatusTooManyRequests, embedRequest(router, token.Token).Code)
+
+	// 其他令牌不受影响
+	other, err := jwtService.GenerateEmbedToken(9, 4, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, embedRequest(router, other.Token).Code)
+}
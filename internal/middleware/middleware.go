@@ -1,14 +1,20 @@
 package middleware
 
 import (
+	"errors"
+	"net"
 	"net/http"
+	"runtime/debug"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
+
+	"chat2sql-go/internal/crash"
 )
 
 // MiddlewareConfig 中间件配置
@@ -54,7 +60,7 @@ func DefaultMiddlewareConfig(logger *zap.Logger) *MiddlewareConfig {
 		CORS: &CORSConfig{
 			AllowOrigins:     []string{"*"}, // 开发环境允许所有源
 			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-			AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-User-ID"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-User-ID", "X-API-Key", "X-Cost-Center", "X-Project", "X-Prompt-Cache"},
 			AllowCredentials: true,
 			MaxAge:           86400, // 24小时
 		},
@@ -90,26 +96,83 @@ func SetupMiddleware(r *gin.Engine, config *MiddlewareConfig) {
 	r.Use(RequestIDMiddleware())
 }
 
+// recoveryContextKey 标记请求已被RecoveryMiddleware保护，重复注册时内层直接放行
+const recoveryContextKey = "crash_recovery"
+
 // RecoveryMiddleware 恢复中间件
-// 捕获panic并记录详细错误日志，防止服务崩溃
+// 捕获panic并生成崩溃报告，防止服务崩溃；同时把每个请求记入崩溃报告器的最近请求缓冲区
 func RecoveryMiddleware(logger *zap.Logger) gin.HandlerFunc {
-	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
-		if logger != nil {
-			logger.Error("Request panic recovered",
-				zap.Any("panic", recovered),
-				zap.String("method", c.Request.Method),
-				zap.String("path", c.Request.URL.Path),
-				zap.String("remote_addr", c.ClientIP()),
-				zap.String("user_agent", c.Request.UserAgent()),
-			)
+	return func(c *gin.Context) {
+		if c.GetBool(recoveryContextKey) {
+			c.Next()
+			return
 		}
-		
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    "INTERNAL_ERROR",
-			"message": "服务器内部错误",
-			"timestamp": time.Now().Format(time.RFC3339),
-		})
-	})
+		c.Set(recoveryContextKey, true)
+		start := time.Now()
+
+		defer func() {
+			reporter := crash.Default()
+			record := crash.RequestRecord{
+				Time:      start,
+				Method:    c.Request.Method,
+				Route:     c.FullPath(),
+				Path:      c.Request.URL.Path,
+				RequestID: c.GetString("request_id"),
+				Latency:   time.Since(start),
+			}
+			record.UserID, _ = GetUserIDFromContext(c)
+
+			recovered := recover()
+			if recovered == nil {
+				record.Status = c.Writer.Status()
+				reporter.Record(record)
+				return
+			}
+
+			// 客户端断开连接导致的写入失败不是服务端缺陷，按gin的方式直接中止
+			if isBrokenPipe(recovered) {
+				c.Abort()
+				return
+			}
+
+			record.Status = http.StatusInternalServerError
+			bundle, _ := reporter.Capture("http", recovered, debug.Stack(), &record)
+			reporter.Record(record)
+
+			if logger != nil {
+				logger.Error("Request panic recovered",
+					zap.String("crash_id", bundle.ID),
+					zap.String("method", c.Request.Method),
+					zap.String("path", c.Request.URL.Path),
+					zap.String("remote_addr", c.ClientIP()),
+					zap.String("user_agent", c.Request.UserAgent()),
+				)
+			}
+
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"code":      "INTERNAL_ERROR",
+				"message":   "服务器内部错误",
+				"crash_id":  bundle.ID,
+				"timestamp": time.Now().Format(time.RFC3339),
+			})
+		}()
+
+		c.Next()
+	}
+}
+
+// isBrokenPipe 判断panic是否由客户端断开连接引起
+func isBrokenPipe(recovered any) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	msg := strings.ToLower(opErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
 }
 
 // StructuredLogger 结构化日志中间件
@@ -284,6 +347,31 @@ func RateLimitMiddleware(config *RateLimitConfig) gin.HandlerFunc {
 	}
 }
 
+// RouteRateLimitMiddleware 路由级限流中间件
+// 每个路由使用独立的限流器，互不影响；放在认证中间件之后时按已认证用户限流，否则按IP地址限流
+func RouteRateLimitMiddleware(config *RateLimitConfig) gin.HandlerFunc {
+	limiter := NewRateLimiter(config)
+
+	return func(c *gin.Context) {
+		limitKey := "ip:" + c.ClientIP()
+		if userID, ok := GetUserIDFromContext(c); ok {
+			limitKey = "user:" + strconv.FormatInt(userID, 10)
+		}
+
+		if !limiter.Allow(limitKey) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":        "RATE_LIMIT_EXCEEDED",
+				"message":     "请求频率超过限制，请稍后重试",
+				"retry_after": 60,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // RequestMetrics 请求指标统计
 type RequestMetrics struct {
 	requestTotal     sync.Map // key: method_endpoint_status, value: count
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/crash"
+)
+
+func TestRecoveryMiddleware_CapturesCrashBundle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	crash.SetDefault(crash.NewReporter(&config.CrashReportConfig{Dir: dir, MaxBundles: 5, RecentRequests: 5}, "test", nil, zap.NewNop()))
+	t.Cleanup(func() { crash.SetDefault(nil) })
+
+	r := gin.New()
+	// 重复注册时内层放行，由外层统一生成崩溃报告
+	r.Use(RecoveryMiddleware(zap.NewNop()), RequestIDMiddleware(), RecoveryMiddleware(zap.NewNop()))
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/panic/:id", func(c *gin.Context) { panic("token=sk-abcdefghijklmnopqrstuvwx") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic/1", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "INTERNAL_ERROR", body["code"])
+	require.NotEmpty(t, body["crash_id"])
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+body["crash_id"].(string)+".json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	recent := crash.Default().RecentRequests()
+	require.Len(t, recent, 2)
+	assert.Equal(t, "/ok", recent[0].Route)
+	assert.Equal(t, "/panic/:id", recent[1].Route)
+	assert.Equal(t, http.StatusInternalServerError, recent[1].Status)
+	assert.NotEmpty(t, recent[1].RequestID)
+}
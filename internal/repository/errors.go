@@ -41,6 +41,10 @@ var (
 	// ErrTooManyRequests 请求过多错误
 	// 当请求频率超过限制时返回此错误
 	ErrTooManyRequests = errors.New("请求过于频繁")
+	
+	// ErrUnavailable 数据库暂时不可用错误
+	// 当数据库连续出现连接故障而熔断时返回此错误，调用方应稍后重试
+	ErrUnavailable = errors.New("数据库暂时不可用")
 )
 
 // IsNotFound 检查错误是否为记录不存在错误
@@ -86,4 +90,9 @@ func IsTimeout(err error) bool {
 // IsTooManyRequests 检查错误是否为请求过多错误
 func IsTooManyRequests(err error) bool {
 	return errors.Is(err, ErrTooManyRequests)
-}
\ No newline at end of file
+}
+
+// IsUnavailable 检查错误是否为数据库暂时不可用错误
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
@@ -13,6 +13,22 @@ type Repository interface {
 	ConnectionRepo() ConnectionRepository
 	SchemaRepo() SchemaRepository
 	FeedbackRepo() FeedbackRepository
+	WorkspaceRepo() WorkspaceRepository
+	WriteRequestRepo() WriteRequestRepository
+	ApprovalRepo() ApprovalRepository
+	ClassificationRepo() ClassificationRepository
+	ErasureRepo() ErasureRepository
+	HistoryKeyRepo() HistoryKeyRepository
+	FolderRepo() FolderRepository
+	SavedQueryRepo() SavedQueryRepository
+	SchemaSnapshotRepo() SchemaSnapshotRepository
+	IntegrationCredentialRepo() IntegrationCredentialRepository
+	APIKeyRepo() APIKeyRepository
+	ColumnAccessRepo() ColumnAccessRepository
+	LLMUsageRepo() LLMUsageRepository
+	SchemaChangeRepo() SchemaChangeRepository
+	QueryJobRepo() QueryJobRepository
+	QueryScheduleRepo() QueryScheduleRepository
 	
 	// 事务管理
 	BeginTx(ctx context.Context) (TxRepository, error)
@@ -28,6 +44,22 @@ type TxRepository interface {
 	ConnectionRepo() ConnectionRepository
 	SchemaRepo() SchemaRepository
 	FeedbackRepo() FeedbackRepository
+	WorkspaceRepo() WorkspaceRepository
+	WriteRequestRepo() WriteRequestRepository
+	ApprovalRepo() ApprovalRepository
+	ClassificationRepo() ClassificationRepository
+	ErasureRepo() ErasureRepository
+	HistoryKeyRepo() HistoryKeyRepository
+	FolderRepo() FolderRepository
+	SavedQueryRepo() SavedQueryRepository
+	SchemaSnapshotRepo() SchemaSnapshotRepository
+	IntegrationCredentialRepo() IntegrationCredentialRepository
+	APIKeyRepo() APIKeyRepository
+	ColumnAccessRepo() ColumnAccessRepository
+	LLMUsageRepo() LLMUsageRepository
+	SchemaChangeRepo() SchemaChangeRepository
+	QueryJobRepo() QueryJobRepository
+	QueryScheduleRepo() QueryScheduleRepository
 	
 	Commit() error
 	Rollback() error
@@ -79,13 +111,21 @@ type QueryHistoryRepository interface {
 	ListByConnection(ctx context.Context, connectionID int64, limit, offset int) ([]*QueryHistory, error)
 	ListByStatus(ctx context.Context, status QueryStatus, limit, offset int) ([]*QueryHistory, error)
 	ListRecent(ctx context.Context, userID int64, hours int, limit int) ([]*QueryHistory, error)
+	// ListPage 按create_time、id倒序分页列出用户的查询历史
+	// after非空时从该位置之后开始（键集分页），否则跳过offset条，offset只为兼容旧的offset参数保留
+	ListPage(ctx context.Context, filter QueryHistoryFilter, after *QueryHistoryKeyset, limit, offset int) ([]*QueryHistory, error)
 	
 	// 统计操作
 	CountByUser(ctx context.Context, userID int64) (int64, error)
 	CountByStatus(ctx context.Context, status QueryStatus) (int64, error)
 	GetExecutionStats(ctx context.Context, userID int64, days int) (*QueryExecutionStats, error)
+	GetExecutionStatsSince(ctx context.Context, userID int64, since time.Time) (*QueryExecutionStats, error)
 	GetPopularQueries(ctx context.Context, limit int, days int) ([]*PopularQuery, error)
 	GetSlowQueries(ctx context.Context, minExecutionTime int32, limit int) ([]*QueryHistory, error)
+	// GetConnectionUsage 统计每个未删除连接自since起按星期几与小时的查询量，星期与小时按location时区计算
+	GetConnectionUsage(ctx context.Context, since time.Time, location string) ([]*ConnectionUsage, error)
+	// ListFailuresByConnection 列出连接自since起执行失败（error/timeout）的记录，按时间正序，最多limit条
+	ListFailuresByConnection(ctx context.Context, connectionID int64, since time.Time, limit int) ([]*QueryHistory, error)
 	
 	// 搜索操作
 	SearchByNaturalQuery(ctx context.Context, userID int64, keyword string, limit, offset int) ([]*QueryHistory, error)
@@ -93,6 +133,9 @@ type QueryHistoryRepository interface {
 	
 	// 批量操作
 	BatchUpdateStatus(ctx context.Context, queryIDs []int64, status QueryStatus) error
+	BatchDelete(ctx context.Context, userID int64, queryIDs []int64) ([]int64, error) // 软删除用户自己的记录，返回实际删除的ID
+	// BatchRetag 替换用户自己记录的费用归属标签，tags为nil时清除标签，返回实际修改的ID
+	BatchRetag(ctx context.Context, userID int64, queryIDs []int64, tags *ChargebackTags) ([]int64, error)
 	CleanupOldQueries(ctx context.Context, beforeDate time.Time) (int64, error)
 }
 
@@ -119,7 +162,14 @@ type ConnectionRepository interface {
 	// 状态管理
 	UpdateStatus(ctx context.Context, connectionID int64, status ConnectionStatus) error
 	UpdateLastTested(ctx context.Context, connectionID int64, testTime time.Time) error
+	UpdateWriteMode(ctx context.Context, connectionID int64, enabled bool, updateBy int64) error
+	// UpdateResultLimits 设置连接的结果行数与大小上限，nil表示使用全局配置
+	UpdateResultLimits(ctx context.Context, connectionID int64, maxRows, maxResultMB *int32, updateBy int64) error
 	BatchUpdateStatus(ctx context.Context, connectionIDs []int64, status ConnectionStatus) error
+	// ListUnused 列出自since起没有任何查询的活跃连接（从未使用的按创建时间判断），附带上次提醒所有者的时间
+	ListUnused(ctx context.Context, since time.Time) ([]*UnusedConnection, error)
+	// MarkUnusedNotified 记录已提醒所有者连接闲置
+	MarkUnusedNotified(ctx context.Context, connectionID int64, notifiedAt time.Time) error
 	
 	// 检查操作
 	ExistsByUserAndName(ctx context.Context, userID int64, name string) (bool, error)
@@ -179,6 +229,37 @@ type PopularQuery struct {
 	AvgExecTime  float64 `json:"avg_exec_time"` // 平均执行时间
 }
 
+// ConnectionUsage 连接的查询量分布，期间没有查询的连接Cells为空
+type ConnectionUsage struct {
+	ConnectionID   int64                 `json:"connection_id"`
+	ConnectionName string                `json:"connection_name"`
+	Status         ConnectionStatus      `json:"status"`
+	Cells          []ConnectionUsageCell `json:"cells"` // 只包含有查询的时段
+}
+
+// UnusedConnection 长期没有查询的连接
+type UnusedConnection struct {
+	Connection *DatabaseConnection `json:"connection"`
+	LastUsedAt *time.Time          `json:"last_used_at"` // 最后一次查询时间，从未使用时为nil
+	NotifiedAt *time.Time          `json:"notified_at"`  // 上次提醒所有者连接闲置的时间，未提醒过时为nil
+}
+
+// LastActivity 最后一次查询时间，从未使用时为连接创建时间
+func (u *UnusedConnection) LastActivity() time.Time {
+	if u.LastUsedAt != nil {
+		return *u.LastUsedAt
+	}
+	return u.Connection.CreateTime
+}
+
+// ConnectionUsageCell 某星期几某小时的查询量
+type ConnectionUsageCell struct {
+	DayOfWeek   int   `json:"day_of_week"`  // 0为周日，与time.Weekday一致
+	Hour        int   `json:"hour"`         // 0-23
+	QueryCount  int64 `json:"query_count"`  // 查询次数
+	FailedCount int64 `json:"failed_count"` // 失败或超时的次数
+}
+
 // TableInfo 表信息
 type TableInfo struct {
 	SchemaName   string `json:"schema_name"`   // 模式名
@@ -232,6 +313,288 @@ func (p *PaginationParams) GetPage() int {
 	return (p.Offset / p.Limit) + 1
 }
 
+// QueryHistoryFilter 查询历史列表的筛选条件
+type QueryHistoryFilter struct {
+	UserID       int64       // 只返回该用户的记录
+	ConnectionID int64       // 大于0时按连接筛选
+	Status       QueryStatus // 非空时按执行状态筛选
+	Keyword      string      // 非空时按关键字全文搜索
+	SearchSQL    bool        // 关键字匹配生成的SQL而不是问题，问题被哈希或丢弃时使用
+}
+
+// QueryHistoryKeyset 查询历史键集分页的位置：上一页最后一条记录的排序键
+type QueryHistoryKeyset struct {
+	CreateTime time.Time
+	ID         int64
+}
+
+// WorkspaceRepository 工作空间Repository接口
+// 管理工作空间及成员关系，每个用户至多属于一个工作空间
+type WorkspaceRepository interface {
+	Create(ctx context.Context, workspace *Workspace) error
+	GetByID(ctx context.Context, id int64) (*Workspace, error)
+	// GetByUserID 获取用户所属工作空间，未加入时返回默认工作空间
+	GetByUserID(ctx context.Context, userID int64) (*Workspace, error)
+	Update(ctx context.Context, workspace *Workspace) error
+	UpdateAutoExecutePolicy(ctx context.Context, workspaceID int64, policy *AutoExecutePolicy) error
+	UpdateDataResidency(ctx context.Context, workspaceID int64, policy *DataResidencyPolicy, updateBy int64) error
+	UpdateDefaults(ctx context.Context, workspaceID int64, defaults *WorkspaceDefaults, updateBy int64) error
+	UpdateQuestionRetention(ctx context.Context, workspaceID int64, mode QuestionRetention, updateBy int64) error
+	UpdateLatencyRouting(ctx context.Context, workspaceID int64, policy *LatencyRoutingPolicy, updateBy int64) error
+	UpdateChargebackTaxonomy(ctx context.Context, workspaceID int64, taxonomy *ChargebackTaxonomy, updateBy int64) error
+	
+	// 成员管理
+	AddMember(ctx context.Context, workspaceID, userID int64) error
+	RemoveMember(ctx context.Context, workspaceID, userID int64) error
+	// ListMemberIDs 列出工作空间成员的用户ID，默认工作空间包含未加入任何工作空间的用户
+	ListMemberIDs(ctx context.Context, workspaceID int64) ([]int64, error)
+}
+
+// WriteRequestRepository 写操作申请Repository接口
+// 申请只能从pending流转一次，审批决定与审计事件由审批流程记录
+type WriteRequestRepository interface {
+	Create(ctx context.Context, req *WriteRequest) error
+	GetByID(ctx context.Context, id int64) (*WriteRequest, error)
+	ListByStatus(ctx context.Context, status WriteRequestStatus, limit, offset int) ([]*WriteRequest, error)
+	// ListByRequester 按状态分页列出申请人自己提交的写操作申请
+	ListByRequester(ctx context.Context, requestedBy int64, status WriteRequestStatus, limit, offset int) ([]*WriteRequest, error)
+	ListByConnection(ctx context.Context, connectionID int64, limit, offset int) ([]*WriteRequest, error)
+	SetApprovalID(ctx context.Context, id, approvalID int64) error
+
+	// Review 将pending申请标记为审批结果，reviewerID为空表示系统处理（如过期），申请不存在或已处理时返回ErrNotFound
+	Review(ctx context.Context, id int64, reviewerID *int64, status WriteRequestStatus, comment *string) error
+	// RecordExecution 记录审批通过后的执行结果
+	RecordExecution(ctx context.Context, id int64, status WriteRequestStatus, affectedRows *int64, errorMessage *string) error
+}
+
+// ApprovalRepository 审批申请Repository接口
+// 申请只能从pending流转一次，所有状态变化都记录审计事件
+type ApprovalRepository interface {
+	Create(ctx context.Context, req *ApprovalRequest) error
+	GetByID(ctx context.Context, id int64) (*ApprovalRequest, error)
+	ListByStatus(ctx context.Context, status ApprovalStatus, limit, offset int) ([]*ApprovalRequest, error)
+	// ListByParticipant 按状态分页列出用户提交或处理过的申请，以及actionTypes中各动作类型的全部申请
+	ListByParticipant(ctx context.Context, userID int64, actionTypes []ApprovalActionType, status ApprovalStatus, limit, offset int) ([]*ApprovalRequest, error)
+
+	// Decide 将未过期的pending申请标记为审批结果，申请不存在、已处理或已过期时返回ErrNotFound
+	Decide(ctx context.Context, id, deciderID int64, status ApprovalStatus, comment *string) error
+	// RecordResult 记录审批通过后的执行结果
+	RecordResult(ctx context.Context, id int64, status ApprovalStatus, errorMessage *string) error
+	// ExpirePending 将截至now仍未处理的申请标记为过期并返回这些申请
+	ExpirePending(ctx context.Context, now time.Time) ([]*ApprovalRequest, error)
+
+	// 审计事件
+	AddEvent(ctx context.Context, event *ApprovalEvent) error
+	ListEvents(ctx context.Context, requestID int64) ([]*ApprovalEvent, error)
+
+	// 指定审批人，未指定时由manager或admin审批
+	ListApprovers(ctx context.Context, actionType ApprovalActionType) ([]int64, error)
+	SetApprovers(ctx context.Context, actionType ApprovalActionType, userIDs []int64) error
+}
+
+// ClassificationRepository 列数据分级标签Repository接口
+// 标签按连接与列的自然键唯一，元数据刷新后仍然保留
+type ClassificationRepository interface {
+	// Upsert 设置列的分级，已存在时覆盖
+	Upsert(ctx context.Context, classification *ColumnClassification) error
+	// Delete 移除列的分级，不存在时返回ErrNotFound
+	Delete(ctx context.Context, connectionID int64, schemaName, tableName, columnName string, deleteBy int64) error
+	ListByConnection(ctx context.Context, connectionID int64) ([]*ColumnClassification, error)
+}
+
+// FolderRepository 保存查询文件夹Repository接口
+// 名称在同一父文件夹下唯一，冲突时返回ErrDuplicateEntry
+type FolderRepository interface {
+	// Create 创建文件夹并根据父文件夹路径生成Path，parent为空表示根目录
+	Create(ctx context.Context, folder *QueryFolder, parent *QueryFolder) error
+	GetByID(ctx context.Context, id int64) (*QueryFolder, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]*QueryFolder, error)
+	// ListChildren 列出父文件夹下的子文件夹，parentID为空表示根目录
+	ListChildren(ctx context.Context, workspaceID int64, parentID *int64) ([]*QueryFolder, error)
+	Rename(ctx context.Context, id int64, name string, updateBy int64) error
+	// Move 将文件夹及其整个子树移动到newParent下，newParent为空表示移动到根目录
+	Move(ctx context.Context, folder *QueryFolder, newParent *QueryFolder, updateBy int64) error
+	// Delete 软删除空文件夹，仍有子文件夹或保存查询时返回ErrInvalidInput
+	Delete(ctx context.Context, id int64, deleteBy int64) error
+
+	// 权限管理
+	SetPermission(ctx context.Context, permission *FolderPermission) error
+	RemovePermission(ctx context.Context, folderID, userID int64) error
+	ListPermissions(ctx context.Context, folderIDs []int64) ([]*FolderPermission, error)
+}
+
+// SavedQueryRepository 保存查询Repository接口
+type SavedQueryRepository interface {
+	Create(ctx context.Context, query *SavedQuery) error
+	GetByID(ctx context.Context, id int64) (*SavedQuery, error)
+	// Update 更新名称、描述、问题、SQL、连接与缓存预热设置
+	Update(ctx context.Context, query *SavedQuery) error
+	Delete(ctx context.Context, id int64, deleteBy int64) error
+	// ListByFolder 按名称列出文件夹中的保存查询，folderID为空表示根目录
+	ListByFolder(ctx context.Context, workspaceID int64, folderID *int64, limit, offset int) ([]*SavedQuery, error)
+	// Move 将保存查询移动到另一个文件夹，folderID为空表示根目录
+	Move(ctx context.Context, id int64, folderID *int64, updateBy int64) error
+	// ListDashboardBacked 列出全部工作空间中标记为仪表盘使用且指定了连接的保存查询，用于结果缓存预热
+	ListDashboardBacked(ctx context.Context) ([]*SavedQuery, error)
+}
+
+// ErasureRepository 个人数据删除Repository接口
+type ErasureRepository interface {
+	// Create 创建删除申请，用户已有未完成的申请时返回ErrDuplicateEntry
+	Create(ctx context.Context, req *ErasureRequest) error
+	GetByID(ctx context.Context, id int64) (*ErasureRequest, error)
+	ListBySubject(ctx context.Context, subjectUserID int64) ([]*ErasureRequest, error)
+
+	// ClaimPending 将最多limit个待处理申请标记为处理中并返回，多实例部署时不会重复领取
+	ClaimPending(ctx context.Context, limit int) ([]*ErasureRequest, error)
+	Complete(ctx context.Context, id int64, report *ErasureReport) error
+	Fail(ctx context.Context, id int64, errorMessage string) error
+
+	// EraseUserData 在同一事务中匿名化或删除用户的个人数据，并将用户账号替换为假名
+	EraseUserData(ctx context.Context, userID int64, mode ErasureMode, pseudonym string) (*ErasureReport, error)
+}
+
+// QueryJobRepository 异步查询任务Repository接口
+type QueryJobRepository interface {
+	// Create 创建待执行的任务
+	Create(ctx context.Context, job *QueryJob) error
+	GetByID(ctx context.Context, id int64) (*QueryJob, error)
+
+	// ClaimPending 将最多limit个待执行任务标记为执行中并返回，多实例部署时不会重复领取
+	ClaimPending(ctx context.Context, limit int) ([]*QueryJob, error)
+	// RequeueStale 将心跳早于staleBefore的执行中任务重新排队，已领取maxAttempts次的任务标记为失败，返回处理的任务数
+	RequeueStale(ctx context.Context, staleBefore time.Time, maxAttempts int) (int64, error)
+	// UpdateProgress 更新执行中任务的已读取行数并刷新心跳
+	UpdateProgress(ctx context.Context, id int64, rowsRead int64) error
+	Complete(ctx context.Context, id int64, rowsRead int64, result *QueryJobResult) error
+	// Finish 以failed或cancelled结束任务
+	Finish(ctx context.Context, id int64, status QueryJobStatus, rowsRead int64, errorMessage string) error
+
+	// DeleteFinishedBefore 删除结束时间早于before的任务及其结果，返回删除的任务数
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// QueryScheduleRepository 保存查询定时执行计划Repository接口
+type QueryScheduleRepository interface {
+	Create(ctx context.Context, schedule *QuerySchedule) error
+	GetByID(ctx context.Context, id int64) (*QuerySchedule, error)
+	// Update 更新cron表达式、时区、投递方式、启用状态与下一次执行时间
+	Update(ctx context.Context, schedule *QuerySchedule) error
+	Delete(ctx context.Context, id int64, deleteBy int64) error
+	ListBySavedQuery(ctx context.Context, savedQueryID int64) ([]*QuerySchedule, error)
+
+	// ClaimDue 领取最多limit个下一次执行时间不晚于now的启用计划，并把其下一次执行时间推后lease，多实例部署时不会重复领取
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*QuerySchedule, error)
+	// RecordRun 保存执行记录，同时更新计划的最近执行状态与下一次执行时间（nextRun为空表示不再执行）
+	RecordRun(ctx context.Context, run *QueryScheduleRun, nextRun *time.Time) error
+	// ListRuns 按开始时间倒序列出执行记录，不含执行结果
+	ListRuns(ctx context.Context, scheduleID int64, limit int) ([]*QueryScheduleRun, error)
+	GetRun(ctx context.Context, scheduleID, runID int64) (*QueryScheduleRun, error)
+	// DeleteRunsBefore 删除结束时间早于before的执行记录，返回删除的条数
+	DeleteRunsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// HistoryKeyRepository 查询历史数据密钥Repository接口
+type HistoryKeyRepository interface {
+	// GetActive 获取工作空间生效中的数据密钥，未启用加密时返回ErrNotFound
+	GetActive(ctx context.Context, workspaceID int64) (*HistoryEncryptionKey, error)
+	GetByID(ctx context.Context, id int64) (*HistoryEncryptionKey, error)
+	ListByWorkspace(ctx context.Context, workspaceID int64) ([]*HistoryEncryptionKey, error)
+
+	// Rotate 在同一事务中停用当前密钥并创建下一版本的生效密钥，首次调用即启用加密
+	Rotate(ctx context.Context, workspaceID int64, wrappedKey string, operatorID int64) (*HistoryEncryptionKey, error)
+	// Disable 停用工作空间的生效密钥，之后写入的记录不再加密
+	Disable(ctx context.Context, workspaceID int64, operatorID int64) error
+
+	// ListStaleHistory 列出工作空间内未使用activeKeyID加密的查询历史，activeKeyID为0时列出所有加密记录
+	ListStaleHistory(ctx context.Context, workspaceID, activeKeyID int64, limit int) ([]*QueryHistory, error)
+	// UpdateHistoryText 替换查询历史的问题与SQL文本及其加密密钥
+	UpdateHistoryText(ctx context.Context, historyID int64, naturalQuery, generatedSQL string, keyID *int64) error
+	// GroupHistoryByQuestion 按问题文本分组统计自since起的查询历史，不过滤次数也不截断
+	// 加密记录的密文互不相同，每条自成一组，由调用方解密后合并
+	GroupHistoryByQuestion(ctx context.Context, since time.Time) ([]*PopularQuery, error)
+}
+
+// SchemaSnapshotRepository 表结构快照Repository接口
+type SchemaSnapshotRepository interface {
+	// Record 记录连接的表结构快照，内容与已有快照相同时返回已有快照，否则创建下一版本
+	Record(ctx context.Context, connectionID int64, schemaText string) (*SchemaSnapshot, error)
+	GetByID(ctx context.Context, id int64) (*SchemaSnapshot, error)
+	// GetLatest 获取连接版本号最大的快照，没有快照时返回ErrNotFound
+	GetLatest(ctx context.Context, connectionID int64) (*SchemaSnapshot, error)
+}
+
+// IntegrationCredentialRepository 第三方集成凭据Repository接口
+// 集成类型与名称在未删除的凭据中唯一，冲突时返回ErrDuplicateEntry
+type IntegrationCredentialRepository interface {
+	Create(ctx context.Context, credential *IntegrationCredential) error
+	GetByID(ctx context.Context, id int64) (*IntegrationCredential, error)
+	// GetByName 按集成类型与名称获取凭据，不存在时返回ErrNotFound
+	GetByName(ctx context.Context, integration, name string) (*IntegrationCredential, error)
+	// List 列出凭据，integration为空时列出所有集成的凭据
+	List(ctx context.Context, integration string) ([]*IntegrationCredential, error)
+	UpdateSettings(ctx context.Context, id int64, settings map[string]string, updateBy int64) error
+	// Rotate 替换机密并递增版本号，返回新版本号
+	Rotate(ctx context.Context, id int64, secretEncrypted, secretMask string, updateBy int64) (int, error)
+	// Delete 软删除凭据并清空加密的机密
+	Delete(ctx context.Context, id int64, deleteBy int64) error
+}
+
+// APIKeyRepository API密钥Repository接口
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByID(ctx context.Context, id int64) (*APIKey, error)
+	// GetByHash 按密钥哈希获取密钥，包括已撤销或过期的密钥，不存在时返回ErrNotFound
+	GetByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	// ListByUser 列出用户的密钥，按创建时间倒序
+	ListByUser(ctx context.Context, userID int64) ([]*APIKey, error)
+	// Revoke 撤销密钥，已撤销的密钥返回ErrNotFound
+	Revoke(ctx context.Context, id int64, revokeBy int64) error
+	UpdateLastUsed(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+// ColumnAccessRepository 列访问申请Repository接口
+type ColumnAccessRepository interface {
+	// Create 创建访问申请，同一用户对同一列已有待处理申请时返回ErrDuplicateEntry
+	Create(ctx context.Context, req *ColumnAccessRequest) error
+	GetByID(ctx context.Context, id int64) (*ColumnAccessRequest, error)
+	// ListByRequester 列出用户提交的申请，按创建时间倒序
+	ListByRequester(ctx context.Context, userID int64, limit, offset int) ([]*ColumnAccessRequest, error)
+	// ListPendingByOwner 列出所有者名下连接的待处理申请，按创建时间正序
+	ListPendingByOwner(ctx context.Context, ownerID int64, limit, offset int) ([]*ColumnAccessRequest, error)
+	// ListActiveGrants 列出用户在连接上指定时间仍生效的授权
+	ListActiveGrants(ctx context.Context, connectionID, userID int64, now time.Time) ([]*ColumnAccessRequest, error)
+	// Decide 处理待处理的申请，条件更新保证同一申请只会被处理一次；授权时expiresAt为到期时间
+	Decide(ctx context.Context, id, deciderID int64, status ColumnAccessStatus, comment *string, expiresAt *time.Time) error
+	// Revoke 提前收回授权，未授权或已到期的申请返回ErrNotFound
+	Revoke(ctx context.Context, id, revokeBy int64, comment *string) error
+	AddEvent(ctx context.Context, event *ColumnAccessEvent) error
+	// ListEvents 列出申请的审计事件，按时间正序
+	ListEvents(ctx context.Context, requestID int64) ([]*ColumnAccessEvent, error)
+}
+
+// LLMUsageRepository 模型用量Repository接口
+type LLMUsageRepository interface {
+	// Add 将一次调用的用量累加到对应日期、用户、提供商与模型的汇总行
+	Add(ctx context.Context, usage *LLMUsage) error
+	// ListByDay 列出指定日期所有用户的用量
+	ListByDay(ctx context.Context, day time.Time) ([]*LLMUsage, error)
+	// ListByUser 列出用户在[start, end]日期范围内的用量，按日期倒序
+	ListByUser(ctx context.Context, userID int64, start, end time.Time) ([]*LLMUsage, error)
+	// SetBudgetOverride 设置用户的预算上调，替换该用户之前的上调
+	SetBudgetOverride(ctx context.Context, override *LLMBudgetOverride) error
+	// ListBudgetOverrides 列出在now仍有效的预算上调
+	ListBudgetOverrides(ctx context.Context, now time.Time) ([]*LLMBudgetOverride, error)
+}
+
+// SchemaChangeRepository 表结构变更事件Repository接口
+type SchemaChangeRepository interface {
+	// CreateBatch 批量记录同一次刷新发现的变更事件
+	CreateBatch(ctx context.Context, events []*SchemaChangeEvent) error
+	// ListByConnection 列出连接自since起的变更事件，按发现时间正序
+	ListByConnection(ctx context.Context, connectionID int64, since time.Time) ([]*SchemaChangeEvent, error)
+}
+
 // FeedbackRepository 用户反馈Repository接口
 // 提供用户反馈的管理，支持统计分析和准确率监控
 type FeedbackRepository interface {
@@ -326,4 +689,5 @@ type ErrorStats struct {
 	ErrorCount   int64  `json:"error_count"`   // 错误次数
 	ExampleQuery string `json:"example_query"` // 示例查询
 	ExampleError string `json:"example_error"` // 示例错误信息
-}
\ No newline at end of file
+}
+
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,7 +25,7 @@ type User struct {
 	Username      string     `json:"username" db:"username"`               // 用户名，唯一，3-50字符
 	Email         string     `json:"email" db:"email"`                     // 邮箱地址，唯一，用于登录和通知
 	PasswordHash  string     `json:"-" db:"password_hash"`                 // 密码哈希，使用bcrypt加密，不返回给前端
-	Role          string     `json:"role" db:"role"`                       // 用户角色：user/admin/manager
+	Role          string     `json:"role" db:"role"`                       // 用户角色：viewer/user/manager/admin
 	Status        string     `json:"status" db:"status"`                   // 用户状态：active/inactive/locked
 	LastLoginTime *time.Time `json:"last_login_time" db:"last_login_time"` // 最后登录时间
 }
@@ -31,15 +34,408 @@ type User struct {
 // 记录用户的自然语言查询和AI生成的SQL语句，支持查询分析和优化
 type QueryHistory struct {
 	BaseModel
-	UserID        int64   `json:"user_id" db:"user_id"`               // 查询用户ID，外键关联users表
-	NaturalQuery  string  `json:"natural_query" db:"natural_query"`   // 用户输入的自然语言查询
-	GeneratedSQL  string  `json:"generated_sql" db:"generated_sql"`   // AI生成的SQL语句
-	SQLHash       string  `json:"sql_hash" db:"sql_hash"`             // SQL语句SHA-256哈希，用于去重和缓存
-	ExecutionTime *int32  `json:"execution_time" db:"execution_time"` // SQL执行时间，单位毫秒，可为空
-	ResultRows    *int32  `json:"result_rows" db:"result_rows"`       // 查询结果行数，可为空
-	Status        string  `json:"status" db:"status"`                 // 执行状态：pending/success/error/timeout
-	ErrorMessage  *string `json:"error_message" db:"error_message"`   // 错误信息，执行失败时记录
-	ConnectionID  *int64  `json:"connection_id" db:"connection_id"`   // 使用的数据库连接ID，可为空
+	UserID          int64    `json:"user_id" db:"user_id"`                         // 查询用户ID，外键关联users表
+	NaturalQuery    string   `json:"natural_query" db:"natural_query"`             // 用户输入的自然语言查询
+	GeneratedSQL    string   `json:"generated_sql" db:"generated_sql"`             // AI生成的SQL语句
+	SQLHash         string   `json:"sql_hash" db:"sql_hash"`                       // SQL语句SHA-256哈希，用于去重和缓存
+	ExecutionTime   *int32   `json:"execution_time" db:"execution_time"`           // SQL执行时间，单位毫秒，可为空
+	ResultRows      *int32   `json:"result_rows" db:"result_rows"`                 // 查询结果行数，可为空
+	Status          string   `json:"status" db:"status"`                           // 执行状态：pending/success/error/timeout
+	ErrorMessage    *string  `json:"error_message" db:"error_message"`             // 错误信息，执行失败时记录
+	ConnectionID    *int64   `json:"connection_id" db:"connection_id"`             // 使用的数据库连接ID，可为空
+	AIConfidence    *float64 `json:"ai_confidence,omitempty" db:"ai_confidence"`   // 生成SQL时的AI置信度(0-1)
+	ExecutionPath   *string  `json:"execution_path,omitempty" db:"execution_path"` // 执行路径：auto/confirmed/manual
+	EncryptionKeyID *int64   `json:"-" db:"encryption_key_id"`                     // 加密问题与SQL所用的数据密钥ID，为空表示明文
+
+	SchemaSnapshotID *int64  `json:"schema_snapshot_id,omitempty" db:"schema_snapshot_id"` // 生成SQL时使用的表结构快照ID，为空表示未记录
+	QuestionCategory *string `json:"question_category,omitempty" db:"question_category"`   // 问题分类，问题被哈希或丢弃后仍保留
+	CostCenter       *string `json:"cost_center,omitempty" db:"cost_center"`               // 费用归属的成本中心，为空表示未标记
+	Project          *string `json:"project,omitempty" db:"project"`                       // 费用归属的项目，为空表示未标记
+}
+
+// Workspace 工作空间
+// 团队级的配置边界，成员共享自动执行策略等设置
+type Workspace struct {
+	BaseModel
+	Name              string                `json:"name" db:"name"`                               // 工作空间名称，唯一
+	Description       *string               `json:"description" db:"description"`                 // 描述
+	AutoExecutePolicy *AutoExecutePolicy    `json:"auto_execute_policy" db:"auto_execute_policy"` // 自动执行策略，为空表示始终需要确认
+	DataResidency     *DataResidencyPolicy  `json:"data_residency" db:"data_residency"`           // 数据驻留策略，为空表示不限制存储区域
+	Defaults          *WorkspaceDefaults    `json:"defaults" db:"defaults"`                       // 请求未指定时使用的默认值，为空表示不设默认值
+	QuestionRetention string                `json:"question_retention" db:"question_retention"`   // 自然语言问题保留方式：keep/hash/drop
+	LatencyRouting    *LatencyRoutingPolicy `json:"latency_routing" db:"latency_routing"`         // 按延迟SLO路由策略，为空表示不按延迟路由
+	Chargeback        *ChargebackTaxonomy   `json:"chargeback_taxonomy" db:"chargeback_taxonomy"` // 费用归属标签取值范围，为空表示不接受标签
+}
+
+// WorkspaceDefaults 工作空间默认设置
+// 请求未携带对应字段时按工作空间默认值补全，零值表示该项未设置
+type WorkspaceDefaults struct {
+	ConnectionID *int64 `json:"connection_id,omitempty"` // 默认数据库连接
+	Locale       string `json:"locale,omitempty"`        // 默认语言：zh/en
+	RowLimit     int    `json:"row_limit,omitempty"`     // 默认最大返回行数
+}
+
+// AutoExecutePolicy 自动执行策略
+// 置信度不低于MinConfidence且预估代价不超过MaxEstimatedCost时自动执行生成的SQL
+type AutoExecutePolicy struct {
+	Enabled          bool    `json:"enabled"`
+	MinConfidence    float64 `json:"min_confidence"`     // 最低置信度(0-1)
+	MaxEstimatedCost float64 `json:"max_estimated_cost"` // EXPLAIN估算的最大总代价
+}
+
+// LatencyRoutingPolicy 按延迟SLO路由策略
+// 主模型滚动P95延迟超过PrimarySLOMs且备用模型更快时，交互请求暂时优先使用备用模型，批量与定时请求不受影响
+type LatencyRoutingPolicy struct {
+	Enabled      bool `json:"enabled"`
+	PrimarySLOMs int  `json:"primary_slo_ms"` // 主模型P95延迟目标（毫秒）
+}
+
+// ChargebackTaxonomy 费用归属标签取值范围
+// 请求携带的成本中心与项目标签必须在列表之内，Required为true时每个请求都必须携带两类标签
+type ChargebackTaxonomy struct {
+	CostCenters []string `json:"cost_centers"`
+	Projects    []string `json:"projects"`
+	Required    bool     `json:"required"`
+}
+
+// ChargebackTags 请求的费用归属标签，空字符串表示未标记
+type ChargebackTags struct {
+	CostCenter string `json:"cost_center,omitempty"`
+	Project    string `json:"project,omitempty"`
+}
+
+// DataResidencyPolicy 数据驻留策略
+// 工作空间的快照、导出与归档只能写入按用途固定、且位于Region的对象存储桶
+type DataResidencyPolicy struct {
+	Region    string                      `json:"region"`    // 工作空间数据所在区域，如eu-central-1
+	Locations map[string]*StorageLocation `json:"locations"` // 按用途固定的存储位置，键为snapshot/export/archive
+}
+
+// StorageLocation 对象存储位置
+type StorageLocation struct {
+	Bucket string `json:"bucket"`
+	Region string `json:"region"`
+	Prefix string `json:"prefix,omitempty"` // 对象键前缀
+}
+
+// WriteRequest 写操作申请
+// 开启写模式的连接上生成的INSERT/UPDATE须经过预演，并通过审批流程后才会执行
+type WriteRequest struct {
+	BaseModel
+	ConnectionID  int64         `json:"connection_id" db:"connection_id"`     // 目标数据库连接ID
+	RequestedBy   int64         `json:"requested_by" db:"requested_by"`       // 申请人ID
+	NaturalQuery  *string       `json:"natural_query" db:"natural_query"`     // 原始自然语言描述，可为空
+	SQL           string        `json:"sql" db:"sql"`                         // 待执行的写操作SQL
+	StatementType string        `json:"statement_type" db:"statement_type"`   // 语句类型：INSERT/UPDATE
+	EstimatedRows int64         `json:"estimated_rows" db:"estimated_rows"`   // 预演得到的影响行数
+	Preview       *WritePreview `json:"preview" db:"preview"`                 // 预演时受影响行的样本
+	Status        string        `json:"status" db:"status"`                   // 状态：pending/approved/rejected/expired/executed/failed
+	ApprovalID    *int64        `json:"approval_id" db:"approval_id"`         // 关联的审批申请ID
+	ReviewedBy    *int64        `json:"reviewed_by" db:"reviewed_by"`         // 审批人ID，过期时为空
+	ReviewedTime  *time.Time    `json:"reviewed_time" db:"reviewed_time"`     // 审批时间
+	ReviewComment *string       `json:"review_comment" db:"review_comment"`   // 审批意见
+	AffectedRows  *int64        `json:"affected_rows" db:"affected_rows"`     // 实际影响行数
+	ErrorMessage  *string       `json:"error_message" db:"error_message"`     // 执行失败时的错误信息
+	ExecutedTime  *time.Time    `json:"executed_time" db:"executed_time"`     // 实际执行时间
+}
+
+// WritePreview 写操作预演结果，在回滚的事务中通过RETURNING取得
+type WritePreview struct {
+	Columns   []string         `json:"columns"`
+	Rows      []map[string]any `json:"rows"`
+	Truncated bool             `json:"truncated"` // 影响行数超过样本上限
+}
+
+// ApprovalRequest 审批申请
+// 写操作、策略变更、预算调整等敏感动作先创建审批申请，由指定审批人通过后才执行，过期自动失效
+type ApprovalRequest struct {
+	BaseModel
+	ActionType      string          `json:"action_type" db:"action_type"`           // 动作类型：write_query/policy_change/budget_raise
+	ResourceID      *int64          `json:"resource_id" db:"resource_id"`           // 关联资源ID，如写操作申请ID、工作空间ID
+	Summary         string          `json:"summary" db:"summary"`                   // 供审批人阅读的摘要
+	Payload         json.RawMessage `json:"payload" db:"payload"`                   // 动作参数，由对应执行器解析
+	RequestedBy     int64           `json:"requested_by" db:"requested_by"`         // 申请人ID
+	Status          string          `json:"status" db:"status"`                     // 状态：pending/approved/rejected/expired/executed/failed
+	DecidedBy       *int64          `json:"decided_by" db:"decided_by"`             // 审批人ID，不能是申请人
+	DecidedTime     *time.Time      `json:"decided_time" db:"decided_time"`         // 审批时间
+	DecisionComment *string         `json:"decision_comment" db:"decision_comment"` // 审批意见
+	ErrorMessage    *string         `json:"error_message" db:"error_message"`       // 执行失败时的错误信息
+	ExpiresAt       time.Time       `json:"expires_at" db:"expires_at"`             // 过期时间
+}
+
+// ApprovalEvent 审批申请的审计事件
+type ApprovalEvent struct {
+	ID         int64     `json:"id" db:"id"`
+	RequestID  int64     `json:"request_id" db:"request_id"` // 关联的审批申请ID
+	ActorID    *int64    `json:"actor_id" db:"actor_id"`     // 操作人ID，系统操作（如过期）为空
+	Action     string    `json:"action" db:"action"`         // 事件：created/approved/rejected/expired/executed/failed
+	Detail     *string   `json:"detail" db:"detail"`         // 事件详情
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// ErasureRequest 个人数据删除申请（GDPR被遗忘权）
+// 后台任务按申请匿名化或删除用户的个人数据，完成后记录删除报告
+type ErasureRequest struct {
+	BaseModel
+	SubjectUserID int64          `json:"subject_user_id" db:"subject_user_id"` // 数据主体用户ID
+	Mode          string         `json:"mode" db:"mode"`                       // 处理方式：anonymize/delete
+	Status        string         `json:"status" db:"status"`                   // 状态：pending/running/completed/failed
+	RequestedBy   int64          `json:"requested_by" db:"requested_by"`       // 提交人ID，本人或管理员
+	Report        *ErasureReport `json:"report" db:"report"`                   // 删除报告，完成后填充
+	ErrorMessage  *string        `json:"error_message" db:"error_message"`     // 处理失败时的错误信息
+	CompletedTime *time.Time     `json:"completed_time" db:"completed_time"`   // 完成时间
+}
+
+// ErasureReport 删除报告，记录各类个人数据的处理条数
+type ErasureReport struct {
+	Mode                 string    `json:"mode"`
+	Pseudonym            string    `json:"pseudonym"`              // 用户账号替换后的假名，审计记录中的用户ID指向该假名账号
+	QueryHistory         int64     `json:"query_history"`          // 查询历史中的问题文本
+	WriteRequests        int64     `json:"write_requests"`         // 写操作申请中的问题文本与审批意见
+	Feedback             int64     `json:"feedback"`               // 反馈内容
+	QueryJobs            int64     `json:"query_jobs"`             // 异步查询任务的SQL与结果
+	SavedQueries         int64     `json:"saved_queries"`          // 保存查询中的问题文本与说明
+	QuerySchedules       int64     `json:"query_schedules"`        // 定时执行计划的执行结果与收件人地址
+	ColumnAccessRequests int64     `json:"column_access_requests"` // 列访问申请理由与审批意见
+	AuditEvents          int64     `json:"audit_events"`           // 审批记录中由其填写的说明
+	ConversationMemory   int64     `json:"conversation_memory"`    // 对话记忆中的历史问题
+	CompletedAt          time.Time `json:"completed_at"`
+}
+
+// QueryJob 异步查询任务
+// 后台工作池领取后执行，执行中定期更新已读取行数，结束后保存结果；服务重启时执行中断的任务重新排队
+type QueryJob struct {
+	ID             int64           `json:"id" db:"id"`
+	UserID         int64           `json:"user_id" db:"user_id"`                     // 提交任务的用户ID
+	ConnectionID   int64           `json:"connection_id" db:"connection_id"`         // 执行查询的数据库连接ID
+	QueryHistoryID *int64          `json:"query_id,omitempty" db:"query_history_id"` // 对应的查询历史记录，任务结束时回填执行结果
+	SQL            string          `json:"sql" db:"sql_text"`
+	RowLimit       int             `json:"row_limit" db:"row_limit"`     // 最多读取的行数
+	Status         string          `json:"status" db:"status"`           // 状态：pending/running/completed/failed/cancelled
+	RowsRead       int64           `json:"rows_read" db:"rows_read"`     // 已读取的行数，执行中定期更新
+	Result         *QueryJobResult `json:"result,omitempty" db:"result"` // 执行结果，完成后填充
+	ErrorMessage   *string         `json:"error_message,omitempty" db:"error_message"`
+	Attempts       int             `json:"attempts" db:"attempts"` // 领取次数，重启后重新排队的任务大于1
+	StartedTime    *time.Time      `json:"started_time,omitempty" db:"started_time"`
+	CompletedTime  *time.Time      `json:"completed_time,omitempty" db:"completed_time"`
+	CreateTime     time.Time       `json:"create_time" db:"create_time"`
+	UpdateTime     time.Time       `json:"update_time" db:"update_time"` // 执行中作为心跳，超时未更新的任务重新排队
+}
+
+// QueryJobResult 异步查询任务的执行结果
+type QueryJobResult struct {
+	Columns       []string         `json:"columns"`
+	Rows          []map[string]any `json:"rows"`
+	Truncated     bool             `json:"truncated"`      // 因达到行数上限停止读取
+	ExecutionTime int32            `json:"execution_time"` // 执行时间(毫秒)
+}
+
+// QuerySchedule 保存查询的定时执行计划
+// 按cron表达式在指定时区定时执行保存查询，以保存查询所有者的身份执行，结果保存在执行记录中并按投递方式发送
+type QuerySchedule struct {
+	BaseModel
+	SavedQueryID   int64      `json:"saved_query_id" db:"saved_query_id"`             // 执行的保存查询ID
+	WorkspaceID    int64      `json:"workspace_id" db:"workspace_id"`                 // 所属工作空间ID，与保存查询一致
+	CronExpr       string     `json:"cron" db:"cron_expr"`                            // 五段式cron表达式
+	Timezone       string     `json:"timezone" db:"timezone"`                         // 解释cron表达式的时区，如Asia/Shanghai
+	DeliveryType   string     `json:"delivery_type" db:"delivery_type"`               // 投递方式：none/email/webhook
+	DeliveryTarget *string    `json:"delivery_target,omitempty" db:"delivery_target"` // 逗号分隔的收件人地址或Webhook URL
+	Enabled        bool       `json:"enabled" db:"enabled"`
+	NextRunTime    *time.Time `json:"next_run_time,omitempty" db:"next_run_time"` // 下一次执行时间，停用或永不触发时为空
+	LastRunTime    *time.Time `json:"last_run_time,omitempty" db:"last_run_time"`
+	LastStatus     *string    `json:"last_status,omitempty" db:"last_status"` // 最近一次执行的状态：success/failed
+}
+
+// QueryScheduleRun 定时执行记录
+type QueryScheduleRun struct {
+	ID             int64           `json:"id" db:"id"`
+	ScheduleID     int64           `json:"schedule_id" db:"schedule_id"`
+	Status         string          `json:"status" db:"status"`           // 执行状态：success/failed
+	RowCount       int64           `json:"row_count" db:"row_count"`     // 查询返回的行数
+	Result         *QueryJobResult `json:"result,omitempty" db:"result"` // 执行结果，列表接口不返回
+	ErrorMessage   *string         `json:"error_message,omitempty" db:"error_message"`
+	DeliveryStatus string          `json:"delivery_status" db:"delivery_status"` // 投递状态：skipped/sent/failed
+	DeliveryError  *string         `json:"delivery_error,omitempty" db:"delivery_error"`
+	StartedTime    time.Time       `json:"started_time" db:"started_time"`
+	CompletedTime  time.Time       `json:"completed_time" db:"completed_time"`
+}
+
+// HistoryEncryptionKey 查询历史数据密钥
+// 每个工作空间一个生效版本，密钥本身由主密钥加密后存储，轮换后旧版本保留用于解密
+type HistoryEncryptionKey struct {
+	BaseModel
+	WorkspaceID int64  `json:"workspace_id" db:"workspace_id"` // 所属工作空间ID
+	Version     int    `json:"version" db:"version"`           // 密钥版本，从1开始递增
+	WrappedKey  string `json:"-" db:"wrapped_key"`             // 主密钥加密后的数据密钥（base64）
+	Status      string `json:"status" db:"status"`             // 状态：active/retired
+}
+
+// IntegrationType 第三方集成类型
+type IntegrationType string
+
+const (
+	IntegrationSlack   IntegrationType = "slack"
+	IntegrationJira    IntegrationType = "jira"
+	IntegrationSMTP    IntegrationType = "smtp"
+	IntegrationWebhook IntegrationType = "webhook"
+	IntegrationTeams   IntegrationType = "teams"
+)
+
+// IntegrationCredential 第三方集成凭据
+// 令牌、密码等机密与连接密码使用同一密钥加密保存，API只返回掩码；非机密配置（地址、用户名等）明文保存在Settings中
+type IntegrationCredential struct {
+	BaseModel
+	Integration     string            `json:"integration" db:"integration"` // 集成类型：slack/jira/smtp/webhook/teams
+	Name            string            `json:"name" db:"name"`               // 同一集成下的凭据名称，如default
+	Settings        map[string]string `json:"settings" db:"settings"`       // 非机密配置，如Jira地址与用户名、SMTP主机与端口
+	SecretEncrypted string            `json:"-" db:"secret_encrypted"`      // 加密存储的机密，不返回给前端
+	SecretMask      string            `json:"secret_mask" db:"secret_mask"` // 机密的掩码，只保留末尾4个字符
+	Version         int               `json:"version" db:"version"`         // 机密版本，创建时为1，每次轮换递增
+	RotatedAt       *time.Time        `json:"rotated_at" db:"rotated_at"`   // 最近一次轮换时间，未轮换过为空
+}
+
+// APIKeyScope API密钥可调用的接口范围
+type APIKeyScope string
+
+const (
+	APIKeyScopeSQL APIKeyScope = "sql" // SQL执行、查询历史与验证接口
+	APIKeyScopeAI  APIKeyScope = "ai"  // 自然语言生成SQL接口
+
+	// APIKeyScopeMCP MCP端点的路由范围，不能单独授予；密钥拥有任一mcp:*工具范围即可调用MCP端点
+	APIKeyScopeMCP APIKeyScope = "mcp"
+
+	APIKeyScopeMCPConnectionsRead APIKeyScope = "mcp:connections:read" // MCP列出数据库连接
+	APIKeyScopeMCPSchemaRead      APIKeyScope = "mcp:schema:read"      // MCP读取表结构
+	APIKeyScopeMCPSQLGenerate     APIKeyScope = "mcp:sql:generate"     // MCP生成SQL
+	APIKeyScopeMCPSQLExecute      APIKeyScope = "mcp:sql:execute"      // MCP执行只读SQL
+)
+
+// IsValid 检查接口范围是否可以授予密钥
+func (s APIKeyScope) IsValid() bool {
+	switch s {
+	case APIKeyScopeSQL, APIKeyScopeAI,
+		APIKeyScopeMCPConnectionsRead, APIKeyScopeMCPSchemaRead, APIKeyScopeMCPSQLGenerate, APIKeyScopeMCPSQLExecute:
+		return true
+	}
+	return false
+}
+
+// APIKey 供CI任务与BI工具使用的API密钥
+// 只保存密钥的SHA-256哈希，明文仅在创建时返回一次；通过密钥认证的请求以所属用户的身份和角色执行
+type APIKey struct {
+	BaseModel
+	UserID     int64         `json:"user_id" db:"user_id"`           // 所属用户ID
+	Name       string        `json:"name" db:"name"`                 // 密钥名称，如ci-nightly
+	KeyPrefix  string        `json:"key_prefix" db:"key_prefix"`     // 密钥前缀，用于识别密钥
+	KeyHash    string        `json:"-" db:"key_hash"`                // 密钥的SHA-256哈希，不返回给前端
+	Scopes     []APIKeyScope `json:"scopes" db:"scopes"`             // 可调用的接口范围
+	ExpiresAt  *time.Time    `json:"expires_at" db:"expires_at"`     // 过期时间，为空表示不过期
+	LastUsedAt *time.Time    `json:"last_used_at" db:"last_used_at"` // 最近使用时间
+	RevokedAt  *time.Time    `json:"revoked_at" db:"revoked_at"`     // 撤销时间，撤销后立即失效
+}
+
+// HasScope 检查密钥是否允许调用指定范围的接口
+// 范围族（如mcp）在密钥拥有该族任一细分范围（如mcp:schema:read）时允许，具体工具由细分范围限制
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || strings.HasPrefix(string(s), string(scope)+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive 检查密钥在指定时间是否可用
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// ColumnAccessRequest 列访问申请
+// 用户申请查看被分级策略脱敏或过滤的列，由连接所有者授予有期限的例外；授权到期后恢复默认策略
+type ColumnAccessRequest struct {
+	BaseModel
+	ConnectionID    int64      `json:"connection_id" db:"connection_id"`       // 关联的数据库连接ID
+	SchemaName      string     `json:"schema_name" db:"schema_name"`           // 模式名
+	TableName       string     `json:"table_name" db:"table_name"`             // 表名
+	ColumnName      string     `json:"column_name" db:"column_name"`           // 列名
+	Classification  string     `json:"classification" db:"classification"`     // 申请时列的分级
+	RequestedBy     int64      `json:"requested_by" db:"requested_by"`         // 申请人ID
+	Reason          string     `json:"reason" db:"reason"`                     // 申请理由
+	Status          string     `json:"status" db:"status"`                     // 状态：pending/granted/rejected/revoked
+	DecidedBy       *int64     `json:"decided_by" db:"decided_by"`             // 处理人ID，即连接所有者
+	DecidedTime     *time.Time `json:"decided_time" db:"decided_time"`         // 处理时间
+	DecisionComment *string    `json:"decision_comment" db:"decision_comment"` // 处理意见
+	ExpiresAt       *time.Time `json:"expires_at" db:"expires_at"`             // 授权到期时间
+}
+
+// IsActive 检查授权在指定时间是否生效
+func (r *ColumnAccessRequest) IsActive(now time.Time) bool {
+	return r.Status == string(ColumnAccessGranted) && r.ExpiresAt != nil && now.Before(*r.ExpiresAt)
+}
+
+// ColumnAccessEvent 列访问申请的审计事件
+type ColumnAccessEvent struct {
+	ID         int64     `json:"id" db:"id"`
+	RequestID  int64     `json:"request_id" db:"request_id"` // 关联的访问申请ID
+	ActorID    *int64    `json:"actor_id" db:"actor_id"`     // 操作人ID
+	Action     string    `json:"action" db:"action"`         // 事件：created/granted/rejected/revoked
+	Detail     *string   `json:"detail" db:"detail"`         // 事件详情
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// LLMUsage 模型用量日汇总
+// 按天、用户、提供商与模型累计Token用量与估算成本
+type LLMUsage struct {
+	Day          time.Time `json:"day" db:"day"`                     // 统计日期
+	UserID       int64     `json:"user_id" db:"user_id"`             // 调用用户ID
+	Provider     string    `json:"provider" db:"provider"`           // 模型提供商
+	Model        string    `json:"model" db:"model"`                 // 模型名称
+	Queries      int64     `json:"queries" db:"queries"`             // 调用次数
+	InputTokens  int64     `json:"input_tokens" db:"input_tokens"`   // 输入Token数
+	OutputTokens int64     `json:"output_tokens" db:"output_tokens"` // 输出Token数
+	Cost         float64   `json:"cost" db:"cost"`                   // 估算成本（美元）
+	UpdateTime   time.Time `json:"update_time" db:"update_time"`
+}
+
+// LLMBudgetOverride 每用户模型预算上调，有效期内替代全局每用户每日上限
+type LLMBudgetOverride struct {
+	UserID     int64     `json:"user_id" db:"user_id"`
+	DailyLimit float64   `json:"daily_limit" db:"daily_limit"` // 上调后的每日上限（美元）
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	ApprovalID *int64    `json:"approval_id,omitempty" db:"approval_id"` // 批准此次上调的审批申请
+	UpdateTime time.Time `json:"update_time" db:"update_time"`
+}
+
+// SchemaChangeType 表结构变更类型
+type SchemaChangeType string
+
+const (
+	SchemaChangeAddedTable    SchemaChangeType = "added_table"
+	SchemaChangeRemovedTable  SchemaChangeType = "removed_table"
+	SchemaChangeAddedColumn   SchemaChangeType = "added_column"
+	SchemaChangeRemovedColumn SchemaChangeType = "removed_column"
+	SchemaChangeChangedColumn SchemaChangeType = "changed_column" // 类型、可空、键、默认值或注释变化
+)
+
+// SchemaChangeEvent 表结构变更事件
+// 表结构刷新发现变化时按表或列逐条记录，写入后不再修改
+type SchemaChangeEvent struct {
+	ID           int64            `json:"id" db:"id"`
+	ConnectionID int64            `json:"connection_id" db:"connection_id"`
+	ChangeType   SchemaChangeType `json:"change_type" db:"change_type"`
+	ObjectName   string           `json:"object_name" db:"object_name"` // 表为schema.table，列为schema.table.column
+	DetectedAt   time.Time        `json:"detected_at" db:"detected_at"` // 刷新任务发现变化的时间，实际变更发生在上一轮刷新之后
+}
+
+// SchemaSnapshot 表结构快照
+// 生成SQL时提示词中使用的表结构，写入后不再修改；同一连接内容相同的表结构只保存一次
+type SchemaSnapshot struct {
+	ID           int64     `json:"id" db:"id"`
+	ConnectionID int64     `json:"connection_id" db:"connection_id"` // 所属数据库连接ID
+	Version      int       `json:"version" db:"version"`             // 连接内的版本号，从1开始按首次出现顺序递增
+	SchemaHash   string    `json:"schema_hash" db:"schema_hash"`     // 表结构文本的SHA-256
+	Schema       string    `json:"schema" db:"schema_text"`          // 提示词中的表结构文本
+	CreateTime   time.Time `json:"create_time" db:"create_time"`     // 首次记录时间
 }
 
 // DatabaseConnection 数据库连接配置
@@ -56,6 +452,65 @@ type DatabaseConnection struct {
 	DBType            string     `json:"db_type" db:"db_type"`                       // 数据库类型：postgresql/mysql/sqlite/oracle
 	Status            string     `json:"status" db:"status"`                         // 连接状态：active/inactive/error
 	LastTested        *time.Time `json:"last_tested" db:"last_tested"`               // 最后测试连接时间
+	WriteModeEnabled  bool       `json:"write_mode_enabled" db:"write_mode_enabled"` // 是否允许经审批的写操作，默认只读
+	MaxRows           *int32     `json:"max_rows,omitempty" db:"max_rows"`           // 查询结果行数上限，为空时使用全局配置
+	MaxResultMB       *int32     `json:"max_result_mb,omitempty" db:"max_result_mb"` // 查询结果大小上限(MB)，为空时使用全局配置
+}
+
+// ColumnClassification 列数据分级标签
+// 在数据字典中标注敏感列，策略按分级与用户角色推导默认的脱敏与过滤规则
+type ColumnClassification struct {
+	BaseModel
+	ConnectionID   int64  `json:"connection_id" db:"connection_id"`   // 关联的数据库连接ID
+	SchemaName     string `json:"schema_name" db:"schema_name"`       // 模式名
+	TableName      string `json:"table_name" db:"table_name"`         // 表名
+	ColumnName     string `json:"column_name" db:"column_name"`       // 列名
+	Classification string `json:"classification" db:"classification"` // 分级：pii/financial/internal
+}
+
+// QueryFolder 保存查询的文件夹
+// 同一工作空间内按层级组织，Path记录从根到自身的ID路径（如/1/5/9/），用于子树移动与面包屑
+type QueryFolder struct {
+	BaseModel
+	WorkspaceID int64  `json:"workspace_id" db:"workspace_id"` // 所属工作空间ID
+	ParentID    *int64 `json:"parent_id" db:"parent_id"`       // 父文件夹ID，为空表示位于根目录
+	Name        string `json:"name" db:"name"`                 // 文件夹名称，同一父文件夹下唯一
+	Path        string `json:"path" db:"path"`                 // 从根到自身的ID路径
+}
+
+// AncestorIDs 从根到自身的文件夹ID，最后一个为自身
+func (f *QueryFolder) AncestorIDs() []int64 {
+	var ids []int64
+	for _, part := range strings.Split(strings.Trim(f.Path, "/"), "/") {
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// FolderPermission 文件夹权限授予，向下继承到子文件夹与其中的保存查询
+type FolderPermission struct {
+	FolderID   int64     `json:"folder_id" db:"folder_id"`     // 文件夹ID
+	UserID     int64     `json:"user_id" db:"user_id"`         // 被授权用户ID
+	Level      string    `json:"level" db:"level"`             // 权限级别：view/edit/manage
+	CreateBy   int64     `json:"create_by" db:"create_by"`     // 授权人ID
+	CreateTime time.Time `json:"create_time" db:"create_time"` // 授权时间
+}
+
+// SavedQuery 保存的查询或报表
+type SavedQuery struct {
+	BaseModel
+	WorkspaceID     int64   `json:"workspace_id" db:"workspace_id"`           // 所属工作空间ID
+	FolderID        *int64  `json:"folder_id" db:"folder_id"`                 // 所在文件夹ID，为空表示位于根目录
+	OwnerID         int64   `json:"owner_id" db:"owner_id"`                   // 所有者ID
+	Name            string  `json:"name" db:"name"`                           // 名称
+	Description     *string `json:"description" db:"description"`             // 描述
+	NaturalQuery    string  `json:"natural_query" db:"natural_query"`         // 自然语言问题
+	SQL             string  `json:"sql" db:"sql_text"`                        // 保存的SQL
+	ConnectionID    *int64  `json:"connection_id" db:"connection_id"`         // 使用的数据库连接ID
+	DashboardBacked bool    `json:"dashboard_backed" db:"dashboard_backed"`   // 是否为仪表盘使用的查询，开启后定期预热结果缓存
+	CacheTTLSeconds *int32  `json:"cache_ttl_seconds" db:"cache_ttl_seconds"` // 结果缓存时间（秒），为空时使用全局配置
 }
 
 // SchemaMetadata 数据库表结构元数据
@@ -82,7 +537,8 @@ type SchemaMetadata struct {
 type UserRole string
 
 const (
-	RoleUser    UserRole = "user"    // 普通用户：可以执行查询，管理自己的连接
+	RoleViewer  UserRole = "viewer"  // 只读用户：可以生成SQL，只能在工作空间共享的连接上执行只读查询
+	RoleUser    UserRole = "user"    // 普通用户（编辑者）：可以执行查询，管理自己的连接与保存查询
 	RoleManager UserRole = "manager" // 管理员：可以查看团队查询历史，管理团队连接
 	RoleAdmin   UserRole = "admin"   // 系统管理员：完全访问权限
 )
@@ -104,8 +560,212 @@ const (
 	QuerySuccess QueryStatus = "success" // 执行成功
 	QueryError   QueryStatus = "error"   // 执行失败
 	QueryTimeout QueryStatus = "timeout" // 执行超时
+
+	QueryCancelled QueryStatus = "cancelled" // 执行中被用户取消
+)
+
+// ExecutionPath 查询执行路径枚举，用于事后分析自动执行的准确率
+type ExecutionPath string
+
+const (
+	ExecutionAuto      ExecutionPath = "auto"      // 满足工作空间策略，自动执行
+	ExecutionConfirmed ExecutionPath = "confirmed" // 用户确认后执行
+	ExecutionManual    ExecutionPath = "manual"    // 用户直接提交执行
+)
+
+// QuestionRetention 自然语言问题保留方式枚举
+type QuestionRetention string
+
+const (
+	QuestionRetentionKeep QuestionRetention = "keep" // 保留问题原文
+	QuestionRetentionHash QuestionRetention = "hash" // 只保存问题的SHA-256，可统计重复问题但无法还原
+	QuestionRetentionDrop QuestionRetention = "drop" // 不保存问题
+)
+
+// IsValid 检查问题保留方式是否有效
+func (m QuestionRetention) IsValid() bool {
+	return m == QuestionRetentionKeep || m == QuestionRetentionHash || m == QuestionRetentionDrop
+}
+
+// QuestionHashPrefix 哈希后问题的前缀，完整格式为 sha256:<十六进制摘要>
+const QuestionHashPrefix = "sha256:"
+
+// IsAnonymizedQuestion 问题是否已被哈希或丢弃，这类记录无法按问题回放或搜索
+func IsAnonymizedQuestion(question string) bool {
+	return question == "" || strings.HasPrefix(question, QuestionHashPrefix)
+}
+
+// WriteRequestStatus 写操作申请状态枚举
+type WriteRequestStatus string
+
+const (
+	WriteRequestPending  WriteRequestStatus = "pending"  // 等待审批
+	WriteRequestApproved WriteRequestStatus = "approved" // 已审批，正在执行
+	WriteRequestRejected WriteRequestStatus = "rejected" // 已驳回
+	WriteRequestExpired  WriteRequestStatus = "expired"  // 审批过期
+	WriteRequestExecuted WriteRequestStatus = "executed" // 审批通过并执行成功
+	WriteRequestFailed   WriteRequestStatus = "failed"   // 审批通过但执行失败
 )
 
+// ApprovalActionType 需要审批的动作类型枚举
+type ApprovalActionType string
+
+const (
+	ApprovalWriteQuery   ApprovalActionType = "write_query"   // 写模式连接上的INSERT/UPDATE
+	ApprovalPolicyChange ApprovalActionType = "policy_change" // 工作空间策略变更
+	ApprovalBudgetRaise  ApprovalActionType = "budget_raise"  // 预算上调
+)
+
+// ApprovalStatus 审批申请状态枚举
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"  // 等待审批
+	ApprovalApproved ApprovalStatus = "approved" // 已通过，正在执行
+	ApprovalRejected ApprovalStatus = "rejected" // 已驳回
+	ApprovalExpired  ApprovalStatus = "expired"  // 超过有效期未处理
+	ApprovalExecuted ApprovalStatus = "executed" // 通过并执行成功
+	ApprovalFailed   ApprovalStatus = "failed"   // 通过但执行失败
+)
+
+// ApprovalEventCreated 审批申请创建事件，其余事件与状态同名
+const ApprovalEventCreated = "created"
+
+// StoragePurpose 对象存储用途枚举
+type StoragePurpose string
+
+const (
+	StorageSnapshot StoragePurpose = "snapshot" // 查询结果快照
+	StorageExport   StoragePurpose = "export"   // 结果导出文件
+	StorageArchive  StoragePurpose = "archive"  // 历史数据归档
+)
+
+// IsValid 检查存储用途是否有效
+func (p StoragePurpose) IsValid() bool {
+	return p == StorageSnapshot || p == StorageExport || p == StorageArchive
+}
+
+// ErasureMode 个人数据删除方式枚举
+type ErasureMode string
+
+const (
+	ErasureAnonymize ErasureMode = "anonymize" // 清除文本，保留记录用于统计
+	ErasureDelete    ErasureMode = "delete"    // 删除记录
+)
+
+// ErasureStatus 个人数据删除申请状态枚举
+type ErasureStatus string
+
+const (
+	ErasurePending   ErasureStatus = "pending"   // 待处理
+	ErasureRunning   ErasureStatus = "running"   // 处理中
+	ErasureCompleted ErasureStatus = "completed" // 已完成
+	ErasureFailed    ErasureStatus = "failed"    // 处理失败
+)
+
+// QueryJobStatus 异步查询任务状态枚举
+type QueryJobStatus string
+
+const (
+	QueryJobPending   QueryJobStatus = "pending"   // 等待领取
+	QueryJobRunning   QueryJobStatus = "running"   // 执行中
+	QueryJobCompleted QueryJobStatus = "completed" // 执行成功，结果已保存
+	QueryJobFailed    QueryJobStatus = "failed"    // 执行失败或超时
+	QueryJobCancelled QueryJobStatus = "cancelled" // 被用户取消
+)
+
+// IsFinished 任务是否已结束
+func (s QueryJobStatus) IsFinished() bool {
+	return s == QueryJobCompleted || s == QueryJobFailed || s == QueryJobCancelled
+}
+
+// ScheduleDeliveryType 定时执行结果的投递方式
+type ScheduleDeliveryType string
+
+const (
+	ScheduleDeliveryNone    ScheduleDeliveryType = "none"    // 只保存执行记录
+	ScheduleDeliveryEmail   ScheduleDeliveryType = "email"   // 以CSV附件发送邮件
+	ScheduleDeliveryWebhook ScheduleDeliveryType = "webhook" // 以JSON POST到Webhook
+)
+
+// ScheduleRunStatus 定时执行状态枚举
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunSuccess ScheduleRunStatus = "success"
+	ScheduleRunFailed  ScheduleRunStatus = "failed"
+)
+
+// ScheduleDeliveryStatus 定时执行结果的投递状态枚举
+type ScheduleDeliveryStatus string
+
+const (
+	ScheduleDeliverySkipped ScheduleDeliveryStatus = "skipped" // 未配置投递
+	ScheduleDeliverySent    ScheduleDeliveryStatus = "sent"
+	ScheduleDeliveryFailed  ScheduleDeliveryStatus = "failed"
+)
+
+// HistoryKeyStatus 查询历史数据密钥状态枚举
+type HistoryKeyStatus string
+
+const (
+	HistoryKeyActive  HistoryKeyStatus = "active"  // 生效中，新记录使用该密钥加密
+	HistoryKeyRetired HistoryKeyStatus = "retired" // 已轮换，仅用于解密
+)
+
+// DataClassification 列数据分级枚举
+type DataClassification string
+
+const (
+	ClassificationPII       DataClassification = "pii"       // 个人身份信息：姓名、邮箱、手机号等
+	ClassificationFinancial DataClassification = "financial" // 财务数据：金额、账户、薪资等
+	ClassificationInternal  DataClassification = "internal"  // 内部数据：仅限内部用户查看
+)
+
+// IsValid 检查分级是否有效
+func (c DataClassification) IsValid() bool {
+	return c == ClassificationPII || c == ClassificationFinancial || c == ClassificationInternal
+}
+
+// ColumnAccessStatus 列访问申请状态枚举
+type ColumnAccessStatus string
+
+const (
+	ColumnAccessPending  ColumnAccessStatus = "pending"  // 等待连接所有者处理
+	ColumnAccessGranted  ColumnAccessStatus = "granted"  // 已授权，到期前生效
+	ColumnAccessRejected ColumnAccessStatus = "rejected" // 已驳回
+	ColumnAccessRevoked  ColumnAccessStatus = "revoked"  // 授权被提前收回
+)
+
+// ColumnAccessEventCreated 列访问申请创建事件，其余事件与状态同名
+const ColumnAccessEventCreated = "created"
+
+// FolderPermissionLevel 文件夹权限级别枚举，级别高的包含级别低的权限
+type FolderPermissionLevel string
+
+const (
+	FolderNone   FolderPermissionLevel = ""       // 无权访问
+	FolderView   FolderPermissionLevel = "view"   // 查看文件夹内容与保存查询
+	FolderEdit   FolderPermissionLevel = "edit"   // 创建、修改、移入移出保存查询与子文件夹
+	FolderManage FolderPermissionLevel = "manage" // 重命名、移动、删除文件夹并管理授权
+)
+
+// folderPermissionRank 权限级别的高低
+var folderPermissionRank = map[FolderPermissionLevel]int{FolderNone: 0, FolderView: 1, FolderEdit: 2, FolderManage: 3}
+
+// IsValid 检查权限级别是否有效
+func (l FolderPermissionLevel) IsValid() bool {
+	return l == FolderView || l == FolderEdit || l == FolderManage
+}
+
+// Allows 检查当前级别是否满足所需级别
+func (l FolderPermissionLevel) Allows(required FolderPermissionLevel) bool {
+	return folderPermissionRank[l] >= folderPermissionRank[required]
+}
+
+// DefaultWorkspaceID 默认工作空间ID，未加入任何工作空间的用户归属于此
+const DefaultWorkspaceID int64 = 1
+
 // ConnectionStatus 连接状态枚举
 type ConnectionStatus string
 
@@ -127,7 +787,7 @@ const (
 
 // IsValidRole 验证用户角色是否有效
 func (r UserRole) IsValid() bool {
-	return r == RoleUser || r == RoleManager || r == RoleAdmin
+	return r == RoleViewer || r == RoleUser || r == RoleManager || r == RoleAdmin
 }
 
 // IsValidStatus 验证用户状态是否有效
@@ -137,7 +797,7 @@ func (s UserStatus) IsValid() bool {
 
 // IsValidQueryStatus 验证查询状态是否有效
 func (s QueryStatus) IsValid() bool {
-	return s == QueryPending || s == QuerySuccess || s == QueryError || s == QueryTimeout
+	return s == QueryPending || s == QuerySuccess || s == QueryError || s == QueryTimeout || s == QueryCancelled
 }
 
 // IsValidConnectionStatus 验证连接状态是否有效
@@ -150,27 +810,9 @@ func (t DatabaseType) IsValid() bool {
 	return t == DBTypePostgreSQL || t == DBTypeMySQL || t == DBTypeSQLite || t == DBTypeOracle
 }
 
-// HasPermission 检查用户是否有指定权限
-// 基于角色的权限检查，支持层级权限管理
+// HasPermission 检查用户是否有指定权限，权限矩阵见RolePermissions
 func (u *User) HasPermission(permission string) bool {
-	// 管理员拥有所有权限
-	if u.Role == string(RoleAdmin) {
-		return true
-	}
-	
-	// 根据不同权限类型进行检查
-	switch permission {
-	case "query:execute":
-		return u.Role == string(RoleUser) || u.Role == string(RoleManager)
-	case "connection:manage":
-		return u.Role == string(RoleUser) || u.Role == string(RoleManager)
-	case "history:view_team":
-		return u.Role == string(RoleManager)
-	case "user:manage":
-		return u.Role == string(RoleAdmin)
-	default:
-		return false
-	}
+	return UserRole(u.Role).Can(Permission(permission))
 }
 
 // IsActive 检查用户是否处于活跃状态
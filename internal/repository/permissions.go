@@ -0,0 +1,74 @@
+package repository
+
+import "sort"
+
+// Permission 操作权限
+type Permission string
+
+const (
+	PermProfileRead        Permission = "profile:read"         // 查看自己的资料
+	PermProfileUpdate      Permission = "profile:update"       // 修改自己的资料与密码
+	PermQueryGenerate      Permission = "query:generate"       // 由自然语言生成SQL
+	PermQueryExecute       Permission = "query:execute"        // 在自己的连接上执行查询
+	PermQueryExecuteShared Permission = "query:execute_shared" // 在工作空间共享的默认连接上执行AI生成的只读查询
+	PermQueryWrite         Permission = "query:write"          // 提交写操作申请
+	PermConnectionManage   Permission = "connection:manage"    // 创建、修改、删除自己的连接
+	PermSavedQueryEdit     Permission = "saved_query:edit"     // 创建与修改文件夹、保存查询
+	PermEmbedTokenIssue    Permission = "embed:issue"          // 签发嵌入令牌
+	PermHistoryViewTeam    Permission = "history:view_team"    // 查看团队查询历史
+	PermConnectionViewTeam Permission = "connection:view_team" // 查看团队连接
+	PermUserManage         Permission = "user:manage"          // 管理用户角色与状态
+	PermWriteApprove       Permission = "write:approve"        // 查看全部写操作与审批申请，未指定审批人时审批
+)
+
+// RolePermissions 角色权限矩阵，admin拥有全部权限不在此列出
+// viewer只能生成SQL并在共享连接上执行生成的只读查询；user即编辑者，可以管理自己的连接与保存查询
+var RolePermissions = map[UserRole][]Permission{
+	RoleViewer: {
+		PermProfileRead, PermProfileUpdate,
+		PermQueryGenerate, PermQueryExecuteShared,
+	},
+	RoleUser: {
+		PermProfileRead, PermProfileUpdate,
+		PermQueryGenerate, PermQueryExecute, PermQueryExecuteShared, PermQueryWrite,
+		PermConnectionManage, PermSavedQueryEdit, PermEmbedTokenIssue,
+	},
+	RoleManager: {
+		PermProfileRead, PermProfileUpdate,
+		PermQueryGenerate, PermQueryExecute, PermQueryExecuteShared, PermQueryWrite,
+		PermConnectionManage, PermSavedQueryEdit, PermEmbedTokenIssue,
+		PermHistoryViewTeam, PermConnectionViewTeam, PermWriteApprove,
+	},
+}
+
+// allPermissions 全部权限，即admin的权限
+var allPermissions = []Permission{
+	PermProfileRead, PermProfileUpdate,
+	PermQueryGenerate, PermQueryExecute, PermQueryExecuteShared, PermQueryWrite,
+	PermConnectionManage, PermSavedQueryEdit, PermEmbedTokenIssue,
+	PermHistoryViewTeam, PermConnectionViewTeam, PermWriteApprove, PermUserManage,
+}
+
+// Can 判断角色是否具有指定权限，未知角色没有任何权限
+func (r UserRole) Can(permission Permission) bool {
+	if r == RoleAdmin {
+		return true
+	}
+	for _, p := range RolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions 返回角色拥有的全部权限，按名称排序
+func (r UserRole) Permissions() []Permission {
+	source := RolePermissions[r]
+	if r == RoleAdmin {
+		source = allPermissions
+	}
+	permissions := append([]Permission(nil), source...)
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+	return permissions
+}
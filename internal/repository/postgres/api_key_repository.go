@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// apiKeyQuerier 连接池与事务的公共查询接口
+type apiKeyQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// apiKeyColumns 查询密钥时的列顺序，与scanAPIKey一致
+const apiKeyColumns = `id, user_id, name, key_prefix, key_hash, scopes, expires_at, last_used_at, revoked_at,
+	create_by, create_time, update_by, update_time, is_deleted`
+
+// PostgreSQLAPIKeyRepository PostgreSQL API密钥Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLAPIKeyRepository struct {
+	db     apiKeyQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLAPIKeyRepository 创建API密钥Repository实例
+func NewPostgreSQLAPIKeyRepository(pool DB, logger *zap.Logger) repository.APIKeyRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLAPIKeyRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+// Create 创建密钥
+func (r *PostgreSQLAPIKeyRepository) Create(ctx context.Context, key *repository.APIKey) error {
+	const sqlQuery = `
+		INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scopes, expires_at,
+			create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $7, $8, false)
+		RETURNING id`
+
+	now := time.Now().UTC()
+	createBy := key.UserID
+	if key.CreateBy != nil {
+		createBy = *key.CreateBy
+	}
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		key.UserID,
+		key.Name,
+		key.KeyPrefix,
+		key.KeyHash,
+		scopeStrings(key.Scopes),
+		key.ExpiresAt,
+		createBy,
+		now,
+	).Scan(&key.ID)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("API密钥已存在: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("创建API密钥失败",
+			zap.Int64("user_id", key.UserID),
+			zap.String("name", key.Name),
+			zap.Error(err))
+		return fmt.Errorf("创建API密钥失败: %w", err)
+	}
+
+	key.CreateBy = &createBy
+	key.UpdateBy = &createBy
+	key.CreateTime = now
+	key.UpdateTime = now
+	return nil
+}
+
+// GetByID 根据ID获取密钥
+func (r *PostgreSQLAPIKeyRepository) GetByID(ctx context.Context, id int64) (*repository.APIKey, error) {
+	sqlQuery := `SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE id = $1 AND is_deleted = false`
+
+	return r.get(ctx, sqlQuery, id)
+}
+
+// GetByHash 按密钥哈希获取密钥，是否已撤销或过期由调用方判断
+func (r *PostgreSQLAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*repository.APIKey, error) {
+	sqlQuery := `SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE key_hash = $1 AND is_deleted = false`
+
+	return r.get(ctx, sqlQuery, keyHash)
+}
+
+// ListByUser 列出用户的密钥，按创建时间倒序
+func (r *PostgreSQLAPIKeyRepository) ListByUser(ctx context.Context, userID int64) ([]*repository.APIKey, error) {
+	sqlQuery := `SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1 AND is_deleted = false
+		ORDER BY create_time DESC, id DESC`
+
+	rows, err := r.db.Query(ctx, sqlQuery, userID)
+	if err != nil {
+		r.logger.Error("查询API密钥失败", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, fmt.Errorf("查询API密钥失败: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*repository.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描API密钥失败: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke 撤销密钥，已撤销的密钥返回ErrNotFound
+func (r *PostgreSQLAPIKeyRepository) Revoke(ctx context.Context, id int64, revokeBy int64) error {
+	const sqlQuery = `
+		UPDATE api_keys
+		SET revoked_at = $3, update_by = $2, update_time = $3
+		WHERE id = $1 AND revoked_at IS NULL AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, revokeBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("撤销API密钥失败", zap.Int64("api_key_id", id), zap.Error(err))
+		return fmt.Errorf("撤销API密钥失败: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("API密钥不存在或已撤销: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// UpdateLastUsed 记录最近使用时间，不修改update_time
+func (r *PostgreSQLAPIKeyRepository) UpdateLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	const sqlQuery = `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+
+	if _, err := r.db.Exec(ctx, sqlQuery, id, usedAt); err != nil {
+		return fmt.Errorf("更新API密钥使用时间失败: %w", err)
+	}
+	return nil
+}
+
+// get 查询单个密钥
+func (r *PostgreSQLAPIKeyRepository) get(ctx context.Context, sqlQuery string, args ...any) (*repository.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRow(ctx, sqlQuery, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("API密钥不存在: %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("获取API密钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// scanAPIKey 按apiKeyColumns的顺序扫描一行
+func scanAPIKey(row pgx.Row) (*repository.APIKey, error) {
+	key := &repository.APIKey{}
+	var scopes []string
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.KeyPrefix,
+		&key.KeyHash,
+		&scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreateBy,
+		&key.CreateTime,
+		&key.UpdateBy,
+		&key.UpdateTime,
+		&key.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range scopes {
+		key.Scopes = append(key.Scopes, repository.APIKeyScope(s))
+	}
+	return key, nil
+}
+
+// scopeStrings 将接口范围转换为TEXT[]参数
+func scopeStrings(scopes []repository.APIKeyScope) []string {
+	values := make([]string, len(scopes))
+	for i, s := range scopes {
+		values[i] = string(s)
+	}
+	return values
+}
@@ -0,0 +1,351 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// approvalQuerier 连接池与事务的公共查询接口
+type approvalQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// PostgreSQLApprovalRepository PostgreSQL审批申请Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLApprovalRepository struct {
+	db     approvalQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLApprovalRepository 创建审批申请Repository实例
+func NewPostgreSQLApprovalRepository(pool DB, logger *zap.Logger) repository.ApprovalRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLApprovalRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const approvalColumns = `id, action_type, resource_id, summary, payload, requested_by, status,
+			decided_by, decided_time, decision_comment, error_message, expires_at,
+			create_by, create_time, update_by, update_time, is_deleted`
+
+// Create 创建审批申请
+func (r *PostgreSQLApprovalRepository) Create(ctx context.Context, req *repository.ApprovalRequest) error {
+	const sqlQuery = `
+		INSERT INTO approval_requests (action_type, resource_id, summary, payload, requested_by, status,
+			expires_at, create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`
+
+	now := time.Now().UTC()
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		req.ActionType,
+		req.ResourceID,
+		req.Summary,
+		req.Payload,
+		req.RequestedBy,
+		req.Status,
+		req.ExpiresAt,
+		req.RequestedBy,
+		now,
+		req.RequestedBy,
+		now,
+		false,
+	).Scan(&req.ID)
+
+	if err != nil {
+		r.logger.Error("创建审批申请失败",
+			zap.String("action_type", req.ActionType),
+			zap.Int64("requested_by", req.RequestedBy),
+			zap.Error(err))
+		return fmt.Errorf("创建审批申请失败: %w", err)
+	}
+
+	req.CreateBy = &req.RequestedBy
+	req.UpdateBy = &req.RequestedBy
+	req.CreateTime = now
+	req.UpdateTime = now
+	req.IsDeleted = false
+	return nil
+}
+
+// GetByID 根据ID获取审批申请
+func (r *PostgreSQLApprovalRepository) GetByID(ctx context.Context, id int64) (*repository.ApprovalRequest, error) {
+	sqlQuery := `
+		SELECT ` + approvalColumns + `
+		FROM approval_requests
+		WHERE id = $1 AND is_deleted = false`
+
+	req, err := scanApprovalRequest(r.db.QueryRow(ctx, sqlQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("审批申请不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("获取审批申请失败", zap.Int64("approval_id", id), zap.Error(err))
+		return nil, fmt.Errorf("获取审批申请失败: %w", err)
+	}
+	return req, nil
+}
+
+// ListByStatus 按状态分页列出审批申请，按创建时间倒序
+func (r *PostgreSQLApprovalRepository) ListByStatus(ctx context.Context, status repository.ApprovalStatus, limit, offset int) ([]*repository.ApprovalRequest, error) {
+	sqlQuery := `
+		SELECT ` + approvalColumns + `
+		FROM approval_requests
+		WHERE status = $1 AND is_deleted = false
+		ORDER BY create_time DESC
+		LIMIT $2 OFFSET $3`
+
+	return r.list(ctx, sqlQuery, string(status), limit, offset)
+}
+
+// ListByParticipant 按状态分页列出用户提交或处理过的申请，以及actionTypes中各动作类型的全部申请，按创建时间倒序
+func (r *PostgreSQLApprovalRepository) ListByParticipant(ctx context.Context, userID int64, actionTypes []repository.ApprovalActionType, status repository.ApprovalStatus, limit, offset int) ([]*repository.ApprovalRequest, error) {
+	sqlQuery := `
+		SELECT ` + approvalColumns + `
+		FROM approval_requests
+		WHERE status = $1 AND is_deleted = false
+			AND (requested_by = $2 OR decided_by = $2 OR action_type = ANY($3))
+		ORDER BY create_time DESC
+		LIMIT $4 OFFSET $5`
+
+	types := make([]string, len(actionTypes))
+	for i, actionType := range actionTypes {
+		types[i] = string(actionType)
+	}
+	return r.list(ctx, sqlQuery, string(status), userID, types, limit, offset)
+}
+
+// Decide 将未过期的pending申请标记为审批结果，条件更新保证同一申请只会被处理一次
+func (r *PostgreSQLApprovalRepository) Decide(ctx context.Context, id, deciderID int64, status repository.ApprovalStatus, comment *string) error {
+	const sqlQuery = `
+		UPDATE approval_requests
+		SET status = $2, decided_by = $3, decided_time = $4, decision_comment = $5,
+			update_by = $3, update_time = $4
+		WHERE id = $1 AND status = $6 AND expires_at > $4 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(ctx, sqlQuery, id, string(status), deciderID, now, comment, string(repository.ApprovalPending))
+	if err != nil {
+		r.logger.Error("审批申请处理失败", zap.Int64("approval_id", id), zap.Error(err))
+		return fmt.Errorf("审批申请处理失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("审批申请不存在、已处理或已过期: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// RecordResult 记录审批通过后的执行结果
+func (r *PostgreSQLApprovalRepository) RecordResult(ctx context.Context, id int64, status repository.ApprovalStatus, errorMessage *string) error {
+	const sqlQuery = `
+		UPDATE approval_requests
+		SET status = $2, error_message = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, string(status), errorMessage, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("记录审批执行结果失败", zap.Int64("approval_id", id), zap.Error(err))
+		return fmt.Errorf("记录审批执行结果失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("审批申请不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// ExpirePending 将截至now仍未处理的申请标记为过期并返回这些申请
+func (r *PostgreSQLApprovalRepository) ExpirePending(ctx context.Context, now time.Time) ([]*repository.ApprovalRequest, error) {
+	sqlQuery := `
+		UPDATE approval_requests
+		SET status = $1, update_time = $2
+		WHERE status = $3 AND expires_at <= $2 AND is_deleted = false
+		RETURNING ` + approvalColumns
+
+	return r.list(ctx, sqlQuery, string(repository.ApprovalExpired), now.UTC(), string(repository.ApprovalPending))
+}
+
+// AddEvent 记录审计事件
+func (r *PostgreSQLApprovalRepository) AddEvent(ctx context.Context, event *repository.ApprovalEvent) error {
+	const sqlQuery = `
+		INSERT INTO approval_events (request_id, actor_id, action, detail, create_time)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	now := time.Now().UTC()
+	err := r.db.QueryRow(ctx, sqlQuery,
+		event.RequestID,
+		event.ActorID,
+		event.Action,
+		event.Detail,
+		now,
+	).Scan(&event.ID)
+
+	if err != nil {
+		r.logger.Error("记录审批审计事件失败",
+			zap.Int64("approval_id", event.RequestID),
+			zap.String("action", event.Action),
+			zap.Error(err))
+		return fmt.Errorf("记录审批审计事件失败: %w", err)
+	}
+
+	event.CreateTime = now
+	return nil
+}
+
+// ListEvents 按时间顺序列出申请的审计事件
+func (r *PostgreSQLApprovalRepository) ListEvents(ctx context.Context, requestID int64) ([]*repository.ApprovalEvent, error) {
+	const sqlQuery = `
+		SELECT id, request_id, actor_id, action, detail, create_time
+		FROM approval_events
+		WHERE request_id = $1
+		ORDER BY create_time, id`
+
+	rows, err := r.db.Query(ctx, sqlQuery, requestID)
+	if err != nil {
+		r.logger.Error("查询审批审计事件失败", zap.Int64("approval_id", requestID), zap.Error(err))
+		return nil, fmt.Errorf("查询审批审计事件失败: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*repository.ApprovalEvent
+	for rows.Next() {
+		event := &repository.ApprovalEvent{}
+		if err := rows.Scan(&event.ID, &event.RequestID, &event.ActorID, &event.Action, &event.Detail, &event.CreateTime); err != nil {
+			return nil, fmt.Errorf("扫描审批审计事件失败: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历审批审计事件失败: %w", err)
+	}
+	return events, nil
+}
+
+// ListApprovers 列出动作类型的指定审批人
+func (r *PostgreSQLApprovalRepository) ListApprovers(ctx context.Context, actionType repository.ApprovalActionType) ([]int64, error) {
+	const sqlQuery = `SELECT user_id FROM approval_approvers WHERE action_type = $1 ORDER BY user_id`
+
+	rows, err := r.db.Query(ctx, sqlQuery, string(actionType))
+	if err != nil {
+		r.logger.Error("查询指定审批人失败", zap.String("action_type", string(actionType)), zap.Error(err))
+		return nil, fmt.Errorf("查询指定审批人失败: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("扫描指定审批人失败: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历指定审批人失败: %w", err)
+	}
+	return userIDs, nil
+}
+
+// SetApprovers 替换动作类型的指定审批人，传入空列表表示恢复由manager或admin审批
+func (r *PostgreSQLApprovalRepository) SetApprovers(ctx context.Context, actionType repository.ApprovalActionType, userIDs []int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM approval_approvers WHERE action_type = $1`, string(actionType)); err != nil {
+		return fmt.Errorf("清除指定审批人失败: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO approval_approvers (action_type, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			string(actionType), userID); err != nil {
+			return fmt.Errorf("添加指定审批人失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("更新指定审批人失败", zap.String("action_type", string(actionType)), zap.Error(err))
+		return fmt.Errorf("更新指定审批人失败: %w", err)
+	}
+
+	r.logger.Info("指定审批人已更新",
+		zap.String("action_type", string(actionType)),
+		zap.Int64s("user_ids", userIDs))
+	return nil
+}
+
+// list 查询审批申请列表
+func (r *PostgreSQLApprovalRepository) list(ctx context.Context, sqlQuery string, args ...any) ([]*repository.ApprovalRequest, error) {
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		r.logger.Error("查询审批申请列表失败", zap.Error(err))
+		return nil, fmt.Errorf("查询审批申请列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*repository.ApprovalRequest
+	for rows.Next() {
+		req, err := scanApprovalRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描审批申请失败: %w", err)
+		}
+		requests = append(requests, req)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历审批申请失败: %w", err)
+	}
+	return requests, nil
+}
+
+// scanApprovalRequest 按approvalColumns的顺序扫描一行
+func scanApprovalRequest(row pgx.Row) (*repository.ApprovalRequest, error) {
+	req := &repository.ApprovalRequest{}
+	err := row.Scan(
+		&req.ID,
+		&req.ActionType,
+		&req.ResourceID,
+		&req.Summary,
+		&req.Payload,
+		&req.RequestedBy,
+		&req.Status,
+		&req.DecidedBy,
+		&req.DecidedTime,
+		&req.DecisionComment,
+		&req.ErrorMessage,
+		&req.ExpiresAt,
+		&req.CreateBy,
+		&req.CreateTime,
+		&req.UpdateBy,
+		&req.UpdateTime,
+		&req.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// classificationQuerier 连接池与事务的公共查询接口
+type classificationQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgreSQLClassificationRepository PostgreSQL列数据分级Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLClassificationRepository struct {
+	db     classificationQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLClassificationRepository 创建列数据分级Repository实例
+func NewPostgreSQLClassificationRepository(pool DB, logger *zap.Logger) repository.ClassificationRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLClassificationRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+// Upsert 设置列的分级，已存在（包括已删除）的标签直接覆盖
+func (r *PostgreSQLClassificationRepository) Upsert(ctx context.Context, c *repository.ColumnClassification) error {
+	const sqlQuery = `
+		INSERT INTO column_classifications (connection_id, schema_name, table_name, column_name, classification,
+			create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $6, $7, false)
+		ON CONFLICT (connection_id, schema_name, table_name, column_name)
+		DO UPDATE SET classification = EXCLUDED.classification, update_by = EXCLUDED.update_by,
+			update_time = EXCLUDED.update_time, is_deleted = false
+		RETURNING id, create_by, create_time`
+
+	now := time.Now().UTC()
+	updateBy := int64(0)
+	if c.UpdateBy != nil {
+		updateBy = *c.UpdateBy
+	}
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		c.ConnectionID,
+		c.SchemaName,
+		c.TableName,
+		c.ColumnName,
+		c.Classification,
+		updateBy,
+		now,
+	).Scan(&c.ID, &c.CreateBy, &c.CreateTime)
+
+	if err != nil {
+		r.logger.Error("设置列分级失败",
+			zap.Int64("connection_id", c.ConnectionID),
+			zap.String("table", c.TableName),
+			zap.String("column", c.ColumnName),
+			zap.Error(err))
+		return fmt.Errorf("设置列分级失败: %w", err)
+	}
+
+	c.UpdateTime = now
+	c.IsDeleted = false
+	return nil
+}
+
+// Delete 软删除列的分级
+func (r *PostgreSQLClassificationRepository) Delete(ctx context.Context, connectionID int64, schemaName, tableName, columnName string, deleteBy int64) error {
+	const sqlQuery = `
+		UPDATE column_classifications
+		SET is_deleted = true, update_by = $5, update_time = $6
+		WHERE connection_id = $1 AND schema_name = $2 AND table_name = $3 AND column_name = $4
+			AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, connectionID, schemaName, tableName, columnName, deleteBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("移除列分级失败",
+			zap.Int64("connection_id", connectionID),
+			zap.String("table", tableName),
+			zap.String("column", columnName),
+			zap.Error(err))
+		return fmt.Errorf("移除列分级失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("列分级不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// ListByConnection 列出连接上所有列的分级
+func (r *PostgreSQLClassificationRepository) ListByConnection(ctx context.Context, connectionID int64) ([]*repository.ColumnClassification, error) {
+	const sqlQuery = `
+		SELECT id, connection_id, schema_name, table_name, column_name, classification,
+			create_by, create_time, update_by, update_time, is_deleted
+		FROM column_classifications
+		WHERE connection_id = $1 AND is_deleted = false
+		ORDER BY schema_name, table_name, column_name`
+
+	rows, err := r.db.Query(ctx, sqlQuery, connectionID)
+	if err != nil {
+		r.logger.Error("查询列分级失败", zap.Int64("connection_id", connectionID), zap.Error(err))
+		return nil, fmt.Errorf("查询列分级失败: %w", err)
+	}
+	defer rows.Close()
+
+	var classifications []*repository.ColumnClassification
+	for rows.Next() {
+		c := &repository.ColumnClassification{}
+		if err := rows.Scan(
+			&c.ID,
+			&c.ConnectionID,
+			&c.SchemaName,
+			&c.TableName,
+			&c.ColumnName,
+			&c.Classification,
+			&c.CreateBy,
+			&c.CreateTime,
+			&c.UpdateBy,
+			&c.UpdateTime,
+			&c.IsDeleted,
+		); err != nil {
+			return nil, fmt.Errorf("扫描列分级失败: %w", err)
+		}
+		classifications = append(classifications, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历列分级失败: %w", err)
+	}
+	return classifications, nil
+}
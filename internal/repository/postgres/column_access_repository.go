@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// columnAccessQuerier 连接池与事务的公共查询接口
+type columnAccessQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// columnAccessColumns 查询访问申请时的列顺序，与scanColumnAccessRequest一致
+const columnAccessColumns = `id, connection_id, schema_name, table_name, column_name, classification,
+	requested_by, reason, status, decided_by, decided_time, decision_comment, expires_at,
+	create_by, create_time, update_by, update_time, is_deleted`
+
+// PostgreSQLColumnAccessRepository PostgreSQL列访问申请Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLColumnAccessRepository struct {
+	db     columnAccessQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLColumnAccessRepository 创建列访问申请Repository实例
+func NewPostgreSQLColumnAccessRepository(pool DB, logger *zap.Logger) repository.ColumnAccessRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLColumnAccessRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+// Create 创建访问申请
+func (r *PostgreSQLColumnAccessRepository) Create(ctx context.Context, req *repository.ColumnAccessRequest) error {
+	const sqlQuery = `
+		INSERT INTO column_access_requests (connection_id, schema_name, table_name, column_name, classification,
+			requested_by, reason, status, create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $6, $9, $6, $9, false)
+		RETURNING id`
+
+	now := time.Now().UTC()
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		req.ConnectionID,
+		req.SchemaName,
+		req.TableName,
+		req.ColumnName,
+		req.Classification,
+		req.RequestedBy,
+		req.Reason,
+		req.Status,
+		now,
+	).Scan(&req.ID)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("该列已有待处理的访问申请: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("创建列访问申请失败",
+			zap.Int64("connection_id", req.ConnectionID),
+			zap.Int64("requested_by", req.RequestedBy),
+			zap.Error(err))
+		return fmt.Errorf("创建列访问申请失败: %w", err)
+	}
+
+	req.CreateBy = &req.RequestedBy
+	req.UpdateBy = &req.RequestedBy
+	req.CreateTime = now
+	req.UpdateTime = now
+	return nil
+}
+
+// GetByID 根据ID获取访问申请
+func (r *PostgreSQLColumnAccessRepository) GetByID(ctx context.Context, id int64) (*repository.ColumnAccessRequest, error) {
+	sqlQuery := `SELECT ` + columnAccessColumns + `
+		FROM column_access_requests
+		WHERE id = $1 AND is_deleted = false`
+
+	req, err := scanColumnAccessRequest(r.db.QueryRow(ctx, sqlQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("列访问申请不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("获取列访问申请失败", zap.Int64("request_id", id), zap.Error(err))
+		return nil, fmt.Errorf("获取列访问申请失败: %w", err)
+	}
+	return req, nil
+}
+
+// ListByRequester 列出用户提交的申请，按创建时间倒序
+func (r *PostgreSQLColumnAccessRepository) ListByRequester(ctx context.Context, userID int64, limit, offset int) ([]*repository.ColumnAccessRequest, error) {
+	sqlQuery := `SELECT ` + columnAccessColumns + `
+		FROM column_access_requests
+		WHERE requested_by = $1 AND is_deleted = false
+		ORDER BY create_time DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	return r.list(ctx, sqlQuery, userID, limit, offset)
+}
+
+// ListPendingByOwner 列出所有者名下连接的待处理申请，先提交的先处理
+func (r *PostgreSQLColumnAccessRepository) ListPendingByOwner(ctx context.Context, ownerID int64, limit, offset int) ([]*repository.ColumnAccessRequest, error) {
+	sqlQuery := `SELECT ` + columnAccessColumns + `
+		FROM column_access_requests
+		WHERE status = $2 AND is_deleted = false
+			AND connection_id IN (SELECT id FROM database_connections WHERE user_id = $1 AND is_deleted = false)
+		ORDER BY create_time, id
+		LIMIT $3 OFFSET $4`
+
+	return r.list(ctx, sqlQuery, ownerID, string(repository.ColumnAccessPending), limit, offset)
+}
+
+// ListActiveGrants 列出用户在连接上指定时间仍生效的授权
+func (r *PostgreSQLColumnAccessRepository) ListActiveGrants(ctx context.Context, connectionID, userID int64, now time.Time) ([]*repository.ColumnAccessRequest, error) {
+	sqlQuery := `SELECT ` + columnAccessColumns + `
+		FROM column_access_requests
+		WHERE connection_id = $1 AND requested_by = $2 AND status = $3 AND expires_at > $4 AND is_deleted = false`
+
+	return r.list(ctx, sqlQuery, connectionID, userID, string(repository.ColumnAccessGranted), now)
+}
+
+// Decide 处理待处理的申请，条件更新保证同一申请只会被处理一次
+func (r *PostgreSQLColumnAccessRepository) Decide(ctx context.Context, id, deciderID int64, status repository.ColumnAccessStatus, comment *string, expiresAt *time.Time) error {
+	const sqlQuery = `
+		UPDATE column_access_requests
+		SET status = $2, decided_by = $3, decided_time = $4, decision_comment = $5, expires_at = $6,
+			update_by = $3, update_time = $4
+		WHERE id = $1 AND status = $7 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(ctx, sqlQuery, id, string(status), deciderID, now, comment, expiresAt, string(repository.ColumnAccessPending))
+	if err != nil {
+		r.logger.Error("处理列访问申请失败", zap.Int64("request_id", id), zap.Error(err))
+		return fmt.Errorf("处理列访问申请失败: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("列访问申请不存在或已处理: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// Revoke 提前收回生效中的授权，保留原到期时间之前的收回时间作为新的到期时间
+func (r *PostgreSQLColumnAccessRepository) Revoke(ctx context.Context, id, revokeBy int64, comment *string) error {
+	const sqlQuery = `
+		UPDATE column_access_requests
+		SET status = $2, expires_at = $4, decision_comment = COALESCE($5, decision_comment),
+			update_by = $3, update_time = $4
+		WHERE id = $1 AND status = $6 AND expires_at > $4 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(ctx, sqlQuery, id, string(repository.ColumnAccessRevoked), revokeBy, now, comment, string(repository.ColumnAccessGranted))
+	if err != nil {
+		r.logger.Error("收回列访问授权失败", zap.Int64("request_id", id), zap.Error(err))
+		return fmt.Errorf("收回列访问授权失败: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("列访问授权不存在或已失效: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// AddEvent 追加审计事件
+func (r *PostgreSQLColumnAccessRepository) AddEvent(ctx context.Context, event *repository.ColumnAccessEvent) error {
+	const sqlQuery = `
+		INSERT INTO column_access_events (request_id, actor_id, action, detail, create_time)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	event.CreateTime = time.Now().UTC()
+	if err := r.db.QueryRow(ctx, sqlQuery, event.RequestID, event.ActorID, event.Action, event.Detail, event.CreateTime).Scan(&event.ID); err != nil {
+		return fmt.Errorf("记录列访问审计事件失败: %w", err)
+	}
+	return nil
+}
+
+// ListEvents 列出申请的审计事件，按时间正序
+func (r *PostgreSQLColumnAccessRepository) ListEvents(ctx context.Context, requestID int64) ([]*repository.ColumnAccessEvent, error) {
+	const sqlQuery = `
+		SELECT id, request_id, actor_id, action, detail, create_time
+		FROM column_access_events
+		WHERE request_id = $1
+		ORDER BY create_time, id`
+
+	rows, err := r.db.Query(ctx, sqlQuery, requestID)
+	if err != nil {
+		r.logger.Error("查询列访问审计事件失败", zap.Int64("request_id", requestID), zap.Error(err))
+		return nil, fmt.Errorf("查询列访问审计事件失败: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*repository.ColumnAccessEvent
+	for rows.Next() {
+		event := &repository.ColumnAccessEvent{}
+		if err := rows.Scan(&event.ID, &event.RequestID, &event.ActorID, &event.Action, &event.Detail, &event.CreateTime); err != nil {
+			return nil, fmt.Errorf("扫描列访问审计事件失败: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// list 查询访问申请列表
+func (r *PostgreSQLColumnAccessRepository) list(ctx context.Context, sqlQuery string, args ...any) ([]*repository.ColumnAccessRequest, error) {
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		r.logger.Error("查询列访问申请失败", zap.Error(err))
+		return nil, fmt.Errorf("查询列访问申请失败: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*repository.ColumnAccessRequest
+	for rows.Next() {
+		req, err := scanColumnAccessRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描列访问申请失败: %w", err)
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// scanColumnAccessRequest 按columnAccessColumns的顺序扫描一行
+func scanColumnAccessRequest(row pgx.Row) (*repository.ColumnAccessRequest, error) {
+	req := &repository.ColumnAccessRequest{}
+	err := row.Scan(
+		&req.ID,
+		&req.ConnectionID,
+		&req.SchemaName,
+		&req.TableName,
+		&req.ColumnName,
+		&req.Classification,
+		&req.RequestedBy,
+		&req.Reason,
+		&req.Status,
+		&req.DecidedBy,
+		&req.DecidedTime,
+		&req.DecisionComment,
+		&req.ExpiresAt,
+		&req.CreateBy,
+		&req.CreateTime,
+		&req.UpdateBy,
+		&req.UpdateTime,
+		&req.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
 	"chat2sql-go/internal/repository"
@@ -15,12 +14,12 @@ import (
 // PostgreSQLConnectionRepository PostgreSQL数据库连接Repository实现
 // 支持多数据库连接管理、连接测试、状态监控等功能
 type PostgreSQLConnectionRepository struct {
-	pool   *pgxpool.Pool
+	pool   DB
 	logger *zap.Logger
 }
 
 // NewPostgreSQLConnectionRepository 创建PostgreSQL连接Repository
-func NewPostgreSQLConnectionRepository(pool *pgxpool.Pool, logger *zap.Logger) repository.ConnectionRepository {
+func NewPostgreSQLConnectionRepository(pool DB, logger *zap.Logger) repository.ConnectionRepository {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -94,7 +93,7 @@ func (r *PostgreSQLConnectionRepository) Create(ctx context.Context, conn *repos
 func (r *PostgreSQLConnectionRepository) GetByID(ctx context.Context, id int64) (*repository.DatabaseConnection, error) {
 	const query = `
 		SELECT id, user_id, name, host, port, database_name, username, 
-			password_encrypted, db_type, status, last_tested,
+			password_encrypted, db_type, status, last_tested, write_mode_enabled, max_rows, max_result_mb,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM database_connections 
 		WHERE id = $1 AND is_deleted = false`
@@ -113,6 +112,9 @@ func (r *PostgreSQLConnectionRepository) GetByID(ctx context.Context, id int64)
 		&conn.DBType,
 		&conn.Status,
 		&conn.LastTested,
+		&conn.WriteModeEnabled,
+		&conn.MaxRows,
+		&conn.MaxResultMB,
 		&conn.CreateBy,
 		&conn.CreateTime,
 		&conn.UpdateBy,
@@ -224,7 +226,7 @@ func (r *PostgreSQLConnectionRepository) Delete(ctx context.Context, id int64) e
 func (r *PostgreSQLConnectionRepository) ListByUser(ctx context.Context, userID int64) ([]*repository.DatabaseConnection, error) {
 	const query = `
 		SELECT id, user_id, name, host, port, database_name, username, 
-			password_encrypted, db_type, status, last_tested,
+			password_encrypted, db_type, status, last_tested, write_mode_enabled, max_rows, max_result_mb,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM database_connections 
 		WHERE user_id = $1 AND is_deleted = false 
@@ -247,7 +249,7 @@ func (r *PostgreSQLConnectionRepository) ListByUser(ctx context.Context, userID
 func (r *PostgreSQLConnectionRepository) ListByType(ctx context.Context, dbType repository.DatabaseType) ([]*repository.DatabaseConnection, error) {
 	const query = `
 		SELECT id, user_id, name, host, port, database_name, username, 
-			password_encrypted, db_type, status, last_tested,
+			password_encrypted, db_type, status, last_tested, write_mode_enabled, max_rows, max_result_mb,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM database_connections 
 		WHERE db_type = $1 AND is_deleted = false 
@@ -270,7 +272,7 @@ func (r *PostgreSQLConnectionRepository) ListByType(ctx context.Context, dbType
 func (r *PostgreSQLConnectionRepository) ListByStatus(ctx context.Context, status repository.ConnectionStatus) ([]*repository.DatabaseConnection, error) {
 	const query = `
 		SELECT id, user_id, name, host, port, database_name, username, 
-			password_encrypted, db_type, status, last_tested,
+			password_encrypted, db_type, status, last_tested, write_mode_enabled, max_rows, max_result_mb,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM database_connections 
 		WHERE status = $1 AND is_deleted = false 
@@ -293,7 +295,7 @@ func (r *PostgreSQLConnectionRepository) ListByStatus(ctx context.Context, statu
 func (r *PostgreSQLConnectionRepository) GetByUserAndName(ctx context.Context, userID int64, name string) (*repository.DatabaseConnection, error) {
 	const query = `
 		SELECT id, user_id, name, host, port, database_name, username, 
-			password_encrypted, db_type, status, last_tested,
+			password_encrypted, db_type, status, last_tested, write_mode_enabled, max_rows, max_result_mb,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM database_connections 
 		WHERE user_id = $1 AND name = $2 AND is_deleted = false`
@@ -312,6 +314,9 @@ func (r *PostgreSQLConnectionRepository) GetByUserAndName(ctx context.Context, u
 		&conn.DBType,
 		&conn.Status,
 		&conn.LastTested,
+		&conn.WriteModeEnabled,
+		&conn.MaxRows,
+		&conn.MaxResultMB,
 		&conn.CreateBy,
 		&conn.CreateTime,
 		&conn.UpdateBy,
@@ -450,6 +455,65 @@ func (r *PostgreSQLConnectionRepository) UpdateLastTested(ctx context.Context, c
 	return nil
 }
 
+// UpdateWriteMode 开启或关闭连接的写模式
+func (r *PostgreSQLConnectionRepository) UpdateWriteMode(ctx context.Context, connectionID int64, enabled bool, updateBy int64) error {
+	const query = `
+		UPDATE database_connections
+		SET write_mode_enabled = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.pool.Exec(ctx, query, connectionID, enabled, updateBy, now)
+
+	if err != nil {
+		r.logger.Error("更新连接写模式失败",
+			zap.Int64("connection_id", connectionID),
+			zap.Error(err),
+		)
+		return fmt.Errorf("更新连接写模式失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("数据库连接配置不存在或已删除: %w", repository.ErrNotFound)
+	}
+
+	r.logger.Info("连接写模式已更新",
+		zap.Int64("connection_id", connectionID),
+		zap.Bool("enabled", enabled),
+		zap.Int64("update_by", updateBy),
+	)
+	return nil
+}
+
+// UpdateResultLimits 设置连接的结果行数与大小上限，nil表示使用全局配置
+func (r *PostgreSQLConnectionRepository) UpdateResultLimits(ctx context.Context, connectionID int64, maxRows, maxResultMB *int32, updateBy int64) error {
+	const query = `
+		UPDATE database_connections
+		SET max_rows = $2, max_result_mb = $3, update_by = $4, update_time = $5
+		WHERE id = $1 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.pool.Exec(ctx, query, connectionID, maxRows, maxResultMB, updateBy, now)
+
+	if err != nil {
+		r.logger.Error("更新连接结果上限失败",
+			zap.Int64("connection_id", connectionID),
+			zap.Error(err),
+		)
+		return fmt.Errorf("更新连接结果上限失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("数据库连接配置不存在或已删除: %w", repository.ErrNotFound)
+	}
+
+	r.logger.Info("连接结果上限已更新",
+		zap.Int64("connection_id", connectionID),
+		zap.Int64("update_by", updateBy),
+	)
+	return nil
+}
+
 // BatchUpdateStatus 批量更新连接状态
 func (r *PostgreSQLConnectionRepository) BatchUpdateStatus(ctx context.Context, connectionIDs []int64, status repository.ConnectionStatus) error {
 	if len(connectionIDs) == 0 {
@@ -483,6 +547,97 @@ func (r *PostgreSQLConnectionRepository) BatchUpdateStatus(ctx context.Context,
 	return nil
 }
 
+// ListUnused 列出自since起没有任何查询的活跃连接
+// 按连接取最后一次查询时间（使用idx_query_history_connection_time索引），从未使用的按创建时间判断
+func (r *PostgreSQLConnectionRepository) ListUnused(ctx context.Context, since time.Time) ([]*repository.UnusedConnection, error) {
+	const query = `
+		SELECT c.id, c.user_id, c.name, c.host, c.port, c.database_name, c.username,
+			c.db_type, c.status, c.last_tested, c.write_mode_enabled, c.max_rows, c.max_result_mb,
+			c.create_by, c.create_time, c.update_by, c.update_time, c.is_deleted,
+			u.last_used_at, c.unused_notified_at
+		FROM database_connections c
+		LEFT JOIN LATERAL (
+			SELECT MAX(q.create_time) AS last_used_at
+			FROM query_history q
+			WHERE q.connection_id = c.id AND q.is_deleted = false
+		) u ON true
+		WHERE c.status = 'active' AND c.is_deleted = false
+			AND COALESCE(u.last_used_at, c.create_time) < $1
+		ORDER BY c.id`
+
+	rows, err := r.pool.Query(ctx, query, since)
+	if err != nil {
+		r.logger.Error("获取闲置连接失败",
+			zap.Time("since", since),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("获取闲置连接失败: %w", err)
+	}
+	defer rows.Close()
+
+	var unused []*repository.UnusedConnection
+	for rows.Next() {
+		conn := &repository.DatabaseConnection{}
+		item := &repository.UnusedConnection{Connection: conn}
+		err := rows.Scan(
+			&conn.ID,
+			&conn.UserID,
+			&conn.Name,
+			&conn.Host,
+			&conn.Port,
+			&conn.DatabaseName,
+			&conn.Username,
+			&conn.DBType,
+			&conn.Status,
+			&conn.LastTested,
+			&conn.WriteModeEnabled,
+			&conn.MaxRows,
+			&conn.MaxResultMB,
+			&conn.CreateBy,
+			&conn.CreateTime,
+			&conn.UpdateBy,
+			&conn.UpdateTime,
+			&conn.IsDeleted,
+			&item.LastUsedAt,
+			&item.NotifiedAt,
+		)
+		if err != nil {
+			r.logger.Error("扫描闲置连接失败", zap.Error(err))
+			return nil, fmt.Errorf("扫描闲置连接失败: %w", err)
+		}
+		unused = append(unused, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历闲置连接失败: %w", err)
+	}
+
+	return unused, nil
+}
+
+// MarkUnusedNotified 记录已提醒所有者连接闲置
+func (r *PostgreSQLConnectionRepository) MarkUnusedNotified(ctx context.Context, connectionID int64, notifiedAt time.Time) error {
+	const query = `
+		UPDATE database_connections
+		SET unused_notified_at = $2
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.pool.Exec(ctx, query, connectionID, notifiedAt.UTC())
+	if err != nil {
+		r.logger.Error("记录闲置提醒失败",
+			zap.Int64("connection_id", connectionID),
+			zap.Error(err),
+		)
+		return fmt.Errorf("记录闲置提醒失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("数据库连接配置不存在或已删除: %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
 // ExistsByUserAndName 检查用户的连接名称是否存在
 func (r *PostgreSQLConnectionRepository) ExistsByUserAndName(ctx context.Context, userID int64, name string) (bool, error) {
 	const query = `
@@ -509,7 +664,7 @@ func (r *PostgreSQLConnectionRepository) ExistsByUserAndName(ctx context.Context
 func (r *PostgreSQLConnectionRepository) GetActiveConnections(ctx context.Context) ([]*repository.DatabaseConnection, error) {
 	const query = `
 		SELECT id, user_id, name, host, port, database_name, username, 
-			password_encrypted, db_type, status, last_tested,
+			password_encrypted, db_type, status, last_tested, write_mode_enabled, max_rows, max_result_mb,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM database_connections 
 		WHERE status = 'active' AND is_deleted = false 
@@ -543,6 +698,9 @@ func (r *PostgreSQLConnectionRepository) scanConnections(rows pgx.Rows) ([]*repo
 			&conn.DBType,
 			&conn.Status,
 			&conn.LastTested,
+			&conn.WriteModeEnabled,
+			&conn.MaxRows,
+			&conn.MaxResultMB,
 			&conn.CreateBy,
 			&conn.CreateTime,
 			&conn.UpdateBy,
@@ -0,0 +1,341 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// erasedText 匿名化后替换问题文本的占位值，query_history.natural_query等字段不允许为空
+const erasedText = "[已删除]"
+
+// erasureQuerier 连接池与事务的公共查询接口
+type erasureQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// PostgreSQLErasureRepository PostgreSQL个人数据删除Repository实现
+type PostgreSQLErasureRepository struct {
+	db     erasureQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLErasureRepository 创建个人数据删除Repository实例
+func NewPostgreSQLErasureRepository(pool DB, logger *zap.Logger) repository.ErasureRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLErasureRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const erasureColumns = `id, subject_user_id, mode, status, requested_by, report, error_message, completed_time,
+			create_by, create_time, update_by, update_time, is_deleted`
+
+// Create 创建删除申请
+func (r *PostgreSQLErasureRepository) Create(ctx context.Context, req *repository.ErasureRequest) error {
+	const sqlQuery = `
+		INSERT INTO erasure_requests (subject_user_id, mode, status, requested_by,
+			create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+
+	now := time.Now().UTC()
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		req.SubjectUserID,
+		req.Mode,
+		req.Status,
+		req.RequestedBy,
+		req.RequestedBy,
+		now,
+		req.RequestedBy,
+		now,
+		false,
+	).Scan(&req.ID)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("用户已有未完成的删除申请: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("创建删除申请失败",
+			zap.Int64("subject_user_id", req.SubjectUserID),
+			zap.Int64("requested_by", req.RequestedBy),
+			zap.Error(err))
+		return fmt.Errorf("创建删除申请失败: %w", err)
+	}
+
+	req.CreateBy = &req.RequestedBy
+	req.UpdateBy = &req.RequestedBy
+	req.CreateTime = now
+	req.UpdateTime = now
+	req.IsDeleted = false
+	return nil
+}
+
+// GetByID 根据ID获取删除申请
+func (r *PostgreSQLErasureRepository) GetByID(ctx context.Context, id int64) (*repository.ErasureRequest, error) {
+	sqlQuery := `
+		SELECT ` + erasureColumns + `
+		FROM erasure_requests
+		WHERE id = $1 AND is_deleted = false`
+
+	req, err := scanErasureRequest(r.db.QueryRow(ctx, sqlQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("删除申请不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("获取删除申请失败", zap.Int64("erasure_id", id), zap.Error(err))
+		return nil, fmt.Errorf("获取删除申请失败: %w", err)
+	}
+	return req, nil
+}
+
+// ListBySubject 列出用户的删除申请，按创建时间倒序
+func (r *PostgreSQLErasureRepository) ListBySubject(ctx context.Context, subjectUserID int64) ([]*repository.ErasureRequest, error) {
+	sqlQuery := `
+		SELECT ` + erasureColumns + `
+		FROM erasure_requests
+		WHERE subject_user_id = $1 AND is_deleted = false
+		ORDER BY create_time DESC`
+
+	return r.list(ctx, sqlQuery, subjectUserID)
+}
+
+// ClaimPending 领取待处理申请，SKIP LOCKED保证多实例不会重复处理
+func (r *PostgreSQLErasureRepository) ClaimPending(ctx context.Context, limit int) ([]*repository.ErasureRequest, error) {
+	sqlQuery := `
+		UPDATE erasure_requests
+		SET status = $1, update_time = $2
+		WHERE id IN (
+			SELECT id FROM erasure_requests
+			WHERE status = $3 AND is_deleted = false
+			ORDER BY create_time
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + erasureColumns
+
+	return r.list(ctx, sqlQuery, string(repository.ErasureRunning), time.Now().UTC(), string(repository.ErasurePending), limit)
+}
+
+// Complete 记录删除报告并将申请标记为已完成
+func (r *PostgreSQLErasureRepository) Complete(ctx context.Context, id int64, report *repository.ErasureReport) error {
+	const sqlQuery = `
+		UPDATE erasure_requests
+		SET status = $2, report = $3, error_message = NULL, completed_time = $4, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	return r.finish(ctx, sqlQuery, id, string(repository.ErasureCompleted), report, report.CompletedAt.UTC())
+}
+
+// Fail 将申请标记为处理失败
+func (r *PostgreSQLErasureRepository) Fail(ctx context.Context, id int64, errorMessage string) error {
+	const sqlQuery = `
+		UPDATE erasure_requests
+		SET status = $2, error_message = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	return r.finish(ctx, sqlQuery, id, string(repository.ErasureFailed), errorMessage, time.Now().UTC())
+}
+
+// EraseUserData 在同一事务中匿名化或删除用户的个人数据
+// 查询历史、反馈与保存查询按mode清除文本或删除记录；写操作申请、审批记录与列访问申请属于审计数据，只清除其中由用户填写的文本；
+// 定时执行计划的执行结果一律删除，用户的邮箱从所有计划的收件人中移除；
+// 审计记录中的用户ID保持不变，用户账号改为假名并停用
+func (r *PostgreSQLErasureRepository) EraseUserData(ctx context.Context, userID int64, mode repository.ErasureMode, pseudonym string) (*repository.ErasureReport, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	report := &repository.ErasureReport{Mode: string(mode), Pseudonym: pseudonym}
+
+	historySQL := `UPDATE query_history SET natural_query = $2, error_message = NULL, update_time = $3
+		WHERE user_id = $1 AND natural_query <> $2`
+	feedbackSQL := `UPDATE feedbacks SET user_query = $2, feedback_text = NULL, expected_sql = NULL, update_time = $3
+		WHERE user_id = $1 AND user_query <> $2`
+	savedQuerySQL := `UPDATE saved_queries SET natural_query = $2, description = NULL, update_time = $3
+		WHERE owner_id = $1 AND natural_query <> $2`
+	// 用户创建的执行计划；删除保存查询时引用它们的执行计划一并删除
+	schedulesScope := `SELECT id FROM query_schedules WHERE create_by = $1`
+	scheduleSQL := "" // 匿名化时保留执行计划
+	args := []any{userID, erasedText, now}
+	if mode == repository.ErasureDelete {
+		historySQL = `DELETE FROM query_history WHERE user_id = $1`
+		feedbackSQL = `DELETE FROM feedbacks WHERE user_id = $1`
+		savedQuerySQL = `DELETE FROM saved_queries WHERE owner_id = $1`
+		schedulesScope += ` OR saved_query_id IN (SELECT id FROM saved_queries WHERE owner_id = $1)`
+		scheduleSQL = `DELETE FROM query_schedules WHERE id IN (` + schedulesScope + `)`
+		args = []any{userID}
+	}
+
+	steps := []struct {
+		name  string
+		sql   string
+		args  []any
+		count *int64
+	}{
+		{"查询历史", historySQL, args, &report.QueryHistory},
+		{"反馈", feedbackSQL, args, &report.Feedback},
+		// 异步查询任务保存了SQL与结果数据，不论删除方式一律删除
+		{"异步查询任务", `DELETE FROM query_jobs WHERE user_id = $1`, []any{userID}, &report.QueryJobs},
+		{"写操作申请", `UPDATE write_requests SET natural_query = NULL, update_time = $2
+			WHERE requested_by = $1 AND natural_query IS NOT NULL`, []any{userID, now}, &report.WriteRequests},
+		{"写操作审批意见", `UPDATE write_requests SET review_comment = NULL, update_time = $2
+			WHERE reviewed_by = $1 AND review_comment IS NOT NULL`, []any{userID, now}, &report.WriteRequests},
+		{"审批意见", `UPDATE approval_requests SET decision_comment = NULL, update_time = $2
+			WHERE decided_by = $1 AND decision_comment IS NOT NULL`, []any{userID, now}, &report.AuditEvents},
+		{"审批事件说明", `UPDATE approval_events SET detail = NULL
+			WHERE actor_id = $1 AND action IN ('approved', 'rejected') AND detail IS NOT NULL`, []any{userID}, &report.AuditEvents},
+		// 执行记录保存了查询结果，不论删除方式一律删除
+		{"定时执行记录", `DELETE FROM query_schedule_runs WHERE schedule_id IN (` + schedulesScope + `)`, []any{userID}, &report.QuerySchedules},
+		{"定时执行计划", scheduleSQL, []any{userID}, &report.QuerySchedules},
+		{"收件人地址", scheduleRecipientsSQL, []any{userID, now}, &report.QuerySchedules},
+		{"保存查询", savedQuerySQL, args, &report.SavedQueries},
+		{"列访问申请理由", `UPDATE column_access_requests SET reason = $2, update_time = $3
+			WHERE requested_by = $1 AND reason <> $2`, []any{userID, erasedText, now}, &report.ColumnAccessRequests},
+		{"列访问审批意见", `UPDATE column_access_requests SET decision_comment = NULL, update_time = $2
+			WHERE decided_by = $1 AND decision_comment IS NOT NULL`, []any{userID, now}, &report.ColumnAccessRequests},
+		{"列访问事件说明", `UPDATE column_access_events SET detail = NULL
+			WHERE actor_id = $1 AND detail IS NOT NULL`, []any{userID}, &report.ColumnAccessRequests},
+	}
+
+	for _, step := range steps {
+		if step.sql == "" {
+			continue
+		}
+		result, err := tx.Exec(ctx, step.sql, step.args...)
+		if err != nil {
+			r.logger.Error("清除个人数据失败", zap.Int64("user_id", userID), zap.String("step", step.name), zap.Error(err))
+			return nil, fmt.Errorf("清除%s失败: %w", step.name, err)
+		}
+		*step.count += result.RowsAffected()
+	}
+
+	const userSQL = `
+		UPDATE users
+		SET username = $2, email = $3, password_hash = '!', status = $4,
+			update_time = $5, is_deleted = true
+		WHERE id = $1`
+
+	result, err := tx.Exec(ctx, userSQL, userID, pseudonym, pseudonym+"@erased.invalid", string(repository.StatusInactive), now)
+	if err != nil {
+		r.logger.Error("匿名化用户账号失败", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, fmt.Errorf("匿名化用户账号失败: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("用户不存在: %w", repository.ErrNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("提交个人数据删除失败", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, fmt.Errorf("提交个人数据删除失败: %w", err)
+	}
+
+	r.logger.Info("用户个人数据已清除",
+		zap.Int64("user_id", userID),
+		zap.String("mode", string(mode)),
+		zap.Int64("query_history", report.QueryHistory),
+		zap.Int64("feedback", report.Feedback),
+		zap.Int64("query_jobs", report.QueryJobs),
+		zap.Int64("saved_queries", report.SavedQueries),
+		zap.Int64("query_schedules", report.QuerySchedules),
+		zap.Int64("column_access_requests", report.ColumnAccessRequests),
+		zap.Int64("write_requests", report.WriteRequests),
+		zap.Int64("audit_events", report.AuditEvents))
+	return report, nil
+}
+
+// scheduleRecipientsSQL 从邮件投递的收件人中移除用户的邮箱，没有剩余收件人时改为不投递
+const scheduleRecipientsSQL = `
+	WITH remaining AS (
+		SELECT s.id, array_to_string(ARRAY(
+			SELECT r FROM unnest(string_to_array(s.delivery_target, ',')) AS r
+			WHERE lower(r) <> lower(u.email)), ',') AS target
+		FROM query_schedules s JOIN users u ON u.id = $1
+		WHERE s.delivery_type = 'email' AND lower(u.email) = ANY(string_to_array(lower(s.delivery_target), ','))
+	)
+	UPDATE query_schedules s
+	SET delivery_target = NULLIF(remaining.target, ''),
+		delivery_type = CASE WHEN remaining.target = '' THEN 'none' ELSE s.delivery_type END,
+		update_time = $2
+	FROM remaining
+	WHERE s.id = remaining.id`
+
+// finish 更新申请的终态
+func (r *PostgreSQLErasureRepository) finish(ctx context.Context, sqlQuery string, id int64, args ...any) error {
+	result, err := r.db.Exec(ctx, sqlQuery, append([]any{id}, args...)...)
+	if err != nil {
+		r.logger.Error("更新删除申请状态失败", zap.Int64("erasure_id", id), zap.Error(err))
+		return fmt.Errorf("更新删除申请状态失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("删除申请不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// list 查询删除申请列表
+func (r *PostgreSQLErasureRepository) list(ctx context.Context, sqlQuery string, args ...any) ([]*repository.ErasureRequest, error) {
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		r.logger.Error("查询删除申请列表失败", zap.Error(err))
+		return nil, fmt.Errorf("查询删除申请列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*repository.ErasureRequest
+	for rows.Next() {
+		req, err := scanErasureRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描删除申请失败: %w", err)
+		}
+		requests = append(requests, req)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历删除申请失败: %w", err)
+	}
+	return requests, nil
+}
+
+// scanErasureRequest 按erasureColumns的顺序扫描一行
+func scanErasureRequest(row pgx.Row) (*repository.ErasureRequest, error) {
+	req := &repository.ErasureRequest{}
+	err := row.Scan(
+		&req.ID,
+		&req.SubjectUserID,
+		&req.Mode,
+		&req.Status,
+		&req.RequestedBy,
+		&req.Report,
+		&req.ErrorMessage,
+		&req.CompletedTime,
+		&req.CreateBy,
+		&req.CreateTime,
+		&req.UpdateBy,
+		&req.UpdateTime,
+		&req.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+// recordingTx 记录执行过的语句，每条语句影响一行
+type recordingTx struct {
+	pgx.Tx
+	statements []string
+	committed  bool
+}
+
+func (t *recordingTx) Begin(ctx context.Context) (pgx.Tx, error) { return t, nil }
+
+func (t *recordingTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	t.statements = append(t.statements, strings.Join(strings.Fields(sql), " "))
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (t *recordingTx) Commit(ctx context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *recordingTx) Rollback(ctx context.Context) error { return nil }
+
+// indexOf 返回第一条以prefix开头的语句的位置，不存在时返回-1
+func (t *recordingTx) indexOf(prefix string) int {
+	for i, statement := range t.statements {
+		if strings.HasPrefix(statement, prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestEraseUserData_CoversPersonalTables(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("匿名化", func(t *testing.T) {
+		tx := &recordingTx{}
+		report, err := NewPostgreSQLTxErasureRepository(tx, zaptest.NewLogger(t)).
+			EraseUserData(ctx, 7, repository.ErasureAnonymize, "erased-1")
+		require.NoError(t, err)
+		require.True(t, tx.committed)
+
+		for _, prefix := range []string{
+			"UPDATE saved_queries SET natural_query",
+			"DELETE FROM query_schedule_runs",
+			"WITH remaining AS",
+			"UPDATE column_access_requests SET reason",
+			"UPDATE column_access_requests SET decision_comment",
+			"UPDATE column_access_events SET detail",
+		} {
+			assert.NotEqual(t, -1, tx.indexOf(prefix), prefix)
+		}
+		assert.Equal(t, -1, tx.indexOf("DELETE FROM query_schedules"), "匿名化时保留执行计划")
+		assert.Equal(t, int64(1), report.SavedQueries)
+		assert.Equal(t, int64(2), report.QuerySchedules)
+		assert.Equal(t, int64(3), report.ColumnAccessRequests)
+	})
+
+	t.Run("删除", func(t *testing.T) {
+		tx := &recordingTx{}
+		report, err := NewPostgreSQLTxErasureRepository(tx, zaptest.NewLogger(t)).
+			EraseUserData(ctx, 7, repository.ErasureDelete, "erased-2")
+		require.NoError(t, err)
+
+		// 按外键顺序删除：执行记录、执行计划、保存查询
+		runs := tx.indexOf("DELETE FROM query_schedule_runs")
+		schedules := tx.indexOf("DELETE FROM query_schedules")
+		savedQueries := tx.indexOf("DELETE FROM saved_queries")
+		require.NotEqual(t, -1, runs)
+		assert.Less(t, runs, schedules)
+		assert.Less(t, schedules, savedQueries)
+		assert.Contains(t, tx.statements[schedules], "saved_query_id IN (SELECT id FROM saved_queries WHERE owner_id = $1)",
+			"其他用户基于该用户保存查询的执行计划一并删除")
+		assert.Less(t, savedQueries, tx.indexOf("UPDATE users"), "账号最后改为假名")
+		assert.Equal(t, int64(3), report.QuerySchedules)
+		assert.Equal(t, int64(1), report.SavedQueries)
+	})
+}
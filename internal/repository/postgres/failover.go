@@ -0,0 +1,309 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// DB 连接池的公共查询接口，*pgxpool.Pool与FailoverDB均实现该接口
+type DB interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// FailoverDB 在系统库连接池外层处理主备切换
+// 瞬时错误按幂等性判断后退避重试；连续的连接故障触发熔断，熔断期间直接返回repository.ErrUnavailable，
+// 避免切换窗口内每个请求都等待连接超时后再以500失败
+type FailoverDB struct {
+	db      DB
+	config  *config.DatabaseFailoverConfig
+	breaker *circuitBreaker
+	logger  *zap.Logger
+}
+
+// NewFailoverDB 包装连接池
+func NewFailoverDB(db DB, cfg *config.DatabaseFailoverConfig, logger *zap.Logger) *FailoverDB {
+	if cfg == nil {
+		cfg = config.DefaultDatabaseFailoverConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &FailoverDB{
+		db:      db,
+		config:  cfg,
+		breaker: newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerOpenTimeout, logger),
+		logger:  logger,
+	}
+}
+
+// Available 熔断器未打开时返回true
+func (f *FailoverDB) Available() bool {
+	return !f.breaker.isOpen()
+}
+
+// Query 执行查询，只重试获取结果前发生的错误
+func (f *FailoverDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := f.do(ctx, sql, func() error {
+		var err error
+		rows, err = f.db.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow 执行单行查询，重试在Scan时进行
+func (f *FailoverDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &failoverRow{f: f, ctx: ctx, sql: sql, args: args}
+}
+
+// Exec 执行语句
+func (f *FailoverDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := f.do(ctx, sql, func() error {
+		var err error
+		tag, err = f.db.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+// Begin 开始事务，只重试BEGIN本身；事务内的语句不重试，由调用方决定是否重新执行整个事务
+func (f *FailoverDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	var tx pgx.Tx
+	err := f.do(ctx, "BEGIN", func() error {
+		var err error
+		tx, err = f.db.Begin(ctx)
+		return err
+	})
+	return tx, err
+}
+
+// failoverRow 延迟到Scan时执行的单行查询
+type failoverRow struct {
+	f    *FailoverDB
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+// Scan 执行查询并读取结果
+func (r *failoverRow) Scan(dest ...any) error {
+	return r.f.do(r.ctx, r.sql, func() error {
+		return r.f.db.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
+
+// do 在熔断器保护下执行fn，可安全重试的错误按指数退避重试
+func (f *FailoverDB) do(ctx context.Context, sql string, fn func() error) error {
+	if !f.breaker.allow() {
+		return fmt.Errorf("%w: circuit breaker open", repository.ErrUnavailable)
+	}
+
+	backoff := f.config.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isConnectionFailure(err) && !isRetryableServerError(err) {
+			// 成功或业务错误（如记录不存在、约束冲突）说明数据库可用
+			f.breaker.recordSuccess()
+			return err
+		}
+
+		if attempt >= f.config.MaxAttempts || !retryable(sql, err) || ctx.Err() != nil {
+			f.recordGiveUp(err)
+			return err
+		}
+
+		f.logger.Warn("Transient database error, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			f.recordGiveUp(err)
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// recordGiveUp 放弃重试时更新熔断器：只有连接失败计入熔断，
+// 死锁、串行化冲突等服务端错误说明数据库仍在响应，不应使熔断器打开而拒绝所有请求
+func (f *FailoverDB) recordGiveUp(err error) {
+	if isConnectionFailure(err) {
+		f.breaker.recordFailure()
+		return
+	}
+	f.breaker.recordSuccess()
+}
+
+// retryable 判断失败的语句能否安全重试
+// 语句未发送或被服务端整体回滚时总是可以重试；连接在语句发送后断开时结果未知，只重试只读语句
+func retryable(sql string, err error) bool {
+	if pgconn.SafeToRetry(err) || isRetryableServerError(err) {
+		return true
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	return isReadOnlyStatement(sql)
+}
+
+// isReadOnlyStatement 语句是否只读：以SELECT、WITH或SHOW开头且不含写操作
+func isReadOnlyStatement(sql string) bool {
+	return readOnlyStatement.MatchString(sql) && !writeKeyword.MatchString(sql)
+}
+
+// isRetryableServerError 服务端拒绝执行或已回滚语句的错误：串行化冲突、死锁、关闭中或切换为只读的节点
+func isRetryableServerError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"57P01", // admin_shutdown
+		"57P02", // crash_shutdown
+		"57P03", // cannot_connect_now
+		"25006": // read_only_sql_transaction，旧主库已降为备库
+		return true
+	}
+	// 08类：连接异常
+	return len(pgErr.Code) == 5 && pgErr.Code[:2] == "08"
+}
+
+// isConnectionFailure 连接层面的故障，用于熔断计数
+func isConnectionFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		pgconn.SafeToRetry(err)
+}
+
+var (
+	// readOnlyStatement 以SELECT、WITH或SHOW开头的语句
+	readOnlyStatement = regexp.MustCompile(`(?is)^\s*(SELECT|WITH|SHOW)\b`)
+	// writeKeyword 可写CTE或SELECT ... FOR UPDATE等带副作用的语句
+	writeKeyword = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|nextval|setval)\b`)
+)
+
+// circuitState 熔断器状态
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker 系统库熔断器
+// 连续threshold次调用因连接故障失败后打开，openTimeout后进入半开状态放行一次探测调用，
+// 探测成功则关闭，失败则重新打开
+type circuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+	logger      *zap.Logger
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, openTimeout time.Duration, logger *zap.Logger) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, openTimeout: openTimeout, logger: logger}
+}
+
+// allow 判断是否放行调用
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if time.Since(cb.openedAt) < cb.openTimeout {
+			return false
+		}
+		cb.state = circuitHalfOpen
+		cb.probing = true
+		return true
+	case circuitHalfOpen:
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// isOpen 熔断器是否处于拒绝调用的状态
+func (cb *circuitBreaker) isOpen() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state == circuitOpen && time.Since(cb.openedAt) < cb.openTimeout
+}
+
+// recordSuccess 记录成功调用
+func (cb *circuitBreaker) recordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != circuitClosed {
+		cb.logger.Info("Database circuit breaker closed")
+	}
+	cb.state = circuitClosed
+	cb.failures = 0
+	cb.probing = false
+}
+
+// recordFailure 记录因连接故障失败的调用
+func (cb *circuitBreaker) recordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.probing = false
+	if cb.state == circuitHalfOpen || cb.failures >= cb.threshold {
+		if cb.state != circuitOpen {
+			cb.logger.Warn("Database circuit breaker opened",
+				zap.Int("consecutive_failures", cb.failures),
+				zap.Duration("open_timeout", cb.openTimeout))
+		}
+		cb.state = circuitOpen
+		cb.openedAt = time.Now()
+	}
+}
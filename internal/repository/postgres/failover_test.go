@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// fakeDB 按顺序返回预设错误的DB，错误用完后调用成功
+type fakeDB struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeDB) next() error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *fakeDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, f.next()
+}
+
+func (f *fakeDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return fakeRow{err: f.next()}
+}
+
+func (f *fakeDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("UPDATE 1"), f.next()
+}
+
+func (f *fakeDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return nil, f.next()
+}
+
+type fakeRow struct{ err error }
+
+func (r fakeRow) Scan(dest ...any) error { return r.err }
+
+func newTestFailoverDB(t *testing.T, db DB, threshold int) *FailoverDB {
+	return NewFailoverDB(db, &config.DatabaseFailoverConfig{
+		MaxAttempts:        3,
+		RetryBackoff:       time.Millisecond,
+		BreakerThreshold:   threshold,
+		BreakerOpenTimeout: 20 * time.Millisecond,
+	}, zaptest.NewLogger(t))
+}
+
+func TestFailoverDB_RetriesTransientErrors(t *testing.T) {
+	db := &fakeDB{errs: []error{&pgconn.PgError{Code: "57P01"}, &pgconn.PgError{Code: "40001"}}}
+	f := newTestFailoverDB(t, db, 10)
+
+	_, err := f.Exec(context.Background(), "UPDATE users SET status = $1 WHERE id = $2", "active", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, db.calls, "服务端拒绝执行的错误应重试")
+}
+
+func TestFailoverDB_IdempotencyCheck(t *testing.T) {
+	// 语句发送后连接断开，写语句结果未知，不能重试
+	db := &fakeDB{errs: []error{io.ErrUnexpectedEOF}}
+	f := newTestFailoverDB(t, db, 10)
+	_, err := f.Exec(context.Background(), "INSERT INTO query_history (sql_query) VALUES ($1)", "SELECT 1")
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, 1, db.calls, "结果未知的写语句不应重试")
+
+	// 只读语句可以重试
+	db = &fakeDB{errs: []error{io.ErrUnexpectedEOF}}
+	f = newTestFailoverDB(t, db, 10)
+	var id int64
+	require.NoError(t, f.QueryRow(context.Background(), "SELECT id FROM users WHERE username = $1", "alice").Scan(&id))
+	assert.Equal(t, 2, db.calls, "只读语句应重试")
+
+	// 可写CTE视为写语句
+	db = &fakeDB{errs: []error{io.ErrUnexpectedEOF}}
+	f = newTestFailoverDB(t, db, 10)
+	_, err = f.Query(context.Background(), "WITH d AS (DELETE FROM sessions RETURNING id) SELECT count(*) FROM d")
+	require.Error(t, err)
+	assert.Equal(t, 1, db.calls)
+}
+
+func TestFailoverDB_BusinessErrorsNotRetried(t *testing.T) {
+	db := &fakeDB{errs: []error{pgx.ErrNoRows, &pgconn.PgError{Code: "23505"}}}
+	f := newTestFailoverDB(t, db, 1)
+
+	var id int64
+	assert.ErrorIs(t, f.QueryRow(context.Background(), "SELECT id FROM users WHERE id = $1", 1).Scan(&id), pgx.ErrNoRows)
+	_, err := f.Exec(context.Background(), "INSERT INTO users (username) VALUES ($1)", "alice")
+	require.Error(t, err)
+	assert.Equal(t, 2, db.calls)
+	assert.True(t, f.Available(), "业务错误不应触发熔断")
+}
+
+func TestFailoverDB_ServerErrorsDoNotOpenBreaker(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	db := &fakeDB{errs: []error{deadlock, deadlock, deadlock, deadlock, deadlock, deadlock}}
+	f := newTestFailoverDB(t, db, 1)
+
+	// 重试用完后返回死锁错误，但数据库仍在响应，不计入熔断
+	for i := 0; i < 2; i++ {
+		_, err := f.Exec(context.Background(), "UPDATE users SET status = $1 WHERE id = $2", "active", 1)
+		require.Error(t, err)
+	}
+	assert.Equal(t, 6, db.calls)
+	assert.True(t, f.Available(), "服务端可重试错误不应触发熔断")
+}
+
+func TestFailoverDB_CircuitBreaker(t *testing.T) {
+	connErr := &pgconn.ConnectError{}
+	db := &fakeDB{errs: []error{connErr, connErr, connErr, connErr, connErr, connErr}}
+	f := newTestFailoverDB(t, db, 2)
+	ctx := context.Background()
+
+	// 每次调用重试3次后失败，连续2次后熔断
+	_, err := f.Begin(ctx)
+	require.Error(t, err)
+	assert.True(t, f.Available())
+	_, err = f.Begin(ctx)
+	require.Error(t, err)
+	assert.False(t, f.Available(), "连续连接故障后应熔断")
+
+	_, err = f.Begin(ctx)
+	assert.True(t, repository.IsUnavailable(err), "熔断期间应直接返回不可用错误")
+	assert.Equal(t, 6, db.calls, "熔断期间不应访问数据库")
+
+	// 超时后放行探测调用，成功则关闭熔断
+	time.Sleep(25 * time.Millisecond)
+	_, err = f.Begin(ctx)
+	require.NoError(t, err)
+	assert.True(t, f.Available())
+}
+
+func TestFailoverDB_ContextCanceled(t *testing.T) {
+	db := &fakeDB{errs: []error{errors.Join(context.Canceled, io.EOF)}}
+	f := newTestFailoverDB(t, db, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := f.Query(ctx, "SELECT 1")
+	require.Error(t, err)
+	assert.Equal(t, 1, db.calls)
+	assert.True(t, f.Available(), "调用方取消不应计入熔断")
+}
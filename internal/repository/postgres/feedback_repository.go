@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
 	"chat2sql-go/internal/repository"
@@ -14,12 +13,12 @@ import (
 
 // PostgreSQLFeedbackRepository PostgreSQL反馈Repository实现
 type PostgreSQLFeedbackRepository struct {
-	pool   *pgxpool.Pool
+	pool   DB
 	logger *zap.Logger
 }
 
 // NewPostgreSQLFeedbackRepository 创建PostgreSQL反馈Repository实例
-func NewPostgreSQLFeedbackRepository(pool *pgxpool.Pool, logger *zap.Logger) repository.FeedbackRepository {
+func NewPostgreSQLFeedbackRepository(pool DB, logger *zap.Logger) repository.FeedbackRepository {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -43,12 +42,17 @@ func (r *PostgreSQLFeedbackRepository) Create(ctx context.Context, feedback *rep
 			$11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21
 		) RETURNING id`
 
+	// 已设置创建时间时沿用（反馈时间早于写入时间），否则为当前时间
 	now := time.Now()
+	createTime := feedback.CreateTime
+	if createTime.IsZero() {
+		createTime = now
+	}
 	err := r.pool.QueryRow(ctx, query,
 		feedback.QueryID, feedback.UserID, feedback.UserQuery, feedback.GeneratedSQL, feedback.ExpectedSQL,
 		feedback.IsCorrect, feedback.UserRating, feedback.FeedbackText, feedback.Category, feedback.Difficulty,
 		feedback.ErrorType, feedback.ErrorDetails, feedback.ProcessingTime, feedback.TokensUsed, feedback.ModelUsed,
-		feedback.ConnectionID, feedback.UserID, now, feedback.UserID, now, false,
+		feedback.ConnectionID, feedback.UserID, createTime, feedback.UserID, now, false,
 	).Scan(&feedback.ID)
 
 	if err != nil {
@@ -58,7 +62,7 @@ func (r *PostgreSQLFeedbackRepository) Create(ctx context.Context, feedback *rep
 		return fmt.Errorf("创建反馈记录失败: %w", err)
 	}
 
-	feedback.CreateTime = now
+	feedback.CreateTime = createTime
 	feedback.UpdateTime = now
 
 	r.logger.Debug("反馈记录创建成功", 
@@ -221,13 +225,52 @@ func (r *PostgreSQLFeedbackRepository) ListByUser(ctx context.Context, userID in
 	return feedbacks, nil
 }
 
-// 为了简化实现，其他方法暂时返回空实现
-// 实际项目中需要逐一完整实现所有接口方法
-
+// ListByTimeRange 获取创建时间在[startTime, endTime)内的反馈记录，按创建时间正序排列
 func (r *PostgreSQLFeedbackRepository) ListByTimeRange(ctx context.Context, startTime, endTime time.Time, limit, offset int) ([]*repository.Feedback, error) {
-	return []*repository.Feedback{}, nil
+	query := `
+		SELECT id, query_id, user_id, user_query, generated_sql, expected_sql,
+			   is_correct, user_rating, feedback_text, category, difficulty,
+			   error_type, error_details, processing_time, tokens_used, model_used,
+			   connection_id, create_by, create_time, update_by, update_time, is_deleted
+		FROM feedbacks
+		WHERE create_time >= $1 AND create_time < $2 AND is_deleted = false
+		ORDER BY create_time, id
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.pool.Query(ctx, query, startTime, endTime, limit, offset)
+	if err != nil {
+		r.logger.Error("按时间范围获取反馈列表失败", zap.Time("start_time", startTime), zap.Time("end_time", endTime), zap.Error(err))
+		return nil, fmt.Errorf("按时间范围获取反馈列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var feedbacks []*repository.Feedback
+	for rows.Next() {
+		feedback := &repository.Feedback{}
+		err := rows.Scan(
+			&feedback.ID, &feedback.QueryID, &feedback.UserID, &feedback.UserQuery, &feedback.GeneratedSQL, &feedback.ExpectedSQL,
+			&feedback.IsCorrect, &feedback.UserRating, &feedback.FeedbackText, &feedback.Category, &feedback.Difficulty,
+			&feedback.ErrorType, &feedback.ErrorDetails, &feedback.ProcessingTime, &feedback.TokensUsed, &feedback.ModelUsed,
+			&feedback.ConnectionID, &feedback.CreateBy, &feedback.CreateTime, &feedback.UpdateBy, &feedback.UpdateTime, &feedback.IsDeleted,
+		)
+		if err != nil {
+			r.logger.Error("扫描反馈记录失败", zap.Error(err))
+			return nil, fmt.Errorf("扫描反馈记录失败: %w", err)
+		}
+		feedbacks = append(feedbacks, feedback)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.logger.Error("遍历反馈记录失败", zap.Error(err))
+		return nil, fmt.Errorf("遍历反馈记录失败: %w", err)
+	}
+
+	return feedbacks, nil
 }
 
+// 为了简化实现，其他方法暂时返回空实现
+// 实际项目中需要逐一完整实现所有接口方法
+
 func (r *PostgreSQLFeedbackRepository) ListByCorrectness(ctx context.Context, isCorrect bool, limit, offset int) ([]*repository.Feedback, error) {
 	return []*repository.Feedback{}, nil
 }
@@ -296,6 +339,15 @@ func (r *PostgreSQLFeedbackRepository) BatchUpdateProcessed(ctx context.Context,
 	return nil
 }
 
+// CleanupOldFeedbacks 物理删除创建时间早于beforeDate的反馈记录（含已软删除的记录），返回删除数量
 func (r *PostgreSQLFeedbackRepository) CleanupOldFeedbacks(ctx context.Context, beforeDate time.Time) (int64, error) {
-	return 0, nil
+	result, err := r.pool.Exec(ctx, `DELETE FROM feedbacks WHERE create_time < $1`, beforeDate)
+	if err != nil {
+		r.logger.Error("清理过期反馈记录失败", zap.Time("before", beforeDate), zap.Error(err))
+		return 0, fmt.Errorf("清理过期反馈记录失败: %w", err)
+	}
+
+	deleted := result.RowsAffected()
+	r.logger.Debug("过期反馈记录清理完成", zap.Time("before", beforeDate), zap.Int64("deleted", deleted))
+	return deleted, nil
 }
\ No newline at end of file
@@ -0,0 +1,342 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// folderQuerier 连接池与事务的公共查询接口，文件夹与保存查询Repository共用
+type folderQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgreSQLFolderRepository PostgreSQL保存查询文件夹Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLFolderRepository struct {
+	db     folderQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLFolderRepository 创建保存查询文件夹Repository实例
+func NewPostgreSQLFolderRepository(pool DB, logger *zap.Logger) repository.FolderRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLFolderRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const folderColumns = `id, workspace_id, parent_id, name, path,
+	create_by, create_time, update_by, update_time, is_deleted`
+
+// folderPath 子文件夹的Path前缀，parent为空表示根目录
+func folderPath(parent *repository.QueryFolder) string {
+	if parent == nil {
+		return "/"
+	}
+	return parent.Path
+}
+
+// Create 创建文件夹，ID与Path在同一条语句中生成
+func (r *PostgreSQLFolderRepository) Create(ctx context.Context, folder *repository.QueryFolder, parent *repository.QueryFolder) error {
+	const sqlQuery = `
+		WITH next AS (SELECT nextval(pg_get_serial_sequence('query_folders', 'id')) AS id)
+		INSERT INTO query_folders (id, workspace_id, parent_id, name, path,
+			create_by, create_time, update_by, update_time, is_deleted)
+		SELECT next.id, $1, $2, $3, $4::text || next.id::text || '/', $5, $6, $5, $6, false
+		FROM next
+		RETURNING id, path`
+
+	now := time.Now().UTC()
+	createBy := int64(0)
+	if folder.CreateBy != nil {
+		createBy = *folder.CreateBy
+	}
+
+	folder.ParentID = nil
+	if parent != nil {
+		folder.ParentID = &parent.ID
+	}
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		folder.WorkspaceID,
+		folder.ParentID,
+		folder.Name,
+		folderPath(parent),
+		createBy,
+		now,
+	).Scan(&folder.ID, &folder.Path)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("同名文件夹已存在: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("创建文件夹失败",
+			zap.Int64("workspace_id", folder.WorkspaceID),
+			zap.String("name", folder.Name),
+			zap.Error(err))
+		return fmt.Errorf("创建文件夹失败: %w", err)
+	}
+
+	folder.UpdateBy = folder.CreateBy
+	folder.CreateTime = now
+	folder.UpdateTime = now
+	folder.IsDeleted = false
+	return nil
+}
+
+// GetByID 根据ID获取文件夹
+func (r *PostgreSQLFolderRepository) GetByID(ctx context.Context, id int64) (*repository.QueryFolder, error) {
+	sqlQuery := `SELECT ` + folderColumns + ` FROM query_folders WHERE id = $1 AND is_deleted = false`
+
+	rows, err := r.db.Query(ctx, sqlQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件夹失败: %w", err)
+	}
+	folders, err := scanFolders(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("文件夹不存在: %w", repository.ErrNotFound)
+	}
+	return folders[0], nil
+}
+
+// GetByIDs 批量获取文件夹，不存在或已删除的ID被忽略
+func (r *PostgreSQLFolderRepository) GetByIDs(ctx context.Context, ids []int64) ([]*repository.QueryFolder, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	sqlQuery := `SELECT ` + folderColumns + ` FROM query_folders WHERE id = ANY($1) AND is_deleted = false`
+
+	rows, err := r.db.Query(ctx, sqlQuery, ids)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件夹失败: %w", err)
+	}
+	return scanFolders(rows)
+}
+
+// ListChildren 按名称列出子文件夹
+func (r *PostgreSQLFolderRepository) ListChildren(ctx context.Context, workspaceID int64, parentID *int64) ([]*repository.QueryFolder, error) {
+	sqlQuery := `SELECT ` + folderColumns + `
+		FROM query_folders
+		WHERE workspace_id = $1 AND parent_id IS NOT DISTINCT FROM $2::bigint AND is_deleted = false
+		ORDER BY lower(name)`
+
+	rows, err := r.db.Query(ctx, sqlQuery, workspaceID, parentID)
+	if err != nil {
+		r.logger.Error("查询子文件夹失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return nil, fmt.Errorf("查询子文件夹失败: %w", err)
+	}
+	return scanFolders(rows)
+}
+
+// Rename 重命名文件夹
+func (r *PostgreSQLFolderRepository) Rename(ctx context.Context, id int64, name string, updateBy int64) error {
+	const sqlQuery = `
+		UPDATE query_folders
+		SET name = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, name, updateBy, time.Now().UTC())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("同名文件夹已存在: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("重命名文件夹失败", zap.Int64("folder_id", id), zap.Error(err))
+		return fmt.Errorf("重命名文件夹失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("文件夹不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// Move 移动文件夹，一条语句同时更新自身的父文件夹与整个子树的Path
+// 调用方需保证newParent不在folder的子树中
+func (r *PostgreSQLFolderRepository) Move(ctx context.Context, folder *repository.QueryFolder, newParent *repository.QueryFolder, updateBy int64) error {
+	const sqlQuery = `
+		UPDATE query_folders
+		SET path = $3::text || substr(path, length($2::text) + 1),
+			parent_id = CASE WHEN id = $1 THEN $4::bigint ELSE parent_id END,
+			update_by = $5, update_time = $6
+		WHERE workspace_id = $7 AND path LIKE $2::text || '%' AND is_deleted = false`
+
+	var parentID *int64
+	if newParent != nil {
+		parentID = &newParent.ID
+	}
+	newPath := folderPath(newParent) + strconv.FormatInt(folder.ID, 10) + "/"
+	now := time.Now().UTC()
+
+	result, err := r.db.Exec(ctx, sqlQuery, folder.ID, folder.Path, newPath, parentID, updateBy, now, folder.WorkspaceID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("目标文件夹下已有同名文件夹: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("移动文件夹失败",
+			zap.Int64("folder_id", folder.ID),
+			zap.Any("parent_id", parentID),
+			zap.Error(err))
+		return fmt.Errorf("移动文件夹失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("文件夹不存在: %w", repository.ErrNotFound)
+	}
+
+	folder.ParentID = parentID
+	folder.Path = newPath
+	folder.UpdateBy = &updateBy
+	folder.UpdateTime = now
+	return nil
+}
+
+// Delete 软删除空文件夹
+func (r *PostgreSQLFolderRepository) Delete(ctx context.Context, id int64, deleteBy int64) error {
+	const sqlQuery = `
+		UPDATE query_folders
+		SET is_deleted = true, update_by = $2, update_time = $3
+		WHERE id = $1 AND is_deleted = false
+			AND NOT EXISTS (SELECT 1 FROM query_folders WHERE parent_id = $1 AND is_deleted = false)
+			AND NOT EXISTS (SELECT 1 FROM saved_queries WHERE folder_id = $1 AND is_deleted = false)`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, deleteBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("删除文件夹失败", zap.Int64("folder_id", id), zap.Error(err))
+		return fmt.Errorf("删除文件夹失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: 文件夹不为空", repository.ErrInvalidInput)
+	}
+	return nil
+}
+
+// SetPermission 授予或修改用户在文件夹上的权限
+func (r *PostgreSQLFolderRepository) SetPermission(ctx context.Context, permission *repository.FolderPermission) error {
+	const sqlQuery = `
+		INSERT INTO query_folder_permissions (folder_id, user_id, level, create_by, create_time)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (folder_id, user_id)
+		DO UPDATE SET level = EXCLUDED.level, create_by = EXCLUDED.create_by, create_time = EXCLUDED.create_time`
+
+	permission.CreateTime = time.Now().UTC()
+	_, err := r.db.Exec(ctx, sqlQuery,
+		permission.FolderID,
+		permission.UserID,
+		permission.Level,
+		permission.CreateBy,
+		permission.CreateTime,
+	)
+	if err != nil {
+		r.logger.Error("设置文件夹权限失败",
+			zap.Int64("folder_id", permission.FolderID),
+			zap.Int64("user_id", permission.UserID),
+			zap.Error(err))
+		return fmt.Errorf("设置文件夹权限失败: %w", err)
+	}
+	return nil
+}
+
+// RemovePermission 撤销用户在文件夹上的权限
+func (r *PostgreSQLFolderRepository) RemovePermission(ctx context.Context, folderID, userID int64) error {
+	const sqlQuery = `DELETE FROM query_folder_permissions WHERE folder_id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, sqlQuery, folderID, userID)
+	if err != nil {
+		r.logger.Error("撤销文件夹权限失败",
+			zap.Int64("folder_id", folderID),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		return fmt.Errorf("撤销文件夹权限失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("文件夹权限不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// ListPermissions 列出多个文件夹上的全部授权
+func (r *PostgreSQLFolderRepository) ListPermissions(ctx context.Context, folderIDs []int64) ([]*repository.FolderPermission, error) {
+	if len(folderIDs) == 0 {
+		return nil, nil
+	}
+
+	const sqlQuery = `
+		SELECT folder_id, user_id, level, create_by, create_time
+		FROM query_folder_permissions
+		WHERE folder_id = ANY($1)
+		ORDER BY folder_id, user_id`
+
+	rows, err := r.db.Query(ctx, sqlQuery, folderIDs)
+	if err != nil {
+		r.logger.Error("查询文件夹权限失败", zap.Error(err))
+		return nil, fmt.Errorf("查询文件夹权限失败: %w", err)
+	}
+	defer rows.Close()
+
+	var permissions []*repository.FolderPermission
+	for rows.Next() {
+		p := &repository.FolderPermission{}
+		if err := rows.Scan(&p.FolderID, &p.UserID, &p.Level, &p.CreateBy, &p.CreateTime); err != nil {
+			return nil, fmt.Errorf("扫描文件夹权限失败: %w", err)
+		}
+		permissions = append(permissions, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历文件夹权限失败: %w", err)
+	}
+	return permissions, nil
+}
+
+// scanFolders 扫描文件夹查询结果
+func scanFolders(rows pgx.Rows) ([]*repository.QueryFolder, error) {
+	defer rows.Close()
+
+	var folders []*repository.QueryFolder
+	for rows.Next() {
+		f := &repository.QueryFolder{}
+		if err := rows.Scan(
+			&f.ID,
+			&f.WorkspaceID,
+			&f.ParentID,
+			&f.Name,
+			&f.Path,
+			&f.CreateBy,
+			&f.CreateTime,
+			&f.UpdateBy,
+			&f.UpdateTime,
+			&f.IsDeleted,
+		); err != nil {
+			return nil, fmt.Errorf("扫描文件夹失败: %w", err)
+		}
+		folders = append(folders, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历文件夹失败: %w", err)
+	}
+	return folders, nil
+}
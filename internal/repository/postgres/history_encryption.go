@@ -0,0 +1,462 @@
+package postgres
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// historyCiphertextPrefix 加密文本前缀，完整格式为 enc:v1:<密钥ID>:<base64(nonce+密文)>
+// 密钥ID写在密文中，解密不依赖encryption_key_id列；不带前缀的值按明文处理
+const historyCiphertextPrefix = "enc:v1:"
+
+// HistoryKeyring 查询历史密钥环
+// 用主密钥加解密各工作空间的数据密钥，缓存解密后的数据密钥与用户当前生效的密钥
+type HistoryKeyring struct {
+	master     cipher.AEAD
+	keys       repository.HistoryKeyRepository
+	workspaces repository.WorkspaceRepository
+	cacheTTL   time.Duration
+	logger     *zap.Logger
+
+	now      func() time.Time
+	mutex    sync.RWMutex
+	dataKeys map[int64]cipher.AEAD      // 数据密钥ID -> 解密后的数据密钥
+	active   map[int64]activeHistoryKey // 用户ID -> 当前生效的数据密钥
+}
+
+// activeHistoryKey 用户当前生效数据密钥的缓存项，keyID为0表示所属工作空间未启用加密
+type activeHistoryKey struct {
+	keyID     int64
+	expiresAt time.Time
+}
+
+// NewHistoryKeyring 创建查询历史密钥环，masterKey必须为32字节
+// cacheTTL控制生效密钥的缓存时间，密钥轮换或用户更换工作空间后最多延迟cacheTTL生效
+func NewHistoryKeyring(masterKey []byte, keys repository.HistoryKeyRepository, workspaces repository.WorkspaceRepository, cacheTTL time.Duration, logger *zap.Logger) (*HistoryKeyring, error) {
+	if len(masterKey) != 32 {
+		return nil, fmt.Errorf("主密钥长度必须为32字节，当前为%d字节", len(masterKey))
+	}
+	master, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &HistoryKeyring{
+		master:     master,
+		keys:       keys,
+		workspaces: workspaces,
+		cacheTTL:   cacheTTL,
+		logger:     logger,
+		now:        time.Now,
+		dataKeys:   make(map[int64]cipher.AEAD),
+		active:     make(map[int64]activeHistoryKey),
+	}, nil
+}
+
+// Rotate 为工作空间生成新的数据密钥，首次调用即启用查询历史加密
+// 旧密钥保留用于解密，调用Reencrypt把已有记录迁移到新密钥
+func (k *HistoryKeyring) Rotate(ctx context.Context, workspaceID, operatorID int64) (*repository.HistoryEncryptionKey, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("生成数据密钥失败: %w", err)
+	}
+	wrapped, err := sealHistory(k.master, raw)
+	if err != nil {
+		return nil, fmt.Errorf("加密数据密钥失败: %w", err)
+	}
+
+	key, err := k.keys.Rotate(ctx, workspaceID, wrapped, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	k.invalidate()
+	return key, nil
+}
+
+// Disable 停用工作空间的查询历史加密，之后写入的记录为明文
+// 已加密的记录仍可解密，调用Reencrypt将其还原为明文
+func (k *HistoryKeyring) Disable(ctx context.Context, workspaceID, operatorID int64) error {
+	if err := k.keys.Disable(ctx, workspaceID, operatorID); err != nil {
+		return err
+	}
+	k.invalidate()
+	return nil
+}
+
+// Reencrypt 将工作空间的查询历史迁移到当前生效的数据密钥，返回处理的记录数
+// 工作空间已停用加密时把加密记录还原为明文
+func (k *HistoryKeyring) Reencrypt(ctx context.Context, workspaceID int64, batchSize int) (int64, error) {
+	var activeKeyID int64
+	var activeKey cipher.AEAD
+	key, err := k.keys.GetActive(ctx, workspaceID)
+	switch {
+	case err == nil:
+		activeKeyID = key.ID
+		if activeKey, err = k.dataKey(ctx, key.ID); err != nil {
+			return 0, err
+		}
+	case !errors.Is(err, repository.ErrNotFound):
+		return 0, err
+	}
+
+	var total int64
+	for {
+		queries, err := k.keys.ListStaleHistory(ctx, workspaceID, activeKeyID, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(queries) == 0 {
+			return total, nil
+		}
+
+		for _, query := range queries {
+			if err := k.decryptQuery(ctx, query); err != nil {
+				return total, fmt.Errorf("解密查询历史%d失败: %w", query.ID, err)
+			}
+			query.EncryptionKeyID = nil
+			if activeKey != nil {
+				if err := k.encryptQuery(query, activeKeyID, activeKey); err != nil {
+					return total, err
+				}
+			}
+			if err := k.keys.UpdateHistoryText(ctx, query.ID, query.NaturalQuery, query.GeneratedSQL, query.EncryptionKeyID); err != nil {
+				return total, err
+			}
+			total++
+		}
+
+		k.logger.Info("查询历史重新加密进度",
+			zap.Int64("workspace_id", workspaceID),
+			zap.Int64("active_key_id", activeKeyID),
+			zap.Int64("processed", total))
+	}
+}
+
+// activeKey 获取用户所属工作空间当前生效的数据密钥，未启用加密时返回0和nil
+func (k *HistoryKeyring) activeKey(ctx context.Context, userID int64) (int64, cipher.AEAD, error) {
+	k.mutex.RLock()
+	entry, ok := k.active[userID]
+	k.mutex.RUnlock()
+
+	if !ok || k.now().After(entry.expiresAt) {
+		workspace, err := k.workspaces.GetByUserID(ctx, userID)
+		if err != nil {
+			return 0, nil, fmt.Errorf("获取用户工作空间失败: %w", err)
+		}
+
+		entry = activeHistoryKey{expiresAt: k.now().Add(k.cacheTTL)}
+		key, err := k.keys.GetActive(ctx, workspace.ID)
+		switch {
+		case err == nil:
+			entry.keyID = key.ID
+		case !errors.Is(err, repository.ErrNotFound):
+			return 0, nil, err
+		}
+
+		k.mutex.Lock()
+		k.active[userID] = entry
+		k.mutex.Unlock()
+	}
+
+	if entry.keyID == 0 {
+		return 0, nil, nil
+	}
+	aead, err := k.dataKey(ctx, entry.keyID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return entry.keyID, aead, nil
+}
+
+// dataKey 获取并解密数据密钥，数据密钥不会变化因此永久缓存
+func (k *HistoryKeyring) dataKey(ctx context.Context, keyID int64) (cipher.AEAD, error) {
+	k.mutex.RLock()
+	aead, ok := k.dataKeys[keyID]
+	k.mutex.RUnlock()
+	if ok {
+		return aead, nil
+	}
+
+	key, err := k.keys.GetByID(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据密钥%d失败: %w", keyID, err)
+	}
+	raw, err := openHistory(k.master, key.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("解密数据密钥%d失败，主密钥可能不正确: %w", keyID, err)
+	}
+	if aead, err = newGCM(raw); err != nil {
+		return nil, err
+	}
+
+	k.mutex.Lock()
+	k.dataKeys[keyID] = aead
+	k.mutex.Unlock()
+	return aead, nil
+}
+
+// invalidate 清空生效密钥缓存
+func (k *HistoryKeyring) invalidate() {
+	k.mutex.Lock()
+	k.active = make(map[int64]activeHistoryKey)
+	k.mutex.Unlock()
+}
+
+// encryptQuery 用指定数据密钥加密问题与SQL
+func (k *HistoryKeyring) encryptQuery(query *repository.QueryHistory, keyID int64, aead cipher.AEAD) error {
+	prefix := historyCiphertextPrefix + strconv.FormatInt(keyID, 10) + ":"
+	for _, field := range []*string{&query.NaturalQuery, &query.GeneratedSQL} {
+		sealed, err := sealHistory(aead, []byte(*field))
+		if err != nil {
+			return fmt.Errorf("加密查询历史失败: %w", err)
+		}
+		*field = prefix + sealed
+	}
+	query.EncryptionKeyID = &keyID
+	return nil
+}
+
+// decryptQuery 解密问题与SQL，明文字段原样保留
+func (k *HistoryKeyring) decryptQuery(ctx context.Context, query *repository.QueryHistory) error {
+	for _, field := range []*string{&query.NaturalQuery, &query.GeneratedSQL} {
+		if !strings.HasPrefix(*field, historyCiphertextPrefix) {
+			continue
+		}
+
+		idText, sealed, ok := strings.Cut(strings.TrimPrefix(*field, historyCiphertextPrefix), ":")
+		keyID, err := strconv.ParseInt(idText, 10, 64)
+		if !ok || err != nil {
+			return errors.New("密文格式错误")
+		}
+		aead, err := k.dataKey(ctx, keyID)
+		if err != nil {
+			return err
+		}
+		plaintext, err := openHistory(aead, sealed)
+		if err != nil {
+			return fmt.Errorf("解密失败: %w", err)
+		}
+		*field = string(plaintext)
+	}
+	return nil
+}
+
+// newGCM 创建AES-256-GCM
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("创建AES cipher失败: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealHistory 加密并返回base64(nonce+密文)
+func sealHistory(aead cipher.AEAD, plaintext []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成nonce失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// openHistory 解密base64(nonce+密文)
+func openHistory(aead cipher.AEAD, encoded string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("base64解码失败: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("密文长度不足")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
+
+// EncryptedQueryHistoryRepository 透明加密的查询历史Repository
+// 写入时用用户所属工作空间的生效密钥加密问题与SQL，读取时按密文中的密钥ID解密；
+// 加密记录无法参与全文搜索，搜索只会命中明文记录
+type EncryptedQueryHistoryRepository struct {
+	repository.QueryHistoryRepository
+	keyring *HistoryKeyring
+}
+
+// NewEncryptedQueryHistoryRepository 创建透明加密的查询历史Repository
+func NewEncryptedQueryHistoryRepository(inner repository.QueryHistoryRepository, keyring *HistoryKeyring) repository.QueryHistoryRepository {
+	return &EncryptedQueryHistoryRepository{
+		QueryHistoryRepository: inner,
+		keyring:                keyring,
+	}
+}
+
+// Create 加密后创建查询历史记录，调用方持有的记录保持明文
+func (r *EncryptedQueryHistoryRepository) Create(ctx context.Context, query *repository.QueryHistory) error {
+	return r.write(ctx, query, r.QueryHistoryRepository.Create)
+}
+
+// Update 加密后更新查询历史记录，调用方持有的记录保持明文
+func (r *EncryptedQueryHistoryRepository) Update(ctx context.Context, query *repository.QueryHistory) error {
+	return r.write(ctx, query, r.QueryHistoryRepository.Update)
+}
+
+// GetByID 获取并解密查询历史记录
+func (r *EncryptedQueryHistoryRepository) GetByID(ctx context.Context, id int64) (*repository.QueryHistory, error) {
+	query, err := r.QueryHistoryRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return query, r.keyring.decryptQuery(ctx, query)
+}
+
+// ListByUser 分页获取并解密用户的查询历史
+func (r *EncryptedQueryHistoryRepository) ListByUser(ctx context.Context, userID int64, limit, offset int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.ListByUser(ctx, userID, limit, offset)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// ListByConnection 分页获取并解密连接的查询历史
+func (r *EncryptedQueryHistoryRepository) ListByConnection(ctx context.Context, connectionID int64, limit, offset int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.ListByConnection(ctx, connectionID, limit, offset)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// ListPage 分页获取并解密用户的查询历史，关键字搜索只会命中明文记录
+func (r *EncryptedQueryHistoryRepository) ListPage(ctx context.Context, filter repository.QueryHistoryFilter, after *repository.QueryHistoryKeyset, limit, offset int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.ListPage(ctx, filter, after, limit, offset)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// ListFailuresByConnection 获取并解密连接的失败记录
+func (r *EncryptedQueryHistoryRepository) ListFailuresByConnection(ctx context.Context, connectionID int64, since time.Time, limit int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.ListFailuresByConnection(ctx, connectionID, since, limit)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// ListByStatus 分页获取并解密指定状态的查询历史
+func (r *EncryptedQueryHistoryRepository) ListByStatus(ctx context.Context, status repository.QueryStatus, limit, offset int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.ListByStatus(ctx, status, limit, offset)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// ListRecent 获取并解密用户最近的查询历史
+func (r *EncryptedQueryHistoryRepository) ListRecent(ctx context.Context, userID int64, hours int, limit int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.ListRecent(ctx, userID, hours, limit)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// GetSlowQueries 获取并解密慢查询列表
+func (r *EncryptedQueryHistoryRepository) GetSlowQueries(ctx context.Context, minExecutionTime int32, limit int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.GetSlowQueries(ctx, minExecutionTime, limit)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// SearchByNaturalQuery 按问题关键字搜索，只会命中明文记录
+func (r *EncryptedQueryHistoryRepository) SearchByNaturalQuery(ctx context.Context, userID int64, keyword string, limit, offset int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.SearchByNaturalQuery(ctx, userID, keyword, limit, offset)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// SearchBySQL 按SQL关键字搜索，只会命中明文记录
+func (r *EncryptedQueryHistoryRepository) SearchBySQL(ctx context.Context, userID int64, keyword string, limit, offset int) ([]*repository.QueryHistory, error) {
+	queries, err := r.QueryHistoryRepository.SearchBySQL(ctx, userID, keyword, limit, offset)
+	return r.decryptAll(ctx, queries, err)
+}
+
+// write 用生效密钥加密问题与SQL后写入，写入完成后恢复调用方记录中的明文
+func (r *EncryptedQueryHistoryRepository) write(ctx context.Context, query *repository.QueryHistory, store func(context.Context, *repository.QueryHistory) error) error {
+	keyID, aead, err := r.keyring.activeKey(ctx, query.UserID)
+	if err != nil {
+		return err
+	}
+	if aead == nil {
+		query.EncryptionKeyID = nil
+		return store(ctx, query)
+	}
+
+	naturalQuery, generatedSQL := query.NaturalQuery, query.GeneratedSQL
+	defer func() {
+		query.NaturalQuery, query.GeneratedSQL = naturalQuery, generatedSQL
+	}()
+
+	if err := r.keyring.encryptQuery(query, keyID, aead); err != nil {
+		return err
+	}
+	return store(ctx, query)
+}
+
+// GetPopularQueries 获取热门查询统计
+// 同一问题每次加密的密文都不同，无法在数据库中分组，这里取回按问题文本分组的统计，
+// 解密后按明文合并，再按次数与成功率排序；成功率与平均执行时间按各组次数加权合并
+func (r *EncryptedQueryHistoryRepository) GetPopularQueries(ctx context.Context, limit int, days int) ([]*repository.PopularQuery, error) {
+	since := time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour)
+	groups, err := r.keyring.keys.GroupHistoryByQuestion(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]*repository.PopularQuery)
+	for _, group := range groups {
+		query := &repository.QueryHistory{NaturalQuery: group.NaturalQuery}
+		if err := r.keyring.decryptQuery(ctx, query); err != nil {
+			return nil, fmt.Errorf("解密热门查询失败: %w", err)
+		}
+
+		total, ok := merged[query.NaturalQuery]
+		if !ok {
+			total = &repository.PopularQuery{NaturalQuery: query.NaturalQuery}
+			merged[query.NaturalQuery] = total
+		}
+		count := float64(total.QueryCount + group.QueryCount)
+		total.SuccessRate = (total.SuccessRate*float64(total.QueryCount) + group.SuccessRate*float64(group.QueryCount)) / count
+		total.AvgExecTime = (total.AvgExecTime*float64(total.QueryCount) + group.AvgExecTime*float64(group.QueryCount)) / count
+		total.QueryCount += group.QueryCount
+	}
+
+	popular := make([]*repository.PopularQuery, 0, len(merged))
+	for _, query := range merged {
+		if query.QueryCount > 1 {
+			popular = append(popular, query)
+		}
+	}
+	sort.Slice(popular, func(i, j int) bool {
+		if popular[i].QueryCount != popular[j].QueryCount {
+			return popular[i].QueryCount > popular[j].QueryCount
+		}
+		if popular[i].SuccessRate != popular[j].SuccessRate {
+			return popular[i].SuccessRate > popular[j].SuccessRate
+		}
+		return popular[i].NaturalQuery < popular[j].NaturalQuery
+	})
+	if limit >= 0 && len(popular) > limit {
+		popular = popular[:limit]
+	}
+	return popular, nil
+}
+
+// decryptAll 解密内层Repository返回的查询历史列表
+func (r *EncryptedQueryHistoryRepository) decryptAll(ctx context.Context, queries []*repository.QueryHistory, err error) ([]*repository.QueryHistory, error) {
+	if err != nil {
+		return nil, err
+	}
+	for _, query := range queries {
+		if err := r.keyring.decryptQuery(ctx, query); err != nil {
+			return nil, fmt.Errorf("解密查询历史%d失败: %w", query.ID, err)
+		}
+	}
+	return queries, nil
+}
@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// memoryHistoryKeyRepository 内存数据密钥Repository，同时保存查询历史行
+type memoryHistoryKeyRepository struct {
+	keys    []*repository.HistoryEncryptionKey
+	history map[int64]*repository.QueryHistory
+}
+
+func (m *memoryHistoryKeyRepository) GetActive(ctx context.Context, workspaceID int64) (*repository.HistoryEncryptionKey, error) {
+	for _, key := range m.keys {
+		if key.WorkspaceID == workspaceID && key.Status == string(repository.HistoryKeyActive) {
+			return key, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memoryHistoryKeyRepository) GetByID(ctx context.Context, id int64) (*repository.HistoryEncryptionKey, error) {
+	for _, key := range m.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memoryHistoryKeyRepository) ListByWorkspace(ctx context.Context, workspaceID int64) ([]*repository.HistoryEncryptionKey, error) {
+	return m.keys, nil
+}
+
+func (m *memoryHistoryKeyRepository) Rotate(ctx context.Context, workspaceID int64, wrappedKey string, operatorID int64) (*repository.HistoryEncryptionKey, error) {
+	_ = m.Disable(ctx, workspaceID, operatorID)
+	key := &repository.HistoryEncryptionKey{
+		WorkspaceID: workspaceID,
+		Version:     len(m.keys) + 1,
+		WrappedKey:  wrappedKey,
+		Status:      string(repository.HistoryKeyActive),
+	}
+	key.ID = int64(100 + len(m.keys))
+	m.keys = append(m.keys, key)
+	return key, nil
+}
+
+func (m *memoryHistoryKeyRepository) Disable(ctx context.Context, workspaceID int64, operatorID int64) error {
+	for _, key := range m.keys {
+		key.Status = string(repository.HistoryKeyRetired)
+	}
+	return nil
+}
+
+func (m *memoryHistoryKeyRepository) ListStaleHistory(ctx context.Context, workspaceID, activeKeyID int64, limit int) ([]*repository.QueryHistory, error) {
+	var stale []*repository.QueryHistory
+	for _, row := range m.history {
+		switch {
+		case row.EncryptionKeyID != nil && *row.EncryptionKeyID != activeKeyID,
+			row.EncryptionKeyID == nil && activeKeyID != 0:
+			copied := *row
+			stale = append(stale, &copied)
+		}
+	}
+	return stale, nil
+}
+
+func (m *memoryHistoryKeyRepository) UpdateHistoryText(ctx context.Context, historyID int64, naturalQuery, generatedSQL string, keyID *int64) error {
+	row := m.history[historyID]
+	row.NaturalQuery, row.GeneratedSQL, row.EncryptionKeyID = naturalQuery, generatedSQL, keyID
+	return nil
+}
+
+// GroupHistoryByQuestion 按原始问题文本分组，每条记录成功与否决定成功率，执行时间取ExecutionTime
+func (m *memoryHistoryKeyRepository) GroupHistoryByQuestion(ctx context.Context, since time.Time) ([]*repository.PopularQuery, error) {
+	groups := make(map[string]*repository.PopularQuery)
+	var ordered []*repository.PopularQuery
+	for _, row := range m.history {
+		group, ok := groups[row.NaturalQuery]
+		if !ok {
+			group = &repository.PopularQuery{NaturalQuery: row.NaturalQuery}
+			groups[row.NaturalQuery] = group
+			ordered = append(ordered, group)
+		}
+		var success, execTime float64
+		if row.Status == string(repository.QuerySuccess) {
+			success = 1
+		}
+		if row.ExecutionTime != nil {
+			execTime = float64(*row.ExecutionTime)
+		}
+		count := float64(group.QueryCount)
+		group.SuccessRate = (group.SuccessRate*count + success) / (count + 1)
+		group.AvgExecTime = (group.AvgExecTime*count + execTime) / (count + 1)
+		group.QueryCount++
+	}
+	return ordered, nil
+}
+
+// memoryQueryHistoryRepository 内存查询历史Repository，与密钥Repository共享行
+type memoryQueryHistoryRepository struct {
+	repository.QueryHistoryRepository
+	rows map[int64]*repository.QueryHistory
+}
+
+func (m *memoryQueryHistoryRepository) Create(ctx context.Context, query *repository.QueryHistory) error {
+	query.ID = int64(len(m.rows) + 1)
+	copied := *query
+	m.rows[query.ID] = &copied
+	return nil
+}
+
+func (m *memoryQueryHistoryRepository) GetByID(ctx context.Context, id int64) (*repository.QueryHistory, error) {
+	copied := *m.rows[id]
+	return &copied, nil
+}
+
+func (m *memoryQueryHistoryRepository) ListByUser(ctx context.Context, userID int64, limit, offset int) ([]*repository.QueryHistory, error) {
+	var queries []*repository.QueryHistory
+	for id := int64(1); id <= int64(len(m.rows)); id++ {
+		copied := *m.rows[id]
+		queries = append(queries, &copied)
+	}
+	return queries, nil
+}
+
+// fixedWorkspaceRepository 所有用户都属于默认工作空间
+type fixedWorkspaceRepository struct {
+	repository.WorkspaceRepository
+}
+
+func (fixedWorkspaceRepository) GetByUserID(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	workspace := &repository.Workspace{}
+	workspace.ID = repository.DefaultWorkspaceID
+	return workspace, nil
+}
+
+func TestEncryptedQueryHistoryRepository(t *testing.T) {
+	ctx := context.Background()
+	rows := make(map[int64]*repository.QueryHistory)
+	keys := &memoryHistoryKeyRepository{history: rows}
+
+	keyring, err := NewHistoryKeyring([]byte(strings.Repeat("m", 32)), keys, fixedWorkspaceRepository{}, time.Minute, zap.NewNop())
+	require.NoError(t, err)
+	repo := NewEncryptedQueryHistoryRepository(&memoryQueryHistoryRepository{rows: rows}, keyring)
+
+	// 未启用加密时按明文写入
+	plain := &repository.QueryHistory{UserID: 7, NaturalQuery: "用户总数", GeneratedSQL: "SELECT COUNT(*) FROM users"}
+	require.NoError(t, repo.Create(ctx, plain))
+	assert.Equal(t, "用户总数", rows[plain.ID].NaturalQuery)
+	assert.Nil(t, rows[plain.ID].EncryptionKeyID)
+
+	// 启用加密后写入密文，调用方持有的记录保持明文
+	v1, err := keyring.Rotate(ctx, repository.DefaultWorkspaceID, 1)
+	require.NoError(t, err)
+	secret := &repository.QueryHistory{UserID: 7, NaturalQuery: "各部门薪资", GeneratedSQL: "SELECT dept, SUM(salary) FROM staff GROUP BY dept"}
+	require.NoError(t, repo.Create(ctx, secret))
+	assert.Equal(t, "各部门薪资", secret.NaturalQuery)
+	assert.True(t, strings.HasPrefix(rows[secret.ID].NaturalQuery, historyCiphertextPrefix))
+	assert.NotContains(t, rows[secret.ID].GeneratedSQL, "salary")
+	require.NotNil(t, rows[secret.ID].EncryptionKeyID)
+	assert.Equal(t, v1.ID, *rows[secret.ID].EncryptionKeyID)
+
+	got, err := repo.GetByID(ctx, secret.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "各部门薪资", got.NaturalQuery)
+	assert.Equal(t, secret.GeneratedSQL, got.GeneratedSQL)
+
+	// 轮换后重新加密：旧密钥记录与明文记录都迁移到新密钥，读取结果不变
+	v2, err := keyring.Rotate(ctx, repository.DefaultWorkspaceID, 1)
+	require.NoError(t, err)
+	count, err := keyring.Reencrypt(ctx, repository.DefaultWorkspaceID, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	for _, row := range rows {
+		require.NotNil(t, row.EncryptionKeyID)
+		assert.Equal(t, v2.ID, *row.EncryptionKeyID)
+	}
+
+	list, err := repo.ListByUser(ctx, 7, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "用户总数", list[0].NaturalQuery)
+	assert.Equal(t, "各部门薪资", list[1].NaturalQuery)
+
+	// 停用后重新加密把记录还原为明文
+	require.NoError(t, keyring.Disable(ctx, repository.DefaultWorkspaceID, 1))
+	_, err = keyring.Reencrypt(ctx, repository.DefaultWorkspaceID, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "各部门薪资", rows[secret.ID].NaturalQuery)
+	assert.Nil(t, rows[secret.ID].EncryptionKeyID)
+}
+
+func TestEncryptedQueryHistoryRepository_GetPopularQueries(t *testing.T) {
+	ctx := context.Background()
+	rows := make(map[int64]*repository.QueryHistory)
+	keys := &memoryHistoryKeyRepository{history: rows}
+
+	keyring, err := NewHistoryKeyring([]byte(strings.Repeat("m", 32)), keys, fixedWorkspaceRepository{}, time.Minute, zap.NewNop())
+	require.NoError(t, err)
+	repo := NewEncryptedQueryHistoryRepository(&memoryQueryHistoryRepository{rows: rows}, keyring)
+
+	create := func(question string, status repository.QueryStatus) {
+		require.NoError(t, repo.Create(ctx, &repository.QueryHistory{
+			UserID: 7, NaturalQuery: question, GeneratedSQL: "SELECT 1", Status: string(status),
+		}))
+	}
+
+	// 启用加密前后各写入同一问题，明文与密文记录合并统计
+	create("用户总数", repository.QuerySuccess)
+	_, err = keyring.Rotate(ctx, repository.DefaultWorkspaceID, 1)
+	require.NoError(t, err)
+	create("用户总数", repository.QueryError)
+	create("用户总数", repository.QuerySuccess)
+	create("各部门薪资", repository.QuerySuccess)
+	create("各部门薪资", repository.QuerySuccess)
+	create("只问过一次", repository.QuerySuccess)
+
+	popular, err := repo.GetPopularQueries(ctx, 10, 7)
+	require.NoError(t, err)
+	require.Len(t, popular, 2, "只出现一次的问题不计入热门")
+	assert.Equal(t, "用户总数", popular[0].NaturalQuery)
+	assert.Equal(t, int64(3), popular[0].QueryCount)
+	assert.InDelta(t, 2.0/3.0, popular[0].SuccessRate, 1e-9)
+	assert.Equal(t, "各部门薪资", popular[1].NaturalQuery)
+	assert.Equal(t, int64(2), popular[1].QueryCount)
+
+	popular, err = repo.GetPopularQueries(ctx, 1, 7)
+	require.NoError(t, err)
+	require.Len(t, popular, 1)
+	assert.Equal(t, "用户总数", popular[0].NaturalQuery)
+}
+
+func TestHistoryKeyring_WrongMasterKey(t *testing.T) {
+	ctx := context.Background()
+	rows := make(map[int64]*repository.QueryHistory)
+	keys := &memoryHistoryKeyRepository{history: rows}
+
+	keyring, err := NewHistoryKeyring([]byte(strings.Repeat("a", 32)), keys, fixedWorkspaceRepository{}, time.Minute, zap.NewNop())
+	require.NoError(t, err)
+	_, err = keyring.Rotate(ctx, repository.DefaultWorkspaceID, 1)
+	require.NoError(t, err)
+	require.NoError(t, NewEncryptedQueryHistoryRepository(&memoryQueryHistoryRepository{rows: rows}, keyring).
+		Create(ctx, &repository.QueryHistory{UserID: 7, NaturalQuery: "q", GeneratedSQL: "SELECT 1"}))
+
+	// 主密钥不一致时拒绝返回数据，而不是把密文当作明文返回
+	other, err := NewHistoryKeyring([]byte(strings.Repeat("b", 32)), keys, fixedWorkspaceRepository{}, time.Minute, zap.NewNop())
+	require.NoError(t, err)
+	_, err = NewEncryptedQueryHistoryRepository(&memoryQueryHistoryRepository{rows: rows}, other).GetByID(ctx, 1)
+	assert.Error(t, err)
+
+	_, err = NewHistoryKeyring([]byte("short"), keys, fixedWorkspaceRepository{}, time.Minute, zap.NewNop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,297 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// historyKeyQuerier 连接池与事务的公共查询接口
+type historyKeyQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// PostgreSQLHistoryKeyRepository PostgreSQL查询历史数据密钥Repository实现
+type PostgreSQLHistoryKeyRepository struct {
+	db     historyKeyQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLHistoryKeyRepository 创建查询历史数据密钥Repository实例
+func NewPostgreSQLHistoryKeyRepository(pool DB, logger *zap.Logger) repository.HistoryKeyRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLHistoryKeyRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const historyKeyColumns = `id, workspace_id, version, wrapped_key, status,
+			create_by, create_time, update_by, update_time, is_deleted`
+
+// GetActive 获取工作空间生效中的数据密钥
+func (r *PostgreSQLHistoryKeyRepository) GetActive(ctx context.Context, workspaceID int64) (*repository.HistoryEncryptionKey, error) {
+	sqlQuery := `
+		SELECT ` + historyKeyColumns + `
+		FROM history_encryption_keys
+		WHERE workspace_id = $1 AND status = $2 AND is_deleted = false`
+
+	return r.getOne(ctx, sqlQuery, workspaceID, string(repository.HistoryKeyActive))
+}
+
+// GetByID 根据ID获取数据密钥，已轮换的密钥同样返回
+func (r *PostgreSQLHistoryKeyRepository) GetByID(ctx context.Context, id int64) (*repository.HistoryEncryptionKey, error) {
+	sqlQuery := `
+		SELECT ` + historyKeyColumns + `
+		FROM history_encryption_keys
+		WHERE id = $1 AND is_deleted = false`
+
+	return r.getOne(ctx, sqlQuery, id)
+}
+
+// ListByWorkspace 列出工作空间的所有数据密钥，按版本倒序
+func (r *PostgreSQLHistoryKeyRepository) ListByWorkspace(ctx context.Context, workspaceID int64) ([]*repository.HistoryEncryptionKey, error) {
+	sqlQuery := `
+		SELECT ` + historyKeyColumns + `
+		FROM history_encryption_keys
+		WHERE workspace_id = $1 AND is_deleted = false
+		ORDER BY version DESC`
+
+	rows, err := r.db.Query(ctx, sqlQuery, workspaceID)
+	if err != nil {
+		r.logger.Error("查询数据密钥列表失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return nil, fmt.Errorf("查询数据密钥列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*repository.HistoryEncryptionKey
+	for rows.Next() {
+		key, err := scanHistoryKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描数据密钥失败: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历数据密钥失败: %w", err)
+	}
+	return keys, nil
+}
+
+// Rotate 停用当前密钥并创建下一版本的生效密钥
+func (r *PostgreSQLHistoryKeyRepository) Rotate(ctx context.Context, workspaceID int64, wrappedKey string, operatorID int64) (*repository.HistoryEncryptionKey, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE history_encryption_keys
+		SET status = $2, update_by = $3, update_time = $4
+		WHERE workspace_id = $1 AND status = $5 AND is_deleted = false`,
+		workspaceID, string(repository.HistoryKeyRetired), operatorID, now, string(repository.HistoryKeyActive),
+	); err != nil {
+		r.logger.Error("停用数据密钥失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return nil, fmt.Errorf("停用数据密钥失败: %w", err)
+	}
+
+	key := &repository.HistoryEncryptionKey{
+		WorkspaceID: workspaceID,
+		WrappedKey:  wrappedKey,
+		Status:      string(repository.HistoryKeyActive),
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO history_encryption_keys (workspace_id, version, wrapped_key, status,
+			create_by, create_time, update_by, update_time, is_deleted)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $4, $5, false
+		FROM history_encryption_keys
+		WHERE workspace_id = $1
+		RETURNING id, version`,
+		workspaceID, wrappedKey, key.Status, operatorID, now,
+	).Scan(&key.ID, &key.Version)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("工作空间正在轮换数据密钥: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("创建数据密钥失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return nil, fmt.Errorf("创建数据密钥失败: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("提交数据密钥轮换失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return nil, fmt.Errorf("提交数据密钥轮换失败: %w", err)
+	}
+
+	key.CreateBy = &operatorID
+	key.UpdateBy = &operatorID
+	key.CreateTime = now
+	key.UpdateTime = now
+
+	r.logger.Info("数据密钥已轮换",
+		zap.Int64("workspace_id", workspaceID),
+		zap.Int64("key_id", key.ID),
+		zap.Int("version", key.Version))
+	return key, nil
+}
+
+// Disable 停用工作空间的生效密钥
+func (r *PostgreSQLHistoryKeyRepository) Disable(ctx context.Context, workspaceID int64, operatorID int64) error {
+	const sqlQuery = `
+		UPDATE history_encryption_keys
+		SET status = $2, update_by = $3, update_time = $4
+		WHERE workspace_id = $1 AND status = $5 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery,
+		workspaceID, string(repository.HistoryKeyRetired), operatorID, time.Now().UTC(), string(repository.HistoryKeyActive))
+	if err != nil {
+		r.logger.Error("停用数据密钥失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return fmt.Errorf("停用数据密钥失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间未启用查询历史加密: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// ListStaleHistory 列出需要重新加密的查询历史
+// 包括使用本工作空间旧密钥加密的记录，以及启用加密时本工作空间成员的明文记录
+func (r *PostgreSQLHistoryKeyRepository) ListStaleHistory(ctx context.Context, workspaceID, activeKeyID int64, limit int) ([]*repository.QueryHistory, error) {
+	const sqlQuery = `
+		SELECT qh.id, qh.user_id, qh.natural_query, qh.generated_sql, qh.encryption_key_id
+		FROM query_history qh
+		WHERE qh.encryption_key_id IN (
+				SELECT k.id FROM history_encryption_keys k WHERE k.workspace_id = $1 AND k.id <> $2)
+			OR ($2 <> 0 AND qh.encryption_key_id IS NULL
+				AND COALESCE((SELECT m.workspace_id FROM workspace_members m WHERE m.user_id = qh.user_id), $3) = $1)
+		ORDER BY qh.id
+		LIMIT $4`
+
+	rows, err := r.db.Query(ctx, sqlQuery, workspaceID, activeKeyID, repository.DefaultWorkspaceID, limit)
+	if err != nil {
+		r.logger.Error("查询待重新加密的查询历史失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return nil, fmt.Errorf("查询待重新加密的查询历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	var queries []*repository.QueryHistory
+	for rows.Next() {
+		query := &repository.QueryHistory{}
+		if err := rows.Scan(&query.ID, &query.UserID, &query.NaturalQuery, &query.GeneratedSQL, &query.EncryptionKeyID); err != nil {
+			return nil, fmt.Errorf("扫描查询历史失败: %w", err)
+		}
+		queries = append(queries, query)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历查询历史失败: %w", err)
+	}
+	return queries, nil
+}
+
+// UpdateHistoryText 替换查询历史的问题与SQL文本及其加密密钥
+func (r *PostgreSQLHistoryKeyRepository) UpdateHistoryText(ctx context.Context, historyID int64, naturalQuery, generatedSQL string, keyID *int64) error {
+	const sqlQuery = `
+		UPDATE query_history
+		SET natural_query = $2, generated_sql = $3, encryption_key_id = $4
+		WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, sqlQuery, historyID, naturalQuery, generatedSQL, keyID)
+	if err != nil {
+		r.logger.Error("更新查询历史密文失败", zap.Int64("query_id", historyID), zap.Error(err))
+		return fmt.Errorf("更新查询历史密文失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("查询历史记录不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// GroupHistoryByQuestion 按问题文本分组统计自since起的查询历史，被哈希或丢弃的问题不参与统计
+func (r *PostgreSQLHistoryKeyRepository) GroupHistoryByQuestion(ctx context.Context, since time.Time) ([]*repository.PopularQuery, error) {
+	const sqlQuery = `
+		SELECT
+			natural_query,
+			COUNT(*) as query_count,
+			AVG(CASE WHEN status = 'success' THEN 1.0 ELSE 0.0 END) as success_rate,
+			COALESCE(AVG(execution_time), 0) as avg_exec_time
+		FROM query_history
+		WHERE create_time >= $1
+			AND is_deleted = false
+			AND natural_query != ''
+			AND natural_query NOT LIKE 'sha256:%'
+		GROUP BY natural_query`
+
+	rows, err := r.db.Query(ctx, sqlQuery, since)
+	if err != nil {
+		r.logger.Error("按问题统计查询历史失败", zap.Time("since", since), zap.Error(err))
+		return nil, fmt.Errorf("按问题统计查询历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*repository.PopularQuery
+	for rows.Next() {
+		group := &repository.PopularQuery{}
+		if err := rows.Scan(&group.NaturalQuery, &group.QueryCount, &group.SuccessRate, &group.AvgExecTime); err != nil {
+			return nil, fmt.Errorf("扫描查询历史统计失败: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历查询历史统计失败: %w", err)
+	}
+	return groups, nil
+}
+
+// getOne 查询单个数据密钥
+func (r *PostgreSQLHistoryKeyRepository) getOne(ctx context.Context, sqlQuery string, args ...any) (*repository.HistoryEncryptionKey, error) {
+	key, err := scanHistoryKey(r.db.QueryRow(ctx, sqlQuery, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("数据密钥不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("获取数据密钥失败", zap.Error(err))
+		return nil, fmt.Errorf("获取数据密钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// scanHistoryKey 按historyKeyColumns的顺序扫描一行
+func scanHistoryKey(row pgx.Row) (*repository.HistoryEncryptionKey, error) {
+	key := &repository.HistoryEncryptionKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.WorkspaceID,
+		&key.Version,
+		&key.WrappedKey,
+		&key.Status,
+		&key.CreateBy,
+		&key.CreateTime,
+		&key.UpdateBy,
+		&key.UpdateTime,
+		&key.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// integrationCredentialQuerier 连接池与事务的公共查询接口
+type integrationCredentialQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// integrationCredentialColumns 查询凭据时的列顺序，与scanIntegrationCredential一致
+const integrationCredentialColumns = `id, integration, name, settings, secret_encrypted, secret_mask, version, rotated_at,
+	create_by, create_time, update_by, update_time, is_deleted`
+
+// PostgreSQLIntegrationCredentialRepository PostgreSQL第三方集成凭据Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLIntegrationCredentialRepository struct {
+	db     integrationCredentialQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLIntegrationCredentialRepository 创建第三方集成凭据Repository实例
+func NewPostgreSQLIntegrationCredentialRepository(pool DB, logger *zap.Logger) repository.IntegrationCredentialRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLIntegrationCredentialRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+// Create 创建凭据，同一集成下已有同名凭据时返回ErrDuplicateEntry
+func (r *PostgreSQLIntegrationCredentialRepository) Create(ctx context.Context, credential *repository.IntegrationCredential) error {
+	const sqlQuery = `
+		INSERT INTO integration_credentials (integration, name, settings, secret_encrypted, secret_mask, version,
+			create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, 1, $6, $7, $6, $7, false)
+		RETURNING id`
+
+	now := time.Now().UTC()
+	createBy := int64(0)
+	if credential.CreateBy != nil {
+		createBy = *credential.CreateBy
+	}
+	if credential.Settings == nil {
+		credential.Settings = map[string]string{}
+	}
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		credential.Integration,
+		credential.Name,
+		credential.Settings,
+		credential.SecretEncrypted,
+		credential.SecretMask,
+		createBy,
+		now,
+	).Scan(&credential.ID)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("同名集成凭据已存在: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("创建集成凭据失败",
+			zap.String("integration", credential.Integration),
+			zap.String("name", credential.Name),
+			zap.Error(err))
+		return fmt.Errorf("创建集成凭据失败: %w", err)
+	}
+
+	credential.Version = 1
+	credential.UpdateBy = credential.CreateBy
+	credential.CreateTime = now
+	credential.UpdateTime = now
+	return nil
+}
+
+// GetByID 根据ID获取凭据
+func (r *PostgreSQLIntegrationCredentialRepository) GetByID(ctx context.Context, id int64) (*repository.IntegrationCredential, error) {
+	sqlQuery := `SELECT ` + integrationCredentialColumns + `
+		FROM integration_credentials
+		WHERE id = $1 AND is_deleted = false`
+
+	return r.get(ctx, sqlQuery, id)
+}
+
+// GetByName 按集成类型与名称获取凭据
+func (r *PostgreSQLIntegrationCredentialRepository) GetByName(ctx context.Context, integration, name string) (*repository.IntegrationCredential, error) {
+	sqlQuery := `SELECT ` + integrationCredentialColumns + `
+		FROM integration_credentials
+		WHERE integration = $1 AND name = $2 AND is_deleted = false`
+
+	return r.get(ctx, sqlQuery, integration, name)
+}
+
+// List 列出凭据，integration为空时列出所有集成的凭据
+func (r *PostgreSQLIntegrationCredentialRepository) List(ctx context.Context, integration string) ([]*repository.IntegrationCredential, error) {
+	sqlQuery := `SELECT ` + integrationCredentialColumns + `
+		FROM integration_credentials
+		WHERE is_deleted = false AND ($1 = '' OR integration = $1)
+		ORDER BY integration, name`
+
+	rows, err := r.db.Query(ctx, sqlQuery, integration)
+	if err != nil {
+		r.logger.Error("查询集成凭据失败", zap.String("integration", integration), zap.Error(err))
+		return nil, fmt.Errorf("查询集成凭据失败: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []*repository.IntegrationCredential
+	for rows.Next() {
+		credential, err := scanIntegrationCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描集成凭据失败: %w", err)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+// UpdateSettings 更新非机密配置
+func (r *PostgreSQLIntegrationCredentialRepository) UpdateSettings(ctx context.Context, id int64, settings map[string]string, updateBy int64) error {
+	const sqlQuery = `
+		UPDATE integration_credentials
+		SET settings = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	if settings == nil {
+		settings = map[string]string{}
+	}
+	result, err := r.db.Exec(ctx, sqlQuery, id, settings, updateBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("更新集成凭据配置失败", zap.Int64("credential_id", id), zap.Error(err))
+		return fmt.Errorf("更新集成凭据配置失败: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("集成凭据不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// Rotate 替换机密并递增版本号
+func (r *PostgreSQLIntegrationCredentialRepository) Rotate(ctx context.Context, id int64, secretEncrypted, secretMask string, updateBy int64) (int, error) {
+	const sqlQuery = `
+		UPDATE integration_credentials
+		SET secret_encrypted = $2, secret_mask = $3, version = version + 1, rotated_at = $5,
+			update_by = $4, update_time = $5
+		WHERE id = $1 AND is_deleted = false
+		RETURNING version`
+
+	var version int
+	err := r.db.QueryRow(ctx, sqlQuery, id, secretEncrypted, secretMask, updateBy, time.Now().UTC()).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("集成凭据不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("轮换集成凭据失败", zap.Int64("credential_id", id), zap.Error(err))
+		return 0, fmt.Errorf("轮换集成凭据失败: %w", err)
+	}
+	return version, nil
+}
+
+// Delete 软删除凭据，同时清空加密的机密，删除后不再保留可解密的副本
+func (r *PostgreSQLIntegrationCredentialRepository) Delete(ctx context.Context, id int64, deleteBy int64) error {
+	const sqlQuery = `
+		UPDATE integration_credentials
+		SET is_deleted = true, secret_encrypted = '', update_by = $2, update_time = $3
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, deleteBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("删除集成凭据失败", zap.Int64("credential_id", id), zap.Error(err))
+		return fmt.Errorf("删除集成凭据失败: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("集成凭据不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// get 查询单个凭据
+func (r *PostgreSQLIntegrationCredentialRepository) get(ctx context.Context, sqlQuery string, args ...any) (*repository.IntegrationCredential, error) {
+	credential, err := scanIntegrationCredential(r.db.QueryRow(ctx, sqlQuery, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("集成凭据不存在: %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("获取集成凭据失败: %w", err)
+	}
+	return credential, nil
+}
+
+// scanIntegrationCredential 按integrationCredentialColumns的顺序扫描一行
+func scanIntegrationCredential(row pgx.Row) (*repository.IntegrationCredential, error) {
+	credential := &repository.IntegrationCredential{}
+	err := row.Scan(
+		&credential.ID,
+		&credential.Integration,
+		&credential.Name,
+		&credential.Settings,
+		&credential.SecretEncrypted,
+		&credential.SecretMask,
+		&credential.Version,
+		&credential.RotatedAt,
+		&credential.CreateBy,
+		&credential.CreateTime,
+		&credential.UpdateBy,
+		&credential.UpdateTime,
+		&credential.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// llmUsageQuerier 连接池与事务的公共查询接口
+type llmUsageQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// llmUsageColumns 查询用量时的列顺序，与scanLLMUsage一致；成本转为float8以便扫描到float64
+const llmUsageColumns = `day, user_id, provider, model, queries, input_tokens, output_tokens, cost::float8, update_time`
+
+// PostgreSQLLLMUsageRepository PostgreSQL模型用量Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLLLMUsageRepository struct {
+	db     llmUsageQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLLLMUsageRepository 创建模型用量Repository实例
+func NewPostgreSQLLLMUsageRepository(pool DB, logger *zap.Logger) repository.LLMUsageRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLLLMUsageRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+// Add 将一次调用的用量累加到汇总行，汇总行不存在时创建
+func (r *PostgreSQLLLMUsageRepository) Add(ctx context.Context, usage *repository.LLMUsage) error {
+	const sqlQuery = `
+		INSERT INTO llm_usage_daily (day, user_id, provider, model, queries, input_tokens, output_tokens, cost, update_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (day, user_id, provider, model) DO UPDATE
+		SET queries = llm_usage_daily.queries + EXCLUDED.queries,
+			input_tokens = llm_usage_daily.input_tokens + EXCLUDED.input_tokens,
+			output_tokens = llm_usage_daily.output_tokens + EXCLUDED.output_tokens,
+			cost = llm_usage_daily.cost + EXCLUDED.cost,
+			update_time = EXCLUDED.update_time`
+
+	now := time.Now().UTC()
+	day := usage.Day
+	if day.IsZero() {
+		day = now
+	}
+
+	_, err := r.db.Exec(ctx, sqlQuery,
+		day.Format(time.DateOnly),
+		usage.UserID,
+		usage.Provider,
+		usage.Model,
+		usage.Queries,
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.Cost,
+		now,
+	)
+	if err != nil {
+		r.logger.Error("记录模型用量失败",
+			zap.Int64("user_id", usage.UserID),
+			zap.String("provider", usage.Provider),
+			zap.String("model", usage.Model),
+			zap.Error(err))
+		return fmt.Errorf("记录模型用量失败: %w", err)
+	}
+
+	usage.UpdateTime = now
+	return nil
+}
+
+// ListByDay 列出指定日期所有用户的用量
+func (r *PostgreSQLLLMUsageRepository) ListByDay(ctx context.Context, day time.Time) ([]*repository.LLMUsage, error) {
+	sqlQuery := `SELECT ` + llmUsageColumns + `
+		FROM llm_usage_daily
+		WHERE day = $1
+		ORDER BY user_id, provider, model`
+
+	return r.list(ctx, sqlQuery, day.Format(time.DateOnly))
+}
+
+// ListByUser 列出用户在[start, end]日期范围内的用量，按日期倒序
+func (r *PostgreSQLLLMUsageRepository) ListByUser(ctx context.Context, userID int64, start, end time.Time) ([]*repository.LLMUsage, error) {
+	sqlQuery := `SELECT ` + llmUsageColumns + `
+		FROM llm_usage_daily
+		WHERE user_id = $1 AND day BETWEEN $2 AND $3
+		ORDER BY day DESC, provider, model`
+
+	return r.list(ctx, sqlQuery, userID, start.Format(time.DateOnly), end.Format(time.DateOnly))
+}
+
+// SetBudgetOverride 设置用户的预算上调，替换该用户之前的上调
+func (r *PostgreSQLLLMUsageRepository) SetBudgetOverride(ctx context.Context, override *repository.LLMBudgetOverride) error {
+	const sqlQuery = `
+		INSERT INTO llm_budget_overrides (user_id, daily_limit, expires_at, approval_id, update_time)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET daily_limit = EXCLUDED.daily_limit,
+			expires_at = EXCLUDED.expires_at,
+			approval_id = EXCLUDED.approval_id,
+			update_time = EXCLUDED.update_time`
+
+	now := time.Now().UTC()
+	if _, err := r.db.Exec(ctx, sqlQuery, override.UserID, override.DailyLimit, override.ExpiresAt, override.ApprovalID, now); err != nil {
+		r.logger.Error("设置预算上调失败", zap.Int64("user_id", override.UserID), zap.Error(err))
+		return fmt.Errorf("设置预算上调失败: %w", err)
+	}
+	override.UpdateTime = now
+	return nil
+}
+
+// ListBudgetOverrides 列出在now仍有效的预算上调
+func (r *PostgreSQLLLMUsageRepository) ListBudgetOverrides(ctx context.Context, now time.Time) ([]*repository.LLMBudgetOverride, error) {
+	const sqlQuery = `
+		SELECT user_id, daily_limit::float8, expires_at, approval_id, update_time
+		FROM llm_budget_overrides
+		WHERE expires_at > $1
+		ORDER BY user_id`
+
+	rows, err := r.db.Query(ctx, sqlQuery, now)
+	if err != nil {
+		r.logger.Error("查询预算上调失败", zap.Error(err))
+		return nil, fmt.Errorf("查询预算上调失败: %w", err)
+	}
+	defer rows.Close()
+
+	var overrides []*repository.LLMBudgetOverride
+	for rows.Next() {
+		override := &repository.LLMBudgetOverride{}
+		if err := rows.Scan(&override.UserID, &override.DailyLimit, &override.ExpiresAt, &override.ApprovalID, &override.UpdateTime); err != nil {
+			return nil, fmt.Errorf("扫描预算上调失败: %w", err)
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, rows.Err()
+}
+
+// list 查询多行用量
+func (r *PostgreSQLLLMUsageRepository) list(ctx context.Context, sqlQuery string, args ...any) ([]*repository.LLMUsage, error) {
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		r.logger.Error("查询模型用量失败", zap.Error(err))
+		return nil, fmt.Errorf("查询模型用量失败: %w", err)
+	}
+	defer rows.Close()
+
+	var usages []*repository.LLMUsage
+	for rows.Next() {
+		usage, err := scanLLMUsage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描模型用量失败: %w", err)
+		}
+		usages = append(usages, usage)
+	}
+	return usages, rows.Err()
+}
+
+// scanLLMUsage 按llmUsageColumns的顺序扫描一行
+func scanLLMUsage(row pgx.Row) (*repository.LLMUsage, error) {
+	usage := &repository.LLMUsage{}
+	err := row.Scan(
+		&usage.Day,
+		&usage.UserID,
+		&usage.Provider,
+		&usage.Model,
+		&usage.Queries,
+		&usage.InputTokens,
+		&usage.OutputTokens,
+		&usage.Cost,
+		&usage.UpdateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
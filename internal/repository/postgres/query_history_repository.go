@@ -3,10 +3,10 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
 	"chat2sql-go/internal/repository"
@@ -15,12 +15,12 @@ import (
 // PostgreSQLQueryHistoryRepository PostgreSQL查询历史Repository实现
 // 支持全文搜索、统计分析、性能监控等高级功能
 type PostgreSQLQueryHistoryRepository struct {
-	pool   *pgxpool.Pool
+	pool   DB
 	logger *zap.Logger
 }
 
 // NewPostgreSQLQueryHistoryRepository 创建PostgreSQL查询历史Repository
-func NewPostgreSQLQueryHistoryRepository(pool *pgxpool.Pool, logger *zap.Logger) repository.QueryHistoryRepository {
+func NewPostgreSQLQueryHistoryRepository(pool DB, logger *zap.Logger) repository.QueryHistoryRepository {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -36,8 +36,10 @@ func (r *PostgreSQLQueryHistoryRepository) Create(ctx context.Context, query *re
 	const sqlQuery = `
 		INSERT INTO query_history (user_id, natural_query, generated_sql, sql_hash,
 			execution_time, result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, encryption_key_id, schema_snapshot_id, question_category,
+			cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		RETURNING id`
 
 	now := time.Now().UTC()
@@ -52,6 +54,13 @@ func (r *PostgreSQLQueryHistoryRepository) Create(ctx context.Context, query *re
 		query.Status,
 		query.ErrorMessage,
 		query.ConnectionID,
+		query.AIConfidence,
+		query.ExecutionPath,
+		query.EncryptionKeyID,
+		query.SchemaSnapshotID,
+		query.QuestionCategory,
+		query.CostCenter,
+		query.Project,
 		query.CreateBy,
 		now,
 		query.UpdateBy,
@@ -86,6 +95,7 @@ func (r *PostgreSQLQueryHistoryRepository) GetByID(ctx context.Context, id int64
 	const sqlQuery = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
 			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, schema_snapshot_id, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history 
 		WHERE id = $1 AND is_deleted = false`
@@ -103,6 +113,12 @@ func (r *PostgreSQLQueryHistoryRepository) GetByID(ctx context.Context, id int64
 		&query.Status,
 		&query.ErrorMessage,
 		&query.ConnectionID,
+		&query.AIConfidence,
+		&query.ExecutionPath,
+		&query.SchemaSnapshotID,
+		&query.QuestionCategory,
+		&query.CostCenter,
+		&query.Project,
 		&query.CreateBy,
 		&query.CreateTime,
 		&query.UpdateBy,
@@ -132,7 +148,9 @@ func (r *PostgreSQLQueryHistoryRepository) Update(ctx context.Context, query *re
 		UPDATE query_history 
 		SET natural_query = $2, generated_sql = $3, sql_hash = $4, execution_time = $5,
 			result_rows = $6, status = $7, error_message = $8,
-			connection_id = $9, update_by = $10, update_time = $11
+			connection_id = $9, update_by = $10, update_time = $11,
+			ai_confidence = $12, execution_path = $13, encryption_key_id = $14,
+			question_category = $15
 		WHERE id = $1 AND is_deleted = false`
 
 	now := time.Now().UTC()
@@ -149,6 +167,10 @@ func (r *PostgreSQLQueryHistoryRepository) Update(ctx context.Context, query *re
 		query.ConnectionID,
 		query.UpdateBy,
 		now,
+		query.AIConfidence,
+		query.ExecutionPath,
+		query.EncryptionKeyID,
+		query.QuestionCategory,
 	)
 	
 	if err != nil {
@@ -208,6 +230,7 @@ func (r *PostgreSQLQueryHistoryRepository) ListByUser(ctx context.Context, userI
 	const sqlQuery = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
 			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history 
 		WHERE user_id = $1 AND is_deleted = false 
@@ -229,11 +252,76 @@ func (r *PostgreSQLQueryHistoryRepository) ListByUser(ctx context.Context, userI
 	return r.scanQueryHistory(rows)
 }
 
+// ListPage 按create_time、id倒序分页列出用户的查询历史
+// after非空时按键集定位，分页期间写入的新记录不会导致后续页重复或跳过记录
+func (r *PostgreSQLQueryHistoryRepository) ListPage(ctx context.Context, filter repository.QueryHistoryFilter, after *repository.QueryHistoryKeyset, limit, offset int) ([]*repository.QueryHistory, error) {
+	sqlQuery, args := queryHistoryPageSQL(filter, after, limit, offset)
+
+	rows, err := r.pool.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		r.logger.Error("分页查询历史记录失败",
+			zap.Int64("user_id", filter.UserID),
+			zap.Int("limit", limit),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("分页查询历史记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanQueryHistory(rows)
+}
+
+// queryHistoryPageSQL 生成分页列出查询历史的语句，普通与事务版本共用
+// 排序加入id，同一时间写入的记录也有确定顺序，(create_time, id)可作为键集分页的位置
+func queryHistoryPageSQL(filter repository.QueryHistoryFilter, after *repository.QueryHistoryKeyset, limit, offset int) (string, []any) {
+	var sb strings.Builder
+	sb.WriteString(`
+		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
+			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
+			create_by, create_time, update_by, update_time, is_deleted
+		FROM query_history
+		WHERE user_id = $1 AND is_deleted = false`)
+
+	args := []any{filter.UserID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.ConnectionID > 0 {
+		sb.WriteString(" AND connection_id = " + arg(filter.ConnectionID))
+	}
+	if filter.Status != "" {
+		sb.WriteString(" AND status = " + arg(string(filter.Status)))
+	}
+	if filter.Keyword != "" {
+		// 处理搜索关键字，支持前缀匹配
+		searchTerm := arg(filter.Keyword + ":*")
+		if filter.SearchSQL {
+			sb.WriteString(" AND to_tsvector('simple', generated_sql) @@ to_tsquery('simple', " + searchTerm + ")")
+		} else {
+			sb.WriteString(" AND to_tsvector('simple', natural_query) @@ to_tsquery('simple', " + searchTerm + ")" +
+				" AND natural_query NOT LIKE 'sha256:%'")
+		}
+	}
+	if after != nil {
+		sb.WriteString(" AND (create_time, id) < (" + arg(after.CreateTime) + ", " + arg(after.ID) + ")")
+	}
+
+	sb.WriteString(" ORDER BY create_time DESC, id DESC LIMIT " + arg(limit))
+	if after == nil && offset > 0 {
+		sb.WriteString(" OFFSET " + arg(offset))
+	}
+	return sb.String(), args
+}
+
 // ListByConnection 根据连接ID分页获取查询历史
 func (r *PostgreSQLQueryHistoryRepository) ListByConnection(ctx context.Context, connectionID int64, limit, offset int) ([]*repository.QueryHistory, error) {
 	const sqlQuery = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
 			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history 
 		WHERE connection_id = $1 AND is_deleted = false 
@@ -255,11 +343,38 @@ func (r *PostgreSQLQueryHistoryRepository) ListByConnection(ctx context.Context,
 	return r.scanQueryHistory(rows)
 }
 
+// ListFailuresByConnection 获取连接自since起执行失败（error/timeout）的记录，按时间正序
+func (r *PostgreSQLQueryHistoryRepository) ListFailuresByConnection(ctx context.Context, connectionID int64, since time.Time, limit int) ([]*repository.QueryHistory, error) {
+	const sqlQuery = `
+		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
+			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
+			create_by, create_time, update_by, update_time, is_deleted
+		FROM query_history 
+		WHERE connection_id = $1 AND status IN ('error', 'timeout') AND create_time >= $2 AND is_deleted = false 
+		ORDER BY create_time ASC
+		LIMIT $3`
+
+	rows, err := r.pool.Query(ctx, sqlQuery, connectionID, since, limit)
+	if err != nil {
+		r.logger.Error("查询连接的失败记录失败",
+			zap.Int64("connection_id", connectionID),
+			zap.Time("since", since),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("查询连接的失败记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanQueryHistory(rows)
+}
+
 // ListByStatus 根据状态分页获取查询历史
 func (r *PostgreSQLQueryHistoryRepository) ListByStatus(ctx context.Context, status repository.QueryStatus, limit, offset int) ([]*repository.QueryHistory, error) {
 	const sqlQuery = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
 			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history 
 		WHERE status = $1 AND is_deleted = false 
@@ -286,6 +401,7 @@ func (r *PostgreSQLQueryHistoryRepository) ListRecent(ctx context.Context, userI
 	const sqlQuery = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
 			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history 
 		WHERE user_id = $1 AND create_time >= $2 AND is_deleted = false 
@@ -345,6 +461,12 @@ func (r *PostgreSQLQueryHistoryRepository) CountByStatus(ctx context.Context, st
 
 // GetExecutionStats 获取用户查询执行统计信息
 func (r *PostgreSQLQueryHistoryRepository) GetExecutionStats(ctx context.Context, userID int64, days int) (*repository.QueryExecutionStats, error) {
+	cutoffTime := time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour)
+	return r.GetExecutionStatsSince(ctx, userID, cutoffTime)
+}
+
+// GetExecutionStatsSince 获取用户自since以来的查询执行统计信息
+func (r *PostgreSQLQueryHistoryRepository) GetExecutionStatsSince(ctx context.Context, userID int64, since time.Time) (*repository.QueryExecutionStats, error) {
 	const sqlQuery = `
 		SELECT 
 			COUNT(*) as total_queries,
@@ -358,11 +480,9 @@ func (r *PostgreSQLQueryHistoryRepository) GetExecutionStats(ctx context.Context
 			AND is_deleted = false 
 			AND execution_time IS NOT NULL`
 
-	cutoffTime := time.Now().UTC().Add(-time.Duration(days) * 24 * time.Hour)
-	
 	stats := &repository.QueryExecutionStats{UserID: userID}
 	
-	err := r.pool.QueryRow(ctx, sqlQuery, userID, cutoffTime).Scan(
+	err := r.pool.QueryRow(ctx, sqlQuery, userID, since.UTC()).Scan(
 		&stats.TotalQueries,
 		&stats.SuccessfulQueries,
 		&stats.FailedQueries,
@@ -373,7 +493,7 @@ func (r *PostgreSQLQueryHistoryRepository) GetExecutionStats(ctx context.Context
 	if err != nil {
 		r.logger.Error("获取查询执行统计失败",
 			zap.Int64("user_id", userID),
-			zap.Int("days", days),
+			zap.Time("since", since),
 			zap.Error(err),
 		)
 		return nil, fmt.Errorf("获取查询执行统计失败: %w", err)
@@ -387,7 +507,7 @@ func (r *PostgreSQLQueryHistoryRepository) GetExecutionStats(ctx context.Context
 	return stats, nil
 }
 
-// GetPopularQueries 获取热门查询统计
+// GetPopularQueries 获取热门查询统计，被哈希或丢弃的问题不参与统计
 func (r *PostgreSQLQueryHistoryRepository) GetPopularQueries(ctx context.Context, limit int, days int) ([]*repository.PopularQuery, error) {
 	const sqlQuery = `
 		SELECT 
@@ -399,6 +519,7 @@ func (r *PostgreSQLQueryHistoryRepository) GetPopularQueries(ctx context.Context
 		WHERE create_time >= $1 
 			AND is_deleted = false 
 			AND natural_query != ''
+			AND natural_query NOT LIKE 'sha256:%'
 		GROUP BY natural_query 
 		HAVING COUNT(*) > 1
 		ORDER BY query_count DESC, success_rate DESC
@@ -444,11 +565,88 @@ func (r *PostgreSQLQueryHistoryRepository) GetPopularQueries(ctx context.Context
 	return popularQueries, nil
 }
 
+// GetConnectionUsage 按连接统计每个星期几与小时的查询量
+// 从连接表左连接查询历史，期间没有查询的连接也会返回，便于发现闲置连接
+func (r *PostgreSQLQueryHistoryRepository) GetConnectionUsage(ctx context.Context, since time.Time, location string) ([]*repository.ConnectionUsage, error) {
+	const sqlQuery = `
+		SELECT
+			c.id,
+			c.name,
+			c.status,
+			EXTRACT(DOW FROM q.create_time AT TIME ZONE $2)::int AS day_of_week,
+			EXTRACT(HOUR FROM q.create_time AT TIME ZONE $2)::int AS hour,
+			COUNT(q.id) AS query_count,
+			COUNT(q.id) FILTER (WHERE q.status IN ('error', 'timeout')) AS failed_count
+		FROM database_connections c
+		LEFT JOIN query_history q
+			ON q.connection_id = c.id
+			AND q.create_time >= $1
+			AND q.is_deleted = false
+		WHERE c.is_deleted = false
+		GROUP BY c.id, c.name, c.status, day_of_week, hour
+		ORDER BY c.id, day_of_week, hour`
+
+	rows, err := r.pool.Query(ctx, sqlQuery, since, location)
+	if err != nil {
+		r.logger.Error("获取连接使用分布失败",
+			zap.Time("since", since),
+			zap.String("location", location),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("获取连接使用分布失败: %w", err)
+	}
+	defer rows.Close()
+
+	var usages []*repository.ConnectionUsage
+	for rows.Next() {
+		var (
+			connectionID int64
+			name         string
+			status       repository.ConnectionStatus
+			dayOfWeek    *int
+			hour         *int
+			queryCount   int64
+			failedCount  int64
+		)
+		if err := rows.Scan(&connectionID, &name, &status, &dayOfWeek, &hour, &queryCount, &failedCount); err != nil {
+			r.logger.Error("扫描连接使用分布失败", zap.Error(err))
+			return nil, fmt.Errorf("扫描连接使用分布失败: %w", err)
+		}
+
+		if len(usages) == 0 || usages[len(usages)-1].ConnectionID != connectionID {
+			usages = append(usages, &repository.ConnectionUsage{
+				ConnectionID:   connectionID,
+				ConnectionName: name,
+				Status:         status,
+			})
+		}
+		// 左连接没有匹配的查询历史时星期与小时为NULL
+		if dayOfWeek == nil || hour == nil {
+			continue
+		}
+		usage := usages[len(usages)-1]
+		usage.Cells = append(usage.Cells, repository.ConnectionUsageCell{
+			DayOfWeek:   *dayOfWeek,
+			Hour:        *hour,
+			QueryCount:  queryCount,
+			FailedCount: failedCount,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("处理连接使用分布结果失败", zap.Error(err))
+		return nil, fmt.Errorf("处理连接使用分布结果失败: %w", err)
+	}
+
+	return usages, nil
+}
+
 // GetSlowQueries 获取慢查询列表
 func (r *PostgreSQLQueryHistoryRepository) GetSlowQueries(ctx context.Context, minExecutionTime int32, limit int) ([]*repository.QueryHistory, error) {
 	const sqlQuery = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
 			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history 
 		WHERE execution_time >= $1 AND is_deleted = false 
@@ -469,15 +667,17 @@ func (r *PostgreSQLQueryHistoryRepository) GetSlowQueries(ctx context.Context, m
 	return r.scanQueryHistory(rows)
 }
 
-// SearchByNaturalQuery 根据自然语言查询关键字搜索
+// SearchByNaturalQuery 根据自然语言查询关键字搜索，哈希后的问题不参与匹配
 func (r *PostgreSQLQueryHistoryRepository) SearchByNaturalQuery(ctx context.Context, userID int64, keyword string, limit, offset int) ([]*repository.QueryHistory, error) {
 	const sqlQuery = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
 			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history 
 		WHERE user_id = $1 
 			AND to_tsvector('simple', natural_query) @@ to_tsquery('simple', $2)
+			AND natural_query NOT LIKE 'sha256:%'
 			AND is_deleted = false 
 		ORDER BY create_time DESC
 		LIMIT $3 OFFSET $4`
@@ -504,6 +704,7 @@ func (r *PostgreSQLQueryHistoryRepository) SearchBySQL(ctx context.Context, user
 	const sqlQuery = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash, execution_time,
 			result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history 
 		WHERE user_id = $1 
@@ -562,6 +763,82 @@ func (r *PostgreSQLQueryHistoryRepository) BatchUpdateStatus(ctx context.Context
 	return nil
 }
 
+// BatchDelete 批量软删除用户自己的查询历史
+// 单条UPDATE在一个事务内完成，返回实际删除的ID；不存在、已删除或不属于该用户的ID不在结果中
+func (r *PostgreSQLQueryHistoryRepository) BatchDelete(ctx context.Context, userID int64, queryIDs []int64) ([]int64, error) {
+	if len(queryIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.pool.Query(ctx, batchDeleteQueryHistorySQL, queryIDs, userID, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("批量删除查询历史失败",
+			zap.Int64("user_id", userID),
+			zap.Int("query_count", len(queryIDs)),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("批量删除查询历史失败: %w", err)
+	}
+
+	deleted, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("批量删除查询历史失败: %w", err)
+	}
+
+	r.logger.Info("批量删除查询历史完成",
+		zap.Int64("user_id", userID),
+		zap.Int("request_count", len(queryIDs)),
+		zap.Int("deleted_count", len(deleted)),
+	)
+	return deleted, nil
+}
+
+// batchDeleteQueryHistorySQL 批量软删除语句，普通与事务版本共用
+const batchDeleteQueryHistorySQL = `
+	UPDATE query_history
+	SET is_deleted = true, update_time = $3
+	WHERE id = ANY($1) AND user_id = $2 AND is_deleted = false
+	RETURNING id`
+
+// BatchRetag 在一条语句中替换用户自己的查询历史的费用归属标签，返回实际修改的ID
+func (r *PostgreSQLQueryHistoryRepository) BatchRetag(ctx context.Context, userID int64, queryIDs []int64, tags *repository.ChargebackTags) ([]int64, error) {
+	if len(queryIDs) == 0 {
+		return nil, nil
+	}
+	if tags == nil {
+		tags = &repository.ChargebackTags{}
+	}
+
+	rows, err := r.pool.Query(ctx, batchRetagQueryHistorySQL, queryIDs, userID, tags.CostCenter, tags.Project, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("批量修改查询历史标签失败",
+			zap.Int64("user_id", userID),
+			zap.Int("query_count", len(queryIDs)),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("批量修改查询历史标签失败: %w", err)
+	}
+
+	retagged, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("批量修改查询历史标签失败: %w", err)
+	}
+
+	r.logger.Info("批量修改查询历史标签完成",
+		zap.Int64("user_id", userID),
+		zap.Int("request_count", len(queryIDs)),
+		zap.Int("retagged_count", len(retagged)),
+	)
+	return retagged, nil
+}
+
+// batchRetagQueryHistorySQL 批量修改费用归属标签语句，空字符串保存为NULL，普通与事务版本共用
+const batchRetagQueryHistorySQL = `
+	UPDATE query_history
+	SET cost_center = NULLIF($3, ''), project = NULLIF($4, ''), update_time = $5
+	WHERE id = ANY($1) AND user_id = $2 AND is_deleted = false
+	RETURNING id`
+
 // CleanupOldQueries 清理旧的查询记录
 func (r *PostgreSQLQueryHistoryRepository) CleanupOldQueries(ctx context.Context, beforeDate time.Time) (int64, error) {
 	const sqlQuery = `
@@ -606,6 +883,11 @@ func (r *PostgreSQLQueryHistoryRepository) scanQueryHistory(rows pgx.Rows) ([]*r
 			&query.Status,
 			&query.ErrorMessage,
 			&query.ConnectionID,
+			&query.AIConfidence,
+			&query.ExecutionPath,
+			&query.QuestionCategory,
+			&query.CostCenter,
+			&query.Project,
 			&query.CreateBy,
 			&query.CreateTime,
 			&query.UpdateBy,
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"chat2sql-go/internal/repository"
+)
+
+func TestQueryHistoryPageSQL(t *testing.T) {
+	filter := repository.QueryHistoryFilter{UserID: 7, ConnectionID: 3, Status: repository.QuerySuccess, Keyword: "orders"}
+
+	t.Run("首页", func(t *testing.T) {
+		sql, args := queryHistoryPageSQL(filter, nil, 21, 0)
+		sql = strings.Join(strings.Fields(sql), " ")
+		assert.Contains(t, sql, "WHERE user_id = $1 AND is_deleted = false AND connection_id = $2 AND status = $3")
+		assert.Contains(t, sql, "to_tsvector('simple', natural_query) @@ to_tsquery('simple', $4)")
+		assert.True(t, strings.HasSuffix(sql, "ORDER BY create_time DESC, id DESC LIMIT $5"))
+		assert.Equal(t, []any{int64(7), int64(3), "success", "orders:*", 21}, args)
+	})
+
+	t.Run("键集位置", func(t *testing.T) {
+		at := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+		sql, args := queryHistoryPageSQL(repository.QueryHistoryFilter{UserID: 7}, &repository.QueryHistoryKeyset{CreateTime: at, ID: 42}, 21, 40)
+		sql = strings.Join(strings.Fields(sql), " ")
+		assert.Contains(t, sql, "AND (create_time, id) < ($2, $3) ORDER BY create_time DESC, id DESC LIMIT $4")
+		assert.NotContains(t, sql, "OFFSET", "键集定位时忽略offset")
+		assert.Equal(t, []any{int64(7), at, int64(42), 21}, args)
+	})
+
+	t.Run("兼容offset", func(t *testing.T) {
+		sql, args := queryHistoryPageSQL(repository.QueryHistoryFilter{UserID: 7, Keyword: "orders", SearchSQL: true}, nil, 11, 10)
+		assert.Contains(t, sql, "to_tsvector('simple', generated_sql)")
+		assert.True(t, strings.HasSuffix(sql, "LIMIT $3 OFFSET $4"))
+		assert.Equal(t, []any{int64(7), "orders:*", 11, 10}, args)
+	})
+}
@@ -0,0 +1,234 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// queryJobQuerier 连接池与事务的公共查询接口
+type queryJobQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgreSQLQueryJobRepository PostgreSQL异步查询任务Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLQueryJobRepository struct {
+	db     queryJobQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLQueryJobRepository 创建异步查询任务Repository实例
+func NewPostgreSQLQueryJobRepository(pool DB, logger *zap.Logger) repository.QueryJobRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLQueryJobRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const queryJobColumns = `id, user_id, connection_id, query_history_id, sql_text, row_limit, status, rows_read,
+			result, error_message, attempts, started_time, completed_time, create_time, update_time`
+
+// Create 创建待执行的任务
+func (r *PostgreSQLQueryJobRepository) Create(ctx context.Context, job *repository.QueryJob) error {
+	const sqlQuery = `
+		INSERT INTO query_jobs (user_id, connection_id, query_history_id, sql_text, row_limit, status, create_time, update_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id`
+
+	now := time.Now().UTC()
+	job.Status = string(repository.QueryJobPending)
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		job.UserID,
+		job.ConnectionID,
+		job.QueryHistoryID,
+		job.SQL,
+		job.RowLimit,
+		job.Status,
+		now,
+	).Scan(&job.ID)
+	if err != nil {
+		r.logger.Error("创建异步查询任务失败",
+			zap.Int64("user_id", job.UserID),
+			zap.Int64("connection_id", job.ConnectionID),
+			zap.Error(err))
+		return fmt.Errorf("创建异步查询任务失败: %w", err)
+	}
+
+	job.CreateTime = now
+	job.UpdateTime = now
+	return nil
+}
+
+// GetByID 根据ID获取任务
+func (r *PostgreSQLQueryJobRepository) GetByID(ctx context.Context, id int64) (*repository.QueryJob, error) {
+	sqlQuery := `
+		SELECT ` + queryJobColumns + `
+		FROM query_jobs
+		WHERE id = $1`
+
+	job, err := scanQueryJob(r.db.QueryRow(ctx, sqlQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("异步查询任务不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("获取异步查询任务失败", zap.Int64("job_id", id), zap.Error(err))
+		return nil, fmt.Errorf("获取异步查询任务失败: %w", err)
+	}
+	return job, nil
+}
+
+// ClaimPending 领取待执行任务，SKIP LOCKED保证多实例不会重复领取
+func (r *PostgreSQLQueryJobRepository) ClaimPending(ctx context.Context, limit int) ([]*repository.QueryJob, error) {
+	sqlQuery := `
+		UPDATE query_jobs
+		SET status = $1, attempts = attempts + 1, rows_read = 0, started_time = $2, update_time = $2
+		WHERE id IN (
+			SELECT id FROM query_jobs
+			WHERE status = $3
+			ORDER BY create_time
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + queryJobColumns
+
+	rows, err := r.db.Query(ctx, sqlQuery, string(repository.QueryJobRunning), time.Now().UTC(), string(repository.QueryJobPending), limit)
+	if err != nil {
+		r.logger.Error("领取异步查询任务失败", zap.Error(err))
+		return nil, fmt.Errorf("领取异步查询任务失败: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*repository.QueryJob
+	for rows.Next() {
+		job, err := scanQueryJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描异步查询任务失败: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// RequeueStale 将心跳超时的执行中任务重新排队，这些任务所在的实例已退出或失去响应
+// 已领取maxAttempts次的任务很可能每次都使实例退出，不再排队而是标记为失败
+func (r *PostgreSQLQueryJobRepository) RequeueStale(ctx context.Context, staleBefore time.Time, maxAttempts int) (int64, error) {
+	const sqlQuery = `
+		UPDATE query_jobs
+		SET status = CASE WHEN attempts < $5 THEN $1 ELSE $6 END,
+			error_message = CASE WHEN attempts < $5 THEN NULL ELSE $7 END,
+			completed_time = CASE WHEN attempts < $5 THEN NULL ELSE $2 END,
+			update_time = $2
+		WHERE status = $3 AND update_time < $4`
+
+	result, err := r.db.Exec(ctx, sqlQuery, string(repository.QueryJobPending), time.Now().UTC(),
+		string(repository.QueryJobRunning), staleBefore.UTC(), maxAttempts,
+		string(repository.QueryJobFailed), fmt.Sprintf("任务执行%d次均未完成", maxAttempts))
+	if err != nil {
+		r.logger.Error("重新排队异步查询任务失败", zap.Error(err))
+		return 0, fmt.Errorf("重新排队异步查询任务失败: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// UpdateProgress 更新执行中任务的已读取行数并刷新心跳
+func (r *PostgreSQLQueryJobRepository) UpdateProgress(ctx context.Context, id int64, rowsRead int64) error {
+	const sqlQuery = `
+		UPDATE query_jobs
+		SET rows_read = $3, update_time = $4
+		WHERE id = $1 AND status = $2`
+
+	return r.update(ctx, sqlQuery, id, string(repository.QueryJobRunning), rowsRead, time.Now().UTC())
+}
+
+// Complete 保存执行结果并将任务标记为已完成
+func (r *PostgreSQLQueryJobRepository) Complete(ctx context.Context, id int64, rowsRead int64, result *repository.QueryJobResult) error {
+	const sqlQuery = `
+		UPDATE query_jobs
+		SET status = $3, rows_read = $4, result = $5, error_message = NULL, completed_time = $6, update_time = $6
+		WHERE id = $1 AND status = $2`
+
+	return r.update(ctx, sqlQuery, id, string(repository.QueryJobRunning),
+		string(repository.QueryJobCompleted), rowsRead, result, time.Now().UTC())
+}
+
+// Finish 以failed或cancelled结束执行中的任务
+func (r *PostgreSQLQueryJobRepository) Finish(ctx context.Context, id int64, status repository.QueryJobStatus, rowsRead int64, errorMessage string) error {
+	if status != repository.QueryJobFailed && status != repository.QueryJobCancelled {
+		return fmt.Errorf("%w: 任务不能以%s结束", repository.ErrInvalidInput, status)
+	}
+
+	const sqlQuery = `
+		UPDATE query_jobs
+		SET status = $3, rows_read = $4, error_message = $5, completed_time = $6, update_time = $6
+		WHERE id = $1 AND status = $2`
+
+	return r.update(ctx, sqlQuery, id, string(repository.QueryJobRunning),
+		string(status), rowsRead, errorMessage, time.Now().UTC())
+}
+
+// DeleteFinishedBefore 删除结束时间早于before的任务及其结果
+func (r *PostgreSQLQueryJobRepository) DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	const sqlQuery = `DELETE FROM query_jobs WHERE completed_time < $1`
+
+	result, err := r.db.Exec(ctx, sqlQuery, before.UTC())
+	if err != nil {
+		r.logger.Error("清理异步查询任务失败", zap.Error(err))
+		return 0, fmt.Errorf("清理异步查询任务失败: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// update 更新执行中的任务，任务不存在或已不在执行中时返回ErrNotFound
+func (r *PostgreSQLQueryJobRepository) update(ctx context.Context, sqlQuery string, id int64, args ...any) error {
+	result, err := r.db.Exec(ctx, sqlQuery, append([]any{id}, args...)...)
+	if err != nil {
+		r.logger.Error("更新异步查询任务失败", zap.Int64("job_id", id), zap.Error(err))
+		return fmt.Errorf("更新异步查询任务失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("执行中的异步查询任务不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// scanQueryJob 按queryJobColumns的顺序扫描一行
+func scanQueryJob(row pgx.Row) (*repository.QueryJob, error) {
+	job := &repository.QueryJob{}
+	err := row.Scan(
+		&job.ID,
+		&job.UserID,
+		&job.ConnectionID,
+		&job.QueryHistoryID,
+		&job.SQL,
+		&job.RowLimit,
+		&job.Status,
+		&job.RowsRead,
+		&job.Result,
+		&job.ErrorMessage,
+		&job.Attempts,
+		&job.StartedTime,
+		&job.CompletedTime,
+		&job.CreateTime,
+		&job.UpdateTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
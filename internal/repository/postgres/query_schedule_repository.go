@@ -0,0 +1,321 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// PostgreSQLQueryScheduleRepository PostgreSQL定时执行计划Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLQueryScheduleRepository struct {
+	db     queryJobQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLQueryScheduleRepository 创建定时执行计划Repository实例
+func NewPostgreSQLQueryScheduleRepository(pool DB, logger *zap.Logger) repository.QueryScheduleRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLQueryScheduleRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const queryScheduleColumns = `id, saved_query_id, workspace_id, cron_expr, timezone, delivery_type, delivery_target, enabled,
+	next_run_time, last_run_time, last_status, create_by, create_time, update_by, update_time, is_deleted`
+
+const queryScheduleRunColumns = `id, schedule_id, status, row_count, error_message, delivery_status, delivery_error,
+	started_time, completed_time`
+
+// Create 创建执行计划
+func (r *PostgreSQLQueryScheduleRepository) Create(ctx context.Context, schedule *repository.QuerySchedule) error {
+	const sqlQuery = `
+		INSERT INTO query_schedules (saved_query_id, workspace_id, cron_expr, timezone, delivery_type, delivery_target,
+			enabled, next_run_time, create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $9, $10, false)
+		RETURNING id`
+
+	now := time.Now().UTC()
+	err := r.db.QueryRow(ctx, sqlQuery,
+		schedule.SavedQueryID,
+		schedule.WorkspaceID,
+		schedule.CronExpr,
+		schedule.Timezone,
+		schedule.DeliveryType,
+		schedule.DeliveryTarget,
+		schedule.Enabled,
+		schedule.NextRunTime,
+		schedule.CreateBy,
+		now,
+	).Scan(&schedule.ID)
+	if err != nil {
+		r.logger.Error("创建定时执行计划失败", zap.Int64("saved_query_id", schedule.SavedQueryID), zap.Error(err))
+		return fmt.Errorf("创建定时执行计划失败: %w", err)
+	}
+
+	schedule.UpdateBy = schedule.CreateBy
+	schedule.CreateTime = now
+	schedule.UpdateTime = now
+	return nil
+}
+
+// GetByID 根据ID获取执行计划
+func (r *PostgreSQLQueryScheduleRepository) GetByID(ctx context.Context, id int64) (*repository.QuerySchedule, error) {
+	sqlQuery := `SELECT ` + queryScheduleColumns + ` FROM query_schedules WHERE id = $1 AND is_deleted = false`
+
+	rows, err := r.db.Query(ctx, sqlQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("查询定时执行计划失败: %w", err)
+	}
+	schedules, err := scanQuerySchedules(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(schedules) == 0 {
+		return nil, fmt.Errorf("定时执行计划不存在: %w", repository.ErrNotFound)
+	}
+	return schedules[0], nil
+}
+
+// Update 更新执行计划的设置与下一次执行时间
+func (r *PostgreSQLQueryScheduleRepository) Update(ctx context.Context, schedule *repository.QuerySchedule) error {
+	const sqlQuery = `
+		UPDATE query_schedules
+		SET cron_expr = $2, timezone = $3, delivery_type = $4, delivery_target = $5, enabled = $6,
+			next_run_time = $7, update_by = $8, update_time = $9
+		WHERE id = $1 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(ctx, sqlQuery,
+		schedule.ID,
+		schedule.CronExpr,
+		schedule.Timezone,
+		schedule.DeliveryType,
+		schedule.DeliveryTarget,
+		schedule.Enabled,
+		schedule.NextRunTime,
+		schedule.UpdateBy,
+		now,
+	)
+	if err != nil {
+		r.logger.Error("更新定时执行计划失败", zap.Int64("schedule_id", schedule.ID), zap.Error(err))
+		return fmt.Errorf("更新定时执行计划失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("定时执行计划不存在: %w", repository.ErrNotFound)
+	}
+	schedule.UpdateTime = now
+	return nil
+}
+
+// Delete 软删除执行计划，执行记录保留到过期清理
+func (r *PostgreSQLQueryScheduleRepository) Delete(ctx context.Context, id int64, deleteBy int64) error {
+	const sqlQuery = `
+		UPDATE query_schedules
+		SET is_deleted = true, enabled = false, next_run_time = NULL, update_by = $2, update_time = $3
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, deleteBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("删除定时执行计划失败", zap.Int64("schedule_id", id), zap.Error(err))
+		return fmt.Errorf("删除定时执行计划失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("定时执行计划不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// ListBySavedQuery 列出保存查询的执行计划
+func (r *PostgreSQLQueryScheduleRepository) ListBySavedQuery(ctx context.Context, savedQueryID int64) ([]*repository.QuerySchedule, error) {
+	sqlQuery := `SELECT ` + queryScheduleColumns + `
+		FROM query_schedules
+		WHERE saved_query_id = $1 AND is_deleted = false
+		ORDER BY id`
+
+	rows, err := r.db.Query(ctx, sqlQuery, savedQueryID)
+	if err != nil {
+		r.logger.Error("查询定时执行计划失败", zap.Int64("saved_query_id", savedQueryID), zap.Error(err))
+		return nil, fmt.Errorf("查询定时执行计划失败: %w", err)
+	}
+	return scanQuerySchedules(rows)
+}
+
+// ClaimDue 领取到期的执行计划，SKIP LOCKED保证多实例不会重复领取；保存查询已删除的计划不再执行
+func (r *PostgreSQLQueryScheduleRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*repository.QuerySchedule, error) {
+	sqlQuery := `
+		UPDATE query_schedules
+		SET next_run_time = $2
+		WHERE id IN (
+			SELECT s.id FROM query_schedules s
+			JOIN saved_queries q ON q.id = s.saved_query_id AND q.is_deleted = false
+			WHERE s.enabled = true AND s.is_deleted = false AND s.next_run_time <= $1
+			ORDER BY s.next_run_time
+			LIMIT $3
+			FOR UPDATE OF s SKIP LOCKED
+		)
+		RETURNING ` + queryScheduleColumns
+
+	rows, err := r.db.Query(ctx, sqlQuery, now.UTC(), now.Add(lease).UTC(), limit)
+	if err != nil {
+		r.logger.Error("领取定时执行计划失败", zap.Error(err))
+		return nil, fmt.Errorf("领取定时执行计划失败: %w", err)
+	}
+	return scanQuerySchedules(rows)
+}
+
+// RecordRun 在同一条语句中写入执行记录并更新计划的执行状态
+func (r *PostgreSQLQueryScheduleRepository) RecordRun(ctx context.Context, run *repository.QueryScheduleRun, nextRun *time.Time) error {
+	const sqlQuery = `
+		WITH run AS (
+			INSERT INTO query_schedule_runs (schedule_id, status, row_count, result, error_message,
+				delivery_status, delivery_error, started_time, completed_time)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		), schedule AS (
+			UPDATE query_schedules
+			SET last_run_time = $8, last_status = $2, next_run_time = $10
+			WHERE id = $1
+		)
+		SELECT id FROM run`
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		run.ScheduleID,
+		run.Status,
+		run.RowCount,
+		run.Result,
+		run.ErrorMessage,
+		run.DeliveryStatus,
+		run.DeliveryError,
+		run.StartedTime.UTC(),
+		run.CompletedTime.UTC(),
+		nextRun,
+	).Scan(&run.ID)
+	if err != nil {
+		r.logger.Error("保存定时执行记录失败", zap.Int64("schedule_id", run.ScheduleID), zap.Error(err))
+		return fmt.Errorf("保存定时执行记录失败: %w", err)
+	}
+	return nil
+}
+
+// ListRuns 按开始时间倒序列出执行记录，不读取执行结果
+func (r *PostgreSQLQueryScheduleRepository) ListRuns(ctx context.Context, scheduleID int64, limit int) ([]*repository.QueryScheduleRun, error) {
+	sqlQuery := `SELECT ` + queryScheduleRunColumns + `
+		FROM query_schedule_runs
+		WHERE schedule_id = $1
+		ORDER BY started_time DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, sqlQuery, scheduleID, limit)
+	if err != nil {
+		r.logger.Error("查询定时执行记录失败", zap.Int64("schedule_id", scheduleID), zap.Error(err))
+		return nil, fmt.Errorf("查询定时执行记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*repository.QueryScheduleRun
+	for rows.Next() {
+		run, err := scanQueryScheduleRun(rows, false)
+		if err != nil {
+			return nil, fmt.Errorf("扫描定时执行记录失败: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// GetRun 获取执行记录及其结果
+func (r *PostgreSQLQueryScheduleRepository) GetRun(ctx context.Context, scheduleID, runID int64) (*repository.QueryScheduleRun, error) {
+	sqlQuery := `SELECT ` + queryScheduleRunColumns + `, result
+		FROM query_schedule_runs
+		WHERE id = $1 AND schedule_id = $2`
+
+	run, err := scanQueryScheduleRun(r.db.QueryRow(ctx, sqlQuery, runID, scheduleID), true)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("定时执行记录不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("获取定时执行记录失败", zap.Int64("run_id", runID), zap.Error(err))
+		return nil, fmt.Errorf("获取定时执行记录失败: %w", err)
+	}
+	return run, nil
+}
+
+// DeleteRunsBefore 删除结束时间早于before的执行记录
+func (r *PostgreSQLQueryScheduleRepository) DeleteRunsBefore(ctx context.Context, before time.Time) (int64, error) {
+	const sqlQuery = `DELETE FROM query_schedule_runs WHERE completed_time < $1`
+
+	result, err := r.db.Exec(ctx, sqlQuery, before.UTC())
+	if err != nil {
+		r.logger.Error("清理定时执行记录失败", zap.Error(err))
+		return 0, fmt.Errorf("清理定时执行记录失败: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// scanQuerySchedules 按queryScheduleColumns的顺序扫描全部行
+func scanQuerySchedules(rows pgx.Rows) ([]*repository.QuerySchedule, error) {
+	defer rows.Close()
+
+	var schedules []*repository.QuerySchedule
+	for rows.Next() {
+		s := &repository.QuerySchedule{}
+		if err := rows.Scan(
+			&s.ID,
+			&s.SavedQueryID,
+			&s.WorkspaceID,
+			&s.CronExpr,
+			&s.Timezone,
+			&s.DeliveryType,
+			&s.DeliveryTarget,
+			&s.Enabled,
+			&s.NextRunTime,
+			&s.LastRunTime,
+			&s.LastStatus,
+			&s.CreateBy,
+			&s.CreateTime,
+			&s.UpdateBy,
+			&s.UpdateTime,
+			&s.IsDeleted,
+		); err != nil {
+			return nil, fmt.Errorf("扫描定时执行计划失败: %w", err)
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// scanQueryScheduleRun 按queryScheduleRunColumns的顺序扫描一行，withResult为true时最后一列为执行结果
+func scanQueryScheduleRun(row pgx.Row, withResult bool) (*repository.QueryScheduleRun, error) {
+	run := &repository.QueryScheduleRun{}
+	dest := []any{
+		&run.ID,
+		&run.ScheduleID,
+		&run.Status,
+		&run.RowCount,
+		&run.ErrorMessage,
+		&run.DeliveryStatus,
+		&run.DeliveryError,
+		&run.StartedTime,
+		&run.CompletedTime,
+	}
+	if withResult {
+		dest = append(dest, &run.Result)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
@@ -0,0 +1,231 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"chat2sql-go/internal/repository"
+)
+
+// questionRetentionCacheTTL 用户所属工作空间问题保留方式的缓存时间，修改设置后最多延迟该时间生效
+const questionRetentionCacheTTL = time.Minute
+
+// questionCategories 问题分类及其关键字，按顺序匹配，都不匹配时为basic_select
+var questionCategories = []struct {
+	category string
+	keywords []string
+}{
+	{"ranking", []string{"排名", "排行", "最高", "最低", "最多", "最少", "前十", "top", "rank", "highest", "lowest"}},
+	{"time_analysis", []string{"趋势", "每月", "每天", "每周", "每年", "同比", "环比", "时间", "日期", "trend", "monthly", "daily", "weekly", "per month", "per day"}},
+	{"comparison", []string{"对比", "比较", "相比", "差异", "compare", "comparison", " vs ", "versus"}},
+	{"aggregation", []string{"多少", "总数", "总和", "合计", "平均", "统计", "数量", "count", "sum", "total", "average", "how many"}},
+}
+
+// QuestionCategory 按关键字为自然语言问题归类，问题被哈希或丢弃后分类仍随查询历史保存
+func QuestionCategory(question string) string {
+	lower := strings.ToLower(question)
+	for _, c := range questionCategories {
+		for _, keyword := range c.keywords {
+			if strings.Contains(lower, keyword) {
+				return c.category
+			}
+		}
+	}
+	return "basic_select"
+}
+
+// HashQuestion 返回问题的哈希形式，空白与大小写不同的相同问题得到相同的哈希
+func HashQuestion(question string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(question), " "))
+	sum := sha256.Sum256([]byte(normalized))
+	return repository.QuestionHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// questionRetentionResolver 解析并缓存用户所属工作空间的问题保留方式，连接池与事务版本共用
+type questionRetentionResolver struct {
+	workspaces repository.WorkspaceRepository
+	now        func() time.Time
+
+	mutex sync.RWMutex
+	modes map[int64]cachedQuestionRetention // 用户ID -> 所属工作空间的保留方式
+}
+
+// cachedQuestionRetention 问题保留方式缓存项
+type cachedQuestionRetention struct {
+	mode      repository.QuestionRetention
+	expiresAt time.Time
+}
+
+// newQuestionRetentionResolver 创建问题保留方式解析器
+func newQuestionRetentionResolver(workspaces repository.WorkspaceRepository) *questionRetentionResolver {
+	return &questionRetentionResolver{
+		workspaces: workspaces,
+		now:        time.Now,
+		modes:      make(map[int64]cachedQuestionRetention),
+	}
+}
+
+// mode 获取用户所属工作空间的问题保留方式，未设置时为keep
+func (r *questionRetentionResolver) mode(ctx context.Context, userID int64) (repository.QuestionRetention, error) {
+	r.mutex.RLock()
+	entry, ok := r.modes[userID]
+	r.mutex.RUnlock()
+	if ok && r.now().Before(entry.expiresAt) {
+		return entry.mode, nil
+	}
+
+	workspace, err := r.workspaces.GetByUserID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("获取用户工作空间失败: %w", err)
+	}
+	entry = cachedQuestionRetention{
+		mode:      repository.QuestionRetention(workspace.QuestionRetention),
+		expiresAt: r.now().Add(questionRetentionCacheTTL),
+	}
+	if !entry.mode.IsValid() {
+		entry.mode = repository.QuestionRetentionKeep
+	}
+
+	r.mutex.Lock()
+	r.modes[userID] = entry
+	r.mutex.Unlock()
+	return entry.mode, nil
+}
+
+// QuestionRetentionQueryHistoryRepository 按工作空间设置保留自然语言问题的查询历史Repository
+// 写入时记录问题分类，工作空间选择hash或drop时把问题替换为哈希或空字符串，只保留分类与SQL；
+// 这类工作空间的关键字搜索改为匹配SQL，哈希后的问题不参与搜索与热门查询统计
+type QuestionRetentionQueryHistoryRepository struct {
+	repository.QueryHistoryRepository
+	retention *questionRetentionResolver
+}
+
+// NewQuestionRetentionQueryHistoryRepository 创建按工作空间设置保留问题的查询历史Repository
+func NewQuestionRetentionQueryHistoryRepository(inner repository.QueryHistoryRepository, workspaces repository.WorkspaceRepository) repository.QueryHistoryRepository {
+	return newQuestionRetentionQueryHistoryRepository(inner, newQuestionRetentionResolver(workspaces))
+}
+
+// newQuestionRetentionQueryHistoryRepository 使用已有解析器创建，事务版本与连接池版本共享缓存
+func newQuestionRetentionQueryHistoryRepository(inner repository.QueryHistoryRepository, retention *questionRetentionResolver) repository.QueryHistoryRepository {
+	return &QuestionRetentionQueryHistoryRepository{
+		QueryHistoryRepository: inner,
+		retention:              retention,
+	}
+}
+
+// Create 按保留方式处理问题后创建查询历史记录，调用方持有的记录保持原问题
+func (r *QuestionRetentionQueryHistoryRepository) Create(ctx context.Context, query *repository.QueryHistory) error {
+	return r.write(ctx, query, r.QueryHistoryRepository.Create)
+}
+
+// Update 按保留方式处理问题后更新查询历史记录，调用方持有的记录保持原问题
+func (r *QuestionRetentionQueryHistoryRepository) Update(ctx context.Context, query *repository.QueryHistory) error {
+	return r.write(ctx, query, r.QueryHistoryRepository.Update)
+}
+
+// SearchByNaturalQuery 按问题关键字搜索，问题被哈希或丢弃的工作空间改为按SQL关键字搜索
+func (r *QuestionRetentionQueryHistoryRepository) SearchByNaturalQuery(ctx context.Context, userID int64, keyword string, limit, offset int) ([]*repository.QueryHistory, error) {
+	mode, err := r.retention.mode(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mode != repository.QuestionRetentionKeep {
+		return r.QueryHistoryRepository.SearchBySQL(ctx, userID, keyword, limit, offset)
+	}
+	return r.QueryHistoryRepository.SearchByNaturalQuery(ctx, userID, keyword, limit, offset)
+}
+
+// ListPage 分页列出查询历史，问题被哈希或丢弃的工作空间按SQL关键字搜索
+func (r *QuestionRetentionQueryHistoryRepository) ListPage(ctx context.Context, filter repository.QueryHistoryFilter, after *repository.QueryHistoryKeyset, limit, offset int) ([]*repository.QueryHistory, error) {
+	if filter.Keyword != "" && !filter.SearchSQL {
+		mode, err := r.retention.mode(ctx, filter.UserID)
+		if err != nil {
+			return nil, err
+		}
+		filter.SearchSQL = mode != repository.QuestionRetentionKeep
+	}
+	return r.QueryHistoryRepository.ListPage(ctx, filter, after, limit, offset)
+}
+
+// write 记录问题分类，按保留方式替换问题后写入，写入完成后恢复调用方记录中的原问题
+func (r *QuestionRetentionQueryHistoryRepository) write(ctx context.Context, query *repository.QueryHistory, store func(context.Context, *repository.QueryHistory) error) error {
+	if repository.IsAnonymizedQuestion(query.NaturalQuery) {
+		return store(ctx, query)
+	}
+	if query.QuestionCategory == nil {
+		category := QuestionCategory(query.NaturalQuery)
+		query.QuestionCategory = &category
+	}
+
+	mode, err := r.retention.mode(ctx, query.UserID)
+	if err != nil {
+		return err
+	}
+
+	naturalQuery := query.NaturalQuery
+	defer func() {
+		query.NaturalQuery = naturalQuery
+	}()
+
+	query.NaturalQuery = retainQuestion(mode, naturalQuery)
+	return store(ctx, query)
+}
+
+// retainQuestion 按保留方式返回实际保存的问题
+func retainQuestion(mode repository.QuestionRetention, question string) string {
+	switch mode {
+	case repository.QuestionRetentionHash:
+		return HashQuestion(question)
+	case repository.QuestionRetentionDrop:
+		return ""
+	}
+	return question
+}
+
+// QuestionRetentionFeedbackRepository 按工作空间设置保留自然语言问题的反馈Repository
+// 反馈与准确率统计写入的问题同查询历史一样按hash或drop处理
+type QuestionRetentionFeedbackRepository struct {
+	repository.FeedbackRepository
+	retention *questionRetentionResolver
+}
+
+// newQuestionRetentionFeedbackRepository 使用已有解析器创建，与查询历史共享缓存
+func newQuestionRetentionFeedbackRepository(inner repository.FeedbackRepository, retention *questionRetentionResolver) repository.FeedbackRepository {
+	return &QuestionRetentionFeedbackRepository{
+		FeedbackRepository: inner,
+		retention:          retention,
+	}
+}
+
+// Create 按保留方式处理问题后创建反馈，调用方持有的记录保持原问题
+func (r *QuestionRetentionFeedbackRepository) Create(ctx context.Context, feedback *repository.Feedback) error {
+	return r.write(ctx, feedback, r.FeedbackRepository.Create)
+}
+
+// Update 按保留方式处理问题后更新反馈，调用方持有的记录保持原问题
+func (r *QuestionRetentionFeedbackRepository) Update(ctx context.Context, feedback *repository.Feedback) error {
+	return r.write(ctx, feedback, r.FeedbackRepository.Update)
+}
+
+// write 按保留方式替换问题后写入，写入完成后恢复调用方记录中的原问题
+func (r *QuestionRetentionFeedbackRepository) write(ctx context.Context, feedback *repository.Feedback, store func(context.Context, *repository.Feedback) error) error {
+	if repository.IsAnonymizedQuestion(feedback.UserQuery) {
+		return store(ctx, feedback)
+	}
+	mode, err := r.retention.mode(ctx, feedback.UserID)
+	if err != nil {
+		return err
+	}
+
+	userQuery := feedback.UserQuery
+	defer func() {
+		feedback.UserQuery = userQuery
+	}()
+	feedback.UserQuery = retainQuestion(mode, userQuery)
+	return store(ctx, feedback)
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/internal/repository"
+)
+
+// retentionWorkspaceRepository 所有用户都属于同一工作空间，记录查询次数
+type retentionWorkspaceRepository struct {
+	repository.WorkspaceRepository
+	mode    repository.QuestionRetention
+	lookups int
+}
+
+func (r *retentionWorkspaceRepository) GetByUserID(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	r.lookups++
+	workspace := &repository.Workspace{QuestionRetention: string(r.mode)}
+	workspace.ID = repository.DefaultWorkspaceID
+	return workspace, nil
+}
+
+// searchRecordingRepository 记录调用的搜索方法
+type searchRecordingRepository struct {
+	memoryQueryHistoryRepository
+	searched string
+}
+
+func (s *searchRecordingRepository) SearchByNaturalQuery(ctx context.Context, userID int64, keyword string, limit, offset int) ([]*repository.QueryHistory, error) {
+	s.searched = "natural_query:" + keyword
+	return nil, nil
+}
+
+func (s *searchRecordingRepository) SearchBySQL(ctx context.Context, userID int64, keyword string, limit, offset int) ([]*repository.QueryHistory, error) {
+	s.searched = "sql:" + keyword
+	return nil, nil
+}
+
+func (s *searchRecordingRepository) ListPage(ctx context.Context, filter repository.QueryHistoryFilter, after *repository.QueryHistoryKeyset, limit, offset int) ([]*repository.QueryHistory, error) {
+	if filter.SearchSQL {
+		s.searched = "sql:" + filter.Keyword
+	} else {
+		s.searched = "natural_query:" + filter.Keyword
+	}
+	return nil, nil
+}
+
+func TestQuestionRetentionQueryHistoryRepository(t *testing.T) {
+	ctx := context.Background()
+	rows := make(map[int64]*repository.QueryHistory)
+	workspaces := &retentionWorkspaceRepository{mode: repository.QuestionRetentionKeep}
+	resolver := newQuestionRetentionResolver(workspaces)
+	now := time.Now()
+	resolver.now = func() time.Time { return now }
+	repo := newQuestionRetentionQueryHistoryRepository(&memoryQueryHistoryRepository{rows: rows}, resolver)
+
+	create := func(question string) *repository.QueryHistory {
+		query := &repository.QueryHistory{UserID: 7, NaturalQuery: question, GeneratedSQL: "SELECT 1"}
+		require.NoError(t, repo.Create(ctx, query))
+		assert.Equal(t, question, query.NaturalQuery, "调用方持有的记录保持原问题")
+		return rows[query.ID]
+	}
+
+	kept := create("各部门平均薪资")
+	assert.Equal(t, "各部门平均薪资", kept.NaturalQuery)
+	require.NotNil(t, kept.QuestionCategory)
+	assert.Equal(t, "aggregation", *kept.QuestionCategory)
+
+	// 设置变更在缓存过期后生效
+	workspaces.mode = repository.QuestionRetentionHash
+	assert.Equal(t, "各部门平均薪资", create("各部门平均薪资").NaturalQuery)
+	now = now.Add(questionRetentionCacheTTL + time.Second)
+
+	hashed := create("  Top 10 customers by revenue ")
+	assert.True(t, strings.HasPrefix(hashed.NaturalQuery, repository.QuestionHashPrefix))
+	assert.Equal(t, HashQuestion("top 10 customers BY revenue"), hashed.NaturalQuery, "空白与大小写不影响哈希")
+	assert.Equal(t, "ranking", *hashed.QuestionCategory)
+	assert.Equal(t, "SELECT 1", hashed.GeneratedSQL)
+
+	workspaces.mode = repository.QuestionRetentionDrop
+	now = now.Add(questionRetentionCacheTTL + time.Second)
+	dropped := create("每月订单趋势")
+	assert.Empty(t, dropped.NaturalQuery)
+	assert.Equal(t, "time_analysis", *dropped.QuestionCategory)
+	assert.Equal(t, 3, workspaces.lookups)
+}
+
+func TestQuestionRetentionQueryHistoryRepository_Search(t *testing.T) {
+	ctx := context.Background()
+	workspaces := &retentionWorkspaceRepository{mode: repository.QuestionRetentionKeep}
+	inner := &searchRecordingRepository{}
+	repo := NewQuestionRetentionQueryHistoryRepository(inner, workspaces)
+
+	_, err := repo.SearchByNaturalQuery(ctx, 7, "orders", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "natural_query:orders", inner.searched)
+
+	// 问题被哈希或丢弃的工作空间按SQL关键字搜索
+	workspaces.mode = repository.QuestionRetentionDrop
+	repo = NewQuestionRetentionQueryHistoryRepository(inner, workspaces)
+	_, err = repo.SearchByNaturalQuery(ctx, 7, "orders", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "sql:orders", inner.searched)
+
+	_, err = repo.ListPage(ctx, repository.QueryHistoryFilter{UserID: 7, Keyword: "orders"}, nil, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "sql:orders", inner.searched, "分页列表的关键字搜索同样改为匹配SQL")
+}
+
+// recordingFeedbackRepository 记录写入存储的问题
+type recordingFeedbackRepository struct {
+	repository.FeedbackRepository
+	stored []string
+}
+
+func (r *recordingFeedbackRepository) Create(ctx context.Context, feedback *repository.Feedback) error {
+	r.stored = append(r.stored, feedback.UserQuery)
+	return nil
+}
+
+func (r *recordingFeedbackRepository) Update(ctx context.Context, feedback *repository.Feedback) error {
+	r.stored = append(r.stored, feedback.UserQuery)
+	return nil
+}
+
+func TestQuestionRetentionFeedbackRepository(t *testing.T) {
+	ctx := context.Background()
+	workspaces := &retentionWorkspaceRepository{mode: repository.QuestionRetentionHash}
+	inner := &recordingFeedbackRepository{}
+	repo := newQuestionRetentionFeedbackRepository(inner, newQuestionRetentionResolver(workspaces))
+
+	feedback := &repository.Feedback{UserID: 7, UserQuery: "各部门平均薪资", GeneratedSQL: "SELECT 1"}
+	require.NoError(t, repo.Create(ctx, feedback))
+	assert.Equal(t, "各部门平均薪资", feedback.UserQuery, "调用方持有的记录保持原问题")
+	require.NoError(t, repo.Update(ctx, feedback))
+	assert.Equal(t, []string{HashQuestion("各部门平均薪资"), HashQuestion("各部门平均薪资")}, inner.stored)
+
+	workspaces.mode = repository.QuestionRetentionDrop
+	repo = newQuestionRetentionFeedbackRepository(inner, newQuestionRetentionResolver(workspaces))
+	require.NoError(t, repo.Create(ctx, &repository.Feedback{UserID: 7, UserQuery: "每月订单趋势"}))
+	assert.Empty(t, inner.stored[2])
+}
+
+func TestIsAnonymizedQuestion(t *testing.T) {
+	assert.True(t, repository.IsAnonymizedQuestion(""))
+	assert.True(t, repository.IsAnonymizedQuestion(HashQuestion("用户总数")))
+	assert.False(t, repository.IsAnonymizedQuestion("用户总数"))
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// ReplicaPicker 选择一个复制延迟在允许范围内的只读副本，没有可用副本时返回nil
+type ReplicaPicker func() DB
+
+// replicaRoutedDB 读写分离的查询对象
+// 只读语句发往副本，写语句、事务与副本连接故障时的重试都由主库处理，
+// 保证所有写入只落在主库；读请求可能看到不超过副本最大延迟的旧数据
+type replicaRoutedDB struct {
+	primary DB
+	pick    ReplicaPicker
+	logger  *zap.Logger
+}
+
+// newReplicaRoutedDB 创建读写分离的查询对象
+func newReplicaRoutedDB(primary DB, pick ReplicaPicker, logger *zap.Logger) *replicaRoutedDB {
+	return &replicaRoutedDB{primary: primary, pick: pick, logger: logger}
+}
+
+// reader 返回执行sql的副本，写语句或没有可用副本时返回nil
+func (d *replicaRoutedDB) reader(sql string) DB {
+	if !isReadOnlyStatement(sql) {
+		return nil
+	}
+	return d.pick()
+}
+
+// fallback 副本连接故障时记录日志并返回true，由调用方改用主库
+func (d *replicaRoutedDB) fallback(err error) bool {
+	if !isConnectionFailure(err) {
+		return false
+	}
+	d.logger.Warn("Read replica query failed, falling back to primary", zap.Error(err))
+	return true
+}
+
+// Query 只读查询优先发往副本
+func (d *replicaRoutedDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if replica := d.reader(sql); replica != nil {
+		rows, err := replica.Query(ctx, sql, args...)
+		if err == nil || !d.fallback(err) {
+			return rows, err
+		}
+	}
+	return d.primary.Query(ctx, sql, args...)
+}
+
+// QueryRow 只读单行查询优先发往副本
+func (d *replicaRoutedDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	replica := d.reader(sql)
+	if replica == nil {
+		return d.primary.QueryRow(ctx, sql, args...)
+	}
+	return &replicaRow{d: d, replica: replica, ctx: ctx, sql: sql, args: args}
+}
+
+// Exec 写语句始终发往主库
+func (d *replicaRoutedDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return d.primary.Exec(ctx, sql, args...)
+}
+
+// Begin 事务始终在主库上执行
+func (d *replicaRoutedDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return d.primary.Begin(ctx)
+}
+
+// replicaRow 在副本上执行的单行查询，副本连接故障时在主库上重新执行
+type replicaRow struct {
+	d       *replicaRoutedDB
+	replica DB
+	ctx     context.Context
+	sql     string
+	args    []any
+}
+
+// Scan 执行查询并读取结果
+func (r *replicaRow) Scan(dest ...any) error {
+	err := r.replica.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	if err == nil || !r.d.fallback(err) {
+		return err
+	}
+	return r.d.primary.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+}
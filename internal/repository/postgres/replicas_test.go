@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestReplicaRoutedDB(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := &fakeDB{}, &fakeDB{}
+	var available bool
+	d := newReplicaRoutedDB(primary, func() DB {
+		if available {
+			return replica
+		}
+		return nil
+	}, zaptest.NewLogger(t))
+
+	// 没有可用副本时由主库处理
+	var id int64
+	require.NoError(t, d.QueryRow(ctx, "SELECT id FROM query_history WHERE id = $1", 1).Scan(&id))
+	assert.Equal(t, 1, primary.calls)
+
+	available = true
+	_, err := d.Query(ctx, "SELECT * FROM schema_metadata WHERE connection_id = $1", 1)
+	require.NoError(t, err)
+	require.NoError(t, d.QueryRow(ctx, "SELECT COUNT(*) FROM query_history WHERE user_id = $1", 1).Scan(&id))
+	assert.Equal(t, 2, replica.calls, "只读查询发往副本")
+
+	// 写语句、RETURNING写入与事务始终发往主库
+	_, err = d.Exec(ctx, "UPDATE query_history SET status = $1", "success")
+	require.NoError(t, err)
+	require.NoError(t, d.QueryRow(ctx, "INSERT INTO query_history (user_id) VALUES ($1) RETURNING id", 1).Scan(&id))
+	_, err = d.Query(ctx, "WITH d AS (UPDATE query_history SET deleted_at = now() RETURNING id) SELECT id FROM d")
+	require.NoError(t, err)
+	_, err = d.Begin(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, replica.calls)
+	assert.Equal(t, 5, primary.calls)
+
+	// 副本连接故障时回到主库
+	replica.errs = []error{io.ErrUnexpectedEOF}
+	require.NoError(t, d.QueryRow(ctx, "SELECT id FROM query_history WHERE id = $1", 1).Scan(&id))
+	assert.Equal(t, 3, replica.calls)
+	assert.Equal(t, 6, primary.calls)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
+	"chat2sql-go/internal/config"
 	"chat2sql-go/internal/repository"
 )
 
@@ -15,33 +16,122 @@ import (
 // 聚合所有子Repository，提供统一的数据访问接口和事务管理
 type PostgreSQLRepository struct {
 	pool   *pgxpool.Pool
+	db     DB // 子Repository使用的查询对象，启用故障转移时为FailoverDB
 	logger *zap.Logger
 
 	// 子Repository实例
-	userRepo         repository.UserRepository
-	queryHistoryRepo repository.QueryHistoryRepository
-	connectionRepo   repository.ConnectionRepository
-	schemaRepo       repository.SchemaRepository
-	feedbackRepo     repository.FeedbackRepository
+	userRepo           repository.UserRepository
+	queryHistoryRepo   repository.QueryHistoryRepository
+	connectionRepo     repository.ConnectionRepository
+	schemaRepo         repository.SchemaRepository
+	feedbackRepo       repository.FeedbackRepository
+	workspaceRepo      repository.WorkspaceRepository
+	writeRequestRepo   repository.WriteRequestRepository
+	approvalRepo       repository.ApprovalRepository
+	classificationRepo repository.ClassificationRepository
+	erasureRepo        repository.ErasureRepository
+	historyKeyRepo     repository.HistoryKeyRepository
+	folderRepo         repository.FolderRepository
+	savedQueryRepo     repository.SavedQueryRepository
+	schemaSnapshotRepo repository.SchemaSnapshotRepository
+	integrationRepo    repository.IntegrationCredentialRepository
+	apiKeyRepo         repository.APIKeyRepository
+	columnAccessRepo   repository.ColumnAccessRepository
+	llmUsageRepo       repository.LLMUsageRepository
+	schemaChangeRepo   repository.SchemaChangeRepository
+	queryJobRepo       repository.QueryJobRepository
+	queryScheduleRepo  repository.QueryScheduleRepository
+
+	historyKeyring    *HistoryKeyring            // 为空时查询历史不加密
+	questionRetention *questionRetentionResolver // 按工作空间设置哈希或丢弃写入的问题
+	failover          *FailoverDB                // 为空时不重试、不熔断
+	replicas          ReplicaPicker              // 为空时所有读请求由主库处理
+}
+
+// RepositoryOption PostgreSQL Repository可选配置
+type RepositoryOption func(*PostgreSQLRepository)
+
+// WithHistoryKeyring 启用查询历史透明加密，启用加密的工作空间写入的问题与SQL将被加密
+func WithHistoryKeyring(keyring *HistoryKeyring) RepositoryOption {
+	return func(r *PostgreSQLRepository) {
+		r.historyKeyring = keyring
+	}
+}
+
+// WithFailover 启用主备切换处理：瞬时错误重试，连续连接故障时熔断并返回repository.ErrUnavailable
+func WithFailover(cfg *config.DatabaseFailoverConfig) RepositoryOption {
+	return func(r *PostgreSQLRepository) {
+		r.failover = NewFailoverDB(r.pool, cfg, r.logger.Named("failover"))
+	}
+}
+
+// WithReadReplicas 启用读写分离：查询历史与元数据的只读查询发往延迟在允许范围内的只读副本
+func WithReadReplicas(pick ReplicaPicker) RepositoryOption {
+	return func(r *PostgreSQLRepository) {
+		r.replicas = pick
+	}
 }
 
 // NewPostgreSQLRepository 创建PostgreSQL Repository实例
-func NewPostgreSQLRepository(pool *pgxpool.Pool, logger *zap.Logger) repository.Repository {
+func NewPostgreSQLRepository(pool *pgxpool.Pool, logger *zap.Logger, opts ...RepositoryOption) repository.Repository {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 
-	return &PostgreSQLRepository{
+	r := &PostgreSQLRepository{
 		pool:   pool,
+		db:     pool,
 		logger: logger,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.failover != nil {
+		r.db = r.failover
+	}
+
+	// 查询历史与元数据浏览是读多写少的场景，启用读副本时只读查询由副本分担
+	db, reads := r.db, r.db
+	if r.replicas != nil {
+		reads = newReplicaRoutedDB(r.db, r.replicas, logger.Named("replicas"))
+	}
 
-		// 初始化所有子Repository
-		userRepo:         NewPostgreSQLUserRepository(pool, logger),
-		queryHistoryRepo: NewPostgreSQLQueryHistoryRepository(pool, logger),
-		connectionRepo:   NewPostgreSQLConnectionRepository(pool, logger),
-		schemaRepo:       NewPostgreSQLSchemaRepository(pool, logger),
-		feedbackRepo:     NewPostgreSQLFeedbackRepository(pool, logger),
+	// 初始化所有子Repository
+	r.userRepo = NewPostgreSQLUserRepository(db, logger)
+	r.queryHistoryRepo = NewPostgreSQLQueryHistoryRepository(reads, logger)
+	r.connectionRepo = NewPostgreSQLConnectionRepository(db, logger)
+	r.schemaRepo = NewPostgreSQLSchemaRepository(reads, logger)
+	r.feedbackRepo = NewPostgreSQLFeedbackRepository(db, logger)
+	r.workspaceRepo = NewPostgreSQLWorkspaceRepository(db, logger)
+	r.writeRequestRepo = NewPostgreSQLWriteRequestRepository(db, logger)
+	r.approvalRepo = NewPostgreSQLApprovalRepository(db, logger)
+	r.classificationRepo = NewPostgreSQLClassificationRepository(db, logger)
+	r.erasureRepo = NewPostgreSQLErasureRepository(db, logger)
+	r.historyKeyRepo = NewPostgreSQLHistoryKeyRepository(db, logger)
+	r.folderRepo = NewPostgreSQLFolderRepository(db, logger)
+	r.savedQueryRepo = NewPostgreSQLSavedQueryRepository(db, logger)
+	r.schemaSnapshotRepo = NewPostgreSQLSchemaSnapshotRepository(db, logger)
+	r.integrationRepo = NewPostgreSQLIntegrationCredentialRepository(db, logger)
+	r.apiKeyRepo = NewPostgreSQLAPIKeyRepository(db, logger)
+	r.columnAccessRepo = NewPostgreSQLColumnAccessRepository(db, logger)
+	r.llmUsageRepo = NewPostgreSQLLLMUsageRepository(db, logger)
+	r.schemaChangeRepo = NewPostgreSQLSchemaChangeRepository(db, logger)
+	r.queryJobRepo = NewPostgreSQLQueryJobRepository(db, logger)
+	r.queryScheduleRepo = NewPostgreSQLQueryScheduleRepository(db, logger)
+
+	// 问题保留方式先于加密处理，加密的是已哈希或丢弃后的问题
+	if r.historyKeyring != nil {
+		r.queryHistoryRepo = NewEncryptedQueryHistoryRepository(r.queryHistoryRepo, r.historyKeyring)
 	}
+	r.questionRetention = newQuestionRetentionResolver(r.workspaceRepo)
+	r.queryHistoryRepo = newQuestionRetentionQueryHistoryRepository(r.queryHistoryRepo, r.questionRetention)
+	r.feedbackRepo = newQuestionRetentionFeedbackRepository(r.feedbackRepo, r.questionRetention)
+	return r
+}
+
+// Available 数据库是否可用，启用故障转移且熔断器打开时返回false
+func (r *PostgreSQLRepository) Available() bool {
+	return r.failover == nil || r.failover.Available()
 }
 
 // UserRepo 获取用户Repository
@@ -69,9 +159,89 @@ func (r *PostgreSQLRepository) FeedbackRepo() repository.FeedbackRepository {
 	return r.feedbackRepo
 }
 
+// WorkspaceRepo 获取工作空间Repository
+func (r *PostgreSQLRepository) WorkspaceRepo() repository.WorkspaceRepository {
+	return r.workspaceRepo
+}
+
+// WriteRequestRepo 获取写操作申请Repository
+func (r *PostgreSQLRepository) WriteRequestRepo() repository.WriteRequestRepository {
+	return r.writeRequestRepo
+}
+
+// ApprovalRepo 获取审批申请Repository
+func (r *PostgreSQLRepository) ApprovalRepo() repository.ApprovalRepository {
+	return r.approvalRepo
+}
+
+// ClassificationRepo 获取列数据分级Repository
+func (r *PostgreSQLRepository) ClassificationRepo() repository.ClassificationRepository {
+	return r.classificationRepo
+}
+
+// ErasureRepo 获取个人数据删除Repository
+func (r *PostgreSQLRepository) ErasureRepo() repository.ErasureRepository {
+	return r.erasureRepo
+}
+
+// HistoryKeyRepo 获取查询历史数据密钥Repository
+func (r *PostgreSQLRepository) HistoryKeyRepo() repository.HistoryKeyRepository {
+	return r.historyKeyRepo
+}
+
+// FolderRepo 获取保存查询文件夹Repository
+func (r *PostgreSQLRepository) FolderRepo() repository.FolderRepository {
+	return r.folderRepo
+}
+
+// SavedQueryRepo 获取保存查询Repository
+func (r *PostgreSQLRepository) SavedQueryRepo() repository.SavedQueryRepository {
+	return r.savedQueryRepo
+}
+
+// SchemaSnapshotRepo 获取表结构快照Repository
+func (r *PostgreSQLRepository) SchemaSnapshotRepo() repository.SchemaSnapshotRepository {
+	return r.schemaSnapshotRepo
+}
+
+// IntegrationCredentialRepo 获取第三方集成凭据Repository
+func (r *PostgreSQLRepository) IntegrationCredentialRepo() repository.IntegrationCredentialRepository {
+	return r.integrationRepo
+}
+
+// APIKeyRepo 获取API密钥Repository
+func (r *PostgreSQLRepository) APIKeyRepo() repository.APIKeyRepository {
+	return r.apiKeyRepo
+}
+
+// ColumnAccessRepo 获取列访问申请Repository
+func (r *PostgreSQLRepository) ColumnAccessRepo() repository.ColumnAccessRepository {
+	return r.columnAccessRepo
+}
+
+// LLMUsageRepo 获取模型用量Repository
+func (r *PostgreSQLRepository) LLMUsageRepo() repository.LLMUsageRepository {
+	return r.llmUsageRepo
+}
+
+// SchemaChangeRepo 获取表结构变更事件Repository
+func (r *PostgreSQLRepository) SchemaChangeRepo() repository.SchemaChangeRepository {
+	return r.schemaChangeRepo
+}
+
+// QueryJobRepo 获取异步查询任务Repository
+func (r *PostgreSQLRepository) QueryJobRepo() repository.QueryJobRepository {
+	return r.queryJobRepo
+}
+
+// QueryScheduleRepo 获取定时执行计划Repository
+func (r *PostgreSQLRepository) QueryScheduleRepo() repository.QueryScheduleRepository {
+	return r.queryScheduleRepo
+}
+
 // BeginTx 开始事务，返回事务Repository
 func (r *PostgreSQLRepository) BeginTx(ctx context.Context) (repository.TxRepository, error) {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		r.logger.Error("开始事务失败", zap.Error(err))
 		return nil, fmt.Errorf("开始事务失败: %w", err)
@@ -79,17 +249,42 @@ func (r *PostgreSQLRepository) BeginTx(ctx context.Context) (repository.TxReposi
 
 	r.logger.Debug("事务开始成功")
 
-	return &PostgreSQLTxRepository{
+	txRepo := &PostgreSQLTxRepository{
 		tx:     tx,
 		logger: r.logger,
 
 		// 创建基于事务的子Repository
-		userRepo:         NewPostgreSQLTxUserRepository(tx, r.logger),
-		queryHistoryRepo: NewPostgreSQLTxQueryHistoryRepository(tx, r.logger),
-		connectionRepo:   NewPostgreSQLTxConnectionRepository(tx, r.logger),
-		schemaRepo:       NewPostgreSQLTxSchemaRepository(tx, r.logger),
-		feedbackRepo:     NewPostgreSQLTxFeedbackRepository(tx, r.logger),
-	}, nil
+		userRepo:           NewPostgreSQLTxUserRepository(tx, r.logger),
+		queryHistoryRepo:   NewPostgreSQLTxQueryHistoryRepository(tx, r.logger),
+		connectionRepo:     NewPostgreSQLTxConnectionRepository(tx, r.logger),
+		schemaRepo:         NewPostgreSQLTxSchemaRepository(tx, r.logger),
+		feedbackRepo:       NewPostgreSQLTxFeedbackRepository(tx, r.logger),
+		workspaceRepo:      NewPostgreSQLTxWorkspaceRepository(tx, r.logger),
+		writeRequestRepo:   NewPostgreSQLTxWriteRequestRepository(tx, r.logger),
+		approvalRepo:       NewPostgreSQLTxApprovalRepository(tx, r.logger),
+		classificationRepo: NewPostgreSQLTxClassificationRepository(tx, r.logger),
+		erasureRepo:        NewPostgreSQLTxErasureRepository(tx, r.logger),
+		historyKeyRepo:     NewPostgreSQLTxHistoryKeyRepository(tx, r.logger),
+		folderRepo:         NewPostgreSQLTxFolderRepository(tx, r.logger),
+		savedQueryRepo:     NewPostgreSQLTxSavedQueryRepository(tx, r.logger),
+		schemaSnapshotRepo: NewPostgreSQLTxSchemaSnapshotRepository(tx, r.logger),
+		integrationRepo:    NewPostgreSQLTxIntegrationCredentialRepository(tx, r.logger),
+		apiKeyRepo:         NewPostgreSQLTxAPIKeyRepository(tx, r.logger),
+		columnAccessRepo:   NewPostgreSQLTxColumnAccessRepository(tx, r.logger),
+		llmUsageRepo:       NewPostgreSQLTxLLMUsageRepository(tx, r.logger),
+		schemaChangeRepo:   NewPostgreSQLTxSchemaChangeRepository(tx, r.logger),
+		queryJobRepo:       NewPostgreSQLTxQueryJobRepository(tx, r.logger),
+		queryScheduleRepo:  NewPostgreSQLTxQueryScheduleRepository(tx, r.logger),
+	}
+
+	if r.historyKeyring != nil {
+		txRepo.queryHistoryRepo = NewEncryptedQueryHistoryRepository(txRepo.queryHistoryRepo, r.historyKeyring)
+	}
+	if r.questionRetention != nil {
+		txRepo.queryHistoryRepo = newQuestionRetentionQueryHistoryRepository(txRepo.queryHistoryRepo, r.questionRetention)
+		txRepo.feedbackRepo = newQuestionRetentionFeedbackRepository(txRepo.feedbackRepo, r.questionRetention)
+	}
+	return txRepo, nil
 }
 
 // Close 关闭Repository（实际上关闭连接池）
@@ -102,7 +297,7 @@ func (r *PostgreSQLRepository) Close() error {
 // HealthCheck 健康检查
 func (r *PostgreSQLRepository) HealthCheck(ctx context.Context) error {
 	var result int
-	err := r.pool.QueryRow(ctx, "SELECT 1").Scan(&result)
+	err := r.db.QueryRow(ctx, "SELECT 1").Scan(&result)
 	if err != nil {
 		r.logger.Error("repository健康检查失败", zap.Error(err))
 		return fmt.Errorf("repository健康检查失败: %w", err)
@@ -125,11 +320,27 @@ type PostgreSQLTxRepository struct {
 	logger *zap.Logger
 
 	// 基于事务的子Repository实例
-	userRepo         repository.UserRepository
-	queryHistoryRepo repository.QueryHistoryRepository
-	connectionRepo   repository.ConnectionRepository
-	schemaRepo       repository.SchemaRepository
-	feedbackRepo     repository.FeedbackRepository
+	userRepo           repository.UserRepository
+	queryHistoryRepo   repository.QueryHistoryRepository
+	connectionRepo     repository.ConnectionRepository
+	schemaRepo         repository.SchemaRepository
+	feedbackRepo       repository.FeedbackRepository
+	workspaceRepo      repository.WorkspaceRepository
+	writeRequestRepo   repository.WriteRequestRepository
+	approvalRepo       repository.ApprovalRepository
+	classificationRepo repository.ClassificationRepository
+	erasureRepo        repository.ErasureRepository
+	historyKeyRepo     repository.HistoryKeyRepository
+	folderRepo         repository.FolderRepository
+	savedQueryRepo     repository.SavedQueryRepository
+	schemaSnapshotRepo repository.SchemaSnapshotRepository
+	integrationRepo    repository.IntegrationCredentialRepository
+	apiKeyRepo         repository.APIKeyRepository
+	columnAccessRepo   repository.ColumnAccessRepository
+	llmUsageRepo       repository.LLMUsageRepository
+	schemaChangeRepo   repository.SchemaChangeRepository
+	queryJobRepo       repository.QueryJobRepository
+	queryScheduleRepo  repository.QueryScheduleRepository
 }
 
 // UserRepo 获取用户Repository（事务版本）
@@ -157,6 +368,86 @@ func (r *PostgreSQLTxRepository) FeedbackRepo() repository.FeedbackRepository {
 	return r.feedbackRepo
 }
 
+// WorkspaceRepo 获取工作空间Repository（事务版本）
+func (r *PostgreSQLTxRepository) WorkspaceRepo() repository.WorkspaceRepository {
+	return r.workspaceRepo
+}
+
+// WriteRequestRepo 获取写操作申请Repository（事务版本）
+func (r *PostgreSQLTxRepository) WriteRequestRepo() repository.WriteRequestRepository {
+	return r.writeRequestRepo
+}
+
+// ApprovalRepo 获取审批申请Repository（事务版本）
+func (r *PostgreSQLTxRepository) ApprovalRepo() repository.ApprovalRepository {
+	return r.approvalRepo
+}
+
+// ClassificationRepo 获取列数据分级Repository（事务版本）
+func (r *PostgreSQLTxRepository) ClassificationRepo() repository.ClassificationRepository {
+	return r.classificationRepo
+}
+
+// ErasureRepo 获取个人数据删除Repository（事务版本）
+func (r *PostgreSQLTxRepository) ErasureRepo() repository.ErasureRepository {
+	return r.erasureRepo
+}
+
+// HistoryKeyRepo 获取查询历史数据密钥Repository（事务版本）
+func (r *PostgreSQLTxRepository) HistoryKeyRepo() repository.HistoryKeyRepository {
+	return r.historyKeyRepo
+}
+
+// FolderRepo 获取保存查询文件夹Repository（事务版本）
+func (r *PostgreSQLTxRepository) FolderRepo() repository.FolderRepository {
+	return r.folderRepo
+}
+
+// SavedQueryRepo 获取保存查询Repository（事务版本）
+func (r *PostgreSQLTxRepository) SavedQueryRepo() repository.SavedQueryRepository {
+	return r.savedQueryRepo
+}
+
+// SchemaSnapshotRepo 获取表结构快照Repository（事务版本）
+func (r *PostgreSQLTxRepository) SchemaSnapshotRepo() repository.SchemaSnapshotRepository {
+	return r.schemaSnapshotRepo
+}
+
+// IntegrationCredentialRepo 获取第三方集成凭据Repository（事务版本）
+func (r *PostgreSQLTxRepository) IntegrationCredentialRepo() repository.IntegrationCredentialRepository {
+	return r.integrationRepo
+}
+
+// APIKeyRepo 获取API密钥Repository（事务版本）
+func (r *PostgreSQLTxRepository) APIKeyRepo() repository.APIKeyRepository {
+	return r.apiKeyRepo
+}
+
+// ColumnAccessRepo 获取列访问申请Repository（事务版本）
+func (r *PostgreSQLTxRepository) ColumnAccessRepo() repository.ColumnAccessRepository {
+	return r.columnAccessRepo
+}
+
+// LLMUsageRepo 获取模型用量Repository（事务版本）
+func (r *PostgreSQLTxRepository) LLMUsageRepo() repository.LLMUsageRepository {
+	return r.llmUsageRepo
+}
+
+// SchemaChangeRepo 获取表结构变更事件Repository（事务版本）
+func (r *PostgreSQLTxRepository) SchemaChangeRepo() repository.SchemaChangeRepository {
+	return r.schemaChangeRepo
+}
+
+// QueryJobRepo 获取异步查询任务Repository（事务版本）
+func (r *PostgreSQLTxRepository) QueryJobRepo() repository.QueryJobRepository {
+	return r.queryJobRepo
+}
+
+// QueryScheduleRepo 获取定时执行计划Repository（事务版本）
+func (r *PostgreSQLTxRepository) QueryScheduleRepo() repository.QueryScheduleRepository {
+	return r.queryScheduleRepo
+}
+
 // Commit 提交事务
 func (r *PostgreSQLTxRepository) Commit() error {
 	err := r.tx.Commit(context.Background())
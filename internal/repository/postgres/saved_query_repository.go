@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// PostgreSQLSavedQueryRepository PostgreSQL保存查询Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLSavedQueryRepository struct {
+	db     folderQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLSavedQueryRepository 创建保存查询Repository实例
+func NewPostgreSQLSavedQueryRepository(pool DB, logger *zap.Logger) repository.SavedQueryRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLSavedQueryRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const savedQueryColumns = `id, workspace_id, folder_id, owner_id, name, description, natural_query, sql_text, connection_id,
+	dashboard_backed, cache_ttl_seconds, create_by, create_time, update_by, update_time, is_deleted`
+
+// Create 创建保存查询
+func (r *PostgreSQLSavedQueryRepository) Create(ctx context.Context, query *repository.SavedQuery) error {
+	const sqlQuery = `
+		INSERT INTO saved_queries (workspace_id, folder_id, owner_id, name, description, natural_query, sql_text,
+			connection_id, dashboard_backed, cache_ttl_seconds, create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $3, $11, $3, $11, false)
+		RETURNING id`
+
+	now := time.Now().UTC()
+	err := r.db.QueryRow(ctx, sqlQuery,
+		query.WorkspaceID,
+		query.FolderID,
+		query.OwnerID,
+		query.Name,
+		query.Description,
+		query.NaturalQuery,
+		query.SQL,
+		query.ConnectionID,
+		query.DashboardBacked,
+		query.CacheTTLSeconds,
+		now,
+	).Scan(&query.ID)
+
+	if err != nil {
+		r.logger.Error("创建保存查询失败",
+			zap.Int64("workspace_id", query.WorkspaceID),
+			zap.Int64("owner_id", query.OwnerID),
+			zap.Error(err))
+		return fmt.Errorf("创建保存查询失败: %w", err)
+	}
+
+	query.CreateBy = &query.OwnerID
+	query.UpdateBy = &query.OwnerID
+	query.CreateTime = now
+	query.UpdateTime = now
+	query.IsDeleted = false
+	return nil
+}
+
+// GetByID 根据ID获取保存查询
+func (r *PostgreSQLSavedQueryRepository) GetByID(ctx context.Context, id int64) (*repository.SavedQuery, error) {
+	sqlQuery := `SELECT ` + savedQueryColumns + ` FROM saved_queries WHERE id = $1 AND is_deleted = false`
+
+	rows, err := r.db.Query(ctx, sqlQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("查询保存查询失败: %w", err)
+	}
+	queries, err := scanSavedQueries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("保存查询不存在: %w", repository.ErrNotFound)
+	}
+	return queries[0], nil
+}
+
+// Update 更新保存查询的内容
+func (r *PostgreSQLSavedQueryRepository) Update(ctx context.Context, query *repository.SavedQuery) error {
+	const sqlQuery = `
+		UPDATE saved_queries
+		SET name = $2, description = $3, natural_query = $4, sql_text = $5, connection_id = $6,
+			dashboard_backed = $7, cache_ttl_seconds = $8, update_by = $9, update_time = $10
+		WHERE id = $1 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	updateBy := query.OwnerID
+	if query.UpdateBy != nil {
+		updateBy = *query.UpdateBy
+	}
+
+	result, err := r.db.Exec(ctx, sqlQuery,
+		query.ID,
+		query.Name,
+		query.Description,
+		query.NaturalQuery,
+		query.SQL,
+		query.ConnectionID,
+		query.DashboardBacked,
+		query.CacheTTLSeconds,
+		updateBy,
+		now,
+	)
+	if err != nil {
+		r.logger.Error("更新保存查询失败", zap.Int64("saved_query_id", query.ID), zap.Error(err))
+		return fmt.Errorf("更新保存查询失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("保存查询不存在: %w", repository.ErrNotFound)
+	}
+	query.UpdateTime = now
+	return nil
+}
+
+// Delete 软删除保存查询
+func (r *PostgreSQLSavedQueryRepository) Delete(ctx context.Context, id int64, deleteBy int64) error {
+	const sqlQuery = `
+		UPDATE saved_queries
+		SET is_deleted = true, update_by = $2, update_time = $3
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, deleteBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("删除保存查询失败", zap.Int64("saved_query_id", id), zap.Error(err))
+		return fmt.Errorf("删除保存查询失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("保存查询不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// ListByFolder 按名称列出文件夹中的保存查询
+func (r *PostgreSQLSavedQueryRepository) ListByFolder(ctx context.Context, workspaceID int64, folderID *int64, limit, offset int) ([]*repository.SavedQuery, error) {
+	sqlQuery := `SELECT ` + savedQueryColumns + `
+		FROM saved_queries
+		WHERE workspace_id = $1 AND folder_id IS NOT DISTINCT FROM $2::bigint AND is_deleted = false
+		ORDER BY name, id
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.Query(ctx, sqlQuery, workspaceID, folderID, limit, offset)
+	if err != nil {
+		r.logger.Error("查询文件夹中的保存查询失败",
+			zap.Int64("workspace_id", workspaceID),
+			zap.Any("folder_id", folderID),
+			zap.Error(err))
+		return nil, fmt.Errorf("查询保存查询失败: %w", err)
+	}
+	return scanSavedQueries(rows)
+}
+
+// ListDashboardBacked 列出全部工作空间中标记为仪表盘使用且指定了连接的保存查询
+func (r *PostgreSQLSavedQueryRepository) ListDashboardBacked(ctx context.Context) ([]*repository.SavedQuery, error) {
+	sqlQuery := `SELECT ` + savedQueryColumns + `
+		FROM saved_queries
+		WHERE dashboard_backed = true AND connection_id IS NOT NULL AND is_deleted = false
+		ORDER BY id`
+
+	rows, err := r.db.Query(ctx, sqlQuery)
+	if err != nil {
+		r.logger.Error("查询仪表盘保存查询失败", zap.Error(err))
+		return nil, fmt.Errorf("查询仪表盘保存查询失败: %w", err)
+	}
+	return scanSavedQueries(rows)
+}
+
+// Move 将保存查询移动到另一个文件夹
+func (r *PostgreSQLSavedQueryRepository) Move(ctx context.Context, id int64, folderID *int64, updateBy int64) error {
+	const sqlQuery = `
+		UPDATE saved_queries
+		SET folder_id = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, folderID, updateBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("移动保存查询失败", zap.Int64("saved_query_id", id), zap.Error(err))
+		return fmt.Errorf("移动保存查询失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("保存查询不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// scanSavedQueries 扫描保存查询结果
+func scanSavedQueries(rows pgx.Rows) ([]*repository.SavedQuery, error) {
+	defer rows.Close()
+
+	var queries []*repository.SavedQuery
+	for rows.Next() {
+		q := &repository.SavedQuery{}
+		if err := rows.Scan(
+			&q.ID,
+			&q.WorkspaceID,
+			&q.FolderID,
+			&q.OwnerID,
+			&q.Name,
+			&q.Description,
+			&q.NaturalQuery,
+			&q.SQL,
+			&q.ConnectionID,
+			&q.DashboardBacked,
+			&q.CacheTTLSeconds,
+			&q.CreateBy,
+			&q.CreateTime,
+			&q.UpdateBy,
+			&q.UpdateTime,
+			&q.IsDeleted,
+		); err != nil {
+			return nil, fmt.Errorf("扫描保存查询失败: %w", err)
+		}
+		queries = append(queries, q)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历保存查询失败: %w", err)
+	}
+	return queries, nil
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// schemaChangeQuerier 连接池与事务的公共查询接口
+type schemaChangeQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgreSQLSchemaChangeRepository PostgreSQL表结构变更事件Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLSchemaChangeRepository struct {
+	db     schemaChangeQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLSchemaChangeRepository 创建表结构变更事件Repository实例
+func NewPostgreSQLSchemaChangeRepository(pool DB, logger *zap.Logger) repository.SchemaChangeRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLSchemaChangeRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+// CreateBatch 用一条语句写入全部事件，发现时间为零值的事件按当前时间记录
+func (r *PostgreSQLSchemaChangeRepository) CreateBatch(ctx context.Context, events []*repository.SchemaChangeEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	const sqlQuery = `
+		INSERT INTO schema_change_events (connection_id, change_type, object_name, detected_at)
+		SELECT * FROM unnest($1::bigint[], $2::text[], $3::text[], $4::timestamptz[])`
+
+	now := time.Now().UTC()
+	connectionIDs := make([]int64, len(events))
+	changeTypes := make([]string, len(events))
+	objectNames := make([]string, len(events))
+	detectedAt := make([]time.Time, len(events))
+	for i, event := range events {
+		if event.DetectedAt.IsZero() {
+			event.DetectedAt = now
+		}
+		connectionIDs[i] = event.ConnectionID
+		changeTypes[i] = string(event.ChangeType)
+		objectNames[i] = event.ObjectName
+		detectedAt[i] = event.DetectedAt
+	}
+
+	if _, err := r.db.Exec(ctx, sqlQuery, connectionIDs, changeTypes, objectNames, detectedAt); err != nil {
+		r.logger.Error("记录表结构变更事件失败",
+			zap.Int64("connection_id", events[0].ConnectionID),
+			zap.Int("events", len(events)),
+			zap.Error(err))
+		return fmt.Errorf("记录表结构变更事件失败: %w", err)
+	}
+	return nil
+}
+
+// ListByConnection 列出连接自since起的变更事件，按发现时间正序
+func (r *PostgreSQLSchemaChangeRepository) ListByConnection(ctx context.Context, connectionID int64, since time.Time) ([]*repository.SchemaChangeEvent, error) {
+	const sqlQuery = `
+		SELECT id, connection_id, change_type, object_name, detected_at
+		FROM schema_change_events
+		WHERE connection_id = $1 AND detected_at >= $2
+		ORDER BY detected_at, id`
+
+	rows, err := r.db.Query(ctx, sqlQuery, connectionID, since)
+	if err != nil {
+		r.logger.Error("查询表结构变更事件失败", zap.Int64("connection_id", connectionID), zap.Error(err))
+		return nil, fmt.Errorf("查询表结构变更事件失败: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*repository.SchemaChangeEvent
+	for rows.Next() {
+		event := &repository.SchemaChangeEvent{}
+		var changeType string
+		if err := rows.Scan(&event.ID, &event.ConnectionID, &changeType, &event.ObjectName, &event.DetectedAt); err != nil {
+			return nil, fmt.Errorf("扫描表结构变更事件失败: %w", err)
+		}
+		event.ChangeType = repository.SchemaChangeType(changeType)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
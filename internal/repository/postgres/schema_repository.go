@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
 	"chat2sql-go/internal/repository"
@@ -15,12 +14,12 @@ import (
 // PostgreSQLSchemaRepository PostgreSQL元数据Repository实现
 // 支持数据库结构探测、元数据缓存、表关系分析等高级功能
 type PostgreSQLSchemaRepository struct {
-	pool   *pgxpool.Pool
+	pool   DB
 	logger *zap.Logger
 }
 
 // NewPostgreSQLSchemaRepository 创建PostgreSQL元数据Repository
-func NewPostgreSQLSchemaRepository(pool *pgxpool.Pool, logger *zap.Logger) repository.SchemaRepository {
+func NewPostgreSQLSchemaRepository(pool DB, logger *zap.Logger) repository.SchemaRepository {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// schemaSnapshotRecordAttempts 并发记录同一连接的新快照时版本号冲突的重试次数
+const schemaSnapshotRecordAttempts = 3
+
+// schemaSnapshotQuerier 连接池与事务的公共查询接口，快照只需单行查询
+type schemaSnapshotQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// PostgreSQLSchemaSnapshotRepository PostgreSQL表结构快照Repository实现
+type PostgreSQLSchemaSnapshotRepository struct {
+	db     schemaSnapshotQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLSchemaSnapshotRepository 创建表结构快照Repository实例
+func NewPostgreSQLSchemaSnapshotRepository(pool DB, logger *zap.Logger) repository.SchemaSnapshotRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLSchemaSnapshotRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+// schemaHash 表结构文本的SHA-256
+func schemaHash(schemaText string) string {
+	sum := sha256.Sum256([]byte(schemaText))
+	return hex.EncodeToString(sum[:])
+}
+
+// Record 记录表结构快照
+// 内容相同的快照已存在时直接返回；否则以下一版本号插入，并发插入导致版本号冲突时重试
+func (r *PostgreSQLSchemaSnapshotRepository) Record(ctx context.Context, connectionID int64, schemaText string) (*repository.SchemaSnapshot, error) {
+	const sqlQuery = `
+		WITH existing AS (
+			SELECT id, version, create_time FROM schema_snapshots
+			WHERE connection_id = $1 AND schema_hash = $2
+		), inserted AS (
+			INSERT INTO schema_snapshots (connection_id, version, schema_hash, schema_text, create_time)
+			SELECT $1, COALESCE((SELECT MAX(version) FROM schema_snapshots WHERE connection_id = $1), 0) + 1, $2, $3, $4
+			WHERE NOT EXISTS (SELECT 1 FROM existing)
+			ON CONFLICT DO NOTHING
+			RETURNING id, version, create_time
+		)
+		SELECT id, version, create_time FROM existing
+		UNION ALL
+		SELECT id, version, create_time FROM inserted`
+
+	snapshot := &repository.SchemaSnapshot{
+		ConnectionID: connectionID,
+		SchemaHash:   schemaHash(schemaText),
+		Schema:       schemaText,
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := r.db.QueryRow(ctx, sqlQuery, connectionID, snapshot.SchemaHash, schemaText, time.Now().UTC()).
+			Scan(&snapshot.ID, &snapshot.Version, &snapshot.CreateTime)
+		if err == nil {
+			return snapshot, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) || attempt >= schemaSnapshotRecordAttempts {
+			r.logger.Error("记录表结构快照失败",
+				zap.Int64("connection_id", connectionID),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("记录表结构快照失败: %w", err)
+		}
+	}
+}
+
+// GetByID 根据ID获取表结构快照
+func (r *PostgreSQLSchemaSnapshotRepository) GetByID(ctx context.Context, id int64) (*repository.SchemaSnapshot, error) {
+	const sqlQuery = `
+		SELECT id, connection_id, version, schema_hash, schema_text, create_time
+		FROM schema_snapshots
+		WHERE id = $1`
+
+	return r.get(ctx, sqlQuery, id)
+}
+
+// GetLatest 获取连接版本号最大的快照
+func (r *PostgreSQLSchemaSnapshotRepository) GetLatest(ctx context.Context, connectionID int64) (*repository.SchemaSnapshot, error) {
+	const sqlQuery = `
+		SELECT id, connection_id, version, schema_hash, schema_text, create_time
+		FROM schema_snapshots
+		WHERE connection_id = $1
+		ORDER BY version DESC
+		LIMIT 1`
+
+	return r.get(ctx, sqlQuery, connectionID)
+}
+
+// get 查询单个快照
+func (r *PostgreSQLSchemaSnapshotRepository) get(ctx context.Context, sqlQuery string, arg int64) (*repository.SchemaSnapshot, error) {
+	snapshot := &repository.SchemaSnapshot{}
+	err := r.db.QueryRow(ctx, sqlQuery, arg).Scan(
+		&snapshot.ID,
+		&snapshot.ConnectionID,
+		&snapshot.Version,
+		&snapshot.SchemaHash,
+		&snapshot.Schema,
+		&snapshot.CreateTime,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("表结构快照不存在: %w", repository.ErrNotFound)
+		}
+		return nil, fmt.Errorf("获取表结构快照失败: %w", err)
+	}
+	return snapshot, nil
+}
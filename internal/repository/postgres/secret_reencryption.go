@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// SecretCipher 连接密码与集成凭据的加解密，由service.AESEncryption实现
+type SecretCipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// encryptedSecretColumns 使用连接加密密钥加密的列
+var encryptedSecretColumns = []struct {
+	table  string
+	column string
+}{
+	{"database_connections", "password_encrypted"},
+	{"integration_credentials", "secret_encrypted"},
+}
+
+// SecretReencryptionResult 重新加密的结果
+type SecretReencryptionResult struct {
+	Table       string
+	Reencrypted int64 // 从旧密钥迁移到新密钥的记录数
+	Skipped     int64 // 已是新密钥加密的记录数
+}
+
+// ReencryptSecrets 把连接密码与集成凭据从旧密钥迁移到新密钥
+// 全部记录在一个事务中处理，任一记录无法用新旧密钥解密时整体回滚；已用新密钥加密的记录跳过，重复执行是安全的
+func ReencryptSecrets(ctx context.Context, pool *pgxpool.Pool, from, to SecretCipher, logger *zap.Logger) ([]SecretReencryptionResult, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	results := make([]SecretReencryptionResult, 0, len(encryptedSecretColumns))
+	for _, target := range encryptedSecretColumns {
+		result, err := reencryptColumn(ctx, tx, target.table, target.column, from, to)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("重新加密完成",
+			zap.String("table", result.Table),
+			zap.Int64("reencrypted", result.Reencrypted),
+			zap.Int64("skipped", result.Skipped))
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return results, nil
+}
+
+// reencryptColumn 重新加密一张表中的加密列，按行加锁防止并发修改
+func reencryptColumn(ctx context.Context, tx pgx.Tx, table, column string, from, to SecretCipher) (SecretReencryptionResult, error) {
+	result := SecretReencryptionResult{Table: table}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT id, %s FROM %s WHERE %s <> '' ORDER BY id FOR UPDATE", column, table, column))
+	if err != nil {
+		return result, fmt.Errorf("读取%s失败: %w", table, err)
+	}
+	type encryptedRow struct {
+		id    int64
+		value string
+	}
+	var pending []encryptedRow
+	for rows.Next() {
+		var row encryptedRow
+		if err := rows.Scan(&row.id, &row.value); err != nil {
+			rows.Close()
+			return result, err
+		}
+		pending = append(pending, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	for _, row := range pending {
+		value, changed, err := reencryptValue(row.value, from, to)
+		if err != nil {
+			return result, fmt.Errorf("%s记录%d: %w", table, row.id, err)
+		}
+		if !changed {
+			result.Skipped++
+			continue
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf("UPDATE %s SET %s = $1 WHERE id = $2", table, column), value, row.id); err != nil {
+			return result, fmt.Errorf("更新%s记录%d失败: %w", table, row.id, err)
+		}
+		result.Reencrypted++
+	}
+	return result, nil
+}
+
+// reencryptValue 用旧密钥解密后以新密钥加密，已能用新密钥解密的值原样返回
+func reencryptValue(value string, from, to SecretCipher) (string, bool, error) {
+	if _, err := to.Decrypt(value); err == nil {
+		return value, false, nil
+	}
+	plaintext, err := from.Decrypt(value)
+	if err != nil {
+		return "", false, fmt.Errorf("新旧密钥均无法解密: %w", err)
+	}
+	encrypted, err := to.Encrypt(plaintext)
+	if err != nil {
+		return "", false, fmt.Errorf("加密失败: %w", err)
+	}
+	return encrypted, true, nil
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixCipher 以密钥名作前缀的测试用加解密
+type prefixCipher string
+
+func (c prefixCipher) Encrypt(plaintext string) (string, error) {
+	return string(c) + ":" + plaintext, nil
+}
+
+func (c prefixCipher) Decrypt(ciphertext string) (string, error) {
+	plaintext, ok := strings.CutPrefix(ciphertext, string(c)+":")
+	if !ok {
+		return "", errors.New("message authentication failed")
+	}
+	return plaintext, nil
+}
+
+func TestReencryptValue(t *testing.T) {
+	legacy, current := prefixCipher("legacy"), prefixCipher("current")
+
+	value, changed, err := reencryptValue("legacy:s3cret", legacy, current)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "current:s3cret", value)
+
+	value, changed, err = reencryptValue("current:s3cret", legacy, current)
+	require.NoError(t, err)
+	assert.False(t, changed, "已迁移的记录跳过，重复执行安全")
+	assert.Equal(t, "current:s3cret", value)
+
+	_, _, err = reencryptValue("other:s3cret", legacy, current)
+	assert.Error(t, err, "新旧密钥都无法解密时中止")
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxAPIKeyRepository 创建基于事务的API密钥Repository实例
+func NewPostgreSQLTxAPIKeyRepository(tx pgx.Tx, logger *zap.Logger) repository.APIKeyRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLAPIKeyRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxApprovalRepository 创建基于事务的审批申请Repository实例
+// 事务版本的SetApprovers使用保存点嵌套在外层事务中
+func NewPostgreSQLTxApprovalRepository(tx pgx.Tx, logger *zap.Logger) repository.ApprovalRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLApprovalRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxClassificationRepository 创建基于事务的列数据分级Repository实例
+func NewPostgreSQLTxClassificationRepository(tx pgx.Tx, logger *zap.Logger) repository.ClassificationRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLClassificationRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
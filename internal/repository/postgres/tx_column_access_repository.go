@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxColumnAccessRepository 创建基于事务的列访问申请Repository实例
+func NewPostgreSQLTxColumnAccessRepository(tx pgx.Tx, logger *zap.Logger) repository.ColumnAccessRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLColumnAccessRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
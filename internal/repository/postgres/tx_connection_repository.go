@@ -77,7 +77,7 @@ func (r *PostgreSQLTxConnectionRepository) Create(ctx context.Context, conn *rep
 func (r *PostgreSQLTxConnectionRepository) GetByID(ctx context.Context, id int64) (*repository.DatabaseConnection, error) {
 	const query = `
 		SELECT id, user_id, name, host, port, database_name, username, 
-			password_encrypted, db_type, status, last_tested,
+			password_encrypted, db_type, status, last_tested, write_mode_enabled, max_rows, max_result_mb,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM database_connections
 		WHERE id = $1 AND is_deleted = false`
@@ -96,6 +96,9 @@ func (r *PostgreSQLTxConnectionRepository) GetByID(ctx context.Context, id int64
 		&conn.DBType,
 		&conn.Status,
 		&conn.LastTested,
+		&conn.WriteModeEnabled,
+		&conn.MaxRows,
+		&conn.MaxResultMB,
 		&conn.CreateBy,
 		&conn.CreateTime,
 		&conn.UpdateBy,
@@ -188,7 +191,7 @@ func (r *PostgreSQLTxConnectionRepository) Delete(ctx context.Context, id int64)
 func (r *PostgreSQLTxConnectionRepository) ListByUser(ctx context.Context, userID int64) ([]*repository.DatabaseConnection, error) {
 	const query = `
 		SELECT id, user_id, name, host, port, database_name, username, 
-			password_encrypted, db_type, status, last_tested,
+			password_encrypted, db_type, status, last_tested, write_mode_enabled, max_rows, max_result_mb,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM database_connections
 		WHERE user_id = $1 AND is_deleted = false
@@ -206,7 +209,7 @@ func (r *PostgreSQLTxConnectionRepository) ListByUser(ctx context.Context, userI
 		err := rows.Scan(
 			&conn.ID, &conn.UserID, &conn.Name, &conn.Host, &conn.Port,
 			&conn.DatabaseName, &conn.Username, &conn.PasswordEncrypted,
-			&conn.DBType, &conn.Status, &conn.LastTested,
+			&conn.DBType, &conn.Status, &conn.LastTested, &conn.WriteModeEnabled, &conn.MaxRows, &conn.MaxResultMB,
 			&conn.CreateBy, &conn.CreateTime, &conn.UpdateBy, &conn.UpdateTime, &conn.IsDeleted,
 		)
 		if err != nil {
@@ -273,6 +276,48 @@ func (r *PostgreSQLTxConnectionRepository) UpdateLastTested(ctx context.Context,
 	return nil
 }
 
+// UpdateWriteMode 开启或关闭连接的写模式（事务版本）
+func (r *PostgreSQLTxConnectionRepository) UpdateWriteMode(ctx context.Context, connectionID int64, enabled bool, updateBy int64) error {
+	const query = `
+		UPDATE database_connections
+		SET write_mode_enabled = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.tx.Exec(ctx, query, connectionID, enabled, updateBy, now)
+
+	if err != nil {
+		return fmt.Errorf("failed to update write mode: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("connection not found or already deleted: %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UpdateResultLimits 设置连接的结果行数与大小上限（事务版本）
+func (r *PostgreSQLTxConnectionRepository) UpdateResultLimits(ctx context.Context, connectionID int64, maxRows, maxResultMB *int32, updateBy int64) error {
+	const query = `
+		UPDATE database_connections
+		SET max_rows = $2, max_result_mb = $3, update_by = $4, update_time = $5
+		WHERE id = $1 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.tx.Exec(ctx, query, connectionID, maxRows, maxResultMB, updateBy, now)
+
+	if err != nil {
+		return fmt.Errorf("failed to update result limits: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("connection not found or already deleted: %w", repository.ErrNotFound)
+	}
+
+	return nil
+}
+
 // BatchUpdateStatus 批量更新连接状态（事务版本）
 func (r *PostgreSQLTxConnectionRepository) BatchUpdateStatus(ctx context.Context, connectionIDs []int64, status repository.ConnectionStatus) error {
 	if len(connectionIDs) == 0 {
@@ -335,4 +380,12 @@ func (r *PostgreSQLTxConnectionRepository) CountByType(ctx context.Context, dbTy
 
 func (r *PostgreSQLTxConnectionRepository) GetActiveConnections(ctx context.Context) ([]*repository.DatabaseConnection, error) {
 	return nil, fmt.Errorf("GetActiveConnections not implemented in transaction version")
-}
\ No newline at end of file
+}
+
+func (r *PostgreSQLTxConnectionRepository) ListUnused(ctx context.Context, since time.Time) ([]*repository.UnusedConnection, error) {
+	return nil, fmt.Errorf("ListUnused not implemented in transaction version")
+}
+
+func (r *PostgreSQLTxConnectionRepository) MarkUnusedNotified(ctx context.Context, connectionID int64, notifiedAt time.Time) error {
+	return fmt.Errorf("MarkUnusedNotified not implemented in transaction version")
+}
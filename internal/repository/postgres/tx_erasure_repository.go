@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxErasureRepository 创建基于事务的个人数据删除Repository实例
+// 事务版本的EraseUserData使用保存点嵌套在外层事务中
+func NewPostgreSQLTxErasureRepository(tx pgx.Tx, logger *zap.Logger) repository.ErasureRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLErasureRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
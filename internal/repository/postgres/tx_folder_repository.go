@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxFolderRepository 创建基于事务的保存查询文件夹Repository实例
+func NewPostgreSQLTxFolderRepository(tx pgx.Tx, logger *zap.Logger) repository.FolderRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLFolderRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxHistoryKeyRepository 创建基于事务的查询历史数据密钥Repository实例
+// 事务版本的Rotate使用保存点嵌套在外层事务中
+func NewPostgreSQLTxHistoryKeyRepository(tx pgx.Tx, logger *zap.Logger) repository.HistoryKeyRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLHistoryKeyRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxIntegrationCredentialRepository 创建基于事务的第三方集成凭据Repository实例
+func NewPostgreSQLTxIntegrationCredentialRepository(tx pgx.Tx, logger *zap.Logger) repository.IntegrationCredentialRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLIntegrationCredentialRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
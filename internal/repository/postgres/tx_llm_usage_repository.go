@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxLLMUsageRepository 创建基于事务的模型用量Repository实例
+func NewPostgreSQLTxLLMUsageRepository(tx pgx.Tx, logger *zap.Logger) repository.LLMUsageRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLLLMUsageRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
@@ -35,8 +35,10 @@ func (r *PostgreSQLTxQueryHistoryRepository) Create(ctx context.Context, query *
 	const sqlQuery = `
 		INSERT INTO query_history (user_id, natural_query, generated_sql, sql_hash,
 			execution_time, result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, encryption_key_id, schema_snapshot_id, question_category,
+			cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		RETURNING id`
 
 	now := time.Now().UTC()
@@ -51,6 +53,13 @@ func (r *PostgreSQLTxQueryHistoryRepository) Create(ctx context.Context, query *
 		query.Status,
 		query.ErrorMessage,
 		query.ConnectionID,
+		query.AIConfidence,
+		query.ExecutionPath,
+		query.EncryptionKeyID,
+		query.SchemaSnapshotID,
+		query.QuestionCategory,
+		query.CostCenter,
+		query.Project,
 		query.CreateBy,
 		now,
 		query.UpdateBy,
@@ -77,6 +86,7 @@ func (r *PostgreSQLTxQueryHistoryRepository) GetByID(ctx context.Context, id int
 	const query = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash,
 			execution_time, result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, schema_snapshot_id, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history
 		WHERE id = $1 AND is_deleted = false`
@@ -94,6 +104,12 @@ func (r *PostgreSQLTxQueryHistoryRepository) GetByID(ctx context.Context, id int
 		&query_history.Status,
 		&query_history.ErrorMessage,
 		&query_history.ConnectionID,
+		&query_history.AIConfidence,
+		&query_history.ExecutionPath,
+		&query_history.SchemaSnapshotID,
+		&query_history.QuestionCategory,
+		&query_history.CostCenter,
+		&query_history.Project,
 		&query_history.CreateBy,
 		&query_history.CreateTime,
 		&query_history.UpdateBy,
@@ -185,6 +201,7 @@ func (r *PostgreSQLTxQueryHistoryRepository) ListByUser(ctx context.Context, use
 	const query = `
 		SELECT id, user_id, natural_query, generated_sql, sql_hash,
 			execution_time, result_rows, status, error_message, connection_id,
+			ai_confidence, execution_path, question_category, cost_center, project,
 			create_by, create_time, update_by, update_time, is_deleted
 		FROM query_history
 		WHERE user_id = $1 AND is_deleted = false
@@ -203,6 +220,7 @@ func (r *PostgreSQLTxQueryHistoryRepository) ListByUser(ctx context.Context, use
 		err := rows.Scan(
 			&qh.ID, &qh.UserID, &qh.NaturalQuery, &qh.GeneratedSQL, &qh.SQLHash,
 			&qh.ExecutionTime, &qh.ResultRows, &qh.Status, &qh.ErrorMessage, &qh.ConnectionID,
+			&qh.AIConfidence, &qh.ExecutionPath, &qh.QuestionCategory, &qh.CostCenter, &qh.Project,
 			&qh.CreateBy, &qh.CreateTime, &qh.UpdateBy, &qh.UpdateTime, &qh.IsDeleted,
 		)
 		if err != nil {
@@ -214,6 +232,34 @@ func (r *PostgreSQLTxQueryHistoryRepository) ListByUser(ctx context.Context, use
 	return results, nil
 }
 
+// ListPage 按create_time、id倒序分页列出用户的查询历史（事务版本）
+func (r *PostgreSQLTxQueryHistoryRepository) ListPage(ctx context.Context, filter repository.QueryHistoryFilter, after *repository.QueryHistoryKeyset, limit, offset int) ([]*repository.QueryHistory, error) {
+	query, args := queryHistoryPageSQL(filter, after, limit, offset)
+
+	rows, err := r.tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list query history page: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*repository.QueryHistory
+	for rows.Next() {
+		qh := &repository.QueryHistory{}
+		err := rows.Scan(
+			&qh.ID, &qh.UserID, &qh.NaturalQuery, &qh.GeneratedSQL, &qh.SQLHash,
+			&qh.ExecutionTime, &qh.ResultRows, &qh.Status, &qh.ErrorMessage, &qh.ConnectionID,
+			&qh.AIConfidence, &qh.ExecutionPath, &qh.QuestionCategory, &qh.CostCenter, &qh.Project,
+			&qh.CreateBy, &qh.CreateTime, &qh.UpdateBy, &qh.UpdateTime, &qh.IsDeleted,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan query history: %w", err)
+		}
+		results = append(results, qh)
+	}
+
+	return results, rows.Err()
+}
+
 // CountByUser 统计用户查询数量（事务版本）
 func (r *PostgreSQLTxQueryHistoryRepository) CountByUser(ctx context.Context, userID int64) (int64, error) {
 	const query = `SELECT COUNT(*) FROM query_history WHERE user_id = $1 AND is_deleted = false`
@@ -258,6 +304,31 @@ func (r *PostgreSQLTxQueryHistoryRepository) GetExecutionStats(ctx context.Conte
 	return stats, nil
 }
 
+// GetExecutionStatsSince 获取自since以来的执行统计（事务版本）
+func (r *PostgreSQLTxQueryHistoryRepository) GetExecutionStatsSince(ctx context.Context, userID int64, since time.Time) (*repository.QueryExecutionStats, error) {
+	const query = `
+		SELECT 
+			COUNT(*) as total_queries,
+			COUNT(CASE WHEN status = 'success' THEN 1 END) as successful_queries,
+			COALESCE(AVG(execution_time), 0) as avg_execution_time
+		FROM query_history 
+		WHERE user_id = $1 AND is_deleted = false 
+			AND create_time >= $2
+			AND execution_time IS NOT NULL`
+
+	stats := &repository.QueryExecutionStats{UserID: userID}
+	err := r.tx.QueryRow(ctx, query, userID, since.UTC()).Scan(
+		&stats.TotalQueries,
+		&stats.SuccessfulQueries,
+		&stats.AverageExecutionTime,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution stats: %w", err)
+	}
+	stats.FailedQueries = stats.TotalQueries - stats.SuccessfulQueries
+	return stats, nil
+}
+
 // 其他接口方法的简化实现，返回未实现错误
 func (r *PostgreSQLTxQueryHistoryRepository) ListByConnection(ctx context.Context, connectionID int64, limit, offset int) ([]*repository.QueryHistory, error) {
 	return nil, fmt.Errorf("ListByConnection not implemented in transaction version")
@@ -279,6 +350,14 @@ func (r *PostgreSQLTxQueryHistoryRepository) GetPopularQueries(ctx context.Conte
 	return nil, fmt.Errorf("GetPopularQueries not implemented in transaction version")
 }
 
+func (r *PostgreSQLTxQueryHistoryRepository) GetConnectionUsage(ctx context.Context, since time.Time, location string) ([]*repository.ConnectionUsage, error) {
+	return nil, fmt.Errorf("GetConnectionUsage not implemented in transaction version")
+}
+
+func (r *PostgreSQLTxQueryHistoryRepository) ListFailuresByConnection(ctx context.Context, connectionID int64, since time.Time, limit int) ([]*repository.QueryHistory, error) {
+	return nil, fmt.Errorf("ListFailuresByConnection not implemented in transaction version")
+}
+
 func (r *PostgreSQLTxQueryHistoryRepository) GetSlowQueries(ctx context.Context, minExecutionTime int32, limit int) ([]*repository.QueryHistory, error) {
 	return nil, fmt.Errorf("GetSlowQueries not implemented in transaction version")
 }
@@ -295,6 +374,43 @@ func (r *PostgreSQLTxQueryHistoryRepository) BatchUpdateStatus(ctx context.Conte
 	return fmt.Errorf("BatchUpdateStatus not implemented in transaction version")
 }
 
+// BatchDelete 批量软删除用户自己的查询历史（事务版本）
+func (r *PostgreSQLTxQueryHistoryRepository) BatchDelete(ctx context.Context, userID int64, queryIDs []int64) ([]int64, error) {
+	if len(queryIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.tx.Query(ctx, batchDeleteQueryHistorySQL, queryIDs, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch delete query history: %w", err)
+	}
+	deleted, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch delete query history: %w", err)
+	}
+	return deleted, nil
+}
+
+// BatchRetag 批量替换用户自己的查询历史的费用归属标签（事务版本）
+func (r *PostgreSQLTxQueryHistoryRepository) BatchRetag(ctx context.Context, userID int64, queryIDs []int64, tags *repository.ChargebackTags) ([]int64, error) {
+	if len(queryIDs) == 0 {
+		return nil, nil
+	}
+	if tags == nil {
+		tags = &repository.ChargebackTags{}
+	}
+
+	rows, err := r.tx.Query(ctx, batchRetagQueryHistorySQL, queryIDs, userID, tags.CostCenter, tags.Project, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch retag query history: %w", err)
+	}
+	retagged, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch retag query history: %w", err)
+	}
+	return retagged, nil
+}
+
 func (r *PostgreSQLTxQueryHistoryRepository) CleanupOldQueries(ctx context.Context, beforeDate time.Time) (int64, error) {
 	return 0, fmt.Errorf("CleanupOldQueries not implemented in transaction version")
 }
\ No newline at end of file
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxQueryJobRepository 创建基于事务的异步查询任务Repository实例
+func NewPostgreSQLTxQueryJobRepository(tx pgx.Tx, logger *zap.Logger) repository.QueryJobRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLQueryJobRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
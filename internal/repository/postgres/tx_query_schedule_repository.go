@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxQueryScheduleRepository 创建基于事务的定时执行计划Repository实例
+func NewPostgreSQLTxQueryScheduleRepository(tx pgx.Tx, logger *zap.Logger) repository.QueryScheduleRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLQueryScheduleRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxSavedQueryRepository 创建基于事务的保存查询Repository实例
+func NewPostgreSQLTxSavedQueryRepository(tx pgx.Tx, logger *zap.Logger) repository.SavedQueryRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLSavedQueryRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
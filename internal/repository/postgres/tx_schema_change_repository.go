@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxSchemaChangeRepository 创建基于事务的表结构变更事件Repository实例
+func NewPostgreSQLTxSchemaChangeRepository(tx pgx.Tx, logger *zap.Logger) repository.SchemaChangeRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLSchemaChangeRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxSchemaSnapshotRepository 创建基于事务的表结构快照Repository实例
+func NewPostgreSQLTxSchemaSnapshotRepository(tx pgx.Tx, logger *zap.Logger) repository.SchemaSnapshotRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLSchemaSnapshotRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
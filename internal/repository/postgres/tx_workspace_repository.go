@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxWorkspaceRepository 创建基于事务的工作空间Repository实例
+func NewPostgreSQLTxWorkspaceRepository(tx pgx.Tx, logger *zap.Logger) repository.WorkspaceRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLWorkspaceRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// NewPostgreSQLTxWriteRequestRepository 创建基于事务的写操作申请Repository实例
+func NewPostgreSQLTxWriteRequestRepository(tx pgx.Tx, logger *zap.Logger) repository.WriteRequestRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLWriteRequestRepository{
+		db:     tx,
+		logger: logger,
+	}
+}
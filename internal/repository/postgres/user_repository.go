@@ -7,7 +7,6 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
 	"chat2sql-go/internal/repository"
@@ -16,12 +15,12 @@ import (
 // PostgreSQLUserRepository PostgreSQL用户Repository实现
 // 基于pgx/v5实现，支持连接池、事务、批量操作
 type PostgreSQLUserRepository struct {
-	pool   *pgxpool.Pool // PostgreSQL连接池
+	pool   DB // PostgreSQL连接池
 	logger *zap.Logger   // 结构化日志器
 }
 
 // NewPostgreSQLUserRepository 创建PostgreSQL用户Repository
-func NewPostgreSQLUserRepository(pool *pgxpool.Pool, logger *zap.Logger) repository.UserRepository {
+func NewPostgreSQLUserRepository(pool DB, logger *zap.Logger) repository.UserRepository {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
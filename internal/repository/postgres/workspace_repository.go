@@ -0,0 +1,378 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// workspaceQuerier 连接池与事务的公共查询接口
+type workspaceQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgreSQLWorkspaceRepository PostgreSQL工作空间Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLWorkspaceRepository struct {
+	db     workspaceQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLWorkspaceRepository 创建工作空间Repository实例
+func NewPostgreSQLWorkspaceRepository(pool DB, logger *zap.Logger) repository.WorkspaceRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLWorkspaceRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const workspaceColumns = `w.id, w.name, w.description, w.auto_execute_policy, w.data_residency, w.defaults, w.question_retention,
+			w.latency_routing, w.chargeback_taxonomy, w.create_by, w.create_time, w.update_by, w.update_time, w.is_deleted`
+
+// Create 创建工作空间
+func (r *PostgreSQLWorkspaceRepository) Create(ctx context.Context, workspace *repository.Workspace) error {
+	const sqlQuery = `
+		INSERT INTO workspaces (name, description, auto_execute_policy, data_residency, defaults, question_retention,
+			latency_routing, chargeback_taxonomy, create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`
+
+	now := time.Now().UTC()
+	if workspace.QuestionRetention == "" {
+		workspace.QuestionRetention = string(repository.QuestionRetentionKeep)
+	}
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		workspace.Name,
+		workspace.Description,
+		workspace.AutoExecutePolicy,
+		workspace.DataResidency,
+		workspace.Defaults,
+		workspace.QuestionRetention,
+		workspace.LatencyRouting,
+		workspace.Chargeback,
+		workspace.CreateBy,
+		now,
+		workspace.UpdateBy,
+		now,
+		false,
+	).Scan(&workspace.ID)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("工作空间名称已存在: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("创建工作空间失败", zap.String("name", workspace.Name), zap.Error(err))
+		return fmt.Errorf("创建工作空间失败: %w", err)
+	}
+
+	workspace.CreateTime = now
+	workspace.UpdateTime = now
+	workspace.IsDeleted = false
+
+	r.logger.Info("工作空间创建成功", zap.Int64("workspace_id", workspace.ID), zap.String("name", workspace.Name))
+	return nil
+}
+
+// GetByID 根据ID获取工作空间
+func (r *PostgreSQLWorkspaceRepository) GetByID(ctx context.Context, id int64) (*repository.Workspace, error) {
+	sqlQuery := `
+		SELECT ` + workspaceColumns + `
+		FROM workspaces w
+		WHERE w.id = $1 AND w.is_deleted = false`
+
+	return r.getOne(ctx, sqlQuery, id)
+}
+
+// GetByUserID 获取用户所属工作空间，未加入任何工作空间时返回默认工作空间
+func (r *PostgreSQLWorkspaceRepository) GetByUserID(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	sqlQuery := `
+		SELECT ` + workspaceColumns + `
+		FROM workspaces w
+		WHERE w.id = COALESCE(
+			(SELECT m.workspace_id FROM workspace_members m WHERE m.user_id = $1), $2)
+			AND w.is_deleted = false`
+
+	return r.getOne(ctx, sqlQuery, userID, repository.DefaultWorkspaceID)
+}
+
+// getOne 查询单个工作空间
+func (r *PostgreSQLWorkspaceRepository) getOne(ctx context.Context, sqlQuery string, args ...any) (*repository.Workspace, error) {
+	workspace := &repository.Workspace{}
+
+	err := r.db.QueryRow(ctx, sqlQuery, args...).Scan(
+		&workspace.ID,
+		&workspace.Name,
+		&workspace.Description,
+		&workspace.AutoExecutePolicy,
+		&workspace.DataResidency,
+		&workspace.Defaults,
+		&workspace.QuestionRetention,
+		&workspace.LatencyRouting,
+		&workspace.Chargeback,
+		&workspace.CreateBy,
+		&workspace.CreateTime,
+		&workspace.UpdateBy,
+		&workspace.UpdateTime,
+		&workspace.IsDeleted,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("工作空间不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("获取工作空间失败", zap.Error(err))
+		return nil, fmt.Errorf("获取工作空间失败: %w", err)
+	}
+
+	return workspace, nil
+}
+
+// Update 更新工作空间基本信息与策略
+func (r *PostgreSQLWorkspaceRepository) Update(ctx context.Context, workspace *repository.Workspace) error {
+	const sqlQuery = `
+		UPDATE workspaces
+		SET name = $2, description = $3, auto_execute_policy = $4, data_residency = $5, defaults = $6,
+			update_by = $7, update_time = $8
+		WHERE id = $1 AND is_deleted = false`
+
+	now := time.Now().UTC()
+
+	result, err := r.db.Exec(ctx, sqlQuery,
+		workspace.ID,
+		workspace.Name,
+		workspace.Description,
+		workspace.AutoExecutePolicy,
+		workspace.DataResidency,
+		workspace.Defaults,
+		workspace.UpdateBy,
+		now,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("工作空间名称已存在: %w", repository.ErrDuplicateEntry)
+		}
+		r.logger.Error("更新工作空间失败", zap.Int64("workspace_id", workspace.ID), zap.Error(err))
+		return fmt.Errorf("更新工作空间失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间不存在: %w", repository.ErrNotFound)
+	}
+
+	workspace.UpdateTime = now
+	return nil
+}
+
+// UpdateAutoExecutePolicy 更新自动执行策略，policy为nil表示关闭自动执行
+func (r *PostgreSQLWorkspaceRepository) UpdateAutoExecutePolicy(ctx context.Context, workspaceID int64, policy *repository.AutoExecutePolicy) error {
+	const sqlQuery = `
+		UPDATE workspaces
+		SET auto_execute_policy = $2, update_time = $3
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, workspaceID, policy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("更新自动执行策略失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return fmt.Errorf("更新自动执行策略失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间不存在: %w", repository.ErrNotFound)
+	}
+
+	r.logger.Info("自动执行策略已更新", zap.Int64("workspace_id", workspaceID))
+	return nil
+}
+
+// UpdateDataResidency 更新数据驻留策略，policy为nil表示不限制存储区域
+func (r *PostgreSQLWorkspaceRepository) UpdateDataResidency(ctx context.Context, workspaceID int64, policy *repository.DataResidencyPolicy, updateBy int64) error {
+	const sqlQuery = `
+		UPDATE workspaces
+		SET data_residency = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, workspaceID, policy, updateBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("更新数据驻留策略失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return fmt.Errorf("更新数据驻留策略失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间不存在: %w", repository.ErrNotFound)
+	}
+
+	r.logger.Info("数据驻留策略已更新", zap.Int64("workspace_id", workspaceID), zap.Int64("update_by", updateBy))
+	return nil
+}
+
+// UpdateDefaults 更新工作空间默认设置，defaults为nil表示清除全部默认值
+func (r *PostgreSQLWorkspaceRepository) UpdateDefaults(ctx context.Context, workspaceID int64, defaults *repository.WorkspaceDefaults, updateBy int64) error {
+	const sqlQuery = `
+		UPDATE workspaces
+		SET defaults = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, workspaceID, defaults, updateBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("更新工作空间默认设置失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return fmt.Errorf("更新工作空间默认设置失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间不存在: %w", repository.ErrNotFound)
+	}
+
+	r.logger.Info("工作空间默认设置已更新", zap.Int64("workspace_id", workspaceID), zap.Int64("update_by", updateBy))
+	return nil
+}
+
+// UpdateQuestionRetention 更新自然语言问题保留方式，只影响之后写入的查询历史
+func (r *PostgreSQLWorkspaceRepository) UpdateQuestionRetention(ctx context.Context, workspaceID int64, mode repository.QuestionRetention, updateBy int64) error {
+	if !mode.IsValid() {
+		return fmt.Errorf("问题保留方式无效: %s: %w", mode, repository.ErrInvalidInput)
+	}
+
+	const sqlQuery = `
+		UPDATE workspaces
+		SET question_retention = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, workspaceID, string(mode), updateBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("更新问题保留方式失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return fmt.Errorf("更新问题保留方式失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间不存在: %w", repository.ErrNotFound)
+	}
+
+	r.logger.Info("问题保留方式已更新",
+		zap.Int64("workspace_id", workspaceID),
+		zap.String("mode", string(mode)),
+		zap.Int64("update_by", updateBy))
+	return nil
+}
+
+// UpdateLatencyRouting 更新按延迟SLO路由策略，policy为nil表示不按延迟路由
+func (r *PostgreSQLWorkspaceRepository) UpdateLatencyRouting(ctx context.Context, workspaceID int64, policy *repository.LatencyRoutingPolicy, updateBy int64) error {
+	const sqlQuery = `
+		UPDATE workspaces
+		SET latency_routing = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, workspaceID, policy, updateBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("更新延迟路由策略失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return fmt.Errorf("更新延迟路由策略失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间不存在: %w", repository.ErrNotFound)
+	}
+
+	r.logger.Info("延迟路由策略已更新", zap.Int64("workspace_id", workspaceID), zap.Int64("update_by", updateBy))
+	return nil
+}
+
+// UpdateChargebackTaxonomy 更新费用归属标签取值范围，taxonomy为nil表示不接受标签
+func (r *PostgreSQLWorkspaceRepository) UpdateChargebackTaxonomy(ctx context.Context, workspaceID int64, taxonomy *repository.ChargebackTaxonomy, updateBy int64) error {
+	const sqlQuery = `
+		UPDATE workspaces
+		SET chargeback_taxonomy = $2, update_by = $3, update_time = $4
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, workspaceID, taxonomy, updateBy, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("更新费用归属标签失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return fmt.Errorf("更新费用归属标签失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间不存在: %w", repository.ErrNotFound)
+	}
+
+	r.logger.Info("费用归属标签已更新", zap.Int64("workspace_id", workspaceID), zap.Int64("update_by", updateBy))
+	return nil
+}
+
+// AddMember 将用户加入工作空间，用户已属于其他工作空间时转移过来
+func (r *PostgreSQLWorkspaceRepository) AddMember(ctx context.Context, workspaceID, userID int64) error {
+	const sqlQuery = `
+		INSERT INTO workspace_members (workspace_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET workspace_id = EXCLUDED.workspace_id, create_time = CURRENT_TIMESTAMP`
+
+	if _, err := r.db.Exec(ctx, sqlQuery, workspaceID, userID); err != nil {
+		r.logger.Error("添加工作空间成员失败",
+			zap.Int64("workspace_id", workspaceID),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		return fmt.Errorf("添加工作空间成员失败: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember 将用户移出工作空间，之后用户归属默认工作空间
+func (r *PostgreSQLWorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID int64) error {
+	const sqlQuery = `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, sqlQuery, workspaceID, userID)
+	if err != nil {
+		r.logger.Error("移除工作空间成员失败",
+			zap.Int64("workspace_id", workspaceID),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		return fmt.Errorf("移除工作空间成员失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("工作空间成员不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// ListMemberIDs 列出工作空间成员的用户ID，默认工作空间包含未加入任何工作空间的用户
+func (r *PostgreSQLWorkspaceRepository) ListMemberIDs(ctx context.Context, workspaceID int64) ([]int64, error) {
+	const sqlQuery = `
+		SELECT u.id
+		FROM users u
+		LEFT JOIN workspace_members m ON m.user_id = u.id
+		WHERE u.is_deleted = false AND COALESCE(m.workspace_id, $2) = $1
+		ORDER BY u.id`
+
+	rows, err := r.db.Query(ctx, sqlQuery, workspaceID, repository.DefaultWorkspaceID)
+	if err != nil {
+		r.logger.Error("查询工作空间成员失败", zap.Int64("workspace_id", workspaceID), zap.Error(err))
+		return nil, fmt.Errorf("查询工作空间成员失败: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("扫描工作空间成员失败: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历工作空间成员失败: %w", err)
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// writeRequestQuerier 连接池与事务的公共查询接口
+type writeRequestQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgreSQLWriteRequestRepository PostgreSQL写操作申请Repository实现
+// 连接池版本与事务版本共用同一实现，仅底层查询对象不同
+type PostgreSQLWriteRequestRepository struct {
+	db     writeRequestQuerier
+	logger *zap.Logger
+}
+
+// NewPostgreSQLWriteRequestRepository 创建写操作申请Repository实例
+func NewPostgreSQLWriteRequestRepository(pool DB, logger *zap.Logger) repository.WriteRequestRepository {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &PostgreSQLWriteRequestRepository{
+		db:     pool,
+		logger: logger,
+	}
+}
+
+const writeRequestColumns = `id, connection_id, requested_by, natural_query, sql, statement_type,
+			estimated_rows, preview, status, approval_id, reviewed_by, reviewed_time, review_comment,
+			affected_rows, error_message, executed_time,
+			create_by, create_time, update_by, update_time, is_deleted`
+
+// Create 创建写操作申请
+func (r *PostgreSQLWriteRequestRepository) Create(ctx context.Context, req *repository.WriteRequest) error {
+	const sqlQuery = `
+		INSERT INTO write_requests (connection_id, requested_by, natural_query, sql, statement_type,
+			estimated_rows, preview, status, create_by, create_time, update_by, update_time, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`
+
+	now := time.Now().UTC()
+
+	err := r.db.QueryRow(ctx, sqlQuery,
+		req.ConnectionID,
+		req.RequestedBy,
+		req.NaturalQuery,
+		req.SQL,
+		req.StatementType,
+		req.EstimatedRows,
+		req.Preview,
+		req.Status,
+		req.RequestedBy,
+		now,
+		req.RequestedBy,
+		now,
+		false,
+	).Scan(&req.ID)
+
+	if err != nil {
+		r.logger.Error("创建写操作申请失败",
+			zap.Int64("connection_id", req.ConnectionID),
+			zap.Int64("requested_by", req.RequestedBy),
+			zap.Error(err))
+		return fmt.Errorf("创建写操作申请失败: %w", err)
+	}
+
+	req.CreateBy = &req.RequestedBy
+	req.UpdateBy = &req.RequestedBy
+	req.CreateTime = now
+	req.UpdateTime = now
+	req.IsDeleted = false
+	return nil
+}
+
+// GetByID 根据ID获取写操作申请
+func (r *PostgreSQLWriteRequestRepository) GetByID(ctx context.Context, id int64) (*repository.WriteRequest, error) {
+	sqlQuery := `
+		SELECT ` + writeRequestColumns + `
+		FROM write_requests
+		WHERE id = $1 AND is_deleted = false`
+
+	req, err := scanWriteRequest(r.db.QueryRow(ctx, sqlQuery, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("写操作申请不存在: %w", repository.ErrNotFound)
+		}
+		r.logger.Error("获取写操作申请失败", zap.Int64("request_id", id), zap.Error(err))
+		return nil, fmt.Errorf("获取写操作申请失败: %w", err)
+	}
+	return req, nil
+}
+
+// ListByStatus 按状态分页列出写操作申请，按创建时间倒序
+func (r *PostgreSQLWriteRequestRepository) ListByStatus(ctx context.Context, status repository.WriteRequestStatus, limit, offset int) ([]*repository.WriteRequest, error) {
+	sqlQuery := `
+		SELECT ` + writeRequestColumns + `
+		FROM write_requests
+		WHERE status = $1 AND is_deleted = false
+		ORDER BY create_time DESC
+		LIMIT $2 OFFSET $3`
+
+	return r.list(ctx, sqlQuery, string(status), limit, offset)
+}
+
+// ListByRequester 按状态分页列出申请人提交的写操作申请，按创建时间倒序
+func (r *PostgreSQLWriteRequestRepository) ListByRequester(ctx context.Context, requestedBy int64, status repository.WriteRequestStatus, limit, offset int) ([]*repository.WriteRequest, error) {
+	sqlQuery := `
+		SELECT ` + writeRequestColumns + `
+		FROM write_requests
+		WHERE requested_by = $1 AND status = $2 AND is_deleted = false
+		ORDER BY create_time DESC
+		LIMIT $3 OFFSET $4`
+
+	return r.list(ctx, sqlQuery, requestedBy, string(status), limit, offset)
+}
+
+// ListByConnection 分页列出连接上的写操作申请，按创建时间倒序
+func (r *PostgreSQLWriteRequestRepository) ListByConnection(ctx context.Context, connectionID int64, limit, offset int) ([]*repository.WriteRequest, error) {
+	sqlQuery := `
+		SELECT ` + writeRequestColumns + `
+		FROM write_requests
+		WHERE connection_id = $1 AND is_deleted = false
+		ORDER BY create_time DESC
+		LIMIT $2 OFFSET $3`
+
+	return r.list(ctx, sqlQuery, connectionID, limit, offset)
+}
+
+// SetApprovalID 关联审批申请
+func (r *PostgreSQLWriteRequestRepository) SetApprovalID(ctx context.Context, id, approvalID int64) error {
+	const sqlQuery = `
+		UPDATE write_requests
+		SET approval_id = $2, update_time = $3
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, approvalID, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("关联审批申请失败", zap.Int64("request_id", id), zap.Int64("approval_id", approvalID), zap.Error(err))
+		return fmt.Errorf("关联审批申请失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("写操作申请不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// Review 将pending申请标记为审批结果，条件更新保证同一申请只会被处理一次
+func (r *PostgreSQLWriteRequestRepository) Review(ctx context.Context, id int64, reviewerID *int64, status repository.WriteRequestStatus, comment *string) error {
+	const sqlQuery = `
+		UPDATE write_requests
+		SET status = $2, reviewed_by = $3, reviewed_time = $4, review_comment = $5,
+			update_by = COALESCE($3, update_by), update_time = $4
+		WHERE id = $1 AND status = $6 AND is_deleted = false`
+
+	now := time.Now().UTC()
+	result, err := r.db.Exec(ctx, sqlQuery, id, string(status), reviewerID, now, comment, string(repository.WriteRequestPending))
+	if err != nil {
+		r.logger.Error("审批写操作申请失败", zap.Int64("request_id", id), zap.Error(err))
+		return fmt.Errorf("审批写操作申请失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("写操作申请不存在或已处理: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// RecordExecution 记录审批通过后的执行结果
+func (r *PostgreSQLWriteRequestRepository) RecordExecution(ctx context.Context, id int64, status repository.WriteRequestStatus, affectedRows *int64, errorMessage *string) error {
+	const sqlQuery = `
+		UPDATE write_requests
+		SET status = $2, affected_rows = $3, error_message = $4, executed_time = $5, update_time = $5
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.Exec(ctx, sqlQuery, id, string(status), affectedRows, errorMessage, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("记录写操作执行结果失败", zap.Int64("request_id", id), zap.Error(err))
+		return fmt.Errorf("记录写操作执行结果失败: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("写操作申请不存在: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+// list 查询写操作申请列表
+func (r *PostgreSQLWriteRequestRepository) list(ctx context.Context, sqlQuery string, args ...any) ([]*repository.WriteRequest, error) {
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		r.logger.Error("查询写操作申请列表失败", zap.Error(err))
+		return nil, fmt.Errorf("查询写操作申请列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*repository.WriteRequest
+	for rows.Next() {
+		req, err := scanWriteRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描写操作申请失败: %w", err)
+		}
+		requests = append(requests, req)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历写操作申请失败: %w", err)
+	}
+	return requests, nil
+}
+
+// scanWriteRequest 按writeRequestColumns的顺序扫描一行
+func scanWriteRequest(row pgx.Row) (*repository.WriteRequest, error) {
+	req := &repository.WriteRequest{}
+	err := row.Scan(
+		&req.ID,
+		&req.ConnectionID,
+		&req.RequestedBy,
+		&req.NaturalQuery,
+		&req.SQL,
+		&req.StatementType,
+		&req.EstimatedRows,
+		&req.Preview,
+		&req.Status,
+		&req.ApprovalID,
+		&req.ReviewedBy,
+		&req.ReviewedTime,
+		&req.ReviewComment,
+		&req.AffectedRows,
+		&req.ErrorMessage,
+		&req.ExecutedTime,
+		&req.CreateBy,
+		&req.CreateTime,
+		&req.UpdateBy,
+		&req.UpdateTime,
+		&req.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
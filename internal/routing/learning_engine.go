@@ -12,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"chat2sql-go/internal/crash"
 )
 
 // LearningEngine 历史学习引擎
@@ -28,6 +30,10 @@ type LearningEngine struct {
 	// 相似性匹配器
 	similarityMatcher *SimilarityMatcher
 	
+	// 反馈对应的查询不在学习历史中时，按SQL分析复杂度与特征
+	complexityAnalyzer *ComplexityAnalyzer
+	featureExtractor   *FeatureExtractor
+	
 	// 学习统计
 	learningStats *LearningStats
 	
@@ -329,6 +335,8 @@ func NewLearningEngine(ctx context.Context, config *LearningConfig) *LearningEng
 		patternRecognizer: newPatternRecognizer(),
 		feedbackLearner:   newFeedbackLearner(),
 		similarityMatcher: newSimilarityMatcher(config.SimilarityThreshold, config.MaxSimilarQueries),
+		complexityAnalyzer: NewComplexityAnalyzer(nil),
+		featureExtractor:  newFeatureExtractor(),
 		learningStats:     newLearningStats(),
 		config:            config,
 		ctx:               engineCtx,
@@ -352,9 +360,9 @@ func (le *LearningEngine) LearnFromHistory(record *QueryHistoryRecord) error {
 	
 	// 异步学习（如果启用）
 	if le.config.EnableAsyncLearning {
-		go func() {
+		crash.Go("learning_engine.process_record", func() {
 			le.processRecordAsync(record)
-		}()
+		})
 		return nil
 	}
 	
@@ -1485,21 +1493,29 @@ func (le *LearningEngine) startAsyncLearning() {
 	le.wg.Add(1)
 	go func() {
 		defer le.wg.Done()
-		
-		ticker := time.NewTicker(le.config.UpdateInterval)
-		defer ticker.Stop()
-		
-		for {
-			select {
-			case <-ticker.C:
-				le.performPeriodicLearning()
-			case <-le.ctx.Done():
-				return
-			}
-		}
+		le.Run(le.ctx, func() {})
 	}()
 }
 
+// Run 运行定期学习循环直到ctx或引擎关闭，每轮学习后调用beat上报心跳
+// 由看门狗托管时关闭EnableAsyncLearning，改为注册Run，心跳的期望间隔为UpdateInterval
+func (le *LearningEngine) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(le.config.UpdateInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-ticker.C:
+			crash.Run("learning_engine.periodic", le.performPeriodicLearning)
+			beat()
+		case <-ctx.Done():
+			return
+		case <-le.ctx.Done():
+			return
+		}
+	}
+}
+
 func (le *LearningEngine) performPeriodicLearning() {
 	// 执行定期学习任务
 	
@@ -1634,6 +1650,11 @@ func generatePatternID() string {
 	return fmt.Sprintf("pattern_%d", time.Now().UnixNano())
 }
 
+// DefaultLearningConfig 返回默认学习引擎配置
+func DefaultLearningConfig() *LearningConfig {
+	return getDefaultLearningConfig()
+}
+
 func getDefaultLearningConfig() *LearningConfig {
 	return &LearningConfig{
 		MaxHistorySize:          100000,
@@ -0,0 +1,56 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLearningRecordNotFound 反馈对应的查询不在学习历史中，且没有提供可供学习的SQL
+var ErrLearningRecordNotFound = errors.New("学习历史中没有该查询")
+
+// LearnFromFeedback 将用户对一次查询的反馈纳入学习
+// 查询已在学习历史中时按事后修正处理，替换原反馈并重新训练；
+// 否则按SQL分析复杂度与特征，作为带反馈的新历史记录学习
+func (le *LearningEngine) LearnFromFeedback(ctx context.Context, queryID string, userID int64, sql string, feedback *UserFeedback) error {
+	if feedback == nil {
+		return errors.New("反馈不能为空")
+	}
+	if feedback.Timestamp.IsZero() {
+		feedback.Timestamp = time.Now()
+	}
+
+	if le.historyStore.GetRecord(queryID) != nil {
+		le.ApplyCorrections([]FeedbackCorrection{{QueryID: queryID, Feedback: feedback}})
+		return nil
+	}
+	if sql == "" {
+		return ErrLearningRecordNotFound
+	}
+
+	complexity, err := le.complexityAnalyzer.AnalyzeComplexity(ctx, sql, &QueryMetadata{UserID: userID})
+	if err != nil {
+		return fmt.Errorf("分析查询复杂度失败: %w", err)
+	}
+
+	actual := complexity.Category
+	if feedback.ActualCategory != nil {
+		actual = *feedback.ActualCategory
+	}
+
+	return le.LearnFromHistory(&QueryHistoryRecord{
+		ID:                queryID,
+		Query:             sql,
+		NormalizedQuery:   normalizeQueryForPattern(sql),
+		UserID:            userID,
+		PredictedCategory: complexity.Category,
+		ActualCategory:    actual,
+		ComplexityScore:   complexity.Score,
+		Features:          le.featureExtractor.ExtractFeatures(sql, complexity),
+		Feedback:          feedback,
+		Success:           feedback.IsCorrect == nil || *feedback.IsCorrect,
+		Timestamp:         feedback.Timestamp,
+		LastUpdated:       feedback.Timestamp,
+	})
+}
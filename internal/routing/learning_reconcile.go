@@ -0,0 +1,136 @@
+package routing
+
+import (
+	"sort"
+	"time"
+)
+
+// FeedbackCorrection 用户事后对查询的修正，QueryID与学习历史记录的ID一致
+type FeedbackCorrection struct {
+	QueryID  string        `json:"query_id"`
+	Feedback *UserFeedback `json:"feedback"` // 修正后的反馈，替换记录上的原反馈
+}
+
+// ApplyCorrections 用事后修正的反馈替换历史记录上的原反馈，重新训练受影响的模式与反馈权重
+// 模式与反馈权重按修正后的历史重放计算，而不是停留在首次反馈时的结果；
+// 返回修正的记录数，不在学习历史中的查询（已过期或未学习过）被忽略
+func (le *LearningEngine) ApplyCorrections(corrections []FeedbackCorrection) int {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	affected := make(map[string]bool)
+	corrected := 0
+
+	le.historyStore.mu.Lock()
+	for _, correction := range corrections {
+		record := le.historyStore.queryIndex[correction.QueryID]
+		if record == nil || correction.Feedback == nil {
+			continue
+		}
+		record.Feedback = correction.Feedback
+		if correction.Feedback.ActualCategory != nil {
+			record.ActualCategory = *correction.Feedback.ActualCategory
+		}
+		record.UpdateCount++
+		record.LastUpdated = time.Now()
+		corrected++
+
+		for _, pattern := range le.patternRecognizer.extractPatterns(record.NormalizedQuery) {
+			affected[pattern] = true
+		}
+	}
+	history := make([]*QueryHistoryRecord, len(le.historyStore.history))
+	copy(history, le.historyStore.history)
+	le.historyStore.mu.Unlock()
+
+	if corrected == 0 {
+		return 0
+	}
+
+	le.patternRecognizer.retrain(affected, history)
+	le.feedbackLearner.retrain(corrections, le.historyStore.GetRecord)
+	return corrected
+}
+
+// retrain 按时间顺序重放历史记录，重新计算受影响模式的类别、置信度与统计
+func (pr *PatternRecognizer) retrain(affected map[string]bool, history []*QueryHistoryRecord) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+
+	replayed := make(map[string]bool)
+	for _, record := range history {
+		for _, patternKey := range pr.extractPatterns(record.NormalizedQuery) {
+			if !affected[patternKey] {
+				continue
+			}
+			pattern, exists := pr.patternIndex[patternKey]
+			if !exists {
+				continue
+			}
+
+			// 模式的类别取最早记录修正后的实际分类
+			if !replayed[patternKey] {
+				replayed[patternKey] = true
+				pattern.Category = record.ActualCategory
+				pattern.Confidence = 0
+				delete(pr.patternStats, patternKey)
+			} else {
+				pr.updatePatternConfidence(pattern, record)
+			}
+			pr.updatePatternStats(patternKey, record)
+			pattern.UpdatedAt = time.Now()
+		}
+	}
+	pr.lastUpdate = time.Now()
+}
+
+// retrain 用修正后的反馈替换反馈历史中的记录（没有时追加），重放计算类别权重、特征权重与学习指标
+func (fl *FeedbackLearner) retrain(corrections []FeedbackCorrection, lookup func(id string) *QueryHistoryRecord) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	for _, correction := range corrections {
+		record := lookup(correction.QueryID)
+		if record == nil || correction.Feedback == nil {
+			continue
+		}
+
+		replaced := false
+		for _, feedbackRecord := range fl.feedbackHistory {
+			if feedbackRecord.QueryID == correction.QueryID {
+				feedbackRecord.Feedback = correction.Feedback
+				feedbackRecord.Actual = record.ActualCategory
+				replaced = true
+			}
+		}
+		if !replaced {
+			fl.feedbackHistory = append(fl.feedbackHistory, &FeedbackRecord{
+				QueryID:   record.ID,
+				Query:     record.Query,
+				UserID:    record.UserID,
+				Predicted: record.PredictedCategory,
+				Actual:    record.ActualCategory,
+				Feedback:  correction.Feedback,
+				Timestamp: time.Now(),
+			})
+		}
+	}
+
+	fl.categoryWeights = make(map[ComplexityCategory]float64)
+	fl.featureAdjustments = make(map[string]float64)
+	fl.learningMetrics = &LearningMetrics{CategoryAccuracy: make(map[string]float64)}
+
+	for _, feedbackRecord := range fl.feedbackHistory {
+		feedbackRecord.Adjustment = fl.calculateFeedbackAdjustment(feedbackRecord)
+		fl.updateCategoryWeights(feedbackRecord)
+		// 特征取自学习历史，记录已过期时跳过特征权重
+		if record := lookup(feedbackRecord.QueryID); record != nil {
+			fl.updateFeatureWeights(feedbackRecord, record.Features)
+		}
+		fl.updateLearningMetrics(feedbackRecord)
+	}
+}
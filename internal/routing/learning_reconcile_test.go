@@ -0,0 +1,97 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLearningEngine_ApplyCorrections(t *testing.T) {
+	config := getDefaultLearningConfig()
+	config.EnableAsyncLearning = false
+	engine := NewLearningEngine(context.Background(), config)
+	defer engine.Close()
+
+	correct := true
+	start := time.Now().Add(-21 * 24 * time.Hour)
+	for i, id := range []string{"q1", "q2"} {
+		require.NoError(t, engine.LearnFromHistory(&QueryHistoryRecord{
+			ID:                id,
+			Query:             "select name from users where id = 1",
+			NormalizedQuery:   "select name from users where id = ?",
+			UserID:            7,
+			PredictedCategory: CategorySimple,
+			ActualCategory:    CategorySimple,
+			Features:          &QueryFeatures{QueryLength: 0.2, WordCount: 0.1},
+			Feedback:          &UserFeedback{Rating: 5, IsCorrect: &correct},
+			Success:           true,
+			Timestamp:         start.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	pattern := engine.patternRecognizer.patternIndex["KEYWORDS:select,from,where"]
+	require.NotNil(t, pattern)
+	assert.InDelta(t, 0.05, pattern.Confidence, 1e-9)
+	assert.Equal(t, int64(2), engine.feedbackLearner.learningMetrics.PositiveFeedback)
+	assert.Greater(t, engine.feedbackLearner.categoryWeights[CategorySimple], 0.0)
+
+	// 三周后用户指出q2实际是中等复杂度查询
+	incorrect := false
+	medium := CategoryMedium
+	corrected := engine.ApplyCorrections([]FeedbackCorrection{
+		{QueryID: "q2", Feedback: &UserFeedback{Rating: 2, IsCorrect: &incorrect, ActualCategory: &medium}},
+		{QueryID: "expired", Feedback: &UserFeedback{IsCorrect: &incorrect}},
+	})
+	assert.Equal(t, 1, corrected)
+
+	record := engine.historyStore.GetRecord("q2")
+	assert.Equal(t, CategoryMedium, record.ActualCategory)
+	assert.Equal(t, 1, record.UpdateCount)
+
+	// 模式置信度按修正后的历史重放：q2与模式类别不一致
+	assert.Equal(t, CategorySimple, pattern.Category)
+	assert.Zero(t, pattern.Confidence)
+	assert.Equal(t, int64(2), engine.patternRecognizer.patternStats["KEYWORDS:select,from,where"].MatchCount)
+
+	metrics := engine.feedbackLearner.learningMetrics
+	assert.Equal(t, int64(2), metrics.TotalFeedback)
+	assert.Equal(t, int64(1), metrics.PositiveFeedback)
+	assert.Equal(t, int64(1), metrics.NegativeFeedback)
+	assert.Len(t, engine.feedbackLearner.feedbackHistory, 2)
+	assert.InDelta(t, 0.01, engine.feedbackLearner.categoryWeights[CategoryMedium], 1e-9)
+}
+
+func TestLearningEngine_LearnFromFeedback(t *testing.T) {
+	config := DefaultLearningConfig()
+	config.EnableAsyncLearning = false
+	engine := NewLearningEngine(context.Background(), config)
+	defer engine.Close()
+	ctx := context.Background()
+
+	correct := true
+	require.NoError(t, engine.LearnFromFeedback(ctx, "q1", 7, "SELECT name FROM users WHERE id = 1", &UserFeedback{Rating: 5, IsCorrect: &correct}))
+
+	record := engine.historyStore.GetRecord("q1")
+	require.NotNil(t, record)
+	assert.Equal(t, int64(7), record.UserID)
+	assert.Equal(t, record.PredictedCategory, record.ActualCategory)
+	assert.NotNil(t, record.Features)
+	assert.True(t, record.Success)
+	assert.False(t, record.Feedback.Timestamp.IsZero())
+	assert.Equal(t, int64(1), engine.feedbackLearner.learningMetrics.PositiveFeedback)
+
+	// 同一查询的再次反馈按事后修正处理，不再追加历史记录
+	incorrect := false
+	category := CategoryComplex
+	require.NoError(t, engine.LearnFromFeedback(ctx, "q1", 7, "", &UserFeedback{Rating: 1, IsCorrect: &incorrect, ActualCategory: &category}))
+	assert.Equal(t, CategoryComplex, record.ActualCategory)
+	assert.Equal(t, 1, record.UpdateCount)
+	assert.Len(t, engine.historyStore.history, 1)
+	assert.Equal(t, int64(1), engine.feedbackLearner.learningMetrics.NegativeFeedback)
+
+	err := engine.LearnFromFeedback(ctx, "unknown", 7, "", &UserFeedback{Rating: 3})
+	assert.ErrorIs(t, err, ErrLearningRecordNotFound)
+}
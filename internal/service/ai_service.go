@@ -3,10 +3,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -14,20 +16,71 @@ import (
 	"github.com/tmc/langchaingo/llms/anthropic"
 	"github.com/tmc/langchaingo/llms/openai"
 	"github.com/tmc/langchaingo/llms/ollama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"chat2sql-go/internal/ai"
 	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/sqlformat"
+	"chat2sql-go/internal/sqlvalidator"
+	"chat2sql-go/internal/tracing"
 )
 
+// ErrUnsafeGeneratedSQL 模型生成的SQL不是单条只读查询
+var ErrUnsafeGeneratedSQL = errors.New("生成的SQL未通过安全检查")
+
 // AIService AI服务基础架构
 type AIService struct {
 	// LLM客户端
 	primaryClient  llms.Model
 	fallbackClient llms.Model
 	
+	// 本地Ollama模型，降级链的最后一环；为nil表示未配置
+	localClient llms.Model
+	localConfig config.ModelConfig
+	
+	// 模型降级链，决定尝试顺序、重试与熔断；为nil时先主模型后备用模型，不重试也不熔断
+	chain *ProviderChain
+	
 	// 配置管理
 	config *config.AIConfig
 	
+	// 意图分类器，提供置信度信号；内部状态非并发安全，调用需加锁
+	intentAnalyzer *ai.IntentAnalyzer
+	intentMu       sync.Mutex
+	
+	// 按意图类别覆盖温度与top_p；为nil时所有请求使用模型配置的参数
+	intentGeneration *config.IntentGenerationConfig
+	
+	// 截断SQL续写配置；为nil时不续写，模型输出不完整的SQL直接交给校验
+	continuation *config.SQLContinuationConfig
+	
+	// 温度为0的模型响应缓存；为nil时每次都调用提供商
+	promptCache *PromptCache
+	
+	// 多候选生成时的排序器
+	candidateRanker *CandidateRanker
+	
+	// 主备模型的滚动延迟统计，用于按工作空间延迟SLO路由
+	latency *LatencyTracker
+	
+	// 常见意图的SQL模板，命中时不调用模型；为nil表示不启用
+	templates *SQLTemplateEngine
+	
+	// 返回前格式化生成的SQL
+	formatSQLEnabled bool
+	
+	// 按连接解析数据库类型，决定生成SQL的方言；为nil时按PostgreSQL生成
+	dbTypes DBTypeResolver
+	
+	// 请求未携带表结构时按问题从已保存的元数据挑选相关表；为nil时提示词中只有请求携带的表结构
+	prompts *ai.PromptBuilder
+	
+	// 按用户与提供商记录模型用量并检查每日预算；为nil时不记录也不限制
+	costs *ai.CostTracker
+	
 	// HTTP客户端优化
 	httpClient *http.Client
 	
@@ -44,6 +97,9 @@ type AIMetrics struct {
 	RequestDuration  *prometheus.HistogramVec
 	TokensUsed       *prometheus.CounterVec
 	ErrorsTotal      *prometheus.CounterVec
+	
+	// ChargebackTokens 按费用归属标签累计的Token用量，标签取值受工作空间取值范围约束
+	ChargebackTokens *prometheus.CounterVec
 }
 
 // SQLGenerationRequest SQL生成请求
@@ -52,14 +108,131 @@ type SQLGenerationRequest struct {
 	ConnectionID int64  `json:"connection_id"`
 	UserID       int64  `json:"user_id"`
 	Schema       string `json:"schema,omitempty"`
+	
+	// Candidates 大于1时生成多条候选SQL并排序，上限为配置的MaxCandidates
+	Candidates int `json:"candidates,omitempty"`
+	
+	// AllowWrite 允许生成INSERT/UPDATE，仅用于已开启写模式的连接，生成结果仍需预演与审批
+	AllowWrite bool `json:"allow_write,omitempty"`
+	
+	// RestrictedColumns 按数据分级当前用户无权直接查看的列，提示词要求模型不要选择
+	RestrictedColumns []string `json:"restricted_columns,omitempty"`
+	
+	// Locale 用户语言（zh/en），决定结果列别名使用的语言，为空时沿用模型默认行为
+	Locale string `json:"locale,omitempty"`
+	
+	// Model 只使用指定模型（ModelPrimary/ModelFallback/ModelLocal）且失败时不降级，用于回放对比；为空时按降级链顺序尝试
+	Model string `json:"model,omitempty"`
+	
+	// Workload 请求类型（WorkloadInteractive/WorkloadBatch），为空视为交互请求；批量与定时请求始终优先使用主模型
+	Workload string `json:"workload,omitempty"`
+	
+	// LatencySLO 主模型P95延迟目标，交互请求在主模型超过目标且备用模型更快时优先使用备用模型；为0表示不按延迟路由
+	LatencySLO time.Duration `json:"latency_slo,omitempty"`
+	
+	// History 同一对话会话中之前的问答（按时间顺序），追问时作为上下文交给模型；非空时不走SQL模板
+	History []ConversationTurn `json:"history,omitempty"`
+	
+	// Dialect 目标数据库类型（postgresql/mysql），为空时按连接解析，解析失败按PostgreSQL生成
+	Dialect repository.DatabaseType `json:"dialect,omitempty"`
+	
+	// SchemaCorrections 问题中拼错的表名、列名，按连接元数据生成表结构时填充，写入提示词并随响应返回
+	SchemaCorrections []ai.SchemaCorrection `json:"schema_corrections,omitempty"`
+}
+
+// DBTypeResolver 按连接查询数据库类型，由ConnectionManager实现
+type DBTypeResolver interface {
+	GetDBType(ctx context.Context, connectionID int64) (repository.DatabaseType, error)
 }
 
+// 请求类型
+const (
+	WorkloadInteractive = "interactive" // 用户在线等待结果的请求
+	WorkloadBatch       = "batch"       // 批量与定时请求，不受延迟路由影响
+)
+
+// 可指定的生成模型
+const (
+	ModelPrimary  = config.LLMChainPrimary
+	ModelFallback = config.LLMChainFallback
+	ModelLocal    = config.LLMChainLocal // 本地Ollama模型，需先调用EnableLocalModel
+)
+
 // SQLGenerationResponse SQL生成响应
 type SQLGenerationResponse struct {
 	SQL            string        `json:"sql"`
 	Confidence     float64       `json:"confidence"`
 	ProcessingTime time.Duration `json:"processing_time"`
 	Error          error         `json:"error,omitempty"`
+	
+	// 置信度构成与是否需要用户确认后再执行
+	ConfidenceBreakdown  *ConfidenceBreakdown `json:"confidence_breakdown,omitempty"`
+	RequiresConfirmation bool                 `json:"requires_confirmation"`
+	
+	// 多候选模式下的选中候选与其余备选（按得分降序）
+	SelectedCandidate *SQLCandidate   `json:"selected_candidate,omitempty"`
+	Alternates        []*SQLCandidate `json:"alternates,omitempty"`
+	
+	// 生成返回SQL实际使用的模型参数
+	Generation *GenerationParameters `json:"generation,omitempty"`
+	
+	// 命中的SQL模板，非空时SQL由模板直接生成，未调用模型
+	Template string `json:"template,omitempty"`
+	
+	// 问题中引号内的字面值替换为占位符后的SQL与按占位符顺序排列的参数，没有可提取的字面值时为空
+	ParameterizedSQL string   `json:"parameterized_sql,omitempty"`
+	Parameters       []string `json:"parameters,omitempty"`
+	
+	// 问题中拼错的表名、列名及其理解方式，如“costumers”按customers理解
+	SchemaCorrections []ai.SchemaCorrection `json:"schema_corrections,omitempty"`
+	
+	// 发送给模型的提示词与模型原始输出，仅供归档排查，不返回给客户端
+	Prompt     string `json:"-"`
+	Completion string `json:"-"`
+}
+
+// GenerationParameters 实际生效的生成参数，随响应返回以便复现同一结果
+type GenerationParameters struct {
+	Provider      string   `json:"provider"`
+	Model         string   `json:"model"`
+	Temperature   float64  `json:"temperature"`
+	TopP          *float64 `json:"top_p,omitempty"` // 仅按意图类别覆盖了top_p时非空
+	MaxTokens     int      `json:"max_tokens"`
+	Seed          *int     `json:"seed,omitempty"`          // 未固定采样种子时为空
+	Deterministic bool     `json:"deterministic"`           // 温度为0且固定采样种子，同一提示词与模型版本下结果可复现
+	Intent        string   `json:"intent,omitempty"`        // 决定生成参数的意图类别，未启用按意图覆盖时为空
+	Continuations int      `json:"continuations,omitempty"` // 输出被截断后的续写次数
+	Cached        bool     `json:"cached,omitempty"`        // 响应来自模型响应缓存，未调用提供商
+
+	slot string // 产生响应的模型（ModelPrimary/ModelFallback/ModelLocal），续写时调用同一模型
+}
+
+// newGenerationParameters 按模型配置与实际温度构建生成参数
+func newGenerationParameters(cfg config.ModelConfig, temperature float64) *GenerationParameters {
+	params := &GenerationParameters{
+		Provider:    cfg.Provider,
+		Model:       cfg.ModelName,
+		Temperature: temperature,
+		MaxTokens:   cfg.MaxTokens,
+	}
+	if cfg.Seed != 0 {
+		seed := cfg.Seed
+		params.Seed = &seed
+		params.Deterministic = temperature == 0
+	}
+	return params
+}
+
+// generationOptions 模型调用参数，配置了采样种子时一并传递
+func generationOptions(cfg config.ModelConfig, temperature float64) []llms.CallOption {
+	opts := []llms.CallOption{
+		llms.WithTemperature(temperature),
+		llms.WithMaxTokens(cfg.MaxTokens),
+	}
+	if cfg.Seed != 0 {
+		opts = append(opts, llms.WithSeed(cfg.Seed))
+	}
+	return opts
 }
 
 // NewAIService 创建新的AI服务实例
@@ -79,17 +252,8 @@ func NewAIService(aiConfig *config.AIConfig, logger *zap.Logger) (*AIService, er
 		return nil, fmt.Errorf("创建备用模型客户端失败: %w", err)
 	}
 	
-	// 初始化监控指标
-	metrics := createMetrics()
-	
-	service := &AIService{
-		primaryClient:  primaryClient,
-		fallbackClient: fallbackClient,
-		config:         aiConfig,
-		httpClient:     httpClient,
-		metrics:        metrics,
-		logger:         logger,
-	}
+	service := NewAIServiceWithClients(aiConfig, primaryClient, fallbackClient, logger)
+	service.httpClient = httpClient
 	
 	logger.Info("AI服务初始化成功",
 		zap.String("primary_provider", aiConfig.Primary.Provider),
@@ -101,6 +265,109 @@ func NewAIService(aiConfig *config.AIConfig, logger *zap.Logger) (*AIService, er
 	return service, nil
 }
 
+// NewAIServiceWithClients 使用外部提供的模型客户端创建AI服务
+// 用于注入录制/回放代理或mock模型，config仍决定温度、token上限等调用参数
+func NewAIServiceWithClients(aiConfig *config.AIConfig, primaryClient, fallbackClient llms.Model, logger *zap.Logger) *AIService {
+	return &AIService{
+		primaryClient:   primaryClient,
+		fallbackClient:  fallbackClient,
+		config:          aiConfig,
+		intentAnalyzer:  ai.NewIntentAnalyzer(),
+		candidateRanker: NewCandidateRanker(nil, nil, logger),
+		latency:         NewLatencyTracker(nil),
+		metrics:         createMetrics(),
+		logger:          logger,
+	}
+}
+
+// SetCandidateRanker 设置多候选排序器，用于接入代价估算与历史查询相似度
+func (ai *AIService) SetCandidateRanker(ranker *CandidateRanker) {
+	ai.candidateRanker = ranker
+}
+
+// SetLatencyTracker 设置模型延迟统计，用于调整滚动窗口与样本数
+func (ai *AIService) SetLatencyTracker(tracker *LatencyTracker) {
+	ai.latency = tracker
+}
+
+// SetSQLTemplates 启用常见意图的SQL模板快速路径
+func (ai *AIService) SetSQLTemplates(templates *SQLTemplateEngine) {
+	ai.templates = templates
+}
+
+// SetDBTypeResolver 设置连接数据库类型的解析器，用于按连接类型生成对应方言的SQL
+func (ai *AIService) SetDBTypeResolver(resolver DBTypeResolver) {
+	ai.dbTypes = resolver
+}
+
+// SetPromptBuilder 设置提示词表结构构建器，请求未携带schema时按问题挑选连接中相关的表
+func (ai *AIService) SetPromptBuilder(builder *ai.PromptBuilder) {
+	ai.prompts = builder
+}
+
+// SetCostTracker 设置成本追踪器，记录每次模型调用的估算用量，Budget.Enforce开启时超出每日预算拒绝调用模型
+func (ai *AIService) SetCostTracker(tracker *ai.CostTracker) {
+	ai.costs = tracker
+}
+
+// SetProviderChain 设置模型降级链，按配置的顺序尝试各模型并对单个模型重试与熔断
+func (ai *AIService) SetProviderChain(chain *ProviderChain) {
+	ai.chain = chain
+}
+
+// SetSQLFormatting 设置是否在返回前格式化生成的SQL，包括模板生成的SQL与多候选的备选SQL
+func (ai *AIService) SetSQLFormatting(enabled bool) {
+	ai.formatSQLEnabled = enabled
+}
+
+// SetLocalModel 设置本地模型客户端，降级链中的ModelLocal使用该客户端
+func (ai *AIService) SetLocalModel(client llms.Model, modelConfig config.ModelConfig) {
+	ai.localClient = client
+	ai.localConfig = modelConfig
+}
+
+// EnableLocalModel 按配置创建本地Ollama模型客户端
+func (ai *AIService) EnableLocalModel(modelConfig config.ModelConfig) error {
+	client, err := createLLMClient(modelConfig, ai.httpClient)
+	if err != nil {
+		return fmt.Errorf("创建本地模型客户端失败: %w", err)
+	}
+	ai.SetLocalModel(client, modelConfig)
+	ai.logger.Info("本地模型已加入降级链",
+		zap.String("provider", modelConfig.Provider),
+		zap.String("model", modelConfig.ModelName),
+	)
+	return nil
+}
+
+// ProviderHealth 返回降级链中各模型的健康与熔断状态，未启用降级链时返回nil
+func (ai *AIService) ProviderHealth() []ProviderHealth {
+	if ai.chain == nil {
+		return nil
+	}
+	return ai.chain.Health()
+}
+
+// LatencyStats 返回各模型在滚动窗口内的延迟统计
+func (ai *AIService) LatencyStats() []ModelLatency {
+	models := []string{ModelPrimary, ModelFallback}
+	if ai.localClient != nil {
+		models = append(models, ModelLocal)
+	}
+	stats := make([]ModelLatency, 0, len(models))
+	for _, model := range models {
+		cfg := ai.modelConfig(model)
+		p95, samples, ok := ai.latency.P95(model)
+		stat := ModelLatency{Provider: cfg.Provider, Model: cfg.ModelName, Samples: samples}
+		if ok {
+			ms := p95.Milliseconds()
+			stat.P95Ms = &ms
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
 // createLLMClient 根据配置创建LLM客户端
 func createLLMClient(modelConfig config.ModelConfig, httpClient *http.Client) (llms.Model, error) {
 	switch modelConfig.Provider {
@@ -115,6 +382,8 @@ func createLLMClient(modelConfig config.ModelConfig, httpClient *http.Client) (l
 			anthropic.WithToken(modelConfig.APIKey),
 			anthropic.WithModel(modelConfig.ModelName),
 		)
+	case "gemini":
+		return ai.NewGeminiLLM(modelConfig.APIKey, modelConfig.ModelName, ai.WithGeminiHTTPClient(httpClient))
 	case "ollama":
 		// Ollama不需要API密钥，使用默认或配置的服务器URL
 		serverURL := "http://localhost:11434"
@@ -125,6 +394,9 @@ func createLLMClient(modelConfig config.ModelConfig, httpClient *http.Client) (l
 			ollama.WithModel(modelConfig.ModelName),
 			ollama.WithServerURL(serverURL),
 		)
+	case "mock":
+		// 确定性Mock提供商，不访问网络
+		return ai.NewMockLLMFromFile(modelConfig.RulesFile)
 	default:
 		return nil, fmt.Errorf("不支持的模型提供商: %s", modelConfig.Provider)
 	}
@@ -178,11 +450,31 @@ func createMetrics() *AIMetrics {
 			},
 			[]string{"provider", "model", "error_type"},
 		),
+		ChargebackTokens: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ai_chargeback_tokens_total",
+				Help: "Total tokens used by AI services per chargeback tag",
+			},
+			[]string{"cost_center", "project", "model", "type"}, // type: input/output
+		),
 	}
 }
 
 // GenerateSQL 生成SQL语句
 func (ai *AIService) GenerateSQL(ctx context.Context, req *SQLGenerationRequest) (*SQLGenerationResponse, error) {
+	ctx, span := tracing.Start(ctx, "ai.generate_sql", attribute.Int64("chat2sql.connection_id", req.ConnectionID))
+	result, err := ai.generateSQL(ctx, req)
+	if result != nil {
+		span.SetAttributes(
+			attribute.Float64("chat2sql.confidence", result.Confidence),
+			attribute.String("chat2sql.template", result.Template))
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+// generateSQL 生成SQL语句，span由GenerateSQL创建
+func (ai *AIService) generateSQL(ctx context.Context, req *SQLGenerationRequest) (*SQLGenerationResponse, error) {
 	start := time.Now()
 	
 	// 记录请求指标
@@ -200,6 +492,24 @@ func (ai *AIService) GenerateSQL(ctx context.Context, req *SQLGenerationRequest)
 		zap.Int64("user_id", req.UserID),
 	)
 	
+	ai.resolveDialect(ctx, req)
+	
+	// 常见意图由模板直接生成SQL，不调用模型
+	if result := ai.generateFromTemplate(ctx, req, start); result != nil {
+		return result, nil
+	}
+	
+	// 模板路径不调用模型，不受预算限制
+	if ai.costs != nil && ai.config.Budget.Enforce {
+		if err := ai.costs.CheckBudget(req.UserID); err != nil {
+			ai.recordError("budget_exceeded", err)
+			return nil, err
+		}
+	}
+	
+	ai.resolveSchema(ctx, req)
+	ctx = withGenerationProfile(ctx, ai.resolveGenerationProfile(ctx, req))
+	
 	// 构建提示词
 	prompt, err := ai.buildPrompt(req)
 	if err != nil {
@@ -207,15 +517,57 @@ func (ai *AIService) GenerateSQL(ctx context.Context, req *SQLGenerationRequest)
 		return nil, fmt.Errorf("构建提示词失败: %w", err)
 	}
 	
-	// 调用LLM生成内容，带备用机制
-	response, err := ai.callWithFallback(ctx, prompt)
-	if err != nil {
-		ai.recordError("llm_error", err)
-		return nil, fmt.Errorf("LLM调用失败: %w", err)
+	// 调用LLM生成内容：多候选模式下取排序第一的候选，否则带备用机制单次生成
+	var response *llms.ContentResponse
+	var candidates []*SQLCandidate
+	var generation *GenerationParameters
+	if n := ai.candidateCount(req); n > 1 {
+		candidates, err = ai.generateCandidates(ctx, prompt, req, n)
+		if err != nil {
+			ai.recordError("llm_error", err)
+			return nil, fmt.Errorf("LLM调用失败: %w", err)
+		}
+		for _, candidate := range candidates {
+			ai.recordCost(req, prompt, candidate.generation, candidate.choice)
+		}
+		top := *candidates[0].choice
+		top.Content = candidates[0].SQL
+		response = &llms.ContentResponse{Choices: []*llms.ContentChoice{&top}}
+		generation = candidates[0].generation
+	} else {
+		if req.Model != "" {
+			response, generation, err = ai.callModel(ctx, prompt, req.Model)
+		} else {
+			response, generation, err = ai.callWithFallback(ctx, prompt, ai.modelOrder(req))
+		}
+		if err != nil {
+			ai.recordError("llm_error", err)
+			return nil, fmt.Errorf("LLM调用失败: %w", err)
+		}
+		if len(response.Choices) > 0 {
+			ai.recordCost(req, prompt, generation, response.Choices[0])
+		}
+		response = ai.continueTruncated(ctx, req, prompt, response, generation)
 	}
 	
 	// 解析响应
-	sql, confidence := ai.parseResponse(response, req.Query, req.Schema)
+	sql, breakdown := ai.parseResponse(ctx, response, req)
+	
+	// 模型输出不可信，只读请求拒绝写操作、多条语句与有副作用的调用；写模式的SQL另由写模式校验与审批
+	if !req.AllowWrite {
+		_, span := tracing.Start(ctx, "sql.validate")
+		err := sqlvalidator.ValidateReadOnly(sql)
+		tracing.End(span, err)
+		if err != nil {
+			ai.recordError("unsafe_sql", err)
+			ai.logger.Warn("生成的SQL未通过安全检查",
+				zap.String("generated_sql", sql),
+				zap.Error(err),
+			)
+			return nil, fmt.Errorf("%w: %v", ErrUnsafeGeneratedSQL, err)
+		}
+	}
+	confidence := breakdown.Combine()
 	duration := time.Since(start)
 	
 	// 记录成功指标
@@ -226,7 +578,8 @@ func (ai *AIService) GenerateSQL(ctx context.Context, req *SQLGenerationRequest)
 	).Inc()
 	
 	// 记录Token使用量 - 由于 LangChainGo 的 ContentResponse 可能不包含 Usage 字段，我们使用估算方法
-	if response != nil && len(response.Choices) > 0 {
+	// 命中模型响应缓存时未调用提供商，不计入用量
+	if response != nil && len(response.Choices) > 0 && (generation == nil || !generation.Cached) {
 		// 基于文本长度估算 token 使用量
 		estimatedInputTokens := len(prompt) / 4  // 粗略估算：4字符=1token
 		estimatedOutputTokens := len(sql) / 4
@@ -240,6 +593,12 @@ func (ai *AIService) GenerateSQL(ctx context.Context, req *SQLGenerationRequest)
 			ai.config.Primary.ModelName,
 			"output",
 		).Add(float64(estimatedOutputTokens))
+		
+		// 请求携带费用归属标签时同时按标签累计，用于按团队分摊AI费用
+		if tags := chargebackTagsFromContext(ctx); tags != nil {
+			ai.metrics.ChargebackTokens.WithLabelValues(tags.CostCenter, tags.Project, ai.config.Primary.ModelName, "input").Add(float64(estimatedInputTokens))
+			ai.metrics.ChargebackTokens.WithLabelValues(tags.CostCenter, tags.Project, ai.config.Primary.ModelName, "output").Add(float64(estimatedOutputTokens))
+		}
 	}
 	
 	ai.logger.Info("SQL生成成功",
@@ -248,53 +607,333 @@ func (ai *AIService) GenerateSQL(ctx context.Context, req *SQLGenerationRequest)
 		zap.Duration("duration", duration),
 	)
 	
-	return &SQLGenerationResponse{
-		SQL:            sql,
-		Confidence:     confidence,
-		ProcessingTime: duration,
-	}, nil
+	result := &SQLGenerationResponse{
+		SQL:                  ai.formatSQL(sql),
+		Confidence:           confidence,
+		ProcessingTime:       duration,
+		ConfidenceBreakdown:  breakdown,
+		RequiresConfirmation: confidence < ai.confirmationThreshold(),
+		Generation:           generation,
+		Prompt:               prompt,
+	}
+	if len(candidates) > 0 {
+		for _, candidate := range candidates {
+			candidate.SQL = ai.formatSQL(candidate.SQL)
+		}
+		result.SelectedCandidate = candidates[0]
+		result.Alternates = candidates[1:]
+		result.Completion = candidates[0].choice.Content
+	} else if response != nil && len(response.Choices) > 0 {
+		result.Completion = response.Choices[0].Content
+	}
+	
+	// 问题中引号内的字面值提取为绑定参数，执行时不再拼接进SQL
+	if parameterized, params, ok := ParameterizeLiterals(result.SQL, req.Query, req.Dialect); ok {
+		result.ParameterizedSQL, result.Parameters = parameterized, params
+	}
+	result.SchemaCorrections = localizeSchemaCorrections(req.SchemaCorrections, req.Locale)
+	return result, nil
 }
 
-// callWithFallback 调用LLM，带备用机制
-func (ai *AIService) callWithFallback(ctx context.Context, prompt string) (*llms.ContentResponse, error) {
-	// 首先尝试主要模型
-	response, err := ai.primaryClient.GenerateContent(ctx,
-		[]llms.MessageContent{
-			llms.TextParts(llms.ChatMessageTypeHuman, prompt),
-		},
-		llms.WithTemperature(ai.config.Primary.Temperature),
-		llms.WithMaxTokens(ai.config.Primary.MaxTokens),
+// generateFromTemplate 命中SQL模板时直接返回模板生成的SQL，未启用或未命中时返回nil
+// 多候选与指定模型的回放请求需要模型输出，追问需要结合之前的SQL，都不走模板
+func (ai *AIService) generateFromTemplate(ctx context.Context, req *SQLGenerationRequest, start time.Time) *SQLGenerationResponse {
+	// 模板按PostgreSQL语法引用标识符，MySQL连接交给模型生成
+	if ai.templates == nil || req.Candidates > 1 || req.Model != "" || len(req.History) > 0 || req.Dialect == repository.DBTypeMySQL {
+		return nil
+	}
+	
+	intent := ai.analyzeIntent(ctx, req)
+	
+	match, ok := ai.templates.Match(ctx, req, intent)
+	if !ok {
+		return nil
+	}
+	
+	classifier := intent.Confidence
+	breakdown := &ConfidenceBreakdown{Heuristic: 1, Classifier: &classifier}
+	confidence := breakdown.Combine()
+	ai.metrics.RequestsTotal.WithLabelValues("template", match.Template, "success").Inc()
+	
+	ai.logger.Info("SQL模板命中",
+		zap.String("template", match.Template),
+		zap.String("table", match.Table),
+		zap.String("generated_sql", match.SQL),
+		zap.Duration("duration", time.Since(start)),
 	)
 	
-	if err == nil {
-		ai.logger.Debug("主要模型调用成功", zap.String("provider", ai.config.Primary.Provider))
-		return response, nil
+	return &SQLGenerationResponse{
+		SQL:                  ai.formatSQL(match.SQL),
+		Confidence:           confidence,
+		ProcessingTime:       time.Since(start),
+		ConfidenceBreakdown:  breakdown,
+		RequiresConfirmation: confidence < ai.confirmationThreshold(),
+		Template:             match.Template,
+	}
+}
+
+// formatSQL 启用时按统一的缩进与关键字大小写排版返回的SQL，无法安全格式化时保持原样
+func (ai *AIService) formatSQL(sql string) string {
+	if !ai.formatSQLEnabled {
+		return sql
+	}
+	return sqlformat.FormatOrOriginal(sql)
+}
+
+// modelOrder 决定模型的尝试顺序，默认按降级链配置的顺序，未启用降级链时先主模型后备用模型
+// 交互请求设置了延迟目标时，主模型滚动P95超过目标且备用模型P95更低则交换主备模型的位置；
+// 任一模型样本不足时不改道，主模型样本随窗口过期后交互请求自动回到主模型
+func (ai *AIService) modelOrder(req *SQLGenerationRequest) []string {
+	order := []string{ModelPrimary, ModelFallback}
+	if ai.chain != nil {
+		order = ai.chain.Order()
+	}
+	if req.LatencySLO <= 0 || req.Workload == WorkloadBatch {
+		return order
 	}
 	
-	// 记录主要模型失败
-	ai.logger.Warn("主要模型调用失败，尝试备用模型",
-		zap.Error(err),
-		zap.String("primary_provider", ai.config.Primary.Provider),
+	primaryP95, _, ok := ai.latency.P95(ModelPrimary)
+	if !ok || primaryP95 <= req.LatencySLO {
+		return order
+	}
+	fallbackP95, _, ok := ai.latency.P95(ModelFallback)
+	if !ok || fallbackP95 >= primaryP95 {
+		return order
+	}
+	
+	ai.logger.Info("主模型延迟超过目标，交互请求优先使用备用模型",
+		zap.Duration("primary_p95", primaryP95),
+		zap.Duration("fallback_p95", fallbackP95),
+		zap.Duration("slo", req.LatencySLO),
 	)
+	for i, model := range order {
+		switch model {
+		case ModelPrimary:
+			order[i] = ModelFallback
+		case ModelFallback:
+			order[i] = ModelPrimary
+		}
+	}
+	return order
+}
+
+// callWithFallback 按顺序调用模型，前一个失败时尝试下一个，同时返回实际响应的模型使用的生成参数
+// 启用降级链时跳过熔断中的模型，单个模型失败后先按退避重试；调用方取消或超时后不再尝试其他模型
+func (ai *AIService) callWithFallback(ctx context.Context, prompt string, order []string) (*llms.ContentResponse, *GenerationParameters, error) {
+	var err error
+	for i, model := range order {
+		if ai.chain != nil && !ai.chain.Allow(model) {
+			ai.logger.Warn("模型处于熔断状态，跳过", zap.String("model", model))
+			continue
+		}
+		
+		var response *llms.ContentResponse
+		var generation *GenerationParameters
+		response, generation, err = ai.callWithRetry(ctx, prompt, model)
+		if err == nil {
+			ai.logger.Debug("模型调用成功", zap.String("model", model), zap.String("provider", generation.Provider))
+			return response, generation, nil
+		}
+		
+		ai.recordError(model+"_failure", err)
+		if ctx.Err() != nil {
+			return nil, nil, err
+		}
+		if i < len(order)-1 {
+			ai.logger.Warn("模型调用失败，尝试下一个模型",
+				zap.Error(err),
+				zap.String("failed_model", model),
+				zap.String("next_model", order[i+1]),
+			)
+		}
+	}
+	if err == nil {
+		return nil, nil, ErrAllModelsUnavailable
+	}
+	return nil, nil, fmt.Errorf("所有模型都失败: %w", err)
+}
+
+// callWithRetry 调用指定模型，启用降级链时按结果更新熔断器，瞬时错误按退避重试
+// 流式输出时不重试，以免同一模型重复输出；重试期间模型熔断则不再重试
+func (ai *AIService) callWithRetry(ctx context.Context, prompt, model string) (*llms.ContentResponse, *GenerationParameters, error) {
+	if ai.chain == nil {
+		return ai.callModel(ctx, prompt, model)
+	}
 	
-	ai.recordError("primary_failure", err)
+	attempts := ai.chain.config.MaxAttempts
+	if tokenStreamFromContext(ctx) != nil {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		response, generation, err := ai.callModel(ctx, prompt, model)
+		if err == nil {
+			ai.chain.RecordSuccess(model)
+			return response, generation, nil
+		}
+		if ctx.Err() == nil {
+			ai.chain.RecordFailure(model, err)
+		}
+		if attempt >= attempts || !retryableModelError(ctx, err) || ai.chain.IsOpen(model) {
+			return nil, nil, err
+		}
+		
+		backoff := ai.chain.backoff(attempt)
+		ai.logger.Warn("模型调用失败，退避后重试",
+			zap.String("model", model),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		if sleepErr := sleepContext(ctx, backoff); sleepErr != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// callModel 只调用指定模型，失败时不降级
+// 成功或超时的调用计入该模型的延迟统计，快速失败（如认证错误）不计入，以免拉低P95
+func (ai *AIService) callModel(ctx context.Context, prompt, model string) (*llms.ContentResponse, *GenerationParameters, error) {
+	var client llms.Model
+	switch model {
+	case ModelPrimary:
+		client = ai.primaryClient
+	case ModelFallback:
+		client = ai.fallbackClient
+	case ModelLocal:
+		client = ai.localClient
+	default:
+		return nil, nil, fmt.Errorf("未知模型: %s", model)
+	}
+	if client == nil {
+		return nil, nil, fmt.Errorf("模型未配置: %s", model)
+	}
+	profile := generationProfileFromContext(ctx)
+	cfg := profile.apply(ai.modelConfig(model))
 	
-	// 尝试备用模型
-	response, err = ai.fallbackClient.GenerateContent(ctx,
+	// 温度为0时相同提示词的输出确定，命中缓存时不调用提供商，流式请求一次性回调完整输出
+	cacheKey, cached := ai.lookupPromptCache(ctx, cfg, profile, prompt)
+	if cached != nil {
+		if stream := tokenStreamFromContext(ctx); stream != nil {
+			if err := stream(ctx, model, cached.Content); err != nil {
+				return nil, nil, err
+			}
+		}
+		params := profile.record(newGenerationParameters(cfg, cfg.Temperature))
+		params.slot = model
+		params.Cached = true
+		return &llms.ContentResponse{Choices: []*llms.ContentChoice{cached}}, params, nil
+	}
+	
+	opts := profile.options(generationOptions(cfg, cfg.Temperature))
+	if stream := tokenStreamFromContext(ctx); stream != nil {
+		opts = append(opts, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			return stream(ctx, model, string(chunk))
+		}))
+	}
+	
+	start := time.Now()
+	callCtx, span := startLLMSpan(ctx, cfg, attribute.String("chat2sql.model_slot", model))
+	response, err := client.GenerateContent(callCtx,
 		[]llms.MessageContent{
 			llms.TextParts(llms.ChatMessageTypeHuman, prompt),
 		},
-		llms.WithTemperature(ai.config.Fallback.Temperature),
-		llms.WithMaxTokens(ai.config.Fallback.MaxTokens),
+		opts...,
 	)
-	
+	tracing.End(span, err)
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		ai.latency.Observe(model, time.Since(start))
+	}
 	if err != nil {
-		ai.recordError("fallback_failure", err)
-		return nil, fmt.Errorf("主要和备用模型都失败: %w", err)
+		return nil, nil, err
 	}
-	
-	ai.logger.Info("备用模型调用成功", zap.String("provider", ai.config.Fallback.Provider))
-	return response, nil
+	ai.storePromptCache(ctx, cacheKey, response)
+	params := profile.record(newGenerationParameters(cfg, cfg.Temperature))
+	params.slot = model
+	return response, params, nil
+}
+
+// TokenStreamFunc 接收模型逐段输出的回调，model为产生该段输出的模型（ModelPrimary/ModelFallback/ModelLocal）
+// 返回错误时中止本次模型调用；主模型中途失败降级时，后续输出来自备用模型，调用方应丢弃已收到的内容
+type TokenStreamFunc func(ctx context.Context, model, chunk string) error
+
+// tokenStreamKey 流式输出回调的context键
+type tokenStreamKey struct{}
+
+// WithTokenStream 设置本次请求的流式输出回调，模型生成时逐段回调；多候选生成与SQL模板不产生流式输出
+func WithTokenStream(ctx context.Context, stream TokenStreamFunc) context.Context {
+	return context.WithValue(ctx, tokenStreamKey{}, stream)
+}
+
+// tokenStreamFromContext 读取本次请求的流式输出回调，未设置时返回nil
+func tokenStreamFromContext(ctx context.Context) TokenStreamFunc {
+	stream, _ := ctx.Value(tokenStreamKey{}).(TokenStreamFunc)
+	return stream
+}
+
+// modelConfig 返回指定模型（ModelPrimary/ModelFallback/ModelLocal）的配置
+func (ai *AIService) modelConfig(model string) config.ModelConfig {
+	switch model {
+	case ModelFallback:
+		return ai.config.Fallback
+	case ModelLocal:
+		return ai.localConfig
+	}
+	return ai.config.Primary
+}
+
+// ModelName 返回指定模型（ModelPrimary/ModelFallback/ModelLocal）配置的模型名称，未知模型返回空字符串
+func (ai *AIService) ModelName(model string) string {
+	switch model {
+	case ModelPrimary:
+		return ai.config.Primary.ModelName
+	case ModelFallback:
+		return ai.config.Fallback.ModelName
+	case ModelLocal:
+		return ai.localConfig.ModelName
+	}
+	return ""
+}
+
+// resolveDialect 请求未指定方言时按连接的数据库类型补全
+func (ai *AIService) resolveDialect(ctx context.Context, req *SQLGenerationRequest) {
+	if req.Dialect != "" || ai.dbTypes == nil || req.ConnectionID == 0 {
+		return
+	}
+	dbType, err := ai.dbTypes.GetDBType(ctx, req.ConnectionID)
+	if err != nil {
+		ai.logger.Warn("获取连接数据库类型失败，按PostgreSQL生成SQL",
+			zap.Int64("connection_id", req.ConnectionID),
+			zap.Error(err))
+		return
+	}
+	req.Dialect = dbType
+}
+
+// resolveSchema 请求未携带表结构时按问题从连接已保存的元数据生成，失败时保持为空
+func (ai *AIService) resolveSchema(ctx context.Context, req *SQLGenerationRequest) {
+	if req.Schema != "" || ai.prompts == nil || req.ConnectionID == 0 {
+		return
+	}
+	schema, err := ai.prompts.Build(ctx, req.ConnectionID, req.UserID, req.Query)
+	if err != nil {
+		ai.logger.Warn("构建提示词表结构失败",
+			zap.Int64("connection_id", req.ConnectionID),
+			zap.Error(err))
+		return
+	}
+	req.Schema = schema.Text
+	req.SchemaCorrections = schema.Corrections
+}
+
+// 提示词中的语句类型规则，写模式下替换为允许INSERT/UPDATE的版本
+const (
+	readOnlyPromptRule = "1. 只生成SELECT查询，禁止DELETE/UPDATE/INSERT/DROP操作"
+	writePromptRule    = "1. 只生成单条INSERT或UPDATE语句，UPDATE必须带WHERE条件，禁止DELETE/DROP/TRUNCATE操作"
+)
+
+// BuildPrompt 构建SQL生成提示词，供按历史表结构快照重现提示词使用
+func (ai *AIService) BuildPrompt(req *SQLGenerationRequest) (string, error) {
+	return ai.buildPrompt(req)
 }
 
 // buildPrompt 构建SQL生成提示词
@@ -317,6 +956,22 @@ func (ai *AIService) buildPrompt(req *SQLGenerationRequest) (string, error) {
 
 ## 生成SQL：`
 
+	if req.AllowWrite {
+		basePrompt = strings.Replace(basePrompt, readOnlyPromptRule, writePromptRule, 1)
+	}
+	switch req.Dialect {
+	case repository.DBTypeMySQL:
+		basePrompt = strings.NewReplacer(
+			"生成准确的PostgreSQL查询语句", "生成准确的MySQL查询语句",
+			"2. 使用PostgreSQL 17语法", "2. 使用MySQL 8语法，标识符使用反引号引用",
+		).Replace(basePrompt)
+	case repository.DBTypeSQLite:
+		basePrompt = strings.NewReplacer(
+			"生成准确的PostgreSQL查询语句", "生成准确的SQLite查询语句",
+			"2. 使用PostgreSQL 17语法", "2. 使用SQLite 3语法，日期计算使用date()与strftime()函数",
+		).Replace(basePrompt)
+	}
+
 	// 增强提示词模板，添加上下文信息
 	enhancedPrompt := fmt.Sprintf(`%s
 
@@ -332,24 +987,116 @@ func (ai *AIService) buildPrompt(req *SQLGenerationRequest) (string, error) {
 - 时间范围查询建议使用索引优化的日期字段
 - 避免使用SELECT *，明确指定需要的字段
 - 对于大表查询，建议添加LIMIT子句
-
-## 生成SQL：`, basePrompt, req.Schema, req.Query)
+%s%s%s%s%s
+## 生成SQL：`, basePrompt, req.Schema, req.Query, schemaCorrectionsSection(req.SchemaCorrections), questionValuesSection(req.Query, req.Locale), restrictedColumnsSection(req.RestrictedColumns), localeSection(req.Locale), conversationSection(req.History))
 
 	prompt := enhancedPrompt
 	
 	return prompt, nil
 }
 
-// parseResponse 解析LLM响应
-func (ai *AIService) parseResponse(response *llms.ContentResponse, userQuery, schema string) (string, float64) {
+// restrictedColumnsSection 构建受限列提示，无受限列时为空
+func restrictedColumnsSection(columns []string) string {
+	if len(columns) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n## 受限列：\n以下列属于敏感数据（PII/财务/内部），当前用户未获授权查看，不要在查询结果中选择这些列；")
+	b.WriteString("如需统计可使用COUNT等不暴露原始值的聚合：\n")
+	for _, column := range columns {
+		b.WriteString("- ")
+		b.WriteString(column)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// localeSection 构建语言提示，中文或未指定语言时为空
+func localeSection(locale string) string {
+	if locale != "en" {
+		return ""
+	}
+	return "\n## 语言：\n用户使用英文，计算列与聚合列的别名使用英文snake_case命名，SQL中的注释也使用英文\n"
+}
+
+// conversationSection 构建对话上下文提示，不是追问时为空
+func conversationSection(history []ConversationTurn) string {
+	if len(history) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n## 对话上下文：\n用户查询是同一会话中的追问，以下是之前的问题、生成的SQL与执行结果（按时间顺序）。")
+	b.WriteString("如果用户查询是在修改上一轮结果（如“只看上个月”“按地区拆分”“同样的查询但按月份分组”），在上一轮SQL的基础上修改；如果是新问题，忽略对话上下文：\n")
+	for i, turn := range history {
+		fmt.Fprintf(&b, "%d. 问题：%s\n   SQL：%s\n", i+1, turn.Question, strings.Join(strings.Fields(turn.SQL), " "))
+		if turn.Result != nil {
+			truncated := ""
+			if turn.Result.Truncated {
+				truncated = "（已截断）"
+			}
+			fmt.Fprintf(&b, "   结果：%d行%s，列：%s\n", turn.Result.RowCount, truncated, strings.Join(turn.Result.Columns, ", "))
+		}
+	}
+	return b.String()
+}
+
+// parseResponse 解析LLM响应，返回SQL与置信度构成
+func (ai *AIService) parseResponse(ctx context.Context, response *llms.ContentResponse, req *SQLGenerationRequest) (string, *ConfidenceBreakdown) {
 	if len(response.Choices) == 0 {
-		return "", 0.0
+		return "", &ConfidenceBreakdown{}
 	}
 	
-	sql := response.Choices[0].Content
-	confidence := ai.calculateConfidence(sql, userQuery, schema)
+	choice := response.Choices[0]
+	sql := choice.Content
 	
-	return sql, confidence
+	intent := ai.analyzeIntent(ctx, req)
+	classifier := intent.Confidence
+	
+	return sql, &ConfidenceBreakdown{
+		Heuristic:  ai.calculateConfidence(sql, req.Query, req.Schema),
+		Classifier: &classifier,
+		LogProb:    logProbConfidence(choice),
+	}
+}
+
+// analyzeIntent 分析问题的意图，意图分析器不是并发安全的，调用时加锁
+// 优化建议按req.Locale返回；分析器会缓存结果，因此在副本上替换建议
+func (ai *AIService) analyzeIntent(ctx context.Context, req *SQLGenerationRequest) *ai.IntentResult {
+	_, span := tracing.Start(ctx, "ai.intent_analysis")
+	defer span.End()
+	
+	ai.intentMu.Lock()
+	cached := ai.intentAnalyzer.AnalyzeIntentDetailed(req.Query, req.UserID)
+	ai.intentMu.Unlock()
+	
+	intent := *cached
+	intent.Suggestions = make([]string, len(cached.Suggestions))
+	for i, suggestion := range cached.Suggestions {
+		intent.Suggestions[i] = Localize(req.Locale, suggestion)
+	}
+	
+	span.SetAttributes(
+		attribute.String("chat2sql.intent", ai.intentAnalyzer.GetIntentName(intent.PrimaryIntent)),
+		attribute.Float64("chat2sql.intent_confidence", intent.Confidence))
+	return &intent
+}
+
+// startLLMSpan 创建一次模型调用的span，命中模型响应缓存时不调用提供商，也不创建span
+func startLLMSpan(ctx context.Context, cfg config.ModelConfig, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "llm.call", append([]attribute.KeyValue{
+		attribute.String("gen_ai.provider.name", cfg.Provider),
+		attribute.String("gen_ai.request.model", cfg.ModelName),
+	}, attrs...)...)
+}
+
+// confirmationThreshold 需要用户确认的置信度阈值，未配置时使用默认值
+func (ai *AIService) confirmationThreshold() float64 {
+	if ai.config.ConfirmationThreshold > 0 {
+		return ai.config.ConfirmationThreshold
+	}
+	return DefaultConfirmationThreshold
 }
 
 // recordError 记录错误指标
@@ -366,6 +1113,24 @@ func (ai *AIService) recordError(errorType string, err error) {
 	)
 }
 
+// recordCost 按实际使用的提供商与模型记录一次模型调用的用量，命中模型响应缓存的调用不计入
+// 优先使用提供商返回的token数，未返回时按4字符=1token估算；写入存储失败时成本追踪器已记录日志，不影响本次生成
+func (ai *AIService) recordCost(req *SQLGenerationRequest, prompt string, generation *GenerationParameters, choice *llms.ContentChoice) {
+	if ai.costs == nil || generation == nil || generation.Cached {
+		return
+	}
+	inputTokens, outputTokens := usageTokens(prompt, choice)
+	_ = ai.costs.RecordUsage(req.UserID, generation.Provider, generation.Model, req.Query, inputTokens, outputTokens)
+}
+
+// usageTokens 返回一次调用的输入与输出token数，提供商未返回用量时按4字符=1token估算
+func usageTokens(prompt string, choice *llms.ContentChoice) (int, int) {
+	if inputTokens, outputTokens, ok := ai.ReportedTokens(choice.GenerationInfo); ok {
+		return inputTokens, outputTokens
+	}
+	return len(prompt) / 4, len(choice.Content) / 4
+}
+
 // Close 关闭AI服务
 func (ai *AIService) Close() error {
 	ai.logger.Info("AI服务关闭")
@@ -1,15 +1,27 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap/zaptest"
 	
+	"chat2sql-go/internal/ai"
 	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
 )
 
 func TestAIService_DefaultConfig(t *testing.T) {
@@ -212,4 +224,249 @@ func createValidTestConfig() *config.AIConfig {
 			AlertThreshold: 0.8,
 		},
 	}
-}
\ No newline at end of file
+}
+// seededLLM 按采样种子与温度生成输出的模型：温度为0且种子固定时输出不变，否则每次调用不同
+type seededLLM struct {
+	calls   int
+	options llms.CallOptions
+}
+
+func (m *seededLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls++
+	m.options = llms.CallOptions{}
+	for _, opt := range options {
+		opt(&m.options)
+	}
+	limit := m.options.Seed
+	if m.options.Temperature != 0 || m.options.Seed == 0 {
+		limit = m.calls
+	}
+	content := fmt.Sprintf("SELECT id FROM users LIMIT %d", limit)
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: content}}}, nil
+}
+
+func (m *seededLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestAIService_GenerateSQL_Deterministic(t *testing.T) {
+	aiConfig := config.DemoAIConfig("")
+	aiConfig.Primary.Provider = "ollama"
+	aiConfig.ApplyDeterministicProfile(42)
+	primary := &seededLLM{}
+	aiService := NewAIServiceWithClients(aiConfig, primary, &seededLLM{}, zaptest.NewLogger(t))
+
+	req := &SQLGenerationRequest{Query: "列出用户", Schema: "users(id, name)"}
+	first, err := aiService.GenerateSQL(context.Background(), req)
+	require.NoError(t, err)
+	second, err := aiService.GenerateSQL(context.Background(), req)
+	require.NoError(t, err)
+
+	// 种子与温度传递给模型，多次运行结果一致
+	assert.Equal(t, 42, primary.options.Seed)
+	assert.Equal(t, 0.0, primary.options.Temperature)
+	assert.Equal(t, first.SQL, second.SQL)
+
+	require.NotNil(t, first.Generation)
+	assert.Equal(t, "ollama", first.Generation.Provider)
+	assert.Equal(t, aiConfig.Primary.ModelName, first.Generation.Model)
+	assert.Equal(t, 0.0, first.Generation.Temperature)
+	require.NotNil(t, first.Generation.Seed)
+	assert.Equal(t, 42, *first.Generation.Seed)
+	assert.True(t, first.Generation.Deterministic)
+}
+
+func TestAIService_GenerateSQL_NonDeterministic(t *testing.T) {
+	aiConfig := config.DemoAIConfig("")
+	aiConfig.Primary.Temperature = 0.3
+	primary := &seededLLM{}
+	aiService := NewAIServiceWithClients(aiConfig, primary, &seededLLM{}, zaptest.NewLogger(t))
+
+	req := &SQLGenerationRequest{Query: "列出用户", Schema: "users(id, name)"}
+	first, err := aiService.GenerateSQL(context.Background(), req)
+	require.NoError(t, err)
+	second, err := aiService.GenerateSQL(context.Background(), req)
+	require.NoError(t, err)
+
+	// 未配置种子时不传递种子，生成参数中也不包含种子
+	assert.Zero(t, primary.options.Seed)
+	assert.NotEqual(t, first.SQL, second.SQL)
+	require.NotNil(t, first.Generation)
+	assert.InDelta(t, 0.3, first.Generation.Temperature, 1e-9)
+	assert.Nil(t, first.Generation.Seed)
+	assert.False(t, first.Generation.Deterministic)
+}
+
+func TestAIService_GenerateSQL_LatencyRouting(t *testing.T) {
+	primary, fallback := &seededLLM{}, &seededLLM{}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), primary, fallback, zaptest.NewLogger(t))
+	tracker := NewLatencyTracker(nil)
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+	aiService.SetLatencyTracker(tracker)
+
+	// 主模型P95约4秒，备用模型约1秒
+	for i := 0; i < 20; i++ {
+		tracker.Observe(ModelPrimary, 4*time.Second)
+		tracker.Observe(ModelFallback, time.Second)
+	}
+
+	generate := func(req *SQLGenerationRequest) {
+		req.Query, req.Schema = "列出用户", "users(id, name)"
+		_, err := aiService.GenerateSQL(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	generate(&SQLGenerationRequest{Workload: WorkloadInteractive, LatencySLO: 2 * time.Second})
+	assert.Equal(t, 0, primary.calls, "主模型超过延迟目标时交互请求改用备用模型")
+	assert.Equal(t, 1, fallback.calls)
+
+	generate(&SQLGenerationRequest{Workload: WorkloadBatch, LatencySLO: 2 * time.Second})
+	assert.Equal(t, 1, primary.calls, "批量请求始终使用主模型")
+
+	generate(&SQLGenerationRequest{Workload: WorkloadInteractive})
+	assert.Equal(t, 2, primary.calls, "未设置延迟目标时不改道")
+
+	generate(&SQLGenerationRequest{Workload: WorkloadInteractive, LatencySLO: 5 * time.Second})
+	assert.Equal(t, 3, primary.calls, "主模型未超过延迟目标")
+
+	// 窗口内样本过期后主模型延迟未知，交互请求恢复使用主模型
+	now = now.Add(6 * time.Minute)
+	generate(&SQLGenerationRequest{Workload: WorkloadInteractive, LatencySLO: 2 * time.Second})
+	assert.Equal(t, 4, primary.calls)
+	assert.Equal(t, 1, fallback.calls)
+}
+
+// streamingLLM 按块流式输出，err非空时输出完所有块后返回该错误
+type streamingLLM struct {
+	chunks []string
+	err    error
+}
+
+func (m *streamingLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	for _, chunk := range m.chunks {
+		if opts.StreamingFunc != nil {
+			if err := opts.StreamingFunc(ctx, []byte(chunk)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: strings.Join(m.chunks, "")}}}, nil
+}
+
+func (m *streamingLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestAIService_GenerateSQL_TokenStream(t *testing.T) {
+	primary := &streamingLLM{chunks: []string{"SELECT na"}, err: errors.New("connection reset")}
+	fallback := &streamingLLM{chunks: []string{"SELECT name", " FROM users"}}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), primary, fallback, zaptest.NewLogger(t))
+
+	type streamed struct{ model, chunk string }
+	var chunks []streamed
+	ctx := WithTokenStream(context.Background(), func(ctx context.Context, model, chunk string) error {
+		chunks = append(chunks, streamed{model, chunk})
+		return nil
+	})
+
+	resp, err := aiService.GenerateSQL(ctx, &SQLGenerationRequest{Query: "列出用户名", Schema: "users(id, name)"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT name FROM users", resp.SQL)
+	assert.Equal(t, []streamed{
+		{ModelPrimary, "SELECT na"},
+		{ModelFallback, "SELECT name"},
+		{ModelFallback, " FROM users"},
+	}, chunks, "降级后的输出标明来自备用模型")
+
+	// 回调返回错误时中止生成
+	stop := WithTokenStream(context.Background(), func(ctx context.Context, model, chunk string) error {
+		return context.Canceled
+	})
+	_, err = aiService.GenerateSQL(stop, &SQLGenerationRequest{Query: "列出用户名", Schema: "users(id, name)", Model: ModelFallback})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAIService_GenerateSQL_RejectsUnsafeSQL(t *testing.T) {
+	aiConfig := config.DemoAIConfig("")
+	for _, sql := range []string{
+		"DROP TABLE users",
+		"SELECT * FROM users; DELETE FROM users",
+		"WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone",
+	} {
+		mock, err := ai.NewMockLLM(&ai.MockLLMRules{DefaultSQL: sql})
+		require.NoError(t, err)
+		aiService := NewAIServiceWithClients(aiConfig, mock, mock, zaptest.NewLogger(t))
+
+		_, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "清空用户", UserID: 1})
+		assert.ErrorIs(t, err, ErrUnsafeGeneratedSQL, sql)
+	}
+
+	// 写模式生成的SQL由写模式自行校验
+	mock, err := ai.NewMockLLM(&ai.MockLLMRules{DefaultSQL: "UPDATE users SET status = 'inactive' WHERE id = 1"})
+	require.NoError(t, err)
+	aiService := NewAIServiceWithClients(aiConfig, mock, mock, zaptest.NewLogger(t))
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "停用用户1", UserID: 1, AllowWrite: true})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp.SQL, "UPDATE"))
+}
+
+func TestAIService_GenerateSQL_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	mock, err := ai.NewMockLLM(&ai.MockLLMRules{DefaultSQL: "DROP TABLE users"})
+	require.NoError(t, err)
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), mock, mock, zaptest.NewLogger(t))
+
+	_, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "清空用户", UserID: 1, ConnectionID: 3})
+	require.ErrorIs(t, err, ErrUnsafeGeneratedSQL)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	root := spans["ai.generate_sql"]
+	require.NotNil(t, root)
+	assert.Equal(t, codes.Error, root.Status().Code)
+	assert.Contains(t, root.Attributes(), attribute.Int64("chat2sql.connection_id", 3))
+	for _, name := range []string{"ai.intent_analysis", "llm.call", "sql.validate"} {
+		require.Contains(t, spans, name)
+		assert.Equal(t, root.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+	}
+	assert.Contains(t, spans["llm.call"].Attributes(), attribute.String("chat2sql.model_slot", ModelPrimary))
+	assert.Equal(t, codes.Error, spans["sql.validate"].Status().Code, "拒绝的SQL标记校验失败")
+}
+
+func TestAIService_ResolveSchemaFromMetadata(t *testing.T) {
+	schemas := &memSchemaRepository{metadata: map[int64][]*repository.SchemaMetadata{
+		1: schemaMetadataList(testDatabaseSchema(1, map[string][]ColumnInfo{
+			"orders":     {{ColumnName: "id", DataType: "bigint", IsPrimaryKey: true}, {ColumnName: "amount", DataType: "numeric"}},
+			"audit_logs": {{ColumnName: "id", DataType: "bigint", IsPrimaryKey: true}},
+		})),
+	}}
+	aiService := &AIService{logger: zaptest.NewLogger(t)}
+	aiService.SetPromptBuilder(ai.NewPromptBuilder(schemas, nil, zaptest.NewLogger(t)))
+
+	req := &SQLGenerationRequest{Query: "list all orders", ConnectionID: 1}
+	aiService.resolveSchema(context.Background(), req)
+	assert.Equal(t, "orders(id bigint PK, amount numeric NOT NULL)\n", req.Schema, "只写入与问题相关的表")
+
+	prompt, err := aiService.buildPrompt(req)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, req.Schema)
+
+	// 请求携带的表结构优先
+	req = &SQLGenerationRequest{Query: "list all orders", ConnectionID: 1, Schema: "orders(id)"}
+	aiService.resolveSchema(context.Background(), req)
+	assert.Equal(t, "orders(id)", req.Schema)
+}
@@ -0,0 +1,204 @@
+// API密钥
+// CI任务与BI工具使用X-API-Key请求头调用SQL与AI接口，不经过JWT登录流程。
+// 密钥格式为c2s_加32位十六进制随机数，只保存SHA-256哈希，明文仅在创建时返回一次；
+// 通过密钥认证的请求以所属用户的身份与当前角色执行，用户被停用或锁定后其密钥同时失效
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+const (
+	// APIKeyPrefix API密钥前缀，便于在日志与代码仓库中识别泄露的密钥
+	APIKeyPrefix = "c2s_"
+	// MaxAPIKeysPerUser 每个用户未撤销的密钥数上限
+	MaxAPIKeysPerUser = 20
+	// apiKeyDisplayLength 列表中展示的密钥前缀长度
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+	// apiKeyLastUsedInterval 最近使用时间的更新间隔，避免每个请求都写库
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// ErrInvalidAPIKey 密钥不存在、已撤销、已过期或所属用户不可用
+var ErrInvalidAPIKey = errors.New("API密钥无效、已撤销或已过期")
+
+// APIKeyService API密钥服务
+type APIKeyService struct {
+	keys   repository.APIKeyRepository
+	users  repository.UserRepository
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewAPIKeyService 创建API密钥服务
+func NewAPIKeyService(keys repository.APIKeyRepository, users repository.UserRepository, logger *zap.Logger) *APIKeyService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &APIKeyService{
+		keys:   keys,
+		users:  users,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Create 为用户创建密钥，返回密钥记录与明文密钥；明文不保存，之后无法再次获取
+func (s *APIKeyService) Create(ctx context.Context, userID int64, name string, scopes []repository.APIKeyScope, expiresAt *time.Time) (*repository.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return nil, "", fmt.Errorf("密钥名称不能为空且不超过100个字符: %w", repository.ErrInvalidInput)
+	}
+	scopes, err := normalizeAPIKeyScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(s.now()) {
+		return nil, "", fmt.Errorf("过期时间必须晚于当前时间: %w", repository.ErrInvalidInput)
+	}
+
+	existing, err := s.keys.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	active := 0
+	for _, key := range existing {
+		if key.RevokedAt == nil {
+			active++
+		}
+	}
+	if active >= MaxAPIKeysPerUser {
+		return nil, "", fmt.Errorf("每个用户最多保留%d个未撤销的密钥: %w", MaxAPIKeysPerUser, repository.ErrInvalidInput)
+	}
+
+	plaintext, err := newAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &repository.APIKey{
+		BaseModel: repository.BaseModel{CreateBy: &userID},
+		UserID:    userID,
+		Name:      name,
+		KeyPrefix: plaintext[:apiKeyDisplayLength],
+		KeyHash:   hashAPIKey(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.keys.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Info("API密钥已创建",
+		zap.Int64("api_key_id", key.ID),
+		zap.Int64("user_id", userID),
+		zap.String("key_prefix", key.KeyPrefix))
+	return key, plaintext, nil
+}
+
+// List 列出用户的密钥，包括已撤销的密钥
+func (s *APIKeyService) List(ctx context.Context, userID int64) ([]*repository.APIKey, error) {
+	return s.keys.ListByUser(ctx, userID)
+}
+
+// Revoke 撤销用户自己的密钥，不属于该用户的密钥返回ErrNotFound
+func (s *APIKeyService) Revoke(ctx context.Context, userID, id int64) error {
+	key, err := s.keys.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key.UserID != userID {
+		return fmt.Errorf("API密钥不存在: %w", repository.ErrNotFound)
+	}
+	if err := s.keys.Revoke(ctx, id, userID); err != nil {
+		return err
+	}
+
+	s.logger.Info("API密钥已撤销",
+		zap.Int64("api_key_id", id),
+		zap.Int64("user_id", userID),
+		zap.String("key_prefix", key.KeyPrefix))
+	return nil
+}
+
+// Authenticate 验证明文密钥，返回密钥与所属用户；密钥无效或用户不可用时返回ErrInvalidAPIKey
+func (s *APIKeyService) Authenticate(ctx context.Context, plaintext string) (*repository.APIKey, *repository.User, error) {
+	if !strings.HasPrefix(plaintext, APIKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.keys.GetByHash(ctx, hashAPIKey(plaintext))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	now := s.now()
+	if !key.IsActive(now) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.users.GetByID(ctx, key.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive() {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedInterval {
+		if err := s.keys.UpdateLastUsed(ctx, key.ID, now.UTC()); err != nil {
+			s.logger.Warn("Failed to record API key usage", zap.Int64("api_key_id", key.ID), zap.Error(err))
+		}
+	}
+	return key, user, nil
+}
+
+// normalizeAPIKeyScopes 校验并去重接口范围
+func normalizeAPIKeyScopes(scopes []repository.APIKeyScope) ([]repository.APIKeyScope, error) {
+	seen := make(map[repository.APIKeyScope]bool, len(scopes))
+	var normalized []repository.APIKeyScope
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("未知的接口范围%q: %w", scope, repository.ErrInvalidInput)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("至少需要一个接口范围: %w", repository.ErrInvalidInput)
+	}
+	return normalized, nil
+}
+
+// newAPIKey 生成随机明文密钥
+func newAPIKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成API密钥失败: %w", err)
+	}
+	return APIKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// hashAPIKey 计算密钥的SHA-256哈希；密钥本身是高熵随机数，不需要加盐或慢哈希
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
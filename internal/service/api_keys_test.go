@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// memAPIKeyRepository 内存API密钥Repository
+type memAPIKeyRepository struct {
+	keys     map[int64]*repository.APIKey
+	nextID   int64
+	usedAtOf map[int64]int // 记录使用时间的次数
+}
+
+func (m *memAPIKeyRepository) Create(ctx context.Context, key *repository.APIKey) error {
+	m.nextID++
+	key.ID = m.nextID
+	m.keys[key.ID] = key
+	return nil
+}
+
+func (m *memAPIKeyRepository) GetByID(ctx context.Context, id int64) (*repository.APIKey, error) {
+	if k, ok := m.keys[id]; ok {
+		return k, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*repository.APIKey, error) {
+	for _, k := range m.keys {
+		if k.KeyHash == keyHash {
+			return k, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memAPIKeyRepository) ListByUser(ctx context.Context, userID int64) ([]*repository.APIKey, error) {
+	var keys []*repository.APIKey
+	for _, k := range m.keys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (m *memAPIKeyRepository) Revoke(ctx context.Context, id int64, revokeBy int64) error {
+	k, ok := m.keys[id]
+	if !ok || k.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	k.RevokedAt = &now
+	return nil
+}
+
+func (m *memAPIKeyRepository) UpdateLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	m.keys[id].LastUsedAt = &usedAt
+	m.usedAtOf[id]++
+	return nil
+}
+
+// apiKeyUserRepository 按ID返回用户
+type apiKeyUserRepository struct {
+	repository.UserRepository
+	users map[int64]*repository.User
+}
+
+func (r *apiKeyUserRepository) GetByID(ctx context.Context, id int64) (*repository.User, error) {
+	if u, ok := r.users[id]; ok {
+		return u, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func TestAPIKeyService_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	alice := testutil.NewUser(7, testutil.WithUsername("alice"), testutil.WithUserRole(repository.RoleViewer))
+	keys := &memAPIKeyRepository{keys: map[int64]*repository.APIKey{}, usedAtOf: map[int64]int{}}
+	s := NewAPIKeyService(keys, &apiKeyUserRepository{users: map[int64]*repository.User{7: alice}}, zaptest.NewLogger(t))
+	s.now = func() time.Time { return now }
+
+	expires := now.Add(time.Hour)
+	key, plaintext, err := s.Create(ctx, 7, " ci-nightly ", []repository.APIKeyScope{"sql", "sql", "ai"}, &expires)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plaintext, APIKeyPrefix))
+	assert.Equal(t, "ci-nightly", key.Name)
+	assert.Equal(t, plaintext[:12], key.KeyPrefix)
+	assert.Len(t, key.KeyHash, 64, "只保存SHA-256哈希")
+	assert.Equal(t, []repository.APIKeyScope{"sql", "ai"}, key.Scopes)
+
+	got, user, err := s.Authenticate(ctx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, got.ID)
+	assert.Equal(t, "viewer", user.Role, "以所属用户的当前角色执行")
+	_, _, err = s.Authenticate(ctx, plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, 1, keys.usedAtOf[key.ID], "一分钟内只记录一次使用时间")
+
+	_, _, err = s.Authenticate(ctx, plaintext+"0")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, _, err = s.Authenticate(ctx, "Bearer "+plaintext)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	// 用户被停用后密钥同时失效
+	alice.Status = string(repository.StatusLocked)
+	_, _, err = s.Authenticate(ctx, plaintext)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	alice.Status = string(repository.StatusActive)
+
+	// 过期后失效
+	now = now.Add(2 * time.Hour)
+	_, _, err = s.Authenticate(ctx, plaintext)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	// 只能撤销自己的密钥，撤销后立即失效
+	_, other, err := s.Create(ctx, 7, "bi", []repository.APIKeyScope{repository.APIKeyScopeSQL}, nil)
+	require.NoError(t, err)
+	otherKey, _, err := s.Authenticate(ctx, other)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Revoke(ctx, 8, otherKey.ID), repository.ErrNotFound)
+	require.NoError(t, s.Revoke(ctx, 7, otherKey.ID))
+	_, _, err = s.Authenticate(ctx, other)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	assert.ErrorIs(t, s.Revoke(ctx, 7, otherKey.ID), repository.ErrNotFound)
+}
+
+func TestAPIKeyService_CreateValidation(t *testing.T) {
+	ctx := context.Background()
+	keys := &memAPIKeyRepository{keys: map[int64]*repository.APIKey{}, usedAtOf: map[int64]int{}}
+	s := NewAPIKeyService(keys, &apiKeyUserRepository{}, zaptest.NewLogger(t))
+
+	_, _, err := s.Create(ctx, 7, "", []repository.APIKeyScope{"sql"}, nil)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+	_, _, err = s.Create(ctx, 7, "ci", nil, nil)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+	_, _, err = s.Create(ctx, 7, "ci", []repository.APIKeyScope{"admin"}, nil)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+	_, _, err = s.Create(ctx, 7, "ci", []repository.APIKeyScope{repository.APIKeyScopeMCP}, nil)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput, "范围族不能单独授予")
+	past := time.Now().Add(-time.Minute)
+	_, _, err = s.Create(ctx, 7, "ci", []repository.APIKeyScope{"sql"}, &past)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	for i := 0; i < MaxAPIKeysPerUser; i++ {
+		_, _, err = s.Create(ctx, 7, "ci", []repository.APIKeyScope{"sql"}, nil)
+		require.NoError(t, err)
+	}
+	_, _, err = s.Create(ctx, 7, "ci", []repository.APIKeyScope{"sql"}, nil)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput, "未撤销的密钥数达到上限")
+	require.NoError(t, s.Revoke(ctx, 7, 1))
+	_, _, err = s.Create(ctx, 7, "ci", []repository.APIKeyScope{"sql"}, nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,423 @@
+// 审批流程引擎
+// 写操作、策略变更、预算上调等敏感动作先提交审批申请，由指定审批人（未指定时为manager/admin）
+// 通过或驳回；通过后交给该动作注册的执行器执行，超过有效期的申请由后台扫描自动失效，全程记录审计事件
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/crash"
+	"chat2sql-go/internal/repository"
+)
+
+// 审批流程相关错误
+var (
+	ErrSelfApproval          = errors.New("不能审批自己提交的申请")
+	ErrNotApprover           = errors.New("不是该类申请的审批人")
+	ErrApprovalExpired       = errors.New("审批申请已过期")
+	ErrUnknownApprovalAction = errors.New("未注册的审批动作类型")
+)
+
+// approvalActionTypes 全部审批动作类型
+var approvalActionTypes = []repository.ApprovalActionType{
+	repository.ApprovalWriteQuery,
+	repository.ApprovalPolicyChange,
+	repository.ApprovalBudgetRaise,
+}
+
+// ApprovalExecutor 审批动作执行器，每种动作类型注册一个
+type ApprovalExecutor interface {
+	// Execute 审批通过后执行动作，返回写入审计事件的执行详情
+	Execute(ctx context.Context, req *repository.ApprovalRequest, approverID int64) (string, error)
+	// Cancel 申请被驳回或过期时清理关联资源
+	Cancel(ctx context.Context, req *repository.ApprovalRequest, status repository.ApprovalStatus) error
+}
+
+// ApprovalSubmission 审批申请提交参数
+type ApprovalSubmission struct {
+	ActionType  repository.ApprovalActionType
+	ResourceID  *int64
+	Summary     string
+	Payload     any
+	RequestedBy int64
+}
+
+// ApprovalEngine 审批流程引擎
+type ApprovalEngine struct {
+	repo      repository.ApprovalRepository
+	config    *config.ApprovalConfig
+	executors map[repository.ApprovalActionType]ApprovalExecutor
+	logger    *zap.Logger
+
+	now       func() time.Time
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	mutex     sync.RWMutex
+	isRunning bool
+}
+
+// NewApprovalEngine 创建审批流程引擎
+func NewApprovalEngine(repo repository.ApprovalRepository, approvalConfig *config.ApprovalConfig, logger *zap.Logger) *ApprovalEngine {
+	if approvalConfig == nil {
+		approvalConfig = config.DefaultApprovalConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ApprovalEngine{
+		repo:      repo,
+		config:    approvalConfig,
+		executors: make(map[repository.ApprovalActionType]ApprovalExecutor),
+		logger:    logger,
+		now:       time.Now,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Register 注册动作类型的执行器
+func (e *ApprovalEngine) Register(actionType repository.ApprovalActionType, executor ApprovalExecutor) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.executors[actionType] = executor
+}
+
+// Requires 动作是否需要审批
+func (e *ApprovalEngine) Requires(actionType repository.ApprovalActionType) bool {
+	return e.config.Requires(string(actionType))
+}
+
+// Submit 创建待审批的申请
+func (e *ApprovalEngine) Submit(ctx context.Context, submission *ApprovalSubmission) (*repository.ApprovalRequest, error) {
+	if e.executor(submission.ActionType) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownApprovalAction, submission.ActionType)
+	}
+
+	payload, err := json.Marshal(submission.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化审批参数失败: %w", err)
+	}
+
+	req := &repository.ApprovalRequest{
+		ActionType:  string(submission.ActionType),
+		ResourceID:  submission.ResourceID,
+		Summary:     submission.Summary,
+		Payload:     payload,
+		RequestedBy: submission.RequestedBy,
+		Status:      string(repository.ApprovalPending),
+		ExpiresAt:   e.now().Add(e.config.TTL).UTC(),
+	}
+	if err := e.repo.Create(ctx, req); err != nil {
+		return nil, err
+	}
+	e.audit(ctx, req.ID, &submission.RequestedBy, repository.ApprovalEventCreated, submission.Summary)
+
+	e.logger.Info("审批申请已创建",
+		zap.Int64("approval_id", req.ID),
+		zap.String("action_type", req.ActionType),
+		zap.Int64("requested_by", req.RequestedBy),
+		zap.Time("expires_at", req.ExpiresAt))
+	return req, nil
+}
+
+// List 按状态分页列出审批申请
+// 拥有审批权限的角色可以看到全部申请，其他用户只能看到自己提交或处理过的申请，以及自己被指定为审批人的动作类型的申请
+func (e *ApprovalEngine) List(ctx context.Context, userID int64, role string, status repository.ApprovalStatus, limit, offset int) ([]*repository.ApprovalRequest, error) {
+	if repository.UserRole(role).Can(repository.PermWriteApprove) {
+		return e.repo.ListByStatus(ctx, status, limit, offset)
+	}
+
+	var actionTypes []repository.ApprovalActionType
+	for _, actionType := range approvalActionTypes {
+		approvers, err := e.repo.ListApprovers(ctx, actionType)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(approvers, userID) {
+			actionTypes = append(actionTypes, actionType)
+		}
+	}
+	return e.repo.ListByParticipant(ctx, userID, actionTypes, status, limit, offset)
+}
+
+// Get 获取审批申请及其审计事件，用户无权查看时返回ErrNotFound，不暴露申请是否存在
+func (e *ApprovalEngine) Get(ctx context.Context, userID int64, role string, id int64) (*repository.ApprovalRequest, []*repository.ApprovalEvent, error) {
+	req, err := e.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	visible, err := e.canView(ctx, req, userID, role)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !visible {
+		return nil, nil, fmt.Errorf("审批申请%d: %w", id, repository.ErrNotFound)
+	}
+
+	events, err := e.repo.ListEvents(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, events, nil
+}
+
+// ListEvents 列出审批申请的审计事件，调用方负责校验访问权限
+func (e *ApprovalEngine) ListEvents(ctx context.Context, id int64) ([]*repository.ApprovalEvent, error) {
+	return e.repo.ListEvents(ctx, id)
+}
+
+// Approve 审批通过并交给执行器执行
+// 执行失败时申请标记为failed并返回更新后的申请，不作为错误返回
+func (e *ApprovalEngine) Approve(ctx context.Context, approverID int64, role string, id int64, comment string) (*repository.ApprovalRequest, error) {
+	req, err := e.decide(ctx, approverID, role, id, repository.ApprovalApproved, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	executor := e.executor(repository.ApprovalActionType(req.ActionType))
+	if executor == nil {
+		err = fmt.Errorf("%w: %s", ErrUnknownApprovalAction, req.ActionType)
+		e.recordResult(ctx, req, approverID, err, "")
+		return e.repo.GetByID(ctx, id)
+	}
+
+	detail, execErr := executor.Execute(ctx, req, approverID)
+	e.recordResult(ctx, req, approverID, execErr, detail)
+	return e.repo.GetByID(ctx, id)
+}
+
+// Reject 驳回审批申请
+func (e *ApprovalEngine) Reject(ctx context.Context, approverID int64, role string, id int64, comment string) (*repository.ApprovalRequest, error) {
+	req, err := e.decide(ctx, approverID, role, id, repository.ApprovalRejected, comment)
+	if err != nil {
+		return nil, err
+	}
+	e.cancel(ctx, req, repository.ApprovalRejected)
+
+	return e.repo.GetByID(ctx, id)
+}
+
+// ExpirePending 将已过有效期的pending申请标记为过期，返回处理的数量
+func (e *ApprovalEngine) ExpirePending(ctx context.Context) (int, error) {
+	expired, err := e.repo.ExpirePending(ctx, e.now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, req := range expired {
+		e.audit(ctx, req.ID, nil, string(repository.ApprovalExpired), "超过有效期未审批")
+		e.cancel(ctx, req, repository.ApprovalExpired)
+	}
+
+	if len(expired) > 0 {
+		e.logger.Info("过期审批申请已失效", zap.Int("count", len(expired)))
+	}
+	return len(expired), nil
+}
+
+// ListApprovers 列出动作类型的指定审批人
+func (e *ApprovalEngine) ListApprovers(ctx context.Context, actionType repository.ApprovalActionType) ([]int64, error) {
+	return e.repo.ListApprovers(ctx, actionType)
+}
+
+// SetApprovers 设置动作类型的指定审批人，空列表表示由manager或admin审批
+func (e *ApprovalEngine) SetApprovers(ctx context.Context, actionType repository.ApprovalActionType, userIDs []int64) error {
+	return e.repo.SetApprovers(ctx, actionType, userIDs)
+}
+
+// Start 启动过期申请扫描
+func (e *ApprovalEngine) Start() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.isRunning {
+		return errors.New("审批流程引擎已在运行")
+	}
+	e.isRunning = true
+
+	e.wg.Add(1)
+	go e.sweepRoutine()
+
+	e.logger.Info("审批流程引擎已启动",
+		zap.Duration("ttl", e.config.TTL),
+		zap.Duration("sweep_interval", e.config.SweepInterval))
+	return nil
+}
+
+// Stop 停止过期申请扫描
+func (e *ApprovalEngine) Stop() error {
+	e.mutex.Lock()
+	if !e.isRunning {
+		e.mutex.Unlock()
+		return nil
+	}
+	e.isRunning = false
+	close(e.stopCh)
+	e.mutex.Unlock()
+
+	e.wg.Wait()
+	e.logger.Info("审批流程引擎已停止")
+	return nil
+}
+
+// sweepRoutine 定期扫描过期申请
+func (e *ApprovalEngine) sweepRoutine() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			crash.Run("approval.sweep", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				defer cancel()
+				if _, err := e.ExpirePending(ctx); err != nil {
+					e.logger.Error("扫描过期审批申请失败", zap.Error(err))
+				}
+			})
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// decide 校验审批权限并记录审批结果
+func (e *ApprovalEngine) decide(ctx context.Context, approverID int64, role string, id int64, status repository.ApprovalStatus, comment string) (*repository.ApprovalRequest, error) {
+	req, err := e.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != string(repository.ApprovalPending) {
+		return nil, fmt.Errorf("审批申请已处理: %w", repository.ErrNotFound)
+	}
+	if !e.now().Before(req.ExpiresAt) {
+		return nil, ErrApprovalExpired
+	}
+	if err := e.authorize(ctx, req, approverID, role); err != nil {
+		return nil, err
+	}
+
+	if err := e.repo.Decide(ctx, id, approverID, status, optionalString(comment)); err != nil {
+		return nil, err
+	}
+	e.audit(ctx, id, &approverID, string(status), comment)
+
+	now := e.now().UTC()
+	req.Status = string(status)
+	req.DecidedBy = &approverID
+	req.DecidedTime = &now
+	req.DecisionComment = optionalString(comment)
+
+	e.logger.Info("审批申请已处理",
+		zap.Int64("approval_id", id),
+		zap.String("action_type", req.ActionType),
+		zap.Int64("decided_by", approverID),
+		zap.String("status", string(status)))
+	return req, nil
+}
+
+// authorize 申请人不能审批自己的申请；动作类型指定了审批人时只有指定审批人可以处理，否则需要manager或admin角色
+func (e *ApprovalEngine) authorize(ctx context.Context, req *repository.ApprovalRequest, approverID int64, role string) error {
+	if req.RequestedBy == approverID {
+		return ErrSelfApproval
+	}
+
+	approvers, err := e.repo.ListApprovers(ctx, repository.ApprovalActionType(req.ActionType))
+	if err != nil {
+		return err
+	}
+	if len(approvers) > 0 {
+		if !slices.Contains(approvers, approverID) {
+			return ErrNotApprover
+		}
+		return nil
+	}
+
+	if role != string(repository.RoleManager) && role != string(repository.RoleAdmin) {
+		return ErrNotApprover
+	}
+	return nil
+}
+
+// canView 申请人、处理人、拥有审批权限的角色与该动作类型的指定审批人可以查看申请
+func (e *ApprovalEngine) canView(ctx context.Context, req *repository.ApprovalRequest, userID int64, role string) (bool, error) {
+	if req.RequestedBy == userID || (req.DecidedBy != nil && *req.DecidedBy == userID) {
+		return true, nil
+	}
+	if repository.UserRole(role).Can(repository.PermWriteApprove) {
+		return true, nil
+	}
+	approvers, err := e.repo.ListApprovers(ctx, repository.ApprovalActionType(req.ActionType))
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(approvers, userID), nil
+}
+
+// recordResult 记录执行结果，失败只记录日志
+func (e *ApprovalEngine) recordResult(ctx context.Context, req *repository.ApprovalRequest, approverID int64, execErr error, detail string) {
+	status := repository.ApprovalExecuted
+	var message *string
+	if execErr != nil {
+		status = repository.ApprovalFailed
+		detail = execErr.Error()
+		message = &detail
+		e.logger.Warn("审批动作执行失败",
+			zap.Int64("approval_id", req.ID),
+			zap.String("action_type", req.ActionType),
+			zap.Error(execErr))
+	}
+
+	if err := e.repo.RecordResult(ctx, req.ID, status, message); err != nil {
+		e.logger.Error("记录审批执行结果失败", zap.Int64("approval_id", req.ID), zap.Error(err))
+	}
+	e.audit(ctx, req.ID, &approverID, string(status), detail)
+}
+
+// cancel 通知执行器申请被驳回或过期，失败只记录日志
+func (e *ApprovalEngine) cancel(ctx context.Context, req *repository.ApprovalRequest, status repository.ApprovalStatus) {
+	executor := e.executor(repository.ApprovalActionType(req.ActionType))
+	if executor == nil {
+		return
+	}
+	if err := executor.Cancel(ctx, req, status); err != nil {
+		e.logger.Error("取消审批动作失败",
+			zap.Int64("approval_id", req.ID),
+			zap.String("action_type", req.ActionType),
+			zap.Error(err))
+	}
+}
+
+// executor 获取动作类型的执行器
+func (e *ApprovalEngine) executor(actionType repository.ApprovalActionType) ApprovalExecutor {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.executors[actionType]
+}
+
+// audit 记录审计事件，失败只记录日志，不影响主流程
+func (e *ApprovalEngine) audit(ctx context.Context, requestID int64, actorID *int64, action, detail string) {
+	event := &repository.ApprovalEvent{
+		RequestID: requestID,
+		ActorID:   actorID,
+		Action:    action,
+		Detail:    optionalString(detail),
+	}
+	if err := e.repo.AddEvent(ctx, event); err != nil {
+		e.logger.Error("记录审批审计事件失败",
+			zap.Int64("approval_id", requestID),
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// memApprovalRepository 内存审批申请Repository
+type memApprovalRepository struct {
+	requests  map[int64]*repository.ApprovalRequest
+	events    []*repository.ApprovalEvent
+	approvers map[repository.ApprovalActionType][]int64
+}
+
+func newMemApprovalRepository() *memApprovalRepository {
+	return &memApprovalRepository{
+		requests:  make(map[int64]*repository.ApprovalRequest),
+		approvers: make(map[repository.ApprovalActionType][]int64),
+	}
+}
+
+func (m *memApprovalRepository) Create(ctx context.Context, req *repository.ApprovalRequest) error {
+	req.ID = int64(len(m.requests) + 1)
+	m.requests[req.ID] = req
+	return nil
+}
+
+func (m *memApprovalRepository) GetByID(ctx context.Context, id int64) (*repository.ApprovalRequest, error) {
+	req, ok := m.requests[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return req, nil
+}
+
+func (m *memApprovalRepository) ListByStatus(ctx context.Context, status repository.ApprovalStatus, limit, offset int) ([]*repository.ApprovalRequest, error) {
+	var requests []*repository.ApprovalRequest
+	for _, req := range m.requests {
+		if req.Status == string(status) {
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+func (m *memApprovalRepository) ListByParticipant(ctx context.Context, userID int64, actionTypes []repository.ApprovalActionType, status repository.ApprovalStatus, limit, offset int) ([]*repository.ApprovalRequest, error) {
+	var requests []*repository.ApprovalRequest
+	for _, req := range m.requests {
+		participant := req.RequestedBy == userID || (req.DecidedBy != nil && *req.DecidedBy == userID) ||
+			slices.Contains(actionTypes, repository.ApprovalActionType(req.ActionType))
+		if req.Status == string(status) && participant {
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+func (m *memApprovalRepository) Decide(ctx context.Context, id, deciderID int64, status repository.ApprovalStatus, comment *string) error {
+	req, ok := m.requests[id]
+	if !ok || req.Status != string(repository.ApprovalPending) {
+		return repository.ErrNotFound
+	}
+	req.Status = string(status)
+	req.DecidedBy = &deciderID
+	req.DecisionComment = comment
+	return nil
+}
+
+func (m *memApprovalRepository) RecordResult(ctx context.Context, id int64, status repository.ApprovalStatus, errorMessage *string) error {
+	req := m.requests[id]
+	req.Status = string(status)
+	req.ErrorMessage = errorMessage
+	return nil
+}
+
+func (m *memApprovalRepository) ExpirePending(ctx context.Context, now time.Time) ([]*repository.ApprovalRequest, error) {
+	var expired []*repository.ApprovalRequest
+	for _, req := range m.requests {
+		if req.Status == string(repository.ApprovalPending) && !now.Before(req.ExpiresAt) {
+			req.Status = string(repository.ApprovalExpired)
+			expired = append(expired, req)
+		}
+	}
+	return expired, nil
+}
+
+func (m *memApprovalRepository) AddEvent(ctx context.Context, event *repository.ApprovalEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memApprovalRepository) ListEvents(ctx context.Context, requestID int64) ([]*repository.ApprovalEvent, error) {
+	var events []*repository.ApprovalEvent
+	for _, event := range m.events {
+		if event.RequestID == requestID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *memApprovalRepository) ListApprovers(ctx context.Context, actionType repository.ApprovalActionType) ([]int64, error) {
+	return m.approvers[actionType], nil
+}
+
+func (m *memApprovalRepository) SetApprovers(ctx context.Context, actionType repository.ApprovalActionType, userIDs []int64) error {
+	m.approvers[actionType] = userIDs
+	return nil
+}
+
+func (m *memApprovalRepository) actions() []string {
+	var actions []string
+	for _, event := range m.events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+// recordingApprovalExecutor 记录执行与取消调用
+type recordingApprovalExecutor struct {
+	err       error
+	executed  []int64
+	cancelled []repository.ApprovalStatus
+}
+
+func (r *recordingApprovalExecutor) Execute(ctx context.Context, req *repository.ApprovalRequest, approverID int64) (string, error) {
+	r.executed = append(r.executed, req.ID)
+	return "done", r.err
+}
+
+func (r *recordingApprovalExecutor) Cancel(ctx context.Context, req *repository.ApprovalRequest, status repository.ApprovalStatus) error {
+	r.cancelled = append(r.cancelled, status)
+	return nil
+}
+
+func newApprovalTestEngine(t *testing.T) (*ApprovalEngine, *memApprovalRepository) {
+	repo := newMemApprovalRepository()
+	return NewApprovalEngine(repo, config.DefaultApprovalConfig(), zaptest.NewLogger(t)), repo
+}
+
+func TestApprovalEngine_Decisions(t *testing.T) {
+	ctx := context.Background()
+	submit := func(t *testing.T, engine *ApprovalEngine) *repository.ApprovalRequest {
+		req, err := engine.Submit(ctx, &ApprovalSubmission{
+			ActionType:  repository.ApprovalPolicyChange,
+			Summary:     "开启自动执行",
+			Payload:     map[string]bool{"enabled": true},
+			RequestedBy: 7,
+		})
+		require.NoError(t, err)
+		return req
+	}
+
+	t.Run("未注册执行器的动作不能提交", func(t *testing.T) {
+		engine, _ := newApprovalTestEngine(t)
+
+		_, err := engine.Submit(ctx, &ApprovalSubmission{ActionType: repository.ApprovalBudgetRaise, RequestedBy: 7})
+		assert.ErrorIs(t, err, ErrUnknownApprovalAction)
+	})
+
+	t.Run("manager审批通过后执行", func(t *testing.T) {
+		engine, repo := newApprovalTestEngine(t)
+		executor := &recordingApprovalExecutor{}
+		engine.Register(repository.ApprovalPolicyChange, executor)
+
+		req := submit(t, engine)
+		assert.Equal(t, string(repository.ApprovalPending), req.Status)
+		assert.JSONEq(t, `{"enabled":true}`, string(req.Payload))
+
+		_, err := engine.Approve(ctx, 7, "admin", req.ID, "")
+		assert.ErrorIs(t, err, ErrSelfApproval)
+		_, err = engine.Approve(ctx, 8, "user", req.ID, "")
+		assert.ErrorIs(t, err, ErrNotApprover)
+
+		approved, err := engine.Approve(ctx, 9, "manager", req.ID, "同意")
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.ApprovalExecuted), approved.Status)
+		assert.Equal(t, int64(9), *approved.DecidedBy)
+		assert.Equal(t, []int64{req.ID}, executor.executed)
+		assert.Equal(t, []string{"created", "approved", "executed"}, repo.actions())
+
+		// 已处理的申请不能再次审批
+		_, err = engine.Reject(ctx, 9, "manager", req.ID, "")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("指定审批人后只有指定用户可以审批", func(t *testing.T) {
+		engine, _ := newApprovalTestEngine(t)
+		engine.Register(repository.ApprovalPolicyChange, &recordingApprovalExecutor{})
+		require.NoError(t, engine.SetApprovers(ctx, repository.ApprovalPolicyChange, []int64{5}))
+
+		req := submit(t, engine)
+		_, err := engine.Approve(ctx, 9, "admin", req.ID, "")
+		assert.ErrorIs(t, err, ErrNotApprover)
+
+		_, err = engine.Approve(ctx, 5, "user", req.ID, "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("执行失败标记为failed", func(t *testing.T) {
+		engine, repo := newApprovalTestEngine(t)
+		engine.Register(repository.ApprovalPolicyChange, &recordingApprovalExecutor{err: errors.New("boom")})
+
+		req := submit(t, engine)
+		failed, err := engine.Approve(ctx, 9, "manager", req.ID, "")
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.ApprovalFailed), failed.Status)
+		require.NotNil(t, failed.ErrorMessage)
+		assert.Equal(t, "boom", *failed.ErrorMessage)
+		assert.Equal(t, []string{"created", "approved", "failed"}, repo.actions())
+	})
+
+	t.Run("驳回时通知执行器取消", func(t *testing.T) {
+		engine, _ := newApprovalTestEngine(t)
+		executor := &recordingApprovalExecutor{}
+		engine.Register(repository.ApprovalPolicyChange, executor)
+
+		req := submit(t, engine)
+		rejected, err := engine.Reject(ctx, 9, "manager", req.ID, "不需要")
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.ApprovalRejected), rejected.Status)
+		assert.Empty(t, executor.executed)
+		assert.Equal(t, []repository.ApprovalStatus{repository.ApprovalRejected}, executor.cancelled)
+	})
+}
+
+func TestApprovalEngine_Expiry(t *testing.T) {
+	ctx := context.Background()
+	engine, repo := newApprovalTestEngine(t)
+	executor := &recordingApprovalExecutor{}
+	engine.Register(repository.ApprovalPolicyChange, executor)
+
+	now := time.Now()
+	engine.now = func() time.Time { return now }
+
+	req, err := engine.Submit(ctx, &ApprovalSubmission{ActionType: repository.ApprovalPolicyChange, RequestedBy: 7})
+	require.NoError(t, err)
+
+	count, err := engine.ExpirePending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// 超过有效期后不能审批，由扫描标记为过期
+	now = now.Add(73 * time.Hour)
+	_, err = engine.Approve(ctx, 9, "manager", req.ID, "")
+	assert.ErrorIs(t, err, ErrApprovalExpired)
+
+	count, err = engine.ExpirePending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, string(repository.ApprovalExpired), req.Status)
+	assert.Equal(t, []repository.ApprovalStatus{repository.ApprovalExpired}, executor.cancelled)
+
+	_, events, err := engine.Get(ctx, 9, "manager", req.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "expired", events[1].Action)
+	assert.Nil(t, events[1].ActorID, "过期由系统处理")
+	assert.Equal(t, []string{"created", "expired"}, repo.actions())
+}
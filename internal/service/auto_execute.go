@@ -0,0 +1,221 @@
+// 生成SQL自动执行策略
+// 按工作空间策略决定生成的SQL是否直接执行：置信度足够高且预估代价足够低时自动执行，
+// 否则交由用户确认；执行路径写入查询历史，便于事后分析自动执行的准确率
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// AutoExecuteExecutor 自动执行所需的SQL执行能力
+type AutoExecuteExecutor interface {
+	ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error)
+	EstimateCost(ctx context.Context, sql string, connection *repository.DatabaseConnection) (float64, error)
+}
+
+// AutoExecuteDecision 自动执行判定结果
+type AutoExecuteDecision struct {
+	AutoExecute   bool     `json:"auto_execute"`
+	Reason        string   `json:"reason"`
+	EstimatedCost *float64 `json:"estimated_cost,omitempty"`
+}
+
+// AutoExecuteOutcome 自动执行结果，未自动执行时Result为nil
+type AutoExecuteOutcome struct {
+	Decision       *AutoExecuteDecision
+	Result         *QueryResult
+	QueryHistoryID int64
+}
+
+// EvaluateAutoExecute 根据策略判定是否自动执行，判定原因按locale返回
+// estimatedCost为nil表示代价无法估算，此时保守地要求确认
+func EvaluateAutoExecute(policy *repository.AutoExecutePolicy, confidence float64, estimatedCost *float64, locale string) *AutoExecuteDecision {
+	decision := &AutoExecuteDecision{EstimatedCost: estimatedCost}
+
+	switch {
+	case policy == nil || !policy.Enabled:
+		decision.Reason = Localize(locale, "工作空间未启用自动执行")
+	case confidence < policy.MinConfidence:
+		decision.Reason = fmt.Sprintf(Localize(locale, "置信度%.2f低于阈值%.2f"), confidence, policy.MinConfidence)
+	case estimatedCost == nil:
+		decision.Reason = Localize(locale, "无法估算查询代价")
+	case *estimatedCost > policy.MaxEstimatedCost:
+		decision.Reason = fmt.Sprintf(Localize(locale, "预估代价%.0f超过上限%.0f"), *estimatedCost, policy.MaxEstimatedCost)
+	default:
+		decision.AutoExecute = true
+		decision.Reason = Localize(locale, "满足工作空间自动执行策略")
+	}
+	return decision
+}
+
+// AutoExecuteService 自动执行服务
+type AutoExecuteService struct {
+	workspaceRepo  repository.WorkspaceRepository
+	connectionRepo repository.ConnectionRepository
+	queryRepo      repository.QueryHistoryRepository
+	executor       AutoExecuteExecutor
+	validator      *SQLSecurityValidator
+	logger         *zap.Logger
+}
+
+// NewAutoExecuteService 创建自动执行服务
+func NewAutoExecuteService(
+	workspaceRepo repository.WorkspaceRepository,
+	connectionRepo repository.ConnectionRepository,
+	queryRepo repository.QueryHistoryRepository,
+	executor AutoExecuteExecutor,
+	logger *zap.Logger,
+) *AutoExecuteService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &AutoExecuteService{
+		workspaceRepo:  workspaceRepo,
+		connectionRepo: connectionRepo,
+		queryRepo:      queryRepo,
+		executor:       executor,
+		validator:      NewSQLSecurityValidator(logger),
+		logger:         logger,
+	}
+}
+
+// Run 按用户所属工作空间的策略判定并在满足条件时执行SQL
+// 仅只读且通过安全校验的SQL才会自动执行；执行时写入execution_path=auto的查询历史。
+// 连接权限与手动执行相同，按WithQueryRole标记的角色判断；判定原因使用WithLocale设置的语言
+func (s *AutoExecuteService) Run(ctx context.Context, userID, connectionID int64, naturalQuery, sql string, confidence float64) (*AutoExecuteOutcome, error) {
+	locale := localeFromContext(ctx)
+	workspace, err := s.workspaceRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取工作空间失败: %w", err)
+	}
+
+	policy := workspace.AutoExecutePolicy
+	if policy == nil || !policy.Enabled || confidence < policy.MinConfidence {
+		// 无需估算代价即可判定
+		return &AutoExecuteOutcome{Decision: EvaluateAutoExecute(policy, confidence, nil, locale)}, nil
+	}
+
+	if validation := s.validator.ValidateSQL(sql); !validation.IsValid || !validation.IsReadOnly {
+		return &AutoExecuteOutcome{Decision: &AutoExecuteDecision{Reason: Localize(locale, "仅只读查询允许自动执行")}}, nil
+	}
+
+	role, hasRole := queryRole(ctx)
+	connection, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil || !CanExecuteOn(userID, role, hasRole, connection, workspace.Defaults) {
+		return nil, fmt.Errorf("无权访问数据库连接%d: %w", connectionID, repository.ErrPermissionDenied)
+	}
+
+	var estimatedCost *float64
+	if cost, err := s.executor.EstimateCost(ctx, sql, connection); err != nil {
+		s.logger.Warn("估算查询代价失败", zap.Int64("connection_id", connectionID), zap.Error(err))
+	} else {
+		estimatedCost = &cost
+	}
+
+	decision := EvaluateAutoExecute(policy, confidence, estimatedCost, locale)
+	outcome := &AutoExecuteOutcome{Decision: decision}
+	if !decision.AutoExecute {
+		return outcome, nil
+	}
+
+	// 查询历史保存写回参数后的SQL，便于阅读与重放
+	historySQL := sql
+	if params := queryParameters(ctx); len(params) > 0 {
+		if historySQL, err = InlineParameters(sql, params, connectionDBType(connection)); err != nil {
+			return nil, err
+		}
+	}
+
+	path := string(repository.ExecutionAuto)
+	history := &repository.QueryHistory{
+		UserID:        userID,
+		NaturalQuery:  naturalQuery,
+		GeneratedSQL:  historySQL,
+		Status:        string(repository.QueryPending),
+		ConnectionID:  &connectionID,
+		AIConfidence:  &confidence,
+		ExecutionPath: &path,
+
+		SchemaSnapshotID: schemaSnapshotFromContext(ctx),
+	}
+	ApplyChargebackTags(history, chargebackTagsFromContext(ctx))
+	if err := s.queryRepo.Create(ctx, history); err != nil {
+		s.logger.Error("创建自动执行查询历史失败", zap.Int64("user_id", userID), zap.Error(err))
+	}
+
+	result, execErr := s.executor.ExecuteQuery(WithQueryOwner(ctx, userID), sql, connection)
+	if result == nil {
+		result = &QueryResult{Status: string(repository.QueryError)}
+		if execErr != nil {
+			result.Error = execErr.Error()
+		}
+	}
+
+	history.Status = result.Status
+	history.ExecutionTime = &result.ExecutionTime
+	history.ResultRows = &result.RowCount
+	if result.Error != "" {
+		history.ErrorMessage = &result.Error
+	}
+	if history.ID != 0 {
+		if err := s.queryRepo.Update(ctx, history); err != nil {
+			s.logger.Warn("更新自动执行查询历史失败", zap.Int64("query_id", history.ID), zap.Error(err))
+		}
+	}
+
+	s.logger.Info("SQL已自动执行",
+		zap.Int64("user_id", userID),
+		zap.Int64("workspace_id", workspace.ID),
+		zap.Float64("confidence", confidence),
+		zap.Float64("estimated_cost", *estimatedCost),
+		zap.String("status", result.Status))
+
+	outcome.Result = result
+	outcome.QueryHistoryID = history.ID
+	return outcome, nil
+}
+
+// PolicyChangeExecutor 审批通过后应用工作空间自动执行策略，实现ApprovalExecutor
+type PolicyChangeExecutor struct {
+	workspaceRepo repository.WorkspaceRepository
+	logger        *zap.Logger
+}
+
+// NewPolicyChangeExecutor 创建策略变更审批执行器
+func NewPolicyChangeExecutor(workspaceRepo repository.WorkspaceRepository, logger *zap.Logger) *PolicyChangeExecutor {
+	return &PolicyChangeExecutor{workspaceRepo: workspaceRepo, logger: logger}
+}
+
+// Execute 将申请中的策略写入工作空间，申请的ResourceID为工作空间ID
+func (e *PolicyChangeExecutor) Execute(ctx context.Context, req *repository.ApprovalRequest, approverID int64) (string, error) {
+	if req.ResourceID == nil {
+		return "", fmt.Errorf("策略变更申请%d未关联工作空间: %w", req.ID, repository.ErrInvalidInput)
+	}
+
+	var policy repository.AutoExecutePolicy
+	if err := json.Unmarshal(req.Payload, &policy); err != nil {
+		return "", fmt.Errorf("解析策略变更参数失败: %w", err)
+	}
+
+	if err := e.workspaceRepo.UpdateAutoExecutePolicy(ctx, *req.ResourceID, &policy); err != nil {
+		return "", err
+	}
+
+	e.logger.Info("自动执行策略已按审批更新",
+		zap.Int64("workspace_id", *req.ResourceID),
+		zap.Int64("approval_id", req.ID),
+		zap.Int64("approved_by", approverID))
+	return fmt.Sprintf("工作空间%d自动执行策略已更新", *req.ResourceID), nil
+}
+
+// Cancel 策略在审批通过前不生效，驳回或过期无需清理
+func (e *PolicyChangeExecutor) Cancel(ctx context.Context, req *repository.ApprovalRequest, status repository.ApprovalStatus) error {
+	return nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// stubWorkspaceRepository 返回固定工作空间
+type stubWorkspaceRepository struct {
+	repository.WorkspaceRepository
+	workspace *repository.Workspace
+}
+
+func (s *stubWorkspaceRepository) GetByUserID(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	return s.workspace, nil
+}
+
+// stubConnectionRepository 返回固定连接
+type stubConnectionRepository struct {
+	repository.ConnectionRepository
+	connection *repository.DatabaseConnection
+}
+
+func (s *stubConnectionRepository) GetByID(ctx context.Context, id int64) (*repository.DatabaseConnection, error) {
+	if id != s.connection.ID {
+		return nil, repository.ErrNotFound
+	}
+	return s.connection, nil
+}
+
+// recordingQueryHistoryRepository 记录写入的查询历史
+type recordingQueryHistoryRepository struct {
+	repository.QueryHistoryRepository
+	created []*repository.QueryHistory
+	updated []*repository.QueryHistory
+}
+
+func (r *recordingQueryHistoryRepository) Create(ctx context.Context, query *repository.QueryHistory) error {
+	query.ID = int64(len(r.created) + 1)
+	r.created = append(r.created, query)
+	return nil
+}
+
+func (r *recordingQueryHistoryRepository) Update(ctx context.Context, query *repository.QueryHistory) error {
+	r.updated = append(r.updated, query)
+	return nil
+}
+
+// stubAutoExecuteExecutor 固定代价与执行结果
+type stubAutoExecuteExecutor struct {
+	cost     float64
+	costErr  error
+	executed int
+}
+
+func (s *stubAutoExecuteExecutor) ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error) {
+	s.executed++
+	return &QueryResult{Status: string(repository.QuerySuccess), RowCount: 3, ExecutionTime: 12}, nil
+}
+
+func (s *stubAutoExecuteExecutor) EstimateCost(ctx context.Context, sql string, connection *repository.DatabaseConnection) (float64, error) {
+	return s.cost, s.costErr
+}
+
+func TestEvaluateAutoExecute(t *testing.T) {
+	policy := &repository.AutoExecutePolicy{Enabled: true, MinConfidence: 0.8, MaxEstimatedCost: 1000}
+	cost := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name       string
+		policy     *repository.AutoExecutePolicy
+		confidence float64
+		cost       *float64
+		want       bool
+	}{
+		{"未配置策略", nil, 0.99, cost(1), false},
+		{"策略未启用", &repository.AutoExecutePolicy{MinConfidence: 0.5, MaxEstimatedCost: 1000}, 0.99, cost(1), false},
+		{"置信度不足", policy, 0.79, cost(1), false},
+		{"代价无法估算", policy, 0.9, nil, false},
+		{"代价超限", policy, 0.9, cost(1000.5), false},
+		{"满足策略", policy, 0.8, cost(1000), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := EvaluateAutoExecute(tt.policy, tt.confidence, tt.cost, "")
+			assert.Equal(t, tt.want, decision.AutoExecute)
+			assert.NotEmpty(t, decision.Reason)
+		})
+	}
+}
+
+func TestAutoExecuteService_Run(t *testing.T) {
+	ctx := context.Background()
+	policy := &repository.AutoExecutePolicy{Enabled: true, MinConfidence: 0.8, MaxEstimatedCost: 1000}
+	connection := testutil.NewConnection(3, 7)
+
+	newService := func(executor *stubAutoExecuteExecutor) (*AutoExecuteService, *recordingQueryHistoryRepository) {
+		workspaces := &stubWorkspaceRepository{workspace: &repository.Workspace{AutoExecutePolicy: policy}}
+		connections := &stubConnectionRepository{connection: connection}
+		history := &recordingQueryHistoryRepository{}
+		return NewAutoExecuteService(workspaces, connections, history, executor, zap.NewNop()), history
+	}
+
+	t.Run("满足策略时自动执行并记录执行路径", func(t *testing.T) {
+		executor := &stubAutoExecuteExecutor{cost: 42}
+		svc, history := newService(executor)
+
+		outcome, err := svc.Run(ctx, 7, 3, "用户总数", "SELECT COUNT(*) FROM users", 0.9)
+		require.NoError(t, err)
+		assert.True(t, outcome.Decision.AutoExecute)
+		require.NotNil(t, outcome.Result)
+		assert.Equal(t, 1, executor.executed)
+
+		require.Len(t, history.created, 1)
+		record := history.created[0]
+		assert.Equal(t, string(repository.ExecutionAuto), *record.ExecutionPath)
+		assert.InDelta(t, 0.9, *record.AIConfidence, 1e-9)
+		assert.Equal(t, string(repository.QuerySuccess), record.Status)
+		assert.Equal(t, record.ID, outcome.QueryHistoryID)
+	})
+
+	t.Run("绑定参数执行时查询历史保存写回参数后的SQL", func(t *testing.T) {
+		executor := &stubAutoExecuteExecutor{cost: 42}
+		svc, history := newService(executor)
+
+		paramCtx := WithQueryParameters(ctx, []string{"O'Brien"})
+		outcome, err := svc.Run(paramCtx, 7, 3, "客户'O'Brien'的订单", "SELECT * FROM orders WHERE customer = $1", 0.9)
+		require.NoError(t, err)
+		require.NotNil(t, outcome.Result)
+		require.Len(t, history.created, 1)
+		assert.Equal(t, "SELECT * FROM orders WHERE customer = 'O''Brien'", history.created[0].GeneratedSQL)
+	})
+
+	t.Run("代价超限时要求确认", func(t *testing.T) {
+		executor := &stubAutoExecuteExecutor{cost: 5000}
+		svc, history := newService(executor)
+
+		outcome, err := svc.Run(ctx, 7, 3, "全部订单", "SELECT * FROM orders", 0.95)
+		require.NoError(t, err)
+		assert.False(t, outcome.Decision.AutoExecute)
+		assert.Nil(t, outcome.Result)
+		assert.Zero(t, executor.executed)
+		assert.Empty(t, history.created)
+	})
+
+	t.Run("代价估算失败时要求确认", func(t *testing.T) {
+		executor := &stubAutoExecuteExecutor{costErr: errors.New("explain failed")}
+		svc, _ := newService(executor)
+
+		outcome, err := svc.Run(ctx, 7, 3, "全部订单", "SELECT * FROM orders", 0.95)
+		require.NoError(t, err)
+		assert.False(t, outcome.Decision.AutoExecute)
+		assert.Zero(t, executor.executed)
+	})
+
+	t.Run("非只读SQL不自动执行", func(t *testing.T) {
+		executor := &stubAutoExecuteExecutor{cost: 1}
+		svc, _ := newService(executor)
+
+		outcome, err := svc.Run(ctx, 7, 3, "删除订单", "DELETE FROM orders", 0.99)
+		require.NoError(t, err)
+		assert.False(t, outcome.Decision.AutoExecute)
+		assert.Zero(t, executor.executed)
+	})
+
+	t.Run("他人连接拒绝", func(t *testing.T) {
+		svc, _ := newService(&stubAutoExecuteExecutor{cost: 1})
+
+		_, err := svc.Run(ctx, 8, 3, "用户总数", "SELECT COUNT(*) FROM users", 0.9)
+		assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+	})
+
+	t.Run("按角色判断连接权限", func(t *testing.T) {
+		defaultConnection := int64(3)
+		workspaces := &stubWorkspaceRepository{workspace: &repository.Workspace{
+			AutoExecutePolicy: policy,
+			Defaults:          &repository.WorkspaceDefaults{ConnectionID: &defaultConnection},
+		}}
+		executor := &stubAutoExecuteExecutor{cost: 1}
+		svc := NewAutoExecuteService(workspaces, &stubConnectionRepository{connection: connection},
+			&recordingQueryHistoryRepository{}, executor, zap.NewNop())
+		viewerCtx := WithQueryRole(ctx, string(repository.RoleViewer))
+
+		outcome, err := svc.Run(viewerCtx, 8, 3, "用户总数", "SELECT COUNT(*) FROM users", 0.9)
+		require.NoError(t, err, "viewer可以使用工作空间的默认连接")
+		assert.True(t, outcome.Decision.AutoExecute)
+
+		defaultConnection = 4
+		_, err = svc.Run(viewerCtx, 7, 3, "用户总数", "SELECT COUNT(*) FROM users", 0.9)
+		assert.ErrorIs(t, err, repository.ErrPermissionDenied, "降级为viewer后不能在自己的连接上执行")
+		assert.Equal(t, 1, executor.executed)
+	})
+}
+
+func TestParseExplainTotalCost(t *testing.T) {
+	cost, err := parseExplainTotalCost([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Startup Cost": 0.0, "Total Cost": 35.5}}]`))
+	require.NoError(t, err)
+	assert.InDelta(t, 35.5, cost, 1e-9)
+
+	_, err = parseExplainTotalCost([]byte(`[]`))
+	assert.Error(t, err)
+}
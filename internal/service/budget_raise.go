@@ -0,0 +1,94 @@
+// 模型预算上调
+// 用户申请在一段时间内提高自己的每日模型预算，审批通过后写入llm_budget_overrides并立即生效，
+// 其他实例在重启加载用量时恢复；到期后自动恢复DAILY_BUDGET_PER_USER
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// MaxBudgetRaiseDays 预算上调的最长有效天数
+const MaxBudgetRaiseDays = 30
+
+// BudgetRaise 预算上调申请参数
+type BudgetRaise struct {
+	DailyLimit float64 `json:"daily_limit"` // 上调后的每日上限（美元）
+	Days       int     `json:"days"`        // 有效天数
+	Reason     string  `json:"reason"`      // 申请理由
+}
+
+// Validate 校验上调参数，上调后的上限必须高于当前上限；当前不限额时无需上调
+func (r *BudgetRaise) Validate(currentLimit float64) error {
+	if currentLimit <= 0 {
+		return fmt.Errorf("%w: 当前未限制每日预算，无需上调", repository.ErrInvalidInput)
+	}
+	if r.DailyLimit <= currentLimit {
+		return fmt.Errorf("%w: 上调后的每日上限必须高于当前上限%.2f", repository.ErrInvalidInput, currentLimit)
+	}
+	if r.Days < 1 || r.Days > MaxBudgetRaiseDays {
+		return fmt.Errorf("%w: 有效天数必须在1到%d之间", repository.ErrInvalidInput, MaxBudgetRaiseDays)
+	}
+	return nil
+}
+
+// BudgetLimiter 应用每用户预算上限，由ai.CostTracker实现
+type BudgetLimiter interface {
+	SetUserLimit(userID int64, limit float64, expiresAt time.Time)
+}
+
+// BudgetRaiseExecutor 审批通过后应用预算上调，实现ApprovalExecutor
+type BudgetRaiseExecutor struct {
+	store   repository.LLMUsageRepository
+	limiter BudgetLimiter
+	now     func() time.Time
+	logger  *zap.Logger
+}
+
+// NewBudgetRaiseExecutor 创建预算上调审批执行器
+func NewBudgetRaiseExecutor(store repository.LLMUsageRepository, limiter BudgetLimiter, logger *zap.Logger) *BudgetRaiseExecutor {
+	return &BudgetRaiseExecutor{store: store, limiter: limiter, now: time.Now, logger: logger}
+}
+
+// Execute 为申请人保存并应用上调后的上限，有效期从审批通过时开始计算
+func (e *BudgetRaiseExecutor) Execute(ctx context.Context, req *repository.ApprovalRequest, approverID int64) (string, error) {
+	var raise BudgetRaise
+	if err := json.Unmarshal(req.Payload, &raise); err != nil {
+		return "", fmt.Errorf("解析预算上调参数失败: %w", err)
+	}
+	if raise.DailyLimit <= 0 || raise.Days < 1 || raise.Days > MaxBudgetRaiseDays {
+		return "", fmt.Errorf("预算上调申请%d参数无效: %w", req.ID, repository.ErrInvalidInput)
+	}
+
+	approvalID := req.ID
+	override := &repository.LLMBudgetOverride{
+		UserID:     req.RequestedBy,
+		DailyLimit: raise.DailyLimit,
+		ExpiresAt:  e.now().Add(time.Duration(raise.Days) * 24 * time.Hour).UTC(),
+		ApprovalID: &approvalID,
+	}
+	if err := e.store.SetBudgetOverride(ctx, override); err != nil {
+		return "", err
+	}
+	e.limiter.SetUserLimit(override.UserID, override.DailyLimit, override.ExpiresAt)
+
+	e.logger.Info("预算上调已按审批生效",
+		zap.Int64("user_id", override.UserID),
+		zap.Float64("daily_limit", override.DailyLimit),
+		zap.Time("expires_at", override.ExpiresAt),
+		zap.Int64("approval_id", req.ID),
+		zap.Int64("approved_by", approverID))
+	return fmt.Sprintf("用户%d每日预算上调至%.2f美元，%s失效",
+		override.UserID, override.DailyLimit, override.ExpiresAt.Format(time.RFC3339)), nil
+}
+
+// Cancel 上调在审批通过前不生效，驳回或过期无需清理
+func (e *BudgetRaiseExecutor) Cancel(ctx context.Context, req *repository.ApprovalRequest, status repository.ApprovalStatus) error {
+	return nil
+}
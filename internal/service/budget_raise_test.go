@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+// budgetOverrideStore 只保存预算上调的用量存储
+type budgetOverrideStore struct {
+	repository.LLMUsageRepository
+	overrides map[int64]*repository.LLMBudgetOverride
+}
+
+func (s *budgetOverrideStore) SetBudgetOverride(ctx context.Context, override *repository.LLMBudgetOverride) error {
+	s.overrides[override.UserID] = override
+	return nil
+}
+
+// recordingBudgetLimiter 记录应用的每用户上限
+type recordingBudgetLimiter struct {
+	limits map[int64]float64
+}
+
+func (l *recordingBudgetLimiter) SetUserLimit(userID int64, limit float64, expiresAt time.Time) {
+	l.limits[userID] = limit
+}
+
+func TestBudgetRaise_Validate(t *testing.T) {
+	assert.NoError(t, (&BudgetRaise{DailyLimit: 20, Days: 7}).Validate(10))
+	assert.ErrorIs(t, (&BudgetRaise{DailyLimit: 10, Days: 7}).Validate(10), repository.ErrInvalidInput, "必须高于当前上限")
+	assert.ErrorIs(t, (&BudgetRaise{DailyLimit: 20, Days: 0}).Validate(10), repository.ErrInvalidInput)
+	assert.ErrorIs(t, (&BudgetRaise{DailyLimit: 20, Days: MaxBudgetRaiseDays + 1}).Validate(10), repository.ErrInvalidInput)
+	assert.ErrorIs(t, (&BudgetRaise{DailyLimit: 20, Days: 7}).Validate(0), repository.ErrInvalidInput, "不限额时无需上调")
+}
+
+func TestBudgetRaiseExecutor_AppliesOnApproval(t *testing.T) {
+	ctx := context.Background()
+	engine, _ := newApprovalTestEngine(t)
+	store := &budgetOverrideStore{overrides: map[int64]*repository.LLMBudgetOverride{}}
+	limiter := &recordingBudgetLimiter{limits: map[int64]float64{}}
+	executor := NewBudgetRaiseExecutor(store, limiter, zaptest.NewLogger(t))
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	executor.now = func() time.Time { return now }
+	engine.Register(repository.ApprovalBudgetRaise, executor)
+
+	req, err := engine.Submit(ctx, &ApprovalSubmission{
+		ActionType:  repository.ApprovalBudgetRaise,
+		Summary:     "每日预算上调至25美元，7天",
+		Payload:     &BudgetRaise{DailyLimit: 25, Days: 7, Reason: "季度报表"},
+		RequestedBy: 7,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, limiter.limits, "审批通过前不生效")
+
+	approved, err := engine.Approve(ctx, 9, "manager", req.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.ApprovalExecuted), approved.Status)
+
+	override := store.overrides[7]
+	require.NotNil(t, override)
+	assert.Equal(t, 25.0, override.DailyLimit)
+	assert.Equal(t, now.Add(7*24*time.Hour), override.ExpiresAt)
+	assert.Equal(t, req.ID, *override.ApprovalID)
+	assert.Equal(t, 25.0, limiter.limits[7])
+}
@@ -0,0 +1,289 @@
+// 仪表盘查询缓存预热
+// 标记为仪表盘使用的保存查询在结果缓存过期前由后台任务重新执行并写入缓存，仪表盘加载时总能命中缓存。
+// 预热按查询自身的缓存时间调度，经过RefreshRatio比例后即重新执行；每个查询的预热次数、耗时、
+// 最近成功时间与连续失败次数以Prometheus指标暴露，连续失败达到AlertAfter次时记录告警日志
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// cacheWarmingExecutor 预热使用的SQL执行接口，由SQLExecutor实现
+type cacheWarmingExecutor interface {
+	ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error)
+}
+
+// CacheWarmingResult 一轮缓存预热的结果
+type CacheWarmingResult struct {
+	Queries int `json:"queries"` // 仪表盘使用的保存查询数
+	Warmed  int `json:"warmed"`  // 预热成功的查询数
+	Failed  int `json:"failed"`  // 预热失败的查询数
+}
+
+// cacheWarmingState 单个保存查询的预热状态
+type cacheWarmingState struct {
+	sql          string // 上次预热的SQL与连接，查询被修改后立即预热新的SQL
+	connectionID int64
+	nextRun      time.Time
+	failures     int // 连续失败次数
+}
+
+// CacheWarmer 仪表盘查询缓存预热服务
+type CacheWarmer struct {
+	queries     repository.SavedQueryRepository
+	connections repository.ConnectionRepository
+	executor    cacheWarmingExecutor
+	cache       *QueryResultCache
+	config      *config.CacheWarmingConfig
+	logger      *zap.Logger
+	maintenance *MaintenanceMode // 维护期间暂停预热，为nil时不检查
+	now         func() time.Time
+
+	runsTotal           *prometheus.CounterVec
+	duration            *prometheus.GaugeVec
+	lastSuccess         *prometheus.GaugeVec
+	consecutiveFailures *prometheus.GaugeVec
+
+	mu     sync.Mutex // 看门狗重启任务时新旧实例可能短暂并存
+	states map[int64]*cacheWarmingState
+}
+
+// NewCacheWarmer 创建缓存预热服务，配置为nil时使用默认配置，registerer为nil时不注册Prometheus指标
+func NewCacheWarmer(
+	queries repository.SavedQueryRepository,
+	connections repository.ConnectionRepository,
+	executor cacheWarmingExecutor,
+	cache *QueryResultCache,
+	warmingConfig *config.CacheWarmingConfig,
+	registerer prometheus.Registerer,
+	logger *zap.Logger,
+) *CacheWarmer {
+	if warmingConfig == nil {
+		warmingConfig = config.DefaultCacheWarmingConfig()
+	}
+
+	w := &CacheWarmer{
+		queries:     queries,
+		connections: connections,
+		executor:    executor,
+		cache:       cache,
+		config:      warmingConfig,
+		logger:      logger,
+		now:         time.Now,
+		states:      make(map[int64]*cacheWarmingState),
+		runsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chat2sql",
+			Subsystem: "cache_warming",
+			Name:      "runs_total",
+			Help:      "Number of cache warming runs per dashboard-backed saved query",
+		}, []string{"saved_query_id", "status"}),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "chat2sql",
+			Subsystem: "cache_warming",
+			Name:      "last_duration_seconds",
+			Help:      "Duration of the last cache warming run per saved query",
+		}, []string{"saved_query_id"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "chat2sql",
+			Subsystem: "cache_warming",
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful cache warming run per saved query",
+		}, []string{"saved_query_id"}),
+		consecutiveFailures: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "chat2sql",
+			Subsystem: "cache_warming",
+			Name:      "consecutive_failures",
+			Help:      "Number of consecutive failed cache warming runs per saved query",
+		}, []string{"saved_query_id"}),
+	}
+	if registerer != nil {
+		registerer.MustRegister(w.runsTotal, w.duration, w.lastSuccess, w.consecutiveFailures)
+	}
+	return w
+}
+
+// SetMaintenanceMode 设置维护模式开关，维护期间不执行预热查询
+func (w *CacheWarmer) SetMaintenanceMode(maintenance *MaintenanceMode) {
+	w.maintenance = maintenance
+}
+
+// Run 每隔CheckInterval预热到期的查询，直到ctx取消；由看门狗托管，每预热一个查询调用beat上报心跳
+func (w *CacheWarmer) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(w.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := w.sweep(ctx, beat); err != nil {
+			w.logger.Warn("Failed to warm dashboard query caches", zap.Error(err))
+		} else if result.Warmed > 0 || result.Failed > 0 {
+			w.logger.Debug("Warmed dashboard query caches",
+				zap.Int("queries", result.Queries),
+				zap.Int("warmed", result.Warmed),
+				zap.Int("failed", result.Failed))
+		}
+		beat()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sweep 预热一轮到期的查询，单个查询失败只记录日志与指标，到下一次调度时重试
+func (w *CacheWarmer) Sweep(ctx context.Context) (*CacheWarmingResult, error) {
+	return w.sweep(ctx, func() {})
+}
+
+func (w *CacheWarmer) sweep(ctx context.Context, beat func()) (*CacheWarmingResult, error) {
+	if w.maintenance.Active() {
+		return &CacheWarmingResult{}, nil
+	}
+	queries, err := w.queries.ListDashboardBacked(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	result := &CacheWarmingResult{Queries: len(queries)}
+	listed := make(map[int64]bool, len(queries))
+	for _, query := range queries {
+		if query.ConnectionID == nil {
+			continue
+		}
+		listed[query.ID] = true
+
+		state, ok := w.states[query.ID]
+		if !ok || state.sql != query.SQL || state.connectionID != *query.ConnectionID {
+			state = &cacheWarmingState{sql: query.SQL, connectionID: *query.ConnectionID, failures: stateFailures(state)}
+			w.states[query.ID] = state
+		}
+		if w.now().Before(state.nextRun) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		ttl, err := w.warm(ctx, query)
+		beat()
+		state.nextRun = w.now().Add(time.Duration(float64(ttl) * w.config.RefreshRatio))
+		if err != nil {
+			result.Failed++
+			w.recordFailure(query, state, err)
+		} else {
+			result.Warmed++
+			w.recordSuccess(query, state)
+		}
+	}
+
+	// 取消标记或已删除的查询不再预热，清除其状态与指标
+	for id := range w.states {
+		if !listed[id] {
+			delete(w.states, id)
+			w.deleteMetrics(id)
+		}
+	}
+	return result, nil
+}
+
+// warm 执行保存查询并写入结果缓存，返回该查询的缓存时间
+// 以查询所有者的身份执行，不设置返回行数上限，与仪表盘不带row_limit执行同一SQL时使用同一缓存项
+func (w *CacheWarmer) warm(ctx context.Context, query *repository.SavedQuery) (time.Duration, error) {
+	opts := ResultCacheOptions{NoCache: true}
+	if query.CacheTTLSeconds != nil {
+		opts.TTL = time.Duration(*query.CacheTTLSeconds) * time.Second
+	}
+	ttl := w.cache.ttl(opts)
+	label := strconv.FormatInt(query.ID, 10)
+
+	start := w.now()
+	defer func() { w.duration.WithLabelValues(label).Set(w.now().Sub(start).Seconds()) }()
+
+	connection, err := w.connections.GetByID(ctx, *query.ConnectionID)
+	if err != nil {
+		return ttl, fmt.Errorf("获取连接失败: %w", err)
+	}
+	if !connection.IsConnectionHealthy() {
+		return ttl, fmt.Errorf("连接%d不可用，状态: %s", connection.ID, connection.Status)
+	}
+
+	// 缓存的是原始结果，命中时按当前用户应用列策略
+	result, err := w.executor.ExecuteQuery(withoutRealtimeEvents(WithUnfilteredResult(WithQueryOwner(ctx, query.OwnerID))), query.SQL, connection)
+	if err != nil {
+		return ttl, err
+	}
+	if result.Status != string(repository.QuerySuccess) {
+		return ttl, fmt.Errorf("查询执行失败: %s", result.Error)
+	}
+	if !w.cache.Store(ctx, connection.ID, query.SQL, result, opts) {
+		return ttl, fmt.Errorf("结果未写入缓存，%d行可能超过缓存行数或大小上限", result.RowCount)
+	}
+	return ttl, nil
+}
+
+// recordSuccess 记录预热成功，之前已告警的查询记录恢复日志
+func (w *CacheWarmer) recordSuccess(query *repository.SavedQuery, state *cacheWarmingState) {
+	label := strconv.FormatInt(query.ID, 10)
+	w.runsTotal.WithLabelValues(label, "success").Inc()
+	w.lastSuccess.WithLabelValues(label).Set(float64(w.now().Unix()))
+	w.consecutiveFailures.WithLabelValues(label).Set(0)
+
+	if state.failures >= w.config.AlertAfter {
+		w.logger.Info("Dashboard query cache warming recovered",
+			zap.Int64("saved_query_id", query.ID),
+			zap.Int("failures", state.failures))
+	}
+	state.failures = 0
+}
+
+// recordFailure 记录预热失败，连续失败达到AlertAfter次时记录告警
+func (w *CacheWarmer) recordFailure(query *repository.SavedQuery, state *cacheWarmingState, err error) {
+	label := strconv.FormatInt(query.ID, 10)
+	state.failures++
+	w.runsTotal.WithLabelValues(label, "error").Inc()
+	w.consecutiveFailures.WithLabelValues(label).Set(float64(state.failures))
+
+	fields := []zap.Field{
+		zap.Int64("saved_query_id", query.ID),
+		zap.Int64("workspace_id", query.WorkspaceID),
+		zap.Int64("connection_id", *query.ConnectionID),
+		zap.Int("failures", state.failures),
+		zap.Error(err),
+	}
+	if state.failures == w.config.AlertAfter {
+		w.logger.Error("Dashboard query cache warming keeps failing, dashboard loads will miss the cache", fields...)
+		return
+	}
+	w.logger.Warn("Failed to warm dashboard query cache", fields...)
+}
+
+// deleteMetrics 删除不再预热的查询的指标
+func (w *CacheWarmer) deleteMetrics(id int64) {
+	label := strconv.FormatInt(id, 10)
+	w.runsTotal.DeletePartialMatch(prometheus.Labels{"saved_query_id": label})
+	w.duration.DeleteLabelValues(label)
+	w.lastSuccess.DeleteLabelValues(label)
+	w.consecutiveFailures.DeleteLabelValues(label)
+}
+
+// stateFailures 查询被修改后保留连续失败次数，修改后首次预热成功才清零
+func stateFailures(state *cacheWarmingState) int {
+	if state == nil {
+		return 0
+	}
+	return state.failures
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// warmingExecutor 记录执行的SQL与发起用户，failing中的SQL执行失败
+type warmingExecutor struct {
+	executed []string
+	owners   []int64
+	failing  map[string]bool
+}
+
+func (e *warmingExecutor) ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error) {
+	e.executed = append(e.executed, sql)
+	e.owners = append(e.owners, queryOwnerFromContext(ctx))
+	if e.failing[sql] {
+		return nil, errors.New("connection reset")
+	}
+	return &QueryResult{Columns: []string{"n"}, Rows: []map[string]any{{"n": 1}}, RowCount: 1, Status: string(repository.QuerySuccess)}, nil
+}
+
+func TestCacheWarmer_WarmsBeforeExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	connection := &repository.DatabaseConnection{Status: string(repository.ConnectionActive)}
+	connection.ID = 7
+	ttl := int32(60)
+	queries := &memSavedQueryRepository{queries: map[int64]*repository.SavedQuery{}}
+	for _, q := range []*repository.SavedQuery{
+		{OwnerID: 3, SQL: "SELECT count(*) FROM orders", ConnectionID: &connection.ID, DashboardBacked: true, CacheTTLSeconds: &ttl},
+		{OwnerID: 4, SQL: "SELECT sum(amount) FROM orders", ConnectionID: &connection.ID, DashboardBacked: true},
+		{OwnerID: 4, SQL: "SELECT * FROM users", ConnectionID: &connection.ID},
+	} {
+		require.NoError(t, queries.Create(ctx, q))
+	}
+
+	store := newMemResultCacheStore()
+	cache := newQueryResultCache(store, config.DefaultResultCacheConfig(), zaptest.NewLogger(t))
+	cache.now = clock
+	executor := &warmingExecutor{failing: map[string]bool{}}
+	warmer := NewCacheWarmer(queries, &stubConnectionRepository{connection: connection}, executor, cache,
+		config.DefaultCacheWarmingConfig(), prometheus.NewRegistry(), zaptest.NewLogger(t))
+	warmer.now = clock
+
+	result, err := warmer.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &CacheWarmingResult{Queries: 2, Warmed: 2}, result, "未标记为仪表盘的查询不预热")
+	assert.Equal(t, []int64{3, 4}, executor.owners, "以所有者身份执行")
+	_, ok := cache.Lookup(ctx, connection.ID, "SELECT count(*) FROM orders", ResultCacheOptions{})
+	assert.True(t, ok)
+	assert.Equal(t, 60*time.Second, store.ttls[cache.entryKey(ctx, connection.ID, "SELECT count(*) FROM orders")])
+	assert.Equal(t, 5*time.Minute, store.ttls[cache.entryKey(ctx, connection.ID, "SELECT sum(amount) FROM orders")])
+
+	now = now.Add(30 * time.Second)
+	result, err = warmer.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, result.Warmed, "缓存时间经过刷新比例前不重复执行")
+
+	now = now.Add(20 * time.Second)
+	result, err = warmer.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Warmed, "60秒缓存的查询在48秒后、过期前预热")
+	assert.Equal(t, "SELECT count(*) FROM orders", executor.executed[len(executor.executed)-1])
+
+	// 修改SQL后立即预热新的SQL
+	queries.queries[2].SQL = "SELECT sum(amount) FROM orders WHERE paid"
+	result, err = warmer.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Warmed)
+	assert.Equal(t, "SELECT sum(amount) FROM orders WHERE paid", executor.executed[len(executor.executed)-1])
+}
+
+func TestCacheWarmer_FailureMetricsAndCleanup(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	connection := &repository.DatabaseConnection{Status: string(repository.ConnectionActive)}
+	connection.ID = 7
+	queries := &memSavedQueryRepository{queries: map[int64]*repository.SavedQuery{}}
+	query := &repository.SavedQuery{OwnerID: 3, SQL: "SELECT 1", ConnectionID: &connection.ID, DashboardBacked: true}
+	require.NoError(t, queries.Create(ctx, query))
+
+	cache := newQueryResultCache(newMemResultCacheStore(), config.DefaultResultCacheConfig(), zaptest.NewLogger(t))
+	executor := &warmingExecutor{failing: map[string]bool{"SELECT 1": true}}
+	cfg := config.DefaultCacheWarmingConfig()
+	cfg.AlertAfter = 2
+	registry := prometheus.NewRegistry()
+	warmer := NewCacheWarmer(queries, &stubConnectionRepository{connection: connection}, executor, cache, cfg, registry, zaptest.NewLogger(t))
+	warmer.now = clock
+
+	for i := 0; i < 2; i++ {
+		result, err := warmer.Sweep(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Failed)
+		now = now.Add(5 * time.Minute)
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(warmer.consecutiveFailures.WithLabelValues("1")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(warmer.runsTotal.WithLabelValues("1", "error")))
+
+	executor.failing["SELECT 1"] = false
+	result, err := warmer.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Warmed)
+	assert.Zero(t, testutil.ToFloat64(warmer.consecutiveFailures.WithLabelValues("1")))
+	assert.Equal(t, float64(now.Unix()), testutil.ToFloat64(warmer.lastSuccess.WithLabelValues("1")))
+
+	// 停用的连接不执行查询
+	connection.Status = string(repository.ConnectionInactive)
+	now = now.Add(5 * time.Minute)
+	result, err = warmer.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+	assert.Len(t, executor.executed, 3)
+
+	// 取消标记后清除状态与指标
+	query.DashboardBacked = false
+	_, err = warmer.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, warmer.states)
+	count, err := testutil.GatherAndCount(registry, "chat2sql_cache_warming_consecutive_failures")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
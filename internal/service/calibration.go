@@ -0,0 +1,255 @@
+// 置信度校准统计
+// 按置信度分桶比较预测置信度与用户反馈的实际正确率（可靠性图数据），
+// 分别统计意图分类置信度与SQL生成置信度，为确认阈值、自动执行阈值的调整提供依据
+package service
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"chat2sql-go/internal/config"
+)
+
+// 参与校准统计的置信度
+const (
+	CalibrationSignalClassifier = "classifier" // 意图分类置信度
+	CalibrationSignalGeneration = "generation" // 合成后的SQL生成置信度
+)
+
+// calibrationSignals 报告中置信度的输出顺序
+var calibrationSignals = []string{CalibrationSignalClassifier, CalibrationSignalGeneration}
+
+// calibrationSample 一次带反馈的预测
+type calibrationSample struct {
+	confidence float64
+	correct    bool
+}
+
+// pendingPrediction 等待用户反馈的预测，缺失的置信度为nil
+type pendingPrediction struct {
+	at         time.Time
+	classifier *float64
+	generation float64
+}
+
+// CalibrationBucket 可靠性图中的一个置信度区间
+// 区间为[Lower, Upper)，最后一个区间包含1；没有样本时均值与正确率为空
+type CalibrationBucket struct {
+	Lower            float64  `json:"lower"`
+	Upper            float64  `json:"upper"`
+	Samples          int      `json:"samples"`
+	MeanConfidence   *float64 `json:"mean_confidence,omitempty"`   // 区间内预测置信度的均值
+	ObservedAccuracy *float64 `json:"observed_accuracy,omitempty"` // 区间内反馈为正确的比例
+	Gap              *float64 `json:"gap,omitempty"`               // 置信度均值减实际正确率，正数表示过于自信
+}
+
+// SignalCalibration 单类置信度的校准情况，没有样本时各项误差为空
+type SignalCalibration struct {
+	Signal                   string              `json:"signal"`
+	Samples                  int                 `json:"samples"`
+	MeanConfidence           *float64            `json:"mean_confidence,omitempty"`
+	ObservedAccuracy         *float64            `json:"observed_accuracy,omitempty"`
+	ExpectedCalibrationError *float64            `json:"expected_calibration_error,omitempty"` // 按样本数加权的各区间|Gap|之和
+	MaxCalibrationError      *float64            `json:"max_calibration_error,omitempty"`      // 有样本区间中最大的|Gap|
+	BrierScore               *float64            `json:"brier_score,omitempty"`                // 置信度与0/1结果差的平方均值
+	Buckets                  []CalibrationBucket `json:"buckets"`
+}
+
+// CalibrationReport 置信度校准报告
+type CalibrationReport struct {
+	Buckets            int                 `json:"buckets"`
+	PendingPredictions int                 `json:"pending_predictions"` // 尚未收到反馈的预测数
+	Signals            []SignalCalibration `json:"signals"`
+	GeneratedAt        time.Time           `json:"generated_at"`
+}
+
+// CalibrationTracker 按查询ID配对预测置信度与用户反馈，统计置信度校准情况
+// 数据只保存在内存中，每类置信度保留最近MaxSamples个样本
+type CalibrationTracker struct {
+	config *config.CalibrationConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*pendingPrediction  // 查询ID -> 等待反馈的预测
+	order   []string                       // 按记录顺序的查询ID，用于淘汰过期与超量的预测
+	samples map[string][]calibrationSample // 置信度 -> 按反馈顺序的样本
+}
+
+// NewCalibrationTracker 创建置信度校准统计，配置为nil时使用默认配置
+func NewCalibrationTracker(calibrationConfig *config.CalibrationConfig) *CalibrationTracker {
+	if calibrationConfig == nil {
+		calibrationConfig = config.DefaultCalibrationConfig()
+	}
+	return &CalibrationTracker{
+		config:  calibrationConfig,
+		now:     time.Now,
+		pending: make(map[string]*pendingPrediction),
+		samples: make(map[string][]calibrationSample),
+	}
+}
+
+// RecordPrediction 记录一次SQL生成的置信度，等待该查询的用户反馈
+// 置信度构成中没有意图分类置信度时只统计生成置信度
+func (t *CalibrationTracker) RecordPrediction(queryID string, confidence float64, breakdown *ConfidenceBreakdown) {
+	prediction := &pendingPrediction{at: t.now(), generation: clampUnit(confidence)}
+	if breakdown != nil && breakdown.Classifier != nil {
+		classifier := clampUnit(*breakdown.Classifier)
+		prediction.classifier = &classifier
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.pending[queryID]; !exists {
+		t.order = append(t.order, queryID)
+	}
+	t.pending[queryID] = prediction
+	t.prune()
+}
+
+// RecordOutcome 记录用户对查询的反馈，与等待中的预测配对为样本
+// 返回是否找到对应的预测；同一查询只计入第一次反馈
+func (t *CalibrationTracker) RecordOutcome(queryID string, correct bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune()
+	prediction, ok := t.pending[queryID]
+	if !ok {
+		return false
+	}
+	delete(t.pending, queryID)
+
+	if prediction.classifier != nil {
+		t.addSample(CalibrationSignalClassifier, calibrationSample{confidence: *prediction.classifier, correct: correct})
+	}
+	t.addSample(CalibrationSignalGeneration, calibrationSample{confidence: prediction.generation, correct: correct})
+	return true
+}
+
+// Report 生成置信度校准报告，buckets不大于0时使用配置的分桶数
+func (t *CalibrationTracker) Report(buckets int) *CalibrationReport {
+	if buckets <= 0 {
+		buckets = t.config.Buckets
+	}
+
+	t.mu.Lock()
+	t.prune()
+	pending := len(t.pending)
+	samples := make(map[string][]calibrationSample, len(t.samples))
+	for signal, signalSamples := range t.samples {
+		samples[signal] = append([]calibrationSample(nil), signalSamples...)
+	}
+	t.mu.Unlock()
+
+	report := &CalibrationReport{
+		Buckets:            buckets,
+		PendingPredictions: pending,
+		GeneratedAt:        t.now(),
+	}
+	for _, signal := range calibrationSignals {
+		report.Signals = append(report.Signals, calibrate(signal, samples[signal], buckets))
+	}
+	return report
+}
+
+// addSample 追加样本并丢弃超出上限的最早样本，调用方需持有锁
+func (t *CalibrationTracker) addSample(signal string, sample calibrationSample) {
+	samples := append(t.samples[signal], sample)
+	if len(samples) > t.config.MaxSamples {
+		samples = samples[len(samples)-t.config.MaxSamples:]
+	}
+	t.samples[signal] = samples
+}
+
+// prune 淘汰等待超过PendingTTL或超出数量上限的最早预测，调用方需持有锁
+func (t *CalibrationTracker) prune() {
+	cutoff := t.now().Add(-t.config.PendingTTL)
+	drop := 0
+	for _, queryID := range t.order {
+		prediction, ok := t.pending[queryID]
+		switch {
+		case !ok:
+			// 已收到反馈
+		case prediction.at.Before(cutoff) || len(t.pending) > t.config.MaxSamples:
+			delete(t.pending, queryID)
+		default:
+			t.order = t.order[drop:]
+			return
+		}
+		drop++
+	}
+	t.order = t.order[:0]
+}
+
+// calibrate 将样本按置信度分桶，计算各区间与整体的校准误差
+func calibrate(signal string, samples []calibrationSample, buckets int) SignalCalibration {
+	result := SignalCalibration{
+		Signal:  signal,
+		Samples: len(samples),
+		Buckets: make([]CalibrationBucket, buckets),
+	}
+
+	confidenceSums := make([]float64, buckets)
+	correctCounts := make([]int, buckets)
+	var confidenceSum, brierSum float64
+	var correct int
+	for _, sample := range samples {
+		index := int(sample.confidence * float64(buckets))
+		if index >= buckets {
+			index = buckets - 1
+		}
+		result.Buckets[index].Samples++
+		confidenceSums[index] += sample.confidence
+		confidenceSum += sample.confidence
+
+		outcome := 0.0
+		if sample.correct {
+			outcome = 1
+			correctCounts[index]++
+			correct++
+		}
+		brierSum += (sample.confidence - outcome) * (sample.confidence - outcome)
+	}
+
+	var ece, mce float64
+	for i := range result.Buckets {
+		bucket := &result.Buckets[i]
+		bucket.Lower = roundRatio(float64(i) / float64(buckets))
+		bucket.Upper = roundRatio(float64(i+1) / float64(buckets))
+		if bucket.Samples == 0 {
+			continue
+		}
+		mean := confidenceSums[i] / float64(bucket.Samples)
+		accuracy := float64(correctCounts[i]) / float64(bucket.Samples)
+		gap := mean - accuracy
+		bucket.MeanConfidence = ratioPtr(mean)
+		bucket.ObservedAccuracy = ratioPtr(accuracy)
+		bucket.Gap = ratioPtr(gap)
+
+		ece += math.Abs(gap) * float64(bucket.Samples) / float64(len(samples))
+		mce = math.Max(mce, math.Abs(gap))
+	}
+
+	if len(samples) > 0 {
+		total := float64(len(samples))
+		result.MeanConfidence = ratioPtr(confidenceSum / total)
+		result.ObservedAccuracy = ratioPtr(float64(correct) / total)
+		result.ExpectedCalibrationError = ratioPtr(ece)
+		result.MaxCalibrationError = ratioPtr(mce)
+		result.BrierScore = ratioPtr(brierSum / total)
+	}
+	return result
+}
+
+// roundRatio 保留4位小数
+func roundRatio(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+// ratioPtr 保留4位小数后返回指针
+func ratioPtr(v float64) *float64 {
+	rounded := roundRatio(v)
+	return &rounded
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestCalibrationTracker_Report(t *testing.T) {
+	tracker := NewCalibrationTracker(nil)
+
+	classifier := 0.95
+	// 生成置信度0.9的10次预测中只有6次正确，0.3的10次中有3次正确
+	for i := 0; i < 10; i++ {
+		tracker.RecordPrediction(fmt.Sprintf("high-%d", i), 0.9, &ConfidenceBreakdown{Heuristic: 1, Classifier: &classifier})
+		require.True(t, tracker.RecordOutcome(fmt.Sprintf("high-%d", i), i < 6))
+		tracker.RecordPrediction(fmt.Sprintf("low-%d", i), 0.3, &ConfidenceBreakdown{Heuristic: 0.3})
+		require.True(t, tracker.RecordOutcome(fmt.Sprintf("low-%d", i), i < 3))
+	}
+	tracker.RecordPrediction("unanswered", 0.5, nil)
+
+	report := tracker.Report(0)
+	assert.Equal(t, 10, report.Buckets)
+	assert.Equal(t, 1, report.PendingPredictions)
+	require.Len(t, report.Signals, 2)
+
+	classifierCalibration := report.Signals[0]
+	assert.Equal(t, CalibrationSignalClassifier, classifierCalibration.Signal)
+	assert.Equal(t, 10, classifierCalibration.Samples, "没有分类置信度的预测不计入")
+	assert.InDelta(t, 0.35, *classifierCalibration.ExpectedCalibrationError, 1e-9)
+
+	generation := report.Signals[1]
+	assert.Equal(t, CalibrationSignalGeneration, generation.Signal)
+	assert.Equal(t, 20, generation.Samples)
+	require.Len(t, generation.Buckets, 10)
+
+	high := generation.Buckets[9]
+	assert.Equal(t, 0.9, high.Lower)
+	assert.Equal(t, 1.0, high.Upper)
+	assert.Equal(t, 10, high.Samples)
+	assert.InDelta(t, 0.9, *high.MeanConfidence, 1e-9)
+	assert.InDelta(t, 0.6, *high.ObservedAccuracy, 1e-9)
+	assert.InDelta(t, 0.3, *high.Gap, 1e-9, "过于自信")
+
+	low := generation.Buckets[3]
+	assert.Equal(t, 10, low.Samples)
+	assert.InDelta(t, 0.0, *low.Gap, 1e-9)
+	assert.Nil(t, generation.Buckets[5].ObservedAccuracy, "没有样本的区间")
+
+	assert.InDelta(t, 0.15, *generation.ExpectedCalibrationError, 1e-9)
+	assert.InDelta(t, 0.3, *generation.MaxCalibrationError, 1e-9)
+	assert.InDelta(t, 0.45, *generation.ObservedAccuracy, 1e-9)
+	// (6×0.01 + 4×0.81 + 3×0.49 + 7×0.09) / 20
+	assert.InDelta(t, 0.27, *generation.BrierScore, 1e-9)
+
+	coarse := tracker.Report(2)
+	assert.Equal(t, 10, coarse.Signals[1].Buckets[0].Samples)
+	assert.Equal(t, 10, coarse.Signals[1].Buckets[1].Samples)
+}
+
+func TestCalibrationTracker_RecordOutcome(t *testing.T) {
+	cfg := config.DefaultCalibrationConfig()
+	cfg.MaxSamples = 3
+	cfg.PendingTTL = time.Hour
+	tracker := NewCalibrationTracker(cfg)
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	assert.False(t, tracker.RecordOutcome("unknown", true), "没有对应的预测")
+
+	tracker.RecordPrediction("q1", 0.8, nil)
+	assert.True(t, tracker.RecordOutcome("q1", true))
+	assert.False(t, tracker.RecordOutcome("q1", false), "同一查询只计入第一次反馈")
+
+	// 等待超过PendingTTL的预测被丢弃
+	tracker.RecordPrediction("stale", 0.8, nil)
+	now = now.Add(2 * time.Hour)
+	assert.False(t, tracker.RecordOutcome("stale", true))
+
+	// 等待中的预测超过上限时淘汰最早的
+	for i := 0; i < 4; i++ {
+		tracker.RecordPrediction(fmt.Sprintf("q%d", i+10), 0.5, nil)
+	}
+	assert.Equal(t, 3, tracker.Report(0).PendingPredictions)
+	assert.False(t, tracker.RecordOutcome("q10", true))
+	assert.True(t, tracker.RecordOutcome("q13", false))
+
+	// 样本只保留最近MaxSamples个
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("s%d", i)
+		tracker.RecordPrediction(id, 0.1, nil)
+		tracker.RecordOutcome(id, false)
+	}
+	assert.Equal(t, 3, tracker.Report(0).Signals[1].Samples)
+}
@@ -0,0 +1,325 @@
+// 多候选SQL生成与排序
+// 以不同模型/温度生成多条候选SQL，按校验得分、预估代价与历史成功查询相似度综合排序，
+// 返回最优候选并附带备选供用户选择
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/tmc/langchaingo/llms"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/tracing"
+)
+
+// 候选排序权重，缺失的信号不参与计算，其余权重按比例归一
+const (
+	candidateValidatorWeight = 0.5
+	candidateCostWeight      = 0.2
+	candidateHistoryWeight   = 0.3
+)
+
+const (
+	candidateCostScale       = 1000.0 // 代价达到该值时代价得分为0.5
+	candidateTemperatureStep = 0.3    // 同一模型的后续候选逐步提高温度
+	candidateHistoryLimit    = 200    // 参与相似度比较的历史查询数
+)
+
+// SQLCostEstimator 按连接估算SQL执行代价
+type SQLCostEstimator interface {
+	EstimateCostByID(ctx context.Context, sql string, connectionID int64) (float64, error)
+}
+
+// SQLCandidate 候选SQL及其排序依据
+type SQLCandidate struct {
+	SQL               string   `json:"sql"`
+	Model             string   `json:"model"`
+	Temperature       float64  `json:"temperature"`
+	Score             float64  `json:"score"`                        // 综合得分(0-1)
+	ValidatorScore    float64  `json:"validator_score"`              // 安全校验与表结构匹配得分
+	EstimatedCost     *float64 `json:"estimated_cost,omitempty"`     // EXPLAIN估算代价
+	HistorySimilarity *float64 `json:"history_similarity,omitempty"` // 与历史成功查询的最大相似度
+	Errors            []string `json:"errors,omitempty"`
+
+	choice     *llms.ContentChoice   // 原始模型输出，用于计算置信度
+	generation *GenerationParameters // 生成该候选实际使用的参数
+}
+
+// candidateSpec 单个候选的模型与温度
+type candidateSpec struct {
+	client      llms.Model
+	config      config.ModelConfig
+	model       string
+	temperature float64
+}
+
+// CandidateRanker 候选SQL排序器
+// estimator与history均可为空，此时对应信号不参与排序
+type CandidateRanker struct {
+	validator *SQLSecurityValidator
+	estimator SQLCostEstimator
+	history   repository.QueryHistoryRepository
+	logger    *zap.Logger
+}
+
+// NewCandidateRanker 创建候选SQL排序器
+func NewCandidateRanker(estimator SQLCostEstimator, history repository.QueryHistoryRepository, logger *zap.Logger) *CandidateRanker {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &CandidateRanker{
+		validator: NewSQLSecurityValidator(logger),
+		estimator: estimator,
+		history:   history,
+		logger:    logger,
+	}
+}
+
+// Rank 计算各候选得分并按得分降序排列，得分相同时保持生成顺序
+func (r *CandidateRanker) Rank(ctx context.Context, req *SQLGenerationRequest, candidates []*SQLCandidate) {
+	historical := r.successfulSQL(ctx, req.ConnectionID)
+
+	for _, candidate := range candidates {
+		candidate.ValidatorScore, candidate.Errors = r.validatorScore(candidate.SQL, req.Schema)
+
+		if r.estimator != nil && req.ConnectionID > 0 && candidate.ValidatorScore > 0 {
+			if cost, err := r.estimator.EstimateCostByID(ctx, candidate.SQL, req.ConnectionID); err == nil {
+				candidate.EstimatedCost = &cost
+			} else {
+				candidate.Errors = append(candidate.Errors, err.Error())
+			}
+		}
+
+		if len(historical) > 0 {
+			similarity := maxSQLSimilarity(candidate.SQL, historical)
+			candidate.HistorySimilarity = &similarity
+		}
+
+		candidate.Score = candidate.combine()
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+}
+
+// combine 按可用信号的归一化权重合成得分，校验未通过的候选得分为0
+func (c *SQLCandidate) combine() float64 {
+	if c.ValidatorScore <= 0 {
+		return 0
+	}
+
+	total := candidateValidatorWeight * c.ValidatorScore
+	weights := candidateValidatorWeight
+	if c.EstimatedCost != nil {
+		total += candidateCostWeight * (1 / (1 + math.Max(*c.EstimatedCost, 0)/candidateCostScale))
+		weights += candidateCostWeight
+	}
+	if c.HistorySimilarity != nil {
+		total += candidateHistoryWeight * *c.HistorySimilarity
+		weights += candidateHistoryWeight
+	}
+	return math.Round(total/weights*1000) / 1000
+}
+
+// validatorScore 安全校验不通过或非只读为0；引用了表结构中不存在的表时按比例扣分
+func (r *CandidateRanker) validatorScore(sql, schema string) (float64, []string) {
+	result := r.validator.ValidateSQL(sql)
+	if !result.IsValid {
+		return 0, result.Errors
+	}
+	if !result.IsReadOnly {
+		return 0, []string{"候选SQL不是只读查询"}
+	}
+
+	score := 1.0
+	var problems []string
+	if schema != "" && len(result.TablesUsed) > 0 {
+		lowerSchema := strings.ToLower(schema)
+		unknown := 0
+		for _, table := range result.TablesUsed {
+			name := strings.ToLower(table)
+			if idx := strings.LastIndex(name, "."); idx >= 0 {
+				name = name[idx+1:]
+			}
+			if !strings.Contains(lowerSchema, name) {
+				unknown++
+				problems = append(problems, fmt.Sprintf("表%s不在数据库结构中", table))
+			}
+		}
+		score *= 1 - float64(unknown)/float64(len(result.TablesUsed))
+	}
+
+	score -= 0.1 * float64(len(result.Warnings))
+	return clampUnit(score), problems
+}
+
+// successfulSQL 读取连接上历史成功执行的SQL
+func (r *CandidateRanker) successfulSQL(ctx context.Context, connectionID int64) []string {
+	if r.history == nil || connectionID <= 0 {
+		return nil
+	}
+
+	queries, err := r.history.ListByConnection(ctx, connectionID, candidateHistoryLimit, 0)
+	if err != nil {
+		r.logger.Warn("读取历史查询失败", zap.Int64("connection_id", connectionID), zap.Error(err))
+		return nil
+	}
+
+	var sqls []string
+	for _, query := range queries {
+		if query.Status == string(repository.QuerySuccess) && query.GeneratedSQL != "" {
+			sqls = append(sqls, query.GeneratedSQL)
+		}
+	}
+	return sqls
+}
+
+// maxSQLSimilarity 候选与历史SQL词集合的最大Jaccard相似度
+func maxSQLSimilarity(sql string, historical []string) float64 {
+	tokens := sqlTokenSet(sql)
+	best := 0.0
+	for _, other := range historical {
+		if similarity := jaccard(tokens, sqlTokenSet(other)); similarity > best {
+			best = similarity
+		}
+	}
+	return math.Round(best*1000) / 1000
+}
+
+// sqlTokenSet 将SQL拆分为小写的标识符与关键字集合
+func sqlTokenSet(sql string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, token := range strings.FieldsFunc(strings.ToLower(sql), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		set[token] = struct{}{}
+	}
+	return set
+}
+
+// jaccard 集合交并比
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for token := range a {
+		if _, ok := b[token]; ok {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// normalizeCandidateSQL 去除首尾空白、代码块标记与末尾分号，便于去重
+func normalizeCandidateSQL(sql string) string {
+	sql = strings.TrimSpace(sql)
+	sql = strings.TrimPrefix(sql, "```sql")
+	sql = strings.TrimPrefix(sql, "```")
+	sql = strings.TrimSuffix(sql, "```")
+	return strings.TrimSuffix(strings.TrimSpace(sql), ";")
+}
+
+// candidateSpecs 主备模型交替生成，同一模型的后续候选逐步提高温度
+// 按意图类别覆盖了温度时以覆盖值为起点
+func (ai *AIService) candidateSpecs(n int, profile *generationProfile) []candidateSpec {
+	specs := make([]candidateSpec, 0, n)
+	for i := 0; i < n; i++ {
+		cfg, client := ai.config.Primary, ai.primaryClient
+		if i%2 == 1 && ai.fallbackClient != nil {
+			cfg, client = ai.config.Fallback, ai.fallbackClient
+		}
+		cfg = profile.apply(cfg)
+		specs = append(specs, candidateSpec{
+			client:      client,
+			config:      cfg,
+			model:       cfg.ModelName,
+			temperature: math.Min(cfg.Temperature+candidateTemperatureStep*float64(i/2), 1.0),
+		})
+	}
+	return specs
+}
+
+// candidateCount 请求的候选数，受MaxCandidates限制；未配置上限时不启用多候选
+func (ai *AIService) candidateCount(req *SQLGenerationRequest) int {
+	n := req.Candidates
+	if n > ai.config.MaxCandidates {
+		n = ai.config.MaxCandidates
+	}
+	return n
+}
+
+// generateCandidates 并发生成n条候选SQL，去重后排序；全部失败时返回错误
+func (ai *AIService) generateCandidates(ctx context.Context, prompt string, req *SQLGenerationRequest, n int) ([]*SQLCandidate, error) {
+	profile := generationProfileFromContext(ctx)
+	specs := ai.candidateSpecs(n, profile)
+	results := make([]*SQLCandidate, len(specs))
+	errs := make([]error, len(specs))
+
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func(i int, spec candidateSpec) {
+			defer wg.Done()
+			callCtx, span := startLLMSpan(ctx, spec.config, attribute.Float64("gen_ai.request.temperature", spec.temperature))
+			response, err := spec.client.GenerateContent(callCtx,
+				[]llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, prompt)},
+				profile.options(generationOptions(spec.config, spec.temperature))...,
+			)
+			tracing.End(span, err)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if len(response.Choices) == 0 {
+				errs[i] = fmt.Errorf("模型%s未返回内容", spec.model)
+				return
+			}
+			results[i] = &SQLCandidate{
+				SQL:         normalizeCandidateSQL(response.Choices[0].Content),
+				Model:       spec.model,
+				Temperature: spec.temperature,
+				choice:      response.Choices[0],
+				generation:  profile.record(newGenerationParameters(spec.config, spec.temperature)),
+			}
+		}(i, spec)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	var candidates []*SQLCandidate
+	var lastErr error
+	for i, candidate := range results {
+		if candidate == nil {
+			lastErr = errs[i]
+			ai.logger.Warn("候选SQL生成失败", zap.String("model", specs[i].model), zap.Error(errs[i]))
+			continue
+		}
+		key := strings.ToLower(strings.Join(strings.Fields(candidate.SQL), " "))
+		if candidate.SQL == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("所有候选均为空")
+		}
+		return nil, fmt.Errorf("候选SQL全部生成失败: %w", lastErr)
+	}
+
+	ai.candidateRanker.Rank(ctx, req, candidates)
+	return candidates, nil
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// fixedLLM 始终返回固定内容的模型
+type fixedLLM struct {
+	content string
+	err     error
+}
+
+func (f *fixedLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: f.content}}}, nil
+}
+
+func (f *fixedLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, f, prompt, options...)
+}
+
+// stubCostEstimator 按SQL返回固定代价
+type stubCostEstimator map[string]float64
+
+func (s stubCostEstimator) EstimateCostByID(ctx context.Context, sql string, connectionID int64) (float64, error) {
+	if cost, ok := s[sql]; ok {
+		return cost, nil
+	}
+	return 0, errors.New("explain failed")
+}
+
+// stubHistoryRepository 固定的连接查询历史
+type stubHistoryRepository struct {
+	repository.QueryHistoryRepository
+	queries []*repository.QueryHistory
+}
+
+func (s *stubHistoryRepository) ListByConnection(ctx context.Context, connectionID int64, limit, offset int) ([]*repository.QueryHistory, error) {
+	return s.queries, nil
+}
+
+const candidateTestSchema = "users(id, name, email, status)\norders(id, user_id, total_amount)"
+
+func TestCandidateRanker_Rank(t *testing.T) {
+	history := &stubHistoryRepository{queries: []*repository.QueryHistory{
+		{GeneratedSQL: "SELECT name, email FROM users WHERE status = 'active'", Status: string(repository.QuerySuccess)},
+		{GeneratedSQL: "SELECT total_amount FROM orders", Status: string(repository.QueryError)},
+	}}
+	estimator := stubCostEstimator{
+		"SELECT name, email FROM users WHERE status = 'active'": 20,
+		"SELECT * FROM users":             20,
+		"SELECT total_amount FROM orders": 50000,
+	}
+	ranker := NewCandidateRanker(estimator, history, zaptest.NewLogger(t))
+
+	candidates := []*SQLCandidate{
+		{SQL: "SELECT total_amount FROM orders"},
+		{SQL: "SELECT name FROM customers"},
+		{SQL: "DELETE FROM users"},
+		{SQL: "SELECT name, email FROM users WHERE status = 'active'"},
+	}
+	ranker.Rank(context.Background(), &SQLGenerationRequest{ConnectionID: 1, Schema: candidateTestSchema}, candidates)
+
+	assert.Equal(t, "SELECT name, email FROM users WHERE status = 'active'", candidates[0].SQL)
+	assert.InDelta(t, 1.0, *candidates[0].HistorySimilarity, 1e-9)
+	assert.Equal(t, "SELECT total_amount FROM orders", candidates[1].SQL)
+	assert.Less(t, candidates[1].Score, candidates[0].Score)
+
+	// 引用未知表与写操作的候选排在最后且得分为0
+	for _, candidate := range candidates[2:] {
+		assert.Zero(t, candidate.Score, candidate.SQL)
+		assert.NotEmpty(t, candidate.Errors, candidate.SQL)
+		assert.Nil(t, candidate.EstimatedCost, candidate.SQL)
+	}
+}
+
+func TestCandidateRanker_WithoutOptionalSignals(t *testing.T) {
+	ranker := NewCandidateRanker(nil, nil, zaptest.NewLogger(t))
+	candidates := []*SQLCandidate{{SQL: "SELECT id FROM users"}}
+
+	ranker.Rank(context.Background(), &SQLGenerationRequest{Schema: candidateTestSchema}, candidates)
+
+	assert.Equal(t, 1.0, candidates[0].Score)
+	assert.Nil(t, candidates[0].EstimatedCost)
+	assert.Nil(t, candidates[0].HistorySimilarity)
+}
+
+func TestAIService_GenerateSQL_Candidates(t *testing.T) {
+	aiConfig := config.DemoAIConfig("")
+	aiConfig.MaxCandidates = 4
+	primary := &fixedLLM{content: "```sql\nSELECT id, name FROM users;\n```"}
+	fallback := &fixedLLM{content: "SELECT id FROM accounts"}
+	aiService := NewAIServiceWithClients(aiConfig, primary, fallback, zaptest.NewLogger(t))
+
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{
+		Query:      "列出用户",
+		Schema:     candidateTestSchema,
+		Candidates: 4,
+	})
+	require.NoError(t, err)
+
+	// 主备模型各自的重复输出被去重，只剩两条候选
+	assert.Equal(t, "SELECT id, name FROM users", resp.SQL)
+	require.NotNil(t, resp.SelectedCandidate)
+	assert.Equal(t, aiConfig.Primary.ModelName, resp.SelectedCandidate.Model)
+	require.Len(t, resp.Alternates, 1)
+	assert.Equal(t, "SELECT id FROM accounts", resp.Alternates[0].SQL)
+	assert.Greater(t, resp.SelectedCandidate.Score, resp.Alternates[0].Score)
+}
+
+func TestAIService_GenerateSQL_CandidatesCappedAndFailures(t *testing.T) {
+	aiConfig := config.DemoAIConfig("")
+	aiConfig.MaxCandidates = 1
+	primary := &fixedLLM{content: "SELECT id FROM users"}
+	aiService := NewAIServiceWithClients(aiConfig, primary, primary, zaptest.NewLogger(t))
+
+	// 超过上限时退化为单次生成
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "列出用户", Candidates: 3})
+	require.NoError(t, err)
+	assert.Nil(t, resp.SelectedCandidate)
+	assert.Empty(t, resp.Alternates)
+
+	// 全部候选失败时返回错误
+	aiConfig.MaxCandidates = 3
+	broken := &fixedLLM{err: errors.New("unavailable")}
+	aiService = NewAIServiceWithClients(aiConfig, broken, broken, zaptest.NewLogger(t))
+	_, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "列出用户", Candidates: 3})
+	assert.Error(t, err)
+}
+
+func TestAIService_CandidateSpecs(t *testing.T) {
+	aiConfig := config.DemoAIConfig("")
+	aiConfig.Primary.Temperature = 0.1
+	aiConfig.Fallback.Temperature = 0.2
+	aiService := NewAIServiceWithClients(aiConfig, &fixedLLM{}, &fixedLLM{}, zaptest.NewLogger(t))
+
+	specs := aiService.candidateSpecs(5, nil)
+	require.Len(t, specs, 5)
+	temperatures := []float64{0.1, 0.2, 0.4, 0.5, 0.7}
+	for i, spec := range specs {
+		assert.InDelta(t, temperatures[i], spec.temperature, 1e-9, "candidate %d", i)
+	}
+}
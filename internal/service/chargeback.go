@@ -0,0 +1,151 @@
+// 费用归属标签
+// 请求通过X-Cost-Center/X-Project携带成本中心与项目标签，取值必须在工作空间配置的范围内；
+// 标签随查询历史保存，并按标签累计AI的Token用量，AI与数据库费用据此按团队分摊
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+const (
+	// CostCenterHeader 携带成本中心标签的请求头
+	CostCenterHeader = "X-Cost-Center"
+	// ProjectHeader 携带项目标签的请求头
+	ProjectHeader = "X-Project"
+
+	// maxChargebackTagLength 单个标签的最大长度，与query_history列宽一致
+	maxChargebackTagLength = 64
+	// maxChargebackTaxonomySize 每类标签的最大取值数，避免指标标签无限增长
+	maxChargebackTaxonomySize = 200
+)
+
+// ValidateChargebackTaxonomy 校验标签取值范围：标签非空、不超过64个字符、不区分大小写不重复
+func ValidateChargebackTaxonomy(taxonomy *repository.ChargebackTaxonomy) error {
+	if taxonomy == nil {
+		return nil
+	}
+
+	for kind, values := range map[string][]string{"成本中心": taxonomy.CostCenters, "项目": taxonomy.Projects} {
+		if len(values) > maxChargebackTaxonomySize {
+			return fmt.Errorf("%w: %s最多%d个", repository.ErrInvalidInput, kind, maxChargebackTaxonomySize)
+		}
+		seen := make(map[string]bool, len(values))
+		for _, value := range values {
+			if value == "" || value != strings.TrimSpace(value) || len(value) > maxChargebackTagLength {
+				return fmt.Errorf("%w: %s%q不能为空、不能有首尾空格且不超过%d个字符", repository.ErrInvalidInput, kind, value, maxChargebackTagLength)
+			}
+			key := strings.ToLower(value)
+			if seen[key] {
+				return fmt.Errorf("%w: %s%q重复", repository.ErrInvalidInput, kind, value)
+			}
+			seen[key] = true
+		}
+	}
+
+	if taxonomy.Required && (len(taxonomy.CostCenters) == 0 || len(taxonomy.Projects) == 0) {
+		return fmt.Errorf("%w: 要求携带标签时成本中心与项目都不能为空", repository.ErrInvalidInput)
+	}
+	return nil
+}
+
+// UpdateChargebackTaxonomy 校验并更新用户所属工作空间的标签取值范围，taxonomy为nil表示不接受标签
+func (s *WorkspaceSettingsService) UpdateChargebackTaxonomy(ctx context.Context, userID int64, taxonomy *repository.ChargebackTaxonomy) (*WorkspaceSettings, error) {
+	if err := ValidateChargebackTaxonomy(taxonomy); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.workspaceRepo.UpdateChargebackTaxonomy(ctx, settings.WorkspaceID, taxonomy, userID); err != nil {
+		return nil, err
+	}
+	s.Invalidate()
+
+	s.logger.Info("Workspace chargeback taxonomy updated",
+		zap.Int64("workspace_id", settings.WorkspaceID),
+		zap.Bool("required", taxonomy != nil && taxonomy.Required),
+		zap.Int64("user_id", userID))
+
+	settings.Chargeback = taxonomy
+	return settings, nil
+}
+
+// ResolveChargeback 按用户所属工作空间的取值范围校验请求携带的标签
+// 标签不区分大小写匹配，返回值统一为取值范围中的写法；未携带标签且工作空间不要求时返回nil
+func (s *WorkspaceSettingsService) ResolveChargeback(ctx context.Context, userID int64, tags repository.ChargebackTags) (*repository.ChargebackTags, error) {
+	tags.CostCenter = strings.TrimSpace(tags.CostCenter)
+	tags.Project = strings.TrimSpace(tags.Project)
+
+	settings, err := s.Resolve(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取工作空间设置失败: %w", err)
+	}
+	taxonomy := settings.Chargeback
+	if taxonomy == nil {
+		taxonomy = &repository.ChargebackTaxonomy{}
+	}
+
+	if taxonomy.Required && (tags.CostCenter == "" || tags.Project == "") {
+		return nil, fmt.Errorf("%w: 工作空间要求请求携带%s与%s标签", repository.ErrInvalidInput, CostCenterHeader, ProjectHeader)
+	}
+	if tags.CostCenter == "" && tags.Project == "" {
+		return nil, nil
+	}
+
+	resolved := &repository.ChargebackTags{}
+	if resolved.CostCenter, err = matchChargebackTag("成本中心", tags.CostCenter, taxonomy.CostCenters); err != nil {
+		return nil, err
+	}
+	if resolved.Project, err = matchChargebackTag("项目", tags.Project, taxonomy.Projects); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// matchChargebackTag 在取值范围中查找标签，空标签表示未标记
+func matchChargebackTag(kind, value string, allowed []string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(candidate, value) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s%q不在工作空间配置的范围内", repository.ErrInvalidInput, kind, value)
+}
+
+// chargebackKey 费用归属标签的context键
+type chargebackKey struct{}
+
+// WithChargebackTags 为本次请求设置费用归属标签，查询历史与AI用量按标签记录
+func WithChargebackTags(ctx context.Context, tags *repository.ChargebackTags) context.Context {
+	if tags == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, chargebackKey{}, tags)
+}
+
+// chargebackTagsFromContext 读取本次请求的费用归属标签，未设置时返回nil
+func chargebackTagsFromContext(ctx context.Context) *repository.ChargebackTags {
+	tags, _ := ctx.Value(chargebackKey{}).(*repository.ChargebackTags)
+	return tags
+}
+
+// ApplyChargebackTags 将费用归属标签写入查询历史，tags为nil时不修改
+func ApplyChargebackTags(history *repository.QueryHistory, tags *repository.ChargebackTags) {
+	if tags == nil {
+		return
+	}
+	history.CostCenter = optionalString(tags.CostCenter)
+	history.Project = optionalString(tags.Project)
+}
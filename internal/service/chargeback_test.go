@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+// chargebackWorkspaceRepository 记录费用归属标签范围的工作空间Repository
+type chargebackWorkspaceRepository struct {
+	stubWorkspaceRepository
+}
+
+func (r *chargebackWorkspaceRepository) UpdateChargebackTaxonomy(ctx context.Context, workspaceID int64, taxonomy *repository.ChargebackTaxonomy, updateBy int64) error {
+	r.workspace.Chargeback = taxonomy
+	return nil
+}
+
+func TestWorkspaceSettingsService_ResolveChargeback(t *testing.T) {
+	ctx := context.Background()
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &chargebackWorkspaceRepository{stubWorkspaceRepository{workspace: workspace}}
+	s := NewWorkspaceSettingsService(repo, nil, nil, zaptest.NewLogger(t))
+
+	// 未配置取值范围时不接受标签，未携带标签的请求不受影响
+	tags, err := s.ResolveChargeback(ctx, 7, repository.ChargebackTags{})
+	require.NoError(t, err)
+	assert.Nil(t, tags)
+	_, err = s.ResolveChargeback(ctx, 7, repository.ChargebackTags{CostCenter: "CC-100"})
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	_, err = s.UpdateChargebackTaxonomy(ctx, 7, &repository.ChargebackTaxonomy{CostCenters: []string{"CC-100", "cc-100"}})
+	assert.ErrorIs(t, err, repository.ErrInvalidInput, "不区分大小写重复")
+	_, err = s.UpdateChargebackTaxonomy(ctx, 7, &repository.ChargebackTaxonomy{CostCenters: []string{"CC-100"}, Required: true})
+	assert.ErrorIs(t, err, repository.ErrInvalidInput, "要求携带时项目不能为空")
+
+	settings, err := s.UpdateChargebackTaxonomy(ctx, 7, &repository.ChargebackTaxonomy{CostCenters: []string{"CC-100"}, Projects: []string{"Growth"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Growth"}, settings.Chargeback.Projects)
+
+	// 按取值范围的写法返回，可以只携带一类标签
+	tags, err = s.ResolveChargeback(ctx, 7, repository.ChargebackTags{CostCenter: " cc-100 ", Project: "growth"})
+	require.NoError(t, err)
+	assert.Equal(t, &repository.ChargebackTags{CostCenter: "CC-100", Project: "Growth"}, tags)
+	tags, err = s.ResolveChargeback(ctx, 7, repository.ChargebackTags{Project: "Growth"})
+	require.NoError(t, err)
+	assert.Equal(t, &repository.ChargebackTags{Project: "Growth"}, tags)
+	_, err = s.ResolveChargeback(ctx, 7, repository.ChargebackTags{CostCenter: "CC-999"})
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	// 要求携带标签后缺少任一类都被拒绝
+	_, err = s.UpdateChargebackTaxonomy(ctx, 7, &repository.ChargebackTaxonomy{CostCenters: []string{"CC-100"}, Projects: []string{"Growth"}, Required: true})
+	require.NoError(t, err)
+	_, err = s.ResolveChargeback(ctx, 7, repository.ChargebackTags{})
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+	_, err = s.ResolveChargeback(ctx, 7, repository.ChargebackTags{CostCenter: "CC-100"})
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+}
+
+func TestApplyChargebackTags(t *testing.T) {
+	history := &repository.QueryHistory{}
+	ApplyChargebackTags(history, nil)
+	assert.Nil(t, history.CostCenter)
+
+	ApplyChargebackTags(history, &repository.ChargebackTags{CostCenter: "CC-100"})
+	require.NotNil(t, history.CostCenter)
+	assert.Equal(t, "CC-100", *history.CostCenter)
+	assert.Nil(t, history.Project)
+
+	ctx := WithChargebackTags(context.Background(), &repository.ChargebackTags{Project: "Growth"})
+	assert.Equal(t, "Growth", chargebackTagsFromContext(ctx).Project)
+	assert.Nil(t, chargebackTagsFromContext(context.Background()))
+}
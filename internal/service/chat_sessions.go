@@ -0,0 +1,265 @@
+// 多轮对话会话
+// 服务端按会话保存之前的问题、生成的SQL、自动执行结果的摘要与所选连接，追问（如“只看上个月”“同样的查询但按月份分组”）
+// 时作为上下文交给模型，客户端只需发送本轮问题；会话只保存在内存中，空闲超时后清理
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// ErrChatSessionNotFound 会话不存在、已过期或不属于当前用户
+var ErrChatSessionNotFound = errors.New("对话会话不存在或已过期")
+
+// ConversationTurn 会话中已完成的一轮问答
+type ConversationTurn struct {
+	Question string      `json:"question"`
+	SQL      string      `json:"sql"`
+	Result   *TurnResult `json:"result,omitempty"` // 自动执行结果的摘要，未执行或执行失败时为空
+	AskedAt  time.Time   `json:"asked_at"`
+}
+
+// TurnResult 一轮问答执行结果的摘要，只保留列名与行数，不保存结果数据
+type TurnResult struct {
+	Columns   []string `json:"columns"`
+	RowCount  int32    `json:"row_count"`
+	Truncated bool     `json:"truncated,omitempty"` // 结果达到行数或大小上限被截断
+}
+
+// SummarizeResult 提取执行结果的摘要，未执行或执行失败时返回nil
+func SummarizeResult(result *QueryResult) *TurnResult {
+	if result == nil || result.Error != "" || result.Status != string(repository.QuerySuccess) {
+		return nil
+	}
+	return &TurnResult{
+		Columns:   append([]string(nil), result.Columns...),
+		RowCount:  result.RowCount,
+		Truncated: result.Truncated,
+	}
+}
+
+// ChatSession 多轮对话会话
+type ChatSession struct {
+	ID           string             `json:"session_id"`
+	UserID       int64              `json:"user_id"`
+	ConnectionID int64              `json:"connection_id"`
+	Turns        []ConversationTurn `json:"turns"`
+	CreatedAt    time.Time          `json:"created_at"`
+	LastActiveAt time.Time          `json:"last_active_at"`
+}
+
+// ChatSessionStore 内存中的多轮对话会话
+type ChatSessionStore struct {
+	config *config.ChatSessionConfig
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]*ChatSession
+}
+
+// NewChatSessionStore 创建对话会话存储，配置为nil时使用默认配置
+func NewChatSessionStore(sessionConfig *config.ChatSessionConfig, logger *zap.Logger) *ChatSessionStore {
+	if sessionConfig == nil {
+		sessionConfig = config.DefaultChatSessionConfig()
+	}
+	return &ChatSessionStore{
+		config:   sessionConfig,
+		logger:   logger,
+		now:      time.Now,
+		sessions: make(map[string]*ChatSession),
+	}
+}
+
+// Config 返回会话配置
+func (s *ChatSessionStore) Config() *config.ChatSessionConfig {
+	return s.config
+}
+
+// Create 为用户创建会话；用户会话数达到上限时淘汰最久未活动的会话
+func (s *ChatSessionStore) Create(userID, connectionID int64) (*ChatSession, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("生成会话ID失败: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var owned []*ChatSession
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			owned = append(owned, session)
+		}
+	}
+	if len(owned) >= s.config.MaxSessionsPerUser {
+		sort.Slice(owned, func(i, j int) bool { return owned[i].LastActiveAt.Before(owned[j].LastActiveAt) })
+		for _, session := range owned[:len(owned)-s.config.MaxSessionsPerUser+1] {
+			delete(s.sessions, session.ID)
+		}
+	}
+
+	now := s.now()
+	session := &ChatSession{
+		ID:           hex.EncodeToString(buf),
+		UserID:       userID,
+		ConnectionID: connectionID,
+		CreatedAt:    now,
+		LastActiveAt: now,
+	}
+	s.sessions[session.ID] = session
+	return session.clone(), nil
+}
+
+// Get 返回用户的会话副本
+func (s *ChatSessionStore) Get(sessionID string, userID int64) (*ChatSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.lookup(sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return session.clone(), nil
+}
+
+// AppendTurn 记录已完成的一轮问答，只保留最近MaxTurns轮；result为本轮执行结果的摘要，未执行时为nil
+func (s *ChatSessionStore) AppendTurn(sessionID string, userID int64, question, sql string, result *TurnResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.lookup(sessionID, userID)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	session.Turns = append(session.Turns, ConversationTurn{Question: question, SQL: sql, Result: result, AskedAt: now})
+	if len(session.Turns) > s.config.MaxTurns {
+		session.Turns = session.Turns[len(session.Turns)-s.config.MaxTurns:]
+	}
+	session.LastActiveAt = now
+	return nil
+}
+
+// SetConnection 切换会话使用的连接；之前的问答基于另一个数据库，切换后清空
+func (s *ChatSessionStore) SetConnection(sessionID string, userID, connectionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.lookup(sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if session.ConnectionID != connectionID {
+		session.ConnectionID = connectionID
+		session.Turns = nil
+	}
+	session.LastActiveAt = s.now()
+	return nil
+}
+
+// Reset 清空会话的问答上下文，保留所选连接
+func (s *ChatSessionStore) Reset(sessionID string, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.lookup(sessionID, userID)
+	if err != nil {
+		return err
+	}
+	session.Turns = nil
+	session.LastActiveAt = s.now()
+	return nil
+}
+
+// Delete 删除用户的会话
+func (s *ChatSessionStore) Delete(sessionID string, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.lookup(sessionID, userID); err != nil {
+		return err
+	}
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+// ForgetUser 删除用户的全部会话，实现ConversationMemory，返回删除的问答轮数
+func (s *ChatSessionStore) ForgetUser(userID int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forgotten := 0
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			forgotten += len(session.Turns)
+			delete(s.sessions, id)
+		}
+	}
+	return forgotten
+}
+
+// Prune 清理空闲超时的会话，返回清理的会话数
+func (s *ChatSessionStore) Prune() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := s.now().Add(-s.config.IdleTimeout)
+	pruned := 0
+	for id, session := range s.sessions {
+		if session.LastActiveAt.Before(cutoff) {
+			delete(s.sessions, id)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// Run 定期清理空闲会话，直到ctx取消；由看门狗托管，每轮结束时调用beat上报心跳
+func (s *ChatSessionStore) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(s.config.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if pruned := s.Prune(); pruned > 0 {
+				s.logger.Debug("Pruned idle chat sessions", zap.Int("pruned", pruned))
+			}
+			beat()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// lookup 查找用户未过期的会话，调用方需持有锁
+func (s *ChatSessionStore) lookup(sessionID string, userID int64) (*ChatSession, error) {
+	session, ok := s.sessions[sessionID]
+	if !ok || session.UserID != userID {
+		return nil, ErrChatSessionNotFound
+	}
+	if s.now().Sub(session.LastActiveAt) > s.config.IdleTimeout {
+		delete(s.sessions, sessionID)
+		return nil, ErrChatSessionNotFound
+	}
+	return session, nil
+}
+
+// clone 返回会话副本，调用方修改副本不影响存储中的会话
+func (c *ChatSession) clone() *ChatSession {
+	cloned := *c
+	cloned.Turns = append([]ConversationTurn(nil), c.Turns...)
+	return &cloned
+}
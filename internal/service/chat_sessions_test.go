@@ -0,0 +1,129 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+func TestChatSessionStore(t *testing.T) {
+	cfg := config.DefaultChatSessionConfig()
+	cfg.MaxTurns = 2
+	cfg.MaxSessionsPerUser = 2
+	store := NewChatSessionStore(cfg, zaptest.NewLogger(t))
+	now := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	session, err := store.Create(7, 1)
+	require.NoError(t, err)
+	assert.Len(t, session.ID, 24)
+
+	// 只保留最近MaxTurns轮
+	require.NoError(t, store.AppendTurn(session.ID, 7, "各地区销售额", "SELECT region, SUM(amount) FROM orders GROUP BY region", nil))
+	require.NoError(t, store.AppendTurn(session.ID, 7, "只看上个月", "SELECT 2", nil))
+	require.NoError(t, store.AppendTurn(session.ID, 7, "按金额倒序", "SELECT 3", &TurnResult{Columns: []string{"region"}, RowCount: 4}))
+	got, err := store.Get(session.ID, 7)
+	require.NoError(t, err)
+	require.Len(t, got.Turns, 2)
+	assert.Equal(t, "只看上个月", got.Turns[0].Question)
+	assert.Nil(t, got.Turns[0].Result)
+	assert.Equal(t, "SELECT 3", got.Turns[1].SQL)
+	assert.Equal(t, &TurnResult{Columns: []string{"region"}, RowCount: 4}, got.Turns[1].Result)
+
+	// 返回副本，修改不影响会话
+	got.Turns[0].Question = "changed"
+	got, _ = store.Get(session.ID, 7)
+	assert.Equal(t, "只看上个月", got.Turns[0].Question)
+
+	// 其他用户看不到
+	_, err = store.Get(session.ID, 8)
+	assert.ErrorIs(t, err, ErrChatSessionNotFound)
+
+	// 同一连接不清空，切换连接清空之前的问答
+	require.NoError(t, store.SetConnection(session.ID, 7, 1))
+	got, _ = store.Get(session.ID, 7)
+	assert.Len(t, got.Turns, 2)
+	require.NoError(t, store.SetConnection(session.ID, 7, 2))
+	got, _ = store.Get(session.ID, 7)
+	assert.Equal(t, int64(2), got.ConnectionID)
+	assert.Empty(t, got.Turns)
+
+	// 超出每用户会话数时淘汰最久未活动的会话
+	now = now.Add(time.Minute)
+	second, err := store.Create(7, 1)
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	require.NoError(t, store.AppendTurn(session.ID, 7, "q", "SELECT 1", nil))
+	third, err := store.Create(7, 1)
+	require.NoError(t, err)
+	_, err = store.Get(second.ID, 7)
+	assert.ErrorIs(t, err, ErrChatSessionNotFound)
+	_, err = store.Get(session.ID, 7)
+	assert.NoError(t, err)
+
+	// 空闲超时后过期
+	now = now.Add(cfg.IdleTimeout + time.Second)
+	_, err = store.Get(third.ID, 7)
+	assert.ErrorIs(t, err, ErrChatSessionNotFound)
+	assert.Equal(t, 1, store.Prune())
+}
+
+func TestChatSessionStore_ForgetUser(t *testing.T) {
+	store := NewChatSessionStore(nil, zaptest.NewLogger(t))
+
+	mine, err := store.Create(7, 1)
+	require.NoError(t, err)
+	require.NoError(t, store.AppendTurn(mine.ID, 7, "q1", "SELECT 1", nil))
+	require.NoError(t, store.AppendTurn(mine.ID, 7, "q2", "SELECT 2", nil))
+	other, err := store.Create(8, 1)
+	require.NoError(t, err)
+
+	var memory ConversationMemory = store
+	assert.Equal(t, 2, memory.ForgetUser(7))
+	_, err = store.Get(mine.ID, 7)
+	assert.ErrorIs(t, err, ErrChatSessionNotFound)
+	_, err = store.Get(other.ID, 8)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, store.Delete(other.ID, 7), ErrChatSessionNotFound)
+	assert.NoError(t, store.Delete(other.ID, 8))
+}
+
+func TestConversationSection(t *testing.T) {
+	assert.Empty(t, conversationSection(nil))
+
+	section := conversationSection([]ConversationTurn{
+		{Question: "各地区销售额", SQL: "SELECT region, SUM(amount)\nFROM orders\nGROUP BY region"},
+	})
+	assert.Contains(t, section, "## 对话上下文")
+	assert.Contains(t, section, "1. 问题：各地区销售额")
+	assert.Contains(t, section, "SQL：SELECT region, SUM(amount) FROM orders GROUP BY region")
+	assert.NotContains(t, section, "结果：", "未执行的轮次没有结果摘要")
+
+	section = conversationSection([]ConversationTurn{
+		{Question: "每天的订单数", SQL: "SELECT created_at::date AS day, COUNT(*) FROM orders GROUP BY 1", Result: &TurnResult{Columns: []string{"day", "count"}, RowCount: 1000, Truncated: true}},
+	})
+	assert.Contains(t, section, "   结果：1000行（已截断），列：day, count\n")
+}
+
+func TestSummarizeResult(t *testing.T) {
+	assert.Nil(t, SummarizeResult(nil))
+	assert.Nil(t, SummarizeResult(&QueryResult{Status: string(repository.QueryError), Error: "relation does not exist"}))
+
+	result := &QueryResult{
+		Columns:  []string{"region", "total"},
+		Rows:     []map[string]any{{"region": "华东", "total": 100}},
+		RowCount: 1,
+		Status:   string(repository.QuerySuccess),
+	}
+	summary := SummarizeResult(result)
+	assert.Equal(t, &TurnResult{Columns: []string{"region", "total"}, RowCount: 1}, summary, "只保留列名与行数")
+	result.Columns[0] = "changed"
+	assert.Equal(t, "region", summary.Columns[0])
+}
@@ -0,0 +1,410 @@
+// 列数据分级策略
+// 数据字典中的列可标注为PII/财务/内部，策略按分级与用户角色推导默认处理方式：
+// 有权查看时原样返回，否则在结果中脱敏或过滤；生成SQL时提醒模型不要选择受限列。
+// 连接所有者授予的列访问例外在到期前覆盖默认处理方式
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// ColumnAction 受限列的处理方式
+type ColumnAction string
+
+const (
+	ColumnAllow ColumnAction = "allow" // 原样返回
+	ColumnMask  ColumnAction = "mask"  // 值替换为掩码
+	ColumnDrop  ColumnAction = "drop"  // 从结果中过滤
+)
+
+// maskedValue 脱敏后的占位值
+const maskedValue = "***"
+
+// columnActionRank 处理方式的严格程度，同一结果列命中多条规则时取最严格的
+var columnActionRank = map[ColumnAction]int{ColumnAllow: 0, ColumnMask: 1, ColumnDrop: 2}
+
+// defaultColumnActions 分级与角色对应的默认处理方式
+// 未知角色（如外部渠道的调用方）一律过滤，内部数据只对系统内用户开放
+var defaultColumnActions = map[repository.DataClassification]map[repository.UserRole]ColumnAction{
+	repository.ClassificationPII: {
+		repository.RoleUser:    ColumnMask,
+		repository.RoleManager: ColumnMask,
+		repository.RoleAdmin:   ColumnAllow,
+	},
+	repository.ClassificationFinancial: {
+		repository.RoleUser:    ColumnDrop,
+		repository.RoleManager: ColumnAllow,
+		repository.RoleAdmin:   ColumnAllow,
+	},
+	repository.ClassificationInternal: {
+		repository.RoleUser:    ColumnAllow,
+		repository.RoleManager: ColumnAllow,
+		repository.RoleAdmin:   ColumnAllow,
+	},
+}
+
+// DefaultColumnAction 按分级与角色推导列的默认处理方式
+func DefaultColumnAction(classification repository.DataClassification, role string) ColumnAction {
+	if action, ok := defaultColumnActions[classification][repository.UserRole(role)]; ok {
+		return action
+	}
+	return ColumnDrop
+}
+
+// ColumnRule 单列的分级与处理方式
+type ColumnRule struct {
+	SchemaName     string                        `json:"schema_name"`
+	TableName      string                        `json:"table_name"`
+	ColumnName     string                        `json:"column_name"`
+	Classification repository.DataClassification `json:"classification"`
+	Action         ColumnAction                  `json:"action"`
+	GrantExpiresAt *time.Time                    `json:"grant_expires_at,omitempty"` // 访问例外的到期时间，有例外时action为allow
+}
+
+// ColumnPolicy 某个用户在某个连接上的列处理策略
+type ColumnPolicy struct {
+	rules    []*ColumnRule
+	byTable  map[string]*ColumnRule // table.column（小写）
+	byColumn map[string]*ColumnRule // column（小写），同名列取最严格的规则
+}
+
+// NewColumnPolicy 按分级标签与角色构建列处理策略
+func NewColumnPolicy(classifications []*repository.ColumnClassification, role string) *ColumnPolicy {
+	return NewColumnPolicyWithGrants(classifications, role, nil)
+}
+
+// NewColumnPolicyWithGrants 按分级标签与角色构建列处理策略，grants中生效的访问例外对应的列原样返回
+func NewColumnPolicyWithGrants(classifications []*repository.ColumnClassification, role string, grants []*repository.ColumnAccessRequest) *ColumnPolicy {
+	policy := &ColumnPolicy{
+		byTable:  make(map[string]*ColumnRule),
+		byColumn: make(map[string]*ColumnRule),
+	}
+
+	granted := make(map[string]*time.Time, len(grants))
+	for _, g := range grants {
+		granted[strings.ToLower(g.SchemaName+"."+g.TableName+"."+g.ColumnName)] = g.ExpiresAt
+	}
+
+	for _, c := range classifications {
+		classification := repository.DataClassification(c.Classification)
+		rule := &ColumnRule{
+			SchemaName:     c.SchemaName,
+			TableName:      c.TableName,
+			ColumnName:     c.ColumnName,
+			Classification: classification,
+			Action:         DefaultColumnAction(classification, role),
+		}
+		if expiresAt, ok := granted[strings.ToLower(c.SchemaName+"."+c.TableName+"."+c.ColumnName)]; ok && rule.Action != ColumnAllow {
+			rule.Action = ColumnAllow
+			rule.GrantExpiresAt = expiresAt
+		}
+		policy.rules = append(policy.rules, rule)
+
+		policy.byTable[strings.ToLower(c.TableName+"."+c.ColumnName)] = rule
+		column := strings.ToLower(c.ColumnName)
+		if existing, ok := policy.byColumn[column]; !ok || columnActionRank[rule.Action] > columnActionRank[existing.Action] {
+			policy.byColumn[column] = rule
+		}
+	}
+	return policy
+}
+
+// Rules 返回所有列规则
+func (p *ColumnPolicy) Rules() []*ColumnRule {
+	return p.rules
+}
+
+// Restricted 返回当前用户无权直接查看的列，格式为schema.table.column (分级)，用于提示词
+func (p *ColumnPolicy) Restricted() []string {
+	var restricted []string
+	for _, rule := range p.rules {
+		if rule.Action != ColumnAllow {
+			restricted = append(restricted, fmt.Sprintf("%s.%s.%s (%s)", rule.SchemaName, rule.TableName, rule.ColumnName, rule.Classification))
+		}
+	}
+	return restricted
+}
+
+// Apply 对查询结果应用策略，返回被脱敏或过滤的列说明；已应用过策略的结果不重复处理
+func (p *ColumnPolicy) Apply(result *QueryResult, lineage []ColumnLineage) []string {
+	if result == nil || result.columnPolicyApplied {
+		return nil
+	}
+	result.columnPolicyApplied = true
+
+	var notes []string
+	var guardrails []Guardrail
+	result.Columns, notes, guardrails = p.ApplyToRows(result.Columns, result.Rows, lineage)
+	result.Warnings = append(result.Warnings, notes...)
+	result.Guardrails = append(result.Guardrails, guardrails...)
+	return notes
+}
+
+// ApplyToRows 对结果行应用策略：脱敏的列替换为掩码，过滤的列从行与列名中删除
+// 结果列优先按列来源匹配table.column，无法解析来源（如SELECT *）时按列名匹配；
+// columns为空时以首行的键作为列名。返回保留的列、说明与脱敏/删除列的防护措施，
+// 防护措施附带受限源列的访问申请说明
+func (p *ColumnPolicy) ApplyToRows(columns []string, rows []map[string]any, lineage []ColumnLineage) ([]string, []string, []Guardrail) {
+	if len(p.rules) == 0 {
+		return columns, nil, nil
+	}
+
+	if columns == nil && len(rows) > 0 {
+		for column := range rows[0] {
+			columns = append(columns, column)
+		}
+	}
+
+	sources := make(map[string]ColumnLineage, len(lineage))
+	for _, entry := range lineage {
+		sources[strings.ToLower(entry.Column)] = entry
+	}
+
+	kept := make([]string, 0, len(columns))
+	var notes, masked, removed []string
+	maskedHint, removedHint := newColumnAccessHint(), newColumnAccessHint()
+	for _, column := range columns {
+		rule := p.match(column, sources)
+		if rule == nil || rule.Action == ColumnAllow {
+			kept = append(kept, column)
+			continue
+		}
+
+		switch rule.Action {
+		case ColumnMask:
+			for _, row := range rows {
+				if row[column] != nil {
+					row[column] = maskedValue
+				}
+			}
+			kept = append(kept, column)
+			masked = append(masked, column)
+			maskedHint.add(rule)
+			notes = append(notes, fmt.Sprintf("列%s属于%s数据，已脱敏", column, rule.Classification))
+		case ColumnDrop:
+			for _, row := range rows {
+				delete(row, column)
+			}
+			removed = append(removed, column)
+			removedHint.add(rule)
+			notes = append(notes, fmt.Sprintf("列%s属于%s数据，已从结果中过滤", column, rule.Classification))
+		}
+	}
+
+	var guardrails []Guardrail
+	if len(masked) > 0 {
+		guardrails = append(guardrails, Guardrail{Type: GuardrailColumnsMasked, Columns: masked, AccessRequest: maskedHint})
+	}
+	if len(removed) > 0 {
+		guardrails = append(guardrails, Guardrail{Type: GuardrailColumnsRemoved, Columns: removed, AccessRequest: removedHint})
+	}
+	return kept, notes, guardrails
+}
+
+// match 查找结果列命中的最严格规则；COUNT聚合不暴露原始值，不受限制
+func (p *ColumnPolicy) match(column string, sources map[string]ColumnLineage) *ColumnRule {
+	entry, ok := sources[strings.ToLower(column)]
+	if !ok || (len(entry.SourceColumns) == 0 && !entry.Computed) {
+		return p.byColumn[strings.ToLower(column)]
+	}
+	if strings.EqualFold(entry.Aggregate, "COUNT") {
+		return nil
+	}
+
+	var strictest *ColumnRule
+	for _, source := range entry.SourceColumns {
+		rule, ok := p.byTable[strings.ToLower(source)]
+		if !ok {
+			rule = p.byColumn[strings.ToLower(source[strings.LastIndex(source, ".")+1:])]
+		}
+		if rule != nil && (strictest == nil || columnActionRank[rule.Action] > columnActionRank[strictest.Action]) {
+			strictest = rule
+		}
+	}
+	return strictest
+}
+
+// queryRoleKey 查询发起用户角色的context键
+type queryRoleKey struct{}
+
+// WithQueryRole 标记本次查询发起用户的角色，执行器按该角色应用列策略；未标记时按发起用户当前的角色
+func WithQueryRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, queryRoleKey{}, role)
+}
+
+// queryRole 读取WithQueryRole标记的角色，未标记或为空时返回false
+func queryRole(ctx context.Context) (string, bool) {
+	role, _ := ctx.Value(queryRoleKey{}).(string)
+	return role, role != ""
+}
+
+// unfilteredResultKey 跳过执行器列策略的context键
+type unfilteredResultKey struct{}
+
+// WithUnfilteredResult 执行器返回应用列策略前的原始结果
+// 只用于缓存结果的调用方：缓存原始结果，返回给用户前再按当前用户应用策略
+func WithUnfilteredResult(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unfilteredResultKey{}, true)
+}
+
+// ColumnPolicyEnforcer 执行器返回结果前统一应用列策略
+// 所有经过SQLExecutor的查询（MCP、嵌入、Teams、邮件、定时执行、自动执行等）都按发起用户的策略脱敏或过滤；
+// 没有发起用户的查询按未知角色处理，受限列一律过滤
+type ColumnPolicyEnforcer struct {
+	classifications *ClassificationService
+	users           repository.UserRepository
+	lineage         *SQLSecurityValidator
+	logger          *zap.Logger
+}
+
+// NewColumnPolicyEnforcer 创建列策略执行器，users用于查询未标记角色时发起用户的当前角色
+func NewColumnPolicyEnforcer(classifications *ClassificationService, users repository.UserRepository, logger *zap.Logger) *ColumnPolicyEnforcer {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ColumnPolicyEnforcer{
+		classifications: classifications,
+		users:           users,
+		lineage:         NewSQLSecurityValidator(logger),
+		logger:          logger,
+	}
+}
+
+// Enforce 按发起用户的角色与访问例外对结果应用列策略；策略加载失败时隐藏结果数据
+func (e *ColumnPolicyEnforcer) Enforce(ctx context.Context, sql string, connectionID int64, result *QueryResult) {
+	if result == nil || result.columnPolicyApplied || len(result.Rows) == 0 {
+		return
+	}
+	if unfiltered, _ := ctx.Value(unfilteredResultKey{}).(bool); unfiltered {
+		return
+	}
+
+	userID := queryOwnerFromContext(ctx)
+	policy, err := e.classifications.Policy(ctx, connectionID, userID, e.role(ctx, userID))
+	if err != nil {
+		e.logger.Error("加载列数据分级失败，结果数据已隐藏",
+			zap.Int64("connection_id", connectionID),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		result.Rows = nil
+		result.Warnings = append(result.Warnings, "无法加载列数据分级，结果数据已隐藏")
+		result.Guardrails = append(result.Guardrails, Guardrail{Type: GuardrailResultHidden})
+		result.columnPolicyApplied = true
+		return
+	}
+	policy.Apply(result, e.lineage.ExtractColumnLineage(sql))
+}
+
+// role 解析发起用户的角色：优先使用context中标记的角色，其次查询用户当前角色；无法确定时返回空字符串，按未知角色处理
+func (e *ColumnPolicyEnforcer) role(ctx context.Context, userID int64) string {
+	if role, ok := ctx.Value(queryRoleKey{}).(string); ok {
+		return role
+	}
+	if userID <= 0 || e.users == nil {
+		return ""
+	}
+
+	user, err := e.users.GetByID(ctx, userID)
+	if err != nil {
+		e.logger.Warn("获取查询发起用户失败，按未知角色应用列策略", zap.Int64("user_id", userID), zap.Error(err))
+		return ""
+	}
+	return user.Role
+}
+
+// ClassificationService 列数据分级服务
+type ClassificationService struct {
+	repo           repository.ClassificationRepository
+	connectionRepo repository.ConnectionRepository
+	grants         repository.ColumnAccessRepository // 可选：列访问例外
+	logger         *zap.Logger
+	now            func() time.Time
+}
+
+// NewClassificationService 创建列数据分级服务
+func NewClassificationService(repo repository.ClassificationRepository, connectionRepo repository.ConnectionRepository, logger *zap.Logger) *ClassificationService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ClassificationService{
+		repo:           repo,
+		connectionRepo: connectionRepo,
+		logger:         logger,
+		now:            time.Now,
+	}
+}
+
+// SetColumnAccessRepository 启用列访问例外，构建策略时生效中的授权覆盖默认处理方式
+func (s *ClassificationService) SetColumnAccessRepository(grants repository.ColumnAccessRepository) {
+	s.grants = grants
+}
+
+// List 列出连接上的分级标签，只有连接所有者可以查看
+func (s *ClassificationService) List(ctx context.Context, userID, connectionID int64) ([]*repository.ColumnClassification, error) {
+	if err := s.checkOwner(ctx, userID, connectionID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByConnection(ctx, connectionID)
+}
+
+// Tag 设置列的分级，classification为空时移除标签；只有连接所有者可以操作
+func (s *ClassificationService) Tag(ctx context.Context, userID int64, c *repository.ColumnClassification) error {
+	if err := s.checkOwner(ctx, userID, c.ConnectionID); err != nil {
+		return err
+	}
+
+	if c.Classification == "" {
+		return s.repo.Delete(ctx, c.ConnectionID, c.SchemaName, c.TableName, c.ColumnName, userID)
+	}
+	if !repository.DataClassification(c.Classification).IsValid() {
+		return fmt.Errorf("%w: 未知的数据分级%s", repository.ErrInvalidInput, c.Classification)
+	}
+
+	c.UpdateBy = &userID
+	if err := s.repo.Upsert(ctx, c); err != nil {
+		return err
+	}
+
+	s.logger.Info("列分级已设置",
+		zap.Int64("connection_id", c.ConnectionID),
+		zap.String("column", c.SchemaName+"."+c.TableName+"."+c.ColumnName),
+		zap.String("classification", c.Classification),
+		zap.Int64("user_id", userID))
+	return nil
+}
+
+// Policy 构建用户在连接上的列处理策略：按角色推导默认处理方式，再叠加用户生效中的访问例外
+// 访问例外加载失败时返回错误，由调用方隐藏结果
+func (s *ClassificationService) Policy(ctx context.Context, connectionID, userID int64, role string) (*ColumnPolicy, error) {
+	classifications, err := s.repo.ListByConnection(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if s.grants == nil || userID == 0 || len(classifications) == 0 {
+		return NewColumnPolicy(classifications, role), nil
+	}
+
+	grants, err := s.grants.ListActiveGrants(ctx, connectionID, userID, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return NewColumnPolicyWithGrants(classifications, role, grants), nil
+}
+
+// checkOwner 校验连接所有权
+func (s *ClassificationService) checkOwner(ctx context.Context, userID, connectionID int64) error {
+	connection, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil || connection.UserID != userID {
+		return fmt.Errorf("无权访问数据库连接%d: %w", connectionID, repository.ErrPermissionDenied)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// memClassificationRepository 内存列分级Repository
+type memClassificationRepository struct {
+	items []*repository.ColumnClassification
+}
+
+func (m *memClassificationRepository) Upsert(ctx context.Context, c *repository.ColumnClassification) error {
+	for i, item := range m.items {
+		if item.ConnectionID == c.ConnectionID && item.SchemaName == c.SchemaName &&
+			item.TableName == c.TableName && item.ColumnName == c.ColumnName {
+			m.items[i] = c
+			return nil
+		}
+	}
+	m.items = append(m.items, c)
+	return nil
+}
+
+func (m *memClassificationRepository) Delete(ctx context.Context, connectionID int64, schemaName, tableName, columnName string, deleteBy int64) error {
+	for i, item := range m.items {
+		if item.ConnectionID == connectionID && item.SchemaName == schemaName &&
+			item.TableName == tableName && item.ColumnName == columnName {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (m *memClassificationRepository) ListByConnection(ctx context.Context, connectionID int64) ([]*repository.ColumnClassification, error) {
+	var items []*repository.ColumnClassification
+	for _, item := range m.items {
+		if item.ConnectionID == connectionID {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func testClassifications() []*repository.ColumnClassification {
+	return []*repository.ColumnClassification{
+		{ConnectionID: 1, SchemaName: "public", TableName: "customers", ColumnName: "email", Classification: "pii"},
+		{ConnectionID: 1, SchemaName: "public", TableName: "orders", ColumnName: "amount", Classification: "financial"},
+		{ConnectionID: 1, SchemaName: "public", TableName: "orders", ColumnName: "cost_center", Classification: "internal"},
+	}
+}
+
+func TestDefaultColumnAction(t *testing.T) {
+	cases := []struct {
+		classification repository.DataClassification
+		role           string
+		want           ColumnAction
+	}{
+		{repository.ClassificationPII, "user", ColumnMask},
+		{repository.ClassificationPII, "admin", ColumnAllow},
+		{repository.ClassificationFinancial, "user", ColumnDrop},
+		{repository.ClassificationFinancial, "manager", ColumnAllow},
+		{repository.ClassificationInternal, "user", ColumnAllow},
+		{repository.ClassificationInternal, "", ColumnDrop},
+		{repository.ClassificationPII, "guest", ColumnDrop},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, DefaultColumnAction(tc.classification, tc.role), "%s/%s", tc.classification, tc.role)
+	}
+}
+
+func TestColumnPolicy_Restricted(t *testing.T) {
+	policy := NewColumnPolicy(testClassifications(), "user")
+	assert.Equal(t, []string{
+		"public.customers.email (pii)",
+		"public.orders.amount (financial)",
+	}, policy.Restricted())
+
+	assert.Empty(t, NewColumnPolicy(testClassifications(), "admin").Restricted())
+}
+
+func TestColumnPolicy_ApplyToRows(t *testing.T) {
+	validator := NewSQLSecurityValidator(zaptest.NewLogger(t))
+	policy := NewColumnPolicy(testClassifications(), "user")
+
+	t.Run("按列来源脱敏与过滤", func(t *testing.T) {
+		sql := "SELECT c.email AS contact, o.amount, o.id FROM customers c JOIN orders o ON o.customer_id = c.id"
+		rows := []map[string]any{{"contact": "a@example.com", "amount": 10, "id": 1}}
+
+		kept, notes, guardrails := policy.ApplyToRows([]string{"contact", "amount", "id"}, rows, validator.ExtractColumnLineage(sql))
+		assert.Equal(t, []string{"contact", "id"}, kept)
+		assert.Equal(t, maskedValue, rows[0]["contact"])
+		assert.NotContains(t, rows[0], "amount")
+		assert.Len(t, notes, 2)
+		assert.Equal(t, []Guardrail{
+			{Type: GuardrailColumnsMasked, Columns: []string{"contact"}, AccessRequest: &ColumnAccessHint{
+				Endpoint: ColumnAccessRequestEndpoint,
+				Columns:  []*ColumnRule{{SchemaName: "public", TableName: "customers", ColumnName: "email", Classification: "pii", Action: ColumnMask}},
+			}},
+			{Type: GuardrailColumnsRemoved, Columns: []string{"amount"}, AccessRequest: &ColumnAccessHint{
+				Endpoint: ColumnAccessRequestEndpoint,
+				Columns:  []*ColumnRule{{SchemaName: "public", TableName: "orders", ColumnName: "amount", Classification: "financial", Action: ColumnDrop}},
+			}},
+		}, guardrails)
+	})
+
+	t.Run("无法解析来源时按列名匹配", func(t *testing.T) {
+		rows := []map[string]any{{"email": "a@example.com", "name": "Alice"}}
+
+		kept, _, _ := policy.ApplyToRows(nil, rows, validator.ExtractColumnLineage("SELECT * FROM customers"))
+		assert.ElementsMatch(t, []string{"email", "name"}, kept)
+		assert.Equal(t, maskedValue, rows[0]["email"])
+		assert.Equal(t, "Alice", rows[0]["name"])
+	})
+
+	t.Run("COUNT聚合不受限制", func(t *testing.T) {
+		sql := "SELECT COUNT(email) AS emails FROM customers"
+		rows := []map[string]any{{"emails": 42}}
+
+		_, notes, guardrails := policy.ApplyToRows([]string{"emails"}, rows, validator.ExtractColumnLineage(sql))
+		assert.Empty(t, notes)
+		assert.Empty(t, guardrails)
+		assert.Equal(t, 42, rows[0]["emails"])
+	})
+}
+
+func TestColumnPolicyEnforcer_Enforce(t *testing.T) {
+	ctx := context.Background()
+	classifications := NewClassificationService(&memClassificationRepository{items: testClassifications()}, nil, zaptest.NewLogger(t))
+	admin := testutil.NewUser(1, testutil.WithUserRole(repository.RoleAdmin))
+	editor := testutil.NewUser(7)
+	enforcer := NewColumnPolicyEnforcer(classifications, &apiKeyUserRepository{users: map[int64]*repository.User{1: admin, 7: editor}}, zaptest.NewLogger(t))
+
+	const sql = "SELECT email, amount FROM customers c JOIN orders o ON o.customer_id = c.id"
+	run := func(ctx context.Context) *QueryResult {
+		result := &QueryResult{Columns: []string{"email", "amount"}, Rows: []map[string]any{{"email": "a@example.com", "amount": 10}}}
+		enforcer.Enforce(ctx, sql, 1, result)
+		return result
+	}
+
+	// 按发起用户当前的角色处理
+	result := run(WithQueryOwner(ctx, 7))
+	assert.Equal(t, []string{"email"}, result.Columns)
+	assert.Equal(t, maskedValue, result.Rows[0]["email"])
+	assert.Len(t, result.Warnings, 2)
+	result = run(WithQueryOwner(ctx, 1))
+	assert.Equal(t, "a@example.com", result.Rows[0]["email"])
+	assert.Equal(t, 10, result.Rows[0]["amount"])
+
+	// context中标记的角色优先
+	result = run(WithQueryRole(WithQueryOwner(ctx, 1), string(repository.RoleUser)))
+	assert.Equal(t, maskedValue, result.Rows[0]["email"])
+
+	// 没有发起用户（如外部渠道）时受限列一律过滤
+	result = run(ctx)
+	assert.Empty(t, result.Columns)
+	assert.Empty(t, result.Rows[0])
+
+	// 缓存原始结果的调用方自行应用策略
+	result = run(WithUnfilteredResult(WithQueryOwner(ctx, 7)))
+	assert.Equal(t, "a@example.com", result.Rows[0]["email"])
+
+	// 已应用过的结果不重复处理
+	result = run(WithQueryOwner(ctx, 7))
+	assert.Empty(t, NewColumnPolicy(testClassifications(), "user").Apply(result, nil))
+	assert.Len(t, result.Warnings, 2)
+}
+
+func TestClassificationService_Tag(t *testing.T) {
+	connection := testutil.NewConnection(1, 7)
+	repo := &memClassificationRepository{}
+	svc := NewClassificationService(repo, &stubConnectionRepository{connection: connection}, zaptest.NewLogger(t))
+	ctx := context.Background()
+
+	column := &repository.ColumnClassification{ConnectionID: 1, SchemaName: "public", TableName: "customers", ColumnName: "email", Classification: "pii"}
+	require.NoError(t, svc.Tag(ctx, 7, column))
+	require.NotNil(t, column.UpdateBy)
+
+	policy, err := svc.Policy(ctx, 1, 7, "user")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"public.customers.email (pii)"}, policy.Restricted())
+
+	err = svc.Tag(ctx, 8, &repository.ColumnClassification{ConnectionID: 1, TableName: "customers", ColumnName: "phone", Classification: "pii"})
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+
+	err = svc.Tag(ctx, 7, &repository.ColumnClassification{ConnectionID: 1, TableName: "customers", ColumnName: "phone", Classification: "secret"})
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	require.NoError(t, svc.Tag(ctx, 7, &repository.ColumnClassification{ConnectionID: 1, SchemaName: "public", TableName: "customers", ColumnName: "email"}))
+	items, err := svc.List(ctx, 7, 1)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestRestrictedColumnsSection(t *testing.T) {
+	assert.Empty(t, restrictedColumnsSection(nil))
+
+	section := restrictedColumnsSection([]string{"public.customers.email (pii)"})
+	assert.Contains(t, section, "受限列")
+	assert.Contains(t, section, "- public.customers.email (pii)")
+}
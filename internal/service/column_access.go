@@ -0,0 +1,266 @@
+// 列访问申请
+// 分级策略脱敏或过滤了用户需要的列时，结果的防护措施附带访问申请说明；用户据此提交申请，
+// 由数据所有者（连接所有者）授予有期限的例外或驳回，所有者也可以提前收回授权。
+// 授权到期后自动恢复默认策略，申请、授权、驳回与收回均记录审计事件
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+const (
+	// ColumnAccessRequestEndpoint 提交列访问申请的接口
+	ColumnAccessRequestEndpoint = "/api/v1/column-access/requests"
+	// DefaultColumnAccessDuration 授权未指定有效期时的默认有效期
+	DefaultColumnAccessDuration = 7 * 24 * time.Hour
+	// MaxColumnAccessDuration 授权有效期上限，长期需要访问时应调整列分级或用户角色
+	MaxColumnAccessDuration = 90 * 24 * time.Hour
+)
+
+// ColumnAccessHint 受限列的访问申请说明，客户端据此引导用户提交申请
+type ColumnAccessHint struct {
+	Endpoint string        `json:"endpoint"` // 提交申请的接口
+	Columns  []*ColumnRule `json:"columns"`  // 导致脱敏或过滤的源列
+}
+
+// newColumnAccessHint 创建访问申请说明
+func newColumnAccessHint() *ColumnAccessHint {
+	return &ColumnAccessHint{Endpoint: ColumnAccessRequestEndpoint}
+}
+
+// add 记录受限源列，同一列只记录一次
+func (h *ColumnAccessHint) add(rule *ColumnRule) {
+	for _, existing := range h.Columns {
+		if existing == rule {
+			return
+		}
+	}
+	h.Columns = append(h.Columns, rule)
+}
+
+// ColumnAccessService 列访问申请服务
+type ColumnAccessService struct {
+	repo            repository.ColumnAccessRepository
+	classifications repository.ClassificationRepository
+	connectionRepo  repository.ConnectionRepository
+	logger          *zap.Logger
+	now             func() time.Time
+}
+
+// NewColumnAccessService 创建列访问申请服务
+func NewColumnAccessService(repo repository.ColumnAccessRepository, classifications repository.ClassificationRepository, connectionRepo repository.ConnectionRepository, logger *zap.Logger) *ColumnAccessService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ColumnAccessService{
+		repo:            repo,
+		classifications: classifications,
+		connectionRepo:  connectionRepo,
+		logger:          logger,
+		now:             time.Now,
+	}
+}
+
+// Request 提交列访问申请；只能申请当前角色下被脱敏或过滤的列，连接所有者直接调整列分级即可
+func (s *ColumnAccessService) Request(ctx context.Context, userID int64, role string, req *repository.ColumnAccessRequest) error {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len([]rune(req.Reason)) > 500 {
+		return fmt.Errorf("申请理由不能为空且不超过500个字符: %w", repository.ErrInvalidInput)
+	}
+	if req.SchemaName == "" {
+		req.SchemaName = "public"
+	}
+
+	connection, err := s.connectionRepo.GetByID(ctx, req.ConnectionID)
+	if err != nil {
+		return err
+	}
+	if connection.UserID == userID {
+		return fmt.Errorf("连接所有者可以直接调整列分级，无需申请: %w", repository.ErrInvalidInput)
+	}
+
+	classification, err := s.classification(ctx, req)
+	if err != nil {
+		return err
+	}
+	if DefaultColumnAction(repository.DataClassification(classification.Classification), role) == ColumnAllow {
+		return fmt.Errorf("当前角色已可查看列%s.%s.%s，无需申请: %w", req.SchemaName, req.TableName, req.ColumnName, repository.ErrInvalidInput)
+	}
+
+	// 统一使用分级标签中的列名大小写，构建策略时按列匹配授权
+	req.SchemaName = classification.SchemaName
+	req.TableName = classification.TableName
+	req.ColumnName = classification.ColumnName
+	req.Classification = classification.Classification
+	req.RequestedBy = userID
+	req.Status = string(repository.ColumnAccessPending)
+	req.DecidedBy, req.DecidedTime, req.DecisionComment, req.ExpiresAt = nil, nil, nil, nil
+	if err := s.repo.Create(ctx, req); err != nil {
+		return err
+	}
+	s.audit(ctx, req.ID, userID, repository.ColumnAccessEventCreated, req.Reason)
+
+	s.logger.Info("列访问申请已提交",
+		zap.Int64("request_id", req.ID),
+		zap.Int64("connection_id", req.ConnectionID),
+		zap.String("column", req.SchemaName+"."+req.TableName+"."+req.ColumnName),
+		zap.Int64("requested_by", userID))
+	return nil
+}
+
+// Grant 授予有期限的访问例外，duration为0时使用默认有效期；只有连接所有者可以操作
+func (s *ColumnAccessService) Grant(ctx context.Context, ownerID, id int64, duration time.Duration, comment string) (*repository.ColumnAccessRequest, error) {
+	if duration == 0 {
+		duration = DefaultColumnAccessDuration
+	}
+	if duration < time.Hour || duration > MaxColumnAccessDuration {
+		return nil, fmt.Errorf("授权有效期必须在1小时到%d天之间: %w", int(MaxColumnAccessDuration.Hours()/24), repository.ErrInvalidInput)
+	}
+
+	expiresAt := s.now().Add(duration).UTC()
+	detail := "有效期至" + expiresAt.Format(time.RFC3339)
+	if comment != "" {
+		detail += "；" + comment
+	}
+	return s.decide(ctx, ownerID, id, repository.ColumnAccessGranted, comment, &expiresAt, detail)
+}
+
+// Reject 驳回访问申请；只有连接所有者可以操作
+func (s *ColumnAccessService) Reject(ctx context.Context, ownerID, id int64, comment string) (*repository.ColumnAccessRequest, error) {
+	return s.decide(ctx, ownerID, id, repository.ColumnAccessRejected, comment, nil, comment)
+}
+
+// Revoke 提前收回生效中的授权；只有连接所有者可以操作
+func (s *ColumnAccessService) Revoke(ctx context.Context, ownerID, id int64, comment string) (*repository.ColumnAccessRequest, error) {
+	req, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(ctx, ownerID, req.ConnectionID); err != nil {
+		return nil, err
+	}
+	if !req.IsActive(s.now()) {
+		return nil, fmt.Errorf("列访问授权不存在或已失效: %w", repository.ErrNotFound)
+	}
+
+	if err := s.repo.Revoke(ctx, id, ownerID, optionalString(comment)); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, id, ownerID, string(repository.ColumnAccessRevoked), comment)
+
+	s.logger.Info("列访问授权已收回",
+		zap.Int64("request_id", id),
+		zap.Int64("revoked_by", ownerID),
+		zap.Int64("requested_by", req.RequestedBy))
+	return s.repo.GetByID(ctx, id)
+}
+
+// Get 获取访问申请及其审计事件，只有申请人与连接所有者可以查看
+func (s *ColumnAccessService) Get(ctx context.Context, userID, id int64) (*repository.ColumnAccessRequest, []*repository.ColumnAccessEvent, error) {
+	req, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.RequestedBy != userID {
+		if err := s.checkOwner(ctx, userID, req.ConnectionID); err != nil {
+			return nil, nil, fmt.Errorf("列访问申请不存在: %w", repository.ErrNotFound)
+		}
+	}
+
+	events, err := s.repo.ListEvents(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, events, nil
+}
+
+// ListMine 列出用户提交的申请
+func (s *ColumnAccessService) ListMine(ctx context.Context, userID int64, limit, offset int) ([]*repository.ColumnAccessRequest, error) {
+	return s.repo.ListByRequester(ctx, userID, limit, offset)
+}
+
+// ListPending 列出用户作为连接所有者待处理的申请
+func (s *ColumnAccessService) ListPending(ctx context.Context, ownerID int64, limit, offset int) ([]*repository.ColumnAccessRequest, error) {
+	return s.repo.ListPendingByOwner(ctx, ownerID, limit, offset)
+}
+
+// decide 校验所有者身份并记录处理结果
+func (s *ColumnAccessService) decide(ctx context.Context, ownerID, id int64, status repository.ColumnAccessStatus, comment string, expiresAt *time.Time, detail string) (*repository.ColumnAccessRequest, error) {
+	req, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(ctx, ownerID, req.ConnectionID); err != nil {
+		return nil, err
+	}
+	if req.RequestedBy == ownerID {
+		return nil, ErrSelfApproval
+	}
+	if req.Status != string(repository.ColumnAccessPending) {
+		return nil, fmt.Errorf("列访问申请已处理: %w", repository.ErrNotFound)
+	}
+
+	if err := s.repo.Decide(ctx, id, ownerID, status, optionalString(comment), expiresAt); err != nil {
+		return nil, err
+	}
+	s.audit(ctx, id, ownerID, string(status), detail)
+
+	s.logger.Info("列访问申请已处理",
+		zap.Int64("request_id", id),
+		zap.Int64("decided_by", ownerID),
+		zap.Int64("requested_by", req.RequestedBy),
+		zap.String("status", string(status)))
+	return s.repo.GetByID(ctx, id)
+}
+
+// classification 查找申请列的分级标签，未标注的列不受限制，无需申请
+func (s *ColumnAccessService) classification(ctx context.Context, req *repository.ColumnAccessRequest) (*repository.ColumnClassification, error) {
+	classifications, err := s.classifications.ListByConnection(ctx, req.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range classifications {
+		if strings.EqualFold(c.SchemaName, req.SchemaName) && strings.EqualFold(c.TableName, req.TableName) &&
+			strings.EqualFold(c.ColumnName, req.ColumnName) {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("列%s.%s.%s未标注分级，无需申请: %w", req.SchemaName, req.TableName, req.ColumnName, repository.ErrInvalidInput)
+}
+
+// checkOwner 校验用户是连接所有者，即列数据的所有者
+func (s *ColumnAccessService) checkOwner(ctx context.Context, userID, connectionID int64) error {
+	connection, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if err != nil || connection.UserID != userID {
+		return fmt.Errorf("只有数据库连接%d的所有者可以处理访问申请: %w", connectionID, repository.ErrPermissionDenied)
+	}
+	return nil
+}
+
+// audit 记录审计事件，失败只记录日志，不影响主流程
+func (s *ColumnAccessService) audit(ctx context.Context, requestID, actorID int64, action, detail string) {
+	event := &repository.ColumnAccessEvent{
+		RequestID: requestID,
+		ActorID:   &actorID,
+		Action:    action,
+		Detail:    optionalString(detail),
+	}
+	if err := s.repo.AddEvent(ctx, event); err != nil {
+		s.logger.Error("记录列访问审计事件失败",
+			zap.Int64("request_id", requestID),
+			zap.String("action", action),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// memColumnAccessRepository 内存列访问申请Repository
+type memColumnAccessRepository struct {
+	requests map[int64]*repository.ColumnAccessRequest
+	events   []*repository.ColumnAccessEvent
+	nextID   int64
+}
+
+func (m *memColumnAccessRepository) Create(ctx context.Context, req *repository.ColumnAccessRequest) error {
+	for _, r := range m.requests {
+		if r.Status == string(repository.ColumnAccessPending) && r.RequestedBy == req.RequestedBy &&
+			r.ConnectionID == req.ConnectionID && r.TableName == req.TableName && r.ColumnName == req.ColumnName {
+			return repository.ErrDuplicateEntry
+		}
+	}
+	m.nextID++
+	req.ID = m.nextID
+	copied := *req
+	m.requests[req.ID] = &copied
+	return nil
+}
+
+func (m *memColumnAccessRepository) GetByID(ctx context.Context, id int64) (*repository.ColumnAccessRequest, error) {
+	if r, ok := m.requests[id]; ok {
+		copied := *r
+		return &copied, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memColumnAccessRepository) ListByRequester(ctx context.Context, userID int64, limit, offset int) ([]*repository.ColumnAccessRequest, error) {
+	var requests []*repository.ColumnAccessRequest
+	for _, r := range m.requests {
+		if r.RequestedBy == userID {
+			requests = append(requests, r)
+		}
+	}
+	return requests, nil
+}
+
+func (m *memColumnAccessRepository) ListPendingByOwner(ctx context.Context, ownerID int64, limit, offset int) ([]*repository.ColumnAccessRequest, error) {
+	return nil, nil
+}
+
+func (m *memColumnAccessRepository) ListActiveGrants(ctx context.Context, connectionID, userID int64, now time.Time) ([]*repository.ColumnAccessRequest, error) {
+	var grants []*repository.ColumnAccessRequest
+	for _, r := range m.requests {
+		if r.ConnectionID == connectionID && r.RequestedBy == userID && r.IsActive(now) {
+			grants = append(grants, r)
+		}
+	}
+	return grants, nil
+}
+
+func (m *memColumnAccessRepository) Decide(ctx context.Context, id, deciderID int64, status repository.ColumnAccessStatus, comment *string, expiresAt *time.Time) error {
+	r, ok := m.requests[id]
+	if !ok || r.Status != string(repository.ColumnAccessPending) {
+		return repository.ErrNotFound
+	}
+	r.Status = string(status)
+	r.DecidedBy = &deciderID
+	r.DecisionComment = comment
+	r.ExpiresAt = expiresAt
+	return nil
+}
+
+func (m *memColumnAccessRepository) Revoke(ctx context.Context, id, revokeBy int64, comment *string) error {
+	r, ok := m.requests[id]
+	if !ok || r.Status != string(repository.ColumnAccessGranted) {
+		return repository.ErrNotFound
+	}
+	r.Status = string(repository.ColumnAccessRevoked)
+	return nil
+}
+
+func (m *memColumnAccessRepository) AddEvent(ctx context.Context, event *repository.ColumnAccessEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memColumnAccessRepository) ListEvents(ctx context.Context, requestID int64) ([]*repository.ColumnAccessEvent, error) {
+	var events []*repository.ColumnAccessEvent
+	for _, e := range m.events {
+		if e.RequestID == requestID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func TestColumnAccessService_Workflow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	connection := testutil.NewConnection(1, 7)
+	connections := &stubConnectionRepository{connection: connection}
+	classifications := &memClassificationRepository{items: testClassifications()}
+	grants := &memColumnAccessRepository{requests: map[int64]*repository.ColumnAccessRequest{}}
+
+	s := NewColumnAccessService(grants, classifications, connections, zaptest.NewLogger(t))
+	s.now = func() time.Time { return now }
+	policies := NewClassificationService(classifications, connections, zaptest.NewLogger(t))
+	policies.SetColumnAccessRepository(grants)
+	policies.now = func() time.Time { return now }
+
+	// 用户8的财务列被过滤，按分级标签中的列名提交申请
+	req := &repository.ColumnAccessRequest{ConnectionID: 1, TableName: "ORDERS", ColumnName: "Amount", Reason: " 季度对账 "}
+	require.NoError(t, s.Request(ctx, 8, "user", req))
+	assert.Equal(t, "public", req.SchemaName)
+	assert.Equal(t, "amount", req.ColumnName)
+	assert.Equal(t, "financial", req.Classification)
+	assert.Equal(t, "季度对账", req.Reason)
+
+	dup := &repository.ColumnAccessRequest{ConnectionID: 1, TableName: "orders", ColumnName: "amount", Reason: "again"}
+	assert.ErrorIs(t, s.Request(ctx, 8, "user", dup), repository.ErrDuplicateEntry)
+
+	// 只有连接所有者可以授权，有效期受上限约束
+	_, err := s.Grant(ctx, 9, req.ID, 0, "")
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+	_, err = s.Grant(ctx, 7, req.ID, MaxColumnAccessDuration+time.Hour, "")
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	granted, err := s.Grant(ctx, 7, req.ID, 24*time.Hour, "仅限本季度")
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.ColumnAccessGranted), granted.Status)
+	assert.Equal(t, now.Add(24*time.Hour), *granted.ExpiresAt)
+	_, err = s.Reject(ctx, 7, req.ID, "")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "已处理的申请不能再次处理")
+
+	// 授权生效期间该列原样返回，其余受限列不受影响
+	policy, err := policies.Policy(ctx, 1, 8, "user")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"public.customers.email (pii)"}, policy.Restricted())
+	other, err := policies.Policy(ctx, 1, 10, "user")
+	require.NoError(t, err)
+	assert.Len(t, other.Restricted(), 2, "授权只对申请人生效")
+
+	// 到期后恢复默认策略
+	now = now.Add(25 * time.Hour)
+	policy, err = policies.Policy(ctx, 1, 8, "user")
+	require.NoError(t, err)
+	assert.Len(t, policy.Restricted(), 2)
+	_, err = s.Revoke(ctx, 7, req.ID, "")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "已到期的授权不能收回")
+
+	// 申请人与所有者可以查看审计事件，其他用户看不到申请
+	_, events, err := s.Get(ctx, 8, req.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, repository.ColumnAccessEventCreated, events[0].Action)
+	assert.Equal(t, string(repository.ColumnAccessGranted), events[1].Action)
+	assert.Contains(t, *events[1].Detail, "仅限本季度")
+	_, _, err = s.Get(ctx, 7, req.ID)
+	require.NoError(t, err)
+	_, _, err = s.Get(ctx, 10, req.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestColumnAccessService_RequestValidation(t *testing.T) {
+	ctx := context.Background()
+	connection := testutil.NewConnection(1, 7)
+	grants := &memColumnAccessRepository{requests: map[int64]*repository.ColumnAccessRequest{}}
+	s := NewColumnAccessService(grants, &memClassificationRepository{items: testClassifications()}, &stubConnectionRepository{connection: connection}, zaptest.NewLogger(t))
+
+	request := func(userID int64, role, table, column, reason string) error {
+		return s.Request(ctx, userID, role, &repository.ColumnAccessRequest{ConnectionID: 1, TableName: table, ColumnName: column, Reason: reason})
+	}
+
+	assert.ErrorIs(t, request(8, "user", "customers", "email", " "), repository.ErrInvalidInput, "缺少申请理由")
+	assert.ErrorIs(t, request(8, "user", "customers", "name", "need it"), repository.ErrInvalidInput, "未标注分级的列无需申请")
+	assert.ErrorIs(t, request(8, "user", "orders", "cost_center", "need it"), repository.ErrInvalidInput, "当前角色已可查看")
+	assert.ErrorIs(t, request(8, "admin", "customers", "email", "need it"), repository.ErrInvalidInput)
+	assert.ErrorIs(t, request(7, "user", "customers", "email", "need it"), repository.ErrInvalidInput, "连接所有者直接调整分级")
+	assert.ErrorIs(t, s.Request(ctx, 8, "user", &repository.ColumnAccessRequest{ConnectionID: 2, TableName: "customers", ColumnName: "email", Reason: "x"}), repository.ErrNotFound)
+
+	// viewer的内部数据也被过滤，可以申请
+	require.NoError(t, request(8, "viewer", "orders", "cost_center", "need it"))
+	req, err := s.Reject(ctx, 7, 1, "请改用汇总报表")
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.ColumnAccessRejected), req.Status)
+	assert.Nil(t, req.ExpiresAt)
+	assert.Len(t, grants.events, 2)
+}
@@ -0,0 +1,334 @@
+// 结果列的展示名
+// 模型生成的聚合列常带有sum_1、count这类无意义别名，直接作为表头对业务用户不友好；
+// 按问题中的措辞、数据字典中的列注释与列来源推导展示名，与原始列名一起返回，不改写SQL
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// 展示名来源
+const (
+	LabelSourceQuestion  = "question"  // 问题中对应聚合的措辞
+	LabelSourceGlossary  = "glossary"  // 数据字典中的列注释
+	LabelSourceAggregate = "aggregate" // 聚合函数与来源列组合
+	LabelSourceName      = "name"      // 由列名拆词得到
+)
+
+// ColumnLabel 结果列的展示名
+type ColumnLabel struct {
+	Column string `json:"column"` // 原始列名，取行数据时使用
+	Label  string `json:"label"`  // 展示名，用作表头
+	Source string `json:"source"` // 展示名来源
+}
+
+// genericAlias 数据库或模型生成的无意义列名，如sum_1、count、?column?
+var genericAlias = regexp.MustCompile(`(?i)^(?:\?column\?|(?:sum|avg|count|min|max|total|agg|expr|col|column|value|f)_?\d*)$`)
+
+// aggregateTemplate 聚合列展示名模板，%s为来源列的展示名；Bare用于COUNT(*)等没有来源列的聚合
+type aggregateTemplate struct {
+	Format string
+	Bare   string
+}
+
+// aggregateTemplates 按语言的聚合列展示名模板
+var aggregateTemplates = map[string]map[string]aggregateTemplate{
+	"zh": {
+		"SUM":   {Format: "总%s", Bare: "合计"},
+		"AVG":   {Format: "平均%s", Bare: "平均值"},
+		"COUNT": {Format: "%s数量", Bare: "数量"},
+		"MIN":   {Format: "最小%s", Bare: "最小值"},
+		"MAX":   {Format: "最大%s", Bare: "最大值"},
+	},
+	"en": {
+		"SUM":   {Format: "Total %s", Bare: "Total"},
+		"AVG":   {Format: "Average %s", Bare: "Average"},
+		"COUNT": {Format: "Number of %s", Bare: "Count"},
+		"MIN":   {Format: "Min %s", Bare: "Min"},
+		"MAX":   {Format: "Max %s", Bare: "Max"},
+	},
+}
+
+// questionAggregatePatterns 问题中聚合的措辞，第一个分组为完整措辞
+var questionAggregatePatterns = map[string]map[string]*regexp.Regexp{
+	"zh": {
+		"SUM":   regexp.MustCompile(`(总(?:[^共计数\p{P}\s][\p{Han}]{0,5}?))(?:是多少|多少|分别|按|的|和|与|及|$)`),
+		"AVG":   regexp.MustCompile(`(平均[\p{Han}]{1,6}?)(?:是多少|多少|分别|按|的|和|与|及|$)`),
+		"MIN":   regexp.MustCompile(`(最(?:小|低)[\p{Han}]{1,6}?)(?:是多少|多少|分别|按|的|和|与|及|$)`),
+		"MAX":   regexp.MustCompile(`(最(?:大|高)[\p{Han}]{1,6}?)(?:是多少|多少|分别|按|的|和|与|及|$)`),
+		"COUNT": regexp.MustCompile(`([^\P{Han}的每各个]{1,4}?(?:数量|个数|次数))`),
+	},
+	"en": {
+		"SUM":   regexp.MustCompile(`(?i)\b((?:total|sum of)(?: [a-z]+){1,2})`),
+		"AVG":   regexp.MustCompile(`(?i)\b((?:average|avg|mean)(?: [a-z]+){1,2})`),
+		"MIN":   regexp.MustCompile(`(?i)\b((?:min|minimum|lowest|smallest)(?: [a-z]+){1,2})`),
+		"MAX":   regexp.MustCompile(`(?i)\b((?:max|maximum|highest|largest)(?: [a-z]+){1,2})`),
+		"COUNT": regexp.MustCompile(`(?i)\b((?:number|count) of(?: [a-z]+){1,2})`),
+	},
+}
+
+// phraseStopWords 英文措辞在这些词处截断，如 total revenue by city 只取 total revenue
+var phraseStopWords = map[string]bool{
+	"by": true, "per": true, "for": true, "of": true, "in": true, "from": true, "across": true,
+	"each": true, "and": true, "with": true, "over": true, "last": true, "this": true, "is": true, "are": true,
+}
+
+// labelAcronyms 拆词后整体大写的缩写
+var labelAcronyms = map[string]bool{"id": true, "url": true, "ip": true, "sku": true, "api": true, "utc": true}
+
+// ColumnLabeler 推导结果列的展示名
+type ColumnLabeler struct {
+	schemaRepo repository.SchemaRepository
+	logger     *zap.Logger
+}
+
+// NewColumnLabeler 创建结果列展示名推导器，schemaRepo为nil时不使用数据字典
+func NewColumnLabeler(schemaRepo repository.SchemaRepository, logger *zap.Logger) *ColumnLabeler {
+	return &ColumnLabeler{
+		schemaRepo: schemaRepo,
+		logger:     logger,
+	}
+}
+
+// Label 按列顺序返回每列的展示名
+// 聚合列的别名无意义时优先使用问题中对应的措辞，其次为聚合函数与来源列组合；
+// 直接引用的列使用与问题同语言的列注释；其余按列名拆词。locale为空时按问题文本判断
+func (l *ColumnLabeler) Label(ctx context.Context, connectionID int64, question, locale string, columns []string, lineage []ColumnLineage) []ColumnLabel {
+	if len(columns) == 0 {
+		return nil
+	}
+	if locale == "" {
+		locale = DetectLocale(question)
+	}
+	if locale != "en" {
+		locale = "zh"
+	}
+
+	lineageByColumn := make(map[string]ColumnLineage, len(lineage))
+	for _, item := range lineage {
+		lineageByColumn[strings.ToLower(item.Column)] = item
+	}
+	glossary := l.glossary(ctx, connectionID, locale)
+	phrases := questionPhrases(question, locale)
+
+	labels := make([]ColumnLabel, len(columns))
+	for i, column := range columns {
+		labels[i] = ColumnLabel{Column: column, Label: humanizeColumn(column), Source: LabelSourceName}
+
+		item, ok := lineageByColumn[strings.ToLower(column)]
+		if !ok {
+			if term := glossary.lookup(column); term != "" {
+				labels[i].Label, labels[i].Source = term, LabelSourceGlossary
+			}
+			continue
+		}
+
+		switch {
+		case item.Aggregate != "" && genericAlias.MatchString(column):
+			if phrase := phrases[item.Aggregate]; phrase != "" {
+				labels[i].Label, labels[i].Source = phrase, LabelSourceQuestion
+				delete(phrases, item.Aggregate) // 同一措辞只用于一列
+				continue
+			}
+			if label, ok := aggregateLabel(item, glossary, locale); ok {
+				labels[i].Label, labels[i].Source = label, LabelSourceAggregate
+			}
+		case !item.Computed && len(item.SourceColumns) == 1:
+			if term := glossary.lookup(item.SourceColumns[0]); term != "" {
+				labels[i].Label, labels[i].Source = term, LabelSourceGlossary
+			}
+		}
+	}
+	return labels
+}
+
+// columnGlossary 列注释，键为小写的 table.column 与 column（列名在多张表中注释不同时不收录）
+type columnGlossary map[string]string
+
+func (g columnGlossary) lookup(column string) string {
+	column = strings.ToLower(column)
+	if term, ok := g[column]; ok {
+		return term
+	}
+	if idx := strings.LastIndex(column, "."); idx >= 0 {
+		if term, ok := g[column[idx+1:]]; ok {
+			return term
+		}
+	}
+	return ""
+}
+
+// glossary 读取连接数据字典中与locale同语言的列注释，读取失败时不使用数据字典
+func (l *ColumnLabeler) glossary(ctx context.Context, connectionID int64, locale string) columnGlossary {
+	glossary := columnGlossary{}
+	if l.schemaRepo == nil || connectionID <= 0 {
+		return glossary
+	}
+
+	metadata, err := l.schemaRepo.ListByConnection(ctx, connectionID)
+	if err != nil {
+		l.logger.Warn("读取列注释失败", zap.Int64("connection_id", connectionID), zap.Error(err))
+		return glossary
+	}
+
+	ambiguous := make(map[string]bool)
+	for _, column := range metadata {
+		if column.ColumnComment == nil {
+			continue
+		}
+		term := strings.TrimSpace(*column.ColumnComment)
+		// 注释常带有说明，只取第一句作为术语
+		if idx := strings.IndexAny(term, "，。,;；（(\n"); idx > 0 {
+			term = strings.TrimSpace(term[:idx])
+		}
+		if term == "" || (containsHan(term) != (locale == "zh")) {
+			continue
+		}
+
+		name := strings.ToLower(column.ColumnName)
+		glossary[strings.ToLower(column.TableName)+"."+name] = term
+		if existing, ok := glossary[name]; ok && existing != term {
+			ambiguous[name] = true
+		}
+		glossary[name] = term
+	}
+	for name := range ambiguous {
+		delete(glossary, name)
+	}
+	return glossary
+}
+
+// questionPhrases 提取问题中各聚合函数对应的措辞，每种聚合只取第一处
+func questionPhrases(question, locale string) map[string]string {
+	phrases := make(map[string]string)
+	for aggregate, pattern := range questionAggregatePatterns[locale] {
+		m := pattern.FindStringSubmatch(question)
+		if m == nil {
+			continue
+		}
+		phrase := m[1]
+		if locale == "en" {
+			words := strings.Fields(strings.ToLower(phrase))
+			kept := words[:1]
+			for _, word := range words[1:] {
+				if phraseStopWords[word] && !(word == "of" && len(kept) == 1) {
+					break
+				}
+				kept = append(kept, word)
+			}
+			if len(kept) < 2 || kept[len(kept)-1] == "of" {
+				continue
+			}
+			phrase = titleWords(kept)
+		}
+		phrases[aggregate] = phrase
+	}
+	return phrases
+}
+
+// aggregateLabel 由聚合函数与单一来源列组合展示名，COUNT(*)等没有来源列时只用聚合名
+func aggregateLabel(item ColumnLineage, glossary columnGlossary, locale string) (string, bool) {
+	template, ok := aggregateTemplates[locale][item.Aggregate]
+	if !ok {
+		return "", false
+	}
+	switch len(item.SourceColumns) {
+	case 0:
+		return template.Bare, true
+	case 1:
+		subject := glossary.lookup(item.SourceColumns[0])
+		if subject == "" {
+			source := item.SourceColumns[0]
+			subject = humanizeColumn(source[strings.LastIndex(source, ".")+1:])
+		}
+		return strings.Replace(template.Format, "%s", subject, 1), true
+	default:
+		return template.Bare, true
+	}
+}
+
+// humanizeColumn 将snake_case或camelCase列名拆为首字母大写的单词，含汉字的列名原样返回
+func humanizeColumn(column string) string {
+	if containsHan(column) {
+		return column
+	}
+
+	var words []string
+	var current []rune
+	runes := []rune(column)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ' || r == '.':
+			if len(current) > 0 {
+				words = append(words, string(current))
+				current = nil
+			}
+		case unicode.IsUpper(r) && len(current) > 0 && (unicode.IsLower(current[len(current)-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			words = append(words, string(current))
+			current = []rune{r}
+		default:
+			current = append(current, r)
+		}
+	}
+	if len(current) > 0 {
+		words = append(words, string(current))
+	}
+	if len(words) == 0 {
+		return column
+	}
+	return titleWords(words)
+}
+
+// titleWords 单词首字母大写后以空格连接，缩写整体大写，非首词的of保持小写
+func titleWords(words []string) string {
+	titled := make([]string, len(words))
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		if i > 0 && lower == "of" {
+			titled[i] = lower
+			continue
+		}
+		if labelAcronyms[lower] {
+			titled[i] = strings.ToUpper(lower)
+			continue
+		}
+		runes := []rune(lower)
+		runes[0] = unicode.ToUpper(runes[0])
+		titled[i] = string(runes)
+	}
+	return strings.Join(titled, " ")
+}
+
+// containsHan 是否包含汉字
+func containsHan(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// ColumnHeaders 按列顺序返回表头，没有对应展示名的列使用原始列名
+func ColumnHeaders(columns []string, labels []ColumnLabel) []string {
+	byColumn := make(map[string]string, len(labels))
+	for _, label := range labels {
+		byColumn[label.Column] = label.Label
+	}
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column
+		if label, ok := byColumn[column]; ok && label != "" {
+			headers[i] = label
+		}
+	}
+	return headers
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+func TestColumnLabeler_Label(t *testing.T) {
+	cityComment, amountComment := "城市", "订单金额，含税"
+	schemaRepo := &MockSchemaRepository{}
+	schemaRepo.On("ListByConnection", mock.Anything, int64(1)).Return([]*repository.SchemaMetadata{
+		{SchemaName: "public", TableName: "orders", ColumnName: "city", ColumnComment: &cityComment},
+		{SchemaName: "public", TableName: "orders", ColumnName: "amount", ColumnComment: &amountComment},
+	}, nil)
+	labeler := NewColumnLabeler(schemaRepo, zaptest.NewLogger(t))
+	validator := NewSQLSecurityValidator(zap.NewNop())
+
+	label := func(question, sql string) map[string]ColumnLabel {
+		lineage := validator.ExtractColumnLineage(sql)
+		columns := make([]string, len(lineage))
+		for i, item := range lineage {
+			columns[i] = item.Column
+		}
+		labels := make(map[string]ColumnLabel)
+		for _, l := range labeler.Label(context.Background(), 1, question, "", columns, lineage) {
+			labels[l.Column] = l
+		}
+		return labels
+	}
+
+	labels := label("What is the total revenue by city?", "SELECT city, SUM(amount) AS sum_1 FROM orders GROUP BY city")
+	assert.Equal(t, ColumnLabel{Column: "sum_1", Label: "Total Revenue", Source: LabelSourceQuestion}, labels["sum_1"])
+	assert.Equal(t, ColumnLabel{Column: "city", Label: "City", Source: LabelSourceName}, labels["city"], "英文问题不使用中文列注释")
+
+	labels = label("每个城市的订单金额合计", "SELECT o.city, SUM(o.amount) AS sum FROM orders o GROUP BY o.city")
+	assert.Equal(t, ColumnLabel{Column: "city", Label: "城市", Source: LabelSourceGlossary}, labels["city"])
+	assert.Equal(t, ColumnLabel{Column: "sum", Label: "总订单金额", Source: LabelSourceAggregate}, labels["sum"], "注释只取第一句")
+
+	labels = label("每个城市的总销售额", "SELECT city, SUM(amount) AS sum_1, COUNT(*) AS count FROM orders GROUP BY city")
+	assert.Equal(t, "总销售额", labels["sum_1"].Label)
+	assert.Equal(t, "数量", labels["count"].Label)
+
+	labels = label("list order totals", "SELECT user_id, createdAt, SUM(amount) AS order_total FROM orders GROUP BY user_id, createdAt")
+	assert.Equal(t, "User ID", labels["user_id"].Label)
+	assert.Equal(t, "Created At", labels["createdAt"].Label)
+	assert.Equal(t, ColumnLabel{Column: "order_total", Label: "Order Total", Source: LabelSourceName}, labels["order_total"], "有意义的别名保持原意")
+
+	assert.Equal(t, []string{"City", "amount"}, ColumnHeaders([]string{"city", "amount"}, []ColumnLabel{{Column: "city", Label: "City"}}))
+}
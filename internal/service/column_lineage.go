@@ -0,0 +1,381 @@
+package service
+
+import (
+	"strings"
+
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+// ColumnLineage 结果列的来源信息
+// 用于前端在悬停时展示计算列的公式，例如 total = SUM(order_items.price * order_items.quantity)
+type ColumnLineage struct {
+	Column        string   `json:"column"`              // 结果列名（别名或推导出的列名）
+	Expression    string   `json:"expression"`          // SELECT列表中的原始表达式
+	SourceColumns []string `json:"source_columns"`      // 引用的来源列（已将表别名解析为表名）
+	Aggregate     string   `json:"aggregate,omitempty"` // 最外层聚合函数
+	Computed      bool     `json:"computed"`            // 是否为计算列（非直接列引用）
+}
+
+// sqlToken SQL词法单元
+type sqlToken struct {
+	text  string
+	kind  tokenKind
+	depth int // 括号嵌套深度
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+// aggregateFunctions 识别为聚合的函数
+var aggregateFunctions = map[string]bool{
+	"SUM": true, "COUNT": true, "AVG": true, "MIN": true, "MAX": true,
+	"STRING_AGG": true, "ARRAY_AGG": true, "STDDEV": true, "VARIANCE": true,
+	"BOOL_AND": true, "BOOL_OR": true, "PERCENTILE_CONT": true, "PERCENTILE_DISC": true,
+}
+
+// lineageKeywords 表达式中不视为列引用的关键词
+var lineageKeywords = map[string]bool{
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+	"AND": true, "OR": true, "NOT": true, "NULL": true, "IS": true, "IN": true,
+	"LIKE": true, "ILIKE": true, "BETWEEN": true, "TRUE": true, "FALSE": true,
+	"DISTINCT": true, "AS": true, "OVER": true, "PARTITION": true, "BY": true,
+	"ORDER": true, "ASC": true, "DESC": true, "FILTER": true, "WHERE": true,
+	"INTERVAL": true, "CURRENT_DATE": true, "CURRENT_TIMESTAMP": true, "NOW": true,
+	"EXTRACT": true, "FROM": true, "WITHIN": true, "GROUP": true, "ROWS": true,
+	"RANGE": true, "PRECEDING": true, "FOLLOWING": true, "UNBOUNDED": true, "CURRENT": true, "ROW": true,
+}
+
+// fromClauseTerminators 结束FROM子句中表引用的关键词
+var fromClauseTerminators = map[string]bool{
+	"WHERE": true, "GROUP": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "HAVING": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true,
+	"ON": true, "USING": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "WINDOW": true,
+	"NATURAL": true, "FETCH": true, "FOR": true, "LATERAL": true,
+}
+
+// ExtractColumnLineage 从SELECT语句中提取每个结果列的来源信息
+// 使用与只读校验相同的sqlvalidator词法切分；无法解析的语句返回nil，*展开的列不返回
+func (v *SQLSecurityValidator) ExtractColumnLineage(sql string) []ColumnLineage {
+	if queryType := v.detectQueryType(v.cleanSQL(sql)); queryType != "SELECT" && queryType != "WITH" {
+		return nil
+	}
+
+	tokens, err := tokenizeSQL(sql)
+	if err != nil {
+		return nil
+	}
+	selectIdx, listEnd := findMainSelect(tokens)
+	if selectIdx < 0 {
+		return nil
+	}
+
+	var tables map[string]string
+	var defaultTable string
+	if listEnd < len(tokens) && strings.EqualFold(tokens[listEnd].text, "FROM") {
+		tables, defaultTable = collectTableAliases(tokens[listEnd:])
+	}
+
+	items := splitSelectList(tokens[selectIdx+1 : listEnd])
+	lineage := make([]ColumnLineage, 0, len(items))
+	for _, item := range items {
+		if entry, ok := buildColumnLineage(item, tables, defaultTable); ok {
+			lineage = append(lineage, entry)
+		}
+	}
+	return lineage
+}
+
+// tokenizeSQL 按sqlvalidator的词法规则拆分SQL，并记录每个单元所在的括号深度
+// 注释被丢弃，带引号的标识符取引号内的内容，末尾的分号不保留
+func tokenizeSQL(sql string) ([]sqlToken, error) {
+	raw, err := sqlvalidator.Tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	for len(raw) > 0 && raw[len(raw)-1].IsPunct(";") {
+		raw = raw[:len(raw)-1]
+	}
+
+	tokens := make([]sqlToken, 0, len(raw))
+	depth := 0
+	for i := 0; i < len(raw); i++ {
+		tok := raw[i]
+		token := sqlToken{text: tok.Text, kind: tokenSymbol, depth: depth}
+		switch {
+		case tok.Kind == sqlvalidator.TokenWord:
+			token.kind = tokenIdent
+		case tok.Kind == sqlvalidator.TokenQuotedIdent:
+			token.kind, token.text = tokenQuotedIdent, tok.Value
+		case tok.Kind == sqlvalidator.TokenString:
+			token.kind = tokenString
+		case tok.Kind == sqlvalidator.TokenNumber || tok.Kind == sqlvalidator.TokenParam:
+			token.kind = tokenNumber
+		case tok.IsPunct("("):
+			depth++
+		case tok.IsPunct(")"):
+			depth = max(depth-1, 0)
+			token.depth = depth
+		case tok.IsPunct(":") && i+1 < len(raw) && raw[i+1].IsPunct(":"):
+			// 类型转换::由两个:记号组成
+			token.text = "::"
+			i++
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// selectListTerminators 结束SELECT列表的顶层关键词
+var selectListTerminators = map[string]bool{
+	"FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "WINDOW": true, "FETCH": true,
+}
+
+// findMainSelect 定位主查询（括号外第一个SELECT）及其列表的结束位置
+func findMainSelect(tokens []sqlToken) (int, int) {
+	selectIdx := -1
+	for i, tok := range tokens {
+		if tok.depth == 0 && tok.kind == tokenIdent && strings.EqualFold(tok.text, "SELECT") {
+			selectIdx = i
+			break
+		}
+	}
+	if selectIdx < 0 {
+		return -1, -1
+	}
+
+	for i := selectIdx + 1; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.depth == 0 && tok.kind == tokenIdent && selectListTerminators[strings.ToUpper(tok.text)] {
+			return selectIdx, i
+		}
+	}
+	return selectIdx, len(tokens)
+}
+
+// splitSelectList 按顶层逗号拆分SELECT列表，跳过DISTINCT / DISTINCT ON (...) / ALL
+func splitSelectList(list []sqlToken) [][]sqlToken {
+	if len(list) > 0 && list[0].kind == tokenIdent {
+		switch strings.ToUpper(list[0].text) {
+		case "ALL":
+			list = list[1:]
+		case "DISTINCT":
+			list = list[1:]
+			if len(list) > 1 && strings.EqualFold(list[0].text, "ON") && list[1].text == "(" {
+				end := 2
+				for end < len(list) && !(list[end].text == ")" && list[end].depth == 0) {
+					end++
+				}
+				list = list[min(end+1, len(list)):]
+			}
+		}
+	}
+
+	var items [][]sqlToken
+	start := 0
+	for i, tok := range list {
+		if tok.depth == 0 && tok.text == "," {
+			items = append(items, list[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(list) {
+		items = append(items, list[start:])
+	}
+	return items
+}
+
+// collectTableAliases 从FROM子句收集表别名到表名的映射
+// 仅有一张表时返回其名称，供未限定的列引用使用
+func collectTableAliases(tokens []sqlToken) (map[string]string, string) {
+	aliases := make(map[string]string)
+	var tableNames []string
+
+	expectTable := false
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.depth != 0 {
+			continue
+		}
+		upper := strings.ToUpper(tok.text)
+		if tok.kind == tokenIdent && upper != "FROM" && selectListTerminators[upper] {
+			break
+		}
+		if tok.kind == tokenIdent && (upper == "FROM" || upper == "JOIN") || tok.text == "," {
+			expectTable = true
+			continue
+		}
+		if !expectTable || (tok.kind != tokenIdent && tok.kind != tokenQuotedIdent) {
+			expectTable = false
+			continue
+		}
+		expectTable = false
+
+		// schema.table
+		name := tok.text
+		for i+2 < len(tokens) && tokens[i+1].text == "." && tokens[i+2].depth == 0 {
+			name = tokens[i+2].text
+			i += 2
+		}
+		tableNames = append(tableNames, name)
+		aliases[strings.ToLower(name)] = name
+
+		// [AS] alias
+		next := i + 1
+		if next < len(tokens) && strings.EqualFold(tokens[next].text, "AS") {
+			next++
+		}
+		if next < len(tokens) && tokens[next].depth == 0 &&
+			(tokens[next].kind == tokenQuotedIdent ||
+				tokens[next].kind == tokenIdent && !fromClauseTerminators[strings.ToUpper(tokens[next].text)]) {
+			aliases[strings.ToLower(tokens[next].text)] = name
+			i = next
+		}
+	}
+
+	if len(tableNames) == 1 {
+		return aliases, tableNames[0]
+	}
+	return aliases, ""
+}
+
+// buildColumnLineage 分析单个SELECT项
+func buildColumnLineage(item []sqlToken, tables map[string]string, defaultTable string) (ColumnLineage, bool) {
+	if len(item) == 0 {
+		return ColumnLineage{}, false
+	}
+
+	expr, alias := splitAlias(item)
+	if len(expr) == 0 {
+		return ColumnLineage{}, false
+	}
+	// * 与 t.* 无法在不查询元数据的情况下展开
+	if last := expr[len(expr)-1]; last.text == "*" && (len(expr) == 1 || len(expr) == 3 && expr[1].text == ".") {
+		return ColumnLineage{}, false
+	}
+
+	entry := ColumnLineage{
+		Expression:    joinTokens(expr),
+		SourceColumns: []string{},
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < len(expr); i++ {
+		tok := expr[i]
+		if tok.kind != tokenIdent && tok.kind != tokenQuotedIdent {
+			continue
+		}
+		upper := strings.ToUpper(tok.text)
+		// 函数名、关键词与类型转换目标不是列引用
+		if tok.kind == tokenIdent && (lineageKeywords[upper] || i+1 < len(expr) && expr[i+1].text == "(") {
+			continue
+		}
+		if i > 0 && expr[i-1].text == "::" {
+			continue
+		}
+
+		column := tok.text
+		table := defaultTable
+		if i+2 < len(expr) && expr[i+1].text == "." {
+			if expr[i+2].text == "*" {
+				i += 2
+				continue
+			}
+			if resolved, ok := tables[strings.ToLower(tok.text)]; ok {
+				table = resolved
+			} else {
+				table = tok.text
+			}
+			column = expr[i+2].text
+			i += 2
+		}
+
+		source := column
+		if table != "" {
+			source = table + "." + column
+		}
+		if !seen[source] {
+			seen[source] = true
+			entry.SourceColumns = append(entry.SourceColumns, source)
+		}
+	}
+
+	isColumnRef := len(expr) == 1 && (expr[0].kind == tokenIdent || expr[0].kind == tokenQuotedIdent) ||
+		len(expr) == 3 && expr[1].text == "." && expr[2].kind != tokenSymbol
+	entry.Computed = !isColumnRef
+
+	if expr[0].kind == tokenIdent && len(expr) > 1 && expr[1].text == "(" && expr[len(expr)-1].text == ")" &&
+		aggregateFunctions[strings.ToUpper(expr[0].text)] {
+		entry.Aggregate = strings.ToUpper(expr[0].text)
+	}
+
+	switch {
+	case alias != "":
+		entry.Column = alias
+	case isColumnRef:
+		entry.Column = expr[len(expr)-1].text
+	case expr[0].kind == tokenIdent && len(expr) > 1 && expr[1].text == "(":
+		// PostgreSQL默认以函数名作为列名
+		entry.Column = strings.ToLower(expr[0].text)
+	case strings.EqualFold(expr[0].text, "CASE"):
+		entry.Column = "case"
+	default:
+		entry.Column = "?column?"
+	}
+
+	return entry, true
+}
+
+// splitAlias 拆分表达式与别名（显式AS或末尾的裸标识符）
+func splitAlias(item []sqlToken) ([]sqlToken, string) {
+	n := len(item)
+	if n >= 3 && item[n-2].depth == 0 && strings.EqualFold(item[n-2].text, "AS") &&
+		(item[n-1].kind == tokenIdent || item[n-1].kind == tokenQuotedIdent) {
+		return item[:n-2], item[n-1].text
+	}
+
+	if n >= 2 {
+		last, prev := item[n-1], item[n-2]
+		if last.depth == 0 && (last.kind == tokenQuotedIdent || last.kind == tokenIdent && !lineageKeywords[strings.ToUpper(last.text)]) &&
+			prev.text != "." && prev.text != "::" && (prev.kind != tokenSymbol || prev.text == ")") {
+			return item[:n-1], last.text
+		}
+	}
+	return item, ""
+}
+
+// joinTokens 将词法单元还原为规范化的表达式文本
+func joinTokens(tokens []sqlToken) string {
+	var sb strings.Builder
+	for i, tok := range tokens {
+		text := tok.text
+		if tok.kind == tokenQuotedIdent {
+			text = `"` + text + `"`
+		}
+		if i > 0 && needsSpace(tokens[i-1], tok) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
+
+// needsSpace 判断两个相邻词法单元之间是否需要空格
+func needsSpace(prev, cur sqlToken) bool {
+	switch {
+	case prev.text == "(" || prev.text == "." || prev.text == "::":
+		return false
+	case cur.text == ")" || cur.text == "." || cur.text == "," || cur.text == "::":
+		return false
+	case cur.text == "(":
+		return prev.kind == tokenSymbol || lineageKeywords[strings.ToUpper(prev.text)]
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestExtractColumnLineage_ComputedColumns(t *testing.T) {
+	validator := NewSQLSecurityValidator(zap.NewNop())
+
+	lineage := validator.ExtractColumnLineage(`
+		SELECT o.customer_id,
+		       SUM(oi.price * oi.quantity) AS total,
+		       CASE WHEN o.status = 'paid' THEN 1 ELSE 0 END paid_flag,
+		       count(*)
+		FROM orders o
+		JOIN order_items AS oi ON oi.order_id = o.id
+		WHERE o.created_at > now() - interval '30 days'
+		GROUP BY o.customer_id, paid_flag`)
+	require.Len(t, lineage, 4)
+
+	assert.Equal(t, ColumnLineage{
+		Column:        "customer_id",
+		Expression:    "o.customer_id",
+		SourceColumns: []string{"orders.customer_id"},
+	}, lineage[0])
+
+	assert.Equal(t, "total", lineage[1].Column)
+	assert.Equal(t, "SUM(oi.price * oi.quantity)", lineage[1].Expression)
+	assert.Equal(t, []string{"order_items.price", "order_items.quantity"}, lineage[1].SourceColumns)
+	assert.Equal(t, "SUM", lineage[1].Aggregate)
+	assert.True(t, lineage[1].Computed)
+
+	assert.Equal(t, "paid_flag", lineage[2].Column)
+	assert.Equal(t, []string{"orders.status"}, lineage[2].SourceColumns)
+	assert.True(t, lineage[2].Computed)
+	assert.Empty(t, lineage[2].Aggregate)
+
+	assert.Equal(t, "count", lineage[3].Column)
+	assert.Equal(t, "COUNT", lineage[3].Aggregate)
+	assert.Empty(t, lineage[3].SourceColumns)
+}
+
+func TestExtractColumnLineage_SingleTableAndCTE(t *testing.T) {
+	validator := NewSQLSecurityValidator(zap.NewNop())
+
+	lineage := validator.ExtractColumnLineage(`WITH recent AS (SELECT * FROM orders WHERE id > 10)
+		SELECT DISTINCT amount::numeric / 100 AS "金额", region FROM recent ORDER BY region, amount`)
+	require.Len(t, lineage, 2)
+	assert.Equal(t, "金额", lineage[0].Column)
+	assert.Equal(t, []string{"recent.amount"}, lineage[0].SourceColumns)
+	assert.True(t, lineage[0].Computed)
+	assert.Equal(t, "region", lineage[1].Column)
+	assert.False(t, lineage[1].Computed)
+}
+
+func TestExtractColumnLineage_SkipsUnsupported(t *testing.T) {
+	validator := NewSQLSecurityValidator(zap.NewNop())
+
+	assert.Empty(t, validator.ExtractColumnLineage("SELECT * FROM users"))
+	assert.Nil(t, validator.ExtractColumnLineage("DELETE FROM users"))
+
+	lineage := validator.ExtractColumnLineage("SELECT u.*, 1 + 1 FROM users u")
+	require.Len(t, lineage, 1)
+	assert.Equal(t, "?column?", lineage[0].Column)
+	assert.Empty(t, lineage[0].SourceColumns)
+}
+
+func TestExtractColumnLineage_CommentsAndQuoting(t *testing.T) {
+	validator := NewSQLSecurityValidator(zap.NewNop())
+
+	// 注释中的逗号、字符串中的注释符与转义引号不影响列拆分
+	lineage := validator.ExtractColumnLineage(`SELECT name, -- 姓名, 备注
+		'it''s -- not a comment' AS note, /* a, b */ "Order ""Total""" FROM customers;`)
+	require.Len(t, lineage, 3)
+	assert.Equal(t, []string{"customers.name"}, lineage[0].SourceColumns)
+	assert.Equal(t, "note", lineage[1].Column)
+	assert.Equal(t, "'it''s -- not a comment'", lineage[1].Expression)
+	assert.Empty(t, lineage[1].SourceColumns)
+	assert.Equal(t, `Order "Total"`, lineage[2].Column)
+
+	assert.Nil(t, validator.ExtractColumnLineage("SELECT 'unterminated FROM users"), "无法切分的SQL不返回来源")
+}
@@ -0,0 +1,89 @@
+// SQL生成置信度合成
+// 将启发式评分、意图分类置信度与模型logprobs（可用时）合成为0-1的单一置信度。
+// 模型响应缓存按提示词精确命中，不提供相似度得分，因此不作为信号
+package service
+
+import (
+	"math"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// DefaultConfirmationThreshold 低于该置信度的SQL需要用户确认后才能执行
+const DefaultConfirmationThreshold = 0.6
+
+// 各信号权重，缺失的信号不参与计算，其余权重按比例归一
+const (
+	heuristicWeight  = 0.35
+	classifierWeight = 0.25
+	logProbWeight    = 0.2
+)
+
+// ConfidenceBreakdown 置信度构成，缺失的信号为nil
+type ConfidenceBreakdown struct {
+	Heuristic  float64  `json:"heuristic"`            // SQL结构启发式评分
+	Classifier *float64 `json:"classifier,omitempty"` // 意图分类置信度
+	LogProb    *float64 `json:"logprob,omitempty"`    // 由模型token logprobs换算的概率
+}
+
+// Combine 按可用信号的归一化权重合成最终置信度
+func (b *ConfidenceBreakdown) Combine() float64 {
+	total := heuristicWeight * clampUnit(b.Heuristic)
+	weights := heuristicWeight
+
+	for _, signal := range []struct {
+		value  *float64
+		weight float64
+	}{
+		{b.Classifier, classifierWeight},
+		{b.LogProb, logProbWeight},
+	} {
+		if signal.value == nil {
+			continue
+		}
+		total += signal.weight * clampUnit(*signal.value)
+		weights += signal.weight
+	}
+
+	return math.Round(total/weights*1000) / 1000
+}
+
+// logProbConfidence 从模型返回的GenerationInfo中读取logprobs
+// 支持逐token的"logprobs"列表或平均值"avg_logprob"，返回平均token概率
+func logProbConfidence(choice *llms.ContentChoice) *float64 {
+	if choice == nil || choice.GenerationInfo == nil {
+		return nil
+	}
+
+	var avg float64
+	switch v := choice.GenerationInfo["logprobs"].(type) {
+	case []float64:
+		if len(v) == 0 {
+			return nil
+		}
+		for _, lp := range v {
+			avg += lp
+		}
+		avg /= float64(len(v))
+	default:
+		value, ok := choice.GenerationInfo["avg_logprob"].(float64)
+		if !ok {
+			return nil
+		}
+		avg = value
+	}
+
+	probability := clampUnit(math.Exp(avg))
+	return &probability
+}
+
+// clampUnit 将数值限制在[0,1]
+func clampUnit(v float64) float64 {
+	if v < 0 || math.IsNaN(v) {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
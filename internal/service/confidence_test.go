@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+)
+
+func TestConfidenceBreakdown_Combine(t *testing.T) {
+	// 仅启发式评分时直接返回该值
+	assert.Equal(t, 0.8, (&ConfidenceBreakdown{Heuristic: 0.8}).Combine())
+
+	// 缺失的信号不拉低结果，其余权重按比例归一
+	classifier := 0.4
+	combined := (&ConfidenceBreakdown{Heuristic: 0.8, Classifier: &classifier}).Combine()
+	assert.InDelta(t, (0.35*0.8+0.25*0.4)/0.6, combined, 0.001)
+
+	// 越界的信号被截断到[0,1]
+	logProb := -0.2
+	combined = (&ConfidenceBreakdown{Heuristic: 1.5, Classifier: &classifier, LogProb: &logProb}).Combine()
+	assert.InDelta(t, (0.35+0.25*0.4)/0.8, combined, 0.001)
+}
+
+func TestLogProbConfidence(t *testing.T) {
+	assert.Nil(t, logProbConfidence(&llms.ContentChoice{}))
+
+	perToken := logProbConfidence(&llms.ContentChoice{GenerationInfo: map[string]any{"logprobs": []float64{-0.1, -0.3}}})
+	require.NotNil(t, perToken)
+	assert.InDelta(t, math.Exp(-0.2), *perToken, 1e-9)
+
+	avg := logProbConfidence(&llms.ContentChoice{GenerationInfo: map[string]any{"avg_logprob": 0.0}})
+	require.NotNil(t, avg)
+	assert.Equal(t, 1.0, *avg)
+}
+
+func TestAIService_GenerateSQL_RequiresConfirmation(t *testing.T) {
+	mock, err := ai.NewMockLLM(&ai.MockLLMRules{DefaultSQL: "SELECT 1"})
+	require.NoError(t, err)
+
+	aiConfig := config.DemoAIConfig("")
+	aiService := NewAIServiceWithClients(aiConfig, mock, mock, zaptest.NewLogger(t))
+
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "清空用户", UserID: 1})
+	require.NoError(t, err)
+	require.NotNil(t, resp.ConfidenceBreakdown)
+	assert.NotNil(t, resp.ConfidenceBreakdown.Classifier)
+	assert.Nil(t, resp.ConfidenceBreakdown.LogProb)
+	assert.Equal(t, resp.ConfidenceBreakdown.Combine(), resp.Confidence)
+	assert.True(t, resp.RequiresConfirmation, "与问题无关的低置信度结果应要求确认")
+
+	// 阈值调为0.1后不再需要确认
+	aiConfig.ConfirmationThreshold = 0.1
+	resp, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "清空用户", UserID: 1})
+	require.NoError(t, err)
+	assert.False(t, resp.RequiresConfirmation)
+}
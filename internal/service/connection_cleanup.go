@@ -0,0 +1,188 @@
+// 闲置连接清理
+// 定期找出连续N天没有查询的连接并提醒所有者；开启自动停用后，提醒后宽限期内仍未使用的连接被停用，
+// 同时关闭缓存的连接池，减少暴露的数据库凭据与占用的连接资源
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// ConnectionPoolCloser 关闭连接缓存的连接池，由ConnectionManager实现
+type ConnectionPoolCloser interface {
+	ClosePool(connectionID int64) bool
+}
+
+// ConnectionCleanupResult 一轮闲置连接检查的结果
+type ConnectionCleanupResult struct {
+	Unused   int `json:"unused"`   // 闲置的活跃连接数
+	Notified int `json:"notified"` // 本轮提醒所有者的连接数
+	Disabled int `json:"disabled"` // 本轮停用的连接数
+}
+
+// ConnectionCleanupService 闲置连接清理服务
+type ConnectionCleanupService struct {
+	connections repository.ConnectionRepository
+	users       repository.UserRepository
+	pools       ConnectionPoolCloser
+	mailer      Mailer // 可选：未配置SMTP时只记录日志
+	config      *config.ConnectionCleanupConfig
+	logger      *zap.Logger
+	now         func() time.Time
+}
+
+// NewConnectionCleanupService 创建闲置连接清理服务，mailer为nil时不发送提醒邮件，配置为nil时使用默认配置
+func NewConnectionCleanupService(
+	connections repository.ConnectionRepository,
+	users repository.UserRepository,
+	pools ConnectionPoolCloser,
+	mailer Mailer,
+	cleanupConfig *config.ConnectionCleanupConfig,
+	logger *zap.Logger,
+) *ConnectionCleanupService {
+	if cleanupConfig == nil {
+		cleanupConfig = config.DefaultConnectionCleanupConfig()
+	}
+	return &ConnectionCleanupService{
+		connections: connections,
+		users:       users,
+		pools:       pools,
+		mailer:      mailer,
+		config:      cleanupConfig,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Run 启动时与每隔CheckInterval检查一次闲置连接，直到ctx取消；由看门狗托管，每轮结束时调用beat上报心跳
+func (s *ConnectionCleanupService) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := s.Sweep(ctx); err != nil {
+			s.logger.Warn("Failed to check unused connections", zap.Error(err))
+		} else if result.Notified > 0 || result.Disabled > 0 {
+			s.logger.Info("Checked unused connections",
+				zap.Int("unused", result.Unused),
+				zap.Int("notified", result.Notified),
+				zap.Int("disabled", result.Disabled))
+		}
+		beat()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sweep 检查一轮闲置连接
+// 闲置后尚未提醒（或提醒后又使用过）的连接提醒所有者；开启自动停用时，
+// 提醒超过宽限期仍未使用的连接被停用并关闭连接池。单个连接处理失败只记录日志，下一轮重试
+func (s *ConnectionCleanupService) Sweep(ctx context.Context) (*ConnectionCleanupResult, error) {
+	now := s.now()
+	unused, err := s.connections.ListUnused(ctx, now.AddDate(0, 0, -s.config.UnusedDays))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ConnectionCleanupResult{Unused: len(unused)}
+	for _, item := range unused {
+		conn := item.Connection
+		lastActivity := item.LastActivity()
+
+		switch {
+		case item.NotifiedAt == nil || item.NotifiedAt.Before(lastActivity):
+			if err := s.notifyOwner(ctx, conn, s.unusedNotice(conn, lastActivity, now)); err != nil {
+				s.logger.Warn("Failed to notify owner of unused connection",
+					zap.Int64("connection_id", conn.ID),
+					zap.Error(err))
+				continue
+			}
+			if err := s.connections.MarkUnusedNotified(ctx, conn.ID, now); err != nil {
+				s.logger.Warn("Failed to record unused connection notice",
+					zap.Int64("connection_id", conn.ID),
+					zap.Error(err))
+				continue
+			}
+			result.Notified++
+
+		case s.config.AutoDisable && !now.Before(item.NotifiedAt.Add(s.config.GracePeriod)):
+			if err := s.connections.UpdateStatus(ctx, conn.ID, repository.ConnectionInactive); err != nil {
+				s.logger.Warn("Failed to disable unused connection",
+					zap.Int64("connection_id", conn.ID),
+					zap.Error(err))
+				continue
+			}
+			s.pools.ClosePool(conn.ID)
+			result.Disabled++
+			s.logger.Info("Disabled unused connection",
+				zap.Int64("connection_id", conn.ID),
+				zap.Int64("user_id", conn.UserID),
+				zap.Time("last_activity", lastActivity))
+
+			if err := s.notifyOwner(ctx, conn, s.disabledNotice(conn, lastActivity)); err != nil {
+				s.logger.Warn("Failed to notify owner of disabled connection",
+					zap.Int64("connection_id", conn.ID),
+					zap.Error(err))
+			}
+		}
+	}
+	return result, nil
+}
+
+// notifyOwner 给连接所有者发送提醒邮件，未配置邮件发送时只记录日志
+func (s *ConnectionCleanupService) notifyOwner(ctx context.Context, conn *repository.DatabaseConnection, email *OutboundEmail) error {
+	if s.mailer == nil {
+		s.logger.Info("Unused connection notice",
+			zap.Int64("connection_id", conn.ID),
+			zap.Int64("user_id", conn.UserID),
+			zap.String("subject", email.Subject))
+		return nil
+	}
+
+	owner, err := s.users.GetByID(ctx, conn.UserID)
+	if err != nil {
+		return fmt.Errorf("获取连接所有者失败: %w", err)
+	}
+	if owner.Email == "" {
+		return fmt.Errorf("连接所有者%d没有邮箱", owner.ID)
+	}
+	email.To = owner.Email
+	return s.mailer.Send(ctx, email)
+}
+
+// unusedNotice 闲置提醒邮件
+func (s *ConnectionCleanupService) unusedNotice(conn *repository.DatabaseConnection, lastActivity, now time.Time) *OutboundEmail {
+	body := fmt.Sprintf("您的数据库连接「%s」（ID %d）自%s起没有任何查询，已超过%d天。\n\n",
+		conn.Name, conn.ID, lastActivity.Format("2006-01-02"), s.config.UnusedDays)
+	if s.config.AutoDisable {
+		body += fmt.Sprintf("如果在%s前仍未使用，该连接将被自动停用，缓存的连接池与凭据会被清除。"+
+			"停用后可在连接管理中测试连接以重新启用。\n",
+			now.Add(s.config.GracePeriod).Format("2006-01-02"))
+	} else {
+		body += "如不再需要，请删除该连接，以减少保存的数据库凭据。\n"
+	}
+	return &OutboundEmail{
+		Subject: fmt.Sprintf("数据库连接「%s」已闲置%d天", conn.Name, s.config.UnusedDays),
+		Body:    body,
+	}
+}
+
+// disabledNotice 自动停用通知邮件
+func (s *ConnectionCleanupService) disabledNotice(conn *repository.DatabaseConnection, lastActivity time.Time) *OutboundEmail {
+	return &OutboundEmail{
+		Subject: fmt.Sprintf("数据库连接「%s」已被自动停用", conn.Name),
+		Body: fmt.Sprintf("您的数据库连接「%s」（ID %d）自%s起没有任何查询，已按闲置连接策略停用，"+
+			"缓存的连接池与凭据已清除。\n\n如需继续使用，请在连接管理中测试连接以重新启用。\n",
+			conn.Name, conn.ID, lastActivity.Format("2006-01-02")),
+	}
+}
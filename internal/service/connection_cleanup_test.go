@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// cleanupConnectionRepo 按最后一次查询时间筛选闲置连接的内存Repository
+type cleanupConnectionRepo struct {
+	repository.ConnectionRepository
+	items []*repository.UnusedConnection
+}
+
+func (r *cleanupConnectionRepo) ListUnused(_ context.Context, since time.Time) ([]*repository.UnusedConnection, error) {
+	var unused []*repository.UnusedConnection
+	for _, item := range r.items {
+		if item.Connection.Status == string(repository.ConnectionActive) && item.LastActivity().Before(since) {
+			unused = append(unused, item)
+		}
+	}
+	return unused, nil
+}
+
+func (r *cleanupConnectionRepo) MarkUnusedNotified(_ context.Context, connectionID int64, notifiedAt time.Time) error {
+	for _, item := range r.items {
+		if item.Connection.ID == connectionID {
+			item.NotifiedAt = &notifiedAt
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *cleanupConnectionRepo) UpdateStatus(_ context.Context, connectionID int64, status repository.ConnectionStatus) error {
+	for _, item := range r.items {
+		if item.Connection.ID == connectionID {
+			item.Connection.Status = string(status)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+type cleanupUserRepo struct {
+	repository.UserRepository
+}
+
+func (cleanupUserRepo) GetByID(_ context.Context, id int64) (*repository.User, error) {
+	return testutil.NewUser(id, testutil.WithUserEmail("owner@example.com")), nil
+}
+
+type recordingPoolCloser struct {
+	closed []int64
+}
+
+func (p *recordingPoolCloser) ClosePool(connectionID int64) bool {
+	p.closed = append(p.closed, connectionID)
+	return true
+}
+
+func TestConnectionCleanupService_Sweep(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	newItem := func(id int64, lastUsed time.Time) *repository.UnusedConnection {
+		conn := testutil.NewConnection(id, 7)
+		conn.Name = "warehouse"
+		conn.CreateTime = now.AddDate(-1, 0, 0)
+		return &repository.UnusedConnection{Connection: conn, LastUsedAt: &lastUsed}
+	}
+	idle := newItem(1, now.AddDate(0, 0, -40))
+	recent := newItem(2, now.AddDate(0, 0, -3))
+	neverUsed := newItem(3, time.Time{})
+	neverUsed.LastUsedAt = nil
+
+	repo := &cleanupConnectionRepo{items: []*repository.UnusedConnection{idle, recent, neverUsed}}
+	pools := &recordingPoolCloser{}
+	mailer := &emailTestMailer{}
+	cfg := &config.ConnectionCleanupConfig{UnusedDays: 30, AutoDisable: true, GracePeriod: 7 * 24 * time.Hour, CheckInterval: time.Hour}
+	s := NewConnectionCleanupService(repo, cleanupUserRepo{}, pools, mailer, cfg, zap.NewNop())
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// 首轮提醒闲置与从未使用的连接
+	result, err := s.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &ConnectionCleanupResult{Unused: 2, Notified: 2}, result)
+	require.Equal(t, 2, mailer.count())
+	assert.Equal(t, "owner@example.com", mailer.sent[0].To)
+	assert.Contains(t, mailer.sent[0].Subject, "已闲置30天")
+	assert.Contains(t, mailer.sent[0].Body, "2024-06-08前仍未使用")
+
+	// 宽限期内不重复提醒
+	now = now.Add(3 * 24 * time.Hour)
+	result, err = s.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &ConnectionCleanupResult{Unused: 2}, result)
+
+	// 提醒后又使用过的连接重新计算闲置，不会被停用
+	used := now.Add(-time.Hour)
+	neverUsed.LastUsedAt = &used
+
+	// 宽限期过后停用仍未使用的连接并关闭连接池
+	now = now.Add(5 * 24 * time.Hour)
+	result, err = s.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &ConnectionCleanupResult{Unused: 1, Disabled: 1}, result)
+	assert.Equal(t, string(repository.ConnectionInactive), idle.Connection.Status)
+	assert.Equal(t, string(repository.ConnectionActive), neverUsed.Connection.Status)
+	assert.Equal(t, []int64{1}, pools.closed)
+	require.Equal(t, 3, mailer.count())
+	assert.Contains(t, mailer.sent[2].Subject, "已被自动停用")
+
+	// 已停用的连接不再处理
+	result, err = s.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, result.Unused)
+}
+
+func TestConnectionCleanupService_NotifyOnly(t *testing.T) {
+	now := time.Now()
+	lastUsed := now.AddDate(0, 0, -100)
+	notified := now.AddDate(0, 0, -30)
+	conn := testutil.NewConnection(1, 7)
+	repo := &cleanupConnectionRepo{items: []*repository.UnusedConnection{{Connection: conn, LastUsedAt: &lastUsed, NotifiedAt: &notified}}}
+	pools := &recordingPoolCloser{}
+
+	// 未开启自动停用时只提醒；未配置邮件发送时提醒只记录日志
+	s := NewConnectionCleanupService(repo, cleanupUserRepo{}, pools, nil, nil, zap.NewNop())
+	result, err := s.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &ConnectionCleanupResult{Unused: 1}, result)
+	assert.Empty(t, pools.closed)
+	assert.Equal(t, string(repository.ConnectionActive), conn.Status)
+}
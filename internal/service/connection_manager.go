@@ -6,6 +6,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -13,9 +14,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
+	"chat2sql-go/internal/crash"
 	"chat2sql-go/internal/repository"
 )
 
@@ -37,6 +41,7 @@ type ConnectionManager struct {
 	poolIdleTimeout    time.Duration // 连接池空闲超时
 	connectionTimeout  time.Duration // 连接超时时间
 	healthCheckInterval time.Duration // 健康检查间隔
+	sqliteDataDir      string        // SQLite数据库文件所在目录，为空时不允许SQLite连接
 	
 	// 状态管理
 	isRunning      bool          // 是否运行中
@@ -45,8 +50,10 @@ type ConnectionManager struct {
 }
 
 // ManagedPool 托管连接池
+// PostgreSQL连接使用Pool，MySQL与SQLite连接使用DB，另一个为nil
 type ManagedPool struct {
 	Pool         *pgxpool.Pool              // pgx连接池
+	DB           *sql.DB                    // MySQL/SQLite连接池
 	Connection   *repository.DatabaseConnection // 数据库连接配置
 	LastUsed     time.Time                  // 最后使用时间
 	CreatedAt    time.Time                  // 创建时间
@@ -183,7 +190,7 @@ func (cm *ConnectionManager) Stop() error {
 	// 关闭所有连接池
 	cm.connectionPools.Range(func(key, value any) bool {
 		if pool, ok := value.(*ManagedPool); ok {
-			pool.Pool.Close()
+			pool.close()
 		}
 		return true
 	})
@@ -192,15 +199,48 @@ func (cm *ConnectionManager) Stop() error {
 	return nil
 }
 
-// GetConnectionPool 获取数据库连接池
+// GetConnectionPool 获取PostgreSQL连接池，连接为其他数据库类型时返回ErrUnsupportedDBType
 func (cm *ConnectionManager) GetConnectionPool(ctx context.Context, connectionID int64) (*pgxpool.Pool, error) {
+	managedPool, err := cm.getManagedPool(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if managedPool.Pool == nil {
+		return nil, fmt.Errorf("%w: 连接%d的类型为%s", ErrUnsupportedDBType, connectionID, managedPool.Connection.DBType)
+	}
+	return managedPool.Pool, nil
+}
+
+// GetSQLDB 获取MySQL或SQLite连接的database/sql连接池，连接为PostgreSQL等其他类型时返回ErrUnsupportedDBType
+func (cm *ConnectionManager) GetSQLDB(ctx context.Context, connectionID int64) (*sql.DB, error) {
+	managedPool, err := cm.getManagedPool(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if managedPool.DB == nil {
+		return nil, fmt.Errorf("%w: 连接%d的类型为%s", ErrUnsupportedDBType, connectionID, managedPool.Connection.DBType)
+	}
+	return managedPool.DB, nil
+}
+
+// GetDBType 获取连接的数据库类型，未创建连接池时会先创建
+func (cm *ConnectionManager) GetDBType(ctx context.Context, connectionID int64) (repository.DatabaseType, error) {
+	managedPool, err := cm.getManagedPool(ctx, connectionID)
+	if err != nil {
+		return "", err
+	}
+	return connectionDBType(managedPool.Connection), nil
+}
+
+// getManagedPool 获取托管连接池，缓存中不存在时创建
+func (cm *ConnectionManager) getManagedPool(ctx context.Context, connectionID int64) (*ManagedPool, error) {
 	// 从缓存中查找
 	if value, ok := cm.connectionPools.Load(connectionID); ok {
 		if managedPool, ok := value.(*ManagedPool); ok {
 			managedPool.mutex.Lock()
 			managedPool.LastUsed = time.Now()
 			managedPool.mutex.Unlock()
-			return managedPool.Pool, nil
+			return managedPool, nil
 		}
 	}
 	
@@ -209,7 +249,7 @@ func (cm *ConnectionManager) GetConnectionPool(ctx context.Context, connectionID
 }
 
 // createConnectionPool 创建新的连接池
-func (cm *ConnectionManager) createConnectionPool(ctx context.Context, connectionID int64) (*pgxpool.Pool, error) {
+func (cm *ConnectionManager) createConnectionPool(ctx context.Context, connectionID int64) (*ManagedPool, error) {
 	// 获取连接配置
 	connection, err := cm.connectionRepo.GetByID(ctx, connectionID)
 	if err != nil {
@@ -231,8 +271,47 @@ func (cm *ConnectionManager) createConnectionPool(ctx context.Context, connectio
 		return nil, fmt.Errorf("密码解密失败: %w", err)
 	}
 	
+	// 创建托管连接池
+	managedPool := &ManagedPool{
+		Connection: connection,
+		LastUsed:   time.Now(),
+		CreatedAt:  time.Now(),
+		HealthStatus: ConnectionHealthStatus{
+			IsHealthy: true,
+			LastCheck: time.Now(),
+		},
+	}
+	
+	switch dbType := connectionDBType(connection); dbType {
+	case repository.DBTypePostgreSQL:
+		managedPool.Pool, err = cm.openPostgreSQLPool(ctx, connection, decryptedPassword)
+	case repository.DBTypeMySQL:
+		managedPool.DB, err = cm.openMySQLDB(ctx, connection, decryptedPassword)
+	case repository.DBTypeSQLite:
+		managedPool.DB, err = cm.openSQLiteDB(ctx, connection)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedDBType, dbType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	// 存储到缓存
+	cm.connectionPools.Store(connectionID, managedPool)
+	
+	cm.logger.Info("创建数据库连接池",
+		zap.Int64("connection_id", connectionID),
+		zap.String("db_type", connection.DBType),
+		zap.String("host", connection.Host),
+		zap.String("database", connection.DatabaseName))
+	
+	return managedPool, nil
+}
+
+// openPostgreSQLPool 创建PostgreSQL连接池并测试连接
+func (cm *ConnectionManager) openPostgreSQLPool(ctx context.Context, connection *repository.DatabaseConnection, password string) (*pgxpool.Pool, error) {
 	// 构建连接字符串
-	connStr := cm.buildConnectionString(connection, decryptedPassword)
+	connStr := cm.buildConnectionString(connection, password)
 	
 	// 解析连接配置
 	config, err := pgxpool.ParseConfig(connStr)
@@ -258,29 +337,17 @@ func (cm *ConnectionManager) createConnectionPool(ctx context.Context, connectio
 		return nil, fmt.Errorf("连接测试失败: %w", err)
 	}
 	
-	// 创建托管连接池
-	managedPool := &ManagedPool{
-		Pool:       pool,
-		Connection: connection,
-		LastUsed:   time.Now(),
-		CreatedAt:  time.Now(),
-		HealthStatus: ConnectionHealthStatus{
-			IsHealthy: true,
-			LastCheck: time.Now(),
-		},
-	}
-	
-	// 存储到缓存
-	cm.connectionPools.Store(connectionID, managedPool)
-	
-	cm.logger.Info("创建数据库连接池",
-		zap.Int64("connection_id", connectionID),
-		zap.String("host", connection.Host),
-		zap.String("database", connection.DatabaseName))
-	
 	return pool, nil
 }
 
+// connectionDBType 连接的数据库类型，未填写时视为PostgreSQL
+func connectionDBType(connection *repository.DatabaseConnection) repository.DatabaseType {
+	if connection.DBType == "" {
+		return repository.DBTypePostgreSQL
+	}
+	return repository.DatabaseType(connection.DBType)
+}
+
 // buildConnectionString 构建连接字符串
 func (cm *ConnectionManager) buildConnectionString(conn *repository.DatabaseConnection, password string) string {
 	return fmt.Sprintf(
@@ -300,6 +367,11 @@ func (cm *ConnectionManager) configurePoolSettings(config *pgxpool.Config) {
 	config.MinConns = 2                     // 最小连接数
 	config.MaxConnLifetime = 1 * time.Hour  // 连接生命周期
 	config.MaxConnIdleTime = 15 * time.Minute // 最大空闲时间
+	
+	// 查询被取消或超时时向服务端发送取消请求；默认只中断本地连接，服务端的长查询会继续执行
+	config.ConnConfig.BuildContextWatcherHandler = func(pgConn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: pgConn, DeadlineDelay: 5 * time.Second}
+	}
 }
 
 // checkUserPoolLimit 检查用户连接池数量限制
@@ -334,6 +406,16 @@ func (cm *ConnectionManager) TestConnection(ctx context.Context, connection *rep
 
 // testConnectionDirect 使用明文密码直接测试数据库连接
 func (cm *ConnectionManager) testConnectionDirect(ctx context.Context, connection *repository.DatabaseConnection, plainPassword string) error {
+	switch dbType := connectionDBType(connection); dbType {
+	case repository.DBTypePostgreSQL:
+	case repository.DBTypeMySQL:
+		return cm.testMySQLConnection(ctx, connection, plainPassword)
+	case repository.DBTypeSQLite:
+		return cm.testSQLiteConnection(ctx, connection)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedDBType, dbType)
+	}
+	
 	// 构建连接字符串
 	connStr := cm.buildConnectionString(connection, plainPassword)
 	
@@ -422,12 +504,7 @@ func (cm *ConnectionManager) UpdateConnection(ctx context.Context, connection *r
 // DeleteConnection 删除数据库连接配置
 func (cm *ConnectionManager) DeleteConnection(ctx context.Context, connectionID int64) error {
 	// 先关闭连接池
-	if value, ok := cm.connectionPools.Load(connectionID); ok {
-		if managedPool, ok := value.(*ManagedPool); ok {
-			managedPool.Pool.Close()
-		}
-		cm.connectionPools.Delete(connectionID)
-	}
+	cm.ClosePool(connectionID)
 	
 	// 删除配置
 	if err := cm.connectionRepo.Delete(ctx, connectionID); err != nil {
@@ -440,6 +517,19 @@ func (cm *ConnectionManager) DeleteConnection(ctx context.Context, connectionID
 	return nil
 }
 
+// ClosePool 关闭并移除连接的缓存连接池，连同其中保存的数据库凭据，返回是否存在缓存的连接池
+// 连接仍为active时，下次使用会按数据库中的配置重新创建连接池
+func (cm *ConnectionManager) ClosePool(connectionID int64) bool {
+	value, ok := cm.connectionPools.LoadAndDelete(connectionID)
+	if !ok {
+		return false
+	}
+	if managedPool, ok := value.(*ManagedPool); ok {
+		managedPool.close()
+	}
+	return true
+}
+
 // healthCheckRoutine 健康检查例程
 func (cm *ConnectionManager) healthCheckRoutine() {
 	for {
@@ -447,7 +537,7 @@ func (cm *ConnectionManager) healthCheckRoutine() {
 		case <-cm.stopCh:
 			return
 		case <-cm.healthTicker.C:
-			cm.performHealthCheck()
+			crash.Run("connection_manager.health_check", cm.performHealthCheck)
 		}
 	}
 }
@@ -472,8 +562,7 @@ func (cm *ConnectionManager) checkPoolHealth(connectionID int64, managedPool *Ma
 	defer cancel()
 	
 	// 执行健康检查
-	var result int
-	err := managedPool.Pool.QueryRow(ctx, "SELECT 1").Scan(&result)
+	result, err := managedPool.selectOne(ctx)
 	
 	managedPool.HealthStatus.LastCheck = time.Now()
 	managedPool.HealthStatus.CheckCount++
@@ -508,7 +597,7 @@ func (cm *ConnectionManager) cleanupRoutine() {
 		case <-cm.stopCh:
 			return
 		case <-ticker.C:
-			cm.cleanupIdlePools()
+			crash.Run("connection_manager.cleanup", cm.cleanupIdlePools)
 		}
 	}
 }
@@ -529,7 +618,7 @@ func (cm *ConnectionManager) cleanupIdlePools() {
 					zap.Int64("connection_id", connectionID),
 					zap.Duration("idle_time", now.Sub(managedPool.LastUsed)))
 				
-				managedPool.Pool.Close()
+				managedPool.close()
 				cm.connectionPools.Delete(connectionID)
 			}
 		}
@@ -568,7 +657,7 @@ func (cm *ConnectionManager) GetPoolStats() map[string]any {
 			totalPools++
 			
 			managedPool.mutex.RLock()
-			poolStat := managedPool.Pool.Stat()
+			totalConns, idleConns, acquiredConns := managedPool.stat()
 			isHealthy := managedPool.HealthStatus.IsHealthy
 			managedPool.mutex.RUnlock()
 			
@@ -578,9 +667,10 @@ func (cm *ConnectionManager) GetPoolStats() map[string]any {
 			
 			poolInfo := map[string]any{
 				"connection_id":     key,
-				"total_conns":       poolStat.TotalConns(),
-				"idle_conns":        poolStat.IdleConns(),
-				"acquired_conns":    poolStat.AcquiredConns(),
+				"db_type":           string(connectionDBType(managedPool.Connection)),
+				"total_conns":       totalConns,
+				"idle_conns":        idleConns,
+				"acquired_conns":    acquiredConns,
 				"is_healthy":        isHealthy,
 				"last_used":         managedPool.LastUsed,
 				"created_at":        managedPool.CreatedAt,
@@ -599,6 +689,35 @@ func (cm *ConnectionManager) GetPoolStats() map[string]any {
 	return stats
 }
 
+// close 关闭托管的连接池
+func (mp *ManagedPool) close() {
+	if mp.Pool != nil {
+		mp.Pool.Close()
+	}
+	if mp.DB != nil {
+		mp.DB.Close()
+	}
+}
+
+// selectOne 执行SELECT 1检查连接是否可用
+func (mp *ManagedPool) selectOne(ctx context.Context) (int, error) {
+	var result int
+	if mp.DB != nil {
+		return result, mp.DB.QueryRowContext(ctx, "SELECT 1").Scan(&result)
+	}
+	return result, mp.Pool.QueryRow(ctx, "SELECT 1").Scan(&result)
+}
+
+// stat 返回连接池的总连接数、空闲连接数与使用中连接数
+func (mp *ManagedPool) stat() (total, idle, acquired int32) {
+	if mp.DB != nil {
+		stats := mp.DB.Stats()
+		return int32(stats.OpenConnections), int32(stats.Idle), int32(stats.InUse)
+	}
+	stats := mp.Pool.Stat()
+	return stats.TotalConns(), stats.IdleConns(), stats.AcquiredConns()
+}
+
 // NewAESEncryption 创建AES加密服务
 func NewAESEncryption(key []byte) (*AESEncryption, error) {
 	// 验证密钥是否为空或nil
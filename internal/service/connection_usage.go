@@ -0,0 +1,144 @@
+// 连接使用热力图
+// 按查询历史统计每个连接最近N周在星期几与小时上的查询量，
+// 管理员据此安排维护窗口，并发现可以下线的闲置连接
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+var (
+	// ErrInvalidUsageWeeks 统计周数超出范围
+	ErrInvalidUsageWeeks = errors.New("统计周数超出范围")
+	// ErrInvalidUsageTimezone 时区不是有效的IANA时区
+	ErrInvalidUsageTimezone = errors.New("无效的时区")
+)
+
+// HeatmapSlot 一周中的一个小时
+type HeatmapSlot struct {
+	DayOfWeek  int   `json:"day_of_week"` // 0为周日
+	Hour       int   `json:"hour"`
+	QueryCount int64 `json:"query_count"`
+}
+
+// ConnectionHeatmap 单个连接的查询量热力图
+type ConnectionHeatmap struct {
+	ConnectionID  int64                       `json:"connection_id"`
+	Name          string                      `json:"name"`
+	Status        repository.ConnectionStatus `json:"status"`
+	TotalQueries  int64                       `json:"total_queries"`
+	FailedQueries int64                       `json:"failed_queries"`
+	Matrix        [7][24]int64                `json:"matrix"`             // [星期几][小时]的查询次数，星期0为周日
+	Peak          *HeatmapSlot                `json:"peak,omitempty"`     // 查询最多的时段，没有查询时为空
+	Quietest      *HeatmapSlot                `json:"quietest,omitempty"` // 查询最少的最早时段，可作为维护窗口参考，没有查询时为空
+	Unused        bool                        `json:"unused"`             // 统计期间没有任何查询
+}
+
+// ConnectionUsageReport 连接使用热力图报告
+type ConnectionUsageReport struct {
+	Weeks       int                 `json:"weeks"`
+	Timezone    string              `json:"timezone"`
+	Since       time.Time           `json:"since"`
+	Connections []ConnectionHeatmap `json:"connections"`
+	GeneratedAt time.Time           `json:"generated_at"`
+}
+
+// ConnectionUsageService 连接使用热力图服务
+type ConnectionUsageService struct {
+	repo   repository.QueryHistoryRepository
+	config *config.ConnectionUsageConfig
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewConnectionUsageService 创建连接使用热力图服务，配置为nil时使用默认配置
+func NewConnectionUsageService(repo repository.QueryHistoryRepository, usageConfig *config.ConnectionUsageConfig, logger *zap.Logger) *ConnectionUsageService {
+	if usageConfig == nil {
+		usageConfig = config.DefaultConnectionUsageConfig()
+	}
+	return &ConnectionUsageService{
+		repo:   repo,
+		config: usageConfig,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Heatmap 统计最近weeks周每个连接的查询量分布，weeks不大于0时使用配置的默认周数，
+// timezone为空时使用配置的时区
+func (s *ConnectionUsageService) Heatmap(ctx context.Context, weeks int, timezone string) (*ConnectionUsageReport, error) {
+	if weeks <= 0 {
+		weeks = s.config.DefaultWeeks
+	}
+	if weeks > s.config.MaxWeeks {
+		return nil, fmt.Errorf("%w: 最多%d周", ErrInvalidUsageWeeks, s.config.MaxWeeks)
+	}
+	if timezone == "" {
+		timezone = s.config.Timezone
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUsageTimezone, timezone)
+	}
+
+	now := s.now()
+	since := now.AddDate(0, 0, -7*weeks)
+	usages, err := s.repo.GetConnectionUsage(ctx, since, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConnectionUsageReport{
+		Weeks:       weeks,
+		Timezone:    timezone,
+		Since:       since,
+		Connections: make([]ConnectionHeatmap, 0, len(usages)),
+		GeneratedAt: now,
+	}
+	for _, usage := range usages {
+		report.Connections = append(report.Connections, newConnectionHeatmap(usage))
+	}
+	return report, nil
+}
+
+// newConnectionHeatmap 将查询量分布填入7×24矩阵并找出最忙与最闲的时段
+func newConnectionHeatmap(usage *repository.ConnectionUsage) ConnectionHeatmap {
+	heatmap := ConnectionHeatmap{
+		ConnectionID: usage.ConnectionID,
+		Name:         usage.ConnectionName,
+		Status:       usage.Status,
+	}
+	for _, cell := range usage.Cells {
+		if cell.DayOfWeek < 0 || cell.DayOfWeek > 6 || cell.Hour < 0 || cell.Hour > 23 {
+			continue
+		}
+		heatmap.Matrix[cell.DayOfWeek][cell.Hour] += cell.QueryCount
+		heatmap.TotalQueries += cell.QueryCount
+		heatmap.FailedQueries += cell.FailedCount
+	}
+
+	if heatmap.TotalQueries == 0 {
+		heatmap.Unused = true
+		return heatmap
+	}
+
+	for day := range heatmap.Matrix {
+		for hour, count := range heatmap.Matrix[day] {
+			slot := &HeatmapSlot{DayOfWeek: day, Hour: hour, QueryCount: count}
+			if heatmap.Peak == nil || count > heatmap.Peak.QueryCount {
+				heatmap.Peak = slot
+			}
+			if heatmap.Quietest == nil || count < heatmap.Quietest.QueryCount {
+				heatmap.Quietest = slot
+			}
+		}
+	}
+	return heatmap
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+type usageQueryHistoryRepository struct {
+	repository.QueryHistoryRepository
+	usages   []*repository.ConnectionUsage
+	since    time.Time
+	location string
+}
+
+func (r *usageQueryHistoryRepository) GetConnectionUsage(ctx context.Context, since time.Time, location string) ([]*repository.ConnectionUsage, error) {
+	r.since, r.location = since, location
+	return r.usages, nil
+}
+
+func TestConnectionUsageService_Heatmap(t *testing.T) {
+	repo := &usageQueryHistoryRepository{usages: []*repository.ConnectionUsage{
+		{ConnectionID: 1, ConnectionName: "warehouse", Status: repository.ConnectionActive, Cells: []repository.ConnectionUsageCell{
+			{DayOfWeek: 1, Hour: 9, QueryCount: 40, FailedCount: 2},
+			{DayOfWeek: 1, Hour: 10, QueryCount: 25},
+			{DayOfWeek: 5, Hour: 17, QueryCount: 3, FailedCount: 1},
+		}},
+		{ConnectionID: 2, ConnectionName: "legacy", Status: repository.ConnectionInactive},
+	}}
+	svc := NewConnectionUsageService(repo, nil, zap.NewNop())
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	report, err := svc.Heatmap(ctx, 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Weeks)
+	assert.Equal(t, "UTC", report.Timezone)
+	assert.Equal(t, now.AddDate(0, 0, -28), repo.since)
+	require.Len(t, report.Connections, 2)
+
+	warehouse := report.Connections[0]
+	assert.Equal(t, int64(68), warehouse.TotalQueries)
+	assert.Equal(t, int64(3), warehouse.FailedQueries)
+	assert.Equal(t, int64(40), warehouse.Matrix[1][9])
+	assert.Equal(t, &HeatmapSlot{DayOfWeek: 1, Hour: 9, QueryCount: 40}, warehouse.Peak)
+	assert.Equal(t, &HeatmapSlot{DayOfWeek: 0, Hour: 0, QueryCount: 0}, warehouse.Quietest)
+	assert.False(t, warehouse.Unused)
+
+	legacy := report.Connections[1]
+	assert.True(t, legacy.Unused)
+	assert.Nil(t, legacy.Peak)
+
+	_, err = svc.Heatmap(ctx, 12, "Asia/Shanghai")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Shanghai", repo.location)
+
+	_, err = svc.Heatmap(ctx, 27, "")
+	assert.ErrorIs(t, err, ErrInvalidUsageWeeks)
+	_, err = svc.Heatmap(ctx, 4, "Mars/Olympus")
+	assert.ErrorIs(t, err, ErrInvalidUsageTimezone)
+}
@@ -0,0 +1,232 @@
+// 关键查询的自洽性投票
+// 对标记为关键的查询（如财务看板）采样多条候选SQL，在严格限制下执行得分最高的两条不同候选，
+// 仅当两者结果一致时才返回答案，以额外的模型与执行成本换取正确性
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// ErrNoConsensus 候选SQL的执行结果不一致或无法比较
+var ErrNoConsensus = errors.New("候选SQL结果不一致")
+
+// ConsensusResult 投票结果
+type ConsensusResult struct {
+	Agreed     bool                   `json:"agreed"`
+	SQL        string                 `json:"sql"`                  // 一致时返回的SQL（得分最高的候选）
+	Result     *QueryResult           `json:"result,omitempty"`     // 一致时的执行结果
+	Candidates []*SQLCandidate        `json:"candidates,omitempty"` // 参与比较的候选
+	Samples    int                    `json:"samples"`              // 实际采样到的不同候选数
+	Reason     string                 `json:"reason"`
+	Generation *SQLGenerationResponse `json:"-"`
+}
+
+// ConsensusService 自洽性投票服务
+type ConsensusService struct {
+	generator      SQLGenerator
+	executor       QueryExecutor
+	connectionRepo repository.ConnectionRepository
+	config         config.ConsensusConfig
+	logger         *zap.Logger
+}
+
+// NewConsensusService 创建自洽性投票服务
+func NewConsensusService(
+	generator SQLGenerator,
+	executor QueryExecutor,
+	connectionRepo repository.ConnectionRepository,
+	cfg config.ConsensusConfig,
+	logger *zap.Logger,
+) *ConsensusService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.Samples < 2 {
+		cfg.Samples = 3
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = 1000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &ConsensusService{
+		generator:      generator,
+		executor:       executor,
+		connectionRepo: connectionRepo,
+		config:         cfg,
+		logger:         logger,
+	}
+}
+
+// Verify 采样候选并比较得分最高的两条不同候选的执行结果
+// 结果不一致或无法比较时返回的error包装ErrNoConsensus，ConsensusResult仍包含比较详情；Reason按req.Locale返回
+func (s *ConsensusService) Verify(ctx context.Context, req *SQLGenerationRequest) (*ConsensusResult, error) {
+	connection, err := s.connectionRepo.GetByID(ctx, req.ConnectionID)
+	if err != nil || connection.UserID != req.UserID {
+		return nil, fmt.Errorf("无权访问数据库连接%d: %w", req.ConnectionID, repository.ErrPermissionDenied)
+	}
+
+	sampled := *req
+	sampled.Candidates = s.config.Samples
+	generation, err := s.generator.GenerateSQL(ctx, &sampled)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithQueryOwner(ctx, req.UserID)
+	candidates := consensusCandidates(generation)
+	result := &ConsensusResult{Samples: len(candidates), Generation: generation}
+	if len(candidates) == 0 {
+		result.Reason = Localize(req.Locale, "没有通过校验的候选SQL")
+		return result, fmt.Errorf("%w: %s", ErrNoConsensus, result.Reason)
+	}
+	if len(candidates) > 2 {
+		candidates = candidates[:2]
+	}
+	result.Candidates = candidates
+
+	results := make([]*QueryResult, len(candidates))
+	for i, candidate := range candidates {
+		results[i], err = s.executeLimited(ctx, candidate.SQL, connection)
+		if err != nil {
+			result.Reason = fmt.Sprintf(Localize(req.Locale, "候选%d执行失败: %v"), i+1, err)
+			return result, fmt.Errorf("%w: %s", ErrNoConsensus, result.Reason)
+		}
+		if int(results[i].RowCount) > s.config.MaxRows || len(results[i].Warnings) > 0 {
+			result.Reason = fmt.Sprintf(Localize(req.Locale, "候选%d结果超出比较上限(%d行)"), i+1, s.config.MaxRows)
+			return result, fmt.Errorf("%w: %s", ErrNoConsensus, result.Reason)
+		}
+	}
+
+	if len(results) == 2 && !sameQueryResult(results[0], results[1]) {
+		result.Reason = fmt.Sprintf(Localize(req.Locale, "两条候选结果不一致(%d行 vs %d行)"), results[0].RowCount, results[1].RowCount)
+		s.logger.Warn("自洽性投票未达成一致",
+			zap.String("query", req.Query),
+			zap.String("sql_a", candidates[0].SQL),
+			zap.String("sql_b", candidates[1].SQL))
+		return result, fmt.Errorf("%w: %s", ErrNoConsensus, result.Reason)
+	}
+
+	result.Agreed = true
+	result.SQL = candidates[0].SQL
+	result.Result = results[0]
+	if len(results) == 1 {
+		result.Reason = Localize(req.Locale, "所有采样生成了相同的SQL")
+	} else {
+		result.Reason = Localize(req.Locale, "两条不同候选的执行结果一致")
+	}
+	return result, nil
+}
+
+// executeLimited 以独立超时执行并多取一行，用于判断结果是否超出比较上限
+func (s *ConsensusService) executeLimited(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error) {
+	execCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	limited := fmt.Sprintf("SELECT * FROM (%s) AS consensus_sample LIMIT %d", sql, s.config.MaxRows+1)
+	result, err := s.executor.ExecuteQuery(execCtx, limited, connection)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, errors.New(result.Error)
+	}
+	return result, nil
+}
+
+// consensusCandidates 按得分顺序返回通过校验的不同候选
+func consensusCandidates(generation *SQLGenerationResponse) []*SQLCandidate {
+	if generation.SelectedCandidate == nil {
+		if generation.SQL == "" {
+			return nil
+		}
+		return []*SQLCandidate{{SQL: generation.SQL, Score: generation.Confidence}}
+	}
+
+	var candidates []*SQLCandidate
+	for _, candidate := range append([]*SQLCandidate{generation.SelectedCandidate}, generation.Alternates...) {
+		if candidate.Score > 0 {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// sameQueryResult 忽略列名与行顺序比较两次查询结果
+// 列名可能因别名不同而不同，按列位置比较取值
+func sameQueryResult(a, b *QueryResult) bool {
+	if len(a.Columns) != len(b.Columns) || len(a.Rows) != len(b.Rows) {
+		return false
+	}
+
+	rowsA, rowsB := canonicalRows(a), canonicalRows(b)
+	for i := range rowsA {
+		if rowsA[i] != rowsB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalRows 将每行按列顺序序列化并排序
+func canonicalRows(result *QueryResult) []string {
+	rows := make([]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		values := make([]string, len(result.Columns))
+		for i, column := range result.Columns {
+			values[i] = canonicalValue(row[column])
+		}
+		rows = append(rows, strings.Join(values, "\x1f"))
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+// canonicalValue 数值统一为有效数字格式，避免int与numeric等类型差异导致误判
+func canonicalValue(value any) string {
+	var number float64
+	switch v := value.(type) {
+	case nil:
+		return "\x00"
+	case int:
+		number = float64(v)
+	case int16:
+		number = float64(v)
+	case int32:
+		number = float64(v)
+	case int64:
+		number = float64(v)
+	case float32:
+		number = float64(v)
+	case float64:
+		number = v
+	case pgtype.Numeric:
+		f, err := v.Float64Value()
+		if err != nil || !f.Valid {
+			return fmt.Sprint(value)
+		}
+		number = f.Float64
+	default:
+		return fmt.Sprint(value)
+	}
+
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return fmt.Sprint(number)
+	}
+	return strconv.FormatFloat(number, 'g', 10, 64)
+}
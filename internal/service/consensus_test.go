@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// stubGenerator 返回固定的生成结果
+type stubGenerator struct {
+	response *SQLGenerationResponse
+	requests []*SQLGenerationRequest
+}
+
+func (s *stubGenerator) GenerateSQL(ctx context.Context, req *SQLGenerationRequest) (*SQLGenerationResponse, error) {
+	s.requests = append(s.requests, req)
+	return s.response, nil
+}
+
+// scriptedExecutor 按SQL片段返回预置结果，并记录执行的SQL
+type scriptedExecutor struct {
+	results  map[string]*QueryResult
+	executed []string
+}
+
+func (s *scriptedExecutor) ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error) {
+	s.executed = append(s.executed, sql)
+	for fragment, result := range s.results {
+		if strings.Contains(sql, fragment) {
+			return result, nil
+		}
+	}
+	return &QueryResult{Status: string(repository.QueryError), Error: "unexpected sql"}, nil
+}
+
+func revenueResult(column string, rows ...[2]any) *QueryResult {
+	result := &QueryResult{Columns: []string{"month", column}, Status: string(repository.QuerySuccess)}
+	for _, row := range rows {
+		result.Rows = append(result.Rows, map[string]any{"month": row[0], column: row[1]})
+	}
+	result.RowCount = int32(len(result.Rows))
+	return result
+}
+
+func newConsensusTestService(t *testing.T, generation *SQLGenerationResponse, executor *scriptedExecutor) (*ConsensusService, *stubGenerator) {
+	connection := testutil.NewConnection(3, 7)
+	generator := &stubGenerator{response: generation}
+	cfg := config.ConsensusConfig{Samples: 4, MaxRows: 10}
+	return NewConsensusService(generator, executor, &stubConnectionRepository{connection: connection}, cfg, zaptest.NewLogger(t)), generator
+}
+
+func TestConsensusService_Verify(t *testing.T) {
+	generation := &SQLGenerationResponse{
+		SQL:               "SELECT month, SUM(amount) AS revenue FROM sales GROUP BY month",
+		SelectedCandidate: &SQLCandidate{SQL: "SELECT month, SUM(amount) AS revenue FROM sales GROUP BY month", Score: 0.9},
+		Alternates: []*SQLCandidate{
+			{SQL: "SELECT month, SUM(amount) AS total FROM sales GROUP BY 1 ORDER BY 1", Score: 0.8},
+			{SQL: "SELECT 1", Score: 0.5},
+		},
+	}
+	req := &SQLGenerationRequest{Query: "月度收入", ConnectionID: 3, UserID: 7}
+
+	t.Run("结果一致时返回首选SQL", func(t *testing.T) {
+		executor := &scriptedExecutor{results: map[string]*QueryResult{
+			"AS revenue": revenueResult("revenue", [2]any{"2024-01", int64(100)}, [2]any{"2024-02", 250.0}),
+			"AS total":   revenueResult("total", [2]any{"2024-02", int64(250)}, [2]any{"2024-01", 100.0}),
+		}}
+		svc, generator := newConsensusTestService(t, generation, executor)
+
+		outcome, err := svc.Verify(context.Background(), req)
+		require.NoError(t, err)
+		assert.True(t, outcome.Agreed)
+		assert.Equal(t, generation.SQL, outcome.SQL)
+		assert.Len(t, outcome.Candidates, 2)
+		require.Len(t, generator.requests, 1)
+		assert.Equal(t, 4, generator.requests[0].Candidates)
+
+		// 只执行得分最高的两条候选，且附带严格的行数限制
+		require.Len(t, executor.executed, 2)
+		for _, sql := range executor.executed {
+			assert.Contains(t, sql, "LIMIT 11")
+		}
+	})
+
+	t.Run("结果不一致时不返回答案", func(t *testing.T) {
+		executor := &scriptedExecutor{results: map[string]*QueryResult{
+			"AS revenue": revenueResult("revenue", [2]any{"2024-01", int64(100)}),
+			"AS total":   revenueResult("total", [2]any{"2024-01", int64(120)}),
+		}}
+		svc, _ := newConsensusTestService(t, generation, executor)
+
+		outcome, err := svc.Verify(context.Background(), req)
+		assert.ErrorIs(t, err, ErrNoConsensus)
+		assert.False(t, outcome.Agreed)
+		assert.Empty(t, outcome.SQL)
+		assert.NotEmpty(t, outcome.Reason)
+	})
+
+	t.Run("结果超出比较上限视为无法判定", func(t *testing.T) {
+		var rows [][2]any
+		for i := 0; i < 11; i++ {
+			rows = append(rows, [2]any{i, i})
+		}
+		executor := &scriptedExecutor{results: map[string]*QueryResult{
+			"AS revenue": revenueResult("revenue", rows...),
+			"AS total":   revenueResult("total", rows...),
+		}}
+		svc, _ := newConsensusTestService(t, generation, executor)
+
+		_, err := svc.Verify(context.Background(), req)
+		assert.ErrorIs(t, err, ErrNoConsensus)
+	})
+
+	t.Run("所有采样相同时执行一次", func(t *testing.T) {
+		single := &SQLGenerationResponse{
+			SQL:               "SELECT month, SUM(amount) AS revenue FROM sales GROUP BY month",
+			SelectedCandidate: &SQLCandidate{SQL: "SELECT month, SUM(amount) AS revenue FROM sales GROUP BY month", Score: 0.9},
+		}
+		executor := &scriptedExecutor{results: map[string]*QueryResult{
+			"AS revenue": revenueResult("revenue", [2]any{"2024-01", int64(100)}),
+		}}
+		svc, _ := newConsensusTestService(t, single, executor)
+
+		outcome, err := svc.Verify(context.Background(), req)
+		require.NoError(t, err)
+		assert.True(t, outcome.Agreed)
+		assert.Len(t, executor.executed, 1)
+	})
+
+	t.Run("他人连接拒绝", func(t *testing.T) {
+		svc, _ := newConsensusTestService(t, generation, &scriptedExecutor{})
+
+		_, err := svc.Verify(context.Background(), &SQLGenerationRequest{ConnectionID: 3, UserID: 8})
+		assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+	})
+}
+
+func TestSameQueryResult(t *testing.T) {
+	a := revenueResult("revenue", [2]any{"2024-01", int64(100)}, [2]any{"2024-02", nil})
+	b := revenueResult("total", [2]any{"2024-02", nil}, [2]any{"2024-01", float64(100)})
+	assert.True(t, sameQueryResult(a, b))
+
+	c := revenueResult("total", [2]any{"2024-02", int64(0)}, [2]any{"2024-01", float64(100)})
+	assert.False(t, sameQueryResult(a, c))
+
+	d := &QueryResult{Columns: []string{"month"}, Rows: a.Rows, RowCount: a.RowCount}
+	assert.False(t, sameQueryResult(a, d))
+}
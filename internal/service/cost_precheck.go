@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// PlanSummary 执行计划摘要，说明查询被拒绝或提示的原因
+type PlanSummary struct {
+	TotalCost float64  `json:"total_cost"`           // 预估总代价，MySQL为query_cost
+	PlanRows  float64  `json:"plan_rows"`            // 预估返回行数
+	ScanRows  float64  `json:"scan_rows"`            // 单个表扫描预估读取的最多行数，LIMIT提前结束的扫描按比例折算
+	NodeType  string   `json:"node_type,omitempty"`  // 根节点类型，如Aggregate、Seq Scan
+	FullScans []string `json:"full_scans,omitempty"` // 全表扫描的表
+}
+
+// ExplainPlan 在目标连接上执行EXPLAIN并返回执行计划摘要，不执行查询本身
+// 与ExecuteQuery一样按结果上限追加LIMIT，估算的是实际会执行的SQL；SQLite不提供代价估算，返回ErrUnsupportedDBType
+func (e *SQLExecutor) ExplainPlan(ctx context.Context, query string, connection *repository.DatabaseConnection) (*PlanSummary, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, e.queryTimeout)
+	defer cancel()
+
+	if e.autoLimit {
+		if limited, ok := injectLimit(query, int64(e.limitsFor(ctx, connection).maxRows)+1); ok {
+			query = limited
+		}
+	}
+
+	var plan []byte
+	switch connectionDBType(connection) {
+	case repository.DBTypeSQLite:
+		return nil, fmt.Errorf("%w: SQLite不提供查询代价估算", ErrUnsupportedDBType)
+	case repository.DBTypeMySQL:
+		db, err := e.connectionManager.GetSQLDB(queryCtx, connection.ID)
+		if err != nil {
+			return nil, fmt.Errorf("数据库连接失败: %w", err)
+		}
+		if err := db.QueryRowContext(queryCtx, "EXPLAIN FORMAT=JSON "+query, queryArgs(ctx)...).Scan(&plan); err != nil {
+			return nil, fmt.Errorf("获取执行计划失败: %w", err)
+		}
+		return parseMySQLPlan(plan)
+	default:
+		pool, err := e.connectionManager.GetConnectionPool(queryCtx, connection.ID)
+		if err != nil {
+			return nil, fmt.Errorf("数据库连接失败: %w", err)
+		}
+		if err := pool.QueryRow(queryCtx, "EXPLAIN (FORMAT JSON) "+query, queryArgs(ctx)...).Scan(&plan); err != nil {
+			return nil, fmt.Errorf("获取执行计划失败: %w", err)
+		}
+		return parsePostgresPlan(plan)
+	}
+}
+
+// postgresPlanNode EXPLAIN (FORMAT JSON)的计划节点
+type postgresPlanNode struct {
+	NodeType     string             `json:"Node Type"`
+	RelationName string             `json:"Relation Name"`
+	StartupCost  float64            `json:"Startup Cost"`
+	TotalCost    float64            `json:"Total Cost"`
+	PlanRows     float64            `json:"Plan Rows"`
+	Plans        []postgresPlanNode `json:"Plans"`
+}
+
+// parsePostgresPlan 汇总PostgreSQL执行计划：根节点的代价与行数，扫描节点中最多的预估行数与全表扫描的表
+func parsePostgresPlan(plan []byte) (*PlanSummary, error) {
+	var explain []struct {
+		Plan postgresPlanNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return nil, fmt.Errorf("解析执行计划失败: %w", err)
+	}
+	if len(explain) == 0 {
+		return nil, fmt.Errorf("执行计划为空")
+	}
+
+	root := explain[0].Plan
+	summary := &PlanSummary{TotalCost: root.TotalCost, PlanRows: root.PlanRows, NodeType: root.NodeType}
+	// fraction为LIMIT提前结束时节点实际执行的比例，与PostgreSQL计算Limit节点代价的方式一致；
+	// 启动代价不低于子节点总代价的节点（如Sort、Hash）在启动阶段读完该子节点，比例恢复为1
+	var walk func(node postgresPlanNode, fraction float64)
+	walk = func(node postgresPlanNode, fraction float64) {
+		if strings.HasSuffix(node.NodeType, "Scan") {
+			summary.ScanRows = max(summary.ScanRows, node.PlanRows*fraction)
+		}
+		if node.NodeType == "Seq Scan" && node.RelationName != "" && fraction >= 1 {
+			summary.addFullScan(node.RelationName)
+		}
+		for _, child := range node.Plans {
+			childFraction := fraction
+			if node.NodeType == "Limit" {
+				if run := child.TotalCost - child.StartupCost; run > 0 {
+					childFraction *= min(1, max(0, node.TotalCost-child.StartupCost)/run)
+				}
+			} else if node.StartupCost >= child.TotalCost {
+				childFraction = 1
+			}
+			walk(child, childFraction)
+		}
+	}
+	walk(root, 1)
+	return summary, nil
+}
+
+// parseMySQLPlan 汇总MySQL执行计划：query_cost与各表的rows_examined_per_scan，access_type为ALL的表是全表扫描
+func parseMySQLPlan(plan []byte) (*PlanSummary, error) {
+	cost, err := parseMySQLExplainCost(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	var explain map[string]any
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return nil, fmt.Errorf("解析执行计划失败: %w", err)
+	}
+	summary := &PlanSummary{TotalCost: cost, NodeType: "query_block"}
+	var walk func(value any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			if name, ok := v["table_name"].(string); ok {
+				rows := jsonNumber(v["rows_examined_per_scan"])
+				summary.ScanRows = max(summary.ScanRows, rows)
+				summary.PlanRows = max(summary.PlanRows, jsonNumber(v["rows_produced_per_join"]))
+				if v["access_type"] == "ALL" {
+					summary.addFullScan(name)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(explain["query_block"])
+	sort.Strings(summary.FullScans)
+	return summary, nil
+}
+
+// jsonNumber 读取MySQL执行计划中的数值，部分版本以字符串输出
+func jsonNumber(value any) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		n, _ := strconv.ParseFloat(v, 64)
+		return n
+	}
+	return 0
+}
+
+func (s *PlanSummary) addFullScan(table string) {
+	for _, existing := range s.FullScans {
+		if existing == table {
+			return
+		}
+	}
+	s.FullScans = append(s.FullScans, table)
+}
+
+// PlanExplainer 获取执行计划的能力
+type PlanExplainer interface {
+	ExplainPlan(ctx context.Context, query string, connection *repository.DatabaseConnection) (*PlanSummary, error)
+}
+
+// CostThresholds 代价预检阈值，为0的项不检查
+type CostThresholds struct {
+	WarnCost     float64
+	MaxCost      float64
+	WarnScanRows float64
+	MaxScanRows  float64
+}
+
+// CostCheck 代价预检结果，Blocked为true时不应执行查询
+type CostCheck struct {
+	Plan      *PlanSummary
+	Blocked   bool
+	Reason    string    // 拒绝执行的原因
+	Guardrail Guardrail // 拒绝执行时生效的防护措施
+	Warnings  []string
+}
+
+// CostPrecheck 执行SQL前用EXPLAIN预估代价与读取行数，超过阈值时提示或拒绝执行
+type CostPrecheck struct {
+	explainer  PlanExplainer
+	thresholds CostThresholds
+	logger     *zap.Logger
+}
+
+// NewCostPrecheck 创建代价预检
+func NewCostPrecheck(explainer PlanExplainer, thresholds CostThresholds, logger *zap.Logger) *CostPrecheck {
+	return &CostPrecheck{explainer: explainer, thresholds: thresholds, logger: logger}
+}
+
+// Check 获取执行计划并与阈值比较；无法获取执行计划时只提示，不拒绝执行
+func (p *CostPrecheck) Check(ctx context.Context, query string, connection *repository.DatabaseConnection) *CostCheck {
+	plan, err := p.explainer.ExplainPlan(ctx, query, connection)
+	if err != nil {
+		p.logger.Warn("代价预检获取执行计划失败", zap.Int64("connection_id", connection.ID), zap.Error(err))
+		return &CostCheck{Warnings: []string{"无法获取执行计划，未预估查询代价"}}
+	}
+	return EvaluatePlan(plan, p.thresholds)
+}
+
+// EvaluatePlan 按阈值判定执行计划，先检查拒绝阈值再检查提示阈值
+func EvaluatePlan(plan *PlanSummary, thresholds CostThresholds) *CostCheck {
+	check := &CostCheck{Plan: plan}
+	switch {
+	case thresholds.MaxCost > 0 && plan.TotalCost > thresholds.MaxCost:
+		check.Blocked = true
+		check.Reason = fmt.Sprintf("预估代价%.0f超过上限%.0f，查询未执行", plan.TotalCost, thresholds.MaxCost)
+		check.Guardrail = Guardrail{Type: GuardrailCostLimit, Limit: int64(thresholds.MaxCost)}
+	case thresholds.MaxScanRows > 0 && plan.ScanRows > thresholds.MaxScanRows:
+		check.Blocked = true
+		check.Reason = fmt.Sprintf("预估读取%.0f行，超过上限%.0f行，查询未执行", plan.ScanRows, thresholds.MaxScanRows)
+		check.Guardrail = Guardrail{Type: GuardrailScanRowsLimit, Limit: int64(thresholds.MaxScanRows)}
+	}
+	if check.Blocked {
+		if len(plan.FullScans) > 0 {
+			check.Reason += fmt.Sprintf("；全表扫描：%s，可增加过滤条件或限定时间范围", strings.Join(plan.FullScans, ", "))
+		}
+		return check
+	}
+
+	if thresholds.WarnCost > 0 && plan.TotalCost > thresholds.WarnCost {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("预估代价%.0f较高，查询可能较慢", plan.TotalCost))
+	}
+	if thresholds.WarnScanRows > 0 && plan.ScanRows > thresholds.WarnScanRows {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("预估读取%.0f行，查询可能较慢", plan.ScanRows))
+	}
+	return check
+}
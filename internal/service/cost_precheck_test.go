@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+func TestParsePostgresPlan(t *testing.T) {
+	summary, err := parsePostgresPlan([]byte(`[{"Plan": {
+		"Node Type": "Hash Join", "Startup Cost": 30, "Total Cost": 250000, "Plan Rows": 5000,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 180000, "Plan Rows": 4000000},
+			{"Node Type": "Hash", "Total Cost": 20, "Plan Rows": 100, "Plans": [
+				{"Node Type": "Index Scan", "Relation Name": "customers", "Total Cost": 20, "Plan Rows": 100}
+			]}
+		]}}]`))
+	require.NoError(t, err)
+	assert.Equal(t, 250000.0, summary.TotalCost)
+	assert.Equal(t, 5000.0, summary.PlanRows)
+	assert.Equal(t, 4000000.0, summary.ScanRows)
+	assert.Equal(t, "Hash Join", summary.NodeType)
+	assert.Equal(t, []string{"orders"}, summary.FullScans)
+
+	// LIMIT提前结束的扫描按比例折算，需要读完输入的Sort不折算
+	summary, err = parsePostgresPlan([]byte(`[{"Plan": {"Node Type": "Limit", "Startup Cost": 0, "Total Cost": 18, "Plan Rows": 1001,
+		"Plans": [{"Node Type": "Seq Scan", "Relation Name": "events", "Startup Cost": 0, "Total Cost": 180000, "Plan Rows": 10000000}]}}]`))
+	require.NoError(t, err)
+	assert.InDelta(t, 1000, summary.ScanRows, 1)
+	assert.Empty(t, summary.FullScans)
+
+	summary, err = parsePostgresPlan([]byte(`[{"Plan": {"Node Type": "Limit", "Startup Cost": 900000, "Total Cost": 900010, "Plan Rows": 1001,
+		"Plans": [{"Node Type": "Sort", "Startup Cost": 900000, "Total Cost": 910000, "Plan Rows": 10000000,
+			"Plans": [{"Node Type": "Seq Scan", "Relation Name": "events", "Total Cost": 180000, "Plan Rows": 10000000}]}]}}]`))
+	require.NoError(t, err)
+	assert.Equal(t, 10000000.0, summary.ScanRows)
+	assert.Equal(t, []string{"events"}, summary.FullScans)
+
+	_, err = parsePostgresPlan([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestParseMySQLPlan(t *testing.T) {
+	summary, err := parseMySQLPlan([]byte(`{"query_block": {"select_id": 1, "cost_info": {"query_cost": "52000.10"},
+		"nested_loop": [
+			{"table": {"table_name": "orders", "access_type": "ALL", "rows_examined_per_scan": 480000, "rows_produced_per_join": 48000}},
+			{"table": {"table_name": "customers", "access_type": "eq_ref", "rows_examined_per_scan": 1, "rows_produced_per_join": "48000"}}
+		]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 52000.1, summary.TotalCost)
+	assert.Equal(t, 480000.0, summary.ScanRows)
+	assert.Equal(t, 48000.0, summary.PlanRows)
+	assert.Equal(t, []string{"orders"}, summary.FullScans)
+}
+
+func TestEvaluatePlan(t *testing.T) {
+	thresholds := CostThresholds{WarnCost: 1000, MaxCost: 100000, WarnScanRows: 10000, MaxScanRows: 1000000}
+
+	check := EvaluatePlan(&PlanSummary{TotalCost: 500, ScanRows: 100}, thresholds)
+	assert.False(t, check.Blocked)
+	assert.Empty(t, check.Warnings)
+
+	check = EvaluatePlan(&PlanSummary{TotalCost: 5000, ScanRows: 50000}, thresholds)
+	assert.False(t, check.Blocked)
+	assert.Len(t, check.Warnings, 2)
+
+	check = EvaluatePlan(&PlanSummary{TotalCost: 250000, ScanRows: 4000000, FullScans: []string{"orders"}}, thresholds)
+	assert.True(t, check.Blocked)
+	assert.Equal(t, Guardrail{Type: GuardrailCostLimit, Limit: 100000}, check.Guardrail)
+	assert.Contains(t, check.Reason, "250000")
+	assert.Contains(t, check.Reason, "orders")
+
+	check = EvaluatePlan(&PlanSummary{TotalCost: 50000, ScanRows: 4000000}, thresholds)
+	assert.True(t, check.Blocked)
+	assert.Equal(t, GuardrailScanRowsLimit, check.Guardrail.Type)
+
+	// 阈值为0时不检查
+	check = EvaluatePlan(&PlanSummary{TotalCost: 1e12, ScanRows: 1e12}, CostThresholds{})
+	assert.False(t, check.Blocked)
+	assert.Empty(t, check.Warnings)
+}
+
+// stubPlanExplainer 返回固定的执行计划
+type stubPlanExplainer struct {
+	plan *PlanSummary
+	err  error
+}
+
+func (s *stubPlanExplainer) ExplainPlan(ctx context.Context, query string, connection *repository.DatabaseConnection) (*PlanSummary, error) {
+	return s.plan, s.err
+}
+
+func TestCostPrecheck_ExplainFailure(t *testing.T) {
+	precheck := NewCostPrecheck(&stubPlanExplainer{err: errors.New("permission denied")}, CostThresholds{MaxCost: 1}, zap.NewNop())
+
+	check := precheck.Check(context.Background(), "SELECT 1", &repository.DatabaseConnection{})
+	assert.False(t, check.Blocked, "无法获取执行计划时不拒绝执行")
+	assert.Nil(t, check.Plan)
+	assert.NotEmpty(t, check.Warnings)
+}
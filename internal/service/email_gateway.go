@@ -0,0 +1,302 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/crash"
+	"chat2sql-go/internal/repository"
+)
+
+// ErrEmailQueueFull 邮件查询队列已满
+var ErrEmailQueueFull = errors.New("邮件查询队列已满")
+
+var emailConnectionPattern = regexp.MustCompile(`#(\d+)`)
+
+// SQLGenerator SQL生成能力（由AIService实现）
+type SQLGenerator interface {
+	GenerateSQL(ctx context.Context, req *SQLGenerationRequest) (*SQLGenerationResponse, error)
+}
+
+// QueryExecutor SQL执行能力（由SQLExecutor实现）
+type QueryExecutor interface {
+	ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error)
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, email *OutboundEmail) error
+}
+
+// InboundEmail 已验证用户发送的入站邮件
+type InboundEmail struct {
+	UserID  int64  `json:"user_id"`
+	From    string `json:"from"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// OutboundEmail 回复邮件
+type OutboundEmail struct {
+	To          string            `json:"to"`
+	Subject     string            `json:"subject"`
+	Body        string            `json:"body"`
+	Attachments []EmailAttachment `json:"attachments,omitempty"`
+}
+
+// EmailAttachment 邮件附件
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"-"`
+}
+
+// EmailQueryJob 邮件触发的查询任务
+type EmailQueryJob struct {
+	Email       InboundEmail `json:"email"`
+	ScheduledAt time.Time    `json:"scheduled_at"`
+}
+
+// EmailGateway 邮件查询网关
+// 将已验证用户的邮件提问排入执行队列，生成并执行SQL后以CSV附件回复
+type EmailGateway struct {
+	generator      SQLGenerator
+	executor       QueryExecutor
+	connectionRepo repository.ConnectionRepository
+	validator      *SQLSecurityValidator
+	mailer         Mailer
+	logger         *zap.Logger
+
+	queue     chan *EmailQueryJob
+	workers   int
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	mutex     sync.Mutex
+	isRunning bool
+}
+
+// NewEmailGateway 创建邮件查询网关
+func NewEmailGateway(
+	generator SQLGenerator,
+	executor QueryExecutor,
+	connectionRepo repository.ConnectionRepository,
+	mailer Mailer,
+	gatewayConfig *config.EmailGatewayConfig,
+	logger *zap.Logger,
+) *EmailGateway {
+	if gatewayConfig == nil {
+		gatewayConfig = config.DefaultEmailGatewayConfig()
+	}
+	return &EmailGateway{
+		generator:      generator,
+		executor:       executor,
+		connectionRepo: connectionRepo,
+		validator:      NewSQLSecurityValidator(logger),
+		mailer:         mailer,
+		logger:         logger,
+		queue:          make(chan *EmailQueryJob, gatewayConfig.QueueSize),
+		workers:        gatewayConfig.Workers,
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start 启动执行worker
+func (g *EmailGateway) Start() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.isRunning {
+		return errors.New("邮件网关已在运行")
+	}
+	g.isRunning = true
+
+	for i := 0; i < g.workers; i++ {
+		g.wg.Add(1)
+		go g.workerRoutine()
+	}
+
+	g.logger.Info("邮件查询网关已启动", zap.Int("workers", g.workers))
+	return nil
+}
+
+// Stop 停止执行worker，等待进行中的任务完成
+func (g *EmailGateway) Stop() error {
+	g.mutex.Lock()
+	if !g.isRunning {
+		g.mutex.Unlock()
+		return nil
+	}
+	g.isRunning = false
+	close(g.stopCh)
+	g.mutex.Unlock()
+
+	g.wg.Wait()
+	g.logger.Info("邮件查询网关已停止")
+	return nil
+}
+
+// Enqueue 将邮件提问排入执行队列
+func (g *EmailGateway) Enqueue(email *InboundEmail) (*EmailQueryJob, error) {
+	job := &EmailQueryJob{Email: *email, ScheduledAt: time.Now()}
+
+	select {
+	case g.queue <- job:
+		return job, nil
+	default:
+		return nil, ErrEmailQueueFull
+	}
+}
+
+// workerRoutine 从队列中取出任务执行
+func (g *EmailGateway) workerRoutine() {
+	defer g.wg.Done()
+
+	for {
+		select {
+		case job := <-g.queue:
+			crash.Run("email.worker", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+				defer cancel()
+				g.ProcessJob(ctx, job)
+			})
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+// ProcessJob 执行单个邮件查询任务并发送回复
+func (g *EmailGateway) ProcessJob(ctx context.Context, job *EmailQueryJob) {
+	reply := g.runJob(ctx, job)
+	if err := g.mailer.Send(ctx, reply); err != nil {
+		g.logger.Error("发送邮件回复失败",
+			zap.Error(err),
+			zap.Int64("user_id", job.Email.UserID),
+			zap.String("to", job.Email.From))
+	}
+}
+
+// runJob 生成并执行SQL，构建回复邮件（失败时回复错误说明）
+func (g *EmailGateway) runJob(ctx context.Context, job *EmailQueryJob) *OutboundEmail {
+	email := job.Email
+	reply := &OutboundEmail{To: email.From, Subject: "Re: " + email.Subject}
+
+	question := strings.TrimSpace(email.Body)
+	if question == "" {
+		question = strings.TrimSpace(emailConnectionPattern.ReplaceAllString(email.Subject, ""))
+	}
+	if question == "" {
+		reply.Body = "未能识别您的问题，请在邮件正文中描述需要查询的数据。"
+		return reply
+	}
+
+	connection, err := g.resolveConnection(ctx, email)
+	if err != nil {
+		reply.Body = err.Error()
+		return reply
+	}
+
+	generated, err := g.generator.GenerateSQL(ctx, &SQLGenerationRequest{
+		Query:        question,
+		ConnectionID: connection.ID,
+		UserID:       email.UserID,
+		Locale:       DetectLocale(question),
+		Workload:     WorkloadBatch, // 邮件回复异步送达，不需要为延迟改用更贵的模型
+	})
+	if err != nil {
+		g.logger.Error("邮件提问生成SQL失败", zap.Error(err), zap.Int64("user_id", email.UserID))
+		reply.Body = "生成SQL失败，请稍后重试或登录Chat2SQL查看。"
+		return reply
+	}
+
+	if result := g.validator.ValidateSQL(generated.SQL); !result.IsValid || !result.IsReadOnly {
+		reply.Body = "生成的SQL未通过安全检查，已拒绝执行：\n\n" + generated.SQL
+		return reply
+	}
+
+	result, err := g.executor.ExecuteQuery(WithQueryOwner(ctx, email.UserID), generated.SQL, connection)
+	if err != nil {
+		g.logger.Error("邮件查询执行失败", zap.Error(err), zap.Int64("connection_id", connection.ID))
+		reply.Body = "查询执行失败：" + err.Error() + "\n\nSQL：\n" + generated.SQL
+		return reply
+	}
+
+	data, err := BuildResultCSV(result)
+	if err != nil {
+		reply.Body = "结果导出失败：" + err.Error()
+		return reply
+	}
+
+	reply.Body = fmt.Sprintf("问题：%s\n\nSQL：\n%s\n\n共 %d 行，耗时 %dms，结果见附件。",
+		question, generated.SQL, result.RowCount, result.ExecutionTime)
+	for _, warning := range result.Warnings {
+		reply.Body += "\n注意：" + warning
+	}
+	reply.Attachments = []EmailAttachment{{
+		Filename:    fmt.Sprintf("chat2sql-%s.csv", job.ScheduledAt.Format("20060102-150405")),
+		ContentType: "text/csv",
+		Data:        data,
+	}}
+	return reply
+}
+
+// resolveConnection 解析主题中的 #<连接ID>，否则使用用户第一个可用连接
+func (g *EmailGateway) resolveConnection(ctx context.Context, email InboundEmail) (*repository.DatabaseConnection, error) {
+	if m := emailConnectionPattern.FindStringSubmatch(email.Subject); m != nil {
+		id, _ := strconv.ParseInt(m[1], 10, 64)
+		connection, err := g.connectionRepo.GetByID(ctx, id)
+		if err != nil || connection.UserID != email.UserID {
+			return nil, fmt.Errorf("数据库连接 #%d 不存在或无权访问", id)
+		}
+		return connection, nil
+	}
+
+	connections, err := g.connectionRepo.ListByUser(ctx, email.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败")
+	}
+	for _, connection := range connections {
+		if connection.IsConnectionHealthy() {
+			return connection, nil
+		}
+	}
+	return nil, fmt.Errorf("您没有可用的数据库连接，请先在Chat2SQL中添加连接")
+}
+
+// BuildResultCSV 将查询结果编码为CSV
+func BuildResultCSV(result *QueryResult) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write(result.Columns); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, len(result.Columns))
+	for _, row := range result.Rows {
+		for i, col := range result.Columns {
+			if value := row[col]; value != nil {
+				record[i] = fmt.Sprint(value)
+			} else {
+				record[i] = ""
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// emailTestConnectionRepo 仅实现邮件网关用到的连接查询方法
+type emailTestConnectionRepo struct {
+	repository.ConnectionRepository
+	connections []*repository.DatabaseConnection
+}
+
+func (r *emailTestConnectionRepo) GetByID(_ context.Context, id int64) (*repository.DatabaseConnection, error) {
+	for _, conn := range r.connections {
+		if conn.ID == id {
+			return conn, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *emailTestConnectionRepo) ListByUser(_ context.Context, userID int64) ([]*repository.DatabaseConnection, error) {
+	var result []*repository.DatabaseConnection
+	for _, conn := range r.connections {
+		if conn.UserID == userID {
+			result = append(result, conn)
+		}
+	}
+	return result, nil
+}
+
+type emailTestGenerator struct {
+	sql string
+	err error
+}
+
+func (g *emailTestGenerator) GenerateSQL(_ context.Context, _ *SQLGenerationRequest) (*SQLGenerationResponse, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &SQLGenerationResponse{SQL: g.sql}, nil
+}
+
+type emailTestExecutor struct {
+	result   *QueryResult
+	executed []int64
+}
+
+func (e *emailTestExecutor) ExecuteQuery(_ context.Context, _ string, connection *repository.DatabaseConnection) (*QueryResult, error) {
+	e.executed = append(e.executed, connection.ID)
+	return e.result, nil
+}
+
+type emailTestMailer struct {
+	mu   sync.Mutex
+	sent []*OutboundEmail
+}
+
+func (m *emailTestMailer) Send(_ context.Context, email *OutboundEmail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+func (m *emailTestMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sent)
+}
+
+func newEmailTestGateway(t *testing.T, generator SQLGenerator, executor QueryExecutor, mailer Mailer) *EmailGateway {
+	repo := &emailTestConnectionRepo{connections: []*repository.DatabaseConnection{
+		testutil.NewConnection(1, 10, testutil.WithConnectionStatus(repository.ConnectionInactive)),
+		testutil.NewConnection(2, 10),
+		testutil.NewConnection(3, 20),
+	}}
+	return NewEmailGateway(generator, executor, repo, mailer, config.DefaultEmailGatewayConfig(), zaptest.NewLogger(t))
+}
+
+func TestEmailGateway_RepliesWithCSV(t *testing.T) {
+	executor := &emailTestExecutor{result: &QueryResult{
+		Columns:  []string{"region", "total"},
+		Rows:     []map[string]any{{"region": "华东", "total": 100}, {"region": "华北", "total": nil}},
+		RowCount: 2,
+	}}
+	mailer := &emailTestMailer{}
+	gateway := newEmailTestGateway(t, &emailTestGenerator{sql: "SELECT region, total FROM sales"}, executor, mailer)
+
+	job, err := gateway.Enqueue(&InboundEmail{UserID: 10, From: "ceo@example.com", Subject: "本月销售", Body: "按区域统计本月销售额"})
+	require.NoError(t, err)
+	gateway.ProcessJob(context.Background(), <-gateway.queue)
+
+	require.Len(t, mailer.sent, 1)
+	reply := mailer.sent[0]
+	assert.Equal(t, "ceo@example.com", reply.To)
+	assert.Equal(t, "Re: 本月销售", reply.Subject)
+	require.Len(t, reply.Attachments, 1)
+	assert.Equal(t, "region,total\n华东,100\n华北,\n", string(reply.Attachments[0].Data))
+	assert.Contains(t, reply.Attachments[0].Filename, job.ScheduledAt.Format("20060102"))
+	assert.Equal(t, []int64{2}, executor.executed, "应使用第一个活跃连接")
+}
+
+func TestEmailGateway_ConnectionFromSubject(t *testing.T) {
+	executor := &emailTestExecutor{result: &QueryResult{Columns: []string{"n"}}}
+	mailer := &emailTestMailer{}
+	gateway := newEmailTestGateway(t, &emailTestGenerator{sql: "SELECT 1 AS n"}, executor, mailer)
+
+	gateway.ProcessJob(context.Background(), &EmailQueryJob{Email: InboundEmail{UserID: 10, From: "a@example.com", Subject: "#1 订单数", Body: "订单数"}})
+	assert.Equal(t, []int64{1}, executor.executed)
+
+	// 不允许访问他人的连接
+	gateway.ProcessJob(context.Background(), &EmailQueryJob{Email: InboundEmail{UserID: 10, From: "a@example.com", Subject: "#3 订单数", Body: "订单数"}})
+	assert.Equal(t, []int64{1}, executor.executed)
+	require.Len(t, mailer.sent, 2)
+	assert.Contains(t, mailer.sent[1].Body, "无权访问")
+}
+
+func TestEmailGateway_RejectsUnsafeSQL(t *testing.T) {
+	executor := &emailTestExecutor{}
+	mailer := &emailTestMailer{}
+	gateway := newEmailTestGateway(t, &emailTestGenerator{sql: "DROP TABLE users"}, executor, mailer)
+
+	gateway.ProcessJob(context.Background(), &EmailQueryJob{Email: InboundEmail{UserID: 10, From: "a@example.com", Body: "清空用户"}})
+
+	assert.Empty(t, executor.executed)
+	require.Len(t, mailer.sent, 1)
+	assert.Empty(t, mailer.sent[0].Attachments)
+	assert.Contains(t, mailer.sent[0].Body, "安全检查")
+}
+
+func TestEmailGateway_GenerationError(t *testing.T) {
+	mailer := &emailTestMailer{}
+	gateway := newEmailTestGateway(t, &emailTestGenerator{err: errors.New("llm down")}, &emailTestExecutor{}, mailer)
+
+	gateway.ProcessJob(context.Background(), &EmailQueryJob{Email: InboundEmail{UserID: 10, From: "a@example.com", Body: "订单数"}})
+
+	require.Len(t, mailer.sent, 1)
+	assert.Contains(t, mailer.sent[0].Body, "生成SQL失败")
+}
+
+func TestEmailGateway_QueueFullAndWorkers(t *testing.T) {
+	cfg := config.DefaultEmailGatewayConfig()
+	cfg.QueueSize = 1
+	mailer := &emailTestMailer{}
+	gateway := NewEmailGateway(&emailTestGenerator{sql: "SELECT 1"}, &emailTestExecutor{result: &QueryResult{}},
+		&emailTestConnectionRepo{}, mailer, cfg, zaptest.NewLogger(t))
+
+	_, err := gateway.Enqueue(&InboundEmail{UserID: 1, From: "a@example.com", Body: "q"})
+	require.NoError(t, err)
+	_, err = gateway.Enqueue(&InboundEmail{UserID: 1, From: "a@example.com", Body: "q"})
+	assert.ErrorIs(t, err, ErrEmailQueueFull)
+
+	require.NoError(t, gateway.Start())
+	assert.Eventually(t, func() bool { return mailer.count() == 1 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, gateway.Stop())
+}
+
+func TestBuildMIMEMessage(t *testing.T) {
+	message, err := BuildMIMEMessage("bot@example.com", &OutboundEmail{
+		To:          "ceo@example.com",
+		Subject:     "Re: 销售",
+		Body:        "结果见附件",
+		Attachments: []EmailAttachment{{Filename: "result.csv", ContentType: "text/csv", Data: []byte("a,b\n1,2\n")}},
+	})
+	require.NoError(t, err)
+
+	text := string(message)
+	assert.True(t, strings.HasPrefix(text, "From: bot@example.com\r\nTo: ceo@example.com\r\n"))
+	assert.Contains(t, text, "multipart/mixed")
+	assert.Contains(t, text, `attachment; filename=result.csv`)
+}
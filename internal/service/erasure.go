@@ -0,0 +1,233 @@
+// 个人数据删除（GDPR被遗忘权）
+// 用户本人或管理员提交删除申请，后台任务领取申请后在同一事务中匿名化或删除该用户的个人数据，
+// 再清除进程内的对话记忆，最后把各类数据的处理条数写入删除报告
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/crash"
+	"chat2sql-go/internal/repository"
+)
+
+// ConversationMemory 按用户保存对话记忆的组件，删除个人数据时需要一并清除
+type ConversationMemory interface {
+	// ForgetUser 清除用户的对话记忆，返回清除的记录数
+	ForgetUser(userID int64) int
+}
+
+// ErasureService 个人数据删除服务
+type ErasureService struct {
+	repo     repository.ErasureRepository
+	config   *config.ErasureConfig
+	memories []ConversationMemory
+	logger   *zap.Logger
+
+	now       func() time.Time
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	mutex     sync.RWMutex
+	isRunning bool
+}
+
+// NewErasureService 创建个人数据删除服务
+func NewErasureService(repo repository.ErasureRepository, erasureConfig *config.ErasureConfig, logger *zap.Logger) *ErasureService {
+	if erasureConfig == nil {
+		erasureConfig = config.DefaultErasureConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ErasureService{
+		repo:   repo,
+		config: erasureConfig,
+		logger: logger,
+		now:    time.Now,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// AddConversationMemory 注册需要随个人数据一并清除的对话记忆
+func (s *ErasureService) AddConversationMemory(memory ConversationMemory) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.memories = append(s.memories, memory)
+}
+
+// Submit 提交删除申请，用户只能为自己提交，管理员可以为任意用户提交
+func (s *ErasureService) Submit(ctx context.Context, requestedBy int64, role string, subjectUserID int64, mode repository.ErasureMode) (*repository.ErasureRequest, error) {
+	if requestedBy != subjectUserID && role != string(repository.RoleAdmin) {
+		return nil, fmt.Errorf("只能删除自己的个人数据: %w", repository.ErrPermissionDenied)
+	}
+	if mode != repository.ErasureAnonymize && mode != repository.ErasureDelete {
+		return nil, fmt.Errorf("%w: 未知的删除方式%s", repository.ErrInvalidInput, mode)
+	}
+
+	req := &repository.ErasureRequest{
+		SubjectUserID: subjectUserID,
+		Mode:          string(mode),
+		Status:        string(repository.ErasurePending),
+		RequestedBy:   requestedBy,
+	}
+	if err := s.repo.Create(ctx, req); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("个人数据删除申请已提交",
+		zap.Int64("erasure_id", req.ID),
+		zap.Int64("subject_user_id", subjectUserID),
+		zap.Int64("requested_by", requestedBy),
+		zap.String("mode", req.Mode))
+	return req, nil
+}
+
+// Get 获取删除申请及报告，只有数据主体、提交人或管理员可以查看
+func (s *ErasureService) Get(ctx context.Context, userID int64, role string, id int64) (*repository.ErasureRequest, error) {
+	req, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID != req.SubjectUserID && userID != req.RequestedBy && role != string(repository.RoleAdmin) {
+		return nil, fmt.Errorf("无权查看该删除申请: %w", repository.ErrPermissionDenied)
+	}
+	return req, nil
+}
+
+// List 列出用户本人的删除申请
+func (s *ErasureService) List(ctx context.Context, userID int64) ([]*repository.ErasureRequest, error) {
+	return s.repo.ListBySubject(ctx, userID)
+}
+
+// ProcessPending 领取并处理待处理申请，返回处理完成的申请数
+func (s *ErasureService) ProcessPending(ctx context.Context) (int, error) {
+	requests, err := s.repo.ClaimPending(ctx, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, req := range requests {
+		if err := s.process(ctx, req); err != nil {
+			s.logger.Error("个人数据删除失败",
+				zap.Int64("erasure_id", req.ID),
+				zap.Int64("subject_user_id", req.SubjectUserID),
+				zap.Error(err))
+			if failErr := s.repo.Fail(ctx, req.ID, err.Error()); failErr != nil {
+				s.logger.Error("记录删除失败状态失败", zap.Int64("erasure_id", req.ID), zap.Error(failErr))
+			}
+			continue
+		}
+		completed++
+	}
+	return completed, nil
+}
+
+// process 清除数据库中的个人数据与对话记忆并生成删除报告
+func (s *ErasureService) process(ctx context.Context, req *repository.ErasureRequest) error {
+	pseudonym, err := newPseudonym()
+	if err != nil {
+		return err
+	}
+
+	report, err := s.repo.EraseUserData(ctx, req.SubjectUserID, repository.ErasureMode(req.Mode), pseudonym)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.RLock()
+	for _, memory := range s.memories {
+		report.ConversationMemory += int64(memory.ForgetUser(req.SubjectUserID))
+	}
+	s.mutex.RUnlock()
+
+	report.CompletedAt = s.now().UTC()
+	if err := s.repo.Complete(ctx, req.ID, report); err != nil {
+		return err
+	}
+	req.Status = string(repository.ErasureCompleted)
+	req.Report = report
+
+	s.logger.Info("个人数据删除已完成",
+		zap.Int64("erasure_id", req.ID),
+		zap.Int64("subject_user_id", req.SubjectUserID),
+		zap.String("mode", req.Mode),
+		zap.Int64("conversation_memory", report.ConversationMemory))
+	return nil
+}
+
+// newPseudonym 生成随机假名，与原用户名无关联，无法还原
+func newPseudonym() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成假名失败: %w", err)
+	}
+	return "erased-" + hex.EncodeToString(buf), nil
+}
+
+// Start 启动后台删除任务
+func (s *ErasureService) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.isRunning {
+		return errors.New("个人数据删除任务已在运行")
+	}
+	s.isRunning = true
+
+	s.wg.Add(1)
+	go s.processRoutine()
+
+	s.logger.Info("个人数据删除任务已启动",
+		zap.Duration("process_interval", s.config.ProcessInterval),
+		zap.Int("batch_size", s.config.BatchSize))
+	return nil
+}
+
+// Stop 停止后台删除任务
+func (s *ErasureService) Stop() error {
+	s.mutex.Lock()
+	if !s.isRunning {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.isRunning = false
+	close(s.stopCh)
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("个人数据删除任务已停止")
+	return nil
+}
+
+// processRoutine 定期处理待处理申请
+func (s *ErasureService) processRoutine() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.ProcessInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			crash.Run("erasure.process", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+				defer cancel()
+				if _, err := s.ProcessPending(ctx); err != nil {
+					s.logger.Error("处理个人数据删除申请失败", zap.Error(err))
+				}
+			})
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// memErasureRepository 内存个人数据删除Repository
+type memErasureRepository struct {
+	requests map[int64]*repository.ErasureRequest
+	erased   map[int64]string // userID -> 假名
+	eraseErr error
+}
+
+func newMemErasureRepository() *memErasureRepository {
+	return &memErasureRepository{
+		requests: make(map[int64]*repository.ErasureRequest),
+		erased:   make(map[int64]string),
+	}
+}
+
+func (m *memErasureRepository) Create(ctx context.Context, req *repository.ErasureRequest) error {
+	for _, existing := range m.requests {
+		if existing.SubjectUserID == req.SubjectUserID &&
+			(existing.Status == string(repository.ErasurePending) || existing.Status == string(repository.ErasureRunning)) {
+			return repository.ErrDuplicateEntry
+		}
+	}
+	req.ID = int64(len(m.requests) + 1)
+	m.requests[req.ID] = req
+	return nil
+}
+
+func (m *memErasureRepository) GetByID(ctx context.Context, id int64) (*repository.ErasureRequest, error) {
+	if req, ok := m.requests[id]; ok {
+		return req, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memErasureRepository) ListBySubject(ctx context.Context, subjectUserID int64) ([]*repository.ErasureRequest, error) {
+	var requests []*repository.ErasureRequest
+	for _, req := range m.requests {
+		if req.SubjectUserID == subjectUserID {
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+func (m *memErasureRepository) ClaimPending(ctx context.Context, limit int) ([]*repository.ErasureRequest, error) {
+	var claimed []*repository.ErasureRequest
+	for id := int64(1); id <= int64(len(m.requests)) && len(claimed) < limit; id++ {
+		if req := m.requests[id]; req.Status == string(repository.ErasurePending) {
+			req.Status = string(repository.ErasureRunning)
+			claimed = append(claimed, req)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *memErasureRepository) Complete(ctx context.Context, id int64, report *repository.ErasureReport) error {
+	m.requests[id].Status = string(repository.ErasureCompleted)
+	m.requests[id].Report = report
+	return nil
+}
+
+func (m *memErasureRepository) Fail(ctx context.Context, id int64, errorMessage string) error {
+	m.requests[id].Status = string(repository.ErasureFailed)
+	m.requests[id].ErrorMessage = &errorMessage
+	return nil
+}
+
+func (m *memErasureRepository) EraseUserData(ctx context.Context, userID int64, mode repository.ErasureMode, pseudonym string) (*repository.ErasureReport, error) {
+	if m.eraseErr != nil {
+		return nil, m.eraseErr
+	}
+	m.erased[userID] = pseudonym
+	return &repository.ErasureReport{Mode: string(mode), Pseudonym: pseudonym, QueryHistory: 3, Feedback: 1}, nil
+}
+
+// fakeConversationMemory 记录被清除的用户
+type fakeConversationMemory struct {
+	forgotten []int64
+}
+
+func (f *fakeConversationMemory) ForgetUser(userID int64) int {
+	f.forgotten = append(f.forgotten, userID)
+	return 2
+}
+
+func newErasureTestService(t *testing.T) (*ErasureService, *memErasureRepository) {
+	repo := newMemErasureRepository()
+	svc := NewErasureService(repo, config.DefaultErasureConfig(), zaptest.NewLogger(t))
+	svc.now = func() time.Time { return time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC) }
+	return svc, repo
+}
+
+func TestErasureService_Submit(t *testing.T) {
+	svc, _ := newErasureTestService(t)
+	ctx := context.Background()
+
+	req, err := svc.Submit(ctx, 7, "user", 7, repository.ErasureAnonymize)
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.ErasurePending), req.Status)
+
+	_, err = svc.Submit(ctx, 7, "user", 7, repository.ErasureDelete)
+	assert.ErrorIs(t, err, repository.ErrDuplicateEntry, "同一用户不能同时有两个未完成的申请")
+
+	_, err = svc.Submit(ctx, 7, "manager", 8, repository.ErasureDelete)
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+
+	_, err = svc.Submit(ctx, 1, "admin", 8, "shred")
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	req, err = svc.Submit(ctx, 1, "admin", 8, repository.ErasureDelete)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), req.RequestedBy)
+
+	_, err = svc.Get(ctx, 9, "user", req.ID)
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+	_, err = svc.Get(ctx, 8, "user", req.ID)
+	assert.NoError(t, err, "数据主体可以查看删除报告")
+}
+
+func TestErasureService_ProcessPending(t *testing.T) {
+	svc, repo := newErasureTestService(t)
+	memory := &fakeConversationMemory{}
+	svc.AddConversationMemory(memory)
+	ctx := context.Background()
+
+	req, err := svc.Submit(ctx, 7, "user", 7, repository.ErasureAnonymize)
+	require.NoError(t, err)
+
+	completed, err := svc.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, completed)
+
+	assert.Equal(t, string(repository.ErasureCompleted), req.Status)
+	require.NotNil(t, req.Report)
+	assert.True(t, strings.HasPrefix(req.Report.Pseudonym, "erased-"))
+	assert.Equal(t, repo.erased[7], req.Report.Pseudonym)
+	assert.Equal(t, int64(3), req.Report.QueryHistory)
+	assert.Equal(t, int64(2), req.Report.ConversationMemory)
+	assert.Equal(t, svc.now().UTC(), req.Report.CompletedAt)
+	assert.Equal(t, []int64{7}, memory.forgotten)
+
+	completed, err = svc.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, completed, "已完成的申请不应重复处理")
+}
+
+func TestErasureService_ProcessPending_Failure(t *testing.T) {
+	svc, repo := newErasureTestService(t)
+	memory := &fakeConversationMemory{}
+	svc.AddConversationMemory(memory)
+	repo.eraseErr = errors.New("connection reset")
+	ctx := context.Background()
+
+	req, err := svc.Submit(ctx, 7, "user", 7, repository.ErasureDelete)
+	require.NoError(t, err)
+
+	completed, err := svc.ProcessPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, completed)
+	assert.Equal(t, string(repository.ErasureFailed), req.Status)
+	require.NotNil(t, req.ErrorMessage)
+	assert.Empty(t, memory.forgotten, "数据库清除失败时不应清除对话记忆")
+}
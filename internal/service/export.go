@@ -0,0 +1,325 @@
+// 查询结果导出
+// 将流式查询的结果逐行写成CSV或Excel（xlsx）文件，不在内存中缓存整个结果集。
+// xlsx按OOXML最小结构手工生成：静态部件先写入zip，工作表随行写出，Close时补全
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedExportFormat 不支持的导出格式
+var ErrUnsupportedExportFormat = errors.New("不支持的导出格式")
+
+// ExportFormat 导出文件格式
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportXLSX ExportFormat = "xlsx"
+)
+
+// xlsxMaxDataRows Excel单个工作表最多1048576行，除去表头行
+const xlsxMaxDataRows = 1048575
+
+// ParseExportFormat 解析导出格式，为空时使用CSV
+func ParseExportFormat(value string) (ExportFormat, error) {
+	switch format := ExportFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		return ExportCSV, nil
+	case ExportCSV, ExportXLSX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedExportFormat, value)
+	}
+}
+
+// ContentType 返回导出文件的MIME类型
+func (f ExportFormat) ContentType() string {
+	if f == ExportXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// MaxRows 返回该格式可容纳的最大数据行数，0表示不限制
+func (f ExportFormat) MaxRows() int64 {
+	if f == ExportXLSX {
+		return xlsxMaxDataRows
+	}
+	return 0
+}
+
+// ExportWriter 逐行写出导出文件
+// 先调用一次WriteHeader，再逐行WriteRow，最后必须Close以写完文件尾
+type ExportWriter interface {
+	WriteHeader(headers []string) error
+	WriteRow(values []any) error
+	// Flush 将已写出的行推送到底层Writer，便于边查询边下载
+	Flush() error
+	Close() error
+}
+
+// NewExportWriter 按格式创建导出写入器
+func NewExportWriter(format ExportFormat, w io.Writer) ExportWriter {
+	if format == ExportXLSX {
+		return newXLSXExportWriter(w)
+	}
+	return newCSVExportWriter(w)
+}
+
+// csvExportWriter CSV导出，带UTF-8 BOM以便Excel正确识别中文
+type csvExportWriter struct {
+	w      io.Writer
+	csv    *csv.Writer
+	record []string
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	return &csvExportWriter{w: w, csv: csv.NewWriter(w)}
+}
+
+func (e *csvExportWriter) WriteHeader(headers []string) error {
+	if _, err := io.WriteString(e.w, "\ufeff"); err != nil {
+		return err
+	}
+	return e.csv.Write(headers)
+}
+
+func (e *csvExportWriter) WriteRow(values []any) error {
+	e.record = e.record[:0]
+	for _, value := range values {
+		// 只转义文本列，驱动数值类型转换出的字符串（如负数）保持原样
+		if text, isString := value.(string); isString {
+			e.record = append(e.record, escapeCSVFormula(text))
+			continue
+		}
+		e.record = append(e.record, exportCellText(resolveValuer(value)))
+	}
+	return e.csv.Write(e.record)
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	return e.Flush()
+}
+
+// escapeCSVFormula 在以公式字符开头的文本前加单引号，防止在电子表格中打开时被当作公式执行
+func escapeCSVFormula(text string) string {
+	if text == "" {
+		return text
+	}
+	switch text[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + text
+	}
+	return text
+}
+
+// xlsxExportWriter 流式生成只有一个工作表的xlsx文件
+type xlsxExportWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	row   int
+	err   error
+}
+
+func newXLSXExportWriter(w io.Writer) *xlsxExportWriter {
+	return &xlsxExportWriter{zip: zip.NewWriter(w)}
+}
+
+// xlsxStaticParts 工作表之外的固定部件，按写入顺序排列
+var xlsxStaticParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Result" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func (e *xlsxExportWriter) WriteHeader(headers []string) error {
+	for _, part := range xlsxStaticParts {
+		w, err := e.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+
+	w, err := e.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = bufio.NewWriter(w)
+	e.writeString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	values := make([]any, len(headers))
+	for i, header := range headers {
+		values[i] = header
+	}
+	return e.WriteRow(values)
+}
+
+func (e *xlsxExportWriter) WriteRow(values []any) error {
+	if e.sheet == nil {
+		return errors.New("xlsx导出未写入表头")
+	}
+	e.row++
+	if e.row > xlsxMaxDataRows+1 {
+		return fmt.Errorf("xlsx工作表最多%d行", xlsxMaxDataRows+1)
+	}
+
+	e.writeString(`<row r="` + strconv.Itoa(e.row) + `">`)
+	for i, value := range values {
+		value = resolveValuer(value)
+		if value == nil {
+			continue
+		}
+		ref := xlsxColumnName(i) + strconv.Itoa(e.row)
+		if number, ok := xlsxNumber(value); ok {
+			e.writeString(`<c r="` + ref + `"><v>` + number + `</v></c>`)
+			continue
+		}
+		if b, ok := value.(bool); ok {
+			v := "0"
+			if b {
+				v = "1"
+			}
+			e.writeString(`<c r="` + ref + `" t="b"><v>` + v + `</v></c>`)
+			continue
+		}
+		e.writeString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+		if e.err == nil {
+			e.err = xml.EscapeText(e.sheet, []byte(exportCellText(value)))
+		}
+		e.writeString(`</t></is></c>`)
+	}
+	e.writeString(`</row>`)
+	return e.err
+}
+
+func (e *xlsxExportWriter) Flush() error {
+	if e.sheet != nil && e.err == nil {
+		e.err = e.sheet.Flush()
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return e.zip.Flush()
+}
+
+func (e *xlsxExportWriter) Close() error {
+	if e.sheet == nil {
+		if err := e.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	e.writeString(`</sheetData></worksheet>`)
+	if err := e.Flush(); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// writeString 写入工作表，出错后不再写入，错误由WriteRow或Flush返回
+func (e *xlsxExportWriter) writeString(s string) {
+	if e.err == nil {
+		_, e.err = e.sheet.WriteString(s)
+	}
+}
+
+// xlsxColumnName 将从0开始的列序号转换为A、B、…、Z、AA形式的列名
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxNumber 数值类型按数字单元格写出；NaN与无穷大按文本写出
+func xlsxNumber(value any) (string, bool) {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v), true
+	case int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), true
+	case float32:
+		return xlsxNumber(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	}
+	return "", false
+}
+
+// exportCellText 将结果值转换为单元格文本，NULL为空字符串
+func exportCellText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case fmt.Stringer:
+		return v.String()
+	case map[string]any, []any:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// resolveValuer 将实现driver.Valuer的驱动类型（如pgx的Numeric）转换为基础值
+func resolveValuer(value any) any {
+	valuer, ok := value.(driver.Valuer)
+	if !ok {
+		return value
+	}
+	converted, err := valuer.Value()
+	if err != nil {
+		return nil
+	}
+	return converted
+}
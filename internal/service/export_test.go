@@ -0,0 +1,132 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+// exportRowWriter 将流式结果按列顺序交给ExportWriter
+type exportRowWriter struct {
+	export  ExportWriter
+	columns []string
+}
+
+func (w *exportRowWriter) WriteHeader(columns []string) error {
+	w.columns = columns
+	return w.export.WriteHeader(columns)
+}
+
+func (w *exportRowWriter) WriteRow(row map[string]any) error {
+	values := make([]any, len(w.columns))
+	for i, column := range w.columns {
+		values[i] = row[column]
+	}
+	return w.export.WriteRow(values)
+}
+
+func TestParseExportFormat(t *testing.T) {
+	for value, expected := range map[string]ExportFormat{"": ExportCSV, "csv": ExportCSV, " XLSX ": ExportXLSX} {
+		format, err := ParseExportFormat(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, format)
+	}
+	_, err := ParseExportFormat("pdf")
+	assert.ErrorIs(t, err, ErrUnsupportedExportFormat)
+
+	assert.Equal(t, int64(xlsxMaxDataRows), ExportXLSX.MaxRows())
+	assert.Zero(t, ExportCSV.MaxRows())
+}
+
+func TestCSVExportWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewExportWriter(ExportCSV, &buf)
+	require.NoError(t, w.WriteHeader([]string{"名称", "金额", "备注"}))
+	require.NoError(t, w.WriteRow([]any{"Alice", 19.5, nil}))
+	require.NoError(t, w.WriteRow([]any{"=HYPERLINK(\"x\")", int64(-5), "a,b"}))
+	require.NoError(t, w.Close())
+
+	require.True(t, strings.HasPrefix(buf.String(), "\ufeff"), "带BOM")
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\ufeff"))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"名称", "金额", "备注"},
+		{"Alice", "19.5", ""},
+		{"'=HYPERLINK(\"x\")", "-5", "a,b"},
+	}, records)
+}
+
+func TestXLSXExportWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewExportWriter(ExportXLSX, &buf)
+	require.NoError(t, w.WriteHeader([]string{"name", "total"}))
+	require.NoError(t, w.WriteRow([]any{"<Alice & Bob>", 24.5}))
+	require.NoError(t, w.Flush())
+	require.NoError(t, w.WriteRow([]any{nil, true}))
+	require.NoError(t, w.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		parts[file.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		assert.Contains(t, parts, name)
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>`)
+	assert.Contains(t, sheet, `<t xml:space="preserve">&lt;Alice &amp; Bob&gt;</t>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>24.5</v></c>`)
+	assert.Contains(t, sheet, `<row r="3"><c r="B3" t="b"><v>1</v></c></row>`, "NULL不写单元格")
+	assert.True(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+
+	assert.Equal(t, "A", xlsxColumnName(0))
+	assert.Equal(t, "Z", xlsxColumnName(25))
+	assert.Equal(t, "AA", xlsxColumnName(26))
+	assert.Equal(t, "BA", xlsxColumnName(52))
+}
+
+func TestSQLiteStreamQuery(t *testing.T) {
+	cm, _ := newSQLiteFixture(t)
+	ctx := context.Background()
+
+	db, err := cm.openSQLiteDB(ctx, &repository.DatabaseConnection{DBType: "sqlite", DatabaseName: "shop.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	executor := NewSQLExecutor(nil, cm, zaptest.NewLogger(t))
+	var buf bytes.Buffer
+	export := NewExportWriter(ExportCSV, &buf)
+	written, truncated, err := executor.streamQueryOnDB(ctx, "SELECT id, amount FROM orders ORDER BY id", db, repository.DBTypeSQLite, 2, &exportRowWriter{export: export})
+	require.NoError(t, err)
+	require.NoError(t, export.Close())
+	assert.Equal(t, int64(2), written)
+	assert.True(t, truncated, "第3行超出上限")
+	assert.Equal(t, "\ufeffid,amount\n1,19.5\n2,5\n", buf.String())
+
+	buf.Reset()
+	export = NewExportWriter(ExportCSV, &buf)
+	written, truncated, err = executor.streamQueryOnDB(ctx, "SELECT id FROM orders", db, repository.DBTypeSQLite, 0, &exportRowWriter{export: export})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), written)
+	assert.False(t, truncated)
+
+	_, _, err = executor.streamQueryOnDB(ctx, "DELETE FROM orders", db, repository.DBTypeSQLite, 0, &exportRowWriter{export: NewExportWriter(ExportCSV, io.Discard)})
+	assert.Error(t, err, "只读连接拒绝写操作")
+}
@@ -0,0 +1,239 @@
+// 查询反馈
+// 生成SQL时按查询ID保留问题与SQL等上下文，用户提交反馈时写入准确率统计并交给学习引擎，
+// 使用户在界面上的评价真正进入学习闭环
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/routing"
+)
+
+// ErrFeedbackQueryNotFound 反馈的查询不存在、不属于当前用户，或上下文与反馈记录均已过期
+var ErrFeedbackQueryNotFound = errors.New("反馈的查询不存在或已过期")
+
+// FeedbackSubmission 用户对一次SQL生成的反馈
+type FeedbackSubmission struct {
+	QueryID      string
+	IsCorrect    bool
+	Rating       int    // 1-5评分
+	Comment      string // 文本反馈
+	CorrectedSQL string // 用户给出的正确SQL
+}
+
+// generationContext 等待反馈的SQL生成上下文
+type generationContext struct {
+	at             time.Time
+	userID         int64
+	question       string
+	sql            string
+	model          string
+	processingTime time.Duration
+	submitted      bool // 已记录首次反馈，之后的反馈按修正处理
+}
+
+// FeedbackService 将用户反馈分发到准确率统计与学习引擎
+// 首次反馈写入AccuracyMonitor并由LearningEngine学习；同一查询的再次反馈、
+// 以及上下文过期后的反馈按事后修正交给AccuracyMonitor，对账后再重新训练学习引擎
+type FeedbackService struct {
+	accuracy *ai.AccuracyMonitor
+	learning *routing.LearningEngine
+	config   *config.FeedbackConfig
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu       sync.Mutex
+	contexts map[string]*generationContext // 查询ID -> 生成上下文
+	order    []string                      // 按记录顺序的查询ID，用于淘汰过期与超量的上下文
+}
+
+// NewFeedbackService 创建查询反馈服务，配置为nil时使用默认配置
+func NewFeedbackService(accuracy *ai.AccuracyMonitor, learning *routing.LearningEngine, feedbackConfig *config.FeedbackConfig, logger *zap.Logger) *FeedbackService {
+	if feedbackConfig == nil {
+		feedbackConfig = config.DefaultFeedbackConfig()
+	}
+	return &FeedbackService{
+		accuracy: accuracy,
+		learning: learning,
+		config:   feedbackConfig,
+		logger:   logger,
+		now:      time.Now,
+		contexts: make(map[string]*generationContext),
+	}
+}
+
+// RememberGeneration 记录一次SQL生成的上下文，等待该查询的用户反馈
+func (s *FeedbackService) RememberGeneration(queryID string, userID int64, question string, resp *SQLGenerationResponse) {
+	generation := &generationContext{
+		at:             s.now(),
+		userID:         userID,
+		question:       question,
+		sql:            resp.SQL,
+		processingTime: resp.ProcessingTime,
+	}
+	switch {
+	case resp.Template != "":
+		generation.model = "template:" + resp.Template
+	case resp.Generation != nil:
+		generation.model = resp.Generation.Model
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.contexts[queryID]; !exists {
+		s.order = append(s.order, queryID)
+	}
+	s.contexts[queryID] = generation
+	s.prune()
+}
+
+// Submit 提交用户反馈
+// 查询不存在或不属于该用户时返回ErrFeedbackQueryNotFound；学习引擎处理失败只记录日志
+func (s *FeedbackService) Submit(ctx context.Context, userID int64, submission FeedbackSubmission) error {
+	s.mu.Lock()
+	s.prune()
+	generation, ok := s.contexts[submission.QueryID]
+	if ok && generation.userID != userID {
+		ok = false
+	}
+	first := ok && !generation.submitted
+	var snapshot generationContext
+	if first {
+		generation.submitted = true
+		snapshot = *generation
+	}
+	s.mu.Unlock()
+
+	if !first {
+		return s.correct(ctx, userID, submission)
+	}
+
+	feedback := ai.QueryFeedback{
+		QueryID:        submission.QueryID,
+		UserID:         userID,
+		UserQuery:      snapshot.question,
+		GeneratedSQL:   snapshot.sql,
+		ExpectedSQL:    submission.CorrectedSQL,
+		IsCorrect:      submission.IsCorrect,
+		UserRating:     submission.Rating,
+		Feedback:       submission.Comment,
+		ProcessingTime: snapshot.processingTime,
+		ModelUsed:      snapshot.model,
+	}
+	if !submission.IsCorrect {
+		feedback.ErrorType, feedback.ErrorDetails = inferFeedbackError(submission.Comment, snapshot.question)
+	}
+	if err := s.accuracy.RecordFeedbackContext(ctx, feedback); err != nil {
+		s.mu.Lock()
+		if generation, ok := s.contexts[submission.QueryID]; ok {
+			generation.submitted = false
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("记录反馈失败: %w", err)
+	}
+
+	isCorrect := submission.IsCorrect
+	err := s.learning.LearnFromFeedback(ctx, submission.QueryID, userID, snapshot.sql, &routing.UserFeedback{
+		Rating:    submission.Rating,
+		IsCorrect: &isCorrect,
+		Comments:  submission.Comment,
+	})
+	if err != nil {
+		s.logger.Warn("学习引擎处理反馈失败",
+			zap.String("query_id", submission.QueryID),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// correct 将反馈作为事后修正交给准确率统计，学习引擎在对账后重新训练
+func (s *FeedbackService) correct(ctx context.Context, userID int64, submission FeedbackSubmission) error {
+	correction := ai.FeedbackCorrection{
+		QueryID:     submission.QueryID,
+		UserID:      userID,
+		IsCorrect:   submission.IsCorrect,
+		ExpectedSQL: submission.CorrectedSQL,
+		UserRating:  submission.Rating,
+		Feedback:    submission.Comment,
+	}
+	if !submission.IsCorrect {
+		correction.ErrorType, correction.ErrorDetails = inferFeedbackError(submission.Comment, "")
+	}
+
+	err := s.accuracy.CorrectFeedback(ctx, correction)
+	if errors.Is(err, ai.ErrFeedbackNotFound) {
+		return ErrFeedbackQueryNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("记录反馈修正失败: %w", err)
+	}
+	return nil
+}
+
+// ForgetUser 删除用户等待反馈的生成上下文，实现ConversationMemory，返回删除的上下文数
+func (s *FeedbackService) ForgetUser(userID int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forgotten := 0
+	for queryID, generation := range s.contexts {
+		if generation.userID == userID {
+			delete(s.contexts, queryID)
+			forgotten++
+		}
+	}
+	return forgotten
+}
+
+// prune 淘汰超过ContextTTL或超出数量上限的最早上下文，调用方需持有锁
+func (s *FeedbackService) prune() {
+	cutoff := s.now().Add(-s.config.ContextTTL)
+	drop := 0
+	for _, queryID := range s.order {
+		generation, ok := s.contexts[queryID]
+		switch {
+		case !ok:
+			// 已被删除
+		case generation.at.Before(cutoff) || len(s.contexts) > s.config.MaxContexts:
+			delete(s.contexts, queryID)
+		default:
+			s.order = s.order[drop:]
+			return
+		}
+		drop++
+	}
+	s.order = s.order[:0]
+}
+
+// inferFeedbackError 按用户的文本反馈（其次是问题）推断错误类型
+func inferFeedbackError(comment, question string) (errorType, details string) {
+	details = comment
+	if details == "" {
+		details = "用户标记为不正确"
+	}
+
+	text := strings.ToLower(comment + " " + question)
+	switch {
+	case strings.Contains(text, "表") && strings.Contains(text, "不存在"):
+		return "表不存在", details
+	case strings.Contains(text, "字段") && strings.Contains(text, "错误"):
+		return "字段不存在", details
+	case strings.Contains(text, "语法"):
+		return "语法错误", details
+	case strings.Contains(text, "join"):
+		return "JOIN错误", details
+	case strings.Contains(text, "group") || strings.Contains(text, "聚合"):
+		return "聚合函数错误", details
+	}
+	return "未知错误", details
+}
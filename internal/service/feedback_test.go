@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/routing"
+)
+
+func newFeedbackTestService(t *testing.T) (*FeedbackService, *ai.AccuracyMonitor, *routing.LearningEngine) {
+	learningConfig := routing.DefaultLearningConfig()
+	learningConfig.EnableAsyncLearning = false
+	learning := routing.NewLearningEngine(context.Background(), learningConfig)
+	t.Cleanup(func() { learning.Close() })
+
+	accuracy := ai.NewAccuracyMonitor(ai.DefaultAccuracyConfig(), zap.NewNop())
+	return NewFeedbackService(accuracy, learning, nil, zap.NewNop()), accuracy, learning
+}
+
+func TestFeedbackService_Submit(t *testing.T) {
+	svc, accuracy, learning := newFeedbackTestService(t)
+	ctx := context.Background()
+
+	svc.RememberGeneration("q1", 7, "上个月的订单总数", &SQLGenerationResponse{
+		SQL:            "SELECT COUNT(*) FROM orders WHERE created_at >= '2026-09-01'",
+		ProcessingTime: 800 * time.Millisecond,
+		Generation:     &GenerationParameters{Model: "gpt-4o-mini"},
+	})
+
+	assert.ErrorIs(t, svc.Submit(ctx, 8, FeedbackSubmission{QueryID: "q1", IsCorrect: true, Rating: 5}), ErrFeedbackQueryNotFound, "不能反馈其他用户的查询")
+	assert.ErrorIs(t, svc.Submit(ctx, 7, FeedbackSubmission{QueryID: "unknown", IsCorrect: true, Rating: 5}), ErrFeedbackQueryNotFound)
+
+	require.NoError(t, svc.Submit(ctx, 7, FeedbackSubmission{
+		QueryID: "q1", IsCorrect: false, Rating: 2, Comment: "应该按支付时间统计",
+		CorrectedSQL: "SELECT COUNT(*) FROM orders WHERE paid_at >= '2026-09-01'",
+	}))
+
+	report, err := accuracy.GetAccuracyReport(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.OverallStats.TotalQueries)
+	assert.Zero(t, report.OverallStats.CorrectQueries)
+	assert.Contains(t, report.ModelStats, "gpt-4o-mini")
+
+	stats := learning.GetLearningStats()
+	assert.Equal(t, int64(1), stats.TotalQueries)
+
+	// 再次反馈按事后修正处理，等待对账
+	require.NoError(t, svc.Submit(ctx, 7, FeedbackSubmission{QueryID: "q1", IsCorrect: true, Rating: 4}))
+	reconciled, err := accuracy.ReconcileCorrections(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reconciled)
+	assert.Equal(t, int64(1), learning.GetLearningStats().TotalQueries, "修正不追加学习记录")
+
+	// 上下文被清除后仍可修正自己已有的反馈
+	assert.Equal(t, 1, svc.ForgetUser(7))
+	require.NoError(t, svc.Submit(ctx, 7, FeedbackSubmission{QueryID: "q1", IsCorrect: false, Rating: 1}))
+	assert.ErrorIs(t, svc.Submit(ctx, 8, FeedbackSubmission{QueryID: "q1", IsCorrect: true, Rating: 5}), ErrFeedbackQueryNotFound)
+}
+
+func TestFeedbackService_Prune(t *testing.T) {
+	svc, _, _ := newFeedbackTestService(t)
+	cfg := config.DefaultFeedbackConfig()
+	cfg.MaxContexts = 2
+	cfg.ContextTTL = time.Hour
+	svc.config = cfg
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		svc.RememberGeneration(fmt.Sprintf("q%d", i), 7, "订单总数", &SQLGenerationResponse{SQL: "SELECT COUNT(*) FROM orders", Template: "count"})
+	}
+	assert.ErrorIs(t, svc.Submit(ctx, 7, FeedbackSubmission{QueryID: "q0", IsCorrect: true, Rating: 5}), ErrFeedbackQueryNotFound, "超出上限时淘汰最早的上下文")
+	require.NoError(t, svc.Submit(ctx, 7, FeedbackSubmission{QueryID: "q1", IsCorrect: true, Rating: 5}))
+
+	now = now.Add(2 * time.Hour)
+	assert.ErrorIs(t, svc.Submit(ctx, 7, FeedbackSubmission{QueryID: "q2", IsCorrect: true, Rating: 5}), ErrFeedbackQueryNotFound, "超过ContextTTL")
+}
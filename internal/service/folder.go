@@ -0,0 +1,505 @@
+// 保存查询文件夹
+// 工作空间内的保存查询与报表按多级文件夹组织。文件夹权限按用户授予并向下继承：
+// 离目标最近、设置过授权的文件夹决定访问级别，整条路径都未设置授权时工作空间成员均可编辑；
+// 管理员、文件夹创建者与被授予manage的用户对该文件夹及其子树始终拥有管理权限
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// Breadcrumb 面包屑中的一级文件夹
+type Breadcrumb struct {
+	ID   int64  `json:"id" example:"5"`
+	Name string `json:"name" example:"销售报表"`
+}
+
+// FolderContents 文件夹内容，Folder为空表示根目录
+type FolderContents struct {
+	Folder      *repository.QueryFolder
+	Breadcrumbs []Breadcrumb
+	Permission  repository.FolderPermissionLevel
+	Folders     []*repository.QueryFolder
+	Queries     []*repository.SavedQuery
+}
+
+// folderAccess 用户在某个位置上的访问信息
+type folderAccess struct {
+	workspaceID int64
+	folder      *repository.QueryFolder   // 为空表示根目录
+	ancestors   []*repository.QueryFolder // 从根到自身
+	level       repository.FolderPermissionLevel
+}
+
+// breadcrumbs 从根到当前文件夹的面包屑
+func (a *folderAccess) breadcrumbs() []Breadcrumb {
+	crumbs := make([]Breadcrumb, 0, len(a.ancestors))
+	for _, f := range a.ancestors {
+		crumbs = append(crumbs, Breadcrumb{ID: f.ID, Name: f.Name})
+	}
+	return crumbs
+}
+
+// FolderService 保存查询文件夹服务
+type FolderService struct {
+	folders    repository.FolderRepository
+	queries    repository.SavedQueryRepository
+	workspaces repository.WorkspaceRepository
+	logger     *zap.Logger
+}
+
+// NewFolderService 创建保存查询文件夹服务
+func NewFolderService(folders repository.FolderRepository, queries repository.SavedQueryRepository, workspaces repository.WorkspaceRepository, logger *zap.Logger) *FolderService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &FolderService{
+		folders:    folders,
+		queries:    queries,
+		workspaces: workspaces,
+		logger:     logger,
+	}
+}
+
+// ListContents 列出文件夹中的子文件夹与保存查询，folderID为空表示根目录
+// 子文件夹只返回当前用户可查看的；保存查询按limit与offset分页
+func (s *FolderService) ListContents(ctx context.Context, userID int64, role string, folderID *int64, limit, offset int) (*FolderContents, error) {
+	access, err := s.require(ctx, userID, role, folderID, repository.FolderView)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := s.folders.ListChildren(ctx, access.workspaceID, folderID)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.visibleChildren(ctx, userID, role, access, children)
+	if err != nil {
+		return nil, err
+	}
+
+	queries, err := s.queries.ListByFolder(ctx, access.workspaceID, folderID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FolderContents{
+		Folder:      access.folder,
+		Breadcrumbs: access.breadcrumbs(),
+		Permission:  access.level,
+		Folders:     visible,
+		Queries:     queries,
+	}, nil
+}
+
+// CreateFolder 在父文件夹下创建文件夹，需要父文件夹的编辑权限
+func (s *FolderService) CreateFolder(ctx context.Context, userID int64, role string, parentID *int64, name string) (*repository.QueryFolder, error) {
+	name, err := normalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.require(ctx, userID, role, parentID, repository.FolderEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := &repository.QueryFolder{
+		BaseModel:   repository.BaseModel{CreateBy: &userID},
+		WorkspaceID: access.workspaceID,
+		Name:        name,
+	}
+	if err := s.folders.Create(ctx, folder, access.folder); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("文件夹已创建",
+		zap.Int64("folder_id", folder.ID),
+		zap.Int64("workspace_id", folder.WorkspaceID),
+		zap.Int64("user_id", userID))
+	return folder, nil
+}
+
+// RenameFolder 重命名文件夹，需要管理权限
+func (s *FolderService) RenameFolder(ctx context.Context, userID int64, role string, id int64, name string) (*repository.QueryFolder, error) {
+	name, err := normalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.require(ctx, userID, role, &id, repository.FolderManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.folders.Rename(ctx, id, name, userID); err != nil {
+		return nil, err
+	}
+	access.folder.Name = name
+	access.folder.UpdateBy = &userID
+	return access.folder, nil
+}
+
+// MoveFolder 将文件夹及其子树移动到新的父文件夹下，newParentID为空表示移动到根目录
+// 需要文件夹的管理权限与目标文件夹的编辑权限，不能移动到自身的子树中
+func (s *FolderService) MoveFolder(ctx context.Context, userID int64, role string, id int64, newParentID *int64) (*repository.QueryFolder, error) {
+	access, err := s.require(ctx, userID, role, &id, repository.FolderManage)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.require(ctx, userID, role, newParentID, repository.FolderEdit)
+	if err != nil {
+		return nil, err
+	}
+	if target.folder != nil && strings.HasPrefix(target.folder.Path, access.folder.Path) {
+		return nil, fmt.Errorf("%w: 不能把文件夹移动到自身或其子文件夹中", repository.ErrInvalidInput)
+	}
+
+	if err := s.folders.Move(ctx, access.folder, target.folder, userID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("文件夹已移动",
+		zap.Int64("folder_id", id),
+		zap.Any("parent_id", newParentID),
+		zap.Int64("user_id", userID))
+	return access.folder, nil
+}
+
+// DeleteFolder 删除空文件夹，需要管理权限
+func (s *FolderService) DeleteFolder(ctx context.Context, userID int64, role string, id int64) error {
+	if _, err := s.require(ctx, userID, role, &id, repository.FolderManage); err != nil {
+		return err
+	}
+	return s.folders.Delete(ctx, id, userID)
+}
+
+// ListPermissions 列出文件夹上直接设置的授权，需要管理权限
+func (s *FolderService) ListPermissions(ctx context.Context, userID int64, role string, id int64) ([]*repository.FolderPermission, error) {
+	if _, err := s.require(ctx, userID, role, &id, repository.FolderManage); err != nil {
+		return nil, err
+	}
+	return s.folders.ListPermissions(ctx, []int64{id})
+}
+
+// SetPermission 授予用户文件夹权限，需要管理权限
+// 文件夹设置授权后，未被授权的成员将无法访问该文件夹及其子树（子树中另有授权的除外）
+func (s *FolderService) SetPermission(ctx context.Context, userID int64, role string, id, targetUserID int64, level repository.FolderPermissionLevel) (*repository.FolderPermission, error) {
+	if !level.IsValid() {
+		return nil, fmt.Errorf("%w: 未知的权限级别%s", repository.ErrInvalidInput, level)
+	}
+	if _, err := s.require(ctx, userID, role, &id, repository.FolderManage); err != nil {
+		return nil, err
+	}
+
+	permission := &repository.FolderPermission{
+		FolderID: id,
+		UserID:   targetUserID,
+		Level:    string(level),
+		CreateBy: userID,
+	}
+	if err := s.folders.SetPermission(ctx, permission); err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+// RemovePermission 撤销用户的文件夹权限，需要管理权限
+func (s *FolderService) RemovePermission(ctx context.Context, userID int64, role string, id, targetUserID int64) error {
+	if _, err := s.require(ctx, userID, role, &id, repository.FolderManage); err != nil {
+		return err
+	}
+	return s.folders.RemovePermission(ctx, id, targetUserID)
+}
+
+// CreateSavedQuery 在文件夹中创建保存查询，需要文件夹的编辑权限
+func (s *FolderService) CreateSavedQuery(ctx context.Context, userID int64, role string, query *repository.SavedQuery) error {
+	if err := validateSavedQuery(query); err != nil {
+		return err
+	}
+	access, err := s.require(ctx, userID, role, query.FolderID, repository.FolderEdit)
+	if err != nil {
+		return err
+	}
+
+	query.WorkspaceID = access.workspaceID
+	query.OwnerID = userID
+	return s.queries.Create(ctx, query)
+}
+
+// GetSavedQuery 获取保存查询及其所在位置的面包屑，需要文件夹的查看权限
+func (s *FolderService) GetSavedQuery(ctx context.Context, userID int64, role string, id int64) (*repository.SavedQuery, []Breadcrumb, error) {
+	query, access, err := s.savedQueryAccess(ctx, userID, role, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !access.level.Allows(repository.FolderView) {
+		return nil, nil, fmt.Errorf("无权查看该保存查询: %w", repository.ErrPermissionDenied)
+	}
+	return query, access.breadcrumbs(), nil
+}
+
+// UpdateSavedQuery 修改保存查询，所有者或拥有文件夹编辑权限的用户可以修改
+func (s *FolderService) UpdateSavedQuery(ctx context.Context, userID int64, role string, update *repository.SavedQuery) (*repository.SavedQuery, error) {
+	if err := validateSavedQuery(update); err != nil {
+		return nil, err
+	}
+	query, err := s.EditableSavedQuery(ctx, userID, role, update.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Name = update.Name
+	query.Description = update.Description
+	query.NaturalQuery = update.NaturalQuery
+	query.SQL = update.SQL
+	query.ConnectionID = update.ConnectionID
+	query.DashboardBacked = update.DashboardBacked
+	query.CacheTTLSeconds = update.CacheTTLSeconds
+	query.UpdateBy = &userID
+	if err := s.queries.Update(ctx, query); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// DeleteSavedQuery 删除保存查询，所有者或拥有文件夹编辑权限的用户可以删除
+func (s *FolderService) DeleteSavedQuery(ctx context.Context, userID int64, role string, id int64) error {
+	if _, err := s.EditableSavedQuery(ctx, userID, role, id); err != nil {
+		return err
+	}
+	return s.queries.Delete(ctx, id, userID)
+}
+
+// MoveSavedQuery 将保存查询移动到另一个文件夹，folderID为空表示根目录
+// 需要能修改该保存查询，并拥有目标文件夹的编辑权限
+func (s *FolderService) MoveSavedQuery(ctx context.Context, userID int64, role string, id int64, folderID *int64) (*repository.SavedQuery, error) {
+	query, err := s.EditableSavedQuery(ctx, userID, role, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.require(ctx, userID, role, folderID, repository.FolderEdit); err != nil {
+		return nil, err
+	}
+
+	if err := s.queries.Move(ctx, id, folderID, userID); err != nil {
+		return nil, err
+	}
+	query.FolderID = folderID
+	query.UpdateBy = &userID
+	return query, nil
+}
+
+// BatchMoveSavedQueries 批量移动保存查询，需要目标文件夹的编辑权限
+// 逐条检查能否修改，不存在或无权修改的条目记录在返回的failures中，其余条目照常移动
+func (s *FolderService) BatchMoveSavedQueries(ctx context.Context, userID int64, role string, ids []int64, folderID *int64) (map[int64]error, error) {
+	if _, err := s.require(ctx, userID, role, folderID, repository.FolderEdit); err != nil {
+		return nil, err
+	}
+
+	failures := make(map[int64]error)
+	for _, id := range ids {
+		if _, err := s.EditableSavedQuery(ctx, userID, role, id); err != nil {
+			if !isItemError(err) {
+				return nil, err
+			}
+			failures[id] = err
+			continue
+		}
+		if err := s.queries.Move(ctx, id, folderID, userID); err != nil {
+			if !isItemError(err) {
+				return nil, err
+			}
+			failures[id] = err
+		}
+	}
+	return failures, nil
+}
+
+// isItemError 批量操作中只影响单个条目的错误：条目不存在或无权操作
+func isItemError(err error) bool {
+	return errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrPermissionDenied)
+}
+
+// validateSavedQuery 仪表盘使用的查询需要指定连接才能预热结果缓存
+func validateSavedQuery(query *repository.SavedQuery) error {
+	if query.DashboardBacked && query.ConnectionID == nil {
+		return fmt.Errorf("仪表盘使用的保存查询需要指定连接: %w", repository.ErrInvalidInput)
+	}
+	if query.CacheTTLSeconds != nil && *query.CacheTTLSeconds <= 0 {
+		return fmt.Errorf("缓存时间必须大于0: %w", repository.ErrInvalidInput)
+	}
+	return nil
+}
+
+// savedQueryAccess 获取保存查询及用户在其所在文件夹上的访问信息，其他工作空间的保存查询视为不存在
+func (s *FolderService) savedQueryAccess(ctx context.Context, userID int64, role string, id int64) (*repository.SavedQuery, *folderAccess, error) {
+	query, err := s.queries.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	access, err := s.resolve(ctx, userID, role, query.FolderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if query.WorkspaceID != access.workspaceID {
+		return nil, nil, fmt.Errorf("保存查询不存在: %w", repository.ErrNotFound)
+	}
+	return query, access, nil
+}
+
+// EditableSavedQuery 获取当前用户可以修改的保存查询：所有者或拥有文件夹编辑权限的用户
+func (s *FolderService) EditableSavedQuery(ctx context.Context, userID int64, role string, id int64) (*repository.SavedQuery, error) {
+	query, access, err := s.savedQueryAccess(ctx, userID, role, id)
+	if err != nil {
+		return nil, err
+	}
+	if query.OwnerID != userID && !access.level.Allows(repository.FolderEdit) {
+		return nil, fmt.Errorf("无权修改该保存查询: %w", repository.ErrPermissionDenied)
+	}
+	return query, nil
+}
+
+// require 解析访问信息并检查权限级别
+func (s *FolderService) require(ctx context.Context, userID int64, role string, folderID *int64, required repository.FolderPermissionLevel) (*folderAccess, error) {
+	access, err := s.resolve(ctx, userID, role, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.level.Allows(required) {
+		return nil, fmt.Errorf("文件夹权限不足，需要%s: %w", required, repository.ErrPermissionDenied)
+	}
+	return access, nil
+}
+
+// resolve 计算用户在文件夹（为空表示根目录）上的访问级别，其他工作空间的文件夹视为不存在
+func (s *FolderService) resolve(ctx context.Context, userID int64, role string, folderID *int64) (*folderAccess, error) {
+	workspace, err := s.workspaces.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &folderAccess{workspaceID: workspace.ID, level: repository.FolderEdit}
+	if role == string(repository.RoleAdmin) {
+		access.level = repository.FolderManage
+	}
+	if folderID == nil {
+		return access, nil
+	}
+
+	folder, err := s.folders.GetByID(ctx, *folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folder.WorkspaceID != workspace.ID {
+		return nil, fmt.Errorf("文件夹不存在: %w", repository.ErrNotFound)
+	}
+
+	ancestors, err := s.ancestors(ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.folders.ListPermissions(ctx, folder.AncestorIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	access.folder = folder
+	access.ancestors = ancestors
+	access.level = effectiveFolderLevel(userID, role, ancestors, groupPermissions(permissions), repository.FolderEdit)
+	return access, nil
+}
+
+// ancestors 按从根到自身的顺序获取文件夹路径上的所有文件夹
+func (s *FolderService) ancestors(ctx context.Context, folder *repository.QueryFolder) ([]*repository.QueryFolder, error) {
+	ids := folder.AncestorIDs()
+	folders, err := s.folders.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*repository.QueryFolder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+	ordered := make([]*repository.QueryFolder, 0, len(ids))
+	for _, id := range ids {
+		if f, ok := byID[id]; ok {
+			ordered = append(ordered, f)
+		}
+	}
+	return ordered, nil
+}
+
+// visibleChildren 过滤出当前用户可以查看的子文件夹
+func (s *FolderService) visibleChildren(ctx context.Context, userID int64, role string, parent *folderAccess, children []*repository.QueryFolder) ([]*repository.QueryFolder, error) {
+	if len(children) == 0 {
+		return children, nil
+	}
+
+	ids := make([]int64, 0, len(children))
+	for _, child := range children {
+		ids = append(ids, child.ID)
+	}
+	permissions, err := s.folders.ListPermissions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	grants := groupPermissions(permissions)
+
+	visible := make([]*repository.QueryFolder, 0, len(children))
+	for _, child := range children {
+		level := effectiveFolderLevel(userID, role, []*repository.QueryFolder{child}, grants, parent.level)
+		if level.Allows(repository.FolderView) {
+			visible = append(visible, child)
+		}
+	}
+	return visible, nil
+}
+
+// groupPermissions 按文件夹分组授权
+func groupPermissions(permissions []*repository.FolderPermission) map[int64]map[int64]repository.FolderPermissionLevel {
+	grants := make(map[int64]map[int64]repository.FolderPermissionLevel)
+	for _, p := range permissions {
+		if grants[p.FolderID] == nil {
+			grants[p.FolderID] = make(map[int64]repository.FolderPermissionLevel)
+		}
+		grants[p.FolderID][p.UserID] = repository.FolderPermissionLevel(p.Level)
+	}
+	return grants
+}
+
+// effectiveFolderLevel 按继承规则自上而下计算权限级别
+// path为从上到下的文件夹，inherited为path之上继承下来的级别。设置过授权的文件夹以授权为准，
+// 否则沿用上级的级别；一旦获得管理权限（管理员、创建者或manage授权）即覆盖整个子树
+func effectiveFolderLevel(userID int64, role string, path []*repository.QueryFolder, grants map[int64]map[int64]repository.FolderPermissionLevel, inherited repository.FolderPermissionLevel) repository.FolderPermissionLevel {
+	if role == string(repository.RoleAdmin) {
+		return repository.FolderManage
+	}
+
+	level := inherited
+	for _, f := range path {
+		if level == repository.FolderManage || (f.CreateBy != nil && *f.CreateBy == userID) {
+			return repository.FolderManage
+		}
+		if folderGrants, ok := grants[f.ID]; ok {
+			level = folderGrants[userID]
+		}
+	}
+	return level
+}
+
+// normalizeFolderName 检查并规范化文件夹名称
+func normalizeFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return "", fmt.Errorf("%w: 文件夹名称不能为空且不超过100个字符", repository.ErrInvalidInput)
+	}
+	return name, nil
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+// memFolderRepository 内存文件夹Repository，按Path维护层级
+type memFolderRepository struct {
+	folders     map[int64]*repository.QueryFolder
+	permissions map[int64]map[int64]*repository.FolderPermission
+	nextID      int64
+}
+
+func newMemFolderRepository() *memFolderRepository {
+	return &memFolderRepository{
+		folders:     make(map[int64]*repository.QueryFolder),
+		permissions: make(map[int64]map[int64]*repository.FolderPermission),
+	}
+}
+
+func (m *memFolderRepository) Create(ctx context.Context, folder *repository.QueryFolder, parent *repository.QueryFolder) error {
+	m.nextID++
+	folder.ID = m.nextID
+	folder.Path = "/"
+	folder.ParentID = nil
+	if parent != nil {
+		folder.Path = parent.Path
+		folder.ParentID = &parent.ID
+	}
+	folder.Path += strconv.FormatInt(folder.ID, 10) + "/"
+	m.folders[folder.ID] = folder
+	return nil
+}
+
+func (m *memFolderRepository) GetByID(ctx context.Context, id int64) (*repository.QueryFolder, error) {
+	if f, ok := m.folders[id]; ok {
+		return f, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memFolderRepository) GetByIDs(ctx context.Context, ids []int64) ([]*repository.QueryFolder, error) {
+	var folders []*repository.QueryFolder
+	for _, id := range ids {
+		if f, ok := m.folders[id]; ok {
+			folders = append(folders, f)
+		}
+	}
+	return folders, nil
+}
+
+func (m *memFolderRepository) ListChildren(ctx context.Context, workspaceID int64, parentID *int64) ([]*repository.QueryFolder, error) {
+	var children []*repository.QueryFolder
+	for _, f := range m.folders {
+		if f.WorkspaceID == workspaceID && ((parentID == nil && f.ParentID == nil) ||
+			(parentID != nil && f.ParentID != nil && *parentID == *f.ParentID)) {
+			children = append(children, f)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	return children, nil
+}
+
+func (m *memFolderRepository) Rename(ctx context.Context, id int64, name string, updateBy int64) error {
+	m.folders[id].Name = name
+	return nil
+}
+
+func (m *memFolderRepository) Move(ctx context.Context, folder *repository.QueryFolder, newParent *repository.QueryFolder, updateBy int64) error {
+	oldPath := folder.Path
+	newPath := "/"
+	folder.ParentID = nil
+	if newParent != nil {
+		newPath = newParent.Path
+		folder.ParentID = &newParent.ID
+	}
+	newPath += strconv.FormatInt(folder.ID, 10) + "/"
+	for _, f := range m.folders {
+		if strings.HasPrefix(f.Path, oldPath) {
+			f.Path = newPath + strings.TrimPrefix(f.Path, oldPath)
+		}
+	}
+	folder.Path = newPath
+	return nil
+}
+
+func (m *memFolderRepository) Delete(ctx context.Context, id int64, deleteBy int64) error {
+	delete(m.folders, id)
+	return nil
+}
+
+func (m *memFolderRepository) SetPermission(ctx context.Context, permission *repository.FolderPermission) error {
+	if m.permissions[permission.FolderID] == nil {
+		m.permissions[permission.FolderID] = make(map[int64]*repository.FolderPermission)
+	}
+	m.permissions[permission.FolderID][permission.UserID] = permission
+	return nil
+}
+
+func (m *memFolderRepository) RemovePermission(ctx context.Context, folderID, userID int64) error {
+	delete(m.permissions[folderID], userID)
+	return nil
+}
+
+func (m *memFolderRepository) ListPermissions(ctx context.Context, folderIDs []int64) ([]*repository.FolderPermission, error) {
+	var permissions []*repository.FolderPermission
+	for _, id := range folderIDs {
+		for _, p := range m.permissions[id] {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions, nil
+}
+
+// memSavedQueryRepository 内存保存查询Repository
+type memSavedQueryRepository struct {
+	queries map[int64]*repository.SavedQuery
+	nextID  int64
+}
+
+func (m *memSavedQueryRepository) Create(ctx context.Context, query *repository.SavedQuery) error {
+	m.nextID++
+	query.ID = m.nextID
+	m.queries[query.ID] = query
+	return nil
+}
+
+func (m *memSavedQueryRepository) GetByID(ctx context.Context, id int64) (*repository.SavedQuery, error) {
+	if q, ok := m.queries[id]; ok {
+		return q, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memSavedQueryRepository) Update(ctx context.Context, query *repository.SavedQuery) error {
+	m.queries[query.ID] = query
+	return nil
+}
+
+func (m *memSavedQueryRepository) Delete(ctx context.Context, id int64, deleteBy int64) error {
+	delete(m.queries, id)
+	return nil
+}
+
+func (m *memSavedQueryRepository) ListByFolder(ctx context.Context, workspaceID int64, folderID *int64, limit, offset int) ([]*repository.SavedQuery, error) {
+	var queries []*repository.SavedQuery
+	for _, q := range m.queries {
+		if q.WorkspaceID == workspaceID && ((folderID == nil && q.FolderID == nil) ||
+			(folderID != nil && q.FolderID != nil && *folderID == *q.FolderID)) {
+			queries = append(queries, q)
+		}
+	}
+	return queries, nil
+}
+
+func (m *memSavedQueryRepository) Move(ctx context.Context, id int64, folderID *int64, updateBy int64) error {
+	m.queries[id].FolderID = folderID
+	return nil
+}
+
+func (m *memSavedQueryRepository) ListDashboardBacked(ctx context.Context) ([]*repository.SavedQuery, error) {
+	var queries []*repository.SavedQuery
+	for _, q := range m.queries {
+		if q.DashboardBacked && q.ConnectionID != nil {
+			queries = append(queries, q)
+		}
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].ID < queries[j].ID })
+	return queries, nil
+}
+
+// memberWorkspaceRepository 按用户返回所属工作空间
+type memberWorkspaceRepository struct {
+	repository.WorkspaceRepository
+	byUser map[int64]int64
+}
+
+func (m *memberWorkspaceRepository) GetByUserID(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	workspace := &repository.Workspace{}
+	workspace.ID = repository.DefaultWorkspaceID
+	if id, ok := m.byUser[userID]; ok {
+		workspace.ID = id
+	}
+	return workspace, nil
+}
+
+func newTestFolderService(t *testing.T) (*FolderService, *memSavedQueryRepository) {
+	queries := &memSavedQueryRepository{queries: make(map[int64]*repository.SavedQuery)}
+	workspaces := &memberWorkspaceRepository{byUser: map[int64]int64{9: 2}}
+	return NewFolderService(newMemFolderRepository(), queries, workspaces, zaptest.NewLogger(t)), queries
+}
+
+func TestFolderService_BreadcrumbsAndMove(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestFolderService(t)
+
+	sales, err := s.CreateFolder(ctx, 1, "user", nil, " 销售 ")
+	require.NoError(t, err)
+	assert.Equal(t, "销售", sales.Name)
+	q1, err := s.CreateFolder(ctx, 2, "user", &sales.ID, "Q1")
+	require.NoError(t, err)
+	archive, err := s.CreateFolder(ctx, 2, "user", nil, "归档")
+	require.NoError(t, err)
+
+	query := &repository.SavedQuery{Name: "月度销售额", NaturalQuery: "每月销售额", SQL: "SELECT 1", FolderID: &q1.ID}
+	require.NoError(t, s.CreateSavedQuery(ctx, 3, "user", query))
+
+	// 仪表盘使用的查询需要指定连接才能预热缓存
+	update := *query
+	update.DashboardBacked = true
+	_, err = s.UpdateSavedQuery(ctx, 3, "user", &update)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	contents, err := s.ListContents(ctx, 3, "user", &q1.ID, 20, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []Breadcrumb{{ID: sales.ID, Name: "销售"}, {ID: q1.ID, Name: "Q1"}}, contents.Breadcrumbs)
+	assert.Equal(t, repository.FolderEdit, contents.Permission)
+	require.Len(t, contents.Queries, 1)
+
+	// 不能移动到自身的子树中
+	_, err = s.MoveFolder(ctx, 1, "user", sales.ID, &q1.ID)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	// 移动后子树路径与面包屑随之更新
+	_, err = s.MoveFolder(ctx, 1, "user", sales.ID, &archive.ID)
+	require.NoError(t, err)
+	_, crumbs, err := s.GetSavedQuery(ctx, 3, "user", query.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"归档", "销售", "Q1"}, []string{crumbs[0].Name, crumbs[1].Name, crumbs[2].Name})
+
+	moved, err := s.MoveSavedQuery(ctx, 3, "user", query.ID, nil)
+	require.NoError(t, err)
+	assert.Nil(t, moved.FolderID)
+
+	// 其他工作空间的成员看不到该保存查询
+	_, _, err = s.GetSavedQuery(ctx, 9, "user", query.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestFolderService_Permissions(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestFolderService(t)
+
+	finance, err := s.CreateFolder(ctx, 1, "user", nil, "财务")
+	require.NoError(t, err)
+	reports, err := s.CreateFolder(ctx, 1, "user", &finance.ID, "报表")
+	require.NoError(t, err)
+	_, err = s.CreateFolder(ctx, 1, "user", nil, "公共")
+	require.NoError(t, err)
+
+	// 未设置授权时成员均可编辑，但只有创建者能管理
+	_, err = s.RenameFolder(ctx, 2, "user", finance.ID, "财务部")
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+
+	_, err = s.SetPermission(ctx, 1, "user", finance.ID, 2, repository.FolderView)
+	require.NoError(t, err)
+	_, err = s.SetPermission(ctx, 1, "user", finance.ID, 4, repository.FolderManage)
+	require.NoError(t, err)
+
+	// 设置授权后未授权成员无法访问，也看不到该文件夹
+	_, err = s.ListContents(ctx, 3, "user", &reports.ID, 20, 0)
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+	root, err := s.ListContents(ctx, 3, "user", nil, 20, 0)
+	require.NoError(t, err)
+	require.Len(t, root.Folders, 1)
+	assert.Equal(t, "公共", root.Folders[0].Name)
+
+	// view授权向下继承，只能查看不能编辑
+	contents, err := s.ListContents(ctx, 2, "user", &reports.ID, 20, 0)
+	require.NoError(t, err)
+	assert.Equal(t, repository.FolderView, contents.Permission)
+	err = s.CreateSavedQuery(ctx, 2, "user", &repository.SavedQuery{Name: "x", FolderID: &reports.ID})
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+
+	// 子文件夹上的授权覆盖上级，但manage授权覆盖整个子树
+	_, err = s.SetPermission(ctx, 1, "user", reports.ID, 2, repository.FolderEdit)
+	require.NoError(t, err)
+	contents, err = s.ListContents(ctx, 2, "user", &reports.ID, 20, 0)
+	require.NoError(t, err)
+	assert.Equal(t, repository.FolderEdit, contents.Permission)
+	contents, err = s.ListContents(ctx, 4, "user", &reports.ID, 20, 0)
+	require.NoError(t, err)
+	assert.Equal(t, repository.FolderManage, contents.Permission)
+
+	// 管理员始终拥有管理权限
+	_, err = s.RenameFolder(ctx, 5, "admin", reports.ID, "月报")
+	require.NoError(t, err)
+
+	_, err = s.SetPermission(ctx, 1, "user", finance.ID, 2, "owner")
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+}
+
+func TestFolderService_BatchMoveSavedQueries(t *testing.T) {
+	ctx := context.Background()
+	s, queries := newTestFolderService(t)
+
+	finance, err := s.CreateFolder(ctx, 1, "user", nil, "财务")
+	require.NoError(t, err)
+	archive, err := s.CreateFolder(ctx, 1, "user", nil, "归档")
+	require.NoError(t, err)
+	_, err = s.SetPermission(ctx, 1, "user", finance.ID, 2, repository.FolderView)
+	require.NoError(t, err)
+
+	locked := &repository.SavedQuery{Name: "预算", SQL: "SELECT 1", FolderID: &finance.ID}
+	require.NoError(t, s.CreateSavedQuery(ctx, 1, "user", locked))
+	own := &repository.SavedQuery{Name: "我的查询", SQL: "SELECT 2"}
+	require.NoError(t, s.CreateSavedQuery(ctx, 2, "user", own))
+	other := &repository.SavedQuery{Name: "其他工作空间", SQL: "SELECT 3"}
+	require.NoError(t, s.CreateSavedQuery(ctx, 9, "user", other))
+
+	// 逐条检查：只能查看的文件夹中他人的查询无权移动，其他工作空间的查询视为不存在
+	failures, err := s.BatchMoveSavedQueries(ctx, 2, "user", []int64{locked.ID, own.ID, other.ID, 999}, &archive.ID)
+	require.NoError(t, err)
+	require.Len(t, failures, 3)
+	assert.ErrorIs(t, failures[locked.ID], repository.ErrPermissionDenied)
+	assert.ErrorIs(t, failures[other.ID], repository.ErrNotFound)
+	assert.ErrorIs(t, failures[999], repository.ErrNotFound)
+	assert.Equal(t, &archive.ID, queries.queries[own.ID].FolderID)
+	assert.Equal(t, &finance.ID, queries.queries[locked.ID].FolderID)
+
+	// 没有目标文件夹的编辑权限时整体拒绝
+	_, err = s.BatchMoveSavedQueries(ctx, 2, "user", []int64{own.ID}, &finance.ID)
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+	assert.Equal(t, &archive.ID, queries.queries[own.ID].FolderID)
+}
@@ -0,0 +1,31 @@
+package service
+
+import "time"
+
+// 防护措施类型，客户端据此说明执行结果与原始SQL预期不同的原因
+const (
+	GuardrailTimeout        = "timeout"         // 查询设置了执行超时，limit为毫秒
+	GuardrailRowLimit       = "row_limit"       // 结果达到行数上限被截断，limit为行数
+	GuardrailSizeLimit      = "size_limit"      // 结果达到大小上限被截断，limit为字节数
+	GuardrailReadOnly       = "read_only"       // 查询在只读事务中执行
+	GuardrailColumnsMasked  = "columns_masked"  // 受限列的值被替换为掩码
+	GuardrailColumnsRemoved = "columns_removed" // 受限列从结果中删除
+	GuardrailResultHidden   = "result_hidden"   // 无法确认列访问权限，结果数据被隐藏
+	GuardrailAutoLimit      = "auto_limit"      // SQL没有LIMIT，执行时自动追加，limit为追加的行数（行数上限加1，用于判断截断）
+	GuardrailCostLimit      = "cost_limit"      // 预估代价超过上限，查询未执行，limit为代价上限
+	GuardrailScanRowsLimit  = "scan_rows_limit" // 预估读取行数超过上限，查询未执行，limit为行数上限
+)
+
+// Guardrail 执行查询时实际生效的一项防护措施
+type Guardrail struct {
+	Type    string   `json:"type"`              // 防护类型，见Guardrail*常量
+	Limit   int64    `json:"limit,omitempty"`   // 超时、行数与大小上限的取值
+	Columns []string `json:"columns,omitempty"` // 被脱敏或删除的列
+
+	AccessRequest *ColumnAccessHint `json:"access_request,omitempty"` // 受限列的访问申请说明
+}
+
+// timeoutGuardrail 查询执行超时的防护说明
+func timeoutGuardrail(timeout time.Duration) Guardrail {
+	return Guardrail{Type: GuardrailTimeout, Limit: timeout.Milliseconds()}
+}
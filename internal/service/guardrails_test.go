@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestResultLimiter_Guardrails(t *testing.T) {
+	executor := NewSQLExecutor(nil, nil, zap.NewNop())
+
+	t.Run("达到行数上限", func(t *testing.T) {
+		result := &QueryResult{}
+		limiter := executor.newResultLimiter(WithRowLimit(context.Background(), 2), result)
+		for i := 0; i < 2; i++ {
+			assert.False(t, limiter.full())
+			added, err := limiter.add(map[string]any{"id": i})
+			require.NoError(t, err)
+			assert.True(t, added)
+		}
+		assert.True(t, limiter.full())
+		assert.Equal(t, []Guardrail{{Type: GuardrailRowLimit, Limit: 2}}, result.Guardrails)
+		assert.True(t, result.Truncated)
+		assert.Equal(t, GuardrailRowLimit, result.TruncatedBy)
+	})
+
+	t.Run("达到大小上限", func(t *testing.T) {
+		result := &QueryResult{}
+		limiter := executor.newResultLimiter(context.Background(), result)
+		limiter.maxBytes = 10
+		added, err := limiter.add(map[string]any{"name": "a long value"})
+		require.NoError(t, err)
+		assert.False(t, added)
+		assert.Equal(t, []Guardrail{{Type: GuardrailSizeLimit, Limit: 10}}, result.Guardrails)
+		assert.Equal(t, GuardrailSizeLimit, result.TruncatedBy)
+	})
+
+	t.Run("未截断", func(t *testing.T) {
+		result := &QueryResult{}
+		limiter := executor.newResultLimiter(context.Background(), result)
+		_, err := limiter.add(map[string]any{"id": 1})
+		require.NoError(t, err)
+		assert.False(t, limiter.full())
+		assert.Empty(t, result.Guardrails)
+		assert.False(t, result.Truncated)
+	})
+}
@@ -0,0 +1,241 @@
+// 第三方集成凭据
+// Slack令牌、Jira凭据、SMTP密码与Webhook签名密钥等机密使用与连接密码相同的AES密钥加密保存，
+// API只返回掩码；每条凭据可单独轮换，轮换后版本号递增，集成调用方通过SecretSource在每次使用时读取明文
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/logging"
+	"chat2sql-go/internal/repository"
+)
+
+// DefaultIntegrationCredentialName 未指定名称时使用的凭据名称
+const DefaultIntegrationCredentialName = "default"
+
+// 内置集成读取的凭据：SMTP密码与Teams签名密钥使用default，Webhook类机密按用途区分名称
+const (
+	// IntegrationCredentialEmailInbound 入站邮件Webhook共享令牌（webhook集成）
+	IntegrationCredentialEmailInbound = "email-inbound"
+	// IntegrationCredentialScheduleWebhook 定时执行结果Webhook的签名密钥（webhook集成）
+	IntegrationCredentialScheduleWebhook = "query-schedules"
+)
+
+// SecretFunc 按需读取集成机密，每次调用返回当前生效的机密，凭据轮换后立即生效
+type SecretFunc func(ctx context.Context) (string, error)
+
+// StaticSecret 返回固定机密的SecretFunc，用于未接入凭据存储的场景
+func StaticSecret(secret string) SecretFunc {
+	return func(context.Context) (string, error) { return secret, nil }
+}
+
+// integrationTypes 支持的集成类型
+var integrationTypes = map[repository.IntegrationType]bool{
+	repository.IntegrationSlack:   true,
+	repository.IntegrationJira:    true,
+	repository.IntegrationSMTP:    true,
+	repository.IntegrationWebhook: true,
+	repository.IntegrationTeams:   true,
+}
+
+// SecretCipher 机密加解密接口，AESEncryption实现了该接口
+type SecretCipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+// IntegrationCredentialService 第三方集成凭据服务
+type IntegrationCredentialService struct {
+	repo   repository.IntegrationCredentialRepository
+	cipher SecretCipher
+	logger *zap.Logger
+}
+
+// NewIntegrationCredentialService 创建第三方集成凭据服务
+func NewIntegrationCredentialService(repo repository.IntegrationCredentialRepository, cipher SecretCipher, logger *zap.Logger) *IntegrationCredentialService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &IntegrationCredentialService{
+		repo:   repo,
+		cipher: cipher,
+		logger: logger,
+	}
+}
+
+// Create 创建凭据，name为空时使用default
+func (s *IntegrationCredentialService) Create(ctx context.Context, integration, name string, settings map[string]string, secret string, operatorID int64) (*repository.IntegrationCredential, error) {
+	integration, err := normalizeIntegrationType(integration)
+	if err != nil {
+		return nil, err
+	}
+	name, err = normalizeIntegrationCredentialName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateIntegrationSettings(settings); err != nil {
+		return nil, err
+	}
+	encrypted, mask, err := s.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &repository.IntegrationCredential{
+		BaseModel:       repository.BaseModel{CreateBy: &operatorID},
+		Integration:     integration,
+		Name:            name,
+		Settings:        settings,
+		SecretEncrypted: encrypted,
+		SecretMask:      mask,
+	}
+	if err := s.repo.Create(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("集成凭据已创建",
+		zap.Int64("credential_id", credential.ID),
+		zap.String("integration", integration),
+		zap.String("name", name),
+		zap.Int64("operator_id", operatorID))
+	return credential, nil
+}
+
+// List 列出凭据，integration为空时列出所有集成的凭据
+func (s *IntegrationCredentialService) List(ctx context.Context, integration string) ([]*repository.IntegrationCredential, error) {
+	if integration != "" {
+		var err error
+		if integration, err = normalizeIntegrationType(integration); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.List(ctx, integration)
+}
+
+// Get 获取凭据，返回的凭据只包含机密掩码
+func (s *IntegrationCredentialService) Get(ctx context.Context, id int64) (*repository.IntegrationCredential, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// UpdateSettings 替换凭据的非机密配置
+func (s *IntegrationCredentialService) UpdateSettings(ctx context.Context, id int64, settings map[string]string, operatorID int64) (*repository.IntegrationCredential, error) {
+	if err := validateIntegrationSettings(settings); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateSettings(ctx, id, settings, operatorID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Rotate 用新机密替换旧机密，旧机密立即失效
+func (s *IntegrationCredentialService) Rotate(ctx context.Context, id int64, secret string, operatorID int64) (*repository.IntegrationCredential, error) {
+	encrypted, mask, err := s.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.repo.Rotate(ctx, id, encrypted, mask, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("集成凭据已轮换",
+		zap.Int64("credential_id", id),
+		zap.Int("version", version),
+		zap.Int64("operator_id", operatorID))
+	return s.repo.GetByID(ctx, id)
+}
+
+// Delete 删除凭据
+func (s *IntegrationCredentialService) Delete(ctx context.Context, id int64, operatorID int64) error {
+	if err := s.repo.Delete(ctx, id, operatorID); err != nil {
+		return err
+	}
+	s.logger.Info("集成凭据已删除", zap.Int64("credential_id", id), zap.Int64("operator_id", operatorID))
+	return nil
+}
+
+// Secret 供集成调用方按名称取出凭据与解密后的机密，name为空时使用default
+func (s *IntegrationCredentialService) Secret(ctx context.Context, integration repository.IntegrationType, name string) (*repository.IntegrationCredential, string, error) {
+	if name == "" {
+		name = DefaultIntegrationCredentialName
+	}
+	credential, err := s.repo.GetByName(ctx, string(integration), name)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := s.cipher.Decrypt(credential.SecretEncrypted)
+	if err != nil {
+		s.logger.Error("解密集成凭据失败", zap.Int64("credential_id", credential.ID), zap.Error(err))
+		return nil, "", fmt.Errorf("解密集成凭据失败: %w", err)
+	}
+	return credential, secret, nil
+}
+
+// SecretSource 返回读取指定凭据机密的函数
+// 凭据存储中没有该凭据时使用fallback，即启动时从环境变量读取的引导值；读取或解密失败时返回错误，不回退到环境变量
+func (s *IntegrationCredentialService) SecretSource(integration repository.IntegrationType, name, fallback string) SecretFunc {
+	return func(ctx context.Context) (string, error) {
+		_, secret, err := s.Secret(ctx, integration, name)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fallback, nil
+		}
+		return secret, err
+	}
+}
+
+// seal 加密机密并生成掩码
+func (s *IntegrationCredentialService) seal(secret string) (string, string, error) {
+	if strings.TrimSpace(secret) == "" {
+		return "", "", fmt.Errorf("%w: 机密不能为空", repository.ErrInvalidInput)
+	}
+	encrypted, err := s.cipher.Encrypt(secret)
+	if err != nil {
+		return "", "", fmt.Errorf("加密集成凭据失败: %w", err)
+	}
+	return encrypted, MaskSecret(secret), nil
+}
+
+// MaskSecret 生成机密的掩码，只保留末尾4个字符；不超过8个字符的机密完全隐藏
+func MaskSecret(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 8 {
+		return "****"
+	}
+	return "****" + string(runes[len(runes)-4:])
+}
+
+func normalizeIntegrationType(integration string) (string, error) {
+	integration = strings.ToLower(strings.TrimSpace(integration))
+	if !integrationTypes[repository.IntegrationType(integration)] {
+		return "", fmt.Errorf("%w: 不支持的集成类型 %q", repository.ErrInvalidInput, integration)
+	}
+	return integration, nil
+}
+
+func normalizeIntegrationCredentialName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return DefaultIntegrationCredentialName, nil
+	}
+	if len([]rune(name)) > 100 {
+		return "", fmt.Errorf("%w: 凭据名称不能超过100个字符", repository.ErrInvalidInput)
+	}
+	return name, nil
+}
+
+// validateIntegrationSettings 机密只能通过secret字段提交，配置中出现token、password等键时拒绝，避免明文落库
+func validateIntegrationSettings(settings map[string]string) error {
+	for key := range settings {
+		if logging.IsSecretKey(key) {
+			return fmt.Errorf("%w: 配置项 %s 属于机密，请通过secret字段提交", repository.ErrInvalidInput, key)
+		}
+	}
+	return nil
+}
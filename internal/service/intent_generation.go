@@ -0,0 +1,109 @@
+// 按意图类别调整生成参数
+// 路由模型前按意图分析器识别的意图确定类别，配置了覆盖时替换该类别的温度与top_p，
+// 实际使用的类别与参数记录在GenerationParameters中，随响应返回并归档
+package service
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+)
+
+// exploratoryIntentConfidence 意图置信度不高于该值时视为开放式问题
+// 未匹配任何意图模式时意图分析器默认返回数据查询，置信度即为该值
+const exploratoryIntentConfidence = 0.6
+
+// intentCategories 意图分析器识别的意图对应的生成参数类别
+var intentCategories = map[ai.QueryIntent]string{
+	ai.IntentUnknown:            config.IntentCategoryExploratory,
+	ai.IntentDataQuery:          config.IntentCategoryDataQuery,
+	ai.IntentAggregation:        config.IntentCategoryAggregation,
+	ai.IntentJoinQuery:          config.IntentCategoryJoin,
+	ai.IntentTimeSeriesAnalysis: config.IntentCategoryTimeSeries,
+	ai.IntentComparison:         config.IntentCategoryComparison,
+	ai.IntentRanking:            config.IntentCategoryRanking,
+	ai.IntentFiltering:          config.IntentCategoryFiltering,
+	ai.IntentGrouping:           config.IntentCategoryGrouping,
+}
+
+// generationProfile 本次请求按意图类别确定的生成参数覆盖
+type generationProfile struct {
+	category string
+	override config.GenerationOverride
+}
+
+// apply 返回覆盖温度后的模型配置
+func (p *generationProfile) apply(cfg config.ModelConfig) config.ModelConfig {
+	if p != nil && p.override.Temperature != nil {
+		cfg.Temperature = *p.override.Temperature
+	}
+	return cfg
+}
+
+// options 追加覆盖的top_p；未覆盖时不传递，由模型使用自身默认值
+func (p *generationProfile) options(opts []llms.CallOption) []llms.CallOption {
+	if p != nil && p.override.TopP != nil {
+		opts = append(opts, llms.WithTopP(*p.override.TopP))
+	}
+	return opts
+}
+
+// record 在生成参数中记录意图类别与覆盖的top_p
+func (p *generationProfile) record(params *GenerationParameters) *GenerationParameters {
+	if p != nil {
+		params.Intent = p.category
+		params.TopP = p.override.TopP
+	}
+	return params
+}
+
+// generationProfileKey 生成参数覆盖的context键
+type generationProfileKey struct{}
+
+// withGenerationProfile 设置本次请求的生成参数覆盖，profile为nil时不修改
+func withGenerationProfile(ctx context.Context, profile *generationProfile) context.Context {
+	if profile == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, generationProfileKey{}, profile)
+}
+
+// generationProfileFromContext 读取本次请求的生成参数覆盖，未设置时返回nil
+func generationProfileFromContext(ctx context.Context) *generationProfile {
+	profile, _ := ctx.Value(generationProfileKey{}).(*generationProfile)
+	return profile
+}
+
+// SetIntentGeneration 设置按意图类别的生成参数覆盖，为nil或未配置任何类别时不分析意图
+func (ai *AIService) SetIntentGeneration(intentConfig *config.IntentGenerationConfig) {
+	ai.intentGeneration = intentConfig
+}
+
+// resolveGenerationProfile 按问题的意图类别确定生成参数覆盖，未启用时返回nil
+// 类别未配置覆盖时仍返回类别，以便在生成参数中记录
+func (ai *AIService) resolveGenerationProfile(ctx context.Context, req *SQLGenerationRequest) *generationProfile {
+	if ai.intentGeneration == nil || len(ai.intentGeneration.Overrides) == 0 {
+		return nil
+	}
+
+	intent := ai.analyzeIntent(ctx, req)
+
+	category, ok := intentCategories[intent.PrimaryIntent]
+	if !ok || intent.Confidence <= exploratoryIntentConfidence {
+		category = config.IntentCategoryExploratory
+	}
+	profile := &generationProfile{category: category}
+	if override, ok := ai.intentGeneration.Override(category); ok {
+		profile.override = override
+		ai.logger.Debug("按意图类别覆盖生成参数",
+			zap.String("intent", category),
+			zap.Any("temperature", override.Temperature),
+			zap.Any("top_p", override.TopP),
+		)
+	}
+	return profile
+}
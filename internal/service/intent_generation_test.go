@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestAIService_IntentGeneration(t *testing.T) {
+	aiConfig := config.DemoAIConfig("")
+	aiConfig.Primary.Temperature = 0.3
+	primary := &seededLLM{}
+	aiService := NewAIServiceWithClients(aiConfig, primary, &seededLLM{}, zaptest.NewLogger(t))
+
+	t.Setenv("AI_INTENT_GENERATION_PARAMS", "aggregation:temperature=0;exploratory:temperature=0.8,top_p=0.95")
+	intentConfig, err := config.LoadIntentGenerationConfigFromEnv()
+	require.NoError(t, err)
+	aiService.SetIntentGeneration(intentConfig)
+
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "统计订单总数", Schema: "orders(id)"})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Generation)
+	assert.Equal(t, config.IntentCategoryAggregation, resp.Generation.Intent)
+	assert.Equal(t, 0.0, resp.Generation.Temperature)
+	assert.Nil(t, resp.Generation.TopP)
+	assert.Equal(t, 0.0, primary.options.Temperature)
+	assert.Zero(t, primary.options.TopP, "未覆盖top_p时不传递")
+
+	resp, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "随便看看", Schema: "orders(id)"})
+	require.NoError(t, err)
+	assert.Equal(t, config.IntentCategoryExploratory, resp.Generation.Intent)
+	assert.Equal(t, 0.8, resp.Generation.Temperature)
+	require.NotNil(t, resp.Generation.TopP)
+	assert.Equal(t, 0.95, *resp.Generation.TopP)
+	assert.Equal(t, 0.95, primary.options.TopP)
+
+	// 多候选生成以覆盖的温度为起点
+	specs := aiService.candidateSpecs(3, &generationProfile{category: config.IntentCategoryAggregation, override: intentConfig.Overrides[config.IntentCategoryAggregation]})
+	assert.InDelta(t, 0.0, specs[0].temperature, 1e-9)
+	assert.InDelta(t, candidateTemperatureStep, specs[2].temperature, 1e-9)
+
+	// 未启用时不记录意图类别，沿用模型配置
+	aiService.SetIntentGeneration(nil)
+	resp, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "统计订单总数", Schema: "orders(id)"})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Generation.Intent)
+	assert.Equal(t, 0.3, resp.Generation.Temperature)
+}
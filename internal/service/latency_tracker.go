@@ -0,0 +1,108 @@
+package service
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"chat2sql-go/internal/config"
+)
+
+// latencySample 一次模型调用的耗时
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// ModelLatency 模型在滚动窗口内的延迟统计
+type ModelLatency struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Samples  int    `json:"samples"`          // 窗口内的样本数
+	P95Ms    *int64 `json:"p95_ms,omitempty"` // 样本不足时为空
+}
+
+// LatencyTracker 按模型统计滚动窗口内的调用延迟
+// 只保留窗口内最多MaxSamples个样本，P95按窗口内样本实时计算；样本少于MinSamples时视为未知
+type LatencyTracker struct {
+	config *config.LatencyRoutingConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	samples map[string][]latencySample // 模型（ModelPrimary/ModelFallback）-> 按时间顺序的样本
+}
+
+// NewLatencyTracker 创建模型延迟统计，配置为nil时使用默认配置
+func NewLatencyTracker(latencyConfig *config.LatencyRoutingConfig) *LatencyTracker {
+	if latencyConfig == nil {
+		latencyConfig = config.DefaultLatencyRoutingConfig()
+	}
+	return &LatencyTracker{
+		config:  latencyConfig,
+		now:     time.Now,
+		samples: make(map[string][]latencySample),
+	}
+}
+
+// Observe 记录一次模型调用耗时
+func (t *LatencyTracker) Observe(model string, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := append(t.prune(model), latencySample{at: t.now(), duration: duration})
+	if len(samples) > t.config.MaxSamples {
+		samples = samples[len(samples)-t.config.MaxSamples:]
+	}
+	t.samples[model] = samples
+}
+
+// P95 返回模型在窗口内的P95延迟，样本不足时ok为false
+func (t *LatencyTracker) P95(model string) (p95 time.Duration, samples int, ok bool) {
+	t.mu.Lock()
+	window := t.prune(model)
+	t.samples[model] = window
+	durations := make([]time.Duration, len(window))
+	for i, sample := range window {
+		durations[i] = sample.duration
+	}
+	t.mu.Unlock()
+
+	if len(durations) < t.config.MinSamples {
+		return 0, len(durations), false
+	}
+	slices.Sort(durations)
+	// 最近秩法：第ceil(0.95n)个样本
+	rank := (len(durations)*95 + 99) / 100
+	return durations[rank-1], len(durations), true
+}
+
+// Mean 返回所有模型在窗口内的平均调用耗时，没有样本时samples为0
+func (t *LatencyTracker) Mean() (mean time.Duration, samples int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var total time.Duration
+	for model := range t.samples {
+		window := t.prune(model)
+		t.samples[model] = window
+		for _, sample := range window {
+			total += sample.duration
+		}
+		samples += len(window)
+	}
+	if samples == 0 {
+		return 0, 0
+	}
+	return total / time.Duration(samples), samples
+}
+
+// prune 丢弃窗口外的样本，调用方需持有锁
+func (t *LatencyTracker) prune(model string) []latencySample {
+	samples := t.samples[model]
+	cutoff := t.now().Add(-t.config.Window)
+	i := 0
+	for i < len(samples) && samples[i].at.Before(cutoff) {
+		i++
+	}
+	return samples[i:]
+}
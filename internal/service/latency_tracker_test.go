@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestLatencyTracker_P95(t *testing.T) {
+	tracker := NewLatencyTracker(&config.LatencyRoutingConfig{Window: time.Minute, MinSamples: 5, MaxSamples: 20})
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	for i := 1; i <= 4; i++ {
+		tracker.Observe(ModelPrimary, time.Duration(i)*100*time.Millisecond)
+	}
+	_, samples, ok := tracker.P95(ModelPrimary)
+	assert.False(t, ok, "样本不足")
+	assert.Equal(t, 4, samples)
+
+	for i := 5; i <= 20; i++ {
+		tracker.Observe(ModelPrimary, time.Duration(i)*100*time.Millisecond)
+	}
+	p95, samples, ok := tracker.P95(ModelPrimary)
+	assert.True(t, ok)
+	assert.Equal(t, 20, samples)
+	assert.Equal(t, 1900*time.Millisecond, p95)
+
+	// 超过最大样本数时丢弃最早的样本
+	tracker.Observe(ModelPrimary, 10*time.Second)
+	p95, samples, _ = tracker.P95(ModelPrimary)
+	assert.Equal(t, 20, samples)
+	assert.Equal(t, 2*time.Second, p95)
+
+	_, _, ok = tracker.P95(ModelFallback)
+	assert.False(t, ok)
+
+	// 窗口外的样本过期
+	now = now.Add(2 * time.Minute)
+	_, samples, ok = tracker.P95(ModelPrimary)
+	assert.False(t, ok)
+	assert.Equal(t, 0, samples)
+}
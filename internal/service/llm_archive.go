@@ -0,0 +1,329 @@
+// 模型请求与响应归档
+// 按查询ID保存脱敏后的完整提示词与模型原始输出，生成质量有问题时直接查看当时发给模型的内容，
+// 不必再手工拼凑提示词；归档超过保留时间后删除，管理员通过有时效的签名链接下载
+
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/logging"
+	"chat2sql-go/internal/repository"
+)
+
+// 模型归档相关错误
+var (
+	ErrArchiveNotFound     = errors.New("archive not found")
+	ErrArchiveLinkInvalid  = errors.New("archive link signature is invalid")
+	ErrArchiveLinkExpired  = errors.New("archive link has expired")
+	ErrArchiveInvalidQuery = errors.New("invalid archive query id")
+)
+
+// archiveQueryIDPattern 查询ID格式，同时保证可以安全地用作对象键
+var archiveQueryIDPattern = regexp.MustCompile(`^[0-9a-z]+-[0-9a-z]+$`)
+
+// archiveObjectSuffix 归档对象键后缀
+const archiveObjectSuffix = ".json"
+
+// ObjectInfo 对象元数据
+type ObjectInfo struct {
+	Key      string
+	Modified time.Time
+}
+
+// ObjectStore 对象存储
+// 归档只依赖这组基本操作，部署时可替换为云厂商对象存储的实现
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error) // 对象不存在时返回fs.ErrNotExist
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) ([]ObjectInfo, error)
+}
+
+// DirObjectStore 以本地目录实现的对象存储，目录可挂载对象存储桶（如s3fs、gcsfuse）
+type DirObjectStore struct {
+	dir string
+}
+
+// NewDirObjectStore 创建目录对象存储，目录不存在时自动创建
+func NewDirObjectStore(dir string) (*DirObjectStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("创建归档目录失败: %w", err)
+	}
+	return &DirObjectStore{dir: dir}, nil
+}
+
+// Put 写入对象，先写临时文件再重命名，读取方不会看到写了一半的对象
+func (s *DirObjectStore) Put(ctx context.Context, key string, data []byte) error {
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, key))
+}
+
+// Get 读取对象
+func (s *DirObjectStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, key))
+}
+
+// Delete 删除对象，对象不存在时不报错
+func (s *DirObjectStore) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(filepath.Join(s.dir, key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List 列出全部对象，忽略子目录与未写完的临时文件
+func (s *DirObjectStore) List(ctx context.Context) ([]ObjectInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]ObjectInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, ObjectInfo{Key: entry.Name(), Modified: info.ModTime()})
+	}
+	return objects, nil
+}
+
+// piiRules 个人信息脱敏规则，在logging.Redact清除凭据之后执行
+var piiRules = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[EMAIL]"},
+	// 身份证号须在银行卡号与手机号之前处理，避免被部分匹配
+	{regexp.MustCompile(`\b\d{17}[\dXx]\b`), "[ID_NUMBER]"},
+	{regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), "[CARD_NUMBER]"},
+	{regexp.MustCompile(`(?:\+?86[ -]?)?\b1[3-9]\d{9}\b`), "[PHONE]"},
+	{regexp.MustCompile(`\+\d{1,3}[ -]?\(?\d{1,4}\)?(?:[ -]?\d{2,4}){2,3}\b`), "[PHONE]"},
+}
+
+// ScrubPII 清除文本中的凭据与个人信息（邮箱、身份证号、银行卡号、电话号码）
+func ScrubPII(text string) string {
+	text = logging.Redact(text)
+	for _, rule := range piiRules {
+		text = rule.pattern.ReplaceAllString(text, rule.replacement)
+	}
+	return text
+}
+
+// LLMArchiveEntry 一次SQL生成的模型请求与响应
+type LLMArchiveEntry struct {
+	QueryID      string                `json:"query_id"`
+	UserID       int64                 `json:"user_id"`
+	ConnectionID int64                 `json:"connection_id"`
+	Prompt       string                `json:"prompt"`     // 发送给模型的完整提示词，已脱敏
+	Completion   string                `json:"completion"` // 模型原始输出，已脱敏
+	SQL          string                `json:"sql"`        // 从模型输出中解析出的SQL
+	Generation   *GenerationParameters `json:"generation,omitempty"`
+	ArchivedAt   time.Time             `json:"archived_at"`
+}
+
+// LLMArchiveService 模型请求与响应归档服务
+// 工作空间不保留问题原文（question_retention不为keep）时不归档，提示词包含问题原文；
+// 配置了数据驻留策略时，归档位置（DATA_RESIDENCY_ARCHIVE_LOCATION）须与策略固定的归档存储桶一致，否则不归档
+type LLMArchiveService struct {
+	store      ObjectStore
+	workspaces repository.WorkspaceRepository
+	residency  *ResidencyService
+	config     *config.LLMArchiveConfig
+	now        func() time.Time
+	logger     *zap.Logger
+}
+
+// NewLLMArchiveService 创建模型归档服务
+func NewLLMArchiveService(store ObjectStore, workspaces repository.WorkspaceRepository, archiveConfig *config.LLMArchiveConfig, logger *zap.Logger) *LLMArchiveService {
+	return &LLMArchiveService{
+		store:      store,
+		workspaces: workspaces,
+		config:     archiveConfig,
+		now:        time.Now,
+		logger:     logger,
+	}
+}
+
+// SetResidency 启用数据驻留校验，未启用时配置了驻留策略的工作空间一律不归档
+func (s *LLMArchiveService) SetResidency(residency *ResidencyService) {
+	s.residency = residency
+}
+
+// Archive 脱敏后保存一次生成的提示词与模型输出，返回是否已归档
+func (s *LLMArchiveService) Archive(ctx context.Context, entry *LLMArchiveEntry) (bool, error) {
+	if !archiveQueryIDPattern.MatchString(entry.QueryID) {
+		return false, ErrArchiveInvalidQuery
+	}
+
+	workspace, err := s.workspaces.GetByUserID(ctx, entry.UserID)
+	if err != nil {
+		return false, fmt.Errorf("获取用户工作空间失败: %w", err)
+	}
+	if workspace.QuestionRetention != string(repository.QuestionRetentionKeep) {
+		return false, nil
+	}
+	if allowed, err := s.residencyAllows(ctx, workspace, entry.UserID); err != nil || !allowed {
+		return false, err
+	}
+
+	archived := *entry
+	archived.Prompt = ScrubPII(entry.Prompt)
+	archived.Completion = ScrubPII(entry.Completion)
+	archived.SQL = ScrubPII(entry.SQL)
+	archived.ArchivedAt = s.now().UTC()
+
+	data, err := json.MarshalIndent(&archived, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if err := s.store.Put(ctx, entry.QueryID+archiveObjectSuffix, data); err != nil {
+		return false, fmt.Errorf("写入模型归档失败: %w", err)
+	}
+	return true, nil
+}
+
+// residencyAllows 归档位置是否符合工作空间的数据驻留策略
+func (s *LLMArchiveService) residencyAllows(ctx context.Context, workspace *repository.Workspace, userID int64) (bool, error) {
+	if workspace.DataResidency == nil {
+		return true, nil
+	}
+	if s.residency == nil {
+		return false, nil
+	}
+	if err := s.residency.CheckLocalWrite(ctx, userID, repository.StorageArchive); err != nil {
+		if IsResidencyViolation(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Get 读取归档，不存在或已过保留时间时返回ErrArchiveNotFound
+func (s *LLMArchiveService) Get(ctx context.Context, queryID string) (*LLMArchiveEntry, error) {
+	if !archiveQueryIDPattern.MatchString(queryID) {
+		return nil, ErrArchiveInvalidQuery
+	}
+
+	data, err := s.store.Get(ctx, queryID+archiveObjectSuffix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrArchiveNotFound
+		}
+		return nil, fmt.Errorf("读取模型归档失败: %w", err)
+	}
+
+	var entry LLMArchiveEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("解析模型归档失败: %w", err)
+	}
+	if s.now().Sub(entry.ArchivedAt) > s.config.Retention {
+		return nil, ErrArchiveNotFound
+	}
+	return &entry, nil
+}
+
+// SignLink 为归档生成下载签名，返回签名与过期时间
+func (s *LLMArchiveService) SignLink(queryID string) (string, time.Time) {
+	expiresAt := s.now().Add(s.config.URLTTL).Truncate(time.Second)
+	return s.signature(queryID, expiresAt.Unix()), expiresAt
+}
+
+// VerifyLink 校验下载签名与有效期
+func (s *LLMArchiveService) VerifyLink(queryID string, expires int64, signature string) error {
+	provided, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrArchiveLinkInvalid
+	}
+	expected, _ := hex.DecodeString(s.signature(queryID, expires))
+	if !hmac.Equal(provided, expected) {
+		return ErrArchiveLinkInvalid
+	}
+	if s.now().Unix() > expires {
+		return ErrArchiveLinkExpired
+	}
+	return nil
+}
+
+// signature 对查询ID与过期时间计算HMAC签名
+func (s *LLMArchiveService) signature(queryID string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.config.SigningKey))
+	mac.Write([]byte(queryID + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Prune 删除超过保留时间的归档，返回删除数量
+func (s *LLMArchiveService) Prune(ctx context.Context) (int, error) {
+	objects, err := s.store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("列出模型归档失败: %w", err)
+	}
+
+	cutoff := s.now().Add(-s.config.Retention)
+	deleted := 0
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, archiveObjectSuffix) || !object.Modified.Before(cutoff) {
+			continue
+		}
+		if err := s.store.Delete(ctx, object.Key); err != nil {
+			return deleted, fmt.Errorf("删除模型归档失败: %w", err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// Run 按清理间隔定期删除过期归档，由看门狗托管
+func (s *LLMArchiveService) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(s.config.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := s.Prune(ctx)
+			if err != nil {
+				s.logger.Warn("Failed to prune LLM archive", zap.Error(err))
+			} else if deleted > 0 {
+				s.logger.Info("Pruned expired LLM archives", zap.Int("deleted", deleted))
+			}
+			beat()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// newTestLLMArchive 创建使用临时目录的模型归档服务
+func newTestLLMArchive(t *testing.T, workspace *repository.Workspace) (*LLMArchiveService, string) {
+	dir := t.TempDir()
+	store, err := NewDirObjectStore(dir)
+	require.NoError(t, err)
+
+	cfg := config.DefaultLLMArchiveConfig()
+	cfg.Enabled = true
+	cfg.Dir = dir
+	cfg.SigningKey = strings.Repeat("k", 32)
+	return NewLLMArchiveService(store, &stubWorkspaceRepository{workspace: workspace}, cfg, zaptest.NewLogger(t)), dir
+}
+
+func TestScrubPII(t *testing.T) {
+	text := "查询 zhang.san@example.com 的订单，手机13812345678，身份证11010519491231002X，" +
+		"卡号 6222 0212 3456 7890 123，最近30天，api_key=abcdef123456"
+	scrubbed := ScrubPII(text)
+
+	for _, leaked := range []string{"zhang.san@example.com", "13812345678", "11010519491231002X", "6222 0212 3456 7890 123", "abcdef123456"} {
+		assert.NotContains(t, scrubbed, leaked)
+	}
+	assert.Contains(t, scrubbed, "[EMAIL]")
+	assert.Contains(t, scrubbed, "[PHONE]")
+	assert.Contains(t, scrubbed, "[ID_NUMBER]")
+	assert.Contains(t, scrubbed, "[CARD_NUMBER]")
+	assert.Contains(t, scrubbed, "最近30天")
+	assert.Equal(t, "SELECT id FROM users LIMIT 100", ScrubPII("SELECT id FROM users LIMIT 100"))
+}
+
+func TestLLMArchiveService_ArchiveAndGet(t *testing.T) {
+	archive, _ := newTestLLMArchive(t, &repository.Workspace{QuestionRetention: string(repository.QuestionRetentionKeep)})
+	ctx := context.Background()
+
+	archived, err := archive.Archive(ctx, &LLMArchiveEntry{
+		QueryID:    "7-lx3k9q2a",
+		UserID:     7,
+		Prompt:     "用户问题：查询 zhang.san@example.com 的订单",
+		Completion: "```sql\nSELECT * FROM orders WHERE email = 'zhang.san@example.com'\n```",
+		SQL:        "SELECT * FROM orders WHERE email = 'zhang.san@example.com'",
+	})
+	require.NoError(t, err)
+	assert.True(t, archived)
+
+	entry, err := archive.Get(ctx, "7-lx3k9q2a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), entry.UserID)
+	assert.Equal(t, "用户问题：查询 [EMAIL] 的订单", entry.Prompt)
+	assert.NotContains(t, entry.Completion, "zhang.san@example.com")
+	assert.NotContains(t, entry.SQL, "zhang.san@example.com")
+	assert.False(t, entry.ArchivedAt.IsZero())
+
+	_, err = archive.Get(ctx, "7-missing")
+	assert.ErrorIs(t, err, ErrArchiveNotFound)
+	_, err = archive.Get(ctx, "../etc/passwd")
+	assert.ErrorIs(t, err, ErrArchiveInvalidQuery)
+
+	// 超过保留时间后不再可读
+	archive.now = func() time.Time { return time.Now().Add(8 * 24 * time.Hour) }
+	_, err = archive.Get(ctx, "7-lx3k9q2a")
+	assert.ErrorIs(t, err, ErrArchiveNotFound)
+}
+
+func TestLLMArchiveService_SkipsRestrictedWorkspaces(t *testing.T) {
+	workspaces := map[string]*repository.Workspace{
+		"问题被哈希": {QuestionRetention: string(repository.QuestionRetentionHash)},
+		"数据驻留":  {QuestionRetention: string(repository.QuestionRetentionKeep), DataResidency: &repository.DataResidencyPolicy{Region: "eu-central-1"}},
+	}
+	for name, workspace := range workspaces {
+		t.Run(name, func(t *testing.T) {
+			archive, dir := newTestLLMArchive(t, workspace)
+			archived, err := archive.Archive(context.Background(), &LLMArchiveEntry{QueryID: "7-lx3k9q2a", UserID: 7, Prompt: "p"})
+			require.NoError(t, err)
+			assert.False(t, archived)
+
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, files)
+		})
+	}
+}
+
+func TestLLMArchiveService_ResidencyLocation(t *testing.T) {
+	workspace := &repository.Workspace{
+		QuestionRetention: string(repository.QuestionRetentionKeep),
+		DataResidency: &repository.DataResidencyPolicy{
+			Region:    "eu-central-1",
+			Locations: map[string]*repository.StorageLocation{"archive": {Bucket: "archive-eu", Region: "eu-central-1"}},
+		},
+	}
+	locations := map[string]struct {
+		target   config.StorageTarget
+		archived bool
+	}{
+		"归档位置与策略一致": {config.StorageTarget{Bucket: "archive-eu", Region: "eu-central-1"}, true},
+		"归档位置在其他区域": {config.StorageTarget{Bucket: "archive-us", Region: "us-east-1"}, false},
+	}
+	for name, tt := range locations {
+		t.Run(name, func(t *testing.T) {
+			archive, dir := newTestLLMArchive(t, workspace)
+			residencyConfig := &config.ResidencyConfig{Locations: map[string]config.StorageTarget{"archive": tt.target}}
+			archive.SetResidency(NewResidencyService(&stubWorkspaceRepository{workspace: workspace}, residencyConfig, zaptest.NewLogger(t)))
+
+			archived, err := archive.Archive(context.Background(), &LLMArchiveEntry{QueryID: "7-lx3k9q2a", UserID: 7, Prompt: "p"})
+			require.NoError(t, err)
+			assert.Equal(t, tt.archived, archived)
+
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, files, map[bool]int{true: 1, false: 0}[tt.archived])
+		})
+	}
+}
+
+func TestLLMArchiveService_SignedLinks(t *testing.T) {
+	archive, _ := newTestLLMArchive(t, &repository.Workspace{})
+
+	signature, expiresAt := archive.SignLink("7-lx3k9q2a")
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), expiresAt, 2*time.Second)
+	require.NoError(t, archive.VerifyLink("7-lx3k9q2a", expiresAt.Unix(), signature))
+
+	assert.ErrorIs(t, archive.VerifyLink("7-other", expiresAt.Unix(), signature), ErrArchiveLinkInvalid)
+	assert.ErrorIs(t, archive.VerifyLink("7-lx3k9q2a", expiresAt.Unix()+3600, signature), ErrArchiveLinkInvalid, "篡改过期时间")
+	assert.ErrorIs(t, archive.VerifyLink("7-lx3k9q2a", expiresAt.Unix(), "not-hex"), ErrArchiveLinkInvalid)
+
+	archive.now = func() time.Time { return expiresAt.Add(time.Second) }
+	assert.ErrorIs(t, archive.VerifyLink("7-lx3k9q2a", expiresAt.Unix(), signature), ErrArchiveLinkExpired)
+}
+
+func TestLLMArchiveService_Prune(t *testing.T) {
+	archive, dir := newTestLLMArchive(t, &repository.Workspace{QuestionRetention: string(repository.QuestionRetentionKeep)})
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		_, err := archive.Archive(ctx, &LLMArchiveEntry{QueryID: "7-q" + strconv.Itoa(i), UserID: 7})
+		require.NoError(t, err)
+	}
+	old := time.Now().Add(-8 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "7-q1.json"), old, old))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0o600))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "notes.txt"), old, old))
+
+	deleted, err := archive.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	_, err = archive.Get(ctx, "7-q1")
+	assert.ErrorIs(t, err, ErrArchiveNotFound)
+	_, err = archive.Get(ctx, "7-q2")
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"), "只清理归档对象")
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"unicode"
+)
+
+// DetectLocale 按问题文本判断用户使用的语言，返回zh、en，无法判断时返回空字符串
+// 汉字按字计数、拉丁字母按单词计数，二者比较：中文问题中夹杂的表名、字段名不会被误判为英文
+func DetectLocale(text string) string {
+	han, words := 0, 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+			inWord = false
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		case r == '_' || unicode.IsDigit(r):
+			// 标识符内部的下划线与数字不拆分单词
+		default:
+			inWord = false
+		}
+	}
+
+	switch {
+	case han == 0 && words == 0:
+		return ""
+	case han >= words:
+		return "zh"
+	default:
+		return "en"
+	}
+}
+
+// localeKey 用户语言的context键
+type localeKey struct{}
+
+// WithLocale 设置本次请求的用户语言，返回给用户的说明与原因按该语言生成
+func WithLocale(ctx context.Context, locale string) context.Context {
+	if locale == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// localeFromContext 读取本次请求的用户语言，未设置时返回空字符串
+func localeFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// englishMessages 返回给用户的中文消息对应的英文，格式化消息以格式字符串为键
+var englishMessages = map[string]string{
+	// 自动执行
+	"工作空间未启用自动执行":      "Auto-execution is not enabled for this workspace",
+	"置信度%.2f低于阈值%.2f":  "Confidence %.2f is below the threshold %.2f",
+	"无法估算查询代价":         "Unable to estimate the query cost",
+	"预估代价%.0f超过上限%.0f": "Estimated cost %.0f exceeds the limit %.0f",
+	"满足工作空间自动执行策略":     "Meets the workspace auto-execution policy",
+	"仅只读查询允许自动执行":      "Only read-only queries can be auto-executed",
+
+	// 关键查询投票
+	"没有通过校验的候选SQL":          "No candidate SQL passed validation",
+	"候选%d执行失败: %v":          "Candidate %d failed to execute: %v",
+	"候选%d结果超出比较上限(%d行)":     "Candidate %d result exceeds the comparison limit (%d rows)",
+	"两条候选结果不一致(%d行 vs %d行)": "The two candidates returned different results (%d rows vs %d rows)",
+	"所有采样生成了相同的SQL":         "All samples generated the same SQL",
+	"两条不同候选的执行结果一致":         "Two different candidates returned the same result",
+	"候选SQL结果不一致，未返回答案":      "Candidate SQL results disagree, no answer returned",
+
+	// Chat2SQL错误与警告
+	"请求参数无效":       "Invalid request parameters",
+	"认证信息无效":       "Invalid authentication",
+	"用户认证错误":       "User authentication error",
+	"AI查询处理失败":     "Failed to process the AI query",
+	"查询处理超时，请稍后重试": "Query processing timed out, please try again later",
+	"请求过于频繁，请稍后重试": "Too many requests, please try again later",
+	"生成的SQL未通过安全检查，请换一种方式描述查询": "The generated SQL failed the safety check, please rephrase the question",
+	"关键查询模式未启用":                "Critical query mode is not enabled",
+	"无权访问该数据库连接":               "You do not have access to this database connection",
+	"获取工作空间默认设置失败":             "Failed to load workspace defaults",
+	"获取工作空间设置失败":               "Failed to load workspace settings",
+	"费用归属标签无效":                 "Invalid chargeback tags",
+	"无法加载列数据分级，结果数据已隐藏":        "Column classifications could not be loaded, result data has been hidden",
+	"今日AI预算已用完，请明天再试":          "Your daily AI budget has been used up, please try again tomorrow",
+	"系统今日AI预算已用完，请明天再试":        "The daily AI budget for this system has been used up, please try again tomorrow",
+	"未启用模型用量统计":                "LLM usage tracking is not enabled",
+	"查询模型用量失败":                 "Failed to load LLM usage",
+
+	// 结果表格
+	"（无结果列）":        "(no result columns)",
+	"（无数据）":         "(no rows)",
+	"… 另有%d行未显示":    "… %d more rows not shown",
+	"… 另有%d列未显示：%s": "… %d more columns not shown: %s",
+
+	// 意图分析建议
+	"考虑添加分组条件以获得更详细的统计结果":      "Consider grouping the results for a more detailed breakdown",
+	"可以添加筛选条件来限制统计范围":          "Add filters to narrow down what is counted",
+	"建议按时间排序以更好地展现趋势":          "Sort by time to show the trend more clearly",
+	"查询意图不够明确，建议提供更具体的描述":      "The question is ambiguous, please describe what you need more specifically",
+	"查询较为复杂，建议添加适当的条件来限制结果集大小": "The query is complex, add conditions to limit the size of the result",
+
+	// 表名、列名拼写纠正
+	"“%s”按表%s理解": "Interpreted \"%s\" as table %s",
+	"“%s”按列%s理解": "Interpreted \"%s\" as column %s",
+}
+
+// Localize 返回消息在指定语言下的文本，英文以外的语言或没有译文时原样返回中文消息
+// 格式化消息应先Localize格式字符串再填入参数
+func Localize(locale, message string) string {
+	if locale != "en" {
+		return message
+	}
+	if translated, ok := englishMessages[message]; ok {
+		return translated
+	}
+	return message
+}
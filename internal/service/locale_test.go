@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/internal/ai"
+)
+
+func TestDetectLocale(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"每个用户的订单数", "zh"},
+		{"统计order_items表中每个product_id的销量", "zh"},
+		{"How many orders did each customer place last month?", "en"},
+		{"show 订单 count by month", "en"},
+		{"SELECT 1", "en"},
+		{"2024-01-01", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, DetectLocale(tt.text), tt.text)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	assert.Equal(t, "工作空间未启用自动执行", Localize("zh", "工作空间未启用自动执行"))
+	assert.Equal(t, "Auto-execution is not enabled for this workspace", Localize("en", "工作空间未启用自动执行"))
+	assert.Equal(t, "未翻译的消息", Localize("en", "未翻译的消息"), "没有译文时返回原消息")
+
+	decision := EvaluateAutoExecute(nil, 0.9, nil, localeFromContext(WithLocale(context.Background(), "en")))
+	assert.Equal(t, "Auto-execution is not enabled for this workspace", decision.Reason)
+	assert.Equal(t, "", localeFromContext(WithLocale(context.Background(), "")))
+}
+
+func TestAnalyzeIntent_LocalizesSuggestions(t *testing.T) {
+	service := &AIService{intentAnalyzer: ai.NewIntentAnalyzer()}
+	ctx := context.Background()
+
+	en := service.analyzeIntent(ctx, &SQLGenerationRequest{Query: "统计用户总数", Locale: "en"})
+	require.NotEmpty(t, en.Suggestions)
+	for _, suggestion := range en.Suggestions {
+		assert.Equal(t, "en", DetectLocale(suggestion), suggestion)
+	}
+
+	// 分析器缓存的结果不受影响，同一问题按中文返回原建议
+	zh := service.analyzeIntent(ctx, &SQLGenerationRequest{Query: "统计用户总数", Locale: "zh"})
+	require.Len(t, zh.Suggestions, len(en.Suggestions))
+	assert.Equal(t, "zh", DetectLocale(zh.Suggestions[0]))
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// DefaultMaintenanceMessage 未设置说明时返回给用户的提示
+const DefaultMaintenanceMessage = "系统维护中，暂时无法生成或执行查询，历史记录与表结构仍可正常浏览"
+
+const (
+	// maintenanceStateKey 维护状态在Redis中的键
+	maintenanceStateKey = "chat2sql:maintenance"
+	// MaintenanceSyncInterval 各实例从共享存储同步维护状态的间隔，其他实例的修改最多延迟该时间生效
+	MaintenanceSyncInterval = 5 * time.Second
+)
+
+// MaintenanceState 维护模式状态
+type MaintenanceState struct {
+	Enabled   bool       `json:"enabled"`
+	Message   string     `json:"message,omitempty"`
+	ETA       *time.Time `json:"eta,omitempty"`        // 预计恢复时间，为空表示未知
+	StartedAt *time.Time `json:"started_at,omitempty"` // 本次维护开始时间
+	UpdatedBy *int64     `json:"updated_by,omitempty"` // 最近一次修改状态的管理员，由配置开启时为空
+}
+
+// MaintenanceStore 维护状态的共享存储，多实例通过它同步开关
+type MaintenanceStore interface {
+	Load(ctx context.Context) (*MaintenanceState, error) // 从未设置过时返回nil
+	Save(ctx context.Context, state MaintenanceState) error
+}
+
+// MaintenanceMode 只读维护模式开关
+// 设置共享存储后，修改先写入存储再生效，各实例按MaintenanceSyncInterval同步；
+// 存储中没有状态时沿用配置值。未设置存储时状态只在当前进程内有效
+type MaintenanceMode struct {
+	mu     sync.RWMutex
+	state  MaintenanceState
+	store  MaintenanceStore
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewMaintenanceMode 按配置创建维护模式开关，配置为nil时默认关闭
+func NewMaintenanceMode(maintenanceConfig *config.MaintenanceConfig) *MaintenanceMode {
+	m := &MaintenanceMode{now: time.Now}
+	if maintenanceConfig != nil && maintenanceConfig.Enabled {
+		var eta *time.Time
+		if !maintenanceConfig.ETA.IsZero() {
+			eta = &maintenanceConfig.ETA
+		}
+		m.state = m.enabledState(maintenanceConfig.Message, eta, nil)
+	}
+	return m
+}
+
+// SetStore 设置共享存储，需在Sync与修改状态前调用
+func (m *MaintenanceMode) SetStore(store MaintenanceStore, logger *zap.Logger) {
+	m.store = store
+	m.logger = logger
+}
+
+// State 返回当前维护状态，开启时Message不为空
+func (m *MaintenanceMode) State() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Active 判断是否处于维护模式，m为nil时返回false
+func (m *MaintenanceMode) Active() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.Enabled
+}
+
+// Enable 开启维护模式或更新说明与预计恢复时间，已开启时保留原开始时间
+func (m *MaintenanceMode) Enable(ctx context.Context, message string, eta *time.Time, operatorID int64) (MaintenanceState, error) {
+	return m.update(ctx, m.enabledState(message, eta, &operatorID))
+}
+
+// Disable 关闭维护模式
+func (m *MaintenanceMode) Disable(ctx context.Context, operatorID int64) (MaintenanceState, error) {
+	return m.update(ctx, MaintenanceState{UpdatedBy: &operatorID})
+}
+
+// Sync 从共享存储读取最新状态，存储中没有状态时写入当前状态（配置的初始值）
+func (m *MaintenanceMode) Sync(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+	state, err := m.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return m.store.Save(ctx, m.State())
+	}
+
+	m.mu.Lock()
+	m.state = *state
+	m.mu.Unlock()
+	return nil
+}
+
+// Run 按MaintenanceSyncInterval同步共享存储中的状态，由看门狗托管，需先设置共享存储
+func (m *MaintenanceMode) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(MaintenanceSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Sync(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Warn("同步维护状态失败", zap.Error(err))
+		}
+		beat()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// update 写入共享存储后更新当前状态，写入失败时状态不变
+func (m *MaintenanceMode) update(ctx context.Context, state MaintenanceState) (MaintenanceState, error) {
+	if m.store != nil {
+		if err := m.store.Save(ctx, state); err != nil {
+			return m.State(), err
+		}
+	}
+	m.mu.Lock()
+	m.state = state
+	m.mu.Unlock()
+	return state, nil
+}
+
+// enabledState 由当前状态得到开启后的状态
+func (m *MaintenanceMode) enabledState(message string, eta *time.Time, operatorID *int64) MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if message == "" {
+		message = DefaultMaintenanceMessage
+	}
+	startedAt := m.state.StartedAt
+	if !m.state.Enabled {
+		now := m.now()
+		startedAt = &now
+	}
+	return MaintenanceState{
+		Enabled:   true,
+		Message:   message,
+		ETA:       eta,
+		StartedAt: startedAt,
+		UpdatedBy: operatorID,
+	}
+}
+
+// RedisMaintenanceStore 基于Redis的维护状态存储，状态不过期
+type RedisMaintenanceStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisMaintenanceStore 创建维护状态存储
+func NewRedisMaintenanceStore(client redis.UniversalClient) *RedisMaintenanceStore {
+	return &RedisMaintenanceStore{client: client}
+}
+
+// Load 读取维护状态，从未设置过时返回nil
+func (s *RedisMaintenanceStore) Load(ctx context.Context) (*MaintenanceState, error) {
+	data, err := s.client.Get(ctx, maintenanceStateKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取维护状态失败: %w", err)
+	}
+
+	var state MaintenanceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析维护状态失败: %w", err)
+	}
+	return &state, nil
+}
+
+// Save 保存维护状态
+func (s *RedisMaintenanceStore) Save(ctx context.Context, state MaintenanceState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, maintenanceStateKey, data, 0).Err(); err != nil {
+		return fmt.Errorf("保存维护状态失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	ctx := context.Background()
+	mode := NewMaintenanceMode(nil)
+	assert.False(t, mode.Active())
+
+	first, err := mode.Enable(ctx, "", nil, 1)
+	require.NoError(t, err)
+	assert.True(t, mode.Active())
+	assert.Equal(t, DefaultMaintenanceMessage, first.Message)
+	require.NotNil(t, first.StartedAt)
+
+	eta := time.Now().Add(time.Hour)
+	updated, err := mode.Enable(ctx, "元数据迁移中", &eta, 2)
+	require.NoError(t, err)
+	assert.Equal(t, first.StartedAt, updated.StartedAt, "更新说明时保留开始时间")
+	assert.Equal(t, &eta, updated.ETA)
+	assert.Equal(t, int64(2), *updated.UpdatedBy)
+
+	disabled, err := mode.Disable(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, mode.Active())
+	assert.Empty(t, disabled.Message)
+	assert.Nil(t, disabled.StartedAt)
+}
+
+func TestNewMaintenanceMode_FromConfig(t *testing.T) {
+	eta := time.Date(2026, 1, 8, 6, 0, 0, 0, time.UTC)
+	mode := NewMaintenanceMode(&config.MaintenanceConfig{Enabled: true, ETA: eta})
+
+	state := mode.State()
+	assert.True(t, state.Enabled)
+	assert.Equal(t, DefaultMaintenanceMessage, state.Message)
+	require.NotNil(t, state.ETA)
+	assert.True(t, state.ETA.Equal(eta))
+	assert.Nil(t, state.UpdatedBy)
+}
+
+// memoryMaintenanceStore 多个实例共享的内存维护状态存储
+type memoryMaintenanceStore struct {
+	state *MaintenanceState
+}
+
+func (s *memoryMaintenanceStore) Load(ctx context.Context) (*MaintenanceState, error) {
+	return s.state, nil
+}
+
+func (s *memoryMaintenanceStore) Save(ctx context.Context, state MaintenanceState) error {
+	s.state = &state
+	return nil
+}
+
+func TestMaintenanceMode_SharedStore(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryMaintenanceStore{}
+
+	// 存储中没有状态时写入配置的初始值
+	first := NewMaintenanceMode(&config.MaintenanceConfig{Enabled: true})
+	first.SetStore(store, zap.NewNop())
+	require.NoError(t, first.Sync(ctx))
+	require.NotNil(t, store.state)
+	assert.True(t, store.state.Enabled)
+
+	// 其他实例同步后以存储中的状态为准
+	second := NewMaintenanceMode(nil)
+	second.SetStore(store, zap.NewNop())
+	require.NoError(t, second.Sync(ctx))
+	assert.True(t, second.Active())
+
+	_, err := second.Disable(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, first.Active(), "同步前保持原状态")
+	require.NoError(t, first.Sync(ctx))
+	assert.False(t, first.Active())
+
+	// 重启后存储中已有状态，不再使用配置值
+	restarted := NewMaintenanceMode(&config.MaintenanceConfig{Enabled: true})
+	restarted.SetStore(store, zap.NewNop())
+	require.NoError(t, restarted.Sync(ctx))
+	assert.False(t, restarted.Active())
+}
@@ -9,6 +9,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"chat2sql-go/internal/crash"
 	"chat2sql-go/internal/repository"
 )
 
@@ -165,7 +166,7 @@ func (mc *MetadataCache) Start() error {
 	mc.isRunning = true
 
 	// 启动清理例程
-	go mc.cleanupRoutine()
+	go mc.Run(context.Background(), func() {})
 
 	mc.logger.Info("元数据缓存服务已启动",
 		zap.Duration("default_ttl", mc.defaultTTL),
@@ -570,14 +571,18 @@ func (mc *MetadataCache) GetCacheStatistics() *CacheStatistics {
 	}
 }
 
-// cleanupRoutine 清理过期缓存例程
-func (mc *MetadataCache) cleanupRoutine() {
+// Run 运行缓存清理循环直到ctx取消或Stop，每轮清理后调用beat上报心跳
+// 由看门狗托管时注册Run而不是调用Start，心跳的期望间隔为CleanupInterval
+func (mc *MetadataCache) Run(ctx context.Context, beat func()) {
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-mc.stopCh:
 			return
 		case <-mc.cleanupTicker.C:
-			mc.performCleanup()
+			crash.Run("metadata_cache.cleanup", mc.performCleanup)
+			beat()
 		}
 	}
 }
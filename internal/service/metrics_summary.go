@@ -0,0 +1,79 @@
+// 运行指标摘要
+// 为界面状态栏提供QPS、模型生成耗时、当日准确率与AI预算用量，均取自本实例的进程内统计，无需抓取Prometheus
+package service
+
+import (
+	"time"
+
+	"chat2sql-go/internal/ai"
+)
+
+// RequestRateSource 进程内HTTP请求速率
+type RequestRateSource interface {
+	RequestRate() float64
+}
+
+// MetricsSummary 运行指标摘要
+type MetricsSummary struct {
+	QPS               float64  `json:"qps"`                         // 最近一分钟平均每秒请求数
+	AvgGenerationMs   *int64   `json:"avg_generation_ms"`           // 延迟统计窗口内模型调用的平均耗时，无样本时为空
+	GenerationSamples int      `json:"generation_samples"`          // 延迟统计窗口内的模型调用次数
+	AccuracyToday     *float64 `json:"accuracy_today"`              // 当日用户反馈的准确率，无反馈时为空
+	FeedbackToday     int      `json:"feedback_today"`              // 当日用户反馈数
+	BudgetUsed        float64  `json:"budget_used"`                 // 当日全部用户的估算模型成本（美元）
+	BudgetLimit       float64  `json:"budget_limit"`                // 全局每日预算，0表示不限
+	BudgetUsedRatio   *float64 `json:"budget_used_ratio,omitempty"` // 预算使用比例，不限预算时为空
+	Timestamp         string   `json:"timestamp"`
+}
+
+// MetricsSummaryService 汇总各组件的进程内统计，未配置的组件对应字段保持零值
+type MetricsSummaryService struct {
+	requests RequestRateSource
+	latency  *LatencyTracker
+	accuracy *ai.AccuracyMonitor
+	costs    *ai.CostTracker
+	now      func() time.Time
+}
+
+// NewMetricsSummaryService 创建运行指标摘要服务，各数据来源均可为nil
+func NewMetricsSummaryService(requests RequestRateSource, latency *LatencyTracker, accuracy *ai.AccuracyMonitor, costs *ai.CostTracker) *MetricsSummaryService {
+	return &MetricsSummaryService{
+		requests: requests,
+		latency:  latency,
+		accuracy: accuracy,
+		costs:    costs,
+		now:      time.Now,
+	}
+}
+
+// Summary 返回当前的运行指标摘要
+func (s *MetricsSummaryService) Summary() *MetricsSummary {
+	summary := &MetricsSummary{Timestamp: s.now().UTC().Format(time.RFC3339)}
+
+	if s.requests != nil {
+		summary.QPS = s.requests.RequestRate()
+	}
+	if s.latency != nil {
+		mean, samples := s.latency.Mean()
+		summary.GenerationSamples = samples
+		if samples > 0 {
+			ms := mean.Milliseconds()
+			summary.AvgGenerationMs = &ms
+		}
+	}
+	if s.accuracy != nil {
+		accuracy, total := s.accuracy.TodayAccuracy()
+		summary.FeedbackToday = total
+		if total > 0 {
+			summary.AccuracyToday = &accuracy
+		}
+	}
+	if s.costs != nil {
+		summary.BudgetUsed, summary.BudgetLimit = s.costs.DailySpend()
+		if summary.BudgetLimit > 0 {
+			ratio := summary.BudgetUsed / summary.BudgetLimit
+			summary.BudgetUsedRatio = &ratio
+		}
+	}
+	return summary
+}
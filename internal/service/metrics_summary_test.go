@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+)
+
+// fixedRequestRate 固定请求速率
+type fixedRequestRate float64
+
+func (r fixedRequestRate) RequestRate() float64 { return float64(r) }
+
+func TestMetricsSummaryService_Summary(t *testing.T) {
+	// 未配置任何来源时返回零值，可选字段为空
+	empty := NewMetricsSummaryService(nil, nil, nil, nil).Summary()
+	assert.Zero(t, empty.QPS)
+	assert.Nil(t, empty.AvgGenerationMs)
+	assert.Nil(t, empty.AccuracyToday)
+	assert.Nil(t, empty.BudgetUsedRatio)
+
+	latency := NewLatencyTracker(&config.LatencyRoutingConfig{Window: time.Minute, MinSamples: 5, MaxSamples: 20})
+	latency.Observe(ModelPrimary, 200*time.Millisecond)
+	latency.Observe(ModelFallback, 400*time.Millisecond)
+
+	accuracy := ai.NewAccuracyMonitor(ai.DefaultAccuracyConfig(), zap.NewNop())
+	require.NoError(t, accuracy.RecordFeedback(ai.QueryFeedback{QueryID: "q1", UserID: 7, IsCorrect: true, UserRating: 5, Timestamp: time.Now()}))
+	require.NoError(t, accuracy.RecordFeedback(ai.QueryFeedback{QueryID: "q2", UserID: 7, IsCorrect: false, UserRating: 2, Timestamp: time.Now()}))
+
+	costs := ai.NewCostTracker(ai.CostConfigFromBudget(config.BudgetConfig{DailyLimit: 10, UserLimit: 1}))
+	require.NoError(t, costs.RecordUsage(7, "openai", "gpt-4o", "q1", 1000, 1000))
+
+	summary := NewMetricsSummaryService(fixedRequestRate(2.5), latency, accuracy, costs).Summary()
+	assert.Equal(t, 2.5, summary.QPS)
+	require.NotNil(t, summary.AvgGenerationMs)
+	assert.Equal(t, int64(300), *summary.AvgGenerationMs, "主备模型样本合并平均")
+	assert.Equal(t, 2, summary.GenerationSamples)
+	require.NotNil(t, summary.AccuracyToday)
+	assert.Equal(t, 0.5, *summary.AccuracyToday)
+	assert.Equal(t, 2, summary.FeedbackToday)
+	assert.InDelta(t, 0.075, summary.BudgetUsed, 1e-9)
+	assert.Equal(t, 10.0, summary.BudgetLimit)
+	require.NotNil(t, summary.BudgetUsedRatio)
+	assert.InDelta(t, 0.0075, *summary.BudgetUsedRatio, 1e-9)
+}
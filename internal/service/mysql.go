@@ -0,0 +1,409 @@
+// MySQL连接支持
+// MySQL连接通过database/sql与go-sql-driver/mysql访问，查询在只读事务中执行，
+// 写模式（预演与执行INSERT/UPDATE）依赖PostgreSQL的事务回滚预演，暂不支持MySQL连接
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// ErrUnsupportedDBType 连接的数据库类型不支持该操作
+var ErrUnsupportedDBType = errors.New("不支持的数据库类型")
+
+// mysqlBinaryTypes 按原始字节返回的MySQL列类型，其余列的[]byte值转换为字符串
+var mysqlBinaryTypes = map[string]bool{
+	"BINARY": true, "VARBINARY": true, "BLOB": true, "TINYBLOB": true,
+	"MEDIUMBLOB": true, "LONGBLOB": true, "GEOMETRY": true, "BIT": true,
+}
+
+// buildMySQLConfig 构建MySQL连接配置，服务端支持时使用TLS（与PostgreSQL的sslmode=prefer一致）
+func (cm *ConnectionManager) buildMySQLConfig(conn *repository.DatabaseConnection, password string) *mysql.Config {
+	config := mysql.NewConfig()
+	config.User = conn.Username
+	config.Passwd = password
+	config.Net = "tcp"
+	config.Addr = net.JoinHostPort(conn.Host, strconv.Itoa(int(conn.Port)))
+	config.DBName = conn.DatabaseName
+	config.Timeout = cm.connectionTimeout
+	config.ParseTime = true
+	config.Loc = time.UTC
+	config.TLSConfig = "preferred"
+	return config
+}
+
+// openMySQLDB 创建MySQL连接池并测试连接
+func (cm *ConnectionManager) openMySQLDB(ctx context.Context, connection *repository.DatabaseConnection, password string) (*sql.DB, error) {
+	connector, err := mysql.NewConnector(cm.buildMySQLConfig(connection, password))
+	if err != nil {
+		return nil, fmt.Errorf("解析连接配置失败: %w", err)
+	}
+
+	// 连接池参数与PostgreSQL连接池一致
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(2)
+	db.SetConnMaxLifetime(1 * time.Hour)
+	db.SetConnMaxIdleTime(15 * time.Minute)
+
+	testCtx, cancel := context.WithTimeout(ctx, cm.connectionTimeout)
+	defer cancel()
+
+	if err := db.PingContext(testCtx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接测试失败: %w", err)
+	}
+	return db, nil
+}
+
+// testMySQLConnection 使用明文密码直接测试MySQL连接
+func (cm *ConnectionManager) testMySQLConnection(ctx context.Context, connection *repository.DatabaseConnection, plainPassword string) error {
+	connector, err := mysql.NewConnector(cm.buildMySQLConfig(connection, plainPassword))
+	if err != nil {
+		return fmt.Errorf("创建测试连接失败: %w", err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	testCtx, cancel := context.WithTimeout(ctx, cm.connectionTimeout)
+	defer cancel()
+
+	var result int
+	if err := db.QueryRowContext(testCtx, "SELECT 1").Scan(&result); err != nil {
+		return fmt.Errorf("连接测试查询失败: %w", err)
+	}
+	if result != 1 {
+		return fmt.Errorf("连接测试返回异常结果: %d", result)
+	}
+	return nil
+}
+
+// mysqlValue 文本协议下DECIMAL、字符串等列以[]byte返回，除二进制列外转换为字符串
+func mysqlValue(value any, columnType *sql.ColumnType) any {
+	raw, ok := value.([]byte)
+	if !ok || mysqlBinaryTypes[strings.ToUpper(columnType.DatabaseTypeName())] {
+		return value
+	}
+	return string(raw)
+}
+
+// estimateMySQLCost 通过EXPLAIN FORMAT=JSON估算MySQL查询代价
+// MySQL的代价单位与PostgreSQL不同，工作空间的代价上限需按连接类型分别设置
+func (e *SQLExecutor) estimateMySQLCost(ctx context.Context, query string, db *sql.DB) (float64, error) {
+	var plan []byte
+	if err := db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query, queryArgs(ctx)...).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("获取执行计划失败: %w", err)
+	}
+	return parseMySQLExplainCost(plan)
+}
+
+// parseMySQLExplainCost 从EXPLAIN FORMAT=JSON输出中读取query_block的query_cost
+func parseMySQLExplainCost(plan []byte) (float64, error) {
+	var explain struct {
+		QueryBlock *struct {
+			CostInfo *struct {
+				QueryCost string `json:"query_cost"`
+			} `json:"cost_info"`
+		} `json:"query_block"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return 0, fmt.Errorf("解析执行计划失败: %w", err)
+	}
+	if explain.QueryBlock == nil || explain.QueryBlock.CostInfo == nil {
+		return 0, fmt.Errorf("执行计划中没有代价信息")
+	}
+	cost, err := strconv.ParseFloat(explain.QueryBlock.CostInfo.QueryCost, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析执行计划代价失败: %w", err)
+	}
+	return cost, nil
+}
+
+// introspectMySQL 探测MySQL连接所在数据库的表结构，数据库名作为schema名
+func (si *SchemaIntrospector) introspectMySQL(ctx context.Context, db *sql.DB) ([]SchemaInfo, error) {
+	var database sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database); err != nil {
+		return nil, fmt.Errorf("查询当前数据库失败: %w", err)
+	}
+	if database.String == "" {
+		return nil, errors.New("MySQL连接未指定数据库")
+	}
+	schemaName := database.String
+
+	tables, err := si.getMySQLTables(ctx, db, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("获取表列表失败: %w", err)
+	}
+	if len(tables) > si.maxTablesPerSchema {
+		si.logger.Warn("Schema中表数量超过限制，将截断",
+			zap.String("schema", schemaName),
+			zap.Int("total_tables", len(tables)),
+			zap.Int("max_tables", si.maxTablesPerSchema))
+		tables = tables[:si.maxTablesPerSchema]
+	}
+
+	columns, err := si.getMySQLColumns(ctx, db, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("获取列信息失败: %w", err)
+	}
+
+	// 索引与约束信息获取失败不是致命错误
+	var indexes map[string][]IndexInfo
+	if si.enableIndexInfo {
+		if indexes, err = si.getMySQLIndexes(ctx, db, schemaName); err != nil {
+			si.logger.Warn("查询索引信息失败，跳过索引信息收集", zap.String("schema", schemaName), zap.Error(err))
+		}
+	}
+	var constraints map[string][]Constraint
+	if si.enableConstraintInfo {
+		if constraints, err = si.getMySQLConstraints(ctx, db, schemaName); err != nil {
+			si.logger.Warn("查询约束信息失败，跳过约束信息收集", zap.String("schema", schemaName), zap.Error(err))
+		}
+	}
+
+	for i := range tables {
+		table := &tables[i]
+		table.Columns = columns[table.TableName]
+		table.ColumnCount = len(table.Columns)
+		table.Indexes = indexes[table.TableName]
+		table.Constraints = constraints[table.TableName]
+	}
+
+	return []SchemaInfo{{
+		SchemaName: schemaName,
+		Tables:     tables,
+		TableCount: len(tables),
+	}}, nil
+}
+
+// getMySQLTables 获取MySQL数据库中的表，TABLE_ROWS为InnoDB的统计估计值
+func (si *SchemaIntrospector) getMySQLTables(ctx context.Context, db *sql.DB, schemaName string) ([]TableInfo, error) {
+	query := `
+		SELECT TABLE_NAME, TABLE_TYPE, TABLE_COMMENT, TABLE_ROWS
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+	`
+
+	rows, err := db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []TableInfo
+	for rows.Next() {
+		table := TableInfo{SchemaName: schemaName}
+		var comment sql.NullString
+		var estimatedRows sql.NullInt64
+		if err := rows.Scan(&table.TableName, &table.TableType, &comment, &estimatedRows); err != nil {
+			return nil, fmt.Errorf("扫描表信息失败: %w", err)
+		}
+		if comment.String != "" {
+			table.TableComment = &comment.String
+		}
+		if estimatedRows.Valid {
+			table.EstimatedRows = &estimatedRows.Int64
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// getMySQLColumns 获取MySQL数据库中所有表的列信息，按表名分组
+func (si *SchemaIntrospector) getMySQLColumns(ctx context.Context, db *sql.DB, schemaName string) (map[string][]ColumnInfo, error) {
+	query := `
+		SELECT
+			c.TABLE_NAME,
+			c.COLUMN_NAME,
+			c.DATA_TYPE,
+			c.IS_NULLABLE = 'YES',
+			c.COLUMN_DEFAULT,
+			c.ORDINAL_POSITION,
+			c.CHARACTER_MAXIMUM_LENGTH,
+			c.NUMERIC_PRECISION,
+			c.NUMERIC_SCALE,
+			c.COLUMN_COMMENT,
+			c.COLUMN_KEY = 'PRI',
+			k.REFERENCED_TABLE_NAME,
+			k.REFERENCED_COLUMN_NAME
+		FROM information_schema.COLUMNS c
+		LEFT JOIN information_schema.KEY_COLUMN_USAGE k
+			ON k.TABLE_SCHEMA = c.TABLE_SCHEMA
+			AND k.TABLE_NAME = c.TABLE_NAME
+			AND k.COLUMN_NAME = c.COLUMN_NAME
+			AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		WHERE c.TABLE_SCHEMA = ?
+		ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION
+	`
+
+	rows, err := db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("查询列信息失败: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]ColumnInfo)
+	for rows.Next() {
+		var tableName string
+		var col ColumnInfo
+		var columnDefault, comment, foreignTable, foreignColumn sql.NullString
+		var maxLength, precision, scale sql.NullInt64
+		if err := rows.Scan(
+			&tableName,
+			&col.ColumnName,
+			&col.DataType,
+			&col.IsNullable,
+			&columnDefault,
+			&col.OrdinalPosition,
+			&maxLength,
+			&precision,
+			&scale,
+			&comment,
+			&col.IsPrimaryKey,
+			&foreignTable,
+			&foreignColumn,
+		); err != nil {
+			return nil, fmt.Errorf("扫描列信息失败: %w", err)
+		}
+
+		// 同一列属于多个外键时只保留第一个
+		existing := columns[tableName]
+		if n := len(existing); n > 0 && existing[n-1].OrdinalPosition == col.OrdinalPosition {
+			continue
+		}
+
+		if columnDefault.Valid {
+			col.ColumnDefault = &columnDefault.String
+		}
+		if comment.String != "" {
+			col.ColumnComment = &comment.String
+		}
+		if foreignTable.Valid {
+			col.IsForeignKey = true
+			col.ForeignTable = &foreignTable.String
+			col.ForeignColumn = &foreignColumn.String
+		}
+		col.CharacterMaxLength = clampInt32(maxLength)
+		col.NumericPrecision = clampInt32(precision)
+		col.NumericScale = clampInt32(scale)
+
+		columns[tableName] = append(existing, col)
+	}
+	return columns, rows.Err()
+}
+
+// getMySQLIndexes 获取MySQL数据库中所有表的索引信息，按表名分组
+func (si *SchemaIntrospector) getMySQLIndexes(ctx context.Context, db *sql.DB, schemaName string) (map[string][]IndexInfo, error) {
+	query := `
+		SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE = 0, INDEX_TYPE, COLUMN_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX
+	`
+
+	rows, err := db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][]IndexInfo)
+	for rows.Next() {
+		var tableName, indexName, method string
+		var unique bool
+		var column sql.NullString // 函数索引没有列名
+		if err := rows.Scan(&tableName, &indexName, &unique, &method, &column); err != nil {
+			return nil, err
+		}
+
+		tableIndexes := indexes[tableName]
+		if n := len(tableIndexes); n == 0 || tableIndexes[n-1].IndexName != indexName {
+			index := IndexInfo{IndexName: indexName, IndexType: "INDEX", IsUnique: unique, IsPrimary: indexName == "PRIMARY"}
+			switch {
+			case index.IsPrimary:
+				index.IndexType = "PRIMARY"
+			case unique:
+				index.IndexType = "UNIQUE"
+			}
+			tableIndexes = append(tableIndexes, index)
+		}
+		if column.Valid {
+			last := &tableIndexes[len(tableIndexes)-1]
+			last.Columns = append(last.Columns, column.String)
+		}
+		indexes[tableName] = tableIndexes
+	}
+	return indexes, rows.Err()
+}
+
+// getMySQLConstraints 获取MySQL数据库中所有表的主键、唯一与外键约束，按表名分组
+func (si *SchemaIntrospector) getMySQLConstraints(ctx context.Context, db *sql.DB, schemaName string) (map[string][]Constraint, error) {
+	query := `
+		SELECT tc.TABLE_NAME, tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE,
+			k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME
+		FROM information_schema.TABLE_CONSTRAINTS tc
+		JOIN information_schema.KEY_COLUMN_USAGE k
+			ON k.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+			AND k.TABLE_NAME = tc.TABLE_NAME
+			AND k.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		WHERE tc.TABLE_SCHEMA = ?
+		ORDER BY tc.TABLE_NAME, tc.CONSTRAINT_NAME, k.ORDINAL_POSITION
+	`
+
+	rows, err := db.QueryContext(ctx, query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	constraints := make(map[string][]Constraint)
+	for rows.Next() {
+		var tableName, name, constraintType, column string
+		var refTable, refColumn sql.NullString
+		if err := rows.Scan(&tableName, &name, &constraintType, &column, &refTable, &refColumn); err != nil {
+			return nil, err
+		}
+
+		tableConstraints := constraints[tableName]
+		if n := len(tableConstraints); n == 0 || tableConstraints[n-1].ConstraintName != name {
+			constraint := Constraint{ConstraintName: name, ConstraintType: constraintType}
+			if refTable.Valid {
+				constraint.RefTable = &refTable.String
+			}
+			tableConstraints = append(tableConstraints, constraint)
+		}
+		last := &tableConstraints[len(tableConstraints)-1]
+		last.Columns = append(last.Columns, column)
+		if refColumn.Valid {
+			last.RefColumns = append(last.RefColumns, refColumn.String)
+		}
+		constraints[tableName] = tableConstraints
+	}
+	return constraints, rows.Err()
+}
+
+// clampInt32 将可空的整数转换为int32指针，超出范围时取int32最大值（如LONGTEXT的最大长度）
+func clampInt32(v sql.NullInt64) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	n := int32(math.MaxInt32)
+	if v.Int64 < math.MaxInt32 {
+		n = int32(v.Int64)
+	}
+	return &n
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+func TestParseMySQLExplainCost(t *testing.T) {
+	cost, err := parseMySQLExplainCost([]byte(`{"query_block":{"select_id":1,"cost_info":{"query_cost":"1204.50"},"table":{"table_name":"orders"}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1204.5, cost)
+
+	_, err = parseMySQLExplainCost([]byte(`{"query_block":{"select_id":1}}`))
+	assert.Error(t, err)
+
+	_, err = parseMySQLExplainCost([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestBuildMySQLConfig(t *testing.T) {
+	cm := &ConnectionManager{connectionTimeout: 5 * time.Second}
+	config := cm.buildMySQLConfig(&repository.DatabaseConnection{
+		Host:         "db.internal",
+		Port:         3306,
+		DatabaseName: "shop",
+		Username:     "reader",
+	}, "p@ss")
+
+	assert.Equal(t, "db.internal:3306", config.Addr)
+	assert.Equal(t, "p@ss", config.Passwd)
+	assert.True(t, config.ParseTime)
+	assert.Equal(t, time.UTC, config.Loc)
+
+	dsn := config.FormatDSN()
+	assert.Contains(t, dsn, "reader:p@ss@tcp(db.internal:3306)/shop")
+	assert.Contains(t, dsn, "tls=preferred")
+	assert.Contains(t, dsn, "timeout=5s")
+}
+
+func TestConnectionDBType(t *testing.T) {
+	assert.Equal(t, repository.DBTypePostgreSQL, connectionDBType(&repository.DatabaseConnection{}))
+	assert.Equal(t, repository.DBTypeMySQL, connectionDBType(&repository.DatabaseConnection{DBType: "mysql"}))
+
+	cm := &ConnectionManager{connectionTimeout: time.Second, logger: zaptest.NewLogger(t)}
+	err := cm.testConnectionDirect(context.Background(), &repository.DatabaseConnection{DBType: "oracle"}, "secret")
+	assert.ErrorIs(t, err, ErrUnsupportedDBType)
+}
+
+func TestClampInt32(t *testing.T) {
+	assert.Nil(t, clampInt32(sql.NullInt64{}))
+	assert.Equal(t, int32(255), *clampInt32(sql.NullInt64{Int64: 255, Valid: true}))
+	// LONGTEXT的最大长度超出int32
+	assert.Equal(t, int32(math.MaxInt32), *clampInt32(sql.NullInt64{Int64: 4294967295, Valid: true}))
+}
+
+type stubDBTypeResolver map[int64]repository.DatabaseType
+
+func (s stubDBTypeResolver) GetDBType(ctx context.Context, connectionID int64) (repository.DatabaseType, error) {
+	if dbType, ok := s[connectionID]; ok {
+		return dbType, nil
+	}
+	return "", repository.ErrNotFound
+}
+
+func TestAIService_MySQLDialect(t *testing.T) {
+	ai := &AIService{logger: zaptest.NewLogger(t)}
+	ai.SetDBTypeResolver(stubDBTypeResolver{1: repository.DBTypeMySQL})
+
+	req := &SQLGenerationRequest{Query: "最近10笔订单", ConnectionID: 1}
+	ai.resolveDialect(context.Background(), req)
+	assert.Equal(t, repository.DBTypeMySQL, req.Dialect)
+
+	prompt, err := ai.buildPrompt(req)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "生成准确的MySQL查询语句")
+	assert.Contains(t, prompt, "使用MySQL 8语法")
+	assert.NotContains(t, prompt, "PostgreSQL")
+
+	// 解析失败时按PostgreSQL生成
+	req = &SQLGenerationRequest{Query: "最近10笔订单", ConnectionID: 2}
+	ai.resolveDialect(context.Background(), req)
+	assert.Empty(t, req.Dialect)
+	prompt, err = ai.buildPrompt(req)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "使用PostgreSQL 17语法")
+}
@@ -0,0 +1,156 @@
+// 工作空间新手引导清单
+// 各步骤是否完成全部由已有数据推导，不单独记录进度：任一成员添加过连接、连接已同步表结构、
+// 执行过查询、数据字典中有表或列注释、提交过反馈，即视为该步骤完成
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// OnboardingStep 新手引导步骤
+type OnboardingStep string
+
+const (
+	OnboardingConnectionAdded OnboardingStep = "connection_added" // 添加数据库连接
+	OnboardingSchemaSynced    OnboardingStep = "schema_synced"    // 连接表结构已同步
+	OnboardingFirstQuery      OnboardingStep = "first_query_run"  // 执行第一条查询
+	OnboardingGlossarySeeded  OnboardingStep = "glossary_seeded"  // 数据字典中有表或列注释
+	OnboardingFeedbackGiven   OnboardingStep = "feedback_given"   // 对生成的SQL提交过反馈
+)
+
+// onboardingSteps 清单中步骤的顺序与说明
+var onboardingSteps = []struct {
+	step  OnboardingStep
+	title string
+}{
+	{OnboardingConnectionAdded, "添加数据库连接"},
+	{OnboardingSchemaSynced, "同步连接的表结构"},
+	{OnboardingFirstQuery, "执行第一条查询"},
+	{OnboardingGlossarySeeded, "为表和列补充注释"},
+	{OnboardingFeedbackGiven, "对生成的SQL提交反馈"},
+}
+
+// OnboardingItem 清单中的一个步骤
+type OnboardingItem struct {
+	Step  OnboardingStep `json:"step"`
+	Title string         `json:"title"`
+	Done  bool           `json:"done"`
+}
+
+// OnboardingChecklist 工作空间新手引导清单
+type OnboardingChecklist struct {
+	WorkspaceID int64            `json:"workspace_id"`
+	Steps       []OnboardingItem `json:"steps"`
+	Completed   int              `json:"completed"` // 已完成的步骤数
+	Total       int              `json:"total"`
+}
+
+// OnboardingService 新手引导清单服务
+type OnboardingService struct {
+	workspaces  repository.WorkspaceRepository
+	connections repository.ConnectionRepository
+	schemas     repository.SchemaRepository
+	queries     repository.QueryHistoryRepository
+	feedback    repository.FeedbackRepository
+	logger      *zap.Logger
+}
+
+// NewOnboardingService 创建新手引导清单服务
+func NewOnboardingService(
+	workspaces repository.WorkspaceRepository,
+	connections repository.ConnectionRepository,
+	schemas repository.SchemaRepository,
+	queries repository.QueryHistoryRepository,
+	feedback repository.FeedbackRepository,
+	logger *zap.Logger,
+) *OnboardingService {
+	return &OnboardingService{
+		workspaces:  workspaces,
+		connections: connections,
+		schemas:     schemas,
+		queries:     queries,
+		feedback:    feedback,
+		logger:      logger,
+	}
+}
+
+// Checklist 计算用户所属工作空间的新手引导清单，已确认完成的步骤不再查询
+func (s *OnboardingService) Checklist(ctx context.Context, userID int64) (*OnboardingChecklist, error) {
+	workspace, err := s.workspaces.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取工作空间失败: %w", err)
+	}
+	members, err := s.workspaces.ListMemberIDs(ctx, workspace.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询工作空间成员失败: %w", err)
+	}
+
+	done := make(map[OnboardingStep]bool, len(onboardingSteps))
+	var connections []*repository.DatabaseConnection
+	for _, memberID := range members {
+		owned, err := s.connections.ListByUser(ctx, memberID)
+		if err != nil {
+			return nil, fmt.Errorf("查询成员连接失败: %w", err)
+		}
+		connections = append(connections, owned...)
+
+		if !done[OnboardingFirstQuery] {
+			count, err := s.queries.CountByUser(ctx, memberID)
+			if err != nil {
+				return nil, fmt.Errorf("统计成员查询失败: %w", err)
+			}
+			done[OnboardingFirstQuery] = count > 0
+		}
+		if !done[OnboardingFeedbackGiven] {
+			feedback, err := s.feedback.ListByUser(ctx, memberID, 1, 0)
+			if err != nil {
+				return nil, fmt.Errorf("查询成员反馈失败: %w", err)
+			}
+			done[OnboardingFeedbackGiven] = len(feedback) > 0
+		}
+	}
+	done[OnboardingConnectionAdded] = len(connections) > 0
+
+	for _, conn := range connections {
+		if done[OnboardingSchemaSynced] && done[OnboardingGlossarySeeded] {
+			break
+		}
+		metadata, err := s.schemas.ListByConnection(ctx, conn.ID)
+		if err != nil {
+			return nil, fmt.Errorf("查询连接元数据失败: %w", err)
+		}
+		if len(metadata) > 0 {
+			done[OnboardingSchemaSynced] = true
+		}
+		if !done[OnboardingGlossarySeeded] {
+			done[OnboardingGlossarySeeded] = hasSchemaComment(metadata)
+		}
+	}
+
+	checklist := &OnboardingChecklist{WorkspaceID: workspace.ID, Total: len(onboardingSteps)}
+	for _, item := range onboardingSteps {
+		checklist.Steps = append(checklist.Steps, OnboardingItem{Step: item.step, Title: item.title, Done: done[item.step]})
+		if done[item.step] {
+			checklist.Completed++
+		}
+	}
+	return checklist, nil
+}
+
+// hasSchemaComment 判断元数据中是否有非空的表或列注释
+func hasSchemaComment(metadata []*repository.SchemaMetadata) bool {
+	for _, m := range metadata {
+		if (m.TableComment != nil && strings.TrimSpace(*m.TableComment) != "") ||
+			(m.ColumnComment != nil && strings.TrimSpace(*m.ColumnComment) != "") {
+			return true
+		}
+	}
+	return false
+}
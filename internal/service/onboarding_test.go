@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// onboardingWorkspaceRepository 返回固定工作空间与成员
+type onboardingWorkspaceRepository struct {
+	stubWorkspaceRepository
+	members []int64
+}
+
+func (r *onboardingWorkspaceRepository) ListMemberIDs(ctx context.Context, workspaceID int64) ([]int64, error) {
+	return r.members, nil
+}
+
+// ownedConnectionRepository 按所有者返回连接
+type ownedConnectionRepository struct {
+	repository.ConnectionRepository
+	owned map[int64][]int64
+}
+
+func (r *ownedConnectionRepository) ListByUser(ctx context.Context, userID int64) ([]*repository.DatabaseConnection, error) {
+	var connections []*repository.DatabaseConnection
+	for _, id := range r.owned[userID] {
+		connections = append(connections, testutil.NewConnection(id, userID))
+	}
+	return connections, nil
+}
+
+// countingQueryRepository 按用户返回查询数
+type countingQueryRepository struct {
+	repository.QueryHistoryRepository
+	counts map[int64]int64
+}
+
+func (r *countingQueryRepository) CountByUser(ctx context.Context, userID int64) (int64, error) {
+	return r.counts[userID], nil
+}
+
+// userFeedbackRepository 按用户返回反馈
+type userFeedbackRepository struct {
+	repository.FeedbackRepository
+	users map[int64]bool
+}
+
+func (r *userFeedbackRepository) ListByUser(ctx context.Context, userID int64, limit, offset int) ([]*repository.Feedback, error) {
+	if r.users[userID] {
+		return []*repository.Feedback{{UserID: userID}}, nil
+	}
+	return nil, nil
+}
+
+func TestOnboardingService_Checklist(t *testing.T) {
+	workspace := &repository.Workspace{Name: "analytics"}
+	workspace.ID = 5
+	workspaces := &onboardingWorkspaceRepository{stubWorkspaceRepository: stubWorkspaceRepository{workspace: workspace}, members: []int64{1, 2}}
+	connections := &ownedConnectionRepository{owned: map[int64][]int64{}}
+	schemas := &memSchemaRepository{metadata: map[int64][]*repository.SchemaMetadata{}}
+	queries := &countingQueryRepository{counts: map[int64]int64{}}
+	feedback := &userFeedbackRepository{users: map[int64]bool{}}
+	svc := NewOnboardingService(workspaces, connections, schemas, queries, feedback, zaptest.NewLogger(t))
+
+	steps := func() map[OnboardingStep]bool {
+		checklist, err := svc.Checklist(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), checklist.WorkspaceID)
+		require.Len(t, checklist.Steps, checklist.Total)
+		done := map[OnboardingStep]bool{}
+		completed := 0
+		for _, item := range checklist.Steps {
+			assert.NotEmpty(t, item.Title)
+			done[item.Step] = item.Done
+			if item.Done {
+				completed++
+			}
+		}
+		assert.Equal(t, completed, checklist.Completed)
+		return done
+	}
+
+	assert.Equal(t, map[OnboardingStep]bool{
+		OnboardingConnectionAdded: false, OnboardingSchemaSynced: false, OnboardingFirstQuery: false,
+		OnboardingGlossarySeeded: false, OnboardingFeedbackGiven: false,
+	}, steps(), "新工作空间所有步骤未完成")
+
+	// 其他成员完成的步骤同样计入工作空间
+	connections.owned[2] = []int64{10}
+	schemas.metadata[10] = schemaMetadataList(testDatabaseSchema(10, map[string][]ColumnInfo{"orders": {{ColumnName: "id", DataType: "bigint"}}}))
+	queries.counts[2] = 3
+	done := steps()
+	assert.True(t, done[OnboardingConnectionAdded])
+	assert.True(t, done[OnboardingSchemaSynced])
+	assert.True(t, done[OnboardingFirstQuery])
+	assert.False(t, done[OnboardingGlossarySeeded], "没有注释的表结构不算数据字典")
+	assert.False(t, done[OnboardingFeedbackGiven])
+
+	comment := "订单金额"
+	schemas.metadata[10] = schemaMetadataList(testDatabaseSchema(10, map[string][]ColumnInfo{"orders": {{ColumnName: "amount", DataType: "numeric", ColumnComment: &comment}}}))
+	feedback.users[1] = true
+	done = steps()
+	assert.True(t, done[OnboardingGlossarySeeded])
+	assert.True(t, done[OnboardingFeedbackGiven])
+}
@@ -0,0 +1,206 @@
+// 模型响应缓存
+// 温度为0的调用输出确定，按(提供商, 模型, 生成参数, 提示词)的哈希缓存模型响应，TTL内相同请求直接返回缓存的响应，不再调用提供商。
+// 响应保存在Redis中，多实例共享；请求头X-Prompt-Cache可跳过缓存，便于排查模型输出
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// PromptCacheHeader 控制模型响应缓存的请求头：no-cache不读取缓存，新响应仍写入缓存；no-store不读取也不写入
+const PromptCacheHeader = "X-Prompt-Cache"
+
+// errPromptCacheMiss 缓存中没有该响应
+var errPromptCacheMiss = errors.New("prompt cache miss")
+
+// PromptCacheOptions 单次请求的模型响应缓存控制
+type PromptCacheOptions struct {
+	NoCache bool // 不读取缓存，新响应仍写入缓存
+	NoStore bool // 不读取也不写入缓存
+}
+
+// ParsePromptCacheHeader 解析PromptCacheHeader的取值，无法识别的取值按未设置处理
+func ParsePromptCacheHeader(value string) PromptCacheOptions {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "no-cache":
+		return PromptCacheOptions{NoCache: true}
+	case "no-store":
+		return PromptCacheOptions{NoStore: true}
+	}
+	return PromptCacheOptions{}
+}
+
+// promptCacheOptionsKey 模型响应缓存控制的context键
+type promptCacheOptionsKey struct{}
+
+// WithPromptCacheOptions 设置本次请求的模型响应缓存控制
+func WithPromptCacheOptions(ctx context.Context, opts PromptCacheOptions) context.Context {
+	return context.WithValue(ctx, promptCacheOptionsKey{}, opts)
+}
+
+// promptCacheOptionsFromContext 读取本次请求的模型响应缓存控制，未设置时读写缓存
+func promptCacheOptionsFromContext(ctx context.Context) PromptCacheOptions {
+	opts, _ := ctx.Value(promptCacheOptionsKey{}).(PromptCacheOptions)
+	return opts
+}
+
+// promptCacheEntry Redis中保存的缓存项
+type promptCacheEntry struct {
+	Content    string    `json:"content"`
+	StopReason string    `json:"stop_reason,omitempty"`
+	CachedAt   time.Time `json:"cached_at"`
+}
+
+// promptCacheStore 模型响应缓存的存储，由Redis实现
+type promptCacheStore interface {
+	// Get 读取缓存项，不存在时返回errPromptCacheMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set 写入缓存项
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// PromptCache 模型响应缓存
+type PromptCache struct {
+	store  promptCacheStore
+	config *config.PromptCacheConfig
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewPromptCache 创建基于Redis的模型响应缓存，配置为nil时使用默认配置
+func NewPromptCache(client redis.UniversalClient, cacheConfig *config.PromptCacheConfig, logger *zap.Logger) *PromptCache {
+	return newPromptCache(&redisPromptCacheStore{client: client}, cacheConfig, logger)
+}
+
+func newPromptCache(store promptCacheStore, cacheConfig *config.PromptCacheConfig, logger *zap.Logger) *PromptCache {
+	if cacheConfig == nil {
+		cacheConfig = config.DefaultPromptCacheConfig()
+	}
+	return &PromptCache{
+		store:  store,
+		config: cacheConfig,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Lookup 查找缓存的响应，读取失败按未命中处理，只记录日志
+func (c *PromptCache) Lookup(ctx context.Context, key string) (*llms.ContentChoice, bool) {
+	data, err := c.store.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, errPromptCacheMiss) {
+			c.logger.Warn("Failed to read cached model response", zap.Error(err))
+		}
+		return nil, false
+	}
+
+	var entry promptCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Content == "" {
+		c.logger.Warn("Discarding malformed cached model response", zap.Error(err))
+		return nil, false
+	}
+	return &llms.ContentChoice{Content: entry.Content, StopReason: entry.StopReason}, true
+}
+
+// Store 缓存模型响应，空响应与超过大小上限的响应不缓存，返回响应是否已写入缓存
+func (c *PromptCache) Store(ctx context.Context, key string, choice *llms.ContentChoice) bool {
+	if choice == nil || choice.Content == "" {
+		return false
+	}
+
+	data, err := json.Marshal(promptCacheEntry{Content: choice.Content, StopReason: choice.StopReason, CachedAt: c.now()})
+	if err != nil {
+		c.logger.Warn("Failed to encode model response for cache", zap.Error(err))
+		return false
+	}
+	if len(data) > c.config.MaxEntryBytes {
+		c.logger.Debug("Model response too large to cache", zap.Int("bytes", len(data)))
+		return false
+	}
+	if err := c.store.Set(ctx, key, data, c.config.TTL); err != nil {
+		c.logger.Warn("Failed to cache model response", zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// promptCacheKey 缓存键：温度为0时按提供商、模型、影响输出的生成参数与提示词计算哈希，温度非0时不可缓存
+func promptCacheKey(cfg config.ModelConfig, profile *generationProfile, prompt string) (string, bool) {
+	if cfg.Temperature != 0 {
+		return "", false
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%d\x00", cfg.Provider, cfg.ModelName, cfg.MaxTokens, cfg.Seed)
+	if profile != nil && profile.override.TopP != nil {
+		fmt.Fprintf(h, "%g", *profile.override.TopP)
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(prompt))
+	return "prompt_cache:" + hex.EncodeToString(h.Sum(nil)), true
+}
+
+// SetPromptCache 设置模型响应缓存，为nil时每次都调用提供商
+func (ai *AIService) SetPromptCache(cache *PromptCache) {
+	ai.promptCache = cache
+}
+
+// lookupPromptCache 返回本次调用的缓存键与命中的响应
+// 不可缓存或请求要求no-store时缓存键为空，调用后不写入缓存；no-cache时只返回缓存键
+func (ai *AIService) lookupPromptCache(ctx context.Context, cfg config.ModelConfig, profile *generationProfile, prompt string) (string, *llms.ContentChoice) {
+	if ai.promptCache == nil {
+		return "", nil
+	}
+	opts := promptCacheOptionsFromContext(ctx)
+	key, ok := promptCacheKey(cfg, profile, prompt)
+	if !ok || opts.NoStore {
+		return "", nil
+	}
+	if opts.NoCache {
+		return key, nil
+	}
+	choice, hit := ai.promptCache.Lookup(ctx, key)
+	if !hit {
+		return key, nil
+	}
+	ai.logger.Debug("命中模型响应缓存", zap.String("provider", cfg.Provider), zap.String("model", cfg.ModelName))
+	return key, choice
+}
+
+// storePromptCache 写入模型响应，缓存键为空时不写入
+func (ai *AIService) storePromptCache(ctx context.Context, key string, response *llms.ContentResponse) {
+	if key == "" || len(response.Choices) == 0 {
+		return
+	}
+	ai.promptCache.Store(ctx, key, response.Choices[0])
+}
+
+// redisPromptCacheStore 基于Redis的模型响应缓存存储
+type redisPromptCacheStore struct {
+	client redis.UniversalClient
+}
+
+func (s *redisPromptCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errPromptCacheMiss
+	}
+	return data, err
+}
+
+func (s *redisPromptCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+)
+
+// memPromptCacheStore 内存模型响应缓存存储，记录TTL
+type memPromptCacheStore struct {
+	entries map[string][]byte
+	ttls    map[string]time.Duration
+}
+
+func newMemPromptCacheStore() *memPromptCacheStore {
+	return &memPromptCacheStore{entries: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (s *memPromptCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, ok := s.entries[key]
+	if !ok {
+		return nil, errPromptCacheMiss
+	}
+	return data, nil
+}
+
+func (s *memPromptCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.entries[key] = value
+	s.ttls[key] = ttl
+	return nil
+}
+
+func TestAIService_PromptCache(t *testing.T) {
+	aiConfig := config.DemoAIConfig("")
+	aiConfig.Primary.Temperature = 0
+	primary := &seededLLM{}
+	aiService := NewAIServiceWithClients(aiConfig, primary, &seededLLM{}, zaptest.NewLogger(t))
+	store := newMemPromptCacheStore()
+	aiService.SetPromptCache(newPromptCache(store, config.DefaultPromptCacheConfig(), zaptest.NewLogger(t)))
+
+	req := func() *SQLGenerationRequest {
+		return &SQLGenerationRequest{Query: "列出用户", Schema: "users(id, name)"}
+	}
+	ctx := context.Background()
+
+	resp, err := aiService.GenerateSQL(ctx, req())
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users LIMIT 1", resp.SQL)
+	assert.False(t, resp.Generation.Cached)
+	require.Len(t, store.entries, 1)
+	for _, ttl := range store.ttls {
+		assert.Equal(t, 10*time.Minute, ttl)
+	}
+
+	// 相同提示词命中缓存，不再调用模型；流式请求一次性收到完整输出
+	var chunks []string
+	streamCtx := WithTokenStream(ctx, func(ctx context.Context, model, chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	resp, err = aiService.GenerateSQL(streamCtx, req())
+	require.NoError(t, err)
+	assert.Equal(t, 1, primary.calls)
+	assert.Equal(t, "SELECT id FROM users LIMIT 1", resp.SQL)
+	assert.True(t, resp.Generation.Cached)
+	assert.Equal(t, []string{"SELECT id FROM users LIMIT 1"}, chunks)
+
+	// no-cache跳过读取，新响应覆盖缓存
+	resp, err = aiService.GenerateSQL(WithPromptCacheOptions(ctx, ParsePromptCacheHeader("no-cache")), req())
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users LIMIT 2", resp.SQL)
+	assert.False(t, resp.Generation.Cached)
+
+	// no-store既不读取也不写入
+	resp, err = aiService.GenerateSQL(WithPromptCacheOptions(ctx, ParsePromptCacheHeader("No-Store")), req())
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users LIMIT 3", resp.SQL)
+
+	resp, err = aiService.GenerateSQL(ctx, req())
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users LIMIT 2", resp.SQL)
+	assert.Equal(t, 3, primary.calls)
+
+	// 温度非0的调用输出不确定，不缓存
+	aiConfig.Primary.Temperature = 0.3
+	for i := 0; i < 2; i++ {
+		resp, err = aiService.GenerateSQL(ctx, req())
+		require.NoError(t, err)
+		assert.False(t, resp.Generation.Cached)
+	}
+	assert.Equal(t, 5, primary.calls)
+	assert.Len(t, store.entries, 1)
+}
+
+func TestPromptCacheKey(t *testing.T) {
+	cfg := config.ModelConfig{Provider: "openai", ModelName: "gpt-4o", MaxTokens: 1000}
+	key, ok := promptCacheKey(cfg, nil, "列出用户")
+	require.True(t, ok)
+
+	same, _ := promptCacheKey(cfg, nil, "列出用户")
+	assert.Equal(t, key, same)
+
+	other := cfg
+	other.ModelName = "gpt-4o-mini"
+	otherKey, _ := promptCacheKey(other, nil, "列出用户")
+	assert.NotEqual(t, key, otherKey, "模型不同")
+
+	topP := 0.9
+	topPKey, _ := promptCacheKey(cfg, &generationProfile{override: config.GenerationOverride{TopP: &topP}}, "列出用户")
+	assert.NotEqual(t, key, topPKey, "top_p不同")
+
+	cfg.Temperature = 0.2
+	_, ok = promptCacheKey(cfg, nil, "列出用户")
+	assert.False(t, ok)
+
+	assert.Equal(t, PromptCacheOptions{}, ParsePromptCacheHeader("bypass"), "无法识别的取值")
+}
@@ -0,0 +1,224 @@
+// 模型降级链
+// 按配置顺序依次尝试主模型、备用模型与本地Ollama模型，单个模型的瞬时错误按指数退避重试；
+// 每个模型独立统计健康状态，连续失败达到阈值后熔断，熔断期间直接跳过该模型，
+// 到期后放行一次探测调用，成功则恢复，失败则重新熔断
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// ErrAllModelsUnavailable 降级链中所有模型都处于熔断状态
+var ErrAllModelsUnavailable = errors.New("所有模型都处于熔断状态")
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"    // 正常放行
+	BreakerOpen     = "open"      // 熔断中，跳过该模型
+	BreakerHalfOpen = "half_open" // 熔断到期，放行一次探测调用
+)
+
+// ProviderHealth 降级链中单个模型的健康状态
+type ProviderHealth struct {
+	Model               string     `json:"model"` // ModelPrimary/ModelFallback/ModelLocal
+	State               string     `json:"state"` // closed/open/half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Successes           int64      `json:"successes"`
+	Failures            int64      `json:"failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"` // 熔断到期时间，仅熔断中返回
+}
+
+// providerState 单个模型的熔断器与调用统计
+type providerState struct {
+	state         string
+	failures      int // 连续失败次数
+	openedAt      time.Time
+	probing       bool
+	successes     int64
+	totalFailures int64
+	lastError     string
+	lastFailureAt time.Time
+}
+
+// ProviderChain 模型降级链，决定模型尝试顺序、重试次数与熔断状态，并发安全
+type ProviderChain struct {
+	config *config.LLMFailoverConfig
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*providerState
+}
+
+// NewProviderChain 创建模型降级链，配置为nil时使用默认配置
+func NewProviderChain(failoverConfig *config.LLMFailoverConfig, logger *zap.Logger) *ProviderChain {
+	if failoverConfig == nil {
+		failoverConfig = config.DefaultLLMFailoverConfig()
+	}
+	states := make(map[string]*providerState, len(failoverConfig.Order))
+	for _, model := range failoverConfig.Order {
+		states[model] = &providerState{state: BreakerClosed}
+	}
+	return &ProviderChain{
+		config: failoverConfig,
+		logger: logger,
+		now:    time.Now,
+		states: states,
+	}
+}
+
+// Order 返回配置的模型尝试顺序
+func (pc *ProviderChain) Order() []string {
+	return append([]string(nil), pc.config.Order...)
+}
+
+// Allow 判断是否放行对模型的调用，熔断到期后只放行一次探测调用
+func (pc *ProviderChain) Allow(model string) bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	s := pc.state(model)
+	switch s.state {
+	case BreakerOpen:
+		if pc.now().Sub(s.openedAt) < pc.config.BreakerOpenTimeout {
+			return false
+		}
+		s.state = BreakerHalfOpen
+		s.probing = true
+		return true
+	case BreakerHalfOpen:
+		if s.probing {
+			return false
+		}
+		s.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// IsOpen 模型是否处于熔断中
+func (pc *ProviderChain) IsOpen(model string) bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	s := pc.state(model)
+	return s.state == BreakerOpen && pc.now().Sub(s.openedAt) < pc.config.BreakerOpenTimeout
+}
+
+// RecordSuccess 记录一次成功调用，熔断器恢复为关闭状态
+func (pc *ProviderChain) RecordSuccess(model string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	s := pc.state(model)
+	if s.state != BreakerClosed {
+		pc.logger.Info("LLM circuit breaker closed", zap.String("model", model))
+	}
+	s.state = BreakerClosed
+	s.failures = 0
+	s.probing = false
+	s.successes++
+}
+
+// RecordFailure 记录一次失败调用，连续失败达到阈值或探测失败时熔断
+func (pc *ProviderChain) RecordFailure(model string, err error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	s := pc.state(model)
+	s.failures++
+	s.totalFailures++
+	s.probing = false
+	s.lastFailureAt = pc.now()
+	if err != nil {
+		s.lastError = err.Error()
+	}
+	if s.state == BreakerHalfOpen || s.failures >= pc.config.BreakerThreshold {
+		if s.state != BreakerOpen {
+			pc.logger.Warn("LLM circuit breaker opened",
+				zap.String("model", model),
+				zap.Int("consecutive_failures", s.failures),
+				zap.Duration("open_timeout", pc.config.BreakerOpenTimeout))
+		}
+		s.state = BreakerOpen
+		s.openedAt = pc.now()
+	}
+}
+
+// Health 按尝试顺序返回各模型的健康状态
+func (pc *ProviderChain) Health() []ProviderHealth {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	health := make([]ProviderHealth, 0, len(pc.config.Order))
+	for _, model := range pc.config.Order {
+		s := pc.state(model)
+		h := ProviderHealth{
+			Model:               model,
+			State:               s.state,
+			ConsecutiveFailures: s.failures,
+			Successes:           s.successes,
+			Failures:            s.totalFailures,
+			LastError:           s.lastError,
+		}
+		if !s.lastFailureAt.IsZero() {
+			at := s.lastFailureAt
+			h.LastFailureAt = &at
+		}
+		if s.state == BreakerOpen {
+			until := s.openedAt.Add(pc.config.BreakerOpenTimeout)
+			h.OpenUntil = &until
+		}
+		health = append(health, h)
+	}
+	return health
+}
+
+// backoff 返回第attempt次失败后重试前的等待时间，按首次等待时间翻倍
+func (pc *ProviderChain) backoff(attempt int) time.Duration {
+	return pc.config.RetryBackoff << (attempt - 1)
+}
+
+// state 返回模型的状态，调用方需持有锁
+func (pc *ProviderChain) state(model string) *providerState {
+	s, ok := pc.states[model]
+	if !ok {
+		s = &providerState{state: BreakerClosed}
+		pc.states[model] = s
+	}
+	return s
+}
+
+// retryableModelError 判断模型调用错误是否值得在同一模型上重试
+// 调用方取消或超时后不再重试，也不再尝试其他模型
+func retryableModelError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// sleepContext 等待d或直到ctx结束
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+)
+
+// flakyLLM 前failures次调用返回错误，之后返回固定内容；failures小于0时始终失败
+type flakyLLM struct {
+	content  string
+	failures int
+	calls    int
+}
+
+func (f *flakyLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	f.calls++
+	if f.failures < 0 || f.calls <= f.failures {
+		return nil, errors.New("503 service unavailable")
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: f.content}}}, nil
+}
+
+func (f *flakyLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, f, prompt, options...)
+}
+
+func TestAIService_ProviderChain(t *testing.T) {
+	primary := &flakyLLM{failures: -1}
+	fallback := &flakyLLM{failures: -1}
+	local := &flakyLLM{content: "SELECT name FROM users"}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), primary, fallback, zaptest.NewLogger(t))
+	aiService.SetLocalModel(local, config.ModelConfig{Provider: "ollama", ModelName: "llama3.1:8b", Temperature: 0.1, MaxTokens: 256, TopP: 0.9})
+
+	now := time.Now()
+	chain := NewProviderChain(&config.LLMFailoverConfig{
+		Order:              []string{ModelPrimary, ModelFallback, ModelLocal},
+		LocalModel:         "llama3.1:8b",
+		MaxAttempts:        3,
+		RetryBackoff:       time.Millisecond,
+		BreakerThreshold:   2,
+		BreakerOpenTimeout: time.Minute,
+	}, zaptest.NewLogger(t))
+	chain.now = func() time.Time { return now }
+	aiService.SetProviderChain(chain)
+
+	generate := func() (*SQLGenerationResponse, error) {
+		return aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "列出用户名", Schema: "users(id, name)"})
+	}
+
+	// 主备模型连续失败达到阈值后熔断，不再用完全部重试次数，请求由本地模型完成
+	resp, err := generate()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT name FROM users", resp.SQL)
+	assert.Equal(t, 2, primary.calls)
+	assert.Equal(t, 2, fallback.calls)
+	assert.Equal(t, 1, local.calls)
+
+	// 熔断期间直接跳过主备模型
+	_, err = generate()
+	require.NoError(t, err)
+	assert.Equal(t, 2, primary.calls)
+	assert.Equal(t, 2, fallback.calls)
+
+	health := aiService.ProviderHealth()
+	require.Len(t, health, 3)
+	assert.Equal(t, BreakerOpen, health[0].State)
+	assert.Equal(t, "503 service unavailable", health[0].LastError)
+	require.NotNil(t, health[0].OpenUntil)
+	assert.Equal(t, BreakerClosed, health[2].State)
+	assert.Equal(t, int64(2), health[2].Successes)
+
+	// 熔断到期后放行一次探测调用，成功则恢复
+	primary.failures, primary.content = 0, "SELECT id FROM users"
+	now = now.Add(2 * time.Minute)
+	resp, err = generate()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id FROM users", resp.SQL)
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, BreakerClosed, aiService.ProviderHealth()[0].State)
+}
+
+func TestAIService_ProviderChain_RetryAndAllOpen(t *testing.T) {
+	primary := &flakyLLM{content: "SELECT 1", failures: 1}
+	fallback := &flakyLLM{failures: -1}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), primary, fallback, zaptest.NewLogger(t))
+	aiService.SetProviderChain(NewProviderChain(&config.LLMFailoverConfig{
+		Order:              []string{ModelPrimary, ModelFallback},
+		MaxAttempts:        2,
+		RetryBackoff:       time.Millisecond,
+		BreakerThreshold:   2,
+		BreakerOpenTimeout: time.Minute,
+	}, zaptest.NewLogger(t)))
+
+	// 单个模型的瞬时错误重试后成功，不降级到备用模型
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "查询", Schema: "t(id)"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", resp.SQL)
+	assert.Equal(t, 2, primary.calls)
+	assert.Equal(t, 0, fallback.calls)
+
+	// 所有模型都熔断时直接返回，不调用模型
+	primary.failures = -1
+	_, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "查询", Schema: "t(id)"})
+	require.Error(t, err)
+	_, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "查询", Schema: "t(id)"})
+	assert.ErrorIs(t, err, ErrAllModelsUnavailable)
+	assert.Equal(t, 4, primary.calls)
+	assert.Equal(t, 2, fallback.calls)
+}
@@ -0,0 +1,380 @@
+// 异步查询任务
+// 耗时数分钟的分析查询以任务方式提交，后台工作池领取后以流式方式执行，定期更新已读取行数作为进度与心跳，
+// 结束后把结果保存在任务中供客户端轮询。任务状态保存在数据库：实例退出时执行中的任务停止更新心跳，
+// 超过StaleAfter后由任意实例重新排队执行
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/crash"
+	"chat2sql-go/internal/repository"
+)
+
+// maxQueryJobAttempts 任务最多领取次数，每次都在执行中断的任务（如使实例崩溃的查询）不再重新排队
+const maxQueryJobAttempts = 3
+
+// queryJobExecutor 以流式方式执行任务查询，由SQLExecutor实现
+type queryJobExecutor interface {
+	StreamQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64, w RowWriter) (int64, bool, error)
+}
+
+// QueryJobService 异步查询任务服务
+type QueryJobService struct {
+	jobs        repository.QueryJobRepository
+	connections repository.ConnectionRepository
+	history     repository.QueryHistoryRepository
+	executor    queryJobExecutor
+	config      *config.QueryJobConfig
+	logger      *zap.Logger
+	maintenance *MaintenanceMode // 维护期间不领取任务，为nil时不检查
+
+	now       func() time.Time
+	wake      chan struct{}
+	stopCh    chan struct{}
+	baseCtx   context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mutex     sync.Mutex
+	isRunning bool
+}
+
+// NewQueryJobService 创建异步查询任务服务，配置为nil时使用默认配置
+func NewQueryJobService(
+	jobs repository.QueryJobRepository,
+	connections repository.ConnectionRepository,
+	history repository.QueryHistoryRepository,
+	executor queryJobExecutor,
+	jobConfig *config.QueryJobConfig,
+	logger *zap.Logger,
+) *QueryJobService {
+	if jobConfig == nil {
+		jobConfig = config.DefaultQueryJobConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	baseCtx, cancel := context.WithCancel(context.Background())
+	return &QueryJobService{
+		jobs:        jobs,
+		connections: connections,
+		history:     history,
+		executor:    executor,
+		config:      jobConfig,
+		logger:      logger,
+		now:         time.Now,
+		wake:        make(chan struct{}, 1),
+		stopCh:      make(chan struct{}),
+		baseCtx:     baseCtx,
+		cancel:      cancel,
+	}
+}
+
+// Submit 提交任务，行数上限未指定或超过MaxRows时按MaxRows读取
+func (s *QueryJobService) Submit(ctx context.Context, job *repository.QueryJob) error {
+	if job.RowLimit <= 0 || job.RowLimit > s.config.MaxRows {
+		job.RowLimit = s.config.MaxRows
+	}
+	if err := s.jobs.Create(ctx, job); err != nil {
+		return err
+	}
+
+	// 唤醒空闲的工作协程，不必等到下一次扫描
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	s.logger.Info("异步查询任务已提交",
+		zap.Int64("job_id", job.ID),
+		zap.Int64("user_id", job.UserID),
+		zap.Int64("connection_id", job.ConnectionID),
+		zap.Int("row_limit", job.RowLimit))
+	return nil
+}
+
+// Get 获取任务状态与结果，只有提交人或管理员可以查看
+func (s *QueryJobService) Get(ctx context.Context, userID int64, role string, id int64) (*repository.QueryJob, error) {
+	job, err := s.jobs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID && role != string(repository.RoleAdmin) {
+		return nil, fmt.Errorf("无权查看该查询任务: %w", repository.ErrPermissionDenied)
+	}
+	return job, nil
+}
+
+// SetMaintenanceMode 设置维护模式开关，维护期间已提交的任务保持排队，结束后继续执行
+func (s *QueryJobService) SetMaintenanceMode(maintenance *MaintenanceMode) {
+	s.maintenance = maintenance
+}
+
+// RunNext 领取并执行一个待执行任务，没有待执行任务或处于维护模式时返回false
+func (s *QueryJobService) RunNext(ctx context.Context) (bool, error) {
+	if s.maintenance.Active() {
+		return false, nil
+	}
+	jobs, err := s.jobs.ClaimPending(ctx, 1)
+	if err != nil || len(jobs) == 0 {
+		return false, err
+	}
+	s.run(ctx, jobs[0])
+	return true, nil
+}
+
+// run 执行任务并记录结束状态；ctx因服务停止而取消时不记录，任务在心跳超时后重新排队
+func (s *QueryJobService) run(ctx context.Context, job *repository.QueryJob) {
+	logger := s.logger.With(zap.Int64("job_id", job.ID), zap.Int("attempt", job.Attempts))
+
+	connection, err := s.connections.GetByID(ctx, job.ConnectionID)
+	if err != nil {
+		logger.Warn("异步查询任务的数据库连接不可用", zap.Error(err))
+		s.finish(ctx, job, repository.QueryJobFailed, 0, "数据库连接不存在或已删除", repository.QueryError, 0)
+		return
+	}
+
+	collector := &queryJobCollector{}
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		s.heartbeat(heartbeatCtx, job.ID, &collector.rowsRead)
+	}()
+
+	queryCtx, cancel := context.WithTimeout(WithQueryOwner(ctx, job.UserID), s.config.Timeout)
+	if job.QueryHistoryID != nil {
+		queryCtx = WithRealtimeQueryID(queryCtx, *job.QueryHistoryID)
+	}
+	start := s.now()
+	rowsRead, truncated, err := s.executor.StreamQuery(queryCtx, job.SQL, connection, int64(job.RowLimit), collector)
+	elapsed := int32(s.now().Sub(start).Milliseconds())
+	timedOut := errors.Is(queryCtx.Err(), context.DeadlineExceeded)
+	cancel()
+	stopHeartbeat()
+	<-heartbeatDone
+
+	switch {
+	case err == nil:
+		result := &repository.QueryJobResult{
+			Columns:       collector.columns,
+			Rows:          collector.rows,
+			Truncated:     truncated,
+			ExecutionTime: elapsed,
+		}
+		if completeErr := s.jobs.Complete(ctx, job.ID, rowsRead, result); completeErr != nil {
+			logger.Error("保存异步查询任务结果失败", zap.Error(completeErr))
+			return
+		}
+		s.updateHistory(ctx, job, repository.QuerySuccess, elapsed, rowsRead, "")
+		logger.Info("异步查询任务已完成", zap.Int64("rows_read", rowsRead), zap.Int32("execution_time", elapsed))
+	case errors.Is(err, ErrQueryCancelled):
+		s.finish(ctx, job, repository.QueryJobCancelled, rowsRead, err.Error(), repository.QueryCancelled, elapsed)
+	case timedOut:
+		s.finish(ctx, job, repository.QueryJobFailed, rowsRead,
+			fmt.Sprintf("查询执行超过%v", s.config.Timeout), repository.QueryTimeout, elapsed)
+	case ctx.Err() != nil:
+		logger.Info("服务停止，异步查询任务将在心跳超时后重新排队")
+	default:
+		s.finish(ctx, job, repository.QueryJobFailed, rowsRead, err.Error(), repository.QueryError, elapsed)
+	}
+}
+
+// finish 以失败或取消结束任务，并同步查询历史的状态
+func (s *QueryJobService) finish(ctx context.Context, job *repository.QueryJob, status repository.QueryJobStatus, rowsRead int64, message string, historyStatus repository.QueryStatus, elapsed int32) {
+	if err := s.jobs.Finish(ctx, job.ID, status, rowsRead, message); err != nil {
+		s.logger.Error("记录异步查询任务状态失败", zap.Int64("job_id", job.ID), zap.Error(err))
+		return
+	}
+	s.updateHistory(ctx, job, historyStatus, elapsed, rowsRead, message)
+	s.logger.Info("异步查询任务已结束",
+		zap.Int64("job_id", job.ID),
+		zap.String("status", string(status)),
+		zap.String("error", message))
+}
+
+// updateHistory 把任务的执行结果回填到提交时创建的查询历史
+func (s *QueryJobService) updateHistory(ctx context.Context, job *repository.QueryJob, status repository.QueryStatus, elapsed int32, rowsRead int64, message string) {
+	if job.QueryHistoryID == nil || s.history == nil {
+		return
+	}
+	history, err := s.history.GetByID(ctx, *job.QueryHistoryID)
+	if err != nil {
+		s.logger.Warn("获取异步查询任务的查询历史失败", zap.Int64("job_id", job.ID), zap.Error(err))
+		return
+	}
+
+	rows := int32(min(rowsRead, math.MaxInt32))
+	history.Status = string(status)
+	history.ExecutionTime = &elapsed
+	history.ResultRows = &rows
+	if message != "" {
+		history.ErrorMessage = &message
+	}
+	if err := s.history.Update(ctx, history); err != nil {
+		s.logger.Warn("更新异步查询任务的查询历史失败", zap.Int64("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// heartbeat 定期更新已读取行数，同时告知其他实例该任务仍在执行
+func (s *QueryJobService) heartbeat(ctx context.Context, id int64, rowsRead *atomic.Int64) {
+	ticker := time.NewTicker(s.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.jobs.UpdateProgress(ctx, id, rowsRead.Load()); err != nil && ctx.Err() == nil {
+				s.logger.Warn("更新异步查询任务进度失败", zap.Int64("job_id", id), zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Maintain 将心跳超时的任务重新排队，并删除超过保留时长的已结束任务
+func (s *QueryJobService) Maintain(ctx context.Context) error {
+	now := s.now()
+	requeued, err := s.jobs.RequeueStale(ctx, now.Add(-s.config.StaleAfter), maxQueryJobAttempts)
+	if err != nil {
+		return err
+	}
+	if requeued > 0 {
+		s.logger.Warn("执行中断的异步查询任务已重新排队", zap.Int64("jobs", requeued))
+	}
+
+	deleted, err := s.jobs.DeleteFinishedBefore(ctx, now.Add(-s.config.Retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.Info("已清理过期的异步查询任务", zap.Int64("jobs", deleted))
+	}
+	return nil
+}
+
+// Start 启动工作池与维护任务，工作数为0时本实例只接受提交
+func (s *QueryJobService) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.isRunning {
+		return errors.New("异步查询任务工作池已在运行")
+	}
+	if !s.config.Enabled() {
+		s.logger.Info("本实例不执行异步查询任务")
+		return nil
+	}
+	s.isRunning = true
+
+	s.wg.Add(s.config.Workers + 1)
+	for i := 0; i < s.config.Workers; i++ {
+		go s.workerRoutine()
+	}
+	go s.maintenanceRoutine()
+
+	s.logger.Info("异步查询任务工作池已启动",
+		zap.Int("workers", s.config.Workers),
+		zap.Duration("timeout", s.config.Timeout))
+	return nil
+}
+
+// Stop 停止工作池，执行中的查询被中止，这些任务在心跳超时后由其他实例或重启后的实例重新执行
+func (s *QueryJobService) Stop() error {
+	s.mutex.Lock()
+	if !s.isRunning {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.isRunning = false
+	close(s.stopCh)
+	s.cancel()
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+	s.logger.Info("异步查询任务工作池已停止")
+	return nil
+}
+
+// workerRoutine 有待执行任务时连续执行，空闲时等待扫描间隔或新任务提交
+func (s *QueryJobService) workerRoutine() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for s.baseCtx.Err() == nil {
+			claimed := false
+			crash.Run("query_jobs.run", func() {
+				var err error
+				if claimed, err = s.RunNext(s.baseCtx); err != nil && s.baseCtx.Err() == nil {
+					s.logger.Error("领取异步查询任务失败", zap.Error(err))
+				}
+			})
+			if !claimed {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// maintenanceRoutine 启动时及之后每隔StaleAfter的一半执行一次维护
+func (s *QueryJobService) maintenanceRoutine() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.StaleAfter / 2)
+	defer ticker.Stop()
+
+	for {
+		crash.Run("query_jobs.maintain", func() {
+			ctx, cancel := context.WithTimeout(s.baseCtx, time.Minute)
+			defer cancel()
+			if err := s.Maintain(ctx); err != nil && s.baseCtx.Err() == nil {
+				s.logger.Error("维护异步查询任务失败", zap.Error(err))
+			}
+		})
+
+		select {
+		case <-ticker.C:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// queryJobCollector 收集流式查询的结果行，并计数供心跳上报进度
+type queryJobCollector struct {
+	columns  []string
+	rows     []map[string]any
+	rowsRead atomic.Int64
+}
+
+func (c *queryJobCollector) WriteHeader(columns []string) error {
+	c.columns = columns
+	return nil
+}
+
+func (c *queryJobCollector) WriteRow(row map[string]any) error {
+	c.rows = append(c.rows, row)
+	c.rowsRead.Add(1)
+	return nil
+}
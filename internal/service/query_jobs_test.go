@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// memQueryJobRepository 内存异步查询任务Repository，心跳协程会并发更新进度
+type memQueryJobRepository struct {
+	mu   sync.Mutex
+	jobs []*repository.QueryJob
+	now  time.Time
+}
+
+func (m *memQueryJobRepository) Create(ctx context.Context, job *repository.QueryJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.ID = int64(len(m.jobs) + 1)
+	job.Status = string(repository.QueryJobPending)
+	job.CreateTime, job.UpdateTime = m.now, m.now
+	m.jobs = append(m.jobs, job)
+	return nil
+}
+
+func (m *memQueryJobRepository) GetByID(ctx context.Context, id int64) (*repository.QueryJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == id {
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memQueryJobRepository) ClaimPending(ctx context.Context, limit int) ([]*repository.QueryJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var claimed []*repository.QueryJob
+	for _, job := range m.jobs {
+		if job.Status == string(repository.QueryJobPending) && len(claimed) < limit {
+			job.Status = string(repository.QueryJobRunning)
+			job.Attempts++
+			job.UpdateTime = m.now
+			copied := *job
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *memQueryJobRepository) RequeueStale(ctx context.Context, staleBefore time.Time, maxAttempts int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, job := range m.jobs {
+		if job.Status == string(repository.QueryJobRunning) && job.UpdateTime.Before(staleBefore) {
+			job.Status = string(repository.QueryJobPending)
+			if job.Attempts >= maxAttempts {
+				job.Status = string(repository.QueryJobFailed)
+				job.CompletedTime = &m.now
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memQueryJobRepository) UpdateProgress(ctx context.Context, id int64, rowsRead int64) error {
+	return m.update(id, func(job *repository.QueryJob) { job.RowsRead = rowsRead })
+}
+
+func (m *memQueryJobRepository) Complete(ctx context.Context, id int64, rowsRead int64, result *repository.QueryJobResult) error {
+	return m.update(id, func(job *repository.QueryJob) {
+		job.Status = string(repository.QueryJobCompleted)
+		job.RowsRead, job.Result, job.CompletedTime = rowsRead, result, &m.now
+	})
+}
+
+func (m *memQueryJobRepository) Finish(ctx context.Context, id int64, status repository.QueryJobStatus, rowsRead int64, errorMessage string) error {
+	return m.update(id, func(job *repository.QueryJob) {
+		job.Status = string(status)
+		job.RowsRead, job.ErrorMessage, job.CompletedTime = rowsRead, &errorMessage, &m.now
+	})
+}
+
+func (m *memQueryJobRepository) DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.jobs[:0]
+	for _, job := range m.jobs {
+		if job.CompletedTime == nil || !job.CompletedTime.Before(before) {
+			kept = append(kept, job)
+		}
+	}
+	deleted := int64(len(m.jobs) - len(kept))
+	m.jobs = kept
+	return deleted, nil
+}
+
+func (m *memQueryJobRepository) update(id int64, fn func(job *repository.QueryJob)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == id && job.Status == string(repository.QueryJobRunning) {
+			fn(job)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (m *memQueryJobRepository) rowsRead(id int64) int64 {
+	job, _ := m.GetByID(context.Background(), id)
+	return job.RowsRead
+}
+
+// jobQueryHistoryRepository 按ID读取并记录回填的查询历史
+type jobQueryHistoryRepository struct {
+	recordingQueryHistoryRepository
+}
+
+func (r *jobQueryHistoryRepository) GetByID(ctx context.Context, id int64) (*repository.QueryHistory, error) {
+	for _, history := range r.created {
+		if history.ID == id {
+			return history, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// jobExecutorFunc 以函数实现任务执行器
+type jobExecutorFunc func(ctx context.Context, maxRows int64, w RowWriter) (int64, bool, error)
+
+func (f jobExecutorFunc) StreamQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64, w RowWriter) (int64, bool, error) {
+	return f(ctx, maxRows, w)
+}
+
+func newQueryJobTestService(t *testing.T, executor jobExecutorFunc) (*QueryJobService, *memQueryJobRepository, *jobQueryHistoryRepository) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	jobs := &memQueryJobRepository{now: now}
+	history := &jobQueryHistoryRepository{}
+	connection := testutil.NewConnection(3, 7)
+
+	jobConfig := config.DefaultQueryJobConfig()
+	jobConfig.MaxRows = 100
+	jobConfig.HeartbeatInterval = 5 * time.Millisecond
+	s := NewQueryJobService(jobs, &stubConnectionRepository{connection: connection}, history, executor, jobConfig, zaptest.NewLogger(t))
+	s.now = func() time.Time { return now }
+	return s, jobs, history
+}
+
+// submitQueryJob 创建查询历史并提交对应的任务
+func submitQueryJob(t *testing.T, s *QueryJobService, history *jobQueryHistoryRepository, rowLimit int) *repository.QueryJob {
+	record := &repository.QueryHistory{UserID: 7, Status: string(repository.QueryPending)}
+	require.NoError(t, history.Create(context.Background(), record))
+	job := &repository.QueryJob{UserID: 7, ConnectionID: 3, SQL: "SELECT id FROM events", RowLimit: rowLimit, QueryHistoryID: &record.ID}
+	require.NoError(t, s.Submit(context.Background(), job))
+	return job
+}
+
+func TestQueryJobService_RunNext(t *testing.T) {
+	var jobs *memQueryJobRepository
+	s, jobs, history := newQueryJobTestService(t, func(ctx context.Context, maxRows int64, w RowWriter) (int64, bool, error) {
+		require.NoError(t, w.WriteHeader([]string{"id"}))
+		for i := int64(1); i <= 3; i++ {
+			require.NoError(t, w.WriteRow(map[string]any{"id": i}))
+		}
+		// 心跳上报已读取行数后再结束
+		require.Eventually(t, func() bool { return jobs.rowsRead(1) == 3 }, time.Second, time.Millisecond)
+		return 3, maxRows == 3, nil
+	})
+	ctx := context.Background()
+
+	ran, err := s.RunNext(ctx)
+	require.NoError(t, err)
+	assert.False(t, ran, "没有待执行任务")
+
+	job := submitQueryJob(t, s, history, 0)
+	assert.Equal(t, 100, job.RowLimit, "未指定行数上限时按MaxRows读取")
+	assert.Equal(t, 100, submitQueryJob(t, s, history, 5000).RowLimit)
+	submitQueryJob(t, s, history, 3)
+
+	for i := 0; i < 3; i++ {
+		ran, err = s.RunNext(ctx)
+		require.NoError(t, err)
+		assert.True(t, ran)
+	}
+
+	done, err := s.Get(ctx, 7, "user", job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.QueryJobCompleted), done.Status)
+	assert.Equal(t, int64(3), done.RowsRead)
+	assert.Equal(t, []string{"id"}, done.Result.Columns)
+	assert.Len(t, done.Result.Rows, 3)
+	assert.False(t, done.Result.Truncated)
+
+	truncated, err := s.Get(ctx, 7, "user", 3)
+	require.NoError(t, err)
+	assert.True(t, truncated.Result.Truncated)
+
+	require.Len(t, history.updated, 3)
+	assert.Equal(t, string(repository.QuerySuccess), history.updated[0].Status)
+	assert.Equal(t, int32(3), *history.updated[0].ResultRows)
+
+	_, err = s.Get(ctx, 8, "user", job.ID)
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+	_, err = s.Get(ctx, 1, "admin", job.ID)
+	assert.NoError(t, err, "管理员可以查看任何人的任务")
+}
+
+func TestQueryJobService_RunNextMaintenance(t *testing.T) {
+	s, _, history := newQueryJobTestService(t, func(ctx context.Context, maxRows int64, w RowWriter) (int64, bool, error) {
+		return 0, false, nil
+	})
+	ctx := context.Background()
+	maintenance := NewMaintenanceMode(&config.MaintenanceConfig{Enabled: true})
+	s.SetMaintenanceMode(maintenance)
+
+	job := submitQueryJob(t, s, history, 0)
+	ran, err := s.RunNext(ctx)
+	require.NoError(t, err)
+	assert.False(t, ran, "维护期间不领取任务")
+	pending, err := s.Get(ctx, 7, "user", job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.QueryJobPending), pending.Status)
+
+	_, err = maintenance.Disable(ctx, 1)
+	require.NoError(t, err)
+	ran, err = s.RunNext(ctx)
+	require.NoError(t, err)
+	assert.True(t, ran, "维护结束后继续执行排队的任务")
+}
+
+func TestQueryJobService_RunNextInterrupted(t *testing.T) {
+	var queryErr error
+	s, _, history := newQueryJobTestService(t, func(ctx context.Context, maxRows int64, w RowWriter) (int64, bool, error) {
+		if queryErr != nil {
+			return 0, false, queryErr
+		}
+		<-ctx.Done()
+		return 0, false, ctx.Err()
+	})
+	s.config.Timeout = 10 * time.Millisecond
+	ctx := context.Background()
+
+	cases := []struct {
+		err           error
+		status        repository.QueryJobStatus
+		historyStatus repository.QueryStatus
+	}{
+		{nil, repository.QueryJobFailed, repository.QueryTimeout},
+		{ErrQueryCancelled, repository.QueryJobCancelled, repository.QueryCancelled},
+		{errors.New(`relation "events" does not exist`), repository.QueryJobFailed, repository.QueryError},
+	}
+	for i, tc := range cases {
+		queryErr = tc.err
+		job := submitQueryJob(t, s, history, 0)
+		_, err := s.RunNext(ctx)
+		require.NoError(t, err)
+
+		finished, err := s.Get(ctx, 7, "user", job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, string(tc.status), finished.Status)
+		require.NotNil(t, finished.ErrorMessage)
+		assert.Equal(t, string(tc.historyStatus), history.updated[i].Status)
+	}
+
+	// 连接已删除的任务直接失败
+	job := &repository.QueryJob{UserID: 7, ConnectionID: 99, SQL: "SELECT 1"}
+	require.NoError(t, s.Submit(ctx, job))
+	_, err := s.RunNext(ctx)
+	require.NoError(t, err)
+	failed, err := s.Get(ctx, 7, "user", job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.QueryJobFailed), failed.Status)
+}
+
+func TestQueryJobService_Maintain(t *testing.T) {
+	s, jobs, history := newQueryJobTestService(t, nil)
+	ctx := context.Background()
+
+	stale := submitQueryJob(t, s, history, 0)
+	_, err := jobs.ClaimPending(ctx, 1)
+	require.NoError(t, err)
+	exhausted := submitQueryJob(t, s, history, 0)
+	_, err = jobs.ClaimPending(ctx, 1)
+	require.NoError(t, err)
+	jobs.jobs[1].Attempts = maxQueryJobAttempts
+
+	// 实例退出后心跳停止更新，超过StaleAfter后重新排队
+	s.now = func() time.Time { return jobs.now.Add(s.config.StaleAfter + time.Second) }
+	require.NoError(t, s.Maintain(ctx))
+
+	requeued, err := s.Get(ctx, 7, "user", stale.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.QueryJobPending), requeued.Status)
+	failed, err := s.Get(ctx, 7, "user", exhausted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.QueryJobFailed), failed.Status, "达到最多领取次数的任务不再排队")
+
+	// 结束超过保留时长的任务被删除
+	s.now = func() time.Time { return jobs.now.Add(s.config.Retention + time.Second) }
+	require.NoError(t, s.Maintain(ctx))
+	_, err = s.Get(ctx, 7, "user", exhausted.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = s.Get(ctx, 7, "user", stale.ID)
+	assert.NoError(t, err)
+}
+
+func TestQueryJobService_StartStop(t *testing.T) {
+	executed := make(chan struct{})
+	s, _, history := newQueryJobTestService(t, func(ctx context.Context, maxRows int64, w RowWriter) (int64, bool, error) {
+		close(executed)
+		return 0, false, nil
+	})
+	s.config.PollInterval = time.Hour
+
+	require.NoError(t, s.Start())
+	assert.Error(t, s.Start())
+
+	// 提交后唤醒空闲的工作协程，不必等到下一次扫描
+	submitQueryJob(t, s, history, 0)
+	select {
+	case <-executed:
+	case <-time.After(time.Second):
+		t.Fatal("任务未被执行")
+	}
+	require.NoError(t, s.Stop())
+	require.NoError(t, s.Stop())
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/repository"
+)
+
+// 回放使用的表结构
+const (
+	ReplaySchemaPinned  = "pinned"  // 原查询生成时记录的快照
+	ReplaySchemaCurrent = "current" // 连接最新的快照
+)
+
+// ReplayOptions 回放选项
+type ReplayOptions struct {
+	Schema string // ReplaySchemaPinned/ReplaySchemaCurrent，为空时使用pinned
+	Model  string // ModelPrimary/ModelFallback，为空时按正常流程使用主模型并在失败时降级
+}
+
+// ReplayResult 回放结果
+type ReplayResult struct {
+	QueryID      int64                      `json:"query_id"`
+	NaturalQuery string                     `json:"natural_query"`
+	OriginalSQL  string                     `json:"original_sql"`
+	ReplayedSQL  string                     `json:"replayed_sql"`
+	Confidence   float64                    `json:"confidence"`
+	Schema       string                     `json:"schema"`               // 使用的表结构：pinned/current
+	Snapshot     *repository.SchemaSnapshot `json:"snapshot"`             // 使用的表结构快照
+	Model        string                     `json:"model,omitempty"`      // 指定模型时为配置的模型名称
+	Generation   *GenerationParameters      `json:"generation,omitempty"` // 回放实际使用的生成参数
+	Identical    bool                       `json:"identical"`            // 忽略空白、代码块标记与末尾分号后两条SQL是否相同
+	Diff         string                     `json:"diff,omitempty"`       // 按子句分行的差异，"-"为原SQL，"+"为回放结果
+}
+
+// QueryReplayService 查询回放
+// 用查询历史中的问题与表结构快照重新走一遍当前的生成流程，并与原SQL对比，
+// 用于验证提示词或模型调整是否修复或引入了具体案例的回归。回放只生成SQL，不执行也不写入查询历史
+type QueryReplayService struct {
+	queries   repository.QueryHistoryRepository
+	snapshots repository.SchemaSnapshotRepository
+	generator SQLGenerator
+	logger    *zap.Logger
+}
+
+// NewQueryReplayService 创建查询回放服务
+func NewQueryReplayService(queries repository.QueryHistoryRepository, snapshots repository.SchemaSnapshotRepository, generator SQLGenerator, logger *zap.Logger) *QueryReplayService {
+	return &QueryReplayService{queries: queries, snapshots: snapshots, generator: generator, logger: logger}
+}
+
+// Replay 回放一条查询历史
+// 查询不存在时返回repository.ErrNotFound，所需的表结构快照不存在时返回ErrNoSchemaSnapshot
+func (s *QueryReplayService) Replay(ctx context.Context, queryID int64, opts ReplayOptions) (*ReplayResult, error) {
+	if opts.Schema == "" {
+		opts.Schema = ReplaySchemaPinned
+	}
+
+	history, err := s.queries.GetByID(ctx, queryID)
+	if err != nil {
+		return nil, err
+	}
+	if repository.IsAnonymizedQuestion(strings.TrimSpace(history.NaturalQuery)) {
+		return nil, fmt.Errorf("%w: 查询没有保留自然语言问题，无法回放", repository.ErrInvalidInput)
+	}
+
+	snapshot, err := s.replaySnapshot(ctx, history, opts.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.generator.GenerateSQL(ctx, &SQLGenerationRequest{
+		Query:        history.NaturalQuery,
+		ConnectionID: snapshot.ConnectionID,
+		UserID:       history.UserID,
+		Schema:       snapshot.Schema,
+		Model:        opts.Model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("回放生成SQL失败: %w", err)
+	}
+
+	result := &ReplayResult{
+		QueryID:      history.ID,
+		NaturalQuery: history.NaturalQuery,
+		OriginalSQL:  history.GeneratedSQL,
+		ReplayedSQL:  resp.SQL,
+		Confidence:   resp.Confidence,
+		Schema:       opts.Schema,
+		Snapshot:     snapshot,
+		Generation:   resp.Generation,
+		Identical:    normalizeReplaySQL(history.GeneratedSQL) == normalizeReplaySQL(resp.SQL),
+	}
+	if named, ok := s.generator.(interface{ ModelName(string) string }); ok && opts.Model != "" {
+		result.Model = named.ModelName(opts.Model)
+	}
+	if !result.Identical {
+		result.Diff = ai.DiffLines(formatReplaySQL(history.GeneratedSQL), formatReplaySQL(resp.SQL))
+	}
+
+	s.logger.Info("Query replayed",
+		zap.Int64("query_id", history.ID),
+		zap.String("schema", opts.Schema),
+		zap.Int("schema_version", snapshot.Version),
+		zap.String("model", opts.Model),
+		zap.Bool("identical", result.Identical))
+	return result, nil
+}
+
+// replaySnapshot 选择回放使用的表结构快照
+func (s *QueryReplayService) replaySnapshot(ctx context.Context, history *repository.QueryHistory, mode string) (*repository.SchemaSnapshot, error) {
+	var snapshot *repository.SchemaSnapshot
+	var err error
+	switch mode {
+	case ReplaySchemaPinned:
+		if history.SchemaSnapshotID == nil {
+			return nil, ErrNoSchemaSnapshot
+		}
+		snapshot, err = s.snapshots.GetByID(ctx, *history.SchemaSnapshotID)
+		if err == nil && !snapshotOfHistory(snapshot, history) {
+			return nil, ErrNoSchemaSnapshot
+		}
+	case ReplaySchemaCurrent:
+		if history.ConnectionID == nil {
+			return nil, ErrNoSchemaSnapshot
+		}
+		snapshot, err = s.snapshots.GetLatest(ctx, *history.ConnectionID)
+	default:
+		return nil, fmt.Errorf("%w: 未知的表结构选项 %s", repository.ErrInvalidInput, mode)
+	}
+
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNoSchemaSnapshot
+	}
+	return snapshot, err
+}
+
+// normalizeReplaySQL 去除代码块标记与末尾分号并合并空白，用于判断两条SQL是否相同
+func normalizeReplaySQL(sql string) string {
+	return strings.Join(strings.Fields(normalizeCandidateSQL(sql)), " ")
+}
+
+// replayClauseKeyword 在其前换行的SQL子句关键字
+var replayClauseKeyword = regexp.MustCompile(`(?i)\s+(SELECT|FROM|WHERE|GROUP BY|HAVING|ORDER BY|LIMIT|OFFSET|UNION(?: ALL)?|(?:LEFT |RIGHT |FULL |INNER |CROSS )?(?:OUTER )?JOIN)\b`)
+
+// formatReplaySQL 按子句分行，单行SQL的差异也能定位到具体子句
+func formatReplaySQL(sql string) string {
+	return replayClauseKeyword.ReplaceAllString(normalizeReplaySQL(sql), "\n$1")
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// stubHistoryLookup 按ID返回预置的查询历史
+type stubHistoryLookup struct {
+	repository.QueryHistoryRepository
+	queries map[int64]*repository.QueryHistory
+}
+
+func (s *stubHistoryLookup) GetByID(ctx context.Context, id int64) (*repository.QueryHistory, error) {
+	if q, ok := s.queries[id]; ok {
+		return q, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func TestQueryReplayService_Replay(t *testing.T) {
+	ctx := context.Background()
+	snapshots := &fakeSchemaSnapshotRepo{}
+	pinned, err := snapshots.Record(ctx, 3, "orders(id, user_id)")
+	require.NoError(t, err)
+	_, err = snapshots.Record(ctx, 3, "orders(id, customer_id)")
+	require.NoError(t, err)
+
+	connectionID := int64(3)
+	history := testutil.NewQueryHistory(42, 7,
+		testutil.WithQuery("每个用户的订单数", "SELECT user_id, COUNT(*) FROM orders GROUP BY user_id;"),
+		testutil.WithQueryConnection(connectionID))
+	history.SchemaSnapshotID = &pinned.ID
+	legacy := testutil.NewQueryHistory(43, 7, testutil.WithQuery("用户数", "SELECT COUNT(*) FROM users"))
+	anonymized := testutil.NewQueryHistory(44, 7, testutil.WithQuery(repository.QuestionHashPrefix+"9f86d081", "SELECT 1"))
+	anonymized.SchemaSnapshotID = &pinned.ID
+	queries := &stubHistoryLookup{queries: map[int64]*repository.QueryHistory{42: history, 43: legacy, 44: anonymized}}
+
+	t.Run("固定快照且结果相同", func(t *testing.T) {
+		generator := &stubGenerator{response: &SQLGenerationResponse{SQL: "SELECT user_id, COUNT(*)\nFROM orders GROUP BY user_id", Confidence: 0.9}}
+		s := NewQueryReplayService(queries, snapshots, generator, zap.NewNop())
+
+		result, err := s.Replay(ctx, 42, ReplayOptions{Model: ModelFallback})
+		require.NoError(t, err)
+		assert.True(t, result.Identical, "忽略空白与末尾分号")
+		assert.Empty(t, result.Diff)
+		assert.Equal(t, ReplaySchemaPinned, result.Schema)
+		assert.Equal(t, 1, result.Snapshot.Version)
+
+		require.Len(t, generator.requests, 1)
+		assert.Equal(t, "orders(id, user_id)", generator.requests[0].Schema)
+		assert.Equal(t, "每个用户的订单数", generator.requests[0].Query)
+		assert.Equal(t, ModelFallback, generator.requests[0].Model)
+	})
+
+	t.Run("当前快照且结果不同", func(t *testing.T) {
+		generator := &stubGenerator{response: &SQLGenerationResponse{SQL: "SELECT customer_id, COUNT(*) FROM orders GROUP BY customer_id"}}
+		s := NewQueryReplayService(queries, snapshots, generator, zap.NewNop())
+
+		result, err := s.Replay(ctx, 42, ReplayOptions{Schema: ReplaySchemaCurrent})
+		require.NoError(t, err)
+		assert.False(t, result.Identical)
+		assert.Equal(t, 2, result.Snapshot.Version)
+		assert.Equal(t, "orders(id, customer_id)", generator.requests[0].Schema)
+		assert.Equal(t, "- SELECT user_id, COUNT(*)\n+ SELECT customer_id, COUNT(*)\n  FROM orders\n- GROUP BY user_id\n+ GROUP BY customer_id\n",
+			result.Diff, "按子句对比，未变化的子句作为上下文")
+	})
+
+	t.Run("缺少快照或记录", func(t *testing.T) {
+		s := NewQueryReplayService(queries, snapshots, &stubGenerator{}, zap.NewNop())
+
+		_, err := s.Replay(ctx, 43, ReplayOptions{})
+		assert.ErrorIs(t, err, ErrNoSchemaSnapshot, "未记录快照时无法按原表结构回放")
+		_, err = s.Replay(ctx, 43, ReplayOptions{Schema: ReplaySchemaCurrent})
+		assert.ErrorIs(t, err, ErrNoSchemaSnapshot, "没有连接时没有当前快照")
+		_, err = s.Replay(ctx, 99, ReplayOptions{})
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+		_, err = s.Replay(ctx, 42, ReplayOptions{Schema: "yesterday"})
+		assert.ErrorIs(t, err, repository.ErrInvalidInput)
+		_, err = s.Replay(ctx, 44, ReplayOptions{})
+		assert.ErrorIs(t, err, repository.ErrInvalidInput, "问题已被哈希的记录无法回放")
+	})
+}
+
+func TestFormatReplaySQL(t *testing.T) {
+	assert.Equal(t,
+		"SELECT u.name, COUNT(*)\nFROM users u\nLEFT JOIN orders o ON o.user_id = u.id\nGROUP BY u.name\nORDER BY 2 DESC\nLIMIT 10",
+		formatReplaySQL("```sql\nSELECT u.name, COUNT(*) FROM users u LEFT JOIN orders o ON o.user_id = u.id GROUP BY u.name ORDER BY 2 DESC LIMIT 10;\n```"))
+}
@@ -0,0 +1,626 @@
+// 保存查询定时执行
+// 保存查询可配置多个按cron表达式触发的执行计划。调度器定期领取到期的计划，以保存查询所有者的身份执行，
+// 结果保存为执行记录，并按计划的投递方式以CSV附件发送邮件或以JSON POST到Webhook。
+// 领取时计划的下一次执行时间被推后一个超时时长作为租约，执行中的实例退出后计划在租约到期时重新执行
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/cron"
+	"chat2sql-go/internal/repository"
+)
+
+// scheduleIntervalSamples 检查最短执行间隔时向后计算的触发次数
+const scheduleIntervalSamples = 100
+
+// scheduleRunsCleanupInterval 清理过期执行记录的间隔
+const scheduleRunsCleanupInterval = time.Hour
+
+// savedQueryAccess 保存查询的访问检查，由FolderService实现
+type savedQueryAccess interface {
+	GetSavedQuery(ctx context.Context, userID int64, role string, id int64) (*repository.SavedQuery, []Breadcrumb, error)
+	EditableSavedQuery(ctx context.Context, userID int64, role string, id int64) (*repository.SavedQuery, error)
+}
+
+// ScheduleWebhookPayload Webhook投递的请求体
+type ScheduleWebhookPayload struct {
+	ScheduleID   int64            `json:"schedule_id"`
+	RunID        int64            `json:"run_id,omitempty"`
+	SavedQueryID int64            `json:"saved_query_id"`
+	Name         string           `json:"name"`
+	Status       string           `json:"status"`
+	RowCount     int64            `json:"row_count"`
+	Columns      []string         `json:"columns,omitempty"`
+	Rows         []map[string]any `json:"rows,omitempty"`
+	Truncated    bool             `json:"truncated"` // 超过保存行数上限，只投递了前面的行
+	Error        string           `json:"error,omitempty"`
+	ExecutedAt   time.Time        `json:"executed_at"`
+}
+
+// QueryScheduleService 保存查询定时执行服务
+type QueryScheduleService struct {
+	schedules     repository.QueryScheduleRepository
+	queries       repository.SavedQueryRepository
+	connections   repository.ConnectionRepository
+	access        savedQueryAccess
+	executor      QueryExecutor
+	mailer        Mailer // 为nil时不支持邮件投递
+	webhookSecret SecretFunc
+	client        *http.Client
+	config        *config.QueryScheduleConfig
+	logger        *zap.Logger
+	maintenance   *MaintenanceMode // 维护期间不执行计划，为nil时不检查
+	now           func() time.Time
+
+	lastCleanup time.Time
+}
+
+// NewQueryScheduleService 创建定时执行服务，配置为nil时使用默认配置，mailer为nil时不接受邮件投递的计划
+func NewQueryScheduleService(
+	schedules repository.QueryScheduleRepository,
+	queries repository.SavedQueryRepository,
+	connections repository.ConnectionRepository,
+	access savedQueryAccess,
+	executor QueryExecutor,
+	mailer Mailer,
+	scheduleConfig *config.QueryScheduleConfig,
+	logger *zap.Logger,
+) *QueryScheduleService {
+	if scheduleConfig == nil {
+		scheduleConfig = config.DefaultQueryScheduleConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &QueryScheduleService{
+		schedules:     schedules,
+		queries:       queries,
+		connections:   connections,
+		access:        access,
+		executor:      executor,
+		mailer:        mailer,
+		webhookSecret: StaticSecret(scheduleConfig.WebhookSecret),
+		client:        &http.Client{Timeout: 30 * time.Second},
+		config:        scheduleConfig,
+		logger:        logger,
+		now:           time.Now,
+	}
+}
+
+// SetWebhookSecret 设置Webhook签名密钥的来源，未设置时使用配置中的QUERY_SCHEDULE_WEBHOOK_SECRET
+func (s *QueryScheduleService) SetWebhookSecret(secret SecretFunc) {
+	s.webhookSecret = secret
+}
+
+// SetMaintenanceMode 设置维护模式开关，维护期间到期的计划推迟到维护结束后执行
+func (s *QueryScheduleService) SetMaintenanceMode(maintenance *MaintenanceMode) {
+	s.maintenance = maintenance
+}
+
+// Create 为保存查询创建执行计划，需要能修改该保存查询，且保存查询指定了连接
+func (s *QueryScheduleService) Create(ctx context.Context, userID int64, role string, schedule *repository.QuerySchedule) error {
+	query, err := s.access.EditableSavedQuery(ctx, userID, role, schedule.SavedQueryID)
+	if err != nil {
+		return err
+	}
+	if query.ConnectionID == nil {
+		return fmt.Errorf("定时执行的保存查询需要指定连接: %w", repository.ErrInvalidInput)
+	}
+	if err := s.prepare(schedule); err != nil {
+		return err
+	}
+
+	schedule.WorkspaceID = query.WorkspaceID
+	schedule.CreateBy = &userID
+	schedule.UpdateBy = &userID
+	if err := s.schedules.Create(ctx, schedule); err != nil {
+		return err
+	}
+
+	s.logger.Info("已创建定时执行计划",
+		zap.Int64("schedule_id", schedule.ID),
+		zap.Int64("saved_query_id", schedule.SavedQueryID),
+		zap.String("cron", schedule.CronExpr),
+		zap.String("delivery_type", schedule.DeliveryType))
+	return nil
+}
+
+// List 列出保存查询的执行计划，需要保存查询的查看权限
+func (s *QueryScheduleService) List(ctx context.Context, userID int64, role string, savedQueryID int64) ([]*repository.QuerySchedule, error) {
+	if _, _, err := s.access.GetSavedQuery(ctx, userID, role, savedQueryID); err != nil {
+		return nil, err
+	}
+	return s.schedules.ListBySavedQuery(ctx, savedQueryID)
+}
+
+// Get 获取执行计划，需要保存查询的查看权限
+func (s *QueryScheduleService) Get(ctx context.Context, userID int64, role string, id int64) (*repository.QuerySchedule, error) {
+	schedule, err := s.schedules.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := s.access.GetSavedQuery(ctx, userID, role, schedule.SavedQueryID); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Update 修改执行计划的cron表达式、时区、投递方式与启用状态，并重新计算下一次执行时间
+func (s *QueryScheduleService) Update(ctx context.Context, userID int64, role string, update *repository.QuerySchedule) (*repository.QuerySchedule, error) {
+	schedule, err := s.editable(ctx, userID, role, update.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.CronExpr = update.CronExpr
+	schedule.Timezone = update.Timezone
+	schedule.DeliveryType = update.DeliveryType
+	schedule.DeliveryTarget = update.DeliveryTarget
+	schedule.Enabled = update.Enabled
+	if err := s.prepare(schedule); err != nil {
+		return nil, err
+	}
+	schedule.UpdateBy = &userID
+	if err := s.schedules.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Delete 删除执行计划，需要能修改对应的保存查询
+func (s *QueryScheduleService) Delete(ctx context.Context, userID int64, role string, id int64) error {
+	if _, err := s.editable(ctx, userID, role, id); err != nil {
+		return err
+	}
+	return s.schedules.Delete(ctx, id, userID)
+}
+
+// Trigger 把启用中的计划的下一次执行时间提前到当前时间，由调度器在下一轮扫描时执行
+func (s *QueryScheduleService) Trigger(ctx context.Context, userID int64, role string, id int64) (*repository.QuerySchedule, error) {
+	schedule, err := s.editable(ctx, userID, role, id)
+	if err != nil {
+		return nil, err
+	}
+	if !schedule.Enabled {
+		return nil, fmt.Errorf("执行计划已停用: %w", repository.ErrInvalidInput)
+	}
+
+	now := s.now().UTC()
+	schedule.NextRunTime = &now
+	schedule.UpdateBy = &userID
+	if err := s.schedules.Update(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// ListRuns 按时间倒序列出执行记录，不含执行结果
+func (s *QueryScheduleService) ListRuns(ctx context.Context, userID int64, role string, id int64, limit int) ([]*repository.QueryScheduleRun, error) {
+	if _, err := s.Get(ctx, userID, role, id); err != nil {
+		return nil, err
+	}
+	return s.schedules.ListRuns(ctx, id, limit)
+}
+
+// GetRun 获取执行记录及其保存的结果
+func (s *QueryScheduleService) GetRun(ctx context.Context, userID int64, role string, id, runID int64) (*repository.QueryScheduleRun, error) {
+	if _, err := s.Get(ctx, userID, role, id); err != nil {
+		return nil, err
+	}
+	return s.schedules.GetRun(ctx, id, runID)
+}
+
+// editable 获取当前用户可以修改的执行计划：需要能修改对应的保存查询
+func (s *QueryScheduleService) editable(ctx context.Context, userID int64, role string, id int64) (*repository.QuerySchedule, error) {
+	schedule, err := s.schedules.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.access.EditableSavedQuery(ctx, userID, role, schedule.SavedQueryID); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// prepare 校验cron表达式、时区与投递设置，并计算下一次执行时间；停用的计划没有下一次执行时间
+func (s *QueryScheduleService) prepare(schedule *repository.QuerySchedule) error {
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if schedule.DeliveryType == "" {
+		schedule.DeliveryType = string(repository.ScheduleDeliveryNone)
+	}
+	expr, loc, err := s.parseSchedule(schedule)
+	if err != nil {
+		return err
+	}
+	if err := s.validateDelivery(schedule); err != nil {
+		return err
+	}
+
+	schedule.NextRunTime = nil
+	if schedule.Enabled {
+		next := expr.Next(s.now().In(loc))
+		if next.IsZero() {
+			return fmt.Errorf("cron表达式永远不会触发: %w", repository.ErrInvalidInput)
+		}
+		next = next.UTC()
+		schedule.NextRunTime = &next
+	}
+	return nil
+}
+
+// parseSchedule 解析cron表达式与时区，并检查两次执行的间隔不短于MinInterval
+func (s *QueryScheduleService) parseSchedule(schedule *repository.QuerySchedule) (*cron.Schedule, *time.Location, error) {
+	expr, err := cron.Parse(schedule.CronExpr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", err, repository.ErrInvalidInput)
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("未知的时区%q: %w", schedule.Timezone, repository.ErrInvalidInput)
+	}
+
+	prev := expr.Next(s.now().In(loc))
+	for i := 0; i < scheduleIntervalSamples && !prev.IsZero(); i++ {
+		next := expr.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(prev); gap < s.config.MinInterval {
+			return nil, nil, fmt.Errorf("两次执行间隔%v短于允许的最短间隔%v: %w", gap, s.config.MinInterval, repository.ErrInvalidInput)
+		}
+		prev = next
+	}
+	return expr, loc, nil
+}
+
+// validateDelivery 校验投递方式与目标：邮件为逗号分隔的收件人地址，Webhook为http或https URL
+func (s *QueryScheduleService) validateDelivery(schedule *repository.QuerySchedule) error {
+	target := ""
+	if schedule.DeliveryTarget != nil {
+		target = strings.TrimSpace(*schedule.DeliveryTarget)
+	}
+
+	switch repository.ScheduleDeliveryType(schedule.DeliveryType) {
+	case repository.ScheduleDeliveryNone:
+		schedule.DeliveryTarget = nil
+		return nil
+	case repository.ScheduleDeliveryEmail:
+		if s.mailer == nil {
+			return fmt.Errorf("未配置SMTP，不支持邮件投递: %w", repository.ErrInvalidInput)
+		}
+		recipients, err := parseRecipients(target)
+		if err != nil {
+			return err
+		}
+		if len(recipients) > s.config.MaxRecipients {
+			return fmt.Errorf("收件人不能超过%d个: %w", s.config.MaxRecipients, repository.ErrInvalidInput)
+		}
+		target = strings.Join(recipients, ",")
+	case repository.ScheduleDeliveryWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Webhook地址须为http或https URL: %w", repository.ErrInvalidInput)
+		}
+	default:
+		return fmt.Errorf("不支持的投递方式%q: %w", schedule.DeliveryType, repository.ErrInvalidInput)
+	}
+	schedule.DeliveryTarget = &target
+	return nil
+}
+
+// parseRecipients 解析逗号分隔的收件人地址，返回去掉显示名的地址
+func parseRecipients(target string) ([]string, error) {
+	var recipients []string
+	for _, part := range strings.Split(target, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		address, err := mail.ParseAddress(part)
+		if err != nil {
+			return nil, fmt.Errorf("收件人地址%q无效: %w", part, repository.ErrInvalidInput)
+		}
+		recipients = append(recipients, address.Address)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("邮件投递需要至少一个收件人: %w", repository.ErrInvalidInput)
+	}
+	return recipients, nil
+}
+
+// Run 调度器主循环，由看门狗托管；每轮执行全部到期的计划，每小时清理一次过期的执行记录
+func (s *QueryScheduleService) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx, beat); err != nil && ctx.Err() == nil {
+			s.logger.Error("执行到期的定时计划失败", zap.Error(err))
+		}
+		s.cleanup(ctx)
+		beat()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunDue 领取并执行到期的计划，直到没有到期计划或进入维护模式，返回执行的计划数；每执行完一个计划调用beat上报心跳
+func (s *QueryScheduleService) RunDue(ctx context.Context, beat func()) (int, error) {
+	executed := 0
+	for ctx.Err() == nil && !s.maintenance.Active() {
+		schedules, err := s.schedules.ClaimDue(ctx, s.now(), s.config.Timeout, s.config.BatchSize)
+		if err != nil {
+			return executed, err
+		}
+		for _, schedule := range schedules {
+			s.runSchedule(ctx, schedule)
+			executed++
+			beat()
+		}
+		if len(schedules) < s.config.BatchSize {
+			break
+		}
+	}
+	return executed, nil
+}
+
+// runSchedule 执行计划并投递结果，保存执行记录与下一次执行时间；ctx因服务停止而取消时不记录，计划在租约到期后重新执行
+func (s *QueryScheduleService) runSchedule(ctx context.Context, schedule *repository.QuerySchedule) {
+	logger := s.logger.With(zap.Int64("schedule_id", schedule.ID), zap.Int64("saved_query_id", schedule.SavedQueryID))
+	start := s.now()
+
+	runCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	run := &repository.QueryScheduleRun{
+		ScheduleID:     schedule.ID,
+		StartedTime:    start,
+		DeliveryStatus: string(repository.ScheduleDeliverySkipped),
+	}
+	query, result, err := s.execute(runCtx, schedule)
+	if ctx.Err() != nil {
+		logger.Info("服务停止，定时计划将在租约到期后重新执行")
+		return
+	}
+	if err != nil {
+		message := err.Error()
+		run.Status = string(repository.ScheduleRunFailed)
+		run.ErrorMessage = &message
+	} else {
+		run.Status = string(repository.ScheduleRunSuccess)
+		run.RowCount = int64(result.RowCount)
+		run.Result = s.storedResult(result)
+	}
+
+	if query != nil && schedule.DeliveryType != string(repository.ScheduleDeliveryNone) {
+		if deliverErr := s.deliver(runCtx, schedule, query, run, result); deliverErr != nil {
+			message := deliverErr.Error()
+			run.DeliveryStatus = string(repository.ScheduleDeliveryFailed)
+			run.DeliveryError = &message
+			logger.Warn("定时执行结果投递失败", zap.String("delivery_type", schedule.DeliveryType), zap.Error(deliverErr))
+		} else {
+			run.DeliveryStatus = string(repository.ScheduleDeliverySent)
+		}
+	}
+	run.CompletedTime = s.now()
+
+	if err := s.schedules.RecordRun(ctx, run, s.nextRun(schedule)); err != nil {
+		logger.Error("保存定时执行记录失败", zap.Error(err))
+		return
+	}
+	logger.Info("定时计划已执行",
+		zap.String("status", run.Status),
+		zap.Int64("row_count", run.RowCount),
+		zap.String("delivery_status", run.DeliveryStatus),
+		zap.Duration("elapsed", run.CompletedTime.Sub(start)))
+}
+
+// execute 以保存查询所有者的身份在其连接上执行保存的SQL
+func (s *QueryScheduleService) execute(ctx context.Context, schedule *repository.QuerySchedule) (*repository.SavedQuery, *QueryResult, error) {
+	query, err := s.queries.GetByID(ctx, schedule.SavedQueryID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("保存查询不存在或已删除")
+	}
+	if query.ConnectionID == nil {
+		return query, nil, fmt.Errorf("保存查询未指定连接")
+	}
+	connection, err := s.connections.GetByID(ctx, *query.ConnectionID)
+	if err != nil {
+		return query, nil, fmt.Errorf("数据库连接不存在或已删除")
+	}
+
+	result, err := s.executor.ExecuteQuery(WithQueryOwner(ctx, query.OwnerID), query.SQL, connection)
+	if err != nil {
+		if result != nil && result.Error != "" {
+			return query, nil, fmt.Errorf("%s", result.Error)
+		}
+		return query, nil, err
+	}
+	return query, result, nil
+}
+
+// nextRun 计划的下一次执行时间，从当前时间起算，错过的执行不补跑
+func (s *QueryScheduleService) nextRun(schedule *repository.QuerySchedule) *time.Time {
+	expr, err := cron.Parse(schedule.CronExpr)
+	if err != nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil
+	}
+	next := expr.Next(s.now().In(loc))
+	if next.IsZero() {
+		return nil
+	}
+	next = next.UTC()
+	return &next
+}
+
+// storedResult 按MaxStoredRows截取保存与投递的结果
+func (s *QueryScheduleService) storedResult(result *QueryResult) *repository.QueryJobResult {
+	rows := result.Rows
+	truncated := result.Truncated
+	if len(rows) > s.config.MaxStoredRows {
+		rows = rows[:s.config.MaxStoredRows]
+		truncated = true
+	}
+	return &repository.QueryJobResult{
+		Columns:       result.Columns,
+		Rows:          rows,
+		Truncated:     truncated,
+		ExecutionTime: result.ExecutionTime,
+	}
+}
+
+// deliver 按计划的投递方式发送执行结果，执行失败时发送失败原因
+func (s *QueryScheduleService) deliver(ctx context.Context, schedule *repository.QuerySchedule, query *repository.SavedQuery, run *repository.QueryScheduleRun, result *QueryResult) error {
+	target := ""
+	if schedule.DeliveryTarget != nil {
+		target = *schedule.DeliveryTarget
+	}
+
+	switch repository.ScheduleDeliveryType(schedule.DeliveryType) {
+	case repository.ScheduleDeliveryEmail:
+		if s.mailer == nil {
+			return fmt.Errorf("未配置SMTP")
+		}
+		email, err := s.buildEmail(query, run)
+		if err != nil {
+			return err
+		}
+		recipients, err := parseRecipients(target)
+		if err != nil {
+			return err
+		}
+		for _, to := range recipients {
+			email.To = to
+			if err := s.mailer.Send(ctx, email); err != nil {
+				return fmt.Errorf("发送邮件到%s失败: %w", to, err)
+			}
+		}
+		return nil
+	case repository.ScheduleDeliveryWebhook:
+		return s.postWebhook(ctx, target, s.webhookPayload(schedule, query, run))
+	}
+	return nil
+}
+
+// buildEmail 构建投递邮件，成功时以CSV附件携带保存的结果
+func (s *QueryScheduleService) buildEmail(query *repository.SavedQuery, run *repository.QueryScheduleRun) (*OutboundEmail, error) {
+	email := &OutboundEmail{Subject: "Chat2SQL定时查询：" + query.Name}
+	if run.Status != string(repository.ScheduleRunSuccess) {
+		email.Body = fmt.Sprintf("定时查询“%s”执行失败：%s\n\nSQL：\n%s", query.Name, *run.ErrorMessage, query.SQL)
+		return email, nil
+	}
+
+	data, err := BuildResultCSV(&QueryResult{Columns: run.Result.Columns, Rows: run.Result.Rows})
+	if err != nil {
+		return nil, fmt.Errorf("结果导出失败: %w", err)
+	}
+	email.Body = fmt.Sprintf("定时查询“%s”已执行，共 %d 行，耗时 %dms，结果见附件。\n\nSQL：\n%s",
+		query.Name, run.RowCount, run.Result.ExecutionTime, query.SQL)
+	if run.Result.Truncated {
+		email.Body += fmt.Sprintf("\n\n注意：附件只包含前 %d 行。", len(run.Result.Rows))
+	}
+	email.Attachments = []EmailAttachment{{
+		Filename:    fmt.Sprintf("chat2sql-schedule-%d-%s.csv", run.ScheduleID, run.StartedTime.UTC().Format("20060102-150405")),
+		ContentType: "text/csv",
+		Data:        data,
+	}}
+	return email, nil
+}
+
+// webhookPayload 构建Webhook请求体，执行记录尚未保存，不含run_id
+func (s *QueryScheduleService) webhookPayload(schedule *repository.QuerySchedule, query *repository.SavedQuery, run *repository.QueryScheduleRun) *ScheduleWebhookPayload {
+	payload := &ScheduleWebhookPayload{
+		ScheduleID:   schedule.ID,
+		SavedQueryID: query.ID,
+		Name:         query.Name,
+		Status:       run.Status,
+		RowCount:     run.RowCount,
+		ExecutedAt:   run.StartedTime.UTC(),
+	}
+	if run.ErrorMessage != nil {
+		payload.Error = *run.ErrorMessage
+	}
+	if run.Result != nil {
+		payload.Columns = run.Result.Columns
+		payload.Rows = run.Result.Rows
+		payload.Truncated = run.Result.Truncated
+	}
+	return payload
+}
+
+// postWebhook 以JSON POST执行结果，配置了签名密钥时在X-Chat2SQL-Signature中附带请求体的HMAC-SHA256
+func (s *QueryScheduleService) postWebhook(ctx context.Context, target string, payload *ScheduleWebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("编码Webhook请求失败: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建Webhook请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	secret, err := s.webhookSecret(ctx)
+	if err != nil {
+		return fmt.Errorf("读取Webhook签名密钥失败: %w", err)
+	}
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-Chat2SQL-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求Webhook失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook返回状态码%d", resp.StatusCode)
+	}
+	return nil
+}
+
+// cleanup 每隔scheduleRunsCleanupInterval删除超过保留时长的执行记录
+func (s *QueryScheduleService) cleanup(ctx context.Context) {
+	now := s.now()
+	if now.Sub(s.lastCleanup) < scheduleRunsCleanupInterval {
+		return
+	}
+	s.lastCleanup = now
+
+	deleted, err := s.schedules.DeleteRunsBefore(ctx, now.Add(-s.config.RunRetention))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("清理定时执行记录失败", zap.Error(err))
+		}
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("已清理过期的定时执行记录", zap.Int64("runs", deleted))
+	}
+}
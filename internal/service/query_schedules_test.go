@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// memQueryScheduleRepository 内存执行计划Repository
+type memQueryScheduleRepository struct {
+	schedules map[int64]*repository.QuerySchedule
+	runs      []*repository.QueryScheduleRun
+}
+
+func newMemQueryScheduleRepository() *memQueryScheduleRepository {
+	return &memQueryScheduleRepository{schedules: make(map[int64]*repository.QuerySchedule)}
+}
+
+func (m *memQueryScheduleRepository) Create(ctx context.Context, schedule *repository.QuerySchedule) error {
+	schedule.ID = int64(len(m.schedules) + 1)
+	stored := *schedule
+	m.schedules[schedule.ID] = &stored
+	return nil
+}
+
+func (m *memQueryScheduleRepository) GetByID(ctx context.Context, id int64) (*repository.QuerySchedule, error) {
+	schedule, ok := m.schedules[id]
+	if !ok || schedule.IsDeleted {
+		return nil, repository.ErrNotFound
+	}
+	copied := *schedule
+	return &copied, nil
+}
+
+func (m *memQueryScheduleRepository) Update(ctx context.Context, schedule *repository.QuerySchedule) error {
+	stored := *schedule
+	m.schedules[schedule.ID] = &stored
+	return nil
+}
+
+func (m *memQueryScheduleRepository) Delete(ctx context.Context, id int64, deleteBy int64) error {
+	m.schedules[id].IsDeleted = true
+	return nil
+}
+
+func (m *memQueryScheduleRepository) ListBySavedQuery(ctx context.Context, savedQueryID int64) ([]*repository.QuerySchedule, error) {
+	var schedules []*repository.QuerySchedule
+	for _, schedule := range m.schedules {
+		if schedule.SavedQueryID == savedQueryID && !schedule.IsDeleted {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+func (m *memQueryScheduleRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*repository.QuerySchedule, error) {
+	var due []*repository.QuerySchedule
+	for id := int64(1); id <= int64(len(m.schedules)) && len(due) < limit; id++ {
+		schedule := m.schedules[id]
+		if schedule.IsDeleted || !schedule.Enabled || schedule.NextRunTime == nil || schedule.NextRunTime.After(now) {
+			continue
+		}
+		leased := now.Add(lease)
+		schedule.NextRunTime = &leased
+		copied := *schedule
+		due = append(due, &copied)
+	}
+	return due, nil
+}
+
+func (m *memQueryScheduleRepository) RecordRun(ctx context.Context, run *repository.QueryScheduleRun, nextRun *time.Time) error {
+	run.ID = int64(len(m.runs) + 1)
+	m.runs = append(m.runs, run)
+	schedule := m.schedules[run.ScheduleID]
+	schedule.LastRunTime = &run.StartedTime
+	schedule.LastStatus = &run.Status
+	schedule.NextRunTime = nextRun
+	return nil
+}
+
+func (m *memQueryScheduleRepository) ListRuns(ctx context.Context, scheduleID int64, limit int) ([]*repository.QueryScheduleRun, error) {
+	var runs []*repository.QueryScheduleRun
+	for _, run := range m.runs {
+		if run.ScheduleID == scheduleID {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedTime.After(runs[j].StartedTime) })
+	return runs, nil
+}
+
+func (m *memQueryScheduleRepository) GetRun(ctx context.Context, scheduleID, runID int64) (*repository.QueryScheduleRun, error) {
+	for _, run := range m.runs {
+		if run.ID == runID && run.ScheduleID == scheduleID {
+			return run, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memQueryScheduleRepository) DeleteRunsBefore(ctx context.Context, before time.Time) (int64, error) {
+	kept := m.runs[:0]
+	for _, run := range m.runs {
+		if !run.CompletedTime.Before(before) {
+			kept = append(kept, run)
+		}
+	}
+	deleted := int64(len(m.runs) - len(kept))
+	m.runs = kept
+	return deleted, nil
+}
+
+// scheduleTestExecutor 记录发起用户，返回rows，failing时执行失败
+type scheduleTestExecutor struct {
+	owners  []int64
+	rows    []map[string]any
+	failing bool
+}
+
+func (e *scheduleTestExecutor) ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error) {
+	e.owners = append(e.owners, queryOwnerFromContext(ctx))
+	if e.failing {
+		return &QueryResult{Status: string(repository.QueryError), Error: "connection reset"}, errors.New("查询执行失败")
+	}
+	return &QueryResult{Columns: []string{"n"}, Rows: e.rows, RowCount: int32(len(e.rows)), Status: string(repository.QuerySuccess)}, nil
+}
+
+type scheduleTestEnv struct {
+	service   *QueryScheduleService
+	schedules *memQueryScheduleRepository
+	executor  *scheduleTestExecutor
+	mailer    *emailTestMailer
+	query     *repository.SavedQuery
+	now       *time.Time
+}
+
+func newScheduleTestEnv(t *testing.T, cfg *config.QueryScheduleConfig) *scheduleTestEnv {
+	ctx := context.Background()
+	folders, queries := newTestFolderService(t)
+	connection := testutil.NewConnection(7, 3)
+	query := &repository.SavedQuery{Name: "日销售额", NaturalQuery: "每天销售额", SQL: "SELECT count(*) FROM orders", ConnectionID: &connection.ID}
+	require.NoError(t, folders.CreateSavedQuery(ctx, 3, "user", query))
+
+	now := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	env := &scheduleTestEnv{
+		schedules: newMemQueryScheduleRepository(),
+		executor:  &scheduleTestExecutor{rows: []map[string]any{{"n": 1}}},
+		mailer:    &emailTestMailer{},
+		query:     query,
+		now:       &now,
+	}
+	env.service = NewQueryScheduleService(env.schedules, queries, &stubConnectionRepository{connection: connection},
+		folders, env.executor, env.mailer, cfg, zaptest.NewLogger(t))
+	env.service.now = func() time.Time { return *env.now }
+	return env
+}
+
+func TestQueryScheduleService_CreateValidation(t *testing.T) {
+	ctx := context.Background()
+	env := newScheduleTestEnv(t, nil)
+
+	target := "a@example.com, Bob <bob@example.com>"
+	schedule := &repository.QuerySchedule{SavedQueryID: env.query.ID, CronExpr: "0 9 * * 1-5", Timezone: "Asia/Shanghai",
+		DeliveryType: string(repository.ScheduleDeliveryEmail), DeliveryTarget: &target, Enabled: true}
+	require.NoError(t, env.service.Create(ctx, 3, "user", schedule))
+	assert.Equal(t, "a@example.com,bob@example.com", *schedule.DeliveryTarget)
+	// 2024-03-01是星期五，上海时间09:00即UTC 01:00，当天已过，下一次为星期一
+	assert.Equal(t, time.Date(2024, 3, 4, 1, 0, 0, 0, time.UTC), *schedule.NextRunTime)
+
+	ftp := "ftp://example.com"
+	for name, invalid := range map[string]*repository.QuerySchedule{
+		"cron格式错误":  {CronExpr: "0 9 * *"},
+		"间隔过短":      {CronExpr: "*/5 * * * *"},
+		"未知时区":      {CronExpr: "@daily", Timezone: "Mars/Olympus"},
+		"缺少收件人":     {CronExpr: "@daily", DeliveryType: string(repository.ScheduleDeliveryEmail)},
+		"Webhook地址": {CronExpr: "@daily", DeliveryType: string(repository.ScheduleDeliveryWebhook), DeliveryTarget: &ftp},
+	} {
+		invalid.SavedQueryID = env.query.ID
+		assert.ErrorIs(t, env.service.Create(ctx, 3, "user", invalid), repository.ErrInvalidInput, name)
+	}
+
+	// 其他工作空间的用户看不到该保存查询
+	other := &repository.QuerySchedule{SavedQueryID: env.query.ID, CronExpr: "@daily"}
+	assert.ErrorIs(t, env.service.Create(ctx, 9, "user", other), repository.ErrNotFound)
+}
+
+func TestQueryScheduleService_RunDueDeliversEmail(t *testing.T) {
+	ctx := context.Background()
+	env := newScheduleTestEnv(t, nil)
+
+	target := "ops@example.com"
+	schedule := &repository.QuerySchedule{SavedQueryID: env.query.ID, CronExpr: "0 9 * * *",
+		DeliveryType: string(repository.ScheduleDeliveryEmail), DeliveryTarget: &target, Enabled: true}
+	require.NoError(t, env.service.Create(ctx, 3, "user", schedule))
+
+	executed, err := env.service.RunDue(ctx, func() {})
+	require.NoError(t, err)
+	assert.Zero(t, executed, "未到执行时间")
+
+	*env.now = time.Date(2024, 3, 1, 9, 0, 30, 0, time.UTC)
+	executed, err = env.service.RunDue(ctx, func() {})
+	require.NoError(t, err)
+	assert.Equal(t, 1, executed)
+	assert.Equal(t, []int64{3}, env.executor.owners, "以保存查询所有者的身份执行")
+
+	require.Len(t, env.schedules.runs, 1)
+	run := env.schedules.runs[0]
+	assert.Equal(t, string(repository.ScheduleRunSuccess), run.Status)
+	assert.Equal(t, string(repository.ScheduleDeliverySent), run.DeliveryStatus)
+	assert.Equal(t, int64(1), run.RowCount)
+	require.NotNil(t, run.Result)
+	assert.Equal(t, []string{"n"}, run.Result.Columns)
+
+	require.Equal(t, 1, env.mailer.count())
+	email := env.mailer.sent[0]
+	assert.Equal(t, "ops@example.com", email.To)
+	require.Len(t, email.Attachments, 1)
+	assert.Equal(t, "text/csv", email.Attachments[0].ContentType)
+
+	stored, err := env.service.Get(ctx, 3, "user", schedule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC), *stored.NextRunTime)
+	assert.Equal(t, string(repository.ScheduleRunSuccess), *stored.LastStatus)
+
+	// 手动触发后下一轮立即执行，执行失败也保存记录
+	_, err = env.service.Trigger(ctx, 3, "user", schedule.ID)
+	require.NoError(t, err)
+	env.executor.failing = true
+	executed, err = env.service.RunDue(ctx, func() {})
+	require.NoError(t, err)
+	assert.Equal(t, 1, executed)
+	runs, err := env.service.ListRuns(ctx, 3, "user", schedule.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, string(repository.ScheduleRunFailed), env.schedules.runs[1].Status)
+	assert.Contains(t, *env.schedules.runs[1].ErrorMessage, "connection reset")
+	assert.Empty(t, env.mailer.sent[1].Attachments, "失败时邮件只包含失败原因")
+
+	// 过期的执行记录被清理
+	*env.now = env.now.Add(31 * 24 * time.Hour)
+	env.service.cleanup(ctx)
+	assert.Empty(t, env.schedules.runs)
+}
+
+func TestQueryScheduleService_WebhookSignatureAndRowCap(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DefaultQueryScheduleConfig()
+	cfg.WebhookSecret = "s3cret"
+	cfg.MaxStoredRows = 1
+	env := newScheduleTestEnv(t, cfg)
+
+	var payload ScheduleWebhookPayload
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Chat2SQL-Signature")
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+		require.NoError(t, json.Unmarshal(body, &payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	schedule := &repository.QuerySchedule{SavedQueryID: env.query.ID, CronExpr: "@hourly",
+		DeliveryType: string(repository.ScheduleDeliveryWebhook), DeliveryTarget: &server.URL, Enabled: true}
+	require.NoError(t, env.service.Create(ctx, 3, "user", schedule))
+
+	env.executor.rows = []map[string]any{{"n": 1}, {"n": 2}}
+	*env.now = env.now.Add(time.Hour)
+	_, err := env.service.RunDue(ctx, func() {})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, signature)
+	assert.Equal(t, schedule.ID, payload.ScheduleID)
+	assert.Equal(t, "日销售额", payload.Name)
+	assert.Len(t, payload.Rows, 1)
+	assert.True(t, payload.Truncated)
+	require.Len(t, env.schedules.runs, 1)
+	assert.Equal(t, string(repository.ScheduleDeliverySent), env.schedules.runs[0].DeliveryStatus)
+	assert.Len(t, env.schedules.runs[0].Result.Rows, 1)
+}
+
+func TestQueryScheduleService_WebhookSecretFromCredentialStore(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DefaultQueryScheduleConfig()
+	cfg.WebhookSecret = "env-secret"
+	env := newScheduleTestEnv(t, cfg)
+
+	integrations, _ := newTestIntegrationCredentialService(t)
+	_, err := integrations.Create(ctx, "webhook", IntegrationCredentialScheduleWebhook, nil, "stored-secret", 1)
+	require.NoError(t, err)
+	env.service.SetWebhookSecret(integrations.SecretSource(repository.IntegrationWebhook,
+		IntegrationCredentialScheduleWebhook, cfg.WebhookSecret))
+
+	var signature string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Chat2SQL-Signature")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	schedule := &repository.QuerySchedule{SavedQueryID: env.query.ID, CronExpr: "@hourly",
+		DeliveryType: string(repository.ScheduleDeliveryWebhook), DeliveryTarget: &server.URL, Enabled: true}
+	require.NoError(t, env.service.Create(ctx, 3, "user", schedule))
+
+	*env.now = env.now.Add(time.Hour)
+	_, err = env.service.RunDue(ctx, func() {})
+	require.NoError(t, err)
+
+	// 凭据库中的密钥优先于环境变量
+	mac := hmac.New(sha256.New, []byte("stored-secret"))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 问题中识别出的取值类型
+const (
+	QuestionValueNumber = "number" // 十进制数
+	QuestionValueDate   = "date"   // YYYY-MM-DD
+	QuestionValueMonth  = "month"  // YYYY-MM
+	QuestionValueYear   = "year"   // YYYY
+)
+
+// QuestionValue 问题中按区域习惯书写的数值或日期及其规范形式
+type QuestionValue struct {
+	Text        string `json:"text"`                  // 问题中的原文
+	Kind        string `json:"kind"`                  // 取值类型，见QuestionValue*常量
+	Value       string `json:"value"`                 // 规范形式
+	Start       string `json:"start,omitempty"`       // 月份与年份的起始日期（含）
+	End         string `json:"end,omitempty"`         // 月份与年份的结束日期（不含）
+	Alternative string `json:"alternative,omitempty"` // 日月顺序有歧义时的另一种解析
+}
+
+var (
+	// 年在前的日期：2024-03-05、2024/3/5、2024年3月5日
+	yearFirstDatePattern = regexp.MustCompile(`\b(\d{4})\s*(?:[-/.]|年)\s*(\d{1,2})\s*(?:[-/.]|月)\s*(\d{1,2})(?:\s*[日号]|\b)`)
+	// 年在后的日期：03/04/2024（日月顺序有歧义）、03.04.2024（日.月.年）
+	yearLastDatePattern = regexp.MustCompile(`\b(\d{1,2})([/.])(\d{1,2})[/.](\d{4})\b`)
+	// 年月：2024年3月、2024/3、2024-03
+	yearMonthPattern = regexp.MustCompile(`\b(\d{4})(?:\s*年\s*(\d{1,2})\s*月|[-/](\d{1,2})\b)`)
+	// 英文月份名：5 March 2024、March 5, 2024、March 2024
+	dayMonthNamePattern = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+([a-z]+)\.?,?\s+(\d{4})\b`)
+	monthNameDayPattern = regexp.MustCompile(`(?i)\b([a-z]+)\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	monthNamePattern    = regexp.MustCompile(`(?i)\b([a-z]+)\.?,?\s+(\d{4})\b`)
+	// 年份：2024年
+	yearPattern = regexp.MustCompile(`\b(\d{4})\s*年`)
+	// 数值：1,5 million、1.500.000、1,234.56、1.5万
+	numberPattern = regexp.MustCompile(`\b(\d+(?:[.,]\d+)*)(?:\s*((?i:thousand|million|billion|mn|bn|k)\b|十万|百万|千万|万|亿|千))?`)
+)
+
+// monthNames 英文月份名与缩写
+var monthNames = map[string]int{
+	"january": 1, "february": 2, "march": 3, "april": 4, "may": 5, "june": 6,
+	"july": 7, "august": 8, "september": 9, "october": 10, "november": 11, "december": 12,
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "jun": 6, "jul": 7, "aug": 8,
+	"sep": 9, "sept": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// numberScales 数量级单位对应的10的幂
+var numberScales = map[string]int{
+	"thousand": 3, "k": 3, "million": 6, "mn": 6, "billion": 9, "bn": 9,
+	"千": 3, "万": 4, "十万": 5, "百万": 6, "千万": 7, "亿": 8,
+}
+
+// questionValueParser 按一种写法识别取值，groups为正则的子匹配
+type questionValueParser struct {
+	pattern *regexp.Regexp
+	parse   func(groups []string, locale string) (QuestionValue, bool)
+}
+
+// questionValueParsers 按优先级排列：先识别完整日期，已被识别的片段不再按年月、年份或数值解析
+var questionValueParsers = []questionValueParser{
+	{yearFirstDatePattern, func(g []string, _ string) (QuestionValue, bool) {
+		return dateValue(atoi(g[1]), atoi(g[2]), atoi(g[3]))
+	}},
+	{yearLastDatePattern, parseYearLastDate},
+	{dayMonthNamePattern, func(g []string, _ string) (QuestionValue, bool) {
+		month, ok := monthNames[strings.ToLower(g[2])]
+		if !ok {
+			return QuestionValue{}, false
+		}
+		return dateValue(atoi(g[3]), month, atoi(g[1]))
+	}},
+	{monthNameDayPattern, func(g []string, _ string) (QuestionValue, bool) {
+		month, ok := monthNames[strings.ToLower(g[1])]
+		if !ok {
+			return QuestionValue{}, false
+		}
+		return dateValue(atoi(g[3]), month, atoi(g[2]))
+	}},
+	{yearMonthPattern, func(g []string, _ string) (QuestionValue, bool) {
+		month := g[2]
+		if month == "" {
+			month = g[3]
+		}
+		return monthValue(atoi(g[1]), atoi(month))
+	}},
+	{monthNamePattern, func(g []string, _ string) (QuestionValue, bool) {
+		month, ok := monthNames[strings.ToLower(g[1])]
+		if !ok {
+			return QuestionValue{}, false
+		}
+		return monthValue(atoi(g[2]), month)
+	}},
+	{yearPattern, func(g []string, _ string) (QuestionValue, bool) {
+		year := atoi(g[1])
+		return QuestionValue{
+			Kind:  QuestionValueYear,
+			Value: g[1],
+			Start: fmt.Sprintf("%04d-01-01", year),
+			End:   fmt.Sprintf("%04d-01-01", year+1),
+		}, true
+	}},
+	{numberPattern, func(g []string, _ string) (QuestionValue, bool) {
+		scale := strings.ToLower(g[2])
+		value, ok := parseLocaleNumber(g[1], scale != "")
+		if !ok {
+			return QuestionValue{}, false
+		}
+		if scale != "" {
+			value.Mul(value, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(numberScales[scale])), nil)))
+		}
+		return QuestionValue{Kind: QuestionValueNumber, Value: formatRat(value)}, true
+	}},
+}
+
+// parseQuestionValues 识别问题中按区域习惯书写的数值与日期，按出现顺序返回；
+// 原文已是规范形式（如2024-03-05、1500）的取值不返回。locale决定03/04/2024这类日月顺序有歧义的日期
+// 优先按哪种顺序解析：英文按月/日/年，其余按日/月/年，另一种解析放在Alternative中
+func parseQuestionValues(question, locale string) []QuestionValue {
+	type span struct {
+		start, end int
+		value      QuestionValue
+	}
+
+	var found []span
+	overlaps := func(start, end int) bool {
+		for _, s := range found {
+			if start < s.end && s.start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, parser := range questionValueParsers {
+		for _, loc := range parser.pattern.FindAllStringSubmatchIndex(question, -1) {
+			if overlaps(loc[0], loc[1]) {
+				continue
+			}
+			groups := make([]string, len(loc)/2)
+			for i := range groups {
+				if loc[2*i] >= 0 {
+					groups[i] = question[loc[2*i]:loc[2*i+1]]
+				}
+			}
+
+			value, ok := parser.parse(groups, locale)
+			if !ok {
+				continue
+			}
+			value.Text = strings.TrimSpace(groups[0])
+			// 已识别的片段即使不需要规范化也占位，避免其中的数字再被当作数值
+			found = append(found, span{start: loc[0], end: loc[1], value: value})
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+	var values []QuestionValue
+	for _, s := range found {
+		if s.value.Text != s.value.Value {
+			values = append(values, s.value)
+		}
+	}
+	return values
+}
+
+// parseYearLastDate 解析年在后的日期：点分隔按日.月.年；斜杠分隔时能由取值判断顺序的直接解析，
+// 否则英文按月/日/年、其余按日/月/年，另一种解析作为Alternative
+func parseYearLastDate(g []string, locale string) (QuestionValue, bool) {
+	first, second, year := atoi(g[1]), atoi(g[3]), atoi(g[4])
+	if g[2] == "." || first > 12 {
+		return dateValue(year, second, first)
+	}
+	if second > 12 {
+		return dateValue(year, first, second)
+	}
+
+	month, day := second, first
+	if locale == "en" {
+		month, day = first, second
+	}
+	value, ok := dateValue(year, month, day)
+	if ok && first != second {
+		value.Alternative = fmt.Sprintf("%04d-%02d-%02d", year, day, month)
+	}
+	return value, ok
+}
+
+// dateValue 构建日期取值，日期不存在时返回false
+func dateValue(year, month, day int) (QuestionValue, bool) {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || date.Day() != day {
+		return QuestionValue{}, false
+	}
+	return QuestionValue{Kind: QuestionValueDate, Value: date.Format("2006-01-02")}, true
+}
+
+// monthValue 构建月份取值，起止日期为半开区间
+func monthValue(year, month int) (QuestionValue, bool) {
+	if month < 1 || month > 12 {
+		return QuestionValue{}, false
+	}
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return QuestionValue{
+		Kind:  QuestionValueMonth,
+		Value: start.Format("2006-01"),
+		Start: start.Format("2006-01-02"),
+		End:   start.AddDate(0, 1, 0).Format("2006-01-02"),
+	}, true
+}
+
+// parseLocaleNumber 按千分位与小数点的区域习惯解析数值：
+// 同时出现逗号与点时后出现的是小数点；只有逗号时按千分位解析，不符合三位分组且带数量级单位时
+// 按小数逗号解析（1,5 million）；多个点按千分位解析（1.500.000）。无法确定时返回false
+func parseLocaleNumber(raw string, scaled bool) (*big.Rat, bool) {
+	commas, dots := strings.Count(raw, ","), strings.Count(raw, ".")
+	groupSep, decimalSep := "", ""
+	switch {
+	case commas > 0 && dots > 0:
+		groupSep, decimalSep = ",", "."
+		if strings.LastIndex(raw, ",") > strings.LastIndex(raw, ".") {
+			groupSep, decimalSep = ".", ","
+		}
+	case commas > 0:
+		groupSep = ","
+		if commas == 1 && scaled && !thousandsGrouped(raw, ",") {
+			groupSep, decimalSep = "", ","
+		}
+	case dots > 1:
+		groupSep = "."
+	case dots == 1:
+		decimalSep = "."
+	}
+
+	intPart, fracPart := raw, ""
+	if decimalSep != "" {
+		if strings.Count(raw, decimalSep) != 1 {
+			return nil, false
+		}
+		i := strings.Index(raw, decimalSep)
+		intPart, fracPart = raw[:i], raw[i+1:]
+	}
+	if groupSep != "" {
+		if !thousandsGrouped(intPart, groupSep) {
+			return nil, false
+		}
+		intPart = strings.ReplaceAll(intPart, groupSep, "")
+	}
+
+	number := intPart
+	if fracPart != "" {
+		number += "." + fracPart
+	}
+	value, ok := new(big.Rat).SetString(number)
+	return value, ok
+}
+
+// thousandsGrouped 判断整数部分是否按三位分组，如1,500,000
+func thousandsGrouped(s, sep string) bool {
+	groups := strings.Split(s, sep)
+	if len(groups) < 2 || len(groups[0]) == 0 || len(groups[0]) > 3 {
+		return false
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// formatRat 以不带指数的十进制形式输出数值，去掉小数末尾的0
+func formatRat(value *big.Rat) string {
+	if value.IsInt() {
+		return value.Num().String()
+	}
+	return strings.TrimRight(strings.TrimRight(value.FloatString(10), "0"), ".")
+}
+
+// atoi 转换正则匹配到的数字，匹配保证只含数字
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// questionValuesSection 构建问题中数值与日期的提示，没有需要规范化的取值时为空
+func questionValuesSection(question, locale string) string {
+	if locale == "" {
+		locale = DetectLocale(question)
+	}
+	values := parseQuestionValues(question, locale)
+	if len(values) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n## 问题中的数值与日期：\n以下取值已从用户查询中解析为规范形式，生成SQL时直接使用规范形式，不要按其他区域习惯重新理解原文：\n")
+	for _, value := range values {
+		fmt.Fprintf(&b, "- \"%s\" = %s", value.Text, value.Value)
+		switch {
+		case value.Start != "":
+			fmt.Fprintf(&b, "（>= '%s' 且 < '%s'）", value.Start, value.End)
+		case value.Alternative != "":
+			fmt.Fprintf(&b, "（日月顺序有歧义，也可能是%s，无法从上下文判断时按%s）", value.Alternative, value.Value)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
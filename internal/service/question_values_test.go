@@ -0,0 +1,120 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuestionValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		question string
+		locale   string
+		want     []QuestionValue
+	}{
+		{
+			name:     "小数逗号与数量级",
+			question: "revenue above 1,5 million in Germany",
+			locale:   "en",
+			want:     []QuestionValue{{Text: "1,5 million", Kind: QuestionValueNumber, Value: "1500000"}},
+		},
+		{
+			name:     "千分位",
+			question: "orders over 1,234.56 or 1.500.000",
+			locale:   "en",
+			want: []QuestionValue{
+				{Text: "1,234.56", Kind: QuestionValueNumber, Value: "1234.56"},
+				{Text: "1.500.000", Kind: QuestionValueNumber, Value: "1500000"},
+			},
+		},
+		{
+			name:     "中文数量级",
+			question: "销售额超过1.5万或者3亿的客户",
+			locale:   "zh",
+			want: []QuestionValue{
+				{Text: "1.5万", Kind: QuestionValueNumber, Value: "15000"},
+				{Text: "3亿", Kind: QuestionValueNumber, Value: "300000000"},
+			},
+		},
+		{
+			name:     "中文年月",
+			question: "2024年3月各地区的销售额",
+			locale:   "zh",
+			want:     []QuestionValue{{Text: "2024年3月", Kind: QuestionValueMonth, Value: "2024-03", Start: "2024-03-01", End: "2024-04-01"}},
+		},
+		{
+			name:     "中文日期与年份",
+			question: "2023年的订单中2023年12月5日下的单",
+			locale:   "zh",
+			want: []QuestionValue{
+				{Text: "2023年", Kind: QuestionValueYear, Value: "2023", Start: "2023-01-01", End: "2024-01-01"},
+				{Text: "2023年12月5日", Kind: QuestionValueDate, Value: "2023-12-05"},
+			},
+		},
+		{
+			name:     "英文按月日年并给出另一种解析",
+			question: "orders placed on 03/04/2024",
+			locale:   "en",
+			want:     []QuestionValue{{Text: "03/04/2024", Kind: QuestionValueDate, Value: "2024-03-04", Alternative: "2024-04-03"}},
+		},
+		{
+			name:     "中文按日月年",
+			question: "03/04/2024的订单",
+			locale:   "zh",
+			want:     []QuestionValue{{Text: "03/04/2024", Kind: QuestionValueDate, Value: "2024-04-03", Alternative: "2024-03-04"}},
+		},
+		{
+			name:     "取值能确定日月顺序",
+			question: "orders between 25/12/2024 and 12.01.2025",
+			locale:   "en",
+			want: []QuestionValue{
+				{Text: "25/12/2024", Kind: QuestionValueDate, Value: "2024-12-25"},
+				{Text: "12.01.2025", Kind: QuestionValueDate, Value: "2025-01-12"},
+			},
+		},
+		{
+			name:     "英文月份名",
+			question: "signups from March 5, 2024 to 9 Apr 2024 and in May 2024",
+			locale:   "en",
+			want: []QuestionValue{
+				{Text: "March 5, 2024", Kind: QuestionValueDate, Value: "2024-03-05"},
+				{Text: "9 Apr 2024", Kind: QuestionValueDate, Value: "2024-04-09"},
+				{Text: "May 2024", Kind: QuestionValueMonth, Value: "2024-05", Start: "2024-05-01", End: "2024-06-01"},
+			},
+		},
+		{
+			name:     "规范形式与有歧义的写法不返回",
+			question: "top 10 orders since 2024-03-05 with ids 1,5 and amount 1.5",
+			locale:   "en",
+			want:     nil,
+		},
+		{
+			name:     "不存在的日期只按年月解析",
+			question: "2024年2月30日的订单",
+			locale:   "zh",
+			want:     []QuestionValue{{Text: "2024年2月", Kind: QuestionValueMonth, Value: "2024-02", Start: "2024-02-01", End: "2024-03-01"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseQuestionValues(tt.question, tt.locale))
+		})
+	}
+}
+
+func TestQuestionValuesSection(t *testing.T) {
+	assert.Empty(t, questionValuesSection("每个地区的订单数", "zh"))
+
+	// 未指定语言时按问题文本判断
+	section := questionValuesSection("orders on 03/04/2024 over 1,5 million", "")
+	require.NotEmpty(t, section)
+	assert.Contains(t, section, "## 问题中的数值与日期")
+	assert.Contains(t, section, `- "03/04/2024" = 2024-03-04（日月顺序有歧义，也可能是2024-04-03`)
+	assert.Contains(t, section, `- "1,5 million" = 1500000`)
+
+	section = questionValuesSection("2024年3月的销售额", "zh")
+	assert.Contains(t, section, `- "2024年3月" = 2024-03（>= '2024-03-01' 且 < '2024-04-01'）`)
+}
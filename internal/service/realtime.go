@@ -0,0 +1,307 @@
+// 实时事件
+// RealtimeHub按工作空间向订阅者广播轻量事件（成员上下线、查询开始/结束等），用于协作界面上的在线与"查询中"提示；
+// 事件只携带ID与状态，不包含SQL或结果数据。订阅者缓冲写满时丢弃新事件而不阻塞发布方，
+// 客户端重连后通过presence.snapshot事件重新获得在线成员列表
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// 实时事件类型
+const (
+	EventPresenceSnapshot = "presence.snapshot" // 订阅成功后发送给订阅者本人的在线成员列表
+	EventPresenceJoined   = "presence.joined"   // 成员的第一个连接上线
+	EventPresenceLeft     = "presence.left"     // 成员的最后一个连接下线
+	EventQueryStarted     = "query.started"     // 成员开始在某个连接上执行查询
+	EventQueryFinished    = "query.finished"    // 查询执行结束
+)
+
+// RealtimeEvent 实时事件
+type RealtimeEvent struct {
+	Type         string         `json:"type"`
+	WorkspaceID  int64          `json:"workspace_id"`
+	UserID       int64          `json:"user_id,omitempty"`
+	Username     string         `json:"username,omitempty"`
+	ConnectionID int64          `json:"connection_id,omitempty"`
+	QueryID      int64          `json:"query_id,omitempty"`
+	Status       string         `json:"status,omitempty"`      // query.finished：执行状态
+	DurationMS   int64          `json:"duration_ms,omitempty"` // query.finished：执行耗时
+	Online       []PresenceUser `json:"online,omitempty"`      // presence.snapshot：当前在线成员
+	Time         time.Time      `json:"time"`
+}
+
+// PresenceUser 在线成员
+type PresenceUser struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// RealtimeSubscription 实时事件订阅，Events在Close后关闭
+type RealtimeSubscription struct {
+	Events      <-chan *RealtimeEvent
+	WorkspaceID int64
+
+	hub    *RealtimeHub
+	ch     chan *RealtimeEvent
+	userID int64
+	once   sync.Once
+}
+
+// Close 取消订阅，成员的最后一个连接关闭时向工作空间广播下线事件
+func (s *RealtimeSubscription) Close() {
+	s.once.Do(func() { s.hub.unsubscribe(s) })
+}
+
+// workspaceRoom 工作空间内的订阅者与在线成员
+type workspaceRoom struct {
+	subscribers map[*RealtimeSubscription]struct{}
+	online      map[int64]*presenceEntry
+}
+
+// presenceEntry 在线成员及其连接数，同一成员可以从多个标签页同时订阅
+type presenceEntry struct {
+	user        PresenceUser
+	connections int
+}
+
+// RealtimeHub 工作空间实时事件中心
+type RealtimeHub struct {
+	workspaces *WorkspaceSettingsService // 解析用户所属工作空间（带缓存）
+	config     *config.RealtimeConfig
+	logger     *zap.Logger
+
+	mu    sync.Mutex
+	rooms map[int64]*workspaceRoom
+	now   func() time.Time
+}
+
+// NewRealtimeHub 创建实时事件中心
+func NewRealtimeHub(workspaces *WorkspaceSettingsService, realtimeConfig *config.RealtimeConfig, logger *zap.Logger) *RealtimeHub {
+	if realtimeConfig == nil {
+		realtimeConfig = config.DefaultRealtimeConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &RealtimeHub{
+		workspaces: workspaces,
+		config:     realtimeConfig,
+		logger:     logger,
+		rooms:      make(map[int64]*workspaceRoom),
+		now:        time.Now,
+	}
+}
+
+// Config 返回实时事件推送配置
+func (h *RealtimeHub) Config() *config.RealtimeConfig {
+	return h.config
+}
+
+// Subscribe 订阅用户所属工作空间的实时事件
+// 订阅者首先收到presence.snapshot；成员的第一个连接上线时向工作空间广播presence.joined
+func (h *RealtimeHub) Subscribe(ctx context.Context, userID int64, username string) (*RealtimeSubscription, error) {
+	settings, err := h.workspaces.Resolve(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *RealtimeEvent, h.config.SubscriberBuffer)
+	sub := &RealtimeSubscription{
+		Events:      ch,
+		WorkspaceID: settings.WorkspaceID,
+		hub:         h,
+		ch:          ch,
+		userID:      userID,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room := h.rooms[sub.WorkspaceID]
+	if room == nil {
+		room = &workspaceRoom{
+			subscribers: make(map[*RealtimeSubscription]struct{}),
+			online:      make(map[int64]*presenceEntry),
+		}
+		h.rooms[sub.WorkspaceID] = room
+	}
+
+	entry := room.online[userID]
+	if entry == nil {
+		entry = &presenceEntry{user: PresenceUser{UserID: userID, Username: username}}
+		room.online[userID] = entry
+		h.broadcastLocked(room, &RealtimeEvent{
+			Type:        EventPresenceJoined,
+			WorkspaceID: sub.WorkspaceID,
+			UserID:      userID,
+			Username:    username,
+			Time:        h.now(),
+		})
+	}
+	entry.connections++
+
+	room.subscribers[sub] = struct{}{}
+	ch <- &RealtimeEvent{
+		Type:        EventPresenceSnapshot,
+		WorkspaceID: sub.WorkspaceID,
+		Online:      room.onlineUsers(),
+		Time:        h.now(),
+	}
+	return sub, nil
+}
+
+// unsubscribe 移除订阅并关闭事件通道
+func (h *RealtimeHub) unsubscribe(sub *RealtimeSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room := h.rooms[sub.WorkspaceID]
+	if room == nil {
+		return
+	}
+	delete(room.subscribers, sub)
+	close(sub.ch)
+
+	if entry := room.online[sub.userID]; entry != nil {
+		entry.connections--
+		if entry.connections == 0 {
+			delete(room.online, sub.userID)
+			h.broadcastLocked(room, &RealtimeEvent{
+				Type:        EventPresenceLeft,
+				WorkspaceID: sub.WorkspaceID,
+				UserID:      sub.userID,
+				Username:    entry.user.Username,
+				Time:        h.now(),
+			})
+		}
+	}
+
+	if len(room.subscribers) == 0 {
+		delete(h.rooms, sub.WorkspaceID)
+	}
+}
+
+// Publish 向用户所属工作空间广播事件，WorkspaceID与UserID由中心填充
+// 发布不阻塞调用方：无法解析工作空间时只记录日志，订阅者缓冲已满时丢弃该事件
+func (h *RealtimeHub) Publish(ctx context.Context, userID int64, event *RealtimeEvent) {
+	settings, err := h.workspaces.Resolve(ctx, userID)
+	if err != nil {
+		h.logger.Warn("Failed to resolve workspace for realtime event",
+			zap.String("type", event.Type),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		return
+	}
+
+	event.WorkspaceID = settings.WorkspaceID
+	event.UserID = userID
+	if event.Time.IsZero() {
+		event.Time = h.now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if room := h.rooms[event.WorkspaceID]; room != nil {
+		if entry := room.online[userID]; entry != nil && event.Username == "" {
+			event.Username = entry.user.Username
+		}
+		h.broadcastLocked(room, event)
+	}
+}
+
+// Online 返回用户所属工作空间及其当前在线成员，按用户ID排序
+func (h *RealtimeHub) Online(ctx context.Context, userID int64) (int64, []PresenceUser, error) {
+	settings, err := h.workspaces.Resolve(ctx, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if room := h.rooms[settings.WorkspaceID]; room != nil {
+		return settings.WorkspaceID, room.onlineUsers(), nil
+	}
+	return settings.WorkspaceID, []PresenceUser{}, nil
+}
+
+// broadcastLocked 向工作空间内所有订阅者投递事件，调用方需持有h.mu
+func (h *RealtimeHub) broadcastLocked(room *workspaceRoom, event *RealtimeEvent) {
+	for sub := range room.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			h.logger.Debug("Realtime subscriber buffer full, event dropped",
+				zap.String("type", event.Type),
+				zap.Int64("workspace_id", event.WorkspaceID),
+				zap.Int64("subscriber_user_id", sub.userID))
+		}
+	}
+}
+
+// onlineUsers 当前在线成员，按用户ID排序
+func (r *workspaceRoom) onlineUsers() []PresenceUser {
+	users := make([]PresenceUser, 0, len(r.online))
+	for _, entry := range r.online {
+		users = append(users, entry.user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	return users
+}
+
+// realtimeQueryIDKey 查询事件携带的查询历史ID的context键
+type realtimeQueryIDKey struct{}
+
+// realtimeSilentKey 不发布查询事件的context键
+type realtimeSilentKey struct{}
+
+// WithRealtimeQueryID 标记本次执行对应的查询历史ID，执行器发布的查询事件携带该ID
+func WithRealtimeQueryID(ctx context.Context, queryID int64) context.Context {
+	if queryID <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, realtimeQueryIDKey{}, queryID)
+}
+
+// withoutRealtimeEvents 后台预热缓存等不代表成员操作的执行不发布查询事件
+func withoutRealtimeEvents(ctx context.Context) context.Context {
+	return context.WithValue(ctx, realtimeSilentKey{}, true)
+}
+
+// publishQuery 为带发起用户的查询发布query.started，返回发布query.finished的函数
+// 中心为nil、context中没有发起用户或标记为不发布时返回空操作
+func (h *RealtimeHub) publishQuery(ctx context.Context, connectionID int64) func(status string) {
+	userID := queryOwnerFromContext(ctx)
+	if h == nil || userID == 0 || ctx.Value(realtimeSilentKey{}) != nil {
+		return func(string) {}
+	}
+	queryID, _ := ctx.Value(realtimeQueryIDKey{}).(int64)
+
+	// 发布使用独立的context，查询被取消或超时后仍能发布结束事件
+	publishCtx := context.WithoutCancel(ctx)
+	start := h.now()
+	h.Publish(publishCtx, userID, &RealtimeEvent{
+		Type:         EventQueryStarted,
+		ConnectionID: connectionID,
+		QueryID:      queryID,
+	})
+	return func(status string) {
+		h.Publish(publishCtx, userID, &RealtimeEvent{
+			Type:         EventQueryFinished,
+			ConnectionID: connectionID,
+			QueryID:      queryID,
+			Status:       status,
+			DurationMS:   h.now().Sub(start).Milliseconds(),
+		})
+	}
+}
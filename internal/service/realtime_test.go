@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+)
+
+func newTestRealtimeHub(t *testing.T, buffer int) *RealtimeHub {
+	workspaces := &memberWorkspaceRepository{byUser: map[int64]int64{9: 2}}
+	settings := NewWorkspaceSettingsService(workspaces, nil, nil, zaptest.NewLogger(t))
+	cfg := config.DefaultRealtimeConfig()
+	cfg.SubscriberBuffer = buffer
+	return NewRealtimeHub(settings, cfg, zaptest.NewLogger(t))
+}
+
+func TestRealtimeHub_PresenceAndQueryEvents(t *testing.T) {
+	ctx := context.Background()
+	hub := newTestRealtimeHub(t, 16)
+
+	alice, err := hub.Subscribe(ctx, 1, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, EventPresenceSnapshot, (<-alice.Events).Type)
+
+	// 同一成员的第二个连接不重复广播上线
+	bob, err := hub.Subscribe(ctx, 2, "bob")
+	require.NoError(t, err)
+	bobTab, err := hub.Subscribe(ctx, 2, "bob")
+	require.NoError(t, err)
+	snapshot := <-bob.Events
+	assert.Equal(t, []PresenceUser{{1, "alice"}, {2, "bob"}}, snapshot.Online)
+	<-bobTab.Events
+
+	joined := <-alice.Events
+	assert.Equal(t, EventPresenceJoined, joined.Type)
+	assert.Equal(t, int64(2), joined.UserID)
+	assert.Empty(t, alice.Events)
+
+	// 其他工作空间的成员收不到事件
+	other, err := hub.Subscribe(ctx, 9, "carol")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), other.WorkspaceID)
+	<-other.Events
+
+	hub.Publish(ctx, 2, &RealtimeEvent{Type: EventQueryStarted, ConnectionID: 5, QueryID: 42})
+	started := <-alice.Events
+	assert.Equal(t, EventQueryStarted, started.Type)
+	assert.Equal(t, "bob", started.Username)
+	assert.Equal(t, int64(1), started.WorkspaceID)
+	assert.Empty(t, other.Events)
+
+	// 最后一个连接关闭时才广播下线
+	<-bob.Events // query.started
+	<-bobTab.Events
+	bob.Close()
+	assert.Empty(t, alice.Events)
+	bobTab.Close()
+	left := <-alice.Events
+	assert.Equal(t, EventPresenceLeft, left.Type)
+	assert.Equal(t, int64(2), left.UserID)
+
+	_, online, err := hub.Online(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []PresenceUser{{1, "alice"}}, online)
+
+	_, ok := <-bob.Events
+	assert.False(t, ok, "取消订阅后事件通道关闭")
+}
+
+func TestRealtimeHub_DropsEventsForSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	hub := newTestRealtimeHub(t, 2)
+
+	slow, err := hub.Subscribe(ctx, 1, "alice")
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		hub.Publish(ctx, 1, &RealtimeEvent{Type: EventQueryStarted, QueryID: int64(i)})
+	}
+
+	// 缓冲中保留快照与第一个事件，其余事件被丢弃且发布方不阻塞
+	assert.Equal(t, EventPresenceSnapshot, (<-slow.Events).Type)
+	assert.Equal(t, int64(0), (<-slow.Events).QueryID)
+	assert.Empty(t, slow.Events)
+	slow.Close()
+}
+
+func TestRealtimeHub_PublishQuery(t *testing.T) {
+	ctx := context.Background()
+	hub := newTestRealtimeHub(t, 16)
+
+	alice, err := hub.Subscribe(ctx, 1, "alice")
+	require.NoError(t, err)
+	<-alice.Events
+
+	// 执行器按发起用户发布开始与结束事件，查询被取消后仍能发布结束事件
+	queryCtx, cancel := context.WithCancel(WithRealtimeQueryID(WithQueryOwner(ctx, 1), 42))
+	finished := hub.publishQuery(queryCtx, 5)
+	started := <-alice.Events
+	assert.Equal(t, EventQueryStarted, started.Type)
+	assert.Equal(t, int64(42), started.QueryID)
+	assert.Equal(t, int64(5), started.ConnectionID)
+	cancel()
+	finished("cancelled")
+	done := <-alice.Events
+	assert.Equal(t, EventQueryFinished, done.Type)
+	assert.Equal(t, "cancelled", done.Status)
+	assert.Equal(t, "alice", done.Username)
+
+	// 没有发起用户、标记为不发布或未启用实时事件时不发布
+	hub.publishQuery(ctx, 5)("success")
+	hub.publishQuery(withoutRealtimeEvents(WithQueryOwner(ctx, 1)), 5)("success")
+	var disabled *RealtimeHub
+	disabled.publishQuery(WithQueryOwner(ctx, 1), 5)("success")
+	assert.Empty(t, alice.Events)
+}
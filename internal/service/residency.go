@@ -0,0 +1,163 @@
+// 数据驻留
+// 管理员为工作空间指定数据所在区域，并按用途固定快照、导出与归档使用的对象存储桶；
+// 写入对象存储的组件先通过ResolveTarget解析目标位置，或用CheckWrite校验自行指定的位置，
+// 目标与策略不一致时拒绝写入，保证数据不会跨区域落地。本服务自身的写入位置由DATA_RESIDENCY_*_LOCATION配置，
+// SQL执行（结果快照）、导出与模型归档写入前通过CheckLocalWrite校验
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// 数据驻留相关错误
+var (
+	ErrCrossRegionWrite       = errors.New("目标存储位置不符合工作空间的数据驻留策略")
+	ErrStorageLocationMissing = errors.New("工作空间未为该用途固定存储位置")
+)
+
+// ResidencyService 数据驻留服务
+type ResidencyService struct {
+	workspaceRepo repository.WorkspaceRepository
+	config        *config.ResidencyConfig
+	logger        *zap.Logger
+}
+
+// NewResidencyService 创建数据驻留服务
+func NewResidencyService(workspaceRepo repository.WorkspaceRepository, residencyConfig *config.ResidencyConfig, logger *zap.Logger) *ResidencyService {
+	if residencyConfig == nil {
+		residencyConfig = config.DefaultResidencyConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ResidencyService{
+		workspaceRepo: workspaceRepo,
+		config:        residencyConfig,
+		logger:        logger,
+	}
+}
+
+// Validate 校验数据驻留策略：区域必须可用，每个存储位置都必须位于策略区域内
+func (s *ResidencyService) Validate(policy *repository.DataResidencyPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if !s.config.Allows(policy.Region) {
+		return fmt.Errorf("%w: 不允许的数据驻留区域%q", repository.ErrInvalidInput, policy.Region)
+	}
+
+	for purpose, location := range policy.Locations {
+		if !repository.StoragePurpose(purpose).IsValid() {
+			return fmt.Errorf("%w: 未知的存储用途%s", repository.ErrInvalidInput, purpose)
+		}
+		if location == nil || location.Bucket == "" {
+			return fmt.Errorf("%w: 存储用途%s未指定存储桶", repository.ErrInvalidInput, purpose)
+		}
+		if location.Region != policy.Region {
+			return fmt.Errorf("%w: 存储用途%s的存储桶%s位于%s，与驻留区域%s不一致",
+				ErrCrossRegionWrite, purpose, location.Bucket, location.Region, policy.Region)
+		}
+	}
+	return nil
+}
+
+// Get 获取用户所属工作空间及其数据驻留策略
+func (s *ResidencyService) Get(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	return s.workspaceRepo.GetByUserID(ctx, userID)
+}
+
+// Update 校验并更新用户所属工作空间的数据驻留策略，policy为nil表示取消限制
+func (s *ResidencyService) Update(ctx context.Context, userID int64, policy *repository.DataResidencyPolicy) (*repository.Workspace, error) {
+	if err := s.Validate(policy); err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.workspaceRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.workspaceRepo.UpdateDataResidency(ctx, workspace.ID, policy, userID); err != nil {
+		return nil, err
+	}
+	workspace.DataResidency = policy
+
+	region := ""
+	if policy != nil {
+		region = policy.Region
+	}
+	s.logger.Info("数据驻留策略已更新",
+		zap.Int64("workspace_id", workspace.ID),
+		zap.String("region", region),
+		zap.Int64("user_id", userID))
+	return workspace, nil
+}
+
+// ResolveTarget 解析用户写入某类数据时应使用的存储位置
+// 工作空间未配置驻留策略时返回nil，由调用方使用默认位置；配置了策略但未固定该用途时拒绝写入
+func (s *ResidencyService) ResolveTarget(ctx context.Context, userID int64, purpose repository.StoragePurpose) (*repository.StorageLocation, error) {
+	policy, err := s.policy(ctx, userID)
+	if err != nil || policy == nil {
+		return nil, err
+	}
+
+	location := policy.Locations[string(purpose)]
+	if location == nil {
+		return nil, fmt.Errorf("%w: %s（驻留区域%s）", ErrStorageLocationMissing, purpose, policy.Region)
+	}
+	return location, nil
+}
+
+// CheckWrite 校验写入目标是否符合驻留策略，目标必须是该用途固定的存储桶
+func (s *ResidencyService) CheckWrite(ctx context.Context, userID int64, purpose repository.StoragePurpose, target *repository.StorageLocation) error {
+	pinned, err := s.ResolveTarget(ctx, userID, purpose)
+	if err != nil || pinned == nil {
+		return err
+	}
+
+	if target == nil || target.Region != pinned.Region || target.Bucket != pinned.Bucket {
+		s.logger.Warn("拒绝跨区域写入",
+			zap.Int64("user_id", userID),
+			zap.String("purpose", string(purpose)),
+			zap.Any("target", target),
+			zap.String("pinned_bucket", pinned.Bucket),
+			zap.String("pinned_region", pinned.Region))
+		return fmt.Errorf("%w: %s只能写入%s（%s）", ErrCrossRegionWrite, purpose, pinned.Bucket, pinned.Region)
+	}
+	return nil
+}
+
+// Location 返回本服务写入某类数据时使用的存储位置，未配置时返回nil
+func (s *ResidencyService) Location(purpose repository.StoragePurpose) *repository.StorageLocation {
+	target, ok := s.config.Locations[string(purpose)]
+	if !ok {
+		return nil
+	}
+	return &repository.StorageLocation{Bucket: target.Bucket, Region: target.Region}
+}
+
+// CheckLocalWrite 校验本服务按用途写入的存储位置是否符合用户所属工作空间的驻留策略
+func (s *ResidencyService) CheckLocalWrite(ctx context.Context, userID int64, purpose repository.StoragePurpose) error {
+	return s.CheckWrite(ctx, userID, purpose, s.Location(purpose))
+}
+
+// IsResidencyViolation 错误是否表示写入违反数据驻留策略
+func IsResidencyViolation(err error) bool {
+	return errors.Is(err, ErrCrossRegionWrite) || errors.Is(err, ErrStorageLocationMissing)
+}
+
+// policy 获取用户所属工作空间的数据驻留策略
+func (s *ResidencyService) policy(ctx context.Context, userID int64) (*repository.DataResidencyPolicy, error) {
+	workspace, err := s.workspaceRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return workspace.DataResidency, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+func TestResidencyService_Validate(t *testing.T) {
+	svc := NewResidencyService(nil, &config.ResidencyConfig{AllowedRegions: []string{"eu-central-1", "us-east-1"}}, zap.NewNop())
+
+	tests := []struct {
+		name    string
+		policy  *repository.DataResidencyPolicy
+		wantErr error
+	}{
+		{"未配置策略", nil, nil},
+		{"存储桶位于驻留区域", &repository.DataResidencyPolicy{
+			Region: "eu-central-1",
+			Locations: map[string]*repository.StorageLocation{
+				"snapshot": {Bucket: "snapshots-eu", Region: "eu-central-1"},
+				"archive":  {Bucket: "archive-eu", Region: "eu-central-1", Prefix: "llm/"},
+			},
+		}, nil},
+		{"区域不在允许列表", &repository.DataResidencyPolicy{Region: "ap-south-1"}, repository.ErrInvalidInput},
+		{"未知用途", &repository.DataResidencyPolicy{
+			Region:    "eu-central-1",
+			Locations: map[string]*repository.StorageLocation{"backup": {Bucket: "b", Region: "eu-central-1"}},
+		}, repository.ErrInvalidInput},
+		{"缺少存储桶", &repository.DataResidencyPolicy{
+			Region:    "eu-central-1",
+			Locations: map[string]*repository.StorageLocation{"export": {Region: "eu-central-1"}},
+		}, repository.ErrInvalidInput},
+		{"存储桶跨区域", &repository.DataResidencyPolicy{
+			Region:    "eu-central-1",
+			Locations: map[string]*repository.StorageLocation{"export": {Bucket: "exports-us", Region: "us-east-1"}},
+		}, ErrCrossRegionWrite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.Validate(tt.policy)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
+
+func TestResidencyService_CheckWrite(t *testing.T) {
+	ctx := context.Background()
+	snapshot := &repository.StorageLocation{Bucket: "snapshots-eu", Region: "eu-central-1"}
+	workspaces := &stubWorkspaceRepository{workspace: &repository.Workspace{}}
+	svc := NewResidencyService(workspaces, nil, zap.NewNop())
+
+	t.Run("未配置策略时不限制", func(t *testing.T) {
+		target, err := svc.ResolveTarget(ctx, 7, repository.StorageSnapshot)
+		require.NoError(t, err)
+		assert.Nil(t, target)
+		assert.NoError(t, svc.CheckWrite(ctx, 7, repository.StorageExport, &repository.StorageLocation{Bucket: "any", Region: "us-east-1"}))
+	})
+
+	workspaces.workspace.DataResidency = &repository.DataResidencyPolicy{
+		Region:    "eu-central-1",
+		Locations: map[string]*repository.StorageLocation{"snapshot": snapshot},
+	}
+
+	t.Run("解析固定的存储位置", func(t *testing.T) {
+		target, err := svc.ResolveTarget(ctx, 7, repository.StorageSnapshot)
+		require.NoError(t, err)
+		assert.Equal(t, snapshot, target)
+		assert.NoError(t, svc.CheckWrite(ctx, 7, repository.StorageSnapshot, &repository.StorageLocation{Bucket: "snapshots-eu", Region: "eu-central-1"}))
+	})
+
+	t.Run("未固定用途时拒绝写入", func(t *testing.T) {
+		_, err := svc.ResolveTarget(ctx, 7, repository.StorageExport)
+		assert.True(t, errors.Is(err, ErrStorageLocationMissing))
+	})
+
+	t.Run("拒绝写入其他区域或存储桶", func(t *testing.T) {
+		err := svc.CheckWrite(ctx, 7, repository.StorageSnapshot, &repository.StorageLocation{Bucket: "snapshots-eu", Region: "us-east-1"})
+		assert.True(t, errors.Is(err, ErrCrossRegionWrite))
+		err = svc.CheckWrite(ctx, 7, repository.StorageSnapshot, &repository.StorageLocation{Bucket: "other", Region: "eu-central-1"})
+		assert.True(t, errors.Is(err, ErrCrossRegionWrite))
+		err = svc.CheckWrite(ctx, 7, repository.StorageSnapshot, nil)
+		assert.True(t, errors.Is(err, ErrCrossRegionWrite))
+	})
+}
+
+func TestResidencyService_CheckLocalWrite(t *testing.T) {
+	ctx := context.Background()
+	workspaces := &stubWorkspaceRepository{workspace: &repository.Workspace{DataResidency: &repository.DataResidencyPolicy{
+		Region:    "eu-central-1",
+		Locations: map[string]*repository.StorageLocation{"snapshot": {Bucket: "snapshots-eu", Region: "eu-central-1"}},
+	}}}
+	svc := NewResidencyService(workspaces, &config.ResidencyConfig{Locations: map[string]config.StorageTarget{
+		"snapshot": {Bucket: "snapshots-eu", Region: "eu-central-1"},
+		"export":   {Bucket: "exports-us", Region: "us-east-1"},
+	}}, zap.NewNop())
+
+	assert.NoError(t, svc.CheckLocalWrite(ctx, 7, repository.StorageSnapshot))
+	assert.True(t, IsResidencyViolation(svc.CheckLocalWrite(ctx, 7, repository.StorageExport)), "策略未固定导出位置")
+	assert.True(t, IsResidencyViolation(svc.CheckLocalWrite(ctx, 7, repository.StorageArchive)))
+
+	workspaces.workspace.DataResidency = nil
+	assert.NoError(t, svc.CheckLocalWrite(ctx, 7, repository.StorageArchive), "未配置策略时不限制")
+}
+
+func TestSQLExecutor_ResidencyCheckedBeforeExecution(t *testing.T) {
+	workspaces := &stubWorkspaceRepository{workspace: &repository.Workspace{DataResidency: &repository.DataResidencyPolicy{
+		Region:    "eu-central-1",
+		Locations: map[string]*repository.StorageLocation{"snapshot": {Bucket: "snapshots-eu", Region: "eu-central-1"}},
+	}}}
+	executor := NewSQLExecutor(nil, nil, zap.NewNop())
+	executor.SetResidency(NewResidencyService(workspaces, nil, zap.NewNop()))
+
+	// 本服务未配置快照位置，结果不能写入策略要求的区域，执行前拒绝，不获取连接池
+	connection := &repository.DatabaseConnection{UserID: 7}
+	result, err := executor.ExecuteQuery(WithQueryOwner(context.Background(), 7), "SELECT 1", connection)
+	assert.True(t, IsResidencyViolation(err))
+	require.NotNil(t, result)
+	assert.Equal(t, string(repository.QueryError), result.Status)
+}
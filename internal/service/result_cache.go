@@ -0,0 +1,225 @@
+// 查询结果缓存
+// 按(连接, SQL哈希, 返回行数上限)缓存执行成功的结果，仪表盘反复执行同一条生成的SQL时不再访问目标库。
+// 结果保存在Redis中，多实例共享；每个连接维护一个按写入时间排序的索引，超过条数上限时淘汰最早的结果
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// errResultCacheMiss 缓存中没有该结果
+var errResultCacheMiss = errors.New("result cache miss")
+
+// ResultCacheOptions 单次执行的缓存控制
+type ResultCacheOptions struct {
+	NoCache bool          // 不读取缓存，执行结果仍写入缓存
+	NoStore bool          // 不读取也不写入缓存
+	MaxAge  time.Duration // 只接受MaxAge内写入的结果，0表示不限制
+	TTL     time.Duration // 本次结果的缓存时间，0使用配置值，超过配置上限时按上限
+}
+
+// CachedQueryResult 缓存命中的结果
+type CachedQueryResult struct {
+	Result   *QueryResult
+	CachedAt time.Time
+}
+
+// resultCacheEntry Redis中保存的缓存项
+type resultCacheEntry struct {
+	Result   *QueryResult `json:"result"`
+	CachedAt time.Time    `json:"cached_at"`
+}
+
+// resultCacheStore 结果缓存的存储，由Redis实现
+type resultCacheStore interface {
+	// Get 读取缓存项，不存在时返回errResultCacheMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set 写入缓存项并登记到连接索引，索引超过maxEntries时删除最早写入的缓存项
+	Set(ctx context.Context, index, key string, value []byte, ttl time.Duration, maxEntries int) error
+}
+
+// QueryResultCache 查询结果缓存
+type QueryResultCache struct {
+	store  resultCacheStore
+	config *config.ResultCacheConfig
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewQueryResultCache 创建基于Redis的查询结果缓存，配置为nil时使用默认配置
+func NewQueryResultCache(client redis.UniversalClient, cacheConfig *config.ResultCacheConfig, logger *zap.Logger) *QueryResultCache {
+	if cacheConfig == nil {
+		cacheConfig = config.DefaultResultCacheConfig()
+	}
+	return newQueryResultCache(&redisResultCacheStore{client: client, indexTTL: cacheConfig.MaxTTL}, cacheConfig, logger)
+}
+
+func newQueryResultCache(store resultCacheStore, cacheConfig *config.ResultCacheConfig, logger *zap.Logger) *QueryResultCache {
+	if cacheConfig == nil {
+		cacheConfig = config.DefaultResultCacheConfig()
+	}
+	return &QueryResultCache{
+		store:  store,
+		config: cacheConfig,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Lookup 查找连接上相同SQL的缓存结果，行数上限取自ctx（WithRowLimit）
+// 缓存读取失败按未命中处理，只记录日志
+func (c *QueryResultCache) Lookup(ctx context.Context, connectionID int64, sql string, opts ResultCacheOptions) (*CachedQueryResult, bool) {
+	if opts.NoCache || opts.NoStore {
+		return nil, false
+	}
+
+	data, err := c.store.Get(ctx, c.entryKey(ctx, connectionID, sql))
+	if err != nil {
+		if !errors.Is(err, errResultCacheMiss) {
+			c.logger.Warn("Failed to read cached query result",
+				zap.Int64("connection_id", connectionID),
+				zap.Error(err))
+		}
+		return nil, false
+	}
+
+	var entry resultCacheEntry
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // 保留大整数精度
+	if err := decoder.Decode(&entry); err != nil || entry.Result == nil {
+		c.logger.Warn("Discarding malformed cached query result",
+			zap.Int64("connection_id", connectionID),
+			zap.Error(err))
+		return nil, false
+	}
+	if opts.MaxAge > 0 && c.now().Sub(entry.CachedAt) > opts.MaxAge {
+		return nil, false
+	}
+	return &CachedQueryResult{Result: entry.Result, CachedAt: entry.CachedAt}, true
+}
+
+// Store 缓存执行成功的结果，超过行数或大小上限的结果不缓存，返回结果是否已写入缓存
+func (c *QueryResultCache) Store(ctx context.Context, connectionID int64, sql string, result *QueryResult, opts ResultCacheOptions) bool {
+	if opts.NoStore || result == nil || result.Status != string(repository.QuerySuccess) {
+		return false
+	}
+	if int(result.RowCount) > c.config.MaxRows {
+		return false
+	}
+
+	data, err := json.Marshal(resultCacheEntry{Result: result, CachedAt: c.now()})
+	if err != nil {
+		c.logger.Warn("Failed to encode query result for cache", zap.Error(err))
+		return false
+	}
+	if len(data) > c.config.MaxEntryBytes {
+		c.logger.Debug("Query result too large to cache",
+			zap.Int64("connection_id", connectionID),
+			zap.Int("bytes", len(data)))
+		return false
+	}
+
+	err = c.store.Set(ctx, c.indexKey(connectionID), c.entryKey(ctx, connectionID, sql), data, c.ttl(opts), c.config.MaxEntries)
+	if err != nil {
+		c.logger.Warn("Failed to cache query result",
+			zap.Int64("connection_id", connectionID),
+			zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// ttl 本次结果的缓存时间
+func (c *QueryResultCache) ttl(opts ResultCacheOptions) time.Duration {
+	switch {
+	case opts.TTL <= 0:
+		return c.config.TTL
+	case opts.TTL > c.config.MaxTTL:
+		return c.config.MaxTTL
+	}
+	return opts.TTL
+}
+
+// entryKey 缓存键：只去掉首尾空白与结尾分号，字符串字面量中的空白可能有意义，不做其他规范化
+// 带绑定参数时参数的JSON编码一并计入哈希，相同SQL不同参数值的结果互不命中；
+// 连接ID作为hash tag，集群模式下同一连接的缓存项与索引位于同一slot，可以在一个事务中修改
+func (c *QueryResultCache) entryKey(ctx context.Context, connectionID int64, sql string) string {
+	normalized := strings.TrimRight(strings.TrimSpace(sql), "; \t\n")
+	h := sha256.New()
+	h.Write([]byte(normalized))
+	if params := queryParameters(ctx); len(params) > 0 {
+		encoded, _ := json.Marshal(params) // 字符串切片的编码不会失败，且不同切片的编码互不相同
+		h.Write([]byte{0})
+		h.Write(encoded)
+	}
+	sum := h.Sum(nil)
+	return fmt.Sprintf("result_cache:{%d}:%d:%s", connectionID, rowLimitFromContext(ctx), hex.EncodeToString(sum[:]))
+}
+
+func (c *QueryResultCache) indexKey(connectionID int64) string {
+	return fmt.Sprintf("result_cache:{%d}:index", connectionID)
+}
+
+// redisResultCacheStore 基于Redis的结果缓存存储，连接索引为按写入时间排序的有序集合
+type redisResultCacheStore struct {
+	client   redis.UniversalClient
+	indexTTL time.Duration // 缓存项的最长存活时间，早于该时间写入的索引成员一定已过期
+}
+
+func (s *redisResultCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errResultCacheMiss
+	}
+	return data, err
+}
+
+func (s *redisResultCacheStore) Set(ctx context.Context, index, key string, value []byte, ttl time.Duration, maxEntries int) error {
+	now := time.Now()
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, key, value, ttl)
+	pipe.ZAdd(ctx, index, redis.Z{Score: float64(now.UnixMilli()), Member: key})
+	pipe.ZRemRangeByScore(ctx, index, "-inf", fmt.Sprint(now.Add(-s.indexTTL).UnixMilli()))
+	pipe.Expire(ctx, index, s.indexTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	count, err := s.client.ZCard(ctx, index).Result()
+	if err != nil || count <= int64(maxEntries) {
+		return err
+	}
+	evicted, err := s.client.ZRange(ctx, index, 0, count-int64(maxEntries)-1).Result()
+	if err != nil || len(evicted) == 0 {
+		return err
+	}
+	pipe = s.client.TxPipeline()
+	pipe.Del(ctx, evicted...)
+	pipe.ZRem(ctx, index, stringsToAny(evicted)...)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// stringsToAny 转换为ZRem需要的成员列表
+func stringsToAny(values []string) []any {
+	members := make([]any, len(values))
+	for i, v := range values {
+		members[i] = v
+	}
+	return members
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// memResultCacheStore 内存结果缓存存储，记录写入顺序与TTL
+type memResultCacheStore struct {
+	entries map[string][]byte
+	ttls    map[string]time.Duration
+	index   map[string][]string
+}
+
+func newMemResultCacheStore() *memResultCacheStore {
+	return &memResultCacheStore{entries: map[string][]byte{}, ttls: map[string]time.Duration{}, index: map[string][]string{}}
+}
+
+func (s *memResultCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, ok := s.entries[key]
+	if !ok {
+		return nil, errResultCacheMiss
+	}
+	return data, nil
+}
+
+func (s *memResultCacheStore) Set(ctx context.Context, index, key string, value []byte, ttl time.Duration, maxEntries int) error {
+	s.entries[key] = value
+	s.ttls[key] = ttl
+	s.index[index] = append(s.index[index], key)
+	for len(s.index[index]) > maxEntries {
+		delete(s.entries, s.index[index][0])
+		s.index[index] = s.index[index][1:]
+	}
+	return nil
+}
+
+func TestQueryResultCache_StoreAndLookup(t *testing.T) {
+	store := newMemResultCacheStore()
+	cfg := config.DefaultResultCacheConfig()
+	cache := newQueryResultCache(store, cfg, zaptest.NewLogger(t))
+	now := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	result := &QueryResult{
+		Columns:  []string{"id", "total"},
+		Rows:     []map[string]any{{"id": int64(9007199254740993), "total": "12.50"}},
+		RowCount: 1,
+		Status:   string(repository.QuerySuccess),
+	}
+	cache.Store(ctx, 1, "SELECT id, total FROM orders;", result, ResultCacheOptions{})
+
+	cached, ok := cache.Lookup(ctx, 1, "  SELECT id, total FROM orders ", ResultCacheOptions{})
+	require.True(t, ok, "首尾空白与结尾分号不影响命中")
+	assert.Equal(t, now, cached.CachedAt)
+	assert.Equal(t, []string{"id", "total"}, cached.Result.Columns)
+	assert.Equal(t, json.Number("9007199254740993"), cached.Result.Rows[0]["id"], "大整数不丢失精度")
+
+	_, ok = cache.Lookup(ctx, 2, "SELECT id, total FROM orders", ResultCacheOptions{})
+	assert.False(t, ok, "不同连接不共享缓存")
+	_, ok = cache.Lookup(WithRowLimit(ctx, 10), 1, "SELECT id, total FROM orders", ResultCacheOptions{})
+	assert.False(t, ok, "返回行数上限不同的结果不共享缓存")
+	_, ok = cache.Lookup(ctx, 1, "SELECT id, total FROM orders", ResultCacheOptions{NoCache: true})
+	assert.False(t, ok)
+
+	// 参数化SQL按绑定参数区分，相同SQL不同参数值不互相命中
+	parameterized := "SELECT id, total FROM orders WHERE customer = $1 AND region = $2"
+	cache.Store(WithQueryParameters(ctx, []string{"alice", "east"}), 1, parameterized, result, ResultCacheOptions{})
+	_, ok = cache.Lookup(WithQueryParameters(ctx, []string{"alice", "east"}), 1, parameterized, ResultCacheOptions{})
+	assert.True(t, ok)
+	_, ok = cache.Lookup(WithQueryParameters(ctx, []string{"bob", "east"}), 1, parameterized, ResultCacheOptions{})
+	assert.False(t, ok, "参数值不同的结果不共享缓存")
+	_, ok = cache.Lookup(WithQueryParameters(ctx, []string{"alice,east"}), 1, parameterized, ResultCacheOptions{})
+	assert.False(t, ok, "参数的划分不同时不共享缓存")
+	_, ok = cache.Lookup(ctx, 1, parameterized, ResultCacheOptions{})
+	assert.False(t, ok, "未带参数的请求不命中带参数的结果")
+
+	cache.now = func() time.Time { return now.Add(2 * time.Minute) }
+	_, ok = cache.Lookup(ctx, 1, "SELECT id, total FROM orders", ResultCacheOptions{MaxAge: time.Minute})
+	assert.False(t, ok, "超过max_age的结果不使用")
+	_, ok = cache.Lookup(ctx, 1, "SELECT id, total FROM orders", ResultCacheOptions{MaxAge: 5 * time.Minute})
+	assert.True(t, ok)
+}
+
+func TestQueryResultCache_StoreLimits(t *testing.T) {
+	store := newMemResultCacheStore()
+	cfg := config.DefaultResultCacheConfig()
+	cfg.MaxRows = 2
+	cfg.MaxEntryBytes = 512
+	cfg.MaxEntries = 2
+	cache := newQueryResultCache(store, cfg, zaptest.NewLogger(t))
+	ctx := context.Background()
+
+	success := func(rows int) *QueryResult {
+		r := &QueryResult{Columns: []string{"n"}, Status: string(repository.QuerySuccess), RowCount: int32(rows)}
+		for i := 0; i < rows; i++ {
+			r.Rows = append(r.Rows, map[string]any{"n": i})
+		}
+		return r
+	}
+
+	cache.Store(ctx, 1, "SELECT 1", &QueryResult{Status: string(repository.QueryError), Error: "timeout"}, ResultCacheOptions{})
+	cache.Store(ctx, 1, "SELECT 2", success(3), ResultCacheOptions{})
+	cache.Store(ctx, 1, "SELECT 3", &QueryResult{Status: string(repository.QuerySuccess), Rows: []map[string]any{{"blob": string(make([]byte, 1024))}}, RowCount: 1}, ResultCacheOptions{})
+	cache.Store(ctx, 1, "SELECT 4", success(1), ResultCacheOptions{NoStore: true})
+	assert.Empty(t, store.entries, "失败、行数过多、过大或no_store的结果不缓存")
+
+	cache.Store(ctx, 1, "SELECT 5", success(1), ResultCacheOptions{TTL: 30 * time.Second})
+	cache.Store(ctx, 1, "SELECT 6", success(1), ResultCacheOptions{TTL: 24 * time.Hour})
+	cache.Store(ctx, 1, "SELECT 7", success(1), ResultCacheOptions{})
+	assert.Len(t, store.entries, 2, "超过每个连接的条数上限时淘汰最早的结果")
+	_, ok := cache.Lookup(ctx, 1, "SELECT 5", ResultCacheOptions{})
+	assert.False(t, ok)
+
+	key := cache.entryKey(ctx, 1, "SELECT 6")
+	assert.Equal(t, cfg.MaxTTL, store.ttls[key], "请求的缓存时间不超过配置上限")
+	assert.Equal(t, cfg.TTL, store.ttls[cache.entryKey(ctx, 1, "SELECT 7")])
+	assert.Equal(t, 30*time.Second, store.ttls[cache.entryKey(ctx, 1, "SELECT 5")])
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+// resultLimits 一次查询的结果行数与大小上限
+type resultLimits struct {
+	maxRows     int32
+	maxResultMB int32
+}
+
+type resultLimitsKey struct{}
+
+// limitsFor 连接设置了结果上限时覆盖全局配置，请求携带更小的行数上限（如工作空间默认返回行数）时以其为准
+func (e *SQLExecutor) limitsFor(ctx context.Context, connection *repository.DatabaseConnection) resultLimits {
+	limits := resultLimits{maxRows: e.maxRows, maxResultMB: e.maxResultMB}
+	if connection != nil && connection.MaxRows != nil && *connection.MaxRows > 0 {
+		limits.maxRows = *connection.MaxRows
+	}
+	if connection != nil && connection.MaxResultMB != nil && *connection.MaxResultMB > 0 {
+		limits.maxResultMB = *connection.MaxResultMB
+	}
+	if limit := rowLimitFromContext(ctx); limit > 0 && int64(limit) < int64(limits.maxRows) {
+		limits.maxRows = int32(limit)
+	}
+	return limits
+}
+
+// withResultLimits 记录本次查询按连接解析出的结果上限，供读取结果时使用
+func withResultLimits(ctx context.Context, limits resultLimits) context.Context {
+	return context.WithValue(ctx, resultLimitsKey{}, limits)
+}
+
+// resultLimitsFromContext 读取本次查询的结果上限，未记录时按全局配置与请求的行数上限计算
+func (e *SQLExecutor) resultLimitsFromContext(ctx context.Context) resultLimits {
+	if limits, ok := ctx.Value(resultLimitsKey{}).(resultLimits); ok {
+		return limits
+	}
+	return e.limitsFor(ctx, nil)
+}
+
+// limitBlockers 顶层出现时不追加LIMIT的关键字：已限制行数、MySQL要求OFFSET在LIMIT之后、行锁与SELECT INTO
+var limitBlockers = map[string]bool{
+	"LIMIT": true, "FETCH": true, "OFFSET": true, "FOR": true, "INTO": true,
+}
+
+// writeKeywords 任意层级出现时不追加LIMIT，如WITH中的数据修改语句
+var writeKeywords = map[string]bool{"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true}
+
+// injectLimit 为没有行数限制的单条查询追加LIMIT，数据库读到limit行即停止，避免无界查询占满内存
+// 只处理以SELECT、WITH或VALUES开头的语句；顶层已有LIMIT/FETCH/OFFSET、行锁、写操作、多条语句与无法切分的SQL保持原样
+func injectLimit(sql string, limit int64) (string, bool) {
+	tokens, err := sqlvalidator.Tokenize(sql)
+	if err != nil || len(tokens) == 0 {
+		return sql, false
+	}
+	switch tokens[0].Keyword() {
+	case "SELECT", "WITH", "VALUES":
+	default:
+		return sql, false
+	}
+
+	end := len(sql)
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case writeKeywords[token.Keyword()]:
+			return sql, false
+		case token.IsPunct("(") || token.IsPunct("["):
+			depth++
+		case token.IsPunct(")") || token.IsPunct("]"):
+			depth--
+		case depth > 0:
+		case token.IsPunct(";"):
+			// 只允许末尾的分号
+			for _, rest := range tokens[i+1:] {
+				if !rest.IsPunct(";") {
+					return sql, false
+				}
+			}
+			end = token.Pos
+		case limitBlockers[token.Keyword()]:
+			return sql, false
+		}
+		if end < len(sql) {
+			break
+		}
+	}
+
+	// 换行后追加，SQL以行注释结尾时LIMIT不会被注释掉
+	return fmt.Sprintf("%s\nLIMIT %d", strings.TrimRight(sql[:end], " \t\r\n"), limit), true
+}
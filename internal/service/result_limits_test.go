@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+func TestInjectLimit(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM orders", "SELECT * FROM orders\nLIMIT 1001"},
+		{"select id from orders where id in (select order_id from items limit 5);  ", "select id from orders where id in (select order_id from items limit 5)\nLIMIT 1001"},
+		{"WITH t AS (SELECT 1) SELECT * FROM t -- 全部", "WITH t AS (SELECT 1) SELECT * FROM t -- 全部\nLIMIT 1001"},
+		{"SELECT a FROM x UNION SELECT a FROM y", "SELECT a FROM x UNION SELECT a FROM y\nLIMIT 1001"},
+		// 以下保持原样
+		{"SELECT * FROM orders LIMIT 10", ""},
+		{"SELECT * FROM orders OFFSET 10", ""},
+		{"SELECT * FROM orders FETCH FIRST 10 ROWS ONLY", ""},
+		{"SELECT * FROM orders FOR UPDATE", ""},
+		{"SELECT * INTO copy FROM orders", ""},
+		{"WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", ""},
+		{"SELECT 1; SELECT 2", ""},
+		{"EXPLAIN SELECT * FROM orders", ""},
+		{"SELECT 'abc", ""},
+	}
+	for _, tt := range tests {
+		got, ok := injectLimit(tt.sql, 1001)
+		if tt.want == "" {
+			assert.False(t, ok, tt.sql)
+			assert.Equal(t, tt.sql, got)
+			continue
+		}
+		assert.True(t, ok, tt.sql)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestSQLExecutor_LimitsFor(t *testing.T) {
+	executor := NewSQLExecutor(nil, nil, zap.NewNop())
+	ctx := context.Background()
+
+	assert.Equal(t, resultLimits{maxRows: 1000, maxResultMB: 10}, executor.limitsFor(ctx, &repository.DatabaseConnection{}))
+
+	rows, mb := int32(50000), int32(64)
+	connection := &repository.DatabaseConnection{MaxRows: &rows, MaxResultMB: &mb}
+	assert.Equal(t, resultLimits{maxRows: 50000, maxResultMB: 64}, executor.limitsFor(ctx, connection))
+
+	// 请求的行数上限更小时以其为准
+	assert.Equal(t, resultLimits{maxRows: 20, maxResultMB: 64}, executor.limitsFor(WithRowLimit(ctx, 20), connection))
+}
+
+func TestSQLExecutor_AutoLimitTruncation(t *testing.T) {
+	cm, _ := newSQLiteFixture(t)
+	ctx := context.Background()
+
+	db, err := cm.openSQLiteDB(ctx, &repository.DatabaseConnection{DBType: "sqlite", DatabaseName: "shop.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	executor := NewSQLExecutor(nil, cm, zaptest.NewLogger(t))
+	rows := int32(2)
+	limits := executor.limitsFor(ctx, &repository.DatabaseConnection{MaxRows: &rows})
+	sql, ok := injectLimit("SELECT id FROM orders ORDER BY id", int64(limits.maxRows)+1)
+	require.True(t, ok)
+
+	result, err := executor.executeQueryOnDB(withResultLimits(ctx, limits), sql, db, repository.DBTypeSQLite)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, GuardrailRowLimit, result.TruncatedBy)
+}
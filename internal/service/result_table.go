@@ -0,0 +1,214 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"chat2sql-go/internal/config"
+)
+
+// 结果表格格式
+const (
+	ResultFormatText     = "text"     // 对齐的纯文本表格
+	ResultFormatMarkdown = "markdown" // Markdown表格
+)
+
+// truncationMarker 被截断单元格的结尾标记
+const truncationMarker = "…"
+
+// ResultTableRenderer 将结果集渲染为纯文本或Markdown表格
+// 供聊天集成与屏幕阅读器直接展示，不需要客户端再排版；超出行数、单元格宽度与整行宽度限制的部分以截断标记提示
+type ResultTableRenderer struct {
+	config *config.ResultTableConfig
+}
+
+// NewResultTableRenderer 创建结果表格渲染器，配置为nil时使用默认配置
+func NewResultTableRenderer(tableConfig *config.ResultTableConfig) *ResultTableRenderer {
+	if tableConfig == nil {
+		tableConfig = config.DefaultResultTableConfig()
+	}
+	return &ResultTableRenderer{config: tableConfig}
+}
+
+// Render 按format渲染结果表格，format不是text或markdown时返回空字符串
+// columns为空时按首行的键排列列；截断提示按locale返回
+func (r *ResultTableRenderer) Render(format string, columns []string, rows []map[string]any, locale string) string {
+	return r.RenderWithHeaders(format, columns, nil, rows, locale)
+}
+
+// RenderWithHeaders 与Render相同，表头使用headers中对应位置的展示名，headers为空或长度不符时使用列名
+func (r *ResultTableRenderer) RenderWithHeaders(format string, columns, headers []string, rows []map[string]any, locale string) string {
+	if format != ResultFormatText && format != ResultFormatMarkdown {
+		return ""
+	}
+	if len(columns) == 0 && len(rows) > 0 {
+		for column := range rows[0] {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+	}
+	if len(columns) == 0 {
+		return Localize(locale, "（无结果列）") + "\n"
+	}
+
+	shown := rows
+	if len(shown) > r.config.MaxRows {
+		shown = shown[:r.config.MaxRows]
+	}
+
+	// 单元格文本与各列宽度
+	header := make([]string, len(columns))
+	widths := make([]int, len(columns))
+	numeric := make([]bool, len(columns)) // 非NULL值全部为数值的列
+	mixed := make([]bool, len(columns))
+	cells := make([][]string, len(shown))
+	if len(headers) != len(columns) {
+		headers = columns
+	}
+	for i := range columns {
+		header[i] = r.cellText(headers[i], format)
+		widths[i] = max(displayWidth(header[i]), 3)
+	}
+	for j, row := range shown {
+		cells[j] = make([]string, len(columns))
+		for i, column := range columns {
+			value := row[column]
+			if value != nil {
+				numeric[i] = numeric[i] || isNumericValue(value)
+				mixed[i] = mixed[i] || !isNumericValue(value)
+			}
+			cells[j][i] = r.cellText(formatResultValue(value), format)
+			widths[i] = max(widths[i], displayWidth(cells[j][i]))
+		}
+	}
+
+	for i := range numeric {
+		numeric[i] = numeric[i] && !mixed[i]
+	}
+
+	// 整行超宽时省略靠后的列，至少保留第一列
+	visible := 1
+	lineWidth := widths[0]
+	for visible < len(columns) && lineWidth+3+widths[visible] <= r.config.MaxWidth {
+		lineWidth += 3 + widths[visible]
+		visible++
+	}
+
+	var b strings.Builder
+	writeRow := func(values []string) {
+		parts := make([]string, visible)
+		for i := 0; i < visible; i++ {
+			parts[i] = pad(values[i], widths[i], numeric[i])
+		}
+		if format == ResultFormatMarkdown {
+			b.WriteString("| " + strings.Join(parts, " | ") + " |\n")
+		} else {
+			b.WriteString(strings.TrimRight(strings.Join(parts, " | "), " ") + "\n")
+		}
+	}
+
+	writeRow(header)
+	rules := make([]string, visible)
+	for i := 0; i < visible; i++ {
+		rules[i] = strings.Repeat("-", widths[i])
+		if format == ResultFormatMarkdown && numeric[i] {
+			rules[i] = strings.Repeat("-", widths[i]-1) + ":"
+		}
+	}
+	if format == ResultFormatMarkdown {
+		b.WriteString("| " + strings.Join(rules, " | ") + " |\n")
+	} else {
+		b.WriteString(strings.Join(rules, "-+-") + "\n")
+	}
+	for _, row := range cells {
+		writeRow(row)
+	}
+
+	if len(rows) == 0 {
+		b.WriteString(Localize(locale, "（无数据）") + "\n")
+	}
+	if hidden := len(rows) - len(shown); hidden > 0 {
+		b.WriteString(fmt.Sprintf(Localize(locale, "… 另有%d行未显示"), hidden) + "\n")
+	}
+	if hidden := len(columns) - visible; hidden > 0 {
+		b.WriteString(fmt.Sprintf(Localize(locale, "… 另有%d列未显示：%s"), hidden, strings.Join(columns[visible:], ", ")) + "\n")
+	}
+	return b.String()
+}
+
+// cellText 单元格文本：换行与制表符替换为空格，Markdown中转义竖线，超宽时截断
+func (r *ResultTableRenderer) cellText(text, format string) string {
+	text = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(text)
+	if format == ResultFormatMarkdown {
+		text = strings.ReplaceAll(text, "|", `\|`)
+	}
+	if displayWidth(text) <= r.config.MaxColumnWidth {
+		return text
+	}
+
+	limit := r.config.MaxColumnWidth - displayWidth(truncationMarker)
+	width := 0
+	for i, rn := range text {
+		if width+runeWidth(rn) > limit {
+			return text[:i] + truncationMarker
+		}
+		width += runeWidth(rn)
+	}
+	return text
+}
+
+// formatResultValue 格式化单元格值
+func formatResultValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isNumericValue 数值列右对齐
+func isNumericValue(value any) bool {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
+// pad 按显示宽度补齐，数值右对齐
+func pad(text string, width int, right bool) string {
+	padding := strings.Repeat(" ", max(width-displayWidth(text), 0))
+	if right {
+		return padding + text
+	}
+	return text + padding
+}
+
+// displayWidth 文本在等宽字体下的显示宽度
+func displayWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth 中日韩字符与全角符号占两列
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF01 && r <= 0xFF60) {
+		return 2
+	}
+	return 1
+}
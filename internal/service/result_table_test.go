@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestResultTableRenderer_Render(t *testing.T) {
+	columns := []string{"city", "orders", "first_order"}
+	rows := []map[string]any{
+		{"city": "上海", "orders": int64(1200), "first_order": time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"city": "New York", "orders": int64(87), "first_order": nil},
+	}
+	r := NewResultTableRenderer(nil)
+
+	assert.Equal(t,
+		"city     | orders | first_order\n"+
+			"---------+--------+------------\n"+
+			"上海     |   1200 | 2024-01-05\n"+
+			"New York |     87 | NULL\n",
+		r.Render(ResultFormatText, columns, rows, ""), "中文按两列宽度对齐，数值列右对齐")
+
+	assert.Equal(t,
+		"| city     | orders | first_order |\n"+
+			"| -------- | -----: | ----------- |\n"+
+			"| 上海     |   1200 | 2024-01-05  |\n"+
+			"| New York |     87 | NULL        |\n",
+		r.Render(ResultFormatMarkdown, columns, rows, ""))
+
+	assert.Empty(t, r.Render("json", columns, rows, ""))
+	assert.Equal(t, "city | orders | first_order\n-----+--------+------------\n(no rows)\n",
+		r.Render(ResultFormatText, columns, nil, "en"))
+}
+
+func TestResultTableRenderer_Truncation(t *testing.T) {
+	r := NewResultTableRenderer(&config.ResultTableConfig{MaxRows: 2, MaxColumnWidth: 8, MaxWidth: 24})
+	columns := []string{"id", "note", "extra_a", "extra_b"}
+	rows := []map[string]any{
+		{"id": 1, "note": "a|very long\nnote", "extra_a": "x", "extra_b": "y"},
+		{"id": 2, "note": "短", "extra_a": "x", "extra_b": "y"},
+		{"id": 3, "note": "c", "extra_a": "x", "extra_b": "y"},
+	}
+
+	assert.Equal(t,
+		"|  id | note     | extra_a |\n"+
+			"| --: | -------- | ------- |\n"+
+			"|   1 | a\\|very… | x       |\n"+
+			"|   2 | 短       | x       |\n"+
+			"… 1 more rows not shown\n"+
+			"… 1 more columns not shown: extra_b\n",
+		r.Render(ResultFormatMarkdown, columns, rows, "en"))
+}
+
+func TestResultTableRenderer_RenderWithHeaders(t *testing.T) {
+	columns := []string{"city", "sum_1"}
+	rows := []map[string]any{{"city": "上海", "sum_1": int64(1200)}}
+	r := NewResultTableRenderer(nil)
+
+	assert.Equal(t,
+		"城市 | 总销售额\n"+
+			"-----+---------\n"+
+			"上海 |     1200\n",
+		r.RenderWithHeaders(ResultFormatText, columns, []string{"城市", "总销售额"}, rows, ""))
+	assert.Equal(t, r.Render(ResultFormatText, columns, rows, ""),
+		r.RenderWithHeaders(ResultFormatText, columns, []string{"城市"}, rows, ""), "表头数量不符时使用列名")
+}
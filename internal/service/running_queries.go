@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// runningQuerySQLLimit 运行中查询保留的SQL最大长度（字符）
+const runningQuerySQLLimit = 200
+
+var (
+	// ErrQueryCancelled 查询被用户取消，作为查询context的取消原因
+	ErrQueryCancelled = errors.New("查询已被取消")
+	// ErrRunningQueryNotFound 查询不存在、已结束或不属于该用户
+	ErrRunningQueryNotFound = errors.New("运行中的查询不存在")
+)
+
+// RunningQuery 正在执行的查询
+type RunningQuery struct {
+	ID           uint64    `json:"id"`
+	UserID       int64     `json:"-"`
+	ConnectionID int64     `json:"connection_id"`
+	SQL          string    `json:"sql"`
+	StartedAt    time.Time `json:"started_at"`
+
+	cancel context.CancelCauseFunc
+}
+
+// RunningQueryRegistry 正在执行的查询登记表
+// SQL执行器在查询开始时登记、结束时注销，用户可据此查看自己当前有哪些查询在执行，并取消失控的查询
+type RunningQueryRegistry struct {
+	nextID  atomic.Uint64
+	mu      sync.RWMutex
+	queries map[uint64]*RunningQuery
+}
+
+// NewRunningQueryRegistry 创建运行中查询登记表
+func NewRunningQueryRegistry() *RunningQueryRegistry {
+	return &RunningQueryRegistry{queries: make(map[uint64]*RunningQuery)}
+}
+
+// queryOwnerKey 查询发起用户的context键
+type queryOwnerKey struct{}
+
+// WithQueryOwner 标记本次查询的发起用户，执行器据此登记运行中查询
+func WithQueryOwner(ctx context.Context, userID int64) context.Context {
+	if userID <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryOwnerKey{}, userID)
+}
+
+// queryOwnerFromContext 读取本次查询的发起用户，未设置时返回0
+func queryOwnerFromContext(ctx context.Context) int64 {
+	userID, _ := ctx.Value(queryOwnerKey{}).(int64)
+	return userID
+}
+
+// Track 登记一次查询，返回执行查询应使用的context与查询结束时调用的注销函数
+// 查询被Cancel取消时该context以ErrQueryCancelled为原因取消；登记表为nil或context中没有发起用户时不登记，原样返回ctx
+func (r *RunningQueryRegistry) Track(ctx context.Context, connectionID int64, sql string) (context.Context, func()) {
+	if r == nil {
+		return ctx, func() {}
+	}
+	userID := queryOwnerFromContext(ctx)
+	if userID == 0 {
+		return ctx, func() {}
+	}
+
+	if utf8.RuneCountInString(sql) > runningQuerySQLLimit {
+		sql = string([]rune(sql)[:runningQuerySQLLimit]) + "..."
+	}
+	query := &RunningQuery{
+		ID:           r.nextID.Add(1),
+		UserID:       userID,
+		ConnectionID: connectionID,
+		SQL:          sql,
+		StartedAt:    time.Now(),
+	}
+	ctx, query.cancel = context.WithCancelCause(ctx)
+
+	r.mu.Lock()
+	r.queries[query.ID] = query
+	r.mu.Unlock()
+
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.queries, query.ID)
+		r.mu.Unlock()
+		query.cancel(nil)
+	}
+}
+
+// Cancel 取消正在执行的查询，执行器随即中止数据库上的执行
+// asAdmin为false时只能取消userID自己的查询，不属于该用户的查询按不存在处理
+func (r *RunningQueryRegistry) Cancel(id uint64, userID int64, asAdmin bool) error {
+	if r == nil {
+		return ErrRunningQueryNotFound
+	}
+	r.mu.RLock()
+	query, ok := r.queries[id]
+	r.mu.RUnlock()
+	if !ok || (!asAdmin && query.UserID != userID) {
+		return ErrRunningQueryNotFound
+	}
+	query.cancel(ErrQueryCancelled)
+	return nil
+}
+
+// QueryCancelled 查询是否因被取消而中止
+func QueryCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrQueryCancelled)
+}
+
+// ListByUser 返回用户正在执行的查询，按开始时间排序，登记表为nil时返回空列表
+func (r *RunningQueryRegistry) ListByUser(userID int64) []RunningQuery {
+	if r == nil {
+		return []RunningQuery{}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	queries := make([]RunningQuery, 0)
+	for _, q := range r.queries {
+		if q.UserID == userID {
+			queries = append(queries, *q)
+		}
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].StartedAt.Before(queries[j].StartedAt) })
+	return queries
+}
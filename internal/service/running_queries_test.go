@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunningQueryRegistry(t *testing.T) {
+	registry := NewRunningQueryRegistry()
+
+	// 未标记发起用户的查询不登记
+	_, finish := registry.Track(context.Background(), 1, "SELECT 1")
+	finish()
+	assert.Empty(t, registry.ListByUser(7))
+
+	ctx := WithQueryOwner(context.Background(), 7)
+	_, finishFirst := registry.Track(ctx, 1, "SELECT * FROM orders")
+	_, finishSecond := registry.Track(ctx, 2, strings.Repeat("x", 300))
+	_, finishOther := registry.Track(WithQueryOwner(context.Background(), 8), 3, "SELECT 2")
+
+	running := registry.ListByUser(7)
+	require.Len(t, running, 2)
+	assert.Equal(t, int64(1), running[0].ConnectionID, "按开始时间排序")
+	assert.Equal(t, runningQuerySQLLimit+3, len(running[1].SQL), "过长的SQL被截断")
+
+	finishFirst()
+	running = registry.ListByUser(7)
+	require.Len(t, running, 1)
+	assert.Equal(t, int64(2), running[0].ConnectionID)
+
+	finishSecond()
+	finishOther()
+	assert.Empty(t, registry.ListByUser(7))
+	assert.Empty(t, registry.ListByUser(8))
+
+	var disabled *RunningQueryRegistry
+	_, finish = disabled.Track(ctx, 1, "SELECT 1")
+	finish()
+	assert.NotNil(t, disabled.ListByUser(7), "登记表为nil时返回空列表")
+}
+
+func TestRunningQueryRegistry_Cancel(t *testing.T) {
+	registry := NewRunningQueryRegistry()
+	ctx, finish := registry.Track(WithQueryOwner(context.Background(), 7), 1, "SELECT pg_sleep(60)")
+	defer finish()
+	id := registry.ListByUser(7)[0].ID
+
+	assert.ErrorIs(t, registry.Cancel(id, 8, false), ErrRunningQueryNotFound, "不能取消他人的查询")
+	assert.NoError(t, ctx.Err())
+
+	require.NoError(t, registry.Cancel(id, 7, false))
+	<-ctx.Done()
+	assert.True(t, QueryCancelled(ctx))
+	assert.False(t, QueryCancelled(context.Background()))
+
+	// 管理员可以取消任何人的查询，已结束的查询不能取消
+	adminCtx, finishAdmin := registry.Track(WithQueryOwner(context.Background(), 8), 2, "SELECT 1")
+	require.NoError(t, registry.Cancel(registry.ListByUser(8)[0].ID, 1, true))
+	assert.True(t, QueryCancelled(adminCtx))
+	finishAdmin()
+	assert.ErrorIs(t, registry.Cancel(999, 7, true), ErrRunningQueryNotFound)
+
+	// 查询正常结束后context被释放，但不视为取消
+	doneCtx, finishDone := registry.Track(WithQueryOwner(context.Background(), 7), 1, "SELECT 2")
+	finishDone()
+	assert.Error(t, doneCtx.Err())
+	assert.False(t, QueryCancelled(doneCtx))
+
+	var disabled *RunningQueryRegistry
+	assert.ErrorIs(t, disabled.Cancel(id, 7, false), ErrRunningQueryNotFound)
+}
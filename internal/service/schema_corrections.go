@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"chat2sql-go/internal/ai"
+)
+
+// schemaCorrectionName 纠正目标在提示词与说明中的写法，列带上所在的表
+func schemaCorrectionName(c ai.SchemaCorrection) string {
+	if c.Kind == ai.SchemaCorrectionColumn && c.Table != "" {
+		return c.Table + "." + c.Name
+	}
+	return c.Name
+}
+
+// localizeSchemaCorrections 按用户语言填写纠正说明，如“costumers”按表customers理解；不修改传入的切片
+func localizeSchemaCorrections(corrections []ai.SchemaCorrection, locale string) []ai.SchemaCorrection {
+	if len(corrections) == 0 {
+		return nil
+	}
+	localized := make([]ai.SchemaCorrection, len(corrections))
+	for i, c := range corrections {
+		format := "“%s”按表%s理解"
+		if c.Kind == ai.SchemaCorrectionColumn {
+			format = "“%s”按列%s理解"
+		}
+		c.Note = fmt.Sprintf(Localize(locale, format), c.Text, schemaCorrectionName(c))
+		localized[i] = c
+	}
+	return localized
+}
+
+// schemaCorrectionsSection 构建拼写纠正提示，问题中没有拼错的表名、列名时为空
+func schemaCorrectionsSection(corrections []ai.SchemaCorrection) string {
+	if len(corrections) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n## 表名与列名纠正：\n用户查询中以下单词与表结构中的名称不完全一致，按拼写错误理解为对应的表或列，生成SQL时使用表结构中的名称：\n")
+	for _, c := range corrections {
+		kind := "表"
+		if c.Kind == ai.SchemaCorrectionColumn {
+			kind = "列"
+		}
+		fmt.Fprintf(&b, "- \"%s\" = %s%s\n", c.Text, kind, schemaCorrectionName(c))
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+func TestAIService_GenerateSQL_SchemaCorrections(t *testing.T) {
+	schemas := &memSchemaRepository{metadata: map[int64][]*repository.SchemaMetadata{
+		1: schemaMetadataList(testDatabaseSchema(1, map[string][]ColumnInfo{
+			"customers":  {{ColumnName: "id", DataType: "bigint", IsPrimaryKey: true}, {ColumnName: "country", DataType: "varchar"}},
+			"audit_logs": {{ColumnName: "id", DataType: "bigint", IsPrimaryKey: true}},
+		})),
+	}}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), &fixedLLM{content: "SELECT COUNT(*) FROM customers"}, &fixedLLM{}, zaptest.NewLogger(t))
+	aiService.SetPromptBuilder(ai.NewPromptBuilder(schemas, nil, zaptest.NewLogger(t)))
+
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "how many costumers per contry", ConnectionID: 1, Locale: "en"})
+	require.NoError(t, err)
+	assert.Equal(t, []ai.SchemaCorrection{
+		{Text: "costumers", Name: "customers", Kind: ai.SchemaCorrectionTable, Note: `Interpreted "costumers" as table customers`},
+		{Text: "contry", Name: "country", Kind: ai.SchemaCorrectionColumn, Table: "customers", Note: `Interpreted "contry" as column customers.country`},
+	}, resp.SchemaCorrections)
+	assert.Contains(t, resp.Prompt, "## 表名与列名纠正：")
+	assert.Contains(t, resp.Prompt, `- "costumers" = 表customers`)
+	assert.Contains(t, resp.Prompt, `- "contry" = 列customers.country`)
+	assert.Contains(t, resp.Prompt, "customers(id bigint PK", "纠正后的表写入提示词")
+	assert.NotContains(t, resp.Prompt, "audit_logs(")
+}
+
+func TestLocalizeSchemaCorrections(t *testing.T) {
+	corrections := []ai.SchemaCorrection{{Text: "ordr_items", Name: "order_items", Kind: ai.SchemaCorrectionTable}}
+
+	localized := localizeSchemaCorrections(corrections, "zh")
+	assert.Equal(t, "“ordr_items”按表order_items理解", localized[0].Note)
+	assert.Empty(t, corrections[0].Note, "不修改传入的切片")
+	assert.Nil(t, localizeSchemaCorrections(nil, "en"))
+	assert.Empty(t, schemaCorrectionsSection(nil))
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"sort"
 	"strings"
@@ -160,33 +161,27 @@ func (si *SchemaIntrospector) IntrospectDatabase(ctx context.Context, connection
 	defer cancel()
 	
 	// 获取数据库连接
-	pool, err := si.connectionManager.GetConnectionPool(introspectCtx, connectionID)
+	dbType, err := si.connectionManager.GetDBType(introspectCtx, connectionID)
 	if err != nil {
 		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
 	}
 	
-	// 获取所有schema
-	schemas, err := si.getSchemas(introspectCtx, pool)
+	var schemaInfos []SchemaInfo
+	switch dbType {
+	case repository.DBTypeMySQL:
+		schemaInfos, err = si.introspectSQLDatabase(introspectCtx, connectionID, si.introspectMySQL)
+	case repository.DBTypeSQLite:
+		schemaInfos, err = si.introspectSQLDatabase(introspectCtx, connectionID, si.introspectSQLite)
+	default:
+		schemaInfos, err = si.introspectPostgreSQLDatabase(introspectCtx, connectionID)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("获取schema列表失败: %w", err)
+		return nil, err
 	}
 	
-	// 探测每个schema的表结构
-	var schemaInfos []SchemaInfo
 	var totalTables, totalColumns int
-	
-	for _, schemaName := range schemas {
-		schemaInfo, err := si.introspectSchema(introspectCtx, pool, schemaName)
-		if err != nil {
-			si.logger.Warn("探测schema失败",
-				zap.String("schema", schemaName),
-				zap.Error(err))
-			continue
-		}
-		
-		schemaInfos = append(schemaInfos, *schemaInfo)
+	for _, schemaInfo := range schemaInfos {
 		totalTables += schemaInfo.TableCount
-		
 		for _, table := range schemaInfo.Tables {
 			totalColumns += table.ColumnCount
 		}
@@ -210,6 +205,50 @@ func (si *SchemaIntrospector) IntrospectDatabase(ctx context.Context, connection
 	return databaseSchema, nil
 }
 
+// introspectPostgreSQLDatabase 探测PostgreSQL数据库中除系统schema外的所有schema
+func (si *SchemaIntrospector) introspectPostgreSQLDatabase(ctx context.Context, connectionID int64) ([]SchemaInfo, error) {
+	pool, err := si.connectionManager.GetConnectionPool(ctx, connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	
+	// 获取所有schema
+	schemas, err := si.getSchemas(ctx, pool)
+	if err != nil {
+		return nil, fmt.Errorf("获取schema列表失败: %w", err)
+	}
+	
+	// 探测每个schema的表结构
+	var schemaInfos []SchemaInfo
+	for _, schemaName := range schemas {
+		schemaInfo, err := si.introspectSchema(ctx, pool, schemaName)
+		if err != nil {
+			si.logger.Warn("探测schema失败",
+				zap.String("schema", schemaName),
+				zap.Error(err))
+			continue
+		}
+		
+		schemaInfos = append(schemaInfos, *schemaInfo)
+	}
+	
+	return schemaInfos, nil
+}
+
+// introspectSQLDatabase 探测MySQL、SQLite等通过database/sql访问的数据库
+func (si *SchemaIntrospector) introspectSQLDatabase(ctx context.Context, connectionID int64, introspect func(context.Context, *sql.DB) ([]SchemaInfo, error)) ([]SchemaInfo, error) {
+	db, err := si.connectionManager.GetSQLDB(ctx, connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	
+	schemaInfos, err := introspect(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("探测数据库失败: %w", err)
+	}
+	return schemaInfos, nil
+}
+
 // getSchemas 获取数据库中的所有schema
 func (si *SchemaIntrospector) getSchemas(ctx context.Context, pool *pgxpool.Pool) ([]string, error) {
 	query := `
@@ -649,12 +688,28 @@ func (si *SchemaIntrospector) SaveSchemaMetadata(ctx context.Context, databaseSc
 	}
 	
 	// 转换为SchemaMetadata格式并批量保存
-	var metadataList []*repository.SchemaMetadata
+	metadataList := schemaMetadataList(databaseSchema)
+	
+	if len(metadataList) > 0 {
+		if err := si.schemaRepo.BatchCreate(ctx, metadataList); err != nil {
+			return fmt.Errorf("保存Schema元数据失败: %w", err)
+		}
+	}
+	
+	si.logger.Info("Schema元数据保存成功",
+		zap.Int64("connection_id", databaseSchema.ConnectionID),
+		zap.Int("metadata_count", len(metadataList)))
 	
+	return nil
+}
+
+// schemaMetadataList 将探测结果转换为按列保存的元数据
+func schemaMetadataList(databaseSchema *DatabaseSchema) []*repository.SchemaMetadata {
+	var metadataList []*repository.SchemaMetadata
 	for _, schema := range databaseSchema.Schemas {
 		for _, table := range schema.Tables {
 			for _, column := range table.Columns {
-				metadata := &repository.SchemaMetadata{
+				metadataList = append(metadataList, &repository.SchemaMetadata{
 					ConnectionID:    databaseSchema.ConnectionID,
 					SchemaName:      schema.SchemaName,
 					TableName:       table.TableName,
@@ -669,24 +724,11 @@ func (si *SchemaIntrospector) SaveSchemaMetadata(ctx context.Context, databaseSc
 					TableComment:    table.TableComment,
 					ColumnComment:   column.ColumnComment,
 					OrdinalPosition: column.OrdinalPosition,
-				}
-				
-				metadataList = append(metadataList, metadata)
+				})
 			}
 		}
 	}
-	
-	if len(metadataList) > 0 {
-		if err := si.schemaRepo.BatchCreate(ctx, metadataList); err != nil {
-			return fmt.Errorf("保存Schema元数据失败: %w", err)
-		}
-	}
-	
-	si.logger.Info("Schema元数据保存成功",
-		zap.Int64("connection_id", databaseSchema.ConnectionID),
-		zap.Int("metadata_count", len(metadataList)))
-	
-	return nil
+	return metadataList
 }
 
 // RefreshConnectionMetadata 刷新指定连接的元数据
@@ -0,0 +1,281 @@
+// 表结构定期刷新
+// 元数据只在创建连接（预热）或手动刷新时保存，用户改表后提示词中的表结构就会过时。
+// 刷新任务定期重新探测活跃连接的表结构，与已保存的元数据逐列比较，有变化时整体替换该连接的元数据，
+// 并按表或列记录变更事件，供查询失败时间线对照
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// schemaRefreshIntrospector 刷新使用的表结构探测接口，由SchemaIntrospector实现
+type schemaRefreshIntrospector interface {
+	IntrospectDatabase(ctx context.Context, connectionID int64) (*DatabaseSchema, error)
+}
+
+// SchemaDiff 探测结果与已保存元数据的差异，表名为schema.table，列名为schema.table.column
+type SchemaDiff struct {
+	AddedTables    []string `json:"added_tables,omitempty"`
+	RemovedTables  []string `json:"removed_tables,omitempty"`
+	AddedColumns   []string `json:"added_columns,omitempty"`   // 已有表中新增的列
+	RemovedColumns []string `json:"removed_columns,omitempty"` // 仍存在的表中删除的列
+	ChangedColumns []string `json:"changed_columns,omitempty"` // 类型、可空、键、默认值或注释变化的列
+}
+
+// Empty 判断表结构是否没有变化
+func (d *SchemaDiff) Empty() bool {
+	return len(d.AddedTables) == 0 && len(d.RemovedTables) == 0 &&
+		len(d.AddedColumns) == 0 && len(d.RemovedColumns) == 0 && len(d.ChangedColumns) == 0
+}
+
+// Events 将差异展开为逐表或逐列的变更事件
+func (d *SchemaDiff) Events(connectionID int64, detectedAt time.Time) []*repository.SchemaChangeEvent {
+	var events []*repository.SchemaChangeEvent
+	for _, group := range []struct {
+		changeType repository.SchemaChangeType
+		names      []string
+	}{
+		{repository.SchemaChangeAddedTable, d.AddedTables},
+		{repository.SchemaChangeRemovedTable, d.RemovedTables},
+		{repository.SchemaChangeAddedColumn, d.AddedColumns},
+		{repository.SchemaChangeRemovedColumn, d.RemovedColumns},
+		{repository.SchemaChangeChangedColumn, d.ChangedColumns},
+	} {
+		for _, name := range group.names {
+			events = append(events, &repository.SchemaChangeEvent{
+				ConnectionID: connectionID,
+				ChangeType:   group.changeType,
+				ObjectName:   name,
+				DetectedAt:   detectedAt,
+			})
+		}
+	}
+	return events
+}
+
+// SchemaRefreshResult 一轮表结构刷新的结果
+type SchemaRefreshResult struct {
+	Connections int `json:"connections"` // 检查的活跃连接数
+	Updated     int `json:"updated"`     // 表结构有变化并已更新的连接数
+	Failed      int `json:"failed"`      // 探测或保存失败的连接数
+}
+
+// SchemaRefreshService 表结构定期刷新服务
+type SchemaRefreshService struct {
+	connections  repository.ConnectionRepository
+	schemas      repository.SchemaRepository
+	introspector schemaRefreshIntrospector
+	changes      repository.SchemaChangeRepository // 为nil时不记录变更事件
+	config       *config.SchemaRefreshConfig
+	logger       *zap.Logger
+}
+
+// NewSchemaRefreshService 创建表结构刷新服务，配置为nil时使用默认配置
+func NewSchemaRefreshService(
+	connections repository.ConnectionRepository,
+	schemas repository.SchemaRepository,
+	introspector schemaRefreshIntrospector,
+	refreshConfig *config.SchemaRefreshConfig,
+	logger *zap.Logger,
+) *SchemaRefreshService {
+	if refreshConfig == nil {
+		refreshConfig = config.DefaultSchemaRefreshConfig()
+	}
+	return &SchemaRefreshService{
+		connections:  connections,
+		schemas:      schemas,
+		introspector: introspector,
+		config:       refreshConfig,
+		logger:       logger,
+	}
+}
+
+// SetChangeRepository 设置变更事件存储，表结构有变化时按表或列记录变更事件
+func (s *SchemaRefreshService) SetChangeRepository(changes repository.SchemaChangeRepository) {
+	s.changes = changes
+}
+
+// Run 启动时与每隔Interval刷新一次全部活跃连接，直到ctx取消；由看门狗托管，每处理完一个连接调用beat上报心跳
+func (s *SchemaRefreshService) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := s.sweep(ctx, beat); err != nil {
+			s.logger.Warn("Failed to refresh connection schemas", zap.Error(err))
+		} else if result.Updated > 0 || result.Failed > 0 {
+			s.logger.Info("Refreshed connection schemas",
+				zap.Int("connections", result.Connections),
+				zap.Int("updated", result.Updated),
+				zap.Int("failed", result.Failed))
+		}
+		beat()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sweep 刷新一轮全部活跃连接，单个连接失败只记录日志，下一轮重试
+func (s *SchemaRefreshService) Sweep(ctx context.Context) (*SchemaRefreshResult, error) {
+	return s.sweep(ctx, func() {})
+}
+
+func (s *SchemaRefreshService) sweep(ctx context.Context, beat func()) (*SchemaRefreshResult, error) {
+	connections, err := s.connections.ListByStatus(ctx, repository.ConnectionActive)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SchemaRefreshResult{Connections: len(connections)}
+	for _, conn := range connections {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		diff, err := s.Refresh(ctx, conn.ID)
+		beat()
+		if err != nil {
+			result.Failed++
+			s.logger.Warn("Failed to refresh connection schema",
+				zap.Int64("connection_id", conn.ID),
+				zap.Error(err))
+			continue
+		}
+		if !diff.Empty() {
+			result.Updated++
+		}
+	}
+	return result, nil
+}
+
+// Refresh 重新探测连接的表结构，与已保存的元数据比较，有变化时整体替换并返回差异
+// 探测不到任何表而已保存的元数据不为空时，多半是权限变化或探测异常，不覆盖已有元数据
+func (s *SchemaRefreshService) Refresh(ctx context.Context, connectionID int64) (*SchemaDiff, error) {
+	databaseSchema, err := s.introspector.IntrospectDatabase(ctx, connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("探测数据库Schema失败: %w", err)
+	}
+	discovered := schemaMetadataList(databaseSchema)
+
+	stored, err := s.schemas.ListByConnection(ctx, connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("查询已保存的元数据失败: %w", err)
+	}
+
+	diff := DiffSchemaMetadata(stored, discovered)
+	if diff.Empty() {
+		return diff, nil
+	}
+	if len(discovered) == 0 {
+		return nil, fmt.Errorf("未探测到任何表，保留已有的%d条元数据", len(stored))
+	}
+
+	if err := s.schemas.RefreshConnectionMetadata(ctx, connectionID, discovered); err != nil {
+		return nil, fmt.Errorf("保存Schema元数据失败: %w", err)
+	}
+	s.logger.Info("Connection schema changed",
+		zap.Int64("connection_id", connectionID),
+		zap.Strings("added_tables", diff.AddedTables),
+		zap.Strings("removed_tables", diff.RemovedTables),
+		zap.Strings("added_columns", diff.AddedColumns),
+		zap.Strings("removed_columns", diff.RemovedColumns),
+		zap.Strings("changed_columns", diff.ChangedColumns))
+
+	// 元数据已更新，变更事件写入失败只影响时间线，不让本次刷新失败
+	if s.changes != nil {
+		if err := s.changes.CreateBatch(ctx, diff.Events(connectionID, time.Now().UTC())); err != nil {
+			s.logger.Warn("Failed to record schema change events",
+				zap.Int64("connection_id", connectionID),
+				zap.Error(err))
+		}
+	}
+	return diff, nil
+}
+
+// DiffSchemaMetadata 逐列比较已保存与新探测的元数据
+// 新增或删除的整张表只记在AddedTables/RemovedTables中，不再逐列列出
+func DiffSchemaMetadata(stored, discovered []*repository.SchemaMetadata) *SchemaDiff {
+	storedColumns, storedTables := indexSchemaMetadata(stored)
+	discoveredColumns, discoveredTables := indexSchemaMetadata(discovered)
+
+	diff := &SchemaDiff{}
+	for table := range discoveredTables {
+		if !storedTables[table] {
+			diff.AddedTables = append(diff.AddedTables, table)
+		}
+	}
+	for table := range storedTables {
+		if !discoveredTables[table] {
+			diff.RemovedTables = append(diff.RemovedTables, table)
+		}
+	}
+
+	for key, column := range discoveredColumns {
+		old, ok := storedColumns[key]
+		switch {
+		case !ok && storedTables[column.SchemaName+"."+column.TableName]:
+			diff.AddedColumns = append(diff.AddedColumns, key)
+		case ok && !sameColumnMetadata(old, column):
+			diff.ChangedColumns = append(diff.ChangedColumns, key)
+		}
+	}
+	for key, column := range storedColumns {
+		if _, ok := discoveredColumns[key]; !ok && discoveredTables[column.SchemaName+"."+column.TableName] {
+			diff.RemovedColumns = append(diff.RemovedColumns, key)
+		}
+	}
+
+	for _, names := range [][]string{diff.AddedTables, diff.RemovedTables, diff.AddedColumns, diff.RemovedColumns, diff.ChangedColumns} {
+		sort.Strings(names)
+	}
+	return diff
+}
+
+// indexSchemaMetadata 按schema.table.column索引列，并返回出现过的表
+func indexSchemaMetadata(metadata []*repository.SchemaMetadata) (map[string]*repository.SchemaMetadata, map[string]bool) {
+	columns := make(map[string]*repository.SchemaMetadata, len(metadata))
+	tables := make(map[string]bool)
+	for _, m := range metadata {
+		table := m.SchemaName + "." + m.TableName
+		tables[table] = true
+		columns[table+"."+m.ColumnName] = m
+	}
+	return columns, tables
+}
+
+// sameColumnMetadata 比较提示词会用到的列属性，列位置变化不算表结构变化
+func sameColumnMetadata(a, b *repository.SchemaMetadata) bool {
+	return a.DataType == b.DataType &&
+		a.IsNullable == b.IsNullable &&
+		a.IsPrimaryKey == b.IsPrimaryKey &&
+		a.IsForeignKey == b.IsForeignKey &&
+		sameOptionalString(a.ColumnDefault, b.ColumnDefault) &&
+		sameOptionalString(a.ForeignTable, b.ForeignTable) &&
+		sameOptionalString(a.ForeignColumn, b.ForeignColumn) &&
+		sameOptionalString(a.TableComment, b.TableComment) &&
+		sameOptionalString(a.ColumnComment, b.ColumnComment)
+}
+
+// sameOptionalString 比较可空字符串，nil与空字符串视为相同
+func sameOptionalString(a, b *string) bool {
+	var x, y string
+	if a != nil {
+		x = *a
+	}
+	if b != nil {
+		y = *b
+	}
+	return x == y
+}
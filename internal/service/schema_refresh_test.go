@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// activeConnectionRepository 只实现ListByStatus的连接Repository
+type activeConnectionRepository struct {
+	repository.ConnectionRepository
+	ids []int64
+}
+
+func (r *activeConnectionRepository) ListByStatus(ctx context.Context, status repository.ConnectionStatus) ([]*repository.DatabaseConnection, error) {
+	var connections []*repository.DatabaseConnection
+	for _, id := range r.ids {
+		connections = append(connections, testutil.NewConnection(id, 7, testutil.WithConnectionStatus(status)))
+	}
+	return connections, nil
+}
+
+// memSchemaRepository 按连接保存元数据的Schema Repository
+type memSchemaRepository struct {
+	repository.SchemaRepository
+	metadata  map[int64][]*repository.SchemaMetadata
+	refreshed []int64
+}
+
+func (r *memSchemaRepository) ListByConnection(ctx context.Context, connectionID int64) ([]*repository.SchemaMetadata, error) {
+	return r.metadata[connectionID], nil
+}
+
+func (r *memSchemaRepository) RefreshConnectionMetadata(ctx context.Context, connectionID int64, schemas []*repository.SchemaMetadata) error {
+	r.metadata[connectionID] = schemas
+	r.refreshed = append(r.refreshed, connectionID)
+	return nil
+}
+
+// stubSchemaIntrospector 返回预设表结构的探测器
+type stubSchemaIntrospector struct {
+	schemas map[int64]*DatabaseSchema
+}
+
+func (s *stubSchemaIntrospector) IntrospectDatabase(ctx context.Context, connectionID int64) (*DatabaseSchema, error) {
+	schema, ok := s.schemas[connectionID]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return schema, nil
+}
+
+func testDatabaseSchema(connectionID int64, tables map[string][]ColumnInfo) *DatabaseSchema {
+	info := SchemaInfo{SchemaName: "public"}
+	for name, columns := range tables {
+		info.Tables = append(info.Tables, TableInfo{SchemaName: "public", TableName: name, Columns: columns})
+	}
+	return &DatabaseSchema{ConnectionID: connectionID, Schemas: []SchemaInfo{info}}
+}
+
+func TestSchemaRefreshService_Sweep(t *testing.T) {
+	ordersV1 := []ColumnInfo{{ColumnName: "id", DataType: "bigint", IsPrimaryKey: true}, {ColumnName: "amount", DataType: "integer"}}
+	ordersV2 := []ColumnInfo{{ColumnName: "id", DataType: "bigint", IsPrimaryKey: true}, {ColumnName: "amount", DataType: "numeric"}, {ColumnName: "status", DataType: "text"}}
+	users := []ColumnInfo{{ColumnName: "id", DataType: "bigint", IsPrimaryKey: true}}
+
+	schemas := &memSchemaRepository{metadata: map[int64][]*repository.SchemaMetadata{
+		1: schemaMetadataList(testDatabaseSchema(1, map[string][]ColumnInfo{"orders": ordersV1, "legacy": users})),
+		2: schemaMetadataList(testDatabaseSchema(2, map[string][]ColumnInfo{"users": users})),
+	}}
+	introspector := &stubSchemaIntrospector{schemas: map[int64]*DatabaseSchema{
+		1: testDatabaseSchema(1, map[string][]ColumnInfo{"orders": ordersV2, "users": users}),
+		2: testDatabaseSchema(2, map[string][]ColumnInfo{"users": users}),
+	}}
+	svc := NewSchemaRefreshService(&activeConnectionRepository{ids: []int64{1, 2, 3}}, schemas, introspector, nil, zaptest.NewLogger(t))
+	changes := &memSchemaChangeRepository{}
+	svc.SetChangeRepository(changes)
+
+	result, err := svc.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &SchemaRefreshResult{Connections: 3, Updated: 1, Failed: 1}, result)
+	assert.Equal(t, []int64{1}, schemas.refreshed, "表结构未变化的连接不重写元数据")
+	assert.Len(t, schemas.metadata[1], 4)
+	require.Len(t, changes.events, 4, "新增表、删除表、新增列与修改列各一条")
+	assert.Equal(t, repository.SchemaChangeAddedTable, changes.events[0].ChangeType)
+	assert.Equal(t, "public.users", changes.events[0].ObjectName)
+
+	diff, err := svc.Refresh(context.Background(), 1)
+	require.NoError(t, err)
+	assert.True(t, diff.Empty(), "刷新后再次比较应无差异")
+}
+
+func TestSchemaRefreshService_KeepsMetadataWhenNothingDiscovered(t *testing.T) {
+	stored := schemaMetadataList(testDatabaseSchema(1, map[string][]ColumnInfo{"orders": {{ColumnName: "id", DataType: "bigint"}}}))
+	schemas := &memSchemaRepository{metadata: map[int64][]*repository.SchemaMetadata{1: stored}}
+	introspector := &stubSchemaIntrospector{schemas: map[int64]*DatabaseSchema{1: {ConnectionID: 1}}}
+	svc := NewSchemaRefreshService(&activeConnectionRepository{}, schemas, introspector, nil, zaptest.NewLogger(t))
+
+	_, err := svc.Refresh(context.Background(), 1)
+	assert.Error(t, err)
+	assert.Empty(t, schemas.refreshed)
+	assert.Equal(t, stored, schemas.metadata[1])
+}
+
+func TestDiffSchemaMetadata(t *testing.T) {
+	comment := "订单金额"
+	stored := schemaMetadataList(testDatabaseSchema(1, map[string][]ColumnInfo{
+		"orders": {{ColumnName: "id", DataType: "bigint"}, {ColumnName: "amount", DataType: "integer"}, {ColumnName: "note", DataType: "text"}},
+		"legacy": {{ColumnName: "id", DataType: "bigint"}},
+	}))
+	discovered := schemaMetadataList(testDatabaseSchema(1, map[string][]ColumnInfo{
+		"orders":   {{ColumnName: "id", DataType: "bigint", OrdinalPosition: 9}, {ColumnName: "amount", DataType: "integer", ColumnComment: &comment}, {ColumnName: "status", DataType: "text"}},
+		"payments": {{ColumnName: "id", DataType: "bigint"}},
+	}))
+
+	diff := DiffSchemaMetadata(stored, discovered)
+	assert.Equal(t, []string{"public.payments"}, diff.AddedTables)
+	assert.Equal(t, []string{"public.legacy"}, diff.RemovedTables)
+	assert.Equal(t, []string{"public.orders.status"}, diff.AddedColumns)
+	assert.Equal(t, []string{"public.orders.note"}, diff.RemovedColumns)
+	assert.Equal(t, []string{"public.orders.amount"}, diff.ChangedColumns, "列位置变化不算表结构变化")
+
+	assert.True(t, DiffSchemaMetadata(stored, stored).Empty())
+}
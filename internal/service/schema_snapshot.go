@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// 表结构快照相关错误
+var (
+	ErrNoSchemaSnapshot       = errors.New("查询未记录表结构快照")
+	ErrSchemaSnapshotMismatch = errors.New("表结构快照不属于该连接")
+)
+
+// promptBuilder 构建SQL生成提示词，由AIService实现
+type promptBuilder interface {
+	BuildPrompt(req *SQLGenerationRequest) (string, error)
+}
+
+// PromptReproduction 按生成时的表结构快照重现的提示词
+type PromptReproduction struct {
+	QueryID      int64                      `json:"query_id"`
+	NaturalQuery string                     `json:"natural_query"`
+	GeneratedSQL string                     `json:"generated_sql"`
+	Snapshot     *repository.SchemaSnapshot `json:"snapshot"`
+	Prompt       string                     `json:"prompt"`
+}
+
+// SchemaSnapshotService 表结构快照
+// 生成SQL时记录提示词中使用的表结构，查询历史据此关联快照版本；
+// 之后即使表结构已变化，也能按生成时的结构重现提示词
+type SchemaSnapshotService struct {
+	repo    repository.SchemaSnapshotRepository
+	prompts promptBuilder
+	logger  *zap.Logger
+}
+
+// NewSchemaSnapshotService 创建表结构快照服务
+func NewSchemaSnapshotService(repo repository.SchemaSnapshotRepository, prompts promptBuilder, logger *zap.Logger) *SchemaSnapshotService {
+	return &SchemaSnapshotService{repo: repo, prompts: prompts, logger: logger}
+}
+
+// Record 记录生成SQL时使用的表结构，表结构为空时不记录并返回nil
+func (s *SchemaSnapshotService) Record(ctx context.Context, connectionID int64, schema string) (*repository.SchemaSnapshot, error) {
+	if connectionID <= 0 || strings.TrimSpace(schema) == "" {
+		return nil, nil
+	}
+	return s.repo.Record(ctx, connectionID, schema)
+}
+
+// Verify 校验客户端提交的快照属于执行SQL的连接，快照不存在时同样返回ErrSchemaSnapshotMismatch
+func (s *SchemaSnapshotService) Verify(ctx context.Context, snapshotID, connectionID int64) error {
+	snapshot, err := s.repo.GetByID(ctx, snapshotID)
+	if err != nil {
+		if repository.IsNotFound(err) {
+			return ErrSchemaSnapshotMismatch
+		}
+		return err
+	}
+	if snapshot.ConnectionID != connectionID {
+		return ErrSchemaSnapshotMismatch
+	}
+	return nil
+}
+
+// Reproduce 按查询历史关联的快照重现生成时的提示词
+// 受限列与语言提示取决于生成时的用户权限与设置，不在快照范围内，重现的提示词不包含这两部分
+func (s *SchemaSnapshotService) Reproduce(ctx context.Context, history *repository.QueryHistory) (*PromptReproduction, error) {
+	if history.SchemaSnapshotID == nil {
+		return nil, ErrNoSchemaSnapshot
+	}
+
+	snapshot, err := s.repo.GetByID(ctx, *history.SchemaSnapshotID)
+	if err != nil {
+		if repository.IsNotFound(err) {
+			return nil, ErrNoSchemaSnapshot
+		}
+		return nil, err
+	}
+	// 快照须属于查询执行的连接，避免通过查询历史读取其他连接的表结构
+	if !snapshotOfHistory(snapshot, history) {
+		return nil, ErrNoSchemaSnapshot
+	}
+
+	prompt, err := s.prompts.BuildPrompt(&SQLGenerationRequest{
+		Query:        history.NaturalQuery,
+		ConnectionID: snapshot.ConnectionID,
+		UserID:       history.UserID,
+		Schema:       snapshot.Schema,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("构建提示词失败: %w", err)
+	}
+
+	return &PromptReproduction{
+		QueryID:      history.ID,
+		NaturalQuery: history.NaturalQuery,
+		GeneratedSQL: history.GeneratedSQL,
+		Snapshot:     snapshot,
+		Prompt:       prompt,
+	}, nil
+}
+
+// snapshotOfHistory 判断快照是否属于查询历史执行的连接
+func snapshotOfHistory(snapshot *repository.SchemaSnapshot, history *repository.QueryHistory) bool {
+	return history.ConnectionID != nil && *history.ConnectionID == snapshot.ConnectionID
+}
+
+// schemaSnapshotKey 生成SQL时所用表结构快照的context键
+type schemaSnapshotKey struct{}
+
+// WithSchemaSnapshot 标记本次生成所用的表结构快照，自动执行写入查询历史时一并记录
+func WithSchemaSnapshot(ctx context.Context, snapshot *repository.SchemaSnapshot) context.Context {
+	if snapshot == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, schemaSnapshotKey{}, snapshot.ID)
+}
+
+// schemaSnapshotFromContext 读取本次生成所用的表结构快照ID，未设置时返回nil
+func schemaSnapshotFromContext(ctx context.Context) *int64 {
+	id, ok := ctx.Value(schemaSnapshotKey{}).(int64)
+	if !ok {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// fakeSchemaSnapshotRepo 内存表结构快照Repository，按内容去重
+type fakeSchemaSnapshotRepo struct {
+	snapshots []*repository.SchemaSnapshot
+}
+
+func (f *fakeSchemaSnapshotRepo) Record(ctx context.Context, connectionID int64, schemaText string) (*repository.SchemaSnapshot, error) {
+	version := 0
+	for _, s := range f.snapshots {
+		if s.ConnectionID != connectionID {
+			continue
+		}
+		if s.Schema == schemaText {
+			return s, nil
+		}
+		version = max(version, s.Version)
+	}
+	snapshot := &repository.SchemaSnapshot{
+		ID:           int64(len(f.snapshots) + 1),
+		ConnectionID: connectionID,
+		Version:      version + 1,
+		Schema:       schemaText,
+	}
+	f.snapshots = append(f.snapshots, snapshot)
+	return snapshot, nil
+}
+
+func (f *fakeSchemaSnapshotRepo) GetByID(ctx context.Context, id int64) (*repository.SchemaSnapshot, error) {
+	for _, s := range f.snapshots {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("表结构快照不存在: %w", repository.ErrNotFound)
+}
+
+func (f *fakeSchemaSnapshotRepo) GetLatest(ctx context.Context, connectionID int64) (*repository.SchemaSnapshot, error) {
+	var latest *repository.SchemaSnapshot
+	for _, s := range f.snapshots {
+		if s.ConnectionID == connectionID && (latest == nil || s.Version > latest.Version) {
+			latest = s
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("表结构快照不存在: %w", repository.ErrNotFound)
+	}
+	return latest, nil
+}
+
+// echoPromptBuilder 将表结构与问题拼接为提示词
+type echoPromptBuilder struct{}
+
+func (echoPromptBuilder) BuildPrompt(req *SQLGenerationRequest) (string, error) {
+	return req.Schema + "\n" + req.Query, nil
+}
+
+func TestSchemaSnapshotService_RecordVersions(t *testing.T) {
+	s := NewSchemaSnapshotService(&fakeSchemaSnapshotRepo{}, echoPromptBuilder{}, zap.NewNop())
+	ctx := context.Background()
+
+	v1, err := s.Record(ctx, 3, "orders(id, user_id)")
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+
+	same, err := s.Record(ctx, 3, "orders(id, user_id)")
+	require.NoError(t, err)
+	assert.Equal(t, v1.ID, same.ID, "内容相同时复用已有快照")
+
+	v2, err := s.Record(ctx, 3, "orders(id, customer_id)")
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+
+	other, err := s.Record(ctx, 4, "orders(id, user_id)")
+	require.NoError(t, err)
+	assert.Equal(t, 1, other.Version, "版本号按连接独立计数")
+
+	empty, err := s.Record(ctx, 3, "  ")
+	require.NoError(t, err)
+	assert.Nil(t, empty, "空表结构不记录")
+}
+
+func TestSchemaSnapshotService_Reproduce(t *testing.T) {
+	repo := &fakeSchemaSnapshotRepo{}
+	s := NewSchemaSnapshotService(repo, echoPromptBuilder{}, zap.NewNop())
+	ctx := context.Background()
+
+	old, err := s.Record(ctx, 3, "orders(id, user_id)")
+	require.NoError(t, err)
+	_, err = s.Record(ctx, 3, "orders(id, customer_id)")
+	require.NoError(t, err)
+
+	history := testutil.NewQueryHistory(42, 7, testutil.WithQuery("每个用户的订单数", "SELECT user_id, COUNT(*) FROM orders GROUP BY user_id"))
+	history.SchemaSnapshotID = &old.ID
+	connectionID := int64(3)
+	history.ConnectionID = &connectionID
+
+	reproduction, err := s.Reproduce(ctx, history)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reproduction.Snapshot.Version)
+	assert.Equal(t, "orders(id, user_id)\n每个用户的订单数", reproduction.Prompt, "使用生成时的表结构而不是最新版本")
+	assert.Equal(t, history.GeneratedSQL, reproduction.GeneratedSQL)
+
+	history.SchemaSnapshotID = nil
+	_, err = s.Reproduce(ctx, history)
+	assert.ErrorIs(t, err, ErrNoSchemaSnapshot)
+
+	missing := int64(99)
+	history.SchemaSnapshotID = &missing
+	_, err = s.Reproduce(ctx, history)
+	assert.ErrorIs(t, err, ErrNoSchemaSnapshot)
+
+	// 其他连接的快照不能通过查询历史读取
+	other, err := s.Record(ctx, 4, "salaries(id, amount)")
+	require.NoError(t, err)
+	history.SchemaSnapshotID = &other.ID
+	_, err = s.Reproduce(ctx, history)
+	assert.ErrorIs(t, err, ErrNoSchemaSnapshot)
+}
+
+func TestSchemaSnapshotService_Verify(t *testing.T) {
+	s := NewSchemaSnapshotService(&fakeSchemaSnapshotRepo{}, echoPromptBuilder{}, zap.NewNop())
+	ctx := context.Background()
+
+	snapshot, err := s.Record(ctx, 3, "orders(id, user_id)")
+	require.NoError(t, err)
+
+	assert.NoError(t, s.Verify(ctx, snapshot.ID, 3))
+	assert.ErrorIs(t, s.Verify(ctx, snapshot.ID, 4), ErrSchemaSnapshotMismatch)
+	assert.ErrorIs(t, s.Verify(ctx, 99, 3), ErrSchemaSnapshotMismatch)
+}
+
+func TestSchemaSnapshotContext(t *testing.T) {
+	assert.Nil(t, schemaSnapshotFromContext(context.Background()))
+	assert.Nil(t, schemaSnapshotFromContext(WithSchemaSnapshot(context.Background(), nil)))
+
+	ctx := WithSchemaSnapshot(context.Background(), &repository.SchemaSnapshot{ID: 12})
+	require.NotNil(t, schemaSnapshotFromContext(ctx))
+	assert.Equal(t, int64(12), *schemaSnapshotFromContext(ctx))
+}
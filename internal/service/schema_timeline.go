@@ -0,0 +1,257 @@
+// 表结构变更时间线
+// 将连接上执行失败的查询与表结构刷新记录的变更事件按时间对照：失败的SQL引用了此前被删除或修改的表、列时，
+// 视为疑似由该变更导致，按SQL汇总首次失败距变更的时间，例如"删除列public.orders.amount约2天后开始失败"
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// maxTimelineFailures 单次时间线最多分析的失败记录数
+const maxTimelineFailures = 1000
+
+// 时间线条目类型
+const (
+	TimelineSchemaChange = "schema_change"
+	TimelineQueryFailure = "query_failure"
+)
+
+// SchemaTimeline 连接的表结构变更与查询失败时间线
+type SchemaTimeline struct {
+	ConnectionID int64                      `json:"connection_id"`
+	Since        time.Time                  `json:"since"`
+	Entries      []SchemaTimelineEntry      `json:"entries"`      // 按时间正序
+	Correlations []SchemaFailureCorrelation `json:"correlations"` // 疑似由表结构变更导致的失败，按变更时间正序
+	Truncated    bool                       `json:"truncated"`    // 失败记录超过上限，只分析了最早的部分
+}
+
+// SchemaTimelineEntry 时间线条目，Kind为schema_change时Change非空，为query_failure时Failure非空
+type SchemaTimelineEntry struct {
+	Time    time.Time                     `json:"time"`
+	Kind    string                        `json:"kind"`
+	Change  *repository.SchemaChangeEvent `json:"change,omitempty"`
+	Failure *QueryFailure                 `json:"failure,omitempty"`
+}
+
+// QueryFailure 一次执行失败的查询
+type QueryFailure struct {
+	HistoryID          int64   `json:"history_id"`
+	SQLHash            string  `json:"sql_hash"`
+	Status             string  `json:"status"` // error/timeout
+	ErrorMessage       string  `json:"error_message,omitempty"`
+	SuspectedChangeIDs []int64 `json:"suspected_change_ids,omitempty"` // 疑似导致失败的变更事件ID
+}
+
+// SchemaFailureCorrelation 同一SQL在某次表结构变更之后的失败汇总
+type SchemaFailureCorrelation struct {
+	Change         *repository.SchemaChangeEvent `json:"change"`
+	SQLHash        string                        `json:"sql_hash"`
+	SQL            string                        `json:"sql"`
+	FirstFailureAt time.Time                     `json:"first_failure_at"`
+	Failures       int                           `json:"failures"`      // 变更之后该SQL的失败次数
+	AfterSeconds   int64                         `json:"after_seconds"` // 首次失败距发现变更的秒数，变更在发现前已生效时可能为负
+	Summary        string                        `json:"summary"`
+}
+
+// SchemaTimelineService 表结构变更时间线服务
+type SchemaTimelineService struct {
+	history     repository.QueryHistoryRepository
+	changes     repository.SchemaChangeRepository
+	connections repository.ConnectionRepository
+	// detectionLag 变更发生到被刷新任务发现的最长间隔，发现前这段时间内的失败也视为发生在变更之后
+	detectionLag time.Duration
+	now          func() time.Time
+	logger       *zap.Logger
+}
+
+// NewSchemaTimelineService 创建表结构变更时间线服务，刷新配置为nil时使用默认配置
+func NewSchemaTimelineService(
+	history repository.QueryHistoryRepository,
+	changes repository.SchemaChangeRepository,
+	connections repository.ConnectionRepository,
+	refreshConfig *config.SchemaRefreshConfig,
+	logger *zap.Logger,
+) *SchemaTimelineService {
+	if refreshConfig == nil {
+		refreshConfig = config.DefaultSchemaRefreshConfig()
+	}
+	return &SchemaTimelineService{
+		history:      history,
+		changes:      changes,
+		connections:  connections,
+		detectionLag: refreshConfig.Interval,
+		now:          time.Now,
+		logger:       logger,
+	}
+}
+
+// Timeline 返回连接最近days天的表结构变更与查询失败时间线，只有连接所有者可以查看
+func (s *SchemaTimelineService) Timeline(ctx context.Context, userID, connectionID int64, days int) (*SchemaTimeline, error) {
+	connection, err := s.connections.GetByID(ctx, connectionID)
+	if err != nil || connection.UserID != userID {
+		return nil, fmt.Errorf("无权访问数据库连接%d: %w", connectionID, repository.ErrPermissionDenied)
+	}
+
+	since := s.now().UTC().AddDate(0, 0, -days)
+	changes, err := s.changes.ListByConnection(ctx, connectionID, since)
+	if err != nil {
+		return nil, err
+	}
+	failures, err := s.history.ListFailuresByConnection(ctx, connectionID, since, maxTimelineFailures+1)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := &SchemaTimeline{ConnectionID: connectionID, Since: since}
+	if len(failures) > maxTimelineFailures {
+		failures = failures[:maxTimelineFailures]
+		timeline.Truncated = true
+	}
+	timeline.Entries, timeline.Correlations = s.correlate(changes, failures)
+	return timeline, nil
+}
+
+// correlate 合并变更与失败为时间线，并找出失败SQL引用了此前被删除或修改对象的情况
+func (s *SchemaTimelineService) correlate(changes []*repository.SchemaChangeEvent, failures []*repository.QueryHistory) ([]SchemaTimelineEntry, []SchemaFailureCorrelation) {
+	entries := make([]SchemaTimelineEntry, 0, len(changes)+len(failures))
+	for _, change := range changes {
+		entries = append(entries, SchemaTimelineEntry{Time: change.DetectedAt, Kind: TimelineSchemaChange, Change: change})
+	}
+
+	type correlationKey struct {
+		changeID int64
+		sqlHash  string
+	}
+	byKey := make(map[correlationKey]*SchemaFailureCorrelation)
+	var correlations []*SchemaFailureCorrelation
+
+	for _, history := range failures {
+		failure := &QueryFailure{HistoryID: history.ID, SQLHash: history.SQLHash, Status: history.Status}
+		if history.ErrorMessage != nil {
+			failure.ErrorMessage = *history.ErrorMessage
+		}
+
+		for _, change := range changes {
+			if history.CreateTime.Before(change.DetectedAt.Add(-s.detectionLag)) || !breakingSchemaChange(change.ChangeType) {
+				continue
+			}
+			if !referencesSchemaObject(history.GeneratedSQL, failure.ErrorMessage, change.ObjectName) {
+				continue
+			}
+			failure.SuspectedChangeIDs = append(failure.SuspectedChangeIDs, change.ID)
+
+			key := correlationKey{change.ID, history.SQLHash}
+			correlation, ok := byKey[key]
+			if !ok {
+				after := history.CreateTime.Sub(change.DetectedAt)
+				correlation = &SchemaFailureCorrelation{
+					Change:         change,
+					SQLHash:        history.SQLHash,
+					SQL:            history.GeneratedSQL,
+					FirstFailureAt: history.CreateTime,
+					AfterSeconds:   int64(after.Seconds()),
+					Summary:        schemaChangeSummary(change, after),
+				}
+				byKey[key] = correlation
+				correlations = append(correlations, correlation)
+			}
+			correlation.Failures++
+		}
+		entries = append(entries, SchemaTimelineEntry{Time: history.CreateTime, Kind: TimelineQueryFailure, Failure: failure})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	sort.SliceStable(correlations, func(i, j int) bool {
+		return correlations[i].Change.DetectedAt.Before(correlations[j].Change.DetectedAt)
+	})
+
+	result := make([]SchemaFailureCorrelation, 0, len(correlations))
+	for _, correlation := range correlations {
+		result = append(result, *correlation)
+	}
+	return entries, result
+}
+
+// breakingSchemaChange 可能导致已有查询失败的变更类型，新增表或列不会
+func breakingSchemaChange(changeType repository.SchemaChangeType) bool {
+	switch changeType {
+	case repository.SchemaChangeRemovedTable, repository.SchemaChangeRemovedColumn, repository.SchemaChangeChangedColumn:
+		return true
+	}
+	return false
+}
+
+// referencesSchemaObject 判断失败的SQL或错误信息是否引用了变更对象
+// 表（schema.table）按表名匹配；列（schema.table.column）要求SQL引用了所在的表，且SQL或错误信息中出现列名
+func referencesSchemaObject(sql, errorMessage, objectName string) bool {
+	parts := strings.Split(objectName, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	sql = strings.ToLower(sql)
+	if !containsIdentifier(sql, strings.ToLower(parts[1])) {
+		return false
+	}
+	if len(parts) == 2 {
+		return true
+	}
+	column := strings.ToLower(parts[2])
+	return containsIdentifier(sql, column) || containsIdentifier(strings.ToLower(errorMessage), column)
+}
+
+// containsIdentifier 判断text中是否出现完整的标识符，前后不能紧邻字母、数字或下划线
+func containsIdentifier(text, identifier string) bool {
+	if identifier == "" {
+		return false
+	}
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], identifier)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(identifier)
+		if (start == 0 || !isIdentifierByte(text[start-1])) && (end == len(text) || !isIdentifierByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// schemaChangeSummary 生成"删除列x约2天后开始失败"形式的说明
+func schemaChangeSummary(change *repository.SchemaChangeEvent, after time.Duration) string {
+	action := map[repository.SchemaChangeType]string{
+		repository.SchemaChangeRemovedTable:  "删除表",
+		repository.SchemaChangeRemovedColumn: "删除列",
+		repository.SchemaChangeChangedColumn: "修改列",
+	}[change.ChangeType]
+	if after <= 0 {
+		return fmt.Sprintf("查询在发现%s%s时已经失败", action, change.ObjectName)
+	}
+	return fmt.Sprintf("查询在%s%s约%s后开始失败", action, change.ObjectName, humanizeDuration(after))
+}
+
+// humanizeDuration 按天、小时或分钟粗略描述时长
+func humanizeDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%d天", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%d小时", int(d/time.Hour))
+	case d >= time.Minute:
+		return fmt.Sprintf("%d分钟", int(d/time.Minute))
+	}
+	return "1分钟"
+}
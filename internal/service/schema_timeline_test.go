@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// memSchemaChangeRepository 内存表结构变更事件Repository
+type memSchemaChangeRepository struct {
+	events []*repository.SchemaChangeEvent
+}
+
+func (r *memSchemaChangeRepository) CreateBatch(ctx context.Context, events []*repository.SchemaChangeEvent) error {
+	for _, event := range events {
+		event.ID = int64(len(r.events) + 1)
+		r.events = append(r.events, event)
+	}
+	return nil
+}
+
+func (r *memSchemaChangeRepository) ListByConnection(ctx context.Context, connectionID int64, since time.Time) ([]*repository.SchemaChangeEvent, error) {
+	var events []*repository.SchemaChangeEvent
+	for _, event := range r.events {
+		if event.ConnectionID == connectionID && !event.DetectedAt.Before(since) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// failureQueryHistoryRepository 只实现ListFailuresByConnection的查询历史Repository
+type failureQueryHistoryRepository struct {
+	repository.QueryHistoryRepository
+	failures []*repository.QueryHistory
+}
+
+func (r *failureQueryHistoryRepository) ListFailuresByConnection(ctx context.Context, connectionID int64, since time.Time, limit int) ([]*repository.QueryHistory, error) {
+	return r.failures, nil
+}
+
+func TestSchemaTimelineService_Timeline(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	failedAt := func(id int64, at time.Time, sql, message string) *repository.QueryHistory {
+		history := testutil.NewQueryHistory(id, 7, testutil.WithQuery("", sql), testutil.WithQueryError(message))
+		history.SQLHash, history.CreateTime = sql, at
+		return history
+	}
+
+	changes := &memSchemaChangeRepository{}
+	dropped := now.Add(-72 * time.Hour)
+	diff := &SchemaDiff{RemovedColumns: []string{"public.orders.amount"}, AddedTables: []string{"public.refunds"}}
+	require.NoError(t, changes.CreateBatch(context.Background(), diff.Events(3, dropped)))
+
+	history := &failureQueryHistoryRepository{failures: []*repository.QueryHistory{
+		failedAt(1, now.Add(-96*time.Hour), "SELECT amount FROM orders", "timeout"),
+		failedAt(2, now.Add(-24*time.Hour), "SELECT sum(amount) FROM orders", `column "amount" does not exist`),
+		failedAt(3, now.Add(-12*time.Hour), "SELECT sum(amount) FROM orders", `column "amount" does not exist`),
+		failedAt(4, now.Add(-6*time.Hour), "SELECT amount_total FROM orders", "syntax error"),
+	}}
+	connection := testutil.NewConnection(3, 7)
+	connections := &stubConnectionRepository{connection: connection}
+
+	s := NewSchemaTimelineService(history, changes, connections, &config.SchemaRefreshConfig{Interval: time.Hour}, zaptest.NewLogger(t))
+	s.now = func() time.Time { return now }
+
+	_, err := s.Timeline(context.Background(), 8, 3, 30)
+	assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+
+	timeline, err := s.Timeline(context.Background(), 7, 3, 30)
+	require.NoError(t, err)
+	require.Len(t, timeline.Entries, 6)
+	assert.Equal(t, TimelineQueryFailure, timeline.Entries[0].Kind, "变更之前的失败")
+	assert.Empty(t, timeline.Entries[0].Failure.SuspectedChangeIDs)
+	assert.Equal(t, TimelineSchemaChange, timeline.Entries[1].Kind)
+
+	// 新增表不会导致失败；只引用了相似列名的SQL不算
+	require.Len(t, timeline.Correlations, 1)
+	correlation := timeline.Correlations[0]
+	assert.Equal(t, "public.orders.amount", correlation.Change.ObjectName)
+	assert.Equal(t, 2, correlation.Failures)
+	assert.Equal(t, int64(48*3600), correlation.AfterSeconds)
+	assert.Equal(t, "查询在删除列public.orders.amount约2天后开始失败", correlation.Summary)
+	assert.Empty(t, timeline.Entries[5].Failure.SuspectedChangeIDs)
+}
+
+func TestReferencesSchemaObject(t *testing.T) {
+	assert.True(t, referencesSchemaObject(`SELECT * FROM public."Orders"`, "", "public.orders"))
+	assert.False(t, referencesSchemaObject("SELECT * FROM orders_archive", "", "public.orders"))
+	assert.True(t, referencesSchemaObject("SELECT o.* FROM orders o", `column o.amount does not exist`, "public.orders.amount"), "列名只出现在错误信息中")
+	assert.False(t, referencesSchemaObject("SELECT amount FROM payments", "", "public.orders.amount"), "未引用列所在的表")
+}
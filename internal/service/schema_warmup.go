@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/crash"
+)
+
+// WarmupState 预热任务状态
+type WarmupState string
+
+const (
+	WarmupQueued    WarmupState = "queued"    // 排队等待执行
+	WarmupRunning   WarmupState = "running"   // 执行中
+	WarmupCompleted WarmupState = "completed" // 已完成
+	WarmupFailed    WarmupState = "failed"    // 执行失败
+)
+
+// WarmupStatus 预热任务进度
+type WarmupStatus struct {
+	State           WarmupState `json:"state"`
+	Stage           string      `json:"stage,omitempty"` // 当前（或失败时所在）阶段
+	CompletedStages int         `json:"completed_stages"`
+	TotalStages     int         `json:"total_stages"`
+	Progress        int         `json:"progress"` // 完成百分比
+	Tables          int         `json:"tables"`
+	Columns         int         `json:"columns"`
+	EstimatedRows   int64       `json:"estimated_rows"` // 各表估计行数之和
+	Error           string      `json:"error,omitempty"`
+	QueuedAt        time.Time   `json:"queued_at"`
+	StartedAt       *time.Time  `json:"started_at,omitempty"`
+	FinishedAt      *time.Time  `json:"finished_at,omitempty"`
+}
+
+// schemaWarmupIntrospector 预热使用的元数据探测接口，由SchemaIntrospector实现
+type schemaWarmupIntrospector interface {
+	IntrospectDatabase(ctx context.Context, connectionID int64) (*DatabaseSchema, error)
+	SaveSchemaMetadata(ctx context.Context, databaseSchema *DatabaseSchema) error
+}
+
+// warmupStage 预热阶段
+type warmupStage struct {
+	name string
+	run  func(ctx context.Context, job *warmupJob) error
+}
+
+// warmupJob 单个连接的预热任务
+type warmupJob struct {
+	connectionID int64
+	status       WarmupStatus
+	schema       *DatabaseSchema // introspect阶段的结果，供后续阶段使用
+	cancel       context.CancelFunc
+}
+
+// SchemaWarmupService 新建连接的元数据预热
+// 连接创建后立即在后台探测表结构与行数估计并保存元数据，首次提问时不必再承担完整的探测开销；
+// 任务按连接跟踪进度，同时执行的任务数受MaxConcurrent限制
+type SchemaWarmupService struct {
+	introspector schemaWarmupIntrospector
+	config       *config.SchemaWarmupConfig
+	logger       *zap.Logger
+	stages       []warmupStage
+
+	slots  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[int64]*warmupJob
+}
+
+// NewSchemaWarmupService 创建元数据预热服务
+func NewSchemaWarmupService(introspector schemaWarmupIntrospector, warmupConfig *config.SchemaWarmupConfig, logger *zap.Logger) *SchemaWarmupService {
+	if warmupConfig == nil {
+		warmupConfig = config.DefaultSchemaWarmupConfig()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &SchemaWarmupService{
+		introspector: introspector,
+		config:       warmupConfig,
+		logger:       logger,
+		slots:        make(chan struct{}, warmupConfig.MaxConcurrent),
+		ctx:          ctx,
+		cancel:       cancel,
+		jobs:         make(map[int64]*warmupJob),
+	}
+	s.stages = []warmupStage{
+		{name: "introspect", run: s.introspect},
+		{name: "save_metadata", run: s.saveMetadata},
+	}
+	return s
+}
+
+// AddStage 在内置阶段之后追加预热阶段，需要在首次调用Warmup之前调用
+func (s *SchemaWarmupService) AddStage(name string, run func(ctx context.Context, connectionID int64) error) {
+	s.stages = append(s.stages, warmupStage{name: name, run: func(ctx context.Context, job *warmupJob) error {
+		return run(ctx, job.connectionID)
+	}})
+}
+
+// Warmup 为连接启动预热任务并返回当前进度
+// 同一连接已有排队或执行中的任务时不重复启动
+func (s *SchemaWarmupService) Warmup(connectionID int64) *WarmupStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[connectionID]; ok && (job.status.State == WarmupQueued || job.status.State == WarmupRunning) {
+		status := job.status
+		return &status
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	job := &warmupJob{
+		connectionID: connectionID,
+		cancel:       cancel,
+		status: WarmupStatus{
+			State:       WarmupQueued,
+			TotalStages: len(s.stages),
+			QueuedAt:    time.Now(),
+		},
+	}
+	s.jobs[connectionID] = job
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		crash.Run("schema_warmup", func() { s.run(ctx, job) })
+	}()
+
+	status := job.status
+	return &status
+}
+
+// Status 返回连接最近一次预热任务的进度
+func (s *SchemaWarmupService) Status(connectionID int64) (*WarmupStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[connectionID]
+	if !ok {
+		return nil, false
+	}
+	status := job.status
+	return &status, true
+}
+
+// Forget 取消并移除连接的预热任务，连接删除时调用
+func (s *SchemaWarmupService) Forget(connectionID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[connectionID]; ok {
+		job.cancel()
+		delete(s.jobs, connectionID)
+	}
+}
+
+// Stop 取消全部预热任务并等待退出
+func (s *SchemaWarmupService) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 等待执行名额后依次执行各阶段
+func (s *SchemaWarmupService) run(ctx context.Context, job *warmupJob) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.finish(job, "", ctx.Err())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	s.update(job, func(status *WarmupStatus) {
+		now := time.Now()
+		status.State = WarmupRunning
+		status.StartedAt = &now
+	})
+
+	for _, stage := range s.stages {
+		s.update(job, func(status *WarmupStatus) { status.Stage = stage.name })
+		if err := stage.run(ctx, job); err != nil {
+			s.finish(job, stage.name, err)
+			return
+		}
+		s.update(job, func(status *WarmupStatus) {
+			status.CompletedStages++
+			status.Progress = status.CompletedStages * 100 / status.TotalStages
+		})
+	}
+	s.finish(job, "", nil)
+}
+
+// introspect 探测表结构、索引、约束与各表估计行数
+func (s *SchemaWarmupService) introspect(ctx context.Context, job *warmupJob) error {
+	schema, err := s.introspector.IntrospectDatabase(ctx, job.connectionID)
+	if err != nil {
+		return err
+	}
+	job.schema = schema
+
+	var estimatedRows int64
+	for _, info := range schema.Schemas {
+		for _, table := range info.Tables {
+			if table.EstimatedRows != nil && *table.EstimatedRows > 0 {
+				estimatedRows += *table.EstimatedRows
+			}
+		}
+	}
+	s.update(job, func(status *WarmupStatus) {
+		status.Tables = schema.TotalTables
+		status.Columns = schema.TotalColumns
+		status.EstimatedRows = estimatedRows
+	})
+	return nil
+}
+
+// saveMetadata 保存探测结果，供提示词构建与结构浏览使用
+func (s *SchemaWarmupService) saveMetadata(ctx context.Context, job *warmupJob) error {
+	return s.introspector.SaveSchemaMetadata(ctx, job.schema)
+}
+
+// update 在锁内修改任务进度
+func (s *SchemaWarmupService) update(job *warmupJob, fn func(status *WarmupStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&job.status)
+}
+
+// finish 记录任务结束状态
+func (s *SchemaWarmupService) finish(job *warmupJob, stage string, err error) {
+	s.update(job, func(status *WarmupStatus) {
+		now := time.Now()
+		status.FinishedAt = &now
+		if err == nil {
+			status.State = WarmupCompleted
+			status.Stage = ""
+			return
+		}
+		status.State = WarmupFailed
+		status.Stage = stage
+		status.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			status.Error = "预热超时: " + err.Error()
+		}
+	})
+
+	if err != nil {
+		s.logger.Warn("Schema warmup failed",
+			zap.Int64("connection_id", job.connectionID),
+			zap.String("stage", stage),
+			zap.Error(err))
+		return
+	}
+	s.logger.Info("Schema warmup completed",
+		zap.Int64("connection_id", job.connectionID),
+		zap.Int("tables", job.status.Tables),
+		zap.Int("columns", job.status.Columns))
+}
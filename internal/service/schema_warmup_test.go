@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// fakeWarmupIntrospector 可控的元数据探测
+type fakeWarmupIntrospector struct {
+	release chan struct{} // 非nil时IntrospectDatabase阻塞到关闭
+	err     error
+	saved   chan *DatabaseSchema
+}
+
+func (f *fakeWarmupIntrospector) IntrospectDatabase(ctx context.Context, connectionID int64) (*DatabaseSchema, error) {
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	rows := int64(1200)
+	return &DatabaseSchema{
+		ConnectionID: connectionID,
+		Schemas: []SchemaInfo{{
+			SchemaName: "public",
+			Tables: []TableInfo{
+				{TableName: "orders", ColumnCount: 5, EstimatedRows: &rows},
+				{TableName: "users", ColumnCount: 3},
+			},
+		}},
+		TotalTables:  2,
+		TotalColumns: 8,
+	}, nil
+}
+
+func (f *fakeWarmupIntrospector) SaveSchemaMetadata(ctx context.Context, databaseSchema *DatabaseSchema) error {
+	f.saved <- databaseSchema
+	return nil
+}
+
+func waitForWarmup(t *testing.T, s *SchemaWarmupService, connectionID int64, state WarmupState) *WarmupStatus {
+	t.Helper()
+	var status *WarmupStatus
+	require.Eventually(t, func() bool {
+		status, _ = s.Status(connectionID)
+		return status != nil && status.State == state
+	}, 2*time.Second, 5*time.Millisecond, "预热任务应进入%s状态", state)
+	return status
+}
+
+func TestSchemaWarmupService_Completes(t *testing.T) {
+	introspector := &fakeWarmupIntrospector{release: make(chan struct{}), saved: make(chan *DatabaseSchema, 1)}
+	s := NewSchemaWarmupService(introspector, config.DefaultSchemaWarmupConfig(), zap.NewNop())
+	defer s.Stop(context.Background())
+
+	var extra int64
+	s.AddStage("embeddings", func(ctx context.Context, connectionID int64) error {
+		extra = connectionID
+		return nil
+	})
+
+	status := s.Warmup(42)
+	assert.Equal(t, WarmupQueued, status.State)
+	assert.Equal(t, 3, status.TotalStages)
+
+	waitForWarmup(t, s, 42, WarmupRunning)
+	again := s.Warmup(42)
+	assert.Equal(t, WarmupRunning, again.State, "执行中的任务不重复启动")
+
+	close(introspector.release)
+	status = waitForWarmup(t, s, 42, WarmupCompleted)
+	assert.Equal(t, 100, status.Progress)
+	assert.Equal(t, 2, status.Tables)
+	assert.Equal(t, 8, status.Columns)
+	assert.Equal(t, int64(1200), status.EstimatedRows)
+	assert.NotNil(t, status.FinishedAt)
+	assert.Equal(t, int64(42), extra, "追加的阶段应被执行")
+
+	saved := <-introspector.saved
+	assert.Equal(t, int64(42), saved.ConnectionID)
+
+	s.Forget(42)
+	_, ok := s.Status(42)
+	assert.False(t, ok, "删除连接后不再保留预热状态")
+}
+
+func TestSchemaWarmupService_Failure(t *testing.T) {
+	introspector := &fakeWarmupIntrospector{err: errors.New("connection refused")}
+	s := NewSchemaWarmupService(introspector, config.DefaultSchemaWarmupConfig(), zap.NewNop())
+	defer s.Stop(context.Background())
+
+	s.Warmup(1)
+	status := waitForWarmup(t, s, 1, WarmupFailed)
+	assert.Equal(t, "introspect", status.Stage)
+	assert.Equal(t, "connection refused", status.Error)
+	assert.Equal(t, 0, status.Progress)
+}
+
+func TestSchemaWarmupService_LimitsConcurrency(t *testing.T) {
+	introspector := &fakeWarmupIntrospector{release: make(chan struct{}), saved: make(chan *DatabaseSchema, 3)}
+	cfg := config.DefaultSchemaWarmupConfig()
+	cfg.MaxConcurrent = 1
+	s := NewSchemaWarmupService(introspector, cfg, zap.NewNop())
+
+	s.Warmup(1)
+	s.Warmup(2)
+	require.Eventually(t, func() bool {
+		first, _ := s.Status(1)
+		second, _ := s.Status(2)
+		return first.State == WarmupRunning || second.State == WarmupRunning
+	}, 2*time.Second, 5*time.Millisecond)
+	first, _ := s.Status(1)
+	second, _ := s.Status(2)
+	assert.ElementsMatch(t, []WarmupState{WarmupRunning, WarmupQueued}, []WarmupState{first.State, second.State},
+		"超过并发上限的任务排队等待")
+
+	// 停止服务时排队和执行中的任务都被取消
+	require.NoError(t, s.Stop(context.Background()))
+	first, _ = s.Status(1)
+	second, _ = s.Status(2)
+	assert.Equal(t, WarmupFailed, first.State)
+	assert.Equal(t, WarmupFailed, second.State)
+}
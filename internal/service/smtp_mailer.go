@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"chat2sql-go/internal/config"
+)
+
+// SMTPMailer 基于SMTP的邮件发送实现
+type SMTPMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password SecretFunc
+}
+
+// NewSMTPMailer 创建SMTP邮件发送器，密码在每次发送时通过password读取，为nil时使用配置中的SMTP_PASSWORD
+func NewSMTPMailer(gatewayConfig *config.EmailGatewayConfig, password SecretFunc) *SMTPMailer {
+	if password == nil {
+		password = StaticSecret(gatewayConfig.SMTPPassword)
+	}
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(gatewayConfig.SMTPHost, strconv.Itoa(gatewayConfig.SMTPPort)),
+		host:     gatewayConfig.SMTPHost,
+		from:     gatewayConfig.FromAddress,
+		username: gatewayConfig.SMTPUsername,
+		password: password,
+	}
+}
+
+// Send 发送邮件（net/smtp不支持context，仅在发送前检查取消状态）
+func (m *SMTPMailer) Send(ctx context.Context, email *OutboundEmail) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	message, err := BuildMIMEMessage(m.from, email)
+	if err != nil {
+		return fmt.Errorf("构建邮件失败: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		password, err := m.password(ctx)
+		if err != nil {
+			return fmt.Errorf("读取SMTP密码失败: %w", err)
+		}
+		auth = smtp.PlainAuth("", m.username, password, m.host)
+	}
+	return smtp.SendMail(m.addr, auth, m.from, []string{email.To}, message)
+}
+
+// BuildMIMEMessage 构建multipart/mixed格式的MIME邮件
+func BuildMIMEMessage(from string, email *OutboundEmail) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", email.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", writer.Boundary())
+
+	textPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64Lines(textPart, []byte(email.Body)); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range email.Attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(part, attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64Lines 按RFC 2045要求以76字符换行写入Base64内容
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := w.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := w.Write([]byte(encoded + "\r\n"))
+	return err
+}
@@ -0,0 +1,202 @@
+// 截断SQL续写
+// 模型输出达到MaxTokens时可能停在语句中途，检测到括号未闭合、字符串未结束、以逗号或关键字结尾，
+// 或因长度停止且SELECT缺少FROM时，以同一模型从截断处继续输出并拼接，避免把残缺的SQL交给校验器
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// 截断原因
+const (
+	truncationUnbalancedParens = "unbalanced_parens"
+	truncationUnterminated     = "unterminated_literal"
+	truncationDanglingClause   = "dangling_clause"
+	truncationMissingFrom      = "missing_from"
+)
+
+// lengthStopReasons 各提供商表示因达到token上限而停止的原因，比较时不区分大小写
+var lengthStopReasons = map[string]bool{
+	"length":     true, // OpenAI、Azure OpenAI
+	"max_tokens": true, // Anthropic、Gemini
+}
+
+// danglingKeywords 出现在语句末尾时说明子句未写完的关键字
+var danglingKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true, "NOT": true,
+	"JOIN": true, "ON": true, "BY": true, "AS": true, "IN": true, "HAVING": true,
+	"LIMIT": true, "OFFSET": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true,
+	"UNION": true, "ALL": true, "DISTINCT": true, "LEFT": true, "RIGHT": true, "INNER": true,
+	"OUTER": true, "FULL": true, "CROSS": true, "ORDER": true, "GROUP": true, "WITH": true,
+	"BETWEEN": true, "LIKE": true, "IS": true, "OVER": true, "PARTITION": true,
+}
+
+// SetSQLContinuation 设置截断SQL续写配置，为nil或最多续写0次时不续写
+func (ai *AIService) SetSQLContinuation(continuationConfig *config.SQLContinuationConfig) {
+	ai.continuation = continuationConfig
+}
+
+// continueTruncated 模型输出的SQL不完整时请求同一模型续写，返回拼接后的响应
+// 续写调用失败或达到次数上限时返回已拼接的内容，由后续的校验拒绝残缺的SQL
+func (ai *AIService) continueTruncated(ctx context.Context, req *SQLGenerationRequest, prompt string, response *llms.ContentResponse, generation *GenerationParameters) *llms.ContentResponse {
+	if !ai.continuation.Enabled() || generation == nil || len(response.Choices) == 0 {
+		return response
+	}
+
+	stitched := *response.Choices[0]
+	for generation.Continuations < ai.continuation.MaxContinuations {
+		reason := sqlTruncation(stitched.Content, stitched.StopReason)
+		if reason == "" {
+			break
+		}
+		ai.logger.Info("生成的SQL不完整，请求模型续写",
+			zap.String("reason", reason),
+			zap.String("stop_reason", stitched.StopReason),
+			zap.Int("continuation", generation.Continuations+1),
+		)
+
+		continuationPrompt := buildContinuationPrompt(prompt, stitched.Content)
+		next, nextGeneration, err := ai.callModel(ctx, continuationPrompt, generation.slot)
+		if err != nil {
+			ai.logger.Warn("续写SQL失败", zap.Error(err))
+			break
+		}
+		if len(next.Choices) == 0 {
+			break
+		}
+		ai.recordCost(req, continuationPrompt, nextGeneration, next.Choices[0])
+		generation.Continuations++
+		generation.Cached = generation.Cached && nextGeneration.Cached
+
+		stitched.Content = stitchContinuation(stitched.Content, next.Choices[0].Content)
+		stitched.StopReason = next.Choices[0].StopReason
+		stitched.GenerationInfo = next.Choices[0].GenerationInfo
+	}
+	if generation.Continuations == 0 {
+		return response
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{&stitched}}
+}
+
+// buildContinuationPrompt 在原提示词后附上已输出的部分，要求模型只输出剩余部分
+func buildContinuationPrompt(prompt, partial string) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\n## 已输出的部分\n")
+	b.WriteString("上一次输出在SQL语句中途被截断，已输出的内容如下：\n")
+	b.WriteString(partial)
+	b.WriteString("\n\n请从截断处的下一个字符开始继续输出SQL的剩余部分，不要重复已输出的内容，不要添加解释或代码块标记。")
+	return b.String()
+}
+
+// stitchContinuation 拼接续写内容：去掉续写开头的代码块标记，以及与已输出内容末尾重复的部分
+func stitchContinuation(partial, continuation string) string {
+	trimmed := strings.TrimLeft(continuation, " \t\r\n")
+	for _, fence := range []string{"```sql", "```"} {
+		if rest, ok := strings.CutPrefix(trimmed, fence); ok {
+			continuation = strings.TrimLeft(rest, "\r\n")
+			break
+		}
+	}
+
+	// 模型有时从截断处之前的位置重新输出，只去掉足够长的重复以免误删恰好相同的短片段
+	const minOverlap = 8
+	for n := min(len(partial), len(continuation)); n >= minOverlap; n-- {
+		if strings.HasSuffix(partial, continuation[:n]) {
+			return partial + continuation[n:]
+		}
+	}
+	return partial + continuation
+}
+
+// sqlTruncation 判断模型输出的SQL是否在中途被截断，返回截断原因，完整时返回空串
+// 结构上不完整的SQL不论停止原因都视为截断；SELECT缺少FROM只在因长度停止时才视为截断，
+// 以免误判SELECT 1、SELECT now()这类不需要FROM的查询
+func sqlTruncation(content, stopReason string) string {
+	sql := normalizeCandidateSQL(content)
+	if sql == "" {
+		return ""
+	}
+	if reason := incompleteSQL(sql); reason != "" {
+		return reason
+	}
+	if lengthStopReasons[strings.ToLower(stopReason)] && selectWithoutFrom(sql) {
+		return truncationMissingFrom
+	}
+	return ""
+}
+
+// incompleteSQL 检查括号与引号是否闭合、语句是否以逗号、运算符或关键字结尾，注释中的内容不参与检查
+func incompleteSQL(sql string) string {
+	depth := 0
+	var quote byte
+	var tail strings.Builder
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		if quote != 0 {
+			if c == quote {
+				if i+1 < len(sql) && sql[i+1] == quote {
+					i++
+					continue
+				}
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			tail.WriteByte(' ')
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		}
+		tail.WriteByte(c)
+	}
+
+	if quote != 0 {
+		return truncationUnterminated
+	}
+	if depth > 0 {
+		return truncationUnbalancedParens
+	}
+
+	code := strings.TrimRight(strings.TrimSpace(tail.String()), ";")
+	if code == "" {
+		return ""
+	}
+	if strings.ContainsRune(",(=<>+/.|", rune(code[len(code)-1])) {
+		return truncationDanglingClause
+	}
+	fields := strings.Fields(code)
+	if danglingKeywords[strings.ToUpper(fields[len(fields)-1])] {
+		return truncationDanglingClause
+	}
+	return ""
+}
+
+// selectWithoutFrom 以SELECT开头且不含FROM关键字
+func selectWithoutFrom(sql string) bool {
+	fields := strings.Fields(strings.ToUpper(sql))
+	if len(fields) == 0 || fields[0] != "SELECT" {
+		return false
+	}
+	for _, field := range fields {
+		if field == "FROM" || strings.HasPrefix(field, "FROM(") {
+			return false
+		}
+	}
+	return true
+}
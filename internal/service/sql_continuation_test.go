@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+)
+
+// truncatingLLM 依次返回预设的输出片段，除最后一段外都以长度上限停止
+type truncatingLLM struct {
+	parts   []string
+	prompts []string
+}
+
+func (m *truncatingLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.prompts = append(m.prompts, messages[0].Parts[0].(llms.TextContent).Text)
+	i := len(m.prompts) - 1
+	stopReason := "length"
+	if i == len(m.parts)-1 {
+		stopReason = "stop"
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.parts[i], StopReason: stopReason}}}, nil
+}
+
+func (m *truncatingLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestAIService_GenerateSQL_ContinuesTruncatedSQL(t *testing.T) {
+	primary := &truncatingLLM{parts: []string{
+		"SELECT u.name, COUNT(o.id) AS orders\nFROM users u JOIN orders o ON (o.user_id = u.id",
+		"o.user_id = u.id)\nGROUP BY u.name",
+	}}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), primary, &seededLLM{}, zaptest.NewLogger(t))
+	aiService.SetSQLContinuation(config.DefaultSQLContinuationConfig())
+
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "每个用户的订单数", Schema: "users(id, name), orders(id, user_id)"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT u.name, COUNT(o.id) AS orders\nFROM users u JOIN orders o ON (o.user_id = u.id)\nGROUP BY u.name", resp.SQL)
+	assert.Equal(t, 1, resp.Generation.Continuations)
+	assert.Equal(t, resp.SQL, resp.Completion)
+
+	require.Len(t, primary.prompts, 2)
+	assert.True(t, strings.HasPrefix(primary.prompts[1], primary.prompts[0]), "续写提示词包含原提示词")
+	assert.Contains(t, primary.prompts[1], primary.parts[0])
+}
+
+func TestAIService_GenerateSQL_ContinuationLimit(t *testing.T) {
+	primary := &truncatingLLM{parts: []string{"SELECT name,", " email,", " phone,", " FROM users"}}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), primary, &seededLLM{}, zaptest.NewLogger(t))
+
+	// 未启用时不续写
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "列出用户", Schema: "users(name)"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT name,", resp.SQL)
+	assert.Zero(t, resp.Generation.Continuations)
+
+	primary.prompts = nil
+	aiService.SetSQLContinuation(&config.SQLContinuationConfig{MaxContinuations: 2})
+	resp, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "列出用户", Schema: "users(name)"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT name, email, phone,", resp.SQL, "达到续写上限后返回已拼接的内容")
+	assert.Equal(t, 2, resp.Generation.Continuations)
+	assert.Len(t, primary.prompts, 3)
+}
+
+func TestSQLTruncation(t *testing.T) {
+	tests := []struct {
+		sql        string
+		stopReason string
+		want       string
+	}{
+		{"SELECT id FROM users WHERE id IN (1, 2)", "length", ""},
+		{"SELECT * FROM users WHERE name = 'a(b'", "", ""},
+		{"SELECT now()", "length", truncationMissingFrom},
+		{"SELECT now()", "stop", ""},
+		{"SELECT id, name", "MAX_TOKENS", truncationMissingFrom},
+		{"SELECT id FROM users WHERE id IN (1, 2", "", truncationUnbalancedParens},
+		{"SELECT id FROM users WHERE name = 'O''Bri", "", truncationUnterminated},
+		{"SELECT id FROM users WHERE active AND", "stop", truncationDanglingClause},
+		{"SELECT id FROM users ORDER BY", "", truncationDanglingClause},
+		{"SELECT id FROM users WHERE age >", "", truncationDanglingClause},
+		{"SELECT id FROM users -- 按id排序 (\nORDER BY id;", "", ""},
+		{"```sql\nSELECT id FROM users\n```", "length", ""},
+		{"", "length", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, sqlTruncation(tt.sql, tt.stopReason), tt.sql)
+	}
+}
+
+func TestStitchContinuation(t *testing.T) {
+	assert.Equal(t, "SELECT id FROM users", stitchContinuation("SELECT id ", "FROM users"))
+	assert.Equal(t, "SELECT id FROM users", stitchContinuation("SELECT id ", "```sql\nFROM users"), "去掉代码块标记")
+	assert.Equal(t, "SELECT id, name FROM users", stitchContinuation("SELECT id, name", "SELECT id, name FROM users"), "去掉重复输出的部分")
+	assert.Equal(t, "SELECT a, b, b FROM t", stitchContinuation("SELECT a, b", ", b FROM t"), "短片段相同不视为重复")
+}
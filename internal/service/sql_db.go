@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+
+	"chat2sql-go/internal/repository"
+)
+
+// executeQueryOnDB 在database/sql连接池（MySQL、SQLite）上执行查询
+// 查询在只读事务中执行：MySQL拒绝其中的写操作，SQLite连接本身以只读方式打开
+func (e *SQLExecutor) executeQueryOnDB(ctx context.Context, query string, db *sql.DB, dbType repository.DatabaseType) (*QueryResult, error) {
+	result := &QueryResult{
+		Columns:    []string{},
+		Rows:       []map[string]any{},
+		QueryType:  e.detectQueryType(query),
+		Status:     string(repository.QuerySuccess),
+		Warnings:   []string{},
+		Guardrails: []Guardrail{{Type: GuardrailReadOnly}},
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		result.Status = string(repository.QueryError)
+		result.Error = fmt.Sprintf("开启只读事务失败: %v", err)
+		return result, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, queryArgs(ctx)...)
+	if err != nil {
+		result.Status = string(repository.QueryError)
+		result.Error = databaseErrorMessage(err)
+		return result, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		result.Status = string(repository.QueryError)
+		result.Error = fmt.Sprintf("读取查询结果失败: %v", err)
+		return result, err
+	}
+	columns := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = columnType.Name()
+	}
+	result.Columns = columns
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	limiter := e.newResultLimiter(ctx, result)
+	for rows.Next() {
+		if limiter.full() {
+			break
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			result.Status = string(repository.QueryError)
+			result.Error = fmt.Sprintf("读取查询结果失败: %v", err)
+			return result, err
+		}
+
+		rowData := make(map[string]any, len(columns))
+		for i, value := range values {
+			if dbType == repository.DBTypeMySQL {
+				value = mysqlValue(value, columnTypes[i])
+			}
+			rowData[columns[i]] = e.convertValue(value)
+		}
+
+		added, err := limiter.add(rowData)
+		if err != nil {
+			return result, err
+		}
+		if !added {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		result.Status = string(repository.QueryError)
+		result.Error = fmt.Sprintf("读取查询结果时发生错误: %v", err)
+		return result, err
+	}
+
+	return result, nil
+}
+
+// databaseErrorMessage 格式化查询错误，数据库返回的错误附带错误码
+func databaseErrorMessage(err error) string {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return fmt.Sprintf("数据库错误 [%d]: %s", mysqlErr.Number, mysqlErr.Message)
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return fmt.Sprintf("数据库错误 [%d]: %s", sqliteErr.Code, sqliteErr.Error())
+	}
+	return fmt.Sprintf("查询执行失败: %v", err)
+}
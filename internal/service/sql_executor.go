@@ -4,29 +4,40 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 
+	"chat2sql-go/internal/ai"
 	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/tracing"
 )
 
 // SQLExecutor SQL执行器
 // 基于pgxpool实现高性能SQL查询执行，支持多数据库连接和超时控制
 type SQLExecutor struct {
 	// 核心组件
-	systemPool        *pgxpool.Pool       // 主连接池（用于系统数据库）
-	connectionManager *ConnectionManager  // 连接管理器
-	logger            *zap.Logger         // 日志器
+	systemPool        *pgxpool.Pool         // 主连接池（用于系统数据库）
+	connectionManager *ConnectionManager    // 连接管理器
+	runningQueries    *RunningQueryRegistry // 运行中查询登记表（可选）
+	metrics           QueryMetricsRecorder  // 按连接与查询类别记录执行指标（可选）
+	columnPolicy      *ColumnPolicyEnforcer // 返回结果前按发起用户应用列策略（可选）
+	residency         *ResidencyService     // 执行前校验结果快照的数据驻留策略（可选）
+	realtime          *RealtimeHub          // 向发起用户的工作空间广播查询开始与结束（可选）
+	logger            *zap.Logger           // 日志器
 
 	// 配置参数
 	queryTimeout time.Duration // 查询超时时间
 	maxRows      int32         // 最大返回行数
 	maxResultMB  int32         // 最大结果集大小(MB)
+	autoLimit    bool          // SQL没有LIMIT时追加LIMIT，由数据库限制读取的行数
 }
 
 // SQLExecutorConfig SQL执行器配置
@@ -34,6 +45,12 @@ type SQLExecutorConfig struct {
 	QueryTimeout time.Duration `json:"query_timeout"`   // 查询超时时间，默认30秒
 	MaxRows      int32         `json:"max_rows"`        // 最大返回行数，默认1000行
 	MaxResultMB  int32         `json:"max_result_mb"`   // 最大结果集大小，默认10MB
+	AutoLimit    bool          `json:"auto_limit"`      // SQL没有LIMIT时自动追加
+}
+
+// QueryMetricsRecorder SQL执行指标记录接口，由metrics.PrometheusMetrics实现
+type QueryMetricsRecorder interface {
+	RecordQueryExecution(connectionID int64, category, status string, duration time.Duration, rows int)
 }
 
 // QueryResult SQL查询结果
@@ -46,6 +63,11 @@ type QueryResult struct {
 	Status        string                     `json:"status"`        // 执行状态
 	Error         string                     `json:"error,omitempty"` // 错误信息
 	Warnings      []string                   `json:"warnings,omitempty"` // 警告信息
+	Guardrails    []Guardrail                `json:"guardrails,omitempty"` // 实际生效的防护措施
+	Truncated     bool                       `json:"truncated,omitempty"`    // 结果达到行数或大小上限被截断
+	TruncatedBy   string                     `json:"truncated_by,omitempty"` // 截断原因：row_limit/size_limit
+
+	columnPolicyApplied bool // 已应用列策略，避免重复脱敏与重复的说明
 }
 
 // NewSQLExecutor 创建SQL执行器
@@ -55,6 +77,7 @@ func NewSQLExecutor(systemPool *pgxpool.Pool, connectionManager *ConnectionManag
 		QueryTimeout: 30 * time.Second,
 		MaxRows:      1000,
 		MaxResultMB:  10,
+		AutoLimit:    true,
 	}
 
 	return &SQLExecutor{
@@ -64,6 +87,7 @@ func NewSQLExecutor(systemPool *pgxpool.Pool, connectionManager *ConnectionManag
 		queryTimeout:      config.QueryTimeout,
 		maxRows:           config.MaxRows,
 		maxResultMB:       config.MaxResultMB,
+		autoLimit:         config.AutoLimit,
 	}
 }
 
@@ -91,15 +115,73 @@ func NewSQLExecutorWithConfig(systemPool *pgxpool.Pool, connectionManager *Conne
 		queryTimeout:      config.QueryTimeout,
 		maxRows:           config.MaxRows,
 		maxResultMB:       config.MaxResultMB,
+		autoLimit:         config.AutoLimit,
 	}
 }
 
+// SetRunningQueries 启用运行中查询登记，通过WithQueryOwner标记发起用户的查询会被登记
+func (e *SQLExecutor) SetRunningQueries(registry *RunningQueryRegistry) {
+	e.runningQueries = registry
+}
+
+// SetMetrics 启用执行指标：按连接与查询类别记录耗时、返回行数与执行状态
+func (e *SQLExecutor) SetMetrics(recorder QueryMetricsRecorder) {
+	e.metrics = recorder
+}
+
+// SetColumnPolicy 启用列策略：所有查询结果在返回前按发起用户脱敏或过滤受限列
+func (e *SQLExecutor) SetColumnPolicy(enforcer *ColumnPolicyEnforcer) {
+	e.columnPolicy = enforcer
+}
+
+// SetResidency 启用数据驻留校验：查询结果会作为快照写入结果缓存、异步任务与定时执行记录，
+// 发起用户（未标记时为连接所有者）的工作空间要求的快照位置与本服务不一致时拒绝执行
+func (e *SQLExecutor) SetResidency(residency *ResidencyService) {
+	e.residency = residency
+}
+
+// SetRealtimeHub 启用实时事件：通过WithQueryOwner标记发起用户的查询在开始与结束时向其工作空间广播
+func (e *SQLExecutor) SetRealtimeHub(hub *RealtimeHub) {
+	e.realtime = hub
+}
+
 // ExecuteQuery 执行SQL查询
-// 在指定的数据库连接上执行SELECT查询，支持超时控制和结果大小限制
+// 在指定的数据库连接上执行SELECT查询，支持超时控制和结果大小限制；启用列策略时返回前应用
 func (e *SQLExecutor) ExecuteQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error) {
+	// span只记录连接与结果规模，不记录SQL文本
+	ctx, span := tracing.Start(ctx, "db.query",
+		attribute.String("db.system.name", string(connectionDBType(connection))),
+		attribute.String("db.namespace", connection.DatabaseName),
+		attribute.Int64("chat2sql.connection_id", connection.ID))
+	finished := e.realtime.publishQuery(ctx, connection.ID)
+	result, err := e.executeQuery(ctx, sql, connection)
+	if err == nil && e.columnPolicy != nil {
+		e.columnPolicy.Enforce(ctx, sql, connection.ID, result)
+	}
+	if result != nil {
+		finished(result.Status)
+	} else {
+		finished(string(repository.QueryError))
+	}
+	if result != nil {
+		span.SetAttributes(
+			attribute.String("chat2sql.query_status", result.Status),
+			attribute.Int("db.response.returned_rows", int(result.RowCount)))
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+// executeQuery 执行SQL查询，span由ExecuteQuery创建
+func (e *SQLExecutor) executeQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection) (*QueryResult, error) {
 	start := time.Now()
+	if err := e.checkResidency(ctx, connection); err != nil {
+		return &QueryResult{Status: string(repository.QueryError), Error: err.Error()}, err
+	}
+	ctx, finish := e.runningQueries.Track(ctx, connection.ID, sql)
+	defer finish()
 
-	e.logger.Info("开始执行SQL查询",
+	e.logger.Debug("开始执行SQL查询",
 		zap.String("sql", sql),
 		zap.Int64("connection_id", connection.ID),
 		zap.String("database", connection.DatabaseName))
@@ -108,20 +190,54 @@ func (e *SQLExecutor) ExecuteQuery(ctx context.Context, sql string, connection *
 	queryCtx, cancel := context.WithTimeout(ctx, e.queryTimeout)
 	defer cancel()
 
-	// 通过ConnectionManager获取目标数据库连接池
-	targetPool, err := e.connectionManager.GetConnectionPool(queryCtx, connection.ID)
-	if err != nil {
-		return &QueryResult{
-			Status:        string(repository.QueryError),
-			Error:         fmt.Sprintf("数据库连接失败: %v", err),
-			ExecutionTime: int32(time.Since(start).Milliseconds()),
-		}, err
+	// 按连接解析结果上限；SQL没有LIMIT时多取一行，用于判断结果是否被截断
+	limits := e.limitsFor(ctx, connection)
+	queryCtx = withResultLimits(queryCtx, limits)
+	var guardrails []Guardrail
+	if e.autoLimit {
+		if limited, ok := injectLimit(sql, int64(limits.maxRows)+1); ok {
+			sql = limited
+			guardrails = append(guardrails, Guardrail{Type: GuardrailAutoLimit, Limit: int64(limits.maxRows) + 1})
+		}
 	}
-	// 注意：不需要关闭连接池，由ConnectionManager管理
 
-	// 执行查询
-	result, err := e.executeQueryOnPool(queryCtx, sql, targetPool)
+	// 通过ConnectionManager获取目标数据库连接池并执行查询
+	// 注意：不需要关闭连接池，由ConnectionManager管理
+	var result *QueryResult
+	var err error
+	if dbType := connectionDBType(connection); dbType == repository.DBTypeMySQL || dbType == repository.DBTypeSQLite {
+		db, dbErr := e.connectionManager.GetSQLDB(queryCtx, connection.ID)
+		if dbErr != nil {
+			result := connectionFailedResult(dbErr, start)
+			e.recordMetrics(connection.ID, sql, result, start)
+			return result, dbErr
+		}
+		result, err = e.executeQueryOnDB(queryCtx, sql, db, dbType)
+	} else {
+		targetPool, poolErr := e.connectionManager.GetConnectionPool(queryCtx, connection.ID)
+		if poolErr != nil {
+			result := connectionFailedResult(poolErr, start)
+			e.recordMetrics(connection.ID, sql, result, start)
+			return result, poolErr
+		}
+		result, err = e.executeQueryOnPool(queryCtx, sql, targetPool)
+	}
 	result.ExecutionTime = int32(time.Since(start).Milliseconds())
+	result.Guardrails = append(append([]Guardrail{timeoutGuardrail(e.queryTimeout)}, guardrails...), result.Guardrails...)
+
+	// 被用户取消或超时中止的查询单独标记状态，与数据库返回的错误区分
+	if err != nil {
+		switch {
+		case QueryCancelled(queryCtx):
+			result.Status = string(repository.QueryCancelled)
+			result.Error = ErrQueryCancelled.Error()
+			err = ErrQueryCancelled
+		case errors.Is(queryCtx.Err(), context.DeadlineExceeded):
+			result.Status = string(repository.QueryTimeout)
+			result.Error = fmt.Sprintf("查询执行超时（超过%s）", e.queryTimeout)
+		}
+	}
+	e.recordMetrics(connection.ID, sql, result, start)
 
 	if err != nil {
 		e.logger.Error("SQL查询执行失败",
@@ -141,8 +257,38 @@ func (e *SQLExecutor) ExecuteQuery(ctx context.Context, sql string, connection *
 	return result, nil
 }
 
+// recordMetrics 记录一次执行的指标，查询类别按SQL关键词划分，取值固定；未启用执行指标时不记录
+func (e *SQLExecutor) recordMetrics(connectionID int64, sql string, result *QueryResult, start time.Time) {
+	if e.metrics == nil {
+		return
+	}
+	category := ai.CategorizeQuery(sql)
+	e.metrics.RecordQueryExecution(connectionID, string(category), result.Status, time.Since(start), int(result.RowCount))
+}
+
+// checkResidency 按发起用户所属工作空间的驻留策略校验结果快照的存储位置，未启用时不校验
+func (e *SQLExecutor) checkResidency(ctx context.Context, connection *repository.DatabaseConnection) error {
+	if e.residency == nil {
+		return nil
+	}
+	userID := queryOwnerFromContext(ctx)
+	if userID == 0 {
+		userID = connection.UserID
+	}
+	return e.residency.CheckLocalWrite(ctx, userID, repository.StorageSnapshot)
+}
 
-// executeQueryOnPool 在指定连接池上执行查询
+// connectionFailedResult 获取连接池失败时的查询结果
+func connectionFailedResult(err error, start time.Time) *QueryResult {
+	return &QueryResult{
+		Status:        string(repository.QueryError),
+		Error:         fmt.Sprintf("数据库连接失败: %v", err),
+		ExecutionTime: int32(time.Since(start).Milliseconds()),
+	}
+}
+
+// executeQueryOnPool 在指定连接池的PostgreSQL只读事务中执行查询
+// SQL校验只是词法预检，只读由这里的只读事务保证，名单外的函数与以字符串执行的语句中的写操作都会被拒绝
 func (e *SQLExecutor) executeQueryOnPool(ctx context.Context, sql string, pool *pgxpool.Pool) (*QueryResult, error) {
 	result := &QueryResult{
 		Columns:   []string{},
@@ -152,8 +298,16 @@ func (e *SQLExecutor) executeQueryOnPool(ctx context.Context, sql string, pool *
 		Warnings:  []string{},
 	}
 
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		result.Status = string(repository.QueryError)
+		result.Error = fmt.Sprintf("开启只读事务失败: %v", err)
+		return result, err
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
 	// 执行查询
-	rows, err := pool.Query(ctx, sql)
+	rows, err := tx.Query(ctx, sql, queryArgs(ctx)...)
 	if err != nil {
 		result.Status = string(repository.QueryError)
 		
@@ -177,15 +331,10 @@ func (e *SQLExecutor) executeQueryOnPool(ctx context.Context, sql string, pool *
 	result.Columns = columns
 
 	// 读取数据行
-	var rowCount int32 = 0
-	var totalSizeBytes int64 = 0
-	maxSizeBytes := int64(e.maxResultMB * 1024 * 1024) // 转换为字节
-
+	limiter := e.newResultLimiter(ctx, result)
 	for rows.Next() {
 		// 检查行数限制
-		if rowCount >= e.maxRows {
-			result.Warnings = append(result.Warnings, 
-				fmt.Sprintf("查询结果超过最大行数限制(%d行)，已截断显示", e.maxRows))
+		if limiter.full() {
 			break
 		}
 
@@ -204,29 +353,16 @@ func (e *SQLExecutor) executeQueryOnPool(ctx context.Context, sql string, pool *
 			rowData[columns[i]] = e.convertValue(value)
 		}
 
-		// 估算行大小
-		rowJSON, err := json.Marshal(rowData)
+		// 检查结果集大小限制
+		added, err := limiter.add(rowData)
 		if err != nil {
-			result.Status = string(repository.QueryError)
-			result.Error = fmt.Sprintf("JSON序列化失败: %v", err)
 			return result, err
 		}
-		rowSize := int64(len(rowJSON))
-		
-		// 检查结果集大小限制
-		if totalSizeBytes+rowSize > maxSizeBytes {
-			result.Warnings = append(result.Warnings,
-				fmt.Sprintf("查询结果超过最大大小限制(%dMB)，已截断显示", e.maxResultMB))
+		if !added {
 			break
 		}
-
-		result.Rows = append(result.Rows, rowData)
-		rowCount++
-		totalSizeBytes += rowSize
 	}
 
-	result.RowCount = rowCount
-
 	// 检查迭代器错误
 	if err := rows.Err(); err != nil {
 		result.Status = string(repository.QueryError)
@@ -237,6 +373,63 @@ func (e *SQLExecutor) executeQueryOnPool(ctx context.Context, sql string, pool *
 	return result, nil
 }
 
+// resultLimiter 按行数与结果集大小上限收集查询结果
+type resultLimiter struct {
+	result      *QueryResult
+	maxRows     int32
+	maxBytes    int64
+	maxResultMB int32
+	totalBytes  int64
+}
+
+// newResultLimiter 创建结果收集器，上限见limitsFor
+func (e *SQLExecutor) newResultLimiter(ctx context.Context, result *QueryResult) *resultLimiter {
+	limits := e.resultLimitsFromContext(ctx)
+	return &resultLimiter{
+		result:      result,
+		maxRows:     limits.maxRows,
+		maxBytes:    int64(limits.maxResultMB) * 1024 * 1024, // 转换为字节
+		maxResultMB: limits.maxResultMB,
+	}
+}
+
+// full 已达到行数上限时记录截断警告并返回true
+func (l *resultLimiter) full() bool {
+	if l.result.RowCount < l.maxRows {
+		return false
+	}
+	l.result.Warnings = append(l.result.Warnings,
+		fmt.Sprintf("查询结果超过最大行数限制(%d行)，已截断显示", l.maxRows))
+	l.result.Guardrails = append(l.result.Guardrails, Guardrail{Type: GuardrailRowLimit, Limit: int64(l.maxRows)})
+	l.result.Truncated, l.result.TruncatedBy = true, GuardrailRowLimit
+	return true
+}
+
+// add 追加一行数据，超过结果集大小上限时记录截断警告并返回false
+func (l *resultLimiter) add(row map[string]any) (bool, error) {
+	// 估算行大小
+	rowJSON, err := json.Marshal(row)
+	if err != nil {
+		l.result.Status = string(repository.QueryError)
+		l.result.Error = fmt.Sprintf("JSON序列化失败: %v", err)
+		return false, err
+	}
+	rowSize := int64(len(rowJSON))
+
+	if l.totalBytes+rowSize > l.maxBytes {
+		l.result.Warnings = append(l.result.Warnings,
+			fmt.Sprintf("查询结果超过最大大小限制(%dMB)，已截断显示", l.maxResultMB))
+		l.result.Guardrails = append(l.result.Guardrails, Guardrail{Type: GuardrailSizeLimit, Limit: l.maxBytes})
+		l.result.Truncated, l.result.TruncatedBy = true, GuardrailSizeLimit
+		return false, nil
+	}
+
+	l.result.Rows = append(l.result.Rows, row)
+	l.result.RowCount++
+	l.totalBytes += rowSize
+	return true, nil
+}
+
 // convertValue 转换数据库值为JSON友好的格式
 func (e *SQLExecutor) convertValue(value any) any {
 	if value == nil {
@@ -274,6 +467,152 @@ func (e *SQLExecutor) detectQueryType(sql string) string {
 	}
 }
 
+// EstimateCost 通过EXPLAIN估算查询的总代价（按连接类型使用PostgreSQL或MySQL的代价单位），不实际执行查询
+func (e *SQLExecutor) EstimateCost(ctx context.Context, sql string, connection *repository.DatabaseConnection) (float64, error) {
+	return e.EstimateCostByID(ctx, sql, connection.ID)
+}
+
+// EstimateCostByID 按连接ID估算查询代价，调用方需自行确认连接访问权限
+func (e *SQLExecutor) EstimateCostByID(ctx context.Context, sql string, connectionID int64) (float64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, e.queryTimeout)
+	defer cancel()
+
+	dbType, err := e.connectionManager.GetDBType(queryCtx, connectionID)
+	if err != nil {
+		return 0, fmt.Errorf("数据库连接失败: %w", err)
+	}
+	if dbType == repository.DBTypeMySQL {
+		db, err := e.connectionManager.GetSQLDB(queryCtx, connectionID)
+		if err != nil {
+			return 0, fmt.Errorf("数据库连接失败: %w", err)
+		}
+		return e.estimateMySQLCost(queryCtx, sql, db)
+	}
+	if dbType == repository.DBTypeSQLite {
+		return 0, fmt.Errorf("%w: SQLite不提供查询代价估算", ErrUnsupportedDBType)
+	}
+
+	targetPool, err := e.connectionManager.GetConnectionPool(queryCtx, connectionID)
+	if err != nil {
+		return 0, fmt.Errorf("数据库连接失败: %w", err)
+	}
+
+	var plan []byte
+	if err := targetPool.QueryRow(queryCtx, "EXPLAIN (FORMAT JSON) "+sql, queryArgs(ctx)...).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("获取执行计划失败: %w", err)
+	}
+
+	return parseExplainTotalCost(plan)
+}
+
+// parseExplainTotalCost 从EXPLAIN (FORMAT JSON)输出中读取根节点的Total Cost
+func parseExplainTotalCost(plan []byte) (float64, error) {
+	var explain []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return 0, fmt.Errorf("解析执行计划失败: %w", err)
+	}
+	if len(explain) == 0 {
+		return 0, fmt.Errorf("执行计划为空")
+	}
+	return explain[0].Plan.TotalCost, nil
+}
+
+// PreviewWrite 在事务中执行写操作并回滚，返回受影响行数与前sampleRows行的样本
+// 调用方需确保SQL已通过写模式校验
+func (e *SQLExecutor) PreviewWrite(ctx context.Context, sql string, connection *repository.DatabaseConnection, sampleRows int) (*repository.WritePreview, int64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, e.queryTimeout)
+	defer cancel()
+
+	targetPool, err := e.connectionManager.GetConnectionPool(queryCtx, connection.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("数据库连接失败: %w", err)
+	}
+
+	tx, err := targetPool.Begin(queryCtx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("开启预演事务失败: %w", err)
+	}
+	// 预演无论成功与否都回滚
+	defer tx.Rollback(context.Background())
+
+	rows, err := tx.Query(queryCtx, withReturning(sql))
+	if err != nil {
+		return nil, 0, fmt.Errorf("预演写操作失败: %w", err)
+	}
+	defer rows.Close()
+
+	preview := &repository.WritePreview{Rows: []map[string]any{}}
+	for _, desc := range rows.FieldDescriptions() {
+		preview.Columns = append(preview.Columns, desc.Name)
+	}
+
+	var affected int64
+	for rows.Next() {
+		affected++
+		if len(preview.Rows) >= sampleRows {
+			preview.Truncated = true
+			continue
+		}
+		values, err := rows.Values()
+		if err != nil {
+			return nil, 0, fmt.Errorf("读取预演结果失败: %w", err)
+		}
+		row := make(map[string]any, len(values))
+		for i, value := range values {
+			row[preview.Columns[i]] = e.convertValue(value)
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("预演写操作失败: %w", err)
+	}
+
+	e.logger.Info("写操作预演完成",
+		zap.Int64("connection_id", connection.ID),
+		zap.Int64("affected_rows", affected))
+	return preview, affected, nil
+}
+
+// ExecuteWrite 在事务中执行写操作，影响行数超过maxRows时回滚并返回ErrWriteImpactExceeded
+func (e *SQLExecutor) ExecuteWrite(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64) (int64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, e.queryTimeout)
+	defer cancel()
+
+	targetPool, err := e.connectionManager.GetConnectionPool(queryCtx, connection.ID)
+	if err != nil {
+		return 0, fmt.Errorf("数据库连接失败: %w", err)
+	}
+
+	tx, err := targetPool.Begin(queryCtx)
+	if err != nil {
+		return 0, fmt.Errorf("开启写事务失败: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	tag, err := tx.Exec(queryCtx, sql)
+	if err != nil {
+		return 0, fmt.Errorf("执行写操作失败: %w", err)
+	}
+
+	affected := tag.RowsAffected()
+	if affected > maxRows {
+		return affected, fmt.Errorf("%w: 实际%d行，预演%d行", ErrWriteImpactExceeded, affected, maxRows)
+	}
+
+	if err := tx.Commit(queryCtx); err != nil {
+		return 0, fmt.Errorf("提交写事务失败: %w", err)
+	}
+
+	e.logger.Info("写操作执行成功",
+		zap.Int64("connection_id", connection.ID),
+		zap.Int64("affected_rows", affected))
+	return affected, nil
+}
+
 // TestConnection 测试数据库连接
 func (e *SQLExecutor) TestConnection(ctx context.Context, connection *repository.DatabaseConnection) error {
 	e.logger.Info("测试数据库连接",
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+// ErrParameterMismatch 参数个数与SQL中的占位符不一致
+var ErrParameterMismatch = errors.New("参数个数与SQL中的占位符不一致")
+
+// maxQueryParameters 单条SQL允许的绑定参数个数上限
+const maxQueryParameters = 100
+
+// questionLiteralPatterns 问题中用双引号与中文引号括起的字面值，如"O'Brien"、“华东区”、「张三」
+var questionLiteralPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`"([^"\n]+)"`),
+	regexp.MustCompile(`“([^”\n]+)”`),
+	regexp.MustCompile(`‘([^’\n]+)’`),
+	regexp.MustCompile(`「([^」\n]+)」`),
+	regexp.MustCompile(`『([^』\n]+)』`),
+}
+
+// questionLiterals 返回问题中用引号括起的字面值，如 'Acme, Inc.'
+func questionLiterals(question string) map[string]bool {
+	literals := make(map[string]bool)
+	add := func(value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			literals[value] = true
+		}
+	}
+	for _, pattern := range questionLiteralPatterns {
+		for _, match := range pattern.FindAllStringSubmatch(question, -1) {
+			add(match[1])
+		}
+	}
+
+	// 单引号的开引号前与闭引号后不能是字母或数字，避免把customer's之类的撇号当作引号
+	open := -1
+	for i := 0; i < len(question); i++ {
+		switch {
+		case question[i] == '\n':
+			open = -1
+		case question[i] != '\'':
+		case open < 0:
+			if i == 0 || !isASCIIAlnum(question[i-1]) {
+				open = i
+			}
+		case i+1 == len(question) || !isASCIIAlnum(question[i+1]):
+			add(question[open+1 : i])
+			open = -1
+		}
+	}
+	return literals
+}
+
+func isASCIIAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parameterContexts 字符串常量前可以换成占位符的关键字；其余单词之后的常量可能是DATE '...'、INTERVAL '...'之类的类型化常量，保持原样
+var parameterContexts = map[string]bool{
+	"LIKE": true, "ILIKE": true, "THEN": true, "ELSE": true, "WHEN": true,
+	"AND": true, "OR": true, "NOT": true, "BETWEEN": true, "SELECT": true, "TO": true,
+}
+
+// ParameterizeLiterals 把SQL中取自问题引号内的字符串常量换成占位符，返回改写后的SQL与按占位符顺序排列的参数
+// 常量的值须与问题中的字面值相同，或是两侧加了%的LIKE模式；PostgreSQL使用$1形式的占位符，MySQL与SQLite使用?。
+// SQL已含占位符、无法切分或没有可替换的常量时返回false
+func ParameterizeLiterals(sql, question string, dialect repository.DatabaseType) (string, []string, bool) {
+	literals := questionLiterals(question)
+	if len(literals) == 0 {
+		return sql, nil, false
+	}
+	tokens, err := sqlvalidator.Tokenize(sql)
+	if err != nil {
+		return sql, nil, false
+	}
+
+	var replace []sqlvalidator.Token
+	for i, token := range tokens {
+		if token.Kind == sqlvalidator.TokenParam || (token.Kind == sqlvalidator.TokenOperator && token.Text == "?") {
+			return sql, nil, false
+		}
+		if token.Kind != sqlvalidator.TokenString || !strings.HasPrefix(token.Text, "'") || !literals[strings.Trim(token.Value, "%")] {
+			continue
+		}
+		// MySQL的反斜杠转义与PostgreSQL词法不同，无法确定常量的实际值
+		if dialect == repository.DBTypeMySQL && strings.Contains(token.Text, `\`) {
+			continue
+		}
+		if i > 0 && tokens[i-1].Kind == sqlvalidator.TokenWord && !parameterContexts[tokens[i-1].Keyword()] {
+			continue
+		}
+		replace = append(replace, token)
+	}
+	if len(replace) == 0 || len(replace) > maxQueryParameters {
+		return sql, nil, false
+	}
+
+	var b strings.Builder
+	params := make([]string, 0, len(replace))
+	last := 0
+	for _, token := range replace {
+		params = append(params, token.Value)
+		b.WriteString(sql[last:token.Pos])
+		b.WriteString(placeholder(dialect, len(params)))
+		last = token.Pos + len(token.Text)
+	}
+	b.WriteString(sql[last:])
+	return b.String(), params, true
+}
+
+// placeholder 第n个参数的占位符
+func placeholder(dialect repository.DatabaseType, n int) string {
+	if dialect == repository.DBTypeMySQL || dialect == repository.DBTypeSQLite {
+		return "?"
+	}
+	return "$" + strconv.Itoa(n)
+}
+
+// InlineParameters 把参数按方言转义后写回占位符，得到可直接阅读与重放的SQL，用于查询历史
+// 参数个数与占位符不一致时返回ErrParameterMismatch
+func InlineParameters(sql string, params []string, dialect repository.DatabaseType) (string, error) {
+	tokens, err := sqlvalidator.Tokenize(sql)
+	if err != nil {
+		return "", err
+	}
+
+	type slot struct {
+		token sqlvalidator.Token
+		index int
+	}
+	var slots []slot
+	used := make([]bool, len(params))
+	positional := dialect == repository.DBTypeMySQL || dialect == repository.DBTypeSQLite
+	for _, token := range tokens {
+		switch {
+		case positional && token.Kind == sqlvalidator.TokenOperator && token.Text == "?":
+			slots = append(slots, slot{token, len(slots)})
+		case !positional && token.Kind == sqlvalidator.TokenParam:
+			n, err := strconv.Atoi(token.Text[1:])
+			if err != nil || n < 1 {
+				return "", fmt.Errorf("%w: 无法识别的占位符%s", ErrParameterMismatch, token.Text)
+			}
+			slots = append(slots, slot{token, n - 1})
+		default:
+			continue
+		}
+		index := slots[len(slots)-1].index
+		if index >= len(params) {
+			return "", fmt.Errorf("%w: SQL引用了第%d个参数，只提供了%d个", ErrParameterMismatch, index+1, len(params))
+		}
+		used[index] = true
+	}
+	for i, ok := range used {
+		if !ok {
+			return "", fmt.Errorf("%w: 第%d个参数未被引用", ErrParameterMismatch, i+1)
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool { return slots[i].token.Pos < slots[j].token.Pos })
+	var b strings.Builder
+	last := 0
+	for _, s := range slots {
+		b.WriteString(sql[last:s.token.Pos])
+		b.WriteString(quoteLiteral(params[s.index], dialect))
+		last = s.token.Pos + len(s.token.Text)
+	}
+	b.WriteString(sql[last:])
+	return b.String(), nil
+}
+
+// quoteLiteral 按方言转义字符串常量，MySQL默认把反斜杠视为转义符
+func quoteLiteral(value string, dialect repository.DatabaseType) string {
+	if dialect == repository.DBTypeMySQL {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// queryParametersKey 本次查询绑定参数的context键
+type queryParametersKey struct{}
+
+// WithQueryParameters 为本次查询设置按占位符顺序排列的绑定参数，执行器执行与估算代价时随SQL一起传给数据库
+func WithQueryParameters(ctx context.Context, params []string) context.Context {
+	if len(params) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryParametersKey{}, params)
+}
+
+// queryParameters 读取本次查询的绑定参数，未设置时返回nil
+func queryParameters(ctx context.Context) []string {
+	params, _ := ctx.Value(queryParametersKey{}).([]string)
+	return params
+}
+
+// queryArgs 以驱动接受的形式返回本次查询的绑定参数
+func queryArgs(ctx context.Context) []any {
+	params := queryParameters(ctx)
+	if len(params) == 0 {
+		return nil
+	}
+	args := make([]any, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
+	return args
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+func TestParameterizeLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		question string
+		dialect  repository.DatabaseType
+		want     string
+		params   []string
+	}{
+		{
+			name:     "PostgreSQL按顺序编号",
+			sql:      "SELECT * FROM orders o JOIN customers c ON c.id = o.customer_id WHERE c.name = 'Acme, Inc.' AND o.status = 'paid'",
+			question: "orders for customer 'Acme, Inc.' that are \"paid\"",
+			want:     "SELECT * FROM orders o JOIN customers c ON c.id = o.customer_id WHERE c.name = $1 AND o.status = $2",
+			params:   []string{"Acme, Inc.", "paid"},
+		},
+		{
+			name:     "单引号转义与LIKE模式",
+			sql:      "SELECT * FROM customers WHERE name = 'O''Brien' OR name LIKE '%O''Brien%'",
+			question: `客户“O'Brien”的资料`,
+			dialect:  repository.DBTypeMySQL,
+			want:     "SELECT * FROM customers WHERE name = ? OR name LIKE ?",
+			params:   []string{"O'Brien", "%O'Brien%"},
+		},
+		{
+			name:     "IN列表",
+			sql:      "SELECT * FROM users WHERE city IN ('北京', '上海')",
+			question: "「北京」和「上海」的用户",
+			dialect:  repository.DBTypeSQLite,
+			want:     "SELECT * FROM users WHERE city IN (?, ?)",
+			params:   []string{"北京", "上海"},
+		},
+		{
+			name:     "类型化常量保持原样",
+			sql:      "SELECT * FROM orders WHERE created_at >= DATE '2024-03-01' AND note = '2024-03-01'",
+			question: "note为'2024-03-01'的订单",
+			want:     "SELECT * FROM orders WHERE created_at >= DATE '2024-03-01' AND note = $1",
+			params:   []string{"2024-03-01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, params, ok := ParameterizeLiterals(tt.sql, tt.question, tt.dialect)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, sql)
+			assert.Equal(t, tt.params, params)
+		})
+	}
+
+	unchanged := []struct{ sql, question string }{
+		{"SELECT * FROM orders WHERE status = 'paid'", "customer's paid orders"},
+		{"SELECT * FROM orders WHERE status = 'paid'", "status为'pending'的订单"},
+		{"SELECT * FROM orders WHERE status = $1 OR status = 'paid'", "'paid'订单"},
+		{"SELECT * FROM orders WHERE status = 'paid", "'paid'订单"},
+		{"SELECT * FROM events WHERE age < INTERVAL '1 day'", "'1 day'内的事件"},
+	}
+	for _, tt := range unchanged {
+		sql, params, ok := ParameterizeLiterals(tt.sql, tt.question, repository.DBTypePostgreSQL)
+		assert.False(t, ok, tt.sql)
+		assert.Equal(t, tt.sql, sql)
+		assert.Nil(t, params)
+	}
+}
+
+func TestInlineParameters(t *testing.T) {
+	sql, err := InlineParameters("SELECT * FROM t WHERE a = $1 OR b = $1 OR c = $2", []string{"O'Brien", `C:\dir`}, repository.DBTypePostgreSQL)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM t WHERE a = 'O''Brien' OR b = 'O''Brien' OR c = 'C:\dir'`, sql)
+
+	sql, err = InlineParameters("SELECT * FROM t WHERE a = ? AND b = '?'", []string{`C:\dir`}, repository.DBTypeMySQL)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM t WHERE a = 'C:\\dir' AND b = '?'`, sql)
+
+	for _, params := range [][]string{{"x"}, {"x", "y", "z"}} {
+		_, err = InlineParameters("SELECT * FROM t WHERE a = $1 AND b = $2", params, repository.DBTypePostgreSQL)
+		assert.ErrorIs(t, err, ErrParameterMismatch)
+	}
+	_, err = InlineParameters("SELECT * FROM t WHERE a = ?", []string{"x", "y"}, repository.DBTypeSQLite)
+	assert.ErrorIs(t, err, ErrParameterMismatch)
+}
+
+func TestSQLExecutor_QueryParameters(t *testing.T) {
+	cm, _ := newSQLiteFixture(t)
+	ctx := context.Background()
+
+	db, err := cm.openSQLiteDB(ctx, &repository.DatabaseConnection{DBType: "sqlite", DatabaseName: "shop.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	executor := NewSQLExecutor(nil, cm, zaptest.NewLogger(t))
+	sql, params, ok := ParameterizeLiterals("SELECT id FROM customers WHERE name = 'Alice'", "客户'Alice'", repository.DBTypeSQLite)
+	require.True(t, ok)
+
+	result, err := executor.executeQueryOnDB(WithQueryParameters(ctx, params), sql, db, repository.DBTypeSQLite)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.EqualValues(t, 1, result.Rows[0]["id"])
+
+	// 参数按值传递，引号不会改变SQL结构
+	result, err = executor.executeQueryOnDB(WithQueryParameters(ctx, []string{"x' OR '1'='1"}), sql, db, repository.DBTypeSQLite)
+	require.NoError(t, err)
+	assert.Empty(t, result.Rows)
+}
+
+func TestAIService_GenerateSQL_Parameters(t *testing.T) {
+	primary := &fixedLLM{content: "SELECT * FROM orders WHERE customer = 'Acme, Inc.'"}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), primary, &fixedLLM{}, zaptest.NewLogger(t))
+
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "orders for customer 'Acme, Inc.'"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM orders WHERE customer = 'Acme, Inc.'", resp.SQL)
+	assert.Equal(t, "SELECT * FROM orders WHERE customer = $1", resp.ParameterizedSQL)
+	assert.Equal(t, []string{"Acme, Inc."}, resp.Parameters)
+}
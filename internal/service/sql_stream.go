@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// RowWriter 逐行接收流式查询结果
+// 读到第一行之前先调用一次WriteHeader；返回错误时停止读取
+type RowWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(row map[string]any) error
+}
+
+// StreamQuery 在只读事务中执行查询，逐行交给w而不在内存中缓存整个结果集
+// maxRows大于0时最多读取maxRows行，返回写出的行数与是否因达到上限而截断；
+// 超时由调用方通过ctx控制，不使用交互查询的超时与结果集大小上限
+func (e *SQLExecutor) StreamQuery(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64, w RowWriter) (int64, bool, error) {
+	ctx, finish := e.runningQueries.Track(ctx, connection.ID, sql)
+	defer finish()
+
+	var written int64
+	var truncated bool
+	var err error
+	finished := e.realtime.publishQuery(ctx, connection.ID)
+	defer func() { finished(streamStatus(err)) }()
+	if dbType := connectionDBType(connection); dbType == repository.DBTypeMySQL || dbType == repository.DBTypeSQLite {
+		db, dbErr := e.connectionManager.GetSQLDB(ctx, connection.ID)
+		if dbErr != nil {
+			err = fmt.Errorf("数据库连接失败: %w", dbErr)
+			return 0, false, err
+		}
+		written, truncated, err = e.streamQueryOnDB(ctx, sql, db, dbType, maxRows, w)
+	} else {
+		pool, poolErr := e.connectionManager.GetConnectionPool(ctx, connection.ID)
+		if poolErr != nil {
+			err = fmt.Errorf("数据库连接失败: %w", poolErr)
+			return 0, false, err
+		}
+		written, truncated, err = e.streamQueryOnPool(ctx, sql, pool, maxRows, w)
+	}
+
+	if err != nil && QueryCancelled(ctx) {
+		err = ErrQueryCancelled
+	}
+	if err != nil {
+		e.logger.Error("流式SQL查询失败",
+			zap.Error(err),
+			zap.Int64("connection_id", connection.ID),
+			zap.Int64("rows_written", written))
+		return written, truncated, err
+	}
+
+	e.logger.Info("流式SQL查询完成",
+		zap.Int64("connection_id", connection.ID),
+		zap.Int64("rows_written", written),
+		zap.Bool("truncated", truncated))
+	return written, truncated, nil
+}
+
+// streamStatus 流式查询结束时的执行状态
+func streamStatus(err error) string {
+	switch {
+	case err == nil:
+		return string(repository.QuerySuccess)
+	case errors.Is(err, ErrQueryCancelled):
+		return string(repository.QueryCancelled)
+	case errors.Is(err, context.DeadlineExceeded):
+		return string(repository.QueryTimeout)
+	}
+	return string(repository.QueryError)
+}
+
+// streamQueryOnPool 在PostgreSQL只读事务中逐行读取结果
+func (e *SQLExecutor) streamQueryOnPool(ctx context.Context, query string, pool *pgxpool.Pool, maxRows int64, w RowWriter) (int64, bool, error) {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return 0, false, fmt.Errorf("开启只读事务失败: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return 0, false, fmt.Errorf("查询执行失败: %w", err)
+	}
+	defer rows.Close()
+
+	fieldDescriptions := rows.FieldDescriptions()
+	columns := make([]string, len(fieldDescriptions))
+	for i, desc := range fieldDescriptions {
+		columns[i] = desc.Name
+	}
+	if err := w.WriteHeader(columns); err != nil {
+		return 0, false, err
+	}
+
+	var written int64
+	for rows.Next() {
+		if maxRows > 0 && written >= maxRows {
+			return written, true, nil
+		}
+		values, err := rows.Values()
+		if err != nil {
+			return written, false, fmt.Errorf("读取查询结果失败: %w", err)
+		}
+		row := make(map[string]any, len(columns))
+		for i, value := range values {
+			row[columns[i]] = e.convertValue(value)
+		}
+		if err := w.WriteRow(row); err != nil {
+			return written, false, err
+		}
+		written++
+	}
+	if err := rows.Err(); err != nil {
+		return written, false, fmt.Errorf("读取查询结果时发生错误: %w", err)
+	}
+	return written, false, nil
+}
+
+// streamQueryOnDB 在database/sql只读事务（MySQL、SQLite）中逐行读取结果
+func (e *SQLExecutor) streamQueryOnDB(ctx context.Context, query string, db *sql.DB, dbType repository.DatabaseType, maxRows int64, w RowWriter) (int64, bool, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, false, fmt.Errorf("开启只读事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return 0, false, fmt.Errorf("查询执行失败: %w", err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, false, fmt.Errorf("读取查询结果失败: %w", err)
+	}
+	columns := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = columnType.Name()
+	}
+	if err := w.WriteHeader(columns); err != nil {
+		return 0, false, err
+	}
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	var written int64
+	for rows.Next() {
+		if maxRows > 0 && written >= maxRows {
+			return written, true, nil
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return written, false, fmt.Errorf("读取查询结果失败: %w", err)
+		}
+		row := make(map[string]any, len(columns))
+		for i, value := range values {
+			if dbType == repository.DBTypeMySQL {
+				value = mysqlValue(value, columnTypes[i])
+			}
+			row[columns[i]] = e.convertValue(value)
+		}
+		if err := w.WriteRow(row); err != nil {
+			return written, false, err
+		}
+		written++
+	}
+	if err := rows.Err(); err != nil {
+		return written, false, fmt.Errorf("读取查询结果时发生错误: %w", err)
+	}
+	return written, false, nil
+}
@@ -0,0 +1,225 @@
+// 常见意图的SQL模板
+// 统计表行数、查看表最近N行这类问题措辞固定、SQL唯一，由意图分析的结果与数据库元数据直接拼出SQL，
+// 不调用模型；问题措辞、意图置信度或表名任一不确定时不命中，交给模型生成
+package service
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// SQL模板名称
+const (
+	TemplateCountRows = "count_rows" // 统计表行数
+	TemplateLastRows  = "last_rows"  // 查看表最近N行
+)
+
+// sqlTemplate 一类问题的措辞与对应的意图
+// 措辞须完整匹配整个问题，命名分组table为表名或表注释
+type sqlTemplate struct {
+	name     string
+	intent   ai.QueryIntent
+	patterns []*regexp.Regexp
+}
+
+var sqlTemplates = []sqlTemplate{
+	{
+		name:   TemplateCountRows,
+		intent: ai.IntentAggregation,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:统计|查询|查看|请问)?\s*(?P<table>[\p{Han}\w.]+?)\s*表?(?:里|中|里面)?(?:一共|总共|共)?有多少(?:行|条)(?:数据|记录)?$`),
+			regexp.MustCompile(`^(?:统计|查询|查看)?\s*(?P<table>[\p{Han}\w.]+?)\s*表?的?总?(?:行数|记录数|数据量)$`),
+			regexp.MustCompile(`^(?i)(?:count|number of) (?:the )?(?:rows|records) (?:in|of) (?:the )?(?P<table>[\w.]+?)(?: table)?$`),
+			regexp.MustCompile(`^(?i)how many (?:rows|records) (?:are )?(?:there )?in (?:the )?(?P<table>[\w.]+?)(?: table)?$`),
+		},
+	},
+	{
+		name:   TemplateLastRows,
+		intent: ai.IntentRanking,
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:查看|显示|列出|查询)?\s*(?P<table>[\p{Han}\w.]+?)\s*表?的?(?:最近|最新|最后)的?\s*\d+\s*(?:条|行)(?:数据|记录)?$`),
+			regexp.MustCompile(`^(?:查看|显示|列出|查询)?(?:最近|最新|最后)的?\s*\d+\s*(?:条|行)\s*(?P<table>[\p{Han}\w.]+?)\s*表?的?(?:数据|记录)?$`),
+			regexp.MustCompile(`^(?i)(?:show|list|get) (?:me )?(?:the )?(?:last|latest|newest|most recent) \d+ (?:rows|records) (?:of|from|in) (?:the )?(?P<table>[\w.]+?)(?: table)?$`),
+		},
+	},
+}
+
+// recencyColumns 表示记录创建时间的常见列名，最近N行按其倒序；都没有时按单列主键倒序
+var recencyColumns = []string{"created_at", "create_time", "created_time", "inserted_at", "created"}
+
+// questionSpaces 连续空白，匹配前合并为一个空格
+var questionSpaces = regexp.MustCompile(`\s+`)
+
+// TemplateMatch 命中的模板与生成的SQL
+type TemplateMatch struct {
+	Template string
+	Table    string // schema.table
+	SQL      string
+}
+
+// templateTable 元数据中的一张表
+type templateTable struct {
+	schema  string
+	name    string
+	comment string
+	columns []*repository.SchemaMetadata
+}
+
+// SQLTemplateEngine 常见意图的SQL模板引擎
+type SQLTemplateEngine struct {
+	schemaRepo repository.SchemaRepository
+	config     *config.SQLTemplateConfig
+	logger     *zap.Logger
+}
+
+// NewSQLTemplateEngine 创建SQL模板引擎，配置为nil时使用默认配置
+func NewSQLTemplateEngine(schemaRepo repository.SchemaRepository, templateConfig *config.SQLTemplateConfig, logger *zap.Logger) *SQLTemplateEngine {
+	if templateConfig == nil {
+		templateConfig = config.DefaultSQLTemplateConfig()
+	}
+	return &SQLTemplateEngine{
+		schemaRepo: schemaRepo,
+		config:     templateConfig,
+		logger:     logger,
+	}
+}
+
+// Match 按意图分析结果与连接的元数据匹配模板，未命中时ok为false
+func (e *SQLTemplateEngine) Match(ctx context.Context, req *SQLGenerationRequest, intent *ai.IntentResult) (*TemplateMatch, bool) {
+	if !e.config.Enabled || req.ConnectionID <= 0 || intent == nil || intent.Confidence < e.config.MinIntentConfidence {
+		return nil, false
+	}
+
+	question := strings.TrimRight(strings.TrimSpace(req.Query), "？?。.!！ ")
+	question = questionSpaces.ReplaceAllString(question, " ")
+
+	for _, template := range sqlTemplates {
+		if template.intent != intent.PrimaryIntent {
+			continue
+		}
+		for _, pattern := range template.patterns {
+			m := pattern.FindStringSubmatch(question)
+			if m == nil {
+				continue
+			}
+			table, ok := e.resolveTable(ctx, req.ConnectionID, m[pattern.SubexpIndex("table")])
+			if !ok {
+				return nil, false
+			}
+			sql, ok := e.render(template.name, table, req, intent)
+			if !ok {
+				return nil, false
+			}
+			return &TemplateMatch{Template: template.name, Table: table.schema + "." + table.name, SQL: sql}, true
+		}
+	}
+	return nil, false
+}
+
+// render 按模板生成SQL
+func (e *SQLTemplateEngine) render(template string, table *templateTable, req *SQLGenerationRequest, intent *ai.IntentResult) (string, bool) {
+	from := pgx.Identifier{table.schema, table.name}.Sanitize()
+
+	switch template {
+	case TemplateCountRows:
+		alias := `"行数"`
+		if req.Locale == "en" {
+			alias = "row_count"
+		}
+		return "SELECT COUNT(*) AS " + alias + " FROM " + from, true
+
+	case TemplateLastRows:
+		// SELECT *无法逐列标注来源，表中有受限列时交给模型按受限列提示生成
+		prefix := strings.ToLower(table.schema + "." + table.name + ".")
+		for _, column := range req.RestrictedColumns {
+			if strings.HasPrefix(strings.ToLower(column), prefix) {
+				return "", false
+			}
+		}
+
+		// 行数取意图分析提取的数字实体，问题中只能有一个数字
+		numbers := intent.Entities["number"]
+		if len(numbers) != 1 {
+			return "", false
+		}
+		n, err := strconv.Atoi(numbers[0])
+		if err != nil || n <= 0 || n > e.config.MaxRows {
+			return "", false
+		}
+
+		orderBy := recencyColumn(table.columns)
+		if orderBy == "" {
+			return "", false
+		}
+		return "SELECT * FROM " + from + " ORDER BY " + pgx.Identifier{orderBy}.Sanitize() + " DESC LIMIT " + strconv.Itoa(n), true
+	}
+	return "", false
+}
+
+// resolveTable 按表名、schema.表名或表注释在连接元数据中查找唯一的表
+func (e *SQLTemplateEngine) resolveTable(ctx context.Context, connectionID int64, name string) (*templateTable, bool) {
+	metadata, err := e.schemaRepo.ListByConnection(ctx, connectionID)
+	if err != nil {
+		e.logger.Warn("读取表结构元数据失败", zap.Int64("connection_id", connectionID), zap.Error(err))
+		return nil, false
+	}
+
+	tables := make(map[string]*templateTable)
+	for _, column := range metadata {
+		key := column.SchemaName + "." + column.TableName
+		table, ok := tables[key]
+		if !ok {
+			table = &templateTable{schema: column.SchemaName, name: column.TableName}
+			tables[key] = table
+		}
+		if column.TableComment != nil && table.comment == "" {
+			table.comment = strings.TrimSuffix(strings.TrimSpace(*column.TableComment), "表")
+		}
+		table.columns = append(table.columns, column)
+	}
+
+	var found *templateTable
+	for key, table := range tables {
+		if !strings.EqualFold(name, table.name) && !strings.EqualFold(name, key) && (table.comment == "" || name != table.comment) {
+			continue
+		}
+		if found != nil {
+			return nil, false // 多个schema下同名，无法确定
+		}
+		found = table
+	}
+	return found, found != nil
+}
+
+// recencyColumn 最近N行的排序列：优先创建时间列，其次单列主键
+func recencyColumn(columns []*repository.SchemaMetadata) string {
+	for _, name := range recencyColumns {
+		for _, column := range columns {
+			dataType := strings.ToLower(column.DataType)
+			if strings.EqualFold(column.ColumnName, name) && (strings.Contains(dataType, "timestamp") || strings.Contains(dataType, "date")) {
+				return column.ColumnName
+			}
+		}
+	}
+
+	var primaryKey string
+	for _, column := range columns {
+		if !column.IsPrimaryKey {
+			continue
+		}
+		if primaryKey != "" {
+			return "" // 联合主键没有自然的先后顺序
+		}
+		primaryKey = column.ColumnName
+	}
+	return primaryKey
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// templateMetadata orders表按created_at排序，audit_logs只有主键，events为联合主键
+func templateMetadata() []*repository.SchemaMetadata {
+	comment := "订单表"
+	return []*repository.SchemaMetadata{
+		{ConnectionID: 1, SchemaName: "public", TableName: "orders", ColumnName: "id", DataType: "bigint", IsPrimaryKey: true, TableComment: &comment},
+		{ConnectionID: 1, SchemaName: "public", TableName: "orders", ColumnName: "created_at", DataType: "timestamp with time zone", TableComment: &comment},
+		{ConnectionID: 1, SchemaName: "public", TableName: "audit_logs", ColumnName: "id", DataType: "bigint", IsPrimaryKey: true},
+		{ConnectionID: 1, SchemaName: "public", TableName: "events", ColumnName: "source", DataType: "text", IsPrimaryKey: true},
+		{ConnectionID: 1, SchemaName: "public", TableName: "events", ColumnName: "seq", DataType: "bigint", IsPrimaryKey: true},
+	}
+}
+
+func newTestTemplateEngine(t *testing.T) *SQLTemplateEngine {
+	schemaRepo := &MockSchemaRepository{}
+	schemaRepo.On("ListByConnection", mock.Anything, int64(1)).Return(templateMetadata(), nil)
+	return NewSQLTemplateEngine(schemaRepo, nil, zaptest.NewLogger(t))
+}
+
+func TestSQLTemplateEngine_Match(t *testing.T) {
+	engine := newTestTemplateEngine(t)
+	analyzer := ai.NewIntentAnalyzer()
+
+	cases := []struct {
+		query    string
+		locale   string
+		template string
+		sql      string
+	}{
+		{"orders表有多少行", "", TemplateCountRows, `SELECT COUNT(*) AS "行数" FROM "public"."orders"`},
+		{"订单表有多少条数据？", "", TemplateCountRows, `SELECT COUNT(*) AS "行数" FROM "public"."orders"`},
+		{"How many rows are in the orders table?", "en", TemplateCountRows, `SELECT COUNT(*) AS row_count FROM "public"."orders"`},
+		{"show last 10 rows of orders", "en", TemplateLastRows, `SELECT * FROM "public"."orders" ORDER BY "created_at" DESC LIMIT 10`},
+		{"最近20条orders记录", "", TemplateLastRows, `SELECT * FROM "public"."orders" ORDER BY "created_at" DESC LIMIT 20`},
+		{"查看audit_logs表最后5行", "", TemplateLastRows, `SELECT * FROM "public"."audit_logs" ORDER BY "id" DESC LIMIT 5`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			req := &SQLGenerationRequest{Query: tc.query, ConnectionID: 1, Locale: tc.locale}
+			match, ok := engine.Match(context.Background(), req, analyzer.AnalyzeIntentDetailed(tc.query, 7))
+			require.True(t, ok)
+			assert.Equal(t, tc.template, match.Template)
+			assert.Equal(t, tc.sql, match.SQL)
+		})
+	}
+}
+
+func TestSQLTemplateEngine_NoMatch(t *testing.T) {
+	engine := newTestTemplateEngine(t)
+	analyzer := ai.NewIntentAnalyzer()
+
+	cases := map[string]*SQLGenerationRequest{
+		"未知的表":     {Query: "customers表有多少行", ConnectionID: 1},
+		"带条件的问题":   {Query: "已支付的订单有多少条", ConnectionID: 1},
+		"联合主键无法排序": {Query: "show last 10 rows of events", ConnectionID: 1},
+		"超过最大行数":   {Query: "show last 5000 rows of orders", ConnectionID: 1},
+		"表中有受限列":   {Query: "show last 10 rows of orders", ConnectionID: 1, RestrictedColumns: []string{"public.orders.email (pii)"}},
+		"未指定连接":    {Query: "orders表有多少行"},
+		"意图置信度不足":  {Query: "最近20条订单记录", ConnectionID: 1},
+	}
+	for name, req := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, ok := engine.Match(context.Background(), req, analyzer.AnalyzeIntentDetailed(req.Query, 7))
+			assert.False(t, ok)
+		})
+	}
+
+	disabled := config.DefaultSQLTemplateConfig()
+	disabled.Enabled = false
+	engine.config = disabled
+	req := &SQLGenerationRequest{Query: "orders表有多少行", ConnectionID: 1}
+	_, ok := engine.Match(context.Background(), req, analyzer.AnalyzeIntentDetailed(req.Query, 7))
+	assert.False(t, ok)
+}
+
+func TestAIService_GenerateSQL_Template(t *testing.T) {
+	primary := &seededLLM{}
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), primary, &seededLLM{}, zaptest.NewLogger(t))
+	aiService.SetSQLTemplates(newTestTemplateEngine(t))
+
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "orders表有多少行", ConnectionID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, TemplateCountRows, resp.Template)
+	assert.Equal(t, `SELECT COUNT(*) AS "行数" FROM "public"."orders"`, resp.SQL)
+	assert.Equal(t, 0, primary.calls, "命中模板时不调用模型")
+	assert.False(t, resp.RequiresConfirmation)
+
+	// 未命中模板时交给模型生成
+	resp, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "每个城市的订单金额", ConnectionID: 1})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Template)
+	assert.Equal(t, 1, primary.calls)
+}
+
+func TestAIService_GenerateSQL_Formatting(t *testing.T) {
+	aiService := NewAIServiceWithClients(config.DemoAIConfig(""), &seededLLM{}, &seededLLM{}, zaptest.NewLogger(t))
+	aiService.SetSQLTemplates(newTestTemplateEngine(t))
+	aiService.SetSQLFormatting(true)
+
+	resp, err := aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "orders表有多少行", ConnectionID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT COUNT(*) AS \"行数\"\nFROM \"public\".\"orders\"", resp.SQL)
+
+	resp, err = aiService.GenerateSQL(context.Background(), &SQLGenerationRequest{Query: "每个城市的订单金额", ConnectionID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id\nFROM users\nLIMIT 1", resp.SQL)
+	assert.Equal(t, "SELECT id FROM users LIMIT 1", resp.Completion, "模型原始输出不变")
+}
@@ -0,0 +1,325 @@
+// SQLite连接支持
+// SQLite连接读取服务端SQLITE_DATA_DIR下的数据库文件，database_name为文件在该目录下的相对路径；
+// 文件以只读方式打开（mode=ro且query_only），不提供代价估算，写模式不支持SQLite连接
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // 注册sqlite3驱动
+
+	"chat2sql-go/internal/repository"
+)
+
+// SQLite连接错误
+var (
+	ErrSQLiteDisabled       = errors.New("未配置SQLITE_DATA_DIR，不允许创建SQLite连接")
+	ErrSQLitePathNotAllowed = errors.New("SQLite数据库文件不在允许的目录中")
+)
+
+// sqliteSchemaName SQLite连接的schema名，与SQLite中主数据库的名称一致
+const sqliteSchemaName = "main"
+
+// SetSQLiteDataDir 设置SQLite数据库文件所在目录，为空时不允许SQLite连接
+func (cm *ConnectionManager) SetSQLiteDataDir(dir string) {
+	cm.sqliteDataDir = dir
+}
+
+// resolveSQLitePath 将连接的database_name解析为数据目录下的文件路径
+// 解析符号链接后仍需位于数据目录内，且必须是已存在的普通文件
+func (cm *ConnectionManager) resolveSQLitePath(name string) (string, error) {
+	if cm.sqliteDataDir == "" {
+		return "", ErrSQLiteDisabled
+	}
+
+	dataDir, err := filepath.EvalSymlinks(cm.sqliteDataDir)
+	if err != nil {
+		return "", fmt.Errorf("SQLite数据目录不可用: %w", err)
+	}
+
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dataDir, path)
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("SQLite数据库文件不存在: %s", name)
+	}
+
+	rel, err := filepath.Rel(dataDir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrSQLitePathNotAllowed, name)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("SQLite数据库文件不存在: %s", name)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: %s不是普通文件", ErrSQLitePathNotAllowed, name)
+	}
+	return path, nil
+}
+
+// sqliteDSN 构建只读打开SQLite文件的连接串
+func sqliteDSN(path string, busyTimeout time.Duration) string {
+	query := url.Values{}
+	query.Set("mode", "ro")
+	query.Set("_query_only", "true")
+	query.Set("_busy_timeout", fmt.Sprint(busyTimeout.Milliseconds()))
+	return (&url.URL{Scheme: "file", Path: path, RawQuery: query.Encode()}).String()
+}
+
+// openSQLiteDB 以只读方式打开SQLite文件并确认是有效的数据库
+func (cm *ConnectionManager) openSQLiteDB(ctx context.Context, connection *repository.DatabaseConnection) (*sql.DB, error) {
+	path, err := cm.resolveSQLitePath(connection.DatabaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", sqliteDSN(path, cm.connectionTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("打开SQLite数据库失败: %w", err)
+	}
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(2)
+	db.SetConnMaxIdleTime(15 * time.Minute)
+
+	testCtx, cancel := context.WithTimeout(ctx, cm.connectionTimeout)
+	defer cancel()
+
+	// Ping不读取文件内容，查询sqlite_master才能发现不是SQLite数据库的文件
+	var tables int
+	if err := db.QueryRowContext(testCtx, "SELECT count(*) FROM sqlite_master").Scan(&tables); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接测试失败: %w", err)
+	}
+	return db, nil
+}
+
+// testSQLiteConnection 测试SQLite文件能否以只读方式打开
+func (cm *ConnectionManager) testSQLiteConnection(ctx context.Context, connection *repository.DatabaseConnection) error {
+	db, err := cm.openSQLiteDB(ctx, connection)
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// introspectSQLite 探测SQLite数据库的表结构，主数据库作为唯一的schema
+// SQLite没有表注释与行数统计，EstimatedRows与注释为空
+func (si *SchemaIntrospector) introspectSQLite(ctx context.Context, db *sql.DB) ([]SchemaInfo, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询表列表失败: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("扫描表信息失败: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询表列表失败: %w", err)
+	}
+
+	if len(names) > si.maxTablesPerSchema {
+		names = names[:si.maxTablesPerSchema]
+	}
+
+	tables := make([]TableInfo, 0, len(names))
+	for _, name := range names {
+		table := TableInfo{SchemaName: sqliteSchemaName, TableName: name, TableType: "BASE TABLE"}
+		if table.Columns, err = si.getSQLiteColumns(ctx, db, name); err != nil {
+			return nil, fmt.Errorf("获取表%s的列信息失败: %w", name, err)
+		}
+		table.ColumnCount = len(table.Columns)
+
+		var uniqueIndexes []IndexInfo
+		if si.enableIndexInfo || si.enableConstraintInfo {
+			indexes, err := si.getSQLiteIndexes(ctx, db, name)
+			if err != nil {
+				return nil, fmt.Errorf("获取表%s的索引信息失败: %w", name, err)
+			}
+			if si.enableIndexInfo {
+				table.Indexes = indexes
+			}
+			for _, index := range indexes {
+				if index.IsUnique && !index.IsPrimary {
+					uniqueIndexes = append(uniqueIndexes, index)
+				}
+			}
+		}
+		if si.enableConstraintInfo {
+			table.Constraints = sqliteConstraints(name, table.Columns, uniqueIndexes)
+		}
+		tables = append(tables, table)
+	}
+
+	return []SchemaInfo{{
+		SchemaName: sqliteSchemaName,
+		Tables:     tables,
+		TableCount: len(tables),
+	}}, nil
+}
+
+// getSQLiteColumns 通过table_info与foreign_key_list获取列信息
+func (si *SchemaIntrospector) getSQLiteColumns(ctx context.Context, db *sql.DB, table string) ([]ColumnInfo, error) {
+	foreignKeys := make(map[string][2]string)
+	fkRows, err := db.QueryContext(ctx, `SELECT "from", "table", coalesce("to", '') FROM pragma_foreign_key_list(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	for fkRows.Next() {
+		var from, refTable, refColumn string
+		if err := fkRows.Scan(&from, &refTable, &refColumn); err != nil {
+			fkRows.Close()
+			return nil, err
+		}
+		if _, ok := foreignKeys[from]; !ok {
+			foreignKeys[from] = [2]string{refTable, refColumn}
+		}
+	}
+	fkRows.Close()
+	if err := fkRows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT cid, name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var cid, pk int32
+		var notNull bool
+		var columnDefault sql.NullString
+		col := ColumnInfo{}
+		if err := rows.Scan(&cid, &col.ColumnName, &col.DataType, &notNull, &columnDefault, &pk); err != nil {
+			return nil, err
+		}
+		col.OrdinalPosition = cid + 1
+		col.IsNullable = !notNull && pk == 0
+		col.IsPrimaryKey = pk > 0
+		if columnDefault.Valid {
+			col.ColumnDefault = &columnDefault.String
+		}
+		if fk, ok := foreignKeys[col.ColumnName]; ok {
+			refTable, refColumn := fk[0], fk[1]
+			col.IsForeignKey = true
+			col.ForeignTable = &refTable
+			// 外键未指定引用列时引用对方的主键
+			if refColumn != "" {
+				col.ForeignColumn = &refColumn
+			}
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// getSQLiteIndexes 通过index_list与index_info获取索引信息，origin为pk的是主键索引
+func (si *SchemaIntrospector) getSQLiteIndexes(ctx context.Context, db *sql.DB, table string) ([]IndexInfo, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, "unique", origin FROM pragma_index_list(?) ORDER BY seq`, table)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []IndexInfo
+	for rows.Next() {
+		var index IndexInfo
+		var origin string
+		if err := rows.Scan(&index.IndexName, &index.IsUnique, &origin); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index.IsPrimary = origin == "pk"
+		index.IndexType = "btree"
+		indexes = append(indexes, index)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range indexes {
+		columnRows, err := db.QueryContext(ctx, `SELECT name FROM pragma_index_info(?) WHERE name IS NOT NULL ORDER BY seqno`, indexes[i].IndexName)
+		if err != nil {
+			return nil, err
+		}
+		for columnRows.Next() {
+			var column string
+			if err := columnRows.Scan(&column); err != nil {
+				columnRows.Close()
+				return nil, err
+			}
+			indexes[i].Columns = append(indexes[i].Columns, column)
+		}
+		columnRows.Close()
+		if err := columnRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return indexes, nil
+}
+
+// sqliteConstraints 由主键列、外键列与唯一索引构造约束信息，SQLite的约束通常没有名称，按表名生成
+func sqliteConstraints(table string, columns []ColumnInfo, uniqueIndexes []IndexInfo) []Constraint {
+	var constraints []Constraint
+
+	var primaryKey []string
+	for _, col := range columns {
+		if col.IsPrimaryKey {
+			primaryKey = append(primaryKey, col.ColumnName)
+		}
+	}
+	if len(primaryKey) > 0 {
+		constraints = append(constraints, Constraint{
+			ConstraintName: table + "_pkey",
+			ConstraintType: "PRIMARY KEY",
+			Columns:        primaryKey,
+		})
+	}
+
+	for _, col := range columns {
+		if !col.IsForeignKey {
+			continue
+		}
+		constraint := Constraint{
+			ConstraintName: table + "_" + col.ColumnName + "_fkey",
+			ConstraintType: "FOREIGN KEY",
+			Columns:        []string{col.ColumnName},
+			RefTable:       col.ForeignTable,
+		}
+		if col.ForeignColumn != nil {
+			constraint.RefColumns = []string{*col.ForeignColumn}
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	for _, index := range uniqueIndexes {
+		constraints = append(constraints, Constraint{
+			ConstraintName: index.IndexName,
+			ConstraintType: "UNIQUE",
+			Columns:        index.Columns,
+		})
+	}
+	return constraints
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+// newSQLiteFixture 在临时数据目录中创建包含customers与orders两张表的SQLite文件
+func newSQLiteFixture(t *testing.T) (*ConnectionManager, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "shop.db"))
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, name TEXT);
+		CREATE TABLE orders (
+			id INTEGER PRIMARY KEY,
+			customer_id INTEGER NOT NULL REFERENCES customers(id),
+			amount REAL DEFAULT 0,
+			created_at TEXT
+		);
+		CREATE INDEX idx_orders_customer ON orders(customer_id);
+		INSERT INTO customers VALUES (1, 'a@example.com', 'Alice'), (2, 'b@example.com', NULL);
+		INSERT INTO orders VALUES (1, 1, 19.5, '2024-03-01'), (2, 1, 5, '2024-03-02'), (3, 2, 12.25, '2024-03-02');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	cm := &ConnectionManager{connectionTimeout: 5 * time.Second, logger: zaptest.NewLogger(t)}
+	cm.SetSQLiteDataDir(dir)
+	return cm, dir
+}
+
+func TestResolveSQLitePath(t *testing.T) {
+	cm, dir := newSQLiteFixture(t)
+
+	path, err := cm.resolveSQLitePath("shop.db")
+	require.NoError(t, err)
+	assert.Equal(t, "shop.db", filepath.Base(path))
+
+	// 数据目录外的文件与指向目录外的符号链接都被拒绝
+	outside := filepath.Join(t.TempDir(), "other.db")
+	require.NoError(t, os.WriteFile(outside, nil, 0o600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link.db")))
+	relative, err := filepath.Rel(dir, outside)
+	require.NoError(t, err)
+	for _, name := range []string{relative, outside, "link.db", "."} {
+		_, err := cm.resolveSQLitePath(name)
+		assert.ErrorIs(t, err, ErrSQLitePathNotAllowed, name)
+	}
+
+	_, err = cm.resolveSQLitePath("missing.db")
+	assert.Error(t, err)
+
+	_, err = (&ConnectionManager{}).resolveSQLitePath("shop.db")
+	assert.ErrorIs(t, err, ErrSQLiteDisabled)
+}
+
+func TestSQLiteConnection_Test(t *testing.T) {
+	cm, dir := newSQLiteFixture(t)
+	ctx := context.Background()
+
+	assert.NoError(t, cm.testConnectionDirect(ctx, &repository.DatabaseConnection{DBType: "sqlite", DatabaseName: "shop.db"}, ""))
+
+	// 不是SQLite数据库的文件在测试连接时即报错
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.db"), []byte("plain text, not a database file"), 0o600))
+	assert.Error(t, cm.testConnectionDirect(ctx, &repository.DatabaseConnection{DBType: "sqlite", DatabaseName: "notes.db"}, ""))
+}
+
+func TestSQLiteExecuteQuery(t *testing.T) {
+	cm, _ := newSQLiteFixture(t)
+	ctx := context.Background()
+
+	db, err := cm.openSQLiteDB(ctx, &repository.DatabaseConnection{DBType: "sqlite", DatabaseName: "shop.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	executor := NewSQLExecutor(nil, cm, zaptest.NewLogger(t))
+	result, err := executor.executeQueryOnDB(ctx, "SELECT c.name, sum(o.amount) AS total FROM orders o JOIN customers c ON c.id = o.customer_id GROUP BY c.id ORDER BY c.id", db, repository.DBTypeSQLite)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "total"}, result.Columns)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, "Alice", result.Rows[0]["name"])
+	assert.Equal(t, 24.5, result.Rows[0]["total"])
+	assert.Nil(t, result.Rows[1]["name"])
+	assert.Contains(t, result.Guardrails, Guardrail{Type: GuardrailReadOnly})
+
+	// 文件以只读方式打开，写操作被拒绝
+	result, err = executor.executeQueryOnDB(ctx, "DELETE FROM orders", db, repository.DBTypeSQLite)
+	assert.Error(t, err)
+	assert.Equal(t, string(repository.QueryError), result.Status)
+	assert.Contains(t, result.Error, "readonly")
+}
+
+func TestIntrospectSQLite(t *testing.T) {
+	cm, _ := newSQLiteFixture(t)
+	ctx := context.Background()
+
+	db, err := cm.openSQLiteDB(ctx, &repository.DatabaseConnection{DBType: "sqlite", DatabaseName: "shop.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	si := NewSchemaIntrospector(cm, nil, zaptest.NewLogger(t))
+	schemas, err := si.introspectSQLite(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, schemas, 1)
+	assert.Equal(t, "main", schemas[0].SchemaName)
+	require.Equal(t, 2, schemas[0].TableCount)
+
+	customers, orders := schemas[0].Tables[0], schemas[0].Tables[1]
+	assert.Equal(t, "customers", customers.TableName)
+	assert.Equal(t, "orders", orders.TableName)
+
+	require.Len(t, orders.Columns, 4)
+	id, customerID, amount := orders.Columns[0], orders.Columns[1], orders.Columns[2]
+	assert.True(t, id.IsPrimaryKey)
+	assert.False(t, id.IsNullable)
+	assert.True(t, customerID.IsForeignKey)
+	assert.Equal(t, "customers", *customerID.ForeignTable)
+	assert.Equal(t, "id", *customerID.ForeignColumn)
+	assert.False(t, customerID.IsNullable)
+	assert.Equal(t, "REAL", amount.DataType)
+	assert.Equal(t, "0", *amount.ColumnDefault)
+	assert.Equal(t, int32(3), amount.OrdinalPosition)
+
+	require.Len(t, orders.Indexes, 1)
+	assert.Equal(t, "idx_orders_customer", orders.Indexes[0].IndexName)
+	assert.Equal(t, []string{"customer_id"}, orders.Indexes[0].Columns)
+
+	var types []string
+	for _, constraint := range customers.Constraints {
+		types = append(types, constraint.ConstraintType)
+	}
+	assert.Equal(t, []string{"PRIMARY KEY", "UNIQUE"}, types)
+	assert.Equal(t, []string{"email"}, customers.Constraints[1].Columns)
+	assert.Equal(t, "FOREIGN KEY", orders.Constraints[1].ConstraintType)
+}
+
+func TestAIService_SQLiteDialect(t *testing.T) {
+	ai := &AIService{logger: zaptest.NewLogger(t)}
+	prompt, err := ai.buildPrompt(&SQLGenerationRequest{Query: "每天的订单数", Dialect: repository.DBTypeSQLite})
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "生成准确的SQLite查询语句")
+	assert.Contains(t, prompt, "使用SQLite 3语法")
+	assert.NotContains(t, prompt, "PostgreSQL")
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+// tableUsageHistoryLimit 统计表使用次数时读取的连接最近历史查询数
+const tableUsageHistoryLimit = 500
+
+// TableUsageStats 按连接的查询历史统计各表被成功查询的次数，供提示词在相似的表之间优先选择用户与工作空间常用的表
+type TableUsageStats struct {
+	history    repository.QueryHistoryRepository
+	workspaces repository.WorkspaceRepository
+	logger     *zap.Logger
+}
+
+// NewTableUsageStats 创建表使用统计，workspaces为nil时连接上其他用户的查询都计入工作空间
+func NewTableUsageStats(history repository.QueryHistoryRepository, workspaces repository.WorkspaceRepository, logger *zap.Logger) *TableUsageStats {
+	return &TableUsageStats{history: history, workspaces: workspaces, logger: logger}
+}
+
+// TableUsage 统计连接最近成功执行的查询引用各表的次数，同一条查询多次引用同一张表只计一次
+func (s *TableUsageStats) TableUsage(ctx context.Context, connectionID, userID int64) (*ai.TableUsage, error) {
+	queries, err := s.history.ListByConnection(ctx, connectionID, tableUsageHistoryLimit, 0)
+	if err != nil {
+		return nil, fmt.Errorf("读取历史查询失败: %w", err)
+	}
+	members, err := s.workspaceMembers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &ai.TableUsage{User: make(map[string]int), Workspace: make(map[string]int)}
+	for _, query := range queries {
+		if query.Status != string(repository.QuerySuccess) || query.GeneratedSQL == "" {
+			continue
+		}
+		counts := usage.Workspace
+		switch {
+		case userID > 0 && query.UserID == userID:
+			counts = usage.User
+		case members != nil && !members[query.UserID]:
+			continue
+		}
+		for _, table := range referencedTables(query.GeneratedSQL) {
+			counts[table]++
+		}
+	}
+	return usage, nil
+}
+
+// workspaceMembers 用户所属工作空间的成员，未设置工作空间或未指定用户时返回nil，表示不按成员过滤
+func (s *TableUsageStats) workspaceMembers(ctx context.Context, userID int64) (map[int64]bool, error) {
+	if s.workspaces == nil || userID <= 0 {
+		return nil, nil
+	}
+	workspace, err := s.workspaces.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取工作空间失败: %w", err)
+	}
+	ids, err := s.workspaces.ListMemberIDs(ctx, workspace.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询工作空间成员失败: %w", err)
+	}
+	members := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		members[id] = true
+	}
+	return members, nil
+}
+
+// tableListTerminators 结束FROM子句表列表的关键字
+var tableListTerminators = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "FETCH": true,
+	"UNION": true, "EXCEPT": true, "INTERSECT": true, "WINDOW": true, "SELECT": true,
+	"RETURNING": true, "SET": true, "VALUES": true, "FOR": true,
+}
+
+// referencedTables 返回SQL在FROM、JOIN、UPDATE、INTO后引用的表（含子查询），小写且去重，public模式省略模式名；
+// WITH定义的公用表表达式与FROM中的函数调用不计入，无法切分的SQL返回nil
+func referencedTables(sql string) []string {
+	tokens, err := sqlvalidator.Tokenize(sql)
+	if err != nil {
+		return nil
+	}
+
+	ctes := make(map[string]bool)
+	for i := 0; i+2 < len(tokens); i++ {
+		// name AS ( 或 name AS MATERIALIZED (
+		if isTableIdent(tokens[i]) && tokens[i+1].Keyword() == "AS" &&
+			(tokens[i+2].IsPunct("(") || tokens[i+2].Keyword() == "MATERIALIZED" || tokens[i+2].Keyword() == "NOT") {
+			ctes[identName(tokens[i])] = true
+		}
+	}
+
+	var tables []string
+	seen := make(map[string]bool)
+	// inList[depth]为true表示该括号层级处于FROM的表列表中，逗号之后是下一张表；
+	// query[depth]为false表示括号是函数调用，其中的FROM是EXTRACT(YEAR FROM ...)之类的语法
+	inList := map[int]bool{}
+	query := map[int]bool{0: true}
+	depth := 0
+	expect := false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token.IsPunct("("):
+			depth++
+			expect = false
+			query[depth] = i+1 < len(tokens) && (tokens[i+1].Keyword() == "SELECT" || tokens[i+1].Keyword() == "WITH")
+			continue
+		case token.IsPunct(")"):
+			inList[depth] = false
+			depth--
+			continue
+		case token.IsPunct(","):
+			expect = inList[depth]
+			continue
+		}
+
+		keyword := token.Keyword()
+		switch {
+		case keyword == "FROM" && query[depth]:
+			expect, inList[depth] = true, true
+			continue
+		case keyword == "JOIN":
+			expect = true
+			continue
+		case keyword == "UPDATE" || keyword == "INTO":
+			expect = true
+			continue
+		case keyword == "LATERAL" || keyword == "ONLY":
+			continue
+		case tableListTerminators[keyword]:
+			inList[depth], expect = false, false
+			continue
+		}
+		if !expect || !isTableIdent(token) {
+			expect = false
+			continue
+		}
+		expect = false
+
+		// schema.table，跳过函数调用
+		name := identName(token)
+		qualified := false
+		for i+2 < len(tokens) && tokens[i+1].IsPunct(".") && isTableIdent(tokens[i+2]) {
+			if qualified || name != "public" {
+				name += "." + identName(tokens[i+2])
+			} else {
+				name = identName(tokens[i+2])
+			}
+			qualified = true
+			i += 2
+		}
+		if i+1 < len(tokens) && tokens[i+1].IsPunct("(") {
+			continue
+		}
+		if !qualified && ctes[name] {
+			continue
+		}
+		if !seen[name] {
+			seen[name] = true
+			tables = append(tables, name)
+		}
+	}
+	return tables
+}
+
+func isTableIdent(token sqlvalidator.Token) bool {
+	return token.Kind == sqlvalidator.TokenWord || token.Kind == sqlvalidator.TokenQuotedIdent
+}
+
+// identName 标识符的小写形式，带引号的标识符取引号内的内容
+func identName(token sqlvalidator.Token) string {
+	return strings.ToLower(token.Value)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+)
+
+func TestReferencedTables(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{"单表", "SELECT * FROM orders WHERE id = 1", []string{"orders"}},
+		{"连接与逗号分隔的表", "SELECT * FROM Orders o JOIN order_items i ON i.order_id = o.id, users u WHERE u.id = o.user_id", []string{"orders", "order_items", "users"}},
+		{"模式名与引号", `SELECT * FROM sales."Orders" JOIN public.users ON true`, []string{"sales.orders", "users"}},
+		{"MySQL反引号", "SELECT * FROM `shop`.`orders` LIMIT 10", []string{"shop.orders"}},
+		{"子查询", "SELECT * FROM users WHERE id IN (SELECT user_id FROM orders)", []string{"users", "orders"}},
+		{"公用表表达式", "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent JOIN users ON true", []string{"orders", "users"}},
+		{"函数中的FROM与表函数", "SELECT EXTRACT(YEAR FROM created_at) FROM orders, generate_series(1, 3)", []string{"orders"}},
+		{"写语句", "UPDATE accounts SET status = 'x' WHERE id IN (SELECT account_id FROM audit_logs)", []string{"accounts", "audit_logs"}},
+		{"无法切分", "SELECT 'unterminated FROM orders", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, referencedTables(tt.sql))
+		})
+	}
+}
+
+func TestTableUsageStats_TableUsage(t *testing.T) {
+	history := &stubHistoryRepository{queries: []*repository.QueryHistory{
+		{UserID: 1, GeneratedSQL: "SELECT * FROM sales.orders o JOIN sales.orders p ON true", Status: string(repository.QuerySuccess)},
+		{UserID: 1, GeneratedSQL: "SELECT * FROM sales.orders", Status: string(repository.QuerySuccess)},
+		{UserID: 1, GeneratedSQL: "SELECT * FROM staging.orders", Status: string(repository.QueryError)},
+		{UserID: 2, GeneratedSQL: "SELECT * FROM customers", Status: string(repository.QuerySuccess)},
+		{UserID: 3, GeneratedSQL: "SELECT * FROM staging.orders", Status: string(repository.QuerySuccess)},
+	}}
+	workspaces := &onboardingWorkspaceRepository{stubWorkspaceRepository: stubWorkspaceRepository{workspace: &repository.Workspace{}}, members: []int64{1, 2}}
+
+	stats := NewTableUsageStats(history, workspaces, zaptest.NewLogger(t))
+	usage, err := stats.TableUsage(context.Background(), 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"sales.orders": 2}, usage.User, "同一条查询只计一次，失败的查询不计入")
+	assert.Equal(t, map[string]int{"customers": 1}, usage.Workspace, "其他工作空间的用户不计入")
+
+	// 未设置工作空间时连接上其他用户的查询都计入
+	usage, err = NewTableUsageStats(history, nil, zaptest.NewLogger(t)).TableUsage(context.Background(), 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"customers": 1, "staging.orders": 1}, usage.Workspace)
+}
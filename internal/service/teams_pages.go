@@ -0,0 +1,78 @@
+// Teams结果卡片分页
+// 分页所需的问题、SQL与连接保存在Redis中，卡片的分页按钮只携带不透明令牌，
+// 避免按卡片数据中客户端可改写的SQL重新执行查询；多实例共享，过期后需重新提问
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// teamsPageKeyPrefix 分页令牌在Redis中的键前缀
+const teamsPageKeyPrefix = "chat2sql:teams:page:"
+
+// ErrTeamsPageNotFound 分页令牌不存在或已过期
+var ErrTeamsPageNotFound = errors.New("teams page token not found")
+
+// TeamsPage 一张结果卡片分页所需的查询
+type TeamsPage struct {
+	Question       string    `json:"question"`
+	SQL            string    `json:"sql"`
+	ConnectionID   int64     `json:"connection_id"`
+	ConversationID string    `json:"conversation_id"` // 卡片所在的Teams会话，只有同一会话中的分页动作有效
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TeamsPageStore 基于Redis的分页令牌存储
+type TeamsPageStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+// NewTeamsPageStore 创建分页令牌存储，令牌在ttl后过期
+func NewTeamsPageStore(client redis.UniversalClient, ttl time.Duration) *TeamsPageStore {
+	return &TeamsPageStore{client: client, ttl: ttl}
+}
+
+// Save 保存查询并返回新的分页令牌
+func (s *TeamsPageStore) Save(ctx context.Context, page *TeamsPage) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成分页令牌失败: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		return "", err
+	}
+	if err := s.client.Set(ctx, teamsPageKeyPrefix+token, data, s.ttl).Err(); err != nil {
+		return "", fmt.Errorf("保存分页令牌失败: %w", err)
+	}
+	return token, nil
+}
+
+// Load 读取分页令牌对应的查询，不存在或已过期时返回ErrTeamsPageNotFound
+func (s *TeamsPageStore) Load(ctx context.Context, token string) (*TeamsPage, error) {
+	data, err := s.client.Get(ctx, teamsPageKeyPrefix+token).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTeamsPageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取分页令牌失败: %w", err)
+	}
+
+	var page TeamsPage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("解析分页令牌失败: %w", err)
+	}
+	return &page, nil
+}
@@ -0,0 +1,300 @@
+// 工作空间默认设置
+// 管理员为工作空间配置默认连接、默认语言与默认返回行数，请求未携带这些字段时由Apply按用户所属工作空间补全；
+// 解析结果按用户缓存CacheTTL，通过本服务更新设置时立即清空缓存，其他途径（如审批通过的策略变更）在缓存过期后生效
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+)
+
+// SupportedLocales 支持的请求语言
+var SupportedLocales = []string{"zh", "en"}
+
+// WorkspaceSettings 工作空间设置
+type WorkspaceSettings struct {
+	WorkspaceID       int64                            `json:"workspace_id"`
+	Defaults          *repository.WorkspaceDefaults    `json:"defaults"`
+	AutoExecutePolicy *repository.AutoExecutePolicy    `json:"auto_execute_policy"`
+	QuestionRetention string                           `json:"question_retention"`  // 自然语言问题保留方式：keep/hash/drop
+	LatencyRouting    *repository.LatencyRoutingPolicy `json:"latency_routing"`     // 按延迟SLO路由策略，为空表示不按延迟路由
+	Chargeback        *repository.ChargebackTaxonomy   `json:"chargeback_taxonomy"` // 费用归属标签取值范围，为空表示不接受标签
+}
+
+// RequestDefaults 请求中可由工作空间默认值补全的字段，零值表示请求未指定
+type RequestDefaults struct {
+	ConnectionID int64
+	Locale       string
+	RowLimit     int
+}
+
+// cachedWorkspaceSettings 按用户缓存的工作空间设置
+type cachedWorkspaceSettings struct {
+	settings  *WorkspaceSettings
+	expiresAt time.Time
+}
+
+// WorkspaceSettingsService 工作空间默认设置服务
+type WorkspaceSettingsService struct {
+	workspaceRepo  repository.WorkspaceRepository
+	connectionRepo repository.ConnectionRepository
+	config         *config.WorkspaceSettingsConfig
+	logger         *zap.Logger
+
+	mu    sync.RWMutex
+	cache map[int64]cachedWorkspaceSettings // 用户ID -> 所属工作空间设置
+	now   func() time.Time
+}
+
+// NewWorkspaceSettingsService 创建工作空间默认设置服务
+func NewWorkspaceSettingsService(workspaceRepo repository.WorkspaceRepository, connectionRepo repository.ConnectionRepository, settingsConfig *config.WorkspaceSettingsConfig, logger *zap.Logger) *WorkspaceSettingsService {
+	if settingsConfig == nil {
+		settingsConfig = config.DefaultWorkspaceSettingsConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &WorkspaceSettingsService{
+		workspaceRepo:  workspaceRepo,
+		connectionRepo: connectionRepo,
+		config:         settingsConfig,
+		logger:         logger,
+		cache:          make(map[int64]cachedWorkspaceSettings),
+		now:            time.Now,
+	}
+}
+
+// Get 读取用户所属工作空间的最新设置，不经过缓存
+func (s *WorkspaceSettingsService) Get(ctx context.Context, userID int64) (*WorkspaceSettings, error) {
+	workspace, err := s.workspaceRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkspaceSettings{
+		WorkspaceID:       workspace.ID,
+		Defaults:          workspace.Defaults,
+		AutoExecutePolicy: workspace.AutoExecutePolicy,
+		QuestionRetention: workspace.QuestionRetention,
+		LatencyRouting:    workspace.LatencyRouting,
+		Chargeback:        workspace.Chargeback,
+	}, nil
+}
+
+// Resolve 获取用户所属工作空间设置，优先使用缓存
+func (s *WorkspaceSettingsService) Resolve(ctx context.Context, userID int64) (*WorkspaceSettings, error) {
+	now := s.now()
+
+	s.mu.RLock()
+	cached, ok := s.cache[userID]
+	s.mu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.settings, nil
+	}
+
+	settings, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.CacheTTL > 0 {
+		s.mu.Lock()
+		s.cache[userID] = cachedWorkspaceSettings{settings: settings, expiresAt: now.Add(s.config.CacheTTL)}
+		s.mu.Unlock()
+	}
+	return settings, nil
+}
+
+// Apply 用用户所属工作空间的默认值补全请求未指定的字段，请求已指定的字段保持不变
+// 工作空间未配置默认连接时ConnectionID仍为0，由调用方决定如何处理
+func (s *WorkspaceSettingsService) Apply(ctx context.Context, userID int64, fields *RequestDefaults) error {
+	if fields.ConnectionID != 0 && fields.Locale != "" && fields.RowLimit != 0 {
+		return nil
+	}
+
+	settings, err := s.Resolve(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("获取工作空间默认设置失败: %w", err)
+	}
+	defaults := settings.Defaults
+	if defaults == nil {
+		return nil
+	}
+
+	if fields.ConnectionID == 0 && defaults.ConnectionID != nil {
+		fields.ConnectionID = *defaults.ConnectionID
+	}
+	if fields.Locale == "" {
+		fields.Locale = defaults.Locale
+	}
+	if fields.RowLimit == 0 {
+		fields.RowLimit = defaults.RowLimit
+	}
+	return nil
+}
+
+// Validate 校验默认设置：连接必须存在，语言必须受支持，行数不超过配置上限
+func (s *WorkspaceSettingsService) Validate(ctx context.Context, defaults *repository.WorkspaceDefaults) error {
+	if defaults == nil {
+		return nil
+	}
+
+	if defaults.Locale != "" && !slices.Contains(SupportedLocales, defaults.Locale) {
+		return fmt.Errorf("%w: 不支持的语言%q", repository.ErrInvalidInput, defaults.Locale)
+	}
+	if defaults.RowLimit < 0 || defaults.RowLimit > s.config.MaxRowLimit {
+		return fmt.Errorf("%w: 默认返回行数必须在1到%d之间", repository.ErrInvalidInput, s.config.MaxRowLimit)
+	}
+
+	if defaults.ConnectionID != nil {
+		if _, err := s.connectionRepo.GetByID(ctx, *defaults.ConnectionID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("%w: 数据库连接%d不存在", repository.ErrInvalidInput, *defaults.ConnectionID)
+			}
+			return fmt.Errorf("校验默认连接失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Update 校验并更新用户所属工作空间的默认设置，defaults为nil表示清除全部默认值
+func (s *WorkspaceSettingsService) Update(ctx context.Context, userID int64, defaults *repository.WorkspaceDefaults) (*WorkspaceSettings, error) {
+	if err := s.Validate(ctx, defaults); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.workspaceRepo.UpdateDefaults(ctx, settings.WorkspaceID, defaults, userID); err != nil {
+		return nil, err
+	}
+	s.Invalidate()
+
+	s.logger.Info("Workspace defaults updated",
+		zap.Int64("workspace_id", settings.WorkspaceID),
+		zap.Int64("user_id", userID))
+
+	settings.Defaults = defaults
+	return settings, nil
+}
+
+// UpdateQuestionRetention 更新用户所属工作空间的问题保留方式，只影响之后写入的查询历史
+func (s *WorkspaceSettingsService) UpdateQuestionRetention(ctx context.Context, userID int64, mode repository.QuestionRetention) (*WorkspaceSettings, error) {
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("%w: 不支持的问题保留方式%q", repository.ErrInvalidInput, mode)
+	}
+
+	settings, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.workspaceRepo.UpdateQuestionRetention(ctx, settings.WorkspaceID, mode, userID); err != nil {
+		return nil, err
+	}
+	s.Invalidate()
+
+	s.logger.Info("Workspace question retention updated",
+		zap.Int64("workspace_id", settings.WorkspaceID),
+		zap.String("mode", string(mode)),
+		zap.Int64("user_id", userID))
+
+	settings.QuestionRetention = string(mode)
+	return settings, nil
+}
+
+// 主模型延迟目标的取值范围
+const (
+	minLatencySLO = 100 * time.Millisecond
+	maxLatencySLO = time.Minute
+)
+
+// LatencySLO 返回工作空间策略中的主模型延迟目标，策略为空或未启用时返回0
+func (s *WorkspaceSettings) LatencySLO() time.Duration {
+	if s.LatencyRouting == nil || !s.LatencyRouting.Enabled {
+		return 0
+	}
+	return time.Duration(s.LatencyRouting.PrimarySLOMs) * time.Millisecond
+}
+
+// UpdateLatencyRouting 校验并更新用户所属工作空间的延迟路由策略，policy为nil表示不按延迟路由
+func (s *WorkspaceSettingsService) UpdateLatencyRouting(ctx context.Context, userID int64, policy *repository.LatencyRoutingPolicy) (*WorkspaceSettings, error) {
+	if policy != nil && policy.Enabled {
+		slo := time.Duration(policy.PrimarySLOMs) * time.Millisecond
+		if slo < minLatencySLO || slo > maxLatencySLO {
+			return nil, fmt.Errorf("%w: 主模型延迟目标必须在%d到%d毫秒之间", repository.ErrInvalidInput,
+				minLatencySLO.Milliseconds(), maxLatencySLO.Milliseconds())
+		}
+	}
+
+	settings, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.workspaceRepo.UpdateLatencyRouting(ctx, settings.WorkspaceID, policy, userID); err != nil {
+		return nil, err
+	}
+	s.Invalidate()
+
+	s.logger.Info("Workspace latency routing updated",
+		zap.Int64("workspace_id", settings.WorkspaceID),
+		zap.Bool("enabled", policy != nil && policy.Enabled),
+		zap.Int64("user_id", userID))
+
+	settings.LatencyRouting = policy
+	return settings, nil
+}
+
+// Invalidate 清空设置缓存
+// 成员与工作空间是多对一关系，按工作空间精确失效需要反查成员，设置变更不频繁，直接全部清空
+func (s *WorkspaceSettingsService) Invalidate() {
+	s.mu.Lock()
+	clear(s.cache)
+	s.mu.Unlock()
+}
+
+// rowLimitKey 单次请求返回行数上限的context键
+type rowLimitKey struct{}
+
+// WithRowLimit 为本次查询设置返回行数上限，执行器取其与全局MaxRows中较小的值
+func WithRowLimit(ctx context.Context, limit int) context.Context {
+	if limit <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, rowLimitKey{}, limit)
+}
+
+// rowLimitFromContext 读取本次查询的返回行数上限，未设置时返回0
+func rowLimitFromContext(ctx context.Context) int {
+	limit, _ := ctx.Value(rowLimitKey{}).(int)
+	return limit
+}
+
+// CanExecuteOn 判断用户能否在连接上执行查询
+// 连接所有者需要query:execute权限；viewer等只有query:execute_shared权限的角色只能使用工作空间的默认连接，
+// 且只能执行服务端生成的SQL（自动执行），/sql/execute提交的手写SQL另需query:execute权限；
+// hasRole为false（开发模式，请求上下文中没有角色）时不做角色限制
+func CanExecuteOn(userID int64, role string, hasRole bool, connection *repository.DatabaseConnection, defaults *repository.WorkspaceDefaults) bool {
+	userRole := repository.UserRole(role)
+	if connection.UserID == userID && (!hasRole || userRole.Can(repository.PermQueryExecute)) {
+		return true
+	}
+	if !hasRole || !userRole.Can(repository.PermQueryExecuteShared) {
+		return false
+	}
+	return defaults != nil && defaults.ConnectionID != nil && *defaults.ConnectionID == connection.ID
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// countingWorkspaceRepository 统计工作空间查询次数，用于验证缓存
+type countingWorkspaceRepository struct {
+	stubWorkspaceRepository
+	lookups int
+}
+
+func (r *countingWorkspaceRepository) GetByUserID(ctx context.Context, userID int64) (*repository.Workspace, error) {
+	r.lookups++
+	return r.workspace, nil
+}
+
+func (r *countingWorkspaceRepository) UpdateDefaults(ctx context.Context, workspaceID int64, defaults *repository.WorkspaceDefaults, updateBy int64) error {
+	r.workspace.Defaults = defaults
+	return nil
+}
+
+func TestWorkspaceSettingsService_ApplyDefaults(t *testing.T) {
+	ctx := context.Background()
+	workspace := &repository.Workspace{Name: "default"}
+	workspace.ID = repository.DefaultWorkspaceID
+	repo := &countingWorkspaceRepository{stubWorkspaceRepository: stubWorkspaceRepository{workspace: workspace}}
+	connections := &stubConnectionRepository{connection: testutil.NewConnection(3, 7)}
+
+	cfg := config.DefaultWorkspaceSettingsConfig()
+	s := NewWorkspaceSettingsService(repo, connections, cfg, zaptest.NewLogger(t))
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	// 未配置默认设置时不补全
+	fields := &RequestDefaults{}
+	require.NoError(t, s.Apply(ctx, 7, fields))
+	assert.Equal(t, RequestDefaults{}, *fields)
+
+	connectionID := int64(3)
+	_, err := s.Update(ctx, 7, &repository.WorkspaceDefaults{ConnectionID: &connectionID, Locale: "en", RowLimit: 200})
+	require.NoError(t, err)
+
+	// 更新后缓存立即失效，请求已指定的字段保持不变
+	fields = &RequestDefaults{RowLimit: 50}
+	require.NoError(t, s.Apply(ctx, 7, fields))
+	assert.Equal(t, RequestDefaults{ConnectionID: 3, Locale: "en", RowLimit: 50}, *fields)
+
+	// 缓存有效期内不再查询数据库，过期后重新加载
+	lookups := repo.lookups
+	require.NoError(t, s.Apply(ctx, 7, &RequestDefaults{}))
+	assert.Equal(t, lookups, repo.lookups)
+	now = now.Add(cfg.CacheTTL)
+	require.NoError(t, s.Apply(ctx, 7, &RequestDefaults{}))
+	assert.Equal(t, lookups+1, repo.lookups)
+}
+
+func TestWorkspaceSettingsService_Validate(t *testing.T) {
+	ctx := context.Background()
+	connections := &stubConnectionRepository{connection: testutil.NewConnection(3, 7)}
+	s := NewWorkspaceSettingsService(&stubWorkspaceRepository{}, connections, nil, zaptest.NewLogger(t))
+
+	missing := int64(4)
+	for name, defaults := range map[string]*repository.WorkspaceDefaults{
+		"unknown connection": {ConnectionID: &missing},
+		"unsupported locale": {Locale: "fr"},
+		"row limit too high": {RowLimit: 5000},
+	} {
+		assert.ErrorIs(t, s.Validate(ctx, defaults), repository.ErrInvalidInput, name)
+	}
+	assert.NoError(t, s.Validate(ctx, nil))
+}
+
+func TestWithRowLimit(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, 0, rowLimitFromContext(WithRowLimit(ctx, 0)))
+	assert.Equal(t, 200, rowLimitFromContext(WithRowLimit(ctx, 200)))
+}
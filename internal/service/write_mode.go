@@ -0,0 +1,359 @@
+// 受控写模式
+// 连接默认只读；显式开启写模式的连接可以提交INSERT/UPDATE，但必须先在回滚的事务中预演得到影响行数，
+// 再经审批流程（见approval.go）由申请人以外的审批人通过后才会执行
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/repository"
+)
+
+// 写模式相关错误
+var (
+	ErrWriteModeDisabled   = errors.New("连接未开启写模式")
+	ErrWriteImpactExceeded = errors.New("写操作实际影响行数超过预演结果")
+)
+
+// writePreviewSampleRows 预演结果中保留的样本行数
+const writePreviewSampleRows = 20
+
+// writeForbiddenPattern 写模式下仍然禁止的关键字
+var writeForbiddenPattern = regexp.MustCompile(`(?i)\b(DELETE|DROP|TRUNCATE|ALTER|CREATE|GRANT|REVOKE|COPY|VACUUM|EXECUTE|CALL|DO)\b`)
+
+// writeWherePattern UPDATE必须带WHERE条件
+var writeWherePattern = regexp.MustCompile(`(?i)\bWHERE\b`)
+
+// writeReturningPattern 已显式指定RETURNING的写操作
+var writeReturningPattern = regexp.MustCompile(`(?i)\bRETURNING\b`)
+
+// WriteExecutor 写模式所需的SQL执行能力
+type WriteExecutor interface {
+	PreviewWrite(ctx context.Context, sql string, connection *repository.DatabaseConnection, sampleRows int) (*repository.WritePreview, int64, error)
+	ExecuteWrite(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64) (int64, error)
+}
+
+// WriteProposal 写操作提交参数，SQL为空时按NaturalQuery生成
+type WriteProposal struct {
+	UserID       int64
+	ConnectionID int64
+	NaturalQuery string
+	Schema       string
+	SQL          string
+}
+
+// writeQueryPayload 写操作审批参数，供审批人核对
+type writeQueryPayload struct {
+	ConnectionID  int64  `json:"connection_id"`
+	SQL           string `json:"sql"`
+	EstimatedRows int64  `json:"estimated_rows"`
+}
+
+// WriteModeService 受控写模式服务
+type WriteModeService struct {
+	connectionRepo repository.ConnectionRepository
+	writeRepo      repository.WriteRequestRepository
+	approvals      *ApprovalEngine
+	executor       WriteExecutor
+	generator      SQLGenerator
+	validator      *SQLSecurityValidator
+	logger         *zap.Logger
+}
+
+// NewWriteModeService 创建受控写模式服务并注册为写操作的审批执行器，generator为空时只接受显式提交的SQL
+func NewWriteModeService(
+	connectionRepo repository.ConnectionRepository,
+	writeRepo repository.WriteRequestRepository,
+	approvals *ApprovalEngine,
+	executor WriteExecutor,
+	generator SQLGenerator,
+	logger *zap.Logger,
+) *WriteModeService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	s := &WriteModeService{
+		connectionRepo: connectionRepo,
+		writeRepo:      writeRepo,
+		approvals:      approvals,
+		executor:       executor,
+		generator:      generator,
+		validator:      NewSQLSecurityValidator(logger),
+		logger:         logger,
+	}
+	approvals.Register(repository.ApprovalWriteQuery, s)
+	return s
+}
+
+// SetWriteMode 开启或关闭连接的写模式，只有连接所有者可以操作
+func (s *WriteModeService) SetWriteMode(ctx context.Context, userID, connectionID int64, enabled bool) error {
+	connection, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil || connection.UserID != userID {
+		return fmt.Errorf("无权访问数据库连接%d: %w", connectionID, repository.ErrPermissionDenied)
+	}
+
+	if err := s.connectionRepo.UpdateWriteMode(ctx, connectionID, enabled, userID); err != nil {
+		return err
+	}
+
+	s.logger.Info("连接写模式已变更",
+		zap.Int64("connection_id", connectionID),
+		zap.Int64("user_id", userID),
+		zap.Bool("enabled", enabled))
+	return nil
+}
+
+// List 按状态分页列出写操作申请，审批人可以看到全部申请，其他用户只能看到自己提交的申请
+func (s *WriteModeService) List(ctx context.Context, userID int64, role string, status repository.WriteRequestStatus, limit, offset int) ([]*repository.WriteRequest, error) {
+	reviewer, err := s.canReview(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	if reviewer {
+		return s.writeRepo.ListByStatus(ctx, status, limit, offset)
+	}
+	return s.writeRepo.ListByRequester(ctx, userID, status, limit, offset)
+}
+
+// Get 获取写操作申请及其审批审计事件，非审批人获取他人的申请时返回ErrNotFound
+func (s *WriteModeService) Get(ctx context.Context, userID int64, role string, requestID int64) (*repository.WriteRequest, []*repository.ApprovalEvent, error) {
+	req, err := s.writeRepo.GetByID(ctx, requestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req.RequestedBy != userID {
+		reviewer, err := s.canReview(ctx, userID, role)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !reviewer {
+			return nil, nil, fmt.Errorf("写操作申请%d: %w", requestID, repository.ErrNotFound)
+		}
+	}
+	if req.ApprovalID == nil {
+		return req, nil, nil
+	}
+
+	events, err := s.approvals.ListEvents(ctx, *req.ApprovalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, events, nil
+}
+
+// canReview 判断用户能否查看全部写操作申请：拥有写操作审批权限的角色或写操作的指定审批人
+func (s *WriteModeService) canReview(ctx context.Context, userID int64, role string) (bool, error) {
+	if repository.UserRole(role).Can(repository.PermWriteApprove) {
+		return true, nil
+	}
+	approvers, err := s.approvals.ListApprovers(ctx, repository.ApprovalWriteQuery)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(approvers, userID), nil
+}
+
+// Propose 校验并预演写操作，创建写操作申请并提交审批
+func (s *WriteModeService) Propose(ctx context.Context, proposal *WriteProposal) (*repository.WriteRequest, error) {
+	connection, err := s.connectionRepo.GetByID(ctx, proposal.ConnectionID)
+	if err != nil || connection.UserID != proposal.UserID {
+		return nil, fmt.Errorf("无权访问数据库连接%d: %w", proposal.ConnectionID, repository.ErrPermissionDenied)
+	}
+	if !connection.WriteModeEnabled {
+		return nil, ErrWriteModeDisabled
+	}
+
+	sql := proposal.SQL
+	if sql == "" {
+		if sql, err = s.generate(ctx, proposal); err != nil {
+			return nil, err
+		}
+	}
+
+	sql, statementType, err := s.validateWriteSQL(sql)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrInvalidInput, err)
+	}
+
+	preview, estimatedRows, err := s.executor.PreviewWrite(ctx, sql, connection, writePreviewSampleRows)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrInvalidInput, err)
+	}
+
+	req := &repository.WriteRequest{
+		ConnectionID:  connection.ID,
+		RequestedBy:   proposal.UserID,
+		SQL:           sql,
+		StatementType: statementType,
+		EstimatedRows: estimatedRows,
+		Preview:       preview,
+		Status:        string(repository.WriteRequestPending),
+	}
+	if proposal.NaturalQuery != "" {
+		req.NaturalQuery = &proposal.NaturalQuery
+	}
+
+	if err := s.writeRepo.Create(ctx, req); err != nil {
+		return nil, err
+	}
+
+	approval, err := s.approvals.Submit(ctx, &ApprovalSubmission{
+		ActionType:  repository.ApprovalWriteQuery,
+		ResourceID:  &req.ID,
+		Summary:     fmt.Sprintf("连接%s上的%s，预演影响%d行", connection.Name, statementType, estimatedRows),
+		Payload:     writeQueryPayload{ConnectionID: connection.ID, SQL: sql, EstimatedRows: estimatedRows},
+		RequestedBy: proposal.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.writeRepo.SetApprovalID(ctx, req.ID, approval.ID); err != nil {
+		return nil, err
+	}
+	req.ApprovalID = &approval.ID
+
+	s.logger.Info("写操作申请已创建",
+		zap.Int64("request_id", req.ID),
+		zap.Int64("approval_id", approval.ID),
+		zap.Int64("connection_id", connection.ID),
+		zap.Int64("requested_by", proposal.UserID),
+		zap.Int64("estimated_rows", estimatedRows))
+	return req, nil
+}
+
+// Execute 审批通过后执行写操作，实现ApprovalExecutor
+// 执行时影响行数不得超过预演结果，连接在审批期间关闭写模式时不执行
+func (s *WriteModeService) Execute(ctx context.Context, approval *repository.ApprovalRequest, approverID int64) (string, error) {
+	req, err := s.writeRequest(ctx, approval)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.writeRepo.Review(ctx, req.ID, &approverID, repository.WriteRequestApproved, approval.DecisionComment); err != nil {
+		return "", err
+	}
+
+	affected, execErr := s.execute(ctx, req)
+	if execErr != nil {
+		message := execErr.Error()
+		if err := s.writeRepo.RecordExecution(ctx, req.ID, repository.WriteRequestFailed, nil, &message); err != nil {
+			s.logger.Error("记录写操作执行结果失败", zap.Int64("request_id", req.ID), zap.Error(err))
+		}
+		return "", execErr
+	}
+
+	if err := s.writeRepo.RecordExecution(ctx, req.ID, repository.WriteRequestExecuted, &affected, nil); err != nil {
+		return "", err
+	}
+
+	s.logger.Info("写操作已执行",
+		zap.Int64("request_id", req.ID),
+		zap.Int64("approved_by", approverID),
+		zap.Int64("affected_rows", affected))
+	return fmt.Sprintf("影响%d行", affected), nil
+}
+
+// Cancel 审批被驳回或过期时同步写操作申请状态，实现ApprovalExecutor
+func (s *WriteModeService) Cancel(ctx context.Context, approval *repository.ApprovalRequest, status repository.ApprovalStatus) error {
+	req, err := s.writeRequest(ctx, approval)
+	if err != nil {
+		return err
+	}
+
+	writeStatus := repository.WriteRequestRejected
+	if status == repository.ApprovalExpired {
+		writeStatus = repository.WriteRequestExpired
+	}
+	return s.writeRepo.Review(ctx, req.ID, approval.DecidedBy, writeStatus, approval.DecisionComment)
+}
+
+// execute 在连接上执行已审批的写操作
+func (s *WriteModeService) execute(ctx context.Context, req *repository.WriteRequest) (int64, error) {
+	connection, err := s.connectionRepo.GetByID(ctx, req.ConnectionID)
+	if err != nil {
+		return 0, err
+	}
+	if !connection.WriteModeEnabled {
+		return 0, ErrWriteModeDisabled
+	}
+	return s.executor.ExecuteWrite(ctx, req.SQL, connection, req.EstimatedRows)
+}
+
+// writeRequest 获取审批申请关联的写操作申请
+func (s *WriteModeService) writeRequest(ctx context.Context, approval *repository.ApprovalRequest) (*repository.WriteRequest, error) {
+	if approval.ResourceID == nil {
+		return nil, fmt.Errorf("审批申请%d未关联写操作: %w", approval.ID, repository.ErrNotFound)
+	}
+	return s.writeRepo.GetByID(ctx, *approval.ResourceID)
+}
+
+// generate 在写模式下按自然语言生成SQL
+func (s *WriteModeService) generate(ctx context.Context, proposal *WriteProposal) (string, error) {
+	if s.generator == nil || proposal.NaturalQuery == "" {
+		return "", fmt.Errorf("%w: 缺少SQL或自然语言描述", repository.ErrInvalidInput)
+	}
+
+	resp, err := s.generator.GenerateSQL(ctx, &SQLGenerationRequest{
+		Query:        proposal.NaturalQuery,
+		ConnectionID: proposal.ConnectionID,
+		UserID:       proposal.UserID,
+		Schema:       proposal.Schema,
+		AllowWrite:   true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("生成写操作SQL失败: %w", err)
+	}
+	return resp.SQL, nil
+}
+
+// validateWriteSQL 只允许单条INSERT或带WHERE的UPDATE，返回规范化后的SQL与语句类型
+func (s *WriteModeService) validateWriteSQL(sql string) (string, string, error) {
+	sql = normalizeCandidateSQL(sql)
+	cleaned := s.validator.cleanSQL(sql)
+	if cleaned == "" {
+		return "", "", errors.New("SQL为空")
+	}
+	if err := s.validator.checkStatementCount(cleaned); err != nil {
+		return "", "", err
+	}
+
+	statementType := strings.ToUpper(strings.Fields(cleaned)[0])
+	switch statementType {
+	case "INSERT":
+	case "UPDATE":
+		if !writeWherePattern.MatchString(cleaned) {
+			return "", "", errors.New("UPDATE必须带WHERE条件")
+		}
+	default:
+		return "", "", fmt.Errorf("写模式只允许INSERT或UPDATE，收到%s", statementType)
+	}
+
+	if match := writeForbiddenPattern.FindString(cleaned); match != "" {
+		return "", "", fmt.Errorf("写模式禁止使用%s", strings.ToUpper(match))
+	}
+	return sql, statementType, nil
+}
+
+// withReturning 未带RETURNING的写操作追加RETURNING *，用于预演时取得受影响的行
+func withReturning(sql string) string {
+	if writeReturningPattern.MatchString(sql) {
+		return sql
+	}
+	return sql + " RETURNING *"
+}
+
+// optionalString 空字符串转为nil
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
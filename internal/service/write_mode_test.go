@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/repository"
+	"chat2sql-go/internal/testutil"
+)
+
+// memWriteRequestRepository 内存写操作申请Repository
+type memWriteRequestRepository struct {
+	repository.WriteRequestRepository
+	requests map[int64]*repository.WriteRequest
+}
+
+func newMemWriteRequestRepository() *memWriteRequestRepository {
+	return &memWriteRequestRepository{requests: make(map[int64]*repository.WriteRequest)}
+}
+
+func (m *memWriteRequestRepository) Create(ctx context.Context, req *repository.WriteRequest) error {
+	req.ID = int64(len(m.requests) + 1)
+	m.requests[req.ID] = req
+	return nil
+}
+
+func (m *memWriteRequestRepository) GetByID(ctx context.Context, id int64) (*repository.WriteRequest, error) {
+	req, ok := m.requests[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return req, nil
+}
+
+func (m *memWriteRequestRepository) ListByStatus(ctx context.Context, status repository.WriteRequestStatus, limit, offset int) ([]*repository.WriteRequest, error) {
+	return m.ListByRequester(ctx, 0, status, limit, offset)
+}
+
+// ListByRequester requestedBy为0时不按申请人过滤，按ID排序
+func (m *memWriteRequestRepository) ListByRequester(ctx context.Context, requestedBy int64, status repository.WriteRequestStatus, limit, offset int) ([]*repository.WriteRequest, error) {
+	var result []*repository.WriteRequest
+	for id := int64(1); id <= int64(len(m.requests)); id++ {
+		req := m.requests[id]
+		if req.Status == string(status) && (requestedBy == 0 || req.RequestedBy == requestedBy) {
+			result = append(result, req)
+		}
+	}
+	return result, nil
+}
+
+func (m *memWriteRequestRepository) SetApprovalID(ctx context.Context, id, approvalID int64) error {
+	m.requests[id].ApprovalID = &approvalID
+	return nil
+}
+
+func (m *memWriteRequestRepository) Review(ctx context.Context, id int64, reviewerID *int64, status repository.WriteRequestStatus, comment *string) error {
+	req, ok := m.requests[id]
+	if !ok || req.Status != string(repository.WriteRequestPending) {
+		return repository.ErrNotFound
+	}
+	req.Status = string(status)
+	req.ReviewedBy = reviewerID
+	req.ReviewComment = comment
+	return nil
+}
+
+func (m *memWriteRequestRepository) RecordExecution(ctx context.Context, id int64, status repository.WriteRequestStatus, affectedRows *int64, errorMessage *string) error {
+	req := m.requests[id]
+	req.Status = string(status)
+	req.AffectedRows = affectedRows
+	req.ErrorMessage = errorMessage
+	return nil
+}
+
+// stubWriteExecutor 固定预演与执行结果
+type stubWriteExecutor struct {
+	previewRows  int64
+	affectedRows int64
+	previewed    []string
+	executed     []string
+}
+
+func (s *stubWriteExecutor) PreviewWrite(ctx context.Context, sql string, connection *repository.DatabaseConnection, sampleRows int) (*repository.WritePreview, int64, error) {
+	s.previewed = append(s.previewed, sql)
+	return &repository.WritePreview{Columns: []string{"id"}, Rows: []map[string]any{{"id": 1}}}, s.previewRows, nil
+}
+
+func (s *stubWriteExecutor) ExecuteWrite(ctx context.Context, sql string, connection *repository.DatabaseConnection, maxRows int64) (int64, error) {
+	s.executed = append(s.executed, sql)
+	if s.affectedRows > maxRows {
+		return s.affectedRows, ErrWriteImpactExceeded
+	}
+	return s.affectedRows, nil
+}
+
+func newWriteModeTestService(t *testing.T, writeEnabled bool, executor *stubWriteExecutor) (*WriteModeService, *ApprovalEngine, *memApprovalRepository) {
+	connection := testutil.NewConnection(3, 7)
+	connection.WriteModeEnabled = writeEnabled
+	engine, approvals := newApprovalTestEngine(t)
+	svc := NewWriteModeService(&stubConnectionRepository{connection: connection}, newMemWriteRequestRepository(), engine, executor, nil, zaptest.NewLogger(t))
+	return svc, engine, approvals
+}
+
+func TestWriteModeService_ValidateWriteSQL(t *testing.T) {
+	svc, _, _ := newWriteModeTestService(t, true, &stubWriteExecutor{})
+
+	tests := []struct {
+		sql       string
+		statement string
+		valid     bool
+	}{
+		{"INSERT INTO tags (name) VALUES ('sandbox');", "INSERT", true},
+		{"update orders set status = 'shipped' where id = 1", "UPDATE", true},
+		{"UPDATE orders SET status = 'shipped'", "", false},
+		{"DELETE FROM orders WHERE id = 1", "", false},
+		{"SELECT * FROM orders", "", false},
+		{"INSERT INTO a SELECT * FROM b; DROP TABLE b", "", false},
+		{"UPDATE orders SET status = 'x' WHERE id IN (SELECT id FROM t); TRUNCATE t", "", false},
+	}
+
+	for _, tt := range tests {
+		sql, statement, err := svc.validateWriteSQL(tt.sql)
+		if !tt.valid {
+			assert.Error(t, err, tt.sql)
+			continue
+		}
+		require.NoError(t, err, tt.sql)
+		assert.Equal(t, tt.statement, statement)
+		assert.NotContains(t, sql, ";")
+	}
+}
+
+func TestWriteModeService_ProposeAndApprove(t *testing.T) {
+	ctx := context.Background()
+	const sql = "UPDATE orders SET status = 'shipped' WHERE id = 1001"
+
+	t.Run("预演后由另一位用户审批执行", func(t *testing.T) {
+		executor := &stubWriteExecutor{previewRows: 1, affectedRows: 1}
+		svc, engine, approvals := newWriteModeTestService(t, true, executor)
+
+		req, err := svc.Propose(ctx, &WriteProposal{UserID: 7, ConnectionID: 3, SQL: sql})
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.WriteRequestPending), req.Status)
+		assert.Equal(t, int64(1), req.EstimatedRows)
+		assert.Equal(t, "UPDATE", req.StatementType)
+		require.NotNil(t, req.ApprovalID)
+		assert.Empty(t, executor.executed, "提交申请时不应执行")
+
+		_, err = engine.Approve(ctx, 7, "manager", *req.ApprovalID, "")
+		assert.ErrorIs(t, err, ErrSelfApproval)
+
+		approval, err := engine.Approve(ctx, 9, "manager", *req.ApprovalID, "已核对")
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.ApprovalExecuted), approval.Status)
+
+		executed, events, err := svc.Get(ctx, 7, "user", req.ID)
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.WriteRequestExecuted), executed.Status)
+		assert.Equal(t, int64(1), *executed.AffectedRows)
+		assert.Equal(t, int64(9), *executed.ReviewedBy)
+		assert.Len(t, events, 3)
+		assert.Equal(t, []string{sql}, executor.executed)
+		assert.Equal(t, []string{"created", "approved", "executed"}, approvals.actions())
+	})
+
+	t.Run("实际影响行数超过预演时标记失败", func(t *testing.T) {
+		executor := &stubWriteExecutor{previewRows: 1, affectedRows: 50}
+		svc, engine, _ := newWriteModeTestService(t, true, executor)
+
+		req, err := svc.Propose(ctx, &WriteProposal{UserID: 7, ConnectionID: 3, SQL: sql})
+		require.NoError(t, err)
+
+		approval, err := engine.Approve(ctx, 9, "manager", *req.ApprovalID, "")
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.ApprovalFailed), approval.Status)
+		assert.Equal(t, string(repository.WriteRequestFailed), req.Status)
+		require.NotNil(t, req.ErrorMessage)
+		assert.Contains(t, *req.ErrorMessage, ErrWriteImpactExceeded.Error())
+	})
+
+	t.Run("驳回或过期后不执行", func(t *testing.T) {
+		executor := &stubWriteExecutor{previewRows: 1, affectedRows: 1}
+		svc, engine, _ := newWriteModeTestService(t, true, executor)
+
+		rejected, err := svc.Propose(ctx, &WriteProposal{UserID: 7, ConnectionID: 3, SQL: sql})
+		require.NoError(t, err)
+		_, err = engine.Reject(ctx, 9, "manager", *rejected.ApprovalID, "影响范围过大")
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.WriteRequestRejected), rejected.Status)
+
+		expired, err := svc.Propose(ctx, &WriteProposal{UserID: 7, ConnectionID: 3, SQL: sql})
+		require.NoError(t, err)
+		engine.now = func() time.Time { return time.Now().Add(73 * time.Hour) }
+		_, err = engine.ExpirePending(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, string(repository.WriteRequestExpired), expired.Status)
+		assert.Nil(t, expired.ReviewedBy)
+
+		assert.Empty(t, executor.executed)
+	})
+
+	t.Run("默认只读的连接拒绝写操作", func(t *testing.T) {
+		executor := &stubWriteExecutor{}
+		svc, _, _ := newWriteModeTestService(t, false, executor)
+
+		_, err := svc.Propose(ctx, &WriteProposal{UserID: 7, ConnectionID: 3, SQL: sql})
+		assert.ErrorIs(t, err, ErrWriteModeDisabled)
+		assert.Empty(t, executor.previewed)
+	})
+
+	t.Run("他人连接拒绝", func(t *testing.T) {
+		svc, _, _ := newWriteModeTestService(t, true, &stubWriteExecutor{})
+
+		_, err := svc.Propose(ctx, &WriteProposal{UserID: 8, ConnectionID: 3, SQL: sql})
+		assert.ErrorIs(t, err, repository.ErrPermissionDenied)
+	})
+
+	t.Run("非法SQL不预演", func(t *testing.T) {
+		executor := &stubWriteExecutor{}
+		svc, _, _ := newWriteModeTestService(t, true, executor)
+
+		_, err := svc.Propose(ctx, &WriteProposal{UserID: 7, ConnectionID: 3, SQL: "DELETE FROM orders WHERE id = 1"})
+		assert.ErrorIs(t, err, repository.ErrInvalidInput)
+		assert.Empty(t, executor.previewed)
+	})
+}
+
+func TestWriteModeService_ListAndGetVisibility(t *testing.T) {
+	ctx := context.Background()
+	svc, engine, _ := newWriteModeTestService(t, true, &stubWriteExecutor{previewRows: 1})
+	req, err := svc.Propose(ctx, &WriteProposal{UserID: 7, ConnectionID: 3, SQL: "INSERT INTO tags (name) VALUES ('a')"})
+	require.NoError(t, err)
+
+	// 申请人与审批人可以查看，其他用户既看不到列表也取不到详情
+	for _, tt := range []struct {
+		userID  int64
+		role    string
+		visible bool
+	}{
+		{7, "user", true},
+		{9, "manager", true},
+		{1, "admin", true},
+		{8, "user", false},
+		{8, "viewer", false},
+	} {
+		list, err := svc.List(ctx, tt.userID, tt.role, repository.WriteRequestPending, 20, 0)
+		require.NoError(t, err)
+		_, _, getErr := svc.Get(ctx, tt.userID, tt.role, req.ID)
+		if tt.visible {
+			assert.Len(t, list, 1, "用户%d", tt.userID)
+			assert.NoError(t, getErr)
+		} else {
+			assert.Empty(t, list, "用户%d", tt.userID)
+			assert.ErrorIs(t, getErr, repository.ErrNotFound)
+		}
+	}
+
+	// 写操作的指定审批人不需要manager角色
+	require.NoError(t, engine.SetApprovers(ctx, repository.ApprovalWriteQuery, []int64{8}))
+	list, err := svc.List(ctx, 8, "user", repository.WriteRequestPending, 20, 0)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestWithReturning(t *testing.T) {
+	assert.Equal(t, "INSERT INTO t (a) VALUES (1) RETURNING *", withReturning("INSERT INTO t (a) VALUES (1)"))
+	assert.Equal(t, "UPDATE t SET a = 1 WHERE id = 2 returning id", withReturning("UPDATE t SET a = 1 WHERE id = 2 returning id"))
+}
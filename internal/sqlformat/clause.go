@@ -0,0 +1,136 @@
+package sqlformat
+
+import "chat2sql-go/internal/sqlvalidator"
+
+// clauseKind 子句主体的排版方式
+type clauseKind int
+
+const (
+	clausePlain      clauseKind = iota // 主体与关键字同行
+	clauseSelect                       // 多列时每列一行
+	clauseWith                         // 每个公用表表达式一行
+	clauseConditions                   // 按顶层AND/OR换行
+)
+
+// clause 以关键字开头的子句，语句开头不属于任何子句的部分keyword为空
+type clause struct {
+	keyword []node
+	body    []node
+	kind    clauseKind
+}
+
+// clauseStart 另起一行的子句关键字，同一首词的多词形式写在前面以优先匹配
+var clauseStarts = []struct {
+	words []string
+	kind  clauseKind
+}{
+	{[]string{"WITH", "RECURSIVE"}, clauseWith},
+	{[]string{"WITH"}, clauseWith},
+	{[]string{"SELECT"}, clauseSelect},
+	{[]string{"FROM"}, clausePlain},
+	{[]string{"WHERE"}, clauseConditions},
+	{[]string{"GROUP", "BY"}, clausePlain},
+	{[]string{"HAVING"}, clauseConditions},
+	{[]string{"WINDOW"}, clausePlain},
+	{[]string{"ORDER", "BY"}, clausePlain},
+	{[]string{"LIMIT"}, clausePlain},
+	{[]string{"OFFSET"}, clausePlain},
+	{[]string{"FETCH"}, clausePlain},
+	{[]string{"FOR", "NO", "KEY", "UPDATE"}, clausePlain},
+	{[]string{"FOR", "KEY", "SHARE"}, clausePlain},
+	{[]string{"FOR", "UPDATE"}, clausePlain},
+	{[]string{"FOR", "SHARE"}, clausePlain},
+	{[]string{"UNION", "ALL"}, clausePlain},
+	{[]string{"UNION"}, clausePlain},
+	{[]string{"INTERSECT", "ALL"}, clausePlain},
+	{[]string{"INTERSECT"}, clausePlain},
+	{[]string{"EXCEPT", "ALL"}, clausePlain},
+	{[]string{"EXCEPT"}, clausePlain},
+	{[]string{"LEFT", "OUTER", "JOIN"}, clauseConditions},
+	{[]string{"LEFT", "JOIN"}, clauseConditions},
+	{[]string{"RIGHT", "OUTER", "JOIN"}, clauseConditions},
+	{[]string{"RIGHT", "JOIN"}, clauseConditions},
+	{[]string{"FULL", "OUTER", "JOIN"}, clauseConditions},
+	{[]string{"FULL", "JOIN"}, clauseConditions},
+	{[]string{"INNER", "JOIN"}, clauseConditions},
+	{[]string{"CROSS", "JOIN"}, clauseConditions},
+	{[]string{"NATURAL", "JOIN"}, clauseConditions},
+	{[]string{"JOIN"}, clauseConditions},
+	{[]string{"INSERT", "INTO"}, clausePlain},
+	{[]string{"VALUES"}, clausePlain},
+	{[]string{"ON", "CONFLICT"}, clausePlain},
+	{[]string{"UPDATE"}, clausePlain},
+	{[]string{"SET"}, clausePlain},
+	{[]string{"DELETE", "FROM"}, clausePlain},
+	{[]string{"RETURNING"}, clausePlain},
+}
+
+// splitClauses 在顶层按子句关键字切分语句
+func splitClauses(nodes []node) []clause {
+	var clauses []clause
+	current := clause{}
+	for i := 0; i < len(nodes); {
+		words, kind := matchClause(nodes, i)
+		if words == 0 {
+			current.body = append(current.body, nodes[i])
+			i++
+			continue
+		}
+		if len(current.keyword) > 0 || len(current.body) > 0 {
+			clauses = append(clauses, current)
+		}
+		current = clause{keyword: nodes[i : i+words], kind: kind}
+		i += words
+	}
+	return append(clauses, current)
+}
+
+// matchClause 返回从nodes[i]开始的子句关键字的词数，不是子句开头时返回0
+func matchClause(nodes []node, i int) (int, clauseKind) {
+	if !isWord(nodes, i) || (i > 0 && isPunct(nodes, i-1, ".")) || isPunct(nodes, i+1, ".") {
+		return 0, clausePlain
+	}
+	first := nodes[i].token.Keyword()
+	switch {
+	case first == "WITH" && i > 0:
+		// WITH只在语句开头引出公用表表达式，其余位置如WITH TIME ZONE属于表达式
+		for _, n := range nodes[:i] {
+			if n.token.Kind != sqlvalidator.TokenComment {
+				return 0, clausePlain
+			}
+		}
+	case first == "FROM" && i > 0 && isWord(nodes, i-1) && nodes[i-1].token.Keyword() == "DISTINCT":
+		// IS [NOT] DISTINCT FROM
+		return 0, clausePlain
+	case first == "SET" && i > 0 && isWord(nodes, i-1) && nodes[i-1].token.Keyword() == "UPDATE" && i > 1 && isWord(nodes, i-2) && nodes[i-2].token.Keyword() == "DO":
+		// ON CONFLICT DO UPDATE SET与DO UPDATE同行
+		return 0, clausePlain
+	case first == "UPDATE" && i > 0 && isWord(nodes, i-1) && nodes[i-1].token.Keyword() == "DO":
+		return 0, clausePlain
+	}
+
+	for _, start := range clauseStarts {
+		if start.words[0] != first {
+			continue
+		}
+		matched := true
+		for k, word := range start.words[1:] {
+			if !isWord(nodes, i+1+k) || nodes[i+1+k].token.Keyword() != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return len(start.words), start.kind
+		}
+	}
+	return 0, clausePlain
+}
+
+func isWord(nodes []node, i int) bool {
+	return i < len(nodes) && !nodes[i].isGrp && nodes[i].token.Kind == sqlvalidator.TokenWord
+}
+
+func isPunct(nodes []node, i int, text string) bool {
+	return i < len(nodes) && !nodes[i].isGrp && nodes[i].token.IsPunct(text)
+}
@@ -0,0 +1,296 @@
+// Package sqlformat 基于语法树的SQL格式化
+// 按sqlvalidator的词法规则切分记号，括号组成嵌套的树，语句按子句切分后以统一规则输出：
+// 关键字大写，每个子句另起一行，多列SELECT每列一行，WHERE/HAVING/JOIN条件按顶层AND/OR换行，子查询缩进。
+// 格式化只改变空白与关键字大小写，输出重新切分后的记号与原SQL逐一比对，不一致时返回原SQL
+package sqlformat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+// indentUnit 每级缩进
+const indentUnit = "  "
+
+// ErrUnsupported SQL含有格式化无法保证不改变语义的内容，或格式化结果与原SQL的记号不一致
+var ErrUnsupported = errors.New("SQL无法格式化")
+
+// Format 格式化SQL
+// 无法切分（如引号未闭合）时返回包装sqlvalidator.ErrSyntax的错误，无法安全格式化时返回ErrUnsupported，两种情况都同时返回原SQL
+func Format(sql string) (string, error) {
+	tokens, err := sqlvalidator.TokenizeWithComments(sql)
+	if err != nil {
+		return sql, err
+	}
+	if len(tokens) == 0 {
+		return strings.TrimSpace(sql), nil
+	}
+	if hashComment(tokens) {
+		return sql, fmt.Errorf("%w: 含有#注释", ErrUnsupported)
+	}
+
+	f := &formatter{}
+	f.statements(parse(tokens))
+	formatted := f.p.String()
+
+	if !sameTokens(tokens, formatted) {
+		return sql, fmt.Errorf("%w: 格式化结果与原SQL不一致", ErrUnsupported)
+	}
+	return formatted, nil
+}
+
+// FormatOrOriginal 格式化SQL，无法格式化时原样返回
+func FormatOrOriginal(sql string) string {
+	formatted, err := Format(sql)
+	if err != nil {
+		return sql
+	}
+	return formatted
+}
+
+// hashComment MySQL的#注释在PostgreSQL词法中是运算符，换行后注释范围会改变；#>、#>>与#-是PostgreSQL的JSON运算符
+func hashComment(tokens []sqlvalidator.Token) bool {
+	for i, token := range tokens {
+		if token.Kind != sqlvalidator.TokenOperator || token.Text != "#" {
+			continue
+		}
+		if i+1 < len(tokens) && adjacent(token, tokens[i+1]) && (tokens[i+1].Text == ">" || tokens[i+1].Text == "-") {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// sameTokens 格式化结果与原SQL的记号类型与规范化的值逐一相同
+func sameTokens(original []sqlvalidator.Token, formatted string) bool {
+	tokens, err := sqlvalidator.TokenizeWithComments(formatted)
+	if err != nil || len(tokens) != len(original) {
+		return false
+	}
+	for i := range tokens {
+		if tokens[i].Kind != original[i].Kind || tokens[i].Value != original[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// node 语法树节点：叶子为单个记号，括号组包含组内的节点
+type node struct {
+	token sqlvalidator.Token  // 叶子记号；括号组为左括号
+	text  string              // 输出的文本，关键字为大写
+	group []node              // 括号组内的节点
+	close *sqlvalidator.Token // 括号组的右括号，未闭合时为nil
+	isGrp bool
+}
+
+// parse 将记号按括号组成树，多余的右括号作为普通记号
+func parse(tokens []sqlvalidator.Token) []node {
+	nodes, _ := parseGroup(tokens, upperWords(tokens), 0, "")
+	return nodes
+}
+
+func parseGroup(tokens []sqlvalidator.Token, upper []bool, start int, closing string) ([]node, int) {
+	var nodes []node
+	for i := start; i < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token.IsPunct(closing):
+			return nodes, i
+		case token.IsPunct("(") || token.IsPunct("["):
+			want := ")"
+			if token.Text == "[" {
+				want = "]"
+			}
+			inner, end := parseGroup(tokens, upper, i+1, want)
+			group := node{token: token, text: token.Text, group: inner, isGrp: true}
+			if end < len(tokens) {
+				group.close = &tokens[end]
+			}
+			nodes = append(nodes, group)
+			i = end
+		default:
+			text := token.Text
+			if upper[i] {
+				text = token.Value
+			}
+			nodes = append(nodes, node{token: token, text: text})
+		}
+	}
+	return nodes, len(tokens)
+}
+
+// formatter 按子句输出语句
+type formatter struct {
+	p printer
+}
+
+// statements 逐条输出分号分隔的语句，语句之间空一行
+func (f *formatter) statements(nodes []node) {
+	start := 0
+	for i, n := range nodes {
+		if !n.isGrp && n.token.IsPunct(";") {
+			f.statement(nodes[start:i], 0)
+			f.p.write(n.token, ";")
+			start = i + 1
+			if start < len(nodes) {
+				f.p.blankLine()
+			}
+		}
+	}
+	if start < len(nodes) {
+		f.statement(nodes[start:], 0)
+	}
+}
+
+// statement 每个子句另起一行
+func (f *formatter) statement(nodes []node, level int) {
+	for _, c := range splitClauses(nodes) {
+		f.p.newline(level)
+		for _, keyword := range c.keyword {
+			f.node(keyword)
+		}
+		switch c.kind {
+		case clauseSelect:
+			f.selectList(c.body, level)
+		case clauseWith:
+			f.withList(c.body, level)
+		case clauseConditions:
+			f.conditions(c.body, level)
+		default:
+			f.inline(c.body)
+		}
+	}
+}
+
+// selectList 单列时与SELECT同行，多列时每列一行；DISTINCT等修饰保留在SELECT行
+func (f *formatter) selectList(body []node, level int) {
+	i := 0
+	for i < len(body) && !body[i].isGrp && (body[i].token.Keyword() == "DISTINCT" || body[i].token.Keyword() == "ALL") {
+		f.node(body[i])
+		i++
+		if i < len(body) && !body[i].isGrp && body[i].token.Keyword() == "ON" && i+1 < len(body) && body[i+1].isGrp {
+			f.node(body[i])
+			f.node(body[i+1])
+			i += 2
+		}
+	}
+
+	items := splitTop(body[i:], func(n node) bool { return n.token.IsPunct(",") })
+	if len(items) == 1 {
+		f.inline(items[0].nodes)
+		return
+	}
+	for k, item := range items {
+		f.p.newline(level + 1)
+		f.inline(item.nodes)
+		if k < len(items)-1 {
+			f.p.write(item.separator.token, ",")
+		}
+	}
+}
+
+// withList 第一个公用表表达式与WITH同行，其余各占一行
+func (f *formatter) withList(body []node, level int) {
+	items := splitTop(body, func(n node) bool { return n.token.IsPunct(",") })
+	for k, item := range items {
+		if k > 0 {
+			f.p.newline(level)
+		}
+		f.inline(item.nodes)
+		if k < len(items)-1 {
+			f.p.write(item.separator.token, ",")
+		}
+	}
+}
+
+// conditions 按顶层AND/OR换行，BETWEEN ... AND与CASE中的AND不换行
+func (f *formatter) conditions(body []node, level int) {
+	between, caseDepth := false, 0
+	items := splitTop(body, func(n node) bool {
+		switch n.token.Keyword() {
+		case "BETWEEN":
+			between = true
+		case "CASE":
+			caseDepth++
+		case "END":
+			caseDepth--
+		case "AND":
+			if between {
+				between = false
+				return false
+			}
+			return caseDepth == 0
+		case "OR":
+			return caseDepth == 0
+		}
+		return false
+	})
+	for k, item := range items {
+		if k > 0 {
+			f.p.newline(level + 1)
+			f.node(items[k-1].separator)
+		}
+		f.inline(item.nodes)
+	}
+}
+
+// inline 在当前行依次输出节点，子查询另起缩进的块
+func (f *formatter) inline(nodes []node) {
+	for _, n := range nodes {
+		f.node(n)
+	}
+}
+
+func (f *formatter) node(n node) {
+	if !n.isGrp {
+		f.p.write(n.token, n.text)
+		return
+	}
+
+	f.p.write(n.token, n.text)
+	if isSubquery(n.group) {
+		open := f.p.lineIndent
+		f.statement(n.group, open+1)
+		f.p.newline(open)
+	} else {
+		f.inline(n.group)
+	}
+	if n.close != nil {
+		f.p.write(*n.close, n.close.Text)
+	}
+}
+
+// isSubquery 括号内以SELECT或WITH开头
+func isSubquery(nodes []node) bool {
+	for _, n := range nodes {
+		if n.token.Kind == sqlvalidator.TokenComment {
+			continue
+		}
+		return !n.isGrp && (n.token.Keyword() == "SELECT" || n.token.Keyword() == "WITH")
+	}
+	return false
+}
+
+// splitItem 按分隔符切分的一段节点，separator为其后的分隔符
+type splitItem struct {
+	nodes     []node
+	separator node
+}
+
+// splitTop 在顶层按分隔符切分，括号组内的节点不参与判断
+func splitTop(nodes []node, isSeparator func(node) bool) []splitItem {
+	var items []splitItem
+	start := 0
+	for i, n := range nodes {
+		if !n.isGrp && isSeparator(n) {
+			items = append(items, splitItem{nodes: nodes[start:i], separator: n})
+			start = i + 1
+		}
+	}
+	return append(items, splitItem{nodes: nodes[start:]})
+}
@@ -0,0 +1,109 @@
+package sqlformat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "子句与条件换行",
+			sql: "select u.id, u.name, count(o.id) as orders from users u left join orders o on o.user_id = u.id and o.status = 'paid' " +
+				"where u.created_at between '2024-01-01' and '2024-12-31' and (u.role = 'admin' or u.role='staff') " +
+				"group by u.id, u.name having count(*) > 5 order by orders desc nulls last limit 10",
+			want: `SELECT
+  u.id,
+  u.name,
+  count(o.id) AS orders
+FROM users u
+LEFT JOIN orders o ON o.user_id = u.id
+  AND o.status = 'paid'
+WHERE u.created_at BETWEEN '2024-01-01' AND '2024-12-31'
+  AND (u.role = 'admin' OR u.role = 'staff')
+GROUP BY u.id, u.name
+HAVING count(*) > 5
+ORDER BY orders DESC NULLS LAST
+LIMIT 10`,
+		},
+		{
+			name: "公用表表达式与子查询缩进",
+			sql:  "with recent as (select * from orders where created_at >= now() - interval '7 days') select * from recent where total>=-1 and id in (select id from vip)",
+			want: `WITH recent AS (
+  SELECT *
+  FROM orders
+  WHERE created_at >= now() - INTERVAL '7 days'
+)
+SELECT *
+FROM recent
+WHERE total >= -1
+  AND id IN (
+    SELECT id
+    FROM vip
+  )`,
+		},
+		{
+			name: "注释、类型转换与运算符",
+			sql:  "SELECT id FROM t -- trailing\nWHERE a IS DISTINCT FROM b AND c::int = 1 AND d->>'x' = 'y' AND arr[1] = 2;",
+			want: `SELECT id
+FROM t -- trailing
+WHERE a IS DISTINCT FROM b
+  AND c::int = 1
+  AND d ->> 'x' = 'y'
+  AND arr[1] = 2;`,
+		},
+		{
+			name: "限定名中的关键字与引号内容保持原样",
+			sql:  `select "Order".select, t.from, 'select' from "Order", t`,
+			want: `SELECT
+  "Order".select,
+  t.from,
+  'select'
+FROM "Order", t`,
+		},
+		{
+			name: "集合运算与多条语句",
+			sql:  "select 1 union all select 2; select count(*) from t",
+			want: "SELECT 1\nUNION ALL\nSELECT 2;\n\nSELECT count(*)\nFROM t",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.sql)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// 重复格式化结果不变
+			again, err := Format(got)
+			require.NoError(t, err)
+			assert.Equal(t, got, again)
+		})
+	}
+}
+
+func TestFormat_Fallback(t *testing.T) {
+	sql := "SELECT 'abc"
+	got, err := Format(sql)
+	assert.ErrorIs(t, err, sqlvalidator.ErrSyntax)
+	assert.Equal(t, sql, got)
+
+	// MySQL的#注释换行后会吞掉后面的记号
+	sql = "select id # note\nfrom t"
+	got, err = Format(sql)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	assert.Equal(t, sql, got)
+	assert.Equal(t, sql, FormatOrOriginal(sql))
+
+	got, err = Format("select data #> '{a}' from t")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT data #> '{a}'\nFROM t", got)
+}
@@ -0,0 +1,80 @@
+package sqlformat
+
+import (
+	"strings"
+
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+// keywords 输出为大写的关键字
+// 常被用作列名的词（如key、time、first）不在其中，只在phrases列出的固定搭配中大写
+var keywords = toSet(`
+	SELECT FROM WHERE AND OR NOT IN IS NULL AS ON USING
+	JOIN LEFT RIGHT INNER OUTER FULL CROSS NATURAL LATERAL
+	GROUP BY HAVING ORDER ASC DESC NULLS LIMIT OFFSET FETCH ROWS ONLY
+	UNION INTERSECT EXCEPT ALL DISTINCT WITH RECURSIVE
+	CASE WHEN THEN ELSE END BETWEEN LIKE ILIKE SIMILAR ESCAPE EXISTS ANY SOME
+	INSERT INTO VALUES UPDATE SET DELETE RETURNING CONFLICT DO NOTHING FOR
+	CAST TRUE FALSE INTERVAL COLLATE ARRAY
+	OVER PARTITION WINDOW FILTER WITHIN PRECEDING FOLLOWING UNBOUNDED
+	COALESCE NULLIF EXTRACT GREATEST LEAST
+`)
+
+// phrases 其中的词单独出现时可能是列名，只在这些固定搭配中大写
+var phrases = [][]string{
+	{"NULLS", "FIRST"},
+	{"NULLS", "LAST"},
+	{"FETCH", "FIRST"},
+	{"FETCH", "NEXT"},
+	{"ROW", "ONLY"},
+	{"CURRENT", "ROW"},
+	{"RANGE", "BETWEEN"},
+	{"FOR", "NO", "KEY", "UPDATE"},
+	{"FOR", "KEY", "SHARE"},
+	{"FOR", "SHARE"},
+	{"AT", "TIME", "ZONE"},
+	{"WITH", "TIME", "ZONE"},
+	{"WITHOUT", "TIME", "ZONE"},
+}
+
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// upperWords 标记需要输出为大写的单词；紧邻.的单词是限定名的一部分，保持原样
+func upperWords(tokens []sqlvalidator.Token) []bool {
+	upper := make([]bool, len(tokens))
+	qualified := func(i int) bool {
+		return (i > 0 && tokens[i-1].IsPunct(".")) || (i+1 < len(tokens) && tokens[i+1].IsPunct("."))
+	}
+	for i, token := range tokens {
+		if token.Kind == sqlvalidator.TokenWord && keywords[token.Value] && !qualified(i) {
+			upper[i] = true
+		}
+	}
+
+	for i := range tokens {
+		for _, phrase := range phrases {
+			if i+len(phrase) > len(tokens) {
+				continue
+			}
+			matched := true
+			for k, word := range phrase {
+				if tokens[i+k].Keyword() != word || qualified(i+k) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				for k := range phrase {
+					upper[i+k] = true
+				}
+			}
+		}
+	}
+	return upper
+}
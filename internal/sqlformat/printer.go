@@ -0,0 +1,137 @@
+package sqlformat
+
+import (
+	"strings"
+
+	"chat2sql-go/internal/sqlvalidator"
+)
+
+// printer 逐个写入记号，按相邻记号决定是否加空格，换行推迟到下一个记号写入前
+type printer struct {
+	buf        strings.Builder
+	lineIndent int // 当前行的缩进层级
+	pending    bool
+	blank      bool // 换行时额外空一行
+	nextIndent int
+	prev       *sqlvalidator.Token
+	before     *sqlvalidator.Token // prev之前的记号
+}
+
+// newline 下一个记号写在新的一行，多次调用以最后一次的缩进为准
+func (p *printer) newline(level int) {
+	if p.buf.Len() == 0 {
+		p.lineIndent = level
+		return
+	}
+	p.pending = true
+	p.nextIndent = level
+}
+
+// blankLine 下一个记号前空一行，用于分隔语句
+func (p *printer) blankLine() {
+	p.newline(0)
+	p.blank = true
+}
+
+func (p *printer) write(token sqlvalidator.Token, text string) {
+	switch {
+	case p.buf.Len() == 0:
+		p.buf.WriteString(strings.Repeat(indentUnit, p.lineIndent))
+	case p.pending:
+		p.buf.WriteByte('\n')
+		if p.blank {
+			p.buf.WriteByte('\n')
+		}
+		p.buf.WriteString(strings.Repeat(indentUnit, p.nextIndent))
+		p.lineIndent = p.nextIndent
+		p.pending, p.blank = false, false
+	case needSpace(p.before, p.prev, token):
+		p.buf.WriteByte(' ')
+	}
+	p.buf.WriteString(text)
+	p.before, p.prev = p.prev, &token
+
+	// 行注释延续到行尾，之后的记号必须换行
+	if token.Kind == sqlvalidator.TokenComment && strings.HasPrefix(token.Text, "--") {
+		p.newline(p.lineIndent)
+	}
+}
+
+func (p *printer) String() string {
+	return p.buf.String()
+}
+
+// functionKeywords 后面紧跟括号时按函数调用书写的关键字
+var functionKeywords = map[string]bool{
+	"CAST": true, "COALESCE": true, "NULLIF": true, "EXTRACT": true, "GREATEST": true, "LEAST": true,
+	"LEFT": true, "RIGHT": true, "ANY": true, "SOME": true,
+}
+
+// comparisonOperators 后面紧跟的+/-在PostgreSQL中是单独的一元运算符
+var comparisonOperators = map[string]bool{"=": true, "<": true, ">": true}
+
+// needSpace 判断相邻的两个记号之间是否需要空格
+func needSpace(before, prev *sqlvalidator.Token, cur sqlvalidator.Token) bool {
+	switch {
+	case prev == nil:
+		return false
+	case prev.Kind == sqlvalidator.TokenComment || cur.Kind == sqlvalidator.TokenComment:
+		return true
+	case cur.Kind == sqlvalidator.TokenPunct && strings.Contains(",;.)]:", cur.Text):
+		return false
+	case prev.Kind == sqlvalidator.TokenPunct && strings.Contains("([.:", prev.Text):
+		return false
+	case cur.IsPunct("("):
+		return !callable(*prev)
+	case cur.IsPunct("["):
+		return !(prev.Kind == sqlvalidator.TokenWord || prev.Kind == sqlvalidator.TokenQuotedIdent || prev.IsPunct(")") || prev.IsPunct("]"))
+	}
+
+	// 紧挨着的运算符字符构成一个运算符，如>=、->>、||
+	if prev.Kind == sqlvalidator.TokenOperator && cur.Kind == sqlvalidator.TokenOperator {
+		if (prev.Text == "-" && cur.Text == "-") || (prev.Text == "/" && cur.Text == "*") {
+			return true
+		}
+		if adjacent(*prev, cur) {
+			return (cur.Text == "-" || cur.Text == "+") && comparisonOperators[prev.Text]
+		}
+		return true
+	}
+	return !isUnary(before, prev)
+}
+
+// callable 左括号前的记号是函数名时不加空格
+func callable(prev sqlvalidator.Token) bool {
+	switch prev.Kind {
+	case sqlvalidator.TokenQuotedIdent:
+		return true
+	case sqlvalidator.TokenWord:
+		return !keywords[prev.Value] || functionKeywords[prev.Value]
+	}
+	return false
+}
+
+// isUnary prev是一元的+/-：位于开头、运算符、左括号、逗号或关键字之后
+func isUnary(before, prev *sqlvalidator.Token) bool {
+	if prev.Kind != sqlvalidator.TokenOperator || (prev.Text != "-" && prev.Text != "+") {
+		return false
+	}
+	if before == nil {
+		return true
+	}
+	switch before.Kind {
+	case sqlvalidator.TokenOperator:
+		return true
+	case sqlvalidator.TokenPunct:
+		return before.Text == "(" || before.Text == "[" || before.Text == ","
+	case sqlvalidator.TokenWord:
+		return keywords[before.Value] && before.Value != "END" && before.Value != "NULL" &&
+			before.Value != "TRUE" && before.Value != "FALSE"
+	}
+	return false
+}
+
+// adjacent 两个记号在原SQL中紧挨着
+func adjacent(a, b sqlvalidator.Token) bool {
+	return a.Pos+len(a.Text) == b.Pos
+}
@@ -0,0 +1,263 @@
+// Package sqlvalidator 基于PostgreSQL词法规则的SQL只读预检
+// 先按词法切分出记号（去掉注释，字符串、美元引用与带引号标识符作为整体），再按记号模式识别
+// 多条语句、CTE中的写操作、SELECT INTO、行锁与已知有副作用的函数调用，
+// 注释与字符串中的关键词、updated_at之类的标识符不会误判。
+//
+// 这是启发式检查而不是SQL解析器：写语句与有副作用的函数是手工维护的名单，名单之外的函数
+// （包括用户自定义的VOLATILE函数）会通过检查。预检只用于尽早拒绝明显的写操作并给出明确提示，
+// 只读的保证来自执行时的只读事务（READ ONLY），执行SQL的代码不能省略只读事务
+package sqlvalidator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind 记号类型
+type TokenKind int
+
+const (
+	TokenWord        TokenKind = iota // 关键字或未加引号的标识符
+	TokenQuotedIdent                  // "标识符"或`标识符`
+	TokenString                       // 字符串常量，包括E''、B''、X''与美元引用
+	TokenNumber                       // 数值常量
+	TokenParam                        // $1形式的参数
+	TokenPunct                        // ( ) [ ] , ; . :
+	TokenOperator                     // 其他运算符字符
+	TokenComment                      // --或/* */注释，只由TokenizeWithComments返回
+)
+
+// Token 词法记号
+type Token struct {
+	Kind TokenKind
+	Text string // 原文
+	// Value 规范化的值：关键字与未加引号的标识符转为大写，带引号的标识符与字符串为去掉引号与转义后的内容
+	Value string
+	Pos   int // 在原SQL中的字节偏移
+}
+
+// Keyword 返回未加引号单词的大写形式，其他记号返回空字符串
+func (t Token) Keyword() string {
+	if t.Kind == TokenWord {
+		return t.Value
+	}
+	return ""
+}
+
+// IsPunct 判断是否为指定的标点
+func (t Token) IsPunct(p string) bool {
+	return t.Kind == TokenPunct && t.Text == p
+}
+
+// Tokenize 按PostgreSQL词法规则切分SQL，注释被丢弃；字符串、注释或带引号的标识符未闭合时返回ErrSyntax
+func Tokenize(sql string) ([]Token, error) {
+	return tokenize(sql, false)
+}
+
+// TokenizeWithComments 与Tokenize相同，但注释作为TokenComment记号保留，用于格式化等需要原样输出注释的场景
+func TokenizeWithComments(sql string) ([]Token, error) {
+	return tokenize(sql, true)
+}
+
+func tokenize(sql string, keepComments bool) ([]Token, error) {
+	var tokens []Token
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			if keepComments {
+				text := strings.TrimRight(sql[i:i+end], " \t\r")
+				tokens = append(tokens, Token{Kind: TokenComment, Text: text, Value: text, Pos: i})
+			}
+			i += end
+
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end, err := skipBlockComment(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			if keepComments {
+				tokens = append(tokens, Token{Kind: TokenComment, Text: sql[i:end], Value: sql[i:end], Pos: i})
+			}
+			i = end
+
+		case c == '\'':
+			token, end, err := scanString(sql, i, i, false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = end
+
+		case c == '"' || c == '`':
+			token, end, err := scanQuotedIdent(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = end
+
+		case c == '$':
+			token, end, err := scanDollar(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = end
+
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			end := i + 1
+			for end < len(sql) && (isIdentChar(sql[end]) || sql[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Text: sql[i:end], Value: sql[i:end], Pos: i})
+			i = end
+
+		case isIdentStart(sql, i):
+			end := i
+			for end < len(sql) && (isIdentChar(sql[end]) || sql[end] == '$' || sql[end] >= utf8.RuneSelf) {
+				end++
+			}
+			word := sql[i:end]
+			// E'...'、B'...'、X'...'、N'...'前缀的字符串常量
+			if end < len(sql) && sql[end] == '\'' && len(word) == 1 && strings.ContainsAny(word, "EeBbXxNn") {
+				token, stringEnd, err := scanString(sql, i, end, word == "E" || word == "e")
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, token)
+				i = stringEnd
+				continue
+			}
+			tokens = append(tokens, Token{Kind: TokenWord, Text: word, Value: strings.ToUpper(word), Pos: i})
+			i = end
+
+		case strings.IndexByte("()[],;.:", c) >= 0:
+			tokens = append(tokens, Token{Kind: TokenPunct, Text: string(c), Value: string(c), Pos: i})
+			i++
+
+		default:
+			_, size := utf8.DecodeRuneInString(sql[i:])
+			tokens = append(tokens, Token{Kind: TokenOperator, Text: sql[i : i+size], Value: sql[i : i+size], Pos: i})
+			i += size
+		}
+	}
+	return tokens, nil
+}
+
+// skipBlockComment 跳过可嵌套的/* */注释，返回注释后的位置
+func skipBlockComment(sql string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(sql)-1; i++ {
+		switch {
+		case sql[i] == '/' && sql[i+1] == '*':
+			depth++
+			i++
+		case sql[i] == '*' && sql[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: 位置%d的注释未闭合", ErrSyntax, start)
+}
+
+// scanString 扫描单引号字符串，连续两个单引号表示一个单引号；backslash为true时（E前缀的字符串）反斜杠转义下一个字符
+func scanString(sql string, start, quote int, backslash bool) (Token, int, error) {
+	var value strings.Builder
+	for i := quote + 1; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case backslash && c == '\\' && i+1 < len(sql):
+			i++
+			value.WriteByte(sql[i])
+		case c == '\'':
+			if i+1 < len(sql) && sql[i+1] == '\'' {
+				value.WriteByte('\'')
+				i++
+				continue
+			}
+			return Token{Kind: TokenString, Text: sql[start : i+1], Value: value.String(), Pos: start}, i + 1, nil
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return Token{}, 0, fmt.Errorf("%w: 位置%d的字符串未闭合", ErrSyntax, start)
+}
+
+// scanQuotedIdent 扫描双引号（或MySQL反引号）标识符，连续两个引号表示一个引号
+func scanQuotedIdent(sql string, start int) (Token, int, error) {
+	quote := sql[start]
+	var value strings.Builder
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] != quote {
+			value.WriteByte(sql[i])
+			continue
+		}
+		if i+1 < len(sql) && sql[i+1] == quote {
+			value.WriteByte(quote)
+			i++
+			continue
+		}
+		return Token{Kind: TokenQuotedIdent, Text: sql[start : i+1], Value: value.String(), Pos: start}, i + 1, nil
+	}
+	return Token{}, 0, fmt.Errorf("%w: 位置%d的标识符引号未闭合", ErrSyntax, start)
+}
+
+// scanDollar 扫描$1形式的参数或$tag$...$tag$形式的美元引用字符串
+func scanDollar(sql string, start int) (Token, int, error) {
+	i := start + 1
+	if i < len(sql) && isDigit(sql[i]) {
+		for i < len(sql) && isDigit(sql[i]) {
+			i++
+		}
+		return Token{Kind: TokenParam, Text: sql[start:i], Value: sql[start:i], Pos: start}, i, nil
+	}
+
+	if i < len(sql) && isIdentStart(sql, i) {
+		for i < len(sql) && isIdentChar(sql[i]) {
+			i++
+		}
+	}
+	if i >= len(sql) || sql[i] != '$' {
+		return Token{Kind: TokenOperator, Text: "$", Value: "$", Pos: start}, start + 1, nil
+	}
+	delimiter := sql[start : i+1]
+	end := strings.Index(sql[i+1:], delimiter)
+	if end < 0 {
+		return Token{}, 0, fmt.Errorf("%w: 位置%d的美元引用字符串未闭合", ErrSyntax, start)
+	}
+	bodyEnd := i + 1 + end
+	return Token{Kind: TokenString, Text: sql[start : bodyEnd+len(delimiter)], Value: sql[i+1 : bodyEnd], Pos: start}, bodyEnd + len(delimiter), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isIdentStart 标识符以字母、下划线或非ASCII字母开头
+func isIdentStart(sql string, i int) bool {
+	c := sql[i]
+	if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+	if c < utf8.RuneSelf {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(sql[i:])
+	return unicode.IsLetter(r)
+}
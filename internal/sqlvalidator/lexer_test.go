@@ -0,0 +1,69 @@
+package sqlvalidator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	sql := `SELECT "Order"."updated_at", E'it\'s', 'a''b', $fn$ DROP TABLE x; $fn$, $1 -- DELETE
+FROM /* outer /* nested */ still comment */ orders`
+	tokens, err := Tokenize(sql)
+	require.NoError(t, err)
+
+	var kinds []TokenKind
+	var values []string
+	for _, token := range tokens {
+		kinds = append(kinds, token.Kind)
+		values = append(values, token.Value)
+	}
+	assert.Equal(t, []string{"SELECT", "Order", ".", "updated_at", ",", "it's", ",", "a'b", ",", " DROP TABLE x; ", ",", "$1", "FROM", "ORDERS"}, values)
+	assert.Equal(t, []TokenKind{
+		TokenWord, TokenQuotedIdent, TokenPunct, TokenQuotedIdent, TokenPunct, TokenString, TokenPunct, TokenString,
+		TokenPunct, TokenString, TokenPunct, TokenParam, TokenWord, TokenWord,
+	}, kinds)
+	assert.Equal(t, "orders", tokens[len(tokens)-1].Text)
+	assert.Equal(t, len(sql)-len("orders"), tokens[len(tokens)-1].Pos)
+}
+
+func TestTokenize_Unterminated(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT 'abc",
+		`SELECT "abc`,
+		"SELECT 1 /* DROP TABLE x",
+		"SELECT 1 /* /* */",
+		"SELECT $$abc",
+		`SELECT E'abc\'`,
+	} {
+		_, err := Tokenize(sql)
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestTokenize_NonASCIIIdentifier(t *testing.T) {
+	tokens, err := Tokenize("SELECT 金额 FROM 订单")
+	require.NoError(t, err)
+	require.Len(t, tokens, 4)
+	assert.Equal(t, TokenWord, tokens[1].Kind)
+	assert.Equal(t, "金额", tokens[1].Text)
+}
+
+func TestTokenizeWithComments(t *testing.T) {
+	sql := "SELECT 1 -- one  \n/* two */ FROM t --end"
+	tokens, err := TokenizeWithComments(sql)
+	require.NoError(t, err)
+
+	var texts []string
+	for _, token := range tokens {
+		if token.Kind == TokenComment {
+			texts = append(texts, token.Text)
+		}
+	}
+	assert.Equal(t, []string{"-- one", "/* two */", "--end"}, texts)
+
+	plain, err := Tokenize(sql)
+	require.NoError(t, err)
+	assert.Len(t, plain, len(tokens)-3)
+}
@@ -0,0 +1,311 @@
+package sqlvalidator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrEmpty SQL为空或只有注释
+	ErrEmpty = errors.New("SQL语句不能为空")
+	// ErrSyntax 字符串、注释、引号或括号未闭合等词法与结构错误
+	ErrSyntax = errors.New("SQL语法错误")
+	// ErrMultipleStatements 一次只允许执行一条语句
+	ErrMultipleStatements = errors.New("不允许一次执行多条SQL语句")
+	// ErrNotSelect 语句不是查询
+	ErrNotSelect = errors.New("仅支持SELECT查询语句")
+	// ErrForbiddenOperation 语句包含写操作或有副作用的调用
+	ErrForbiddenOperation = errors.New("系统仅支持查询操作")
+)
+
+// readStatements 只读的语句类型，VALUES与TABLE等价于SELECT
+var readStatements = map[string]bool{"SELECT": true, "VALUES": true, "TABLE": true}
+
+// writeStatements 修改数据、结构、权限或会话状态的语句类型
+var writeStatements = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "UPSERT": true, "REPLACE": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true, "COMMENT": true,
+	"GRANT": true, "REVOKE": true, "COPY": true, "LOAD": true, "IMPORT": true,
+	"CALL": true, "DO": true, "EXEC": true, "EXECUTE": true, "PREPARE": true, "DEALLOCATE": true, "DECLARE": true,
+	"SET": true, "RESET": true, "LOCK": true, "UNLOCK": true, "DISCARD": true,
+	"VACUUM": true, "ANALYZE": true, "CLUSTER": true, "REINDEX": true, "REFRESH": true,
+	"LISTEN": true, "NOTIFY": true, "UNLISTEN": true, "SECURITY": true, "REASSIGN": true,
+	"BEGIN": true, "START": true, "COMMIT": true, "END": true, "ROLLBACK": true, "SAVEPOINT": true, "RELEASE": true,
+}
+
+// dataModifyingStatements 可以出现在CTE或子查询括号内的写语句
+var dataModifyingStatements = map[string]bool{"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true}
+
+// sideEffectFunctions 已知在SELECT中调用也会产生副作用、阻塞或读取服务器文件的内置与常见扩展函数（小写）
+// 名单无法覆盖用户自定义函数，漏检的写入由只读事务拦截
+var sideEffectFunctions = map[string]bool{
+	// 阻塞
+	"pg_sleep": true, "pg_sleep_for": true, "pg_sleep_until": true, "sleep": true, "benchmark": true,
+	"pg_advisory_lock": true, "pg_advisory_lock_shared": true, "pg_advisory_xact_lock": true, "pg_advisory_xact_lock_shared": true,
+	// 管理操作
+	"pg_terminate_backend": true, "pg_cancel_backend": true, "pg_reload_conf": true, "pg_rotate_logfile": true,
+	"pg_switch_wal": true, "pg_create_restore_point": true, "pg_promote": true, "set_config": true,
+	"pg_logical_emit_message": true, "pg_create_logical_replication_slot": true, "pg_create_physical_replication_slot": true,
+	"pg_drop_replication_slot": true,
+	// 序列
+	"nextval": true, "setval": true,
+	// 大对象与服务器文件
+	"lo_import": true, "lo_export": true, "lo_create": true, "lo_creat": true, "lo_unlink": true, "lo_put": true,
+	"lo_from_bytea": true, "pg_read_file": true, "pg_read_binary_file": true, "pg_ls_dir": true, "pg_stat_file": true,
+	"load_file": true, "lo_get": true, "lo_open": true, "lowrite": true, "lo_truncate": true, "lo_truncate64": true,
+	"pg_ls_logdir": true, "pg_ls_waldir": true, "pg_ls_tmpdir": true, "pg_ls_archive_statusdir": true,
+	"pg_file_write": true, "pg_file_rename": true, "pg_file_unlink": true, "pg_file_sync": true,
+	// 以字符串执行任意SQL或按名称读取整表
+	"query_to_xml": true, "query_to_xmlschema": true, "query_to_xml_and_xmlschema": true,
+	"cursor_to_xml": true, "cursor_to_xmlschema": true,
+	"table_to_xml": true, "table_to_xmlschema": true, "table_to_xml_and_xmlschema": true,
+	"schema_to_xml": true, "schema_to_xmlschema": true, "schema_to_xml_and_xmlschema": true,
+	"database_to_xml": true, "database_to_xmlschema": true, "database_to_xml_and_xmlschema": true,
+	// 跨库执行
+	"dblink": true, "dblink_exec": true, "dblink_connect": true, "dblink_connect_u": true, "dblink_send_query": true,
+	"dblink_open": true, "dblink_fetch": true, "dblink_get_result": true,
+}
+
+// Statement 一条语句的分析结果
+type Statement struct {
+	// Type 语句类型，即首个关键字的大写形式；WITH语句为CTE之后主语句的类型，无法识别时为空
+	Type string
+	// Operations 只读语句中发现的写操作或副作用，如CTE中的DELETE、SELECT INTO、FOR UPDATE、pg_sleep()
+	Operations []string
+	Tokens     []Token
+}
+
+// ReadOnly 判断语句是否为没有副作用的查询
+func (s *Statement) ReadOnly() bool {
+	return readStatements[s.Type] && len(s.Operations) == 0
+}
+
+// Analysis SQL的分析结果，空语句（连续或末尾的分号）被忽略
+type Analysis struct {
+	Statements []*Statement
+}
+
+// ReadOnly 判断所有语句是否都是只读查询
+func (a *Analysis) ReadOnly() bool {
+	for _, statement := range a.Statements {
+		if !statement.ReadOnly() {
+			return false
+		}
+	}
+	return len(a.Statements) > 0
+}
+
+// Analyze 切分并分析SQL中的每条语句，词法错误或括号不匹配时返回ErrSyntax，没有语句时返回ErrEmpty
+func Analyze(sql string) (*Analysis, error) {
+	tokens, err := Tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &Analysis{}
+	depth, start := 0, 0
+	for i, token := range tokens {
+		switch {
+		case token.IsPunct("("):
+			depth++
+		case token.IsPunct(")"):
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("%w: 位置%d有多余的右括号", ErrSyntax, token.Pos)
+			}
+		case token.IsPunct(";"):
+			// 分号只能出现在顶层，括号内的分号会被数据库拒绝，这里同样按错误处理
+			if depth > 0 {
+				return nil, fmt.Errorf("%w: 括号未闭合", ErrSyntax)
+			}
+			if i > start {
+				analysis.Statements = append(analysis.Statements, analyzeStatement(tokens[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("%w: 括号未闭合", ErrSyntax)
+	}
+	if start < len(tokens) {
+		analysis.Statements = append(analysis.Statements, analyzeStatement(tokens[start:]))
+	}
+	if len(analysis.Statements) == 0 {
+		return nil, ErrEmpty
+	}
+	return analysis, nil
+}
+
+// ValidateReadOnly 预检SQL是否为单条没有已知副作用的查询
+// 通过预检不代表SQL一定只读，调用方仍须在只读事务中执行
+func ValidateReadOnly(sql string) error {
+	analysis, err := Analyze(sql)
+	if err != nil {
+		return err
+	}
+	if len(analysis.Statements) > 1 {
+		return ErrMultipleStatements
+	}
+
+	statement := analysis.Statements[0]
+	switch {
+	case statement.Type == "":
+		return fmt.Errorf("%w: 无法识别的语句", ErrSyntax)
+	case writeStatements[statement.Type]:
+		return fmt.Errorf("禁止执行 %s 操作，%w", statement.Type, ErrForbiddenOperation)
+	case !readStatements[statement.Type]:
+		return ErrNotSelect
+	case len(statement.Operations) > 0:
+		return fmt.Errorf("禁止执行 %s 操作，%w", strings.Join(statement.Operations, "、"), ErrForbiddenOperation)
+	}
+	return nil
+}
+
+// analyzeStatement 识别语句类型，只读语句再检查其中的写操作与副作用
+func analyzeStatement(tokens []Token) *Statement {
+	statement := &Statement{Tokens: tokens}
+	var operations []string
+	statement.Type, operations = statementType(tokens)
+	if readStatements[statement.Type] {
+		operations = append(operations, sideEffects(tokens)...)
+	}
+
+	seen := map[string]bool{}
+	for _, operation := range operations {
+		if !seen[operation] {
+			seen[operation] = true
+			statement.Operations = append(statement.Operations, operation)
+		}
+	}
+	return statement
+}
+
+// statementType 返回语句类型与CTE中的写操作
+// WITH语句逐个跳过CTE定义，CTE主体按语句递归分析，返回CTE之后主语句的类型；结构无法识别时类型为空
+func statementType(tokens []Token) (string, []string) {
+	i := 0
+	for i < len(tokens) && tokens[i].IsPunct("(") {
+		i++
+	}
+	if i >= len(tokens) {
+		return "", nil
+	}
+	if keyword := tokens[i].Keyword(); keyword != "WITH" {
+		return keyword, nil
+	}
+
+	i++
+	if i < len(tokens) && tokens[i].Keyword() == "RECURSIVE" {
+		i++
+	}
+	var operations []string
+	for {
+		// 名称 [(列, ...)] AS [[NOT] MATERIALIZED] (主体)
+		if i >= len(tokens) || (tokens[i].Kind != TokenWord && tokens[i].Kind != TokenQuotedIdent) {
+			return "", operations
+		}
+		i++
+		if i < len(tokens) && tokens[i].IsPunct("(") {
+			i = skipGroup(tokens, i)
+		}
+		if i >= len(tokens) || tokens[i].Keyword() != "AS" {
+			return "", operations
+		}
+		i++
+		if i < len(tokens) && tokens[i].Keyword() == "NOT" {
+			i++
+		}
+		if i < len(tokens) && tokens[i].Keyword() == "MATERIALIZED" {
+			i++
+		}
+		if i >= len(tokens) || !tokens[i].IsPunct("(") {
+			return "", operations
+		}
+		end := skipGroup(tokens, i)
+		bodyType, bodyOperations := statementType(tokens[i+1 : end-1])
+		if bodyType == "" {
+			return "", operations
+		}
+		if !readStatements[bodyType] {
+			operations = append(operations, bodyType)
+		}
+		operations = append(operations, bodyOperations...)
+		i = end
+
+		// 跳过递归CTE的SEARCH/CYCLE子句
+		for i < len(tokens) && !tokens[i].IsPunct(",") && !startsStatement(tokens[i]) {
+			i++
+		}
+		if i >= len(tokens) {
+			return "", operations
+		}
+		if !tokens[i].IsPunct(",") {
+			break
+		}
+		i++
+	}
+
+	mainType, mainOperations := statementType(tokens[i:])
+	return mainType, append(operations, mainOperations...)
+}
+
+// startsStatement 判断记号能否作为CTE之后主语句的开头
+func startsStatement(token Token) bool {
+	if token.IsPunct("(") {
+		return true
+	}
+	keyword := token.Keyword()
+	return readStatements[keyword] || dataModifyingStatements[keyword]
+}
+
+// skipGroup 返回从start处左括号到对应右括号之后的位置，括号已由Analyze检查过配对
+func skipGroup(tokens []Token, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch {
+		case tokens[i].IsPunct("("):
+			depth++
+		case tokens[i].IsPunct(")"):
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// sideEffects 找出只读语句中的写操作与副作用：
+// 括号内的写语句、SELECT INTO（建表或写文件）、FOR UPDATE/SHARE行锁与有副作用的函数调用
+func sideEffects(tokens []Token) []string {
+	var operations []string
+	for i, token := range tokens {
+		var next Token
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+
+		switch keyword := token.Keyword(); {
+		case token.IsPunct("(") && dataModifyingStatements[next.Keyword()]:
+			operations = append(operations, next.Keyword())
+		case keyword == "INTO" && (i == 0 || !dataModifyingStatements[tokens[i-1].Keyword()]):
+			operations = append(operations, "SELECT INTO")
+		case keyword == "FOR" && (next.Keyword() == "UPDATE" || next.Keyword() == "SHARE"):
+			operations = append(operations, "FOR "+next.Keyword())
+		case keyword == "FOR" && next.Keyword() == "NO":
+			operations = append(operations, "FOR NO KEY UPDATE")
+		case keyword == "FOR" && next.Keyword() == "KEY":
+			operations = append(operations, "FOR KEY SHARE")
+		case (token.Kind == TokenWord || token.Kind == TokenQuotedIdent) && next.IsPunct("("):
+			name := token.Value
+			if token.Kind == TokenWord {
+				name = strings.ToLower(name)
+			}
+			if sideEffectFunctions[name] {
+				operations = append(operations, name+"()")
+			}
+		}
+	}
+	return operations
+}
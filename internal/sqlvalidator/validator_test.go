@@ -0,0 +1,90 @@
+package sqlvalidator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateReadOnly_Allowed(t *testing.T) {
+	for _, sql := range []string{
+		"SELECT id, updated_at, deleted FROM users WHERE status = 'active'",
+		"select created_at, last_update from orders;",
+		"-- 最近订单\nSELECT * FROM orders /* 不含DELETE */ LIMIT 10",
+		"SELECT 'DROP TABLE users; DELETE FROM x' AS note",
+		`SELECT "insert", "update" FROM audit_log`,
+		"SELECT $$ DELETE FROM users $$",
+		"WITH recent AS (SELECT * FROM orders WHERE created_at > now() - interval '7 days') SELECT count(*) FROM recent",
+		"WITH RECURSIVE tree(id, parent_id) AS (SELECT id, parent_id FROM nodes WHERE parent_id IS NULL UNION ALL SELECT n.id, n.parent_id FROM nodes n JOIN tree t ON n.parent_id = t.id) SELECT * FROM tree",
+		"WITH a AS MATERIALIZED (SELECT 1 AS x), b AS NOT MATERIALIZED (SELECT x FROM a) SELECT * FROM b",
+		"(SELECT 1) UNION (SELECT 2)",
+		"VALUES (1, 'a'), (2, 'b')",
+		"SELECT substring(name FROM 1 FOR 3) FROM users",
+		"SELECT now()",
+	} {
+		assert.NoError(t, ValidateReadOnly(sql), sql)
+	}
+}
+
+func TestValidateReadOnly_Rejected(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected error
+		message  string
+	}{
+		{"DROP TABLE users", ErrForbiddenOperation, "禁止执行 DROP 操作，系统仅支持查询操作"},
+		{"/* SELECT */ DELETE FROM users", ErrForbiddenOperation, "禁止执行 DELETE 操作"},
+		{"SELECT * FROM users; DROP TABLE users", ErrMultipleStatements, ""},
+		{"SELECT 1;; SELECT 2;", ErrMultipleStatements, ""},
+		{"WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone", ErrForbiddenOperation, "禁止执行 DELETE 操作"},
+		{"WITH a AS (SELECT 1), b AS (UPDATE accounts SET balance = 0 RETURNING id) SELECT * FROM a", ErrForbiddenOperation, "UPDATE"},
+		{"WITH a AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM a)", ErrForbiddenOperation, "禁止执行 DELETE 操作"},
+		{"WITH outer_cte AS (WITH inner_cte AS (INSERT INTO t VALUES (1) RETURNING *) SELECT * FROM inner_cte) SELECT * FROM outer_cte", ErrForbiddenOperation, "INSERT"},
+		{"SELECT * INTO backup_users FROM users", ErrForbiddenOperation, "SELECT INTO"},
+		{"SELECT * FROM accounts WHERE id = 1 FOR UPDATE", ErrForbiddenOperation, "FOR UPDATE"},
+		{"SELECT pg_sleep(10)", ErrForbiddenOperation, "pg_sleep()"},
+		{"SELECT pg_catalog.PG_TERMINATE_BACKEND(pid) FROM pg_stat_activity", ErrForbiddenOperation, "pg_terminate_backend()"},
+		{"SELECT set_config('statement_timeout', '0', false)", ErrForbiddenOperation, "set_config()"},
+		{"SELECT query_to_xml('DELETE FROM users RETURNING id', true, false, '')", ErrForbiddenOperation, "query_to_xml()"},
+		{"SELECT * FROM dblink('dbname=prod', 'DELETE FROM users') AS t(id int)", ErrForbiddenOperation, "dblink()"},
+		{`SELECT "dblink_exec"('dbname=prod', 'DROP TABLE users')`, ErrForbiddenOperation, "dblink_exec()"},
+		{"SELECT lo_import('/etc/passwd')", ErrForbiddenOperation, "lo_import()"},
+		{"SELECT pg_catalog.pg_read_file('/etc/passwd')", ErrForbiddenOperation, "pg_read_file()"},
+		{"EXPLAIN ANALYZE DELETE FROM users", ErrNotSelect, ""},
+		{"SHOW search_path", ErrNotSelect, ""},
+		{"SET ROLE admin", ErrForbiddenOperation, "禁止执行 SET 操作"},
+		{"-- 只有注释", ErrEmpty, ""},
+		{"SELECT (1", ErrSyntax, ""},
+		{"SELECT 1)", ErrSyntax, ""},
+		{"WITH broken SELECT 1", ErrSyntax, ""},
+	}
+	for _, tt := range tests {
+		err := ValidateReadOnly(tt.sql)
+		require.ErrorIs(t, err, tt.expected, tt.sql)
+		assert.Contains(t, err.Error(), tt.message, tt.sql)
+	}
+}
+
+func TestValidateReadOnly_UnknownFunctionsPass(t *testing.T) {
+	// 预检只认识名单中的函数，用户自定义函数即使会写入也能通过，写入由执行时的只读事务拒绝
+	assert.NoError(t, ValidateReadOnly("SELECT archive_old_orders()"))
+	assert.NoError(t, ValidateReadOnly("SELECT id FROM users WHERE audit.log_access(id)"))
+}
+
+func TestAnalyze(t *testing.T) {
+	analysis, err := Analyze("WITH d AS (DELETE FROM logs RETURNING id) SELECT count(*) FROM d; SELECT 1;")
+	require.NoError(t, err)
+	require.Len(t, analysis.Statements, 2)
+
+	assert.Equal(t, "SELECT", analysis.Statements[0].Type)
+	assert.Equal(t, []string{"DELETE"}, analysis.Statements[0].Operations)
+	assert.False(t, analysis.Statements[0].ReadOnly())
+	assert.True(t, analysis.Statements[1].ReadOnly())
+	assert.False(t, analysis.ReadOnly())
+
+	analysis, err = Analyze("INSERT INTO logs SELECT * FROM staging")
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT", analysis.Statements[0].Type)
+	assert.Empty(t, analysis.Statements[0].Operations, "写语句本身不再重复记录INTO")
+}
@@ -0,0 +1,77 @@
+package startup
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// probePaths 编排探针与监控抓取使用的端点
+var probePaths = map[string]bool{"/health": true, "/ready": true, "/metrics": true}
+
+// IsProbePath 判断请求路径是否为健康检查或指标端点
+func IsProbePath(path string) bool {
+	return probePaths[path]
+}
+
+// PriorityHandler 按请求类型分流，保证高负载时探针与指标抓取不会排在SQL生成、执行请求之后
+// 探针请求直接交给只挂载恢复中间件的轻量路由，不经过限流、请求日志与压缩；
+// 其余请求在并发上限内交给完整路由，超过上限时立即返回503而不是排队等待
+type PriorityHandler struct {
+	probes http.Handler
+	api    http.Handler
+	slots  chan struct{} // 为nil时不限制并发
+	shed   atomic.Int64
+}
+
+// NewPriorityHandler 创建请求分流处理器，maxInFlight为0时不限制API并发
+func NewPriorityHandler(probes, api http.Handler, maxInFlight int) *PriorityHandler {
+	h := &PriorityHandler{probes: probes, api: api}
+	if maxInFlight > 0 {
+		h.slots = make(chan struct{}, maxInFlight)
+	}
+	return h
+}
+
+// Shed 返回因超过并发上限被拒绝的请求数
+func (h *PriorityHandler) Shed() int64 {
+	return h.shed.Load()
+}
+
+// ServeHTTP 探针请求走轻量路由；WebSocket等升级连接长期占用，不计入并发上限
+func (h *PriorityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if IsProbePath(r.URL.Path) {
+		h.probes.ServeHTTP(w, r)
+		return
+	}
+	if h.slots == nil || r.Header.Get("Upgrade") != "" {
+		h.api.ServeHTTP(w, r)
+		return
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+		defer func() { <-h.slots }()
+		h.api.ServeHTTP(w, r)
+	default:
+		h.shed.Add(1)
+		w.Header().Set("Retry-After", "1")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{
+			"code":    "SERVER_OVERLOADED",
+			"message": "服务繁忙，请稍后重试",
+		})
+	}
+}
+
+// ProbesOnly 独立探针端口使用的处理器，只转发健康检查与指标请求，其余路径返回404
+func ProbesOnly(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsProbePath(r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,66 @@
+package startup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityHandler_ProbesBypassInFlightLimit(t *testing.T) {
+	probes := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	h := NewPriorityHandler(probes, api, 1)
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v2/ai/chat2sql", nil))
+		done <- rec.Code
+	}()
+	<-started
+
+	// 唯一的并发名额被占用时，新的API请求立即被拒绝
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/sql/history", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "SERVER_OVERLOADED")
+	assert.Equal(t, int64(1), h.Shed())
+
+	// 探针与指标不受影响
+	for _, path := range []string{"/health", "/ready", "/metrics"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+
+	close(release)
+	require.Equal(t, http.StatusOK, <-done)
+
+	// 名额释放后恢复处理
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/sql/history", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestProbesOnly(t *testing.T) {
+	s, _ := newTestSupervisor(0)
+	h := ProbesOnly(s)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v2/ai/chat2sql", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
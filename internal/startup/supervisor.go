@@ -0,0 +1,214 @@
+// Package startup 管理服务启动阶段的依赖初始化
+// 依赖服务暂时不可用时按退避策略重试，期间HTTP端口已开始监听并报告starting状态
+package startup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/logging"
+)
+
+// State 启动状态
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateReady    State = "ready"
+	StateFailed   State = "failed"
+)
+
+// DependencyStatus 单个依赖的初始化状态
+type DependencyStatus struct {
+	Name      string     `json:"name"`
+	State     State      `json:"state"`
+	Attempts  int        `json:"attempts"`
+	LastError string     `json:"last_error,omitempty"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+}
+
+// Status 启动状态快照
+type Status struct {
+	Status       State              `json:"status"`
+	StartedAt    time.Time          `json:"started_at"`
+	Deadline     time.Time          `json:"deadline"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Supervisor 启动监督器
+// 按调用顺序逐个初始化依赖；就绪前自身作为HTTP处理器响应健康检查，就绪后转交给完整路由
+type Supervisor struct {
+	config *config.StartupConfig
+	logger *zap.Logger
+
+	mutex     sync.RWMutex
+	state     State
+	startedAt time.Time
+	deps      []*DependencyStatus
+
+	handler atomic.Pointer[http.Handler]
+
+	now  func() time.Time
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+// NewSupervisor 创建启动监督器，最长等待时间从创建时开始计算
+func NewSupervisor(cfg *config.StartupConfig, logger *zap.Logger) *Supervisor {
+	if cfg == nil {
+		cfg = config.DefaultStartupConfig()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &Supervisor{
+		config:    cfg,
+		logger:    logger,
+		state:     StateStarting,
+		startedAt: time.Now(),
+		now:       time.Now,
+		wait:      sleepContext,
+	}
+}
+
+// Retry 初始化一个依赖，失败时按指数退避重试
+// 超过最长等待时间或ctx取消（例如收到SIGTERM）时返回最后一次错误
+func (s *Supervisor) Retry(ctx context.Context, name string, init func(ctx context.Context) error) error {
+	dep := &DependencyStatus{Name: name, State: StateStarting}
+	s.mutex.Lock()
+	s.deps = append(s.deps, dep)
+	s.mutex.Unlock()
+
+	deadline := s.startedAt.Add(s.config.MaxWait)
+	backoff := s.config.InitialBackoff
+
+	for {
+		err := init(ctx)
+
+		s.mutex.Lock()
+		dep.Attempts++
+		if err == nil {
+			readyAt := s.now()
+			dep.State, dep.LastError, dep.ReadyAt = StateReady, "", &readyAt
+			s.mutex.Unlock()
+			s.logger.Info("依赖初始化成功", zap.String("dependency", name), zap.Int("attempts", dep.Attempts))
+			return nil
+		}
+		dep.LastError = logging.Redact(err.Error())
+		attempts := dep.Attempts
+		s.mutex.Unlock()
+
+		delay := jitter(backoff)
+		if s.now().Add(delay).After(deadline) {
+			s.fail(dep)
+			return fmt.Errorf("%s在%v内未就绪（尝试%d次）: %w", name, s.config.MaxWait, attempts, err)
+		}
+
+		s.logger.Warn("依赖暂不可用，稍后重试",
+			zap.String("dependency", name),
+			zap.Int("attempt", attempts),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+
+		if waitErr := s.wait(ctx, delay); waitErr != nil {
+			s.fail(dep)
+			return fmt.Errorf("等待%s时启动被中止（最后一次错误: %v）: %w", name, err, waitErr)
+		}
+
+		backoff *= 2
+		if backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// fail 标记依赖与整体启动失败
+func (s *Supervisor) fail(dep *DependencyStatus) {
+	s.mutex.Lock()
+	dep.State = StateFailed
+	s.state = StateFailed
+	s.mutex.Unlock()
+}
+
+// Ready 所有依赖初始化完成，之后的请求交给handler处理
+func (s *Supervisor) Ready(handler http.Handler) {
+	s.handler.Store(&handler)
+
+	s.mutex.Lock()
+	s.state = StateReady
+	s.mutex.Unlock()
+
+	s.logger.Info("服务启动完成", zap.Duration("elapsed", s.now().Sub(s.startedAt)))
+}
+
+// Status 返回启动状态快照
+func (s *Supervisor) Status() Status {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	status := Status{
+		Status:       s.state,
+		StartedAt:    s.startedAt,
+		Deadline:     s.startedAt.Add(s.config.MaxWait),
+		Dependencies: make([]DependencyStatus, 0, len(s.deps)),
+	}
+	for _, dep := range s.deps {
+		status.Dependencies = append(status.Dependencies, *dep)
+	}
+	return status
+}
+
+// ServeHTTP 就绪后转发给完整路由；启动期间/health返回200以免存活探针重启容器，
+// /ready与其他请求返回503，就绪探针据此把流量挡在外面
+func (s *Supervisor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler := s.handler.Load(); handler != nil {
+		(*handler).ServeHTTP(w, r)
+		return
+	}
+
+	status := s.Status()
+	code := http.StatusServiceUnavailable
+	if r.URL.Path == "/health" && status.Status == StateStarting {
+		code = http.StatusOK
+	}
+
+	var body any = status
+	if r.URL.Path != "/health" && r.URL.Path != "/ready" {
+		w.Header().Set("Retry-After", "5")
+		body = map[string]string{
+			"code":    "SERVICE_STARTING",
+			"message": "服务正在启动，请稍后重试",
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// jitter 在退避时间上叠加±20%的随机抖动，避免多个副本同时重连
+func jitter(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+}
+
+// sleepContext 等待d或ctx取消
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
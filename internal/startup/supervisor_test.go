@@ -0,0 +1,119 @@
+package startup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// newTestSupervisor 使用虚拟时钟，等待只推进时间不真正睡眠
+func newTestSupervisor(maxWait time.Duration) (*Supervisor, *[]time.Duration) {
+	s := NewSupervisor(&config.StartupConfig{
+		Addr:           ":0",
+		MaxWait:        maxWait,
+		InitialBackoff: time.Second,
+		MaxBackoff:     4 * time.Second,
+	}, zap.NewNop())
+
+	clock := s.startedAt
+	var waits []time.Duration
+	s.now = func() time.Time { return clock }
+	s.wait = func(ctx context.Context, d time.Duration) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		waits = append(waits, d)
+		clock = clock.Add(d)
+		return nil
+	}
+	return s, &waits
+}
+
+func TestSupervisor_RetryUntilAvailable(t *testing.T) {
+	s, waits := newTestSupervisor(time.Minute)
+
+	attempts := 0
+	err := s.Retry(context.Background(), "postgres", func(ctx context.Context) error {
+		attempts++
+		if attempts < 5 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, attempts)
+
+	// 指数退避并受MaxBackoff限制，抖动不超过±20%
+	require.Len(t, *waits, 4)
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		assert.InDelta(t, float64(want), float64((*waits)[i]), float64(want)/5)
+	}
+
+	status := s.Status()
+	assert.Equal(t, StateStarting, status.Status)
+	require.Len(t, status.Dependencies, 1)
+	assert.Equal(t, StateReady, status.Dependencies[0].State)
+	assert.Empty(t, status.Dependencies[0].LastError)
+}
+
+func TestSupervisor_GivesUpAfterMaxWait(t *testing.T) {
+	s, _ := newTestSupervisor(10 * time.Second)
+
+	err := s.Retry(context.Background(), "redis", func(ctx context.Context) error {
+		return errors.New("dial tcp: connection refused")
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+
+	status := s.Status()
+	assert.Equal(t, StateFailed, status.Status)
+	assert.Equal(t, StateFailed, status.Dependencies[0].State)
+	assert.GreaterOrEqual(t, status.Dependencies[0].Attempts, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s, _ = newTestSupervisor(time.Minute)
+	err = s.Retry(ctx, "postgres", func(ctx context.Context) error { return errors.New("down") })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSupervisor_ServeHTTP(t *testing.T) {
+	s, _ := newTestSupervisor(time.Minute)
+	require.NoError(t, s.Retry(context.Background(), "postgres", func(ctx context.Context) error { return nil }))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// 启动期间存活探针通过，就绪探针与业务请求返回503
+	w := serve("/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var status Status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, StateStarting, status.Status)
+	assert.Equal(t, "postgres", status.Dependencies[0].Name)
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/ready").Code)
+	w = serve("/api/v1/auth/login")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "SERVICE_STARTING")
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+
+	s.Ready(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	assert.Equal(t, http.StatusTeapot, serve("/health").Code)
+	assert.Equal(t, StateReady, s.Status().Status)
+}
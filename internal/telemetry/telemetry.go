@@ -0,0 +1,258 @@
+// Package telemetry 匿名使用统计
+// 只汇总各接口的调用次数与延迟分布，不记录问题文本、SQL、用户或连接信息。
+// 统计按批次上报，下一批将要发送的内容可通过管理接口预先查看；默认关闭，需显式开启
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// LatencyBucketsMs 延迟分布的桶上界（毫秒），最后一个桶统计超过最大上界的请求
+var LatencyBucketsMs = []float64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// sendTimeout 单次上报的超时时间
+const sendTimeout = 10 * time.Second
+
+// Histogram 延迟分布
+type Histogram struct {
+	Counts []int64 `json:"counts"` // 与LatencyBucketsMs对应，多出的最后一项为超过最大上界的请求数
+	Count  int64   `json:"count"`
+	SumMs  float64 `json:"sum_ms"`
+}
+
+// observe 记录一次耗时
+func (h *Histogram) observe(ms float64) {
+	i := sort.SearchFloat64s(LatencyBucketsMs, ms)
+	h.Counts[i]++
+	h.Count++
+	h.SumMs += ms
+}
+
+// merge 合并另一个分布
+func (h *Histogram) merge(other *Histogram) {
+	for i, n := range other.Counts {
+		h.Counts[i] += n
+	}
+	h.Count += other.Count
+	h.SumMs += other.SumMs
+}
+
+// newHistogram 创建空的延迟分布
+func newHistogram() *Histogram {
+	return &Histogram{Counts: make([]int64, len(LatencyBucketsMs)+1)}
+}
+
+// Batch 一个上报批次，即实际发送的全部内容
+type Batch struct {
+	InstanceID  string                `json:"instance_id"` // 进程启动时随机生成，重启后变化，不与部署或用户关联
+	Version     string                `json:"version"`
+	PeriodStart time.Time             `json:"period_start"`
+	PeriodEnd   time.Time             `json:"period_end"`
+	Features    map[string]int64      `json:"features"`           // 键为"方法 路由模板"，如"POST /api/v1/ai/chat2sql"
+	Latency     map[string]*Histogram `json:"latency"`            // 键与Features相同
+	BucketsMs   []float64             `json:"latency_buckets_ms"` // 延迟分布的桶上界
+}
+
+// Status 统计状态
+type Status struct {
+	Endpoint      string     `json:"endpoint,omitempty"` // 为空表示只在本地汇总，不发送
+	FlushInterval string     `json:"flush_interval"`
+	LastSentAt    *time.Time `json:"last_sent_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Pending       *Batch     `json:"pending"` // 下一次上报将发送的内容
+}
+
+// Collector 匿名使用统计收集器
+type Collector struct {
+	config     *config.TelemetryConfig
+	version    string
+	instanceID string
+	client     *http.Client
+	logger     *zap.Logger
+
+	mu          sync.Mutex
+	periodStart time.Time
+	features    map[string]int64
+	latency     map[string]*Histogram
+	lastSentAt  *time.Time
+	lastError   string
+}
+
+// NewCollector 创建匿名使用统计收集器
+func NewCollector(telemetryConfig *config.TelemetryConfig, version string, logger *zap.Logger) *Collector {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	return &Collector{
+		config:      telemetryConfig,
+		version:     version,
+		instanceID:  hex.EncodeToString(id),
+		client:      &http.Client{Timeout: sendTimeout},
+		logger:      logger,
+		periodStart: time.Now(),
+		features:    make(map[string]int64),
+		latency:     make(map[string]*Histogram),
+	}
+}
+
+// Middleware 按路由模板统计调用次数与延迟
+// 只使用路由模板而不是实际路径，路径参数中的ID等不会进入统计；未匹配路由的请求不统计
+func (c *Collector) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			return
+		}
+		c.Record(ctx.Request.Method+" "+route, time.Since(start))
+	}
+}
+
+// Record 记录一次功能使用及其耗时
+func (c *Collector) Record(feature string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.features[feature]++
+	h, ok := c.latency[feature]
+	if !ok {
+		h = newHistogram()
+		c.latency[feature] = h
+	}
+	h.observe(float64(duration.Microseconds()) / 1000)
+}
+
+// Status 返回统计状态与下一次上报将发送的内容
+func (c *Collector) Status() *Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &Status{
+		Endpoint:      c.config.Endpoint,
+		FlushInterval: c.config.FlushInterval.String(),
+		LastSentAt:    c.lastSentAt,
+		LastError:     c.lastError,
+		Pending:       c.batchLocked(time.Now()),
+	}
+}
+
+// Flush 上报当前批次，未配置上报地址或批次为空时不发送
+// 发送失败时批次内容合并回去，在下一次上报时重试
+func (c *Collector) Flush(ctx context.Context) error {
+	if c.config.Endpoint == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	if len(c.features) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	batch := c.batchLocked(time.Now())
+	c.periodStart = batch.PeriodEnd
+	c.features = make(map[string]int64)
+	c.latency = make(map[string]*Histogram)
+	c.mu.Unlock()
+
+	err := c.send(ctx, batch)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.lastError = err.Error()
+		c.periodStart = batch.PeriodStart
+		for feature, n := range batch.Features {
+			c.features[feature] += n
+		}
+		for feature, h := range batch.Latency {
+			if current, ok := c.latency[feature]; ok {
+				h.merge(current)
+			}
+			c.latency[feature] = h
+		}
+		return err
+	}
+	sentAt := time.Now()
+	c.lastSentAt = &sentAt
+	c.lastError = ""
+	return nil
+}
+
+// Run 按上报间隔定期上报，由看门狗托管
+func (c *Collector) Run(ctx context.Context, beat func()) {
+	ticker := time.NewTicker(c.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(ctx); err != nil {
+				c.logger.Warn("Failed to send telemetry batch", zap.Error(err))
+			}
+			beat()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// send 发送一个批次
+func (c *Collector) send(ctx context.Context, batch *Batch) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// batchLocked 复制当前汇总为批次，调用方需持有锁
+func (c *Collector) batchLocked(now time.Time) *Batch {
+	batch := &Batch{
+		InstanceID:  c.instanceID,
+		Version:     c.version,
+		PeriodStart: c.periodStart,
+		PeriodEnd:   now,
+		Features:    make(map[string]int64, len(c.features)),
+		Latency:     make(map[string]*Histogram, len(c.latency)),
+		BucketsMs:   LatencyBucketsMs,
+	}
+	for feature, n := range c.features {
+		batch.Features[feature] = n
+	}
+	for feature, h := range c.latency {
+		copied := newHistogram()
+		copied.merge(h)
+		batch.Latency[feature] = copied
+	}
+	return batch
+}
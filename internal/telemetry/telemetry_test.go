@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+func TestCollector_MiddlewareRecordsRouteTemplates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := NewCollector(config.DefaultTelemetryConfig(), "1.2.0", zap.NewNop())
+
+	r := gin.New()
+	r.Use(c.Middleware())
+	r.GET("/api/v1/sql/history/:id", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	for _, path := range []string{"/api/v1/sql/history/1", "/api/v1/sql/history/2", "/api/v1/unknown?q=每月销售额"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	pending := c.Status().Pending
+	assert.Equal(t, map[string]int64{"GET /api/v1/sql/history/:id": 2}, pending.Features,
+		"只按路由模板统计，路径参数与未匹配的路径不进入统计")
+	assert.Equal(t, int64(2), pending.Latency["GET /api/v1/sql/history/:id"].Count)
+	assert.Equal(t, "1.2.0", pending.Version)
+
+	body, err := json.Marshal(pending)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "每月销售额")
+	assert.NotContains(t, string(body), "/history/1")
+}
+
+func TestCollector_Flush(t *testing.T) {
+	var received []*Batch
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch Batch
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		received = append(received, &batch)
+	}))
+	defer server.Close()
+
+	cfg := config.DefaultTelemetryConfig()
+	cfg.Enabled = true
+	cfg.Endpoint = server.URL
+	c := NewCollector(cfg, "1.2.0", zap.NewNop())
+
+	c.Record("POST /api/v1/ai/chat2sql", 30*time.Millisecond)
+	c.Record("POST /api/v1/ai/chat2sql", 20*time.Second)
+
+	err := c.Flush(context.Background())
+	require.Error(t, err)
+	status := c.Status()
+	assert.Contains(t, status.LastError, "503")
+	assert.Equal(t, int64(2), status.Pending.Features["POST /api/v1/ai/chat2sql"], "发送失败时保留批次")
+
+	fail = false
+	c.Record("POST /api/v1/ai/chat2sql", 300*time.Millisecond)
+	require.NoError(t, c.Flush(context.Background()))
+	require.Len(t, received, 1)
+	assert.Equal(t, int64(3), received[0].Features["POST /api/v1/ai/chat2sql"])
+	latency := received[0].Latency["POST /api/v1/ai/chat2sql"]
+	assert.Equal(t, []int64{0, 1, 0, 0, 1, 0, 0, 0, 0, 1}, latency.Counts)
+
+	status = c.Status()
+	assert.NotNil(t, status.LastSentAt)
+	assert.Empty(t, status.LastError)
+	assert.Empty(t, status.Pending.Features, "发送成功后开始新的批次")
+
+	require.NoError(t, c.Flush(context.Background()))
+	assert.Len(t, received, 1, "空批次不发送")
+}
+
+func TestCollector_LocalOnly(t *testing.T) {
+	cfg := config.DefaultTelemetryConfig()
+	cfg.Enabled = true
+	c := NewCollector(cfg, "1.2.0", zap.NewNop())
+
+	c.Record("GET /api/v1/connections", time.Millisecond)
+	require.NoError(t, c.Flush(context.Background()))
+	assert.Equal(t, int64(1), c.Status().Pending.Features["GET /api/v1/connections"],
+		"未配置上报地址时只在本地汇总")
+	assert.Empty(t, c.Status().Endpoint)
+}
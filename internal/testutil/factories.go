@@ -0,0 +1,210 @@
+// Package testutil 测试数据工厂与场景夹具
+// 统一构造User、DatabaseConnection、QueryHistory、QueryFeedback等模型，
+// 避免在各个测试文件中手写重复的结构体字面量
+package testutil
+
+import (
+	"fmt"
+	"time"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/repository"
+)
+
+// FixedTime 工厂使用的固定时间，保证测试结果可复现
+var FixedTime = time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+
+// baseModel 构造带ID与固定时间戳的BaseModel
+func baseModel(id int64) repository.BaseModel {
+	return repository.BaseModel{
+		ID:         id,
+		CreateTime: FixedTime,
+		UpdateTime: FixedTime,
+	}
+}
+
+// UserOption 用户工厂选项
+type UserOption func(*repository.User)
+
+// WithUsername 设置用户名
+func WithUsername(username string) UserOption {
+	return func(u *repository.User) {
+		u.Username = username
+	}
+}
+
+// WithPasswordHash 设置密码哈希
+func WithPasswordHash(hash string) UserOption {
+	return func(u *repository.User) {
+		u.PasswordHash = hash
+	}
+}
+
+// WithUserRole 设置用户角色
+func WithUserRole(role repository.UserRole) UserOption {
+	return func(u *repository.User) {
+		u.Role = string(role)
+	}
+}
+
+// WithUserStatus 设置用户状态
+func WithUserStatus(status repository.UserStatus) UserOption {
+	return func(u *repository.User) {
+		u.Status = string(status)
+	}
+}
+
+// WithUserEmail 设置用户邮箱
+func WithUserEmail(email string) UserOption {
+	return func(u *repository.User) {
+		u.Email = email
+	}
+}
+
+// NewUser 创建活跃的普通用户，用户名与邮箱由ID派生
+func NewUser(id int64, opts ...UserOption) *repository.User {
+	user := &repository.User{
+		BaseModel:    baseModel(id),
+		Username:     fmt.Sprintf("user%d", id),
+		Email:        fmt.Sprintf("user%d@example.com", id),
+		PasswordHash: "$2a$10$testutil.placeholder.hash",
+		Role:         string(repository.RoleUser),
+		Status:       string(repository.StatusActive),
+	}
+	for _, opt := range opts {
+		opt(user)
+	}
+	return user
+}
+
+// ConnectionOption 数据库连接工厂选项
+type ConnectionOption func(*repository.DatabaseConnection)
+
+// WithConnectionName 设置连接名称
+func WithConnectionName(name string) ConnectionOption {
+	return func(c *repository.DatabaseConnection) {
+		c.Name = name
+	}
+}
+
+// WithConnectionStatus 设置连接状态
+func WithConnectionStatus(status repository.ConnectionStatus) ConnectionOption {
+	return func(c *repository.DatabaseConnection) {
+		c.Status = string(status)
+	}
+}
+
+// WithConnectionDBType 设置数据库类型
+func WithConnectionDBType(dbType repository.DatabaseType) ConnectionOption {
+	return func(c *repository.DatabaseConnection) {
+		c.DBType = string(dbType)
+	}
+}
+
+// NewConnection 创建属于userID的活跃PostgreSQL连接，指向ecommerce_db
+func NewConnection(id, userID int64, opts ...ConnectionOption) *repository.DatabaseConnection {
+	connection := &repository.DatabaseConnection{
+		BaseModel:         baseModel(id),
+		UserID:            userID,
+		Name:              fmt.Sprintf("connection%d", id),
+		Host:              "localhost",
+		Port:              5432,
+		DatabaseName:      "ecommerce_db",
+		Username:          "readonly",
+		PasswordEncrypted: "encrypted-password",
+		DBType:            string(repository.DBTypePostgreSQL),
+		Status:            string(repository.ConnectionActive),
+	}
+	for _, opt := range opts {
+		opt(connection)
+	}
+	return connection
+}
+
+// QueryHistoryOption 查询历史工厂选项
+type QueryHistoryOption func(*repository.QueryHistory)
+
+// WithQuery 设置自然语言问题与生成的SQL
+func WithQuery(naturalQuery, sql string) QueryHistoryOption {
+	return func(q *repository.QueryHistory) {
+		q.NaturalQuery = naturalQuery
+		q.GeneratedSQL = sql
+	}
+}
+
+// WithQueryConnection 设置查询使用的连接
+func WithQueryConnection(connectionID int64) QueryHistoryOption {
+	return func(q *repository.QueryHistory) {
+		q.ConnectionID = &connectionID
+	}
+}
+
+// WithQueryError 将查询标记为执行失败
+func WithQueryError(message string) QueryHistoryOption {
+	return func(q *repository.QueryHistory) {
+		q.Status = string(repository.QueryError)
+		q.ErrorMessage = &message
+		q.ResultRows = nil
+	}
+}
+
+// NewQueryHistory 创建执行成功的查询历史记录
+func NewQueryHistory(id, userID int64, opts ...QueryHistoryOption) *repository.QueryHistory {
+	executionTime, resultRows := int32(150), int32(10)
+	history := &repository.QueryHistory{
+		BaseModel:     baseModel(id),
+		UserID:        userID,
+		NaturalQuery:  "查询所有用户信息",
+		GeneratedSQL:  "SELECT * FROM users LIMIT 10",
+		ExecutionTime: &executionTime,
+		ResultRows:    &resultRows,
+		Status:        string(repository.QuerySuccess),
+	}
+	for _, opt := range opts {
+		opt(history)
+	}
+	return history
+}
+
+// QueryFeedbackOption 查询反馈工厂选项
+type QueryFeedbackOption func(*ai.QueryFeedback)
+
+// WithFeedbackIncorrect 将反馈标记为SQL错误
+func WithFeedbackIncorrect(expectedSQL, errorType string) QueryFeedbackOption {
+	return func(f *ai.QueryFeedback) {
+		f.IsCorrect = false
+		f.UserRating = 2
+		f.ExpectedSQL = expectedSQL
+		f.ErrorType = errorType
+	}
+}
+
+// WithFeedbackCategory 设置查询类别与难度
+func WithFeedbackCategory(category ai.QueryCategory, difficulty ai.QueryDifficulty) QueryFeedbackOption {
+	return func(f *ai.QueryFeedback) {
+		f.Category = category
+		f.Difficulty = difficulty
+	}
+}
+
+// NewQueryFeedback 创建一条判定为正确的查询反馈
+func NewQueryFeedback(queryID string, userID int64, opts ...QueryFeedbackOption) *ai.QueryFeedback {
+	feedback := &ai.QueryFeedback{
+		QueryID:        queryID,
+		UserID:         userID,
+		UserQuery:      "统计用户总数",
+		GeneratedSQL:   "SELECT COUNT(*) FROM users",
+		IsCorrect:      true,
+		UserRating:     5,
+		Category:       ai.CategoryAggregation,
+		Difficulty:     ai.DifficultyEasy,
+		ProcessingTime: 800 * time.Millisecond,
+		TokensUsed:     320,
+		ModelUsed:      "gpt-4o-mini",
+		Timestamp:      FixedTime,
+	}
+	for _, opt := range opts {
+		opt(feedback)
+	}
+	return feedback
+}
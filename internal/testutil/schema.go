@@ -0,0 +1,116 @@
+package testutil
+
+import (
+	"chat2sql-go/internal/repository"
+)
+
+// schemaColumn 夹具中的列定义
+type schemaColumn struct {
+	name       string
+	dataType   string
+	nullable   bool
+	primaryKey bool
+	references string // "table.column"，为空表示非外键
+	comment    string
+}
+
+// schemaTable 夹具中的表定义
+type schemaTable struct {
+	name    string
+	comment string
+	columns []schemaColumn
+}
+
+// ecommerceTables 电商场景表结构，与AI集成测试使用的ecommerce_db保持一致
+var ecommerceTables = []schemaTable{
+	{name: "users", comment: "用户表", columns: []schemaColumn{
+		{name: "id", dataType: "bigint", primaryKey: true},
+		{name: "name", dataType: "varchar(100)", comment: "用户名"},
+		{name: "email", dataType: "varchar(255)", comment: "邮箱"},
+		{name: "created_at", dataType: "timestamptz"},
+		{name: "status", dataType: "varchar(20)", comment: "状态：active/inactive"},
+	}},
+	{name: "categories", comment: "分类表", columns: []schemaColumn{
+		{name: "id", dataType: "bigint", primaryKey: true},
+		{name: "name", dataType: "varchar(100)", comment: "分类名称"},
+		{name: "description", dataType: "text", nullable: true},
+	}},
+	{name: "products", comment: "产品表", columns: []schemaColumn{
+		{name: "id", dataType: "bigint", primaryKey: true},
+		{name: "name", dataType: "varchar(200)", comment: "产品名称"},
+		{name: "price", dataType: "numeric(10,2)", comment: "单价"},
+		{name: "category_id", dataType: "bigint", references: "categories.id"},
+		{name: "stock_quantity", dataType: "integer", comment: "库存数量"},
+		{name: "created_at", dataType: "timestamptz"},
+	}},
+	{name: "orders", comment: "订单表", columns: []schemaColumn{
+		{name: "id", dataType: "bigint", primaryKey: true},
+		{name: "user_id", dataType: "bigint", references: "users.id"},
+		{name: "total_amount", dataType: "numeric(12,2)", comment: "订单总金额"},
+		{name: "order_status", dataType: "varchar(20)", comment: "订单状态：pending/paid/shipped/cancelled"},
+		{name: "created_at", dataType: "timestamptz"},
+	}},
+	{name: "order_items", comment: "订单项表", columns: []schemaColumn{
+		{name: "id", dataType: "bigint", primaryKey: true},
+		{name: "order_id", dataType: "bigint", references: "orders.id"},
+		{name: "product_id", dataType: "bigint", references: "products.id"},
+		{name: "quantity", dataType: "integer", comment: "购买数量"},
+		{name: "price", dataType: "numeric(10,2)", comment: "成交单价"},
+	}},
+}
+
+// EcommerceTables 电商夹具包含的表名
+func EcommerceTables() []string {
+	names := make([]string, len(ecommerceTables))
+	for i, table := range ecommerceTables {
+		names[i] = table.name
+	}
+	return names
+}
+
+// EcommerceSchema 返回电商场景的表结构元数据（public模式），绑定到指定连接
+func EcommerceSchema(connectionID int64) []*repository.SchemaMetadata {
+	var metadata []*repository.SchemaMetadata
+	var id int64
+
+	for _, table := range ecommerceTables {
+		tableComment := table.comment
+		for position, column := range table.columns {
+			id++
+			meta := &repository.SchemaMetadata{
+				BaseModel:       baseModel(id),
+				ConnectionID:    connectionID,
+				SchemaName:      "public",
+				TableName:       table.name,
+				ColumnName:      column.name,
+				DataType:        column.dataType,
+				IsNullable:      column.nullable,
+				IsPrimaryKey:    column.primaryKey,
+				TableComment:    &tableComment,
+				OrdinalPosition: int32(position + 1),
+			}
+			if column.comment != "" {
+				comment := column.comment
+				meta.ColumnComment = &comment
+			}
+			if column.references != "" {
+				foreignTable, foreignColumn := splitReference(column.references)
+				meta.IsForeignKey = true
+				meta.ForeignTable = &foreignTable
+				meta.ForeignColumn = &foreignColumn
+			}
+			metadata = append(metadata, meta)
+		}
+	}
+	return metadata
+}
+
+// splitReference 拆分"table.column"形式的外键引用
+func splitReference(ref string) (string, string) {
+	for i := len(ref) - 1; i >= 0; i-- {
+		if ref[i] == '.' {
+			return ref[:i], ref[i+1:]
+		}
+	}
+	return ref, ""
+}
@@ -0,0 +1,64 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/internal/ai"
+	"chat2sql-go/internal/repository"
+)
+
+func TestFactories_Defaults(t *testing.T) {
+	user := NewUser(7)
+	assert.Equal(t, int64(7), user.ID)
+	assert.Equal(t, "user7@example.com", user.Email)
+	assert.True(t, user.IsActive())
+
+	connection := NewConnection(3, 7)
+	assert.Equal(t, int64(7), connection.UserID)
+	assert.Equal(t, string(repository.DBTypePostgreSQL), connection.DBType)
+
+	history := NewQueryHistory(1, 7)
+	assert.Equal(t, string(repository.QuerySuccess), history.Status)
+	require.NotNil(t, history.ResultRows)
+
+	feedback := NewQueryFeedback("q-1", 7)
+	assert.True(t, feedback.IsCorrect)
+}
+
+func TestFactories_Options(t *testing.T) {
+	user := NewUser(1, WithUserRole(repository.RoleAdmin), WithUserStatus(repository.StatusLocked))
+	assert.Equal(t, "admin", user.Role)
+	assert.False(t, user.IsActive())
+
+	connection := NewConnection(2, 1, WithConnectionName("warehouse"), WithConnectionStatus(repository.ConnectionError))
+	assert.Equal(t, "warehouse", connection.Name)
+	assert.Equal(t, "error", connection.Status)
+
+	history := NewQueryHistory(3, 1, WithQueryConnection(2), WithQueryError("timeout"))
+	assert.Equal(t, int64(2), *history.ConnectionID)
+	assert.Equal(t, "timeout", *history.ErrorMessage)
+	assert.Nil(t, history.ResultRows)
+
+	feedback := NewQueryFeedback("q-2", 1, WithFeedbackIncorrect("SELECT 1", "syntax"), WithFeedbackCategory(ai.CategoryJoinQuery, ai.DifficultyHard))
+	assert.False(t, feedback.IsCorrect)
+	assert.Equal(t, ai.CategoryJoinQuery, feedback.Category)
+}
+
+func TestEcommerceSchema(t *testing.T) {
+	schema := EcommerceSchema(5)
+	assert.Equal(t, []string{"users", "categories", "products", "orders", "order_items"}, EcommerceTables())
+
+	var foreignKeys int
+	for _, column := range schema {
+		assert.Equal(t, int64(5), column.ConnectionID)
+		if column.IsForeignKey {
+			foreignKeys++
+			require.NotNil(t, column.ForeignTable)
+			assert.Contains(t, EcommerceTables(), *column.ForeignTable)
+		}
+	}
+	assert.Equal(t, 4, foreignKeys)
+}
@@ -0,0 +1,113 @@
+// Package tracing OpenTelemetry分布式追踪
+// 一次请求生成一条trace：HTTP请求下依次为SQL生成、意图分析、模型调用、SQL校验与数据库执行的span。
+// 未开启时全局TracerProvider为空实现，Start返回不记录的span，调用方无需判断是否开启；
+// span不记录问题文本、SQL与查询结果，只记录连接、模型、状态与行数等属性
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// instrumentationName 本服务创建span时使用的tracer名称
+const instrumentationName = "chat2sql-go"
+
+// TraceIDHeader 响应中返回trace ID的响应头，便于按ID在追踪后端查找请求
+const TraceIDHeader = "X-Trace-ID"
+
+// Setup 开启追踪：创建OTLP/HTTP导出器，设置全局TracerProvider与W3C TraceContext传播
+// 返回的关闭函数导出剩余的span；未开启时不做任何设置，关闭函数为空操作
+func Setup(ctx context.Context, cfg *config.TracingConfig, version string, logger *zap.Logger) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("创建OTLP导出器失败: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName), semconv.ServiceVersion(version)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建追踪资源失败: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	// 导出失败等内部错误默认写标准错误，改为写入服务日志
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("OpenTelemetry error", zap.Error(err))
+	}))
+
+	return provider.Shutdown, nil
+}
+
+// Start 在ctx中的span下创建子span，调用方负责结束
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 结束span，err非nil时记录错误并标记为失败
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Middleware 为每个请求创建服务端span，请求头带traceparent时延续上游的trace
+// span以请求方法与路由模板命名，不记录路径参数、查询参数与请求体；5xx响应标记为失败
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(c.Request.Method), semconv.HTTPRoute(route)))
+		defer span.End()
+
+		if span.SpanContext().IsSampled() {
+			c.Header(TraceIDHeader, span.SpanContext().TraceID().String())
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if requestID := c.GetString("request_id"); requestID != "" {
+			span.SetAttributes(attribute.String("chat2sql.request_id", requestID))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
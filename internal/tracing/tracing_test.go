@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+)
+
+// recordSpans 把全局TracerProvider替换为记录span的实现，测试结束后恢复
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestMiddleware_SpansForRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := recordSpans(t)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("request_id", "req-1") })
+	r.Use(Middleware())
+	r.GET("/api/v1/sql/history/:id", func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), "db.query", attribute.Int64("chat2sql.connection_id", 3))
+		End(span, errors.New("连接超时"))
+		c.Status(http.StatusBadGateway)
+	})
+
+	// 上游请求已采样，服务端span延续同一条trace
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/sql/history/7?q=每月销售额", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+
+	assert.Equal(t, "GET /api/v1/sql/history/:id", server.Name(), "按路由模板命名，不含路径参数")
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, codes.Error, server.Status().Code)
+	assert.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", http.StatusBadGateway))
+	assert.Contains(t, server.Attributes(), attribute.String("chat2sql.request_id", "req-1"))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", w.Header().Get(TraceIDHeader))
+
+	assert.Equal(t, "db.query", child.Name())
+	assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Equal(t, codes.Error, child.Status().Code)
+	assert.Equal(t, "连接超时", child.Status().Description)
+	require.Len(t, child.Events(), 1, "错误记录为span事件")
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	previous := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), config.DefaultTracingConfig(), "1.0.0", zap.NewNop())
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Equal(t, previous, otel.GetTracerProvider(), "未开启时不替换全局TracerProvider")
+}
@@ -0,0 +1,252 @@
+// Package watchdog 后台任务心跳与看门狗
+// 后台任务每轮循环上报心跳，看门狗定期检查：超过期望间隔StallFactor倍仍未上报的任务视为卡住，
+// 取消其上下文后重新启动；任务意外退出或panic同样重新启动。最近心跳时间与重启次数以Prometheus指标暴露，
+// 使后台任务静默失败可以被告警发现
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"chat2sql-go/internal/config"
+	"chat2sql-go/internal/crash"
+)
+
+// Worker 受看门狗托管的后台任务
+// 阻塞运行直到ctx取消，每完成一轮循环调用beat上报心跳
+type Worker func(ctx context.Context, beat func())
+
+// WorkerStatus 后台任务状态
+type WorkerStatus struct {
+	Name          string        `json:"name"`
+	Interval      time.Duration `json:"interval"`
+	LastHeartbeat time.Time     `json:"last_heartbeat"`
+	Restarts      int           `json:"restarts"`
+}
+
+// worker 已注册的后台任务
+type worker struct {
+	name     string
+	interval time.Duration
+	run      Worker
+
+	generation int // 每次启动加1，旧实例的心跳被忽略
+	lastBeat   time.Time
+	restarts   int
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// Watchdog 后台任务看门狗
+type Watchdog struct {
+	config *config.WatchdogConfig
+	logger *zap.Logger
+
+	lastHeartbeat *prometheus.GaugeVec
+	restartsTotal *prometheus.CounterVec
+
+	mu      sync.Mutex
+	workers map[string]*worker
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// New 创建看门狗，registerer为nil时不注册Prometheus指标
+func New(cfg *config.WatchdogConfig, registerer prometheus.Registerer, logger *zap.Logger) *Watchdog {
+	if cfg == nil {
+		cfg = config.DefaultWatchdogConfig()
+	}
+
+	w := &Watchdog{
+		config:  cfg,
+		logger:  logger,
+		workers: make(map[string]*worker),
+		lastHeartbeat: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "chat2sql",
+			Subsystem: "worker",
+			Name:      "last_heartbeat_timestamp_seconds",
+			Help:      "Unix time of the last heartbeat reported by a background worker",
+		}, []string{"worker"}),
+		restartsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chat2sql",
+			Subsystem: "worker",
+			Name:      "restarts_total",
+			Help:      "Number of times the watchdog restarted a stalled or exited background worker",
+		}, []string{"worker"}),
+	}
+	if registerer != nil {
+		registerer.MustRegister(w.lastHeartbeat, w.restartsTotal)
+	}
+	return w
+}
+
+// Register 注册后台任务，interval为任务正常情况下两次心跳的最大间隔
+// 需要在Start之前调用
+func (w *Watchdog) Register(name string, interval time.Duration, run Worker) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.workers[name] = &worker{name: name, interval: interval, run: run}
+}
+
+// Start 启动全部已注册的后台任务与心跳检查
+// 后台任务的上下文不继承ctx，只由Stop取消
+func (w *Watchdog) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx != nil {
+		return errors.New("watchdog already started")
+	}
+
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	w.done = make(chan struct{})
+	for _, wk := range w.workers {
+		w.launch(wk)
+	}
+	go w.checkLoop()
+
+	w.logger.Info("Watchdog started",
+		zap.Int("workers", len(w.workers)),
+		zap.Duration("check_interval", w.config.CheckInterval))
+	return nil
+}
+
+// Stop 取消全部后台任务并等待其退出，卡住未退出的任务在ctx到期后放弃等待
+func (w *Watchdog) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	if w.ctx == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	w.cancel()
+	pending := make(map[string]chan struct{}, len(w.workers))
+	for name, wk := range w.workers {
+		pending[name] = wk.done
+	}
+	done := w.done
+	w.mu.Unlock()
+
+	<-done
+	var stuck []string
+	for name, workerDone := range pending {
+		select {
+		case <-workerDone:
+		case <-ctx.Done():
+			stuck = append(stuck, name)
+		}
+	}
+	if len(stuck) > 0 {
+		sort.Strings(stuck)
+		return fmt.Errorf("workers did not stop: %v", stuck)
+	}
+	return nil
+}
+
+// Status 返回全部后台任务的心跳状态，按名称排序
+func (w *Watchdog) Status() []WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	statuses := make([]WorkerStatus, 0, len(w.workers))
+	for _, wk := range w.workers {
+		statuses = append(statuses, WorkerStatus{
+			Name:          wk.name,
+			Interval:      wk.interval,
+			LastHeartbeat: wk.lastBeat,
+			Restarts:      wk.restarts,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// launch 启动后台任务的新实例，调用方需持有锁
+// 启动时记一次心跳，使刚启动的任务有完整的间隔完成第一轮循环
+func (w *Watchdog) launch(wk *worker) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	done := make(chan struct{})
+	wk.generation++
+	wk.cancel = cancel
+	wk.done = done
+
+	generation := wk.generation
+	w.recordBeat(wk)
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		crash.Run("watchdog."+wk.name, func() {
+			wk.run(ctx, func() { w.beat(wk, generation) })
+		})
+	}()
+}
+
+// beat 记录心跳，已被替换的旧实例的心跳忽略
+func (w *Watchdog) beat(wk *worker, generation int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if wk.generation == generation {
+		w.recordBeat(wk)
+	}
+}
+
+// recordBeat 更新最近心跳时间与指标，调用方需持有锁
+func (w *Watchdog) recordBeat(wk *worker) {
+	wk.lastBeat = time.Now()
+	w.lastHeartbeat.WithLabelValues(wk.name).Set(float64(wk.lastBeat.Unix()))
+}
+
+// checkLoop 定期检查心跳，直到看门狗停止
+func (w *Watchdog) checkLoop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check 重启已退出或心跳超时的后台任务
+func (w *Watchdog) check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, wk := range w.workers {
+		select {
+		case <-wk.done:
+			w.logger.Error("Background worker exited unexpectedly, restarting", zap.String("worker", wk.name))
+		default:
+			silence := now.Sub(wk.lastBeat)
+			if silence <= wk.interval*time.Duration(w.config.StallFactor) {
+				continue
+			}
+			// 卡住的实例可能不响应取消，不等待其退出，其后续心跳按generation忽略
+			w.logger.Error("Background worker stalled, restarting",
+				zap.String("worker", wk.name),
+				zap.Duration("since_last_heartbeat", silence),
+				zap.Duration("expected_interval", wk.interval))
+			wk.cancel()
+		}
+
+		wk.restarts++
+		w.restartsTotal.WithLabelValues(wk.name).Inc()
+		w.launch(wk)
+	}
+}
@@ -0,0 +1,82 @@
+package watchdog
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"chat2sql-go/internal/config"
+)
+
+func newTestWatchdog(t *testing.T) *Watchdog {
+	return New(&config.WatchdogConfig{CheckInterval: 5 * time.Millisecond, StallFactor: 2}, prometheus.NewRegistry(), zaptest.NewLogger(t))
+}
+
+// tickingWorker 每个interval上报一次心跳，stuck为true时停止上报并忽略取消
+func tickingWorker(interval time.Duration, starts *int32, stuck *atomic.Bool) Worker {
+	return func(ctx context.Context, beat func()) {
+		atomic.AddInt32(starts, 1)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if stuck.Load() {
+				time.Sleep(50 * time.Millisecond)
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				beat()
+			}
+		}
+	}
+}
+
+func TestWatchdog_RestartsStalledWorker(t *testing.T) {
+	w := newTestWatchdog(t)
+	var starts int32
+	var stuck atomic.Bool
+	w.Register("learning_engine", 10*time.Millisecond, tickingWorker(2*time.Millisecond, &starts, &stuck))
+	require.NoError(t, w.Start(context.Background()))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&starts), "正常上报心跳的任务不应重启")
+
+	stuck.Store(true)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&starts) > 1 }, time.Second, 5*time.Millisecond)
+	stuck.Store(false)
+
+	status := w.Status()
+	require.Len(t, status, 1)
+	assert.GreaterOrEqual(t, status[0].Restarts, 1)
+	assert.GreaterOrEqual(t, testutil.ToFloat64(w.restartsTotal.WithLabelValues("learning_engine")), float64(1))
+	assert.Greater(t, testutil.ToFloat64(w.lastHeartbeat.WithLabelValues("learning_engine")), float64(0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, w.Stop(ctx))
+}
+
+func TestWatchdog_RestartsExitedWorker(t *testing.T) {
+	w := newTestWatchdog(t)
+	var runs int32
+	w.Register("system_monitor", time.Hour, func(ctx context.Context, beat func()) {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			panic("collector crashed")
+		}
+		<-ctx.Done()
+	})
+	require.NoError(t, w.Start(context.Background()))
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&runs) == 2 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, w.Stop(context.Background()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs), "停止后不再重启")
+}
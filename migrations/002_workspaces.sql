@@ -0,0 +1,61 @@
+-- ========================================
+-- Chat2SQL - 工作空间与自动执行策略
+-- ========================================
+-- 工作空间是团队级配置边界，成员共享自动执行策略等设置
+-- 未加入任何工作空间的用户归属于默认工作空间(id=1)
+
+-- ========================================
+-- 1. 工作空间表
+-- ========================================
+CREATE TABLE IF NOT EXISTS workspaces (
+    id              BIGSERIAL PRIMARY KEY,
+    name            VARCHAR(100) NOT NULL,
+    description     TEXT,
+    -- 自动执行策略：{"enabled":bool,"min_confidence":0-1,"max_estimated_cost":number}
+    -- 为空表示生成的SQL始终需要用户确认
+    auto_execute_policy JSONB,
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL,
+
+    CONSTRAINT unique_workspace_name UNIQUE (name)
+);
+
+-- ========================================
+-- 2. 工作空间成员表
+-- ========================================
+-- 每个用户至多属于一个工作空间
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id    BIGINT NOT NULL REFERENCES workspaces(id),
+    user_id         BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (workspace_id, user_id),
+    CONSTRAINT unique_workspace_member_user UNIQUE (user_id)
+);
+
+-- ========================================
+-- 3. 查询历史执行路径
+-- ========================================
+-- 记录SQL是自动执行、确认后执行还是用户直接执行，用于事后分析自动执行准确率
+ALTER TABLE query_history ADD COLUMN IF NOT EXISTS execution_path VARCHAR(20)
+    CHECK (execution_path IN ('auto', 'confirmed', 'manual'));
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_query_history_execution_path
+    ON query_history(execution_path, create_time DESC) WHERE execution_path IS NOT NULL AND is_deleted = FALSE;
+
+CREATE TRIGGER tr_workspaces_update_time
+    BEFORE UPDATE ON workspaces
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
+
+-- 默认工作空间，自动执行策略默认关闭
+INSERT INTO workspaces (id, name, description, auto_execute_policy, create_by, update_by)
+VALUES (1, 'default', '默认工作空间', '{"enabled":false,"min_confidence":0.8,"max_estimated_cost":1000}', 1, 1)
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval('workspaces_id_seq', GREATEST((SELECT MAX(id) FROM workspaces), 1));
@@ -0,0 +1,70 @@
+-- ========================================
+-- Chat2SQL - 受控写模式
+-- ========================================
+-- 默认所有连接只读。显式开启写模式的连接可以生成INSERT/UPDATE，
+-- 但必须先在回滚的事务中预演，再由申请人以外的用户审批后才会执行，全程记录审计事件
+
+-- ========================================
+-- 1. 连接写模式开关
+-- ========================================
+ALTER TABLE database_connections ADD COLUMN IF NOT EXISTS write_mode_enabled BOOLEAN DEFAULT FALSE NOT NULL;
+
+-- ========================================
+-- 2. 写操作申请表
+-- ========================================
+CREATE TABLE IF NOT EXISTS write_requests (
+    id              BIGSERIAL PRIMARY KEY,
+    connection_id   BIGINT NOT NULL REFERENCES database_connections(id),
+    requested_by    BIGINT NOT NULL REFERENCES users(id),
+    natural_query   TEXT,
+    sql             TEXT NOT NULL,
+    statement_type  VARCHAR(10) NOT NULL CHECK (statement_type IN ('INSERT', 'UPDATE')),
+    estimated_rows  BIGINT NOT NULL DEFAULT 0,
+    -- 预演结果：{"columns":[...],"rows":[...],"truncated":bool}
+    preview         JSONB,
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'approved', 'rejected', 'executed', 'failed')),
+    reviewed_by     BIGINT REFERENCES users(id),
+    reviewed_time   TIMESTAMP WITH TIME ZONE,
+    review_comment  TEXT,
+    affected_rows   BIGINT,
+    error_message   TEXT,
+    executed_time   TIMESTAMP WITH TIME ZONE,
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL,
+
+    -- 审批人不能是申请人
+    CONSTRAINT check_write_request_reviewer CHECK (reviewed_by IS NULL OR reviewed_by <> requested_by)
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_write_requests_status
+    ON write_requests(status, create_time DESC) WHERE is_deleted = FALSE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_write_requests_connection
+    ON write_requests(connection_id, create_time DESC) WHERE is_deleted = FALSE;
+
+CREATE TRIGGER tr_write_requests_update_time
+    BEFORE UPDATE ON write_requests
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
+
+-- ========================================
+-- 3. 写操作审计事件表
+-- ========================================
+-- 仅追加，不更新不删除
+CREATE TABLE IF NOT EXISTS write_request_events (
+    id              BIGSERIAL PRIMARY KEY,
+    request_id      BIGINT NOT NULL REFERENCES write_requests(id),
+    actor_id        BIGINT NOT NULL REFERENCES users(id),
+    action          VARCHAR(20) NOT NULL
+                    CHECK (action IN ('created', 'approved', 'rejected', 'executed', 'failed')),
+    detail          TEXT,
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_write_request_events_request
+    ON write_request_events(request_id, create_time);
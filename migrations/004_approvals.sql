@@ -0,0 +1,88 @@
+-- ========================================
+-- Chat2SQL - 审批流程
+-- ========================================
+-- 将写模式的审批抽象为通用审批流程：写操作、策略变更、预算上调等敏感动作先创建审批申请，
+-- 由指定审批人（未指定时为manager/admin）通过或驳回，超过有效期自动失效，全程记录审计事件
+
+-- ========================================
+-- 1. 审批申请表
+-- ========================================
+CREATE TABLE IF NOT EXISTS approval_requests (
+    id               BIGSERIAL PRIMARY KEY,
+    action_type      VARCHAR(30) NOT NULL
+                     CHECK (action_type IN ('write_query', 'policy_change', 'budget_raise')),
+    resource_id      BIGINT,
+    summary          TEXT NOT NULL,
+    -- 动作参数，由对应执行器解析
+    payload          JSONB,
+    requested_by     BIGINT NOT NULL REFERENCES users(id),
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending'
+                     CHECK (status IN ('pending', 'approved', 'rejected', 'expired', 'executed', 'failed')),
+    decided_by       BIGINT REFERENCES users(id),
+    decided_time     TIMESTAMP WITH TIME ZONE,
+    decision_comment TEXT,
+    error_message    TEXT,
+    expires_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    -- 统一基础字段
+    create_by        BIGINT NOT NULL REFERENCES users(id),
+    create_time      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by        BIGINT NOT NULL REFERENCES users(id),
+    update_time      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted       BOOLEAN DEFAULT FALSE NOT NULL,
+
+    -- 审批人不能是申请人
+    CONSTRAINT check_approval_decider CHECK (decided_by IS NULL OR decided_by <> requested_by)
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_approval_requests_status
+    ON approval_requests(status, create_time DESC) WHERE is_deleted = FALSE;
+-- 过期扫描只关心pending申请
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_approval_requests_expiry
+    ON approval_requests(expires_at) WHERE status = 'pending' AND is_deleted = FALSE;
+
+CREATE TRIGGER tr_approval_requests_update_time
+    BEFORE UPDATE ON approval_requests
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
+
+-- ========================================
+-- 2. 审批审计事件表
+-- ========================================
+-- 仅追加，不更新不删除；系统操作（如过期）actor_id为空
+CREATE TABLE IF NOT EXISTS approval_events (
+    id              BIGSERIAL PRIMARY KEY,
+    request_id      BIGINT NOT NULL REFERENCES approval_requests(id),
+    actor_id        BIGINT REFERENCES users(id),
+    action          VARCHAR(20) NOT NULL
+                    CHECK (action IN ('created', 'approved', 'rejected', 'expired', 'executed', 'failed')),
+    detail          TEXT,
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_approval_events_request
+    ON approval_events(request_id, create_time);
+
+-- ========================================
+-- 3. 指定审批人
+-- ========================================
+-- 动作类型未指定审批人时由manager/admin审批
+CREATE TABLE IF NOT EXISTS approval_approvers (
+    action_type     VARCHAR(30) NOT NULL
+                    CHECK (action_type IN ('write_query', 'policy_change', 'budget_raise')),
+    user_id         BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (action_type, user_id)
+);
+
+-- ========================================
+-- 4. 写操作申请接入审批流程
+-- ========================================
+ALTER TABLE write_requests ADD COLUMN IF NOT EXISTS approval_id BIGINT REFERENCES approval_requests(id);
+
+ALTER TABLE write_requests DROP CONSTRAINT IF EXISTS write_requests_status_check;
+ALTER TABLE write_requests ADD CONSTRAINT write_requests_status_check
+    CHECK (status IN ('pending', 'approved', 'rejected', 'expired', 'executed', 'failed'));
+
+-- 审计事件统一记录在approval_events
+DROP TABLE IF EXISTS write_request_events;
@@ -0,0 +1,32 @@
+-- ========================================
+-- Chat2SQL - 列数据分级标签
+-- ========================================
+-- 在数据字典中为列标注PII/财务/内部等分级，策略按分级与用户角色推导默认的脱敏与过滤规则，
+-- 提示词同时提醒模型不要选择当前用户无权查看的受限列。
+-- 标签按连接与列的自然键存储，元数据刷新（重建schema_metadata）后仍然保留
+
+CREATE TABLE IF NOT EXISTS column_classifications (
+    id              BIGSERIAL PRIMARY KEY,
+    connection_id   BIGINT NOT NULL REFERENCES database_connections(id),
+    schema_name     VARCHAR(100) NOT NULL,
+    table_name      VARCHAR(100) NOT NULL,
+    column_name     VARCHAR(100) NOT NULL,
+    classification  VARCHAR(20) NOT NULL CHECK (classification IN ('pii', 'financial', 'internal')),
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL,
+
+    CONSTRAINT uk_column_classification UNIQUE (connection_id, schema_name, table_name, column_name)
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_column_classifications_connection
+    ON column_classifications(connection_id) WHERE is_deleted = FALSE;
+
+CREATE TRIGGER tr_column_classifications_update_time
+    BEFORE UPDATE ON column_classifications
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
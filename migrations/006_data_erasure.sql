@@ -0,0 +1,39 @@
+-- ========================================
+-- Chat2SQL - 个人数据删除（GDPR被遗忘权）
+-- ========================================
+-- 用户本人或管理员提交删除申请，后台任务按申请匿名化或删除该用户的个人数据：
+-- 查询历史与写操作申请中的问题文本、反馈内容、审计记录中由其填写的说明以及对话记忆。
+-- 审计记录中的用户ID保持不变以维持外键与审计链路，用户账号改为假名并停用，
+-- 处理完成后生成删除报告记录各类数据的处理条数
+
+CREATE TABLE IF NOT EXISTS erasure_requests (
+    id              BIGSERIAL PRIMARY KEY,
+    subject_user_id BIGINT NOT NULL REFERENCES users(id),
+    -- anonymize：清除文本保留统计；delete：删除记录
+    mode            VARCHAR(20) NOT NULL CHECK (mode IN ('anonymize', 'delete')),
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    requested_by    BIGINT NOT NULL REFERENCES users(id),
+    -- 删除报告：{"query_history":n,"feedback":n,...,"pseudonym":"erased-..."}
+    report          JSONB,
+    error_message   TEXT,
+    completed_time  TIMESTAMP WITH TIME ZONE,
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL
+);
+
+-- 同一用户同时只能有一个未完成的申请
+CREATE UNIQUE INDEX IF NOT EXISTS uk_erasure_requests_active
+    ON erasure_requests(subject_user_id) WHERE status IN ('pending', 'running') AND is_deleted = FALSE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_erasure_requests_pending
+    ON erasure_requests(create_time) WHERE status = 'pending' AND is_deleted = FALSE;
+
+CREATE TRIGGER tr_erasure_requests_update_time
+    BEFORE UPDATE ON erasure_requests
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
@@ -0,0 +1,9 @@
+-- ========================================
+-- Chat2SQL - 数据驻留
+-- ========================================
+-- 管理员为工作空间指定数据所在区域，并按用途（快照、导出、归档）固定对象存储桶。
+-- 写入对象存储前按策略解析目标位置，目标桶或区域与策略不一致时拒绝写入，避免数据跨区域落地
+
+-- 数据驻留策略：{"region":"eu-central-1","locations":{"export":{"bucket":"...","region":"eu-central-1","prefix":"..."}}}
+-- 为空表示不限制存储区域
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS data_residency JSONB;
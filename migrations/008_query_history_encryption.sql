@@ -0,0 +1,42 @@
+-- ========================================
+-- Chat2SQL - 查询历史静态加密
+-- ========================================
+-- 工作空间可选择在应用层加密query_history中的自然语言问题与生成的SQL。
+-- 每个工作空间使用独立的数据密钥，数据密钥由主密钥（HISTORY_ENCRYPTION_MASTER_KEY）加密后存储；
+-- Repository层写入时加密、读取时解密，业务代码无感知。
+-- 密钥轮换时旧版本标记为retired但保留，用于解密尚未重新加密的记录。
+-- 注意：加密后的记录无法参与全文搜索与热门查询统计，sql_hash仍按明文SQL计算
+
+CREATE TABLE IF NOT EXISTS history_encryption_keys (
+    id              BIGSERIAL PRIMARY KEY,
+    workspace_id    BIGINT NOT NULL REFERENCES workspaces(id),
+    version         INTEGER NOT NULL,
+    -- 主密钥加密后的数据密钥（base64）
+    wrapped_key     TEXT NOT NULL,
+    status          VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'retired')),
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL,
+
+    CONSTRAINT uk_history_encryption_key_version UNIQUE (workspace_id, version)
+);
+
+-- 每个工作空间同时只有一个生效的数据密钥
+CREATE UNIQUE INDEX IF NOT EXISTS uk_history_encryption_keys_active
+    ON history_encryption_keys(workspace_id) WHERE status = 'active' AND is_deleted = FALSE;
+
+CREATE TRIGGER tr_history_encryption_keys_update_time
+    BEFORE UPDATE ON history_encryption_keys
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
+
+-- 加密记录使用的数据密钥，为空表示明文；密钥轮换据此查找需要重新加密的记录
+ALTER TABLE query_history
+    ADD COLUMN IF NOT EXISTS encryption_key_id BIGINT REFERENCES history_encryption_keys(id);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_query_history_encryption_key
+    ON query_history(encryption_key_id) WHERE encryption_key_id IS NOT NULL;
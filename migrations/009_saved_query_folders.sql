@@ -0,0 +1,82 @@
+-- ========================================
+-- Chat2SQL - 保存查询与文件夹
+-- ========================================
+-- 工作空间内的保存查询（含报表）按多级文件夹组织。文件夹记录从根到自身的ID路径，
+-- 移动时一条语句更新整个子树，列表接口据此返回面包屑。
+-- 文件夹权限按用户授予并向下继承：离文件夹最近、设置过授权的祖先决定访问级别，
+-- 整条路径都未设置授权时工作空间成员均可编辑
+
+-- ========================================
+-- 1. 文件夹表
+-- ========================================
+CREATE TABLE IF NOT EXISTS query_folders (
+    id              BIGSERIAL PRIMARY KEY,
+    workspace_id    BIGINT NOT NULL REFERENCES workspaces(id),
+    parent_id       BIGINT REFERENCES query_folders(id),
+    name            VARCHAR(100) NOT NULL,
+    -- 从根到自身的ID路径，如 /1/5/9/
+    path            TEXT NOT NULL,
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL,
+
+    CONSTRAINT check_query_folder_parent CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+-- 同一父文件夹下名称唯一（不区分大小写），根目录的parent_id为空
+CREATE UNIQUE INDEX IF NOT EXISTS uk_query_folders_name
+    ON query_folders(workspace_id, COALESCE(parent_id, 0), lower(name)) WHERE is_deleted = FALSE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_query_folders_path
+    ON query_folders(workspace_id, path text_pattern_ops) WHERE is_deleted = FALSE;
+
+CREATE TRIGGER tr_query_folders_update_time
+    BEFORE UPDATE ON query_folders
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
+
+-- ========================================
+-- 2. 文件夹权限表
+-- ========================================
+CREATE TABLE IF NOT EXISTS query_folder_permissions (
+    folder_id       BIGINT NOT NULL REFERENCES query_folders(id),
+    user_id         BIGINT NOT NULL REFERENCES users(id),
+    level           VARCHAR(10) NOT NULL CHECK (level IN ('view', 'edit', 'manage')),
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (folder_id, user_id)
+);
+
+-- ========================================
+-- 3. 保存查询表
+-- ========================================
+CREATE TABLE IF NOT EXISTS saved_queries (
+    id              BIGSERIAL PRIMARY KEY,
+    workspace_id    BIGINT NOT NULL REFERENCES workspaces(id),
+    -- 为空表示位于根目录
+    folder_id       BIGINT REFERENCES query_folders(id),
+    owner_id        BIGINT NOT NULL REFERENCES users(id),
+    name            VARCHAR(200) NOT NULL,
+    description     TEXT,
+    natural_query   TEXT NOT NULL,
+    sql_text        TEXT NOT NULL,
+    connection_id   BIGINT REFERENCES database_connections(id),
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_saved_queries_folder
+    ON saved_queries(workspace_id, folder_id, name) WHERE is_deleted = FALSE;
+
+CREATE TRIGGER tr_saved_queries_update_time
+    BEFORE UPDATE ON saved_queries
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
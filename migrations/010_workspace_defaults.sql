@@ -0,0 +1,9 @@
+-- ========================================
+-- Chat2SQL - 工作空间默认设置
+-- ========================================
+-- 管理员为工作空间配置默认连接、默认语言与默认返回行数，
+-- 请求未携带这些字段时按所属工作空间的设置补全
+
+-- 默认设置：{"connection_id":1,"locale":"zh","row_limit":200}
+-- 为空表示不设默认值，未指定连接的请求仍然返回参数错误
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS defaults JSONB;
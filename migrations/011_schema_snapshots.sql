@@ -0,0 +1,23 @@
+-- ========================================
+-- Chat2SQL - 表结构快照
+-- ========================================
+-- 生成SQL时提示词中使用的表结构按连接保存为只读快照，查询历史记录生成时所用的快照，
+-- 之后表结构变化也能按当时的结构重现提示词，排查"为什么当时生成了这个JOIN"。
+-- 同一连接内容相同的表结构只保存一次，版本号按首次出现的顺序递增
+
+CREATE TABLE IF NOT EXISTS schema_snapshots (
+    id              BIGSERIAL PRIMARY KEY,
+    connection_id   BIGINT NOT NULL REFERENCES database_connections(id),
+    version         INTEGER NOT NULL,
+    -- schema_text的SHA-256，用于去重
+    schema_hash     VARCHAR(64) NOT NULL,
+    schema_text     TEXT NOT NULL,
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    CONSTRAINT uk_schema_snapshot_version UNIQUE (connection_id, version),
+    CONSTRAINT uk_schema_snapshot_hash UNIQUE (connection_id, schema_hash)
+);
+
+-- 生成SQL时使用的表结构快照，为空表示未记录（如手写SQL或快照功能上线前的记录）
+ALTER TABLE query_history
+    ADD COLUMN IF NOT EXISTS schema_snapshot_id BIGINT REFERENCES schema_snapshots(id);
@@ -0,0 +1,14 @@
+-- ========================================
+-- Chat2SQL - 自然语言问题保留策略
+-- ========================================
+-- 部分团队不希望保存用户原始问题：工作空间可以选择生成SQL后只保存问题的哈希，
+-- 或完全不保存问题，查询历史中只保留问题分类与生成的SQL
+
+-- 问题保留方式：keep=保留原文，hash=只保存SHA-256，drop=不保存
+ALTER TABLE workspaces
+    ADD COLUMN IF NOT EXISTS question_retention VARCHAR(10) NOT NULL DEFAULT 'keep'
+        CHECK (question_retention IN ('keep', 'hash', 'drop'));
+
+-- 写入时按问题关键字归类的问题分类，如aggregation/ranking/time_analysis，
+-- 问题被哈希或丢弃后仍可用于统计
+ALTER TABLE query_history ADD COLUMN IF NOT EXISTS question_category VARCHAR(32);
@@ -0,0 +1,8 @@
+-- ========================================
+-- Chat2SQL - 按延迟SLO路由策略
+-- ========================================
+-- 主模型的滚动P95延迟超过工作空间设定的目标时，交互请求暂时改由更快的备用模型处理，
+-- 批量与定时请求仍使用成本更低的主模型；为空表示不按延迟路由
+
+-- 延迟路由策略：{"enabled": true, "primary_slo_ms": 3000}
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS latency_routing JSONB;
@@ -0,0 +1,9 @@
+-- ========================================
+-- Chat2SQL - SQLite连接
+-- ========================================
+-- SQLite连接读取服务端SQLITE_DATA_DIR下的数据库文件，database_name保存文件的相对路径，
+-- 没有主机与端口：host保存为localhost，port保存为0
+
+ALTER TABLE database_connections DROP CONSTRAINT IF EXISTS database_connections_port_check;
+ALTER TABLE database_connections ADD CONSTRAINT database_connections_port_check
+    CHECK (port >= 0 AND port <= 65535 AND (port > 0 OR db_type = 'sqlite'));
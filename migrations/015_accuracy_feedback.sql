@@ -0,0 +1,38 @@
+-- ========================================
+-- Chat2SQL - AI准确率反馈持久化
+-- ========================================
+-- 准确率监控的用户反馈原先只保存在内存中，重启后丢失且随时间无限增长。
+-- 反馈写入feedbacks表，监控器启动时加载保留期内的反馈重建日、用户、模型与类别统计，
+-- 超过保留天数（data_retention_days）的反馈由清理任务物理删除
+
+CREATE TABLE IF NOT EXISTS feedbacks (
+    id              BIGSERIAL PRIMARY KEY,
+    query_id        VARCHAR(100) NOT NULL,             -- 查询ID，关联到具体的查询请求
+    user_id         BIGINT NOT NULL REFERENCES users(id),
+    user_query      TEXT NOT NULL,                     -- 原始自然语言查询
+    generated_sql   TEXT NOT NULL,                     -- AI生成的SQL语句
+    expected_sql    TEXT,                              -- 用户期望的SQL语句
+    is_correct      BOOLEAN NOT NULL,
+    user_rating     SMALLINT NOT NULL DEFAULT 0 CHECK (user_rating >= 0 AND user_rating <= 5), -- 0表示未评分
+    feedback_text   TEXT,
+    category        VARCHAR(32) NOT NULL DEFAULT '',   -- 查询类别，如aggregation/join_query
+    difficulty      VARCHAR(16) NOT NULL DEFAULT '',   -- 查询难度：easy/medium/hard/expert
+    error_type      VARCHAR(64),
+    error_details   TEXT,
+    processing_time BIGINT NOT NULL DEFAULT 0,         -- 处理时间(毫秒)
+    tokens_used     INTEGER NOT NULL DEFAULT 0,
+    model_used      VARCHAR(100) NOT NULL DEFAULT '',
+    connection_id   BIGINT REFERENCES database_connections(id) ON DELETE SET NULL,
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL
+);
+
+-- 启动加载与保留期清理按时间范围扫描
+CREATE INDEX IF NOT EXISTS idx_feedbacks_create_time ON feedbacks(create_time);
+CREATE INDEX IF NOT EXISTS idx_feedbacks_user ON feedbacks(user_id, create_time DESC) WHERE is_deleted = FALSE;
+CREATE INDEX IF NOT EXISTS idx_feedbacks_query_id ON feedbacks(query_id) WHERE is_deleted = FALSE;
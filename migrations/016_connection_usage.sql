@@ -0,0 +1,8 @@
+-- ========================================
+-- Chat2SQL - 连接使用热力图
+-- ========================================
+-- 管理分析接口按连接统计最近N周每个星期几与小时的查询量，
+-- 按连接与创建时间建立索引，避免按时间范围统计时扫描全部查询历史
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_query_history_connection_time
+    ON query_history(connection_id, create_time DESC) WHERE is_deleted = FALSE;
@@ -0,0 +1,10 @@
+-- ========================================
+-- Chat2SQL - 闲置连接清理
+-- ========================================
+-- 长期没有查询的连接会提醒所有者，记录提醒时间以免重复提醒，
+-- 并在宽限期过后（开启自动停用时）据此停用连接
+
+ALTER TABLE database_connections
+    ADD COLUMN IF NOT EXISTS unused_notified_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN database_connections.unused_notified_at IS '上次提醒所有者连接闲置的时间';
@@ -0,0 +1,34 @@
+-- ========================================
+-- Chat2SQL - 第三方集成凭据
+-- ========================================
+-- Slack令牌、Jira凭据、SMTP密码与Webhook密钥等机密与连接密码使用同一密钥加密保存，
+-- API只返回掩码。每个集成的凭据可单独轮换，轮换时替换机密并递增版本号
+
+CREATE TABLE IF NOT EXISTS integration_credentials (
+    id                  BIGSERIAL PRIMARY KEY,
+    integration         VARCHAR(20) NOT NULL CHECK (integration IN ('slack', 'jira', 'smtp', 'webhook', 'teams')),
+    name                VARCHAR(100) NOT NULL,
+    -- 非机密配置，如Jira地址与用户名、SMTP主机与端口
+    settings            JSONB NOT NULL DEFAULT '{}',
+    -- 加密后的机密（base64），删除时清空
+    secret_encrypted    TEXT NOT NULL DEFAULT '',
+    secret_mask         VARCHAR(20) NOT NULL DEFAULT '',
+    version             INTEGER NOT NULL DEFAULT 1,
+    rotated_at          TIMESTAMP WITH TIME ZONE,
+
+    -- 统一基础字段
+    create_by           BIGINT NOT NULL REFERENCES users(id),
+    create_time         TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by           BIGINT NOT NULL REFERENCES users(id),
+    update_time         TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted          BOOLEAN DEFAULT FALSE NOT NULL
+);
+
+-- 删除后可以重新创建同名凭据
+CREATE UNIQUE INDEX IF NOT EXISTS uk_integration_credential_name
+    ON integration_credentials(integration, name) WHERE is_deleted = FALSE;
+
+CREATE TRIGGER tr_integration_credentials_update_time
+    BEFORE UPDATE ON integration_credentials
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
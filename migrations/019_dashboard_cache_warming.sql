@@ -0,0 +1,15 @@
+-- ========================================
+-- Chat2SQL - 仪表盘查询缓存预热
+-- ========================================
+-- 标记为仪表盘使用的保存查询由后台任务在结果缓存过期前重新执行并写入缓存，
+-- 仪表盘加载时总能命中缓存。缓存时间可按查询单独设置，为空时使用全局配置
+
+ALTER TABLE saved_queries
+    ADD COLUMN IF NOT EXISTS dashboard_backed BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS cache_ttl_seconds INTEGER CHECK (cache_ttl_seconds > 0);
+
+COMMENT ON COLUMN saved_queries.dashboard_backed IS '是否为仪表盘使用的查询，开启后定期预热结果缓存';
+COMMENT ON COLUMN saved_queries.cache_ttl_seconds IS '结果缓存时间（秒），为空时使用全局配置';
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_saved_queries_dashboard
+    ON saved_queries(id) WHERE dashboard_backed = TRUE AND is_deleted = FALSE;
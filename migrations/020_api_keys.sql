@@ -0,0 +1,34 @@
+-- ========================================
+-- Chat2SQL - API密钥
+-- ========================================
+-- CI任务与BI工具通过X-API-Key请求头调用SQL与AI接口，不经过JWT登录流程。
+-- 只保存密钥的SHA-256哈希与前缀，明文仅在创建时返回一次；撤销后立即失效
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             BIGINT NOT NULL REFERENCES users(id),
+    name                VARCHAR(100) NOT NULL,
+    -- 密钥前缀，用于在列表中识别密钥
+    key_prefix          VARCHAR(16) NOT NULL,
+    key_hash            CHAR(64) NOT NULL,
+    -- 可调用的接口范围：sql、ai
+    scopes              TEXT[] NOT NULL CHECK (scopes <@ ARRAY['sql', 'ai']::TEXT[] AND cardinality(scopes) > 0),
+    expires_at          TIMESTAMP WITH TIME ZONE,
+    last_used_at        TIMESTAMP WITH TIME ZONE,
+    revoked_at          TIMESTAMP WITH TIME ZONE,
+
+    -- 统一基础字段
+    create_by           BIGINT NOT NULL REFERENCES users(id),
+    create_time         TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by           BIGINT NOT NULL REFERENCES users(id),
+    update_time         TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted          BOOLEAN DEFAULT FALSE NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_api_keys_hash ON api_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id) WHERE is_deleted = FALSE;
+
+CREATE TRIGGER tr_api_keys_update_time
+    BEFORE UPDATE ON api_keys
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
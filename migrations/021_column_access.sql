@@ -0,0 +1,70 @@
+-- ========================================
+-- Chat2SQL - 列访问申请
+-- ========================================
+-- 列数据分级策略脱敏或过滤了用户需要的列时，用户可以提交访问申请，
+-- 由数据所有者（连接所有者）授予有期限的例外或驳回；授权到期后自动恢复默认策略，全程记录审计事件
+
+-- ========================================
+-- 1. 列访问申请表
+-- ========================================
+CREATE TABLE IF NOT EXISTS column_access_requests (
+    id               BIGSERIAL PRIMARY KEY,
+    connection_id    BIGINT NOT NULL REFERENCES database_connections(id),
+    schema_name      VARCHAR(100) NOT NULL,
+    table_name       VARCHAR(100) NOT NULL,
+    column_name      VARCHAR(100) NOT NULL,
+    -- 申请时列的分级，供所有者审阅
+    classification   VARCHAR(20) NOT NULL CHECK (classification IN ('pii', 'financial', 'internal')),
+    requested_by     BIGINT NOT NULL REFERENCES users(id),
+    reason           TEXT NOT NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending'
+                     CHECK (status IN ('pending', 'granted', 'rejected', 'revoked')),
+    decided_by       BIGINT REFERENCES users(id),
+    decided_time     TIMESTAMP WITH TIME ZONE,
+    decision_comment TEXT,
+    -- 授权到期时间，只有granted状态有值
+    expires_at       TIMESTAMP WITH TIME ZONE,
+
+    -- 统一基础字段
+    create_by        BIGINT NOT NULL REFERENCES users(id),
+    create_time      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by        BIGINT NOT NULL REFERENCES users(id),
+    update_time      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted       BOOLEAN DEFAULT FALSE NOT NULL,
+
+    -- 审批人不能是申请人
+    CONSTRAINT check_column_access_decider CHECK (decided_by IS NULL OR decided_by <> requested_by),
+    CONSTRAINT check_column_access_expiry CHECK (status <> 'granted' OR expires_at IS NOT NULL)
+);
+
+-- 同一用户对同一列只能有一个待处理的申请
+CREATE UNIQUE INDEX IF NOT EXISTS uk_column_access_pending
+    ON column_access_requests(connection_id, schema_name, table_name, column_name, requested_by)
+    WHERE status = 'pending' AND is_deleted = FALSE;
+CREATE INDEX IF NOT EXISTS idx_column_access_requester
+    ON column_access_requests(requested_by, create_time DESC) WHERE is_deleted = FALSE;
+-- 构建列策略时只查询生效中的授权
+CREATE INDEX IF NOT EXISTS idx_column_access_grants
+    ON column_access_requests(connection_id, requested_by, expires_at) WHERE status = 'granted' AND is_deleted = FALSE;
+
+CREATE TRIGGER tr_column_access_requests_update_time
+    BEFORE UPDATE ON column_access_requests
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
+
+-- ========================================
+-- 2. 列访问审计事件表
+-- ========================================
+-- 仅追加，不更新不删除
+CREATE TABLE IF NOT EXISTS column_access_events (
+    id              BIGSERIAL PRIMARY KEY,
+    request_id      BIGINT NOT NULL REFERENCES column_access_requests(id),
+    actor_id        BIGINT REFERENCES users(id),
+    action          VARCHAR(20) NOT NULL
+                    CHECK (action IN ('created', 'granted', 'rejected', 'revoked')),
+    detail          TEXT,
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_column_access_events_request
+    ON column_access_events(request_id, create_time);
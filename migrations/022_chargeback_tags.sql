@@ -0,0 +1,15 @@
+-- ========================================
+-- Chat2SQL - 费用归属标签
+-- ========================================
+-- 请求可以携带成本中心与项目标签，取值必须在工作空间配置的范围内；
+-- 标签随查询历史保存，AI与数据库费用据此按团队分摊
+
+-- 标签取值范围：{"cost_centers": ["CC-100"], "projects": ["growth"], "required": false}
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS chargeback_taxonomy JSONB;
+
+ALTER TABLE query_history ADD COLUMN IF NOT EXISTS cost_center VARCHAR(64);
+ALTER TABLE query_history ADD COLUMN IF NOT EXISTS project VARCHAR(64);
+
+-- 按成本中心汇总费用
+CREATE INDEX IF NOT EXISTS idx_query_history_cost_center ON query_history(cost_center, create_time)
+    WHERE cost_center IS NOT NULL AND is_deleted = false;
@@ -0,0 +1,22 @@
+-- ========================================
+-- Chat2SQL - 模型用量与成本
+-- ========================================
+-- 按天、用户、提供商与模型汇总Token用量与估算成本，重启后据此恢复当日预算统计；
+-- 每次调用累加到对应行，不保存单次调用明细
+
+CREATE TABLE IF NOT EXISTS llm_usage_daily (
+    day                 DATE NOT NULL,
+    user_id             BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider            VARCHAR(50) NOT NULL,
+    model               VARCHAR(100) NOT NULL,
+    queries             BIGINT NOT NULL DEFAULT 0,
+    input_tokens        BIGINT NOT NULL DEFAULT 0,
+    output_tokens       BIGINT NOT NULL DEFAULT 0,
+    -- 估算成本（美元）
+    cost                NUMERIC(14, 6) NOT NULL DEFAULT 0,
+    update_time         TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (day, user_id, provider, model)
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_daily_user ON llm_usage_daily(user_id, day DESC);
@@ -0,0 +1,21 @@
+-- ========================================
+-- Chat2SQL - 表结构变更事件
+-- ========================================
+-- 表结构定期刷新发现变化时按表或列逐条记录变更事件，
+-- 与连接上执行失败的查询按时间对照，定位"删除某列两天后这条查询开始失败"一类问题
+
+CREATE TABLE IF NOT EXISTS schema_change_events (
+    id              BIGSERIAL PRIMARY KEY,
+    connection_id   BIGINT NOT NULL REFERENCES database_connections(id),
+    -- added_table/removed_table/added_column/removed_column/changed_column
+    change_type     VARCHAR(20) NOT NULL,
+    -- 表为schema.table，列为schema.table.column
+    object_name     VARCHAR(320) NOT NULL,
+    detected_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schema_change_events_connection ON schema_change_events(connection_id, detected_at DESC);
+
+-- 按连接查询一段时间内执行失败的记录
+CREATE INDEX IF NOT EXISTS idx_query_history_connection_failures ON query_history(connection_id, create_time DESC)
+    WHERE status IN ('error', 'timeout') AND is_deleted = false;
@@ -0,0 +1,9 @@
+-- ========================================
+-- Chat2SQL - 取消正在执行的查询
+-- ========================================
+-- 用户通过DELETE /api/v1/sql/execute/:query_id取消的查询在查询历史中记录为cancelled，
+-- 与执行失败（error）和超时（timeout）区分
+
+ALTER TABLE query_history DROP CONSTRAINT IF EXISTS query_history_status_check;
+ALTER TABLE query_history ADD CONSTRAINT query_history_status_check
+    CHECK (status IN ('pending', 'success', 'error', 'timeout', 'cached', 'cancelled'));
@@ -0,0 +1,36 @@
+-- ========================================
+-- Chat2SQL - 异步查询任务
+-- ========================================
+-- 耗时数分钟的分析查询以异步方式提交：接口立即返回任务ID，后台工作池领取任务执行，
+-- 客户端轮询任务的状态、已读取行数与结果。任务状态持久化在数据库中，
+-- 服务重启后执行中断的任务按心跳超时重新排队
+
+CREATE TABLE IF NOT EXISTS query_jobs (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT NOT NULL REFERENCES users(id),
+    connection_id   BIGINT NOT NULL REFERENCES database_connections(id),
+    -- 对应的查询历史记录，任务结束时回填执行结果
+    query_history_id BIGINT REFERENCES query_history(id) ON DELETE SET NULL,
+    sql_text        TEXT NOT NULL,
+    row_limit       INTEGER NOT NULL CHECK (row_limit > 0),
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'running', 'completed', 'failed', 'cancelled')),
+    -- 已读取的行数，执行中定期更新，同时作为执行实例的心跳
+    rows_read       BIGINT DEFAULT 0 NOT NULL,
+    -- 执行结果：{"columns":[...],"rows":[...],"truncated":false,...}
+    result          JSONB,
+    error_message   TEXT,
+    attempts        INTEGER DEFAULT 0 NOT NULL,
+    started_time    TIMESTAMP WITH TIME ZONE,
+    completed_time  TIMESTAMP WITH TIME ZONE,
+
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_jobs_pending
+    ON query_jobs(create_time) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_query_jobs_running
+    ON query_jobs(update_time) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_query_jobs_completed
+    ON query_jobs(completed_time) WHERE completed_time IS NOT NULL;
@@ -0,0 +1,10 @@
+-- ========================================
+-- Chat2SQL - 连接级结果上限
+-- ========================================
+-- 查询结果的行数与大小上限默认取SQL_MAX_ROWS与SQL_MAX_RESULT_MB，
+-- 连接可以单独设置更大或更小的上限，为空表示使用全局配置
+
+ALTER TABLE database_connections ADD COLUMN IF NOT EXISTS max_rows INTEGER
+    CHECK (max_rows IS NULL OR max_rows > 0);
+ALTER TABLE database_connections ADD COLUMN IF NOT EXISTS max_result_mb INTEGER
+    CHECK (max_result_mb IS NULL OR max_result_mb > 0);
@@ -0,0 +1,68 @@
+-- ========================================
+-- Chat2SQL - 定时执行保存查询
+-- ========================================
+-- 保存查询可按cron表达式定时执行，结果保存在执行记录中，并可通过邮件（CSV附件）或Webhook投递。
+-- 调度器按next_run_time领取到期的计划，领取时把next_run_time推后一个租约时长，
+-- 多实例部署时不会重复执行；执行结束后按cron表达式计算真正的下一次执行时间
+
+-- ========================================
+-- 1. 执行计划表
+-- ========================================
+CREATE TABLE IF NOT EXISTS query_schedules (
+    id              BIGSERIAL PRIMARY KEY,
+    saved_query_id  BIGINT NOT NULL REFERENCES saved_queries(id),
+    workspace_id    BIGINT NOT NULL REFERENCES workspaces(id),
+    -- 五段式cron表达式，按timezone中的本地时间解释
+    cron_expr       VARCHAR(100) NOT NULL,
+    timezone        VARCHAR(64) DEFAULT 'UTC' NOT NULL,
+    delivery_type   VARCHAR(20) DEFAULT 'none' NOT NULL
+                    CHECK (delivery_type IN ('none', 'email', 'webhook')),
+    -- 邮件为逗号分隔的收件人地址，Webhook为接收结果的URL
+    delivery_target TEXT,
+    enabled         BOOLEAN DEFAULT TRUE NOT NULL,
+    next_run_time   TIMESTAMP WITH TIME ZONE,
+    last_run_time   TIMESTAMP WITH TIME ZONE,
+    last_status     VARCHAR(20),
+
+    -- 统一基础字段
+    create_by       BIGINT NOT NULL REFERENCES users(id),
+    create_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    update_by       BIGINT NOT NULL REFERENCES users(id),
+    update_time     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    is_deleted      BOOLEAN DEFAULT FALSE NOT NULL,
+
+    CONSTRAINT check_query_schedule_target CHECK (delivery_type = 'none' OR delivery_target IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_schedules_due
+    ON query_schedules(next_run_time) WHERE enabled = TRUE AND is_deleted = FALSE;
+CREATE INDEX IF NOT EXISTS idx_query_schedules_saved_query
+    ON query_schedules(saved_query_id) WHERE is_deleted = FALSE;
+
+CREATE TRIGGER tr_query_schedules_update_time
+    BEFORE UPDATE ON query_schedules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp_trigger();
+
+-- ========================================
+-- 2. 执行记录表
+-- ========================================
+CREATE TABLE IF NOT EXISTS query_schedule_runs (
+    id              BIGSERIAL PRIMARY KEY,
+    schedule_id     BIGINT NOT NULL REFERENCES query_schedules(id),
+    status          VARCHAR(20) NOT NULL CHECK (status IN ('success', 'failed')),
+    row_count       BIGINT DEFAULT 0 NOT NULL,
+    -- 执行结果：{"columns":[...],"rows":[...],"truncated":false,...}，超过保存行数上限的部分不保存
+    result          JSONB,
+    error_message   TEXT,
+    delivery_status VARCHAR(20) DEFAULT 'skipped' NOT NULL
+                    CHECK (delivery_status IN ('skipped', 'sent', 'failed')),
+    delivery_error  TEXT,
+    started_time    TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_time  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_schedule_runs_schedule
+    ON query_schedule_runs(schedule_id, started_time DESC);
+CREATE INDEX IF NOT EXISTS idx_query_schedule_runs_completed
+    ON query_schedule_runs(completed_time);
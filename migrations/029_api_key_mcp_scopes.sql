@@ -0,0 +1,11 @@
+-- ========================================
+-- Chat2SQL - API密钥的MCP工具范围
+-- ========================================
+-- 通过API密钥调用MCP端点时按密钥的mcp:*范围限制可用工具，
+-- 例如只授予mcp:connections:read与mcp:schema:read的密钥不能执行SQL
+
+ALTER TABLE api_keys DROP CONSTRAINT IF EXISTS api_keys_scopes_check;
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_scopes_check CHECK (
+    scopes <@ ARRAY['sql', 'ai', 'mcp:connections:read', 'mcp:schema:read', 'mcp:sql:generate', 'mcp:sql:execute']::TEXT[]
+    AND cardinality(scopes) > 0
+);
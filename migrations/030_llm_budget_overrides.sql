@@ -0,0 +1,17 @@
+-- ========================================
+-- Chat2SQL - 每用户模型预算上调
+-- ========================================
+-- 预算上调申请审批通过后，在有效期内以上调后的每日上限替代DAILY_BUDGET_PER_USER；
+-- 每个用户只保留最近一次生效的上调，过期后恢复全局上限
+
+CREATE TABLE IF NOT EXISTS llm_budget_overrides (
+    user_id             BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- 上调后的每日上限（美元）
+    daily_limit         NUMERIC(14, 6) NOT NULL CHECK (daily_limit > 0),
+    expires_at          TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- 批准此次上调的审批申请
+    approval_id         BIGINT REFERENCES approval_requests(id) ON DELETE SET NULL,
+    update_time         TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_budget_overrides_expires ON llm_budget_overrides(expires_at);
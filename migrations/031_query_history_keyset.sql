@@ -0,0 +1,8 @@
+-- ========================================
+-- Chat2SQL - 查询历史键集分页
+-- ========================================
+-- 查询历史列表按(create_time, id)倒序做键集分页，游标记录上一页最后一条记录的排序键，
+-- 分页期间有新记录写入时不会重复或跳过记录；索引加入id，同一时间写入的多条记录也能直接按索引定位
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_query_history_user_time_id
+    ON query_history(user_id, create_time DESC, id DESC) WHERE is_deleted = FALSE;
@@ -0,0 +1,147 @@
+// Package client Chat2SQL HTTP API的Go SDK
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client Chat2SQL API客户端
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option 客户端可选配置
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义的HTTP客户端
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New 创建客户端；token为登录获得的访问令牌
+func New(baseURL, token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GenerateResult SQL生成结果
+type GenerateResult struct {
+	SQL            string  `json:"sql"`
+	Confidence     float64 `json:"confidence"`
+	ProcessingTime int64   `json:"processing_time_ms"`
+	QueryID        string  `json:"query_id"`
+
+	// RequiresConfirmation 置信度低于服务端阈值，执行前应请求用户确认
+	RequiresConfirmation bool `json:"requires_confirmation"`
+}
+
+// ExecuteResult SQL执行结果
+type ExecuteResult struct {
+	QueryID       int64            `json:"query_id"`
+	ExecutionTime int32            `json:"execution_time"`
+	RowCount      int32            `json:"row_count"`
+	Status        string           `json:"status"`
+	Data          []map[string]any `json:"data,omitempty"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// APIError 服务端返回的错误
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("chat2sql: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// GenerateSQL 将自然语言问题转换为SQL
+func (c *Client) GenerateSQL(ctx context.Context, connectionID int64, question string) (*GenerateResult, error) {
+	var result GenerateResult
+	err := c.post(ctx, "/api/v1/ai/chat2sql", map[string]any{
+		"query":         question,
+		"connection_id": connectionID,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExecuteSQL 在指定连接上执行SQL（服务端仅允许只读查询）
+func (c *Client) ExecuteSQL(ctx context.Context, connectionID int64, sql, naturalQuery string) (*ExecuteResult, error) {
+	body := map[string]any{
+		"sql":           sql,
+		"connection_id": connectionID,
+	}
+	if naturalQuery != "" {
+		body["natural_query"] = naturalQuery
+	}
+
+	var result ExecuteResult
+	if err := c.post(ctx, "/api/v1/sql/execute", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// post 发送JSON请求并解析响应
+func (c *Client) post(ctx context.Context, path string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("chat2sql: 编码请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("chat2sql: 创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("chat2sql: 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("chat2sql: 读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("chat2sql: 解析响应失败: %w", err)
+	}
+	return nil
+}
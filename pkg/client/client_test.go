@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GenerateAndExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, float64(7), body["connection_id"])
+
+		switch r.URL.Path {
+		case "/api/v1/ai/chat2sql":
+			assert.Equal(t, "订单数", body["query"])
+			_, _ = w.Write([]byte(`{"sql":"SELECT count(*) FROM orders","confidence":0.9}`))
+		case "/api/v1/sql/execute":
+			assert.Equal(t, "SELECT count(*) FROM orders", body["sql"])
+			_, _ = w.Write([]byte(`{"query_id":1,"row_count":1,"status":"success","data":[{"count":3}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", "secret")
+
+	generated, err := c.GenerateSQL(context.Background(), 7, "订单数")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT count(*) FROM orders", generated.SQL)
+
+	result, err := c.ExecuteSQL(context.Background(), 7, generated.SQL, "订单数")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), result.RowCount)
+	assert.Equal(t, float64(3), result.Data[0]["count"])
+}
+
+func TestClient_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"code":"FORBIDDEN","message":"无权访问该连接"}`))
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL, "").ExecuteSQL(context.Background(), 1, "SELECT 1", "")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	assert.Equal(t, "FORBIDDEN", apiErr.Code)
+}
@@ -0,0 +1,174 @@
+// Package langchain 将Chat2SQL Go SDK包装为langchaingo Tool，供其他Go Agent调用
+package langchain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tmc/langchaingo/tools"
+
+	"chat2sql-go/pkg/client"
+)
+
+// 编译期检查工具类型实现tools.Tool接口
+var (
+	_ tools.Tool = (*GenerateSQLTool)(nil)
+	_ tools.Tool = (*QueryTool)(nil)
+)
+
+// defaultMaxRows 返回给Agent的默认最大行数
+const defaultMaxRows = 50
+
+// SQLClient 工具依赖的SDK能力（*client.Client实现该接口）
+type SQLClient interface {
+	GenerateSQL(ctx context.Context, connectionID int64, question string) (*client.GenerateResult, error)
+	ExecuteSQL(ctx context.Context, connectionID int64, sql, naturalQuery string) (*client.ExecuteResult, error)
+}
+
+// GenerateSQLTool 只生成SQL、不执行的工具
+type GenerateSQLTool struct {
+	client       SQLClient
+	connectionID int64
+}
+
+// NewGenerateSQLTool 创建SQL生成工具，绑定到指定数据库连接
+func NewGenerateSQLTool(c SQLClient, connectionID int64) *GenerateSQLTool {
+	return &GenerateSQLTool{client: c, connectionID: connectionID}
+}
+
+// Name 工具名称
+func (t *GenerateSQLTool) Name() string {
+	return "chat2sql_generate"
+}
+
+// Description 工具描述
+func (t *GenerateSQLTool) Description() string {
+	return "Converts a natural language question about the business database into a SQL query without running it. " +
+		"Input should be the question in plain language."
+}
+
+// Call 生成SQL
+func (t *GenerateSQLTool) Call(ctx context.Context, input string) (string, error) {
+	question := strings.TrimSpace(input)
+	if question == "" {
+		return "", fmt.Errorf("question is empty")
+	}
+
+	result, err := t.client.GenerateSQL(ctx, t.connectionID, question)
+	if err != nil {
+		return "", err
+	}
+	return result.SQL, nil
+}
+
+// QueryTool 生成并执行只读SQL、返回结果表格的工具
+type QueryTool struct {
+	client       SQLClient
+	connectionID int64
+	maxRows      int
+}
+
+// QueryToolOption QueryTool可选配置
+type QueryToolOption func(*QueryTool)
+
+// WithMaxRows 设置返回给Agent的最大行数
+func WithMaxRows(n int) QueryToolOption {
+	return func(t *QueryTool) {
+		if n > 0 {
+			t.maxRows = n
+		}
+	}
+}
+
+// NewQueryTool 创建查询工具，绑定到指定数据库连接
+func NewQueryTool(c SQLClient, connectionID int64, opts ...QueryToolOption) *QueryTool {
+	t := &QueryTool{client: c, connectionID: connectionID, maxRows: defaultMaxRows}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Name 工具名称
+func (t *QueryTool) Name() string {
+	return "chat2sql_query"
+}
+
+// Description 工具描述
+func (t *QueryTool) Description() string {
+	return "Answers a natural language question by generating a read-only SQL query against the business database, " +
+		"running it and returning the result rows. Input should be the question in plain language."
+}
+
+// Call 生成SQL并执行；非只读语句在发送执行前即被拒绝
+func (t *QueryTool) Call(ctx context.Context, input string) (string, error) {
+	question := strings.TrimSpace(input)
+	if question == "" {
+		return "", fmt.Errorf("question is empty")
+	}
+
+	generated, err := t.client.GenerateSQL(ctx, t.connectionID, question)
+	if err != nil {
+		return "", err
+	}
+	if !isReadOnly(generated.SQL) {
+		return "", fmt.Errorf("refusing to execute non read-only SQL: %s", generated.SQL)
+	}
+
+	result, err := t.client.ExecuteSQL(ctx, t.connectionID, generated.SQL, question)
+	if err != nil {
+		return "", err
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("query failed: %s", result.Error)
+	}
+
+	return "SQL: " + generated.SQL + "\n" + formatRows(result.Data, t.maxRows, int(result.RowCount)), nil
+}
+
+// isReadOnly 粗粒度检查SQL是否为只读查询（服务端仍会做完整校验）
+func isReadOnly(sql string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(sql))
+	if strings.Contains(strings.TrimSuffix(upper, ";"), ";") {
+		return false
+	}
+	return strings.HasPrefix(upper, "SELECT") || strings.HasPrefix(upper, "WITH")
+}
+
+// formatRows 将结果行格式化为管道分隔的文本表格
+func formatRows(rows []map[string]any, maxRows, total int) string {
+	if len(rows) == 0 {
+		return "(no rows)"
+	}
+
+	columns := make([]string, 0, len(rows[0]))
+	for col := range rows[0] {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(columns, " | "))
+	sb.WriteString("\n")
+
+	shown := min(len(rows), maxRows)
+	for _, row := range rows[:shown] {
+		values := make([]string, len(columns))
+		for i, col := range columns {
+			if v := row[col]; v != nil {
+				values[i] = fmt.Sprint(v)
+			} else {
+				values[i] = "NULL"
+			}
+		}
+		sb.WriteString(strings.Join(values, " | "))
+		sb.WriteString("\n")
+	}
+
+	if total > shown {
+		fmt.Fprintf(&sb, "(%d of %d rows shown)", shown, total)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
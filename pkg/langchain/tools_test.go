@@ -0,0 +1,59 @@
+package langchain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"chat2sql-go/pkg/client"
+)
+
+type fakeClient struct {
+	sql      string
+	rows     []map[string]any
+	executed int
+}
+
+func (f *fakeClient) GenerateSQL(_ context.Context, _ int64, _ string) (*client.GenerateResult, error) {
+	return &client.GenerateResult{SQL: f.sql}, nil
+}
+
+func (f *fakeClient) ExecuteSQL(_ context.Context, _ int64, _, _ string) (*client.ExecuteResult, error) {
+	f.executed++
+	return &client.ExecuteResult{Data: f.rows, RowCount: int32(len(f.rows))}, nil
+}
+
+func TestGenerateSQLTool_Call(t *testing.T) {
+	fake := &fakeClient{sql: "SELECT 1"}
+	out, err := NewGenerateSQLTool(fake, 1).Call(context.Background(), " 测试 ")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", out)
+	assert.Equal(t, 0, fake.executed)
+
+	_, err = NewGenerateSQLTool(fake, 1).Call(context.Background(), "  ")
+	assert.Error(t, err)
+}
+
+func TestQueryTool_Call(t *testing.T) {
+	fake := &fakeClient{
+		sql:  "SELECT name, total FROM orders",
+		rows: []map[string]any{{"name": "a", "total": 1}, {"name": "b", "total": nil}, {"name": "c", "total": 3}},
+	}
+
+	out, err := NewQueryTool(fake, 1, WithMaxRows(2)).Call(context.Background(), "订单")
+	require.NoError(t, err)
+	assert.Contains(t, out, "SQL: SELECT name, total FROM orders")
+	assert.Contains(t, out, "name | total\na | 1\nb | NULL")
+	assert.Contains(t, out, "(2 of 3 rows shown)")
+}
+
+func TestQueryTool_RejectsWrites(t *testing.T) {
+	for _, sql := range []string{"DELETE FROM orders", "SELECT 1; DROP TABLE orders"} {
+		fake := &fakeClient{sql: sql}
+		_, err := NewQueryTool(fake, 1).Call(context.Background(), "删除订单")
+		assert.Error(t, err, sql)
+		assert.Equal(t, 0, fake.executed, sql)
+	}
+}